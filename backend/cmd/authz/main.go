@@ -1,16 +1,37 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"qm-mcp-server/internal/authz/app"
+	"qm-mcp-server/internal/authz/config"
 )
 
 // main 主函数
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "只校验配置文件后退出，用于 CI 和 Helm pre-install hook")
+	flag.Parse()
+	if *validateConfig {
+		err := config.Load()
+		if err == nil {
+			err = config.GlobalConfig.Validate()
+		}
+		if err != nil {
+			fmt.Printf("Config validation failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Config is valid")
+		return
+	}
+
 	// 创建应用程序实例
 	appInstance := app.New()
+	if appInstance == nil {
+		log.Fatalf("Failed to load configuration, run with --validate-config for details")
+	}
 
 	// 初始化应用程序
 	if err := appInstance.Initialize(); err != nil {
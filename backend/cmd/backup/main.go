@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"qm-mcp-server/internal/market/backup"
+	"qm-mcp-server/internal/market/config"
+	dbpkg "qm-mcp-server/pkg/database"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// passphraseEnv 备份口令环境变量，未指定 -passphrase 时使用
+const passphraseEnv = "MCPBOX_BACKUP_PASSPHRASE"
+
+// 备份与恢复 MCPbox 元数据：实例、模板、环境 (连接配置加密)、镜像仓库凭证、代码包元数据、用户、角色和部门。
+// 代码包文件不在归档中，使用对象存储时无需迁移，使用本地存储时需单独复制 codePath 目录
+func main() {
+	mode := flag.String("mode", "export", "export 导出备份，restore 从备份恢复")
+	file := flag.String("file", "", "备份归档路径 (.tar.gz)")
+	passphrase := flag.String("passphrase", "", "加密归档中环境配置和凭证的口令，默认读取环境变量 "+passphraseEnv)
+	conflict := flag.String("conflict", string(backup.ConflictSkip), "恢复时记录已存在的处理方式：skip、overwrite、rename")
+	dryRun := flag.Bool("dry-run", false, "导出时只统计记录数；恢复时在事务中执行后回滚")
+	flag.Parse()
+
+	if *passphrase == "" {
+		*passphrase = os.Getenv(passphraseEnv)
+	}
+	var err error
+	switch *mode {
+	case "export":
+		err = runExport(*file, *passphrase, *dryRun)
+	case "restore":
+		err = runRestore(*file, *passphrase, *conflict, *dryRun)
+	default:
+		err = fmt.Errorf("unknown mode %q, expected export or restore", *mode)
+	}
+	if err != nil {
+		fmt.Printf("备份任务失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// setup 加载 market 配置并连接数据库，恢复时先执行迁移，新数据库可直接恢复
+func setup(migrate bool) (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	initDB := dbpkg.Init
+	if migrate {
+		initDB = dbpkg.Migrate
+	}
+	if err := initDB(&cfg.Database); err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return cfg, nil
+}
+
+func runExport(file, passphrase string, dryRun bool) error {
+	if file == "" && !dryRun {
+		return fmt.Errorf("-file is required")
+	}
+	cfg, err := setup(false)
+	if err != nil {
+		return err
+	}
+	defer mysql.Close()
+
+	archive, err := backup.Export(context.Background(), cfg.Secret, passphrase)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		logger.Info("Backup dry run completed", zap.Any("counts", archive.Manifest.Counts))
+		return nil
+	}
+
+	// 先写入临时文件，导出失败时不覆盖已有备份
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	err = archive.Write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	logger.Info("Backup completed",
+		zap.String("file", file),
+		zap.Int("format_version", archive.Manifest.FormatVersion),
+		zap.Any("counts", archive.Manifest.Counts),
+	)
+	return nil
+}
+
+func runRestore(file, passphrase, conflict string, dryRun bool) error {
+	if file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	policy, err := backup.ParseConflictPolicy(conflict)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := setup(true)
+	if err != nil {
+		return err
+	}
+	defer mysql.Close()
+
+	archive, err := backup.ReadArchive(f)
+	if err != nil {
+		return err
+	}
+	logger.Info("Restore backup",
+		zap.String("file", file),
+		zap.String("app_version", archive.Manifest.AppVersion),
+		zap.Time("created_at", archive.Manifest.CreatedAt),
+		zap.Any("counts", archive.Manifest.Counts),
+		zap.String("conflict", string(policy)),
+		zap.Bool("dry_run", dryRun),
+	)
+	stats, err := backup.Restore(context.Background(), archive, backup.RestoreOptions{
+		Conflict:   policy,
+		DryRun:     dryRun,
+		Secret:     cfg.Secret,
+		Passphrase: passphrase,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Restore completed", zap.Any("stats", stats), zap.Bool("dry_run", dryRun))
+	return nil
+}
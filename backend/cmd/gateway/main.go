@@ -1,14 +1,32 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"qm-mcp-server/internal/gateway/app"
+	"qm-mcp-server/internal/gateway/config"
 )
 
 // main 主函数
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "只校验配置文件后退出，用于 CI 和 Helm pre-install hook")
+	flag.Parse()
+	if *validateConfig {
+		err := config.Load()
+		if err == nil {
+			err = config.GlobalConfig.Validate()
+		}
+		if err != nil {
+			fmt.Printf("Config validation failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Config is valid")
+		return
+	}
+
 	// 创建应用程序实例
 	appInstance, err := app.New()
 	if err != nil {
@@ -1,15 +1,56 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+
 	"qm-mcp-server/internal/init/app"
 )
 
+// 环境变量，命令行参数未指定时使用，便于 CI 中非交互式安装
+const (
+	adminUsernameEnv    = "MCPBOX_INIT_ADMIN_USERNAME"
+	adminPasswordEnv    = "MCPBOX_INIT_ADMIN_PASSWORD"
+	generatePasswordEnv = "MCPBOX_INIT_GENERATE_PASSWORD"
+	kubeconfigEnv       = "MCPBOX_INIT_KUBECONFIG"
+	namespaceEnv        = "MCPBOX_INIT_NAMESPACE"
+)
+
+// 初始化管理员、默认环境、代码包和模板数据，重复执行时只创建不存在的数据
 func main() {
+	opts := app.Options{}
+	flag.StringVar(&opts.AdminUsername, "admin-username", os.Getenv(adminUsernameEnv), "管理员用户名，覆盖配置文件，环境变量 "+adminUsernameEnv)
+	flag.StringVar(&opts.AdminPassword, "admin-password", os.Getenv(adminPasswordEnv), "管理员密码，覆盖配置文件，环境变量 "+adminPasswordEnv)
+	flag.BoolVar(&opts.GeneratePassword, "generate-password", envBool(generatePasswordEnv), "生成随机管理员密码并只输出一次，环境变量 "+generatePasswordEnv)
+	flag.BoolVar(&opts.ResetPassword, "reset-password", false, "管理员已存在时重置密码，默认保留已有密码")
+	flag.StringVar(&opts.KubeconfigPath, "kubeconfig", os.Getenv(kubeconfigEnv), "默认环境的 kubeconfig 路径，环境变量 "+kubeconfigEnv)
+	flag.StringVar(&opts.Namespace, "namespace", os.Getenv(namespaceEnv), "默认环境的命名空间，环境变量 "+namespaceEnv)
+	flag.BoolVar(&opts.Check, "check", false, "只报告将要创建的数据，不写入数据库")
+	validateConfig := flag.Bool("validate-config", false, "只校验配置文件和参数后退出，用于 CI 和 Helm pre-install hook")
+	flag.Parse()
+
+	if opts.GeneratePassword && opts.AdminPassword != "" {
+		log.Fatalf("--admin-password and --generate-password cannot be used together")
+	}
+
 	// 创建应用程序实例
 	appInstance := app.New()
+	if appInstance == nil {
+		log.Fatalf("Failed to load init configuration")
+	}
+	appInstance.ApplyOptions(opts)
+
+	if *validateConfig {
+		if err := appInstance.ValidateConfig(); err != nil {
+			fmt.Printf("Config validation failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Config is valid")
+		return
+	}
 
 	// 初始化应用程序
 	if err := appInstance.Initialize(); err != nil {
@@ -23,5 +64,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Println("Admin user created successfully!")
+	if !opts.Check {
+		fmt.Println("Initialization completed successfully!")
+	}
+}
+
+func envBool(name string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(name))
+	return v
 }
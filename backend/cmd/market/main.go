@@ -1,13 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	"qm-mcp-server/internal/market/app"
+	"qm-mcp-server/internal/market/config"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "只校验配置文件后退出，用于 CI 和 Helm pre-install hook")
+	flag.Parse()
+	if *validateConfig {
+		cfg, err := config.Load()
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			fmt.Printf("配置校验失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("配置校验通过")
+		return
+	}
+
 	// 创建应用程序实例
 	appInstance, err := app.New()
 	if err != nil {
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"qm-mcp-server/internal/mcpcanctl"
+)
+
+func main() {
+	// Ctrl+C 结束 logs --follow 等持续执行的命令
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := mcpcanctl.Run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "mcpcanctl: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/codepackage"
+	"qm-mcp-server/pkg/common"
+	dbpkg "qm-mcp-server/pkg/database"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 一次性迁移：将本地 codePath 中的代码包原始文件复制到 storage.codeBackend 配置的对象存储，
+// 迁移后 market 服务按需从对象存储下载并解压到本地缓存
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只打印需要复制的代码包，不写入对象存储")
+	overwrite := flag.Bool("overwrite", false, "覆盖对象存储中已存在的代码包")
+	withEdits := flag.Bool("with-edits", false, "同时复制解压目录中的所有文件作为编辑记录，保留在本地编辑器中修改过的文件")
+	flag.Parse()
+
+	if err := run(*dryRun, *overwrite, *withEdits); err != nil {
+		fmt.Printf("迁移代码包失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dryRun, overwrite, withEdits bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	if cfg.Storage.CodeBackend.Type == "" || cfg.Storage.CodeBackend.Type == common.CodeStorageFilesystem {
+		return fmt.Errorf("storage.codeBackend.type is %q, configure the target object storage first", cfg.Storage.CodeBackend.Type)
+	}
+	storage, err := codepackage.NewStorage(cfg.Storage.CodeBackend, cfg.Storage.CodePath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	if err := dbpkg.Init(&cfg.Database); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer mysql.Close()
+
+	ctx := context.Background()
+	packages, err := mysql.McpCodePackageRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list code packages: %w", err)
+	}
+
+	migrated, skipped, missing := 0, 0, 0
+	for _, pkg := range packages {
+		key := codepackage.OriginalKey(pkg)
+		if !overwrite {
+			existing, err := storage.List(ctx, path.Dir(key))
+			if err != nil {
+				return fmt.Errorf("failed to check package %s: %w", pkg.PackageID, err)
+			}
+			if slices.Contains(existing, key) {
+				skipped++
+				continue
+			}
+		}
+
+		keys := []string{key}
+		if withEdits && pkg.ExtractedPath != "" {
+			edits, err := extractedFiles(cfg.Storage.CodePath, pkg)
+			if err != nil {
+				return fmt.Errorf("failed to list extracted files of package %s: %w", pkg.PackageID, err)
+			}
+			keys = append(keys, edits...)
+		}
+
+		logger.Info("Copy code package",
+			zap.String("package_id", pkg.PackageID),
+			zap.String("key", key),
+			zap.Int("files", len(keys)),
+			zap.Bool("dry_run", dryRun),
+		)
+		if dryRun {
+			migrated++
+			continue
+		}
+		if err := copyObjects(ctx, cfg.Storage.CodePath, storage, pkg, keys); err != nil {
+			if errors.Is(err, codepackage.ErrObjectNotFound) {
+				logger.Warn("Original package file not found, skipped", zap.String("package_id", pkg.PackageID), zap.Error(err))
+				missing++
+				continue
+			}
+			return fmt.Errorf("failed to copy package %s: %w", pkg.PackageID, err)
+		}
+		migrated++
+	}
+
+	logger.Info("Code package migration completed",
+		zap.Int("total", len(packages)),
+		zap.Int("migrated", migrated),
+		zap.Int("skipped", skipped),
+		zap.Int("missing", missing),
+		zap.Bool("dry_run", dryRun),
+	)
+	return nil
+}
+
+// extractedFiles 返回解压目录中所有文件相对 codePath 的路径
+func extractedFiles(root string, pkg *model.McpCodePackage) ([]string, error) {
+	dir := filepath.Join(root, pkg.ExtractedPath)
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// copyObjects 将本地文件复制到对象存储，解压目录中的文件写入编辑记录
+func copyObjects(ctx context.Context, root string, remote codepackage.Storage, pkg *model.McpCodePackage, keys []string) error {
+	extracted := filepath.ToSlash(pkg.ExtractedPath) + "/"
+	for _, key := range keys {
+		target := key
+		if rel, ok := strings.CutPrefix(key, extracted); ok {
+			target = codepackage.EditKey(pkg, rel)
+		}
+		if err := putFile(ctx, remote, target, filepath.Join(root, filepath.FromSlash(key))); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// putFile 上传单个本地文件
+func putFile(ctx context.Context, remote codepackage.Storage, key, filePath string) error {
+	f, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", codepackage.ErrObjectNotFound, filePath)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return remote.Put(ctx, key, f, info.Size())
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	dbpkg "qm-mcp-server/pkg/database"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 一次性迁移：将实例公共代理配置中保存的完整访问地址 (域名 + 网关前缀 + 实例路径) 改写为实例相对路径，
+// 域名和网关前缀改为在构建响应时根据 market 配置动态解析
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只打印需要改写的实例，不写入数据库")
+	flag.Parse()
+
+	if err := run(*dryRun); err != nil {
+		fmt.Printf("迁移公共代理配置失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dryRun bool) error {
+	// 使用 market 服务配置，旧地址中的网关前缀按当前配置识别
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	if err := dbpkg.Init(&cfg.Database); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer mysql.Close()
+
+	ctx := context.Background()
+	instances, err := mysql.McpInstanceRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	migrated := 0
+	for _, instance := range instances {
+		publicProxyConfig, changed := common.RelativePublicProxyConfig(instance.PublicProxyConfig)
+		if !changed {
+			continue
+		}
+		logger.Info("Rewrite public proxy config",
+			zap.String("instance_id", instance.InstanceID),
+			zap.String("old", string(instance.PublicProxyConfig)),
+			zap.String("new", string(publicProxyConfig)),
+			zap.Bool("dry_run", dryRun),
+		)
+		if !dryRun {
+			if err := mysql.McpInstanceRepo.UpdatePublicProxyConfig(ctx, instance.InstanceID, publicProxyConfig); err != nil {
+				return fmt.Errorf("failed to update instance %s: %w", instance.InstanceID, err)
+			}
+		}
+		migrated++
+	}
+
+	logger.Info("Public proxy config migration completed",
+		zap.Int("total", len(instances)),
+		zap.Int("migrated", migrated),
+		zap.Bool("dry_run", dryRun),
+	)
+	return nil
+}
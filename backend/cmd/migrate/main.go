@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"qm-mcp-server/internal/init/config"
+	dbpkg "qm-mcp-server/pkg/database"
+	"qm-mcp-server/pkg/database/migration"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 执行数据库表结构迁移，使用 init 服务配置；init 任务启动时也会执行相同的迁移
+func main() {
+	status := flag.Bool("status", false, "只打印当前和目标表结构版本，不执行迁移")
+	flag.Parse()
+
+	if err := run(*status); err != nil {
+		fmt.Printf("数据库迁移失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(status bool) error {
+	if err := config.Load(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := config.GlobalConfig
+	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	if !status {
+		if err := dbpkg.Migrate(&cfg.Database); err != nil {
+			return err
+		}
+		return mysql.Close()
+	}
+
+	// 只读取版本，跳过启动时的版本检查
+	if err := dbpkg.Connect(&cfg.Database); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer mysql.Close()
+	current, err := migration.CurrentVersion(context.Background(), mysql.GetDB())
+	if err != nil {
+		return err
+	}
+	migrations, err := migration.Migrations()
+	if err != nil {
+		return err
+	}
+	pending := make([]string, 0)
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+	logger.Info("Database schema status", zap.Int("current", current), zap.Strings("pending", pending))
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"qm-mcp-server/pkg/openapi/generator"
+)
+
+func main() {
+	root := flag.String("root", ".", "backend 根目录")
+	out := flag.String("out", "pkg/openapi/specs", "OpenAPI 文档输出目录，相对 root")
+	check := flag.Bool("check", false, "只检查已提交的文档是否为最新，不写入文件")
+	flag.Parse()
+
+	docs, err := generator.Generate(*root)
+	if err != nil {
+		fmt.Printf("生成 OpenAPI 文档失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	dir := filepath.Join(*root, *out)
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stale := false
+	for _, name := range names {
+		path := filepath.Join(dir, name+".json")
+		if *check {
+			existing, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(existing, docs[name]) {
+				fmt.Printf("%s 不是最新，请运行 make openapi\n", path)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			fmt.Printf("创建目录失败: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, docs[name], 0o644); err != nil {
+			fmt.Printf("写入 %s 失败: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("已生成 %s\n", path)
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
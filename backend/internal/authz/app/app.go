@@ -19,6 +19,7 @@ import (
 	"qm-mcp-server/pkg/database/repository/mysql"
 	"qm-mcp-server/pkg/logger"
 	"qm-mcp-server/pkg/middleware"
+	"qm-mcp-server/pkg/openapi"
 	"qm-mcp-server/pkg/redis"
 )
 
@@ -53,6 +54,11 @@ func New() *App {
 
 // Initialize initializes the application
 func (a *App) Initialize() error {
+	// Report all configuration problems before connecting to anything
+	if err := a.config.Validate(); err != nil {
+		return err
+	}
+
 	// Initialize Redis
 	if err := redis.Init(&config.GlobalConfig.Database.Redis); err != nil {
 		return fmt.Errorf("failed to initialize redis: %w", err)
@@ -99,6 +105,7 @@ func (a *App) setupHTTPServer() error {
 // setupMiddleware sets up middleware
 func (a *App) setupMiddleware() {
 	// Add middleware
+	a.ginEngine.Use(middleware.RequestIDMiddleware())
 	a.ginEngine.Use(gin.Recovery())
 	a.ginEngine.Use(middleware.RequestResponseLoggingMiddleware())
 
@@ -113,6 +120,9 @@ func (a *App) setupMiddleware() {
 
 	// Add authentication middleware
 	a.ginEngine.Use(middleware.AuthTokenMiddleware(config.GlobalConfig.Secret))
+
+	// Add admin impersonation middleware, requests run as the same user as in the market service
+	a.ginEngine.Use(middleware.ImpersonationMiddleware(middleware.DefaultImpersonationPolicy()))
 }
 
 // setupRoutes sets up routes
@@ -165,6 +175,18 @@ func (a *App) setupRoutes() {
 		// Get encryption key
 		authzGroup.POST("/encryption-key", userAuthService.GetEncryptionKey)
 	}
+
+	// OpenAPI document and Swagger UI
+	if a.config.OpenAPI.Enabled {
+		err := openapi.Register(authzGroup, "authz", openapi.Options{
+			RoutePrefix:  common.GetAuthzRoutePrefix(),
+			SwaggerUIURL: a.config.OpenAPI.SwaggerUIURL,
+			Version:      a.config.VersionInfo.Version,
+		})
+		if err != nil {
+			logger.Error("Failed to register OpenAPI document", zap.Error(err))
+		}
+	}
 }
 
 // Run runs the application
@@ -310,6 +310,8 @@ func (uc *UserBiz) GetUserPermissions(ctx context.Context, userId uint) ([]strin
 				permissions = append(permissions,
 					"user:view", "profile:update",
 				)
+			case model.RoleViewer, "只读":
+				permissions = append(permissions, model.ViewerPermissions...)
 			case "operator", "操作员":
 				permissions = append(permissions,
 					"user:view", "user:update",
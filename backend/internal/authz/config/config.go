@@ -19,6 +19,8 @@ type Config struct {
 	Database    common.DatabaseConfig `mapstructure:"database"`
 	Log         common.LogConfig      `mapstructure:"log"`
 	Secret      string                `mapstructure:"secret"`
+	// OpenAPI 文档和 Swagger UI，默认关闭
+	OpenAPI common.OpenAPIConfig `mapstructure:"openapi"`
 }
 
 // JWTConfig JWT configuration
@@ -76,3 +78,15 @@ func Load() error {
 
 	return nil
 }
+
+// Validate checks the settings required at startup and returns all problems at once
+func (c *Config) Validate() error {
+	v := &common.ConfigValidator{}
+	v.Port("server.httpPort", c.Server.HttpPort)
+	v.Required("secret", c.Secret)
+	v.MySQL("database.mysql", c.Database.MySQL)
+	v.Redis("database.redis", c.Database.Redis)
+	// Avatars are stored under the static path
+	v.WritableDir("storage.staticPath", c.Storage.StaticPath)
+	return v.Err()
+}
@@ -10,9 +10,12 @@ import (
 	"time"
 
 	"qm-mcp-server/internal/gateway/config"
+	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/database"
 	"qm-mcp-server/pkg/database/repository/mysql"
 	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/proxy"
+	"qm-mcp-server/pkg/redis"
 
 	"go.uber.org/zap"
 )
@@ -60,6 +63,11 @@ func New() (*App, error) {
 
 // Initialize 初始化应用程序所有组件
 func (a *App) Initialize() error {
+	// 启动前一次性检查所有配置问题
+	if err := a.config.Validate(); err != nil {
+		return err
+	}
+
 	// 初始化数据库
 	if err := database.Init(&a.config.Database); err != nil {
 		return fmt.Errorf("初始化数据库失败: %w", err)
@@ -70,6 +78,60 @@ func (a *App) Initialize() error {
 		return fmt.Errorf("McpInstanceRepo 未正确初始化，请检查数据库初始化流程")
 	}
 
+	proxy.SetProxyLimits(a.config.ProxyLimits)
+	proxy.SetSSEHeartbeat(a.config.SSEHeartbeat)
+	proxy.SetInstanceFallback(a.config.InstanceFallback)
+	proxy.SetCORS(a.config.CORS)
+	if err := proxy.SetUpstreamTransport(a.config.UpstreamTLS, a.config.OutboundProxy); err != nil {
+		return fmt.Errorf("加载上游 TLS 配置失败: %w", err)
+	}
+	if a.config.UpstreamTLS.InsecureSkipVerify {
+		a.logger.Warn("上游 TLS 证书校验已关闭，仅用于测试环境")
+	}
+
+	// 启用响应缓存、熔断或连接上报时初始化 Redis，状态在多个网关副本间共享
+	if a.config.ResponseCache.Enabled || a.config.CircuitBreaker.Enabled || a.config.SSEConnections.Enabled {
+		if err := redis.Init(&a.config.Database.Redis); err != nil {
+			return fmt.Errorf("初始化Redis失败: %w", err)
+		}
+		// 接收 market 的自定义访问路径变化通知，丢弃按旧路径缓存的实例；
+		// 未启用 Redis 时缓存只在数据库不可用时使用，数据库恢复后按路径重新查询
+		go func() {
+			if err := redis.SubscribeInstanceSlugChange(a.shutdownCtx, proxy.ForgetInstanceSlug); err != nil {
+				a.logger.Error("订阅自定义访问路径变化通知失败", zap.Error(err))
+			}
+		}()
+	}
+	if a.config.ResponseCache.Enabled {
+		proxy.SetResponseCache(a.config.ResponseCache, redis.ResponseCacheStore{})
+	}
+	if a.config.CircuitBreaker.Enabled {
+		proxy.SetCircuitBreaker(a.config.CircuitBreaker, redis.CircuitBreakerStore{})
+		// 接收 market 的熔断重置通知
+		go func() {
+			if err := redis.SubscribeCircuitBreakerReset(a.shutdownCtx, proxy.ResetCircuitBreaker); err != nil {
+				a.logger.Error("订阅熔断重置通知失败", zap.Error(err))
+			}
+		}()
+	}
+
+	if a.config.SSEConnections.Enabled {
+		store := redis.NewSSEConnectionStore()
+		proxy.SetSSEConnections(a.config.SSEConnections, store)
+		proxy.SetMcpSessions(a.config.SSEConnections.SessionTTL, store)
+		go proxy.ReportSSEConnections(a.shutdownCtx)
+		go proxy.ReportMcpSessions(a.shutdownCtx)
+		// 接收 market 的连接排空通知
+		go func() {
+			if err := redis.SubscribeSSEDrain(a.shutdownCtx, proxy.DrainSSEConnections); err != nil {
+				a.logger.Error("订阅连接排空通知失败", zap.Error(err))
+			}
+		}()
+	} else {
+		proxy.SetSSEConnections(a.config.SSEConnections, nil)
+		proxy.SetMcpSessions(a.config.SSEConnections.SessionTTL, nil)
+	}
+
 	// 初始化 HTTP 服务器
 	if err := a.initializeHTTPServer(); err != nil {
 		return fmt.Errorf("初始化HTTP服务器失败: %w", err)
@@ -106,6 +168,9 @@ func (a *App) Run() error {
 	a.logger.Info("应用程序启动成功",
 		zap.String("address", a.httpServer.Addr))
 
+	// 配置文件修改或收到 SIGHUP 时热加载日志级别、代理限制等配置，不断开 SSE 会话
+	common.WatchConfigFile(a.shutdownCtx, config.ConfigFile(), a.reloadConfig)
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
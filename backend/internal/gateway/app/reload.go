@@ -0,0 +1,83 @@
+package app
+
+import (
+	"qm-mcp-server/internal/gateway/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/proxy"
+	"qm-mcp-server/pkg/redis"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// hotReloadKeys 无需重启即可生效的配置项，其他配置项修改后需要重启网关
+var hotReloadKeys = map[string]bool{
+	"log.level":                         true,
+	"proxyLimits.maxRequestBodySize":    true,
+	"proxyLimits.maxResponseBufferSize": true,
+	"proxyLimits.streamingThreshold":    true,
+	"proxyLimits.readTimeout":           true,
+	"proxyLimits.slowRequestThreshold":  true,
+	"sseHeartbeat.enabled":              true,
+	"sseHeartbeat.interval":             true,
+	"sseConnections.maxPerInstance":     true,
+	"sseConnections.sessionTTL":         true,
+	"responseCache.ttl":                 true,
+	"responseCache.methods":             true,
+	"cors.allowedOrigins":               true,
+	"cors.allowedMethods":               true,
+	"cors.allowedHeaders":               true,
+	"cors.exposedHeaders":               true,
+	"cors.allowCredentials":             true,
+	"cors.maxAge":                       true,
+}
+
+// reloadConfig 应用重新读取的配置中可热加载的配置项，只在配置监听的回调中串行调用
+func (a *App) reloadConfig(v *viper.Viper) {
+	newConfig, err := config.Parse(v)
+	if err != nil {
+		a.logger.Error("配置热加载失败，保持当前配置", zap.Error(err))
+		return
+	}
+
+	applied := false
+	for _, change := range common.DiffConfig(*a.config, *newConfig) {
+		if !hotReloadKeys[change.Key] {
+			a.logger.Warn("配置项修改需要重启网关后生效",
+				zap.String("key", change.Key), zap.Any("old", change.Old), zap.Any("new", change.New))
+			continue
+		}
+		if change.Key == "log.level" {
+			if err := logger.SetLevel(newConfig.Log.Level); err != nil {
+				a.logger.Error("日志级别无效，保持当前级别", zap.String("level", newConfig.Log.Level), zap.Error(err))
+				continue
+			}
+			a.config.Log.Level = newConfig.Log.Level
+		}
+		a.logger.Info("配置项已热加载",
+			zap.String("key", change.Key), zap.Any("old", change.Old), zap.Any("new", change.New))
+		applied = true
+	}
+	if !applied {
+		return
+	}
+
+	a.config.ProxyLimits = newConfig.ProxyLimits
+	a.config.SSEHeartbeat = newConfig.SSEHeartbeat
+	a.config.SSEConnections.MaxPerInstance = newConfig.SSEConnections.MaxPerInstance
+	a.config.SSEConnections.SessionTTL = newConfig.SSEConnections.SessionTTL
+	a.config.ResponseCache.TTL = newConfig.ResponseCache.TTL
+	a.config.ResponseCache.Methods = newConfig.ResponseCache.Methods
+	a.config.CORS = newConfig.CORS
+
+	proxy.SetProxyLimits(a.config.ProxyLimits)
+	proxy.SetSSEHeartbeat(a.config.SSEHeartbeat)
+	proxy.SetSSEConnectionLimit(a.config.SSEConnections.MaxPerInstance)
+	proxy.SetMcpSessionTTL(a.config.SSEConnections.SessionTTL)
+	proxy.SetCORS(a.config.CORS)
+	// 开启或关闭响应缓存需要初始化 Redis，只有已开启时才更新 TTL 和方法
+	if a.config.ResponseCache.Enabled {
+		proxy.SetResponseCache(a.config.ResponseCache, redis.ResponseCacheStore{})
+	}
+}
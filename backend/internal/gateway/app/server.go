@@ -3,13 +3,17 @@ package app
 import (
 	"bytes"
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 
+	"qm-mcp-server/internal/gateway/config"
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/middleware"
+	"qm-mcp-server/pkg/openapi"
 	"qm-mcp-server/pkg/proxy"
 
 	"github.com/gin-gonic/gin"
@@ -22,12 +26,15 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 		// 记录请求开始时间
 		start := time.Now()
 
-		// 读取请求体
+		// 读取请求体，最多读取 maxLoggedBodySize 字节，避免大请求在网关内存中整体缓冲
 		var requestBody []byte
 		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			// 重新设置请求体，以便后续处理器可以读取
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodySize+1))
+			// 重新设置请求体，以便后续处理器可以读取完整内容
+			c.Request.Body = loggedBody{
+				Reader: io.MultiReader(bytes.NewReader(requestBody), c.Request.Body),
+				Closer: c.Request.Body,
+			}
 		}
 		// 准备日志字段
 		logFields := []zap.Field{
@@ -60,7 +67,7 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 
 		// 检查Content-Type是否为JSON，并尝试解析请求体
 		contentType := c.GetHeader("Content-Type")
-		if strings.Contains(contentType, "application/json") && len(requestBody) > 0 {
+		if strings.Contains(contentType, "application/json") && len(requestBody) > 0 && len(requestBody) <= maxLoggedBodySize {
 			var jsonBody interface{}
 			if err := json.Unmarshal(requestBody, &jsonBody); err == nil {
 				logFields = append(logFields, zap.Any("json", jsonBody))
@@ -82,7 +89,7 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 			strings.Contains(responseContentType, "application/octet-stream") ||
 			strings.Contains(c.Writer.Header().Get("Content-Disposition"), "attachment") {
 			// 流式数据和下载数据只记录基本信息
-			logger.Info("请求完成",
+			logger.FromContext(c.Request.Context()).Info("请求完成",
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.Int("status", c.Writer.Status()),
@@ -90,7 +97,7 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 			)
 		} else {
 			// 其他数据记录完整响应
-			logger.Info("请求完成",
+			logger.FromContext(c.Request.Context()).Info("请求完成",
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.Int("status", c.Writer.Status()),
@@ -101,14 +108,26 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-// bodyLogWriter 自定义的 ResponseWriter，用于捕获响应体
+// maxLoggedBodySize 日志中记录的请求体和响应体大小上限
+const maxLoggedBodySize = 64 << 10
+
+// loggedBody 已读取部分与剩余请求体拼接后的请求体
+type loggedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter 自定义的 ResponseWriter，用于捕获响应体，SSE 流不捕获，其他响应最多捕获 maxLoggedBodySize 字节
 type bodyLogWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if remaining := maxLoggedBodySize - w.body.Len(); remaining > 0 &&
+		!strings.Contains(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.body.Write(b[:min(len(b), remaining)])
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -116,6 +135,9 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 func NewServer() *gin.Engine {
 	r := gin.Default()
 
+	// 添加请求 ID 中间件，请求头中的 X-Request-ID 会随反向代理透传到 MCP 服务
+	r.Use(middleware.RequestIDMiddleware())
+
 	// 添加请求响应日志中间件
 	r.Use(RequestResponseLoggingMiddleware())
 
@@ -130,5 +152,25 @@ func NewServer() *gin.Engine {
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) { c.String(200, "ok") })
 
+	cfg := config.GetConfig()
+
+	// 网关接口无需登录，运行指标只在配置开启时提供
+	if cfg != nil && cfg.DebugVars.Enabled {
+		// 运行指标，包含响应缓存的命中、未命中和绕过次数
+		r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	}
+
+	// OpenAPI 文档和 Swagger UI，代理路由为通配路径，文档挂在根路径下
+	if cfg != nil && cfg.OpenAPI.Enabled {
+		err := openapi.Register(r, "gateway", openapi.Options{
+			RoutePrefix:  serversPrefix,
+			SwaggerUIURL: cfg.OpenAPI.SwaggerUIURL,
+			Version:      cfg.VersionInfo.Version,
+		})
+		if err != nil {
+			logger.Error("注册 OpenAPI 文档失败", zap.Error(err))
+		}
+	}
+
 	return r
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/version"
@@ -18,6 +19,31 @@ type Config struct {
 	Server      ServerConfig          `mapstructure:"server"`
 	Database    common.DatabaseConfig `mapstructure:"database"`
 	Log         common.LogConfig      `mapstructure:"log"`
+	Image       common.ImageConfig    `mapstructure:"image"`
+	// 网关对外访问配置，SSE endpoint 改写时使用对外路径前缀
+	PublicAccess common.PublicAccessConfig `mapstructure:"publicAccess"`
+	// 幂等 MCP 方法的响应缓存，启用时需要 Redis
+	ResponseCache common.ResponseCacheConfig `mapstructure:"responseCache"`
+	// 上游实例熔断，启用时需要 Redis 发布熔断状态并接收重置通知
+	CircuitBreaker common.CircuitBreakerConfig `mapstructure:"circuitBreaker"`
+	// 代理请求体大小和响应缓冲限制
+	ProxyLimits common.ProxyLimitsConfig `mapstructure:"proxyLimits"`
+	// 空闲 SSE 连接心跳，避免负载均衡器断开长时间无数据的连接
+	SSEHeartbeat common.SSEHeartbeatConfig `mapstructure:"sseHeartbeat"`
+	// SSE 连接数上限，启用时通过 Redis 发布连接数并接收排空通知
+	SSEConnections common.SSEConnectionsConfig `mapstructure:"sseConnections"`
+	// 数据库不可用时使用最近加载的实例配置继续代理
+	InstanceFallback common.InstanceFallbackConfig `mapstructure:"instanceFallback"`
+	// 连接 HTTPS 上游的全局 TLS 配置，实例可在 mcpServers 的 tls 中追加 CA 和客户端证书
+	UpstreamTLS common.UpstreamTLSConfig `mapstructure:"upstreamTLS"`
+	// 连接上游的出站代理，实例可在 mcpServers 的 proxy 中覆盖
+	OutboundProxy common.OutboundProxyConfig `mapstructure:"outboundProxy"`
+	// 浏览器跨域访问的默认策略，实例可在 mcpServers 的 cors 中替换
+	CORS common.GatewayCORSConfig `mapstructure:"cors"`
+	// OpenAPI 文档和 Swagger UI，默认关闭
+	OpenAPI common.OpenAPIConfig `mapstructure:"openapi"`
+	// /debug/vars 运行指标，网关接口无需登录，默认关闭
+	DebugVars common.DebugVarsConfig `mapstructure:"debugVars"`
 }
 
 // ServerConfig 服务器配置
@@ -29,6 +55,14 @@ type ServerConfig struct {
 var serviceName = "gateway"
 var cfgFileName = "gateway.yaml"
 
+// configFile 加载的配置文件路径，配置热加载时监听该文件
+var configFile string
+
+// ConfigFile 获取加载的配置文件路径
+func ConfigFile() string {
+	return configFile
+}
+
 // GetConfig 获取全局配置
 func GetConfig() *Config {
 	return GlobalConfig
@@ -55,16 +89,71 @@ func Load() error {
 	}
 
 	// 解析配置
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return fmt.Errorf("failed to parse config file: %v", err)
+	config, err := Parse(v)
+	if err != nil {
+		return err
 	}
 
+	// 默认托管镜像需与 market 服务保持一致，用于判断请求路径是否补齐末尾斜杠
+	common.SetHostingImage(config.Image.HostingImage)
+	common.SetPublicAccess(config.PublicAccess, "")
+
 	// 追加 Version 信息
 	config.ServiceName = serviceName
 	config.VersionInfo = version.GetVersionInfo()
 
-	GlobalConfig = &config
+	GlobalConfig = config
+	configFile = configPath
 
 	return nil
 }
+
+// Parse 解析配置，配置热加载时用于解析重新读取的配置文件
+func Parse(v *viper.Viper) (*Config, error) {
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+	return &config, nil
+}
+
+// Validate 检查启动必需的配置，一次返回所有问题及其配置路径
+func (c *Config) Validate() error {
+	v := &common.ConfigValidator{}
+	v.Port("server.httpPort", c.Server.HttpPort)
+	v.MySQL("database.mysql", c.Database.MySQL)
+	// 响应缓存、熔断和连接上报依赖 Redis
+	if c.ResponseCache.Enabled || c.CircuitBreaker.Enabled || c.SSEConnections.Enabled {
+		v.Redis("database.redis", c.Database.Redis)
+	}
+	if c.ProxyLimits.SlowRequestThreshold < 0 {
+		v.Addf("proxyLimits.slowRequestThreshold", "must not be negative, got %d", c.ProxyLimits.SlowRequestThreshold)
+	}
+	if c.SSEConnections.MaxPerInstance < 0 {
+		v.Addf("sseConnections.maxPerInstance", "must not be negative, got %d", c.SSEConnections.MaxPerInstance)
+	}
+	if c.SSEConnections.SessionTTL < 0 {
+		v.Addf("sseConnections.sessionTTL", "must not be negative, got %d", c.SSEConnections.SessionTTL)
+	}
+	v.CORSOrigins("cors.allowedOrigins", c.CORS.AllowedOrigins)
+	v.CORSCredentials("cors.allowCredentials", c.CORS.AllowedOrigins, c.CORS.AllowCredentials)
+	v.TrustedProxies("publicAccess.trustedProxies", c.PublicAccess.TrustedProxies)
+	v.ProxyURL("outboundProxy.httpProxy", c.OutboundProxy.HTTPProxy)
+	v.ProxyURL("outboundProxy.httpsProxy", c.OutboundProxy.HTTPSProxy)
+	if (c.UpstreamTLS.ClientCertFile == "") != (c.UpstreamTLS.ClientKeyFile == "") {
+		v.Addf("upstreamTLS", "clientCertFile and clientKeyFile must be set together")
+	}
+	for _, f := range []struct{ key, path string }{
+		{"upstreamTLS.caFile", c.UpstreamTLS.CAFile},
+		{"upstreamTLS.clientCertFile", c.UpstreamTLS.ClientCertFile},
+		{"upstreamTLS.clientKeyFile", c.UpstreamTLS.ClientKeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			v.Addf(f.key, "%v", err)
+		}
+	}
+	return v.Err()
+}
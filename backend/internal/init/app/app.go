@@ -7,7 +7,9 @@ import (
 	"go.uber.org/zap"
 
 	"qm-mcp-server/internal/init/config"
+	"qm-mcp-server/pkg/codepackage"
 	dbpkg "qm-mcp-server/pkg/database"
+	"qm-mcp-server/pkg/database/migration"
 	"qm-mcp-server/pkg/database/model"
 	"qm-mcp-server/pkg/database/repository/mysql"
 	"qm-mcp-server/pkg/logger"
@@ -20,6 +22,30 @@ type App struct {
 	adminUser       *model.SysUser
 	codePackageList []*model.McpCodePackage
 	mcpTemplateList []*model.McpTemplate
+	options         Options
+	// checkItems --check 模式下收集的待创建/已存在数据
+	checkItems []checkItem
+}
+
+// Options 命令行和环境变量参数，非空字段覆盖 init.yaml 中的配置，用于 CI 等非交互式安装
+type Options struct {
+	AdminUsername string
+	AdminPassword string
+	// GeneratePassword 未指定密码时生成随机密码，仅输出到标准输出一次
+	GeneratePassword bool
+	// ResetPassword 管理员已存在时重置其密码，默认保留已有密码
+	ResetPassword  bool
+	KubeconfigPath string
+	Namespace      string
+	// Check 只报告将要创建的数据，不写入数据库
+	Check bool
+}
+
+// checkItem --check 模式下的一条报告
+type checkItem struct {
+	kind   string
+	name   string
+	exists bool
 }
 
 // New creates application instance
@@ -45,19 +71,65 @@ func New() *App {
 	}
 }
 
+// ApplyOptions 使用命令行参数覆盖配置
+func (a *App) ApplyOptions(opts Options) {
+	a.options = opts
+	if opts.AdminUsername != "" {
+		a.config.Init.AdminUsername = opts.AdminUsername
+	}
+	if opts.AdminPassword != "" {
+		a.config.Init.AdminPassword = opts.AdminPassword
+	}
+	if opts.KubeconfigPath != "" {
+		a.config.Kubernetes.DefaultConfigFilePath = opts.KubeconfigPath
+	}
+	if opts.Namespace != "" {
+		a.config.Kubernetes.Namespace = opts.Namespace
+	}
+}
+
+// ValidateConfig 检查命令行参数覆盖后的配置
+func (a *App) ValidateConfig() error {
+	return a.config.Validate()
+}
+
 // Initialize initializes the application
 func (a *App) Initialize() error {
-	// 初始化数据库
-	if err := dbpkg.Init(&a.config.Database); err != nil {
+	// 命令行参数覆盖后检查配置，一次输出所有问题
+	if err := a.ValidateConfig(); err != nil {
+		return err
+	}
+
+	if a.options.Check {
+		// --check 模式只读取数据，不执行迁移
+		if err := dbpkg.Connect(&a.config.Database); err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		return nil
+	}
+
+	// 初始化数据库并执行表结构迁移，其他服务启动时只检查版本
+	if err := dbpkg.Migrate(&a.config.Database); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	// 初始化代码包存储后端，与 market 服务使用相同的存储
+	storage, err := codepackage.NewStorage(a.config.Storage.CodeBackend, a.config.Storage.CodePath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize code package storage: %w", err)
+	}
+	codepackage.SetStorage(storage)
+
 	logger.Info("Authz service initialized successfully")
 	return nil
 }
 
 // Run 运行应用程序
 func (a *App) Run() error {
+	if a.options.Check {
+		return a.runCheck()
+	}
+
 	// 拷贝项目路径下 data 目录所有基础数据到挂载根目录中
 	if err := a.copyInitData("./init-data/static", a.config.Storage.StaticPath); err != nil {
 		return fmt.Errorf("failed to copy data directory: %w", err)
@@ -92,7 +164,48 @@ func (a *App) Shutdown() error {
 	return nil
 }
 
-// initDataScope creates the default environment
+// runCheck 报告各项初始化数据是否已存在，不写入任何数据
+func (a *App) runCheck() error {
+	ctx := context.Background()
+	defer a.Shutdown()
+
+	current, err := migration.CurrentVersion(ctx, mysql.GetDB())
+	if err != nil {
+		return err
+	}
+	latest, err := migration.LatestVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database schema version: %d, latest: %d\n", current, latest)
+	if current == 0 && !mysql.GetDB().Migrator().HasTable(&model.SysUser{}) {
+		// 全新数据库，所有数据都需要创建
+		fmt.Println("Database is empty, all init data would be created")
+	}
+
+	if _, err := a.createAdminUser(); err != nil {
+		return err
+	}
+	if err := a.initDataScope(ctx, a.adminUser); err != nil {
+		return err
+	}
+
+	for _, item := range a.checkItems {
+		action := "create"
+		if item.exists {
+			action = "exists"
+		}
+		fmt.Printf("%-8s %-14s %s\n", action, item.kind, item.name)
+	}
+	return nil
+}
+
+// addCheckItem 记录 --check 模式下的一条报告
+func (a *App) addCheckItem(kind, name string, exists bool) {
+	a.checkItems = append(a.checkItems, checkItem{kind: kind, name: name, exists: exists})
+}
+
+// initDataScope creates the default environment, templates and catalog entries
 func (a *App) initDataScope(ctx context.Context, adminUser *model.SysUser) error {
 	// 初始化默认 Kubernetes 环境
 	envMod, err := a.initDefaultKubernetesEnvironment(ctx, adminUser)
@@ -107,5 +220,9 @@ func (a *App) initDataScope(ctx context.Context, adminUser *model.SysUser) error
 	if err := a.initMcpTemplateData(ctx, envMod); err != nil {
 		return fmt.Errorf("failed to init mcp template data: %w", err)
 	}
+	// 导入内置的 MCP 服务目录
+	if err := a.initCatalogData(ctx); err != nil {
+		return fmt.Errorf("failed to init mcp catalog data: %w", err)
+	}
 	return nil
 }
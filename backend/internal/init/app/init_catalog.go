@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"qm-mcp-server/pkg/catalog"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+
+	"gorm.io/gorm"
+)
+
+// initCatalogData imports the bundled MCP server catalog
+// Bundled entries are refreshed on every run, entries synced from a remote registry with the same ID are kept
+func (a *App) initCatalogData(ctx context.Context) error {
+	entries, err := catalog.Bundled()
+	if err != nil {
+		return fmt.Errorf("failed to load bundled catalog: %w", err)
+	}
+
+	importedCount := 0
+	skippedCount := 0
+	for i := range entries {
+		existing, err := mysql.McpCatalogRepo.FindByCatalogID(ctx, entries[i].ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to query catalog entry '%s': %w", entries[i].ID, err)
+		}
+		if a.options.Check {
+			a.addCheckItem("catalog", entries[i].ID, existing != nil)
+			continue
+		}
+		if existing != nil && existing.Source == model.CatalogSourceRemote {
+			log.Printf("Catalog entry '%s' is synced from a remote registry, skipping", entries[i].ID)
+			skippedCount++
+			continue
+		}
+
+		record := entries[i].Model(model.CatalogSourceBundled)
+		record.PrepareForCreate()
+		if err := mysql.McpCatalogRepo.Upsert(ctx, record); err != nil {
+			return fmt.Errorf("failed to import catalog entry '%s': %w", entries[i].ID, err)
+		}
+		importedCount++
+	}
+
+	if !a.options.Check {
+		log.Printf("MCP catalog initialization completed. Imported: %d, Skipped: %d", importedCount, skippedCount)
+	}
+	return nil
+}
@@ -81,6 +81,11 @@ func (a *App) initCodePackage(ctx context.Context) error {
 
 		// 检查数据库中是否已存在同名的代码包
 		existingPackages, err := mysql.McpCodePackageRepo.FindAll(ctx)
+		if err != nil && a.options.Check {
+			// 表结构尚未迁移
+			a.addCheckItem("code package", fileName, false)
+			continue
+		}
 		if err != nil {
 			logger.Error("Failed to query existing packages", zap.Error(err))
 			skippedCount++
@@ -99,6 +104,10 @@ func (a *App) initCodePackage(ctx context.Context) error {
 			}
 		}
 
+		if a.options.Check {
+			a.addCheckItem("code package", fileName, exists)
+			continue
+		}
 		if exists {
 			continue
 		}
@@ -150,7 +159,7 @@ func (a *App) processCodePackageFile(ctx context.Context, filePath, fileName str
 	fileReader := &fileReaderWrapper{file: file}
 
 	// 使用代码包管理器处理上传和解压
-	packageInfo, err := packageManager.UploadAndExtractPackage(fileReader, header)
+	packageInfo, err := packageManager.UploadAndExtractPackage(ctx, fileReader, header)
 	if err != nil {
 		return fmt.Errorf("failed to upload and extract package: %w", err)
 	}
@@ -167,9 +176,9 @@ func (a *App) processCodePackageFile(ctx context.Context, filePath, fileName str
 	}
 
 	if err := mysql.McpCodePackageRepo.Create(ctx, codePackage); err != nil {
-		// 清理创建的目录
-		if absPath, convErr := packageManager.ToAbsolutePath(packageInfo.PackagePath); convErr == nil {
-			os.RemoveAll(absPath)
+		// 清理已保存的代码包
+		if delErr := packageManager.DeletePackage(ctx, packageInfo.PackagePath); delErr != nil {
+			logger.Warn("Failed to clean up package", zap.String("packageId", packageInfo.PackageID), zap.Error(delErr))
 		}
 		return fmt.Errorf("failed to save package to database: %w", err)
 	}
@@ -11,9 +11,6 @@ import (
 )
 
 func (a *App) initDefaultKubernetesEnvironment(ctx context.Context, adminUser *model.SysUser) (*model.McpEnvironment, error) {
-	if len(a.config.Kubernetes.DefaultConfigFilePath) == 0 || len(a.config.Kubernetes.Namespace) == 0 {
-		return nil, fmt.Errorf("default config file path or namespace is empty")
-	}
 	// 检查是否存在名为 Default-Kubernetes-Env 的环境；不存在则创建
 	const defaultName = common.EnvironmentDefaultName
 
@@ -21,9 +18,22 @@ func (a *App) initDefaultKubernetesEnvironment(ctx context.Context, adminUser *m
 	existingEnv, err := mysql.McpEnvironmentRepo.FindByName(ctx, defaultName)
 	if err == nil && existingEnv != nil {
 		// 已存在，无需处理
+		if a.options.Check {
+			a.addCheckItem("environment", defaultName, true)
+		}
 		return existingEnv, nil
 	}
 
+	// 未配置 kubeconfig 时不创建默认环境，可以在部署后通过页面添加
+	if len(a.config.Kubernetes.DefaultConfigFilePath) == 0 || len(a.config.Kubernetes.Namespace) == 0 {
+		fmt.Println("Default kubeconfig path or namespace is not configured, skipping default environment")
+		return nil, nil
+	}
+	if a.options.Check {
+		a.addCheckItem("environment", defaultName, false)
+		return nil, nil
+	}
+
 	namespace := a.config.Kubernetes.Namespace
 	defaultConfigFilePath := a.config.Kubernetes.DefaultConfigFilePath
 
@@ -92,6 +92,19 @@ func (a *App) initMcpTemplateData(ctx context.Context, env *model.McpEnvironment
 		}
 	}
 
+	if a.options.Check {
+		for _, it := range items {
+			existing, err := mysql.McpTemplateRepo.FindByName(ctx, it.Name)
+			a.addCheckItem("template", it.Name, err == nil && existing != nil)
+		}
+		return nil
+	}
+	// 模板需要关联环境，未创建默认环境时跳过
+	if env == nil {
+		log.Printf("Default environment is not available, skipping MCP template data initialization")
+		return nil
+	}
+
 	if needsDefaultEnv {
 		log.Printf("Some templates require default environment, fetching default environment ID...")
 		defaultEnvironmentID, defaultEnvErr = getDefaultEnvironmentID(ctx)
@@ -2,9 +2,10 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"qm-mcp-server/internal/authz/biz"
-	"qm-mcp-server/internal/init/config"
 	"qm-mcp-server/pkg/database/model"
 	"qm-mcp-server/pkg/database/repository/mysql"
 	"time"
@@ -13,7 +14,7 @@ import (
 func (a *App) createAdminUser() (*model.SysUser, error) {
 	ctx := context.Background()
 	userBiz := biz.NewUserBiz()
-	initConfig := config.GetInitConfig()
+	initConfig := a.config
 
 	// Prepare admin user parameters
 	now := time.Now()
@@ -23,6 +24,9 @@ func (a *App) createAdminUser() (*model.SysUser, error) {
 	roleName := initConfig.Init.AdminRoleName
 	level := initConfig.Init.AdminRoleLevel
 	dataScope := initConfig.Init.AdminDataScope
+	if username == "" {
+		return nil, fmt.Errorf("admin username is required")
+	}
 	if roleName == "" {
 		roleName = username
 	}
@@ -57,16 +61,40 @@ func (a *App) createAdminUser() (*model.SysUser, error) {
 	// Check if admin user already exists
 	existingUser, err := mysql.SysUserRepo.FindByUsername(ctx, *adminUser.Username)
 	if err == nil && existingUser != nil {
-		fmt.Println("Admin user already exists, updating password...")
-		// Update password
-		err := userBiz.SetUserPassword(ctx, existingUser, password)
+		a.adminUser = existingUser
+		if a.options.Check {
+			a.addCheckItem("role", roleName, roleExists(ctx, roleName))
+			a.addCheckItem("admin user", username, true)
+			return existingUser, nil
+		}
+		// 默认保留已有密码，重复执行 init 不会覆盖管理员修改过的密码
+		if !a.options.ResetPassword {
+			fmt.Printf("Admin user %s already exists, skipping\n", username)
+			return existingUser, nil
+		}
+		password, generated, err := a.resolveAdminPassword(password)
 		if err != nil {
+			return nil, err
+		}
+		if err := userBiz.SetUserPassword(ctx, existingUser, password); err != nil {
 			return nil, fmt.Errorf("failed to update admin password: %v", err)
 		}
-		a.adminUser = existingUser
+		fmt.Printf("Admin user %s password reset\n", username)
+		printGeneratedPassword(username, password, generated)
 		return existingUser, nil
 	}
 
+	if a.options.Check {
+		a.addCheckItem("role", roleName, roleExists(ctx, roleName))
+		a.addCheckItem("admin user", username, false)
+		return a.adminUser, nil
+	}
+
+	password, generated, err := a.resolveAdminPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create admin role
 	adminRole, err = createAdminRole(ctx, adminRole)
 	if err != nil {
@@ -98,6 +126,7 @@ func (a *App) createAdminUser() (*model.SysUser, error) {
 	}
 
 	fmt.Printf("Admin user created successfully with ID: %d\n", adminUser.UserID)
+	printGeneratedPassword(username, password, generated)
 	a.adminUser = adminUser
 	return adminUser, nil
 }
@@ -119,6 +148,35 @@ func createAdminRole(ctx context.Context, adminRole *model.SysRole) (*model.SysR
 	return adminRole, nil
 }
 
+func roleExists(ctx context.Context, name string) bool {
+	role, err := mysql.SysRoleRepo.FindByName(ctx, name)
+	return err == nil && role != nil
+}
+
+// resolveAdminPassword 返回要设置的管理员密码，指定 --generate-password 时生成随机密码
+func (a *App) resolveAdminPassword(password string) (string, bool, error) {
+	if !a.options.GeneratePassword {
+		if password == "" {
+			return "", false, fmt.Errorf("admin password is not configured, set --admin-password or use --generate-password")
+		}
+		return password, false, nil
+	}
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, fmt.Errorf("failed to generate admin password: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), true, nil
+}
+
+// printGeneratedPassword 生成的密码只在此输出到标准输出一次，不写入日志
+func printGeneratedPassword(username, password string, generated bool) {
+	if !generated {
+		return
+	}
+	fmt.Printf("Generated password for admin user %s: %s\n", username, password)
+	fmt.Println("Store it now, it will not be shown again.")
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/version"
@@ -75,3 +76,23 @@ func Load() error {
 
 	return nil
 }
+
+// Validate 检查初始化必需的配置，一次返回所有问题及其配置路径
+func (c *InitConfig) Validate() error {
+	v := &common.ConfigValidator{}
+	v.MySQL("database.mysql", c.Database.MySQL)
+	v.WritableDir("storage.codePath", c.Storage.CodePath)
+	v.WritableDir("storage.staticPath", c.Storage.StaticPath)
+	v.CodeBackend("storage.codeBackend", c.Storage.CodeBackend)
+	v.Required("init.admin_username", c.Init.AdminUsername)
+	// 默认环境可选，但 kubeconfig 路径和命名空间需要同时配置
+	if (c.Kubernetes.DefaultConfigFilePath == "") != (c.Kubernetes.Namespace == "") {
+		v.Addf("kubernetes", "defaultConfigFilePath and namespace must be set together")
+	}
+	if c.Kubernetes.DefaultConfigFilePath != "" {
+		if _, err := os.Stat(c.Kubernetes.DefaultConfigFilePath); err != nil {
+			v.Addf("kubernetes.defaultConfigFilePath", "%v", err)
+		}
+	}
+	return v.Err()
+}
@@ -10,15 +10,18 @@ import (
 	"syscall"
 	"time"
 
+	"qm-mcp-server/internal/market/biz"
 	cfg "qm-mcp-server/internal/market/config"
 	"qm-mcp-server/internal/market/service"
 	"qm-mcp-server/internal/market/task"
+	"qm-mcp-server/pkg/codepackage"
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/database"
 	"qm-mcp-server/pkg/database/repository/mysql"
 	"qm-mcp-server/pkg/i18n"
 	"qm-mcp-server/pkg/logger"
 	"qm-mcp-server/pkg/middleware"
+	"qm-mcp-server/pkg/openapi"
 	"qm-mcp-server/pkg/redis"
 	"qm-mcp-server/pkg/scheduler"
 	"qm-mcp-server/pkg/services"
@@ -78,6 +81,11 @@ func New() (*App, error) {
 
 // Initialize 初始化应用程序所有组件
 func (a *App) Initialize() error {
+	// 启动前一次性检查所有配置问题，避免在处理请求时才失败
+	if err := a.config.Validate(); err != nil {
+		return err
+	}
+
 	// 初始化数据库
 	if err := database.Init(&a.config.Database); err != nil {
 		return fmt.Errorf("初始化数据库失败: %w", err)
@@ -93,6 +101,20 @@ func (a *App) Initialize() error {
 		return fmt.Errorf("McpInstanceRepo 未正确初始化，请检查数据库初始化流程")
 	}
 
+	// 初始化代码包存储后端
+	storage, err := codepackage.NewStorage(a.config.Storage.CodeBackend, a.config.Storage.CodePath)
+	if err != nil {
+		return fmt.Errorf("初始化代码包存储失败: %w", err)
+	}
+	codepackage.SetStorage(storage)
+
+	// 初始化图标存储，文件系统后端存放在静态资源目录下
+	iconStorage, err := codepackage.NewStorage(a.config.Storage.CodeBackend, a.config.Storage.StaticPath)
+	if err != nil {
+		return fmt.Errorf("初始化图标存储失败: %w", err)
+	}
+	biz.GIconBiz.SetStorage(iconStorage)
+
 	// 加载服务配置
 	if err := services.LoadServices(&a.config.Services); err != nil {
 		return fmt.Errorf("加载服务配置失败: %w", err)
@@ -115,6 +137,11 @@ func (a *App) Initialize() error {
 		return fmt.Errorf("设置全局任务失败: %w", err)
 	}
 
+	// 重启前排队的托管容器重新加入创建队列
+	if err := biz.GContainerBiz.RestoreCreateQueue(a.shutdownCtx); err != nil {
+		a.logger.Warn("恢复容器创建队列失败", zap.Error(err))
+	}
+
 	// 初始化 HTTP 服务器
 	if err := a.initializeHTTPServer(); err != nil {
 		return fmt.Errorf("初始化HTTP服务器失败: %w", err)
@@ -226,17 +253,53 @@ func (a *App) setupHttpServer() {
 	routerPrefix := common.GetMarketRoutePrefix()
 	routerPrefix = strings.Trim(routerPrefix, "/")
 
+	// 创建接口支持 Idempotency-Key，重试的创建请求返回首次的响应
+	idempotency := middleware.IdempotencyMiddleware(redis.IdempotencyStore{}, middleware.DefaultIdempotencyTTL)
+	// 维护模式下拒绝修改类接口，查询接口和网关代理不受影响
+	maintenance := middleware.MaintenanceMiddleware(redis.GetMaintenance)
+
+	// 注册维护模式接口
+	maintenanceService := service.NewMaintenanceService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/maintenance", routerPrefix), maintenanceService.StatusHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/maintenance", routerPrefix), maintenanceService.EnableHandler)
+	a.ginEngine.DELETE(fmt.Sprintf("/%s/maintenance", routerPrefix), maintenanceService.DisableHandler)
+
 	// 注册实例管理接口
 	instanceService := service.NewInstanceService(context.Background())
-	a.ginEngine.POST(fmt.Sprintf("/%s/instance/create", routerPrefix), instanceService.CreateHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/create", routerPrefix), maintenance, idempotency, instanceService.CreateHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/labels", routerPrefix), instanceService.LabelsHandler)
 	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId", routerPrefix), instanceService.DetailHandler)
-	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/edit", routerPrefix), instanceService.EditHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/edit", routerPrefix), maintenance, instanceService.EditHandler)
 	a.ginEngine.POST(fmt.Sprintf("/%s/instance/list", routerPrefix), instanceService.ListHandler)
-	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/disabled", routerPrefix), instanceService.DisabledHandler)
-	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/restart", routerPrefix), instanceService.RestartHandler)
-	a.ginEngine.DELETE(fmt.Sprintf("/%s/instance/:instanceId", routerPrefix), instanceService.DeleteHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/disabled", routerPrefix), maintenance, instanceService.DisabledHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/restart", routerPrefix), maintenance, instanceService.RestartHandler)
+	a.ginEngine.DELETE(fmt.Sprintf("/%s/instance/:instanceId", routerPrefix), maintenance, instanceService.DeleteHandler)
 	a.ginEngine.GET(fmt.Sprintf("/%s/instance/status/:instanceId", routerPrefix), instanceService.StatusHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/status/batch", routerPrefix), instanceService.BatchStatusHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/bulk", routerPrefix), maintenance, instanceService.BulkHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/events/stream", routerPrefix), instanceService.StatusEventsHandler)
 	a.ginEngine.POST(fmt.Sprintf("/%s/instance/logs", routerPrefix), instanceService.LogsHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/logs/download", routerPrefix), instanceService.DownloadLogsHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/scale", routerPrefix), maintenance, instanceService.ScaleHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/events", routerPrefix), instanceService.EventsHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/timeline", routerPrefix), instanceService.TimelineHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/history", routerPrefix), instanceService.HistoryHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/connections", routerPrefix), instanceService.ConnectionsHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/connection", routerPrefix), instanceService.ConnectionHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/drain", routerPrefix), maintenance, instanceService.DrainHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/circuit-breaker/reset", routerPrefix), maintenance, instanceService.ResetCircuitBreakerHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/tokens/:token/rotate", routerPrefix), maintenance, instanceService.RotateTokenHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/drift", routerPrefix), instanceService.DriftHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/provenance", routerPrefix), instanceService.ProvenanceHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/scan", routerPrefix), maintenance, instanceService.ScanHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/lock", routerPrefix), maintenance, instanceService.LockHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/unlock", routerPrefix), maintenance, instanceService.UnlockHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/instance/:instanceId/health-monitor", routerPrefix), maintenance, instanceService.HealthMonitorHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/health-history", routerPrefix), instanceService.HealthHistoryHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/validate-config", routerPrefix), instanceService.ValidateConfigHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/convert-config", routerPrefix), instanceService.ConvertConfigHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/detect-protocol", routerPrefix), instanceService.DetectProtocolHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/validate-script", routerPrefix), instanceService.ValidateScriptHandler)
 
 	// 创建资源管理服务实例
 	resourceService := service.NewResourceService(context.Background())
@@ -247,12 +310,35 @@ func (a *App) setupHttpServer() {
 
 	// 创建环境管理服务实例
 	environmentService := service.NewEnvironmentService(context.Background())
-	a.ginEngine.POST(fmt.Sprintf("/%s/environments", routerPrefix), environmentService.CreateEnvironmentHandler)
-	a.ginEngine.PUT(fmt.Sprintf("/%s/environments/:id", routerPrefix), environmentService.UpdateEnvironmentHandler)
-	a.ginEngine.DELETE(fmt.Sprintf("/%s/environments/:id", routerPrefix), environmentService.DeleteEnvironmentHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/environments", routerPrefix), maintenance, environmentService.CreateEnvironmentHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/environments/:id", routerPrefix), maintenance, environmentService.UpdateEnvironmentHandler)
+	a.ginEngine.DELETE(fmt.Sprintf("/%s/environments/:id", routerPrefix), maintenance, environmentService.DeleteEnvironmentHandler)
 	a.ginEngine.GET(fmt.Sprintf("/%s/environments", routerPrefix), environmentService.ListEnvironmentsHandler)
 	a.ginEngine.POST(fmt.Sprintf("/%s/environments/namespaces", routerPrefix), environmentService.ListNamespacesHandler)
 	a.ginEngine.POST(fmt.Sprintf("/%s/environments/:id/test", routerPrefix), environmentService.TestConnectivityHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/environments/:id/quota", routerPrefix), environmentService.GetEnvironmentQuotaHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/environments/:id/resources", routerPrefix), environmentService.GetEnvironmentResourcesHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/environments/:id/orphans", routerPrefix), environmentService.ListOrphansHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/environments/:id/orphans/cleanup", routerPrefix), maintenance, environmentService.CleanupOrphansHandler)
+
+	// 创建镜像仓库凭证服务实例
+	registryCredentialService := service.NewRegistryCredentialService(context.Background())
+	a.ginEngine.POST(fmt.Sprintf("/%s/registry-credentials", routerPrefix), registryCredentialService.CreateRegistryCredentialHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/registry-credentials/:id", routerPrefix), registryCredentialService.UpdateRegistryCredentialHandler)
+	a.ginEngine.DELETE(fmt.Sprintf("/%s/registry-credentials/:id", routerPrefix), registryCredentialService.DeleteRegistryCredentialHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/registry-credentials/:id", routerPrefix), registryCredentialService.GetRegistryCredentialHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/registry-credentials", routerPrefix), registryCredentialService.ListRegistryCredentialsHandler)
+
+	// 注册 MCP 服务目录接口
+	catalogService := service.NewCatalogService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/catalog", routerPrefix), catalogService.ListCatalogHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/catalog/categories", routerPrefix), catalogService.ListCatalogCategoriesHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/catalog/:id", routerPrefix), catalogService.GetCatalogEntryHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/catalog/:id/install", routerPrefix), maintenance, idempotency, catalogService.InstallCatalogEntryHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/catalog/sync", routerPrefix), catalogService.SyncCatalogHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/catalog/:id/ratings", routerPrefix), catalogService.RateCatalogEntryHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/catalog/:id/ratings", routerPrefix), catalogService.ListCatalogRatingsHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/catalog/:id/curation", routerPrefix), catalogService.UpdateCatalogCurationHandler)
 
 	// 注册代码管理接口
 	codeService := service.NewCodeService()
@@ -263,15 +349,52 @@ func (a *App) setupHttpServer() {
 	a.ginEngine.GET(fmt.Sprintf("/%s/code/download/:packageId", routerPrefix), codeService.DownloadPackage)
 	a.ginEngine.GET(fmt.Sprintf("/%s/code/packages", routerPrefix), codeService.GetCodePackageList)
 	a.ginEngine.DELETE(fmt.Sprintf("/%s/code/packages/:packageId", routerPrefix), codeService.DeleteCodePackage)
+	a.ginEngine.POST(fmt.Sprintf("/%s/code/packages/:packageId/redeploy", routerPrefix), maintenance, instanceService.RedeployPackageHandler)
+
+	// 注册用户配额接口
+	userQuotaService := service.NewUserQuotaService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/users/:id/quota", routerPrefix), userQuotaService.GetUserQuotaHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/users/:id/quota", routerPrefix), userQuotaService.UpdateUserQuotaHandler)
+
+	// 注册通知偏好接口
+	notificationService := service.NewNotificationService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/users/:id/notifications", routerPrefix), notificationService.GetNotificationPreferenceHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/users/:id/notifications", routerPrefix), notificationService.UpdateNotificationPreferenceHandler)
+
+	// 注册变更请求接口
+	changeRequestService := service.NewChangeRequestService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/change-requests", routerPrefix), changeRequestService.ListChangeRequestsHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/change-requests/:id", routerPrefix), changeRequestService.GetChangeRequestHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/change-requests/:id/approve", routerPrefix), maintenance, changeRequestService.ApproveChangeRequestHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/change-requests/:id/reject", routerPrefix), changeRequestService.RejectChangeRequestHandler)
+
+	// 注册成本估算接口
+	costService := service.NewCostService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/instance/:instanceId/cost", routerPrefix), costService.GetInstanceCostHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/cost/report", routerPrefix), costService.GetCostReportHandler)
+
+	// 注册审计日志接口
+	auditService := service.NewAuditService(context.Background())
+	a.ginEngine.GET(fmt.Sprintf("/%s/audit-logs", routerPrefix), auditService.ListAuditLogsHandler)
+
+	// 注册配置检查接口，只检查不做修改，供 CI 在部署前调用
+	validateService := service.NewValidateService()
+	a.ginEngine.POST(fmt.Sprintf("/%s/validate/mcp-config", routerPrefix), validateService.ValidateMcpConfigHandler)
 
 	// 注册模板管理接口
 	templateService := service.NewTemplateService(context.Background())
-	a.ginEngine.POST(fmt.Sprintf("/%s/template/create", routerPrefix), templateService.TemplateCreateHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/template/create", routerPrefix), maintenance, idempotency, templateService.TemplateCreateHandler)
 	a.ginEngine.GET(fmt.Sprintf("/%s/template/:templateId", routerPrefix), templateService.TemplateDetailHandler)
-	a.ginEngine.PUT(fmt.Sprintf("/%s/template/edit", routerPrefix), templateService.TemplateEditHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/template/:templateId/history", routerPrefix), templateService.TemplateHistoryHandler)
+	a.ginEngine.PUT(fmt.Sprintf("/%s/template/edit", routerPrefix), maintenance, templateService.TemplateEditHandler)
 	a.ginEngine.POST(fmt.Sprintf("/%s/template/list", routerPrefix), templateService.TemplateListHandler)
 	a.ginEngine.GET(fmt.Sprintf("/%s/template/list/pagination", routerPrefix), templateService.TemplateListWithPaginationHandler)
-	a.ginEngine.DELETE(fmt.Sprintf("/%s/template/:templateId", routerPrefix), templateService.TemplateDeleteHandler)
+	a.ginEngine.DELETE(fmt.Sprintf("/%s/template/:templateId", routerPrefix), maintenance, templateService.TemplateDeleteHandler)
+	a.ginEngine.POST(fmt.Sprintf("/%s/instance/:instanceId/save-as-template", routerPrefix), maintenance, templateService.SaveAsTemplateHandler)
+
+	// 注册声明式应用接口
+	applyService := service.NewApplyService(context.Background())
+	a.ginEngine.POST(fmt.Sprintf("/%s/apply", routerPrefix), maintenance, applyService.ApplyHandler)
 
 	// 注册市场管理接口
 	marketService := service.NewMarketService()
@@ -286,6 +409,11 @@ func (a *App) setupHttpServer() {
 	storageService := service.NewStorageService(context.Background())
 	a.ginEngine.POST(fmt.Sprintf("/%s/storage/image", routerPrefix), storageService.UploadImageHandler)
 
+	// 注册图标接口，图标访问无需登录
+	iconService := service.NewIconService(context.Background())
+	a.ginEngine.POST(fmt.Sprintf("/%s/assets/icons", routerPrefix), iconService.UploadIconHandler)
+	a.ginEngine.GET(fmt.Sprintf("/%s/assets/icons/:name", routerPrefix), iconService.GetIconHandler)
+
 	// 注册 dashboard 管理接口
 	dashboardService := service.NewDashboardService(context.Background())
 	a.ginEngine.GET(fmt.Sprintf("/%s/dashboard/statistical", routerPrefix), dashboardService.StatisticalHandler)
@@ -295,10 +423,59 @@ func (a *App) setupHttpServer() {
 	a.ginEngine.GET("/health", func(c *gin.Context) {
 		i18n.SuccessResponse(c, gin.H{"status": "ok"})
 	})
+
+	// 运行指标，包含各后台任务锁的持有副本和 fencing token
+	a.ginEngine.GET("/debug/vars", maintenanceService.DebugVarsHandler)
+
+	// OpenAPI 文档和 Swagger UI
+	if a.config.OpenAPI.Enabled {
+		err := openapi.Register(a.ginEngine, "market", openapi.Options{
+			BasePath:     routerPrefix,
+			RoutePrefix:  routerPrefix,
+			SwaggerUIURL: a.config.OpenAPI.SwaggerUIURL,
+			Version:      a.config.VersionInfo.Version,
+		})
+		if err != nil {
+			a.logger.Error("注册 OpenAPI 文档失败", zap.Error(err))
+		}
+	}
+}
+
+// viewerPolicy 只读角色的访问策略：允许以 POST 提交查询条件的列表、状态和日志接口，
+// 拒绝代码文件、生成连接凭据和返回原始变更内容的历史接口
+func viewerPolicy() middleware.ViewerPolicy {
+	routerPrefix := strings.Trim(common.GetMarketRoutePrefix(), "/")
+	routes := func(paths ...string) []string {
+		for i, path := range paths {
+			paths[i] = fmt.Sprintf("/%s%s", routerPrefix, path)
+		}
+		return paths
+	}
+	return middleware.ViewerPolicy{
+		IsViewer: service.IsViewer,
+		ReadRoutes: routes(
+			"/instance/list",
+			"/instance/status/batch",
+			"/instance/logs",
+			"/template/list",
+			"/market/list",
+			"/validate/mcp-config",
+		),
+		DeniedRoutes: routes(
+			"/instance/:instanceId/connection",
+			"/instance/:instanceId/history",
+			"/template/:templateId/history",
+			"/code/tree",
+			"/code/get",
+		),
+	}
 }
 
 // setupMiddleware 设置中间件
 func (a *App) setupMiddleware() {
+	// 添加请求 ID 中间件（需在日志和错误处理之前）
+	a.ginEngine.Use(middleware.RequestIDMiddleware())
+
 	// 添加恐慌恢复中间件
 	a.ginEngine.Use(middleware.PanicRecovery())
 
@@ -312,12 +489,21 @@ func (a *App) setupMiddleware() {
 	// 添加国际化中间件
 	a.ginEngine.Use(middleware.I18nMiddleware())
 
+	// 添加 API 版本协商中间件
+	a.ginEngine.Use(middleware.APIVersionMiddleware())
+
 	// 添加安全中间件
 	a.ginEngine.Use(middleware.SecurityMiddleware(a.config.Secret))
 
 	// 添加认证中间件
 	a.ginEngine.Use(middleware.AuthTokenMiddleware(a.config.Secret))
 
+	// 添加管理员模拟用户中间件，需在只读角色中间件之前，模拟请求按目标用户的角色处理
+	a.ginEngine.Use(middleware.ImpersonationMiddleware(middleware.DefaultImpersonationPolicy()))
+
+	// 添加只读角色中间件，拒绝修改类接口并对响应脱敏
+	a.ginEngine.Use(middleware.ViewerMiddleware(viewerPolicy()))
+
 	// 添加错误处理中间件（必须在最后）
 	a.ginEngine.Use(middleware.ErrorHandler())
 
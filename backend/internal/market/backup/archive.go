@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/utils"
+)
+
+// FormatVersion 备份归档格式版本，格式不兼容变更时递增
+// 版本 2 起敏感字段的密钥由备份口令经 scrypt 加随机盐派生，版本 1 直接使用口令的 SHA-256
+const FormatVersion = 2
+
+const (
+	// kdfScrypt 备份口令的密钥派生算法
+	kdfScrypt = "scrypt"
+	// kdfSaltSize 随机盐长度（字节）
+	kdfSaltSize = 16
+	// maxScryptN 恢复时允许的最大 scrypt 成本，避免篡改的归档耗尽内存
+	maxScryptN = 1 << 20
+)
+
+const manifestFile = "manifest.json"
+
+// Manifest 归档描述信息
+type Manifest struct {
+	FormatVersion int            `json:"formatVersion"`
+	AppVersion    string         `json:"appVersion"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	Counts        map[string]int `json:"counts"`
+	// KDF 备份口令派生密钥的参数，版本 1 的归档没有此项
+	KDF *KDF `json:"kdf,omitempty"`
+}
+
+// KDF 备份口令的密钥派生参数，与盐一起写入归档，之后提高成本时旧归档仍可恢复
+type KDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	utils.ScryptParams
+}
+
+// newKDF 生成使用随机盐和默认成本的派生参数
+func newKDF() (*KDF, error) {
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return &KDF{Algorithm: kdfScrypt, Salt: salt, ScryptParams: utils.DefaultScryptParams}, nil
+}
+
+// passphraseKey 由备份口令派生加密敏感字段的密钥，版本 1 的归档返回 nil，敏感字段直接使用口令加密
+func (m *Manifest) passphraseKey(passphrase string) ([]byte, error) {
+	if m.KDF == nil {
+		if m.FormatVersion < 2 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid backup archive: kdf is missing from %s", manifestFile)
+	}
+	if m.KDF.Algorithm != kdfScrypt {
+		return nil, fmt.Errorf("unsupported backup kdf %q", m.KDF.Algorithm)
+	}
+	if m.KDF.N > maxScryptN {
+		return nil, fmt.Errorf("backup kdf cost %d exceeds the limit %d", m.KDF.N, maxScryptN)
+	}
+	return utils.DerivePassphraseKey(passphrase, m.KDF.Salt, m.KDF.ScryptParams)
+}
+
+// RegistryCredential 镜像仓库凭证，密码使用备份口令重新加密后写入归档
+type RegistryCredential struct {
+	model.McpRegistryCredential
+	Password string `json:"password"`
+}
+
+// Archive 备份内容，环境连接配置和凭证密码使用备份口令加密
+type Archive struct {
+	Manifest            Manifest
+	Depts               []*model.SysDept
+	Roles               []*model.SysRole
+	Users               []*model.SysUser
+	RolesDepts          []*model.SysRolesDepts
+	UsersRoles          []*model.SysUsersRoles
+	Environments        []*model.McpEnvironment
+	RegistryCredentials []*RegistryCredential
+	CodePackages        []*model.McpCodePackage
+	Templates           []*model.McpTemplate
+	Instances           []*model.McpInstance
+}
+
+// entries 归档中的数据文件，顺序即恢复顺序
+func (a *Archive) entries() []struct {
+	name string
+	data any
+} {
+	return []struct {
+		name string
+		data any
+	}{
+		{"depts.json", &a.Depts},
+		{"roles.json", &a.Roles},
+		{"users.json", &a.Users},
+		{"roles_depts.json", &a.RolesDepts},
+		{"users_roles.json", &a.UsersRoles},
+		{"environments.json", &a.Environments},
+		{"registry_credentials.json", &a.RegistryCredentials},
+		{"code_packages.json", &a.CodePackages},
+		{"templates.json", &a.Templates},
+		{"instances.json", &a.Instances},
+	}
+}
+
+// counts 每类数据的记录数
+func (a *Archive) counts() map[string]int {
+	return map[string]int{
+		"depts":               len(a.Depts),
+		"roles":               len(a.Roles),
+		"users":               len(a.Users),
+		"rolesDepts":          len(a.RolesDepts),
+		"usersRoles":          len(a.UsersRoles),
+		"environments":        len(a.Environments),
+		"registryCredentials": len(a.RegistryCredentials),
+		"codePackages":        len(a.CodePackages),
+		"templates":           len(a.Templates),
+		"instances":           len(a.Instances),
+	}
+}
+
+// Write 将归档写为 tar.gz，manifest.json 在最前面
+func (a *Archive) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	a.Manifest.Counts = a.counts()
+	if err := writeEntry(tw, manifestFile, a.Manifest); err != nil {
+		return err
+	}
+	for _, entry := range a.entries() {
+		if err := writeEntry(tw, entry.name, entry.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, data any) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(content)
+	return err
+}
+
+// ReadArchive 读取 tar.gz 归档，拒绝更高版本的格式
+func ReadArchive(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	a := &Archive{}
+	targets := map[string]any{manifestFile: &a.Manifest}
+	for _, entry := range a.entries() {
+		targets[entry.name] = entry.data
+	}
+	tr := tar.NewReader(gz)
+	seenManifest := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		target, ok := targets[header.Name]
+		if !ok {
+			continue
+		}
+		if err := json.NewDecoder(tr).Decode(target); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", header.Name, err)
+		}
+		seenManifest = seenManifest || header.Name == manifestFile
+	}
+	if !seenManifest {
+		return nil, fmt.Errorf("invalid backup archive: %s not found", manifestFile)
+	}
+	if a.Manifest.FormatVersion < 1 || a.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d, this build supports up to %d", a.Manifest.FormatVersion, FormatVersion)
+	}
+	return a, nil
+}
@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/utils"
+)
+
+// testScryptParams 测试使用的低成本派生参数
+var testScryptParams = utils.ScryptParams{N: 1 << 10, R: 8, P: 1}
+
+func testKDF() *KDF {
+	return &KDF{Algorithm: kdfScrypt, Salt: []byte("0123456789abcdef"), ScryptParams: testScryptParams}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	a := &Archive{
+		Manifest: Manifest{FormatVersion: FormatVersion, AppVersion: "v1.2.3", CreatedAt: time.Unix(1700000000, 0).UTC(), KDF: testKDF()},
+		Roles:    []*model.SysRole{{RoleID: 3, Name: "ops"}},
+		Environments: []*model.McpEnvironment{
+			{ID: 1, Name: "prod", Environment: model.McpEnvironmentKubernetes, Config: "encrypted"},
+		},
+		RegistryCredentials: []*RegistryCredential{
+			{McpRegistryCredential: model.McpRegistryCredential{ID: 2, Name: "hub", Password: "not exported"}, Password: "encrypted"},
+		},
+		Instances: []*model.McpInstance{{InstanceID: "abc", InstanceName: "fetch", EnvironmentID: 1}},
+	}
+	var buf bytes.Buffer
+	if err := a.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	m := got.Manifest
+	if m.FormatVersion != FormatVersion || m.AppVersion != "v1.2.3" || !m.CreatedAt.Equal(a.Manifest.CreatedAt) {
+		t.Errorf("manifest = %+v, want %+v", m, a.Manifest)
+	}
+	if m.KDF == nil || m.KDF.Algorithm != kdfScrypt || string(m.KDF.Salt) != "0123456789abcdef" || m.KDF.ScryptParams != testScryptParams {
+		t.Errorf("manifest kdf = %+v, want %+v", m.KDF, a.Manifest.KDF)
+	}
+	if m.Counts["roles"] != 1 || m.Counts["environments"] != 1 || m.Counts["instances"] != 1 || m.Counts["users"] != 0 {
+		t.Errorf("manifest counts = %v", m.Counts)
+	}
+	if len(got.Roles) != 1 || got.Roles[0].Name != "ops" || got.Roles[0].RoleID != 3 {
+		t.Errorf("roles = %+v", got.Roles)
+	}
+	if len(got.Environments) != 1 || got.Environments[0].Config != "encrypted" {
+		t.Errorf("environments = %+v", got.Environments)
+	}
+	// 凭证只写入使用备份口令加密的密码，数据库中的密文不进入归档
+	if len(got.RegistryCredentials) != 1 || got.RegistryCredentials[0].Password != "encrypted" ||
+		got.RegistryCredentials[0].McpRegistryCredential.Password != "" {
+		t.Errorf("registry credentials = %+v", got.RegistryCredentials)
+	}
+	if len(got.Instances) != 1 || got.Instances[0].InstanceID != "abc" || got.Instances[0].EnvironmentID != 1 {
+		t.Errorf("instances = %+v", got.Instances)
+	}
+}
+
+// writeTestArchive writes a tar.gz with the given files
+func writeTestArchive(t *testing.T, files map[string]any) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		if err := writeEntry(tw, name, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestReadArchiveRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+		wantErr string
+	}{
+		{"not gzip", bytes.NewBufferString("plain text"), "invalid backup archive"},
+		{"missing manifest", writeTestArchive(t, map[string]any{"roles.json": []any{}}), "manifest.json not found"},
+		{"newer format", writeTestArchive(t, map[string]any{manifestFile: Manifest{FormatVersion: FormatVersion + 1}}), "unsupported backup format version"},
+		{"zero format", writeTestArchive(t, map[string]any{manifestFile: Manifest{}}), "unsupported backup format version"},
+		{"malformed entry", writeTestArchive(t, map[string]any{manifestFile: Manifest{FormatVersion: 1}, "roles.json": "x"}), "failed to decode roles.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(tt.archive)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadArchive() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadArchiveIgnoresUnknownEntries(t *testing.T) {
+	archive := writeTestArchive(t, map[string]any{
+		manifestFile:   Manifest{FormatVersion: 1},
+		"future.json":  map[string]string{"a": "b"},
+		"depts.json":   []*model.SysDept{{DeptID: 7, Name: "rd"}},
+		"README":       "x",
+		"extra/x.json": json.RawMessage(`{}`),
+	})
+	a, err := ReadArchive(archive)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	if a.Manifest.KDF != nil || len(a.Depts) != 1 || a.Depts[0].DeptID != 7 {
+		t.Errorf("ReadArchive() = %+v", a)
+	}
+}
+
+func TestManifestPassphraseKey(t *testing.T) {
+	want, err := utils.DerivePassphraseKey("correct horse", testKDF().Salt, testScryptParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantKey  bool
+		wantErr  string
+	}{
+		{name: "version 1 uses the passphrase", manifest: Manifest{FormatVersion: 1}},
+		{name: "scrypt", manifest: Manifest{FormatVersion: 2, KDF: testKDF()}, wantKey: true},
+		{name: "version 2 without kdf", manifest: Manifest{FormatVersion: 2}, wantErr: "kdf is missing"},
+		{name: "unknown algorithm", manifest: Manifest{FormatVersion: 2, KDF: &KDF{Algorithm: "md5", Salt: []byte("s")}}, wantErr: "unsupported backup kdf"},
+		{name: "cost above the limit", manifest: Manifest{FormatVersion: 2, KDF: &KDF{Algorithm: kdfScrypt, Salt: []byte("s"),
+			ScryptParams: utils.ScryptParams{N: maxScryptN * 2, R: 8, P: 1}}}, wantErr: "exceeds the limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.manifest.passphraseKey("correct horse")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("passphraseKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("passphraseKey() error = %v", err)
+			}
+			if tt.wantKey != (key != nil) || (key != nil && !bytes.Equal(key, want)) {
+				t.Errorf("passphraseKey() = %x, want key %v", key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestNewKDF(t *testing.T) {
+	a, err := newKDF()
+	if err != nil {
+		t.Fatalf("newKDF() error = %v", err)
+	}
+	b, _ := newKDF()
+	if a.Algorithm != kdfScrypt || len(a.Salt) != kdfSaltSize || a.ScryptParams != utils.DefaultScryptParams {
+		t.Errorf("newKDF() = %+v", a)
+	}
+	if bytes.Equal(a.Salt, b.Salt) {
+		t.Error("newKDF() returned the same salt twice")
+	}
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+	"qm-mcp-server/pkg/version"
+
+	"go.uber.org/zap"
+)
+
+// Export 导出所有元数据，secret 为当前服务的加密密钥，passphrase 用于加密归档中的敏感字段
+// 实例运行记录 (事件、操作历史) 和登录加密密钥不导出
+func Export(ctx context.Context, secret, passphrase string) (*Archive, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("backup passphrase is required")
+	}
+	db := mysql.GetDB().WithContext(ctx)
+	kdf, err := newKDF()
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.DerivePassphraseKey(passphrase, kdf.Salt, kdf.ScryptParams)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{Manifest: Manifest{
+		FormatVersion: FormatVersion,
+		AppVersion:    version.Version,
+		CreatedAt:     time.Now(),
+		KDF:           kdf,
+	}}
+
+	queries := []struct {
+		name  string
+		query func() error
+	}{
+		{"depts", func() error { return db.Order("dept_id").Find(&a.Depts).Error }},
+		{"roles", func() error { return db.Order("role_id").Find(&a.Roles).Error }},
+		{"users", func() error { return db.Order("user_id").Find(&a.Users).Error }},
+		{"rolesDepts", func() error { return db.Find(&a.RolesDepts).Error }},
+		{"usersRoles", func() error { return db.Find(&a.UsersRoles).Error }},
+		{"environments", func() error { return db.Where("is_deleted = ?", false).Order("id").Find(&a.Environments).Error }},
+		{"registryCredentials", func() error {
+			var credentials []*model.McpRegistryCredential
+			if err := db.Order("id").Find(&credentials).Error; err != nil {
+				return err
+			}
+			for _, credential := range credentials {
+				a.RegistryCredentials = append(a.RegistryCredentials, &RegistryCredential{McpRegistryCredential: *credential})
+			}
+			return nil
+		}},
+		{"codePackages", func() error { return db.Where("is_deleted = ?", false).Order("id").Find(&a.CodePackages).Error }},
+		{"templates", func() error { return db.Order("id").Find(&a.Templates).Error }},
+		{"instances", func() error { return db.Order("id").Find(&a.Instances).Error }},
+	}
+	for _, q := range queries {
+		if err := q.query(); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", q.name, err)
+		}
+		logger.Info("Exported", zap.String("table", q.name), zap.Int("count", a.counts()[q.name]))
+	}
+
+	if err := encryptSecrets(a, secret, key); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// encryptSecrets 敏感字段使用备份口令派生的密钥重新加密，恢复到使用不同 secret 的环境时仍可解密
+func encryptSecrets(a *Archive, secret string, key []byte) error {
+	for _, env := range a.Environments {
+		if env.Config == "" {
+			continue
+		}
+		encrypted, err := utils.AESEncryptWithKey(env.Config, key)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt config of environment %d: %w", env.ID, err)
+		}
+		env.Config = encrypted
+	}
+	for _, credential := range a.RegistryCredentials {
+		password, err := utils.AESDecrypt(credential.McpRegistryCredential.Password, secret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt registry credential %s: %w", credential.Name, err)
+		}
+		if credential.Password, err = utils.AESEncryptWithKey(password, key); err != nil {
+			return fmt.Errorf("failed to encrypt registry credential %s: %w", credential.Name, err)
+		}
+		credential.McpRegistryCredential.Password = ""
+	}
+	return nil
+}
@@ -0,0 +1,533 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConflictPolicy 恢复时目标数据库已存在同一记录的处理方式
+type ConflictPolicy string
+
+const (
+	// ConflictSkip 保留数据库中的记录，引用它的数据指向已有记录
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite 使用归档内容覆盖已有记录
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename 作为新记录导入并在名称后追加后缀；
+	// 部门、角色、用户和托管实例 (容器按实例ID命名) 不重命名，按 skip 处理
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// ParseConflictPolicy 解析冲突处理方式
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy %q, expected skip, overwrite or rename", s)
+	}
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	Conflict ConflictPolicy
+	// DryRun 在事务中执行完整恢复后回滚，统计结果与实际恢复一致
+	DryRun bool
+	// Secret 目标服务的加密密钥，凭证密码使用它重新加密
+	Secret string
+	// Passphrase 导出时使用的备份口令
+	Passphrase string
+}
+
+// TableStats 单类数据的恢复结果
+type TableStats struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Renamed     int `json:"renamed"`
+	Skipped     int `json:"skipped"`
+}
+
+// errDryRun 回滚演练事务
+var errDryRun = errors.New("dry run")
+
+// restorer 单次恢复的状态，归档中的 ID 映射到数据库中的 ID
+type restorer struct {
+	tx     *gorm.DB
+	opts   RestoreOptions
+	key    []byte // 备份口令派生的密钥，版本 1 的归档为 nil
+	suffix string
+	stats  map[string]*TableStats
+
+	deptIDs     map[uint]uint
+	roleIDs     map[uint]uint
+	userIDs     map[uint]uint
+	envIDs      map[uint]uint
+	templateIDs map[uint]uint
+	packageIDs  map[string]string
+}
+
+// Restore 将归档导入数据库，所有写入在同一事务中完成，任一记录失败时整体回滚
+func Restore(ctx context.Context, a *Archive, opts RestoreOptions) (map[string]*TableStats, error) {
+	if opts.Passphrase == "" {
+		return nil, fmt.Errorf("backup passphrase is required")
+	}
+	key, err := a.Manifest.passphraseKey(opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r := &restorer{
+		opts:        opts,
+		key:         key,
+		suffix:      "-restored-" + time.Now().Format("0102150405"),
+		stats:       map[string]*TableStats{},
+		deptIDs:     map[uint]uint{},
+		roleIDs:     map[uint]uint{},
+		userIDs:     map[uint]uint{},
+		envIDs:      map[uint]uint{},
+		templateIDs: map[uint]uint{},
+		packageIDs:  map[string]string{},
+	}
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"depts", func() error { return forEach(r, "depts", a.Depts, r.restoreDept) }},
+		{"roles", func() error { return forEach(r, "roles", a.Roles, r.restoreRole) }},
+		{"users", func() error { return forEach(r, "users", a.Users, r.restoreUser) }},
+		{"rolesDepts", func() error { return forEach(r, "rolesDepts", a.RolesDepts, r.restoreRoleDept) }},
+		{"usersRoles", func() error { return forEach(r, "usersRoles", a.UsersRoles, r.restoreUserRole) }},
+		{"environments", func() error { return forEach(r, "environments", a.Environments, r.restoreEnvironment) }},
+		{"registryCredentials", func() error {
+			return forEach(r, "registryCredentials", a.RegistryCredentials, r.restoreRegistryCredential)
+		}},
+		{"codePackages", func() error { return forEach(r, "codePackages", a.CodePackages, r.restoreCodePackage) }},
+		{"templates", func() error { return forEach(r, "templates", a.Templates, r.restoreTemplate) }},
+		{"instances", func() error { return forEach(r, "instances", a.Instances, r.restoreInstance) }},
+	}
+
+	err = mysql.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r.tx = tx
+		for _, step := range steps {
+			if err := step.run(); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", step.name, err)
+			}
+			s := r.table(step.name)
+			logger.Info("Restored",
+				zap.String("table", step.name),
+				zap.Int("created", s.Created),
+				zap.Int("overwritten", s.Overwritten),
+				zap.Int("renamed", s.Renamed),
+				zap.Int("skipped", s.Skipped),
+				zap.Bool("dry_run", opts.DryRun),
+			)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return r.stats, nil
+}
+
+// forEach 依次恢复每条记录
+func forEach[T any](r *restorer, table string, records []T, restore func(T, *TableStats) error) error {
+	s := r.table(table)
+	for _, record := range records {
+		if err := restore(record, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) table(name string) *TableStats {
+	if r.stats[name] == nil {
+		r.stats[name] = &TableStats{}
+	}
+	return r.stats[name]
+}
+
+// find 查找第一条匹配记录
+func (r *restorer) find(dest any, query string, args ...any) (bool, error) {
+	result := r.tx.Where(query, args...).Limit(1).Find(dest)
+	return result.RowsAffected > 0, result.Error
+}
+
+// conflict 记录冲突处理日志，返回是否作为新记录重命名导入
+func (r *restorer) conflict(table, key string, renamable bool, s *TableStats) bool {
+	policy := r.opts.Conflict
+	if policy == ConflictRename && !renamable {
+		logger.Warn("Record cannot be renamed, skipped", zap.String("table", table), zap.String("key", key))
+		policy = ConflictSkip
+	}
+	logger.Info("Conflict", zap.String("table", table), zap.String("key", key), zap.String("policy", string(policy)))
+	switch policy {
+	case ConflictOverwrite:
+		s.Overwritten++
+	case ConflictRename:
+		s.Renamed++
+		return true
+	default:
+		s.Skipped++
+	}
+	return false
+}
+
+func (r *restorer) restoreDept(d *model.SysDept, s *TableStats) error {
+	var existing model.SysDept
+	found, err := r.find(&existing, "dept_id = ?", d.DeptID)
+	if err != nil {
+		return err
+	}
+	r.deptIDs[d.DeptID] = d.DeptID
+	if !found {
+		s.Created++
+		return r.tx.Create(d).Error
+	}
+	r.conflict("depts", d.Name, false, s)
+	if r.opts.Conflict == ConflictOverwrite {
+		return r.tx.Save(d).Error
+	}
+	return nil
+}
+
+func (r *restorer) restoreRole(role *model.SysRole, s *TableStats) error {
+	archiveID := role.RoleID
+	var existing model.SysRole
+	found, err := r.find(&existing, "name = ?", role.Name)
+	if err != nil {
+		return err
+	}
+	if found {
+		r.roleIDs[archiveID] = existing.RoleID
+		r.conflict("roles", role.Name, false, s)
+		if r.opts.Conflict != ConflictOverwrite {
+			return nil
+		}
+		role.RoleID = existing.RoleID
+		return r.tx.Save(role).Error
+	}
+	if taken, err := r.find(&existing, "role_id = ?", role.RoleID); err != nil {
+		return err
+	} else if taken {
+		role.RoleID = 0
+	}
+	if err := r.tx.Create(role).Error; err != nil {
+		return err
+	}
+	r.roleIDs[archiveID] = role.RoleID
+	s.Created++
+	return nil
+}
+
+func (r *restorer) restoreUser(u *model.SysUser, s *TableStats) error {
+	archiveID := u.UserID
+	var existing model.SysUser
+	found, err := r.find(&existing, "username = ?", u.Username)
+	if err != nil {
+		return err
+	}
+	if found {
+		r.userIDs[archiveID] = existing.UserID
+		r.conflict("users", stringValue(u.Username), false, s)
+		if r.opts.Conflict != ConflictOverwrite {
+			return nil
+		}
+		u.UserID = existing.UserID
+		return r.tx.Save(u).Error
+	}
+	if taken, err := r.find(&existing, "user_id = ?", u.UserID); err != nil {
+		return err
+	} else if taken {
+		u.UserID = 0
+	}
+	if err := r.tx.Create(u).Error; err != nil {
+		return err
+	}
+	r.userIDs[archiveID] = u.UserID
+	s.Created++
+	return nil
+}
+
+func (r *restorer) restoreRoleDept(rd *model.SysRolesDepts, s *TableStats) error {
+	roleID, ok1 := r.roleIDs[rd.RoleID]
+	deptID, ok2 := r.deptIDs[rd.DeptID]
+	if !ok1 || !ok2 {
+		s.Skipped++
+		return nil
+	}
+	result := r.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.SysRolesDepts{RoleID: roleID, DeptID: deptID})
+	if result.RowsAffected == 0 {
+		s.Skipped++
+	} else {
+		s.Created++
+	}
+	return result.Error
+}
+
+func (r *restorer) restoreUserRole(ur *model.SysUsersRoles, s *TableStats) error {
+	userID, ok1 := r.userIDs[ur.UserID]
+	roleID, ok2 := r.roleIDs[ur.RoleID]
+	if !ok1 || !ok2 {
+		s.Skipped++
+		return nil
+	}
+	result := r.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.SysUsersRoles{UserID: userID, RoleID: roleID})
+	if result.RowsAffected == 0 {
+		s.Skipped++
+	} else {
+		s.Created++
+	}
+	return result.Error
+}
+
+// decrypt 解密归档中的敏感字段
+func (r *restorer) decrypt(ciphertext string) (string, error) {
+	if r.key == nil {
+		return utils.AESDecrypt(ciphertext, r.opts.Passphrase)
+	}
+	return utils.AESDecryptWithKey(ciphertext, r.key)
+}
+
+func (r *restorer) restoreEnvironment(env *model.McpEnvironment, s *TableStats) error {
+	archiveID := env.ID
+	if env.Config != "" {
+		config, err := r.decrypt(env.Config)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt config of environment %s, check the passphrase: %w", env.Name, err)
+		}
+		env.Config = config
+	}
+	var existing model.McpEnvironment
+	found, err := r.find(&existing, "id = ?", env.ID)
+	if err != nil {
+		return err
+	}
+	if found {
+		if !r.conflict("environments", env.Name, true, s) {
+			r.envIDs[archiveID] = existing.ID
+			if r.opts.Conflict == ConflictOverwrite {
+				return r.tx.Save(env).Error
+			}
+			return nil
+		}
+		env.ID = 0
+		env.Name += r.suffix
+	} else {
+		s.Created++
+	}
+	if err := r.tx.Create(env).Error; err != nil {
+		return err
+	}
+	r.envIDs[archiveID] = env.ID
+	return nil
+}
+
+// reencryptCredential 将归档中的凭证密码解密后使用目标服务的密钥重新加密
+func (r *restorer) reencryptCredential(c *RegistryCredential) error {
+	password, err := r.decrypt(c.Password)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt registry credential %s, check the passphrase: %w", c.Name, err)
+	}
+	if c.McpRegistryCredential.Password, err = utils.AESEncrypt(password, r.opts.Secret); err != nil {
+		return fmt.Errorf("failed to encrypt registry credential %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+func (r *restorer) restoreRegistryCredential(c *RegistryCredential, s *TableStats) error {
+	if err := r.reencryptCredential(c); err != nil {
+		return err
+	}
+	record := &c.McpRegistryCredential
+	var existing model.McpRegistryCredential
+	found, err := r.find(&existing, "name = ?", record.Name)
+	if err != nil {
+		return err
+	}
+	if found {
+		if !r.conflict("registryCredentials", record.Name, true, s) {
+			if r.opts.Conflict == ConflictOverwrite {
+				record.ID = existing.ID
+				return r.tx.Save(record).Error
+			}
+			return nil
+		}
+		record.Name += r.suffix
+	} else {
+		s.Created++
+	}
+	if taken, err := r.find(&existing, "id = ?", record.ID); err != nil {
+		return err
+	} else if taken {
+		record.ID = 0
+	}
+	return r.tx.Create(record).Error
+}
+
+func (r *restorer) restoreCodePackage(pkg *model.McpCodePackage, s *TableStats) error {
+	archiveID := pkg.PackageID
+	var existing model.McpCodePackage
+	found, err := r.find(&existing, "package_id = ?", pkg.PackageID)
+	if err != nil {
+		return err
+	}
+	if found {
+		if !r.conflict("codePackages", pkg.PackageID, true, s) {
+			r.packageIDs[archiveID] = existing.PackageID
+			if r.opts.Conflict == ConflictOverwrite {
+				pkg.ID = existing.ID
+				return r.tx.Save(pkg).Error
+			}
+			return nil
+		}
+		// 重命名的代码包仍指向同一份存储文件
+		pkg.PackageID = uuid.New().String()
+	} else {
+		s.Created++
+	}
+	pkg.ID = 0
+	if err := r.tx.Create(pkg).Error; err != nil {
+		return err
+	}
+	r.packageIDs[archiveID] = pkg.PackageID
+	return nil
+}
+
+func (r *restorer) restoreTemplate(t *model.McpTemplate, s *TableStats) error {
+	archiveID := t.ID
+	t.EnvironmentID = int32(r.mapEnvironment(uint(t.EnvironmentID)))
+	t.PackageID = r.mapPackage(t.PackageID)
+	var existing model.McpTemplate
+	found, err := r.find(&existing, "id = ?", t.ID)
+	if err != nil {
+		return err
+	}
+	if found {
+		if !r.conflict("templates", t.Name, true, s) {
+			r.templateIDs[archiveID] = existing.ID
+			if r.opts.Conflict == ConflictOverwrite {
+				return r.tx.Save(t).Error
+			}
+			return nil
+		}
+		t.ID = 0
+		t.Name += r.suffix
+	} else {
+		s.Created++
+	}
+	if err := r.tx.Create(t).Error; err != nil {
+		return err
+	}
+	r.templateIDs[archiveID] = t.ID
+	return nil
+}
+
+func (r *restorer) restoreInstance(instance *model.McpInstance, s *TableStats) error {
+	r.remapInstance(instance)
+
+	var existing model.McpInstance
+	found, err := r.find(&existing, "instance_id = ? OR instance_name = ?", instance.InstanceID, instance.InstanceName)
+	if err != nil {
+		return err
+	}
+	rename := false
+	if found {
+		// 托管实例的容器、服务和标签按实例ID生成，不能作为副本导入
+		rename = r.conflict("instances", instance.InstanceID, instance.AccessType != model.AccessTypeHosting, s)
+		if !rename && r.opts.Conflict != ConflictOverwrite {
+			return nil
+		}
+		if rename {
+			instance.InstanceID = uuid.New().String()
+			instance.InstanceName += r.suffix
+		}
+	} else {
+		s.Created++
+	}
+
+	// 自定义访问路径已被其他实例使用时不恢复，重命名导入的副本也不使用原实例的路径
+	if instance.Slug != nil {
+		var owner model.McpInstance
+		taken, err := r.find(&owner, "slug = ? AND instance_id <> ?", *instance.Slug, instance.InstanceID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			logger.Info("Slug taken, restoring instance without it", zap.String("instanceId", instance.InstanceID), zap.String("slug", *instance.Slug))
+			instance.Slug = nil
+		}
+	}
+
+	if err := rebuildPublicProxyConfig(instance); err != nil {
+		return err
+	}
+
+	if found && !rename {
+		instance.ID = existing.ID
+		return r.tx.Save(instance).Error
+	}
+	instance.ID = 0
+	return r.tx.Create(instance).Error
+}
+
+// remapInstance 将实例引用的环境、模板和代码包替换为数据库中的ID，并清除运行时状态
+func (r *restorer) remapInstance(instance *model.McpInstance) {
+	instance.EnvironmentID = r.mapEnvironment(instance.EnvironmentID)
+	if id, ok := r.templateIDs[instance.TemplateID]; ok {
+		instance.TemplateID = id
+	}
+	instance.PackageID = r.mapPackage(instance.PackageID)
+	// 运行时观测到的状态由监控任务重新探测
+	instance.ContainerIsReady = false
+	instance.ContainerRestartCount = 0
+}
+
+// rebuildPublicProxyConfig 公共代理配置由实例配置重新生成，不使用归档中的值，重命名导入的实例使用新的实例ID
+func rebuildPublicProxyConfig(instance *model.McpInstance) error {
+	publicProxyConfig, err := biz.GInstanceBiz.DerivePublicProxyConfig(instance)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild public proxy config of instance %s: %w", instance.InstanceID, err)
+	}
+	instance.PublicProxyConfig = publicProxyConfig
+	return nil
+}
+
+// mapEnvironment 归档中的环境ID对应的数据库环境ID，未导入的环境保持原值
+func (r *restorer) mapEnvironment(id uint) uint {
+	if mapped, ok := r.envIDs[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// mapPackage 归档中的代码包ID对应的数据库代码包ID
+func (r *restorer) mapPackage(id string) string {
+	if mapped, ok := r.packageIDs[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
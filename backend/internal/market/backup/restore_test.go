@@ -0,0 +1,183 @@
+package backup
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	for input, want := range map[string]ConflictPolicy{
+		"skip":      ConflictSkip,
+		"overwrite": ConflictOverwrite,
+		"rename":    ConflictRename,
+		"":          "",
+		"Skip":      "",
+		"replace":   "",
+	} {
+		got, err := ParseConflictPolicy(input)
+		if got != want || (err != nil) != (want == "") {
+			t.Errorf("ParseConflictPolicy(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestConflict(t *testing.T) {
+	logger.Init("error", "json")
+	tests := []struct {
+		policy     ConflictPolicy
+		renamable  bool
+		wantRename bool
+		want       TableStats
+	}{
+		{policy: ConflictSkip, renamable: true, want: TableStats{Skipped: 1}},
+		{policy: ConflictOverwrite, renamable: true, want: TableStats{Overwritten: 1}},
+		{policy: ConflictOverwrite, renamable: false, want: TableStats{Overwritten: 1}},
+		{policy: ConflictRename, renamable: true, wantRename: true, want: TableStats{Renamed: 1}},
+		// 部门、角色、用户和托管实例不能重命名，按 skip 处理
+		{policy: ConflictRename, renamable: false, want: TableStats{Skipped: 1}},
+	}
+	for _, tt := range tests {
+		r := &restorer{opts: RestoreOptions{Conflict: tt.policy}}
+		var s TableStats
+		if got := r.conflict("templates", "fetch", tt.renamable, &s); got != tt.wantRename || s != tt.want {
+			t.Errorf("conflict(%s, renamable=%v) = %v %+v, want %v %+v", tt.policy, tt.renamable, got, s, tt.wantRename, tt.want)
+		}
+	}
+}
+
+func TestRestoreJoinTablesSkipUnmapped(t *testing.T) {
+	r := &restorer{
+		roleIDs: map[uint]uint{1: 10},
+		deptIDs: map[uint]uint{},
+		userIDs: map[uint]uint{},
+	}
+	var s TableStats
+	// 引用的角色、部门或用户未导入时跳过，不写入数据库
+	if err := r.restoreRoleDept(&model.SysRolesDepts{RoleID: 1, DeptID: 2}, &s); err != nil {
+		t.Fatalf("restoreRoleDept() error = %v", err)
+	}
+	if err := r.restoreUserRole(&model.SysUsersRoles{UserID: 3, RoleID: 1}, &s); err != nil {
+		t.Fatalf("restoreUserRole() error = %v", err)
+	}
+	if s != (TableStats{Skipped: 2}) {
+		t.Errorf("stats = %+v, want 2 skipped", s)
+	}
+}
+
+func TestRemapInstance(t *testing.T) {
+	r := &restorer{
+		envIDs:      map[uint]uint{1: 11},
+		templateIDs: map[uint]uint{2: 22},
+		packageIDs:  map[string]string{"pkg-a": "pkg-b"},
+	}
+	tests := []struct {
+		name     string
+		instance model.McpInstance
+		want     model.McpInstance
+	}{
+		{
+			name:     "mapped references",
+			instance: model.McpInstance{EnvironmentID: 1, TemplateID: 2, PackageID: "pkg-a", ContainerIsReady: true, ContainerRestartCount: 3},
+			want:     model.McpInstance{EnvironmentID: 11, TemplateID: 22, PackageID: "pkg-b"},
+		},
+		{
+			name:     "references not in the archive are kept",
+			instance: model.McpInstance{EnvironmentID: 5, TemplateID: 6, PackageID: "pkg-c"},
+			want:     model.McpInstance{EnvironmentID: 5, TemplateID: 6, PackageID: "pkg-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := tt.instance
+			r.remapInstance(&instance)
+			if instance.EnvironmentID != tt.want.EnvironmentID || instance.TemplateID != tt.want.TemplateID ||
+				instance.PackageID != tt.want.PackageID || instance.ContainerIsReady || instance.ContainerRestartCount != 0 {
+				t.Errorf("remapInstance() = env %d template %d package %s ready %v restarts %d, want %+v",
+					instance.EnvironmentID, instance.TemplateID, instance.PackageID,
+					instance.ContainerIsReady, instance.ContainerRestartCount, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildPublicProxyConfig(t *testing.T) {
+	// 重命名导入的实例使用新的实例ID，归档中的公共代理配置被替换
+	instance := &model.McpInstance{
+		InstanceID:        "11111111-new",
+		AccessType:        model.AccessTypeProxy,
+		McpProtocol:       model.McpProtocolStreamableHttp,
+		SourceConfig:      json.RawMessage(`{"mcpServers":{"a":{"url":"https://a.example.com/mcp"},"b":{"url":"https://b.example.com/mcp"}}}`),
+		PublicProxyConfig: json.RawMessage(`{"mcpServers":{"a":{"url":"/00000000-old/a"}}}`),
+	}
+	if err := rebuildPublicProxyConfig(instance); err != nil {
+		t.Fatalf("rebuildPublicProxyConfig() error = %v", err)
+	}
+	config := string(instance.PublicProxyConfig)
+	if strings.Contains(config, "00000000-old") || !strings.Contains(config, "/11111111-new/a") || !strings.Contains(config, "/11111111-new/b") {
+		t.Errorf("PublicProxyConfig = %s, want entries of the new instance id", config)
+	}
+
+	instance.AccessType = "unknown"
+	if err := rebuildPublicProxyConfig(instance); err == nil {
+		t.Error("rebuildPublicProxyConfig() of an unsupported access type error = nil")
+	}
+}
+
+func TestSecretsReencryptedWithPassphrase(t *testing.T) {
+	const sourceSecret, targetSecret, passphrase = "source-secret", "target-secret", "correct horse"
+	manifest := Manifest{FormatVersion: FormatVersion, KDF: testKDF()}
+	exportKey, err := manifest.passphraseKey(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := utils.AESEncrypt("registry-password", sourceSecret)
+	a := &Archive{
+		Environments:        []*model.McpEnvironment{{ID: 1, Name: "prod", Config: "kubeconfig"}, {ID: 2, Name: "docker"}},
+		RegistryCredentials: []*RegistryCredential{{McpRegistryCredential: model.McpRegistryCredential{Name: "hub", Password: stored}}},
+	}
+	if err := encryptSecrets(a, sourceSecret, exportKey); err != nil {
+		t.Fatalf("encryptSecrets() error = %v", err)
+	}
+	credential := a.RegistryCredentials[0]
+	if a.Environments[0].Config == "kubeconfig" || a.Environments[1].Config != "" || credential.McpRegistryCredential.Password != "" {
+		t.Fatalf("encryptSecrets() left plaintext or the stored ciphertext: %+v %+v", a.Environments[0], credential)
+	}
+
+	// 目标服务使用不同的 secret，凭证密码按目标 secret 重新加密
+	restoreKey, _ := manifest.passphraseKey(passphrase)
+	r := &restorer{key: restoreKey, opts: RestoreOptions{Secret: targetSecret, Passphrase: passphrase}}
+	if config, err := r.decrypt(a.Environments[0].Config); err != nil || config != "kubeconfig" {
+		t.Errorf("decrypt() = %q, %v, want kubeconfig", config, err)
+	}
+	if err := r.reencryptCredential(credential); err != nil {
+		t.Fatalf("reencryptCredential() error = %v", err)
+	}
+	if password, err := utils.AESDecrypt(credential.McpRegistryCredential.Password, targetSecret); err != nil || password != "registry-password" {
+		t.Errorf("restored password = %q, %v, want registry-password", password, err)
+	}
+
+	// 口令错误时解密失败
+	wrongKey, _ := manifest.passphraseKey("wrong horse")
+	wrong := &restorer{key: wrongKey, opts: RestoreOptions{Secret: targetSecret, Passphrase: "wrong horse"}}
+	if _, err := wrong.decrypt(a.Environments[0].Config); err == nil {
+		t.Error("decrypt() with a wrong passphrase error = nil")
+	}
+}
+
+func TestDecryptVersion1Archive(t *testing.T) {
+	// 版本 1 的归档直接使用口令加密敏感字段
+	encrypted, _ := utils.AESEncrypt("kubeconfig", "correct horse")
+	key, err := (&Manifest{FormatVersion: 1}).passphraseKey("correct horse")
+	if err != nil || key != nil {
+		t.Fatalf("passphraseKey() of version 1 = %x, %v, want nil", key, err)
+	}
+	r := &restorer{key: key, opts: RestoreOptions{Passphrase: "correct horse"}}
+	if config, err := r.decrypt(encrypted); err != nil || config != "kubeconfig" {
+		t.Errorf("decrypt() = %q, %v, want kubeconfig", config, err)
+	}
+}
@@ -0,0 +1,70 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// AuditBiz 审计日志数据处理层
+type AuditBiz struct {
+	ctx context.Context
+}
+
+// GAuditBiz 全局审计日志数据处理层实例
+var GAuditBiz *AuditBiz
+
+func init() {
+	GAuditBiz = NewAuditBiz(context.Background())
+}
+
+// NewAuditBiz 创建审计日志数据处理层实例
+func NewAuditBiz(ctx context.Context) *AuditBiz {
+	return &AuditBiz{
+		ctx: ctx,
+	}
+}
+
+// Record 记录审计日志，操作人和请求ID取自上下文；写入失败只记录日志，不影响操作本身
+func (biz *AuditBiz) Record(ctx context.Context, action, resourceType, resourceID string, detail any) {
+	biz.record(ctx, action, resourceType, resourceID, detail)
+}
+
+// record 写入审计日志，返回写入的记录，失败时返回 nil
+func (biz *AuditBiz) record(ctx context.Context, action, resourceType, resourceID string, detail any) *model.SysAuditLog {
+	log := logger.FromContext(ctx)
+	data, err := json.Marshal(detail)
+	if err != nil {
+		log.Warn("Failed to marshal audit detail", zap.String("action", action), zap.Error(err))
+		data = nil
+	}
+	record := &model.SysAuditLog{
+		Actor:        common.ActorFromContext(ctx),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Detail:       data,
+		RequestID:    logger.RequestIDFromContext(ctx),
+	}
+	if err := mysql.SysAuditLogRepo.Create(ctx, record); err != nil {
+		log.Warn("Failed to record audit log", zap.String("action", action), zap.String("resourceId", resourceID), zap.Error(err))
+		return nil
+	}
+	return record
+}
+
+// List 按时间从新到旧查询审计日志，最多返回 limit 条
+func (biz *AuditBiz) List(ctx context.Context, filter mysql.SysAuditLogFilter, limit int) ([]*model.SysAuditLog, error) {
+	logs, err := mysql.SysAuditLogRepo.List(ctx, filter, limit)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	return logs, nil
+}
@@ -0,0 +1,85 @@
+package biz
+
+import (
+	"context"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/catalog"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+)
+
+// CatalogBiz MCP 服务目录数据处理层
+type CatalogBiz struct {
+	ctx context.Context
+}
+
+// GCatalogBiz 全局服务目录数据处理层实例
+var GCatalogBiz *CatalogBiz
+
+func init() {
+	GCatalogBiz = NewCatalogBiz(context.Background())
+}
+
+// NewCatalogBiz 创建服务目录数据处理层实例
+func NewCatalogBiz(ctx context.Context) *CatalogBiz {
+	return &CatalogBiz{
+		ctx: ctx,
+	}
+}
+
+// GetEntry 根据目录条目ID获取条目
+func (biz *CatalogBiz) GetEntry(ctx context.Context, catalogID string) (*model.McpCatalogEntry, error) {
+	return mysql.McpCatalogRepo.FindByCatalogID(ctx, catalogID)
+}
+
+// ListEntries 分页搜索目录条目
+func (biz *CatalogBiz) ListEntries(ctx context.Context, keyword, category string, verifiedOnly bool, sortBy string, page, pageSize int32) ([]*model.McpCatalogEntry, int64, error) {
+	return mysql.McpCatalogRepo.FindWithPagination(ctx, keyword, category, verifiedOnly, sortBy, page, pageSize)
+}
+
+// RecordInstall 记录一次安装
+func (biz *CatalogBiz) RecordInstall(ctx context.Context, catalogID string) error {
+	return mysql.McpCatalogRepo.IncrementInstallCount(ctx, catalogID)
+}
+
+// UpdateCuration 更新条目的认证状态和展示顺序
+func (biz *CatalogBiz) UpdateCuration(ctx context.Context, catalogID string, verified bool, displayOrder int32) (*model.McpCatalogEntry, error) {
+	if err := mysql.McpCatalogRepo.UpdateCuration(ctx, catalogID, verified, displayOrder); err != nil {
+		return nil, err
+	}
+	return mysql.McpCatalogRepo.FindByCatalogID(ctx, catalogID)
+}
+
+// Rate 保存用户对条目的评分，同一用户重复评分时覆盖原评分
+func (biz *CatalogBiz) Rate(ctx context.Context, rating *model.McpCatalogRating) error {
+	return mysql.McpCatalogRatingRepo.Upsert(ctx, rating)
+}
+
+// ListRatings 分页获取条目的评分
+func (biz *CatalogBiz) ListRatings(ctx context.Context, catalogID string, page, pageSize int32) ([]*model.McpCatalogRating, int64, error) {
+	return mysql.McpCatalogRatingRepo.FindWithPagination(ctx, catalogID, page, pageSize)
+}
+
+// Categories 获取所有分类及条目数
+func (biz *CatalogBiz) Categories(ctx context.Context) ([]mysql.CategoryCount, error) {
+	return mysql.McpCatalogRepo.Categories(ctx)
+}
+
+// Sync 拉取远程目录并按条目ID创建或更新，远程目录中已删除的条目保留，避免影响已安装的模板和实例
+func (biz *CatalogBiz) Sync(ctx context.Context, url string) (int, error) {
+	timeout := time.Duration(config.GlobalConfig.Catalog.SyncTimeout) * time.Second
+	entries, err := catalog.Fetch(ctx, url, timeout)
+	if err != nil {
+		return 0, err
+	}
+	for i := range entries {
+		record := entries[i].Model(model.CatalogSourceRemote)
+		record.PrepareForCreate()
+		if err := mysql.McpCatalogRepo.Upsert(ctx, record); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
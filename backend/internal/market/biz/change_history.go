@@ -0,0 +1,117 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// InstanceDiffOptions 实例修改历史的字段比较选项。容器状态、锁定和健康状态等由服务端维护的字段不记录；
+// 容器创建选项和边车、初始化容器中包含环境变量的值，只记录已修改
+var InstanceDiffOptions = common.DiffOptions{
+	Ignore: []string{
+		"ID", "instanceID", "status", "containerStatus", "containerName", "containerServiceName",
+		"containerIsReady", "containerLastMessage", "containerRestartCount", "previousReplicas",
+		"targetConfig", "publicProxyConfig", "locked", "lockReason", "lockedBy", "lockedAt",
+		"healthStatus", "healthCheckedAt", "codePackageVersion", "createdAt", "updatedAt",
+	},
+	Sensitive:  []string{"tokens", "containerCreateOptions", "sidecars", "initContainers"},
+	EnvMaps:    []string{"environmentVariables"},
+	McpServers: []string{"sourceConfig"},
+}
+
+// TemplateDiffOptions 模板修改历史的字段比较选项
+var TemplateDiffOptions = common.DiffOptions{
+	Ignore:     []string{"ID", "sourceInstanceID", "createdAt", "updatedAt"},
+	Sensitive:  []string{"tokens"},
+	EnvMaps:    []string{"EnvironmentVariables"},
+	McpServers: []string{"mcpServers"},
+}
+
+// RecordChanges 记录一次修改的审计日志，并保存修改前后的字段级差异。before 为修改前的快照，
+// 可以是修改前编码的 JSON；没有字段变化时只记录审计日志。写入失败只记录日志，不影响修改本身
+func (biz *AuditBiz) RecordChanges(ctx context.Context, action, resourceType, resourceID string, before, after any, opts common.DiffOptions) {
+	log := logger.FromContext(ctx)
+	changes, err := common.DiffFields(before, after, opts)
+	if err != nil {
+		log.Warn("Failed to compute field changes", zap.String("action", action), zap.String("resourceId", resourceID), zap.Error(err))
+	}
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	record := biz.record(ctx, action, resourceType, resourceID, map[string]any{"fields": fields})
+	if len(changes) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		log.Warn("Failed to marshal field changes", zap.String("action", action), zap.Error(err))
+		return
+	}
+	history := &model.SysChangeHistory{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Actor:        common.ActorFromContext(ctx),
+		Changes:      data,
+		RequestID:    logger.RequestIDFromContext(ctx),
+	}
+	if record != nil {
+		history.AuditLogID = record.ID
+		history.Actor = record.Actor
+		history.CreatedAt = record.CreatedAt
+	} else {
+		history.CreatedAt = time.Now()
+	}
+	if err := mysql.SysChangeHistoryRepo.Create(ctx, history); err != nil {
+		log.Warn("Failed to record change history", zap.String("action", action), zap.String("resourceId", resourceID), zap.Error(err))
+	}
+}
+
+// ChangeHistory 分页查询资源的修改历史，从最新开始
+func (biz *AuditBiz) ChangeHistory(ctx context.Context, resourceType, resourceID string, page, pageSize int32) (*instancepb.ChangeHistoryResp, error) {
+	histories, total, err := mysql.SysChangeHistoryRepo.FindByResource(ctx, resourceType, resourceID, int(page), int(pageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &instancepb.ChangeHistoryResp{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		List:     make([]*instancepb.ChangeHistoryEntry, 0, len(histories)),
+	}
+	for _, history := range histories {
+		entry := &instancepb.ChangeHistoryEntry{
+			Id:          uint64(history.ID),
+			AuditLogId:  uint64(history.AuditLogID),
+			Actor:       history.Actor,
+			RequestId:   history.RequestID,
+			CreatedAt:   common.FormatTimeRFC3339(ctx, history.CreatedAt),
+			CreatedAtMs: common.TimeMillis(history.CreatedAt),
+		}
+		var changes []common.FieldChange
+		if err := json.Unmarshal(history.Changes, &changes); err != nil {
+			logger.FromContext(ctx).Warn("Failed to parse change history", zap.Uint("id", history.ID), zap.Error(err))
+		}
+		for _, change := range changes {
+			entry.Changes = append(entry.Changes, &instancepb.FieldChange{
+				Field:    change.Field,
+				Old:      string(change.Old),
+				New:      string(change.New),
+				Redacted: change.Redacted,
+			})
+		}
+		resp.List = append(resp.List, entry)
+	}
+	return resp, nil
+}
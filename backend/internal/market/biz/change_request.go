@@ -0,0 +1,216 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// approvedChangeKey 上下文中审批通过、正在执行的变更请求
+type approvedChangeKey struct{}
+
+// ChangeRequestBiz 受保护环境的双人审批：编辑和删除受保护环境中的实例时先创建变更请求，
+// 另一位管理员审批通过后按正常流程执行保存的请求
+type ChangeRequestBiz struct {
+	ctx context.Context
+}
+
+// GChangeRequestBiz 全局变更请求数据处理层实例
+var GChangeRequestBiz *ChangeRequestBiz
+
+func init() {
+	GChangeRequestBiz = NewChangeRequestBiz(context.Background())
+}
+
+// NewChangeRequestBiz 创建变更请求数据处理层实例
+func NewChangeRequestBiz(ctx context.Context) *ChangeRequestBiz {
+	return &ChangeRequestBiz{
+		ctx: ctx,
+	}
+}
+
+// WithApprovedChange 标记上下文正在执行审批通过的变更请求，执行时不再要求审批
+func WithApprovedChange(ctx context.Context, request *model.McpChangeRequest) context.Context {
+	return context.WithValue(ctx, approvedChangeKey{}, request)
+}
+
+// ApprovedChangeFromContext 上下文中正在执行的变更请求，没有时返回 nil
+func ApprovedChangeFromContext(ctx context.Context) *model.McpChangeRequest {
+	request, _ := ctx.Value(approvedChangeKey{}).(*model.McpChangeRequest)
+	return request
+}
+
+// RequireApproval 实例属于受保护环境时创建待审批的变更请求，payload 为审批通过后执行的请求。
+// 返回 CodeChangeRequestPending，data 中为创建的请求；实例已有待审批请求时返回 CodeChangeRequestExists。
+// 非受保护环境和执行审批通过的请求时返回 nil，调用方直接执行
+func (biz *ChangeRequestBiz) RequireApproval(ctx context.Context, instance *model.McpInstance, operation string, payload any) error {
+	if instance.EnvironmentID == 0 || ApprovedChangeFromContext(ctx) != nil {
+		return nil
+	}
+	environment, err := mysql.McpEnvironmentRepo.FindByID(ctx, instance.EnvironmentID)
+	if err != nil {
+		// 环境已删除时按非受保护环境处理
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return common.WrapError(err, i18n.CodeChangeRequestFailure)
+	}
+	if !environment.Protected {
+		return nil
+	}
+
+	now := time.Now()
+	pending, err := mysql.McpChangeRequestRepo.FindPendingByResource(ctx, model.AuditResourceInstance, instance.InstanceID, now)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeChangeRequestFailure)
+	}
+	if pending != nil {
+		return &common.Error{Code: i18n.CodeChangeRequestExists, Args: []any{instance.InstanceName, pending.ID}, Data: pending}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeChangeRequestFailure)
+	}
+	request := &model.McpChangeRequest{
+		Operation:        operation,
+		ResourceType:     model.AuditResourceInstance,
+		ResourceID:       instance.InstanceID,
+		ResourceName:     instance.InstanceName,
+		EnvironmentID:    environment.ID,
+		Payload:          data,
+		RequestedBy:      common.ActorFromContext(ctx),
+		RequestedByID:    common.UserIDFromContext(ctx),
+		ImpersonatedByID: common.ImpersonatorIDFromContext(ctx),
+		ExpiresAt:        now.Add(time.Duration(config.GlobalConfig.ChangeRequest.ExpireHours) * time.Hour),
+	}
+	if err := mysql.McpChangeRequestRepo.Create(ctx, request); err != nil {
+		return common.WrapError(err, i18n.CodeChangeRequestFailure)
+	}
+	GAuditBiz.Record(ctx, model.AuditActionChangeRequestCreate, model.AuditResourceInstance, instance.InstanceID, map[string]any{
+		"changeRequestId": request.ID,
+		"operation":       operation,
+		"environmentId":   environment.ID,
+		"expiresAt":       request.ExpiresAt,
+		"impersonatedBy":  request.ImpersonatedByID,
+	})
+	return &common.Error{
+		Code: i18n.CodeChangeRequestPending,
+		Args: []any{instance.InstanceName, environment.Name, operation, request.ID},
+		Data: request,
+	}
+}
+
+// Get 查询变更请求，过期的待审批请求返回时标记为过期
+func (biz *ChangeRequestBiz) Get(ctx context.Context, id uint) (*model.McpChangeRequest, error) {
+	request, err := mysql.McpChangeRequestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	if request == nil {
+		return nil, common.NewError(i18n.CodeChangeRequestNotFound, id)
+	}
+	if request.IsExpired(time.Now()) {
+		request.Status = model.ChangeRequestStatusExpired
+	}
+	return request, nil
+}
+
+// List 分页查询变更请求，查询前将过期的待审批请求标记为过期
+func (biz *ChangeRequestBiz) List(ctx context.Context, status string, requestedByID uint, page, pageSize int) ([]*model.McpChangeRequest, int64, error) {
+	if _, err := mysql.McpChangeRequestRepo.ExpirePending(ctx, time.Now()); err != nil {
+		logger.Warn("Failed to expire change requests", zap.Error(err))
+	}
+	return mysql.McpChangeRequestRepo.FindPage(ctx, status, requestedByID, page, pageSize)
+}
+
+// Review 审批变更请求，审批人取自上下文且不能是申请人，管理员模拟用户提交或审批时同样按管理员本人比较。
+// approve 为 true 时状态置为 approved，调用方随后执行保存的请求并调用 RecordResult；审批结果和双方写入审计日志
+func (biz *ChangeRequestBiz) Review(ctx context.Context, id uint, approve bool, comment string) (*model.McpChangeRequest, error) {
+	request, err := mysql.McpChangeRequestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	if request == nil {
+		return nil, common.NewError(i18n.CodeChangeRequestNotFound, id)
+	}
+	now := time.Now()
+	if request.IsExpired(now) {
+		if err := mysql.McpChangeRequestRepo.UpdateResult(ctx, request.ID, model.ChangeRequestStatusExpired, ""); err != nil {
+			logger.Warn("Failed to expire change request", zap.Uint("changeRequestId", request.ID), zap.Error(err))
+		}
+		return nil, common.NewError(i18n.CodeChangeRequestExpired, id, common.FormatTimeRFC3339(ctx, request.ExpiresAt))
+	}
+	if request.Status != model.ChangeRequestStatusPending {
+		return nil, common.NewError(i18n.CodeChangeRequestNotPending, id, request.Status)
+	}
+	reviewer, reviewerID := common.ActorFromContext(ctx), common.UserIDFromContext(ctx)
+	if reviewer == request.RequestedBy || sameRequester(request, reviewerID, common.ImpersonatorIDFromContext(ctx)) {
+		return nil, common.NewError(i18n.CodeChangeRequestSelfReview, id)
+	}
+
+	request.Status = model.ChangeRequestStatusRejected
+	action := model.AuditActionChangeRequestReject
+	if approve {
+		request.Status = model.ChangeRequestStatusApproved
+		action = model.AuditActionChangeRequestApprove
+	}
+	request.ReviewedBy = reviewer
+	request.ReviewedByID = reviewerID
+	request.ReviewComment = comment
+	request.ReviewedAt = &now
+	claimed, err := mysql.McpChangeRequestRepo.Review(ctx, request)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	if !claimed {
+		// 其他管理员已审批或请求刚好过期，返回最新状态
+		status := model.ChangeRequestStatusExpired
+		if current, err := mysql.McpChangeRequestRepo.FindByID(ctx, id); err == nil && current != nil && current.Status != model.ChangeRequestStatusPending {
+			status = current.Status
+		}
+		return nil, common.NewError(i18n.CodeChangeRequestNotPending, id, status)
+	}
+
+	GAuditBiz.Record(ctx, action, request.ResourceType, request.ResourceID, map[string]any{
+		"changeRequestId": request.ID,
+		"operation":       request.Operation,
+		"requestedBy":     request.RequestedBy,
+		"reviewedBy":      request.ReviewedBy,
+		"comment":         comment,
+	})
+	return request, nil
+}
+
+// sameRequester 审批人或模拟审批人的管理员是否为申请人或模拟申请人提交的管理员
+func sameRequester(request *model.McpChangeRequest, reviewerIDs ...uint) bool {
+	for _, id := range reviewerIDs {
+		if id != 0 && (id == request.RequestedByID || id == request.ImpersonatedByID) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordResult 记录审批通过后执行的结果，执行失败时状态置为 failed 并保存原因
+func (biz *ChangeRequestBiz) RecordResult(ctx context.Context, request *model.McpChangeRequest, execErr error) {
+	if execErr == nil {
+		return
+	}
+	request.Status = model.ChangeRequestStatusFailed
+	request.Result = execErr.Error()
+	if err := mysql.McpChangeRequestRepo.UpdateResult(ctx, request.ID, request.Status, request.Result); err != nil {
+		logger.Warn("Failed to record change request result", zap.Uint("changeRequestId", request.ID), zap.Error(err))
+	}
+}
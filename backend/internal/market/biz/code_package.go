@@ -0,0 +1,175 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/codepackage"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+
+	"go.uber.org/zap"
+)
+
+// CodePackageEventChanged 代码包内容修改通知的事件类型
+const CodePackageEventChanged = "code.package.changed"
+
+// CodePackageChangeEdit 通过在线编辑修改代码包文件
+const CodePackageChangeEdit = "edit"
+
+// CodePackageWebhookEvent 代码包内容修改时 POST 给 webhook 的内容
+type CodePackageWebhookEvent struct {
+	Event          string `json:"event"`
+	PackageID      string `json:"packageId"`
+	PackageName    string `json:"packageName"`
+	ContentVersion int    `json:"contentVersion"`
+	Change         string `json:"change"`
+	FilePath       string `json:"filePath,omitempty"`
+	// OutdatedInstances 仍在运行旧代码的托管实例ID
+	OutdatedInstances []string `json:"outdatedInstances"`
+	ChangedAt         string   `json:"changedAt"`
+}
+
+// CodePackageBiz 代码包内容变更跟踪，托管实例的容器在创建时下载代码包，之后的修改需要重新部署才会生效
+type CodePackageBiz struct {
+	ctx context.Context
+}
+
+// GCodePackageBiz 全局代码包数据处理层实例
+var GCodePackageBiz *CodePackageBiz
+
+func init() {
+	GCodePackageBiz = NewCodePackageBiz(context.Background())
+}
+
+// NewCodePackageBiz 创建代码包数据处理层实例
+func NewCodePackageBiz(ctx context.Context) *CodePackageBiz {
+	return &CodePackageBiz{
+		ctx: ctx,
+	}
+}
+
+// StampVersion 容器即将使用代码包创建时，记录代码包当前的内容版本和内容校验和到实例，由调用方保存实例。
+// 查询失败时保持原版本，实例最多被误报为运行旧代码；计算校验和失败时清空校验和
+func (biz *CodePackageBiz) StampVersion(ctx context.Context, instance *model.McpInstance) {
+	if instance.PackageID == "" {
+		return
+	}
+	codePackage, err := mysql.McpCodePackageRepo.FindByPackageID(ctx, instance.PackageID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get code package version",
+			zap.String("instanceId", instance.InstanceID), zap.String("packageId", instance.PackageID), zap.Error(err))
+		return
+	}
+	instance.CodePackageVersion = codePackage.ContentVersion
+
+	checksum, err := biz.Checksum(ctx, codePackage)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to compute code package checksum",
+			zap.String("instanceId", instance.InstanceID), zap.String("packageId", instance.PackageID), zap.Error(err))
+	}
+	instance.CodePackageChecksum = checksum
+}
+
+// Checksum 计算代码包解压后内容的 sha256，包含在线编辑的修改
+func (biz *CodePackageBiz) Checksum(ctx context.Context, codePackage *model.McpCodePackage) (string, error) {
+	packageManager := codepackage.NewCodePackageManager(&config.GlobalConfig.Code, config.GlobalConfig.Storage.CodePath)
+	extractedPath, err := packageManager.EnsureExtracted(ctx, codePackage)
+	if err != nil {
+		return "", err
+	}
+	return utils.DirChecksum(extractedPath)
+}
+
+// OutdatedInstanceIDs 返回实例中运行旧代码的实例ID集合
+func (biz *CodePackageBiz) OutdatedInstanceIDs(ctx context.Context, instances []*model.McpInstance) (map[string]bool, error) {
+	var packageIDs []string
+	seen := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.PackageID != "" && !seen[instance.PackageID] {
+			seen[instance.PackageID] = true
+			packageIDs = append(packageIDs, instance.PackageID)
+		}
+	}
+	versions, err := mysql.McpCodePackageRepo.FindContentVersions(ctx, packageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code package versions: %w", err)
+	}
+	outdated := make(map[string]bool)
+	for _, instance := range instances {
+		if instance.CodeOutdated(versions[instance.PackageID]) {
+			outdated[instance.InstanceID] = true
+		}
+	}
+	return outdated, nil
+}
+
+// OutdatedInstances 使用代码包、仍在运行旧代码的启用中托管实例
+func (biz *CodePackageBiz) OutdatedInstances(ctx context.Context, packageID string) ([]*model.McpInstance, error) {
+	instances, err := mysql.McpInstanceRepo.FindByPackageID(ctx, packageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instances of code package: %w", err)
+	}
+	outdatedIDs, err := biz.OutdatedInstanceIDs(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+	var outdated []*model.McpInstance
+	for _, instance := range instances {
+		if outdatedIDs[instance.InstanceID] && instance.Status == model.InstanceStatusActive {
+			outdated = append(outdated, instance)
+		}
+	}
+	return outdated, nil
+}
+
+// MarkChanged 代码包内容修改后递增内容版本，记录受影响实例的时间线并异步通知所有 webhook，
+// 通知失败只记录日志
+func (biz *CodePackageBiz) MarkChanged(ctx context.Context, codePackage *model.McpCodePackage, change, filePath string) error {
+	version, err := mysql.McpCodePackageRepo.IncrementContentVersion(ctx, codePackage.PackageID)
+	if err != nil {
+		return fmt.Errorf("failed to update code package version: %w", err)
+	}
+	codePackage.ContentVersion = version
+
+	instances, err := biz.OutdatedInstances(ctx, codePackage.PackageID)
+	if err != nil {
+		return err
+	}
+	event := &CodePackageWebhookEvent{
+		Event:             CodePackageEventChanged,
+		PackageID:         codePackage.PackageID,
+		PackageName:       codePackage.OriginalName,
+		ContentVersion:    version,
+		Change:            change,
+		FilePath:          filePath,
+		OutdatedInstances: make([]string, 0, len(instances)),
+		ChangedAt:         time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, instance := range instances {
+		event.OutdatedInstances = append(event.OutdatedInstances, instance.InstanceID)
+		GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationCodeOutdated,
+			fmt.Sprintf("code package %s changed (%s), redeploy to apply", codePackage.PackageID, change))
+	}
+	logger.FromContext(ctx).Info("Code package changed",
+		zap.String("packageId", codePackage.PackageID), zap.Int("contentVersion", version),
+		zap.Strings("outdatedInstances", event.OutdatedInstances))
+
+	cfg := config.GlobalConfig.Code
+	timeout := time.Duration(cfg.WebhookTimeout) * time.Second
+	// 通知不受请求上下文取消的影响
+	notifyCtx := context.WithoutCancel(ctx)
+	for _, webhook := range cfg.Webhooks {
+		go func() {
+			if err := utils.PostJSON(notifyCtx, webhook, event, timeout); err != nil {
+				logger.Warn("Failed to send code package webhook",
+					zap.String("packageId", codePackage.PackageID), zap.String("webhook", webhook), zap.Error(err))
+			}
+		}()
+	}
+	return nil
+}
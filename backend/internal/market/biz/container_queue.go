@@ -0,0 +1,216 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// defaultCreateConcurrency 未配置时每个环境同时创建的容器数
+const defaultCreateConcurrency = 3
+
+// createQueueStats 各环境创建队列的排队数和创建中数，通过 expvar 在 /debug/vars 暴露
+var createQueueStats = expvar.NewMap("container_create_queue")
+
+// createQueue 单个环境的容器创建队列。创建中的容器占用名额直到就绪或等待超时，
+// 避免大量实例同时拉取镜像；超出名额的实例按创建顺序排队
+type createQueue struct {
+	creating int
+	waiting  []string
+	stats    *expvar.Map
+}
+
+// publish 更新队列指标，调用方持有 queueMu
+func (q *createQueue) publish() {
+	queued, creating := new(expvar.Int), new(expvar.Int)
+	queued.Set(int64(len(q.waiting)))
+	creating.Set(int64(q.creating))
+	q.stats.Set("queued", queued)
+	q.stats.Set("creating", creating)
+}
+
+// createConcurrency 每个环境同时创建的容器数
+func createConcurrency() int {
+	if config.GlobalConfig != nil && config.GlobalConfig.ContainerQueue.Concurrency > 0 {
+		return config.GlobalConfig.ContainerQueue.Concurrency
+	}
+	return defaultCreateConcurrency
+}
+
+// queue 获取环境的创建队列，不存在时创建，调用方持有 queueMu
+func (cd *ContainerBiz) queue(environmentID uint) *createQueue {
+	q, ok := cd.queues[environmentID]
+	if !ok {
+		q = &createQueue{stats: new(expvar.Map).Init()}
+		cd.queues[environmentID] = q
+		createQueueStats.Set(strconv.FormatUint(uint64(environmentID), 10), q.stats)
+	}
+	return q
+}
+
+// TryAcquireCreateSlot 环境有空闲名额且没有排队的实例时占用一个名额，返回是否占用成功。
+// 占用后由调用方创建容器，并通过 HoldCreateSlot 或 ReleaseCreateSlot 释放
+func (cd *ContainerBiz) TryAcquireCreateSlot(environmentID uint) bool {
+	cd.queueMu.Lock()
+	defer cd.queueMu.Unlock()
+	q := cd.queue(environmentID)
+	if q.creating >= createConcurrency() || len(q.waiting) > 0 {
+		return false
+	}
+	q.creating++
+	q.publish()
+	return true
+}
+
+// ReleaseCreateSlot 释放名额，并开始创建下一个排队的实例
+func (cd *ContainerBiz) ReleaseCreateSlot(environmentID uint) {
+	cd.queueMu.Lock()
+	defer cd.queueMu.Unlock()
+	q := cd.queue(environmentID)
+	if q.creating > 0 {
+		q.creating--
+	}
+	cd.dispatch(environmentID, q)
+}
+
+// HoldCreateSlot 在后台等待实例容器就绪或等待超时后释放名额
+func (cd *ContainerBiz) HoldCreateSlot(instance *model.McpInstance) {
+	go func() {
+		defer cd.ReleaseCreateSlot(instance.EnvironmentID)
+		cd.waitCreated(instance)
+	}()
+}
+
+// EnqueueCreate 将已保存为排队状态的实例加入环境的创建队列，返回排队位置（从 1 开始）
+func (cd *ContainerBiz) EnqueueCreate(environmentID uint, instanceID string) int32 {
+	cd.queueMu.Lock()
+	defer cd.queueMu.Unlock()
+	q := cd.queue(environmentID)
+	if !slices.Contains(q.waiting, instanceID) {
+		q.waiting = append(q.waiting, instanceID)
+	}
+	cd.dispatch(environmentID, q)
+	return int32(slices.Index(q.waiting, instanceID) + 1)
+}
+
+// DequeueCreate 将实例移出创建队列，返回实例是否在本副本的队列中
+func (cd *ContainerBiz) DequeueCreate(environmentID uint, instanceID string) bool {
+	cd.queueMu.Lock()
+	defer cd.queueMu.Unlock()
+	q := cd.queue(environmentID)
+	i := slices.Index(q.waiting, instanceID)
+	if i < 0 {
+		return false
+	}
+	q.waiting = slices.Delete(q.waiting, i, i+1)
+	q.publish()
+	return true
+}
+
+// QueuePosition 实例在创建队列中的位置（从 1 开始），不在队列中时为 0
+func (cd *ContainerBiz) QueuePosition(environmentID uint, instanceID string) int32 {
+	cd.queueMu.Lock()
+	defer cd.queueMu.Unlock()
+	return int32(slices.Index(cd.queue(environmentID).waiting, instanceID) + 1)
+}
+
+// RestoreCreateQueue 服务启动时将仍处于排队状态的实例按创建时间重新加入队列。
+// 队列只在本副本内存中，多副本部署时各副本分别限流，同一实例只会被一个副本认领创建
+func (cd *ContainerBiz) RestoreCreateQueue(ctx context.Context) error {
+	instances, err := mysql.McpInstanceRepo.FindByContainerStatus(ctx, []model.ContainerStatus{model.ContainerStatusQueued})
+	if err != nil {
+		return fmt.Errorf("failed to find queued instances: %w", err)
+	}
+	sort.SliceStable(instances, func(i, j int) bool { return instances[i].CreatedAt.Before(instances[j].CreatedAt) })
+	for _, instance := range instances {
+		cd.EnqueueCreate(instance.EnvironmentID, instance.InstanceID)
+	}
+	if len(instances) > 0 {
+		logger.FromContext(ctx).Info("Restored container creation queue", zap.Int("count", len(instances)))
+	}
+	return nil
+}
+
+// dispatch 在有空闲名额时开始创建排队的实例，调用方持有 queueMu
+func (cd *ContainerBiz) dispatch(environmentID uint, q *createQueue) {
+	for q.creating < createConcurrency() && len(q.waiting) > 0 {
+		instanceID := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.creating++
+		go cd.createQueued(environmentID, instanceID)
+	}
+	q.publish()
+}
+
+// createQueued 认领并创建排队的实例，实例已删除、停用或被其他副本认领时直接释放名额
+func (cd *ContainerBiz) createQueued(environmentID uint, instanceID string) {
+	ctx := cd.ctx
+	log := logger.FromContext(ctx).With(zap.String("instanceId", instanceID), zap.Uint("environmentId", environmentID))
+
+	claimed, err := mysql.McpInstanceRepo.ClaimQueued(ctx, instanceID)
+	if err != nil || !claimed {
+		if err != nil {
+			log.Warn("Failed to claim queued instance", zap.Error(err))
+		}
+		cd.ReleaseCreateSlot(environmentID)
+		return
+	}
+	instance, err := mysql.McpInstanceRepo.FindByInstanceID(ctx, instanceID)
+	if err != nil {
+		log.Warn("Failed to load queued instance", zap.Error(err))
+		cd.ReleaseCreateSlot(environmentID)
+		return
+	}
+
+	var options container.ContainerCreateOptions
+	err = json.Unmarshal(instance.ContainerCreateOptions, &options)
+	if err == nil {
+		err = cd.CreateContainer(&options, int32(environmentID), int32(instance.StartupTimeout))
+	}
+	if err != nil {
+		log.Warn("Failed to create queued container", zap.Error(err))
+		if err := mysql.McpInstanceRepo.UpdateContainerStatus(ctx, instanceID, model.ContainerStatusCreateFailed, err.Error()); err != nil {
+			log.Warn("Failed to update instance status", zap.Error(err))
+		}
+		GInstanceOperationBiz.Record(ctx, instanceID, model.InstanceOperationCreateFailed, err.Error())
+		cd.ReleaseCreateSlot(environmentID)
+		return
+	}
+	log.Info("Created queued container", zap.String("containerName", options.ContainerName))
+	cd.HoldCreateSlot(instance)
+}
+
+// waitCreated 轮询容器就绪状态直到就绪或超过实例的就绪等待时间，只用于占用创建名额，不更新实例
+func (cd *ContainerBiz) waitCreated(instance *model.McpInstance) {
+	entry, err := cd.GetRuntimeEntry(cd.ctx, instance.EnvironmentID)
+	if err != nil || entry == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(cd.ctx, ReadyWaitTimeout(instance))
+	defer cancel()
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if ready, _, err := entry.GetContainerManager().IsReady(ctx, instance.ContainerName); err == nil && ready {
+			return
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/cost"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// CostMonthLayout 成本查询的月份格式
+	CostMonthLayout = "2006-01"
+	// CostGroupByInstance 成本报表按实例分组
+	CostGroupByInstance = "instance"
+	// CostGroupByEnvironment 成本报表按环境分组
+	CostGroupByEnvironment = "environment"
+	// CostGroupByLabelPrefix 成本报表按标签分组，如 label:team
+	CostGroupByLabelPrefix = "label:"
+
+	// costBackfillDays 汇总任务未执行时最多补算的天数
+	costBackfillDays = 7
+	// maxCostOperations 一次汇总读取的实例操作记录上限
+	maxCostOperations = 200000
+)
+
+// costRunningOperations 之后实例运行并占用资源的操作，未就绪和崩溃重启期间仍占用资源
+var costRunningOperations = []string{
+	model.InstanceOperationCreate,
+	model.InstanceOperationEnable,
+	model.InstanceOperationRestart,
+	model.InstanceOperationRecreate,
+	model.InstanceOperationReady,
+	model.InstanceOperationUnready,
+	model.InstanceOperationCrash,
+}
+
+// costStoppedOperations 之后实例停止、不再计费的操作
+var costStoppedOperations = []string{
+	model.InstanceOperationDisable,
+	model.InstanceOperationStartupTimeout,
+	model.InstanceOperationRunningTimeout,
+	model.InstanceOperationCreateFailed,
+	model.InstanceOperationDelete,
+}
+
+// costOperations 影响运行状态和副本数的操作
+var costOperations = append(append([]string{model.InstanceOperationScale}, costRunningOperations...), costStoppedOperations...)
+
+// CostGroup 成本报表分组
+type CostGroup struct {
+	Key          string
+	Name         string
+	Cost         float64
+	RunningHours float64
+	Instances    int
+}
+
+// CostBiz 托管实例成本估算：按实例请求的 CPU 和内存、运行副本数和运行时长乘以环境的小时费率，
+// 运行时长由实例操作记录推导，停止期间不计费。每晚汇总前一天的成本，记录中保存当天的费率
+type CostBiz struct {
+	ctx context.Context
+}
+
+// GCostBiz 全局成本估算数据处理层实例
+var GCostBiz *CostBiz
+
+func init() {
+	GCostBiz = NewCostBiz(context.Background())
+}
+
+// NewCostBiz 创建成本估算数据处理层实例
+func NewCostBiz(ctx context.Context) *CostBiz {
+	return &CostBiz{
+		ctx: ctx,
+	}
+}
+
+// AggregateMissing 汇总最近一次汇总之后到昨天的每日成本，最多补算 costBackfillDays 天
+func (biz *CostBiz) AggregateMissing(ctx context.Context, now time.Time) error {
+	today := startOfDay(now)
+	first := today.AddDate(0, 0, -costBackfillDays)
+	latest, err := mysql.McpInstanceDailyCostRepo.LatestDay(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != "" {
+		if day, err := time.ParseInLocation(model.CostDayLayout, latest, now.Location()); err == nil && day.AddDate(0, 0, 1).After(first) {
+			first = day.AddDate(0, 0, 1)
+		}
+	}
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		if err := biz.Aggregate(ctx, day); err != nil {
+			return fmt.Errorf("aggregate cost of %s: %w", day.Format(model.CostDayLayout), err)
+		}
+	}
+	return nil
+}
+
+// Aggregate 汇总托管实例 day 当天的成本，重复汇总时覆盖。汇总时已删除的实例不再计算
+func (biz *CostBiz) Aggregate(ctx context.Context, day time.Time) error {
+	start := startOfDay(day)
+	end := start.AddDate(0, 0, 1)
+	dayKey := start.Format(model.CostDayLayout)
+
+	instances, err := mysql.McpInstanceRepo.FindByAccessType(ctx, model.AccessTypeHosting)
+	if err != nil {
+		return err
+	}
+	instances = slices.DeleteFunc(instances, func(instance *model.McpInstance) bool {
+		return !instance.CreatedAt.Before(end)
+	})
+	if len(instances) == 0 {
+		return nil
+	}
+	rates, err := biz.environmentRates(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.InstanceID)
+	}
+	operations, err := mysql.McpInstanceOperationRepo.FindByInstanceIDsSince(ctx, ids, costOperations, start, end, maxCostOperations)
+	if err != nil {
+		return err
+	}
+	changes := make(map[string][]*model.McpInstanceOperation)
+	for _, op := range operations {
+		changes[op.InstanceID] = append(changes[op.InstanceID], op)
+	}
+
+	costs := make([]*model.McpInstanceDailyCost, 0, len(instances))
+	for _, instance := range instances {
+		initial, err := biz.stateAt(ctx, instance, start, dayKey)
+		if err != nil {
+			logger.Warn("Failed to load instance state for cost", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+			continue
+		}
+		states := make([]cost.State, 0, len(changes[instance.InstanceID]))
+		state := initial
+		for _, op := range changes[instance.InstanceID] {
+			state = applyCostOperation(state, op, instance.DesiredReplicas())
+			states = append(states, state)
+		}
+		usage := cost.Accumulate(initial, states, start, end)
+		resources := instanceResources(instance)
+		rate := rates[instance.EnvironmentID]
+		costs = append(costs, &model.McpInstanceDailyCost{
+			InstanceID:    instance.InstanceID,
+			InstanceName:  instance.InstanceName,
+			EnvironmentID: instance.EnvironmentID,
+			Day:           dayKey,
+			Labels:        instance.Labels,
+			RunningHours:  cost.Round(usage.RunningHours),
+			ReplicaHours:  cost.Round(usage.ReplicaHours),
+			CPUCores:      resources.CPUCores,
+			MemoryGiB:     resources.MemoryGiB,
+			CPUHourRate:   rate.CPUHour,
+			GiBHourRate:   rate.GiBHour,
+			Cost:          cost.Round(usage.ReplicaHours * resources.HourlyCost(rate)),
+			RunningAtEnd:  usage.End.Running,
+			ReplicasAtEnd: usage.End.Replicas,
+		})
+	}
+	if err := mysql.McpInstanceDailyCostRepo.Upsert(ctx, costs); err != nil {
+		return err
+	}
+	logger.Info("Aggregated instance costs", zap.String("day", dayKey), zap.Int("instances", len(costs)))
+	return nil
+}
+
+// stateAt 实例在 start 时的运行状态，优先使用前一天汇总的结束状态，没有时从操作记录推导
+func (biz *CostBiz) stateAt(ctx context.Context, instance *model.McpInstance, start time.Time, dayKey string) (cost.State, error) {
+	previous, err := mysql.McpInstanceDailyCostRepo.FindLatestBefore(ctx, instance.InstanceID, dayKey)
+	if err != nil {
+		return cost.State{}, err
+	}
+	if previous != nil && previous.Day == start.AddDate(0, 0, -1).Format(model.CostDayLayout) {
+		return cost.State{At: start, Running: previous.RunningAtEnd, Replicas: previous.ReplicasAtEnd}, nil
+	}
+
+	operations, err := mysql.McpInstanceOperationRepo.FindByInstanceIDsSince(ctx, []string{instance.InstanceID}, costOperations, time.Time{}, start, maxCostOperations)
+	if err != nil {
+		return cost.State{}, err
+	}
+	state := cost.State{}
+	for _, op := range operations {
+		state = applyCostOperation(state, op, instance.DesiredReplicas())
+	}
+	state.At = start
+	return state, nil
+}
+
+// applyCostOperation 实例操作后的运行状态，缩容到 0 时保留之前的副本数用于启动时恢复
+func applyCostOperation(state cost.State, op *model.McpInstanceOperation, defaultReplicas int32) cost.State {
+	next := state
+	next.At = op.CreatedAt
+	switch {
+	case op.Operation == model.InstanceOperationScale:
+		var from, to int32
+		if _, err := fmt.Sscanf(op.Detail, "replicas: %d -> %d", &from, &to); err == nil {
+			next.Running = to > 0
+			if to > 0 {
+				next.Replicas = to
+			}
+		}
+	case slices.Contains(costRunningOperations, op.Operation):
+		next.Running = true
+	case slices.Contains(costStoppedOperations, op.Operation):
+		next.Running = false
+	}
+	if next.Running && next.Replicas <= 0 {
+		next.Replicas = max(defaultReplicas, 1)
+	}
+	return next
+}
+
+// instanceResources 实例每个副本请求的资源，主容器和边车容器累加
+func instanceResources(instance *model.McpInstance) cost.Resources {
+	var options container.ContainerCreateOptions
+	if len(instance.ContainerCreateOptions) == 0 || json.Unmarshal(instance.ContainerCreateOptions, &options) != nil {
+		return cost.Resources{}
+	}
+	resources := cost.ContainerResources(options.ResourceRequests, options.ResourceLimits)
+	for _, sc := range options.Sidecars {
+		resources = resources.Add(cost.ContainerResources(sc.ResourceRequests, sc.ResourceLimits))
+	}
+	return resources
+}
+
+// environmentRates 各环境当前的费率
+func (biz *CostBiz) environmentRates(ctx context.Context) (map[uint]cost.Rates, error) {
+	environments, err := mysql.McpEnvironmentRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[uint]cost.Rates, len(environments))
+	for _, env := range environments {
+		rates[env.ID] = cost.Rates{CPUHour: env.CostPerCPUHour, GiBHour: env.CostPerGiBHour}
+	}
+	return rates, nil
+}
+
+// HourlyCost 按实例当前的资源、副本数和环境费率估算每小时成本，实例停止时为 0
+func (biz *CostBiz) HourlyCost(ctx context.Context, instance *model.McpInstance) (float64, error) {
+	if instance.AccessType != model.AccessTypeHosting || instance.Status != model.InstanceStatusActive || instance.Replicas <= 0 {
+		return 0, nil
+	}
+	rates, err := biz.environmentRates(ctx)
+	if err != nil {
+		return 0, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	return cost.Round(float64(instance.Replicas) * instanceResources(instance).HourlyCost(rates[instance.EnvironmentID])), nil
+}
+
+// InstanceCosts 实例在 month 内的每日成本
+func (biz *CostBiz) InstanceCosts(ctx context.Context, instanceID string, month time.Time) ([]*model.McpInstanceDailyCost, error) {
+	from, to := monthDays(month)
+	costs, err := mysql.McpInstanceDailyCostRepo.FindByDays(ctx, instanceID, from, to)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	return costs, nil
+}
+
+// Report 月度成本报表，groupBy 为 instance、environment 或 label:<标签键>，按成本从高到低排列
+func (biz *CostBiz) Report(ctx context.Context, groupBy string, month time.Time) ([]*CostGroup, error) {
+	from, to := monthDays(month)
+	costs, err := mysql.McpInstanceDailyCostRepo.FindByDays(ctx, "", from, to)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeDatabaseError)
+	}
+
+	groups := make(map[string]*CostGroup)
+	instances := make(map[string]map[string]bool)
+	for _, c := range costs {
+		key, name := costGroupKey(groupBy, c)
+		group, ok := groups[key]
+		if !ok {
+			group = &CostGroup{Key: key, Name: name}
+			groups[key] = group
+			instances[key] = make(map[string]bool)
+		}
+		group.Cost += c.Cost
+		group.RunningHours += c.RunningHours
+		instances[key][c.InstanceID] = true
+	}
+
+	if groupBy == CostGroupByEnvironment && len(groups) > 0 {
+		ids := make([]string, 0, len(groups))
+		for key := range groups {
+			ids = append(ids, key)
+		}
+		if names, err := mysql.McpEnvironmentRepo.FindNamesByIDs(ctx, ids); err == nil {
+			for key, group := range groups {
+				if name, ok := names[key]; ok {
+					group.Name = name
+				}
+			}
+		}
+	}
+
+	result := make([]*CostGroup, 0, len(groups))
+	for key, group := range groups {
+		group.Cost = cost.Round(group.Cost)
+		group.RunningHours = cost.Round(group.RunningHours)
+		group.Instances = len(instances[key])
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// costGroupKey 成本记录所属分组，按标签分组时使用汇总当天的标签
+func costGroupKey(groupBy string, c *model.McpInstanceDailyCost) (string, string) {
+	switch {
+	case groupBy == CostGroupByEnvironment:
+		return strconv.FormatUint(uint64(c.EnvironmentID), 10), ""
+	case strings.HasPrefix(groupBy, CostGroupByLabelPrefix):
+		value := c.GetLabels()[strings.TrimPrefix(groupBy, CostGroupByLabelPrefix)]
+		return value, value
+	default:
+		return c.InstanceID, c.InstanceName
+	}
+}
+
+// monthDays month 的日期范围 [from, to)
+func monthDays(month time.Time) (string, string) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	return first.Format(model.CostDayLayout), first.AddDate(0, 1, 0).Format(model.CostDayLayout)
+}
+
+// startOfDay 当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
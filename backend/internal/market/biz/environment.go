@@ -41,7 +41,12 @@ func (biz *EnvironmentBiz) CreateEnvironment(ctx context.Context, environment *m
 
 // UpdateEnvironment 更新环境
 func (biz *EnvironmentBiz) UpdateEnvironment(ctx context.Context, environment *model.McpEnvironment) error {
-	return biz.repo.Update(ctx, environment)
+	if err := biz.repo.Update(ctx, environment); err != nil {
+		return err
+	}
+	GContainerBiz.InvalidateRuntimeEntry(environment.ID)
+	invalidateResourceUsage(environment.ID)
+	return nil
 }
 
 // DeleteEnvironment 删除环境
@@ -64,7 +69,12 @@ func (biz *EnvironmentBiz) DeleteEnvironment(ctx context.Context, id uint) error
 		return fmt.Errorf("cannot delete environment: %d instances are still associated with this environment", len(instances))
 	}
 
-	return biz.repo.Delete(ctx, id)
+	if err := biz.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	GContainerBiz.InvalidateRuntimeEntry(id)
+	invalidateResourceUsage(id)
+	return nil
 }
 
 // GetEnvironment 根据ID获取环境
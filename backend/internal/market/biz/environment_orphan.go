@@ -0,0 +1,185 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// OrphanKindContainer 孤儿资源类型：容器（Kubernetes Deployment）
+	OrphanKindContainer = "container"
+	// OrphanKindService 孤儿资源类型：服务（Kubernetes Service）
+	OrphanKindService = "service"
+)
+
+// OrphanResource 带有本服务管理标签，但 instance 标签对应的实例记录已不存在的集群资源
+type OrphanResource struct {
+	Kind       string
+	Name       string
+	InstanceID string
+	CreatedAt  time.Time
+}
+
+// Key 资源标识 "kind/name"
+func (r *OrphanResource) Key() string {
+	return r.Kind + "/" + r.Name
+}
+
+// Age 资源创建至今的时长
+func (r *OrphanResource) Age(now time.Time) time.Duration {
+	return now.Sub(r.CreatedAt)
+}
+
+// OrphanFailure 删除失败的孤儿资源
+type OrphanFailure struct {
+	Resource *OrphanResource
+	Err      error
+}
+
+// OrphanCleanup 孤儿资源清理结果
+type OrphanCleanup struct {
+	Deleted []*OrphanResource
+	// Skipped 创建时间不足最小时长的资源，可能属于正在创建的实例
+	Skipped []*OrphanResource
+	Failed  []OrphanFailure
+}
+
+// managedResourceLabels 本服务创建的托管资源都带有的标签
+func managedResourceLabels() map[string]string {
+	return map[string]string{"managed-by": common.SourceServerName}
+}
+
+// ListOrphans 列出环境集群中的孤儿资源，按类型和名称排序。
+// 实例记录在所有环境中查找，多个环境共用同一命名空间时不会误判其他环境的资源
+func (biz *EnvironmentBiz) ListOrphans(ctx context.Context, environmentID uint) ([]*OrphanResource, error) {
+	entry, err := GContainerBiz.GetRuntimeEntry(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := entry.GetContainerManager().List(ctx, managedResourceLabels())
+	if err != nil {
+		return nil, err
+	}
+	services, err := entry.GetServiceManager().List(ctx, managedResourceLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var candidates []*OrphanResource
+	add := func(kind, name string, labels map[string]string, createdAt string) {
+		// 没有 instance 标签的资源无法判断归属，不作处理
+		instanceID := labels["instance"]
+		if instanceID == "" {
+			return
+		}
+		created, err := time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			// 创建时间未知时按刚创建处理，避免被清理
+			created = now
+		}
+		candidates = append(candidates, &OrphanResource{Kind: kind, Name: name, InstanceID: instanceID, CreatedAt: created})
+	}
+	for _, c := range containers {
+		add(OrphanKindContainer, c.Name, c.Labels, c.CreatedAt)
+	}
+	for _, s := range services {
+		add(OrphanKindService, s.Name, s.Labels, s.CreatedAt)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var instanceIDs []string
+	for _, r := range candidates {
+		if !seen[r.InstanceID] {
+			seen[r.InstanceID] = true
+			instanceIDs = append(instanceIDs, r.InstanceID)
+		}
+	}
+	existing, err := mysql.McpInstanceRepo.FindExistingInstanceIDs(ctx, instanceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instances: %w", err)
+	}
+	live := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		live[id] = true
+	}
+
+	var orphans []*OrphanResource
+	for _, r := range candidates {
+		if !live[r.InstanceID] {
+			orphans = append(orphans, r)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans, nil
+}
+
+// CleanupOrphans 重新扫描并删除环境中的孤儿资源，创建不足 minAge 的资源跳过。
+// keys 不为空时只处理其中列出的 "kind/name" 资源，单个资源删除失败不影响其他资源
+func (biz *EnvironmentBiz) CleanupOrphans(ctx context.Context, environmentID uint, minAge time.Duration, keys []string) (*OrphanCleanup, error) {
+	orphans, err := biz.ListOrphans(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := GContainerBiz.GetRuntimeEntry(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		selected[key] = true
+	}
+
+	now := time.Now()
+	result := &OrphanCleanup{}
+	for _, r := range orphans {
+		if len(selected) > 0 && !selected[r.Key()] {
+			continue
+		}
+		if r.Age(now) < minAge {
+			result.Skipped = append(result.Skipped, r)
+			continue
+		}
+		if err := deleteOrphan(ctx, entry, r); err != nil {
+			logger.FromContext(ctx).Warn("Failed to delete orphaned resource",
+				zap.Uint("environmentId", environmentID), zap.String("resource", r.Key()), zap.Error(err))
+			result.Failed = append(result.Failed, OrphanFailure{Resource: r, Err: err})
+			continue
+		}
+		logger.FromContext(ctx).Info("Deleted orphaned resource",
+			zap.Uint("environmentId", environmentID), zap.String("resource", r.Key()), zap.String("instanceId", r.InstanceID))
+		result.Deleted = append(result.Deleted, r)
+	}
+	return result, nil
+}
+
+// deleteOrphan 删除单个孤儿资源，容器删除时一并删除配置文件 Secret
+func deleteOrphan(ctx context.Context, entry *container.Entry, r *OrphanResource) error {
+	switch r.Kind {
+	case OrphanKindContainer:
+		return entry.GetContainerManager().Delete(ctx, r.Name)
+	case OrphanKindService:
+		return entry.GetServiceManager().Delete(ctx, r.Name)
+	default:
+		return fmt.Errorf("unknown resource kind: %s", r.Kind)
+	}
+}
@@ -0,0 +1,138 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// EnvironmentQuotaUsage 环境托管实例配额使用量
+type EnvironmentQuotaUsage struct {
+	Instances int
+	Memory    resource.Quantity
+	CPU       resource.Quantity
+}
+
+// ContainerQuotaUsage 计算单个托管实例占用的配额
+// 资源为各容器声明的资源限制乘以副本数，未声明限制的容器不计入
+func ContainerQuotaUsage(options container.ContainerCreateOptions, replicas int32) EnvironmentQuotaUsage {
+	usage := EnvironmentQuotaUsage{Instances: 1}
+	limits := []map[string]string{options.ResourceLimits}
+	for _, sc := range options.Sidecars {
+		limits = append(limits, sc.ResourceLimits)
+	}
+	for _, l := range limits {
+		if limit, err := resource.ParseQuantity(l[string(corev1.ResourceMemory)]); err == nil {
+			usage.Memory.Add(limit)
+		}
+		if limit, err := resource.ParseQuantity(l[string(corev1.ResourceCPU)]); err == nil {
+			usage.CPU.Add(limit)
+		}
+	}
+	usage.Memory.Mul(int64(max(replicas, 1)))
+	usage.CPU.Mul(int64(max(replicas, 1)))
+	return usage
+}
+
+// add 累加另一份使用量
+func (u *EnvironmentQuotaUsage) add(other EnvironmentQuotaUsage) {
+	u.Instances += other.Instances
+	u.Memory.Add(other.Memory)
+	u.CPU.Add(other.CPU)
+}
+
+// QuotaUsage 统计环境中计入配额的托管实例，环境设置 QuotaExcludeInactive 时跳过禁用和已停止的实例
+func (biz *EnvironmentBiz) QuotaUsage(ctx context.Context, environment *model.McpEnvironment) (*EnvironmentQuotaUsage, error) {
+	instances, err := GInstanceBiz.GetInstancesByEnvironmentID(ctx, environment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &EnvironmentQuotaUsage{}
+	for _, instance := range instances {
+		if instance.AccessType != model.AccessTypeHosting {
+			continue
+		}
+		if environment.QuotaExcludeInactive && instance.IsStopped() {
+			continue
+		}
+		var options container.ContainerCreateOptions
+		if err := json.Unmarshal(instance.ContainerCreateOptions, &options); err != nil {
+			// 配置无法解析时只计入实例数量
+			logger.FromContext(ctx).Warn("Failed to parse container options for quota usage",
+				zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		}
+		usage.add(ContainerQuotaUsage(options, instance.DesiredReplicas()))
+	}
+	return usage, nil
+}
+
+// CheckQuota 校验环境在新增 request 后是否超出配额，超出时返回包含已使用量和上限的错误
+func (biz *EnvironmentBiz) CheckQuota(ctx context.Context, environment *model.McpEnvironment, request EnvironmentQuotaUsage) error {
+	if environment.MaxInstances <= 0 && environment.MaxTotalMemory == "" && environment.MaxTotalCPU == "" {
+		return nil
+	}
+	usage, err := biz.QuotaUsage(ctx, environment)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeEnvironmentQuotaFailure)
+	}
+
+	if environment.MaxInstances > 0 && usage.Instances+request.Instances > environment.MaxInstances {
+		return common.NewError(i18n.CodeEnvironmentQuotaExceeded, environment.Name, "instances",
+			usage.Instances, request.Instances, environment.MaxInstances)
+	}
+	for _, q := range []struct {
+		name      string
+		limit     string
+		used, req resource.Quantity
+	}{
+		{"memory", environment.MaxTotalMemory, usage.Memory, request.Memory},
+		{"cpu", environment.MaxTotalCPU, usage.CPU, request.CPU},
+	} {
+		if q.limit == "" || q.req.Sign() <= 0 {
+			continue
+		}
+		limit, err := resource.ParseQuantity(q.limit)
+		if err != nil {
+			return common.WrapError(err, i18n.CodeEnvironmentQuotaFailure)
+		}
+		total := q.used.DeepCopy()
+		total.Add(q.req)
+		if total.Cmp(limit) > 0 {
+			return common.NewError(i18n.CodeEnvironmentQuotaExceeded, environment.Name, q.name,
+				q.used.String(), q.req.String(), q.limit)
+		}
+	}
+	return nil
+}
+
+// CheckInstanceQuota 校验已有托管实例以 replicas 个副本启动或扩缩容后环境是否超出配额
+// 已计入配额的实例只校验新增副本的资源，不计入配额的已停止实例按新实例校验
+func (biz *EnvironmentBiz) CheckInstanceQuota(ctx context.Context, instance *model.McpInstance, replicas int32) error {
+	environment, err := biz.GetEnvironment(ctx, instance.EnvironmentID)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeEnvironmentQuotaFailure)
+	}
+	var options container.ContainerCreateOptions
+	if err := json.Unmarshal(instance.ContainerCreateOptions, &options); err != nil {
+		return common.WrapError(err, i18n.CodeEnvironmentQuotaFailure)
+	}
+
+	request := ContainerQuotaUsage(options, replicas)
+	if !environment.QuotaExcludeInactive || !instance.IsStopped() {
+		counted := ContainerQuotaUsage(options, instance.DesiredReplicas())
+		request.Instances = 0
+		request.Memory.Sub(counted.Memory)
+		request.CPU.Sub(counted.CPU)
+	}
+	return biz.CheckQuota(ctx, environment, request)
+}
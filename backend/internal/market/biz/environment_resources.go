@@ -0,0 +1,173 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/database/repository/mysql"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourceUsageTTL 环境资源使用量缓存时长，指标查询需要列出命名空间中的所有托管 Pod
+const resourceUsageTTL = 30 * time.Second
+
+// InstanceResourceUsage 托管实例所有 Pod 的资源使用量、请求量和限制量之和
+type InstanceResourceUsage struct {
+	InstanceID   string
+	InstanceName string
+	Pods         int
+
+	CPUUsage       resource.Quantity
+	MemoryUsage    resource.Quantity
+	CPURequests    resource.Quantity
+	MemoryRequests resource.Quantity
+	CPULimits      resource.Quantity
+	MemoryLimits   resource.Quantity
+}
+
+// add 累加另一份使用量
+func (u *InstanceResourceUsage) add(other *InstanceResourceUsage) {
+	u.Pods += other.Pods
+	u.CPUUsage.Add(other.CPUUsage)
+	u.MemoryUsage.Add(other.MemoryUsage)
+	u.CPURequests.Add(other.CPURequests)
+	u.MemoryRequests.Add(other.MemoryRequests)
+	u.CPULimits.Add(other.CPULimits)
+	u.MemoryLimits.Add(other.MemoryLimits)
+}
+
+// EnvironmentResourceUsage 环境命名空间中托管实例的资源使用情况
+type EnvironmentResourceUsage struct {
+	Namespace   string
+	CollectedAt time.Time
+	// Instances 按 CPU 使用量从高到低排序
+	Instances []*InstanceResourceUsage
+	Total     InstanceResourceUsage
+}
+
+// resourceUsageCache 按环境 ID 缓存资源使用量
+var resourceUsageCache = struct {
+	mu      sync.Mutex
+	entries map[uint]*EnvironmentResourceUsage
+}{entries: make(map[uint]*EnvironmentResourceUsage)}
+
+// ResourceUsage 查询环境命名空间中托管 Pod 的当前资源使用量，并按实例汇总请求量和限制量。
+// 结果缓存 resourceUsageTTL，集群未安装 metrics-server 时返回包装了 k8s.ErrMetricsUnavailable 的错误
+func (biz *EnvironmentBiz) ResourceUsage(ctx context.Context, environmentID uint) (*EnvironmentResourceUsage, error) {
+	resourceUsageCache.mu.Lock()
+	cached, ok := resourceUsageCache.entries[environmentID]
+	resourceUsageCache.mu.Unlock()
+	if ok && time.Since(cached.CollectedAt) < resourceUsageTTL {
+		return cached, nil
+	}
+
+	usage, err := biz.collectResourceUsage(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	resourceUsageCache.mu.Lock()
+	resourceUsageCache.entries[environmentID] = usage
+	resourceUsageCache.mu.Unlock()
+	return usage, nil
+}
+
+// collectResourceUsage 从指标 API 和 Pod 规格汇总资源使用量
+func (biz *EnvironmentBiz) collectResourceUsage(ctx context.Context, environmentID uint) (*EnvironmentResourceUsage, error) {
+	entry, err := GContainerBiz.GetRuntimeEntry(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	runtime := entry.GetK8sRuntime()
+	if runtime == nil {
+		return nil, fmt.Errorf("environment %d is not a Kubernetes environment", environmentID)
+	}
+	client := runtime.Entry.Client
+
+	metrics, err := client.Metrics().ListPodUsage(ctx, managedResourceLabels())
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.Pod().List(managedResourceLabels())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	byInstance := make(map[string]*InstanceResourceUsage)
+	instanceUsage := func(instanceID string) *InstanceResourceUsage {
+		u, ok := byInstance[instanceID]
+		if !ok {
+			u = &InstanceResourceUsage{InstanceID: instanceID}
+			byInstance[instanceID] = u
+		}
+		return u
+	}
+	for _, pod := range pods {
+		instanceID := pod.Labels["instance"]
+		// 已结束的 Pod 不再占用资源
+		if instanceID == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		u := instanceUsage(instanceID)
+		u.Pods++
+		for _, c := range pod.Spec.Containers {
+			u.CPURequests.Add(c.Resources.Requests[corev1.ResourceCPU])
+			u.MemoryRequests.Add(c.Resources.Requests[corev1.ResourceMemory])
+			u.CPULimits.Add(c.Resources.Limits[corev1.ResourceCPU])
+			u.MemoryLimits.Add(c.Resources.Limits[corev1.ResourceMemory])
+		}
+	}
+	for _, m := range metrics {
+		instanceID := m.Labels["instance"]
+		if instanceID == "" {
+			continue
+		}
+		u := instanceUsage(instanceID)
+		u.CPUUsage.Add(m.CPU)
+		u.MemoryUsage.Add(m.Memory)
+	}
+
+	ids := make([]string, 0, len(byInstance))
+	for id := range byInstance {
+		ids = append(ids, id)
+	}
+	instances, err := mysql.McpInstanceRepo.FindByInstanceIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instances: %w", err)
+	}
+	for _, instance := range instances {
+		byInstance[instance.InstanceID].InstanceName = instance.InstanceName
+	}
+
+	result := &EnvironmentResourceUsage{
+		Namespace:   client.GetNamespace(),
+		CollectedAt: time.Now(),
+		Instances:   make([]*InstanceResourceUsage, 0, len(byInstance)),
+	}
+	for _, u := range byInstance {
+		result.Instances = append(result.Instances, u)
+		result.Total.add(u)
+	}
+	sort.Slice(result.Instances, func(i, j int) bool {
+		a, b := result.Instances[i], result.Instances[j]
+		if c := a.CPUUsage.Cmp(b.CPUUsage); c != 0 {
+			return c > 0
+		}
+		if c := a.MemoryUsage.Cmp(b.MemoryUsage); c != 0 {
+			return c > 0
+		}
+		return a.InstanceID < b.InstanceID
+	})
+	return result, nil
+}
+
+// invalidateResourceUsage 清除环境资源使用量缓存，环境更新或删除后调用
+func invalidateResourceUsage(environmentID uint) {
+	resourceUsageCache.mu.Lock()
+	delete(resourceUsageCache.entries, environmentID)
+	resourceUsageCache.mu.Unlock()
+}
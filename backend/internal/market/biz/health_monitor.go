@@ -0,0 +1,231 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+
+	"go.uber.org/zap"
+)
+
+// 健康状态变化通知的事件类型
+const (
+	HealthEventDown = "instance.health.down"
+	HealthEventUp   = "instance.health.up"
+)
+
+// maxHealthCheckError 检查结果中保存的失败原因最大长度，与表字段长度一致
+const maxHealthCheckError = 500
+
+// HealthWebhookEvent 健康状态变化时 POST 给 webhook 的内容
+type HealthWebhookEvent struct {
+	Event          string `json:"event"`
+	InstanceID     string `json:"instanceId"`
+	InstanceName   string `json:"instanceName"`
+	PreviousStatus string `json:"previousStatus"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	CheckedAt      string `json:"checkedAt"`
+}
+
+// HealthMonitorBiz 直连和代理实例的定时健康检查
+type HealthMonitorBiz struct {
+	ctx context.Context
+}
+
+// GHealthMonitorBiz 全局健康检查数据处理层实例
+var GHealthMonitorBiz *HealthMonitorBiz
+
+func init() {
+	GHealthMonitorBiz = NewHealthMonitorBiz(context.Background())
+}
+
+// NewHealthMonitorBiz 创建健康检查数据处理层实例
+func NewHealthMonitorBiz(ctx context.Context) *HealthMonitorBiz {
+	return &HealthMonitorBiz{
+		ctx: ctx,
+	}
+}
+
+// RunDue 检查所有到达检查间隔的实例，单个实例检查失败只记录日志
+func (biz *HealthMonitorBiz) RunDue(ctx context.Context) error {
+	instances, err := mysql.McpInstanceRepo.FindHealthMonitored(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find health monitored instances: %w", err)
+	}
+
+	now := time.Now()
+	semaphore := make(chan struct{}, config.GlobalConfig.HealthMonitor.Concurrency)
+	var wg sync.WaitGroup
+	for _, instance := range instances {
+		monitor := instance.GetHealthMonitor()
+		if monitor == nil || !monitor.Enabled {
+			continue
+		}
+		if instance.HealthCheckedAt != nil && now.Before(instance.HealthCheckedAt.Add(monitor.IntervalDuration())) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			// 任务锁丢失时中止本批次剩余的检查
+			if ctx.Err() != nil {
+				return
+			}
+			if _, err := biz.Check(ctx, instance, monitor); err != nil {
+				logger.Warn("Failed to record instance health check", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Check 检查实例健康状态，保存检查结果，状态在 up 和 down 之间变化时记录时间线并通知 webhook
+func (biz *HealthMonitorBiz) Check(ctx context.Context, instance *model.McpInstance, monitor *model.HealthMonitorConfig) (*model.McpInstanceHealthCheck, error) {
+	check := biz.probe(ctx, instance, monitor)
+	if err := mysql.McpInstanceHealthCheckRepo.Create(ctx, check, config.GlobalConfig.HealthMonitor.HistorySize); err != nil {
+		return nil, err
+	}
+
+	status := model.HealthStatusUp
+	if !check.Success {
+		status = model.HealthStatusDown
+	}
+	if err := mysql.McpInstanceRepo.UpdateHealthStatus(ctx, instance.InstanceID, status, check.CheckedAt); err != nil {
+		return nil, err
+	}
+
+	previous := instance.HealthStatus
+	instance.HealthStatus = status
+	instance.HealthCheckedAt = &check.CheckedAt
+	// 首次检查没有之前的状态，不视为状态变化
+	if previous != "" && previous != status {
+		biz.notifyTransition(ctx, instance, previous, check)
+	}
+	return check, nil
+}
+
+// History 查询实例最近的 limit 条检查结果，从最新开始
+func (biz *HealthMonitorBiz) History(ctx context.Context, instanceID string, limit int) ([]*model.McpInstanceHealthCheck, error) {
+	return mysql.McpInstanceHealthCheckRepo.FindByInstanceID(ctx, instanceID, limit)
+}
+
+// probe 依次检查实例的每个 MCP 服务，全部成功才视为正常，耗时取最大值
+func (biz *HealthMonitorBiz) probe(ctx context.Context, instance *model.McpInstance, monitor *model.HealthMonitorConfig) *model.McpInstanceHealthCheck {
+	check := &model.McpInstanceHealthCheck{
+		InstanceID: instance.InstanceID,
+		Success:    true,
+		CheckedAt:  time.Now(),
+	}
+
+	_, servers, _, err := instance.GetTargetConfig()
+	names := servers.ServerNames()
+	if err == nil && len(names) == 0 {
+		err = fmt.Errorf("no mcp servers found in config")
+	}
+	if err != nil {
+		check.Success = false
+		check.Error = err.Error()
+		return check
+	}
+
+	timeout := time.Duration(config.GlobalConfig.HealthMonitor.CheckTimeout) * time.Second
+	var failures []string
+	for _, name := range names {
+		result := probeServer(ctx, instance.McpProtocol, servers.McpServers[name], monitor, timeout)
+		if result.Latency.Milliseconds() > check.LatencyMs {
+			check.LatencyMs = result.Latency.Milliseconds()
+		}
+		if result.StatusCode != 0 && (check.StatusCode == 0 || !result.Success) {
+			check.StatusCode = int32(result.StatusCode)
+		}
+		if !result.Success {
+			check.Success = false
+			if len(names) > 1 {
+				failures = append(failures, name+": "+result.Error)
+			} else {
+				failures = append(failures, result.Error)
+			}
+		}
+	}
+	check.Error = strings.Join(failures, "; ")
+	if len(check.Error) > maxHealthCheckError {
+		check.Error = check.Error[:maxHealthCheckError]
+	}
+	return check
+}
+
+// probeServer 按检查方式检查单个 MCP 服务，端口探测没有状态码
+func probeServer(ctx context.Context, protocol model.McpProtocol, server *model.McpConfig, monitor *model.HealthMonitorConfig, timeout time.Duration) *utils.HTTPProbeResult {
+	if server == nil || server.URL == "" {
+		return &utils.HTTPProbeResult{Error: "server url is empty"}
+	}
+	switch monitor.Mode {
+	case model.HealthCheckModeHTTP:
+		target := server.URL
+		if monitor.Path != "" {
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				return &utils.HTTPProbeResult{Error: fmt.Sprintf("failed to parse URL: %v", err)}
+			}
+			u.Path = monitor.Path
+			u.RawQuery = ""
+			target = u.String()
+		}
+		return utils.ProbeHTTP(ctx, utils.HTTPProbeOptions{URL: target, Timeout: timeout, Method: "GET"}, int(monitor.ExpectedStatus))
+	case model.HealthCheckModeInitialize:
+		return utils.ProbeMCPInitialize(ctx, utils.MCPProbeOptions{
+			URL:      server.URL,
+			Protocol: protocol.String(),
+			Headers:  server.Headers,
+			Timeout:  timeout,
+		})
+	default:
+		result := utils.ProbePortFromURL(ctx, server.URL, timeout)
+		return &utils.HTTPProbeResult{Success: result.Success, Error: result.Error, Latency: result.Latency}
+	}
+}
+
+// notifyTransition 记录健康状态变化到实例时间线，并异步通知所有 webhook，通知失败只记录日志
+func (biz *HealthMonitorBiz) notifyTransition(ctx context.Context, instance *model.McpInstance, previous string, check *model.McpInstanceHealthCheck) {
+	event := &HealthWebhookEvent{
+		Event:          HealthEventUp,
+		InstanceID:     instance.InstanceID,
+		InstanceName:   instance.InstanceName,
+		PreviousStatus: previous,
+		Status:         instance.HealthStatus,
+		Error:          check.Error,
+		CheckedAt:      check.CheckedAt.UTC().Format(time.RFC3339),
+	}
+	operation := model.InstanceOperationHealthUp
+	if instance.HealthStatus == model.HealthStatusDown {
+		event.Event = HealthEventDown
+		operation = model.InstanceOperationHealthDown
+	}
+	GInstanceOperationBiz.Record(ctx, instance.InstanceID, operation, check.Error)
+
+	cfg := config.GlobalConfig.HealthMonitor
+	timeout := time.Duration(cfg.WebhookTimeout) * time.Second
+	// 通知不受任务锁上下文取消的影响
+	notifyCtx := context.WithoutCancel(ctx)
+	for _, webhook := range cfg.Webhooks {
+		go func() {
+			if err := utils.PostJSON(notifyCtx, webhook, event, timeout); err != nil {
+				logger.Warn("Failed to send instance health webhook",
+					zap.String("instanceId", instance.InstanceID), zap.String("event", event.Event), zap.String("webhook", webhook), zap.Error(err))
+			}
+		}()
+	}
+}
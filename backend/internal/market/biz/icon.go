@@ -0,0 +1,142 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/codepackage"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/icon"
+)
+
+// iconCleanupBatchSize 清理任务每批查询的图标数
+const iconCleanupBatchSize = 100
+
+// IconBiz 上传图标数据处理层
+type IconBiz struct {
+	ctx context.Context
+
+	mu      sync.RWMutex
+	storage codepackage.Storage
+}
+
+// GIconBiz 全局图标数据处理层实例
+var GIconBiz *IconBiz
+
+func init() {
+	GIconBiz = NewIconBiz(context.Background())
+}
+
+// NewIconBiz 创建图标数据处理层实例
+func NewIconBiz(ctx context.Context) *IconBiz {
+	return &IconBiz{
+		ctx: ctx,
+	}
+}
+
+// SetStorage 设置图标存储后端，与代码包使用相同的存储类型
+func (biz *IconBiz) SetStorage(storage codepackage.Storage) {
+	biz.mu.Lock()
+	defer biz.mu.Unlock()
+	biz.storage = storage
+}
+
+// getStorage 获取图标存储后端
+func (biz *IconBiz) getStorage() (codepackage.Storage, error) {
+	biz.mu.RLock()
+	defer biz.mu.RUnlock()
+	if biz.storage == nil {
+		return nil, fmt.Errorf("icon storage is not initialized")
+	}
+	return biz.storage, nil
+}
+
+// limits 根据配置获取图标限制
+func (biz *IconBiz) limits() icon.Limits {
+	return icon.Limits{
+		MaxFileSize:  config.GlobalConfig.Icon.MaxFileSize << 10,
+		MinDimension: config.GlobalConfig.Icon.MinDimension,
+		MaxDimension: config.GlobalConfig.Icon.MaxDimension,
+	}
+}
+
+// MaxFileSize 图标文件大小上限（字节）
+func (biz *IconBiz) MaxFileSize() int64 {
+	return int64(config.GlobalConfig.Icon.MaxFileSize) << 10
+}
+
+// Upload 校验图标并按内容哈希保存，相同内容重复上传时返回同一个文件名
+// 校验失败时返回的错误包装 icon.ErrInvalidIcon
+func (biz *IconBiz) Upload(ctx context.Context, data []byte) (*icon.Icon, error) {
+	processed, err := icon.Process(data, biz.limits())
+	if err != nil {
+		return nil, err
+	}
+	storage, err := biz.getStorage()
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Put(ctx, processed.Key(), bytes.NewReader(processed.Data), int64(len(processed.Data))); err != nil {
+		return nil, fmt.Errorf("failed to store icon: %w", err)
+	}
+	record := &model.McpIcon{
+		Name:        processed.Name,
+		ContentType: processed.ContentType,
+		Size:        int64(len(processed.Data)),
+		Width:       int32(processed.Width),
+		Height:      int32(processed.Height),
+	}
+	if err := mysql.McpIconRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save icon record: %w", err)
+	}
+	return processed, nil
+}
+
+// Open 打开图标文件，不存在时返回的错误包装 codepackage.ErrObjectNotFound
+func (biz *IconBiz) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	storage, err := biz.getStorage()
+	if err != nil {
+		return nil, err
+	}
+	return storage.Get(ctx, icon.Key(name))
+}
+
+// Cleanup 删除超过保留时间且未被实例、模板或目录条目引用的图标，返回删除的数量
+func (biz *IconBiz) Cleanup(ctx context.Context) (int, error) {
+	storage, err := biz.getStorage()
+	if err != nil {
+		return 0, err
+	}
+	before := time.Now().Add(-time.Duration(config.GlobalConfig.Icon.CleanupGracePeriod) * time.Hour)
+
+	deleted := 0
+	for {
+		icons, err := mysql.McpIconRepo.FindUnreferenced(ctx, before, iconCleanupBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for _, record := range icons {
+			// 先删除记录，期间重新上传的图标创建时间已重置，不会被删除
+			ok, err := mysql.McpIconRepo.DeleteCreatedBefore(ctx, record.Name, before)
+			if err != nil {
+				return deleted, err
+			}
+			if !ok {
+				continue
+			}
+			if err := storage.Delete(ctx, icon.Key(record.Name)); err != nil {
+				return deleted, fmt.Errorf("failed to delete icon %s: %w", record.Name, err)
+			}
+			deleted++
+		}
+		if len(icons) < iconCleanupBatchSize || ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/registry"
+
+	"go.uber.org/zap"
+)
+
+// ImagePullSecrets 返回配置的镜像拉取密钥名称列表
+func (cd *ContainerBiz) ImagePullSecrets() []string {
+	return config.GlobalConfig.Image.PullSecrets
+}
+
+// IsDefaultHostingImage 判断镜像是否为环境使用的默认托管镜像（全局配置或环境覆盖）
+func (cd *ContainerBiz) IsDefaultHostingImage(ctx context.Context, environmentID uint, image string) bool {
+	if common.IsDefaultHostingImage(image) {
+		return true
+	}
+	environment, err := GEnvironmentBiz.GetEnvironment(ctx, environmentID)
+	if err != nil {
+		return false
+	}
+	return common.IsDefaultHostingImage(image, environment.HostingImage)
+}
+
+// CheckImageAvailable 创建实例前查询镜像仓库，确认镜像及标签存在，返回标签当前指向的镜像摘要，仓库未返回摘要时为空
+// 配置 image.skipRegistryCheck 时跳过（离线环境无法访问镜像仓库），不解析摘要
+func (cd *ContainerBiz) CheckImageAvailable(ctx context.Context, environmentID uint, image string) (string, error) {
+	imageCfg := config.GlobalConfig.Image
+	if imageCfg.SkipRegistryCheck {
+		return "", nil
+	}
+
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", common.NewError(i18n.CodeImageNotFound, image)
+	}
+
+	cred := cd.registryCredential(ctx, environmentID, ref.Registry)
+	checker := registry.NewChecker(time.Duration(imageCfg.CheckTimeout)*time.Second, imageCfg.InsecureRegistries)
+	digest, err := checker.ResolveDigest(ctx, ref, cred)
+	switch {
+	case err == nil:
+		return digest, nil
+	case errors.Is(err, registry.ErrManifestNotFound):
+		return "", common.NewError(i18n.CodeImageNotFound, image)
+	case errors.Is(err, registry.ErrUnauthorized):
+		return "", common.NewError(i18n.CodeImageAccessDenied, image)
+	default:
+		return "", common.NewError(i18n.CodeImageRegistryUnreachable, ref.Registry, err)
+	}
+}
+
+// StampImageDigest 容器即将创建时，记录镜像标签当前指向的摘要到实例，由调用方保存实例。
+// 无法解析时清空摘要，摘要与之前记录的不同时记录到实例时间线
+func (cd *ContainerBiz) StampImageDigest(ctx context.Context, instance *model.McpInstance) {
+	image := instanceImage(instance)
+	if image == "" {
+		return
+	}
+	digest, err := cd.CheckImageAvailable(ctx, instance.EnvironmentID, image)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to resolve image digest",
+			zap.String("instanceId", instance.InstanceID), zap.String("image", image), zap.Error(err))
+	}
+	if instance.ImageDigest != "" && digest != "" && digest != instance.ImageDigest {
+		GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationImageChanged,
+			fmt.Sprintf("%s: %s -> %s", image, instance.ImageDigest, digest))
+	}
+	instance.ImageDigest = digest
+}
+
+// ImageDigestDrift 检查实例镜像标签当前指向的摘要是否与容器创建时记录的不同，不同时返回差异。
+// 未记录摘要、镜像按摘要引用或无法查询镜像仓库时不视为漂移
+func (cd *ContainerBiz) ImageDigestDrift(ctx context.Context, instance *model.McpInstance) *container.DriftItem {
+	image := instanceImage(instance)
+	if image == "" || instance.ImageDigest == "" || strings.Contains(image, "@sha256:") {
+		return nil
+	}
+	digest, err := cd.CheckImageAvailable(ctx, instance.EnvironmentID, image)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to resolve image digest, skip the check",
+			zap.String("instanceId", instance.InstanceID), zap.String("image", image), zap.Error(err))
+		return nil
+	}
+	if digest == "" || digest == instance.ImageDigest {
+		return nil
+	}
+	return &container.DriftItem{
+		Field:    container.DriftFieldImageDigest,
+		Kind:     container.DriftChanged,
+		Name:     image,
+		Expected: instance.ImageDigest,
+		Actual:   digest,
+	}
+}
+
+// instanceImage 托管实例容器使用的镜像，来自容器创建选项
+func instanceImage(instance *model.McpInstance) string {
+	var options container.ContainerCreateOptions
+	if len(instance.ContainerCreateOptions) == 0 || json.Unmarshal(instance.ContainerCreateOptions, &options) != nil {
+		return ""
+	}
+	return options.ImageName
+}
+
+// registryCredential 查找仓库凭证，优先使用已保存的仓库凭证，其次是环境命名空间下配置的镜像拉取密钥，找不到时匿名访问
+func (cd *ContainerBiz) registryCredential(ctx context.Context, environmentID uint, registryHost string) *registry.Credential {
+	if _, cred, err := GRegistryCredentialBiz.CredentialForRegistry(ctx, registryHost); err != nil {
+		logger.FromContext(ctx).Warn("Failed to load registry credential",
+			zap.String("registry", registryHost),
+			zap.Error(err),
+		)
+	} else if cred != nil {
+		return cred
+	}
+
+	pullSecrets := config.GlobalConfig.Image.PullSecrets
+	if len(pullSecrets) == 0 {
+		return nil
+	}
+	entry, err := cd.GetRuntimeEntry(ctx, environmentID)
+	if err != nil || entry == nil || entry.GetK8sRuntime() == nil {
+		return nil
+	}
+	secrets := entry.GetK8sRuntime().Entry.Secret
+	for _, name := range pullSecrets {
+		data, err := secrets.GetDockerConfigJSON(name)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to read image pull secret",
+				zap.String("secret", name),
+				zap.Error(err),
+			)
+			continue
+		}
+		if cred, ok := registry.CredentialFromDockerConfigJSON(data, registryHost); ok {
+			return cred
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/registry"
+
+	"go.uber.org/zap"
+)
+
+// 镜像漏洞扫描状态
+const (
+	ImageScanStatusScanning  = "scanning"
+	ImageScanStatusCompleted = "completed"
+	ImageScanStatusFailed    = "failed"
+)
+
+// maxImageScanError 扫描结果中保存的失败原因最大长度
+const maxImageScanError = 500
+
+// ImageScanResult 实例上保存的最近一次镜像漏洞扫描结果
+type ImageScanResult struct {
+	Status    string                `json:"status"`
+	Error     string                `json:"error,omitempty"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+	Report    *container.ScanReport `json:"report,omitempty"`
+}
+
+// ImageScanBlockedError 镜像严重漏洞数超过环境允许的上限
+type ImageScanBlockedError struct {
+	Report      *container.ScanReport
+	MaxCritical int
+}
+
+func (e *ImageScanBlockedError) Error() string {
+	return fmt.Sprintf("image %s has %d critical vulnerabilities, at most %d allowed", e.Report.Image, e.Report.Critical, e.MaxCritical)
+}
+
+// scanningInstances 本副本正在扫描的实例，避免重复触发同一实例的扫描
+var scanningInstances sync.Map
+
+// imageScanner 按配置创建扫描器，未配置 trivy 服务时返回 nil
+func imageScanner(image string) container.ImageScanner {
+	cfg := config.GlobalConfig.ImageScan
+	if cfg.TrivyServer == "" {
+		return nil
+	}
+	scanner := &container.TrivyScanner{Binary: cfg.TrivyPath, ServerURL: cfg.TrivyServer, Token: cfg.TrivyToken}
+	if ref, err := registry.ParseReference(image); err == nil {
+		scanner.Insecure = slices.Contains(config.GlobalConfig.Image.InsecureRegistries, ref.Registry)
+	}
+	return scanner
+}
+
+// ImageScanEnabled 是否配置了镜像漏洞扫描
+func (cd *ContainerBiz) ImageScanEnabled() bool {
+	return config.GlobalConfig.ImageScan.TrivyServer != ""
+}
+
+// ScanImage 同步扫描镜像，使用镜像所在仓库的凭证拉取镜像
+func (cd *ContainerBiz) ScanImage(ctx context.Context, environmentID uint, image string) (*container.ScanReport, error) {
+	scanner := imageScanner(image)
+	if scanner == nil {
+		return nil, common.NewError(i18n.CodeImageScanNotConfigured)
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return nil, common.NewError(i18n.CodeImageNotFound, image)
+	}
+	cred := cd.registryCredential(ctx, environmentID, ref.Registry)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.GlobalConfig.ImageScan.Timeout)*time.Second)
+	defer cancel()
+	report, err := scanner.Scan(ctx, image, cred)
+	if err != nil {
+		return nil, common.WrapError(err, i18n.CodeImageScanFailure, image)
+	}
+	return report, nil
+}
+
+// CheckImageVulnerabilities 环境开启严重漏洞拦截时，创建托管实例前同步扫描镜像。
+// 严重漏洞数超过上限时返回 *ImageScanBlockedError；未开启拦截时不扫描，返回 nil 报告
+func (cd *ContainerBiz) CheckImageVulnerabilities(ctx context.Context, environment *model.McpEnvironment, image string) (*container.ScanReport, error) {
+	if !environment.BlockCriticalVulnerabilities {
+		return nil, nil
+	}
+	report, err := cd.ScanImage(ctx, environment.ID, image)
+	if err != nil {
+		return nil, err
+	}
+	if report.Critical > environment.MaxCriticalVulnerabilities {
+		return report, &ImageScanBlockedError{Report: report, MaxCritical: environment.MaxCriticalVulnerabilities}
+	}
+	return report, nil
+}
+
+// CompletedImageScan 将创建前的扫描报告转换为实例保存的扫描结果
+func CompletedImageScan(report *container.ScanReport) json.RawMessage {
+	data, _ := json.Marshal(&ImageScanResult{Status: ImageScanStatusCompleted, UpdatedAt: report.ScannedAt, Report: report})
+	return data
+}
+
+// GetImageScan 解析实例保存的扫描结果，未扫描时返回 nil
+func (cd *ContainerBiz) GetImageScan(instance *model.McpInstance) *ImageScanResult {
+	if len(instance.ImageScan) == 0 || string(instance.ImageScan) == "null" {
+		return nil
+	}
+	var result ImageScanResult
+	if err := json.Unmarshal(instance.ImageScan, &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// ScanInstanceImage 异步扫描托管实例的镜像，立即保存扫描中状态并返回，扫描结束后保存报告或失败原因。
+// 实例正在扫描时不重复扫描，返回当前状态
+func (cd *ContainerBiz) ScanInstanceImage(ctx context.Context, instance *model.McpInstance) (*ImageScanResult, error) {
+	if !cd.ImageScanEnabled() {
+		return nil, common.NewError(i18n.CodeImageScanNotConfigured)
+	}
+	var options container.ContainerCreateOptions
+	if err := json.Unmarshal(instance.ContainerCreateOptions, &options); err != nil {
+		return nil, fmt.Errorf("解析容器创建参数失败: %w", err)
+	}
+
+	result := &ImageScanResult{Status: ImageScanStatusScanning, UpdatedAt: time.Now()}
+	if _, scanning := scanningInstances.LoadOrStore(instance.InstanceID, struct{}{}); scanning {
+		if current := cd.GetImageScan(instance); current != nil {
+			return current, nil
+		}
+		return result, nil
+	}
+	if err := cd.saveImageScan(ctx, instance.InstanceID, result); err != nil {
+		scanningInstances.Delete(instance.InstanceID)
+		return nil, err
+	}
+
+	// 扫描不受请求上下文取消的影响
+	scanCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer scanningInstances.Delete(instance.InstanceID)
+		done := &ImageScanResult{Status: ImageScanStatusCompleted}
+		report, err := cd.ScanImage(scanCtx, instance.EnvironmentID, options.ImageName)
+		if err != nil {
+			done.Status = ImageScanStatusFailed
+			done.Error = truncateScanError(err.Error())
+			logger.Warn("Failed to scan instance image",
+				zap.String("instanceId", instance.InstanceID), zap.String("image", options.ImageName), zap.Error(err))
+		}
+		done.Report = report
+		done.UpdatedAt = time.Now()
+		if err := cd.saveImageScan(scanCtx, instance.InstanceID, done); err != nil {
+			logger.Warn("Failed to save image scan result", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		}
+	}()
+	return result, nil
+}
+
+// saveImageScan 保存实例的扫描结果
+func (cd *ContainerBiz) saveImageScan(ctx context.Context, instanceID string, result *ImageScanResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return mysql.McpInstanceRepo.UpdateImageScan(ctx, instanceID, data)
+}
+
+// truncateScanError 截断过长的失败原因
+func truncateScanError(msg string) string {
+	if len(msg) <= maxImageScanError {
+		return msg
+	}
+	return msg[:maxImageScanError]
+}
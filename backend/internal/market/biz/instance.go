@@ -4,15 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
-	"qm-mcp-server/internal/market/config"
 	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
 	"qm-mcp-server/pkg/database/model"
 	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/redis"
 	"qm-mcp-server/pkg/utils"
-	"strings"
+	"sort"
+	"sync"
+	"time"
 
 	instancepb "qm-mcp-server/api/market/instance"
+
+	"go.uber.org/zap"
 )
 
 // InstanceBiz 实例数据处理层
@@ -67,11 +72,25 @@ func (biz *InstanceBiz) DeleteInstance(instanceID string) error {
 	if err != nil {
 		return err
 	}
-	return mysql.McpInstanceRepo.Delete(biz.ctx, instanceID)
+	if err := mysql.McpInstanceRepo.Delete(biz.ctx, instanceID); err != nil {
+		return err
+	}
+	// 健康检查历史和容器事件随实例删除，失败只记录日志
+	if err := mysql.McpInstanceHealthCheckRepo.DeleteByInstanceID(biz.ctx, instanceID); err != nil {
+		logger.Warn("Failed to delete instance health checks", zap.String("instanceId", instanceID), zap.Error(err))
+	}
+	if err := mysql.McpInstanceEventRepo.DeleteByInstanceID(biz.ctx, instanceID); err != nil {
+		logger.Warn("Failed to delete instance events", zap.String("instanceId", instanceID), zap.Error(err))
+	}
+	// 旧路径不再重定向到已删除的实例
+	if err := mysql.McpInstanceSlugAliasRepo.DeleteByInstanceID(biz.ctx, instanceID); err != nil {
+		logger.Warn("Failed to delete instance slug aliases", zap.String("instanceId", instanceID), zap.Error(err))
+	}
+	return nil
 }
 
 // ListInstance 获取实例列表
-func (biz *InstanceBiz) ListInstance(page, pageSize int32, filters map[string]interface{}, sortBy, sortOrder string) (*instancepb.ListResp, error) {
+func (biz *InstanceBiz) ListInstance(ctx context.Context, page, pageSize int32, filters map[string]interface{}, sortBy, sortOrder string) (*instancepb.ListResp, error) {
 	// 查询数据
 	instances, total, err := mysql.McpInstanceRepo.FindWithPagination(biz.ctx, page, pageSize, filters, sortBy, sortOrder)
 	if err != nil {
@@ -88,13 +107,20 @@ func (biz *InstanceBiz) ListInstance(page, pageSize int32, filters map[string]in
 		return nil, fmt.Errorf("查询环境名称失败: %v", err)
 	}
 
+	// 代码包版本查询失败不影响列表返回
+	outdated, err := GCodePackageBiz.OutdatedInstanceIDs(ctx, instances)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to check outdated code packages", zap.Error(err))
+	}
+
 	// 转换为proto响应
 	instanceInfos := make([]*instancepb.ListResp_InstanceInfo, 0, len(instances))
 	for _, instance := range instances {
-		instanceInfo := common.ConvertToInstanceInfo(instance)
+		instanceInfo := common.ConvertToInstanceInfo(ctx, instance)
 		if envName, ok := envNames[fmt.Sprintf("%d", instance.EnvironmentID)]; ok {
 			instanceInfo.EnvironmentName = envName
 		}
+		instanceInfo.CodeOutdated = outdated[instance.InstanceID]
 		instanceInfos = append(instanceInfos, instanceInfo)
 	}
 
@@ -112,11 +138,38 @@ func (biz *InstanceBiz) CreateInstance(instance *model.McpInstance) error {
 		return fmt.Errorf("instance name cannot be empty")
 	}
 	// 查询 name 是否存在
-	existingInstance, err := mysql.McpInstanceRepo.FindByName(biz.ctx, instance.InstanceName)
-	if err == nil && existingInstance != nil {
-		return fmt.Errorf("实例名称 %s 已存在", instance.InstanceName)
+	if err := biz.CheckInstanceName(biz.ctx, instance.InstanceName, ""); err != nil {
+		return err
+	}
+	if err := mysql.McpInstanceRepo.Create(biz.ctx, instance); err != nil {
+		// 并发创建同名实例时由唯一索引兜底
+		if mysql.IsDuplicateKeyError(err) {
+			return common.ErrInstanceNameConflict(instance.InstanceName)
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckInstanceName 校验实例名称未被其他实例占用，excludeInstanceID 为重命名的实例自身
+func (biz *InstanceBiz) CheckInstanceName(ctx context.Context, name, excludeInstanceID string) error {
+	if name == "" {
+		return nil
+	}
+	existingInstance, err := mysql.McpInstanceRepo.FindByName(ctx, name)
+	if err == nil && existingInstance != nil && existingInstance.InstanceID != excludeInstanceID {
+		return common.ErrInstanceNameConflict(name)
+	}
+	return nil
+}
+
+// SuggestInstanceName 返回下一个可用的 "name-N" 形式的实例名称
+func (biz *InstanceBiz) SuggestInstanceName(ctx context.Context, name string) (string, error) {
+	names, err := mysql.McpInstanceRepo.FindNamesByPrefix(ctx, common.NameSuggestionBase(name))
+	if err != nil {
+		return "", err
 	}
-	return mysql.McpInstanceRepo.Create(biz.ctx, instance)
+	return common.SuggestName(name, names), nil
 }
 
 // UpdateInstanceForDirect 更新实例
@@ -154,6 +207,9 @@ func (biz *InstanceBiz) UpdateInstanceForDirect(ctx context.Context, req *instan
 
 	// 保存到数据库
 	err = mysql.McpInstanceRepo.Update(ctx, oriInstance)
+	if mysql.IsDuplicateKeyError(err) {
+		return nil, common.ErrInstanceNameConflict(oriInstance.InstanceName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("更新实例失败: %v", err)
 	}
@@ -203,21 +259,32 @@ func (biz *InstanceBiz) UpdateInstanceForProxy(ctx context.Context, req *instanc
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate mcp servers: %w", err)
 	}
+	beforeTargetConfig := oriInstance.TargetConfig
 	if !utils.CompareMcpValidationResult(reqMcpResult, oriMcpResult) {
 		sourceConfig := json.RawMessage([]byte(req.McpServers))
 		oriInstance.SourceConfig = sourceConfig
 		oriInstance.TargetConfig = sourceConfig
-		// Create proxy configuration
-		publicProxyConfig := biz.CreatePublicProxyConfig(oriInstance.InstanceID, oriInstance.McpProtocol)
+		// Create proxy configuration, one entry per server
+		_, servers, _, err := oriInstance.GetSourceConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mcp servers: %w", err)
+		}
+		publicProxyConfig := biz.CreatePublicProxyConfig(oriInstance.InstanceID, oriInstance.McpProtocol, servers.ServerNames())
 		pb, e2 := common.MarshalAndAssignConfig(publicProxyConfig)
 		if e2 != nil {
 			return nil, fmt.Errorf("failed to marshal public proxy config: %w", e2)
 		}
 		oriInstance.PublicProxyConfig = pb
 	}
+	if oriInstance.TargetConfig, err = editRequestTimeouts(req, beforeTargetConfig, oriInstance.TargetConfig); err != nil {
+		return nil, fmt.Errorf("failed to apply request timeouts: %w", err)
+	}
 
 	// 保存到数据库
 	err = mysql.McpInstanceRepo.Update(ctx, oriInstance)
+	if mysql.IsDuplicateKeyError(err) {
+		return nil, common.ErrInstanceNameConflict(oriInstance.InstanceName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("更新实例失败: %v", err)
 	}
@@ -253,6 +320,24 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 	imgAddress := req.ImgAddress
 	envs := req.EnvironmentVariables
 	vms := req.VolumeMounts
+	// 未传初始化容器时保持原配置，传空数组时清空
+	initContainers := req.InitContainers
+	if initContainers == nil && len(oriInstance.InitContainers) > 0 {
+		if err := json.Unmarshal(oriInstance.InitContainers, &initContainers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal init containers: %w", err)
+		}
+	}
+	initSharedPath := req.InitSharedPath
+	if initSharedPath == "" {
+		initSharedPath = oriInstance.InitSharedPath
+	}
+	// 边车容器同样未传时保持原配置
+	sidecars := req.Sidecars
+	if sidecars == nil && len(oriInstance.Sidecars) > 0 {
+		if err := json.Unmarshal(oriInstance.Sidecars, &sidecars); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal sidecars: %w", err)
+		}
+	}
 	startupTimeout := req.StartupTimeout
 	runningTimeout := req.RunningTimeout
 	mcpServers := req.McpServers
@@ -274,11 +359,37 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 		oriInstance.SourceConfig = json.RawMessage([]byte(mcpServers))
 	}
 
+	// 编辑时沿用创建时继承的默认值，环境默认值后续的修改不影响已有实例
+	var oriContainerOptions container.ContainerCreateOptions
+	if len(oriInstance.ContainerCreateOptions) > 0 {
+		if err := json.Unmarshal(oriInstance.ContainerCreateOptions, &oriContainerOptions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal container create options: %w", err)
+		}
+	}
 	newContainerCreateOptions, err := GContainerBiz.BuildContainerOptions(ctx, instanceID, oriInstance.McpProtocol, mcpServers, packageID, port, initScript,
-		command, imgAddress, envs, vms, startupTimeout, runningTimeout)
+		command, imgAddress, envs, vms, initContainers, initSharedPath, sidecars, startupTimeout, runningTimeout, oriInstance.GetLabels(),
+		InheritedDefaults(oriContainerOptions))
 	if err != nil {
 		return nil, fmt.Errorf("构建容器配置失败: %v", err)
 	}
+	// 创建时指定的优先级类、PodDisruptionBudget、亲和规则和服务账号编辑时保持不变
+	newContainerCreateOptions.PriorityClassName = oriContainerOptions.PriorityClassName
+	newContainerCreateOptions.DisruptionBudget = oriContainerOptions.DisruptionBudget
+	newContainerCreateOptions.PodAffinity = oriContainerOptions.PodAffinity
+	newContainerCreateOptions.ServiceAccount = oriContainerOptions.ServiceAccount
+	newContainerCreateOptions.IsolateAccount = oriContainerOptions.IsolateAccount
+	// 未传出站目的地时保持原配置，传空数组时取消出站限制
+	allowedEgress := req.AllowedEgress
+	if allowedEgress == nil {
+		allowedEgress = oriContainerOptions.AllowedEgress
+	}
+	newContainerCreateOptions.AllowedEgress = allowedEgress
+	// 未指定副本数时保持原副本数
+	replicas := req.Replicas
+	if replicas <= 0 {
+		replicas = oriInstance.DesiredReplicas()
+	}
+	newContainerCreateOptions.Replicas = replicas
 	containerCreateOptions, err := common.MarshalAndAssignConfig(newContainerCreateOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal container create containerCreateOptions: %w", err)
@@ -290,6 +401,12 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 		return nil, fmt.Errorf("删除容器失败: %v", err)
 	}
 
+	// 未传服务路径时保持原路径
+	servicePath := req.ServicePath
+	if servicePath == "" {
+		servicePath = oriInstance.ServicePath
+	}
+
 	// Create target configuration
 	toMcpProtocol := oriInstance.McpProtocol
 	if oriInstance.McpProtocol == model.McpProtocolStdio {
@@ -299,18 +416,21 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 	tb := []byte{}
 	switch oriInstance.McpProtocol {
 	case model.McpProtocolStdio:
-		if strings.Contains(req.ImgAddress, common.DefatuleHostingImg) {
-			targetConfig := common.CreateTargetProxyConfigForDefatuleHostingImg(newContainerCreateOptions.ServiceName, newContainerCreateOptions.Port, newContainerCreateOptions.ContainerName, toMcpProtocol)
+		if GContainerBiz.IsDefaultHostingImage(ctx, oriInstance.EnvironmentID, req.ImgAddress) {
+			targetConfig := common.CreateTargetProxyConfigForDefaultHostingImage(newContainerCreateOptions.ServiceName, newContainerCreateOptions.Port, newContainerCreateOptions.ContainerName, toMcpProtocol)
 			tb, _ = common.MarshalAndAssignConfig(targetConfig)
 		}
 	case model.McpProtocolSSE, model.McpProtocolStreamableHttp:
-		targetConfig := common.CreateTargetProxyConfigForHttp(newContainerCreateOptions.ServiceName, newContainerCreateOptions.Port, newContainerCreateOptions.ContainerName, oriInstance.McpProtocol, req.ServicePath)
+		targetConfig := common.CreateTargetProxyConfigForHttp(newContainerCreateOptions.ServiceName, newContainerCreateOptions.Port, newContainerCreateOptions.ContainerName, oriInstance.McpProtocol, servicePath)
 		tb, _ = common.MarshalAndAssignConfig(targetConfig)
 	default:
 		return nil, fmt.Errorf("unsupported mcp protocol: %v", oriInstance.McpProtocol)
 	}
+	if tb, err = editRequestTimeouts(req, oriInstance.TargetConfig, tb); err != nil {
+		return nil, fmt.Errorf("failed to apply request timeouts: %w", err)
+	}
 	// Create proxy configuration
-	publicProxyConfig := GInstanceBiz.CreatePublicProxyConfig(instanceID, toMcpProtocol)
+	publicProxyConfig := GInstanceBiz.CreatePublicProxyConfig(instanceID, toMcpProtocol, nil)
 	pb, _ := common.MarshalAndAssignConfig(publicProxyConfig)
 
 	// 更新
@@ -322,16 +442,28 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 	oriInstance.ImgAddr = imgAddress
 	oriInstance.EnvironmentVariables, _ = common.MarshalAndAssignConfig(envs)
 	oriInstance.VolumeMounts, _ = common.MarshalAndAssignConfig(vms)
+	oriInstance.InitContainers, _ = common.MarshalAndAssignConfig(initContainers)
+	oriInstance.InitSharedPath = newContainerCreateOptions.InitSharedPath
+	oriInstance.Sidecars, _ = common.MarshalAndAssignConfig(sidecars)
 	oriInstance.StartupTimeout = int64(startupTimeout)
 	oriInstance.RunningTimeout = int64(runningTimeout)
 	oriInstance.ContainerCreateOptions = containerCreateOptions
+	oriInstance.Replicas = replicas
+	oriInstance.PreviousReplicas = 0
 	oriInstance.ContainerStatus = model.ContainerStatusPending
 	oriInstance.ContainerIsReady = false
 	oriInstance.SourceConfig = json.RawMessage([]byte(mcpServers))
 	oriInstance.TargetConfig = tb
 	oriInstance.PublicProxyConfig = pb
-	oriInstance.ServicePath = req.ServicePath
+	oriInstance.ServicePath = servicePath
+	GCodePackageBiz.StampVersion(ctx, oriInstance)
+	// 编辑可能更换镜像，按新配置重新记录摘要
+	oriInstance.ImageDigest = ""
+	GContainerBiz.StampImageDigest(ctx, oriInstance)
 	err = mysql.McpInstanceRepo.Update(ctx, oriInstance)
+	if mysql.IsDuplicateKeyError(err) {
+		return nil, common.ErrInstanceNameConflict(oriInstance.InstanceName)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("更新实例失败: %v", err)
 	}
@@ -351,25 +483,174 @@ func (biz *InstanceBiz) UpdateInstanceForHosting(ctx context.Context, req *insta
 		AccessType:  accessType,
 		McpProtocol: mcpProtocol,
 		Status:      string(model.InstanceStatusActive),
+		Warnings:    GContainerBiz.EgressWarnings(ctx, oriInstance.EnvironmentID, allowedEgress),
 	}
 	return resp, nil
 }
 
+// editRequestTimeouts 编辑后重新写入网关代理超时：请求中的值优先，其次是新目标配置中的值，都未设置时保持编辑前的值
+func editRequestTimeouts(req *instancepb.EditRequest, before, after json.RawMessage) (json.RawMessage, error) {
+	timeout, sseReadTimeout := req.Timeout, req.SseReadTimeout
+	beforeTimeout, beforeSseReadTimeout := common.RequestTimeouts(before)
+	afterTimeout, afterSseReadTimeout := common.RequestTimeouts(after)
+	if timeout == 0 && afterTimeout == 0 {
+		timeout = beforeTimeout
+	}
+	if sseReadTimeout == 0 && afterSseReadTimeout == 0 {
+		sseReadTimeout = beforeSseReadTimeout
+	}
+	return common.ApplyRequestTimeouts(after, timeout, sseReadTimeout)
+}
+
 // CreatePublicProxyConfig creates public proxy configuration
-func (biz *InstanceBiz) CreatePublicProxyConfig(instanceID string, mcpProtocol model.McpProtocol) *model.McpServersConfig {
-	mcpName := fmt.Sprintf("mcp-%s", instanceID[:8])
-	addr, _ := url.JoinPath(config.GlobalConfig.Domain, strings.TrimPrefix(common.GetGatewayRoutePrefix(), "/"), instanceID)
+// Only the instance-relative path is stored, domain and gateway prefix are resolved when building responses.
+// Multi-server instances get one entry per server, addressed as /{instanceId}/{serverName}
+func (biz *InstanceBiz) CreatePublicProxyConfig(instanceID string, mcpProtocol model.McpProtocol, serverNames []string) *model.McpServersConfig {
+	if len(serverNames) <= 1 {
+		return &model.McpServersConfig{
+			McpServers: map[string]*model.McpConfig{
+				fmt.Sprintf("mcp-%s", instanceID[:8]): publicProxyServerConfig("/"+instanceID, mcpProtocol),
+			},
+		}
+	}
+	config := &model.McpServersConfig{McpServers: make(map[string]*model.McpConfig, len(serverNames))}
+	for _, name := range serverNames {
+		config.McpServers[name] = publicProxyServerConfig("/"+instanceID+"/"+name, mcpProtocol)
+	}
+	return config
+}
+
+// DerivePublicProxyConfig rebuilds the public proxy configuration of an instance from its access type and source config,
+// the same way it is built when the instance is created
+func (biz *InstanceBiz) DerivePublicProxyConfig(instance *model.McpInstance) (json.RawMessage, error) {
+	switch instance.AccessType {
+	case model.AccessTypeDirect:
+		return instance.SourceConfig, nil
+	case model.AccessTypeProxy:
+		_, servers, _, err := instance.GetSourceConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mcp servers: %w", err)
+		}
+		return common.MarshalAndAssignConfig(biz.CreatePublicProxyConfig(instance.InstanceID, instance.McpProtocol, servers.ServerNames()))
+	case model.AccessTypeHosting:
+		return common.MarshalAndAssignConfig(biz.CreatePublicProxyConfig(instance.InstanceID, instance.McpProtocol, nil))
+	default:
+		return nil, fmt.Errorf("unsupported access type: %s", instance.AccessType)
+	}
+}
+
+// publicProxyServerConfig public proxy entry of a server, SSE servers are reached through the /sse endpoint
+func publicProxyServerConfig(addr string, mcpProtocol model.McpProtocol) *model.McpConfig {
 	if mcpProtocol == model.McpProtocolSSE {
 		addr += fmt.Sprintf("/%s", mcpProtocol.String())
 	}
-	return &model.McpServersConfig{
-		McpServers: map[string]*model.McpConfig{
-			mcpName: {
-				Type: mcpProtocol.String(),
-				URL:  addr,
-			},
-		},
+	return &model.McpConfig{
+		Type: mcpProtocol.String(),
+		URL:  addr,
+	}
+}
+
+// ProbeServers 并发探测直连和代理实例的每个 MCP 服务，结果按服务名称排序
+func (biz *InstanceBiz) ProbeServers(ctx context.Context, instance *model.McpInstance) ([]*instancepb.ServerProbe, error) {
+	_, servers, _, err := instance.GetTargetConfig()
+	if err != nil {
+		return nil, err
+	}
+	names := servers.ServerNames()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no mcp servers found in config")
+	}
+
+	probes := make([]*instancepb.ServerProbe, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		probe := &instancepb.ServerProbe{Name: name}
+		if cfg := servers.McpServers[name]; cfg != nil {
+			probe.Url = cfg.URL
+		}
+		probes[i] = probe
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := utils.ProbePortFromURL(ctx, probe.Url, 5*time.Second)
+			probe.ProbeHttp = result.Success
+			probe.ErrorMessage = result.Error
+		}()
+	}
+	wg.Wait()
+	return probes, nil
+}
+
+// InvalidateResponseCache 清除网关对实例的响应缓存，实例编辑或重启后调用，失败只记录日志
+func (biz *InstanceBiz) InvalidateResponseCache(instanceID string) {
+	if err := redis.DeleteResponseCache(instanceID); err != nil {
+		logger.Warn("Failed to invalidate gateway response cache", zap.String("instanceId", instanceID), zap.Error(err))
+	}
+}
+
+// GetCircuitBreakerStatus 查询网关对实例的熔断状态，未记录熔断时返回关闭状态，查询失败返回 nil
+func (biz *InstanceBiz) GetCircuitBreakerStatus(instanceID string) *instancepb.CircuitBreakerStatus {
+	state, err := redis.GetCircuitBreakerState(instanceID)
+	if err != nil {
+		logger.Warn("Failed to get gateway circuit breaker state", zap.String("instanceId", instanceID), zap.Error(err))
+		return nil
+	}
+	if state == nil {
+		return &instancepb.CircuitBreakerStatus{State: common.CircuitBreakerClosed}
+	}
+	return &instancepb.CircuitBreakerStatus{
+		State:     state.State,
+		Failures:  int32(state.Failures),
+		LastError: state.LastError,
+		OpenedAt:  state.OpenedAt,
+		RetryAt:   state.RetryAt,
+	}
+}
+
+// ResetCircuitBreaker 重置网关对实例的熔断，所有网关副本通过 Redis 订阅收到通知
+func (biz *InstanceBiz) ResetCircuitBreaker(instanceID string) error {
+	return redis.ResetCircuitBreaker(instanceID)
+}
+
+// GetConnections 查询实例在各网关上的 SSE 连接数和 streamable HTTP 活跃会话数，按网关标识排序
+func (biz *InstanceBiz) GetConnections(instanceID string) ([]*instancepb.GatewayConnections, error) {
+	connCounts, err := redis.GetSSEConnectionCounts(instanceID)
+	if err != nil {
+		return nil, err
 	}
+	sessionCounts, err := redis.GetMcpSessionCounts(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	byGateway := make(map[string]*instancepb.GatewayConnections, len(connCounts))
+	gatewayOf := func(count *redis.GatewayCount) *instancepb.GatewayConnections {
+		gateway, ok := byGateway[count.Gateway]
+		if !ok {
+			gateway = &instancepb.GatewayConnections{Gateway: count.Gateway}
+			byGateway[count.Gateway] = gateway
+		}
+		gateway.UpdatedAt = max(gateway.UpdatedAt, count.UpdatedAt)
+		return gateway
+	}
+	for _, count := range connCounts {
+		gatewayOf(count).SseConnections = int32(count.Count)
+	}
+	for _, count := range sessionCounts {
+		gatewayOf(count).Sessions = int32(count.Count)
+	}
+
+	gateways := make([]*instancepb.GatewayConnections, 0, len(byGateway))
+	for _, gateway := range byGateway {
+		gateways = append(gateways, gateway)
+	}
+	sort.Slice(gateways, func(i, j int) bool { return gateways[i].Gateway < gateways[j].Gateway })
+	return gateways, nil
+}
+
+// DrainSSEConnections 通知所有网关关闭实例的 SSE 连接，客户端收到结束事件后重连
+func (biz *InstanceBiz) DrainSSEConnections(instanceID string) error {
+	return redis.DrainSSEConnections(instanceID)
 }
 
 // GetInstancesByEnvironmentID 根据环境ID获取实例列表
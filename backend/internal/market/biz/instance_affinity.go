@@ -0,0 +1,106 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/k8s"
+)
+
+const (
+	// AffinityTypeAffinity 调度到目标实例所在的拓扑域
+	AffinityTypeAffinity = "affinity"
+	// AffinityTypeAntiAffinity 避开目标实例所在的拓扑域
+	AffinityTypeAntiAffinity = "antiAffinity"
+)
+
+// affinityInstanceLabel 托管实例 Pod 上标识实例ID的标签
+const affinityInstanceLabel = "instance"
+
+// AffinityTargetError 亲和规则引用的实例不存在，或不是同一环境中的托管实例
+type AffinityTargetError struct {
+	InstanceID string
+}
+
+func (e *AffinityTargetError) Error() string {
+	return fmt.Sprintf("affinity target %s is not a hosting instance in the same environment", e.InstanceID)
+}
+
+// BuildPodAffinity 将亲和规则转换为基于 Pod 标签的亲和项，实例ID匹配 instance 标签，实例标签匹配带 mcp.user/ 前缀的 Pod 标签。
+// 引用的实例需为 environmentID 环境中的托管实例，否则返回 *AffinityTargetError
+func (biz *InstanceBiz) BuildPodAffinity(ctx context.Context, environmentID uint, rules []*instancepb.PodAffinityRule) ([]k8s.PodAffinityTerm, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	var ids []string
+	for _, rule := range rules {
+		for _, id := range rule.InstanceIds {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	instances, err := mysql.McpInstanceRepo.FindByInstanceIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find affinity targets: %w", err)
+	}
+	valid := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		valid[instance.InstanceID] = instance.AccessType == model.AccessTypeHosting && instance.EnvironmentID == environmentID
+	}
+	for _, id := range ids {
+		if !valid[id] {
+			return nil, &AffinityTargetError{InstanceID: id}
+		}
+	}
+
+	terms := make([]k8s.PodAffinityTerm, 0, len(rules))
+	for _, rule := range rules {
+		term := k8s.PodAffinityTerm{
+			Anti:        rule.Type == AffinityTypeAntiAffinity,
+			Required:    rule.Required,
+			MatchLabels: common.PodLabels(rule.Labels),
+			TopologyKey: rule.TopologyKey,
+			Weight:      rule.Weight,
+		}
+		if len(rule.InstanceIds) > 0 {
+			term.MatchIn = map[string][]string{affinityInstanceLabel: slices.Clone(rule.InstanceIds)}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// PodAffinityRules 从保存的亲和项还原亲和规则
+func PodAffinityRules(terms []k8s.PodAffinityTerm) []*instancepb.PodAffinityRule {
+	rules := make([]*instancepb.PodAffinityRule, 0, len(terms))
+	for _, term := range terms {
+		rule := &instancepb.PodAffinityRule{
+			Type:        AffinityTypeAffinity,
+			InstanceIds: term.MatchIn[affinityInstanceLabel],
+			Required:    term.Required,
+			TopologyKey: term.TopologyKey,
+			Weight:      term.Weight,
+		}
+		if term.Anti {
+			rule.Type = AffinityTypeAntiAffinity
+		}
+		for key, value := range term.MatchLabels {
+			if name, ok := strings.CutPrefix(key, common.UserLabelPrefix); ok {
+				if rule.Labels == nil {
+					rule.Labels = make(map[string]string)
+				}
+				rule.Labels[name] = value
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
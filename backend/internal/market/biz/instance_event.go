@@ -0,0 +1,88 @@
+package biz
+
+import (
+	"context"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+
+	instancepb "qm-mcp-server/api/market/instance"
+)
+
+// InstanceEventBiz 实例事件历史数据处理层
+// Kubernetes 事件默认一小时后过期，状态查询和容器监控采集到的事件持久化后供历史查询
+type InstanceEventBiz struct {
+	ctx context.Context
+}
+
+// GInstanceEventBiz 全局实例事件历史数据处理层实例
+var GInstanceEventBiz *InstanceEventBiz
+
+func init() {
+	GInstanceEventBiz = NewInstanceEventBiz(context.Background())
+}
+
+// NewInstanceEventBiz 创建实例事件历史数据处理层实例
+func NewInstanceEventBiz(ctx context.Context) *InstanceEventBiz {
+	return &InstanceEventBiz{
+		ctx: ctx,
+	}
+}
+
+// RecordEvents 持久化采集到的容器事件，按 reason+message+timestamp 去重，
+// 每个实例只保留 instanceEvents.historySize 条最近采集到的事件
+func (biz *InstanceEventBiz) RecordEvents(ctx context.Context, instanceID string, events []container.ContainerEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	records := make([]*model.McpInstanceEvent, 0, len(events))
+	for _, event := range events {
+		eventType := event.Type
+		if eventType == "" {
+			eventType = model.InstanceEventTypeWarning
+		}
+		records = append(records, &model.McpInstanceEvent{
+			InstanceID:     instanceID,
+			Type:           eventType,
+			Reason:         event.Reason,
+			Message:        event.Message,
+			FirstTimestamp: event.Timestamp,
+		})
+	}
+	if err := mysql.McpInstanceEventRepo.Upsert(ctx, records); err != nil {
+		return err
+	}
+	return mysql.McpInstanceEventRepo.Prune(ctx, instanceID, config.GlobalConfig.InstanceEvents.HistorySize)
+}
+
+// CountEvents 统计实例的历史事件数量
+func (biz *InstanceEventBiz) CountEvents(ctx context.Context, instanceID string) (int64, error) {
+	return mysql.McpInstanceEventRepo.CountByInstanceID(ctx, instanceID)
+}
+
+// ListEvents 分页查询实例的历史事件
+func (biz *InstanceEventBiz) ListEvents(ctx context.Context, instanceID, severity string, page, pageSize int32) (*instancepb.EventsResp, error) {
+	records, total, err := mysql.McpInstanceEventRepo.FindWithPagination(ctx, instanceID, severity, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*instancepb.ContainerEvent, 0, len(records))
+	for _, record := range records {
+		list = append(list, &instancepb.ContainerEvent{
+			Type:           record.Type,
+			Reason:         record.Reason,
+			Message:        record.Message,
+			FirstTimestamp: record.FirstTimestamp,
+			LastTimestamp:  record.LastTimestamp,
+			Count:          record.Count,
+		})
+	}
+	return &instancepb.EventsResp{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		List:     list,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package biz
+
+import (
+	"context"
+	"sort"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	instancepb "qm-mcp-server/api/market/instance"
+
+	"go.uber.org/zap"
+)
+
+// 时间线条目来源
+const (
+	TimelineKindOperation = "operation"
+	TimelineKindEvent     = "event"
+)
+
+// recentTimelineSize 实例详情中内联的时间线条目数量
+const recentTimelineSize = 5
+
+// InstanceOperationBiz 实例操作记录数据处理层
+// 服务层和容器监控在实例生命周期变化时写入操作记录，与持久化的容器事件合并为时间线
+type InstanceOperationBiz struct {
+	ctx context.Context
+}
+
+// GInstanceOperationBiz 全局实例操作记录数据处理层实例
+var GInstanceOperationBiz *InstanceOperationBiz
+
+func init() {
+	GInstanceOperationBiz = NewInstanceOperationBiz(context.Background())
+}
+
+// NewInstanceOperationBiz 创建实例操作记录数据处理层实例
+func NewInstanceOperationBiz(ctx context.Context) *InstanceOperationBiz {
+	return &InstanceOperationBiz{
+		ctx: ctx,
+	}
+}
+
+// Record 记录实例操作，操作人取自上下文，未设置时为 system；写入失败只记录日志，不影响操作本身。
+// 写入成功后按实例创建人的通知偏好发送邮件通知
+func (biz *InstanceOperationBiz) Record(ctx context.Context, instanceID, operation, detail string) {
+	record := &model.McpInstanceOperation{
+		InstanceID: instanceID,
+		Operation:  operation,
+		Actor:      common.ActorFromContext(ctx),
+		Detail:     detail,
+	}
+	if err := mysql.McpInstanceOperationRepo.Create(ctx, record); err != nil {
+		logger.FromContext(ctx).Warn("Failed to record instance operation",
+			zap.String("instanceId", instanceID), zap.String("operation", operation), zap.Error(err))
+		return
+	}
+	GNotificationBiz.Dispatch(ctx, instanceID, operation, detail, record.CreatedAt)
+}
+
+// Creator 实例的创建人，取自创建操作记录，没有记录时为空
+func (biz *InstanceOperationBiz) Creator(ctx context.Context, instanceID string) (string, error) {
+	operation, err := mysql.McpInstanceOperationRepo.FindFirstByOperation(ctx, instanceID, model.InstanceOperationCreate)
+	if err != nil || operation == nil {
+		return "", err
+	}
+	return operation.Actor, nil
+}
+
+// Timeline 分页查询实例时间线，合并操作记录和持久化的容器事件，ascending 为 true 时按时间先后
+// 两张表各取前 page*pageSize 条后归并，避免跨表分页需要数据库支持 UNION 排序
+func (biz *InstanceOperationBiz) Timeline(ctx context.Context, instanceID string, ascending bool, page, pageSize int32) (*instancepb.TimelineResp, error) {
+	opCount, err := mysql.McpInstanceOperationRepo.CountByInstanceID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	eventCount, err := mysql.McpInstanceEventRepo.CountByInstanceID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(page * pageSize)
+	entries, err := biz.loadTimeline(ctx, instanceID, ascending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := int((page - 1) * pageSize)
+	list := []*instancepb.TimelineEntry{}
+	if offset < len(entries) {
+		list = entries[offset:min(len(entries), limit)]
+	}
+	return &instancepb.TimelineResp{
+		Total:    opCount + eventCount,
+		Page:     page,
+		PageSize: pageSize,
+		List:     list,
+	}, nil
+}
+
+// Recent 返回最近的时间线条目，按时间先后排列，查询失败时返回空
+func (biz *InstanceOperationBiz) Recent(ctx context.Context, instanceID string) []*instancepb.TimelineEntry {
+	entries, err := biz.loadTimeline(ctx, instanceID, false, recentTimelineSize)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to load recent instance timeline", zap.String("instanceId", instanceID), zap.Error(err))
+		return nil
+	}
+	if len(entries) > recentTimelineSize {
+		entries = entries[:recentTimelineSize]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// loadTimeline 从两张表各取前 limit 条并按时间归并
+func (biz *InstanceOperationBiz) loadTimeline(ctx context.Context, instanceID string, ascending bool, limit int) ([]*instancepb.TimelineEntry, error) {
+	operations, err := mysql.McpInstanceOperationRepo.FindByInstanceID(ctx, instanceID, ascending, limit)
+	if err != nil {
+		return nil, err
+	}
+	events, err := mysql.McpInstanceEventRepo.FindByInstanceID(ctx, instanceID, ascending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*instancepb.TimelineEntry, 0, len(operations)+len(events))
+	for _, op := range operations {
+		entries = append(entries, &instancepb.TimelineEntry{
+			Kind:      TimelineKindOperation,
+			Operation: op.Operation,
+			Actor:     op.Actor,
+			Timestamp: op.CreatedAt.Unix(),
+			Detail:    op.Detail,
+		})
+	}
+	for _, event := range events {
+		entries = append(entries, &instancepb.TimelineEntry{
+			Kind:      TimelineKindEvent,
+			Operation: event.Reason,
+			Timestamp: event.FirstTimestamp,
+			Detail:    event.Message,
+			EventType: event.Type,
+		})
+	}
+	// 两个来源各自有序，稳定排序保证同一秒内操作记录排在事件之前
+	sort.SliceStable(entries, func(i, j int) bool {
+		if ascending {
+			return entries[i].Timestamp < entries[j].Timestamp
+		}
+		return entries[i].Timestamp > entries[j].Timestamp
+	})
+	return entries, nil
+}
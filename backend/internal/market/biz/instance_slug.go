@@ -0,0 +1,73 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/redis"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CheckInstanceSlug 检查自定义访问路径是否已被其他实例使用，为空时不检查。
+// 其他实例修改前的旧路径可以直接使用，旧路径的重定向随之结束
+func (biz *InstanceBiz) CheckInstanceSlug(ctx context.Context, slug, excludeInstanceID string) error {
+	if slug == "" {
+		return nil
+	}
+	existing, err := mysql.McpInstanceRepo.FindBySlug(ctx, slug)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return common.WrapError(err, i18n.CodeDatabaseError)
+	}
+	if existing.InstanceID != excludeInstanceID {
+		return common.NewError(i18n.CodeInstanceSlugAlreadyExists, slug)
+	}
+	return nil
+}
+
+// SlugChanged 实例的自定义访问路径保存后调用：旧路径在配置的宽限期内重定向到实例当前路径，
+// 新路径不再重定向到其他实例，并通知网关丢弃按这两个路径缓存的实例。失败只记录日志
+func (biz *InstanceBiz) SlugChanged(ctx context.Context, instanceID, oldSlug, newSlug string) {
+	if oldSlug == newSlug {
+		return
+	}
+	now := time.Now()
+	if err := mysql.McpInstanceSlugAliasRepo.DeleteExpired(ctx, now); err != nil {
+		logger.Warn("Failed to delete expired instance slug aliases", zap.Error(err))
+	}
+	if newSlug != "" {
+		if err := mysql.McpInstanceSlugAliasRepo.DeleteBySlug(ctx, newSlug); err != nil {
+			logger.Warn("Failed to delete instance slug alias", zap.String("slug", newSlug), zap.Error(err))
+		}
+	}
+	if period := config.GlobalConfig.Slug.RedirectPeriod; oldSlug != "" && period > 0 {
+		alias := &model.McpInstanceSlugAlias{
+			Slug:       oldSlug,
+			InstanceID: instanceID,
+			ExpiresAt:  now.Add(time.Duration(period) * time.Hour),
+		}
+		if err := mysql.McpInstanceSlugAliasRepo.Upsert(ctx, alias); err != nil {
+			logger.Warn("Failed to save instance slug alias", zap.String("instanceId", instanceID), zap.String("slug", oldSlug), zap.Error(err))
+		}
+	}
+
+	for _, slug := range []string{oldSlug, newSlug} {
+		if slug == "" {
+			continue
+		}
+		if err := redis.PublishInstanceSlugChange(slug); err != nil {
+			logger.Warn("Failed to notify gateways of instance slug change", zap.String("slug", slug), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package biz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	instancepb "qm-mcp-server/api/market/instance"
+
+	"go.uber.org/zap"
+)
+
+// statusFeedBufferSize 每个订阅者缓存的事件数，缓存满时断开该订阅者
+const statusFeedBufferSize = 64
+
+// InstanceStatusFeed 实例状态变化广播
+// 实例状态经 McpInstanceRepo.Update 写入时发布，服务层、容器监控和 Pod watch 的更新都会经过这里；
+// 订阅者只在当前进程内，发布从不阻塞，消费过慢的订阅者会被断开，由客户端重连
+type InstanceStatusFeed struct {
+	mu          sync.RWMutex
+	subscribers map[*InstanceStatusSubscription]struct{}
+}
+
+// InstanceStatusSubscription 状态变化订阅
+type InstanceStatusSubscription struct {
+	feed    *InstanceStatusFeed
+	events  chan *instancepb.StatusEvent
+	filter  map[string]bool
+	dropped chan struct{}
+	once    sync.Once
+}
+
+// GInstanceStatusFeed 全局实例状态变化广播
+var GInstanceStatusFeed *InstanceStatusFeed
+
+func init() {
+	GInstanceStatusFeed = NewInstanceStatusFeed()
+	mysql.OnInstanceStatusChange(func(ctx context.Context, old, instance *model.McpInstance) {
+		GInstanceStatusFeed.Publish(&instancepb.StatusEvent{
+			InstanceId:         instance.InstanceID,
+			OldStatus:          string(old.Status),
+			NewStatus:          string(instance.Status),
+			OldContainerStatus: string(old.ContainerStatus),
+			NewContainerStatus: string(instance.ContainerStatus),
+			OldReady:           old.ContainerIsReady,
+			NewReady:           instance.ContainerIsReady,
+			Timestamp:          time.Now().UnixMilli(),
+		})
+	})
+}
+
+// NewInstanceStatusFeed 创建实例状态变化广播
+func NewInstanceStatusFeed() *InstanceStatusFeed {
+	return &InstanceStatusFeed{
+		subscribers: make(map[*InstanceStatusSubscription]struct{}),
+	}
+}
+
+// Subscribe 订阅状态变化，instanceIDs 为空时订阅所有实例，使用完毕后调用 Close
+func (f *InstanceStatusFeed) Subscribe(instanceIDs []string) *InstanceStatusSubscription {
+	sub := &InstanceStatusSubscription{
+		feed:    f,
+		events:  make(chan *instancepb.StatusEvent, statusFeedBufferSize),
+		dropped: make(chan struct{}),
+	}
+	if len(instanceIDs) > 0 {
+		sub.filter = make(map[string]bool, len(instanceIDs))
+		for _, id := range instanceIDs {
+			sub.filter[id] = true
+		}
+	}
+
+	f.mu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+// Publish 向订阅者发送事件，缓存已满的订阅者被断开而不是等待
+func (f *InstanceStatusFeed) Publish(event *instancepb.StatusEvent) {
+	var slow []*InstanceStatusSubscription
+	f.mu.RLock()
+	for sub := range f.subscribers {
+		if sub.filter != nil && !sub.filter[event.InstanceId] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	f.mu.RUnlock()
+
+	for _, sub := range slow {
+		logger.Warn("Dropping slow instance status subscriber", zap.String("instanceId", event.InstanceId))
+		sub.drop()
+	}
+}
+
+// Events 状态变化事件
+func (s *InstanceStatusSubscription) Events() <-chan *instancepb.StatusEvent {
+	return s.events
+}
+
+// Dropped 订阅因消费过慢被断开或已关闭时关闭
+func (s *InstanceStatusSubscription) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+// Close 取消订阅
+func (s *InstanceStatusSubscription) Close() {
+	s.drop()
+}
+
+// drop 从广播中移除订阅
+func (s *InstanceStatusSubscription) drop() {
+	s.once.Do(func() {
+		s.feed.mu.Lock()
+		delete(s.feed.subscribers, s)
+		s.feed.mu.Unlock()
+		close(s.dropped)
+	})
+}
@@ -0,0 +1,285 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/notify"
+
+	"go.uber.org/zap"
+)
+
+// notifyRetryBackoff 邮件发送失败后首次重试的等待时间，之后指数增长
+const notifyRetryBackoff = 5 * time.Second
+
+// digestMaxEvents 一封汇总邮件最多包含的事件数量
+const digestMaxEvents = 200
+
+// NotificationEvents 可以订阅邮件通知的实例操作
+var NotificationEvents = []string{
+	model.InstanceOperationCrash,
+	model.InstanceOperationStartupTimeout,
+	model.InstanceOperationRunningTimeout,
+	model.InstanceOperationCreateFailed,
+	model.InstanceOperationRecreate,
+	model.InstanceOperationHealthDown,
+	model.InstanceOperationHealthUp,
+	model.InstanceOperationTokenExpiring,
+	model.InstanceOperationCodeOutdated,
+	model.InstanceOperationImageChanged,
+}
+
+// DefaultNotificationEvents 通知偏好未选择事件时接收的事件
+var DefaultNotificationEvents = []string{
+	model.InstanceOperationCrash,
+	model.InstanceOperationStartupTimeout,
+	model.InstanceOperationCreateFailed,
+	model.InstanceOperationHealthDown,
+}
+
+// NotificationBiz 实例事件的邮件通知，只通知实例创建人，用户在通知偏好中开启后才发送
+// 与 webhook 通知同样来自实例操作记录，立即发送在后台进行，不阻塞写入操作记录的调用方
+type NotificationBiz struct {
+	ctx context.Context
+}
+
+// GNotificationBiz 全局邮件通知数据处理层实例
+var GNotificationBiz *NotificationBiz
+
+func init() {
+	GNotificationBiz = NewNotificationBiz(context.Background())
+}
+
+// NewNotificationBiz 创建邮件通知数据处理层实例
+func NewNotificationBiz(ctx context.Context) *NotificationBiz {
+	return &NotificationBiz{
+		ctx: ctx,
+	}
+}
+
+// Enabled 是否配置了 SMTP 服务
+func (biz *NotificationBiz) Enabled() bool {
+	return config.GlobalConfig != nil && config.GlobalConfig.Notify.SMTP.Host != ""
+}
+
+// sender 根据配置创建 SMTP 发送器
+func (biz *NotificationBiz) sender() notify.Sender {
+	cfg := config.GlobalConfig.Notify.SMTP
+	return &notify.SMTPSender{
+		Host:               cfg.Host,
+		Port:               cfg.Port,
+		Username:           cfg.Username,
+		Password:           cfg.Password,
+		From:               cfg.From,
+		TLSMode:            cfg.TLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+// GetPreference 查询用户的通知偏好，未设置时返回关闭通知的默认偏好
+func (biz *NotificationBiz) GetPreference(ctx context.Context, userID uint) (*model.McpNotificationPreference, error) {
+	preference, err := mysql.McpNotificationPreferenceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		events, _ := json.Marshal(DefaultNotificationEvents)
+		preference = &model.McpNotificationPreference{UserID: userID, Events: events, Mode: model.NotificationModeImmediate}
+	}
+	return preference, nil
+}
+
+// SetPreference 保存用户的通知偏好，切换为汇总发送时从当前时间开始汇总
+func (biz *NotificationBiz) SetPreference(ctx context.Context, preference *model.McpNotificationPreference) error {
+	previous, err := mysql.McpNotificationPreferenceRepo.FindByUserID(ctx, preference.UserID)
+	if err != nil {
+		return err
+	}
+	if preference.Mode == model.NotificationModeDigest {
+		if previous != nil && previous.Mode == model.NotificationModeDigest && previous.LastDigestAt != nil {
+			preference.LastDigestAt = previous.LastDigestAt
+		} else {
+			now := time.Now()
+			preference.LastDigestAt = &now
+		}
+	}
+	return mysql.McpNotificationPreferenceRepo.Upsert(ctx, preference)
+}
+
+// preferenceEvents 偏好中订阅的事件，未选择时使用默认事件
+func preferenceEvents(preference *model.McpNotificationPreference) []string {
+	if events := preference.GetEvents(); len(events) > 0 {
+		return events
+	}
+	return DefaultNotificationEvents
+}
+
+// Dispatch 实例操作记录写入后调用，在后台按实例创建人的通知偏好发送邮件，并按配置发送飞书卡片。
+// 发送失败按配置重试，最终失败只记录日志
+func (biz *NotificationBiz) Dispatch(ctx context.Context, instanceID, operation, detail string, at time.Time) {
+	email := biz.Enabled() && slices.Contains(NotificationEvents, operation)
+	feishu := biz.feishuEventEnabled(operation)
+	if !email && !feishu {
+		return
+	}
+	// 通知不受调用方上下文取消的影响
+	notifyCtx := context.WithoutCancel(ctx)
+	go func() {
+		instance, err := mysql.McpInstanceRepo.FindByInstanceID(notifyCtx, instanceID)
+		if err != nil {
+			logger.Warn("Failed to find instance of notification",
+				zap.String("instanceId", instanceID), zap.String("operation", operation), zap.Error(err))
+			return
+		}
+		event := instanceEvent(instance, operation, detail, at)
+		if email {
+			if err := biz.dispatchEmail(notifyCtx, instance, event); err != nil {
+				logger.Warn("Failed to send instance event email",
+					zap.String("instanceId", instanceID), zap.String("operation", operation), zap.Error(err))
+			}
+		}
+		if feishu {
+			biz.dispatchFeishu(notifyCtx, instance, event)
+		}
+	}()
+}
+
+// dispatchEmail 实例创建人订阅了该事件且选择立即发送时发送邮件
+func (biz *NotificationBiz) dispatchEmail(ctx context.Context, instance *model.McpInstance, event notify.InstanceEvent) error {
+	if instance.CreatorID == 0 {
+		return nil
+	}
+	preference, err := mysql.McpNotificationPreferenceRepo.FindByUserID(ctx, instance.CreatorID)
+	if err != nil {
+		return fmt.Errorf("failed to find notification preference: %w", err)
+	}
+	if preference == nil || !preference.Enabled || preference.Mode != model.NotificationModeImmediate ||
+		!slices.Contains(preferenceEvents(preference), event.Event) {
+		return nil
+	}
+	to, err := biz.recipient(ctx, preference)
+	if err != nil || to == "" {
+		return err
+	}
+
+	msg, err := notify.RenderInstanceEvents([]string{to}, []notify.InstanceEvent{event})
+	if err != nil {
+		return err
+	}
+	return notify.SendWithRetry(ctx, biz.sender(), msg, config.GlobalConfig.Notify.MaxRetries, notifyRetryBackoff)
+}
+
+// SendDigests 为到达汇总间隔的用户发送上次汇总以来的事件，发送成功后才推进汇总时间，失败的汇总下一轮重发。
+// 单个用户处理失败只记录日志
+func (biz *NotificationBiz) SendDigests(ctx context.Context) error {
+	if !biz.Enabled() {
+		return nil
+	}
+	preferences, err := mysql.McpNotificationPreferenceRepo.FindEnabledByMode(ctx, model.NotificationModeDigest)
+	if err != nil {
+		return fmt.Errorf("failed to find digest preferences: %w", err)
+	}
+
+	now := time.Now()
+	interval := time.Duration(config.GlobalConfig.Notify.DigestInterval) * time.Minute
+	for _, preference := range preferences {
+		// 任务锁丢失时中止本批次
+		if ctx.Err() != nil {
+			return nil
+		}
+		since := now.Add(-interval)
+		if preference.LastDigestAt != nil {
+			if now.Sub(*preference.LastDigestAt) < interval {
+				continue
+			}
+			since = *preference.LastDigestAt
+		}
+		if err := biz.sendDigest(ctx, preference, since, now); err != nil {
+			logger.Warn("Failed to send notification digest", zap.Uint("userId", preference.UserID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// sendDigest 发送用户在 (since, until] 内的事件汇总，没有事件时只推进汇总时间
+func (biz *NotificationBiz) sendDigest(ctx context.Context, preference *model.McpNotificationPreference, since, until time.Time) error {
+	instances, err := mysql.McpInstanceRepo.FindByCreator(ctx, preference.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to find instances: %w", err)
+	}
+	byID := make(map[string]*model.McpInstance, len(instances))
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		byID[instance.InstanceID] = instance
+		ids = append(ids, instance.InstanceID)
+	}
+	operations, err := mysql.McpInstanceOperationRepo.FindByInstanceIDsSince(ctx, ids, preferenceEvents(preference), since, until, digestMaxEvents)
+	if err != nil {
+		return fmt.Errorf("failed to find instance operations: %w", err)
+	}
+
+	if len(operations) > 0 {
+		to, err := biz.recipient(ctx, preference)
+		if err != nil {
+			return err
+		}
+		if to == "" {
+			return nil
+		}
+		events := make([]notify.InstanceEvent, 0, len(operations))
+		for _, operation := range operations {
+			events = append(events, instanceEvent(byID[operation.InstanceID], operation.Operation, operation.Detail, operation.CreatedAt))
+		}
+		msg, err := notify.RenderInstanceEvents([]string{to}, events)
+		if err != nil {
+			return err
+		}
+		if err := notify.SendWithRetry(ctx, biz.sender(), msg, config.GlobalConfig.Notify.MaxRetries, notifyRetryBackoff); err != nil {
+			return err
+		}
+	}
+	return mysql.McpNotificationPreferenceRepo.UpdateLastDigestAt(ctx, preference.UserID, until)
+}
+
+// recipient 通知接收地址，偏好中未填写时使用用户资料中的邮箱，都没有时返回空
+func (biz *NotificationBiz) recipient(ctx context.Context, preference *model.McpNotificationPreference) (string, error) {
+	if preference.Email != "" {
+		return preference.Email, nil
+	}
+	users, err := mysql.SysUserRepo.FindByIDs(ctx, []uint{preference.UserID})
+	if err != nil {
+		return "", fmt.Errorf("failed to find user: %w", err)
+	}
+	if len(users) == 0 {
+		return "", nil
+	}
+	return users[0].GetEmail(), nil
+}
+
+// instanceEvent 邮件中展示的实例事件，详情为空时使用容器上次状态信息，未配置控制台地址时不带链接
+func instanceEvent(instance *model.McpInstance, operation, detail string, at time.Time) notify.InstanceEvent {
+	if detail == "" {
+		detail = instance.ContainerLastMessage
+	}
+	event := notify.InstanceEvent{
+		InstanceID:   instance.InstanceID,
+		InstanceName: instance.InstanceName,
+		Event:        operation,
+		Status:       fmt.Sprintf("%s / %s", instance.Status, instance.ContainerStatus),
+		Message:      detail,
+		Time:         at,
+	}
+	if consoleURL := config.GlobalConfig.Notify.ConsoleURL; consoleURL != "" {
+		event.Link = strings.TrimSuffix(consoleURL, "/") + "/instance-log?instanceId=" + instance.InstanceID
+	}
+	return event
+}
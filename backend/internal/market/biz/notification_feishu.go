@@ -0,0 +1,147 @@
+package biz
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/notify"
+
+	"go.uber.org/zap"
+)
+
+// FeishuNotificationEvents 可以发送飞书卡片的实例操作，删除实例时实例记录已不存在，不发送
+var FeishuNotificationEvents = append([]string{
+	model.InstanceOperationCreate,
+	model.InstanceOperationRestart,
+	model.InstanceOperationEnable,
+	model.InstanceOperationDisable,
+}, NotificationEvents...)
+
+// DefaultFeishuNotificationEvents 配置中未设置开关的事件默认发送的事件
+var DefaultFeishuNotificationEvents = []string{
+	model.InstanceOperationCrash,
+	model.InstanceOperationStartupTimeout,
+	model.InstanceOperationRunningTimeout,
+	model.InstanceOperationCreateFailed,
+	model.InstanceOperationHealthDown,
+	model.InstanceOperationTokenExpiring,
+}
+
+// feishuBotCache 飞书机器人在配置不变时复用，保留租户令牌和每个会话的限流状态
+var feishuBotCache = struct {
+	mu  sync.Mutex
+	cfg common.FeishuConfig
+	bot *notify.FeishuBot
+}{}
+
+// feishuEventEnabled 是否配置了飞书机器人并开启了该事件
+func (biz *NotificationBiz) feishuEventEnabled(operation string) bool {
+	if config.GlobalConfig == nil || config.GlobalConfig.Notify.Feishu.AppID == "" {
+		return false
+	}
+	if !slices.Contains(FeishuNotificationEvents, operation) {
+		return false
+	}
+	if enabled, ok := config.GlobalConfig.Notify.Feishu.Events[operation]; ok {
+		return enabled
+	}
+	return slices.Contains(DefaultFeishuNotificationEvents, operation)
+}
+
+// feishuBot 根据配置获取飞书机器人，应用或限流配置变化时重新创建
+func (biz *NotificationBiz) feishuBot() *notify.FeishuBot {
+	cfg := config.GlobalConfig.Notify.Feishu
+	feishuBotCache.mu.Lock()
+	defer feishuBotCache.mu.Unlock()
+	cached := feishuBotCache.cfg
+	if feishuBotCache.bot == nil || cached.AppID != cfg.AppID || cached.AppSecret != cfg.AppSecret ||
+		cached.BaseURL != cfg.BaseURL || cached.RatePerSecond != cfg.RatePerSecond || cached.Timeout != cfg.Timeout {
+		feishuBotCache.cfg = cfg
+		feishuBotCache.bot = &notify.FeishuBot{
+			AppID:         cfg.AppID,
+			AppSecret:     cfg.AppSecret,
+			BaseURL:       cfg.BaseURL,
+			Timeout:       time.Duration(cfg.Timeout) * time.Second,
+			RatePerSecond: cfg.RatePerSecond,
+		}
+	}
+	return feishuBotCache.bot
+}
+
+// dispatchFeishu 发送飞书卡片给实例创建人，创建人没有飞书ID时发送到配置的群聊；
+// alwaysNotifyChat 开启时同时发送到群聊。每个接收方单独重试，失败只记录日志
+func (biz *NotificationBiz) dispatchFeishu(ctx context.Context, instance *model.McpInstance, event notify.InstanceEvent) {
+	cfg := config.GlobalConfig.Notify.Feishu
+	type receiver struct{ idType, id string }
+	var receivers []receiver
+	if feishuID := biz.creatorFeishuID(ctx, instance); feishuID != "" {
+		receivers = append(receivers, receiver{cfg.UserIDType, feishuID})
+	}
+	if cfg.ChatID != "" && (len(receivers) == 0 || cfg.AlwaysNotifyChat) {
+		receivers = append(receivers, receiver{notify.FeishuReceiveChatID, cfg.ChatID})
+	}
+
+	card := notify.FeishuInstanceCard(event, feishuEventColor(event.Event), feishuEventLinks(instance.InstanceID)...)
+	bot := biz.feishuBot()
+	for _, r := range receivers {
+		err := notify.Retry(ctx, config.GlobalConfig.Notify.MaxRetries, notifyRetryBackoff, func() error {
+			return bot.SendCard(ctx, r.idType, r.id, card)
+		})
+		if err != nil {
+			logger.Warn("Failed to send instance event to feishu",
+				zap.String("instanceId", instance.InstanceID), zap.String("operation", event.Event),
+				zap.String("receiveIdType", r.idType), zap.Error(err))
+		}
+	}
+}
+
+// creatorFeishuID 实例创建人的飞书ID，系统创建的实例或创建人没有飞书ID时为空
+func (biz *NotificationBiz) creatorFeishuID(ctx context.Context, instance *model.McpInstance) string {
+	if instance.CreatorID == 0 {
+		return ""
+	}
+	users, err := mysql.SysUserRepo.FindByIDs(ctx, []uint{instance.CreatorID})
+	if err != nil {
+		logger.Warn("Failed to find instance creator", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		return ""
+	}
+	if len(users) == 0 || !users[0].HasFeishuID() {
+		return ""
+	}
+	return strings.TrimSpace(*users[0].FeishuID)
+}
+
+// feishuEventLinks 卡片按钮，只跳转到控制台页面，重启等操作由用户在控制台确认后执行
+func feishuEventLinks(instanceID string) []notify.FeishuLink {
+	consoleURL := strings.TrimSuffix(config.GlobalConfig.Notify.ConsoleURL, "/")
+	if consoleURL == "" {
+		return nil
+	}
+	return []notify.FeishuLink{
+		{Text: "View logs", URL: consoleURL + "/instance-log?instanceId=" + instanceID},
+		{Text: "Restart instance", URL: consoleURL + "/instance-manage?instanceId=" + instanceID},
+	}
+}
+
+// feishuEventColor 卡片标题颜色，故障为红色，需要关注的事件为橙色，恢复为绿色
+func feishuEventColor(operation string) string {
+	switch operation {
+	case model.InstanceOperationCrash, model.InstanceOperationStartupTimeout, model.InstanceOperationRunningTimeout,
+		model.InstanceOperationCreateFailed, model.InstanceOperationHealthDown:
+		return notify.FeishuColorRed
+	case model.InstanceOperationTokenExpiring, model.InstanceOperationCodeOutdated, model.InstanceOperationRecreate:
+		return notify.FeishuColorOrange
+	case model.InstanceOperationHealthUp, model.InstanceOperationEnable:
+		return notify.FeishuColorGreen
+	default:
+		return notify.FeishuColorBlue
+	}
+}
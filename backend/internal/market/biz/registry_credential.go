@@ -0,0 +1,136 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/registry"
+	"qm-mcp-server/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// RegistryCredentialBiz 私有镜像仓库凭证数据处理层
+// 密码/令牌使用配置的 secret 派生密钥加密后存储
+type RegistryCredentialBiz struct {
+	ctx context.Context
+}
+
+// GRegistryCredentialBiz 全局镜像仓库凭证数据处理层实例
+var GRegistryCredentialBiz *RegistryCredentialBiz
+
+func init() {
+	GRegistryCredentialBiz = NewRegistryCredentialBiz(context.Background())
+}
+
+// NewRegistryCredentialBiz 创建镜像仓库凭证数据处理层实例
+func NewRegistryCredentialBiz(ctx context.Context) *RegistryCredentialBiz {
+	return &RegistryCredentialBiz{
+		ctx: ctx,
+	}
+}
+
+// CreateCredential 加密密码后创建凭证，password 为明文
+func (biz *RegistryCredentialBiz) CreateCredential(ctx context.Context, credential *model.McpRegistryCredential, password string) error {
+	encrypted, err := utils.AESEncrypt(password, config.GlobalConfig.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt registry password: %w", err)
+	}
+	credential.RegistryHost = registry.NormalizeRegistry(credential.RegistryHost)
+	credential.Password = encrypted
+	credential.PrepareForCreate()
+	return mysql.McpRegistryCredentialRepo.Create(ctx, credential)
+}
+
+// UpdateCredential 更新凭证，password 为空时保留原密码
+func (biz *RegistryCredentialBiz) UpdateCredential(ctx context.Context, credential *model.McpRegistryCredential, password string) error {
+	if password != "" {
+		encrypted, err := utils.AESEncrypt(password, config.GlobalConfig.Secret)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt registry password: %w", err)
+		}
+		credential.Password = encrypted
+	}
+	credential.RegistryHost = registry.NormalizeRegistry(credential.RegistryHost)
+	credential.PrepareForUpdate()
+	return mysql.McpRegistryCredentialRepo.Update(ctx, credential)
+}
+
+// DeleteCredential 删除凭证，已同步到命名空间中的镜像拉取密钥保留，避免影响运行中的实例
+func (biz *RegistryCredentialBiz) DeleteCredential(ctx context.Context, id uint) error {
+	return mysql.McpRegistryCredentialRepo.Delete(ctx, id)
+}
+
+// GetCredential 根据ID获取凭证
+func (biz *RegistryCredentialBiz) GetCredential(ctx context.Context, id uint) (*model.McpRegistryCredential, error) {
+	return mysql.McpRegistryCredentialRepo.FindByID(ctx, id)
+}
+
+// GetCredentialByName 根据名称获取凭证
+func (biz *RegistryCredentialBiz) GetCredentialByName(ctx context.Context, name string) (*model.McpRegistryCredential, error) {
+	return mysql.McpRegistryCredentialRepo.FindByName(ctx, name)
+}
+
+// ListCredentials 分页查询凭证
+func (biz *RegistryCredentialBiz) ListCredentials(ctx context.Context, registryHost string, page, pageSize int32) ([]*model.McpRegistryCredential, int64, error) {
+	if registryHost != "" {
+		registryHost = registry.NormalizeRegistry(registryHost)
+	}
+	return mysql.McpRegistryCredentialRepo.FindWithPagination(ctx, registryHost, page, pageSize)
+}
+
+// CredentialForRegistry 查找仓库地址对应的凭证并解密，未配置凭证时返回 nil
+func (biz *RegistryCredentialBiz) CredentialForRegistry(ctx context.Context, registryHost string) (*model.McpRegistryCredential, *registry.Credential, error) {
+	record, err := mysql.McpRegistryCredentialRepo.FindByRegistryHost(ctx, registry.NormalizeRegistry(registryHost))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	password, err := utils.AESDecrypt(record.Password, config.GlobalConfig.Secret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt registry credential %s: %w", record.Name, err)
+	}
+	return record, &registry.Credential{Username: record.Username, Password: password}, nil
+}
+
+// SyncPullSecret 镜像仓库匹配已保存的凭证时，在环境命名空间中创建或刷新 dockerconfigjson 密钥
+// 返回密钥名称，未匹配到凭证时返回空字符串
+func (cd *ContainerBiz) SyncPullSecret(ctx context.Context, environmentID uint, image string) (string, error) {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", nil
+	}
+	record, cred, err := GRegistryCredentialBiz.CredentialForRegistry(ctx, ref.Registry)
+	if err != nil || record == nil {
+		return "", err
+	}
+
+	entry, err := cd.GetRuntimeEntry(ctx, environmentID)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil || entry.GetK8sRuntime() == nil {
+		return "", fmt.Errorf("image pull secrets require a Kubernetes environment")
+	}
+
+	data, err := registry.DockerConfigJSON(ref.Registry, *cred)
+	if err != nil {
+		return "", err
+	}
+	labels := map[string]string{
+		"managed-by":          common.SourceServerName,
+		"registry-credential": fmt.Sprintf("%d", record.ID),
+	}
+	secretName := record.PullSecretName()
+	if _, err := entry.GetK8sRuntime().Entry.Secret.ApplyDockerConfigJSON(secretName, data, labels); err != nil {
+		return "", err
+	}
+	return secretName, nil
+}
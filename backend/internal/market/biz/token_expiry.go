@@ -0,0 +1,188 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+
+	"go.uber.org/zap"
+)
+
+// TokenEventExpiring 令牌即将过期通知的事件类型
+const TokenEventExpiring = "instance.token.expiring"
+
+var (
+	// ErrTokenNotFound 实例中不存在要轮换的令牌
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenRotating 令牌已经轮换过
+	ErrTokenRotating = errors.New("token is already rotating")
+)
+
+// TokenWebhookEvent 令牌即将过期时 POST 给 webhook 的内容，令牌只包含首尾字符
+type TokenWebhookEvent struct {
+	Event           string `json:"event"`
+	InstanceID      string `json:"instanceId"`
+	InstanceName    string `json:"instanceName"`
+	Token           string `json:"token"`
+	ExpireAt        string `json:"expireAt"`
+	DaysUntilExpiry int32  `json:"daysUntilExpiry"`
+}
+
+// TokenRotation 令牌轮换结果
+type TokenRotation struct {
+	// Token 新生成的令牌
+	Token model.McpToken
+	// Previous 被轮换的令牌，ExpireAt 为宽限期结束时间
+	Previous model.McpToken
+}
+
+// TokenExpiryBiz 实例令牌的过期通知和轮换
+type TokenExpiryBiz struct {
+	ctx context.Context
+}
+
+// GTokenExpiryBiz 全局令牌过期数据处理层实例
+var GTokenExpiryBiz *TokenExpiryBiz
+
+func init() {
+	GTokenExpiryBiz = NewTokenExpiryBiz(context.Background())
+}
+
+// NewTokenExpiryBiz 创建令牌过期数据处理层实例
+func NewTokenExpiryBiz(ctx context.Context) *TokenExpiryBiz {
+	return &TokenExpiryBiz{
+		ctx: ctx,
+	}
+}
+
+// NotifyExpiring 查找活跃实例中进入过期预警窗口的令牌，每个令牌只通知一次，
+// 已轮换的令牌不通知。单个实例处理失败只记录日志
+func (biz *TokenExpiryBiz) NotifyExpiring(ctx context.Context) error {
+	instances, err := mysql.McpInstanceRepo.FindByStatus(ctx, model.InstanceStatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to find active instances: %w", err)
+	}
+
+	now := time.Now()
+	window := common.TokenWarningWindow()
+	for _, instance := range instances {
+		// 任务锁丢失时中止本批次
+		if ctx.Err() != nil {
+			return nil
+		}
+		var expiring []int
+		for i := range instance.Tokens {
+			token := &instance.Tokens[i]
+			if token.NotifiedAt == 0 && token.State(now, window) == model.TokenStateExpiring {
+				expiring = append(expiring, i)
+			}
+		}
+		if len(expiring) == 0 {
+			continue
+		}
+
+		for _, i := range expiring {
+			instance.Tokens[i].NotifiedAt = now.UnixMilli()
+		}
+		// 先保存通知时间，保存失败时不发送通知，避免重复通知
+		if err := mysql.McpInstanceRepo.UpdateTokens(ctx, instance.InstanceID, instance.Tokens); err != nil {
+			logger.Warn("Failed to record token expiry notification", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+			continue
+		}
+		for _, i := range expiring {
+			biz.notifyExpiring(ctx, instance, &instance.Tokens[i], now)
+		}
+	}
+	return nil
+}
+
+// notifyExpiring 记录令牌即将过期到实例时间线，并异步通知所有 webhook，通知失败只记录日志
+func (biz *TokenExpiryBiz) notifyExpiring(ctx context.Context, instance *model.McpInstance, token *model.McpToken, now time.Time) {
+	expireAt := time.UnixMilli(token.ExpireAt).UTC().Format(time.RFC3339)
+	event := &TokenWebhookEvent{
+		Event:           TokenEventExpiring,
+		InstanceID:      instance.InstanceID,
+		InstanceName:    instance.InstanceName,
+		Token:           utils.MaskToken(token.Token),
+		ExpireAt:        expireAt,
+		DaysUntilExpiry: token.DaysUntilExpiry(now),
+	}
+	GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationTokenExpiring,
+		fmt.Sprintf("token %s expires at %s", event.Token, expireAt))
+
+	cfg := config.GlobalConfig.TokenExpiry
+	timeout := time.Duration(cfg.WebhookTimeout) * time.Second
+	// 通知不受任务锁上下文取消的影响
+	notifyCtx := context.WithoutCancel(ctx)
+	for _, webhook := range cfg.Webhooks {
+		go func() {
+			if err := utils.PostJSON(notifyCtx, webhook, event, timeout); err != nil {
+				logger.Warn("Failed to send token expiry webhook",
+					zap.String("instanceId", instance.InstanceID), zap.String("webhook", webhook), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// Rotate 为实例令牌生成替换令牌，新令牌沿用原令牌的有效时长，scope 为空时沿用原令牌的使用场景。
+// 原令牌在宽限期内继续有效，原令牌更早过期时不延长。grace 为 0 时使用配置的宽限期
+func (biz *TokenExpiryBiz) Rotate(ctx context.Context, instance *model.McpInstance, value, scope string, grace time.Duration) (*TokenRotation, error) {
+	index := -1
+	for i := range instance.Tokens {
+		if instance.Tokens[i].Token == value {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, ErrTokenNotFound
+	}
+	previous := &instance.Tokens[index]
+	if previous.RotatedAt > 0 {
+		return nil, ErrTokenRotating
+	}
+
+	newValue, err := utils.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	if grace <= 0 {
+		grace = time.Duration(config.GlobalConfig.TokenExpiry.GracePeriod) * time.Hour
+	}
+	now := time.Now()
+	token := model.McpToken{
+		Token:     newValue,
+		PublishAt: now.UnixMilli(),
+		Usages:    previous.Usages,
+	}
+	if scope != "" {
+		token.Usages = []string{scope}
+	}
+	if previous.ExpireAt > 0 && previous.PublishAt > 0 && previous.ExpireAt > previous.PublishAt {
+		token.ExpireAt = token.PublishAt + previous.ExpireAt - previous.PublishAt
+	}
+
+	graceEnd := now.Add(grace).UnixMilli()
+	if previous.ExpireAt <= 0 || previous.ExpireAt > graceEnd {
+		previous.ExpireAt = graceEnd
+	}
+	previous.RotatedAt = now.UnixMilli()
+	instance.Tokens = append(instance.Tokens, token)
+	if err := mysql.McpInstanceRepo.UpdateTokens(ctx, instance.InstanceID, instance.Tokens); err != nil {
+		return nil, err
+	}
+
+	result := &TokenRotation{Token: token, Previous: instance.Tokens[index]}
+	GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationTokenRotate,
+		fmt.Sprintf("token %s replaced by %s, valid until %s", utils.MaskToken(value), utils.MaskToken(newValue),
+			time.UnixMilli(result.Previous.ExpireAt).UTC().Format(time.RFC3339)))
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package biz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+)
+
+// packageUsageTTL 用户代码包存储使用量缓存时长，上传校验不需要每次汇总代码包表
+const packageUsageTTL = 30 * time.Second
+
+// 用户配额的资源名称，用于配额不足的错误信息
+const (
+	UserQuotaResourceInstances        = "instances"
+	UserQuotaResourceHostingInstances = "hostingInstances"
+	UserQuotaResourcePackageBytes     = "packageBytes"
+)
+
+// UserQuotaUsage 用户已使用的配额
+type UserQuotaUsage struct {
+	Instances        int64
+	HostingInstances int64
+	PackageBytes     int64
+}
+
+// packageUsage 缓存的代码包存储使用量
+type packageUsage struct {
+	bytes       int64
+	collectedAt time.Time
+}
+
+// packageUsageCache 按用户 ID 缓存代码包存储使用量
+var packageUsageCache = struct {
+	mu      sync.Mutex
+	entries map[uint]packageUsage
+}{entries: make(map[uint]packageUsage)}
+
+// UserQuotaBiz 用户配额数据处理层，实例和代码包按创建用户计入配额
+type UserQuotaBiz struct {
+	ctx context.Context
+}
+
+// GUserQuotaBiz 全局用户配额数据处理层实例
+var GUserQuotaBiz *UserQuotaBiz
+
+func init() {
+	GUserQuotaBiz = NewUserQuotaBiz(context.Background())
+}
+
+// NewUserQuotaBiz 创建用户配额数据处理层实例
+func NewUserQuotaBiz(ctx context.Context) *UserQuotaBiz {
+	return &UserQuotaBiz{
+		ctx: ctx,
+	}
+}
+
+// GetQuota 查询用户配额，未设置时返回不限制的配额
+func (biz *UserQuotaBiz) GetQuota(ctx context.Context, userID uint) (*model.McpUserQuota, error) {
+	quota, err := mysql.McpUserQuotaRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		quota = &model.McpUserQuota{UserID: userID}
+	}
+	return quota, nil
+}
+
+// SetQuota 设置用户配额，操作人取自上下文
+func (biz *UserQuotaBiz) SetQuota(ctx context.Context, quota *model.McpUserQuota) error {
+	quota.UpdatedBy = common.ActorFromContext(ctx)
+	return mysql.McpUserQuotaRepo.Upsert(ctx, quota)
+}
+
+// Usage 统计用户已使用的配额，代码包存储使用量缓存 packageUsageTTL
+func (biz *UserQuotaBiz) Usage(ctx context.Context, userID uint) (*UserQuotaUsage, error) {
+	instances, hosting, err := mysql.McpInstanceRepo.CountByCreator(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	packageBytes, err := biz.packageBytes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserQuotaUsage{Instances: instances, HostingInstances: hosting, PackageBytes: packageBytes}, nil
+}
+
+// packageBytes 用户上传的代码包大小之和
+func (biz *UserQuotaBiz) packageBytes(ctx context.Context, userID uint) (int64, error) {
+	packageUsageCache.mu.Lock()
+	cached, ok := packageUsageCache.entries[userID]
+	packageUsageCache.mu.Unlock()
+	if ok && time.Since(cached.collectedAt) < packageUsageTTL {
+		return cached.bytes, nil
+	}
+
+	total, err := mysql.McpCodePackageRepo.SumFileSizeByCreator(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	packageUsageCache.mu.Lock()
+	packageUsageCache.entries[userID] = packageUsage{bytes: total, collectedAt: time.Now()}
+	packageUsageCache.mu.Unlock()
+	return total, nil
+}
+
+// InvalidatePackageUsage 用户上传或删除代码包后清除缓存的存储使用量，其他副本的缓存在 packageUsageTTL 后过期
+func (biz *UserQuotaBiz) InvalidatePackageUsage(userID uint) {
+	packageUsageCache.mu.Lock()
+	delete(packageUsageCache.entries, userID)
+	packageUsageCache.mu.Unlock()
+}
+
+// CheckInstanceQuota 校验当前用户能否再创建一个实例，hosting 表示创建托管实例。
+// 上下文中没有用户（平台自动操作）或用户未设置配额时不限制
+func (biz *UserQuotaBiz) CheckInstanceQuota(ctx context.Context, hosting bool) error {
+	userID := common.UserIDFromContext(ctx)
+	if userID == 0 {
+		return nil
+	}
+	quota, err := biz.GetQuota(ctx, userID)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeUserQuotaFailure)
+	}
+	if quota.MaxInstances <= 0 && (!hosting || quota.MaxHostingInstances <= 0) {
+		return nil
+	}
+	instances, hostingInstances, err := mysql.McpInstanceRepo.CountByCreator(ctx, userID)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeUserQuotaFailure)
+	}
+
+	if quota.MaxInstances > 0 && instances+1 > int64(quota.MaxInstances) {
+		return quotaExceeded(ctx, UserQuotaResourceInstances, instances, 1, int64(quota.MaxInstances))
+	}
+	if hosting && quota.MaxHostingInstances > 0 && hostingInstances+1 > int64(quota.MaxHostingInstances) {
+		return quotaExceeded(ctx, UserQuotaResourceHostingInstances, hostingInstances, 1, int64(quota.MaxHostingInstances))
+	}
+	return nil
+}
+
+// CheckPackageQuota 校验当前用户能否再上传 size 字节的代码包
+func (biz *UserQuotaBiz) CheckPackageQuota(ctx context.Context, size int64) error {
+	userID := common.UserIDFromContext(ctx)
+	if userID == 0 {
+		return nil
+	}
+	quota, err := biz.GetQuota(ctx, userID)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeUserQuotaFailure)
+	}
+	if quota.MaxPackageBytes <= 0 {
+		return nil
+	}
+	used, err := biz.packageBytes(ctx, userID)
+	if err != nil {
+		return common.WrapError(err, i18n.CodeUserQuotaFailure)
+	}
+	if used+size > quota.MaxPackageBytes {
+		return quotaExceeded(ctx, UserQuotaResourcePackageBytes, used, size, quota.MaxPackageBytes)
+	}
+	return nil
+}
+
+// quotaExceeded 配额不足的错误，data 中附带已使用量和上限
+func quotaExceeded(ctx context.Context, resource string, used, requested, limit int64) error {
+	return &common.Error{
+		Code: i18n.CodeUserQuotaExceeded,
+		Args: []any{common.ActorFromContext(ctx), resource, used, requested, limit},
+		Data: map[string]any{"resource": resource, "used": used, "requested": requested, "limit": limit},
+	}
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
 
 	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/notify"
 	"qm-mcp-server/pkg/utils"
 	"qm-mcp-server/pkg/version"
 
@@ -25,6 +29,33 @@ type Config struct {
 	Log         common.LogConfig      `mapstructure:"log"`
 	Secret      string                `mapstructure:"secret"`
 	Storage     common.StorageConfig  `mapstructure:"storage"`
+	Image       common.ImageConfig    `mapstructure:"image"`
+	Status      common.StatusConfig   `mapstructure:"status"`
+	PodWatch    common.PodWatchConfig `mapstructure:"podWatch"`
+	// 每个环境同时创建的托管容器数，超出时排队
+	ContainerQueue common.ContainerQueueConfig `mapstructure:"containerQueue"`
+	// 网关对外访问配置，用于动态生成实例访问地址
+	PublicAccess common.PublicAccessConfig `mapstructure:"publicAccess"`
+	// OpenAPI 文档和 Swagger UI，默认关闭
+	OpenAPI common.OpenAPIConfig `mapstructure:"openapi"`
+	// MCP 服务目录，远程目录同步地址
+	Catalog common.CatalogConfig `mapstructure:"catalog"`
+	// 上传图标的大小和尺寸限制
+	Icon common.IconConfig `mapstructure:"icon"`
+	// 直连和代理实例的健康检查，状态变化通知地址
+	HealthMonitor common.HealthMonitorConfig `mapstructure:"healthMonitor"`
+	// 托管实例持久化的容器事件
+	InstanceEvents common.InstanceEventsConfig `mapstructure:"instanceEvents"`
+	// 实例令牌即将过期通知和轮换宽限期
+	TokenExpiry common.TokenExpiryConfig `mapstructure:"tokenExpiry"`
+	// 托管实例镜像漏洞扫描，未配置 trivy 服务时关闭
+	ImageScan common.ImageScanConfig `mapstructure:"imageScan"`
+	// 实例事件邮件通知，未配置 SMTP 服务时关闭
+	Notify common.NotifyConfig `mapstructure:"notify"`
+	// 受保护环境中编辑和删除实例的变更请求
+	ChangeRequest common.ChangeRequestConfig `mapstructure:"changeRequest"`
+	// 实例自定义访问路径修改后旧路径的重定向宽限期
+	Slug common.InstanceSlugConfig `mapstructure:"slug"`
 }
 
 var serviceName = "market"
@@ -84,6 +115,121 @@ func Load() (*Config, error) {
 	}
 	utils.MkdirP(config.Storage.StaticPath)
 
+	if config.Image.CheckTimeout <= 0 {
+		config.Image.CheckTimeout = 10
+	}
+	if config.Image.HostingImage == "" {
+		config.Image.HostingImage = common.DefaultHostingImage
+	}
+	if config.Image.SupergatewayImage == "" {
+		config.Image.SupergatewayImage = common.DefaultSupergatewayImage
+	}
+	if config.Status.BatchMaxInstances <= 0 {
+		config.Status.BatchMaxInstances = 100
+	}
+	if config.Status.BatchConcurrency <= 0 {
+		config.Status.BatchConcurrency = 10
+	}
+	if config.Status.CheckTimeout <= 0 {
+		config.Status.CheckTimeout = 10
+	}
+	if config.Status.BatchDeadline <= 0 {
+		config.Status.BatchDeadline = 15
+	}
+	if config.ContainerQueue.Concurrency <= 0 {
+		config.ContainerQueue.Concurrency = 3
+	}
+	if config.PodWatch.MaxEnvironments <= 0 {
+		config.PodWatch.MaxEnvironments = 20
+	}
+	if config.PodWatch.ResyncInterval <= 0 {
+		config.PodWatch.ResyncInterval = 10
+	}
+	if config.PodWatch.MaxBackoff <= 0 {
+		config.PodWatch.MaxBackoff = 60
+	}
+	if config.Catalog.SyncTimeout <= 0 {
+		config.Catalog.SyncTimeout = 30
+	}
+	if config.Icon.MaxFileSize <= 0 {
+		config.Icon.MaxFileSize = 512
+	}
+	if config.Icon.MinDimension <= 0 {
+		config.Icon.MinDimension = 16
+	}
+	if config.Icon.MaxDimension <= 0 {
+		config.Icon.MaxDimension = 1024
+	}
+	if config.Icon.CleanupGracePeriod <= 0 {
+		config.Icon.CleanupGracePeriod = 24
+	}
+	if config.HealthMonitor.HistorySize <= 0 {
+		config.HealthMonitor.HistorySize = 100
+	}
+	if config.InstanceEvents.HistorySize <= 0 {
+		config.InstanceEvents.HistorySize = 500
+	}
+	if config.HealthMonitor.CheckTimeout <= 0 {
+		config.HealthMonitor.CheckTimeout = 5
+	}
+	if config.HealthMonitor.Concurrency <= 0 {
+		config.HealthMonitor.Concurrency = 10
+	}
+	if config.HealthMonitor.WebhookTimeout <= 0 {
+		config.HealthMonitor.WebhookTimeout = 10
+	}
+	if config.TokenExpiry.WarningDays <= 0 {
+		config.TokenExpiry.WarningDays = 7
+	}
+	if config.TokenExpiry.GracePeriod <= 0 {
+		config.TokenExpiry.GracePeriod = 24
+	}
+	if config.TokenExpiry.WebhookTimeout <= 0 {
+		config.TokenExpiry.WebhookTimeout = 10
+	}
+	if config.Code.WebhookTimeout <= 0 {
+		config.Code.WebhookTimeout = 10
+	}
+	if config.ImageScan.Timeout <= 0 {
+		config.ImageScan.Timeout = 300
+	}
+	if config.Notify.SMTP.Port <= 0 {
+		config.Notify.SMTP.Port = 587
+	}
+	if config.Notify.SMTP.TLS == "" {
+		config.Notify.SMTP.TLS = "starttls"
+	}
+	if config.Notify.SMTP.Timeout <= 0 {
+		config.Notify.SMTP.Timeout = 30
+	}
+	if config.Notify.ConsoleURL == "" {
+		config.Notify.ConsoleURL = config.Domain
+	}
+	if config.Notify.DigestInterval <= 0 {
+		config.Notify.DigestInterval = 60
+	}
+	if config.Notify.MaxRetries <= 0 {
+		config.Notify.MaxRetries = 3
+	}
+	if config.ChangeRequest.ExpireHours <= 0 {
+		config.ChangeRequest.ExpireHours = 72
+	}
+	if config.Notify.Feishu.BaseURL == "" {
+		config.Notify.Feishu.BaseURL = notify.FeishuBaseURL
+	}
+	if config.Notify.Feishu.UserIDType == "" {
+		config.Notify.Feishu.UserIDType = notify.FeishuReceiveOpenID
+	}
+	if config.Notify.Feishu.RatePerSecond <= 0 {
+		config.Notify.Feishu.RatePerSecond = 4
+	}
+	if config.Notify.Feishu.Timeout <= 0 {
+		config.Notify.Feishu.Timeout = 10
+	}
+	common.SetHostingImage(config.Image.HostingImage)
+	common.SetPublicAccess(config.PublicAccess, config.Domain)
+	common.SetTokenExpiry(config.TokenExpiry)
+
 	// 追加 Version 信息
 	config.ServiceName = serviceName
 	config.VersionInfo = version.GetVersionInfo()
@@ -92,3 +238,74 @@ func Load() (*Config, error) {
 
 	return &config, nil
 }
+
+// Validate 检查启动必需的配置，一次返回所有问题及其配置路径
+func (c *Config) Validate() error {
+	v := &common.ConfigValidator{}
+	v.Port("server.httpPort", c.Server.HttpPort)
+	v.Required("secret", c.Secret)
+	v.MySQL("database.mysql", c.Database.MySQL)
+	v.Redis("database.redis", c.Database.Redis)
+	// 生成代码包下载链接等场景需要访问自身服务地址
+	v.Service("services.mcpMarket", c.Services.McpMarket)
+	v.WritableDir("storage.rootPath", c.Storage.RootPath)
+	v.WritableDir("storage.codePath", c.Storage.CodePath)
+	v.WritableDir("storage.staticPath", c.Storage.StaticPath)
+	v.CodeBackend("storage.codeBackend", c.Storage.CodeBackend)
+	v.TrustedProxies("publicAccess.trustedProxies", c.PublicAccess.TrustedProxies)
+	v.Positive("code.upload.maxFileSize", int64(c.Code.Upload.MaxFileSize))
+	if len(c.Code.Upload.AllowedExtensions) == 0 {
+		v.Addf("code.upload.allowedExtensions", "must list at least one extension")
+	}
+	if c.Catalog.RegistryURL != "" {
+		if u, err := url.Parse(c.Catalog.RegistryURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf("catalog.registryURL", "must be an http or https URL")
+		}
+	}
+	for i, webhook := range c.HealthMonitor.Webhooks {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf(fmt.Sprintf("healthMonitor.webhooks[%d]", i), "must be an http or https URL")
+		}
+	}
+	for i, webhook := range c.TokenExpiry.Webhooks {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf(fmt.Sprintf("tokenExpiry.webhooks[%d]", i), "must be an http or https URL")
+		}
+	}
+	for i, webhook := range c.Code.Webhooks {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf(fmt.Sprintf("code.webhooks[%d]", i), "must be an http or https URL")
+		}
+	}
+	if c.ImageScan.TrivyServer != "" {
+		if u, err := url.Parse(c.ImageScan.TrivyServer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf("imageScan.trivyServer", "must be an http or https URL")
+		}
+	}
+	if c.Notify.SMTP.Host != "" {
+		if _, err := mail.ParseAddress(c.Notify.SMTP.From); err != nil {
+			v.Addf("notify.smtp.from", "must be an email address")
+		}
+		if !slices.Contains([]string{notify.TLSModeStartTLS, notify.TLSModeTLS, notify.TLSModeNone}, c.Notify.SMTP.TLS) {
+			v.Addf("notify.smtp.tls", "must be one of starttls, tls, none")
+		}
+	}
+	if c.Notify.Feishu.AppID != "" {
+		if c.Notify.Feishu.AppSecret == "" {
+			v.Addf("notify.feishu.appSecret", "is required when notify.feishu.appID is set")
+		}
+		if !slices.Contains([]string{notify.FeishuReceiveOpenID, notify.FeishuReceiveUnionID, notify.FeishuReceiveUserID}, c.Notify.Feishu.UserIDType) {
+			v.Addf("notify.feishu.userIDType", "must be one of open_id, union_id, user_id")
+		}
+		if u, err := url.Parse(c.Notify.Feishu.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Addf("notify.feishu.baseURL", "must be an http or https URL")
+		}
+	}
+	if c.Slug.RedirectPeriod < 0 {
+		v.Addf("slug.redirectPeriod", "must not be negative")
+	}
+	if c.Icon.MinDimension > c.Icon.MaxDimension {
+		v.Addf("icon.minDimension", "must not be greater than icon.maxDimension")
+	}
+	return v.Err()
+}
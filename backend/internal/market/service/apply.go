@@ -0,0 +1,646 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	i18nresp "qm-mcp-server/pkg/i18n"
+)
+
+// 资源类型
+const (
+	applyKindTemplate = "template"
+	applyKindInstance = "instance"
+)
+
+// 执行动作
+const (
+	applyActionCreate    = "create"
+	applyActionUpdate    = "update"
+	applyActionDelete    = "delete"
+	applyActionUnchanged = "unchanged"
+)
+
+// applyServerManagedFields 服务端维护的字段，清单中声明时忽略
+var applyServerManagedFields = []string{
+	"instanceId", "templateId", "status", "containerName", "containerServiceName", "containerIsReady",
+	"containerStatus", "containerLastMessage", "publicProxyConfig", "servers",
+	"createdAt", "updatedAt", "createdAtMs", "updatedAtMs", "environmentName",
+}
+
+// applyInstanceCreateOnlyFields 实例详情不返回的字段，创建或更新时随请求提交，不参与比对
+var applyInstanceCreateOnlyFields = []string{"startupTimeout", "runningTimeout"}
+
+// applyInstanceImmutableFields 实例创建后不能通过编辑修改的字段
+var applyInstanceImmutableFields = map[string]bool{
+	"accessType": true, "mcpProtocol": true, "environmentId": true,
+	"mcpServerId": true, "sourceType": true, "tokens": true,
+}
+
+// applyEnumFields 枚举字段，清单中可以使用枚举名称代替数值
+var applyEnumFields = map[string]map[string]int32{
+	"accessType":  instancepb.AccessType_value,
+	"mcpProtocol": instancepb.McpProtocol_value,
+	"sourceType":  instancepb.SourceType_value,
+}
+
+// ApplyService 按清单声明式管理模板和实例
+type ApplyService struct {
+	ctx       context.Context
+	instances *InstanceService
+	templates *TemplateService
+}
+
+// NewApplyService creates a new ApplyService instance
+func NewApplyService(ctx context.Context) *ApplyService {
+	return &ApplyService{
+		ctx:       ctx,
+		instances: NewInstanceService(ctx),
+		templates: NewTemplateService(ctx),
+	}
+}
+
+// applyManifest 声明式清单，YAML 和 JSON 格式均可
+type applyManifest struct {
+	DryRun    bool             `yaml:"dryRun"`
+	Prune     bool             `yaml:"prune"`
+	Templates []map[string]any `yaml:"templates"`
+	Instances []map[string]any `yaml:"instances"`
+}
+
+// applyEntry 清单中声明的模板或实例，按名称与现有资源匹配
+type applyEntry struct {
+	name string
+	// template 实例基于的模板名称
+	template string
+	// environment 环境名称，解析为 environmentId
+	environment string
+	// fields 清单中声明的字段，已转换为 JSON 语义，只比对这些字段
+	fields map[string]any
+}
+
+// ApplyHandler 声明式应用HTTP处理函数，请求体为清单，查询参数 dryRun 和 prune 优先于清单中的同名字段
+func (s *ApplyService) ApplyHandler(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeApplyManifestInvalid, err.Error()))
+		return
+	}
+	manifest, err := parseApplyManifest(data)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	req := instancepb.ApplyRequest{DryRun: manifest.DryRun, Prune: manifest.Prune}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.GinErrorFrom(c, common.ErrValidation("dryRun/prune", err.Error()))
+		return
+	}
+
+	templates, err := parseApplyEntries(applyKindTemplate, manifest.Templates)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	instances, err := parseApplyEntries(applyKindInstance, manifest.Instances)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	result, err := s.apply(c, &req, templates, instances)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// apply 比对清单与现有资源并执行，模板先于实例处理，dryRun 时只返回执行计划
+func (s *ApplyService) apply(ctx context.Context, req *instancepb.ApplyRequest, templates, instances []*applyEntry) (*instancepb.ApplyResp, error) {
+	resp := &instancepb.ApplyResp{DryRun: req.DryRun, Prune: req.Prune}
+
+	existingTemplates, err := s.templates.templateData.GetAllTemplates(ctx)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
+	}
+	templateByName := make(map[string]*model.McpTemplate, len(existingTemplates))
+	for _, template := range existingTemplates {
+		templateByName[template.Name] = template
+	}
+	existingInstances, err := mysql.McpInstanceRepo.FindAll(ctx)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceQueryFailure)
+	}
+	instanceByName := make(map[string]*model.McpInstance, len(existingInstances))
+	for _, instance := range existingInstances {
+		instanceByName[instance.InstanceName] = instance
+	}
+
+	declaredTemplates := make(map[string]*applyEntry, len(templates))
+	for _, entry := range templates {
+		declaredTemplates[entry.name] = entry
+		resp.Actions = append(resp.Actions, s.applyTemplate(ctx, entry, templateByName[entry.name], req.DryRun))
+	}
+	declaredInstances := make(map[string]bool, len(instances))
+	for _, entry := range instances {
+		declaredInstances[entry.name] = true
+		resp.Actions = append(resp.Actions, s.applyInstance(ctx, entry, instanceByName[entry.name], declaredTemplates, req.DryRun))
+	}
+
+	// 删除清单中未声明的资源，先删除实例再删除模板
+	if req.Prune {
+		sort.Slice(existingInstances, func(i, j int) bool { return existingInstances[i].InstanceName < existingInstances[j].InstanceName })
+		for _, instance := range existingInstances {
+			if declaredInstances[instance.InstanceName] {
+				continue
+			}
+			action := &instancepb.ApplyAction{Kind: applyKindInstance, Name: instance.InstanceName, Action: applyActionDelete, Id: instance.InstanceID}
+			if !req.DryRun {
+				if _, err := s.instances.delete(ctx, instance.InstanceID); err != nil {
+					action.Error = applyErrorMessage(ctx, err)
+				}
+			}
+			resp.Actions = append(resp.Actions, action)
+		}
+		sort.Slice(existingTemplates, func(i, j int) bool { return existingTemplates[i].Name < existingTemplates[j].Name })
+		for _, template := range existingTemplates {
+			if declaredTemplates[template.Name] != nil {
+				continue
+			}
+			action := &instancepb.ApplyAction{Kind: applyKindTemplate, Name: template.Name, Action: applyActionDelete, Id: strconv.Itoa(int(template.ID))}
+			if !req.DryRun {
+				if _, err := s.templates.TemplateDelete(ctx, &instancepb.TemplateDeleteRequest{TemplateId: int32(template.ID)}); err != nil {
+					action.Error = applyErrorMessage(ctx, err)
+				}
+			}
+			resp.Actions = append(resp.Actions, action)
+		}
+	}
+
+	for _, action := range resp.Actions {
+		if action.Error != "" {
+			resp.Failed++
+			continue
+		}
+		switch action.Action {
+		case applyActionCreate:
+			resp.Created++
+		case applyActionUpdate:
+			resp.Updated++
+		case applyActionDelete:
+			resp.Deleted++
+		case applyActionUnchanged:
+			resp.Unchanged++
+		}
+	}
+	return resp, nil
+}
+
+// applyTemplate 创建或更新单个模板
+func (s *ApplyService) applyTemplate(ctx context.Context, entry *applyEntry, current *model.McpTemplate, dryRun bool) *instancepb.ApplyAction {
+	action := &instancepb.ApplyAction{Kind: applyKindTemplate, Name: entry.name, Action: applyActionCreate}
+	if current != nil {
+		action.Action = applyActionUpdate
+		action.Id = strconv.Itoa(int(current.ID))
+	}
+	fields, err := s.resolveEnvironment(ctx, entry)
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+
+	if current == nil {
+		if dryRun {
+			return action
+		}
+		var req instancepb.TemplateCreateRequest
+		if err := decodeApplyFields(fields, &req); err != nil {
+			action.Error = applyErrorMessage(ctx, err)
+			return action
+		}
+		resp, err := s.templates.TemplateCreate(ctx, &req)
+		if err != nil {
+			action.Error = applyErrorMessage(ctx, err)
+			return action
+		}
+		action.Id = strconv.Itoa(int(resp.TemplateId))
+		return action
+	}
+
+	detail, err := s.templates.TemplateDetail(ctx, &instancepb.TemplateDetailRequest{TemplateId: int32(current.ID)})
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	currentFields, err := toApplyFields(detail)
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	action.Changes = diffApplyFields(fields, currentFields, nil)
+	if len(action.Changes) == 0 {
+		action.Action = applyActionUnchanged
+		return action
+	}
+	if dryRun {
+		return action
+	}
+
+	var req instancepb.TemplateEditRequest
+	if err := decodeApplyFields(mergeApplyFields(currentFields, fields), &req); err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	req.TemplateId = int32(current.ID)
+	if _, err := s.templates.TemplateEdit(ctx, &req); err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+	}
+	return action
+}
+
+// applyInstance 创建或更新单个实例，访问类型等创建后不可修改的字段变化时报错
+func (s *ApplyService) applyInstance(ctx context.Context, entry *applyEntry, current *model.McpInstance, templates map[string]*applyEntry, dryRun bool) *instancepb.ApplyAction {
+	action := &instancepb.ApplyAction{Kind: applyKindInstance, Name: entry.name, Action: applyActionCreate}
+	if current != nil {
+		action.Action = applyActionUpdate
+		action.Id = current.InstanceID
+	}
+	fields, err := s.resolveEnvironment(ctx, entry)
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+
+	if current == nil {
+		req, err := s.instanceCreateRequest(ctx, entry, fields, templates, dryRun)
+		if err != nil {
+			action.Error = applyErrorMessage(ctx, err)
+			return action
+		}
+		if dryRun {
+			return action
+		}
+		resp, err := s.instances.create(ctx, req)
+		if err != nil {
+			action.Error = applyErrorMessage(ctx, err)
+			return action
+		}
+		action.Id = resp.InstanceId
+		return action
+	}
+
+	detail, err := s.instances.buildDetail(current, "", false)
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	currentFields, err := toApplyFields(detail)
+	if err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	action.Changes = diffApplyFields(fields, currentFields, applyInstanceCreateOnlyFields)
+	if len(action.Changes) == 0 {
+		action.Action = applyActionUnchanged
+		return action
+	}
+	var immutable []string
+	for _, field := range action.Changes {
+		if applyInstanceImmutableFields[field] {
+			immutable = append(immutable, field)
+		}
+	}
+	if len(immutable) > 0 {
+		action.Error = i18nresp.FormatWithContext(ctx, i18nresp.CodeApplyFieldImmutable, strings.Join(immutable, ", "))
+		return action
+	}
+	if dryRun {
+		return action
+	}
+
+	var req instancepb.EditRequest
+	if err := decodeApplyFields(mergeApplyFields(currentFields, fields), &req); err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+		return action
+	}
+	req.InstanceId = current.InstanceID
+	// 清单是期望状态，覆盖集群中对运行容器的手动修改
+	req.Force = true
+	if _, err := s.instances.edit(ctx, &req); err != nil {
+		action.Error = applyErrorMessage(ctx, err)
+	}
+	return action
+}
+
+// instanceCreateRequest 构建实例创建请求，声明了模板时以模板配置为基础，清单字段覆盖模板字段
+func (s *ApplyService) instanceCreateRequest(ctx context.Context, entry *applyEntry, fields map[string]any, templates map[string]*applyEntry, dryRun bool) (*instancepb.CreateRequest, error) {
+	base := map[string]any{"sourceType": float64(instancepb.SourceType_CUSTOM)}
+	if entry.template != "" {
+		var err error
+		if base, err = s.templateFields(ctx, entry.template, templates, dryRun); err != nil {
+			return nil, err
+		}
+		base["sourceType"] = float64(instancepb.SourceType_TEMPLATE)
+	}
+
+	var req instancepb.CreateRequest
+	if err := decodeApplyFields(mergeApplyFields(base, fields), &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// templateFields 模板配置，dryRun 时模板可能尚未创建，使用清单中声明的模板字段
+func (s *ApplyService) templateFields(ctx context.Context, name string, templates map[string]*applyEntry, dryRun bool) (map[string]any, error) {
+	template, err := s.templates.templateData.GetTemplateByName(ctx, name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
+	}
+	if template == nil {
+		if entry, ok := templates[name]; ok && dryRun {
+			return mergeApplyFields(nil, entry.fields), nil
+		}
+		return nil, common.ErrTemplateNotFound(name)
+	}
+
+	detail, err := s.templates.TemplateDetail(ctx, &instancepb.TemplateDetailRequest{TemplateId: int32(template.ID)})
+	if err != nil {
+		return nil, err
+	}
+	fields, err := toApplyFields(detail)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range applyServerManagedFields {
+		delete(fields, field)
+	}
+	fields["templateId"] = float64(template.ID)
+	return fields, nil
+}
+
+// resolveEnvironment 将清单中的环境名称解析为 environmentId
+func (s *ApplyService) resolveEnvironment(ctx context.Context, entry *applyEntry) (map[string]any, error) {
+	if entry.environment == "" {
+		return entry.fields, nil
+	}
+	environment, err := biz.GEnvironmentBiz.GetEnvironmentByName(ctx, entry.environment)
+	if err != nil || environment == nil {
+		return nil, common.NewError(i18nresp.CodeApplyEnvironmentNotFound, entry.environment)
+	}
+	return mergeApplyFields(entry.fields, map[string]any{"environmentId": float64(environment.ID)}), nil
+}
+
+// parseApplyManifest 解析 YAML 或 JSON 清单
+func parseApplyManifest(data []byte) (*applyManifest, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, common.NewError(i18nresp.CodeApplyManifestInvalid, "manifest is empty")
+	}
+	var manifest applyManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, common.NewError(i18nresp.CodeApplyManifestInvalid, err.Error())
+	}
+	return &manifest, nil
+}
+
+// parseApplyEntries 校验并规范化清单条目：名称必填且唯一，忽略服务端维护的字段，
+// 枚举名称转换为数值，对象形式的 mcpServers 转换为 JSON 字符串
+func parseApplyEntries(kind string, items []map[string]any) ([]*applyEntry, error) {
+	entries := make([]*applyEntry, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		invalid := func(format string, args ...any) error {
+			return common.NewError(i18nresp.CodeApplyManifestInvalid, fmt.Sprintf("%ss[%d]: ", kind, i)+fmt.Sprintf(format, args...))
+		}
+
+		name, _ := item["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return nil, invalid("name is required")
+		}
+		if seen[name] {
+			return nil, invalid("duplicate name %s", name)
+		}
+		seen[name] = true
+
+		entry := &applyEntry{name: name, fields: make(map[string]any, len(item))}
+		for key, value := range item {
+			entry.fields[key] = value
+		}
+		for _, key := range []string{"template", "environment"} {
+			value, ok := entry.fields[key]
+			if !ok {
+				continue
+			}
+			str, ok := value.(string)
+			if !ok {
+				return nil, invalid("%s must be a name", key)
+			}
+			if key == "template" {
+				if kind != applyKindInstance {
+					return nil, invalid("template is only supported for instances")
+				}
+				entry.template = str
+			} else {
+				entry.environment = str
+			}
+			delete(entry.fields, key)
+		}
+		for _, field := range applyServerManagedFields {
+			delete(entry.fields, field)
+		}
+		for field, values := range applyEnumFields {
+			str, ok := entry.fields[field].(string)
+			if !ok {
+				continue
+			}
+			value, ok := applyEnumValue(values, str)
+			if !ok {
+				return nil, invalid("unknown %s %s", field, str)
+			}
+			entry.fields[field] = value
+		}
+		if servers, ok := entry.fields["mcpServers"]; ok && servers != nil {
+			if _, isString := servers.(string); !isString {
+				data, err := json.Marshal(servers)
+				if err != nil {
+					return nil, invalid("mcpServers: %v", err)
+				}
+				entry.fields["mcpServers"] = string(data)
+			}
+		}
+
+		// 转换为 JSON 语义并按创建请求校验字段名和类型
+		data, err := json.Marshal(entry.fields)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		var target any = &instancepb.CreateRequest{}
+		if kind == applyKindTemplate {
+			target = &instancepb.TemplateCreateRequest{}
+		}
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(target); err != nil {
+			return nil, invalid("%v", err)
+		}
+		entry.fields = nil
+		if err := json.Unmarshal(data, &entry.fields); err != nil {
+			return nil, invalid("%v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// applyEnumValue 按名称查找枚举值，忽略大小写，STREAMABLE_HTTP 为 STEAMABLE_HTTP 的别名
+func applyEnumValue(values map[string]int32, name string) (float64, bool) {
+	if strings.EqualFold(name, "STREAMABLE_HTTP") {
+		name = instancepb.McpProtocol_STEAMABLE_HTTP.String()
+	}
+	for key, value := range values {
+		if strings.EqualFold(key, name) {
+			return float64(value), true
+		}
+	}
+	return 0, false
+}
+
+// diffApplyFields 返回清单字段中与现有资源不同的字段，按字段名排序
+func diffApplyFields(desired, current map[string]any, skip []string) []string {
+	var changes []string
+	for key, want := range desired {
+		if key == "name" || slices.Contains(skip, key) {
+			continue
+		}
+		if !applyFieldEqual(key, want, current[key]) {
+			changes = append(changes, key)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// applyFieldEqual 比较字段值，mcpServers 按 JSON 内容比较
+func applyFieldEqual(key string, want, got any) bool {
+	if key == "mcpServers" {
+		wantStr, _ := want.(string)
+		gotStr, _ := got.(string)
+		var wantJSON, gotJSON any
+		if json.Unmarshal([]byte(wantStr), &wantJSON) == nil && json.Unmarshal([]byte(gotStr), &gotJSON) == nil {
+			return reflect.DeepEqual(wantJSON, gotJSON)
+		}
+		return wantStr == gotStr
+	}
+	return applyValueMatches(want, got, false)
+}
+
+// applyValueMatches 比较清单值与现有值，零值与缺失视为相同；
+// 数组中的对象只比较清单中声明的键，避免令牌发布时间等服务端填充的字段产生差异
+func applyValueMatches(want, got any, subset bool) bool {
+	if isApplyZero(want) && isApplyZero(got) {
+		return true
+	}
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok || (!subset && len(w) != len(g)) {
+			return false
+		}
+		for key, value := range w {
+			if !applyValueMatches(value, g[key], subset) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		for i := range w {
+			if !applyValueMatches(w[i], g[i], true) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func isApplyZero(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case float64:
+		return value == 0
+	case bool:
+		return !value
+	case []any:
+		return len(value) == 0
+	case map[string]any:
+		return len(value) == 0
+	}
+	return false
+}
+
+// mergeApplyFields 以 base 为基础覆盖 overlay 中的字段，返回新的 map
+func mergeApplyFields(base, overlay map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(overlay))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	return merged
+}
+
+// toApplyFields 将响应结构转换为 JSON 语义的字段
+func toApplyFields(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// decodeApplyFields 将字段解码为请求结构
+func decodeApplyFields(fields map[string]any, out any) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// applyErrorMessage 本地化的错误信息
+func applyErrorMessage(ctx context.Context, err error) string {
+	if e, ok := common.AsError(err); ok {
+		return i18nresp.FormatWithContext(ctx, e.Code, e.Args...)
+	}
+	return err.Error()
+}
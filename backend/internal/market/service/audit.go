@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	auditpb "qm-mcp-server/api/market/audit"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+)
+
+// auditLogCSVHeader 审计日志 CSV 导出的列，调整时只能在末尾追加
+var auditLogCSVHeader = []string{"id", "createdAt", "actor", "action", "resourceType", "resourceId", "requestId", "detail"}
+
+// AuditService 审计日志服务，仅管理员可用
+type AuditService struct {
+	ctx context.Context
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(ctx context.Context) *AuditService {
+	return &AuditService{
+		ctx: ctx,
+	}
+}
+
+// ListAuditLogsHandler 按时间从新到旧查询审计日志，format=csv 或 Accept: text/csv 时导出 CSV
+func (s *AuditService) ListAuditLogsHandler(c *gin.Context) {
+	var req auditpb.ListAuditLogsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	// 格式已由校验器检查
+	cursor, _ := common.DecodePageToken(req.PageToken)
+	filter := mysql.SysAuditLogFilter{
+		Actor:        req.Actor,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceId,
+		BeforeID:     uint(cursor),
+	}
+	if req.From != "" {
+		filter.From, _ = time.Parse(time.RFC3339, req.From)
+	}
+	if req.To != "" {
+		filter.To, _ = time.Parse(time.RFC3339, req.To)
+	}
+	csv := common.WantsCSV(c, req.Format)
+	limit := exportPageSize(req.PageSize, csv)
+
+	ctx := c.Request.Context()
+	logs, err := biz.GAuditBiz.List(ctx, filter, limit+1)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var nextPageToken string
+	if len(logs) > limit {
+		logs = logs[:limit]
+		nextPageToken = common.EncodePageToken(int64(logs[len(logs)-1].ID))
+	}
+
+	if csv {
+		fileName := fmt.Sprintf("audit-logs-%s.csv", time.Now().Format("20060102150405"))
+		err := common.GinCSV(c, fileName, auditLogCSVHeader, nextPageToken, func(write func(record ...string) error) error {
+			for _, log := range logs {
+				if err := write(strconv.FormatUint(uint64(log.ID), 10), common.FormatTimeRFC3339(ctx, log.CreatedAt),
+					log.Actor, log.Action, log.ResourceType, log.ResourceID, log.RequestID, string(log.Detail)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Warn("Failed to export audit logs", zap.Error(err))
+		}
+		return
+	}
+
+	resp := &auditpb.ListAuditLogsResp{
+		List:          make([]*auditpb.AuditLog, 0, len(logs)),
+		NextPageToken: nextPageToken,
+	}
+	for _, log := range logs {
+		resp.List = append(resp.List, &auditpb.AuditLog{
+			Id:           int64(log.ID),
+			Actor:        log.Actor,
+			Action:       log.Action,
+			ResourceType: log.ResourceType,
+			ResourceId:   log.ResourceID,
+			Detail:       string(log.Detail),
+			RequestId:    log.RequestID,
+			CreatedAt:    common.FormatTimeRFC3339(ctx, log.CreatedAt),
+		})
+	}
+	common.GinSuccess(c, resp)
+}
+
+// exportPageSize 分页查询的每页数量，CSV 导出默认且最多 common.MaxCSVRows 行
+func exportPageSize(pageSize int32, csv bool) int {
+	maxSize, defaultSize := common.MaxPageSize, common.DefaultPageSize
+	if csv {
+		maxSize, defaultSize = common.MaxCSVRows, common.MaxCSVRows
+	}
+	if pageSize <= 0 {
+		return defaultSize
+	}
+	return min(int(pageSize), maxSize)
+}
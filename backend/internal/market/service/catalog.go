@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	catalogpb "qm-mcp-server/api/market/catalog"
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/catalog"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	i18nresp "qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+)
+
+const (
+	// catalogTargetTemplate 安装目录条目时创建模板
+	catalogTargetTemplate = "template"
+	// catalogTargetInstance 安装目录条目时创建实例
+	catalogTargetInstance = "instance"
+)
+
+// CatalogService provides MCP server catalog browsing and one-click install
+type CatalogService struct {
+	ctx       context.Context
+	instances *InstanceService
+	templates *TemplateService
+}
+
+// NewCatalogService creates a new CatalogService instance
+func NewCatalogService(ctx context.Context) *CatalogService {
+	return &CatalogService{
+		ctx:       ctx,
+		instances: NewInstanceService(ctx),
+		templates: NewTemplateService(ctx),
+	}
+}
+
+// catalogQueryError 转换目录查询错误，条目不存在时返回 404
+func catalogQueryError(err error, id string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return common.NewError(i18nresp.CodeCatalogEntryNotFound, id)
+	}
+	return common.WrapError(err, i18nresp.CodeCatalogQueryFailure)
+}
+
+// toCatalogEnvVars converts the env schema of a catalog entry to the install form schema
+func toCatalogEnvVars(vars []model.CatalogEnvVar) []*catalogpb.CatalogEnvVar {
+	list := make([]*catalogpb.CatalogEnvVar, 0, len(vars))
+	for _, env := range vars {
+		list = append(list, &catalogpb.CatalogEnvVar{
+			Name:         env.Name,
+			Description:  env.Description,
+			Required:     env.Required,
+			Secret:       env.Secret,
+			DefaultValue: env.Default,
+		})
+	}
+	return list
+}
+
+// modelToCatalogEntryInfo converts model to catalog entry info
+func modelToCatalogEntryInfo(ctx context.Context, entry *model.McpCatalogEntry) *catalogpb.CatalogEntryInfo {
+	// 环境变量在同步时已校验，解析失败时只返回空表单
+	vars, _ := entry.EnvVars()
+	return &catalogpb.CatalogEntryInfo{
+		Id:            entry.CatalogID,
+		Name:          entry.Name,
+		Description:   entry.Description,
+		Category:      entry.Category,
+		IconUrl:       entry.IconURL,
+		RepositoryUrl: entry.RepositoryURL,
+		Version:       entry.Version,
+		AccessType:    string(entry.AccessType),
+		McpProtocol:   string(entry.McpProtocol),
+		ImgAddress:    entry.ImgAddress,
+		Command:       entry.Command,
+		Port:          entry.Port,
+		ServicePath:   entry.ServicePath,
+		McpServers:    string(entry.McpServers),
+		Env:           toCatalogEnvVars(vars),
+		Source:        string(entry.Source),
+		CreatedAt:     common.FormatTimeRFC3339(ctx, entry.CreatedAt),
+		UpdatedAt:     common.FormatTimeRFC3339(ctx, entry.UpdatedAt),
+		CreatedAtMs:   common.TimeMillis(entry.CreatedAt),
+		UpdatedAtMs:   common.TimeMillis(entry.UpdatedAt),
+		InstallCount:  entry.InstallCount,
+		RatingCount:   entry.RatingCount,
+		RatingAvg:     entry.RatingAvg,
+		Verified:      entry.Verified,
+		DisplayOrder:  entry.DisplayOrder,
+	}
+}
+
+// ListCatalogHandler handles catalog list and search requests
+func (s *CatalogService) ListCatalogHandler(c *gin.Context) {
+	var req catalogpb.ListCatalogRequest
+	if err := common.BindAndValidateQuery(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.ListCatalog(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ListCatalog lists catalog entries matching the keyword and category
+func (s *CatalogService) ListCatalog(ctx context.Context, req *catalogpb.ListCatalogRequest) (*catalogpb.ListCatalogResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = common.DefaultPageSize
+	}
+	if req.PageSize > common.MaxPageSize {
+		req.PageSize = common.MaxPageSize
+	}
+
+	entries, total, err := biz.GCatalogBiz.ListEntries(s.ctx, strings.TrimSpace(req.Keyword), req.Category, req.VerifiedOnly, req.SortBy, req.Page, req.PageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeCatalogQueryFailure)
+	}
+
+	list := make([]*catalogpb.CatalogEntryInfo, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, modelToCatalogEntryInfo(ctx, entry))
+	}
+
+	return &catalogpb.ListCatalogResponse{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// ListCatalogCategoriesHandler handles catalog category list requests
+func (s *CatalogService) ListCatalogCategoriesHandler(c *gin.Context) {
+	categories, err := biz.GCatalogBiz.Categories(s.ctx)
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeCatalogQueryFailure))
+		return
+	}
+
+	list := make([]*catalogpb.CatalogCategory, 0, len(categories))
+	for _, category := range categories {
+		list = append(list, &catalogpb.CatalogCategory{Name: category.Category, Count: category.Count})
+	}
+	common.GinSuccess(c, &catalogpb.ListCatalogCategoriesResponse{List: list})
+}
+
+// GetCatalogEntryHandler handles catalog entry detail requests
+func (s *CatalogService) GetCatalogEntryHandler(c *gin.Context) {
+	id := c.Param("id")
+	entry, err := biz.GCatalogBiz.GetEntry(s.ctx, id)
+	if err != nil {
+		common.GinErrorFrom(c, catalogQueryError(err, id))
+		return
+	}
+
+	common.GinSuccess(c, modelToCatalogEntryInfo(c.Request.Context(), entry))
+}
+
+// InstallCatalogEntryHandler handles catalog install requests
+func (s *CatalogService) InstallCatalogEntryHandler(c *gin.Context) {
+	var req catalogpb.InstallCatalogRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	req.Id = c.Param("id")
+
+	result, err := s.InstallCatalogEntry(c, &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// InstallCatalogEntry creates a template or instance pre-filled from the catalog entry,
+// the created template or instance links back to the entry through its mcpServerId
+func (s *CatalogService) InstallCatalogEntry(c *gin.Context, req *catalogpb.InstallCatalogRequest) (*catalogpb.InstallCatalogResponse, error) {
+	ctx := c.Request.Context()
+	if req.Target == "" {
+		req.Target = catalogTargetTemplate
+	}
+
+	entry, err := biz.GCatalogBiz.GetEntry(s.ctx, req.Id)
+	if err != nil {
+		return nil, catalogQueryError(err, req.Id)
+	}
+	vars, err := entry.EnvVars()
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeCatalogQueryFailure)
+	}
+	accessType, err := common.ConvertToProtoAccessType(entry.AccessType)
+	if err != nil {
+		return nil, common.NewError(i18nresp.CodeCatalogUnsupportedAccessType, entry.CatalogID, entry.AccessType)
+	}
+	mcpProtocol, err := common.ConvertToProtoMcpProtocol(entry.McpProtocol)
+	if err != nil {
+		return nil, common.NewError(i18nresp.CodeUnsupportedMcpProtocol, entry.McpProtocol)
+	}
+
+	values, missing := catalog.ResolveEnv(vars, req.Env)
+	if req.Target == catalogTargetInstance {
+		// 实例创建后立即启动，必填的环境变量必须提供，未填写的可选变量替换为空值
+		if len(missing) > 0 {
+			return nil, common.NewError(i18nresp.CodeCatalogEnvRequired, strings.Join(missing, ", "))
+		}
+		for _, env := range vars {
+			if _, ok := values[env.Name]; !ok {
+				values[env.Name] = ""
+			}
+		}
+	}
+	// 模板中未填写的变量保留 ${NAME} 占位符，基于模板创建实例时再填写
+	mcpServers := string(catalog.ExpandMcpServers(entry.McpServers, values))
+	// 托管实例同时以容器环境变量的形式注入
+	var envs map[string]string
+	imgAddress := entry.ImgAddress
+	if entry.AccessType == model.AccessTypeHosting {
+		envs = make(map[string]string, len(values))
+		for name, value := range values {
+			if value != "" {
+				envs[name] = value
+			}
+		}
+		if imgAddress == "" {
+			imgAddress = s.defaultHostingImage(ctx, uint(req.EnvironmentId))
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = entry.Name
+	}
+	notes := req.Notes
+	if notes == "" {
+		notes = entry.Description
+	}
+	resp := &catalogpb.InstallCatalogResponse{
+		CatalogId: entry.CatalogID,
+		Target:    req.Target,
+		Name:      name,
+		Env:       toCatalogEnvVars(vars),
+	}
+
+	if req.Target == catalogTargetTemplate {
+		templateReq := &instancepb.TemplateCreateRequest{
+			Name:                 name,
+			Port:                 entry.Port,
+			Command:              entry.Command,
+			EnvironmentVariables: envs,
+			EnvironmentId:        req.EnvironmentId,
+			AccessType:           accessType,
+			McpServers:           mcpServers,
+			ImgAddress:           imgAddress,
+			McpServerId:          entry.CatalogID,
+			Notes:                notes,
+			McpProtocol:          mcpProtocol,
+			IconPath:             entry.IconURL,
+		}
+		if err := common.ValidateRequest(templateReq); err != nil {
+			return nil, err
+		}
+		result, err := s.templates.TemplateCreate(ctx, templateReq)
+		if err != nil {
+			return nil, err
+		}
+		resp.TemplateId = result.TemplateId
+		resp.MissingEnv = missing
+		s.recordInstall(ctx, entry.CatalogID)
+		return resp, nil
+	}
+
+	instanceReq := &instancepb.CreateRequest{
+		Name:                 name,
+		Port:                 entry.Port,
+		Command:              entry.Command,
+		EnvironmentVariables: envs,
+		EnvironmentId:        req.EnvironmentId,
+		AccessType:           accessType,
+		McpServers:           mcpServers,
+		ImgAddress:           imgAddress,
+		SourceType:           instancepb.SourceType_MARKET,
+		McpServerId:          entry.CatalogID,
+		Notes:                notes,
+		McpProtocol:          mcpProtocol,
+		ServicePath:          entry.ServicePath,
+		IconPath:             entry.IconURL,
+	}
+	if err := common.ValidateRequest(instanceReq); err != nil {
+		return nil, err
+	}
+	result, err := s.instances.create(ctx, instanceReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.InstanceId = result.InstanceId
+	s.recordInstall(ctx, entry.CatalogID)
+	return resp, nil
+}
+
+// recordInstall 安装次数只用于排序展示，更新失败不影响已创建的模板或实例
+func (s *CatalogService) recordInstall(ctx context.Context, catalogID string) {
+	if err := biz.GCatalogBiz.RecordInstall(ctx, catalogID); err != nil {
+		logger.Warn("Failed to record catalog install", zap.String("catalogId", catalogID), zap.Error(err))
+	}
+}
+
+// defaultHostingImage 目录条目未指定镜像时使用环境覆盖的托管镜像，未覆盖时使用全局配置
+func (s *CatalogService) defaultHostingImage(ctx context.Context, environmentID uint) string {
+	if environmentID > 0 {
+		if environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, environmentID); err == nil && environment.HostingImage != "" {
+			return environment.HostingImage
+		}
+	}
+	return config.GlobalConfig.Image.HostingImage
+}
+
+// SyncCatalogHandler handles remote catalog sync requests, admin only
+func (s *CatalogService) SyncCatalogHandler(c *gin.Context) {
+	var req catalogpb.SyncCatalogRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	url := req.Url
+	if url == "" {
+		url = config.GlobalConfig.Catalog.RegistryURL
+	}
+	if url == "" {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeCatalogRegistryURLRequired))
+		return
+	}
+
+	total, err := biz.GCatalogBiz.Sync(c.Request.Context(), url)
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeCatalogSyncFailure, url))
+		return
+	}
+
+	common.GinSuccess(c, &catalogpb.SyncCatalogResponse{Url: url, Total: int32(total)})
+}
+
+// RateCatalogEntryHandler handles catalog entry rating requests
+func (s *CatalogService) RateCatalogEntryHandler(c *gin.Context) {
+	var req catalogpb.RateCatalogRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	req.Id = c.Param("id")
+
+	ctx := c.Request.Context()
+	userID := uint(c.GetInt64("userId"))
+	if userID == 0 {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeInsufficientPermissions))
+		return
+	}
+	if _, err := biz.GCatalogBiz.GetEntry(ctx, req.Id); err != nil {
+		common.GinErrorFrom(c, catalogQueryError(err, req.Id))
+		return
+	}
+
+	rating := &model.McpCatalogRating{
+		CatalogID: req.Id,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Comment:   strings.TrimSpace(req.Comment),
+	}
+	if err := biz.GCatalogBiz.Rate(ctx, rating); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeCatalogRateFailure))
+		return
+	}
+
+	common.GinSuccess(c, modelToCatalogRatingInfo(ctx, rating, catalogRatingUserNames(ctx, []*model.McpCatalogRating{rating})))
+}
+
+// ListCatalogRatingsHandler handles catalog entry rating list requests
+func (s *CatalogService) ListCatalogRatingsHandler(c *gin.Context) {
+	var req catalogpb.ListCatalogRatingsRequest
+	if err := common.BindAndValidateQuery(c, &req); err != nil {
+		return
+	}
+	req.Id = c.Param("id")
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = common.DefaultPageSize
+	}
+	if req.PageSize > common.MaxPageSize {
+		req.PageSize = common.MaxPageSize
+	}
+
+	ctx := c.Request.Context()
+	ratings, total, err := biz.GCatalogBiz.ListRatings(ctx, req.Id, req.Page, req.PageSize)
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeCatalogQueryFailure))
+		return
+	}
+
+	names := catalogRatingUserNames(ctx, ratings)
+	list := make([]*catalogpb.CatalogRatingInfo, 0, len(ratings))
+	for _, rating := range ratings {
+		list = append(list, modelToCatalogRatingInfo(ctx, rating, names))
+	}
+	common.GinSuccess(c, &catalogpb.ListCatalogRatingsResponse{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+}
+
+// catalogRatingUserNames 查询评分用户的展示名称，优先使用昵称，查询失败时名称留空
+func catalogRatingUserNames(ctx context.Context, ratings []*model.McpCatalogRating) map[uint]string {
+	ids := make([]uint, 0, len(ratings))
+	for _, rating := range ratings {
+		ids = append(ids, rating.UserID)
+	}
+	names := make(map[uint]string, len(ids))
+	users, err := mysql.SysUserRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		logger.Warn("Failed to query catalog rating users", zap.Error(err))
+		return names
+	}
+	for _, user := range users {
+		switch {
+		case user.NickName != nil && *user.NickName != "":
+			names[user.UserID] = *user.NickName
+		case user.Username != nil:
+			names[user.UserID] = *user.Username
+		}
+	}
+	return names
+}
+
+// modelToCatalogRatingInfo converts model to catalog rating info
+func modelToCatalogRatingInfo(ctx context.Context, rating *model.McpCatalogRating, names map[uint]string) *catalogpb.CatalogRatingInfo {
+	return &catalogpb.CatalogRatingInfo{
+		CatalogId:   rating.CatalogID,
+		UserId:      int64(rating.UserID),
+		UserName:    names[rating.UserID],
+		Rating:      rating.Rating,
+		Comment:     rating.Comment,
+		CreatedAt:   common.FormatTimeRFC3339(ctx, rating.CreatedAt),
+		UpdatedAt:   common.FormatTimeRFC3339(ctx, rating.UpdatedAt),
+		CreatedAtMs: common.TimeMillis(rating.CreatedAt),
+		UpdatedAtMs: common.TimeMillis(rating.UpdatedAt),
+	}
+}
+
+// UpdateCatalogCurationHandler handles catalog entry verified flag and display order updates, admin only
+func (s *CatalogService) UpdateCatalogCurationHandler(c *gin.Context) {
+	var req catalogpb.UpdateCatalogCurationRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	req.Id = c.Param("id")
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	entry, err := biz.GCatalogBiz.UpdateCuration(ctx, req.Id, req.Verified, req.DisplayOrder)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.GinErrorFrom(c, common.NewError(i18nresp.CodeCatalogEntryNotFound, req.Id))
+			return
+		}
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeCatalogCurationFailure))
+		return
+	}
+
+	common.GinSuccess(c, modelToCatalogEntryInfo(ctx, entry))
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	changerequestpb "qm-mcp-server/api/market/change_request"
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	i18nresp "qm-mcp-server/pkg/i18n"
+)
+
+// ChangeRequestService 受保护环境的变更请求服务，审批通过后通过实例服务执行保存的请求
+type ChangeRequestService struct {
+	ctx       context.Context
+	instances *InstanceService
+}
+
+// NewChangeRequestService 创建变更请求服务
+func NewChangeRequestService(ctx context.Context) *ChangeRequestService {
+	return &ChangeRequestService{
+		ctx:       ctx,
+		instances: NewInstanceService(ctx),
+	}
+}
+
+// ListChangeRequestsHandler 查询变更请求列表，非管理员只能查看自己提交的请求
+func (s *ChangeRequestService) ListChangeRequestsHandler(c *gin.Context) {
+	var req changerequestpb.ListChangeRequestsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := validateListChangeRequestsRequest(&req); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	var requestedByID uint
+	if !isAdmin(c) {
+		requestedByID = uint(c.GetInt64("userId"))
+	}
+	ctx := c.Request.Context()
+	page, pageSize := historyPage(req.Page, req.PageSize)
+	requests, total, err := biz.GChangeRequestBiz.List(ctx, req.Status, requestedByID, int(page), int(pageSize))
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeDatabaseError))
+		return
+	}
+
+	resp := &changerequestpb.ListChangeRequestsResp{Total: total, Page: page, PageSize: pageSize}
+	for _, request := range requests {
+		resp.List = append(resp.List, changeRequestToPb(ctx, request))
+	}
+	common.GinSuccess(c, resp)
+}
+
+// GetChangeRequestHandler 查询变更请求详情，非管理员只能查看自己提交的请求
+func (s *ChangeRequestService) GetChangeRequestHandler(c *gin.Context) {
+	var req changerequestpb.GetChangeRequestRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	request, err := biz.GChangeRequestBiz.Get(ctx, uint(req.Id))
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if request.RequestedByID != uint(c.GetInt64("userId")) {
+		if err := requireAdmin(c); err != nil {
+			common.GinErrorFrom(c, err)
+			return
+		}
+	}
+	common.GinSuccess(c, changeRequestToPb(ctx, request))
+}
+
+// ApproveChangeRequestHandler 审批通过变更请求并执行，仅管理员可用
+func (s *ChangeRequestService) ApproveChangeRequestHandler(c *gin.Context) {
+	s.review(c, true)
+}
+
+// RejectChangeRequestHandler 拒绝变更请求，仅管理员可用
+func (s *ChangeRequestService) RejectChangeRequestHandler(c *gin.Context) {
+	s.review(c, false)
+}
+
+// review 审批变更请求，审批通过时以审批人的身份按正常流程执行保存的请求
+func (s *ChangeRequestService) review(c *gin.Context, approve bool) {
+	var req changerequestpb.ReviewChangeRequestRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := validateReviewChangeRequestRequest(&req); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	request, err := biz.GChangeRequestBiz.Review(ctx, uint(req.Id), approve, req.Comment)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if approve {
+		execErr := s.execute(biz.WithApprovedChange(ctx, request), request)
+		biz.GChangeRequestBiz.RecordResult(ctx, request, execErr)
+	}
+	common.GinSuccess(c, changeRequestToPb(ctx, request))
+}
+
+// execute 执行审批通过的变更请求
+func (s *ChangeRequestService) execute(ctx context.Context, request *model.McpChangeRequest) error {
+	switch request.Operation {
+	case model.ChangeOperationInstanceEdit:
+		var req instancepb.EditRequest
+		if err := json.Unmarshal(request.Payload, &req); err != nil {
+			return common.WrapError(err, i18nresp.CodeChangeRequestFailure)
+		}
+		_, err := s.instances.edit(ctx, &req)
+		return err
+	case model.ChangeOperationInstanceDelete:
+		_, err := s.instances.delete(ctx, request.ResourceID)
+		return err
+	default:
+		return common.NewError(i18nresp.CodeChangeRequestFailure, request.Operation)
+	}
+}
+
+// changeRequestToPb 转换变更请求
+func changeRequestToPb(ctx context.Context, request *model.McpChangeRequest) *changerequestpb.ChangeRequestInfo {
+	info := &changerequestpb.ChangeRequestInfo{
+		Id:            int64(request.ID),
+		Operation:     request.Operation,
+		ResourceType:  request.ResourceType,
+		ResourceId:    request.ResourceID,
+		ResourceName:  request.ResourceName,
+		EnvironmentId: int64(request.EnvironmentID),
+		Payload:       string(request.Payload),
+		Status:        request.Status,
+		RequestedBy:   request.RequestedBy,
+		ReviewedBy:    request.ReviewedBy,
+		ReviewComment: request.ReviewComment,
+		Result:        request.Result,
+		ExpiresAt:     common.FormatTimeRFC3339(ctx, request.ExpiresAt),
+		CreatedAt:     common.FormatTimeRFC3339(ctx, request.CreatedAt),
+	}
+	if request.ReviewedAt != nil {
+		info.ReviewedAt = common.FormatTimeRFC3339(ctx, *request.ReviewedAt)
+	}
+	return info
+}
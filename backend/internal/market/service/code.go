@@ -2,14 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"qm-mcp-server/api/market/code"
+	"qm-mcp-server/internal/market/biz"
 	"qm-mcp-server/internal/market/config"
 	"qm-mcp-server/pkg/codepackage"
 	"qm-mcp-server/pkg/common"
@@ -45,18 +48,16 @@ func NewCodeService() *CodeService {
 func (s *CodeService) UploadPackage(c *gin.Context) {
 	// 记录上传开始时间
 	startTime := time.Now()
-	logger.Info("Starting code package upload request",
+	logger.FromContext(c.Request.Context()).Info("Starting code package upload request",
 		zap.String("client_ip", c.ClientIP()),
-		zap.String("request_id", c.GetString("RequestID")),
 		zap.String("content_type", c.ContentType()))
 
 	// 获取上传的文件
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		logger.Error("Failed to get uploaded file",
+		logger.FromContext(c.Request.Context()).Error("Failed to get uploaded file",
 			zap.Error(err),
-			zap.String("client_ip", c.ClientIP()),
-			zap.String("request_id", c.GetString("RequestID")))
+			zap.String("client_ip", c.ClientIP()))
 		common.GinError(c, i18nresp.CodeInternalError, "failed to get uploaded file")
 		return
 	}
@@ -70,8 +71,14 @@ func (s *CodeService) UploadPackage(c *gin.Context) {
 		zap.Float64("size_mb", float64(header.Size)/(1024*1024)),
 		zap.String("content_type", header.Header.Get("Content-Type")))
 
+	// 超出用户代码包存储配额时在保存文件之前拒绝
+	if err := biz.GUserQuotaBiz.CheckPackageQuota(c.Request.Context(), header.Size); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
 	// 使用代码包管理器处理上传和解压
-	packageInfo, err := s.packageManager.UploadAndExtractPackage(file, header)
+	packageInfo, err := s.packageManager.UploadAndExtractPackage(c.Request.Context(), file, header)
 	if err != nil {
 		logger.Error("Failed to upload and extract package", zap.Error(err))
 		common.GinError(c, i18nresp.CodeInternalError, err.Error())
@@ -89,15 +96,19 @@ func (s *CodeService) UploadPackage(c *gin.Context) {
 		ExtractedPath: packageInfo.ExtractedPath,
 		OriginalName:  packageInfo.OriginalName,
 		FileSize:      packageInfo.FileSize,
+		CreatorID:     common.UserIDFromContext(c.Request.Context()),
 	}
 
 	if err := s.codePackageRepo.Create(ctx, codePackage); err != nil {
 		logger.Error("Failed to save package to database", zap.Error(err))
-		// 清理创建的目录
-		os.RemoveAll(packageInfo.PackagePath)
+		// 清理已保存的代码包
+		if err := s.packageManager.DeletePackage(ctx, packageInfo.PackagePath); err != nil {
+			logger.Warn("Failed to clean up package", zap.String("packageId", packageInfo.PackageID), zap.Error(err))
+		}
 		common.GinError(c, i18nresp.CodeInternalError, "failed to save package information")
 		return
 	}
+	biz.GUserQuotaBiz.InvalidatePackageUsage(codePackage.CreatorID)
 
 	// 计算总体耗时
 	totalElapsed := time.Since(startTime)
@@ -132,17 +143,10 @@ func (s *CodeService) GetCodeTree(c *gin.Context) {
 		return
 	}
 
-	// 使用解压后的路径构建文件结构
-	extractedPath := codePackage.ExtractedPath
-	if extractedPath == "" {
-		// 兼容旧数据，如果没有解压路径，使用包路径
-		extractedPath = codePackage.PackagePath
-	}
-
-	// 将相对路径转换为绝对路径
-	absExtractedPath, err := s.packageManager.ToAbsolutePath(extractedPath)
+	// 使用解压后的路径构建文件结构，对象存储时从本地缓存读取
+	absExtractedPath, err := s.packageManager.EnsureExtracted(ctx, codePackage)
 	if err != nil {
-		logger.Error("Failed to convert to absolute path", zap.String("relativePath", extractedPath), zap.Error(err))
+		logger.Error("Failed to prepare extracted package", zap.String("packageId", req.PackageId), zap.Error(err))
 		common.GinError(c, i18nresp.CodeInternalError, "invalid package path")
 		return
 	}
@@ -176,17 +180,10 @@ func (s *CodeService) GetCodeFile(c *gin.Context) {
 		return
 	}
 
-	// 使用解压后的路径
-	extractedPath := codePackage.ExtractedPath
-	if extractedPath == "" {
-		// 兼容旧数据，如果没有解压路径，使用包路径
-		extractedPath = codePackage.PackagePath
-	}
-
-	// 将相对路径转换为绝对路径
-	absExtractedPath, err := s.packageManager.ToAbsolutePath(extractedPath)
+	// 使用解压后的路径，对象存储时从本地缓存读取
+	absExtractedPath, err := s.packageManager.EnsureExtracted(ctx, codePackage)
 	if err != nil {
-		logger.Error("Failed to convert to absolute path", zap.String("relativePath", extractedPath), zap.Error(err))
+		logger.Error("Failed to prepare extracted package", zap.String("packageId", req.PackageId), zap.Error(err))
 		common.GinError(c, i18nresp.CodeInternalError, "invalid package path")
 		return
 	}
@@ -253,17 +250,10 @@ func (s *CodeService) EditCodeFile(c *gin.Context) {
 		return
 	}
 
-	// 使用解压后的路径
-	extractedPath := codePackage.ExtractedPath
-	if extractedPath == "" {
-		// 兼容旧数据，如果没有解压路径，使用包路径
-		extractedPath = codePackage.PackagePath
-	}
-
-	// 将相对路径转换为绝对路径
-	absExtractedPath, err := s.packageManager.ToAbsolutePath(extractedPath)
+	// 使用解压后的路径，对象存储时从本地缓存读取
+	absExtractedPath, err := s.packageManager.EnsureExtracted(ctx, codePackage)
 	if err != nil {
-		logger.Error("Failed to convert to absolute path", zap.String("relativePath", extractedPath), zap.Error(err))
+		logger.Error("Failed to prepare extracted package", zap.String("packageId", req.PackageId), zap.Error(err))
 		common.GinError(c, i18nresp.CodeInternalError, "invalid package path")
 		return
 	}
@@ -308,6 +298,17 @@ func (s *CodeService) EditCodeFile(c *gin.Context) {
 		common.GinError(c, i18nresp.CodeInternalError, "failed to write file")
 		return
 	}
+	// 对象存储时同时保存修改，本地缓存重建后仍然保留
+	relFilePath, _ := filepath.Rel(absPackagePath, absFilePath)
+	if err := s.packageManager.PersistEdit(ctx, codePackage, relFilePath, []byte(req.Content)); err != nil {
+		logger.Error("Failed to store edited file", zap.String("path", relFilePath), zap.Error(err))
+		common.GinError(c, i18nresp.CodeInternalError, "failed to write file")
+		return
+	}
+	// 文件已保存，通知失败不影响编辑结果
+	if err := biz.GCodePackageBiz.MarkChanged(ctx, codePackage, biz.CodePackageChangeEdit, relFilePath); err != nil {
+		logger.Error("Failed to mark code package changed", zap.String("packageId", codePackage.PackageID), zap.Error(err))
+	}
 	common.GinSuccess(c, &code.EditCodeFileResponse{
 		Success: true,
 		Message: "file edited successfully",
@@ -347,18 +348,24 @@ func (s *CodeService) DownloadPackage(c *gin.Context) {
 		return
 	}
 
-	// Build file path
-	absFilePath := filepath.Join(config.GlobalConfig.Storage.CodePath, codePackage.PackagePath, codePackage.OriginalName)
+	// 存储后端支持时重定向到预签名地址，文件不经过 market 服务
+	if url, err := s.packageManager.PresignOriginal(c.Request.Context(), codePackage, codePackagePresignExpiry()); err == nil {
+		c.Redirect(http.StatusFound, url)
+		return
+	} else if !errors.Is(err, codepackage.ErrPresignUnsupported) {
+		logger.Warn("Failed to presign package download, serving from storage", zap.String("packageId", packageID), zap.Error(err))
+	}
 
-	// Check if file exists
-	if _, err := os.Stat(absFilePath); os.IsNotExist(err) {
-		logger.Error("File not found", zap.String("filePath", absFilePath), zap.Error(err))
+	reader, err := s.packageManager.OpenOriginal(c.Request.Context(), codePackage)
+	if err != nil {
+		logger.Error("File not found", zap.String("packageId", packageID), zap.String("key", codepackage.OriginalKey(codePackage)), zap.Error(err))
 		c.JSON(404, gin.H{
 			"code":    404,
 			"message": "file not found",
 		})
 		return
 	}
+	defer reader.Close()
 
 	// Determine the actual filename to use for download
 	downloadFileName := codePackage.OriginalName
@@ -372,15 +379,22 @@ func (s *CodeService) DownloadPackage(c *gin.Context) {
 	}
 
 	// Set response headers for proper file download
-	c.Header("Content-Type", contentType)
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", downloadFileName))
 	c.Header("Content-Transfer-Encoding", "binary")
 	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
 	c.Header("Pragma", "no-cache")
 	c.Header("Expires", "0")
 
-	// Serve the file
-	c.File(absFilePath)
+	// Stream the file from the storage backend
+	c.DataFromReader(http.StatusOK, codePackage.FileSize, contentType, reader, nil)
+}
+
+// codePackagePresignExpiry validity of presigned code package download URLs
+func codePackagePresignExpiry() time.Duration {
+	if expiry := config.GlobalConfig.Storage.CodeBackend.S3.PresignExpiry; expiry > 0 {
+		return time.Duration(expiry) * time.Second
+	}
+	return 15 * time.Minute
 }
 
 // GenerateDownloadZip 处理下载请求的通用逻辑
@@ -431,13 +445,15 @@ func (s *CodeService) GetCodePackageList(c *gin.Context) {
 	var packageList []*code.CodePackageInfo
 	for _, pkg := range packages {
 		packageInfo := &code.CodePackageInfo{
-			Id:        pkg.PackageID,
-			Name:      pkg.OriginalName,
-			Path:      pkg.PackagePath,
-			Size:      pkg.FileSize,
-			Type:      convertPackageType(pkg.PackageType),
-			CreatedAt: pkg.CreatedAt.String(),
-			UpdatedAt: pkg.UpdatedAt.String(),
+			Id:          pkg.PackageID,
+			Name:        pkg.OriginalName,
+			Path:        pkg.PackagePath,
+			Size:        pkg.FileSize,
+			Type:        convertPackageType(pkg.PackageType),
+			CreatedAt:   common.FormatTime(c.Request.Context(), pkg.CreatedAt),
+			UpdatedAt:   common.FormatTime(c.Request.Context(), pkg.UpdatedAt),
+			CreatedAtMs: common.TimeMillis(pkg.CreatedAt),
+			UpdatedAtMs: common.TimeMillis(pkg.UpdatedAt),
 		}
 		packageList = append(packageList, packageInfo)
 	}
@@ -537,7 +553,7 @@ func (s *CodeService) DeleteCodePackage(c *gin.Context) {
 
 	// Delete physical files using package manager
 	if codePackage.PackagePath != "" {
-		if err := s.packageManager.DeletePackage(codePackage.PackagePath); err != nil {
+		if err := s.packageManager.DeletePackage(ctx, codePackage.PackagePath); err != nil {
 			logger.Error("Failed to delete package files",
 				zap.String("packageId", req.PackageId),
 				zap.String("packagePath", codePackage.PackagePath),
@@ -555,6 +571,7 @@ func (s *CodeService) DeleteCodePackage(c *gin.Context) {
 		common.GinError(c, i18nresp.CodeInternalError, "failed to delete package record")
 		return
 	}
+	biz.GUserQuotaBiz.InvalidatePackageUsage(codePackage.CreatorID)
 
 	logger.Info("Code package deleted successfully", zap.String("packageId", req.PackageId))
 
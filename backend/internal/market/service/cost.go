@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	costpb "qm-mcp-server/api/market/cost"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/cost"
+	"qm-mcp-server/pkg/logger"
+)
+
+// costReportCSVHeader 成本报表 CSV 导出的列，调整时只能在末尾追加
+var costReportCSVHeader = []string{"key", "name", "cost", "runningHours", "instances"}
+
+// CostService 托管实例成本估算服务
+type CostService struct {
+	ctx       context.Context
+	instances *InstanceService
+}
+
+// NewCostService 创建成本估算服务
+func NewCostService(ctx context.Context) *CostService {
+	return &CostService{
+		ctx:       ctx,
+		instances: NewInstanceService(ctx),
+	}
+}
+
+// GetInstanceCostHandler 查询实例的月度成本和当前的每小时成本
+func (s *CostService) GetInstanceCostHandler(c *gin.Context) {
+	var req costpb.InstanceCostRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	instance, err := s.instances.getInstanceByID(req.InstanceId)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	month := costMonth(req.Month)
+	ctx := c.Request.Context()
+	costs, err := biz.GCostBiz.InstanceCosts(ctx, instance.InstanceID, month)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	hourlyCost, err := biz.GCostBiz.HourlyCost(ctx, instance)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	resp := &costpb.InstanceCostResp{
+		InstanceId:   instance.InstanceID,
+		InstanceName: instance.InstanceName,
+		Month:        month.Format(biz.CostMonthLayout),
+		HourlyCost:   hourlyCost,
+		Days:         make([]*costpb.DailyCost, 0, len(costs)),
+	}
+	for _, day := range costs {
+		resp.Cost += day.Cost
+		resp.RunningHours += day.RunningHours
+		resp.Days = append(resp.Days, &costpb.DailyCost{
+			Day:          day.Day,
+			RunningHours: day.RunningHours,
+			ReplicaHours: day.ReplicaHours,
+			CpuCores:     day.CPUCores,
+			MemoryGiB:    day.MemoryGiB,
+			CpuHourRate:  day.CPUHourRate,
+			GibHourRate:  day.GiBHourRate,
+			Cost:         day.Cost,
+		})
+	}
+	resp.Cost = cost.Round(resp.Cost)
+	resp.RunningHours = cost.Round(resp.RunningHours)
+	common.GinSuccess(c, resp)
+}
+
+// GetCostReportHandler 按实例、环境或标签汇总月度成本，仅管理员可用，format=csv 或 Accept: text/csv 时导出 CSV
+func (s *CostService) GetCostReportHandler(c *gin.Context) {
+	var req costpb.CostReportRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = biz.CostGroupByInstance
+	}
+	month := costMonth(req.Month)
+	groups, err := biz.GCostBiz.Report(c.Request.Context(), groupBy, month)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if common.WantsCSV(c, req.Format) {
+		exportCostReport(c, groupBy, month, groups, req.PageToken)
+		return
+	}
+
+	resp := &costpb.CostReportResp{
+		Month:   month.Format(biz.CostMonthLayout),
+		GroupBy: groupBy,
+		Groups:  make([]*costpb.CostGroup, 0, len(groups)),
+	}
+	for _, group := range groups {
+		resp.Cost += group.Cost
+		resp.Groups = append(resp.Groups, &costpb.CostGroup{
+			Key:          group.Key,
+			Name:         group.Name,
+			Cost:         group.Cost,
+			RunningHours: group.RunningHours,
+			Instances:    int32(group.Instances),
+		})
+	}
+	resp.Cost = cost.Round(resp.Cost)
+	common.GinSuccess(c, resp)
+}
+
+// exportCostReport 导出成本报表 CSV，每次最多 common.MaxCSVRows 行，分页令牌为分组的偏移量
+func exportCostReport(c *gin.Context, groupBy string, month time.Time, groups []*biz.CostGroup, pageToken string) {
+	// 格式已由校验器检查
+	offset, _ := common.DecodePageToken(pageToken)
+	groups = groups[min(int(offset), len(groups)):]
+	var nextPageToken string
+	if len(groups) > common.MaxCSVRows {
+		groups = groups[:common.MaxCSVRows]
+		nextPageToken = common.EncodePageToken(offset + common.MaxCSVRows)
+	}
+
+	fileName := fmt.Sprintf("cost-report-%s-%s.csv", month.Format(biz.CostMonthLayout), time.Now().Format("20060102150405"))
+	err := common.GinCSV(c, fileName, costReportCSVHeader, nextPageToken, func(write func(record ...string) error) error {
+		for _, group := range groups {
+			if err := write(group.Key, group.Name, strconv.FormatFloat(group.Cost, 'f', -1, 64),
+				strconv.FormatFloat(group.RunningHours, 'f', -1, 64), strconv.Itoa(group.Instances)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to export cost report", zap.String("groupBy", groupBy), zap.Error(err))
+	}
+}
+
+// costMonth 解析查询的月份，为空时使用当月，格式已由校验器检查
+func costMonth(month string) time.Time {
+	if month != "" {
+		if t, err := time.ParseInLocation(biz.CostMonthLayout, month, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
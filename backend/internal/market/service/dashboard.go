@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -16,6 +18,12 @@ import (
 	"qm-mcp-server/pkg/logger"
 )
 
+// statisticalCSVHeader columns of the statistics CSV export, new columns may only be appended
+var statisticalCSVHeader = []string{
+	"totalInstances", "activeInstances", "inactiveInstances",
+	"proxyInstances", "directInstances", "hostingInstances", "totalEnvironments",
+}
+
 // DashboardService dashboard service
 type DashboardService struct {
 	pb.UnimplementedDashboardServiceServer
@@ -37,7 +45,7 @@ func NewDashboardService(ctx context.Context) *DashboardService {
 // Statistical get statistical data
 func (s *DashboardService) Statistical(ctx context.Context, req *pb.StatisticalRequest) (*pb.StatisticalResponse, error) {
 	// Get all instances
-	instances, err := s.instanceBiz.ListInstance(1, 10000, nil, "", "")
+	instances, err := s.instanceBiz.ListInstance(ctx, 1, 10000, nil, "", "")
 	if err != nil {
 		logger.Error("Failed to get all instances", zap.Error(err))
 		return nil, err
@@ -112,14 +120,37 @@ func (s *DashboardService) AvailableCases(ctx context.Context, req *pb.Available
 	}, nil
 }
 
+// StatisticalHandler returns instance usage statistics, exported as a single CSV row with format=csv or Accept: text/csv
 func (s *DashboardService) StatisticalHandler(c *gin.Context) {
 	req := &pb.StatisticalRequest{}
+	if err := common.BindAndValidate(c, req); err != nil {
+		return
+	}
 	resp, err := s.Statistical(c.Request.Context(), req)
 	if err != nil {
 		common.GinError(c, i18n.CodeInternalError, err.Error())
 		return
 	}
-	common.GinSuccess(c, resp)
+	if !common.WantsCSV(c, req.Format) {
+		common.GinSuccess(c, resp)
+		return
+	}
+
+	fileName := fmt.Sprintf("statistics-%s.csv", time.Now().Format("20060102150405"))
+	err = common.GinCSV(c, fileName, statisticalCSVHeader, "", func(write func(record ...string) error) error {
+		return write(
+			strconv.Itoa(int(resp.TotalInstances)),
+			strconv.Itoa(int(resp.ActiveInstances)),
+			strconv.Itoa(int(resp.InactiveInstances)),
+			strconv.Itoa(int(resp.ProxyInstances)),
+			strconv.Itoa(int(resp.DirectInstances)),
+			strconv.Itoa(int(resp.HostingInstances)),
+			strconv.Itoa(int(resp.TotalEnvironments)),
+		)
+	})
+	if err != nil {
+		logger.Warn("Failed to export statistics", zap.Error(err))
+	}
 }
 
 func (s *DashboardService) AvailableCasesHandler(c *gin.Context) {
@@ -2,17 +2,21 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"slices"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"qm-mcp-server/api/market/mcp_environment"
 	"qm-mcp-server/internal/market/biz"
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/database/model"
 	i18nresp "qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/k8s"
 )
 
 // EnvironmentService provides environment management functionality
@@ -27,21 +31,80 @@ func NewEnvironmentService(ctx context.Context) *EnvironmentService {
 	}
 }
 
+// environmentQueryError 转换环境查询错误，记录不存在时返回 404
+func environmentQueryError(err error, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return common.NewError(i18nresp.CodeEnvironmentIDNotFound, id)
+	}
+	return common.WrapError(err, i18nresp.CodeEnvironmentQueryFailure)
+}
+
 // modelToMcpEnvironmentInfo converts model to MCP environment info
-func modelToMcpEnvironmentInfo(env *model.McpEnvironment) *mcp_environment.McpEnvironmentInfo {
+func modelToMcpEnvironmentInfo(ctx context.Context, env *model.McpEnvironment) *mcp_environment.McpEnvironmentInfo {
 	return &mcp_environment.McpEnvironmentInfo{
-		Id:          int32(env.ID),
-		Name:        env.Name,
-		Environment: string(env.Environment),
-		Config:      env.Config,
-		Namespace:   env.Namespace,
-		CreatedAt:   env.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   env.UpdatedAt.Format(time.RFC3339),
+		Id:                           int32(env.ID),
+		Name:                         env.Name,
+		Environment:                  string(env.Environment),
+		Config:                       env.Config,
+		Namespace:                    env.Namespace,
+		HostingImage:                 env.HostingImage,
+		SupergatewayImage:            env.SupergatewayImage,
+		MaxInstances:                 int32(env.MaxInstances),
+		MaxTotalMemory:               env.MaxTotalMemory,
+		MaxTotalCPU:                  env.MaxTotalCPU,
+		QuotaExcludeInactive:         env.QuotaExcludeInactive,
+		BlockCriticalVulnerabilities: env.BlockCriticalVulnerabilities,
+		MaxCriticalVulnerabilities:   int32(env.MaxCriticalVulnerabilities),
+		Protected:                    env.Protected,
+		CostPerCpuHour:               env.CostPerCPUHour,
+		CostPerGibHour:               env.CostPerGiBHour,
+		Defaults:                     environmentDefaultsToProto(env),
+		CreatedAt:                    common.FormatTimeRFC3339(ctx, env.CreatedAt),
+		UpdatedAt:                    common.FormatTimeRFC3339(ctx, env.UpdatedAt),
+		CreatedAtMs:                  common.TimeMillis(env.CreatedAt),
+		UpdatedAtMs:                  common.TimeMillis(env.UpdatedAt),
+	}
+}
+
+// environmentDefaultsToProto converts instance defaults of the environment, nil when unset or unparsable
+func environmentDefaultsToProto(env *model.McpEnvironment) *mcp_environment.EnvironmentDefaults {
+	defaults, err := env.GetDefaults()
+	if err != nil || len(env.Defaults) == 0 {
+		return nil
+	}
+	return &mcp_environment.EnvironmentDefaults{
+		EnvVars:                   defaults.EnvVars,
+		NodeSelector:              defaults.NodeSelector,
+		ResourceRequests:          defaults.ResourceRequests,
+		ResourceLimits:            defaults.ResourceLimits,
+		ImagePullSecrets:          defaults.ImagePullSecrets,
+		Labels:                    defaults.Labels,
+		PriorityClassName:         defaults.PriorityClassName,
+		CreatePodDisruptionBudget: defaults.CreatePodDisruptionBudget,
+		IsolateServiceAccount:     defaults.IsolateServiceAccount,
+	}
+}
+
+// environmentDefaultsFromProto converts instance defaults of a request, nil clears the defaults
+func environmentDefaultsFromProto(defaults *mcp_environment.EnvironmentDefaults) *model.EnvironmentDefaults {
+	if defaults == nil {
+		return nil
+	}
+	return &model.EnvironmentDefaults{
+		EnvVars:                   defaults.EnvVars,
+		NodeSelector:              defaults.NodeSelector,
+		ResourceRequests:          defaults.ResourceRequests,
+		ResourceLimits:            defaults.ResourceLimits,
+		ImagePullSecrets:          defaults.ImagePullSecrets,
+		Labels:                    defaults.Labels,
+		PriorityClassName:         defaults.PriorityClassName,
+		CreatePodDisruptionBudget: defaults.CreatePodDisruptionBudget,
+		IsolateServiceAccount:     defaults.IsolateServiceAccount,
 	}
 }
 
 // modelToEnvironmentResponse converts model to environment response
-func modelToEnvironmentResponse(env *model.McpEnvironment) *mcp_environment.EnvironmentResponse {
+func modelToEnvironmentResponse(ctx context.Context, env *model.McpEnvironment) *mcp_environment.EnvironmentResponse {
 	var envType mcp_environment.McpEnvironmentType
 	switch env.Environment {
 	case model.McpEnvironmentKubernetes:
@@ -53,13 +116,27 @@ func modelToEnvironmentResponse(env *model.McpEnvironment) *mcp_environment.Envi
 	}
 
 	return &mcp_environment.EnvironmentResponse{
-		Id:          int32(env.ID),
-		Name:        env.Name,
-		Environment: envType,
-		Config:      env.Config,
-		Namespace:   env.Namespace,
-		CreatedAt:   env.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   env.UpdatedAt.Format(time.RFC3339),
+		Id:                           int32(env.ID),
+		Name:                         env.Name,
+		Environment:                  envType,
+		Config:                       env.Config,
+		Namespace:                    env.Namespace,
+		HostingImage:                 env.HostingImage,
+		SupergatewayImage:            env.SupergatewayImage,
+		MaxInstances:                 int32(env.MaxInstances),
+		MaxTotalMemory:               env.MaxTotalMemory,
+		MaxTotalCPU:                  env.MaxTotalCPU,
+		QuotaExcludeInactive:         env.QuotaExcludeInactive,
+		BlockCriticalVulnerabilities: env.BlockCriticalVulnerabilities,
+		MaxCriticalVulnerabilities:   int32(env.MaxCriticalVulnerabilities),
+		Protected:                    env.Protected,
+		CostPerCpuHour:               env.CostPerCPUHour,
+		CostPerGibHour:               env.CostPerGiBHour,
+		Defaults:                     environmentDefaultsToProto(env),
+		CreatedAt:                    common.FormatTimeRFC3339(ctx, env.CreatedAt),
+		UpdatedAt:                    common.FormatTimeRFC3339(ctx, env.UpdatedAt),
+		CreatedAtMs:                  common.TimeMillis(env.CreatedAt),
+		UpdatedAtMs:                  common.TimeMillis(env.UpdatedAt),
 	}
 }
 
@@ -71,9 +148,9 @@ func (s *EnvironmentService) CreateEnvironmentHandler(c *gin.Context) {
 	}
 
 	// 使用 EnvironmentService 处理请求
-	result, err := s.CreateEnvironment(&req)
+	result, err := s.CreateEnvironment(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -81,10 +158,10 @@ func (s *EnvironmentService) CreateEnvironmentHandler(c *gin.Context) {
 }
 
 // CreateEnvironment creates a new environment
-func (s *EnvironmentService) CreateEnvironment(req *mcp_environment.CreateEnvironmentRequest) (*mcp_environment.EnvironmentResponse, error) {
+func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *mcp_environment.CreateEnvironmentRequest) (*mcp_environment.EnvironmentResponse, error) {
 	// 验证必填字段
 	if req.Name == "" {
-		return nil, fmt.Errorf("环境名称不能为空")
+		return nil, common.ErrRequiredField("name")
 	}
 
 	// 验证环境类型
@@ -95,38 +172,52 @@ func (s *EnvironmentService) CreateEnvironment(req *mcp_environment.CreateEnviro
 	case mcp_environment.McpEnvironmentType_Docker:
 		envType = model.McpEnvironmentDocker
 	default:
-		return nil, fmt.Errorf("不支持的环境类型，仅支持 kubernetes 或 docker")
+		return nil, common.NewError(i18nresp.CodeEnvironmentTypeUnsupported)
 	}
 
 	// 检查环境名称是否已存在
 	existingEnv, err := biz.GEnvironmentBiz.GetEnvironmentByName(s.ctx, req.Name)
 	if err == nil && existingEnv != nil {
-		return nil, fmt.Errorf("环境名称已存在")
+		return nil, common.NewError(i18nresp.CodeEnvironmentNameConflict, req.Name)
 	}
 
 	// 创建环境对象
 	environment := &model.McpEnvironment{
-		Name:        req.Name,
-		Environment: envType,
-		Config:      req.Config,
-		Namespace:   req.Namespace,
-		CreatorID:   "",
+		Name:                         req.Name,
+		Environment:                  envType,
+		Config:                       req.Config,
+		Namespace:                    req.Namespace,
+		HostingImage:                 req.HostingImage,
+		SupergatewayImage:            req.SupergatewayImage,
+		MaxInstances:                 int(req.MaxInstances),
+		MaxTotalMemory:               req.MaxTotalMemory,
+		MaxTotalCPU:                  req.MaxTotalCPU,
+		QuotaExcludeInactive:         req.QuotaExcludeInactive,
+		BlockCriticalVulnerabilities: req.BlockCriticalVulnerabilities,
+		MaxCriticalVulnerabilities:   int(req.MaxCriticalVulnerabilities),
+		Protected:                    req.Protected,
+		CostPerCPUHour:               req.CostPerCpuHour,
+		CostPerGiBHour:               req.CostPerGibHour,
+		CreatorID:                    "",
+	}
+	if err := environment.SetDefaults(environmentDefaultsFromProto(req.Defaults)); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentValidateFailure)
 	}
 
 	// 验证和准备创建
 	if validationErr := environment.ValidateForCreate(); validationErr != nil {
-		return nil, fmt.Errorf("环境数据验证失败: %s", err.Error())
+		return nil, common.WrapError(validationErr, i18nresp.CodeEnvironmentValidateFailure)
 	}
 	environment.PrepareForCreate()
 
 	// 创建环境
 	err = biz.GEnvironmentBiz.CreateEnvironment(s.ctx, environment)
 	if err != nil {
-		return nil, fmt.Errorf("创建环境失败: %s", err.Error())
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentSaveFailure)
 	}
 
 	// 构建响应
-	response := modelToEnvironmentResponse(environment)
+	response := modelToEnvironmentResponse(ctx, environment)
 
 	return response, nil
 }
@@ -140,7 +231,7 @@ func CreateEnvironmentHandler(c *gin.Context) {
 
 	// 验证必填字段
 	if req.Name == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境名称不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("name"))
 		return
 	}
 
@@ -152,29 +243,44 @@ func CreateEnvironmentHandler(c *gin.Context) {
 	case mcp_environment.McpEnvironmentType_Docker:
 		envType = model.McpEnvironmentDocker
 	default:
-		common.GinError(c, i18nresp.CodeInternalError, "不支持的环境类型，仅支持 kubernetes 或 docker")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentTypeUnsupported))
 		return
 	}
 
 	// 检查环境名称是否已存在
 	existingEnv, err := biz.GEnvironmentBiz.GetEnvironmentByName(c.Request.Context(), req.Name)
 	if err == nil && existingEnv != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "环境名称已存在")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentNameConflict, req.Name))
 		return
 	}
 
 	// 创建环境对象
 	environment := &model.McpEnvironment{
-		Name:        req.Name,
-		Environment: envType,
-		Config:      req.Config,
-		Namespace:   req.Namespace,
-		CreatorID:   "",
+		Name:                         req.Name,
+		Environment:                  envType,
+		Config:                       req.Config,
+		Namespace:                    req.Namespace,
+		HostingImage:                 req.HostingImage,
+		SupergatewayImage:            req.SupergatewayImage,
+		MaxInstances:                 int(req.MaxInstances),
+		MaxTotalMemory:               req.MaxTotalMemory,
+		MaxTotalCPU:                  req.MaxTotalCPU,
+		QuotaExcludeInactive:         req.QuotaExcludeInactive,
+		BlockCriticalVulnerabilities: req.BlockCriticalVulnerabilities,
+		MaxCriticalVulnerabilities:   int(req.MaxCriticalVulnerabilities),
+		Protected:                    req.Protected,
+		CostPerCPUHour:               req.CostPerCpuHour,
+		CostPerGiBHour:               req.CostPerGibHour,
+		CreatorID:                    "",
+	}
+	if err := environment.SetDefaults(environmentDefaultsFromProto(req.Defaults)); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeEnvironmentValidateFailure))
+		return
 	}
 
 	// 验证和准备创建
 	if validationErr := environment.ValidateForCreate(); validationErr != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("环境数据验证失败: %s", err.Error()))
+		common.GinErrorFrom(c, common.WrapError(validationErr, i18nresp.CodeEnvironmentValidateFailure))
 		return
 	}
 	environment.PrepareForCreate()
@@ -182,12 +288,12 @@ func CreateEnvironmentHandler(c *gin.Context) {
 	// 创建环境
 	err = biz.GEnvironmentBiz.CreateEnvironment(c.Request.Context(), environment)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("创建环境失败: %s", err.Error()))
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeEnvironmentSaveFailure))
 		return
 	}
 
 	// 构建响应
-	response := modelToEnvironmentResponse(environment)
+	response := modelToEnvironmentResponse(c.Request.Context(), environment)
 
 	common.GinSuccess(c, response)
 }
@@ -202,21 +308,21 @@ func (s *EnvironmentService) UpdateEnvironmentHandler(c *gin.Context) {
 	// 从URL路径参数获取ID
 	idStr := c.Param("id")
 	if idStr == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境ID不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "无效的环境ID")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
 		return
 	}
 	req.Id = int32(id)
 
 	// 使用 EnvironmentService 处理请求
-	result, err := s.UpdateEnvironment(&req)
+	result, err := s.UpdateEnvironment(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -224,7 +330,7 @@ func (s *EnvironmentService) UpdateEnvironmentHandler(c *gin.Context) {
 }
 
 // UpdateEnvironment updates an existing environment
-func (s *EnvironmentService) UpdateEnvironment(req *mcp_environment.UpdateEnvironmentRequest) (*mcp_environment.EnvironmentResponse, error) {
+func (s *EnvironmentService) UpdateEnvironment(ctx context.Context, req *mcp_environment.UpdateEnvironmentRequest) (*mcp_environment.EnvironmentResponse, error) {
 	// 验证环境类型
 	var envType model.McpEnvironmentType
 	switch req.Environment {
@@ -233,7 +339,7 @@ func (s *EnvironmentService) UpdateEnvironment(req *mcp_environment.UpdateEnviro
 	case mcp_environment.McpEnvironmentType_Docker:
 		envType = model.McpEnvironmentDocker
 	default:
-		return nil, fmt.Errorf("不支持的环境类型，仅支持 kubernetes 或 docker")
+		return nil, common.NewError(i18nresp.CodeEnvironmentTypeUnsupported)
 	}
 
 	// 更新环境
@@ -241,7 +347,7 @@ func (s *EnvironmentService) UpdateEnvironment(req *mcp_environment.UpdateEnviro
 	// 先获取现有环境
 	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, uint(req.Id))
 	if err != nil {
-		return nil, fmt.Errorf("查询环境失败: %s", err.Error())
+		return nil, environmentQueryError(err, uint(req.Id))
 	}
 
 	// 更新字段
@@ -249,21 +355,35 @@ func (s *EnvironmentService) UpdateEnvironment(req *mcp_environment.UpdateEnviro
 	environment.Environment = envType
 	environment.Config = req.Config
 	environment.Namespace = req.Namespace
+	environment.HostingImage = req.HostingImage
+	environment.SupergatewayImage = req.SupergatewayImage
+	environment.MaxInstances = int(req.MaxInstances)
+	environment.MaxTotalMemory = req.MaxTotalMemory
+	environment.MaxTotalCPU = req.MaxTotalCPU
+	environment.QuotaExcludeInactive = req.QuotaExcludeInactive
+	environment.BlockCriticalVulnerabilities = req.BlockCriticalVulnerabilities
+	environment.MaxCriticalVulnerabilities = int(req.MaxCriticalVulnerabilities)
+	environment.Protected = req.Protected
+	environment.CostPerCPUHour = req.CostPerCpuHour
+	environment.CostPerGiBHour = req.CostPerGibHour
+	if err := environment.SetDefaults(environmentDefaultsFromProto(req.Defaults)); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentValidateFailure)
+	}
 
 	// 验证和准备更新
 	if validationErr := environment.ValidateForUpdate(); validationErr != nil {
-		return nil, fmt.Errorf("环境数据验证失败: %s", validationErr.Error())
+		return nil, common.WrapError(validationErr, i18nresp.CodeEnvironmentValidateFailure)
 	}
 	environment.PrepareForUpdate()
 
 	// 执行更新
 	err = biz.GEnvironmentBiz.UpdateEnvironment(s.ctx, environment)
 	if err != nil {
-		return nil, fmt.Errorf("更新环境失败: %s", err.Error())
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentSaveFailure)
 	}
 
 	// 构建响应
-	response := modelToEnvironmentResponse(environment)
+	response := modelToEnvironmentResponse(ctx, environment)
 
 	return response, nil
 }
@@ -278,13 +398,13 @@ func UpdateEnvironmentHandler(c *gin.Context) {
 	// 从URL路径参数获取ID
 	idStr := c.Param("id")
 	if idStr == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境ID不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "无效的环境ID")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
 		return
 	}
 	req.Id = int32(id)
@@ -297,7 +417,7 @@ func UpdateEnvironmentHandler(c *gin.Context) {
 	case mcp_environment.McpEnvironmentType_Docker:
 		envType = model.McpEnvironmentDocker
 	default:
-		common.GinError(c, i18nresp.CodeInternalError, "不支持的环境类型，仅支持 kubernetes 或 docker")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentTypeUnsupported))
 		return
 	}
 
@@ -306,7 +426,7 @@ func UpdateEnvironmentHandler(c *gin.Context) {
 	// 先获取现有环境
 	environment, err := biz.GEnvironmentBiz.GetEnvironment(c.Request.Context(), uint(req.Id))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("查询环境失败: %s", err.Error()))
+		common.GinErrorFrom(c, environmentQueryError(err, uint(req.Id)))
 		return
 	}
 
@@ -315,10 +435,25 @@ func UpdateEnvironmentHandler(c *gin.Context) {
 	environment.Environment = envType
 	environment.Config = req.Config
 	environment.Namespace = req.Namespace
+	environment.HostingImage = req.HostingImage
+	environment.SupergatewayImage = req.SupergatewayImage
+	environment.MaxInstances = int(req.MaxInstances)
+	environment.MaxTotalMemory = req.MaxTotalMemory
+	environment.MaxTotalCPU = req.MaxTotalCPU
+	environment.QuotaExcludeInactive = req.QuotaExcludeInactive
+	environment.BlockCriticalVulnerabilities = req.BlockCriticalVulnerabilities
+	environment.MaxCriticalVulnerabilities = int(req.MaxCriticalVulnerabilities)
+	environment.Protected = req.Protected
+	environment.CostPerCPUHour = req.CostPerCpuHour
+	environment.CostPerGiBHour = req.CostPerGibHour
+	if err := environment.SetDefaults(environmentDefaultsFromProto(req.Defaults)); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeEnvironmentValidateFailure))
+		return
+	}
 
 	// 验证和准备更新
 	if validationErr := environment.ValidateForUpdate(); validationErr != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("环境数据验证失败: %s", validationErr.Error()))
+		common.GinErrorFrom(c, common.WrapError(validationErr, i18nresp.CodeEnvironmentValidateFailure))
 		return
 	}
 	environment.PrepareForUpdate()
@@ -326,12 +461,12 @@ func UpdateEnvironmentHandler(c *gin.Context) {
 	// 执行更新
 	err = biz.GEnvironmentBiz.UpdateEnvironment(c.Request.Context(), environment)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("更新环境失败: %s", err.Error()))
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeEnvironmentSaveFailure))
 		return
 	}
 
 	// 构建响应
-	response := modelToEnvironmentResponse(environment)
+	response := modelToEnvironmentResponse(c.Request.Context(), environment)
 
 	common.GinSuccess(c, response)
 }
@@ -341,20 +476,20 @@ func (s *EnvironmentService) GetEnvironmentHandler(c *gin.Context) {
 	// 从URL路径参数获取ID
 	idStr := c.Param("id")
 	if idStr == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境ID不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "无效的环境ID")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
 		return
 	}
 
 	// 使用 EnvironmentService 处理请求
-	result, err := s.GetEnvironment(uint(id))
+	result, err := s.GetEnvironment(c.Request.Context(), uint(id))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -362,15 +497,15 @@ func (s *EnvironmentService) GetEnvironmentHandler(c *gin.Context) {
 }
 
 // GetEnvironment 获取环境业务逻辑
-func (s *EnvironmentService) GetEnvironment(id uint) (*mcp_environment.EnvironmentResponse, error) {
+func (s *EnvironmentService) GetEnvironment(ctx context.Context, id uint) (*mcp_environment.EnvironmentResponse, error) {
 	// 获取环境
 	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("查询环境失败: %s", err.Error())
+		return nil, environmentQueryError(err, id)
 	}
 
 	// 构建响应
-	response := modelToEnvironmentResponse(environment)
+	response := modelToEnvironmentResponse(ctx, environment)
 
 	return response, nil
 }
@@ -380,24 +515,24 @@ func (s *EnvironmentService) DeleteEnvironmentHandler(c *gin.Context) {
 	// 从URL路径参数获取ID
 	idStr := c.Param("id")
 	if idStr == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境ID不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "无效的环境ID")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
 		return
 	}
 
 	// 使用 EnvironmentService 处理请求
 	err = s.DeleteEnvironment(uint(id))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
-	common.GinSuccess(c, gin.H{"message": "环境删除成功"})
+	common.GinSuccess(c, gin.H{"message": i18nresp.FormatWithGin(c, i18nresp.CodeEnvironmentDeleteSuccess)})
 }
 
 // DeleteEnvironment 删除环境业务逻辑
@@ -405,7 +540,7 @@ func (s *EnvironmentService) DeleteEnvironment(id uint) error {
 	// 删除环境
 	err := biz.GEnvironmentBiz.DeleteEnvironment(s.ctx, id)
 	if err != nil {
-		return fmt.Errorf("删除环境失败: %s", err.Error())
+		return common.WrapError(err, i18nresp.CodeEnvironmentRemoveFailure)
 	}
 
 	return nil
@@ -419,9 +554,9 @@ func (s *EnvironmentService) ListEnvironmentsHandler(c *gin.Context) {
 	}
 
 	// 使用 EnvironmentService 处理请求
-	result, err := s.ListEnvironments(&req)
+	result, err := s.ListEnvironments(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -429,7 +564,7 @@ func (s *EnvironmentService) ListEnvironmentsHandler(c *gin.Context) {
 }
 
 // ListEnvironments 环境列表业务逻辑
-func (s *EnvironmentService) ListEnvironments(req *mcp_environment.ListEnvironmentsRequest) (*mcp_environment.ListEnvironmentsResponse, error) {
+func (s *EnvironmentService) ListEnvironments(ctx context.Context, req *mcp_environment.ListEnvironmentsRequest) (*mcp_environment.ListEnvironmentsResponse, error) {
 	// 设置默认分页参数
 	if req.Page <= 0 {
 		req.Page = 1
@@ -459,7 +594,7 @@ func (s *EnvironmentService) ListEnvironments(req *mcp_environment.ListEnvironme
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("查询环境列表失败: %s", err.Error())
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentListFailure)
 	}
 
 	// 计算分页
@@ -479,7 +614,7 @@ func (s *EnvironmentService) ListEnvironments(req *mcp_environment.ListEnvironme
 	// 构建响应列表
 	var responseList []*mcp_environment.McpEnvironmentInfo
 	for _, env := range environments {
-		responseList = append(responseList, modelToMcpEnvironmentInfo(env))
+		responseList = append(responseList, modelToMcpEnvironmentInfo(ctx, env))
 	}
 
 	response := &mcp_environment.ListEnvironmentsResponse{
@@ -530,7 +665,7 @@ func ListEnvironmentsHandler(c *gin.Context) {
 	}
 
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeEnvironmentListFailure))
 		return
 	}
 
@@ -551,7 +686,7 @@ func ListEnvironmentsHandler(c *gin.Context) {
 	// 构建响应列表
 	var responseList []*mcp_environment.McpEnvironmentInfo
 	for _, env := range environments {
-		responseList = append(responseList, modelToMcpEnvironmentInfo(env))
+		responseList = append(responseList, modelToMcpEnvironmentInfo(c.Request.Context(), env))
 	}
 
 	response := &mcp_environment.ListEnvironmentsResponse{
@@ -569,20 +704,20 @@ func (s *EnvironmentService) TestConnectivityHandler(c *gin.Context) {
 	// 从URL路径参数获取ID
 	idStr := c.Param("id")
 	if idStr == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "环境ID不能为空")
+		common.GinErrorFrom(c, common.ErrRequiredField("id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, "无效的环境ID")
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
 		return
 	}
 
 	// 使用 EnvironmentService 处理请求
 	result, err := s.TestConnectivity(uint(id))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -594,21 +729,273 @@ func (s *EnvironmentService) TestConnectivity(id uint) (*mcp_environment.TestCon
 	// 获取环境信息
 	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("查询环境失败: %s", err.Error())
+		return nil, environmentQueryError(err, id)
 	}
 	if environment == nil {
-		return nil, fmt.Errorf("环境不存在")
+		return nil, common.NewError(i18nresp.CodeEnvironmentIDNotFound, id)
 	}
 
 	// 执行连通性测试
 	result, err := testEnvironmentConnectivity(s.ctx, environment)
 	if err != nil {
-		return nil, fmt.Errorf("连通性测试失败: %s", err.Error())
+		return nil, common.ErrEnvironmentUnreachable(err)
 	}
 
 	return result, nil
 }
 
+// GetEnvironmentQuotaHandler 环境配额使用量接口Handler
+func (s *EnvironmentService) GetEnvironmentQuotaHandler(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
+		return
+	}
+
+	result, err := s.GetEnvironmentQuota(c.Request.Context(), uint(id))
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// GetEnvironmentQuota 查询环境托管实例配额的上限和当前使用量
+func (s *EnvironmentService) GetEnvironmentQuota(ctx context.Context, id uint) (*mcp_environment.EnvironmentQuotaResponse, error) {
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, id)
+	if err != nil {
+		return nil, environmentQueryError(err, id)
+	}
+	usage, err := biz.GEnvironmentBiz.QuotaUsage(ctx, environment)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentQuotaFailure)
+	}
+
+	return &mcp_environment.EnvironmentQuotaResponse{
+		Id:                   int32(environment.ID),
+		Instances:            int32(usage.Instances),
+		MaxInstances:         int32(environment.MaxInstances),
+		TotalMemory:          usage.Memory.String(),
+		MaxTotalMemory:       environment.MaxTotalMemory,
+		TotalCPU:             usage.CPU.String(),
+		MaxTotalCPU:          environment.MaxTotalCPU,
+		QuotaExcludeInactive: environment.QuotaExcludeInactive,
+	}, nil
+}
+
+// GetEnvironmentResourcesHandler 环境资源使用量接口Handler
+func (s *EnvironmentService) GetEnvironmentResourcesHandler(c *gin.Context) {
+	var req mcp_environment.EnvironmentResourcesRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
+		return
+	}
+	req.Id = int32(id)
+
+	result, err := s.GetEnvironmentResources(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// GetEnvironmentResources 查询环境中托管实例的资源使用量、请求量和限制量，按当前使用量从高到低排列
+func (s *EnvironmentService) GetEnvironmentResources(ctx context.Context, req *mcp_environment.EnvironmentResourcesRequest) (*mcp_environment.EnvironmentResourcesResponse, error) {
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, uint(req.Id))
+	if err != nil {
+		return nil, environmentQueryError(err, uint(req.Id))
+	}
+	usage, err := biz.GEnvironmentBiz.ResourceUsage(ctx, environment.ID)
+	if err != nil {
+		if errors.Is(err, k8s.ErrMetricsUnavailable) {
+			return nil, common.WrapError(err, i18nresp.CodeMetricsUnavailable)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentResourcesFailure)
+	}
+
+	// 缓存的结果由多个请求共享，排序前复制
+	instances := slices.Clone(usage.Instances)
+	if req.SortBy == resourceSortMemory {
+		sort.SliceStable(instances, func(i, j int) bool {
+			return instances[i].MemoryUsage.Cmp(instances[j].MemoryUsage) > 0
+		})
+	}
+	list := make([]*mcp_environment.InstanceResourceUsage, 0, len(instances))
+	for _, instance := range instances {
+		list = append(list, convertInstanceResourceUsage(instance))
+	}
+	return &mcp_environment.EnvironmentResourcesResponse{
+		Id:          int32(environment.ID),
+		Namespace:   usage.Namespace,
+		CollectedAt: usage.CollectedAt.UTC().Format(time.RFC3339),
+		Total:       convertInstanceResourceUsage(&usage.Total),
+		List:        list,
+	}, nil
+}
+
+// convertInstanceResourceUsage 转换实例资源使用量
+func convertInstanceResourceUsage(usage *biz.InstanceResourceUsage) *mcp_environment.InstanceResourceUsage {
+	return &mcp_environment.InstanceResourceUsage{
+		InstanceId:     usage.InstanceID,
+		InstanceName:   usage.InstanceName,
+		Pods:           int32(usage.Pods),
+		CpuUsage:       usage.CPUUsage.String(),
+		MemoryUsage:    usage.MemoryUsage.String(),
+		CpuRequests:    usage.CPURequests.String(),
+		MemoryRequests: usage.MemoryRequests.String(),
+		CpuLimits:      usage.CPULimits.String(),
+		MemoryLimits:   usage.MemoryLimits.String(),
+	}
+}
+
+// defaultOrphanMinAge 孤儿资源默认最小存在时长，更新的资源可能属于正在创建的实例
+const defaultOrphanMinAge = 600
+
+// ListOrphansHandler 孤儿资源扫描接口Handler，仅管理员可用
+func (s *EnvironmentService) ListOrphansHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var req mcp_environment.ListOrphansRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
+		return
+	}
+	req.Id = int32(id)
+
+	result, err := s.ListOrphans(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ListOrphans 列出环境集群中实例记录已不存在的托管资源
+func (s *EnvironmentService) ListOrphans(ctx context.Context, req *mcp_environment.ListOrphansRequest) (*mcp_environment.ListOrphansResponse, error) {
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, uint(req.Id))
+	if err != nil {
+		return nil, environmentQueryError(err, uint(req.Id))
+	}
+	orphans, err := biz.GEnvironmentBiz.ListOrphans(ctx, environment.ID)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeOrphanScanFailure)
+	}
+
+	minAge := orphanMinAge(req.MinAge)
+	now := time.Now()
+	list := make([]*mcp_environment.OrphanResource, 0, len(orphans))
+	for _, orphan := range orphans {
+		list = append(list, convertOrphanResource(orphan, now, minAge))
+	}
+	return &mcp_environment.ListOrphansResponse{
+		Id:     int32(environment.ID),
+		MinAge: int32(minAge / time.Second),
+		List:   list,
+	}, nil
+}
+
+// CleanupOrphansHandler 孤儿资源清理接口Handler，仅管理员可用
+func (s *EnvironmentService) CleanupOrphansHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var req mcp_environment.CleanupOrphansRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeEnvironmentIDInvalid, idStr))
+		return
+	}
+	req.Id = int32(id)
+
+	result, err := s.CleanupOrphans(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// CleanupOrphans 删除环境集群中的孤儿资源，需要请求确认，删除前重新扫描，不使用之前的扫描结果
+func (s *EnvironmentService) CleanupOrphans(ctx context.Context, req *mcp_environment.CleanupOrphansRequest) (*mcp_environment.CleanupOrphansResponse, error) {
+	if !req.Confirm {
+		return nil, common.NewError(i18nresp.CodeOrphanCleanupUnconfirmed)
+	}
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, uint(req.Id))
+	if err != nil {
+		return nil, environmentQueryError(err, uint(req.Id))
+	}
+
+	minAge := orphanMinAge(req.MinAge)
+	result, err := biz.GEnvironmentBiz.CleanupOrphans(ctx, environment.ID, minAge, req.Resources)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeOrphanCleanupFailure)
+	}
+
+	now := time.Now()
+	resp := &mcp_environment.CleanupOrphansResponse{
+		Id:      int32(environment.ID),
+		Deleted: make([]*mcp_environment.OrphanResource, 0, len(result.Deleted)),
+		Skipped: make([]*mcp_environment.OrphanResource, 0, len(result.Skipped)),
+		Failed:  make([]*mcp_environment.OrphanResource, 0, len(result.Failed)),
+	}
+	for _, orphan := range result.Deleted {
+		resp.Deleted = append(resp.Deleted, convertOrphanResource(orphan, now, minAge))
+	}
+	for _, orphan := range result.Skipped {
+		resp.Skipped = append(resp.Skipped, convertOrphanResource(orphan, now, minAge))
+	}
+	for _, failure := range result.Failed {
+		resource := convertOrphanResource(failure.Resource, now, minAge)
+		resource.Error = failure.Err.Error()
+		resp.Failed = append(resp.Failed, resource)
+	}
+	return resp, nil
+}
+
+// orphanMinAge 请求中的最小存在时长（秒），0 使用默认值
+func orphanMinAge(seconds int32) time.Duration {
+	if seconds == 0 {
+		seconds = defaultOrphanMinAge
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// convertOrphanResource 转换孤儿资源
+func convertOrphanResource(orphan *biz.OrphanResource, now time.Time, minAge time.Duration) *mcp_environment.OrphanResource {
+	age := orphan.Age(now)
+	return &mcp_environment.OrphanResource{
+		Kind:       orphan.Kind,
+		Name:       orphan.Name,
+		InstanceId: orphan.InstanceID,
+		CreatedAt:  orphan.CreatedAt.UTC().Format(time.RFC3339),
+		AgeSeconds: int64(age / time.Second),
+		Recent:     age < minAge,
+	}
+}
+
 // testEnvironmentConnectivity 执行环境连通性测试
 func testEnvironmentConnectivity(ctx context.Context, environment *model.McpEnvironment) (*mcp_environment.TestConnectivityResponse, error) {
 	// 使用数据层的连通性测试方法
@@ -619,13 +1006,13 @@ func testEnvironmentConnectivity(ctx context.Context, environment *model.McpEnvi
 func ListAllEnvironmentsHandler(c *gin.Context) {
 	environments, err := biz.GEnvironmentBiz.ListAllEnvironments(c.Request.Context())
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
 	var environmentInfos []*mcp_environment.McpEnvironmentInfo
 	for _, env := range environments {
-		environmentInfos = append(environmentInfos, modelToMcpEnvironmentInfo(env))
+		environmentInfos = append(environmentInfos, modelToMcpEnvironmentInfo(c.Request.Context(), env))
 	}
 
 	response := &mcp_environment.ListEnvironmentsResponse{
@@ -643,14 +1030,14 @@ func (s *EnvironmentService) ListNamespacesHandler(c *gin.Context) {
 	// 绑定请求参数
 	var req mcp_environment.ListNamespacesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("参数绑定失败: %s", err.Error()))
+		common.GinErrorFrom(c, common.ValidationErrors{common.InvalidJSON("body", err.Error())})
 		return
 	}
 
 	// 使用 EnvironmentService 处理请求
 	result, err := s.ListNamespaces(&req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -660,7 +1047,7 @@ func (s *EnvironmentService) ListNamespacesHandler(c *gin.Context) {
 // ListNamespaces 获取命名空间列表业务逻辑
 func (s *EnvironmentService) ListNamespaces(req *mcp_environment.ListNamespacesRequest) (*mcp_environment.ListNamespacesResponse, error) {
 	if req.Config == "" {
-		return nil, fmt.Errorf("config参数不能为空")
+		return nil, common.ErrRequiredField("config")
 	}
 
 	// 解析环境类型
@@ -671,7 +1058,7 @@ func (s *EnvironmentService) ListNamespaces(req *mcp_environment.ListNamespacesR
 	case mcp_environment.McpEnvironmentType_Docker:
 		environmentType = model.McpEnvironmentDocker
 	default:
-		return nil, fmt.Errorf("不支持的环境类型")
+		return nil, common.NewError(i18nresp.CodeEnvironmentTypeUnsupported)
 	}
 
 	// 调用业务逻辑
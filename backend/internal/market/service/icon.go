@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	iconpb "qm-mcp-server/api/market/icon"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/codepackage"
+	"qm-mcp-server/pkg/common"
+	i18nresp "qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/icon"
+	"qm-mcp-server/pkg/logger"
+)
+
+// iconCacheControl 图标按内容哈希命名，内容不会变化，浏览器和 CDN 可以长期缓存
+const iconCacheControl = "public, max-age=31536000, immutable"
+
+// iconSVGContentSecurityPolicy 直接打开 SVG 时禁止脚本和外部资源，作为清洗之外的兜底
+const iconSVGContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; img-src data:"
+
+// IconService provides icon upload and serving
+type IconService struct {
+	ctx context.Context
+}
+
+// NewIconService creates a new IconService instance
+func NewIconService(ctx context.Context) *IconService {
+	return &IconService{
+		ctx: ctx,
+	}
+}
+
+// iconPath 图标的访问路径，填入实例、模板的 iconPath
+func iconPath(name string) string {
+	return fmt.Sprintf("/%s/assets/icons/%s", strings.Trim(common.GetMarketRoutePrefix(), "/"), name)
+}
+
+// UploadIconHandler handles icon upload requests
+func (s *IconService) UploadIconHandler(c *gin.Context) {
+	fileHeader, err := c.FormFile("icon")
+	if err != nil {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconFileRequired))
+		return
+	}
+	maxSize := biz.GIconBiz.MaxFileSize()
+	if fileHeader.Size > maxSize {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconTooLarge, maxSize>>10))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeIconSaveFailure))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeIconSaveFailure))
+		return
+	}
+	if int64(len(data)) > maxSize {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconTooLarge, maxSize>>10))
+		return
+	}
+
+	saved, err := biz.GIconBiz.Upload(c.Request.Context(), data)
+	if err != nil {
+		var invalid *icon.ValidationError
+		if errors.As(err, &invalid) {
+			common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconInvalid, invalid.Reason))
+			return
+		}
+		logger.Error("Failed to save icon", zap.Error(err))
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeIconSaveFailure))
+		return
+	}
+
+	common.GinSuccess(c, &iconpb.UploadIconResponse{
+		Path:   iconPath(saved.Name),
+		Name:   saved.Name,
+		Size:   int64(len(saved.Data)),
+		Mime:   saved.ContentType,
+		Width:  int32(saved.Width),
+		Height: int32(saved.Height),
+	})
+}
+
+// GetIconHandler serves uploaded icons with long-lived cache headers, no login required
+func (s *IconService) GetIconHandler(c *gin.Context) {
+	name := c.Param("name")
+	if !icon.ValidName(name) {
+		common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconNotFound, name))
+		return
+	}
+
+	// 文件名即内容哈希，可直接作为 ETag
+	etag := `"` + strings.TrimSuffix(name, path.Ext(name)) + `"`
+	c.Header("Cache-Control", iconCacheControl)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	reader, err := biz.GIconBiz.Open(c.Request.Context(), name)
+	if err != nil {
+		if errors.Is(err, codepackage.ErrObjectNotFound) {
+			c.Header("Cache-Control", "no-store")
+			common.GinErrorFrom(c, common.NewError(i18nresp.CodeIconNotFound, name))
+			return
+		}
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeInternalError))
+		return
+	}
+	defer reader.Close()
+
+	contentType := icon.ContentType(name)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if contentType == "image/svg+xml" {
+		c.Header("Content-Security-Policy", iconSVGContentSecurityPolicy)
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, nil)
+}
@@ -3,24 +3,57 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	instancepb "qm-mcp-server/api/market/instance"
 	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/internal/market/config"
 	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
 	i18nresp "qm-mcp-server/pkg/i18n"
 
 	"qm-mcp-server/pkg/database/model"
 	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
 	"qm-mcp-server/pkg/utils"
 )
 
+// statusEventsHeartbeat interval of heartbeat comments on the status event stream, keeps proxies from closing idle connections
+const statusEventsHeartbeat = 15 * time.Second
+
+// 批量操作类型
+const (
+	bulkActionDisable = "disable"
+	bulkActionEnable  = "enable"
+	bulkActionRestart = "restart"
+	bulkActionDelete  = "delete"
+)
+
+// bulkConcurrency 批量操作同时处理的实例数，容器运行时的调用较重，保持较小的并发
+const bulkConcurrency = 5
+
+// defaultHealthHistoryLimit 健康检查历史默认返回的条数
+const defaultHealthHistoryLimit = 20
+
+// 协议检测的超时时间（秒），AUTO 创建时每个服务使用默认值
+const (
+	defaultDetectProtocolTimeout = 10
+	maxDetectProtocolTimeout     = 30
+)
+
 // InstanceService struct for instance service
 type InstanceService struct {
 	ctx context.Context
@@ -53,16 +86,19 @@ func (s *InstanceService) CreateHandler(c *gin.Context) {
 	if err := common.BindAndValidate(c, &req); err != nil {
 		return
 	}
-
-	// Validate required fields
-	if req.Name == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
-		return
+	if req.Format == utils.McpConfigFormatClient {
+		// 校验时已转换成功，创建使用转换后的标准配置
+		req.McpServers, req.EnvironmentVariables, _ = convertClientConfig(&req)
+		req.Format = ""
 	}
+
 	// Call write instance handler function
-	result, err := s.create(&req)
+	result, err := s.create(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("failed to write instance: %s", err.Error()))
+		if req.Suggest {
+			err = s.withNameSuggestion(err, req.Name)
+		}
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -79,14 +115,14 @@ func (s *InstanceService) DetailHandler(c *gin.Context) {
 
 	// 验证必填字段
 	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
 		return
 	}
 
 	// 调用获取实例详情处理函数
-	result, err := s.detail(&req)
+	result, err := s.detail(&req, common.RequestOrigin(c.Request), isAdmin(c))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("获取实例详情失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -101,79 +137,36 @@ func (s *InstanceService) EditHandler(c *gin.Context) {
 		return
 	}
 
-	// Validate required fields
-	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+	resp, err := s.edit(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
 		return
 	}
 
-	// 获取原始实例信息
-	oriInstance, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
-	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("获取实例信息失败: %s", err.Error()))
-		return
+	common.GinSuccess(c, resp)
+}
+
+// requireAdminForInsecureTLS 跳过上游证书校验只有管理员可以开启，编辑时已开启的实例保持原配置不需要管理员。
+// 操作用户取自上下文，创建和编辑的所有入口（HTTP、apply、应用市场部署）都经过此校验
+func requireAdminForInsecureTLS(ctx context.Context, mcpServers string, oriInstance *model.McpInstance) error {
+	var cfg model.McpServersConfig
+	if json.Unmarshal([]byte(mcpServers), &cfg) != nil || !cfg.InsecureTLS() {
+		return nil
 	}
-	if oriInstance == nil {
-		common.GinError(c, i18nresp.CodeInternalError, "实例不存在")
-		return
+	if oriInstance != nil && instanceInsecureTLS(oriInstance) {
+		return nil
 	}
-
-	var resp *instancepb.EditResp
-	switch oriInstance.AccessType {
-	case model.AccessTypeDirect:
-		// validate instance name
-		if len(req.Name) == 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
-			return
-		}
-		// validate instance mcpServers
-		if len(req.McpServers) == 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: mcpServers")
-			return
-		}
-		resp, err = biz.GInstanceBiz.UpdateInstanceForDirect(c.Request.Context(), &req, oriInstance)
-		if err != nil {
-			common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("编辑实例失败: %s", err.Error()))
-			return
-		}
-	case model.AccessTypeProxy:
-		// validate instance name
-		if len(req.Name) == 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
-			return
-		}
-		// validate instance mcpServers
-		if len(req.McpServers) == 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: mcpServers")
-			return
-		}
-		resp, err = biz.GInstanceBiz.UpdateInstanceForProxy(c.Request.Context(), &req, oriInstance)
-		if err != nil {
-			common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("编辑实例失败: %s", err.Error()))
-			return
-		}
-	case model.AccessTypeHosting:
-		// validate instance name
-		if len(req.Name) == 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
-			return
-		}
-		// validate instance port
-		if req.Port <= 0 {
-			common.GinError(c, i18nresp.CodeInternalError, "missing required field: port")
-			return
-		}
-		resp, err = biz.GInstanceBiz.UpdateInstanceForHosting(c.Request.Context(), &req, oriInstance)
-		if err != nil {
-			common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("编辑实例失败: %s", err.Error()))
-			return
-		}
-	default:
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("未知的访问类型: %s", oriInstance.AccessType))
-		return
+	user, err := mysql.SysUserRepo.FindByID(ctx, common.UserIDFromContext(ctx))
+	if err != nil || user == nil || !user.IsAdmin {
+		return common.NewError(i18nresp.CodeInsufficientPermissions)
 	}
+	return nil
+}
 
-	common.GinSuccess(c, resp)
+// instanceInsecureTLS 实例是否有服务跳过上游证书校验
+func instanceInsecureTLS(instance *model.McpInstance) bool {
+	_, servers, _, err := instance.GetTargetConfig()
+	return err == nil && servers.InsecureTLS()
 }
 
 // ListHandler instance list
@@ -184,9 +177,9 @@ func (s *InstanceService) ListHandler(c *gin.Context) {
 	}
 
 	// Use InstanceService to handle request
-	result, err := s.list(&req)
+	result, err := s.list(c.Request.Context(), &req, common.RequestOrigin(c.Request))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("获取实例列表失败: %s", err.Error()))
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeInstanceQueryFailure))
 		return
 	}
 
@@ -202,14 +195,14 @@ func (s *InstanceService) DisabledHandler(c *gin.Context) {
 
 	// Validate required fields
 	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
 		return
 	}
 
 	// Use InstanceService to handle request
-	result, err := s.disable(&req)
+	result, err := s.disable(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -224,14 +217,14 @@ func (s *InstanceService) RestartHandler(c *gin.Context) {
 	}
 	// Validate required fields
 	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
 		return
 	}
 
 	// Use InstanceService to handle request
-	result, err := s.restart(&req)
+	result, err := s.restart(c.Request.Context(), &req, common.RequestOrigin(c.Request))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -247,442 +240,2405 @@ func (s *InstanceService) DeleteHandler(c *gin.Context) {
 
 	// Validate required fields
 	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
 		return
 	}
 
 	// Use InstanceService to handle request
-	result, err := s.delete(req.InstanceId)
+	result, err := s.delete(c.Request.Context(), req.InstanceId)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
 	common.GinSuccess(c, result)
 }
 
-// StatusHandler query instance status handler
-func (s *InstanceService) StatusHandler(c *gin.Context) {
-	var req instancepb.GetStatusRequest
+// BulkHandler disable, enable, restart or delete multiple instances handler
+func (s *InstanceService) BulkHandler(c *gin.Context) {
+	var req instancepb.BulkRequest
 	if err := common.BindAndValidate(c, &req); err != nil {
 		return
 	}
 
-	// Validate required fields
-	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+	result, err := s.bulk(c.Request.Context(), &req, common.RequestOrigin(c.Request))
+	if err != nil {
+		common.GinErrorFrom(c, err)
 		return
 	}
 
-	// Use InstanceService to handle request
-	result, err := s.getStatus(&req)
+	common.GinSuccess(c, result)
+}
+
+// RedeployPackageHandler restarts the hosting instances still running an older version of a code package
+func (s *InstanceService) RedeployPackageHandler(c *gin.Context) {
+	var req instancepb.RedeployPackageRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.redeployPackage(c.Request.Context(), &req, common.RequestOrigin(c.Request))
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
 	common.GinSuccess(c, result)
 }
 
-// LogsHandler get managed instance logs handler
-func (s *InstanceService) LogsHandler(c *gin.Context) {
-	var req instancepb.LogsRequest
+// StatusHandler query instance status handler
+func (s *InstanceService) StatusHandler(c *gin.Context) {
+	var req instancepb.GetStatusRequest
 	if err := common.BindAndValidate(c, &req); err != nil {
 		return
 	}
 
 	// Validate required fields
 	if req.InstanceId == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: instanceId")
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
 		return
 	}
 
 	// Use InstanceService to handle request
-	result, err := s.getLogs(&req)
+	result, err := s.getStatus(c.Request.Context(), &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, err.Error())
+		common.GinErrorFrom(c, err)
 		return
 	}
 
 	common.GinSuccess(c, result)
 }
 
-// create writes instance method
-func (s *InstanceService) create(req *instancepb.CreateRequest) (*instancepb.CreateResp, error) {
+// BatchStatusHandler query status of multiple instances concurrently handler
+func (s *InstanceService) BatchStatusHandler(c *gin.Context) {
+	var req instancepb.BatchStatusRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
 
-	// Generate instance ID (UUID)
-	instanceID := uuid.New().String()
+	common.GinSuccess(c, s.batchStatus(c.Request.Context(), &req))
+}
 
-	// Hosting mode, Stdio protocol
-	switch req.AccessType {
-	case instancepb.AccessType_DIRECT:
-		return s.createInstanceDirectMode(req, instanceID)
-	case instancepb.AccessType_PROXY:
-		return s.createInstanceProxyMode(req, instanceID)
-	case instancepb.AccessType_HOSTING:
-		return s.createInstanceHosting(req, instanceID)
-	default:
-		return nil, fmt.Errorf("unsupported access type: %v", req.AccessType)
+// ScaleHandler scale hosting instance replicas handler
+func (s *InstanceService) ScaleHandler(c *gin.Context) {
+	var req instancepb.ScaleRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
-}
 
-// Detail 获取实例详情
-func (s *InstanceService) detail(req *instancepb.DetailRequest) (*instancepb.DetailResp, error) {
-	// 获取实例信息
-	instance, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
+	result, err := s.scale(c.Request.Context(), &req)
 	if err != nil {
-		return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		common.GinErrorFrom(c, err)
+		return
 	}
-	if instance == nil {
-		return nil, fmt.Errorf("实例不存在")
+
+	common.GinSuccess(c, result)
+}
+
+// ConnectionsHandler query instance gateway connections handler, admin only
+func (s *InstanceService) ConnectionsHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var req instancepb.ConnectionsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
 
-	// 转换访问类型
-	pbAccessType, err := common.ConvertToProtoAccessType(instance.AccessType)
+	result, err := s.connections(&req)
 	if err != nil {
-		return nil, fmt.Errorf("转换访问类型失败: %w", err)
+		common.GinErrorFrom(c, err)
+		return
 	}
 
-	// 转换MCP协议类型
-	pbMcpProtocol, err := common.ConvertToProtoMcpProtocol(instance.McpProtocol)
-	if err != nil {
-		return nil, fmt.Errorf("转换MCP协议类型失败: %w", err)
+	common.GinSuccess(c, result)
+}
+
+// DrainHandler drain instance gateway SSE connections handler, admin only
+func (s *InstanceService) DrainHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var req instancepb.DrainRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
 
-	// 构建响应
-	resp := &instancepb.DetailResp{
-		InstanceId:  instance.InstanceID,
-		Name:        instance.InstanceName,
-		Status:      string(instance.Status),
-		AccessType:  pbAccessType,
-		McpProtocol: pbMcpProtocol,
-		Notes:       instance.Notes,
-		IconPath:    instance.IconPath,
+	result, err := s.drain(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
 	}
 
-	// 根据访问类型添加特定字段
-	switch instance.AccessType {
-	case model.AccessTypeHosting:
-		resp.PackageId = instance.PackageID
-		resp.EnvironmentId = int32(instance.EnvironmentID)
-		resp.McpServerId = instance.McpServerID
-		resp.TemplateId = int32(instance.TemplateID)
-		resp.ImgAddress = instance.ImgAddr
-		resp.McpServers = string(instance.SourceConfig)
-		resp.Port = instance.Port
-		resp.InitScript = instance.InitScript
-		resp.Command = instance.Command
-		resp.ServicePath = instance.ServicePath
-		resp.ContainerName = instance.ContainerName
-		resp.ContainerServiceName = instance.ContainerServiceName
-		resp.ContainerStatus = string(instance.ContainerStatus)
-		resp.ContainerLastMessage = instance.ContainerLastMessage
-		resp.ContainerIsReady = instance.ContainerIsReady
+	common.GinSuccess(c, result)
+}
 
-		// 转换环境变量
-		if len(instance.EnvironmentVariables) > 0 {
-			envVarsMap := make(map[string]string)
-			if err := json.Unmarshal(instance.EnvironmentVariables, &envVarsMap); err == nil {
-				resp.EnvironmentVariables = envVarsMap
-			}
-		}
+// ResetCircuitBreakerHandler reset instance gateway circuit breaker handler, admin only
+func (s *InstanceService) ResetCircuitBreakerHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	var req instancepb.ResetCircuitBreakerRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
 
-		// 转换卷挂载
-		if len(instance.VolumeMounts) > 0 {
-			var volumeMounts []*instancepb.VolumeMount
-			if err := json.Unmarshal(instance.VolumeMounts, &volumeMounts); err == nil {
-				resp.VolumeMounts = volumeMounts
-			}
-		}
+	result, err := s.resetCircuitBreaker(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
 
-		// 转换令牌
-		resp.Tokens = common.ConvertToProtoMcpToken(instance.Tokens)
+	common.GinSuccess(c, result)
+}
 
-		// 转换公共代理配置
-		resp.PublicProxyConfig = string(instance.PublicProxyConfig)
+// RotateTokenHandler rotate instance token handler
+func (s *InstanceService) RotateTokenHandler(c *gin.Context) {
+	var req instancepb.RotateTokenRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
 
-	case model.AccessTypeDirect, model.AccessTypeProxy:
-		// 对于直连和代理模式，添加MCP服务器配置
-		if len(instance.SourceConfig) > 0 {
-			resp.McpServers = string(instance.SourceConfig)
-		}
+	result, err := s.rotateToken(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
 	}
 
-	return resp, nil
+	common.GinSuccess(c, result)
 }
 
-func (s *InstanceService) list(req *instancepb.ListRequest) (*instancepb.ListResp, error) {
-	// 参数验证
-	page := req.Page
-	if page <= 0 {
-		page = 1
-	}
-	pageSize := req.PageSize
-	if pageSize <= 0 {
-		pageSize = int32(common.DefaultPageSize)
-	}
-	if pageSize > int32(common.MaxPageSize) {
-		pageSize = int32(common.MaxPageSize)
+// ConnectionHandler render a ready-to-paste client configuration of the instance
+func (s *InstanceService) ConnectionHandler(c *gin.Context) {
+	var req instancepb.ConnectionRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
 
-	// Build filter conditions
-	filters := make(map[string]interface{})
-	if req.InstanceName != "" {
-		filters["instanceName"] = req.InstanceName
-	}
-	if req.EnvironmentId > 0 {
-		filters["environmentId"] = req.EnvironmentId
-	}
-	if req.Status != "" {
-		filters["status"] = req.Status
-	}
-	if req.ContainerStatus != "" {
-		filters["containerStatus"] = req.ContainerStatus
-	}
-	if req.AccessType > 0 {
-		accessType, err := common.ConvertToModelAccessType(req.AccessType)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert access type: %w", err)
-		}
-		filters["accessType"] = accessType
-	}
-	if req.McpProtocol > 0 {
-		mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert mcp protocol: %w", err)
-		}
-		filters["mcpProtocol"] = mcpProtocol
+	result, err := s.connection(c.Request.Context(), &req, common.RequestOrigin(c.Request))
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
 	}
 
-	// Sort parameters
-	sortBy := "createdAt"
-	sortOrder := "desc"
-
-	return biz.GInstanceBiz.ListInstance(page, pageSize, filters, sortBy, sortOrder)
+	common.GinSuccess(c, result)
 }
 
-// GetLogs get instance logs
-func (s *InstanceService) getLogs(req *instancepb.LogsRequest) (*instancepb.LogsResp, error) {
-	// Set default number of lines
-	lines := req.Lines
-	if lines <= 0 {
-		lines = 100
+// EventsHandler query instance historical events handler
+func (s *InstanceService) EventsHandler(c *gin.Context) {
+	var req instancepb.EventsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
 
-	instance, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
+	result, err := s.listEvents(c.Request.Context(), &req)
 	if err != nil {
-		return nil, fmt.Errorf("获取实例信息失败: %v", err)
-	}
-	if instance == nil {
-		return nil, fmt.Errorf("实例不存在")
+		common.GinErrorFrom(c, err)
+		return
 	}
 
-	var response instancepb.LogsResp
-	response.InstanceId = req.InstanceId
+	common.GinSuccess(c, result)
+}
 
-	// Check if it is a managed instance
-	if instance.AccessType != model.AccessTypeHosting {
-		response.IsManaged = false
-		response.Message = "Instance is not of managed type"
-		return &response, nil
+// TimelineHandler query instance operation timeline handler
+func (s *InstanceService) TimelineHandler(c *gin.Context) {
+	var req instancepb.TimelineRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
 	}
 
-	response.IsManaged = true
-
-	// Get environment information
-	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, instance.EnvironmentID)
+	result, err := s.timeline(c.Request.Context(), &req)
 	if err != nil {
-		response.Message = fmt.Sprintf("Failed to get environment information: %v", err)
-		return &response, nil
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// DriftHandler instance config drift handler
+func (s *InstanceService) DriftHandler(c *gin.Context) {
+	var req instancepb.DriftRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if req.InstanceId == "" {
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
+		return
+	}
+
+	result, err := s.drift(c.Request.Context(), req.InstanceId)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ProvenanceHandler instance provenance handler
+func (s *InstanceService) ProvenanceHandler(c *gin.Context) {
+	var req instancepb.ProvenanceRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if req.InstanceId == "" {
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
+		return
+	}
+
+	result, err := s.provenance(c.Request.Context(), req.InstanceId)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ScanHandler scan the image of a hosting instance for vulnerabilities handler
+func (s *InstanceService) ScanHandler(c *gin.Context) {
+	var req instancepb.ScanRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.scan(c.Request.Context(), req.InstanceId)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// LockHandler lock instance handler
+func (s *InstanceService) LockHandler(c *gin.Context) {
+	var req instancepb.LockRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.lock(c.Request.Context(), req.InstanceId, true, req.Reason, c.GetString("username"), false)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// UnlockHandler unlock instance handler, only the user who locked the instance or an admin can unlock it
+func (s *InstanceService) UnlockHandler(c *gin.Context) {
+	var req instancepb.UnlockRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.lock(c.Request.Context(), req.InstanceId, false, "", c.GetString("username"), isAdmin(c))
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// HealthMonitorHandler 设置直连和代理实例的定时健康检查
+func (s *InstanceService) HealthMonitorHandler(c *gin.Context) {
+	var req instancepb.HealthMonitorRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.setHealthMonitor(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// HealthHistoryHandler 查询实例最近的健康检查结果
+func (s *InstanceService) HealthHistoryHandler(c *gin.Context) {
+	var req instancepb.HealthHistoryRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.healthHistory(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// HistoryHandler 分页查询实例的字段级修改历史
+func (s *InstanceService) HistoryHandler(c *gin.Context) {
+	var req instancepb.InstanceHistoryRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.history(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// LabelsHandler 查询实例已使用的标签键和值，用于标签自动补全
+func (s *InstanceService) LabelsHandler(c *gin.Context) {
+	var req instancepb.LabelsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.labels(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ValidateConfigHandler validate mcpServers configuration handler, reports every failing field
+func (s *InstanceService) ValidateConfigHandler(c *gin.Context) {
+	var req instancepb.ValidateConfigRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	common.GinSuccess(c, s.validateConfig(&req))
+}
+
+// ConvertConfigHandler convert a client MCP configuration (Claude Desktop, Cursor, VS Code ...) into
+// the canonical mcpServers configuration, reports every transformation and the validation result
+func (s *InstanceService) ConvertConfigHandler(c *gin.Context) {
+	var req instancepb.ConvertConfigRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	common.GinSuccess(c, s.convertConfig(&req))
+}
+
+// DetectProtocolHandler probe an MCP server URL and report whether it speaks SSE or streamable HTTP
+func (s *InstanceService) DetectProtocolHandler(c *gin.Context) {
+	var req instancepb.DetectProtocolRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	common.GinSuccess(c, s.detectProtocol(c.Request.Context(), &req))
+}
+
+// ValidateScriptHandler validate init script and startup command handler, scripts are parsed but never executed
+func (s *InstanceService) ValidateScriptHandler(c *gin.Context) {
+	var req instancepb.ValidateScriptRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	common.GinSuccess(c, s.validateScript(c.Request.Context(), &req))
+}
+
+// StatusEventsHandler stream instance status changes as server-sent events for the admin UI
+// Slow consumers are disconnected by the feed, clients reconnect and reload the list
+func (s *InstanceService) StatusEventsHandler(c *gin.Context) {
+	var req instancepb.StatusEventsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	sub := biz.GInstanceStatusFeed.Subscribe(splitInstanceIDs(req.InstanceIds))
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(statusEventsHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-sub.Dropped():
+			return false
+		case event := <-sub.Events():
+			c.SSEvent("status", event)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
+
+// splitInstanceIDs 解析逗号分隔的实例ID，忽略空白项
+func splitInstanceIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// LogsHandler get managed instance logs handler
+func (s *InstanceService) LogsHandler(c *gin.Context) {
+	var req instancepb.LogsRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	// Validate required fields
+	if req.InstanceId == "" {
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
+		return
+	}
+
+	// Use InstanceService to handle request
+	result, err := s.getLogs(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// DownloadLogsHandler download instance container logs handler, streams the logs as a text file attachment
+func (s *InstanceService) DownloadLogsHandler(c *gin.Context) {
+	var req instancepb.LogsDownloadRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if req.InstanceId == "" {
+		common.GinErrorFrom(c, common.ErrRequiredField("instanceId"))
+		return
+	}
+
+	instance, logs, err := s.streamLogs(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	defer logs.Close()
+
+	// 实例名称可能包含非 ASCII 字符，文件名使用实例ID
+	suffix := time.Now().Format("20060102150405")
+	if req.Previous {
+		suffix = "previous-" + suffix
+	}
+	fileName := fmt.Sprintf("%s-%s.log", instance.InstanceID, suffix)
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Status(http.StatusOK)
+
+	// 分块写出并逐块刷新，日志不在内存中整体缓存
+	buf := make([]byte, 32*1024)
+	c.Stream(func(w io.Writer) bool {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return false
+			}
+		}
+		if err != nil && err != io.EOF {
+			logger.Warn("Failed to stream instance logs", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		}
+		return err == nil
+	})
+}
+
+// create writes instance method
+func (s *InstanceService) create(ctx context.Context, req *instancepb.CreateRequest) (*instancepb.CreateResp, error) {
+	if err := requireAdminForInsecureTLS(ctx, req.McpServers, nil); err != nil {
+		return nil, err
+	}
+
+	// 名称冲突时在创建容器等操作之前返回
+	if err := biz.GInstanceBiz.CheckInstanceName(s.ctx, req.Name, ""); err != nil {
+		return nil, err
+	}
+	if err := biz.GInstanceBiz.CheckInstanceSlug(ctx, req.Slug, ""); err != nil {
+		return nil, err
+	}
+	if err := biz.GUserQuotaBiz.CheckInstanceQuota(ctx, req.AccessType == instancepb.AccessType_HOSTING); err != nil {
+		return nil, err
+	}
+
+	if req.McpProtocol == instancepb.McpProtocol_AUTO {
+		if err := resolveAutoProtocol(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate instance ID (UUID)
+	instanceID := uuid.New().String()
+
+	// Hosting mode, Stdio protocol
+	var resp *instancepb.CreateResp
+	var err error
+	switch req.AccessType {
+	case instancepb.AccessType_DIRECT:
+		resp, err = s.createInstanceDirectMode(ctx, req, instanceID)
+	case instancepb.AccessType_PROXY:
+		resp, err = s.createInstanceProxyMode(ctx, req, instanceID)
+	case instancepb.AccessType_HOSTING:
+		resp, err = s.createInstanceHosting(ctx, req, instanceID)
+	default:
+		return nil, common.NewError(i18nresp.CodeUnsupportedAccessType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return resp, nil
+	}
+	biz.GInstanceBiz.SlugChanged(ctx, instanceID, "", req.Slug)
+	biz.GInstanceOperationBiz.Record(ctx, instanceID, model.InstanceOperationCreate, fmt.Sprintf("access type: %s", req.AccessType))
+	return resp, nil
+}
+
+// applyRequestTimeouts 将请求中的网关代理超时写入实例目标配置，覆盖 mcpServers 中的设置
+func applyRequestTimeouts(instance *model.McpInstance, timeout, sseReadTimeout int32) error {
+	targetConfig, err := common.ApplyRequestTimeouts(instance.TargetConfig, timeout, sseReadTimeout)
+	if err != nil {
+		return common.WrapError(err, i18nresp.CodeGetTargetConfigFailure)
+	}
+	instance.TargetConfig = targetConfig
+	return nil
+}
+
+// marshalLabels 序列化实例标签，未设置标签时保存为 NULL
+func marshalLabels(labels map[string]string) json.RawMessage {
+	if len(labels) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(labels)
+	return b
+}
+
+// instanceSlug 网关自定义访问路径，未设置时保存为 NULL，唯一索引不限制多个未设置的实例
+func instanceSlug(slug string) *string {
+	if slug == "" {
+		return nil
+	}
+	return &slug
+}
+
+// withNameSuggestion 实例名称冲突时在错误响应的 data 中附带下一个可用的名称
+func (s *InstanceService) withNameSuggestion(err error, name string) error {
+	e, ok := common.AsError(err)
+	if !ok || e.Code != i18nresp.CodeInstanceNameAlreadyExists {
+		return err
+	}
+	suggestedName, suggestErr := biz.GInstanceBiz.SuggestInstanceName(s.ctx, name)
+	if suggestErr != nil {
+		logger.Warn("生成实例名称建议失败", zap.String("name", name), zap.Error(suggestErr))
+		return err
+	}
+	return &common.Error{Code: e.Code, Args: e.Args, Err: e.Err, Data: map[string]string{"suggestedName": suggestedName}}
+}
+
+// Detail 获取实例详情，origin 为请求来源，用于选择公共代理地址的访问域名
+// admin 为 true 时返回占位符解析后的环境变量
+func (s *InstanceService) detail(req *instancepb.DetailRequest, origin string, admin bool) (*instancepb.DetailResp, error) {
+	// 获取实例信息
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.buildDetail(instance, origin, true)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecentTimeline = biz.GInstanceOperationBiz.Recent(s.ctx, instance.InstanceID)
+	if admin {
+		resp.ResolvedEnvironmentVariables = resolvedEnvVars(instance, resp.EnvironmentVariables)
+	}
+	return resp, nil
+}
+
+// resolvedEnvVars 从容器创建选项中获取使用了占位符的环境变量的实际值
+func resolvedEnvVars(instance *model.McpInstance, envVars map[string]string) map[string]string {
+	var containerOptions container.ContainerCreateOptions
+	if err := json.Unmarshal(instance.ContainerCreateOptions, &containerOptions); err != nil {
+		return nil
+	}
+	var resolved map[string]string
+	for k, v := range envVars {
+		if !common.HasEnvPlaceholder(v) {
+			continue
+		}
+		if value, ok := containerOptions.EnvVars[k]; ok {
+			if resolved == nil {
+				resolved = make(map[string]string)
+			}
+			resolved[k] = value
+		}
+	}
+	return resolved
+}
+
+// buildDetail 构建实例详情，probe 为 true 时探测直连和代理实例的 MCP 服务
+func (s *InstanceService) buildDetail(instance *model.McpInstance, origin string, probe bool) (*instancepb.DetailResp, error) {
+	// 转换访问类型
+	pbAccessType, err := common.ConvertToProtoAccessType(instance.AccessType)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
+	}
+
+	// 转换MCP协议类型
+	pbMcpProtocol, err := common.ConvertToProtoMcpProtocol(instance.McpProtocol)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
+	}
+
+	// 构建响应
+	resp := &instancepb.DetailResp{
+		InstanceId:  instance.InstanceID,
+		Name:        instance.InstanceName,
+		Status:      string(instance.Status),
+		AccessType:  pbAccessType,
+		McpProtocol: pbMcpProtocol,
+		Notes:       instance.Notes,
+		IconPath:    instance.IconPath,
+		Labels:      instance.GetLabels(),
+		Metadata:    string(instance.Metadata),
+		Slug:        instance.GetSlug(),
+		Locked:      instance.Locked,
+		LockReason:  instance.LockReason,
+		LockedBy:    instance.LockedBy,
+		InsecureTls: instanceInsecureTLS(instance),
+	}
+	if instance.LockedAt != nil {
+		resp.LockedAt = common.FormatTimeRFC3339(s.ctx, *instance.LockedAt)
+		resp.LockedAtMs = common.TimeMillis(*instance.LockedAt)
+	}
+	if instance.AccessType != model.AccessTypeDirect {
+		resp.Timeout, resp.SseReadTimeout = common.RequestTimeouts(instance.TargetConfig)
+	}
+
+	// 根据访问类型添加特定字段
+	switch instance.AccessType {
+	case model.AccessTypeHosting:
+		resp.PackageId = instance.PackageID
+		resp.EnvironmentId = int32(instance.EnvironmentID)
+		resp.McpServerId = instance.McpServerID
+		resp.TemplateId = int32(instance.TemplateID)
+		resp.ImgAddress = instance.ImgAddr
+		resp.McpServers = string(instance.SourceConfig)
+		resp.Port = instance.Port
+		resp.InitScript = instance.InitScript
+		resp.Command = instance.Command
+		resp.ServicePath = instance.ServicePath
+		resp.ContainerName = instance.ContainerName
+		resp.ContainerServiceName = instance.ContainerServiceName
+		resp.ContainerStatus = string(instance.ContainerStatus)
+		resp.ContainerLastMessage = instance.ContainerLastMessage
+		resp.ContainerIsReady = instance.ContainerIsReady
+		resp.Replicas = instance.Replicas
+
+		// 转换环境变量
+		if len(instance.EnvironmentVariables) > 0 {
+			envVarsMap := make(map[string]string)
+			if err := json.Unmarshal(instance.EnvironmentVariables, &envVarsMap); err == nil {
+				resp.EnvironmentVariables = envVarsMap
+			}
+		}
+
+		// 转换卷挂载
+		if len(instance.VolumeMounts) > 0 {
+			var volumeMounts []*instancepb.VolumeMount
+			if err := json.Unmarshal(instance.VolumeMounts, &volumeMounts); err == nil {
+				resp.VolumeMounts = volumeMounts
+			}
+		}
+
+		// 转换初始化容器
+		if len(instance.InitContainers) > 0 {
+			var initContainers []*instancepb.InitContainer
+			if err := json.Unmarshal(instance.InitContainers, &initContainers); err == nil {
+				resp.InitContainers = initContainers
+			}
+		}
+		resp.InitSharedPath = instance.InitSharedPath
+
+		// 调度设置和创建时继承的环境默认值
+		var containerOptions container.ContainerCreateOptions
+		if err := json.Unmarshal(instance.ContainerCreateOptions, &containerOptions); err == nil {
+			resp.PriorityClassName = containerOptions.PriorityClassName
+			resp.PodDisruptionBudget = containerOptions.DisruptionBudget
+			resp.PodAffinity = biz.PodAffinityRules(containerOptions.PodAffinity)
+			resp.AllowedEgress = containerOptions.AllowedEgress
+			resp.ServiceAccountName = containerOptions.ServiceAccount
+			resp.IsolateServiceAccount = containerOptions.IsolateAccount
+		}
+		if instance.PackageID != "" {
+			outdated, err := biz.GCodePackageBiz.OutdatedInstanceIDs(s.ctx, []*model.McpInstance{instance})
+			if err != nil {
+				logger.Warn("Failed to check outdated code package", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+			}
+			resp.CodeOutdated = outdated[instance.InstanceID]
+		}
+		if len(containerOptions.InheritedDefaults) > 0 {
+			defaults := biz.InheritedDefaults(containerOptions)
+			resp.InheritedDefaults = &instancepb.InheritedDefaults{
+				EnvVars:                   defaults.EnvVars,
+				NodeSelector:              defaults.NodeSelector,
+				ResourceRequests:          defaults.ResourceRequests,
+				ResourceLimits:            defaults.ResourceLimits,
+				ImagePullSecrets:          defaults.ImagePullSecrets,
+				Labels:                    defaults.Labels,
+				PriorityClassName:         defaults.PriorityClassName,
+				CreatePodDisruptionBudget: defaults.CreatePodDisruptionBudget,
+				IsolateServiceAccount:     defaults.IsolateServiceAccount,
+			}
+		}
+		resp.ImageScan = imageScanToPb(s.ctx, biz.GContainerBiz.GetImageScan(instance))
+
+		// 转换边车容器
+		if len(instance.Sidecars) > 0 {
+			var sidecars []*instancepb.SidecarContainer
+			if err := json.Unmarshal(instance.Sidecars, &sidecars); err == nil {
+				resp.Sidecars = sidecars
+			}
+		}
+
+		// 转换令牌
+		resp.Tokens = common.ConvertToProtoMcpToken(instance.Tokens)
+
+		// 转换公共代理配置
+		resp.PublicProxyConfig = string(common.ResolvePublicProxyConfig(instance.PublicProxyConfig, origin))
+
+	case model.AccessTypeDirect, model.AccessTypeProxy:
+		// 对于直连和代理模式，添加MCP服务器配置
+		if len(instance.SourceConfig) > 0 {
+			resp.McpServers = string(instance.SourceConfig)
+		}
+
+		// 健康检查配置和最近一次检查结果
+		resp.HealthMonitor = healthMonitorToPb(instance.GetHealthMonitor())
+		resp.HealthStatus = instance.HealthStatus
+		if instance.HealthCheckedAt != nil {
+			resp.HealthCheckedAt = common.FormatTimeRFC3339(s.ctx, *instance.HealthCheckedAt)
+			resp.HealthCheckedAtMs = common.TimeMillis(*instance.HealthCheckedAt)
+		}
+		// 探测每个 MCP 服务，配置无法解析时不影响详情返回
+		if !probe {
+			break
+		}
+		if servers, err := biz.GInstanceBiz.ProbeServers(s.ctx, instance); err == nil {
+			resp.Servers = servers
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *InstanceService) list(ctx context.Context, req *instancepb.ListRequest, origin string) (*instancepb.ListResp, error) {
+	// 参数验证
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = int32(common.DefaultPageSize)
+	}
+	if pageSize > int32(common.MaxPageSize) {
+		pageSize = int32(common.MaxPageSize)
+	}
+
+	// Build filter conditions
+	filters := make(map[string]interface{})
+	if req.InstanceName != "" {
+		filters["instanceName"] = req.InstanceName
+	}
+	if req.EnvironmentId > 0 {
+		filters["environmentId"] = req.EnvironmentId
+	}
+	if req.Status != "" {
+		filters["status"] = req.Status
+	}
+	if req.ContainerStatus != "" {
+		filters["containerStatus"] = req.ContainerStatus
+	}
+	if req.AccessType > 0 {
+		accessType, err := common.ConvertToModelAccessType(req.AccessType)
+		if err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
+		}
+		filters["accessType"] = accessType
+	}
+	if req.McpProtocol > 0 {
+		mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol)
+		if err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
+		}
+		filters["mcpProtocol"] = mcpProtocol
+	}
+	if req.LabelSelector != "" {
+		// 选择器已在请求校验时检查过格式
+		requirements, _ := common.ParseLabelSelector(req.LabelSelector)
+		filters["labelSelector"] = requirements
+	}
+	if req.MetadataSelector != "" {
+		requirements, _ := common.ParseMetadataSelector(req.MetadataSelector)
+		filters["metadataSelector"] = requirements
+	}
+
+	// Sort parameters
+	sortBy := "createdAt"
+	sortOrder := "desc"
+
+	resp, err := biz.GInstanceBiz.ListInstance(ctx, page, pageSize, filters, sortBy, sortOrder)
+	if err != nil {
+		return nil, err
+	}
+	// 按请求来源解析公共代理地址
+	for _, instanceInfo := range resp.List {
+		instanceInfo.PublicProxyConfig = string(common.ResolvePublicProxyConfig(json.RawMessage(instanceInfo.PublicProxyConfig), origin))
+	}
+	return resp, nil
+}
+
+// streamLogs validates the download request and opens the container log stream of a hosting instance
+func (s *InstanceService) streamLogs(ctx context.Context, req *instancepb.LogsDownloadRequest) (*model.McpInstance, io.ReadCloser, error) {
+	params := biz.ContainerLogStreamParams{InstanceID: req.InstanceId, Previous: req.Previous, Container: req.Container}
+	v := &common.Validation{}
+	if req.SinceTime != "" {
+		if t, err := time.Parse(time.RFC3339, req.SinceTime); err != nil {
+			v.Add(common.Invalid("sinceTime", "must be an RFC3339 time"))
+		} else {
+			params.SinceTime = &t
+		}
+	}
+	if req.UntilTime != "" {
+		if t, err := time.Parse(time.RFC3339, req.UntilTime); err != nil {
+			v.Add(common.Invalid("untilTime", "must be an RFC3339 time"))
+		} else {
+			params.UntilTime = &t
+		}
+	}
+	if params.SinceTime != nil && params.UntilTime != nil && !params.UntilTime.After(*params.SinceTime) {
+		v.Add(common.Invalid("untilTime", "must be after sinceTime"))
+	}
+	if err := v.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if instance.AccessType != model.AccessTypeHosting {
+		return nil, nil, common.NewError(i18nresp.CodeInstanceNotManaged)
+	}
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, nil, common.WrapError(err, i18nresp.CodeGetEnvironmentFailure)
+	}
+	if environment.Environment != model.McpEnvironmentKubernetes {
+		return nil, nil, common.NewError(i18nresp.CodeLogsRequireKubernetes)
+	}
+
+	logs, err := biz.GContainerBiz.StreamContainerLogs(ctx, params)
+	if err != nil {
+		return nil, nil, common.WrapError(err, i18nresp.CodeGetInstanceLogsFailure)
+	}
+	return instance, logs, nil
+}
+
+// GetLogs get instance logs
+func (s *InstanceService) getLogs(ctx context.Context, req *instancepb.LogsRequest) (*instancepb.LogsResp, error) {
+	// Set default number of lines
+	lines := req.Lines
+	if lines <= 0 {
+		lines = 100
+	}
+
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	var response instancepb.LogsResp
+	response.InstanceId = req.InstanceId
+
+	// Check if it is a managed instance
+	if instance.AccessType != model.AccessTypeHosting {
+		response.IsManaged = false
+		response.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceNotManaged)
+		return &response, nil
+	}
+
+	response.IsManaged = true
+
+	// Get environment information
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, instance.EnvironmentID)
+	if err != nil {
+		response.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeGetEnvironmentFailure, err)
+		return &response, nil
+	}
+
+	// Validate environment type
+	if environment.Environment != model.McpEnvironmentKubernetes {
+		response.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeLogsRequireKubernetes)
+		return &response, nil
+	}
+
+	// Get container logs
+	logs, err := biz.GContainerBiz.GetContainerLogs(biz.ContainerLogsParams{
+		InstanceID: req.InstanceId,
+		Lines:      int64(lines),
+		Previous:   req.Previous,
+		Container:  req.Container,
+	})
+	if err != nil {
+		response.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeGetInstanceLogsFailure, err)
+		return &response, nil
+	}
+
+	response.Logs = logs
+	response.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceLogsSuccess)
+
+	return &response, nil
+}
+
+// GetStatus retrieves the status of an instance
+func (s *InstanceService) getStatus(ctx context.Context, req *instancepb.GetStatusRequest) (*instancepb.GetStatusResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *instancepb.GetStatusResp
+
+	// Use different status query strategies based on access type
+	switch instance.AccessType {
+	case model.AccessTypeHosting:
+		// Queued containers do not exist in the cluster yet
+		if instance.ContainerStatus == model.ContainerStatusQueued {
+			response = &instancepb.GetStatusResp{
+				InstanceId:    instance.InstanceID,
+				Status:        string(instance.Status),
+				ContainerName: instance.ContainerName,
+				ErrorMessage:  instance.ContainerLastMessage,
+				TotalReplicas: instance.DesiredReplicas(),
+				QueuePosition: biz.GContainerBiz.QueuePosition(instance.EnvironmentID, instance.InstanceID),
+			}
+			break
+		}
+		// Hosting type: query container status
+		params := biz.ContainerStatusParams{
+			InstanceID: req.InstanceId,
+		}
+		result, err := biz.GContainerBiz.GetContainerStatus(ctx, params)
+		if err != nil {
+			return nil, common.ErrContainerRuntime(err)
+		}
+
+		response = result
+	case model.AccessTypeProxy, model.AccessTypeDirect:
+		// Use HTTP probe to check availability of every server
+		servers, err := biz.GInstanceBiz.ProbeServers(ctx, instance)
+		if err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeGetTargetConfigFailure)
+		}
+
+		// Build response, the instance is reachable only when every server is
+		response = &instancepb.GetStatusResp{
+			InstanceId: req.InstanceId,
+			Status:     string(instance.Status),
+			ProbeHttp:  true,
+			Servers:    servers,
+		}
+		for _, server := range servers {
+			if !server.ProbeHttp {
+				response.ProbeHttp = false
+			}
+		}
+	default:
+		return nil, common.NewError(i18nresp.CodeUnsupportedAccessType)
+	}
+
+	// 直连实例不经过网关，没有熔断状态
+	if instance.AccessType != model.AccessTypeDirect {
+		response.CircuitBreaker = biz.GInstanceBiz.GetCircuitBreakerStatus(instance.InstanceID)
+	}
+
+	return response, nil
+}
+
+// batchStatus checks instances concurrently with a bounded worker pool,
+// every check has its own timeout and checks unfinished at the batch deadline are returned as errors
+func (s *InstanceService) batchStatus(ctx context.Context, req *instancepb.BatchStatusRequest) *instancepb.BatchStatusResp {
+	cfg := config.GlobalConfig.Status
+	checkTimeout := time.Duration(cfg.CheckTimeout) * time.Second
+	deadline := time.Duration(cfg.BatchDeadline) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	resp := &instancepb.BatchStatusResp{Statuses: make(map[string]*instancepb.BatchStatusResult, len(req.InstanceIds))}
+	var mu sync.Mutex
+	setResult := func(instanceID string, result *instancepb.BatchStatusResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		resp.Statuses[instanceID] = result
+	}
+
+	// 同一环境的实例复用缓存的运行时入口，并发数由 status.batchConcurrency 限制
+	semaphore := make(chan struct{}, cfg.BatchConcurrency)
+	var wg sync.WaitGroup
+	for _, instanceID := range slices.Compact(slices.Sorted(slices.Values(req.InstanceIds))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				setResult(instanceID, &instancepb.BatchStatusResult{
+					Error: i18nresp.FormatWithContext(ctx, i18nresp.CodeStatusBatchDeadline, cfg.BatchDeadline),
+				})
+				return
+			}
+			defer func() { <-semaphore }()
+			setResult(instanceID, s.checkStatus(ctx, instanceID, checkTimeout))
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range resp.Statuses {
+		if result.Status == nil && ctx.Err() != nil {
+			resp.Partial = true
+			break
+		}
+	}
+	return resp
+}
+
+// checkStatus queries the status of one instance, returning an error result once timeout elapses
+// even if the runtime query does not honour the context
+func (s *InstanceService) checkStatus(ctx context.Context, instanceID string, timeout time.Duration) *instancepb.BatchStatusResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan *instancepb.BatchStatusResult, 1)
+	go func() {
+		status, err := s.getStatus(ctx, &instancepb.GetStatusRequest{InstanceId: instanceID})
+		if err != nil {
+			done <- &instancepb.BatchStatusResult{Error: applyErrorMessage(ctx, err)}
+			return
+		}
+		done <- &instancepb.BatchStatusResult{Status: status}
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return &instancepb.BatchStatusResult{
+			Error: i18nresp.FormatWithContext(ctx, i18nresp.CodeStatusCheckTimeout, int(timeout.Seconds())),
+		}
+	}
+}
+
+// connections sums the SSE connections and streamable HTTP sessions of an instance reported by every gateway
+func (s *InstanceService) connections(req *instancepb.ConnectionsRequest) (*instancepb.ConnectionsResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	gateways, err := biz.GInstanceBiz.GetConnections(instance.InstanceID)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceConnectionsFailure)
+	}
+
+	resp := &instancepb.ConnectionsResp{
+		InstanceId: instance.InstanceID,
+		Gateways:   gateways,
+	}
+	for _, gateway := range gateways {
+		resp.SseConnections += gateway.SseConnections
+		resp.Sessions += gateway.Sessions
+	}
+	return resp, nil
+}
+
+// drain asks every gateway to close the SSE connections of an instance
+func (s *InstanceService) drain(ctx context.Context, req *instancepb.DrainRequest) (*instancepb.DrainResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := biz.GInstanceBiz.DrainSSEConnections(instance.InstanceID); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceDrainFailure)
+	}
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationDrain, "")
+
+	return &instancepb.DrainResp{
+		InstanceId: instance.InstanceID,
+		Message:    i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceDrainSuccess),
+	}, nil
+}
+
+// resetCircuitBreaker closes the gateway circuit breaker of an instance
+func (s *InstanceService) resetCircuitBreaker(ctx context.Context, req *instancepb.ResetCircuitBreakerRequest) (*instancepb.ResetCircuitBreakerResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := biz.GInstanceBiz.ResetCircuitBreaker(instance.InstanceID); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeCircuitBreakerResetFailure)
+	}
+
+	return &instancepb.ResetCircuitBreakerResp{
+		InstanceId: instance.InstanceID,
+		Message:    i18nresp.FormatWithContext(ctx, i18nresp.CodeCircuitBreakerResetSuccess),
+	}, nil
+}
+
+// rotateToken replaces an instance token, the previous token stays valid for the grace period
+func (s *InstanceService) rotateToken(ctx context.Context, req *instancepb.RotateTokenRequest) (*instancepb.RotateTokenResp, error) {
+	instance, err := s.getEditableInstance(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := biz.GTokenExpiryBiz.Rotate(ctx, instance, req.Token, req.Scope, time.Duration(req.GracePeriod)*time.Hour)
+	switch {
+	case errors.Is(err, biz.ErrTokenNotFound):
+		return nil, common.NewError(i18nresp.CodeInstanceTokenNotFound)
+	case errors.Is(err, biz.ErrTokenRotating):
+		return nil, common.NewError(i18nresp.CodeInstanceTokenRotating, formatTokenTime(ctx, rotatedAt(instance, req.Token)))
+	case err != nil:
+		return nil, common.WrapError(err, i18nresp.CodeTokenRotateFailure)
+	}
+
+	now := time.Now()
+	window := common.TokenWarningWindow()
+	return &instancepb.RotateTokenResp{
+		InstanceId: instance.InstanceID,
+		Token:      common.ConvertToProtoTokenAt(&rotation.Token, now, window),
+		Previous:   common.ConvertToProtoTokenAt(&rotation.Previous, now, window),
+		Message:    i18nresp.FormatWithContext(ctx, i18nresp.CodeTokenRotateSuccess, formatTokenTime(ctx, rotation.Previous.ExpireAt)),
+	}, nil
+}
+
+// connectionTokenPlaceholder 实例需要令牌但未选择令牌时写入连接配置的占位符
+const connectionTokenPlaceholder = "<YOUR_TOKEN>"
+
+// connection renders the client configuration of an instance from its public proxy config, the selected or
+// newly generated token is written in plain text into the Authorization header and recorded in the audit log
+func (s *InstanceService) connection(ctx context.Context, req *instancepb.ConnectionRequest, origin string) (*instancepb.ConnectionResp, error) {
+	var instance *model.McpInstance
+	var err error
+	if req.NewToken {
+		instance, err = s.getEditableInstance(req.InstanceId)
+	} else {
+		instance, err = s.getInstanceByID(req.InstanceId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 直连实例客户端直接访问上游服务，不经过网关的令牌校验
+	direct := instance.AccessType == model.AccessTypeDirect
+	if direct && (req.Token != "" || req.NewToken) {
+		return nil, common.NewError(i18nresp.CodeConnectionTokenUnsupported, instance.InstanceName)
+	}
+
+	var servers model.McpServersConfig
+	if err := json.Unmarshal(common.ResolvePublicProxyConfig(instance.PublicProxyConfig, origin), &servers); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeConnectionFailure)
+	}
+	var endpoints []utils.McpClientEndpoint
+	for _, name := range servers.ServerNames() {
+		server := servers.McpServers[name]
+		if server == nil || server.URL == "" {
+			continue
+		}
+		protocol := server.Type
+		if protocol == "" {
+			protocol = server.Transport
+		}
+		endpoints = append(endpoints, utils.McpClientEndpoint{Name: name, URL: server.URL, Protocol: protocol})
 	}
 
-	// Validate environment type
-	if environment.Environment != model.McpEnvironmentKubernetes {
-		response.Message = "Environment type error, only Kubernetes environment is supported"
-		return &response, nil
+	client := req.Client
+	if client == "" {
+		client = utils.McpClientGeneric
+	}
+	resp := &instancepb.ConnectionResp{InstanceId: instance.InstanceID, Client: client}
+	var token *model.McpToken
+	switch {
+	case req.NewToken:
+		if token, err = s.createConnectionToken(ctx, instance, req); err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeConnectionFailure)
+		}
+	case req.Token != "":
+		for i := range instance.Tokens {
+			if instance.Tokens[i].Token == req.Token {
+				token = &instance.Tokens[i]
+				break
+			}
+		}
+		if token == nil {
+			return nil, common.NewError(i18nresp.CodeInstanceTokenNotFound)
+		}
 	}
 
-	// Get container logs
-	logs, err := biz.GContainerBiz.GetContainerLogs(biz.ContainerLogsParams{
+	var headers map[string]string
+	switch {
+	case token != nil:
+		headers = map[string]string{"Authorization": "Bearer " + token.Token}
+		resp.Token = common.ConvertToProtoTokenAt(token, time.Now(), common.TokenWarningWindow())
+	case !direct && len(instance.Tokens) > 0:
+		headers = map[string]string{"Authorization": "Bearer " + connectionTokenPlaceholder}
+		resp.TokenPlaceholder = connectionTokenPlaceholder
+	}
+
+	snippet, err := utils.BuildMcpClientSnippet(client, endpoints, headers)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeConnectionFailure)
+	}
+	resp.Config = snippet.Config
+	resp.Curl = snippet.Curl
+	if len(endpoints) > 0 {
+		resp.McpProtocol = endpoints[0].Protocol
+	}
+
+	if token != nil {
+		biz.GAuditBiz.Record(ctx, model.AuditActionConnectionToken, model.AuditResourceInstance, instance.InstanceID, map[string]any{
+			"client":    client,
+			"token":     utils.MaskToken(token.Token),
+			"generated": req.NewToken,
+		})
+	}
+	return resp, nil
+}
+
+// createConnectionToken adds a new token to the instance for a client connection
+func (s *InstanceService) createConnectionToken(ctx context.Context, instance *model.McpInstance, req *instancepb.ConnectionRequest) (*model.McpToken, error) {
+	value, err := utils.GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token := model.McpToken{Token: value, PublishAt: now.UnixMilli(), Usages: []string{model.TokenScopeFull}}
+	if req.Scope != "" {
+		token.Usages = []string{req.Scope}
+	}
+	if req.ExpireDays > 0 {
+		token.ExpireAt = now.AddDate(0, 0, int(req.ExpireDays)).UnixMilli()
+	}
+	instance.Tokens = append(instance.Tokens, token)
+	if err := mysql.McpInstanceRepo.UpdateTokens(ctx, instance.InstanceID, instance.Tokens); err != nil {
+		return nil, err
+	}
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationTokenCreate,
+		fmt.Sprintf("token %s created for %s client", utils.MaskToken(value), req.Client))
+	return &instance.Tokens[len(instance.Tokens)-1], nil
+}
+
+// rotatedAt returns the rotation time of an instance token
+func rotatedAt(instance *model.McpInstance, value string) int64 {
+	for _, token := range instance.Tokens {
+		if token.Token == value {
+			return token.RotatedAt
+		}
+	}
+	return 0
+}
+
+// formatTokenTime formats a token timestamp in milliseconds for response messages
+func formatTokenTime(ctx context.Context, ms int64) string {
+	return common.FormatTimeRFC3339(ctx, time.UnixMilli(ms))
+}
+
+// scale sets the replica count of a hosting instance
+func (s *InstanceService) scale(ctx context.Context, req *instancepb.ScaleRequest) (*instancepb.ScaleResp, error) {
+	instance, err := s.getEditableInstance(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if instance.AccessType != model.AccessTypeHosting {
+		return nil, common.NewError(i18nresp.CodeInstanceNotManaged)
+	}
+	if err := (&common.Validation{}).Add(validateReplicas(req.Replicas, instance.McpProtocol)).Err(); err != nil {
+		return nil, err
+	}
+	if err := biz.GEnvironmentBiz.CheckInstanceQuota(ctx, instance, req.Replicas); err != nil {
+		return nil, err
+	}
+
+	result, err := biz.GContainerBiz.ScaleContainer(biz.ContainerScaleParams{
 		InstanceID: req.InstanceId,
-		Lines:      int64(lines),
+		Replicas:   req.Replicas,
+	})
+	if err != nil {
+		return nil, common.ErrContainerRuntime(err)
+	}
+	biz.GInstanceOperationBiz.Record(ctx, req.InstanceId, model.InstanceOperationScale,
+		fmt.Sprintf("replicas: %d -> %d", instance.Replicas, req.Replicas))
+
+	return &instancepb.ScaleResp{
+		InstanceId: req.InstanceId,
+		Replicas:   req.Replicas,
+		Message:    result.Message,
+	}, nil
+}
+
+// listEvents lists persisted container events of an instance
+func (s *InstanceService) listEvents(ctx context.Context, req *instancepb.EventsRequest) (*instancepb.EventsResp, error) {
+	if _, err := s.getInstanceByID(req.InstanceId); err != nil {
+		return nil, err
+	}
+
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = int32(common.DefaultPageSize)
+	}
+	if pageSize > int32(common.MaxPageSize) {
+		pageSize = int32(common.MaxPageSize)
+	}
+
+	result, err := biz.GInstanceEventBiz.ListEvents(ctx, req.InstanceId, req.Severity, page, pageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceEventsQueryFailure)
+	}
+	return result, nil
+}
+
+// timeline lists operations and persisted container events of an instance in time order
+func (s *InstanceService) timeline(ctx context.Context, req *instancepb.TimelineRequest) (*instancepb.TimelineResp, error) {
+	if _, err := s.getInstanceByID(req.InstanceId); err != nil {
+		return nil, err
+	}
+
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = int32(common.DefaultPageSize)
+	}
+	if pageSize > int32(common.MaxPageSize) {
+		pageSize = int32(common.MaxPageSize)
+	}
+
+	result, err := biz.GInstanceOperationBiz.Timeline(ctx, req.InstanceId, req.Order != timelineOrderDesc, page, pageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceTimelineFailure)
+	}
+	return result, nil
+}
+
+// history 分页查询实例的字段级修改历史，从最新开始
+func (s *InstanceService) history(ctx context.Context, req *instancepb.InstanceHistoryRequest) (*instancepb.ChangeHistoryResp, error) {
+	if _, err := s.getInstanceByID(req.InstanceId); err != nil {
+		return nil, err
+	}
+
+	page, pageSize := historyPage(req.Page, req.PageSize)
+	result, err := biz.GAuditBiz.ChangeHistory(ctx, model.AuditResourceInstance, req.InstanceId, page, pageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeChangeHistoryFailure)
+	}
+	return result, nil
+}
+
+// historyPage 修改历史的分页参数，未传时使用默认值，每页数量不超过 common.MaxPageSize
+func historyPage(page, pageSize int32) (int32, int32) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = int32(common.DefaultPageSize)
+	}
+	return page, min(pageSize, int32(common.MaxPageSize))
+}
+
+// drift 比较托管实例存储的配置与运行中的容器
+func (s *InstanceService) drift(ctx context.Context, instanceID string) (*instancepb.DriftResp, error) {
+	instance, err := s.getInstanceByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.AccessType != model.AccessTypeHosting {
+		return nil, common.NewError(i18nresp.CodeInstanceNotManaged)
+	}
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeGetEnvironmentFailure)
+	}
+	if environment.Environment != model.McpEnvironmentKubernetes {
+		return nil, common.NewError(i18nresp.CodeLogsRequireKubernetes)
+	}
+
+	items, err := biz.GContainerBiz.DetectDrift(ctx, instance)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceDriftCheckFailure)
+	}
+	if item := biz.GContainerBiz.ImageDigestDrift(ctx, instance); item != nil {
+		items = append(items, *item)
+	}
+	return driftResp(ctx, instance.InstanceID, items), nil
+}
+
+// driftResp 转换配置漂移检查结果
+func driftResp(ctx context.Context, instanceID string, items []container.DriftItem) *instancepb.DriftResp {
+	now := time.Now()
+	resp := &instancepb.DriftResp{
+		InstanceId:  instanceID,
+		InSync:      len(items) == 0,
+		Items:       make([]*instancepb.DriftItem, 0, len(items)),
+		CheckedAt:   common.FormatTimeRFC3339(ctx, now),
+		CheckedAtMs: common.TimeMillis(now),
+	}
+	for _, item := range items {
+		resp.Items = append(resp.Items, &instancepb.DriftItem{
+			Field:    item.Field,
+			Kind:     item.Kind,
+			Name:     item.Name,
+			Expected: item.Expected,
+			Actual:   item.Actual,
+		})
+	}
+	return resp
+}
+
+// checkDrift 编辑托管实例前检查运行中的容器是否被修改过，避免覆盖集群中的手动修改
+// 无法检查时（如容器不存在、Docker 环境）不阻止编辑
+func (s *InstanceService) checkDrift(ctx context.Context, instance *model.McpInstance) error {
+	items, err := biz.GContainerBiz.DetectDrift(ctx, instance)
+	if err != nil {
+		logger.Warn("检查实例配置漂移失败，跳过检查", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		return nil
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	summary := make([]string, 0, len(items))
+	for _, item := range items {
+		summary = append(summary, item.String())
+	}
+	return &common.Error{
+		Code: i18nresp.CodeInstanceConfigDrifted,
+		Args: []interface{}{instance.InstanceName, strings.Join(summary, "; ")},
+		Data: driftResp(ctx, instance.InstanceID, items),
+	}
+}
+
+// lock 锁定或解锁实例，重复锁定时只更新锁定原因；username 为当前用户，只有锁定用户或管理员可以解锁
+func (s *InstanceService) lock(ctx context.Context, instanceID string, locked bool, reason, username string, admin bool) (*instancepb.LockResp, error) {
+	instance, err := s.getInstanceByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !locked && instance.Locked && !admin && (instance.LockedBy == "" || instance.LockedBy != username) {
+		return nil, common.NewError(i18nresp.CodeInstanceUnlockForbidden, instance.InstanceName, instance.LockedBy)
+	}
+
+	var lockedBy string
+	var lockedAt *time.Time
+	if locked {
+		now := time.Now()
+		lockedBy, lockedAt = username, &now
+		// 重复锁定时保留原锁定用户和锁定时间
+		if instance.Locked {
+			lockedBy, lockedAt = instance.LockedBy, instance.LockedAt
+		}
+	}
+	if err := mysql.McpInstanceRepo.UpdateLock(ctx, instance.InstanceID, locked, reason, lockedBy, lockedAt); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceLockFailure)
+	}
+
+	resp := &instancepb.LockResp{
+		InstanceId: instance.InstanceID,
+		Locked:     locked,
+		LockReason: reason,
+		LockedBy:   lockedBy,
+	}
+	if lockedAt != nil {
+		resp.LockedAt = common.FormatTimeRFC3339(ctx, *lockedAt)
+		resp.LockedAtMs = common.TimeMillis(*lockedAt)
+	}
+	return resp, nil
+}
+
+// setHealthMonitor 设置直连和代理实例的健康检查，托管实例由容器监控检查；关闭时清空健康状态
+func (s *InstanceService) setHealthMonitor(ctx context.Context, req *instancepb.HealthMonitorRequest) (*instancepb.HealthMonitorResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if instance.AccessType == model.AccessTypeHosting {
+		return nil, common.NewError(i18nresp.CodeHealthMonitorUnsupported)
+	}
+
+	monitor := &model.HealthMonitorConfig{
+		Enabled:        req.Enabled,
+		Interval:       req.Interval,
+		Mode:           req.Mode,
+		Path:           req.Path,
+		ExpectedStatus: req.ExpectedStatus,
+	}
+	if monitor.Interval == 0 {
+		monitor.Interval = model.DefaultHealthCheckInterval
+	}
+	if monitor.Mode == "" {
+		monitor.Mode = model.HealthCheckModePort
+	}
+	data, err := json.Marshal(monitor)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeHealthMonitorFailure)
+	}
+	if err := mysql.McpInstanceRepo.UpdateHealthMonitor(ctx, instance.InstanceID, data, monitor.Enabled); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeHealthMonitorFailure)
+	}
+	if !monitor.Enabled {
+		instance.HealthStatus = ""
+		instance.HealthCheckedAt = nil
+	}
+
+	resp := &instancepb.HealthMonitorResp{
+		InstanceId:    instance.InstanceID,
+		HealthMonitor: healthMonitorToPb(monitor),
+		HealthStatus:  instance.HealthStatus,
+	}
+	if instance.HealthCheckedAt != nil {
+		resp.HealthCheckedAt = common.FormatTimeRFC3339(ctx, *instance.HealthCheckedAt)
+		resp.HealthCheckedAtMs = common.TimeMillis(*instance.HealthCheckedAt)
+	}
+	return resp, nil
+}
+
+// healthHistory 查询实例最近的健康检查结果，从最新开始
+func (s *InstanceService) healthHistory(ctx context.Context, req *instancepb.HealthHistoryRequest) (*instancepb.HealthHistoryResp, error) {
+	instance, err := s.getInstanceByID(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHealthHistoryLimit
+	}
+	checks, err := biz.GHealthMonitorBiz.History(ctx, instance.InstanceID, limit)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeHealthHistoryFailure)
+	}
+
+	resp := &instancepb.HealthHistoryResp{
+		InstanceId:   instance.InstanceID,
+		HealthStatus: instance.HealthStatus,
+		Checks:       make([]*instancepb.HealthCheck, 0, len(checks)),
+	}
+	for _, check := range checks {
+		resp.Checks = append(resp.Checks, &instancepb.HealthCheck{
+			Success:     check.Success,
+			StatusCode:  check.StatusCode,
+			LatencyMs:   check.LatencyMs,
+			Error:       check.Error,
+			CheckedAt:   common.FormatTimeRFC3339(ctx, check.CheckedAt),
+			CheckedAtMs: common.TimeMillis(check.CheckedAt),
+		})
+	}
+	return resp, nil
+}
+
+// provenance 托管实例的来源记录，创建参数中的敏感值已隐藏
+func (s *InstanceService) provenance(ctx context.Context, instanceID string) (*instancepb.ProvenanceResp, error) {
+	instance, err := s.getInstanceByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.AccessType != model.AccessTypeHosting {
+		return nil, common.NewError(i18nresp.CodeInstanceNotManaged)
+	}
+
+	resp := &instancepb.ProvenanceResp{
+		InstanceId:          instance.InstanceID,
+		Name:                instance.InstanceName,
+		ImageDigest:         instance.ImageDigest,
+		PackageId:           instance.PackageID,
+		CodePackageVersion:  int32(instance.CodePackageVersion),
+		CodePackageChecksum: instance.CodePackageChecksum,
+		TemplateId:          int32(instance.TemplateID),
+		CreatedAt:           common.FormatTimeRFC3339(ctx, instance.CreatedAt),
+		CreatedAtMs:         common.TimeMillis(instance.CreatedAt),
+	}
+	if resp.Creator, err = biz.GInstanceOperationBiz.Creator(ctx, instance.InstanceID); err != nil {
+		logger.Warn("Failed to find instance creator", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+	}
+	if instance.PackageID != "" {
+		outdated, err := biz.GCodePackageBiz.OutdatedInstanceIDs(ctx, []*model.McpInstance{instance})
+		if err != nil {
+			logger.Warn("Failed to check outdated code package", zap.String("instanceId", instance.InstanceID), zap.Error(err))
+		}
+		resp.CodeOutdated = outdated[instance.InstanceID]
+	}
+	if instance.TemplateUpdatedAt != nil {
+		resp.TemplateVersion = common.FormatTimeRFC3339(ctx, *instance.TemplateUpdatedAt)
+		if template, err := mysql.McpTemplateRepo.FindByID(ctx, instance.TemplateID); err == nil {
+			resp.TemplateChanged = template.UpdatedAt.After(*instance.TemplateUpdatedAt)
+		}
+	}
+
+	if len(instance.ContainerCreateOptions) > 0 {
+		var containerOptions container.ContainerCreateOptions
+		if err := json.Unmarshal(instance.ContainerCreateOptions, &containerOptions); err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeParseContainerOptionsFailure)
+		}
+		resp.Image = containerOptions.ImageName
+		secrets := append(containerOptionsSecretValues(&containerOptions), common.McpServersSecretValues(instance.SourceConfig)...)
+		resp.ContainerOptions = string(common.MaskSecrets(instance.ContainerCreateOptions, secrets))
+	}
+	return resp, nil
+}
+
+// scan 后台重新扫描托管实例的镜像漏洞，返回扫描中状态；锁定的实例不能扫描
+func (s *InstanceService) scan(ctx context.Context, instanceID string) (*instancepb.ScanResp, error) {
+	instance, err := s.getEditableInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.AccessType != model.AccessTypeHosting {
+		return nil, common.NewError(i18nresp.CodeInstanceNotManaged)
+	}
+
+	result, err := biz.GContainerBiz.ScanInstanceImage(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+	return &instancepb.ScanResp{InstanceId: instance.InstanceID, ImageScan: imageScanToPb(ctx, result)}, nil
+}
+
+// imageScanToPb 转换镜像漏洞扫描结果，未扫描时返回 nil
+func imageScanToPb(ctx context.Context, result *biz.ImageScanResult) *instancepb.ImageScanResult {
+	if result == nil {
+		return nil
+	}
+	resp := &instancepb.ImageScanResult{
+		Status:      result.Status,
+		Error:       result.Error,
+		UpdatedAt:   common.FormatTimeRFC3339(ctx, result.UpdatedAt),
+		UpdatedAtMs: common.TimeMillis(result.UpdatedAt),
+	}
+	if report := result.Report; report != nil {
+		resp.Image = report.Image
+		resp.Scanner = report.Scanner
+		resp.Critical = int32(report.Critical)
+		resp.High = int32(report.High)
+		resp.Medium = int32(report.Medium)
+		resp.Low = int32(report.Low)
+		resp.Unknown = int32(report.Unknown)
+		for _, f := range report.TopFindings {
+			resp.TopFindings = append(resp.TopFindings, &instancepb.Vulnerability{
+				Id:               f.ID,
+				Severity:         f.Severity,
+				PackageName:      f.Package,
+				InstalledVersion: f.InstalledVersion,
+				FixedVersion:     f.FixedVersion,
+				Title:            f.Title,
+				Url:              f.URL,
+			})
+		}
+	}
+	return resp
+}
+
+// healthMonitorToPb 转换健康检查配置，未配置时返回 nil
+func healthMonitorToPb(monitor *model.HealthMonitorConfig) *instancepb.HealthMonitor {
+	if monitor == nil {
+		return nil
+	}
+	return &instancepb.HealthMonitor{
+		Enabled:        monitor.Enabled,
+		Interval:       monitor.Interval,
+		Mode:           monitor.Mode,
+		Path:           monitor.Path,
+		ExpectedStatus: monitor.ExpectedStatus,
+	}
+}
+
+// labels 汇总所有实例的标签，指定 key 时只返回该标签的值
+func (s *InstanceService) labels(ctx context.Context, req *instancepb.LabelsRequest) (*instancepb.LabelsResp, error) {
+	labelSets, err := mysql.McpInstanceRepo.FindAllLabels(ctx)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceLabelsQueryFailure)
+	}
+
+	resp := &instancepb.LabelsResp{Labels: []*instancepb.LabelValues{}}
+	for _, lv := range common.CollectLabelValues(labelSets) {
+		if req.Key != "" && lv.Key != req.Key {
+			continue
+		}
+		resp.Labels = append(resp.Labels, &instancepb.LabelValues{Key: lv.Key, Values: lv.Values})
+	}
+	return resp, nil
+}
+
+// validateConfig validates mcpServers configuration, the protocol is checked when mcpProtocol is set
+func (s *InstanceService) validateConfig(req *instancepb.ValidateConfigRequest) *instancepb.ValidateConfigResp {
+	result, err := utils.ValidateMcpConfig([]byte(req.McpServers))
+	if err != nil {
+		return &instancepb.ValidateConfigResp{
+			Errors: []*instancepb.McpConfigError{{Path: "mcpServers", Message: err.Error()}},
+		}
+	}
+
+	errs := result.Errors
+	if result.IsValid && req.McpProtocol != instancepb.McpProtocol_McpProtocolUnknown {
+		// 与创建实例的校验保持一致：托管 stdio 模式要求启动命令，直连和代理模式要求协议一致
+		if req.AccessType == instancepb.AccessType_HOSTING {
+			if req.McpProtocol == instancepb.McpProtocol_STDIO {
+				errs = append(errs, result.CheckProtocol("", true)...)
+			}
+		} else if mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol); err == nil {
+			errs = append(errs, result.CheckProtocol(string(mcpProtocol), false)...)
+		}
+	}
+
+	resp := &instancepb.ValidateConfigResp{
+		Valid:        len(errs) == 0,
+		ServiceName:  result.ServiceName,
+		ProtocolType: result.ProtocolType,
+	}
+	for _, e := range errs {
+		resp.Errors = append(resp.Errors, &instancepb.McpConfigError{Path: e.Path, Message: e.Message})
+	}
+	return resp
+}
+
+// convertConfig converts a client configuration and validates the result like validateConfig
+func (s *InstanceService) convertConfig(req *instancepb.ConvertConfigRequest) *instancepb.ConvertConfigResp {
+	conversion, err := utils.ConvertClientMcpConfig([]byte(req.McpServers))
+	if err != nil {
+		return &instancepb.ConvertConfigResp{
+			Errors: []*instancepb.McpConfigError{{Path: err.Path, Message: err.Message}},
+		}
+	}
+
+	validation := s.validateConfig(&instancepb.ValidateConfigRequest{
+		McpServers:  conversion.McpServers,
+		AccessType:  req.AccessType,
+		McpProtocol: req.McpProtocol,
+	})
+	resp := &instancepb.ConvertConfigResp{
+		McpServers:           conversion.McpServers,
+		EnvironmentVariables: conversion.EnvironmentVariables,
+		Valid:                validation.Valid,
+		ServiceName:          validation.ServiceName,
+		ProtocolType:         validation.ProtocolType,
+		Errors:               validation.Errors,
+	}
+	for _, change := range conversion.Changes {
+		resp.Changes = append(resp.Changes, &instancepb.McpConfigChange{Path: change.Path, Message: change.Message})
+	}
+	return resp
+}
+
+// detectProtocol probes the URL with both transports, see utils.DetectMcpProtocol
+func (s *InstanceService) detectProtocol(ctx context.Context, req *instancepb.DetectProtocolRequest) *instancepb.DetectProtocolResp {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultDetectProtocolTimeout
+	}
+	detection := utils.DetectMcpProtocol(ctx, utils.MCPProbeOptions{
+		URL:     req.Url,
+		Headers: req.Headers,
+		Timeout: time.Duration(timeout) * time.Second,
 	})
-	if err != nil {
-		response.Message = fmt.Sprintf("Failed to get container logs: %v", err)
-		return &response, nil
+
+	resp := &instancepb.DetectProtocolResp{
+		Confidence:      detection.Confidence,
+		ServerName:      detection.ServerName,
+		ServerVersion:   detection.ServerVersion,
+		ProtocolVersion: detection.ProtocolVersion,
+		Evidence:        detection.Evidence,
+	}
+	if detection.Protocol != "" {
+		resp.McpProtocol, _ = common.ConvertToProtoMcpProtocol(model.McpProtocol(detection.Protocol))
 	}
+	return resp
+}
 
-	response.Logs = logs
-	response.Message = "Logs retrieved successfully"
+// resolveAutoProtocol 探测 mcpProtocol=AUTO 的直连/代理实例的协议，实例只有一个协议，所有服务的探测结果必须一致
+// 未声明 type/transport 的服务写入检测到的协议，之后按检测到的协议重新校验配置
+func resolveAutoProtocol(ctx context.Context, req *instancepb.CreateRequest) error {
+	var root map[string]json.RawMessage
+	var servers map[string]map[string]json.RawMessage
+	var config model.McpServersConfig
+	if json.Unmarshal([]byte(req.McpServers), &root) != nil || json.Unmarshal(root["mcpServers"], &servers) != nil ||
+		json.Unmarshal([]byte(req.McpServers), &config) != nil {
+		return common.ValidationErrors{common.InvalidJSON("mcpServers", "malformed mcpServers configuration")}
+	}
+
+	detected, detectedBy := "", ""
+	for _, name := range slices.Sorted(maps.Keys(config.McpServers)) {
+		server := config.McpServers[name]
+		if server == nil {
+			continue
+		}
+		detection := utils.DetectMcpProtocol(ctx, utils.MCPProbeOptions{
+			URL:     server.URL,
+			Headers: server.Headers,
+			Timeout: defaultDetectProtocolTimeout * time.Second,
+		})
+		if detection.Protocol == "" {
+			return common.ValidationErrors{common.Invalid("mcpServers."+name+".url",
+				"could not detect the MCP protocol: "+strings.Join(detection.Evidence, "; "))}
+		}
+		if detected != "" && detection.Protocol != detected {
+			return common.ValidationErrors{common.Invalid("mcpProtocol",
+				fmt.Sprintf("servers use different protocols, %s is %s and %s is %s", detectedBy, detected, name, detection.Protocol))}
+		}
+		detected, detectedBy = detection.Protocol, name
+		logger.Info("Detected MCP protocol",
+			zap.String("server", name),
+			zap.String("protocol", detection.Protocol),
+			zap.String("confidence", detection.Confidence),
+		)
+
+		_, hasType := servers[name]["type"]
+		_, hasTransport := servers[name]["transport"]
+		if !hasType && !hasTransport {
+			servers[name]["type"], _ = json.Marshal(detection.Protocol)
+		}
+	}
+	if detected == "" {
+		return common.ErrRequiredField("mcpServers")
+	}
 
-	return &response, nil
+	root["mcpServers"], _ = json.Marshal(servers)
+	mcpServers, _ := json.Marshal(root)
+	req.McpServers = string(mcpServers)
+	req.McpProtocol, _ = common.ConvertToProtoMcpProtocol(model.McpProtocol(detected))
+	if errs := validateMcpServers(req.McpServers, req.McpProtocol, false); len(errs) > 0 {
+		return common.ValidationErrors(errs)
+	}
+	return nil
 }
 
-// GetStatus retrieves the status of an instance
-func (s *InstanceService) getStatus(req *instancepb.GetStatusRequest) (*instancepb.GetStatusResp, error) {
-	instance, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
+// convertClientConfig 将创建请求中的客户端配置转换为标准配置，stdio 服务的 env 合并到环境变量，请求中已有的变量优先
+func convertClientConfig(req *instancepb.CreateRequest) (string, map[string]string, *utils.McpConfigError) {
+	if req.McpServers == "" {
+		return req.McpServers, req.EnvironmentVariables, nil
+	}
+	conversion, err := utils.ConvertClientMcpConfig([]byte(req.McpServers))
 	if err != nil {
-		return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		return "", nil, err
 	}
-	if instance == nil {
-		return nil, fmt.Errorf("实例不存在")
+	if len(conversion.EnvironmentVariables) == 0 {
+		return conversion.McpServers, req.EnvironmentVariables, nil
 	}
+	envVars := maps.Clone(conversion.EnvironmentVariables)
+	maps.Copy(envVars, req.EnvironmentVariables)
+	return conversion.McpServers, envVars, nil
+}
 
-	var response *instancepb.GetStatusResp
-
-	// Use different status query strategies based on access type
-	switch instance.AccessType {
-	case model.AccessTypeHosting:
-		// Hosting type: query container status
-		params := biz.ContainerStatusParams{
-			InstanceID: req.InstanceId,
-		}
-		result, err := biz.GContainerBiz.GetContainerStatus(params)
-		if err != nil {
-			return nil, fmt.Errorf("获取容器状态失败: %s", err.Error())
+// validateScript checks syntax and common mistakes of the init script and startup command
+func (s *InstanceService) validateScript(ctx context.Context, req *instancepb.ValidateScriptRequest) *instancepb.ValidateScriptResp {
+	resp := &instancepb.ValidateScriptResp{
+		Valid:  true,
+		Issues: lintScripts(ctx, req.InitScript, req.Command, req.ImgAddress, req.EnvironmentVariables),
+	}
+	for _, issue := range resp.Issues {
+		if issue.Severity == utils.ScriptSeverityError {
+			resp.Valid = false
 		}
+	}
+	return resp
+}
 
-		response = result
-	case model.AccessTypeProxy:
-		_, _, tMcpConfig, err := instance.GetTargetConfig()
-		if err != nil {
-			return nil, fmt.Errorf("获取目标配置失败: %s", err.Error())
+// hostingPlatformEnvVars 托管容器由平台注入的环境变量
+var hostingPlatformEnvVars = []string{"MCP_INSTANCE_ID", "MCP_PORT", "NODE_ENV", "MCP_INIT_SHARED_DIR"}
+
+// lintScripts 检查初始化脚本和启动命令，结果只作为提示，不阻断创建
+func lintScripts(ctx context.Context, initScript, command, image string, envVars map[string]string) []*instancepb.ScriptIssue {
+	env := make(map[string]string, len(envVars)+len(hostingPlatformEnvVars))
+	for _, name := range hostingPlatformEnvVars {
+		env[name] = ""
+	}
+	for k, v := range envVars {
+		env[k] = v
+	}
+	opts := utils.ScriptLintOptions{Image: image, EnvVars: env}
+
+	var issues []*instancepb.ScriptIssue
+	for _, script := range []struct{ field, content string }{{"initScript", initScript}, {"command", command}} {
+		for _, issue := range utils.LintScript(ctx, script.field, script.content, opts) {
+			issues = append(issues, &instancepb.ScriptIssue{
+				Field:    issue.Field,
+				Line:     int32(issue.Line),
+				Severity: issue.Severity,
+				Message:  issue.Message,
+			})
 		}
+	}
+	return issues
+}
 
-		// Use HTTP probe to check service availability
-		probeResult := utils.ProbePortFromURL(s.ctx, tMcpConfig.URL, 5*time.Second)
-
-		// Build response
-		response = &instancepb.GetStatusResp{
-			InstanceId: req.InstanceId,
-			Status:     string(instance.Status),
-		}
+// edit 编辑实例，按原实例访问类型更新
+func (s *InstanceService) edit(ctx context.Context, req *instancepb.EditRequest) (*instancepb.EditResp, error) {
+	// 获取原始实例信息
+	oriInstance, err := s.getEditableInstance(req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	// 修改前的快照，实例在更新过程中被原地修改
+	before, _ := json.Marshal(oriInstance)
 
-		// If probe fails, add error message
-		if !probeResult.Success {
-			response.ProbeHttp = false
-		} else {
-			response.ProbeHttp = true
+	// 根据原实例访问类型和协议校验请求参数
+	if err := validateEditRequestForInstance(req, oriInstance); err != nil {
+		return nil, err
+	}
+	if req.McpServers != "" {
+		if err := requireAdminForInsecureTLS(ctx, req.McpServers, oriInstance); err != nil {
+			return nil, err
 		}
-	case model.AccessTypeDirect:
-		_, _, sMcpConfig, err := instance.GetTargetConfig()
-		if err != nil {
-			return nil, fmt.Errorf("获取目标配置失败: %s", err.Error())
+	}
+	if req.Name != oriInstance.InstanceName {
+		if err := biz.GInstanceBiz.CheckInstanceName(ctx, req.Name, oriInstance.InstanceID); err != nil {
+			return nil, err
 		}
-
-		// Use HTTP probe to check service availability
-		probeResult := utils.ProbePortFromURL(s.ctx, sMcpConfig.URL, 5*time.Second)
-
-		// Build response
-		response = &instancepb.GetStatusResp{
-			InstanceId: req.InstanceId,
-			Status:     string(instance.Status),
+	}
+	if err := biz.GInstanceBiz.CheckInstanceSlug(ctx, req.Slug, oriInstance.InstanceID); err != nil {
+		return nil, err
+	}
+	if oriInstance.AccessType == model.AccessTypeHosting && !req.Force {
+		if err := s.checkDrift(ctx, oriInstance); err != nil {
+			return nil, err
 		}
+	}
+	// 受保护环境中的实例保存请求等待另一位管理员审批
+	if err := biz.GChangeRequestBiz.RequireApproval(ctx, oriInstance, model.ChangeOperationInstanceEdit, req); err != nil {
+		return nil, err
+	}
+	// 未传标签时保持原标签，托管实例重建容器时同步到 Pod 标签
+	if req.Labels != nil {
+		oriInstance.Labels = marshalLabels(req.Labels)
+	}
+	// 未传元数据时保持原元数据，传 {} 时清空
+	if req.Metadata != "" {
+		oriInstance.Metadata = common.NormalizeMetadata(req.Metadata)
+	}
+	// 未传自定义访问路径时保持原路径
+	oldSlug := oriInstance.GetSlug()
+	if req.ClearSlug {
+		oriInstance.Slug = nil
+	} else if req.Slug != "" {
+		oriInstance.Slug = instanceSlug(req.Slug)
+	}
 
-		// If probe fails, add error message
-		if !probeResult.Success {
-			response.ProbeHttp = false
-		} else {
-			response.ProbeHttp = true
-		}
-	default:
-		return nil, fmt.Errorf("不支持的访问类型")
+	var resp *instancepb.EditResp
+	switch oriInstance.AccessType {
+	case model.AccessTypeDirect:
+		resp, err = biz.GInstanceBiz.UpdateInstanceForDirect(ctx, req, oriInstance)
+	case model.AccessTypeProxy:
+		resp, err = biz.GInstanceBiz.UpdateInstanceForProxy(ctx, req, oriInstance)
+	case model.AccessTypeHosting:
+		resp, err = biz.GInstanceBiz.UpdateInstanceForHosting(ctx, req, oriInstance)
+	}
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeEditInstanceFailure)
 	}
+	biz.GInstanceBiz.InvalidateResponseCache(oriInstance.InstanceID)
+	biz.GInstanceBiz.SlugChanged(ctx, oriInstance.InstanceID, oldSlug, oriInstance.GetSlug())
+	detail := ""
+	if req.Force {
+		detail = "forced over config drift"
+	}
+	biz.GInstanceOperationBiz.Record(ctx, oriInstance.InstanceID, model.InstanceOperationEdit, detail)
+	biz.GAuditBiz.RecordChanges(ctx, model.AuditActionInstanceEdit, model.AuditResourceInstance, oriInstance.InstanceID,
+		json.RawMessage(before), oriInstance, biz.InstanceDiffOptions)
 
-	return response, nil
+	return resp, nil
 }
 
 // delete deletes an instance
-func (s *InstanceService) delete(instanceID string) (*instancepb.DeleteResp, error) {
+func (s *InstanceService) delete(ctx context.Context, instanceID string) (*instancepb.DeleteResp, error) {
 	req := &instancepb.DeleteRequest{
 		InstanceId: instanceID,
 	}
 
 	// Get instance information directly
-	instance, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
+	instance, err := s.getEditableInstance(req.InstanceId)
 	if err != nil {
-		return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		return nil, err
 	}
-	if instance == nil {
-		return nil, fmt.Errorf("实例不存在")
+	// 受保护环境中的实例等待另一位管理员审批
+	if err := biz.GChangeRequestBiz.RequireApproval(ctx, instance, model.ChangeOperationInstanceDelete, req); err != nil {
+		return nil, err
 	}
 
 	switch instance.AccessType {
 	case model.AccessTypeHosting:
+		// 排队中的实例还没有在集群中创建资源，移出队列即可；不在本副本队列中时可能正在创建，按正常流程删除
+		if instance.ContainerStatus == model.ContainerStatusQueued && biz.GContainerBiz.DequeueCreate(instance.EnvironmentID, instance.InstanceID) {
+			break
+		}
 		_, err = biz.GContainerBiz.DeleteContainer(instance)
 		if err != nil {
-			return nil, fmt.Errorf("删除容器失败: %v", err)
+			return nil, common.ErrContainerRuntime(err)
 		}
 	}
 
 	// Disable the instance and set deletion time
 	err = biz.GInstanceBiz.DeleteInstance(req.InstanceId)
 	if err != nil {
-		return nil, fmt.Errorf("禁用实例失败: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeInstanceDeleteFailure)
 	}
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationDelete, "")
 
-	return &instancepb.DeleteResp{Message: "实例删除成功"}, nil
+	return &instancepb.DeleteResp{Message: i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceDeleteSuccess)}, nil
 }
 
 // restart restarts an instance
-func (s *InstanceService) restart(req *instancepb.RestartRequest) (*instancepb.RestartResp, error) {
+func (s *InstanceService) restart(ctx context.Context, req *instancepb.RestartRequest, origin string) (*instancepb.RestartResp, error) {
 	// 1. Query instance data by ID
 	instance, err := s.getInstanceByID(req.InstanceId)
 	if err != nil {
 		return nil, err
 	}
 
+	// 2. Resolve access addresses before restarting, so a broken config fails the request up front
+	accessConfig, err := s.convertMcpConfigToProto(instance.TargetConfig)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeGetTargetConfigFailure)
+	}
+	publicProxyConfig, err := s.convertMcpConfigToProto(common.ResolvePublicProxyConfig(instance.PublicProxyConfig, origin))
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeGetTargetConfigFailure)
+	}
+
 	switch instance.AccessType {
 	case model.AccessTypeHosting:
+		// 镜像标签已指向新的摘要时不悄悄滚动到新镜像，由调用方确认
+		if !req.Force {
+			if item := biz.GContainerBiz.ImageDigestDrift(ctx, instance); item != nil {
+				return nil, &common.Error{
+					Code: i18nresp.CodeImageDigestChanged,
+					Args: []interface{}{instance.InstanceName, item.Name, item.Expected, item.Actual},
+					Data: driftResp(ctx, instance.InstanceID, []container.DriftItem{*item}),
+				}
+			}
+		}
+		if err := biz.GEnvironmentBiz.CheckInstanceQuota(ctx, instance, instance.DesiredReplicas()); err != nil {
+			return nil, err
+		}
 		_, err = biz.GContainerBiz.RestartContainer(instance)
 		if err != nil {
-			return nil, fmt.Errorf("重启容器失败: %w", err)
+			return nil, common.ErrContainerRuntime(err)
 		}
 	default:
-		return nil, fmt.Errorf("此服务无需重启")
+		return nil, common.NewError(i18nresp.CodeInstanceNoRestartNeeded)
 	}
 
 	// 3. Update container status to pending
 	if err = s.updateInstanceStatusToPending(instance); err != nil {
 		return nil, err
 	}
+	biz.GInstanceBiz.InvalidateResponseCache(instance.InstanceID)
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationRestart, "")
 
 	pbAccessType, err := common.ConvertToProtoAccessType(instance.AccessType)
 	if err != nil {
-		return nil, fmt.Errorf("转换访问类型失败: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
 	}
 
-	// 4. Return restart result
-	return &instancepb.RestartResp{
+	resp := &instancepb.RestartResp{
 		InstanceId:        instance.InstanceID,
 		Name:              instance.InstanceName,
 		Status:            string(instance.Status),
 		AccessType:        pbAccessType,
-		AccessConfig:      s.convertMcpConfigToProto(instance.TargetConfig),
-		PublicProxyConfig: s.convertMcpConfigToProto(instance.PublicProxyConfig),
-		Message:           "实例重启成功",
-	}, nil
+		AccessConfig:      accessConfig,
+		PublicProxyConfig: publicProxyConfig,
+		ContainerStatus:   string(instance.ContainerStatus),
+		Message:           i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceRestartSuccess),
+	}
+	if !req.Wait {
+		return resp, nil
+	}
+
+	// 4. Wait for the container to become ready and report the final state
+	ready, err := biz.GContainerBiz.WaitForReady(ctx, instance)
+	if err != nil {
+		return nil, common.ErrContainerRuntime(err)
+	}
+	resp.Ready = ready.Ready
+	resp.ContainerStatus = string(instance.ContainerStatus)
+	for _, event := range ready.WarningEvents {
+		resp.WarningEvents = append(resp.WarningEvents, &instancepb.ContainerEvent{
+			Type:          event.Type,
+			Reason:        event.Reason,
+			Message:       event.Message,
+			LastTimestamp: event.Timestamp,
+		})
+	}
+	if ready.Ready {
+		resp.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceRestartReady)
+	} else {
+		resp.Message = i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceRestartNotReady, int(ready.Timeout.Seconds()), ready.RunInfo)
+	}
+	return resp, nil
 }
 
 // disable disables an instance
-func (s *InstanceService) disable(req *instancepb.DisabledRequest) (*instancepb.DisabledResp, error) {
+func (s *InstanceService) disable(ctx context.Context, req *instancepb.DisabledRequest) (*instancepb.DisabledResp, error) {
+	if _, err := s.getEditableInstance(req.InstanceId); err != nil {
+		return nil, err
+	}
+
 	// Disable the instance and set deletion time
-	msg, err := biz.GInstanceBiz.DisableInstance(req.InstanceId)
+	_, err := biz.GInstanceBiz.DisableInstance(req.InstanceId)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceDisableFailure)
+	}
+	biz.GInstanceOperationBiz.Record(ctx, req.InstanceId, model.InstanceOperationDisable, "")
+
+	return &instancepb.DisabledResp{Message: i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceDisableSuccess)}, nil
+}
+
+// enable re-enables a disabled instance, the container of a hosting instance is started again
+func (s *InstanceService) enable(ctx context.Context, instanceID string) (string, error) {
+	instance, err := s.getEditableInstance(instanceID)
+	if err != nil {
+		return "", err
+	}
+	if instance.Status == model.InstanceStatusActive {
+		return i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceAlreadyEnabled), nil
+	}
+
+	switch instance.AccessType {
+	case model.AccessTypeHosting:
+		if err := biz.GEnvironmentBiz.CheckInstanceQuota(ctx, instance, instance.DesiredReplicas()); err != nil {
+			return "", err
+		}
+		if _, err := biz.GContainerBiz.RestartContainer(instance); err != nil {
+			return "", common.ErrContainerRuntime(err)
+		}
+		if err := s.updateInstanceStatusToPending(instance); err != nil {
+			return "", err
+		}
+	default:
+		instance.Status = model.InstanceStatusActive
+		instance.ContainerStatus = ""
+		instance.ContainerLastMessage = ""
+		if err := mysql.McpInstanceRepo.Update(s.ctx, instance); err != nil {
+			return "", common.WrapError(err, i18nresp.CodeInstanceEnableFailure)
+		}
+	}
+	biz.GInstanceBiz.InvalidateResponseCache(instance.InstanceID)
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationEnable, "")
+
+	return i18nresp.FormatWithContext(ctx, i18nresp.CodeInstanceEnableSuccess), nil
+}
+
+// bulk runs the action on every instance with a bounded worker pool. Each instance goes through the same
+// checks as the single-instance endpoint, failures are reported per instance and the whole request is
+// recorded as one audit entry
+func (s *InstanceService) bulk(ctx context.Context, req *instancepb.BulkRequest, origin string) (*instancepb.BulkResp, error) {
+	instanceIDs, err := s.bulkInstanceIDs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*instancepb.BulkResult, len(instanceIDs))
+	semaphore := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i, instanceID := range instanceIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = s.bulkOne(ctx, req.Action, instanceID, origin)
+		}()
+	}
+	wg.Wait()
+
+	resp := &instancepb.BulkResp{Action: req.Action, Results: results}
+	failedIDs := []string{}
+	for _, result := range results {
+		if result.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+			failedIDs = append(failedIDs, result.InstanceId)
+		}
+	}
+	biz.GAuditBiz.Record(ctx, model.AuditActionInstanceBulk, model.AuditResourceInstance, "", map[string]any{
+		"action":            req.Action,
+		"labelSelector":     req.LabelSelector,
+		"instanceIds":       instanceIDs,
+		"failedInstanceIds": failedIDs,
+	})
+	return resp, nil
+}
+
+// redeployPackage restarts every active hosting instance whose container was created from an older version
+// of the code package, one at a time unless a higher concurrency is requested. The restarted containers
+// download the current package content
+func (s *InstanceService) redeployPackage(ctx context.Context, req *instancepb.RedeployPackageRequest, origin string) (*instancepb.RedeployPackageResp, error) {
+	codePackage, err := mysql.McpCodePackageRepo.FindByPackageID(ctx, req.PackageId)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeFailedToFindCodePackage)
+	}
+	instances, err := biz.GCodePackageBiz.OutdatedInstances(ctx, req.PackageId)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceQueryFailure)
+	}
+
+	concurrency := max(int(req.Concurrency), 1)
+	results := make([]*instancepb.BulkResult, len(instances))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = s.bulkOne(ctx, bulkActionRestart, instance.InstanceID, origin)
+		}()
+	}
+	wg.Wait()
+
+	resp := &instancepb.RedeployPackageResp{
+		PackageId:      req.PackageId,
+		ContentVersion: int32(codePackage.ContentVersion),
+		Results:        results,
+	}
+	instanceIDs := make([]string, 0, len(instances))
+	failedIDs := []string{}
+	for _, result := range results {
+		instanceIDs = append(instanceIDs, result.InstanceId)
+		if result.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+			failedIDs = append(failedIDs, result.InstanceId)
+		}
+	}
+	biz.GAuditBiz.Record(ctx, model.AuditActionCodePackageDeploy, model.AuditResourceCodePackage, req.PackageId, map[string]any{
+		"contentVersion":    codePackage.ContentVersion,
+		"concurrency":       concurrency,
+		"instanceIds":       instanceIDs,
+		"failedInstanceIds": failedIDs,
+	})
+	return resp, nil
+}
+
+// bulkInstanceIDs resolves the target instances of a bulk request, duplicate ids are dropped keeping the request order
+// and a label selector may match at most maxBulkInstances instances
+func (s *InstanceService) bulkInstanceIDs(ctx context.Context, req *instancepb.BulkRequest) ([]string, error) {
+	if len(req.InstanceIds) > 0 {
+		seen := make(map[string]bool, len(req.InstanceIds))
+		instanceIDs := make([]string, 0, len(req.InstanceIds))
+		for _, instanceID := range req.InstanceIds {
+			if !seen[instanceID] {
+				seen[instanceID] = true
+				instanceIDs = append(instanceIDs, instanceID)
+			}
+		}
+		return instanceIDs, nil
+	}
+
+	requirements, _ := common.ParseLabelSelector(req.LabelSelector)
+	instances, err := mysql.McpInstanceRepo.FindByLabelSelector(ctx, requirements, maxBulkInstances+1)
 	if err != nil {
-		return nil, fmt.Errorf("禁用实例失败: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeInstanceBulkQueryFailure)
+	}
+	if len(instances) > maxBulkInstances {
+		return nil, common.NewError(i18nresp.CodeInstanceBulkTooMany, maxBulkInstances)
 	}
+	instanceIDs := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		instanceIDs = append(instanceIDs, instance.InstanceID)
+	}
+	return instanceIDs, nil
+}
 
-	return &instancepb.DisabledResp{Message: msg}, nil
+// bulkOne runs a bulk action on one instance
+func (s *InstanceService) bulkOne(ctx context.Context, action, instanceID, origin string) *instancepb.BulkResult {
+	var (
+		message string
+		err     error
+	)
+	switch action {
+	case bulkActionDisable:
+		var resp *instancepb.DisabledResp
+		resp, err = s.disable(ctx, &instancepb.DisabledRequest{InstanceId: instanceID})
+		message = resp.GetMessage()
+	case bulkActionEnable:
+		message, err = s.enable(ctx, instanceID)
+	case bulkActionRestart:
+		var resp *instancepb.RestartResp
+		resp, err = s.restart(ctx, &instancepb.RestartRequest{InstanceId: instanceID}, origin)
+		message = resp.GetMessage()
+	case bulkActionDelete:
+		var resp *instancepb.DeleteResp
+		resp, err = s.delete(ctx, instanceID)
+		message = resp.GetMessage()
+	}
+	if err != nil {
+		return &instancepb.BulkResult{InstanceId: instanceID, Error: applyErrorMessage(ctx, err)}
+	}
+	return &instancepb.BulkResult{InstanceId: instanceID, Success: true, Message: message}
 }
 
 // getInstanceByID retrieves an instance by its ID
 func (s *InstanceService) getInstanceByID(instanceID string) (*model.McpInstance, error) {
 	instance, err := biz.GInstanceBiz.GetInstance(instanceID)
 	if err != nil {
-		return nil, fmt.Errorf("获取实例信息失败: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrInstanceNotFound(instanceID)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeGetInstanceFailure)
 	}
 	if instance == nil {
-		return nil, fmt.Errorf("实例不存在")
+		return nil, common.ErrInstanceNotFound(instanceID)
+	}
+	return instance, nil
+}
+
+// getEditableInstance retrieves an instance that may be modified, locked instances are rejected
+func (s *InstanceService) getEditableInstance(instanceID string) (*model.McpInstance, error) {
+	instance, err := s.getInstanceByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Locked {
+		return nil, common.NewError(i18nresp.CodeInstanceLocked, instance.InstanceName, instance.LockReason)
 	}
 	return instance, nil
 }
@@ -693,41 +2649,24 @@ func (s *InstanceService) updateInstanceStatusToPending(instance *model.McpInsta
 	instance.ContainerStatus = model.ContainerStatusPending
 	instance.ContainerLastMessage = "Instance is restarting"
 	if err := mysql.McpInstanceRepo.Update(s.ctx, instance); err != nil {
-		return fmt.Errorf("failed to update instance status: %v", err)
+		return common.WrapError(err, i18nresp.CodeInstanceStatusUpdateFailure)
 	}
 	return nil
 }
 
 // createInstanceDirectMode direct connection mode handler function
-func (s *InstanceService) createInstanceDirectMode(req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
+func (s *InstanceService) createInstanceDirectMode(ctx context.Context, req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
 	accessType, err := common.ConvertToModelAccessType(req.AccessType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert access type: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
 	}
 	mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert mcp protocol: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
 	}
 	sourceType, err := common.ConvertToModelSourceType(req.SourceType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert source type: %w", err)
-	}
-	if len(req.McpServers) == 0 {
-		return nil, fmt.Errorf("missing required field: mcpServers")
-	}
-	// Validate MCP configuration format
-	validationResult, err := utils.ValidateMcpConfig([]byte(req.McpServers))
-	if err != nil {
-		return nil, fmt.Errorf("failed to validate mcp servers: %w", err)
-	}
-	if !validationResult.IsValid {
-		return nil, fmt.Errorf("mcp servers config is invalid: %s", validationResult.ErrorMessage)
-	}
-	if validationResult.Url == "" {
-		return nil, fmt.Errorf("mcp servers config is invalid: url is empty")
-	}
-	if validationResult.ProtocolType != string(mcpProtocol) {
-		return nil, fmt.Errorf("mcp servers config is invalid: protocol type is %s, expected %s", validationResult.ProtocolType, mcpProtocol)
+		return nil, common.WrapError(err, i18nresp.CodeSourceTypeConvertFailure)
 	}
 
 	sourceConfig := json.RawMessage([]byte(req.McpServers))
@@ -747,11 +2686,18 @@ func (s *InstanceService) createInstanceDirectMode(req *instancepb.CreateRequest
 		McpServerID:       req.McpServerId,      // Add mcpServerId field handling
 		TemplateID:        uint(req.TemplateId), // Add templateId field handling
 		ServicePath:       req.ServicePath,      // Add servicePath field handling
+		Labels:            marshalLabels(req.Labels),
+		Metadata:          common.NormalizeMetadata(req.Metadata),
+		Slug:              instanceSlug(req.Slug),
+		CreatorID:         common.UserIDFromContext(ctx),
+	}
+	if req.DryRun {
+		return s.dryRunCreateResp(req, instance, nil)
 	}
 
 	// Save instance to database
 	if err := biz.GInstanceBiz.CreateInstance(instance); err != nil {
-		return nil, fmt.Errorf("failed to create instance: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeCreateInstanceFailure)
 	}
 
 	return &instancepb.CreateResp{
@@ -764,39 +2710,26 @@ func (s *InstanceService) createInstanceDirectMode(req *instancepb.CreateRequest
 }
 
 // createInstanceProxyMode proxy mode handler function
-func (s *InstanceService) createInstanceProxyMode(req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
+func (s *InstanceService) createInstanceProxyMode(ctx context.Context, req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
 	accessType, err := common.ConvertToModelAccessType(req.AccessType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert access type: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
 	}
 	mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert mcp protocol: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
 	}
 	sourceType, err := common.ConvertToModelSourceType(req.SourceType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert source type: %w", err)
-	}
-	if len(req.McpServers) == 0 {
-		return nil, fmt.Errorf("missing required field: mcpServers")
-	}
-	// Validate MCP configuration format
-	validationResult, err := utils.ValidateMcpConfig([]byte(req.McpServers))
-	if err != nil {
-		return nil, fmt.Errorf("failed to validate mcp servers: %w", err)
-	}
-	if !validationResult.IsValid {
-		return nil, fmt.Errorf("mcp servers config is invalid: %s", validationResult.ErrorMessage)
-	}
-	if validationResult.Url == "" {
-		return nil, fmt.Errorf("mcp servers config is invalid: url is empty")
-	}
-	if validationResult.ProtocolType != string(mcpProtocol) {
-		return nil, fmt.Errorf("mcp servers config is invalid: protocol type is %s, expected %s", validationResult.ProtocolType, mcpProtocol)
+		return nil, common.WrapError(err, i18nresp.CodeSourceTypeConvertFailure)
 	}
 
-	// Create proxy configuration
-	publicProxyConfig := biz.GInstanceBiz.CreatePublicProxyConfig(instanceID, mcpProtocol)
+	// Create proxy configuration, multi-server instances get one entry per server
+	var sourceConfig model.McpServersConfig
+	if err := json.Unmarshal([]byte(req.McpServers), &sourceConfig); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeGetTargetConfigFailure)
+	}
+	publicProxyConfig := biz.GInstanceBiz.CreatePublicProxyConfig(instanceID, mcpProtocol, sourceConfig.ServerNames())
 	pb, _ := common.MarshalAndAssignConfig(publicProxyConfig)
 
 	// Create new instance record
@@ -815,11 +2748,21 @@ func (s *InstanceService) createInstanceProxyMode(req *instancepb.CreateRequest,
 		McpServerID:       req.McpServerId,      // Add mcpServerId field handling
 		TemplateID:        uint(req.TemplateId), // Add templateId field handling
 		ServicePath:       req.ServicePath,      // Add servicePath field handling
+		Labels:            marshalLabels(req.Labels),
+		Metadata:          common.NormalizeMetadata(req.Metadata),
+		Slug:              instanceSlug(req.Slug),
+		CreatorID:         common.UserIDFromContext(ctx),
+	}
+	if err := applyRequestTimeouts(instance, req.Timeout, req.SseReadTimeout); err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return s.dryRunCreateResp(req, instance, nil)
 	}
 
 	// Save instance to database
 	if err := biz.GInstanceBiz.CreateInstance(instance); err != nil {
-		return nil, fmt.Errorf("failed to create instance: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeCreateInstanceFailure)
 	}
 
 	return &instancepb.CreateResp{
@@ -832,66 +2775,100 @@ func (s *InstanceService) createInstanceProxyMode(req *instancepb.CreateRequest,
 }
 
 // createInstanceHosting Hosting mode handler function
-func (s *InstanceService) createInstanceHosting(req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
-
-	// Validate timeout parameters
-	if err := s.validateTimeoutParams(int(req.StartupTimeout), int(req.RunningTimeout)); err != nil {
-		return nil, fmt.Errorf("parameter validation failed: %w", err)
-	}
+func (s *InstanceService) createInstanceHosting(ctx context.Context, req *instancepb.CreateRequest, instanceID string) (*instancepb.CreateResp, error) {
 	mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert mcp protocol: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
 	}
 	sourceType, err := common.ConvertToModelSourceType(req.SourceType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert source type: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeSourceTypeConvertFailure)
 	}
 
-	if req.Port <= 0 {
-		return nil, fmt.Errorf("missing required field: port")
-	}
-	// Validate environment ID
-	if req.EnvironmentId == 0 {
-		return nil, fmt.Errorf("hosting type instance requires environment ID")
-	}
-	if req.ImgAddress == "" {
-		return nil, fmt.Errorf("missing required field: imgAddress")
-	}
 	// Query Kubernetes configuration and namespace based on environment ID
 	environment, err := biz.GEnvironmentBiz.GetEnvironment(s.ctx, uint(req.EnvironmentId))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get environment information: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeGetEnvironmentFailure)
 	}
 
 	// Validate environment type
 	if environment.Environment != model.McpEnvironmentKubernetes {
-		return nil, fmt.Errorf("environment type is not Kubernetes, cannot create container")
+		return nil, common.NewError(i18nresp.CodeHostingRequiresKubernetes)
 	}
 
-	if mcpProtocol == model.McpProtocolStdio {
-		mcpServers := req.McpServers
-		if len(mcpServers) == 0 {
-			return nil, fmt.Errorf("mcp servers config is empty")
-		}
-		reqMcpResult, err2 := utils.ValidateMcpConfig([]byte(mcpServers))
-		if err2 != nil {
-			return nil, fmt.Errorf("failed to validate mcp servers: %w", err2)
+	defaults, err := environment.GetDefaults()
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceConfigBuildFailure)
+	}
+	containerOptions, err := biz.GContainerBiz.BuildContainerOptions(s.ctx, instanceID, mcpProtocol, req.McpServers, req.PackageId, req.Port,
+		req.InitScript, req.Command, req.ImgAddress, req.EnvironmentVariables, req.VolumeMounts, req.InitContainers, req.InitSharedPath, req.Sidecars,
+		int32(req.StartupTimeout), int32(req.RunningTimeout), req.Labels, defaults)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeInstanceConfigBuildFailure)
+	}
+	if req.Replicas > 0 {
+		containerOptions.Replicas = req.Replicas
+	}
+	biz.ApplyDisruptionSettings(containerOptions, req.PriorityClassName, req.CreatePodDisruptionBudget)
+	biz.ApplyServiceAccountSettings(containerOptions, req.ServiceAccountName, req.IsolateServiceAccount)
+	podAffinity, err := biz.GInstanceBiz.BuildPodAffinity(s.ctx, uint(req.EnvironmentId), req.PodAffinity)
+	if err != nil {
+		var targetErr *biz.AffinityTargetError
+		if errors.As(err, &targetErr) {
+			return nil, common.NewError(i18nresp.CodeAffinityTargetInvalid, targetErr.InstanceID)
 		}
-		if !reqMcpResult.IsValid {
-			return nil, fmt.Errorf("mcp servers config is invalid: %s", reqMcpResult.ErrorMessage)
+		return nil, common.WrapError(err, i18nresp.CodeInstanceConfigBuildFailure)
+	}
+	containerOptions.PodAffinity = podAffinity
+	containerOptions.AllowedEgress = req.AllowedEgress
+	if err := biz.GEnvironmentBiz.CheckQuota(s.ctx, environment, biz.ContainerQuotaUsage(*containerOptions, containerOptions.Replicas)); err != nil {
+		return nil, err
+	}
+	// 全局配置的拉取密钥在前，环境默认的拉取密钥在后
+	for _, secret := range biz.GContainerBiz.ImagePullSecrets() {
+		if !slices.Contains(containerOptions.ImagePullSecrets, secret) {
+			containerOptions.ImagePullSecrets = append(containerOptions.ImagePullSecrets, secret)
 		}
-		if !reqMcpResult.HasCommand {
-			return nil, fmt.Errorf("mcp servers config is invalid: command is required")
+	}
+	// 试运行不同步拉取密钥，避免在集群中创建 Secret
+	if !req.DryRun {
+		if err := s.syncPullSecrets(uint(req.EnvironmentId), containerOptions); err != nil {
+			return nil, err
 		}
 	}
-	containerOptions, err := biz.GContainerBiz.BuildContainerOptions(s.ctx, instanceID, mcpProtocol, req.McpServers, req.PackageId, req.Port,
-		req.InitScript, req.Command, req.ImgAddress, req.EnvironmentVariables, req.VolumeMounts, int32(req.StartupTimeout), int32(req.RunningTimeout))
+
+	// Fail fast when the image or tag does not exist in the registry, the digest the tag resolves to is recorded on the instance
+	imageDigest, err := biz.GContainerBiz.CheckImageAvailable(s.ctx, uint(req.EnvironmentId), containerOptions.ImageName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build container options: %w", err)
+		return nil, err
 	}
-	err = biz.GContainerBiz.CreateContainer(containerOptions, req.EnvironmentId, req.StartupTimeout)
+	if err := biz.GContainerBiz.CheckServiceAccount(s.ctx, uint(req.EnvironmentId), containerOptions.ServiceAccount); err != nil {
+		return nil, err
+	}
+	// 环境开启严重漏洞拦截时创建前同步扫描镜像，超过上限拒绝创建并返回扫描结果
+	scanReport, err := biz.GContainerBiz.CheckImageVulnerabilities(s.ctx, environment, containerOptions.ImageName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create container: %w", err)
+		var blockedErr *biz.ImageScanBlockedError
+		if errors.As(err, &blockedErr) {
+			return nil, &common.Error{
+				Code: i18nresp.CodeImageScanBlocked,
+				Args: []interface{}{containerOptions.ImageName, blockedErr.Report.Critical, blockedErr.MaxCritical},
+				Data: imageScanToPb(s.ctx, &biz.ImageScanResult{Status: biz.ImageScanStatusCompleted, UpdatedAt: blockedErr.Report.ScannedAt, Report: blockedErr.Report}),
+			}
+		}
+		return nil, err
+	}
+	// 环境同时创建的容器数已满时保存为排队状态，由创建队列按顺序创建
+	queued := false
+	if !req.DryRun {
+		queued = !biz.GContainerBiz.TryAcquireCreateSlot(uint(req.EnvironmentId))
+		if !queued {
+			err = biz.GContainerBiz.CreateContainer(containerOptions, req.EnvironmentId, req.StartupTimeout)
+			if err != nil {
+				biz.GContainerBiz.ReleaseCreateSlot(uint(req.EnvironmentId))
+				return nil, common.ErrContainerRuntime(err)
+			}
+		}
 	}
 
 	// Create target configuration
@@ -903,32 +2880,44 @@ func (s *InstanceService) createInstanceHosting(req *instancepb.CreateRequest, i
 	tb := []byte{}
 	switch mcpProtocol {
 	case model.McpProtocolStdio:
-		if strings.Contains(req.ImgAddress, common.DefatuleHostingImg) {
-			targetConfig := common.CreateTargetProxyConfigForDefatuleHostingImg(containerOptions.ServiceName, containerOptions.Port, containerOptions.ContainerName, toMcpProtocol)
+		if common.IsDefaultHostingImage(req.ImgAddress, environment.HostingImage) {
+			targetConfig := common.CreateTargetProxyConfigForDefaultHostingImage(containerOptions.ServiceName, containerOptions.Port, containerOptions.ContainerName, toMcpProtocol)
 			tb, _ = common.MarshalAndAssignConfig(targetConfig)
 		}
 	case model.McpProtocolSSE, model.McpProtocolStreamableHttp:
 		targetConfig := common.CreateTargetProxyConfigForHttp(containerOptions.ServiceName, containerOptions.Port, containerOptions.ContainerName, mcpProtocol, req.ServicePath)
 		tb, _ = common.MarshalAndAssignConfig(targetConfig)
 	default:
-		return nil, fmt.Errorf("unsupported mcp protocol: %v", mcpProtocol)
+		return nil, common.NewError(i18nresp.CodeUnsupportedMcpProtocol, mcpProtocol)
+	}
+	// 网关代理超时写入生成的目标配置
+	if b, err := common.ApplyRequestTimeouts(tb, req.Timeout, req.SseReadTimeout); err == nil {
+		tb = b
 	}
 	// Create proxy configuration
-	publicProxyConfig := biz.GInstanceBiz.CreatePublicProxyConfig(instanceID, toMcpProtocol)
+	publicProxyConfig := biz.GInstanceBiz.CreatePublicProxyConfig(instanceID, toMcpProtocol, nil)
 	pb, _ := common.MarshalAndAssignConfig(publicProxyConfig)
 
 	// Create new instance record
 	containerCreateOptions, err := common.MarshalAndAssignConfig(containerOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal container create options: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "containerCreateOptions")
 	}
 	evs, err := common.MarshalAndAssignConfig(req.EnvironmentVariables)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal environment variables: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "environmentVariables")
 	}
 	vms, err := common.MarshalAndAssignConfig(req.VolumeMounts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal volume mounts: %w", err)
+		return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "volumeMounts")
+	}
+	initContainers, err := common.MarshalAndAssignConfig(req.InitContainers)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "initContainers")
+	}
+	sidecars, err := common.MarshalAndAssignConfig(req.Sidecars)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "sidecars")
 	}
 	instance := &model.McpInstance{
 		InstanceID:             instanceID,
@@ -949,11 +2938,15 @@ func (s *InstanceService) createInstanceHosting(req *instancepb.CreateRequest, i
 		Command:                req.Command,
 		EnvironmentVariables:   evs,
 		VolumeMounts:           vms,
+		InitContainers:         initContainers,
+		InitSharedPath:         containerOptions.InitSharedPath,
+		Sidecars:               sidecars,
 		ContainerName:          containerOptions.ContainerName,
 		ContainerServiceName:   containerOptions.ServiceName,
 		ContainerIsReady:       false,
 		ContainerCreateOptions: containerCreateOptions,
 		ContainerLastMessage:   "container is pending",
+		Replicas:               max(req.Replicas, 1),
 		StartupTimeout:         int64(req.StartupTimeout),
 		RunningTimeout:         int64(req.RunningTimeout),
 		SourceConfig:           json.RawMessage(req.McpServers),
@@ -962,107 +2955,156 @@ func (s *InstanceService) createInstanceHosting(req *instancepb.CreateRequest, i
 		ServicePath:            req.ServicePath,
 		Notes:                  req.Notes,
 		IconPath:               req.IconPath,
+		Labels:                 marshalLabels(req.Labels),
+		Metadata:               common.NormalizeMetadata(req.Metadata),
+		Slug:                   instanceSlug(req.Slug),
+		CreatorID:              common.UserIDFromContext(ctx),
+	}
+	if scanReport != nil {
+		instance.ImageScan = biz.CompletedImageScan(scanReport)
+	}
+	instance.ImageDigest = imageDigest
+	if req.TemplateId != 0 {
+		// 模板没有版本号，以创建时模板的更新时间作为模板版本
+		if template, err := mysql.McpTemplateRepo.FindByID(s.ctx, uint(req.TemplateId)); err == nil {
+			instance.TemplateUpdatedAt = &template.UpdatedAt
+		}
+	}
+	biz.GCodePackageBiz.StampVersion(s.ctx, instance)
+	if req.DryRun {
+		return s.dryRunCreateResp(req, instance, containerOptions)
+	}
+	if queued {
+		instance.ContainerStatus = model.ContainerStatusQueued
+		instance.ContainerLastMessage = "waiting in the environment creation queue"
 	}
 
 	// Save instance to database
 	if err := biz.GInstanceBiz.CreateInstance(instance); err != nil {
-		return nil, fmt.Errorf("failed to create instance: %w", err)
+		if !queued {
+			biz.GContainerBiz.ReleaseCreateSlot(instance.EnvironmentID)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeCreateInstanceFailure)
+	}
+
+	resp := &instancepb.CreateResp{
+		InstanceId:     instanceID,
+		Name:           req.Name,
+		Status:         string(model.InstanceStatusActive),
+		AccessType:     req.AccessType,
+		McpProtocol:    req.McpProtocol,
+		ScriptWarnings: lintScripts(s.ctx, req.InitScript, req.Command, containerOptions.ImageName, containerOptions.EnvVars),
+		Warnings:       biz.GContainerBiz.EgressWarnings(s.ctx, uint(req.EnvironmentId), containerOptions.AllowedEgress),
+	}
+	if queued {
+		resp.QueuePosition = biz.GContainerBiz.EnqueueCreate(instance.EnvironmentID, instanceID)
+	} else {
+		biz.GContainerBiz.HoldCreateSlot(instance)
+	}
+	// 创建前未扫描时在后台扫描镜像，扫描失败不影响创建
+	if scanReport == nil && biz.GContainerBiz.ImageScanEnabled() {
+		if _, err := biz.GContainerBiz.ScanInstanceImage(s.ctx, instance); err != nil {
+			logger.Warn("Failed to start image scan", zap.String("instanceId", instanceID), zap.Error(err))
+		}
 	}
-
-	return &instancepb.CreateResp{
-		InstanceId:  instanceID,
-		Name:        req.Name,
-		Status:      string(model.InstanceStatusActive),
-		AccessType:  req.AccessType,
-		McpProtocol: req.McpProtocol,
-	}, nil
+	return resp, nil
 }
 
-// validateTimeoutParams validates timeout parameters
-func (s *InstanceService) validateTimeoutParams(startupTimeout, runningTimeout int) error {
-	// Startup timeout validation
-	if startupTimeout < 0 {
-		return fmt.Errorf("startup timeout cannot be negative")
+// syncPullSecrets syncs the registry credentials of the main, init and sidecar images to the environment
+// and adds the resulting pull secrets to the container options
+func (s *InstanceService) syncPullSecrets(environmentID uint, containerOptions *container.ContainerCreateOptions) error {
+	pullSecret, err := biz.GContainerBiz.SyncPullSecret(s.ctx, environmentID, containerOptions.ImageName)
+	if err != nil {
+		return common.WrapError(err, i18nresp.CodeImagePullSecretSyncFailure, containerOptions.ImageName)
 	}
-	if startupTimeout > 0 && startupTimeout < 30 {
-		return fmt.Errorf("startup timeout cannot be less than 30 seconds")
+	if pullSecret != "" {
+		containerOptions.ImagePullSecrets = append(containerOptions.ImagePullSecrets, pullSecret)
 	}
-	if startupTimeout > 3600 {
-		return fmt.Errorf("startup timeout cannot exceed 3600 seconds")
+	// 初始化容器和边车容器镜像可能来自其他私有仓库，同步对应的拉取密钥
+	extraImages := make([]string, 0, len(containerOptions.InitContainers)+len(containerOptions.Sidecars))
+	for _, ic := range containerOptions.InitContainers {
+		extraImages = append(extraImages, ic.Image)
 	}
-
-	// Running timeout validation
-	if runningTimeout < 0 {
-		return fmt.Errorf("running timeout cannot be negative")
+	for _, sc := range containerOptions.Sidecars {
+		extraImages = append(extraImages, sc.Image)
+	}
+	for _, image := range extraImages {
+		pullSecret, err := biz.GContainerBiz.SyncPullSecret(s.ctx, environmentID, image)
+		if err != nil {
+			return common.WrapError(err, i18nresp.CodeImagePullSecretSyncFailure, image)
+		}
+		if pullSecret != "" && !slices.Contains(containerOptions.ImagePullSecrets, pullSecret) {
+			containerOptions.ImagePullSecrets = append(containerOptions.ImagePullSecrets, pullSecret)
+		}
 	}
-	if runningTimeout > 0 && runningTimeout < 60 {
-		return fmt.Errorf("running timeout cannot be less than 60 seconds")
+	return nil
+}
+
+// containerOptionsSecretValues returns the secret env values of the main, init and sidecar containers
+func containerOptionsSecretValues(containerOptions *container.ContainerCreateOptions) []string {
+	secrets := common.SecretValues(containerOptions.EnvVars)
+	for _, ic := range containerOptions.InitContainers {
+		secrets = append(secrets, common.SecretValues(ic.EnvVars)...)
 	}
-	if runningTimeout > 86400 {
-		return fmt.Errorf("running timeout cannot exceed 86400 seconds")
+	for _, sc := range containerOptions.Sidecars {
+		secrets = append(secrets, common.SecretValues(sc.EnvVars)...)
 	}
+	return secrets
+}
 
-	return nil
+// dryRunCreateResp builds the response of a dry-run create from the instance that would be saved,
+// secret values from the env vars and the mcpServers config are masked in every returned config
+func (s *InstanceService) dryRunCreateResp(req *instancepb.CreateRequest, instance *model.McpInstance, containerOptions *container.ContainerCreateOptions) (*instancepb.CreateResp, error) {
+	secrets := common.SecretValues(req.EnvironmentVariables)
+	secrets = append(secrets, common.McpServersSecretValues(json.RawMessage(req.McpServers))...)
+	resp := &instancepb.CreateResp{
+		InstanceId:        instance.InstanceID,
+		Name:              req.Name,
+		AccessType:        req.AccessType,
+		McpProtocol:       req.McpProtocol,
+		DryRun:            true,
+		TargetConfig:      string(common.MaskSecrets(instance.TargetConfig, secrets)),
+		PublicProxyConfig: string(common.MaskSecrets(instance.PublicProxyConfig, secrets)),
+	}
+	if containerOptions != nil {
+		secrets = append(secrets, containerOptionsSecretValues(containerOptions)...)
+		data, err := json.Marshal(containerOptions)
+		if err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeMarshalConfigFailure, "containerCreateOptions")
+		}
+		resp.ContainerOptions = string(common.MaskSecrets(data, secrets))
+		resp.ScriptWarnings = lintScripts(s.ctx, req.InitScript, req.Command, containerOptions.ImageName, containerOptions.EnvVars)
+		resp.Warnings = biz.GContainerBiz.EgressWarnings(s.ctx, instance.EnvironmentID, containerOptions.AllowedEgress)
+	}
+	return resp, nil
 }
 
-// List get instance list
 // convertMcpConfigToProto converts JSON configuration from database to proto structure
-func (s *InstanceService) convertMcpConfigToProto(configData json.RawMessage) *instancepb.McpServersConfig {
+func (s *InstanceService) convertMcpConfigToProto(configData json.RawMessage) (*instancepb.McpServersConfig, error) {
 	if len(configData) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Parse configuration from database
 	_, mcpConfig, err := model.ParseMcpServersConfig(configData)
-	if err != nil || mcpConfig == nil {
-		return nil
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract host and port information from URL
-	host := "localhost"
-	port := int32(8080)
-	protocol := "http"
-
-	if mcpConfig.URL != "" {
-		// Simple URL parsing to get host and port
-		if strings.HasPrefix(mcpConfig.URL, "http://") {
-			protocol = "http"
-			urlPart := strings.TrimPrefix(mcpConfig.URL, "http://")
-			if parts := strings.Split(urlPart, ":"); len(parts) >= 2 {
-				host = parts[0]
-				if portStr := strings.Split(parts[1], "/")[0]; portStr != "" {
-					if p, err := strconv.Atoi(portStr); err == nil {
-						port = int32(p)
-					}
-				}
-			} else if len(parts) == 1 {
-				host = strings.Split(parts[0], "/")[0]
-			}
-		} else if strings.HasPrefix(mcpConfig.URL, "https://") {
-			protocol = "https"
-			urlPart := strings.TrimPrefix(mcpConfig.URL, "https://")
-			if parts := strings.Split(urlPart, ":"); len(parts) >= 2 {
-				host = parts[0]
-				if portStr := strings.Split(parts[1], "/")[0]; portStr != "" {
-					if p, err := strconv.Atoi(portStr); err == nil {
-						port = int32(p)
-					}
-				}
-			} else if len(parts) == 1 {
-				host = strings.Split(parts[0], "/")[0]
-				port = 443
-			}
-		}
+	endpoint, err := common.ParseEndpoint(mcpConfig.URL)
+	if err != nil {
+		return nil, err
 	}
 
 	// If Transport field exists, use it first
+	protocol := endpoint.Scheme
 	if mcpConfig.Transport != "" {
 		protocol = mcpConfig.Transport
 	}
 
 	return &instancepb.McpServersConfig{
-		Host:     host,
-		Port:     port,
+		Host:     endpoint.Host,
+		Port:     int32(endpoint.Port),
 		Protocol: protocol,
-	}
+	}, nil
 }
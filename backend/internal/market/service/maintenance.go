@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	maintenancepb "qm-mcp-server/api/market/maintenance"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	i18nresp "qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/redis"
+)
+
+// MaintenanceService 维护模式服务
+type MaintenanceService struct {
+	ctx context.Context
+}
+
+// NewMaintenanceService 创建维护模式服务
+func NewMaintenanceService(ctx context.Context) *MaintenanceService {
+	return &MaintenanceService{ctx: ctx}
+}
+
+// StatusHandler 查询维护模式状态，所有登录用户可用，用于展示维护横幅
+func (s *MaintenanceService) StatusHandler(c *gin.Context) {
+	state, err := redis.GetMaintenance()
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeMaintenanceStateFailure))
+		return
+	}
+	common.GinSuccess(c, maintenanceStatus(c.Request.Context(), state))
+}
+
+// EnableHandler 开启维护模式
+func (s *MaintenanceService) EnableHandler(c *gin.Context) {
+	var req maintenancepb.EnableRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	state := &common.MaintenanceState{
+		Message:   req.Message,
+		StartedAt: time.Now().UnixMilli(),
+		Operator:  c.GetString("username"),
+	}
+	if req.EndAt != "" {
+		endAt, err := time.Parse(time.RFC3339, req.EndAt)
+		if err != nil {
+			common.GinErrorFrom(c, common.ErrValidation("endAt", "must be an RFC3339 time"))
+			return
+		}
+		if !endAt.After(time.Now()) {
+			common.GinErrorFrom(c, common.ErrValidation("endAt", "must be in the future"))
+			return
+		}
+		state.EndAt = endAt.UnixMilli()
+	}
+	// 已处于维护模式时保留开始时间，只更新说明和结束时间
+	if current, err := redis.GetMaintenance(); err == nil && current != nil {
+		state.StartedAt = current.StartedAt
+	}
+
+	if err := redis.SetMaintenance(state); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeMaintenanceStateFailure))
+		return
+	}
+	common.GinSuccess(c, maintenanceStatus(c.Request.Context(), state))
+}
+
+// DisableHandler 关闭维护模式
+func (s *MaintenanceService) DisableHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if err := redis.ClearMaintenance(); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeMaintenanceStateFailure))
+		return
+	}
+	common.GinSuccess(c, maintenanceStatus(c.Request.Context(), nil))
+}
+
+// DebugVarsHandler 运行指标，包含后台任务锁的持有副本等内部状态，仅管理员可以查看
+func (s *MaintenanceService) DebugVarsHandler(c *gin.Context) {
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	expvar.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// maintenanceStatus 转换维护模式状态，state 为 nil 表示未处于维护模式
+func maintenanceStatus(ctx context.Context, state *common.MaintenanceState) *maintenancepb.StatusResp {
+	if state == nil {
+		return &maintenancepb.StatusResp{}
+	}
+	resp := &maintenancepb.StatusResp{
+		Enabled:     true,
+		Message:     state.Message,
+		StartedAt:   common.FormatTimeRFC3339(ctx, time.UnixMilli(state.StartedAt)),
+		StartedAtMs: state.StartedAt,
+		Operator:    state.Operator,
+	}
+	if state.EndAt > 0 {
+		resp.EndAt = common.FormatTimeRFC3339(ctx, time.UnixMilli(state.EndAt))
+		resp.EndAtMs = state.EndAt
+	}
+	return resp
+}
+
+// requireAdmin 校验当前用户为管理员
+func requireAdmin(c *gin.Context) error {
+	if !isAdmin(c) {
+		return common.NewError(i18nresp.CodeInsufficientPermissions)
+	}
+	return nil
+}
+
+// isAdmin 判断当前用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	user, err := mysql.SysUserRepo.FindByID(c.Request.Context(), uint(c.GetInt64("userId")))
+	return err == nil && user != nil && user.IsAdmin
+}
+
+// IsViewer 判断当前用户是否为只读角色，供只读角色中间件使用
+func IsViewer(c *gin.Context) (bool, error) {
+	return mysql.SysUsersRolesRepo.HasRoleName(c.Request.Context(), uint(c.GetInt64("userId")), model.RoleViewer)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	notificationpb "qm-mcp-server/api/market/notification"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	i18nresp "qm-mcp-server/pkg/i18n"
+)
+
+// NotificationService 通知偏好服务
+type NotificationService struct {
+	ctx context.Context
+}
+
+// NewNotificationService 创建通知偏好服务
+func NewNotificationService(ctx context.Context) *NotificationService {
+	return &NotificationService{ctx: ctx}
+}
+
+// GetNotificationPreferenceHandler 查询通知偏好，非管理员只能查询自己的偏好
+func (s *NotificationService) GetNotificationPreferenceHandler(c *gin.Context) {
+	var req notificationpb.GetNotificationPreferenceRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if req.Id != c.GetInt64("userId") {
+		if err := requireAdmin(c); err != nil {
+			common.GinErrorFrom(c, err)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if _, err := findQuotaUser(ctx, uint(req.Id)); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	preference, err := biz.GNotificationBiz.GetPreference(ctx, uint(req.Id))
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeDatabaseError))
+		return
+	}
+	common.GinSuccess(c, s.toResp(ctx, preference))
+}
+
+// UpdateNotificationPreferenceHandler 设置通知偏好，非管理员只能设置自己的偏好
+func (s *NotificationService) UpdateNotificationPreferenceHandler(c *gin.Context) {
+	var req notificationpb.UpdateNotificationPreferenceRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := validateUpdateNotificationPreferenceRequest(&req); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if req.Id != c.GetInt64("userId") {
+		if err := requireAdmin(c); err != nil {
+			common.GinErrorFrom(c, err)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if _, err := findQuotaUser(ctx, uint(req.Id)); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	preference := &model.McpNotificationPreference{
+		UserID:  uint(req.Id),
+		Enabled: req.Enabled,
+		Email:   req.Email,
+		Mode:    req.Mode,
+	}
+	if preference.Mode == "" {
+		preference.Mode = model.NotificationModeImmediate
+	}
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+	events, err := json.Marshal(req.Events)
+	if err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeInternalError))
+		return
+	}
+	preference.Events = events
+	if err := biz.GNotificationBiz.SetPreference(ctx, preference); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeDatabaseError))
+		return
+	}
+	common.GinSuccess(c, s.toResp(ctx, preference))
+}
+
+// toResp 转换通知偏好，未选择事件时返回默认接收的事件
+func (s *NotificationService) toResp(ctx context.Context, preference *model.McpNotificationPreference) *notificationpb.NotificationPreferenceResp {
+	events := preference.GetEvents()
+	if len(events) == 0 {
+		events = biz.DefaultNotificationEvents
+	}
+	resp := &notificationpb.NotificationPreferenceResp{
+		UserId:          int64(preference.UserID),
+		Enabled:         preference.Enabled,
+		Email:           preference.Email,
+		Events:          events,
+		Mode:            preference.Mode,
+		DigestInterval:  int32(config.GlobalConfig.Notify.DigestInterval),
+		AvailableEvents: biz.NotificationEvents,
+		SmtpConfigured:  biz.GNotificationBiz.Enabled(),
+	}
+	if preference.LastDigestAt != nil {
+		resp.LastDigestAt = common.FormatTimeRFC3339(ctx, *preference.LastDigestAt)
+	}
+	return resp
+}
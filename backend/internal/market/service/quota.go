@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	quotapb "qm-mcp-server/api/market/quota"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	i18nresp "qm-mcp-server/pkg/i18n"
+)
+
+// UserQuotaService 用户配额服务
+type UserQuotaService struct {
+	ctx context.Context
+}
+
+// NewUserQuotaService 创建用户配额服务
+func NewUserQuotaService(ctx context.Context) *UserQuotaService {
+	return &UserQuotaService{ctx: ctx}
+}
+
+// GetUserQuotaHandler 查询用户配额和使用量，非管理员只能查询自己的配额
+func (s *UserQuotaService) GetUserQuotaHandler(c *gin.Context) {
+	var req quotapb.GetUserQuotaRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if req.Id != c.GetInt64("userId") {
+		if err := requireAdmin(c); err != nil {
+			common.GinErrorFrom(c, err)
+			return
+		}
+	}
+
+	resp, err := s.userQuota(c.Request.Context(), uint(req.Id))
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	common.GinSuccess(c, resp)
+}
+
+// UpdateUserQuotaHandler 设置用户配额，仅管理员可用
+func (s *UserQuotaService) UpdateUserQuotaHandler(c *gin.Context) {
+	var req quotapb.UpdateUserQuotaRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+	if err := validateUpdateUserQuotaRequest(&req); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	if err := requireAdmin(c); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := findQuotaUser(ctx, uint(req.Id)); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	quota := &model.McpUserQuota{
+		UserID:              uint(req.Id),
+		MaxInstances:        int(req.MaxInstances),
+		MaxHostingInstances: int(req.MaxHostingInstances),
+		MaxPackageBytes:     req.MaxPackageBytes,
+	}
+	if err := biz.GUserQuotaBiz.SetQuota(ctx, quota); err != nil {
+		common.GinErrorFrom(c, common.WrapError(err, i18nresp.CodeDatabaseError))
+		return
+	}
+
+	resp, err := s.userQuota(ctx, quota.UserID)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	common.GinSuccess(c, resp)
+}
+
+// userQuota 查询用户配额的上限和当前使用量
+func (s *UserQuotaService) userQuota(ctx context.Context, userID uint) (*quotapb.UserQuotaResp, error) {
+	user, err := findQuotaUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := biz.GUserQuotaBiz.GetQuota(ctx, userID)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeUserQuotaFailure)
+	}
+	usage, err := biz.GUserQuotaBiz.Usage(ctx, userID)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeUserQuotaFailure)
+	}
+
+	resp := &quotapb.UserQuotaResp{
+		UserId:              int64(userID),
+		Instances:           usage.Instances,
+		MaxInstances:        int32(quota.MaxInstances),
+		HostingInstances:    usage.HostingInstances,
+		MaxHostingInstances: int32(quota.MaxHostingInstances),
+		PackageBytes:        usage.PackageBytes,
+		MaxPackageBytes:     quota.MaxPackageBytes,
+		UpdatedBy:           quota.UpdatedBy,
+	}
+	if user.Username != nil {
+		resp.Username = *user.Username
+	}
+	if !quota.UpdatedAt.IsZero() {
+		resp.UpdatedAt = common.FormatTimeRFC3339(ctx, quota.UpdatedAt)
+	}
+	return resp, nil
+}
+
+// findQuotaUser 查询配额所属的用户，用户不存在时返回 CodeUserNotFound
+func findQuotaUser(ctx context.Context, userID uint) (*model.SysUser, error) {
+	users, err := mysql.SysUserRepo.FindByIDs(ctx, []uint{userID})
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeDatabaseError)
+	}
+	if len(users) == 0 {
+		return nil, common.NewError(i18nresp.CodeUserNotFound)
+	}
+	return users[0], nil
+}
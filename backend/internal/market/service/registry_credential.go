@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"qm-mcp-server/api/market/registry_credential"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	i18nresp "qm-mcp-server/pkg/i18n"
+)
+
+// redactedPassword 列表和详情中返回的脱敏密码
+const redactedPassword = "******"
+
+// RegistryCredentialService provides private registry credential management functionality
+type RegistryCredentialService struct {
+	ctx context.Context
+}
+
+// NewRegistryCredentialService creates a new RegistryCredentialService instance
+func NewRegistryCredentialService(ctx context.Context) *RegistryCredentialService {
+	return &RegistryCredentialService{
+		ctx: ctx,
+	}
+}
+
+// registryCredentialQueryError 转换凭证查询错误，记录不存在时返回 404
+func registryCredentialQueryError(err error, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return common.NewError(i18nresp.CodeRegistryCredentialNotFound, id)
+	}
+	return common.WrapError(err, i18nresp.CodeRegistryCredentialQueryFailure)
+}
+
+// modelToRegistryCredentialInfo converts model to registry credential info, the password is always redacted
+func modelToRegistryCredentialInfo(ctx context.Context, credential *model.McpRegistryCredential) *registry_credential.RegistryCredentialInfo {
+	return &registry_credential.RegistryCredentialInfo{
+		Id:             int32(credential.ID),
+		Name:           credential.Name,
+		RegistryHost:   credential.RegistryHost,
+		Username:       credential.Username,
+		Password:       redactedPassword,
+		PullSecretName: credential.PullSecretName(),
+		CreatedAt:      common.FormatTimeRFC3339(ctx, credential.CreatedAt),
+		UpdatedAt:      common.FormatTimeRFC3339(ctx, credential.UpdatedAt),
+		CreatedAtMs:    common.TimeMillis(credential.CreatedAt),
+		UpdatedAtMs:    common.TimeMillis(credential.UpdatedAt),
+	}
+}
+
+// parseRegistryCredentialID 从URL路径参数获取凭证ID
+func parseRegistryCredentialID(c *gin.Context) (uint, error) {
+	idStr := c.Param("id")
+	if idStr == "" {
+		return 0, common.ErrRequiredField("id")
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return 0, common.NewError(i18nresp.CodeRegistryCredentialIDInvalid, idStr)
+	}
+	return uint(id), nil
+}
+
+// CreateRegistryCredentialHandler handles registry credential creation requests
+func (s *RegistryCredentialService) CreateRegistryCredentialHandler(c *gin.Context) {
+	var req registry_credential.CreateRegistryCredentialRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.CreateRegistryCredential(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// CreateRegistryCredential creates a new registry credential
+func (s *RegistryCredentialService) CreateRegistryCredential(ctx context.Context, req *registry_credential.CreateRegistryCredentialRequest) (*registry_credential.RegistryCredentialInfo, error) {
+	// 检查凭证名称是否已存在
+	if existing, err := biz.GRegistryCredentialBiz.GetCredentialByName(s.ctx, req.Name); err == nil && existing != nil {
+		return nil, common.NewError(i18nresp.CodeRegistryCredentialNameConflict, req.Name)
+	}
+
+	credential := &model.McpRegistryCredential{
+		Name:         req.Name,
+		RegistryHost: req.RegistryHost,
+		Username:     req.Username,
+	}
+	if err := biz.GRegistryCredentialBiz.CreateCredential(s.ctx, credential, req.Password); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeRegistryCredentialSaveFailure)
+	}
+
+	return modelToRegistryCredentialInfo(ctx, credential), nil
+}
+
+// UpdateRegistryCredentialHandler handles registry credential update requests
+func (s *RegistryCredentialService) UpdateRegistryCredentialHandler(c *gin.Context) {
+	var req registry_credential.UpdateRegistryCredentialRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	id, err := parseRegistryCredentialID(c)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+	req.Id = int32(id)
+
+	result, err := s.UpdateRegistryCredential(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// UpdateRegistryCredential updates an existing registry credential, an empty password keeps the stored one
+func (s *RegistryCredentialService) UpdateRegistryCredential(ctx context.Context, req *registry_credential.UpdateRegistryCredentialRequest) (*registry_credential.RegistryCredentialInfo, error) {
+	credential, err := biz.GRegistryCredentialBiz.GetCredential(s.ctx, uint(req.Id))
+	if err != nil {
+		return nil, registryCredentialQueryError(err, uint(req.Id))
+	}
+
+	// 名称变更时检查是否与其他凭证冲突
+	if req.Name != credential.Name {
+		if existing, err := biz.GRegistryCredentialBiz.GetCredentialByName(s.ctx, req.Name); err == nil && existing != nil {
+			return nil, common.NewError(i18nresp.CodeRegistryCredentialNameConflict, req.Name)
+		}
+	}
+
+	credential.Name = req.Name
+	credential.RegistryHost = req.RegistryHost
+	credential.Username = req.Username
+	if err := biz.GRegistryCredentialBiz.UpdateCredential(s.ctx, credential, req.Password); err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeRegistryCredentialSaveFailure)
+	}
+
+	return modelToRegistryCredentialInfo(ctx, credential), nil
+}
+
+// DeleteRegistryCredentialHandler handles registry credential deletion requests
+func (s *RegistryCredentialService) DeleteRegistryCredentialHandler(c *gin.Context) {
+	id, err := parseRegistryCredentialID(c)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	if err := s.DeleteRegistryCredential(id); err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, gin.H{"message": i18nresp.FormatWithGin(c, i18nresp.CodeRegistryCredentialDeleteSuccess)})
+}
+
+// DeleteRegistryCredential deletes a registry credential
+func (s *RegistryCredentialService) DeleteRegistryCredential(id uint) error {
+	if _, err := biz.GRegistryCredentialBiz.GetCredential(s.ctx, id); err != nil {
+		return registryCredentialQueryError(err, id)
+	}
+	if err := biz.GRegistryCredentialBiz.DeleteCredential(s.ctx, id); err != nil {
+		return common.WrapError(err, i18nresp.CodeRegistryCredentialDeleteFailure)
+	}
+	return nil
+}
+
+// GetRegistryCredentialHandler handles registry credential detail requests
+func (s *RegistryCredentialService) GetRegistryCredentialHandler(c *gin.Context) {
+	id, err := parseRegistryCredentialID(c)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	credential, err := biz.GRegistryCredentialBiz.GetCredential(s.ctx, id)
+	if err != nil {
+		common.GinErrorFrom(c, registryCredentialQueryError(err, id))
+		return
+	}
+
+	common.GinSuccess(c, modelToRegistryCredentialInfo(c.Request.Context(), credential))
+}
+
+// ListRegistryCredentialsHandler handles registry credential list requests
+func (s *RegistryCredentialService) ListRegistryCredentialsHandler(c *gin.Context) {
+	var req registry_credential.ListRegistryCredentialsRequest
+	if err := common.BindAndValidateQuery(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.ListRegistryCredentials(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
+// ListRegistryCredentials lists registry credentials with redacted passwords
+func (s *RegistryCredentialService) ListRegistryCredentials(ctx context.Context, req *registry_credential.ListRegistryCredentialsRequest) (*registry_credential.ListRegistryCredentialsResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = common.DefaultPageSize
+	}
+	if req.PageSize > common.MaxPageSize {
+		req.PageSize = common.MaxPageSize
+	}
+
+	credentials, total, err := biz.GRegistryCredentialBiz.ListCredentials(s.ctx, req.RegistryHost, req.Page, req.PageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeRegistryCredentialQueryFailure)
+	}
+
+	list := make([]*registry_credential.RegistryCredentialInfo, 0, len(credentials))
+	for _, credential := range credentials {
+		list = append(list, modelToRegistryCredentialInfo(ctx, credential))
+	}
+
+	return &registry_credential.ListRegistryCredentialsResponse{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
@@ -38,18 +38,23 @@ func NewTemplateService(ctx context.Context) *TemplateService {
 
 // TemplateCreate creates a new template
 func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.TemplateCreateRequest) (*instance.TemplateCreateResp, error) {
+	return s.createTemplate(ctx, req, nil)
+}
+
+// createTemplate creates a template from the request, source is the instance the template is saved from, nil otherwise
+func (s *TemplateService) createTemplate(ctx context.Context, req *instance.TemplateCreateRequest, source *model.McpInstance) (*instance.TemplateCreateResp, error) {
 	// 参数验证
 	if req.Name == "" {
-		return nil, fmt.Errorf("template name is required")
+		return nil, common.ErrRequiredField("name")
 	}
 
 	// 检查模板名称是否已存在
 	existing, err := s.templateData.GetTemplateByName(ctx, req.Name)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("failed to check template name: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeTemplateNameCheckFailure)
 	}
 	if existing != nil {
-		return nil, fmt.Errorf("template name '%s' already exists", req.Name)
+		return nil, common.ErrTemplateNameConflict(req.Name)
 	}
 
 	// 创建模板对象
@@ -66,6 +71,11 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 		McpServerID:    req.McpServerId,
 		Notes:          req.Notes,
 		IconPath:       req.IconPath,
+		Metadata:       common.NormalizeMetadata(req.Metadata),
+	}
+	if source != nil {
+		template.ServicePath = source.ServicePath
+		template.SourceInstanceID = source.InstanceID
 	}
 
 	// 处理访问类型
@@ -97,7 +107,7 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 		envBytes, err := json.Marshal(req.EnvironmentVariables)
 		if err != nil {
 			logger.Error("failed to marshal environment variables", zap.Error(err))
-			return nil, fmt.Errorf("failed to process environment variables: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "environmentVariables")
 		}
 		template.EnvironmentVariables = envBytes
 	}
@@ -107,7 +117,7 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 		volumeBytes, err := json.Marshal(req.VolumeMounts)
 		if err != nil {
 			logger.Error("failed to marshal volume mounts", zap.Error(err))
-			return nil, fmt.Errorf("failed to process volume mounts: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "volumeMounts")
 		}
 		template.VolumeMounts = volumeBytes
 	}
@@ -131,7 +141,7 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 		tokensJSON, err := json.Marshal(tokens)
 		if err != nil {
 			logger.Error("failed to marshal tokens", zap.Error(err))
-			return nil, fmt.Errorf("failed to process tokens: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "tokens")
 		}
 		template.Tokens = json.RawMessage(tokensJSON)
 	}
@@ -139,13 +149,16 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 	// 创建模板
 	if err := s.templateData.CreateTemplate(ctx, template); err != nil {
 		logger.Error("failed to create template", zap.Error(err), zap.String("name", req.Name))
-		return nil, fmt.Errorf("failed to create template: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeTemplateCreateFailure)
 	}
 
 	// 返回响应
 	resp := &instance.TemplateCreateResp{
 		TemplateId: int32(template.ID),
 	}
+	if template.AccessType == model.AccessTypeHosting {
+		resp.ScriptWarnings = lintScripts(ctx, req.InitScript, req.Command, req.ImgAddress, req.EnvironmentVariables)
+	}
 
 	logger.Info("template created successfully", zap.Int32("templateId", resp.TemplateId), zap.String("name", req.Name))
 	return resp, nil
@@ -154,38 +167,45 @@ func (s *TemplateService) TemplateCreate(ctx context.Context, req *instance.Temp
 // TemplateDetail retrieves template details
 func (s *TemplateService) TemplateDetail(ctx context.Context, req *instance.TemplateDetailRequest) (*instance.TemplateDetailResp, error) {
 	if req.TemplateId == 0 {
-		return nil, fmt.Errorf("template ID is required")
+		return nil, common.ErrRequiredField("templateId")
 	}
 
 	// 查询模板
 	template, err := s.templateData.GetTemplateByID(ctx, uint(req.TemplateId))
 	if err != nil {
 		logger.Error("failed to get template", zap.Error(err), zap.Int32("templateId", req.TemplateId))
-		return nil, fmt.Errorf("failed to get template: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrTemplateNotFound(req.TemplateId)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
 	}
 	if template == nil {
-		return nil, fmt.Errorf("template not found")
+		return nil, common.ErrTemplateNotFound(req.TemplateId)
 	}
 
 	// 构建响应
 	resp := &instance.TemplateDetailResp{
-		TemplateId:     int32(template.ID),
-		Name:           template.Name,
-		Port:           template.Port,
-		InitScript:     template.InitScript,
-		Command:        template.Command,
-		StartupTimeout: template.StartupTimeout,
-		RunningTimeout: template.RunningTimeout,
-		EnvironmentId:  int32(template.EnvironmentID),
-		PackageId:      template.PackageID,
-		ImgAddress:     template.ImgAddress,
-		McpServerId:    template.McpServerID,
-		Notes:          template.Notes,
-		IconPath:       template.IconPath,
-		McpServers:     string(template.McpServers),
-		CreatedAt:      template.CreatedAt.String(),
-		UpdatedAt:      template.UpdatedAt.String(),
-		ServicePath:    template.ServicePath,
+		TemplateId:       int32(template.ID),
+		Name:             template.Name,
+		Port:             template.Port,
+		InitScript:       template.InitScript,
+		Command:          template.Command,
+		StartupTimeout:   template.StartupTimeout,
+		RunningTimeout:   template.RunningTimeout,
+		EnvironmentId:    int32(template.EnvironmentID),
+		PackageId:        template.PackageID,
+		ImgAddress:       template.ImgAddress,
+		McpServerId:      template.McpServerID,
+		Notes:            template.Notes,
+		IconPath:         template.IconPath,
+		Metadata:         string(template.Metadata),
+		McpServers:       string(template.McpServers),
+		CreatedAt:        common.FormatTime(ctx, template.CreatedAt),
+		UpdatedAt:        common.FormatTime(ctx, template.UpdatedAt),
+		CreatedAtMs:      common.TimeMillis(template.CreatedAt),
+		UpdatedAtMs:      common.TimeMillis(template.UpdatedAt),
+		ServicePath:      template.ServicePath,
+		SourceInstanceId: template.SourceInstanceID,
 	}
 
 	// 处理访问类型
@@ -248,18 +268,22 @@ func (s *TemplateService) TemplateDetail(ctx context.Context, req *instance.Temp
 // TemplateEdit edits an existing template
 func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.TemplateEditRequest) (*instance.TemplateEditResp, error) {
 	if req.TemplateId == 0 {
-		return nil, fmt.Errorf("template ID is required")
+		return nil, common.ErrRequiredField("templateId")
 	}
 
 	// 查询现有模板
 	template, err := s.templateData.GetTemplateByID(ctx, uint(req.TemplateId))
 	if err != nil {
 		logger.Error("failed to get template", zap.Error(err), zap.Int32("templateId", req.TemplateId))
-		return nil, fmt.Errorf("failed to get template: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrTemplateNotFound(req.TemplateId)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
 	}
 	if template == nil {
-		return nil, fmt.Errorf("template not found")
+		return nil, common.ErrTemplateNotFound(req.TemplateId)
 	}
+	before, _ := json.Marshal(template)
 
 	// 更新模板字段
 	template.Name = req.Name
@@ -273,6 +297,7 @@ func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.Templa
 	template.ImgAddress = req.ImgAddress
 	template.McpServerID = req.McpServerId
 	template.Notes = req.Notes
+	template.Metadata = common.NormalizeMetadata(req.Metadata)
 	template.IconPath = req.IconPath
 
 	// 处理访问类型
@@ -304,7 +329,7 @@ func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.Templa
 		envBytes, err := json.Marshal(req.EnvironmentVariables)
 		if err != nil {
 			logger.Error("failed to marshal environment variables", zap.Error(err))
-			return nil, fmt.Errorf("failed to process environment variables: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "environmentVariables")
 		}
 		template.EnvironmentVariables = envBytes
 	}
@@ -314,7 +339,7 @@ func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.Templa
 		volumeBytes, err := json.Marshal(req.VolumeMounts)
 		if err != nil {
 			logger.Error("failed to marshal volume mounts", zap.Error(err))
-			return nil, fmt.Errorf("failed to process volume mounts: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "volumeMounts")
 		}
 		template.VolumeMounts = volumeBytes
 	}
@@ -338,7 +363,7 @@ func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.Templa
 		tokensJSON, err := json.Marshal(tokens)
 		if err != nil {
 			logger.Error("failed to marshal tokens", zap.Error(err))
-			return nil, fmt.Errorf("failed to process tokens: %v", err)
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "tokens")
 		}
 		template.Tokens = json.RawMessage(tokensJSON)
 	}
@@ -346,12 +371,15 @@ func (s *TemplateService) TemplateEdit(ctx context.Context, req *instance.Templa
 	// 更新模板
 	if err := s.templateData.UpdateTemplate(ctx, template); err != nil {
 		logger.Error("failed to update template", zap.Error(err), zap.Int32("templateId", req.TemplateId))
-		return nil, fmt.Errorf("failed to update template: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeTemplateUpdateFailure)
 	}
 
+	biz.GAuditBiz.RecordChanges(ctx, model.AuditActionTemplateEdit, model.AuditResourceTemplate, strconv.Itoa(int(template.ID)),
+		json.RawMessage(before), template, biz.TemplateDiffOptions)
+
 	// 返回响应
 	resp := &instance.TemplateEditResp{
-		Message: "Template updated successfully",
+		Message: i18nresp.FormatWithContext(ctx, i18nresp.CodeTemplateUpdateSuccess),
 	}
 
 	logger.Info("template updated successfully", zap.Int32("templateId", req.TemplateId), zap.String("name", req.Name))
@@ -390,7 +418,7 @@ func (s *TemplateService) TemplateList(ctx context.Context, req *instance.Templa
 	templates, total, err := s.templateData.GetTemplatesWithPagination(ctx, page, pageSize, filters, "id", "desc")
 	if err != nil {
 		logger.Error("failed to get templates", zap.Error(err))
-		return nil, fmt.Errorf("failed to get templates: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
 	}
 
 	// envIds
@@ -401,7 +429,7 @@ func (s *TemplateService) TemplateList(ctx context.Context, req *instance.Templa
 	envIds = utils.RemoveDuplicates(envIds)
 	envNames, err := mysql.McpEnvironmentRepo.FindNamesByIDs(ctx, envIds)
 	if err != nil {
-		return nil, fmt.Errorf("查询环境名称失败: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeEnvironmentQueryFailure)
 	}
 
 	// 构建响应
@@ -419,24 +447,28 @@ func (s *TemplateService) TemplateList(ctx context.Context, req *instance.Templa
 			envName = ""
 		}
 		templateResp := &instance.TemplateDetailResp{
-			TemplateId:      int32(template.ID),
-			Name:            template.Name,
-			Port:            template.Port,
-			InitScript:      template.InitScript,
-			Command:         template.Command,
-			StartupTimeout:  template.StartupTimeout,
-			RunningTimeout:  template.RunningTimeout,
-			EnvironmentId:   int32(template.EnvironmentID),
-			PackageId:       template.PackageID,
-			ImgAddress:      template.ImgAddress,
-			McpServerId:     template.McpServerID,
-			Notes:           template.Notes,
-			IconPath:        template.IconPath,
-			McpServers:      string(template.McpServers),
-			CreatedAt:       template.CreatedAt.String(),
-			UpdatedAt:       template.UpdatedAt.String(),
-			EnvironmentName: envName,
-			ServicePath:     template.ServicePath,
+			TemplateId:       int32(template.ID),
+			Name:             template.Name,
+			Port:             template.Port,
+			InitScript:       template.InitScript,
+			Command:          template.Command,
+			StartupTimeout:   template.StartupTimeout,
+			RunningTimeout:   template.RunningTimeout,
+			EnvironmentId:    int32(template.EnvironmentID),
+			PackageId:        template.PackageID,
+			ImgAddress:       template.ImgAddress,
+			McpServerId:      template.McpServerID,
+			Notes:            template.Notes,
+			IconPath:         template.IconPath,
+			Metadata:         string(template.Metadata),
+			McpServers:       string(template.McpServers),
+			CreatedAt:        common.FormatTime(ctx, template.CreatedAt),
+			UpdatedAt:        common.FormatTime(ctx, template.UpdatedAt),
+			CreatedAtMs:      common.TimeMillis(template.CreatedAt),
+			UpdatedAtMs:      common.TimeMillis(template.UpdatedAt),
+			EnvironmentName:  envName,
+			ServicePath:      template.ServicePath,
+			SourceInstanceId: template.SourceInstanceID,
 		}
 
 		// 处理访问类型
@@ -505,7 +537,7 @@ func (s *TemplateService) TemplateListWithPagination(ctx context.Context, page,
 	templates, total, err := s.templateData.GetTemplatesWithPagination(ctx, page, pageSize, filters, sortBy, sortOrder)
 	if err != nil {
 		logger.Error("failed to get templates with pagination", zap.Error(err), zap.Int32("page", page), zap.Int32("pageSize", pageSize))
-		return nil, 0, fmt.Errorf("failed to get templates: %v", err)
+		return nil, 0, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
 	}
 
 	// 构建响应
@@ -527,6 +559,7 @@ func (s *TemplateService) TemplateListWithPagination(ctx context.Context, page,
 			McpServerId:    template.McpServerID,
 			Notes:          template.Notes,
 			IconPath:       template.IconPath,
+			Metadata:       string(template.Metadata),
 			McpServers:     string(template.McpServers),
 		}
 
@@ -593,20 +626,23 @@ func (s *TemplateService) TemplateListWithPagination(ctx context.Context, page,
 // TemplateDelete deletes a template
 func (s *TemplateService) TemplateDelete(ctx context.Context, req *instance.TemplateDeleteRequest) (*instance.TemplateDeleteResp, error) {
 	if req.TemplateId == 0 {
-		return nil, fmt.Errorf("template ID is required")
+		return nil, common.ErrRequiredField("templateId")
 	}
 
 	// 查询模板
 	template, err := s.templateData.GetTemplateByID(ctx, uint(req.TemplateId))
 	if err != nil {
 		logger.Error("failed to get template", zap.Error(err), zap.Int32("templateId", req.TemplateId))
-		return nil, fmt.Errorf("failed to get template: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrTemplateNotFound(req.TemplateId)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
 	}
 
 	// 删除模板
 	if err := s.templateData.DeleteTemplate(ctx, template.ID); err != nil {
 		logger.Error("failed to delete template", zap.Error(err), zap.Int32("templateId", req.TemplateId))
-		return nil, fmt.Errorf("failed to delete template: %v", err)
+		return nil, common.WrapError(err, i18nresp.CodeTemplateDeleteFailure)
 	}
 
 	// 返回响应
@@ -616,6 +652,91 @@ func (s *TemplateService) TemplateDelete(ctx context.Context, req *instance.Temp
 	return resp, nil
 }
 
+// SaveAsTemplate creates a template from the configuration of an existing instance. Secret environment variables
+// and headers are cleared unless includeSecrets is set, the instance tokens are never copied
+func (s *TemplateService) SaveAsTemplate(ctx context.Context, req *instance.SaveAsTemplateRequest) (*instance.SaveAsTemplateResp, error) {
+	source, err := biz.GInstanceBiz.GetInstance(req.InstanceId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrInstanceNotFound(req.InstanceId)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeGetInstanceFailure)
+	}
+	if source == nil {
+		return nil, common.ErrInstanceNotFound(req.InstanceId)
+	}
+
+	accessType, err := common.ConvertToProtoAccessType(source.AccessType)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeAccessTypeConvertFailure)
+	}
+	mcpProtocol, err := common.ConvertToProtoMcpProtocol(source.McpProtocol)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeMCPProtocolConvertFailure)
+	}
+
+	notes := req.Notes
+	if notes == "" {
+		notes = source.Notes
+	}
+	metadata := req.Metadata
+	if metadata == "" {
+		metadata = string(source.Metadata)
+	}
+	createReq := &instance.TemplateCreateRequest{
+		Name:           req.Name,
+		Port:           source.Port,
+		InitScript:     source.InitScript,
+		Command:        source.Command,
+		StartupTimeout: int32(source.StartupTimeout),
+		RunningTimeout: int32(source.RunningTimeout),
+		EnvironmentId:  int32(source.EnvironmentID),
+		PackageId:      source.PackageID,
+		AccessType:     accessType,
+		McpProtocol:    mcpProtocol,
+		McpServers:     string(source.SourceConfig),
+		ImgAddress:     source.ImgAddr,
+		McpServerId:    source.McpServerID,
+		Notes:          notes,
+		IconPath:       source.IconPath,
+		Metadata:       metadata,
+	}
+	if len(source.EnvironmentVariables) > 0 {
+		if err := json.Unmarshal(source.EnvironmentVariables, &createReq.EnvironmentVariables); err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "environmentVariables")
+		}
+	}
+	if len(source.VolumeMounts) > 0 {
+		if err := json.Unmarshal(source.VolumeMounts, &createReq.VolumeMounts); err != nil {
+			return nil, common.WrapError(err, i18nresp.CodeTemplateFieldProcessFailure, "volumeMounts")
+		}
+	}
+
+	var strippedSecrets []string
+	if !req.IncludeSecrets {
+		envVars, envNames := common.StripSecretEnvVars(createReq.EnvironmentVariables)
+		for _, name := range envNames {
+			strippedSecrets = append(strippedSecrets, "environmentVariables."+name)
+		}
+		mcpServers, serverNames := common.StripMcpServersSecrets(source.SourceConfig)
+		strippedSecrets = append(strippedSecrets, serverNames...)
+		createReq.EnvironmentVariables = envVars
+		createReq.McpServers = string(mcpServers)
+	}
+
+	created, err := s.createTemplate(ctx, createReq, source)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("instance saved as template", zap.String("instanceId", source.InstanceID),
+		zap.Int32("templateId", created.TemplateId), zap.Strings("strippedSecrets", strippedSecrets))
+	return &instance.SaveAsTemplateResp{
+		TemplateId:       created.TemplateId,
+		SourceInstanceId: source.InstanceID,
+		StrippedSecrets:  strippedSecrets,
+	}, nil
+}
+
 // HTTP Handler 方法
 
 // TemplateCreateHandler 创建模板HTTP处理函数
@@ -625,16 +746,10 @@ func (s *TemplateService) TemplateCreateHandler(c *gin.Context) {
 		return
 	}
 
-	// 验证必填字段
-	if req.Name == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
-		return
-	}
-
 	// 调用创建模板处理函数
 	result, err := s.TemplateCreate(c, &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("创建模板失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -642,6 +757,22 @@ func (s *TemplateService) TemplateCreateHandler(c *gin.Context) {
 	common.GinSuccess(c, result)
 }
 
+// SaveAsTemplateHandler 实例另存为模板HTTP处理函数
+func (s *TemplateService) SaveAsTemplateHandler(c *gin.Context) {
+	var req instance.SaveAsTemplateRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.SaveAsTemplate(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
 // TemplateListWithPaginationHandler 分页获取模板列表HTTP处理函数
 func (s *TemplateService) TemplateListWithPaginationHandler(c *gin.Context) {
 	// 获取分页参数
@@ -691,7 +822,7 @@ func (s *TemplateService) TemplateListWithPaginationHandler(c *gin.Context) {
 	// 调用分页获取模板列表处理函数
 	result, total, err := s.TemplateListWithPagination(c, int32(page), int32(pageSize), filters, sortBy, sortOrder)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("分页获取模板列表失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -708,6 +839,43 @@ func (s *TemplateService) TemplateListWithPaginationHandler(c *gin.Context) {
 	common.GinSuccess(c, response)
 }
 
+// TemplateHistory 分页查询模板的字段级修改历史，从最新开始
+func (s *TemplateService) TemplateHistory(ctx context.Context, req *instance.TemplateHistoryRequest) (*instance.ChangeHistoryResp, error) {
+	template, err := s.templateData.GetTemplateByID(ctx, uint(req.TemplateId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrTemplateNotFound(req.TemplateId)
+		}
+		return nil, common.WrapError(err, i18nresp.CodeTemplateQueryFailure)
+	}
+	if template == nil {
+		return nil, common.ErrTemplateNotFound(req.TemplateId)
+	}
+
+	page, pageSize := historyPage(req.Page, req.PageSize)
+	result, err := biz.GAuditBiz.ChangeHistory(ctx, model.AuditResourceTemplate, strconv.Itoa(int(template.ID)), page, pageSize)
+	if err != nil {
+		return nil, common.WrapError(err, i18nresp.CodeChangeHistoryFailure)
+	}
+	return result, nil
+}
+
+// TemplateHistoryHandler 模板修改历史HTTP处理函数
+func (s *TemplateService) TemplateHistoryHandler(c *gin.Context) {
+	var req instance.TemplateHistoryRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	result, err := s.TemplateHistory(c.Request.Context(), &req)
+	if err != nil {
+		common.GinErrorFrom(c, err)
+		return
+	}
+
+	common.GinSuccess(c, result)
+}
+
 // TemplateDetailHandler 获取模板详情HTTP处理函数
 func (s *TemplateService) TemplateDetailHandler(c *gin.Context) {
 	var req instance.TemplateDetailRequest
@@ -715,14 +883,14 @@ func (s *TemplateService) TemplateDetailHandler(c *gin.Context) {
 		return
 	}
 	if req.TemplateId == 0 {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: templateId")
+		common.GinErrorFrom(c, common.ErrRequiredField("templateId"))
 		return
 	}
 
 	// 调用获取模板详情处理函数
 	result, err := s.TemplateDetail(c, &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("获取模板详情失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -734,20 +902,13 @@ func (s *TemplateService) TemplateDetailHandler(c *gin.Context) {
 func (s *TemplateService) TemplateEditHandler(c *gin.Context) {
 	var req instance.TemplateEditRequest
 	if err := common.BindAndValidate(c, &req); err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("绑定请求体失败: %s", err.Error()))
-		return
-	}
-
-	// 验证必填字段
-	if req.Name == "" {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: name")
 		return
 	}
 
 	// 调用编辑模板处理函数
 	result, err := s.TemplateEdit(c, &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("编辑模板失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -767,7 +928,7 @@ func (s *TemplateService) TemplateListHandler(c *gin.Context) {
 	// 调用获取模板列表处理函数
 	result, err := s.TemplateList(c, &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("获取模板列表失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
@@ -779,18 +940,17 @@ func (s *TemplateService) TemplateListHandler(c *gin.Context) {
 func (s *TemplateService) TemplateDeleteHandler(c *gin.Context) {
 	var req instance.TemplateDeleteRequest
 	if err := common.BindAndValidate(c, &req); err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("绑定请求体失败: %s", err.Error()))
 		return
 	}
 	if req.TemplateId == 0 {
-		common.GinError(c, i18nresp.CodeInternalError, "missing required field: templateId")
+		common.GinErrorFrom(c, common.ErrRequiredField("templateId"))
 		return
 	}
 
 	// 调用删除模板处理函数
 	result, err := s.TemplateDelete(c, &req)
 	if err != nil {
-		common.GinError(c, i18nresp.CodeInternalError, fmt.Sprintf("删除模板失败: %s", err.Error()))
+		common.GinErrorFrom(c, err)
 		return
 	}
 
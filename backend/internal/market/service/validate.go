@@ -0,0 +1,49 @@
+package service
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	validatepb "qm-mcp-server/api/market/validate"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/utils"
+)
+
+// ValidateService 配置检查服务，只检查不做任何修改
+type ValidateService struct{}
+
+// NewValidateService 创建配置检查服务
+func NewValidateService() *ValidateService {
+	return &ValidateService{}
+}
+
+// ValidateMcpConfigHandler 检查 mcpServers 配置，返回所有 error 和 warning，配置的问题不作为请求错误
+func (s *ValidateService) ValidateMcpConfigHandler(c *gin.Context) {
+	var req validatepb.ValidateMcpConfigRequest
+	if err := common.BindAndValidate(c, &req); err != nil {
+		return
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultDetectProtocolTimeout
+	}
+	result := utils.LintMcpConfig(c.Request.Context(), []byte(req.McpServers), utils.McpLintOptions{
+		Probe:        req.Probe,
+		ProbeTimeout: time.Duration(timeout) * time.Second,
+	})
+
+	resp := &validatepb.ValidateMcpConfigResp{
+		Valid:    result.Valid,
+		Servers:  make([]*validatepb.McpConfigServer, 0, len(result.Servers)),
+		Findings: make([]*validatepb.McpConfigFinding, 0, len(result.Findings)),
+	}
+	for _, server := range result.Servers {
+		resp.Servers = append(resp.Servers, &validatepb.McpConfigServer{Name: server.Name, ProtocolType: server.ProtocolType, Url: server.Url})
+	}
+	for _, f := range result.Findings {
+		resp.Findings = append(resp.Findings, &validatepb.McpConfigFinding{Severity: f.Severity, Path: f.Path, Message: f.Message})
+	}
+	common.GinSuccess(c, resp)
+}
@@ -0,0 +1,1194 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	auditpb "qm-mcp-server/api/market/audit"
+	catalogpb "qm-mcp-server/api/market/catalog"
+	changerequestpb "qm-mcp-server/api/market/change_request"
+	costpb "qm-mcp-server/api/market/cost"
+	dashboardpb "qm-mcp-server/api/market/dashboard"
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/api/market/mcp_environment"
+	notificationpb "qm-mcp-server/api/market/notification"
+	quotapb "qm-mcp-server/api/market/quota"
+	"qm-mcp-server/api/market/registry_credential"
+	validatepb "qm-mcp-server/api/market/validate"
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/internal/market/config"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/utils"
+)
+
+// 超时参数范围（秒），0 表示使用默认值
+const (
+	minStartupTimeout = 30
+	maxStartupTimeout = 3600
+	minRunningTimeout = 60
+	maxRunningTimeout = 86400
+)
+
+// maxReplicas 托管实例最大副本数
+const maxReplicas = 10
+
+// maxAllowedEgress 托管实例最多允许的出站目的地数量
+const maxAllowedEgress = 50
+
+// maxAffinityWeight 偏好亲和规则的最大权重，0 表示使用默认权重
+const maxAffinityWeight = 100
+
+// maxInitContainers 托管实例最多配置的初始化容器数
+const maxInitContainers = 5
+
+// maxSidecars 托管实例最多配置的边车容器数
+const maxSidecars = 3
+
+// 网关代理请求超时范围（秒），0 表示使用 mcpServers 中的设置
+const (
+	maxRequestTimeout = 3600
+	maxSseReadTimeout = 86400
+)
+
+// maxBulkInstances 单次批量操作最多涉及的实例数
+const maxBulkInstances = 100
+
+// 健康检查间隔范围（秒），0 表示使用默认值
+const (
+	minHealthCheckInterval = 10
+	maxHealthCheckInterval = 86400
+)
+
+// maxTokenGracePeriod 令牌轮换宽限期的最大小时数，0 表示使用配置的宽限期
+const maxTokenGracePeriod = 720
+
+// maxTokenExpireDays 连接配置中生成的令牌的最大有效天数，0 表示永不过期
+const maxTokenExpireDays = 3650
+
+// maxNotesLength 实例和模板备注的最大字符数，备注按 Markdown 渲染
+const maxNotesLength = 4000
+
+// maxServicePathLength 服务路径的最大长度，与数据库字段长度一致
+const maxServicePathLength = 100
+
+// maxCatalogRatingCommentLength 目录条目评价内容的最大字符数
+const maxCatalogRatingCommentLength = 1000
+
+// maxReviewCommentLength 变更请求审批意见的最大字符数
+const maxReviewCommentLength = 500
+
+func init() {
+	common.RegisterValidator(validateCreateRequest)
+	common.RegisterValidator(validateEditRequest)
+	common.RegisterValidator(validateListRequest)
+	common.RegisterValidator(validateTemplateCreateRequest)
+	common.RegisterValidator(validateTemplateEditRequest)
+	common.RegisterValidator(validateSaveAsTemplateRequest)
+	common.RegisterValidator(validateEventsRequest)
+	common.RegisterValidator(validateTimelineRequest)
+	common.RegisterValidator(validateBatchStatusRequest)
+	common.RegisterValidator(validateBulkRequest)
+	common.RegisterValidator(validateInstanceHistoryRequest)
+	common.RegisterValidator(validateTemplateHistoryRequest)
+	common.RegisterValidator(validateRedeployPackageRequest)
+	common.RegisterValidator(validateHealthMonitorRequest)
+	common.RegisterValidator(validateHealthHistoryRequest)
+	common.RegisterValidator(validateStatusEventsRequest)
+	common.RegisterValidator(validateScaleRequest)
+	common.RegisterValidator(validateResetCircuitBreakerRequest)
+	common.RegisterValidator(validateConnectionsRequest)
+	common.RegisterValidator(validateDrainRequest)
+	common.RegisterValidator(validateScanRequest)
+	common.RegisterValidator(validateLockRequest)
+	common.RegisterValidator(validateUnlockRequest)
+	common.RegisterValidator(validateRotateTokenRequest)
+	common.RegisterValidator(validateConnectionRequest)
+	common.RegisterValidator(validateValidateConfigRequest)
+	common.RegisterValidator(validateConvertConfigRequest)
+	common.RegisterValidator(validateDetectProtocolRequest)
+	common.RegisterValidator(validateValidateScriptRequest)
+	common.RegisterValidator(validateCreateEnvironmentRequest)
+	common.RegisterValidator(validateUpdateEnvironmentRequest)
+	common.RegisterValidator(validateEnvironmentResourcesRequest)
+	common.RegisterValidator(validateListOrphansRequest)
+	common.RegisterValidator(validateCleanupOrphansRequest)
+	common.RegisterValidator(validateRegistryCredentialCreateRequest)
+	common.RegisterValidator(validateRegistryCredentialUpdateRequest)
+	common.RegisterValidator(validateCatalogInstallRequest)
+	common.RegisterValidator(validateCatalogSyncRequest)
+	common.RegisterValidator(validateListCatalogRequest)
+	common.RegisterValidator(validateRateCatalogRequest)
+	common.RegisterValidator(validateInstanceCostRequest)
+	common.RegisterValidator(validateCostReportRequest)
+	common.RegisterValidator(validateListAuditLogsRequest)
+	common.RegisterValidator(validateStatisticalRequest)
+	common.RegisterValidator(validateValidateMcpConfigRequest)
+}
+
+// validateCreateRequest 校验实例创建请求
+func validateCreateRequest(req *instancepb.CreateRequest) error {
+	v := &common.Validation{}
+	v.Required("name", req.Name)
+	v.Add(validateLabels(req.Labels))
+	v.Add(validateSlug(req.Slug))
+	v.Add(validateNotesAndMetadata(req.Notes, req.Metadata)...)
+	v.Add(validateTokenUsages(req.Tokens)...)
+	v.Range("timeout", int64(req.Timeout), 1, maxRequestTimeout).
+		Range("sseReadTimeout", int64(req.SseReadTimeout), 1, maxSseReadTimeout)
+	if req.AccessType == instancepb.AccessType_DIRECT {
+		v.Add(validateDirectRequestTimeouts(req.Timeout, req.SseReadTimeout)...)
+	}
+
+	// 客户端配置校验转换后的结果，转换失败时只报告转换错误
+	mcpServers, envVars := req.McpServers, req.EnvironmentVariables
+	checkMcpServers := true
+	switch req.Format {
+	case "":
+	case utils.McpConfigFormatClient:
+		var err *utils.McpConfigError
+		if mcpServers, envVars, err = convertClientConfig(req); err != nil {
+			v.Add(mcpConfigFieldErrors([]*utils.McpConfigError{err})...)
+			checkMcpServers = false
+		}
+	default:
+		v.Add(common.Invalid("format", fmt.Sprintf("must be empty or %s", utils.McpConfigFormatClient)))
+	}
+
+	switch req.AccessType {
+	case instancepb.AccessType_DIRECT, instancepb.AccessType_PROXY:
+		if v.Required("mcpServers", mcpServers); mcpServers != "" && checkMcpServers {
+			v.Add(validateMcpServers(mcpServers, req.McpProtocol, false)...)
+		}
+	case instancepb.AccessType_HOSTING:
+		v.RequiredInt("port", int64(req.Port)).
+			RequiredInt("environmentId", int64(req.EnvironmentId)).
+			Required("imgAddress", req.ImgAddress).
+			Range("startupTimeout", int64(req.StartupTimeout), minStartupTimeout, maxStartupTimeout).
+			Range("runningTimeout", int64(req.RunningTimeout), minRunningTimeout, maxRunningTimeout)
+		v.Add(validateEnvTemplates("environmentVariables", envVars)...)
+		if mcpProtocol, err := common.ConvertToModelMcpProtocol(req.McpProtocol); err == nil {
+			v.Add(validateReplicas(req.Replicas, mcpProtocol))
+		}
+		v.Add(validateInitContainers(req.InitContainers, req.InitSharedPath)...)
+		v.Add(validateSidecars(req.Sidecars, req.InitContainers, req.Port)...)
+		v.Add(validatePriorityClassName("priorityClassName", req.PriorityClassName))
+		v.Add(validateServicePath("servicePath", req.ServicePath))
+		v.Add(validatePodAffinity(req.PodAffinity)...)
+		v.Add(validateAllowedEgress(req.AllowedEgress)...)
+		v.Add(validateServiceAccount(req.ServiceAccountName, req.IsolateServiceAccount))
+		if req.McpProtocol == instancepb.McpProtocol_AUTO {
+			v.Add(common.Invalid("mcpProtocol", "AUTO is only supported for direct and proxy instances"))
+		}
+		if req.McpProtocol == instancepb.McpProtocol_STDIO {
+			if v.Required("mcpServers", mcpServers); mcpServers != "" && checkMcpServers {
+				v.Add(validateMcpServers(mcpServers, req.McpProtocol, true)...)
+			}
+		}
+	default:
+		v.Add(common.Invalid("accessType", fmt.Sprintf("unsupported access type: %v", req.AccessType)))
+	}
+	return v.Err()
+}
+
+// validateEditRequest 校验实例编辑请求，与访问类型相关的校验在 EditHandler 中完成
+func validateEditRequest(req *instancepb.EditRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Required("name", req.Name).
+		JSON("mcpServers", req.McpServers).
+		Range("startupTimeout", int64(req.StartupTimeout), minStartupTimeout, maxStartupTimeout).
+		Range("runningTimeout", int64(req.RunningTimeout), minRunningTimeout, maxRunningTimeout).
+		Range("timeout", int64(req.Timeout), 1, maxRequestTimeout).
+		Range("sseReadTimeout", int64(req.SseReadTimeout), 1, maxSseReadTimeout)
+	if req.Port < 0 {
+		v.Add(common.Min("port", 0))
+	}
+	v.Add(validateLabels(req.Labels))
+	v.Add(validateSlug(req.Slug))
+	if req.ClearSlug && req.Slug != "" {
+		v.Add(common.Invalid("clearSlug", "must not be set together with slug"))
+	}
+	v.Add(validateNotesAndMetadata(req.Notes, req.Metadata)...)
+	v.Add(validateEnvTemplates("environmentVariables", req.EnvironmentVariables)...)
+	v.Add(validateInitContainers(req.InitContainers, req.InitSharedPath)...)
+	v.Add(validateSidecars(req.Sidecars, req.InitContainers, req.Port)...)
+	v.Add(validateAllowedEgress(req.AllowedEgress)...)
+	return v.Err()
+}
+
+// validateEnvTemplates 校验环境变量值中的占位符，未知占位符不会原样传给容器
+func validateEnvTemplates(field string, envVars map[string]string) []*common.FieldError {
+	var errs []*common.FieldError
+	for _, key := range slices.Sorted(maps.Keys(envVars)) {
+		if err := common.ValidateEnvTemplate(key, envVars[key]); err != nil {
+			errs = append(errs, common.Invalid(field+"."+key, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateListRequest 校验实例列表请求
+func validateListRequest(req *instancepb.ListRequest) error {
+	v := &common.Validation{}
+	if _, err := common.ParseLabelSelector(req.LabelSelector); err != nil {
+		v.Add(common.Invalid("labelSelector", err.Error()))
+	}
+	if _, err := common.ParseMetadataSelector(req.MetadataSelector); err != nil {
+		v.Add(common.Invalid("metadataSelector", err.Error()))
+	}
+	return v.Err()
+}
+
+// validateLabels 校验实例标签，标签键和值需要能作为 Kubernetes 标签使用
+func validateLabels(labels map[string]string) *common.FieldError {
+	if err := common.ValidateLabels(labels); err != nil {
+		return common.Invalid("labels", err.Error())
+	}
+	return nil
+}
+
+// validateSlug 校验网关自定义访问路径，为空时表示不设置
+func validateSlug(slug string) *common.FieldError {
+	if slug == "" {
+		return nil
+	}
+	if err := common.ValidateInstanceSlug(slug); err != nil {
+		return common.Invalid("slug", err.Error())
+	}
+	return nil
+}
+
+// validateDirectRequestTimeouts 直连实例的请求不经过网关，不能设置网关代理超时
+func validateDirectRequestTimeouts(timeout, sseReadTimeout int32) []*common.FieldError {
+	var errs []*common.FieldError
+	if timeout != 0 {
+		errs = append(errs, common.Invalid("timeout", "is not supported for direct instances"))
+	}
+	if sseReadTimeout != 0 {
+		errs = append(errs, common.Invalid("sseReadTimeout", "is not supported for direct instances"))
+	}
+	return errs
+}
+
+// validateNotesAndMetadata 校验备注长度和结构化元数据，元数据必须是 JSON 对象
+func validateNotesAndMetadata(notes, metadata string) []*common.FieldError {
+	var errs []*common.FieldError
+	if utf8.RuneCountInString(notes) > maxNotesLength {
+		errs = append(errs, common.Invalid("notes", fmt.Sprintf("must be at most %d characters", maxNotesLength)))
+	}
+	if err := common.ValidateMetadata(metadata); err != nil {
+		errs = append(errs, common.Invalid("metadata", err.Error()))
+	}
+	return errs
+}
+
+// validateTokenUsages 校验令牌的使用场景，只支持 discovery 和 full 两种权限范围
+func validateTokenUsages(tokens []*instancepb.McpToken) []*common.FieldError {
+	var errs []*common.FieldError
+	for i, token := range tokens {
+		for _, usage := range token.Usages {
+			if !model.IsTokenScope(usage) {
+				errs = append(errs, common.Invalid(fmt.Sprintf("tokens[%d].usages", i),
+					fmt.Sprintf("unsupported scope %q, must be %s or %s", usage, model.TokenScopeDiscovery, model.TokenScopeFull)))
+			}
+		}
+	}
+	return errs
+}
+
+// validateEditRequestForInstance 根据原实例访问类型和协议校验编辑请求
+func validateEditRequestForInstance(req *instancepb.EditRequest, instance *model.McpInstance) error {
+	v := &common.Validation{}
+	switch instance.AccessType {
+	case model.AccessTypeDirect:
+		v.Required("mcpServers", req.McpServers)
+		v.Add(validateDirectRequestTimeouts(req.Timeout, req.SseReadTimeout)...)
+	case model.AccessTypeProxy:
+		v.Required("mcpServers", req.McpServers)
+	case model.AccessTypeHosting:
+		v.RequiredInt("port", int64(req.Port))
+		v.Add(validateReplicas(req.Replicas, instance.McpProtocol))
+		v.Add(validateServicePath("servicePath", req.ServicePath))
+	default:
+		v.Add(common.Invalid("accessType", fmt.Sprintf("unknown access type: %s", instance.AccessType)))
+	}
+	return v.Err()
+}
+
+// validateTemplateCreateRequest 校验模板创建请求
+func validateTemplateCreateRequest(req *instancepb.TemplateCreateRequest) error {
+	v := &common.Validation{}
+	v.Required("name", req.Name).
+		JSON("mcpServers", req.McpServers).
+		Range("startupTimeout", int64(req.StartupTimeout), minStartupTimeout, maxStartupTimeout).
+		Range("runningTimeout", int64(req.RunningTimeout), minRunningTimeout, maxRunningTimeout)
+	v.Add(validateTokenUsages(req.Tokens)...)
+	v.Add(validateNotesAndMetadata(req.Notes, req.Metadata)...)
+	if req.Port < 0 {
+		v.Add(common.Min("port", 0))
+	}
+	v.Add(validateEnvTemplates("environmentVariables", req.EnvironmentVariables)...)
+	return v.Err()
+}
+
+// validateTemplateEditRequest 校验模板编辑请求
+func validateTemplateEditRequest(req *instancepb.TemplateEditRequest) error {
+	v := &common.Validation{}
+	v.RequiredInt("templateId", int64(req.TemplateId)).
+		Required("name", req.Name).
+		JSON("mcpServers", req.McpServers).
+		Range("startupTimeout", int64(req.StartupTimeout), minStartupTimeout, maxStartupTimeout).
+		Range("runningTimeout", int64(req.RunningTimeout), minRunningTimeout, maxRunningTimeout)
+	v.Add(validateTokenUsages(req.Tokens)...)
+	v.Add(validateNotesAndMetadata(req.Notes, req.Metadata)...)
+	if req.Port < 0 {
+		v.Add(common.Min("port", 0))
+	}
+	v.Add(validateEnvTemplates("environmentVariables", req.EnvironmentVariables)...)
+	return v.Err()
+}
+
+// validateSaveAsTemplateRequest 校验实例另存为模板请求
+func validateSaveAsTemplateRequest(req *instancepb.SaveAsTemplateRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Required("name", req.Name)
+	v.Add(validateNotesAndMetadata(req.Notes, req.Metadata)...)
+	return v.Err()
+}
+
+// validateEventsRequest 校验实例历史事件查询请求
+func validateEventsRequest(req *instancepb.EventsRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	switch req.Severity {
+	case "", model.InstanceEventTypeWarning, model.InstanceEventTypeNormal:
+	default:
+		v.Add(common.Invalid("severity", fmt.Sprintf("unsupported severity: %s, expected %s or %s",
+			req.Severity, model.InstanceEventTypeWarning, model.InstanceEventTypeNormal)))
+	}
+	return v.Err()
+}
+
+// 时间线排序方式
+const (
+	timelineOrderAsc  = "asc"
+	timelineOrderDesc = "desc"
+)
+
+// validateTimelineRequest 校验实例时间线查询请求
+func validateTimelineRequest(req *instancepb.TimelineRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	switch req.Order {
+	case "", timelineOrderAsc, timelineOrderDesc:
+	default:
+		v.Add(common.Invalid("order", fmt.Sprintf("unsupported order: %s, expected %s or %s", req.Order, timelineOrderAsc, timelineOrderDesc)))
+	}
+	return v.Err()
+}
+
+// validateInstanceHistoryRequest 校验实例修改历史请求
+func validateInstanceHistoryRequest(req *instancepb.InstanceHistoryRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateTemplateHistoryRequest 校验模板修改历史请求
+func validateTemplateHistoryRequest(req *instancepb.TemplateHistoryRequest) error {
+	v := &common.Validation{}
+	v.RequiredInt("templateId", int64(req.TemplateId))
+	return v.Err()
+}
+
+// validateBatchStatusRequest 校验批量状态查询请求，实例数量上限由 status.batchMaxInstances 配置
+func validateBatchStatusRequest(req *instancepb.BatchStatusRequest) error {
+	v := &common.Validation{}
+	maxInstances := config.GlobalConfig.Status.BatchMaxInstances
+	if len(req.InstanceIds) == 0 {
+		v.Add(common.Required("instanceIds"))
+	} else if len(req.InstanceIds) > maxInstances {
+		v.Add(common.Invalid("instanceIds", fmt.Sprintf("at most %d instances per request, got %d", maxInstances, len(req.InstanceIds))))
+	}
+	for i, instanceID := range req.InstanceIds {
+		v.Required(fmt.Sprintf("instanceIds[%d]", i), instanceID)
+	}
+	return v.Err()
+}
+
+// validateRedeployPackageRequest 校验代码包重新部署请求
+func validateRedeployPackageRequest(req *instancepb.RedeployPackageRequest) error {
+	v := &common.Validation{}
+	v.Required("packageId", req.PackageId)
+	v.Range("concurrency", int64(req.Concurrency), 0, bulkConcurrency)
+	return v.Err()
+}
+
+// validateBulkRequest 校验批量操作请求，instanceIds 和 labelSelector 二选一，禁用和删除需要显式确认
+func validateBulkRequest(req *instancepb.BulkRequest) error {
+	v := &common.Validation{}
+	switch req.Action {
+	case "":
+		v.Add(common.Required("action"))
+	case bulkActionEnable, bulkActionRestart:
+	case bulkActionDisable, bulkActionDelete:
+		if !req.Confirm {
+			v.Add(common.Invalid("confirm", fmt.Sprintf("must be true to %s instances", req.Action)))
+		}
+	default:
+		v.Add(common.Invalid("action", "must be one of disable, enable, restart, delete"))
+	}
+
+	requirements, err := common.ParseLabelSelector(req.LabelSelector)
+	switch {
+	case err != nil:
+		v.Add(common.Invalid("labelSelector", err.Error()))
+	case len(req.InstanceIds) > 0 && req.LabelSelector != "":
+		v.Add(common.Invalid("labelSelector", "instanceIds and labelSelector are mutually exclusive"))
+	case len(req.InstanceIds) == 0 && len(requirements) == 0:
+		v.Add(common.Required("instanceIds"))
+	case len(req.InstanceIds) > maxBulkInstances:
+		v.Add(common.Invalid("instanceIds", fmt.Sprintf("at most %d instances per request, got %d", maxBulkInstances, len(req.InstanceIds))))
+	}
+	for i, instanceID := range req.InstanceIds {
+		v.Required(fmt.Sprintf("instanceIds[%d]", i), instanceID)
+	}
+	return v.Err()
+}
+
+// validateHealthMonitorRequest 校验实例健康检查设置请求，路径和期望状态码只用于 http 方式
+func validateHealthMonitorRequest(req *instancepb.HealthMonitorRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Range("interval", int64(req.Interval), minHealthCheckInterval, maxHealthCheckInterval).
+		Range("expectedStatus", int64(req.ExpectedStatus), 100, 599)
+	switch req.Mode {
+	case "", model.HealthCheckModePort, model.HealthCheckModeHTTP, model.HealthCheckModeInitialize:
+	default:
+		v.Add(common.Invalid("mode", fmt.Sprintf("unsupported mode: %s, expected %s, %s or %s",
+			req.Mode, model.HealthCheckModePort, model.HealthCheckModeHTTP, model.HealthCheckModeInitialize)))
+	}
+	if req.Mode != model.HealthCheckModeHTTP && (req.Path != "" || req.ExpectedStatus != 0) {
+		v.Add(common.Invalid("mode", "path and expectedStatus are only supported by the http mode"))
+	}
+	if req.Path != "" && !strings.HasPrefix(req.Path, "/") {
+		v.Add(common.Invalid("path", "must start with /"))
+	}
+	return v.Err()
+}
+
+// validateHealthHistoryRequest 校验实例健康检查历史查询请求，最多返回 healthMonitor.historySize 条
+func validateHealthHistoryRequest(req *instancepb.HealthHistoryRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Range("limit", int64(req.Limit), 1, int64(config.GlobalConfig.HealthMonitor.HistorySize))
+	return v.Err()
+}
+
+// validateStatusEventsRequest 校验状态事件流请求，过滤的实例数量上限与批量状态查询相同
+func validateStatusEventsRequest(req *instancepb.StatusEventsRequest) error {
+	v := &common.Validation{}
+	maxInstances := config.GlobalConfig.Status.BatchMaxInstances
+	if ids := splitInstanceIDs(req.InstanceIds); len(ids) > maxInstances {
+		v.Add(common.Invalid("instanceIds", fmt.Sprintf("at most %d instances per stream, got %d", maxInstances, len(ids))))
+	}
+	return v.Err()
+}
+
+// validateCreateEnvironmentRequest 校验创建环境请求的配额配置
+func validateCreateEnvironmentRequest(req *mcp_environment.CreateEnvironmentRequest) error {
+	return (&common.Validation{}).
+		Add(validateEnvironmentQuota(req.MaxInstances, req.MaxTotalMemory, req.MaxTotalCPU)...).
+		Add(validateEnvironmentDefaults(req.Defaults)...).
+		Add(validateVulnerabilityPolicy(req.MaxCriticalVulnerabilities)...).
+		Add(validateCostRates(req.CostPerCpuHour, req.CostPerGibHour)...).
+		Err()
+}
+
+// validateUpdateEnvironmentRequest 校验更新环境请求的配额配置
+func validateUpdateEnvironmentRequest(req *mcp_environment.UpdateEnvironmentRequest) error {
+	return (&common.Validation{}).
+		Add(validateEnvironmentQuota(req.MaxInstances, req.MaxTotalMemory, req.MaxTotalCPU)...).
+		Add(validateEnvironmentDefaults(req.Defaults)...).
+		Add(validateVulnerabilityPolicy(req.MaxCriticalVulnerabilities)...).
+		Add(validateCostRates(req.CostPerCpuHour, req.CostPerGibHour)...).
+		Err()
+}
+
+const (
+	// resourceSortCPU 资源使用量按 CPU 排序
+	resourceSortCPU = "cpu"
+	// resourceSortMemory 资源使用量按内存排序
+	resourceSortMemory = "memory"
+)
+
+// validateEnvironmentResourcesRequest 校验环境资源使用量请求的排序字段
+func validateEnvironmentResourcesRequest(req *mcp_environment.EnvironmentResourcesRequest) error {
+	v := &common.Validation{}
+	if req.SortBy != "" && req.SortBy != resourceSortCPU && req.SortBy != resourceSortMemory {
+		v.Add(common.Invalid("sortBy", "must be cpu or memory"))
+	}
+	return v.Err()
+}
+
+// maxOrphanMinAge 孤儿资源最小存在时长上限（秒），7 天
+const maxOrphanMinAge = 7 * 24 * 3600
+
+// validateListOrphansRequest 校验孤儿资源扫描请求
+func validateListOrphansRequest(req *mcp_environment.ListOrphansRequest) error {
+	return (&common.Validation{}).
+		Range("minAge", int64(req.MinAge), 0, maxOrphanMinAge).
+		Err()
+}
+
+// validateCleanupOrphansRequest 校验孤儿资源清理请求，资源需写作 kind/name
+func validateCleanupOrphansRequest(req *mcp_environment.CleanupOrphansRequest) error {
+	v := (&common.Validation{}).Range("minAge", int64(req.MinAge), 0, maxOrphanMinAge)
+	for i, resource := range req.Resources {
+		kind, name, ok := strings.Cut(resource, "/")
+		if !ok || name == "" || (kind != biz.OrphanKindContainer && kind != biz.OrphanKindService) {
+			v.Add(common.Invalid(fmt.Sprintf("resources[%d]", i), "must be container/<name> or service/<name>"))
+		}
+	}
+	return v.Err()
+}
+
+// validateEnvironmentQuota 校验环境配额，实例数量不能为负，资源总量需为合法的 Kubernetes 资源数量
+func validateEnvironmentQuota(maxInstances int32, maxTotalMemory, maxTotalCPU string) []*common.FieldError {
+	var errs []*common.FieldError
+	if maxInstances < 0 {
+		errs = append(errs, common.Invalid("maxInstances", "must not be negative"))
+	}
+	for _, q := range []struct{ field, value string }{{"maxTotalMemory", maxTotalMemory}, {"maxTotalCPU", maxTotalCPU}} {
+		if q.value == "" {
+			continue
+		}
+		if quantity, err := resource.ParseQuantity(q.value); err != nil || quantity.Sign() <= 0 {
+			errs = append(errs, common.Invalid(q.field, fmt.Sprintf("invalid quantity %q", q.value)))
+		}
+	}
+	return errs
+}
+
+// validateVulnerabilityPolicy 校验环境镜像漏洞策略，严重漏洞数上限不能为负
+func validateVulnerabilityPolicy(maxCritical int32) []*common.FieldError {
+	if maxCritical < 0 {
+		return []*common.FieldError{common.Min("maxCriticalVulnerabilities", 0)}
+	}
+	return nil
+}
+
+// validateCostRates 校验环境成本费率，不能为负数
+func validateCostRates(perCPUHour, perGiBHour float64) []*common.FieldError {
+	var errs []*common.FieldError
+	if perCPUHour < 0 {
+		errs = append(errs, common.Min("costPerCpuHour", 0))
+	}
+	if perGiBHour < 0 {
+		errs = append(errs, common.Min("costPerGibHour", 0))
+	}
+	return errs
+}
+
+// validateEnvironmentDefaults 校验环境实例默认值，内容需要能直接用于 Deployment
+func validateEnvironmentDefaults(defaults *mcp_environment.EnvironmentDefaults) []*common.FieldError {
+	if defaults == nil {
+		return nil
+	}
+	var errs []*common.FieldError
+	for name := range defaults.EnvVars {
+		if msgs := validation.IsEnvVarName(name); len(msgs) > 0 {
+			errs = append(errs, common.Invalid("defaults.envVars", fmt.Sprintf("%s: %s", name, strings.Join(msgs, "; "))))
+		}
+	}
+	if err := common.ValidateLabels(defaults.NodeSelector); err != nil {
+		errs = append(errs, common.Invalid("defaults.nodeSelector", err.Error()))
+	}
+	if err := common.ValidateLabels(defaults.Labels); err != nil {
+		errs = append(errs, common.Invalid("defaults.labels", err.Error()))
+	}
+	if err := validatePriorityClassName("defaults.priorityClassName", defaults.PriorityClassName); err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range []struct {
+		field     string
+		resources map[string]string
+	}{{"defaults.resourceRequests", defaults.ResourceRequests}, {"defaults.resourceLimits", defaults.ResourceLimits}} {
+		for name, value := range r.resources {
+			if _, err := resource.ParseQuantity(value); err != nil {
+				errs = append(errs, common.Invalid(r.field, fmt.Sprintf("invalid quantity %q for %s", value, name)))
+			}
+		}
+	}
+	for _, secret := range defaults.ImagePullSecrets {
+		if msgs := validation.IsDNS1123Subdomain(secret); len(msgs) > 0 {
+			errs = append(errs, common.Invalid("defaults.imagePullSecrets", fmt.Sprintf("%s: %s", secret, strings.Join(msgs, "; "))))
+		}
+	}
+	return errs
+}
+
+// validateScaleRequest 校验实例扩缩容请求，协议相关的校验在 ScaleHandler 中完成
+func validateScaleRequest(req *instancepb.ScaleRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		RequiredInt("replicas", int64(req.Replicas)).
+		Range("replicas", int64(req.Replicas), 1, maxReplicas)
+	return v.Err()
+}
+
+// validateResetCircuitBreakerRequest 校验熔断重置请求
+func validateResetCircuitBreakerRequest(req *instancepb.ResetCircuitBreakerRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateRotateTokenRequest 校验令牌轮换请求
+func validateRotateTokenRequest(req *instancepb.RotateTokenRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Required("token", req.Token).
+		Range("gracePeriod", int64(req.GracePeriod), 0, maxTokenGracePeriod)
+	if req.Scope != "" && !model.IsTokenScope(req.Scope) {
+		v.Add(common.Invalid("scope", fmt.Sprintf("must be %s or %s", model.TokenScopeDiscovery, model.TokenScopeFull)))
+	}
+	return v.Err()
+}
+
+// validateConnectionRequest 校验客户端连接配置请求
+func validateConnectionRequest(req *instancepb.ConnectionRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId).
+		Range("expireDays", int64(req.ExpireDays), 0, maxTokenExpireDays)
+	if req.Client != "" && !slices.Contains(utils.McpClients, req.Client) {
+		v.Add(common.Invalid("client", fmt.Sprintf("must be one of %s", strings.Join(utils.McpClients, ", "))))
+	}
+	if req.Token != "" && req.NewToken {
+		v.Add(common.Invalid("newToken", "cannot be used together with token"))
+	}
+	if req.Scope != "" && !model.IsTokenScope(req.Scope) {
+		v.Add(common.Invalid("scope", fmt.Sprintf("must be %s or %s", model.TokenScopeDiscovery, model.TokenScopeFull)))
+	}
+	return v.Err()
+}
+
+// validateConnectionsRequest 校验实例连接数查询请求
+func validateConnectionsRequest(req *instancepb.ConnectionsRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateDrainRequest 校验实例连接排空请求
+func validateDrainRequest(req *instancepb.DrainRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateScanRequest 校验实例镜像漏洞扫描请求
+func validateScanRequest(req *instancepb.ScanRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateLockRequest 校验实例锁定请求
+func validateLockRequest(req *instancepb.LockRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateUnlockRequest 校验实例解锁请求
+func validateUnlockRequest(req *instancepb.UnlockRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	return v.Err()
+}
+
+// validateRegistryCredentialCreateRequest 校验镜像仓库凭证创建请求
+func validateRegistryCredentialCreateRequest(req *registry_credential.CreateRegistryCredentialRequest) error {
+	v := &common.Validation{}
+	v.Required("name", req.Name).
+		Required("registryHost", req.RegistryHost).
+		Required("username", req.Username).
+		Required("password", req.Password)
+	return v.Err()
+}
+
+// validateRegistryCredentialUpdateRequest 校验镜像仓库凭证更新请求，password 为空时保留原密码
+func validateRegistryCredentialUpdateRequest(req *registry_credential.UpdateRegistryCredentialRequest) error {
+	v := &common.Validation{}
+	v.Required("name", req.Name).
+		Required("registryHost", req.RegistryHost).
+		Required("username", req.Username)
+	return v.Err()
+}
+
+// validateCatalogInstallRequest 校验目录条目安装请求，target 为空时创建模板
+func validateCatalogInstallRequest(req *catalogpb.InstallCatalogRequest) error {
+	v := &common.Validation{}
+	switch req.Target {
+	case "", catalogTargetTemplate, catalogTargetInstance:
+	default:
+		v.Add(common.Invalid("target", fmt.Sprintf("must be %s or %s", catalogTargetTemplate, catalogTargetInstance)))
+	}
+	if req.EnvironmentId < 0 {
+		v.Add(common.Min("environmentId", 0))
+	}
+	return v.Err()
+}
+
+// validateCatalogSyncRequest 校验目录同步请求，url 为空时使用 catalog.registryURL
+func validateCatalogSyncRequest(req *catalogpb.SyncCatalogRequest) error {
+	v := &common.Validation{}
+	if req.Url != "" {
+		if u, err := url.Parse(req.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add(common.Invalid("url", "must be an http or https URL"))
+		}
+	}
+	return v.Err()
+}
+
+// validateListCatalogRequest 校验目录列表请求，sortBy 为空时按展示顺序排序
+func validateListCatalogRequest(req *catalogpb.ListCatalogRequest) error {
+	v := &common.Validation{}
+	switch req.SortBy {
+	case "", mysql.CatalogSortPopular, mysql.CatalogSortRating, mysql.CatalogSortName:
+	default:
+		v.Add(common.Invalid("sortBy", fmt.Sprintf("must be %s, %s or %s", mysql.CatalogSortPopular, mysql.CatalogSortRating, mysql.CatalogSortName)))
+	}
+	return v.Err()
+}
+
+// validateRateCatalogRequest 校验目录条目评分请求
+func validateRateCatalogRequest(req *catalogpb.RateCatalogRequest) error {
+	v := &common.Validation{}
+	v.Range("rating", int64(req.Rating), model.CatalogRatingMin, model.CatalogRatingMax)
+	if utf8.RuneCountInString(req.Comment) > maxCatalogRatingCommentLength {
+		v.Add(common.Invalid("comment", fmt.Sprintf("must be at most %d characters", maxCatalogRatingCommentLength)))
+	}
+	return v.Err()
+}
+
+// validateValidateConfigRequest 校验 mcpServers 配置校验请求，配置内容的错误在响应中逐项返回
+func validateValidateConfigRequest(req *instancepb.ValidateConfigRequest) error {
+	v := &common.Validation{}
+	v.Required("mcpServers", req.McpServers)
+	return v.Err()
+}
+
+// validateValidateMcpConfigRequest 校验 mcpServers 配置检查请求，配置内容的问题在响应中逐项返回
+func validateValidateMcpConfigRequest(req *validatepb.ValidateMcpConfigRequest) error {
+	v := &common.Validation{}
+	v.Required("mcpServers", req.McpServers)
+	v.Range("timeout", int64(req.Timeout), 1, maxDetectProtocolTimeout)
+	return v.Err()
+}
+
+// validateConvertConfigRequest 校验客户端配置转换请求，转换和校验的错误在响应中逐项返回
+func validateConvertConfigRequest(req *instancepb.ConvertConfigRequest) error {
+	v := &common.Validation{}
+	v.Required("mcpServers", req.McpServers)
+	return v.Err()
+}
+
+// validateDetectProtocolRequest 校验协议检测请求
+func validateDetectProtocolRequest(req *instancepb.DetectProtocolRequest) error {
+	v := &common.Validation{}
+	if v.Required("url", req.Url); req.Url != "" {
+		if u, err := url.Parse(req.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add(common.Invalid("url", "must be an http or https URL"))
+		}
+	}
+	v.Range("timeout", int64(req.Timeout), 1, maxDetectProtocolTimeout)
+	return v.Err()
+}
+
+// validateValidateScriptRequest 校验脚本校验请求，脚本内容的问题在响应中逐项返回
+func validateValidateScriptRequest(req *instancepb.ValidateScriptRequest) error {
+	v := &common.Validation{}
+	if strings.TrimSpace(req.InitScript) == "" && strings.TrimSpace(req.Command) == "" {
+		v.Add(common.Required("initScript"))
+	}
+	return v.Err()
+}
+
+// validateServicePath 校验托管实例的服务路径，为空表示服务监听在根路径（编辑时表示保持原路径）
+// 路径直接拼接到目标地址后，查询参数和片段会让网关转发到错误的地址
+func validateServicePath(field, servicePath string) *common.FieldError {
+	switch {
+	case servicePath == "":
+		return nil
+	case !strings.HasPrefix(servicePath, "/"):
+		return common.Invalid(field, "must begin with /")
+	case strings.ContainsAny(servicePath, "?#"):
+		return common.Invalid(field, "must not contain a query string or fragment")
+	case len(servicePath) > maxServicePathLength:
+		return common.Invalid(field, fmt.Sprintf("must be at most %d characters", maxServicePathLength))
+	}
+	if u, err := url.Parse(servicePath); err != nil || u.Host != "" || strings.ContainsAny(servicePath, " \t\r\n") {
+		return common.Invalid(field, "must be a valid URL path")
+	}
+	return nil
+}
+
+// validatePriorityClassName 校验 Pod 优先级类名称，为空表示不设置
+func validatePriorityClassName(field, name string) *common.FieldError {
+	if name == "" {
+		return nil
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return common.Invalid(field, strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// validateAllowedEgress 校验出站目的地，每项需为 CIDR、IP 或域名
+func validateAllowedEgress(destinations []string) []*common.FieldError {
+	if len(destinations) > maxAllowedEgress {
+		return []*common.FieldError{common.Invalid("allowedEgress", fmt.Sprintf("at most %d destinations are allowed", maxAllowedEgress))}
+	}
+	var errs []*common.FieldError
+	for i, dest := range destinations {
+		if _, err := netip.ParsePrefix(dest); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(dest); err == nil {
+			continue
+		}
+		if msgs := validation.IsDNS1123Subdomain(dest); len(msgs) > 0 {
+			errs = append(errs, common.Invalid(fmt.Sprintf("allowedEgress[%d]", i), "must be a CIDR, an IP address or a lowercase DNS name"))
+		}
+	}
+	return errs
+}
+
+// validatePodAffinity 校验实例间的亲和规则，每条规则至少通过实例ID或标签指定目标实例，
+// 目标实例是否存在在创建时校验
+func validatePodAffinity(rules []*instancepb.PodAffinityRule) []*common.FieldError {
+	var errs []*common.FieldError
+	for i, rule := range rules {
+		field := fmt.Sprintf("podAffinity[%d]", i)
+		if rule.Type != biz.AffinityTypeAffinity && rule.Type != biz.AffinityTypeAntiAffinity {
+			errs = append(errs, common.Invalid(field+".type",
+				fmt.Sprintf("must be %s or %s", biz.AffinityTypeAffinity, biz.AffinityTypeAntiAffinity)))
+		}
+		if len(rule.InstanceIds) == 0 && len(rule.Labels) == 0 {
+			errs = append(errs, common.Invalid(field, "instanceIds or labels is required"))
+		}
+		if slices.Contains(rule.InstanceIds, "") {
+			errs = append(errs, common.Invalid(field+".instanceIds", "must not contain empty instance ID"))
+		}
+		if err := common.ValidateLabels(rule.Labels); err != nil {
+			errs = append(errs, common.Invalid(field+".labels", err.Error()))
+		}
+		if rule.TopologyKey != "" {
+			if msgs := validation.IsQualifiedName(rule.TopologyKey); len(msgs) > 0 {
+				errs = append(errs, common.Invalid(field+".topologyKey", strings.Join(msgs, "; ")))
+			}
+		}
+		if rule.Weight < 0 || rule.Weight > maxAffinityWeight {
+			errs = append(errs, common.Range(field+".weight", 0, maxAffinityWeight))
+		}
+	}
+	return errs
+}
+
+// validateServiceAccount 校验托管实例的服务账号，指定已有账号时不能同时开启专用账号
+func validateServiceAccount(name string, isolate bool) *common.FieldError {
+	if name == "" {
+		return nil
+	}
+	if isolate {
+		return common.Invalid("serviceAccountName", "cannot be set together with isolateServiceAccount")
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return common.Invalid("serviceAccountName", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// validateReplicas 校验托管实例的副本数，0 表示使用默认值
+// streamable-http 实例无状态；SSE 实例由网关将连接和后续消息粘滞到同一个 Pod；stdio 实例不支持会话粘滞，只能单副本
+func validateReplicas(replicas int32, protocol model.McpProtocol) *common.FieldError {
+	if replicas < 0 || replicas > maxReplicas {
+		return common.Range("replicas", 0, maxReplicas)
+	}
+	if replicas > 1 && protocol != model.McpProtocolStreamableHttp && protocol != model.McpProtocolSSE {
+		return common.Invalid("replicas", fmt.Sprintf("multiple replicas are only supported for %s and %s, got %s", model.McpProtocolStreamableHttp, model.McpProtocolSSE, protocol))
+	}
+	return nil
+}
+
+// validateInitContainers 校验初始化容器配置，名称需符合 DNS-1123 标签规范且不能重复
+func validateInitContainers(initContainers []*instancepb.InitContainer, sharedPath string) []*common.FieldError {
+	var errs []*common.FieldError
+	if len(initContainers) > maxInitContainers {
+		errs = append(errs, common.Invalid("initContainers", fmt.Sprintf("at most %d init containers are allowed", maxInitContainers)))
+	}
+	if sharedPath != "" && !strings.HasPrefix(sharedPath, "/") {
+		errs = append(errs, common.Invalid("initSharedPath", "must be an absolute path"))
+	}
+	names := make(map[string]bool, len(initContainers))
+	for i, ic := range initContainers {
+		field := fmt.Sprintf("initContainers[%d]", i)
+		if ic.Image == "" {
+			errs = append(errs, common.Required(field+".image"))
+		}
+		if msgs := validation.IsDNS1123Label(ic.Name); len(msgs) > 0 {
+			errs = append(errs, common.Invalid(field+".name", strings.Join(msgs, "; ")))
+		} else if names[ic.Name] {
+			errs = append(errs, common.Invalid(field+".name", fmt.Sprintf("duplicate init container name %q", ic.Name)))
+		}
+		names[ic.Name] = true
+		errs = append(errs, validateEnvTemplates(field+".environmentVariables", ic.EnvironmentVariables)...)
+	}
+	return errs
+}
+
+// validateSidecars 校验边车容器配置，名称不能与初始化容器重复，all 保留为日志查询的容器选择
+// 边车容器与主容器共享网络，端口不能与实例端口冲突
+func validateSidecars(sidecars []*instancepb.SidecarContainer, initContainers []*instancepb.InitContainer, port int32) []*common.FieldError {
+	var errs []*common.FieldError
+	if len(sidecars) > maxSidecars {
+		errs = append(errs, common.Invalid("sidecars", fmt.Sprintf("at most %d sidecars are allowed", maxSidecars)))
+	}
+	names := make(map[string]bool, len(initContainers)+len(sidecars))
+	for _, ic := range initContainers {
+		names[ic.Name] = true
+	}
+	ports := map[int32]bool{port: true}
+	for i, sc := range sidecars {
+		field := fmt.Sprintf("sidecars[%d]", i)
+		if sc.Image == "" {
+			errs = append(errs, common.Required(field+".image"))
+		}
+		switch msgs := validation.IsDNS1123Label(sc.Name); {
+		case len(msgs) > 0:
+			errs = append(errs, common.Invalid(field+".name", strings.Join(msgs, "; ")))
+		case sc.Name == container.LogContainerAll:
+			errs = append(errs, common.Invalid(field+".name", fmt.Sprintf("%q is reserved", sc.Name)))
+		case names[sc.Name]:
+			errs = append(errs, common.Invalid(field+".name", fmt.Sprintf("duplicate container name %q", sc.Name)))
+		}
+		names[sc.Name] = true
+		errs = append(errs, validateEnvTemplates(field+".environmentVariables", sc.EnvironmentVariables)...)
+		for _, p := range sc.Ports {
+			if p < 1 || p > 65535 {
+				errs = append(errs, common.Range(field+".ports", 1, 65535))
+			} else if ports[p] {
+				errs = append(errs, common.Invalid(field+".ports", fmt.Sprintf("port %d is already in use", p)))
+			}
+			ports[p] = true
+		}
+		for _, resources := range []map[string]string{sc.ResourceRequests, sc.ResourceLimits} {
+			for name, value := range resources {
+				if _, err := resource.ParseQuantity(value); err != nil {
+					errs = append(errs, common.Invalid(field+".resources", fmt.Sprintf("invalid quantity %q for %s", value, name)))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateMcpServers 校验 mcpServers 配置内容及协议一致性，每个出错字段返回一条错误
+// 直连和代理模式允许配置多个服务，每个服务都需与实例协议一致
+// requireCommand 为 true 时要求配置中只有一个服务且包含启动命令（托管 stdio 模式）
+func validateMcpServers(mcpServers string, protocol instancepb.McpProtocol, requireCommand bool) []*common.FieldError {
+	result, err := utils.ValidateMcpConfig([]byte(mcpServers))
+	if err != nil {
+		return []*common.FieldError{common.InvalidJSON("mcpServers", err.Error())}
+	}
+	if !result.IsValid {
+		return mcpConfigFieldErrors(result.Errors)
+	}
+	// AUTO 创建时才探测协议，这里只校验服务配置
+	expected := ""
+	if !requireCommand && protocol != instancepb.McpProtocol_AUTO {
+		mcpProtocol, err := common.ConvertToModelMcpProtocol(protocol)
+		if err != nil {
+			return []*common.FieldError{common.Invalid("mcpProtocol", err.Error())}
+		}
+		expected = string(mcpProtocol)
+	}
+	return mcpConfigFieldErrors(result.CheckProtocol(expected, requireCommand))
+}
+
+// mcpConfigFieldErrors 将配置错误转换为字段错误，字段名使用配置内的路径（如 mcpServers.github.url）
+func mcpConfigFieldErrors(errs []*utils.McpConfigError) []*common.FieldError {
+	fieldErrs := make([]*common.FieldError, 0, len(errs))
+	for _, e := range errs {
+		field := e.Path
+		if !strings.HasPrefix(field, "mcpServers") {
+			field = "mcpServers"
+		}
+		fieldErrs = append(fieldErrs, common.Invalid(field, e.Message))
+	}
+	return fieldErrs
+}
+
+// validateUpdateUserQuotaRequest 校验设置用户配额请求，上限为 0 表示不限制
+func validateUpdateUserQuotaRequest(req *quotapb.UpdateUserQuotaRequest) error {
+	v := &common.Validation{}
+	if req.Id <= 0 {
+		v.Add(common.Min("id", 1))
+	}
+	if req.MaxInstances < 0 {
+		v.Add(common.Min("maxInstances", 0))
+	}
+	if req.MaxHostingInstances < 0 {
+		v.Add(common.Min("maxHostingInstances", 0))
+	}
+	if req.MaxPackageBytes < 0 {
+		v.Add(common.Min("maxPackageBytes", 0))
+	}
+	return v.Err()
+}
+
+// validateUpdateNotificationPreferenceRequest 校验设置通知偏好请求，事件需为可订阅的实例事件
+func validateUpdateNotificationPreferenceRequest(req *notificationpb.UpdateNotificationPreferenceRequest) error {
+	v := &common.Validation{}
+	if req.Id <= 0 {
+		v.Add(common.Min("id", 1))
+	}
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			v.Add(common.Invalid("email", "invalid email address"))
+		}
+	}
+	if req.Mode != "" && req.Mode != model.NotificationModeImmediate && req.Mode != model.NotificationModeDigest {
+		v.Add(common.Invalid("mode", fmt.Sprintf("must be %s or %s", model.NotificationModeImmediate, model.NotificationModeDigest)))
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(biz.NotificationEvents, event) {
+			v.Add(common.Invalid("events", fmt.Sprintf("unknown event %s", event)))
+		}
+	}
+	return v.Err()
+}
+
+// changeRequestStatuses 可以过滤的变更请求状态
+var changeRequestStatuses = []string{
+	model.ChangeRequestStatusPending,
+	model.ChangeRequestStatusApproved,
+	model.ChangeRequestStatusRejected,
+	model.ChangeRequestStatusExpired,
+	model.ChangeRequestStatusFailed,
+}
+
+// validateListChangeRequestsRequest 校验查询变更请求列表请求
+func validateListChangeRequestsRequest(req *changerequestpb.ListChangeRequestsRequest) error {
+	v := &common.Validation{}
+	if req.Status != "" && !slices.Contains(changeRequestStatuses, req.Status) {
+		v.Add(common.Invalid("status", "must be one of "+strings.Join(changeRequestStatuses, ", ")))
+	}
+	return v.Err()
+}
+
+// validateReviewChangeRequestRequest 校验审批变更请求请求
+func validateReviewChangeRequestRequest(req *changerequestpb.ReviewChangeRequestRequest) error {
+	v := &common.Validation{}
+	if req.Id <= 0 {
+		v.Add(common.Min("id", 1))
+	}
+	if utf8.RuneCountInString(req.Comment) > maxReviewCommentLength {
+		v.Add(common.Invalid("comment", fmt.Sprintf("must be at most %d characters", maxReviewCommentLength)))
+	}
+	return v.Err()
+}
+
+// validateInstanceCostRequest 校验查询实例成本请求
+func validateInstanceCostRequest(req *costpb.InstanceCostRequest) error {
+	v := &common.Validation{}
+	v.Required("instanceId", req.InstanceId)
+	v.Add(validateCostMonth(req.Month))
+	return v.Err()
+}
+
+// validateCostReportRequest 校验成本报表请求
+func validateCostReportRequest(req *costpb.CostReportRequest) error {
+	v := &common.Validation{}
+	switch {
+	case req.GroupBy == "", req.GroupBy == biz.CostGroupByInstance, req.GroupBy == biz.CostGroupByEnvironment:
+	case strings.HasPrefix(req.GroupBy, biz.CostGroupByLabelPrefix) && strings.TrimPrefix(req.GroupBy, biz.CostGroupByLabelPrefix) != "":
+	default:
+		v.Add(common.Invalid("groupBy", fmt.Sprintf("unsupported groupBy: %s, expected %s, %s or %s<key>",
+			req.GroupBy, biz.CostGroupByInstance, biz.CostGroupByEnvironment, biz.CostGroupByLabelPrefix)))
+	}
+	v.Add(validateCostMonth(req.Month))
+	v.Add(validateExportFormat(req.Format), validatePageToken(req.PageToken))
+	return v.Err()
+}
+
+// validateCostMonth 校验成本查询的月份，为空时使用当月
+func validateCostMonth(month string) *common.FieldError {
+	if month == "" {
+		return nil
+	}
+	if _, err := time.Parse(biz.CostMonthLayout, month); err != nil {
+		return common.Invalid("month", "must be in YYYY-MM format")
+	}
+	return nil
+}
+
+// validateListAuditLogsRequest 校验查询审计日志请求
+func validateListAuditLogsRequest(req *auditpb.ListAuditLogsRequest) error {
+	v := &common.Validation{}
+	v.Add(validateRFC3339("from", req.From), validateRFC3339("to", req.To))
+	if req.PageSize < 0 {
+		v.Add(common.Min("pageSize", 0))
+	}
+	v.Add(validateExportFormat(req.Format), validatePageToken(req.PageToken))
+	return v.Err()
+}
+
+// validateRFC3339 校验可选的 RFC3339 时间
+func validateRFC3339(field, value string) *common.FieldError {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return common.Invalid(field, "must be an RFC3339 time")
+	}
+	return nil
+}
+
+// validateStatisticalRequest 校验统计信息请求
+func validateStatisticalRequest(req *dashboardpb.StatisticalRequest) error {
+	return (&common.Validation{}).Add(validateExportFormat(req.Format)).Err()
+}
+
+// validateExportFormat 校验响应格式，为空时按 Accept 头协商
+func validateExportFormat(format string) *common.FieldError {
+	switch strings.ToLower(format) {
+	case "", "json", common.FormatCSV:
+		return nil
+	}
+	return common.Invalid("format", fmt.Sprintf("unsupported format: %s, expected json or %s", format, common.FormatCSV))
+}
+
+// validatePageToken 校验分页令牌
+func validatePageToken(token string) *common.FieldError {
+	if _, err := common.DecodePageToken(token); err != nil {
+		return common.Invalid("pageToken", err.Error())
+	}
+	return nil
+}
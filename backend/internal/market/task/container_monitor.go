@@ -11,6 +11,7 @@ import (
 	"qm-mcp-server/pkg/container"
 	"qm-mcp-server/pkg/database/model"
 	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/redis"
 
 	"go.uber.org/zap"
 )
@@ -71,6 +72,11 @@ func (cm *ContainerMonitorImpl) MonitorContainers(ctx context.Context) error {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
+			// 任务锁丢失时中止本批次剩余的检查，由新的持有者继续
+			if ctx.Err() != nil {
+				return
+			}
+
 			err := cm.CheckContainer(ctx, inst)
 			if err != nil {
 				cm.logger.Error("检查容器失败",
@@ -114,6 +120,11 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 	cm.logger.Debug("开始检查容器",
 		zap.String("instance_id", instance.InstanceID))
 
+	// 多副本部署时确认仍持有任务锁，避免与新的持有者同时修改实例
+	if err := redis.VerifyLock(ctx); err != nil {
+		return err
+	}
+
 	// 如果实例没有容器名称，说明还没有创建过容器，跳过
 	if instance.ContainerName == "" {
 		cm.logger.Debug("实例尚未创建容器，跳过检查",
@@ -168,6 +179,7 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 			zap.String("container_name", instance.ContainerName),
 			zap.Error(err))
 
+		biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationRecreate, "container not found, recreating")
 		return cm.recreateContainerWithStatus(ctx, instance, containerCreateOptions, model.ContainerStatusPending, "容器不存在，重新创建中")
 	}
 
@@ -214,6 +226,9 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 
 	// 根据容器就绪状态进行处理
 	if !isReady {
+		// 采集警告事件并持久化，Kubernetes 事件过期后仍可查询
+		cm.recordWarningEvents(ctx, containerManager, instance)
+
 		// 检查启动超时
 		if instance.StartupTimeout > 0 {
 			if (currentTime - containerCreatedAtMs) > instance.StartupTimeout {
@@ -226,7 +241,7 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 					zap.Int64("timeout_at_ms", instance.StartupTimeout))
 
 				return cm.cleanupAndUpdateStatus(ctx, instance,
-					fmt.Sprintf("容器启动超时，启动时长: %d毫秒，超时时间: %s", startupDuration, time.UnixMilli(instance.StartupTimeout).Format(time.RFC3339)))
+					fmt.Sprintf("容器启动超时，启动时长: %d毫秒，超时时间: %s，状态信息: %s", startupDuration, time.UnixMilli(instance.StartupTimeout).Format(time.RFC3339), runInfo))
 			}
 		}
 
@@ -242,9 +257,9 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 					zap.Int64("timeout_at_ms", instance.RunningTimeout),
 					zap.String("run_info", runInfo))
 
-				return cm.updateInstanceStatus(ctx, instance,
-					model.ContainerStatusRunTimeoutStop,
-					fmt.Sprintf("容器运行中但未就绪，运行超时，运行时长: %d毫秒，状态信息: %s", runningDuration, runInfo))
+				message := fmt.Sprintf("容器运行中但未就绪，运行超时，运行时长: %d毫秒，状态信息: %s", runningDuration, runInfo)
+				biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationRunningTimeout, message)
+				return cm.updateInstanceStatus(ctx, instance, model.ContainerStatusRunTimeoutStop, message)
 			}
 		}
 
@@ -257,6 +272,14 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 			if err != nil {
 				return fmt.Errorf("更新实例状态失败: %w", err)
 			}
+			biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationUnready, runInfo)
+		}
+		// 启动中的 Pod 因调度、配额或优先级受阻时记录原因，便于用户了解启动缓慢的原因
+		if instance.ContainerStatus == model.ContainerStatusPending && runInfo != "not ready" && runInfo != instance.ContainerLastMessage {
+			instance.ContainerLastMessage = runInfo
+			if err := cm.instanceRepo.Update(ctx, instance); err != nil {
+				return fmt.Errorf("更新实例状态失败: %w", err)
+			}
 		}
 		// 容器仍在启动中或运行中但未就绪，继续等待
 		cm.logger.Debug("容器未就绪，继续等待",
@@ -277,6 +300,7 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 					zap.Int64("running_duration_ms", runningDuration),
 					zap.Int64("timeout_at_ms", instance.RunningTimeout))
 
+				biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationRunningTimeout, message)
 				return cm.updateInstanceStatus(ctx, instance, model.ContainerStatusRunTimeoutStop, message)
 			}
 		}
@@ -286,8 +310,25 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 			zap.String("instance_id", instance.InstanceID),
 			zap.String("container_name", instance.ContainerName))
 
+		// 检查运行中的容器是否与存储的配置一致，只记录日志，不自动修复
+		if items, err := biz.GContainerBiz.DetectDrift(ctx, instance); err != nil {
+			cm.logger.Debug("检查容器配置漂移失败",
+				zap.String("instance_id", instance.InstanceID),
+				zap.Error(err))
+		} else if len(items) > 0 {
+			drift := make([]string, 0, len(items))
+			for _, item := range items {
+				drift = append(drift, item.String())
+			}
+			cm.logger.Warn("运行中的容器与存储的配置不一致",
+				zap.String("instance_id", instance.InstanceID),
+				zap.String("container_name", instance.ContainerName),
+				zap.Strings("drift", drift))
+		}
+
 		// 确保实例状态为运行中
 		if instance.ContainerStatus != model.ContainerStatusRunning {
+			biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationReady, "")
 			return cm.updateInstanceStatus(ctx, instance, model.ContainerStatusRunning, "容器运行正常且已就绪")
 		}
 	}
@@ -297,6 +338,12 @@ func (cm *ContainerMonitorImpl) CheckContainer(ctx context.Context, instance *mo
 
 // updateInstanceStatus 更新实例状态
 func (cm *ContainerMonitorImpl) updateInstanceStatus(ctx context.Context, instance *model.McpInstance, containerStatus model.ContainerStatus, message string) error {
+	if err := redis.VerifyLock(ctx); err != nil {
+		cm.logger.Warn("任务锁已丢失，放弃更新实例状态",
+			zap.String("instance_id", instance.InstanceID),
+			zap.Error(err))
+		return err
+	}
 
 	instance.ContainerStatus = containerStatus
 	instance.ContainerLastMessage = message
@@ -371,6 +418,7 @@ func (cm *ContainerMonitorImpl) cleanupAndUpdateStatus(ctx context.Context, inst
 	}
 
 	// 更新实例状态为启动超时停止
+	biz.GInstanceOperationBiz.Record(ctx, instance.InstanceID, model.InstanceOperationStartupTimeout, message)
 	return cm.updateInstanceStatus(ctx, instance,
 		model.ContainerStatusInitTimeoutStop, message)
 }
@@ -455,7 +503,9 @@ func (cm *ContainerMonitorImpl) recreateContainerWithStatus(ctx context.Context,
 		return fmt.Errorf("创建新服务失败: %w", err)
 	}
 
-	// 更新实例信息
+	// 更新实例信息，新容器使用代码包的当前内容和镜像标签当前指向的镜像
+	biz.GCodePackageBiz.StampVersion(ctx, instance)
+	biz.GContainerBiz.StampImageDigest(ctx, instance)
 	instance.ContainerName = newContainerName
 	instance.ContainerServiceName = serviceName
 	instance.ContainerStatus = containerStatus
@@ -476,3 +526,20 @@ func (cm *ContainerMonitorImpl) recreateContainerWithStatus(ctx context.Context,
 
 	return nil
 }
+
+// recordWarningEvents 采集容器警告事件并持久化，失败只记录日志
+func (cm *ContainerMonitorImpl) recordWarningEvents(ctx context.Context, containerManager container.ContainerManager, instance *model.McpInstance) {
+	events, err := containerManager.GetWarningEvents(ctx, instance.ContainerName)
+	if err != nil {
+		cm.logger.Warn("获取容器警告事件失败",
+			zap.String("instance_id", instance.InstanceID),
+			zap.String("container_name", instance.ContainerName),
+			zap.Error(err))
+		return
+	}
+	if err := biz.GInstanceEventBiz.RecordEvents(ctx, instance.InstanceID, events); err != nil {
+		cm.logger.Warn("持久化容器警告事件失败",
+			zap.String("instance_id", instance.InstanceID),
+			zap.Error(err))
+	}
+}
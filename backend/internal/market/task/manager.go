@@ -3,8 +3,13 @@ package task
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/internal/market/config"
 	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/redis"
 	"qm-mcp-server/pkg/scheduler"
 
 	"go.uber.org/zap"
@@ -24,6 +29,25 @@ type TaskManagerImpl struct {
 	// monitorTaskID 监控任务ID
 	monitorTaskID string
 
+	// podWatcher Pod 状态监听器，配置关闭时为 nil
+	podWatcher *PodWatcher
+
+	// stopPodWatcher 停止 Pod 状态监听
+	stopPodWatcher context.CancelFunc
+
+	// monitorElector、podWatcherElector、iconCleanupElector、healthMonitorElector、tokenExpiryElector、notificationDigestElector、
+	// costAggregationElector 多副本部署时只有持有任务锁的副本执行后台任务
+	monitorElector            *redis.LeaderElector
+	podWatcherElector         *redis.LeaderElector
+	iconCleanupElector        *redis.LeaderElector
+	healthMonitorElector      *redis.LeaderElector
+	tokenExpiryElector        *redis.LeaderElector
+	notificationDigestElector *redis.LeaderElector
+	costAggregationElector    *redis.LeaderElector
+
+	// stopElectors 停止竞争并释放任务锁
+	stopElectors context.CancelFunc
+
 	// isRunning 是否正在运行
 	isRunning bool
 }
@@ -48,9 +72,18 @@ func (tm *TaskManagerImpl) SetupGlobalTasks(ctx context.Context) error {
 	// 创建容器监控器
 	containerMonitor := NewContainerMonitor(tm.instanceRepo, tm.logger)
 
-	// 创建任务函数适配器
+	owner := jobLockOwner()
+	tm.monitorElector = redis.NewLeaderElector("market:container_monitor", owner, redis.DefaultLeaderLockTTL)
+
+	// 创建任务函数适配器，未持有任务锁的副本跳过本次执行，执行中失去锁时取消上下文
 	taskFunc := func(ctx context.Context) error {
-		return containerMonitor.MonitorContainers(ctx)
+		leaderCtx, cancel, ok := tm.monitorElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("容器监控任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		return containerMonitor.MonitorContainers(leaderCtx)
 	}
 
 	// 创建容器监控任务 - 使用Cron任务，每30秒执行一次
@@ -84,6 +117,211 @@ func (tm *TaskManagerImpl) SetupGlobalTasks(ctx context.Context) error {
 		zap.String("task_name", task.GetName()),
 		zap.String("cron_expr", "*/30 * * * * *"))
 
+	// 图标清理任务
+	if err := tm.setupIconCleanupTask(owner); err != nil {
+		return err
+	}
+
+	// 直连和代理实例健康检查任务
+	if err := tm.setupHealthMonitorTask(owner); err != nil {
+		return err
+	}
+
+	// 实例令牌即将过期通知任务
+	if err := tm.setupTokenExpiryTask(owner); err != nil {
+		return err
+	}
+
+	// 实例事件邮件汇总任务
+	if err := tm.setupNotificationDigestTask(owner); err != nil {
+		return err
+	}
+
+	// 托管实例每日成本汇总任务
+	if err := tm.setupCostAggregationTask(owner); err != nil {
+		return err
+	}
+
+	// Pod watch 加快启动中实例的就绪检测，定时监控任务仍然保留作为兜底
+	if !config.GlobalConfig.PodWatch.Disabled {
+		tm.podWatcher = NewPodWatcher(tm.instanceRepo, containerMonitor, tm.logger, config.GlobalConfig.PodWatch)
+		tm.podWatcherElector = redis.NewLeaderElector("market:pod_watcher", owner, redis.DefaultLeaderLockTTL)
+	}
+
+	return nil
+}
+
+// setupIconCleanupTask 每小时删除未被实例、模板或目录条目引用的上传图标
+func (tm *TaskManagerImpl) setupIconCleanupTask(owner string) error {
+	tm.iconCleanupElector = redis.NewLeaderElector("market:icon_cleanup", owner, redis.DefaultLeaderLockTTL)
+
+	taskFunc := func(ctx context.Context) error {
+		leaderCtx, cancel, ok := tm.iconCleanupElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("图标清理任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		deleted, err := biz.GIconBiz.Cleanup(leaderCtx)
+		if deleted > 0 {
+			tm.logger.Info("已清理未引用的图标", zap.Int("count", deleted))
+		}
+		return err
+	}
+
+	task, err := scheduler.NewCronTask(
+		"global_icon_cleanup",
+		"未引用图标清理任务",
+		"0 0 * * * *", // 每小时执行一次
+		"icon_cleanup",
+		taskFunc,
+	)
+	if err != nil {
+		tm.logger.Error("创建图标清理任务失败", zap.Error(err))
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	if err := tm.scheduler.AddTask(task); err != nil {
+		tm.logger.Error("添加图标清理任务失败",
+			zap.String("task_id", task.GetID()),
+			zap.Error(err))
+		return fmt.Errorf("添加任务失败: %w", err)
+	}
+	return nil
+}
+
+// setupHealthMonitorTask 每10秒检查一次开启了健康检查且到达检查间隔的直连和代理实例
+func (tm *TaskManagerImpl) setupHealthMonitorTask(owner string) error {
+	tm.healthMonitorElector = redis.NewLeaderElector("market:health_monitor", owner, redis.DefaultLeaderLockTTL)
+
+	taskFunc := func(ctx context.Context) error {
+		leaderCtx, cancel, ok := tm.healthMonitorElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("健康检查任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		return biz.GHealthMonitorBiz.RunDue(leaderCtx)
+	}
+
+	task, err := scheduler.NewCronTask(
+		"global_health_monitor",
+		"实例健康检查任务",
+		"*/10 * * * * *", // 每10秒执行一次
+		"health_monitor",
+		taskFunc,
+	)
+	if err != nil {
+		tm.logger.Error("创建健康检查任务失败", zap.Error(err))
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	if err := tm.scheduler.AddTask(task); err != nil {
+		tm.logger.Error("添加健康检查任务失败",
+			zap.String("task_id", task.GetID()),
+			zap.Error(err))
+		return fmt.Errorf("添加任务失败: %w", err)
+	}
+	return nil
+}
+
+// setupTokenExpiryTask 每小时查找进入过期预警窗口的实例令牌并发送通知
+func (tm *TaskManagerImpl) setupTokenExpiryTask(owner string) error {
+	tm.tokenExpiryElector = redis.NewLeaderElector("market:token_expiry", owner, redis.DefaultLeaderLockTTL)
+
+	taskFunc := func(ctx context.Context) error {
+		leaderCtx, cancel, ok := tm.tokenExpiryElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("令牌过期通知任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		return biz.GTokenExpiryBiz.NotifyExpiring(leaderCtx)
+	}
+
+	task, err := scheduler.NewCronTask(
+		"global_token_expiry",
+		"实例令牌过期通知任务",
+		"0 5 * * * *", // 每小时执行一次
+		"token_expiry",
+		taskFunc,
+	)
+	if err != nil {
+		tm.logger.Error("创建令牌过期通知任务失败", zap.Error(err))
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	if err := tm.scheduler.AddTask(task); err != nil {
+		tm.logger.Error("添加令牌过期通知任务失败",
+			zap.String("task_id", task.GetID()),
+			zap.Error(err))
+		return fmt.Errorf("添加任务失败: %w", err)
+	}
+	return nil
+}
+
+// setupNotificationDigestTask 每 5 分钟为到达汇总间隔的用户发送实例事件汇总邮件
+func (tm *TaskManagerImpl) setupNotificationDigestTask(owner string) error {
+	tm.notificationDigestElector = redis.NewLeaderElector("market:notification_digest", owner, redis.DefaultLeaderLockTTL)
+
+	taskFunc := func(ctx context.Context) error {
+		leaderCtx, cancel, ok := tm.notificationDigestElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("邮件汇总任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		return biz.GNotificationBiz.SendDigests(leaderCtx)
+	}
+
+	task, err := scheduler.NewCronTask(
+		"global_notification_digest",
+		"实例事件邮件汇总任务",
+		"0 */5 * * * *", // 每 5 分钟执行一次
+		"notification_digest",
+		taskFunc,
+	)
+	if err != nil {
+		tm.logger.Error("创建邮件汇总任务失败", zap.Error(err))
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	if err := tm.scheduler.AddTask(task); err != nil {
+		tm.logger.Error("添加邮件汇总任务失败",
+			zap.String("task_id", task.GetID()),
+			zap.Error(err))
+		return fmt.Errorf("添加任务失败: %w", err)
+	}
+	return nil
+}
+
+// setupCostAggregationTask 每天凌晨汇总前一天托管实例的成本，任务未执行的日期在下次执行时补算
+func (tm *TaskManagerImpl) setupCostAggregationTask(owner string) error {
+	tm.costAggregationElector = redis.NewLeaderElector("market:cost_aggregation", owner, redis.DefaultLeaderLockTTL)
+
+	taskFunc := func(ctx context.Context) error {
+		leaderCtx, cancel, ok := tm.costAggregationElector.LeaderContext(ctx)
+		if !ok {
+			tm.logger.Debug("成本汇总任务锁由其他副本持有，跳过本次执行")
+			return nil
+		}
+		defer cancel()
+		return biz.GCostBiz.AggregateMissing(leaderCtx, time.Now())
+	}
+
+	task, err := scheduler.NewCronTask(
+		"global_cost_aggregation",
+		"托管实例成本汇总任务",
+		"0 30 0 * * *", // 每天 00:30 执行一次
+		"cost_aggregation",
+		taskFunc,
+	)
+	if err != nil {
+		tm.logger.Error("创建成本汇总任务失败", zap.Error(err))
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	if err := tm.scheduler.AddTask(task); err != nil {
+		tm.logger.Error("添加成本汇总任务失败",
+			zap.String("task_id", task.GetID()),
+			zap.Error(err))
+		return fmt.Errorf("添加任务失败: %w", err)
+	}
 	return nil
 }
 
@@ -103,6 +341,24 @@ func (tm *TaskManagerImpl) StartMonitoring(ctx context.Context) error {
 		return fmt.Errorf("启动调度器失败: %w", err)
 	}
 
+	// 竞争任务锁，停止时释放
+	electCtx, stopElectors := context.WithCancel(ctx)
+	tm.stopElectors = stopElectors
+	go tm.monitorElector.Run(electCtx)
+	go tm.iconCleanupElector.Run(electCtx)
+	go tm.healthMonitorElector.Run(electCtx)
+	go tm.tokenExpiryElector.Run(electCtx)
+	go tm.notificationDigestElector.Run(electCtx)
+	go tm.costAggregationElector.Run(electCtx)
+
+	// 启动 Pod 状态监听，只在持有任务锁期间运行
+	if tm.podWatcher != nil {
+		go tm.podWatcherElector.Run(electCtx)
+		watchCtx, cancel := context.WithCancel(ctx)
+		tm.stopPodWatcher = cancel
+		go tm.podWatcherElector.RunWhileLeader(watchCtx, tm.podWatcher.Run)
+	}
+
 	tm.isRunning = true
 	tm.logger.Info("任务监控启动成功")
 
@@ -118,6 +374,12 @@ func (tm *TaskManagerImpl) StopMonitoring(ctx context.Context) error {
 
 	tm.logger.Info("停止任务监控")
 
+	// 停止 Pod 状态监听
+	if tm.stopPodWatcher != nil {
+		tm.stopPodWatcher()
+		tm.stopPodWatcher = nil
+	}
+
 	// 停止调度器
 	err := tm.scheduler.Stop()
 	if err != nil {
@@ -125,6 +387,12 @@ func (tm *TaskManagerImpl) StopMonitoring(ctx context.Context) error {
 		return fmt.Errorf("停止调度器失败: %w", err)
 	}
 
+	// 释放任务锁，其他副本在下一次续期周期内接管
+	if tm.stopElectors != nil {
+		tm.stopElectors()
+		tm.stopElectors = nil
+	}
+
 	tm.isRunning = false
 	tm.logger.Info("任务监控停止成功")
 
@@ -140,3 +408,12 @@ func (tm *TaskManagerImpl) IsRunning() bool {
 func (tm *TaskManagerImpl) GetMonitorTaskID() string {
 	return tm.monitorTaskID
 }
+
+// jobLockOwner 任务锁持有者标识，Kubernetes 中主机名即 Pod 名称
+func jobLockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -0,0 +1,305 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"qm-mcp-server/internal/market/biz"
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/k8s"
+	"qm-mcp-server/pkg/redis"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// podWatchMinBackoff watch 断开后首次重连的等待时间
+const podWatchMinBackoff = time.Second
+
+// PodWatcher Pod 状态监听器
+// 只 watch 有启动中或未就绪托管实例的 Kubernetes 环境，Pod 状态变化时立即检查对应实例，
+// 不必等待每 30 秒一次的容器监控；watch 不可用或环境数超过上限时由定时监控兜底
+type PodWatcher struct {
+	instanceRepo *mysql.McpInstanceRepository
+	monitor      ContainerMonitor
+	logger       *zap.Logger
+	config       common.PodWatchConfig
+
+	mu sync.Mutex
+	// watches 环境ID -> 取消该环境的 watch
+	watches map[uint]context.CancelFunc
+	// pending 正在检查的实例，检查期间状态又发生变化时记录最新状态，检查完成后再检查一次
+	pending map[string]*podChange
+	// semaphore 限制同时检查的实例数
+	semaphore chan struct{}
+}
+
+// podChange 实例 Pod 的最新状态
+type podChange struct {
+	state, reason string
+	dirty         bool
+}
+
+// NewPodWatcher 创建 Pod 状态监听器
+func NewPodWatcher(instanceRepo *mysql.McpInstanceRepository, monitor ContainerMonitor, logger *zap.Logger, config common.PodWatchConfig) *PodWatcher {
+	return &PodWatcher{
+		instanceRepo: instanceRepo,
+		monitor:      monitor,
+		logger:       logger,
+		config:       config,
+		watches:      make(map[uint]context.CancelFunc),
+		pending:      make(map[string]*podChange),
+		semaphore:    make(chan struct{}, 10),
+	}
+}
+
+// Run 定期刷新需要 watch 的环境，直到 ctx 取消
+func (w *PodWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(w.config.ResyncInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		w.resync(ctx)
+		select {
+		case <-ctx.Done():
+			w.stopAll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resync 为有启动中实例的环境开启 watch，关闭不再需要的 watch
+func (w *PodWatcher) resync(ctx context.Context) {
+	instances, err := w.instanceRepo.FindByContainerStatus(ctx, []model.ContainerStatus{
+		model.ContainerStatusPending, model.ContainerStatusRunningUnready,
+	})
+	if err != nil {
+		w.logger.Warn("查询启动中的实例失败", zap.Error(err))
+		return
+	}
+	wanted := make(map[uint]bool)
+	for _, instance := range instances {
+		if instance.AccessType == model.AccessTypeHosting && instance.Status == model.InstanceStatusActive && instance.EnvironmentID > 0 {
+			wanted[instance.EnvironmentID] = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for envID, cancel := range w.watches {
+		if !wanted[envID] {
+			cancel()
+			delete(w.watches, envID)
+		}
+	}
+	envIDs := make([]uint, 0, len(wanted))
+	for envID := range wanted {
+		envIDs = append(envIDs, envID)
+	}
+	slices.Sort(envIDs)
+	skipped := 0
+	for _, envID := range envIDs {
+		if _, ok := w.watches[envID]; ok {
+			continue
+		}
+		if len(w.watches) >= w.config.MaxEnvironments {
+			skipped++
+			continue
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		w.watches[envID] = cancel
+		go w.watchEnvironment(watchCtx, envID)
+	}
+	if skipped > 0 {
+		w.logger.Debug("watch 环境数已达上限，其余环境由定时监控检查",
+			zap.Int("max_environments", w.config.MaxEnvironments),
+			zap.Int("skipped", skipped))
+	}
+}
+
+// stopAll 关闭所有 watch
+func (w *PodWatcher) stopAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for envID, cancel := range w.watches {
+		cancel()
+		delete(w.watches, envID)
+	}
+}
+
+// watchEnvironment 持续 watch 环境中平台管理的 Pod，断开后按指数退避重连
+func (w *PodWatcher) watchEnvironment(ctx context.Context, envID uint) {
+	maxBackoff := time.Duration(w.config.MaxBackoff) * time.Second
+	backoff := podWatchMinBackoff
+	// states 记录每个 Pod 最近的状态，重连后收到的 ADDED 事件不会重复触发检查
+	states := make(map[types.UID]string)
+	for {
+		err := w.watchOnce(ctx, envID, states)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.logger.Warn("Pod watch 失败，稍后重连，期间由定时监控检查",
+				zap.Uint("environment_id", envID),
+				zap.Duration("backoff", backoff),
+				zap.Error(err))
+		} else {
+			// 服务端正常关闭 watch，不增加退避
+			backoff = podWatchMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if err != nil {
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+// watchOnce 打开一次 watch 并处理事件，直到 watch 关闭或出错
+// 非 Kubernetes 环境不支持 watch，阻塞到 ctx 取消，避免每次刷新都重新尝试
+func (w *PodWatcher) watchOnce(ctx context.Context, envID uint, states map[types.UID]string) error {
+	environment, err := biz.GEnvironmentBiz.GetEnvironment(ctx, envID)
+	if err != nil {
+		return err
+	}
+	if environment.Environment != model.McpEnvironmentKubernetes {
+		<-ctx.Done()
+		return nil
+	}
+	entry, err := biz.GContainerBiz.GetRuntimeEntry(ctx, envID)
+	if err != nil {
+		return err
+	}
+	runtime := entry.GetK8sRuntime()
+	if runtime == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	selector := labels.Set{"managed-by": common.SourceServerName}.String()
+	watcher, err := runtime.Entry.Pod.Watch(ctx, selector)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if event.Type == watch.Error {
+				return apierrors.FromObject(event.Object)
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			if event.Type == watch.Deleted {
+				delete(states, pod.UID)
+				continue
+			}
+			state, reason := k8s.PodState(pod)
+			if states[pod.UID] == state {
+				continue
+			}
+			states[pod.UID] = state
+			w.check(ctx, pod.Labels["instance"], state, reason)
+		}
+	}
+}
+
+// check 异步检查实例，同一实例同时只有一个检查，检查期间的状态变化合并为一次重新检查
+func (w *PodWatcher) check(ctx context.Context, instanceID, state, reason string) {
+	if instanceID == "" {
+		return
+	}
+	w.mu.Lock()
+	if change, running := w.pending[instanceID]; running {
+		change.state, change.reason, change.dirty = state, reason, true
+		w.mu.Unlock()
+		return
+	}
+	change := &podChange{state: state, reason: reason}
+	w.pending[instanceID] = change
+	w.mu.Unlock()
+
+	go func() {
+		for {
+			w.checkInstance(ctx, instanceID, state, reason)
+			w.mu.Lock()
+			if !change.dirty {
+				delete(w.pending, instanceID)
+				w.mu.Unlock()
+				return
+			}
+			state, reason, change.dirty = change.state, change.reason, false
+			w.mu.Unlock()
+		}
+	}()
+}
+
+// checkInstance 使用容器监控检查实例状态，Pod 崩溃或失败时立即更新实例的最近消息
+func (w *PodWatcher) checkInstance(ctx context.Context, instanceID, state, reason string) {
+	select {
+	case w.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-w.semaphore }()
+
+	instance, err := w.instanceRepo.FindByInstanceID(ctx, instanceID)
+	if err != nil {
+		w.logger.Debug("Pod 对应的实例不存在", zap.String("instance_id", instanceID), zap.Error(err))
+		return
+	}
+	if instance.AccessType != model.AccessTypeHosting || instance.Status != model.InstanceStatusActive {
+		return
+	}
+
+	w.logger.Debug("Pod 状态变化，检查实例",
+		zap.String("instance_id", instanceID),
+		zap.String("pod_state", state))
+	if err := w.monitor.CheckContainer(ctx, instance); err != nil {
+		w.logger.Warn("检查容器失败",
+			zap.String("instance_id", instanceID),
+			zap.Error(err))
+		return
+	}
+
+	// 启动中的实例崩溃时定时监控只会等待超时，这里先把原因写入实例
+	if state != k8s.PodStateCrashLoop && state != k8s.PodStateFailed {
+		return
+	}
+	if instance.ContainerStatus != model.ContainerStatusPending && instance.ContainerStatus != model.ContainerStatusRunningUnready {
+		return
+	}
+	message := fmt.Sprintf("Pod %s: %s", state, reason)
+	if instance.ContainerLastMessage == message {
+		return
+	}
+	instance.ContainerLastMessage = message
+	if err := redis.VerifyLock(ctx); err != nil {
+		return
+	}
+	if err := w.instanceRepo.Update(ctx, instance); err != nil {
+		w.logger.Warn("更新实例状态失败", zap.String("instance_id", instanceID), zap.Error(err))
+		return
+	}
+	biz.GInstanceOperationBiz.Record(ctx, instanceID, model.InstanceOperationCrash, message)
+}
@@ -0,0 +1,260 @@
+package mcpcanctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"qm-mcp-server/api/authz/user_auth"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/utils"
+)
+
+// defaultTimeout 单个请求超时，重启等待就绪等长请求单独设置
+const defaultTimeout = 60 * time.Second
+
+// APIError 接口返回的业务错误
+type APIError struct {
+	Status    int
+	Code      int
+	Message   string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s (status %d, code %d)", e.Message, e.Status, e.Code)
+	if e.RequestID != "" {
+		msg += ", request id " + e.RequestID
+	}
+	return msg
+}
+
+// envelope market 和 authz 的统一响应结构
+type envelope struct {
+	Code      int             `json:"code"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"requestId"`
+}
+
+// Client market 和 authz 接口客户端，请求和响应使用 proto 生成的类型
+type Client struct {
+	cfg  *Config
+	http *http.Client
+	// loggedIn 本次运行已通过用户名密码登录，避免重复登录
+	loggedIn bool
+}
+
+// NewClient 创建客户端
+func NewClient(cfg *Config) *Client {
+	return &Client{cfg: cfg, http: &http.Client{}}
+}
+
+// marketURL market 接口地址
+func (c *Client) marketURL(path string) string {
+	return c.cfg.Server + "/" + strings.Trim(c.cfg.MarketPrefix, "/") + path
+}
+
+// authzURL authz 接口地址
+func (c *Client) authzURL(path string) string {
+	return c.cfg.Server + "/" + strings.Trim(c.cfg.AuthzPrefix, "/") + path
+}
+
+// Market 调用 market 接口，body 为 nil 时不发送请求体
+func (c *Client) Market(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	return c.call(ctx, method, c.marketURL(path), query, body, out)
+}
+
+// MarketRaw 以原始请求体调用 market 接口，用于提交 YAML 清单等非 JSON 内容
+func (c *Client) MarketRaw(ctx context.Context, method, path string, query url.Values, contentType string, payload []byte, out any) error {
+	return c.callRaw(ctx, method, c.marketURL(path), query, contentType, payload, out)
+}
+
+// call 发送 JSON 请求，令牌过期且配置了用户名密码时重新登录后重试一次
+func (c *Client) call(ctx context.Context, method, rawURL string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.callRaw(ctx, method, rawURL, query, "application/json", payload, out)
+}
+
+// callRaw 发送请求，payload 为 nil 时不发送请求体
+func (c *Client) callRaw(ctx context.Context, method, rawURL string, query url.Values, contentType string, payload []byte, out any) error {
+	build := func() (*http.Request, error) {
+		target := rawURL
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, reader)
+		if err != nil {
+			return nil, err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req, nil
+	}
+	return c.send(ctx, build, out)
+}
+
+// Upload 以 multipart 表单上传文件到 market 接口
+func (c *Client) Upload(ctx context.Context, path, field, file string, out any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	build := func() (*http.Request, error) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreateFormFile(field, filepath.Base(file))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.marketURL(path), &buf)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, nil
+	}
+	return c.send(ctx, build, out)
+}
+
+// send 附加令牌后发送请求
+func (c *Client) send(ctx context.Context, build func() (*http.Request, error), out any) error {
+	if c.cfg.Token == "" && c.canLogin() {
+		if err := c.loginWithPassword(ctx); err != nil {
+			return err
+		}
+	}
+	req, err := build()
+	if err != nil {
+		return err
+	}
+	err = c.do(req, out)
+	if apiErr, ok := err.(*APIError); ok && apiErr.Status == http.StatusUnauthorized && c.canLogin() {
+		// 令牌过期时使用配置的用户名密码重新登录
+		if err := c.loginWithPassword(ctx); err != nil {
+			return err
+		}
+		if req, err = build(); err != nil {
+			return err
+		}
+		return c.do(req, out)
+	}
+	return err
+}
+
+// do 发送请求并将响应 data 解析到 out
+func (c *Client) do(req *http.Request, out any) error {
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	if _, ok := req.Context().Deadline(); !ok {
+		ctx, cancel := context.WithTimeout(req.Context(), defaultTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return &APIError{Status: resp.StatusCode, Code: -1, Message: strings.TrimSpace(string(data))}
+	}
+	if resp.StatusCode >= http.StatusBadRequest || env.Code != i18n.CodeSuccess {
+		return &APIError{Status: resp.StatusCode, Code: env.Code, Message: env.Message, RequestID: env.RequestID}
+	}
+	if out == nil || len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+// canLogin 是否配置了用户名密码且本次运行尚未登录
+func (c *Client) canLogin() bool {
+	return c.cfg.Username != "" && c.cfg.Password != "" && !c.loggedIn
+}
+
+func (c *Client) loginWithPassword(ctx context.Context) error {
+	c.loggedIn = true
+	resp, err := c.Login(ctx, c.cfg.Username, c.cfg.Password)
+	if err != nil {
+		return fmt.Errorf("login as %s failed: %w", c.cfg.Username, err)
+	}
+	c.cfg.Token = resp.Token
+	c.cfg.RefreshToken = resp.RefreshToken
+	return nil
+}
+
+// Login 用户名密码登录，密码使用 authz 下发的 RSA 公钥加密，与前端登录流程一致
+func (c *Client) Login(ctx context.Context, username, password string) (*user_auth.LoginResponse, error) {
+	var key user_auth.GetEncryptionKeyResponse
+	if err := c.call(ctx, http.MethodPost, c.authzURL("/encryption-key"), nil, &user_auth.GetEncryptionKeyRequest{}, &key); err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	publicKey, err := utils.ParsePublicKeyFromBase64(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.RSAEncrypt([]byte(password), publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &user_auth.LoginRequest{
+		Username:          username,
+		EncryptedPassword: encrypted,
+		KeyId:             key.KeyId,
+		Timestamp:         time.Now().UnixMilli(),
+	}
+	var resp user_auth.LoginResponse
+	if err := c.call(ctx, http.MethodPost, c.authzURL("/login"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ValidateToken 校验令牌（API key），返回令牌所属用户
+func (c *Client) ValidateToken(ctx context.Context, token string) (*user_auth.ValidateTokenResponse, error) {
+	var resp user_auth.ValidateTokenResponse
+	req := &user_auth.ValidateTokenRequest{Token: token}
+	if err := c.call(ctx, http.MethodPost, c.authzURL("/validate"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Valid {
+		return nil, fmt.Errorf("token is invalid or expired")
+	}
+	return &resp, nil
+}
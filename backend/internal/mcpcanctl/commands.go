@@ -0,0 +1,763 @@
+// Package mcpcanctl 命令行客户端，调用 market 和 authz 接口完成常用运维操作
+package mcpcanctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/api/market/code"
+	"qm-mcp-server/api/market/instance"
+	"qm-mcp-server/api/market/mcp_environment"
+	"qm-mcp-server/pkg/utils"
+)
+
+const usage = `mcpcanctl 管理 MCPBox 实例、模板、环境和代码包
+
+用法:
+  mcpcanctl [--config 文件] [--server 地址] [-o table|json] <命令> [参数]
+
+命令:
+  login [--token KEY | --username 用户名 --password 密码] [--save-password]
+  instance list [--env ID] [--name 关键词] [--status 状态] [-l 标签选择器] [--page N] [--page-size N]
+  instance create --template ID [--env ID] [--name 名称] | --file create.json
+  instance delete <实例ID>
+  instance restart <实例ID> [--wait] [--force]
+  instance logs <实例ID> [--lines N] [--follow] [--interval 2s] [--previous] [--container 名称]
+  template list [--name 关键词] [--page N] [--page-size N]
+  template export <模板ID> [--file template.json]
+  template import <template.json> [--env ID] [--name 名称]
+  env list [--page N] [--page-size N]
+  env test <环境ID>
+  code upload <代码包文件>
+  apply <清单文件> [--dry-run] [--prune]
+  validate -f <配置文件> [--probe] [--timeout 10s]
+`
+
+// restartWaitTimeout 重启等待就绪的请求超时，服务端最多等待 10 分钟
+const restartWaitTimeout = 11 * time.Minute
+
+// cli 一次命令执行的上下文
+type cli struct {
+	configPath string
+	cfg        *Config
+	client     *Client
+	out        *printer
+}
+
+// command 子命令处理函数
+type command func(c *cli, ctx context.Context, args []string) error
+
+var commands = map[string]map[string]command{
+	"instance": {
+		"list":    (*cli).instanceList,
+		"create":  (*cli).instanceCreate,
+		"delete":  (*cli).instanceDelete,
+		"restart": (*cli).instanceRestart,
+		"logs":    (*cli).instanceLogs,
+	},
+	"template": {
+		"list":   (*cli).templateList,
+		"export": (*cli).templateExport,
+		"import": (*cli).templateImport,
+	},
+	"env": {
+		"list": (*cli).envList,
+		"test": (*cli).envTest,
+	},
+	"code": {
+		"upload": (*cli).codeUpload,
+	},
+}
+
+// Run 解析全局参数并执行子命令
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	global := flag.NewFlagSet("mcpcanctl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	configPath := global.String("config", DefaultConfigPath(), "配置文件路径")
+	server := global.String("server", "", "MCPBox 访问地址，覆盖配置文件")
+	output := global.String("o", OutputTable, "输出格式 table|json")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *server != "" {
+		cfg.Server = strings.TrimSuffix(*server, "/")
+	}
+	c := &cli{
+		configPath: *configPath,
+		cfg:        cfg,
+		client:     NewClient(cfg),
+		out:        &printer{format: *output, w: stdout},
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		global.Usage()
+		return flag.ErrHelp
+	}
+	switch rest[0] {
+	case "login":
+		return c.login(ctx, rest[1:], *server)
+	case "apply":
+		return c.apply(ctx, rest[1:])
+	case "validate":
+		return c.validate(ctx, rest[1:])
+	}
+	group, ok := commands[rest[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run mcpcanctl --help", rest[0])
+	}
+	if len(rest) < 2 || group[rest[1]] == nil {
+		return fmt.Errorf("unknown %s command, run mcpcanctl --help", rest[0])
+	}
+	return group[rest[1]](c, ctx, rest[2:])
+}
+
+// flags 子命令参数，-o 在子命令后也可使用
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	fs.StringVar(&c.out.format, "o", c.out.format, "输出格式 table|json")
+	return fs
+}
+
+// parseFlags 解析参数，允许参数出现在位置参数之后，返回位置参数
+func parseFlags(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	var values []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		values = append(values, args[0])
+		args = args[1:]
+	}
+	if len(values) != positional {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", fs.Name(), positional, len(values))
+	}
+	return values, nil
+}
+
+func parseID(name, value string) (int32, error) {
+	id, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return int32(id), nil
+}
+
+// formatMillis 毫秒时间戳转本地时间，0 表示未知
+func formatMillis(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return time.UnixMilli(ms).Format("2006-01-02 15:04:05")
+}
+
+func (c *cli) login(ctx context.Context, args []string, server string) error {
+	fs := c.flags("login")
+	token := fs.String("token", "", "API key 或访问令牌，校验通过后写入配置文件")
+	username := fs.String("username", c.cfg.Username, "用户名")
+	password := fs.String("password", c.cfg.Password, "密码，也可通过 MCPCANCTL_PASSWORD 提供")
+	savePassword := fs.Bool("save-password", false, "将用户名和密码写入配置文件，令牌过期后自动重新登录")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	// 只写回配置文件中的内容和本次登录结果，不带入环境变量中的凭据
+	fileCfg, err := loadFile(c.configPath)
+	if err != nil {
+		return err
+	}
+	if server != "" {
+		fileCfg.Server = c.cfg.Server
+	}
+
+	var user string
+	if *token != "" {
+		resp, err := c.client.ValidateToken(ctx, *token)
+		if err != nil {
+			return err
+		}
+		fileCfg.Token, fileCfg.RefreshToken = *token, ""
+		if resp.UserInfo != nil {
+			user = resp.UserInfo.Username
+		}
+	} else {
+		if *username == "" || *password == "" {
+			return errors.New("login requires --token, or --username and --password")
+		}
+		resp, err := c.client.Login(ctx, *username, *password)
+		if err != nil {
+			return err
+		}
+		fileCfg.Token, fileCfg.RefreshToken = resp.Token, resp.RefreshToken
+		user = *username
+		if *savePassword {
+			fileCfg.Username, fileCfg.Password = *username, *password
+		}
+	}
+	if err := fileCfg.Save(c.configPath); err != nil {
+		return err
+	}
+	return c.out.message(map[string]string{"username": user, "config": c.configPath},
+		"Logged in as %s, credentials saved to %s", user, c.configPath)
+}
+
+func (c *cli) instanceList(ctx context.Context, args []string) error {
+	fs := c.flags("instance list")
+	env := fs.Int("env", 0, "环境ID")
+	name := fs.String("name", "", "实例名称或ID关键词")
+	status := fs.String("status", "", "实例状态 active|inactive")
+	selector := fs.String("l", "", "标签选择器，如 team=data,env!=prod,cost-center")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页数量")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	req := &instance.ListRequest{
+		Page:          int32(*page),
+		PageSize:      int32(*pageSize),
+		InstanceName:  *name,
+		EnvironmentId: int32(*env),
+		Status:        *status,
+		LabelSelector: *selector,
+	}
+	var resp instance.ListResp
+	if err := c.client.Market(ctx, http.MethodPost, "/instance/list", nil, req, &resp); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.List))
+	for _, item := range resp.List {
+		rows = append(rows, []string{
+			item.InstanceId,
+			item.InstanceName,
+			item.EnvironmentName,
+			item.McpProtocol.String(),
+			item.AccessType.String(),
+			item.Status,
+			item.ContainerStatus,
+			strconv.FormatBool(item.ContainerIsReady),
+			formatMillis(item.CreatedAtMs),
+		})
+	}
+	return c.out.print(&resp, []string{"ID", "NAME", "ENV", "PROTOCOL", "ACCESS", "STATUS", "CONTAINER", "READY", "CREATED"}, rows)
+}
+
+func (c *cli) instanceCreate(ctx context.Context, args []string) error {
+	fs := c.flags("instance create")
+	templateID := fs.Int("template", 0, "模板ID，使用模板配置创建实例")
+	env := fs.Int("env", 0, "环境ID，默认使用模板的环境")
+	name := fs.String("name", "", "实例名称，默认使用模板名称")
+	file := fs.String("file", "", "创建请求 JSON 文件，字段与 /instance/create 接口一致")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	req := &instance.CreateRequest{}
+	switch {
+	case *file != "":
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, req); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", *file, err)
+		}
+	case *templateID > 0:
+		tpl, err := c.template(ctx, int32(*templateID))
+		if err != nil {
+			return err
+		}
+		req = createRequestFromTemplate(tpl)
+	default:
+		return errors.New("instance create requires --template or --file")
+	}
+	if *env > 0 {
+		req.EnvironmentId = int32(*env)
+	}
+	if *name != "" {
+		req.Name = *name
+	}
+
+	var resp instance.CreateResp
+	if err := c.client.Market(ctx, http.MethodPost, "/instance/create", nil, req, &resp); err != nil {
+		return err
+	}
+	return c.out.message(&resp, "Instance %s (%s) created, status %s", resp.InstanceId, resp.Name, resp.Status)
+}
+
+// createRequestFromTemplate 按模板配置填充创建请求，与控制台从模板创建实例一致
+func createRequestFromTemplate(tpl *instance.TemplateDetailResp) *instance.CreateRequest {
+	return &instance.CreateRequest{
+		Name:                 tpl.Name,
+		Port:                 tpl.Port,
+		InitScript:           tpl.InitScript,
+		Command:              tpl.Command,
+		EnvironmentVariables: tpl.EnvironmentVariables,
+		VolumeMounts:         tpl.VolumeMounts,
+		StartupTimeout:       tpl.StartupTimeout,
+		RunningTimeout:       tpl.RunningTimeout,
+		EnvironmentId:        tpl.EnvironmentId,
+		PackageId:            tpl.PackageId,
+		AccessType:           tpl.AccessType,
+		McpServers:           tpl.McpServers,
+		ImgAddress:           tpl.ImgAddress,
+		SourceType:           instance.SourceType_TEMPLATE,
+		McpServerId:          tpl.McpServerId,
+		TemplateId:           tpl.TemplateId,
+		Tokens:               tpl.Tokens,
+		Notes:                tpl.Notes,
+		McpProtocol:          tpl.McpProtocol,
+		ServicePath:          tpl.ServicePath,
+		IconPath:             tpl.IconPath,
+		Metadata:             tpl.Metadata,
+	}
+}
+
+func (c *cli) instanceDelete(ctx context.Context, args []string) error {
+	values, err := parseFlags(c.flags("instance delete"), args, 1)
+	if err != nil {
+		return err
+	}
+	var resp instance.DeleteResp
+	if err := c.client.Market(ctx, http.MethodDelete, "/instance/"+url.PathEscape(values[0]), nil, nil, &resp); err != nil {
+		return err
+	}
+	return c.out.message(&resp, "Instance %s deleted", values[0])
+}
+
+func (c *cli) instanceRestart(ctx context.Context, args []string) error {
+	fs := c.flags("instance restart")
+	wait := fs.Bool("wait", false, "等待实例就绪，超时未就绪时返回告警事件")
+	force := fs.Bool("force", false, "镜像标签已指向新的摘要时仍然重启，使用新的镜像")
+	values, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *wait {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, restartWaitTimeout)
+		defer cancel()
+	}
+
+	req := &instance.RestartRequest{InstanceId: values[0], Wait: *wait, Force: *force}
+	var resp instance.RestartResp
+	if err := c.client.Market(ctx, http.MethodPut, "/instance/restart", nil, req, &resp); err != nil {
+		return err
+	}
+	if err := c.out.message(&resp, "Instance %s restarted, container %s: %s", resp.InstanceId, resp.ContainerStatus, resp.Message); err != nil {
+		return err
+	}
+	if *wait && !resp.Ready {
+		if c.out.format == OutputTable {
+			for _, event := range resp.WarningEvents {
+				fmt.Fprintf(c.out.w, "  %s %s: %s\n", event.Type, event.Reason, event.Message)
+			}
+		}
+		return fmt.Errorf("instance %s is not ready", resp.InstanceId)
+	}
+	return nil
+}
+
+func (c *cli) instanceLogs(ctx context.Context, args []string) error {
+	fs := c.flags("instance logs")
+	lines := fs.Int("lines", 100, "日志行数")
+	follow := fs.Bool("follow", false, "持续输出新日志，Ctrl+C 退出")
+	interval := fs.Duration("interval", 2*time.Second, "--follow 时的刷新间隔")
+	previous := fs.Bool("previous", false, "输出上一个已终止容器的日志，用于查看崩溃重启前的输出")
+	containerName := fs.String("container", "", "边车容器名称，all 输出所有容器的日志，默认为主容器")
+	values, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *previous && *follow {
+		return fmt.Errorf("--previous cannot be used with --follow")
+	}
+
+	req := &instance.LogsRequest{InstanceId: values[0], Lines: int32(*lines), Previous: *previous, Container: *containerName}
+	fetch := func() (*instance.LogsResp, error) {
+		var resp instance.LogsResp
+		if err := c.client.Market(ctx, http.MethodPost, "/instance/logs", nil, req, &resp); err != nil {
+			return nil, err
+		}
+		if !resp.IsManaged {
+			return nil, fmt.Errorf("instance %s is not hosted, logs are not available: %s", resp.InstanceId, resp.Message)
+		}
+		return &resp, nil
+	}
+
+	resp, err := fetch()
+	if err != nil {
+		return err
+	}
+	if !*follow {
+		if c.out.format == OutputJSON {
+			return c.out.json(resp)
+		}
+		_, err := io.WriteString(c.out.w, resp.Logs)
+		return err
+	}
+
+	// 日志接口只返回最近的若干行，轮询时只输出新增的行
+	var prev []string
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		cur := splitLines(resp.Logs)
+		for _, line := range newLines(prev, cur) {
+			fmt.Fprintln(c.out.w, line)
+		}
+		prev = cur
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if resp, err = fetch(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func splitLines(logs string) []string {
+	logs = strings.TrimRight(logs, "\n")
+	if logs == "" {
+		return nil
+	}
+	return strings.Split(logs, "\n")
+}
+
+// newLines 返回 cur 中 prev 之后新增的行：取 prev 的后缀与 cur 的前缀最长重叠部分，
+// 没有重叠时（日志增长超过窗口或容器已重建）返回 cur 全部
+func newLines(prev, cur []string) []string {
+	for k := min(len(prev), len(cur)); k > 0; k-- {
+		if equalLines(prev[len(prev)-k:], cur[:k]) {
+			return cur[k:]
+		}
+	}
+	return cur
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// template 获取模板详情
+func (c *cli) template(ctx context.Context, id int32) (*instance.TemplateDetailResp, error) {
+	var tpl instance.TemplateDetailResp
+	if err := c.client.Market(ctx, http.MethodGet, fmt.Sprintf("/template/%d", id), nil, nil, &tpl); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (c *cli) templateList(ctx context.Context, args []string) error {
+	fs := c.flags("template list")
+	name := fs.String("name", "", "模板名称关键词")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页数量")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	req := &instance.TemplateListRequest{Page: int32(*page), PageSize: int32(*pageSize), Name: *name}
+	var resp instance.TemplateListResp
+	if err := c.client.Market(ctx, http.MethodPost, "/template/list", nil, req, &resp); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.List))
+	for _, tpl := range resp.List {
+		rows = append(rows, []string{
+			strconv.Itoa(int(tpl.TemplateId)),
+			tpl.Name,
+			tpl.EnvironmentName,
+			tpl.McpProtocol.String(),
+			tpl.AccessType.String(),
+			formatMillis(tpl.UpdatedAtMs),
+		})
+	}
+	return c.out.print(&resp, []string{"ID", "NAME", "ENV", "PROTOCOL", "ACCESS", "UPDATED"}, rows)
+}
+
+// templateExport 导出为模板创建请求，可直接用于 template import
+func (c *cli) templateExport(ctx context.Context, args []string) error {
+	fs := c.flags("template export")
+	file := fs.String("file", "", "输出文件，默认输出到标准输出")
+	values, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("template id", values[0])
+	if err != nil {
+		return err
+	}
+	tpl, err := c.template(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	req := &instance.TemplateCreateRequest{
+		Name:                 tpl.Name,
+		Port:                 tpl.Port,
+		InitScript:           tpl.InitScript,
+		Command:              tpl.Command,
+		EnvironmentVariables: tpl.EnvironmentVariables,
+		VolumeMounts:         tpl.VolumeMounts,
+		StartupTimeout:       tpl.StartupTimeout,
+		RunningTimeout:       tpl.RunningTimeout,
+		EnvironmentId:        tpl.EnvironmentId,
+		PackageId:            tpl.PackageId,
+		AccessType:           tpl.AccessType,
+		McpServers:           tpl.McpServers,
+		ImgAddress:           tpl.ImgAddress,
+		McpServerId:          tpl.McpServerId,
+		Tokens:               tpl.Tokens,
+		Notes:                tpl.Notes,
+		McpProtocol:          tpl.McpProtocol,
+		IconPath:             tpl.IconPath,
+		Metadata:             tpl.Metadata,
+	}
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *file == "" {
+		_, err := c.out.w.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*file, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out.w, "Template %d exported to %s\n", id, *file)
+	return nil
+}
+
+func (c *cli) templateImport(ctx context.Context, args []string) error {
+	fs := c.flags("template import")
+	env := fs.Int("env", 0, "环境ID，覆盖导出文件中的环境")
+	name := fs.String("name", "", "模板名称，覆盖导出文件中的名称")
+	values, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(values[0])
+	if err != nil {
+		return err
+	}
+	req := &instance.TemplateCreateRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", values[0], err)
+	}
+	if *env > 0 {
+		req.EnvironmentId = int32(*env)
+	}
+	if *name != "" {
+		req.Name = *name
+	}
+
+	var resp instance.TemplateCreateResp
+	if err := c.client.Market(ctx, http.MethodPost, "/template/create", nil, req, &resp); err != nil {
+		return err
+	}
+	return c.out.message(&resp, "Template %s imported as %d", req.Name, resp.TemplateId)
+}
+
+func (c *cli) envList(ctx context.Context, args []string) error {
+	fs := c.flags("env list")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页数量")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{"page": {strconv.Itoa(*page)}, "pageSize": {strconv.Itoa(*pageSize)}}
+	var resp mcp_environment.ListEnvironmentsResponse
+	if err := c.client.Market(ctx, http.MethodGet, "/environments", query, nil, &resp); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.List))
+	for _, env := range resp.List {
+		rows = append(rows, []string{
+			strconv.Itoa(int(env.Id)),
+			env.Name,
+			env.Environment,
+			env.Namespace,
+			formatMillis(env.CreatedAtMs),
+		})
+	}
+	return c.out.print(&resp, []string{"ID", "NAME", "TYPE", "NAMESPACE", "CREATED"}, rows)
+}
+
+func (c *cli) envTest(ctx context.Context, args []string) error {
+	values, err := parseFlags(c.flags("env test"), args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("environment id", values[0])
+	if err != nil {
+		return err
+	}
+	var resp mcp_environment.TestConnectivityResponse
+	if err := c.client.Market(ctx, http.MethodPost, fmt.Sprintf("/environments/%d/test", id), nil, &mcp_environment.TestConnectivityRequest{Id: id}, &resp); err != nil {
+		return err
+	}
+	if err := c.out.message(&resp, "Environment %d: %s", id, resp.Message); err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("environment %d is not reachable", id)
+	}
+	return nil
+}
+
+func (c *cli) codeUpload(ctx context.Context, args []string) error {
+	values, err := parseFlags(c.flags("code upload"), args, 1)
+	if err != nil {
+		return err
+	}
+	var resp code.UploadPackageResponse
+	if err := c.client.Upload(ctx, "/code/upload", "file", values[0], &resp); err != nil {
+		return err
+	}
+	return c.out.message(&resp, "Package uploaded, id %s", resp.PackageId)
+}
+
+// apply 提交声明式清单，YAML 和 JSON 均可，存在失败的动作时返回错误
+func (c *cli) apply(ctx context.Context, args []string) error {
+	fs := c.flags("apply")
+	dryRun := fs.Bool("dry-run", false, "只输出执行计划，不做任何修改")
+	prune := fs.Bool("prune", false, "删除清单中未声明的模板和实例")
+	values, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(values[0])
+	if err != nil {
+		return err
+	}
+	contentType := "application/yaml"
+	if strings.EqualFold(filepath.Ext(values[0]), ".json") {
+		contentType = "application/json"
+	}
+
+	query := url.Values{"dryRun": {strconv.FormatBool(*dryRun)}, "prune": {strconv.FormatBool(*prune)}}
+	var resp instance.ApplyResp
+	if err := c.client.MarketRaw(ctx, http.MethodPost, "/apply", query, contentType, data, &resp); err != nil {
+		return err
+	}
+
+	if c.out.format == OutputJSON {
+		if err := c.out.json(&resp); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, 0, len(resp.Actions))
+		for _, action := range resp.Actions {
+			id, changes, result := action.Id, strings.Join(action.Changes, ","), "ok"
+			if id == "" {
+				id = "-"
+			}
+			if changes == "" {
+				changes = "-"
+			}
+			if action.Error != "" {
+				result = action.Error
+			} else if resp.DryRun {
+				result = "planned"
+			}
+			rows = append(rows, []string{action.Kind, action.Name, action.Action, id, changes, result})
+		}
+		if err := c.out.table([]string{"KIND", "NAME", "ACTION", "ID", "CHANGES", "RESULT"}, rows); err != nil {
+			return err
+		}
+		summary := "Applied"
+		if resp.DryRun {
+			summary = "Plan"
+		}
+		fmt.Fprintf(c.out.w, "\n%s: %d create, %d update, %d delete, %d unchanged, %d failed\n",
+			summary, resp.Created, resp.Updated, resp.Deleted, resp.Unchanged, resp.Failed)
+	}
+	if resp.Failed > 0 {
+		return fmt.Errorf("%d action(s) failed", resp.Failed)
+	}
+	return nil
+}
+
+// validate 在本地检查 mcpServers 配置，不需要登录，存在 error 级别的结果时返回错误
+func (c *cli) validate(ctx context.Context, args []string) error {
+	fs := c.flags("validate")
+	file := fs.String("f", "", "mcpServers 配置文件，- 表示标准输入")
+	probe := fs.Bool("probe", false, "对使用 URL 的服务执行 MCP 初始化握手，检查地址是否可达")
+	timeout := fs.Duration("timeout", 10*time.Second, "--probe 时每个服务的超时时间")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("validate requires -f <file>")
+	}
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := utils.LintMcpConfig(ctx, data, utils.McpLintOptions{Probe: *probe, ProbeTimeout: *timeout})
+	errorCount := result.Errors()
+	if c.out.format == OutputJSON {
+		if err := c.out.json(result); err != nil {
+			return err
+		}
+	} else {
+		if len(result.Findings) > 0 {
+			rows := make([][]string, 0, len(result.Findings))
+			for _, f := range result.Findings {
+				rows = append(rows, []string{f.Severity, f.Path, f.Message})
+			}
+			if err := c.out.table([]string{"SEVERITY", "PATH", "MESSAGE"}, rows); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.out.w)
+		}
+		fmt.Fprintf(c.out.w, "%s: %d server(s), %d error(s), %d warning(s)\n",
+			*file, len(result.Servers), errorCount, len(result.Findings)-errorCount)
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%s: %d error(s)", *file, errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package mcpcanctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewLines(t *testing.T) {
+	tests := []struct {
+		name string
+		prev []string
+		cur  []string
+		want []string
+	}{
+		{"first fetch", nil, []string{"a", "b"}, []string{"a", "b"}},
+		{"no change", []string{"a", "b"}, []string{"a", "b"}, []string{}},
+		{"appended", []string{"a", "b"}, []string{"a", "b", "c"}, []string{"c"}},
+		{"window moved", []string{"a", "b", "c"}, []string{"b", "c", "d"}, []string{"d"}},
+		{"repeated lines", []string{"x", "x"}, []string{"x", "x", "x"}, []string{"x"}},
+		{"no overlap", []string{"a", "b"}, []string{"c", "d"}, []string{"c", "d"}},
+	}
+	for _, tt := range tests {
+		got := newLines(tt.prev, tt.cur)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	fs := flag.NewFlagSet("instance restart", flag.ContinueOnError)
+	wait := fs.Bool("wait", false, "")
+	values, err := parseFlags(fs, []string{"abc", "--wait"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*wait || len(values) != 1 || values[0] != "abc" {
+		t.Errorf("got wait=%v values=%v", *wait, values)
+	}
+	if _, err := parseFlags(flag.NewFlagSet("env test", flag.ContinueOnError), nil, 1); err == nil {
+		t.Error("expected an error for a missing argument")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	valid := write("valid.json", `{"mcpServers":{"github":{"url":"https://example.com/mcp"}}}`)
+	invalid := write("invalid.json", `{"mcpServers":{"github":{"type":"sse"}}}`)
+	cfg := filepath.Join(dir, "mcpcanctl.yaml")
+
+	var out bytes.Buffer
+	if err := Run(context.Background(), []string{"--config", cfg, "validate", "-f", valid}, &out); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+	if !strings.Contains(out.String(), "0 error(s)") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	out.Reset()
+	if err := Run(context.Background(), []string{"--config", cfg, "-o", "json", "validate", "-f", invalid}, &out); err == nil {
+		t.Fatal("expected an error for an invalid config")
+	}
+	var result struct {
+		Valid    bool `json:"valid"`
+		Findings []struct {
+			Severity string `json:"severity"`
+			Path     string `json:"path"`
+		} `json:"findings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out.String(), err)
+	}
+	if result.Valid || len(result.Findings) != 1 || result.Findings[0].Path != "mcpServers.github.url" {
+		t.Errorf("result = %+v", result)
+	}
+}
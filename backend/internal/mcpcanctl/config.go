@@ -0,0 +1,108 @@
+package mcpcanctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qm-mcp-server/pkg/common"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultConfigFile 配置文件默认位于用户主目录
+	defaultConfigFile = ".mcpcanctl.yaml"
+	defaultServer     = "http://localhost:8080"
+)
+
+// Config mcpcanctl 配置，环境变量 MCPCANCTL_SERVER / MCPCANCTL_TOKEN /
+// MCPCANCTL_USERNAME / MCPCANCTL_PASSWORD 优先于配置文件
+type Config struct {
+	// Server MCPBox 访问地址，market 和 authz 通过同一入口访问
+	Server string `yaml:"server"`
+	// MarketPrefix market 路由前缀，与服务端 MCP_MARKET_SERVER_PREFIX 保持一致
+	MarketPrefix string `yaml:"marketPrefix,omitempty"`
+	// AuthzPrefix authz 路由前缀，与服务端 MCP_AUTHZ_SERVER_PREFIX 保持一致
+	AuthzPrefix string `yaml:"authzPrefix,omitempty"`
+	// Token 访问令牌，login 后写入，也可直接配置 API key
+	Token string `yaml:"token,omitempty"`
+	// RefreshToken 刷新令牌，login 后写入
+	RefreshToken string `yaml:"refreshToken,omitempty"`
+	// Username、Password 未配置令牌或令牌过期时用于自动登录
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// DefaultConfigPath 配置文件路径，可通过 MCPCANCTL_CONFIG 指定
+func DefaultConfigPath() string {
+	if path := os.Getenv("MCPCANCTL_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultConfigFile
+	}
+	return filepath.Join(home, defaultConfigFile)
+}
+
+// LoadConfig 加载配置文件并应用环境变量和默认值，文件不存在时使用默认配置
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for env, field := range map[string]*string{
+		"MCPCANCTL_SERVER":   &cfg.Server,
+		"MCPCANCTL_TOKEN":    &cfg.Token,
+		"MCPCANCTL_USERNAME": &cfg.Username,
+		"MCPCANCTL_PASSWORD": &cfg.Password,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	if cfg.MarketPrefix == "" {
+		cfg.MarketPrefix = common.MarketRoutePrefix
+	}
+	if cfg.AuthzPrefix == "" {
+		cfg.AuthzPrefix = common.AuthzRoutePrefix
+	}
+	cfg.Server = strings.TrimSuffix(cfg.Server, "/")
+	return cfg, nil
+}
+
+// loadFile 只读取配置文件本身，写回时不会带入环境变量中的凭据
+func loadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save 写入配置文件，包含令牌和密码，仅当前用户可读写
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o600)
+}
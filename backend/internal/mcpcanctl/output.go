@@ -0,0 +1,55 @@
+package mcpcanctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// OutputTable 表格输出，便于阅读
+	OutputTable = "table"
+	// OutputJSON JSON 输出，字段与接口响应一致，便于脚本处理
+	OutputJSON = "json"
+)
+
+// printer 按输出格式打印结果
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// print JSON 模式输出 v，表格模式输出 headers 和 rows
+func (p *printer) print(v any, headers []string, rows [][]string) error {
+	if p.format == OutputJSON {
+		return p.json(v)
+	}
+	return p.table(headers, rows)
+}
+
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+func (p *printer) table(headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// message 输出操作结果，JSON 模式输出完整响应
+func (p *printer) message(v any, format string, args ...any) error {
+	if p.format == OutputJSON {
+		return p.json(v)
+	}
+	_, err := fmt.Fprintf(p.w, format+"\n", args...)
+	return err
+}
@@ -0,0 +1,232 @@
+package catalog
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// bundledCatalog catalog shipped with the init service
+//
+//go:embed catalog.json
+var bundledCatalog []byte
+
+// maxCatalogSize upper bound of a remote catalog document
+const maxCatalogSize = 8 << 20
+
+var (
+	idPattern          = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+	envNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// ErrInvalidCatalog the catalog document cannot be parsed or has invalid entries
+var ErrInvalidCatalog = errors.New("invalid catalog")
+
+// Catalog catalog document, the bundled catalog and remote registries share this format
+type Catalog struct {
+	Version int     `json:"version"`
+	Servers []Entry `json:"servers"`
+}
+
+// Entry catalog entry
+// ${NAME} placeholders in mcpServers are replaced with the env values given at install time
+type Entry struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Category    string                `json:"category,omitempty"`
+	Icon        string                `json:"icon,omitempty"`
+	Repository  string                `json:"repository,omitempty"`
+	Version     string                `json:"version,omitempty"`
+	AccessType  string                `json:"accessType"`
+	McpProtocol string                `json:"mcpProtocol"`
+	Image       string                `json:"image,omitempty"`
+	Command     string                `json:"command,omitempty"`
+	Port        int32                 `json:"port,omitempty"`
+	ServicePath string                `json:"servicePath,omitempty"`
+	McpServers  json.RawMessage       `json:"mcpServers,omitempty"`
+	Env         []model.CatalogEnvVar `json:"env,omitempty"`
+}
+
+// Bundled returns the entries of the bundled catalog
+func Bundled() ([]Entry, error) {
+	return Parse(bundledCatalog)
+}
+
+// Parse parses and validates a catalog document
+func Parse(data []byte) ([]Entry, error) {
+	var doc Catalog
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCatalog, err)
+	}
+	seen := make(map[string]bool, len(doc.Servers))
+	for i := range doc.Servers {
+		entry := &doc.Servers[i]
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: servers[%d]: %v", ErrInvalidCatalog, i, err)
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("%w: servers[%d]: duplicate id %s", ErrInvalidCatalog, i, entry.ID)
+		}
+		seen[entry.ID] = true
+	}
+	return doc.Servers, nil
+}
+
+// Fetch downloads and parses a remote catalog document
+func Fetch(ctx context.Context, url string, timeout time.Duration) ([]Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCatalogSize {
+		return nil, fmt.Errorf("catalog exceeds %d bytes", maxCatalogSize)
+	}
+	return Parse(data)
+}
+
+// Validate checks the fields required to install the entry
+func (e *Entry) Validate() error {
+	if !idPattern.MatchString(e.ID) {
+		return fmt.Errorf("id %q must be lowercase letters, digits, '.', '_' or '-' and at most 100 characters", e.ID)
+	}
+	if strings.TrimSpace(e.Name) == "" {
+		return fmt.Errorf("%s: name is required", e.ID)
+	}
+
+	accessType := model.AccessType(e.AccessType)
+	switch accessType {
+	case model.AccessTypeDirect, model.AccessTypeProxy, model.AccessTypeHosting:
+	default:
+		return fmt.Errorf("%s: unsupported accessType %q", e.ID, e.AccessType)
+	}
+	protocol := model.McpProtocol(e.McpProtocol)
+	switch protocol {
+	case model.McpProtocolSSE, model.McpProtocolStreamableHttp, model.McpProtocolStdio:
+	default:
+		return fmt.Errorf("%s: unsupported mcpProtocol %q", e.ID, e.McpProtocol)
+	}
+	if protocol == model.McpProtocolStdio && accessType != model.AccessTypeHosting {
+		return fmt.Errorf("%s: stdio servers must use the hosting access type", e.ID)
+	}
+
+	if len(e.McpServers) > 0 {
+		var servers map[string]json.RawMessage
+		if err := json.Unmarshal(e.McpServers, &servers); err != nil || servers == nil {
+			return fmt.Errorf("%s: mcpServers must be a JSON object", e.ID)
+		}
+	}
+	switch {
+	case accessType != model.AccessTypeHosting && len(e.McpServers) == 0:
+		return fmt.Errorf("%s: mcpServers is required for %s servers", e.ID, accessType)
+	case protocol == model.McpProtocolStdio && len(e.McpServers) == 0:
+		return fmt.Errorf("%s: mcpServers is required for stdio servers", e.ID)
+	case accessType == model.AccessTypeHosting && protocol != model.McpProtocolStdio && e.Command == "":
+		return fmt.Errorf("%s: command is required for hosted %s servers", e.ID, protocol)
+	}
+
+	declared := make(map[string]bool, len(e.Env))
+	for _, env := range e.Env {
+		if !envNamePattern.MatchString(env.Name) {
+			return fmt.Errorf("%s: invalid env name %q", e.ID, env.Name)
+		}
+		if declared[env.Name] {
+			return fmt.Errorf("%s: duplicate env %s", e.ID, env.Name)
+		}
+		declared[env.Name] = true
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(string(e.McpServers), -1) {
+		if !declared[match[1]] {
+			return fmt.Errorf("%s: mcpServers references undeclared env %s", e.ID, match[1])
+		}
+	}
+	return nil
+}
+
+// Model converts the entry to a catalog table record
+func (e *Entry) Model(source model.CatalogSource) *model.McpCatalogEntry {
+	entry := &model.McpCatalogEntry{
+		CatalogID:     e.ID,
+		Name:          e.Name,
+		Description:   e.Description,
+		Category:      e.Category,
+		IconURL:       e.Icon,
+		RepositoryURL: e.Repository,
+		Version:       e.Version,
+		AccessType:    model.AccessType(e.AccessType),
+		McpProtocol:   model.McpProtocol(e.McpProtocol),
+		ImgAddress:    e.Image,
+		Command:       e.Command,
+		Port:          e.Port,
+		ServicePath:   e.ServicePath,
+		McpServers:    e.McpServers,
+		Source:        source,
+	}
+	if len(e.Env) > 0 {
+		entry.EnvSchema, _ = json.Marshal(e.Env)
+	}
+	return entry
+}
+
+// ResolveEnv merges the given values with the defaults of the declared env vars,
+// and returns the names of the required env vars that have no value
+func ResolveEnv(vars []model.CatalogEnvVar, values map[string]string) (map[string]string, []string) {
+	resolved := make(map[string]string, len(vars))
+	var missing []string
+	for _, env := range vars {
+		value, ok := values[env.Name]
+		if !ok || value == "" {
+			value = env.Default
+		}
+		if value == "" {
+			if env.Required {
+				missing = append(missing, env.Name)
+			}
+			continue
+		}
+		resolved[env.Name] = value
+	}
+	return resolved, missing
+}
+
+// ExpandMcpServers replaces the ${NAME} placeholders in the mcpServers config,
+// values are JSON escaped since placeholders only appear inside JSON strings
+func ExpandMcpServers(raw json.RawMessage, values map[string]string) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	expanded := placeholderPattern.ReplaceAllStringFunc(string(raw), func(placeholder string) string {
+		value, ok := values[placeholder[2:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	})
+	return json.RawMessage(expanded)
+}
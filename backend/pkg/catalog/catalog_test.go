@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestBundled(t *testing.T) {
+	entries, err := Bundled()
+	if err != nil {
+		t.Fatalf("Bundled() error = %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("Bundled() returned no entries")
+	}
+	for _, entry := range entries {
+		if entry.Category == "" {
+			t.Errorf("bundled entry %s has no category", entry.ID)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers string
+		want    string
+	}{
+		{"bad id", `[{"id":"Bad ID","name":"x","accessType":"proxy","mcpProtocol":"sse","mcpServers":{}}]`, "id"},
+		{"missing name", `[{"id":"a","accessType":"proxy","mcpProtocol":"sse","mcpServers":{}}]`, "name is required"},
+		{"unknown access type", `[{"id":"a","name":"a","accessType":"remote","mcpProtocol":"sse"}]`, "accessType"},
+		{"stdio proxy", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"stdio","mcpServers":{}}]`, "hosting"},
+		{"proxy without servers", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"sse"}]`, "mcpServers is required"},
+		{"hosted http without command", `[{"id":"a","name":"a","accessType":"hosting","mcpProtocol":"sse"}]`, "command is required"},
+		{"servers not object", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"sse","mcpServers":[]}]`, "JSON object"},
+		{"undeclared env", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"sse","mcpServers":{"url":"${TOKEN}"}}]`, "undeclared env TOKEN"},
+		{"duplicate env", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"sse","mcpServers":{},"env":[{"name":"A"},{"name":"A"}]}]`, "duplicate env"},
+		{"duplicate id", `[{"id":"a","name":"a","accessType":"proxy","mcpProtocol":"sse","mcpServers":{}},{"id":"a","name":"b","accessType":"proxy","mcpProtocol":"sse","mcpServers":{}}]`, "duplicate id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(`{"version":1,"servers":` + tt.servers + `}`))
+			if !errors.Is(err, ErrInvalidCatalog) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	vars := []model.CatalogEnvVar{
+		{Name: "TOKEN", Required: true},
+		{Name: "REGION", Default: "us"},
+		{Name: "DEBUG"},
+		{Name: "KEY", Required: true, Default: "k"},
+	}
+	resolved, missing := ResolveEnv(vars, map[string]string{"REGION": "eu", "UNKNOWN": "x"})
+	if want := map[string]string{"REGION": "eu", "KEY": "k"}; !reflect.DeepEqual(resolved, want) {
+		t.Errorf("resolved = %v, want %v", resolved, want)
+	}
+	if want := []string{"TOKEN"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %v, want %v", missing, want)
+	}
+}
+
+func TestExpandMcpServers(t *testing.T) {
+	raw := json.RawMessage(`{"mcpServers":{"a":{"url":"https://x/mcp?key=${KEY}","headers":{"X":"${OTHER}"}}}}`)
+	got := ExpandMcpServers(raw, map[string]string{"KEY": `a"b`})
+	want := `{"mcpServers":{"a":{"url":"https://x/mcp?key=a\"b","headers":{"X":"${OTHER}"}}}}`
+	if string(got) != want {
+		t.Errorf("ExpandMcpServers() = %s, want %s", got, want)
+	}
+	if !json.Valid(got) {
+		t.Errorf("ExpandMcpServers() produced invalid JSON")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"version":1,"servers":[{"id":"remote","name":"Remote","accessType":"proxy","mcpProtocol":"sse","mcpServers":{"mcpServers":{"remote":{"url":"https://example.com/sse"}}}}]}`))
+	}))
+	defer srv.Close()
+
+	entries, err := Fetch(context.Background(), srv.URL+"/catalog.json", time.Second)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "remote" {
+		t.Errorf("Fetch() = %+v, want the remote entry", entries)
+	}
+	if _, err := Fetch(context.Background(), srv.URL+"/missing.json", time.Second); err == nil {
+		t.Error("Fetch() of a missing catalog succeeded")
+	}
+}
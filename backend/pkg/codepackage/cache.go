@@ -0,0 +1,183 @@
+package codepackage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// editsDir directory under the package key holding files edited in the code editor.
+// With a remote backend the extraction cache can be rebuilt on any replica, edits are replayed on top of the extracted tree.
+const editsDir = ".edits"
+
+// extractLocks serializes cache population per package
+var extractLocks sync.Map
+
+// remote reports whether packages are stored outside the local extraction cache
+func (m *CodePackageManager) remote() bool {
+	fs, ok := m.storage.(*FileStorage)
+	if !ok {
+		return true
+	}
+	root, err1 := filepath.Abs(fs.root)
+	prefix, err2 := filepath.Abs(m.pathPrefix)
+	return err1 != nil || err2 != nil || root != prefix
+}
+
+// putFile streams a local file to the storage backend
+func (m *CodePackageManager) putFile(ctx context.Context, key, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return m.storage.Put(ctx, key, f, info.Size())
+}
+
+// OriginalKey returns the storage key of the original package file
+func OriginalKey(pkg *model.McpCodePackage) string {
+	if pkg.OriginalPath != "" {
+		return filepath.ToSlash(pkg.OriginalPath)
+	}
+	return path.Join(filepath.ToSlash(pkg.PackagePath), pkg.OriginalName)
+}
+
+// EditKey returns the storage key of an edited file, relPath is relative to the extracted tree
+func EditKey(pkg *model.McpCodePackage, relPath string) string {
+	return path.Join(filepath.ToSlash(pkg.PackagePath), editsDir, filepath.ToSlash(relPath))
+}
+
+// OpenOriginal opens the original package file in the storage backend
+func (m *CodePackageManager) OpenOriginal(ctx context.Context, pkg *model.McpCodePackage) (io.ReadCloser, error) {
+	return m.storage.Get(ctx, OriginalKey(pkg))
+}
+
+// PresignOriginal returns a direct download URL of the original package, or ErrPresignUnsupported
+func (m *CodePackageManager) PresignOriginal(ctx context.Context, pkg *model.McpCodePackage, expires time.Duration) (string, error) {
+	return m.storage.Presign(ctx, OriginalKey(pkg), expires)
+}
+
+// EnsureExtracted returns the absolute path of the extracted tree, downloading and extracting the package
+// into the local cache when a remote backend is used and the tree is not cached yet
+func (m *CodePackageManager) EnsureExtracted(ctx context.Context, pkg *model.McpCodePackage) (string, error) {
+	extractedPath := pkg.ExtractedPath
+	if extractedPath == "" {
+		// 兼容旧数据，如果没有解压路径，使用包路径
+		extractedPath = pkg.PackagePath
+	}
+	absExtractedPath, err := m.ToAbsolutePath(extractedPath)
+	if err != nil {
+		return "", err
+	}
+	if !m.remote() || pkg.ExtractedPath == "" {
+		return absExtractedPath, nil
+	}
+
+	lock, _ := extractLocks.LoadOrStore(pkg.PackageID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if _, err := os.Stat(absExtractedPath); err == nil {
+		return absExtractedPath, nil
+	}
+	if err := m.populateCache(ctx, pkg, absExtractedPath); err != nil {
+		return "", err
+	}
+	return absExtractedPath, nil
+}
+
+// populateCache downloads the original package, extracts it in a staging directory, replays edits
+// and moves the tree into place, so a failed extraction never leaves a partial tree in the cache
+func (m *CodePackageManager) populateCache(ctx context.Context, pkg *model.McpCodePackage, absExtractedPath string) error {
+	startTime := time.Now()
+	packageDir := filepath.Dir(absExtractedPath)
+	if err := os.MkdirAll(packageDir, 0755); err != nil {
+		return fmt.Errorf("failed to create package directory: %v", err)
+	}
+	stagingDir, err := os.MkdirTemp(packageDir, ".extract-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// 保留原始文件名，解压目录名由文件名决定
+	originalPath := filepath.Join(stagingDir, pkg.OriginalName)
+	if err := m.download(ctx, OriginalKey(pkg), originalPath); err != nil {
+		return fmt.Errorf("failed to download package: %w", err)
+	}
+	stagedPath, err := m.extractPackage(originalPath, stagingDir, pkg.PackageType)
+	if err != nil {
+		return fmt.Errorf("failed to extract package: %w", err)
+	}
+
+	prefix := path.Join(filepath.ToSlash(pkg.PackagePath), editsDir)
+	edits, err := m.storage.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list edited files: %w", err)
+	}
+	for _, key := range edits {
+		rel := strings.TrimPrefix(key, prefix+"/")
+		target := filepath.Join(stagedPath, filepath.FromSlash(rel))
+		if !strings.HasPrefix(target, stagedPath+string(filepath.Separator)) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %v", err)
+		}
+		if err := m.download(ctx, key, target); err != nil {
+			return fmt.Errorf("failed to restore edited file %s: %w", rel, err)
+		}
+	}
+
+	if err := os.Rename(stagedPath, absExtractedPath); err != nil {
+		return fmt.Errorf("failed to move extracted package into cache: %v", err)
+	}
+	logger.Info("Code package cache populated",
+		zap.String("packageId", pkg.PackageID),
+		zap.Int("editedFiles", len(edits)),
+		zap.Duration("elapsed", time.Since(startTime)))
+	return nil
+}
+
+// download writes an object to a local file
+func (m *CodePackageManager) download(ctx context.Context, key, target string) error {
+	r, err := m.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// PersistEdit stores a file edited in the extracted tree so it survives cache rebuilds, relPath is relative to the extracted tree.
+// With the filesystem backend the extracted tree is the storage, nothing else needs to be written
+func (m *CodePackageManager) PersistEdit(ctx context.Context, pkg *model.McpCodePackage, relPath string, content []byte) error {
+	if !m.remote() {
+		return nil
+	}
+	return m.storage.Put(ctx, EditKey(pkg, relPath), bytes.NewReader(content), int64(len(content)))
+}
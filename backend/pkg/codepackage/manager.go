@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -21,16 +22,25 @@ import (
 )
 
 // CodePackageManager manages code packages.
+// Original packages are kept in the storage backend, extracted trees live under pathPrefix,
+// which is a lazily populated cache when the backend is remote.
 type CodePackageManager struct {
 	config     *common.CodeConfig
 	pathPrefix string
+	storage    Storage
 }
 
-// NewCodePackageManager creates a new CodePackageManager instance.
+// NewCodePackageManager creates a new CodePackageManager instance using the backend set by SetStorage,
+// or the local filesystem under pathPrefix when none is set.
 func NewCodePackageManager(codeConfig *common.CodeConfig, pathPrefix string) *CodePackageManager {
+	storage := currentStorage()
+	if storage == nil {
+		storage = NewFileStorage(pathPrefix)
+	}
 	return &CodePackageManager{
 		config:     codeConfig,
 		pathPrefix: pathPrefix,
+		storage:    storage,
 	}
 }
 
@@ -46,7 +56,8 @@ type PackageInfo struct {
 }
 
 // UploadAndExtractPackage uploads and extracts a code package.
-func (m *CodePackageManager) UploadAndExtractPackage(file multipart.File, header *multipart.FileHeader) (*PackageInfo, error) {
+// The package is staged and extracted under pathPrefix, then streamed to a remote backend.
+func (m *CodePackageManager) UploadAndExtractPackage(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*PackageInfo, error) {
 	// Log upload start information
 	logger.Info("Starting code package upload",
 		zap.String("filename", header.Filename),
@@ -102,6 +113,15 @@ func (m *CodePackageManager) UploadAndExtractPackage(file multipart.File, header
 	relOriginalPath, _ := m.ToRelativePath(originalPath)
 	relExtractedPath, _ := m.ToRelativePath(extractedPath)
 
+	// Store the original package in the remote backend, the local copy is not kept in the cache
+	if m.remote() {
+		if err := m.putFile(ctx, filepath.ToSlash(relOriginalPath), originalPath); err != nil {
+			os.RemoveAll(packageDir)
+			return nil, fmt.Errorf("failed to store original package: %v", err)
+		}
+		os.Remove(originalPath)
+	}
+
 	packageInfo := &PackageInfo{
 		PackageID:     packageID,
 		PackagePath:   relPackagePath,
@@ -540,8 +560,17 @@ func (m *CodePackageManager) ToAbsolutePath(relativePath string) (string, error)
 	return absolutePath, nil
 }
 
-// DeletePackage removes a code package directory and all its contents
-func (m *CodePackageManager) DeletePackage(packagePath string) error {
+// DeletePackage removes a code package from the storage backend and the local cache
+func (m *CodePackageManager) DeletePackage(ctx context.Context, packagePath string) error {
+	if m.remote() {
+		if err := m.storage.Delete(ctx, filepath.ToSlash(packagePath)); err != nil {
+			logger.Error("Failed to remove package from storage",
+				zap.String("path", packagePath),
+				zap.Error(err))
+			return fmt.Errorf("failed to remove package from storage: %v", err)
+		}
+	}
+
 	// Convert relative path to absolute path
 	absPackagePath, err := m.ToAbsolutePath(packagePath)
 	if err != nil {
@@ -0,0 +1,171 @@
+package codepackage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+)
+
+// ErrPresignUnsupported is returned by Presign when the backend cannot issue direct download URLs.
+var ErrPresignUnsupported = errors.New("presigned URLs are not supported by the storage backend")
+
+// ErrObjectNotFound is returned by Get when the key does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage stores code package objects. Keys are slash separated paths relative to the storage root,
+// e.g. package-{id}/server.zip.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key and every object under the key/ prefix.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys of all objects under the key/ prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Presign returns a URL that downloads the object without credentials until it expires.
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+var (
+	storageMu      sync.RWMutex
+	defaultStorage Storage
+)
+
+// SetStorage sets the backend used by code package managers created afterwards.
+// Managers store packages on the local filesystem under their path prefix when no backend is set.
+func SetStorage(storage Storage) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	defaultStorage = storage
+}
+
+// currentStorage returns the backend set by SetStorage.
+func currentStorage() Storage {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+	return defaultStorage
+}
+
+// NewStorage creates the backend selected by the configuration, localRoot is the root of the filesystem backend.
+func NewStorage(cfg common.CodeStorageConfig, localRoot string) (Storage, error) {
+	switch cfg.Type {
+	case "", common.CodeStorageFilesystem:
+		return NewFileStorage(localRoot), nil
+	case common.CodeStorageS3:
+		return NewS3Storage(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unsupported code package storage type: %s", cfg.Type)
+	}
+}
+
+// FileStorage stores objects as files under a root directory.
+type FileStorage struct {
+	root string
+}
+
+// NewFileStorage creates a filesystem backend rooted at root.
+func NewFileStorage(root string) *FileStorage {
+	return &FileStorage{root: root}
+}
+
+// path resolves key under the root, rejecting keys that escape it.
+func (s *FileStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place.
+func (s *FileStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write %s: wrote %d bytes, expected %d", key, written, size)
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Get opens the file stored under key.
+func (s *FileStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return f, err
+}
+
+// Delete removes the file or directory stored under key.
+func (s *FileStorage) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(target)
+}
+
+// List walks the directory stored under prefix.
+func (s *FileStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	dir, err := s.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	return keys, err
+}
+
+// Presign is not supported, files are served by the market service.
+func (s *FileStorage) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+type actorKey struct{}
+
+// SetActorToContext 将当前操作人写入上下文，用于记录实例操作
+func SetActorToContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext 从上下文获取操作人，未设置时为 system（容器监控等平台自动操作）
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return model.InstanceOperationActorSystem
+}
+
+type userIDKey struct{}
+
+// SetUserIDToContext 将当前登录用户ID写入上下文，用于记录实例和代码包的创建用户
+func SetUserIDToContext(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext 从上下文获取当前登录用户ID，未设置时为 0（平台自动操作）
+func UserIDFromContext(ctx context.Context) uint {
+	userID, _ := ctx.Value(userIDKey{}).(uint)
+	return userID
+}
+
+type impersonatorIDKey struct{}
+
+// SetImpersonatorIDToContext 管理员模拟用户时将管理员自己的用户ID写入上下文，上下文中的用户为被模拟的用户
+func SetImpersonatorIDToContext(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, impersonatorIDKey{}, userID)
+}
+
+// ImpersonatorIDFromContext 从上下文获取模拟用户的管理员ID，未模拟时为 0
+func ImpersonatorIDFromContext(ctx context.Context) uint {
+	userID, _ := ctx.Value(impersonatorIDKey{}).(uint)
+	return userID
+}
@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// FieldChange 单个字段的修改，值为 JSON。敏感字段只标记为已修改，不记录值
+type FieldChange struct {
+	Field    string          `json:"field"`
+	Old      json.RawMessage `json:"old,omitempty"`
+	New      json.RawMessage `json:"new,omitempty"`
+	Redacted bool            `json:"redacted,omitempty"`
+}
+
+// DiffOptions 字段比较选项，字段使用 JSON 名称
+type DiffOptions struct {
+	// Ignore 服务端维护的字段，不参与比较
+	Ignore []string
+	// Sensitive 修改时只记录字段名的字段，如令牌
+	Sensitive []string
+	// EnvMaps 字符串映射字段，按键比较，敏感名称的值不记录，如环境变量
+	EnvMaps []string
+	// McpServers mcpServers 配置字段，记录时清除其中服务的敏感 env 和 headers
+	McpServers []string
+}
+
+// DiffFields compares two values of the same struct type field by field using their JSON encoding and
+// returns the changed fields sorted by name. Values may also be given already JSON encoded, e.g. a snapshot
+// taken before the value was modified in place
+func DiffFields(before, after any, opts DiffOptions) ([]FieldChange, error) {
+	oldFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(newFields))
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+	var changes []FieldChange
+	for name := range names {
+		if slices.Contains(opts.Ignore, name) {
+			continue
+		}
+		oldValue, newValue := oldFields[name], newFields[name]
+		if jsonEqual(oldValue, newValue) {
+			continue
+		}
+		switch {
+		case slices.Contains(opts.Sensitive, name):
+			changes = append(changes, FieldChange{Field: name, Redacted: true})
+		case slices.Contains(opts.EnvMaps, name):
+			changes = append(changes, diffEnvMap(name, oldValue, newValue)...)
+		case slices.Contains(opts.McpServers, name):
+			changes = append(changes, diffMcpServers(name, oldValue, newValue))
+		default:
+			changes = append(changes, FieldChange{Field: name, Old: nullToEmpty(oldValue), New: nullToEmpty(newValue)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// diffEnvMap compares a string map field key by key, values of secret names are redacted. A value that
+// cannot be parsed as a string map is reported as a whole
+func diffEnvMap(name string, oldValue, newValue json.RawMessage) []FieldChange {
+	oldMap, oldOK := stringMap(oldValue)
+	newMap, newOK := stringMap(newValue)
+	if !oldOK || !newOK {
+		return []FieldChange{{Field: name, Redacted: true}}
+	}
+
+	var changes []FieldChange
+	for key, oldVal := range oldMap {
+		newVal, ok := newMap[key]
+		if ok && newVal == oldVal {
+			continue
+		}
+		change := FieldChange{Field: name + "." + key}
+		if IsSecretName(key) {
+			change.Redacted = true
+		} else {
+			change.Old, _ = json.Marshal(oldVal)
+			if ok {
+				change.New, _ = json.Marshal(newVal)
+			}
+		}
+		changes = append(changes, change)
+	}
+	for key, newVal := range newMap {
+		if _, ok := oldMap[key]; ok {
+			continue
+		}
+		change := FieldChange{Field: name + "." + key}
+		if IsSecretName(key) {
+			change.Redacted = true
+		} else {
+			change.New, _ = json.Marshal(newVal)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// diffMcpServers reports a changed mcpServers config with the secret env and headers values cleared,
+// when only secret values changed the field is reported as redacted without values
+func diffMcpServers(name string, oldValue, newValue json.RawMessage) FieldChange {
+	oldStripped, oldNames := StripMcpServersSecrets(oldValue)
+	newStripped, newNames := StripMcpServersSecrets(newValue)
+	change := FieldChange{Field: name, Redacted: len(oldNames) > 0 || len(newNames) > 0}
+	if jsonEqual(oldStripped, newStripped) {
+		change.Redacted = true
+		return change
+	}
+	change.Old = nullToEmpty(oldStripped)
+	change.New = nullToEmpty(newStripped)
+	return change
+}
+
+// jsonFields encodes v as a JSON object and returns its undecoded fields
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("value is not a JSON object: %w", err)
+	}
+	return fields, nil
+}
+
+// jsonEqual reports whether two JSON values are equal ignoring formatting and key order,
+// a missing value equals null
+func jsonEqual(a, b json.RawMessage) bool {
+	a, b = nullToEmpty(a), nullToEmpty(b)
+	if bytes.Equal(a, b) {
+		return true
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+// nullToEmpty treats a JSON null as a missing value
+func nullToEmpty(v json.RawMessage) json.RawMessage {
+	if bytes.Equal(bytes.TrimSpace(v), []byte("null")) {
+		return nil
+	}
+	return v
+}
+
+// stringMap parses a JSON string map, a missing value is an empty map
+func stringMap(v json.RawMessage) (map[string]string, bool) {
+	values := map[string]string{}
+	if len(nullToEmpty(v)) == 0 {
+		return values, true
+	}
+	if err := json.Unmarshal(v, &values); err != nil {
+		return nil, false
+	}
+	return values, true
+}
@@ -0,0 +1,25 @@
+package common
+
+// Gateway circuit breaker states
+const (
+	// CircuitBreakerClosed requests pass through
+	CircuitBreakerClosed = "closed"
+	// CircuitBreakerOpen requests are rejected until the cooldown ends
+	CircuitBreakerOpen = "open"
+	// CircuitBreakerHalfOpen a single probe request checks whether the upstream recovered
+	CircuitBreakerHalfOpen = "half-open"
+)
+
+// CircuitBreakerState circuit breaker state of an instance, published by the gateway
+// and reported by the market instance status endpoint
+type CircuitBreakerState struct {
+	State string `json:"state"`
+	// Consecutive upstream connection errors
+	Failures int `json:"failures"`
+	// Last upstream connection error
+	LastError string `json:"lastError,omitempty"`
+	// Time the breaker opened, in milliseconds
+	OpenedAt int64 `json:"openedAt,omitempty"`
+	// Time the next probe is allowed, in milliseconds
+	RetryAt int64 `json:"retryAt,omitempty"`
+}
@@ -18,10 +18,47 @@ type StorageConfig struct {
 	RootPath   string `mapstructure:"rootPath"`
 	CodePath   string `mapstructure:"codePath"`
 	StaticPath string `mapstructure:"staticPath"`
+	// Code package storage backend, codePath is used as the local extraction cache when the backend is remote
+	CodeBackend CodeStorageConfig `mapstructure:"codeBackend"`
+}
+
+// Code package storage backend types
+const (
+	CodeStorageFilesystem = "filesystem"
+	CodeStorageS3         = "s3"
+)
+
+// CodeStorageConfig code package storage backend configuration
+type CodeStorageConfig struct {
+	// filesystem (default) or s3
+	Type string `mapstructure:"type"`
+	// S3-compatible object storage (AWS S3, MinIO, Tencent COS, Aliyun OSS)
+	S3 S3StorageConfig `mapstructure:"s3"`
+}
+
+// S3StorageConfig S3-compatible object storage configuration
+type S3StorageConfig struct {
+	// Endpoint URL, e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Endpoint string `mapstructure:"endpoint"`
+	// Signing region, defaults to us-east-1
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"accessKey"`
+	SecretKey string `mapstructure:"secretKey"`
+	// Key prefix of code package objects in the bucket
+	Prefix string `mapstructure:"prefix"`
+	// Address the bucket in the path instead of the host name, required by MinIO
+	PathStyle bool `mapstructure:"pathStyle"`
+	// Validity of presigned download URLs in seconds, defaults to 900
+	PresignExpiry int `mapstructure:"presignExpiry"`
 }
 
 type CodeConfig struct {
 	Upload UploadConfig `mapstructure:"upload"`
+	// URLs notified with a JSON POST when the content of a code package changes
+	Webhooks []string `mapstructure:"webhooks"`
+	// Timeout of a webhook request in seconds
+	WebhookTimeout int `mapstructure:"webhookTimeout"`
 }
 
 type UploadConfig struct {
@@ -50,6 +87,10 @@ type MySQLConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
+	// AutoMigrate legacy mode, repositories run AutoMigrate and create indexes on connect instead of versioned migrations
+	AutoMigrate bool `mapstructure:"autoMigrate"`
+	// MigrateLockTimeout seconds to wait for another process holding the migration lock, default 60
+	MigrateLockTimeout int `mapstructure:"migrateLockTimeout"`
 }
 
 type RedisConfig struct {
@@ -69,6 +110,327 @@ type MarketConfig struct {
 	CustomerUuid string `mapstructure:"customerUuid"`
 }
 
+// StatusConfig instance status check configuration
+type StatusConfig struct {
+	// Max instances of a batch status request
+	BatchMaxInstances int `mapstructure:"batchMaxInstances"`
+	// Concurrent status checks of a batch status request
+	BatchConcurrency int `mapstructure:"batchConcurrency"`
+	// Timeout of a single status check in seconds
+	CheckTimeout int `mapstructure:"checkTimeout"`
+	// Deadline of a batch status request in seconds, unfinished checks are returned as errors
+	BatchDeadline int `mapstructure:"batchDeadline"`
+}
+
+// ContainerQueueConfig hosting container creation queue configuration
+// Creations beyond the concurrency of an environment wait in a FIFO queue with container status queued
+type ContainerQueueConfig struct {
+	// Containers created at the same time per environment, a slot is held until the container is ready or its startup wait ends
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// PodWatchConfig Kubernetes pod watch configuration
+// Environments with provisioning instances are watched so readiness is detected without waiting for the periodic monitor
+type PodWatchConfig struct {
+	// Disable the watch and rely on the periodic container monitor only
+	Disabled bool `mapstructure:"disabled"`
+	// Max environments watched at the same time, the rest fall back to polling
+	MaxEnvironments int `mapstructure:"maxEnvironments"`
+	// Interval in seconds to refresh the watched environments
+	ResyncInterval int `mapstructure:"resyncInterval"`
+	// Max reconnect backoff in seconds after a watch fails
+	MaxBackoff int `mapstructure:"maxBackoff"`
+}
+
+// ImageConfig image registry configuration
+type ImageConfig struct {
+	// Skip the registry manifest check before creating instances, for air-gapped registries that can't be queried
+	SkipRegistryCheck bool `mapstructure:"skipRegistryCheck"`
+	// Registry query timeout in seconds
+	CheckTimeout int `mapstructure:"checkTimeout"`
+	// Image pull secrets (kubernetes.io/dockerconfigjson) in the environment namespace
+	PullSecrets []string `mapstructure:"pullSecrets"`
+	// Registries queried over plain HTTP
+	InsecureRegistries []string `mapstructure:"insecureRegistries"`
+	// Default hosting image, defaults to DefaultHostingImage
+	HostingImage string `mapstructure:"hostingImage"`
+	// Supergateway image, defaults to DefaultSupergatewayImage
+	SupergatewayImage string `mapstructure:"supergatewayImage"`
+}
+
+// PublicAccessConfig gateway public access configuration, used to build instance access URLs
+type PublicAccessConfig struct {
+	// Gateway path prefix exposed to clients, defaults to the gateway route prefix
+	PathPrefix string `mapstructure:"pathPrefix"`
+	// Public domains, e.g. internal and external; the first one is the default
+	Domains []PublicDomainConfig `mapstructure:"domains"`
+	// IPs or CIDRs of reverse proxies whose X-Forwarded-Proto/Host/Prefix headers are trusted,
+	// defaults to DefaultTrustedProxies
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+}
+
+// PublicDomainConfig public domain configuration
+type PublicDomainConfig struct {
+	// Domain name, e.g. internal / external
+	Name string `mapstructure:"name"`
+	// Base URL, e.g. https://mcp.example.com
+	URL string `mapstructure:"url"`
+	// Request hosts served by this domain, defaults to the host of URL
+	Hosts []string `mapstructure:"hosts"`
+}
+
+// ResponseCacheConfig gateway response cache of idempotent MCP methods, stored in redis
+type ResponseCacheConfig struct {
+	// Enable caching of streamable-http responses, disabled by default
+	Enabled bool `mapstructure:"enabled"`
+	// Cache TTL in seconds, defaults to 30
+	TTL int `mapstructure:"ttl"`
+	// JSON-RPC methods to cache, defaults to tools/list, prompts/list and resources/list
+	Methods []string `mapstructure:"methods"`
+}
+
+// CircuitBreakerConfig gateway circuit breaker of upstream instances
+type CircuitBreakerConfig struct {
+	// Enable the circuit breaker, disabled by default
+	Enabled bool `mapstructure:"enabled"`
+	// Consecutive connection errors that open the breaker, defaults to 5
+	FailureThreshold int `mapstructure:"failureThreshold"`
+	// Seconds the breaker stays open before a probe request is allowed, defaults to 30
+	Cooldown int `mapstructure:"cooldown"`
+}
+
+// ProxyLimitsConfig gateway request and response size limits in bytes and upstream timeout,
+// instances may raise them in their mcpServers config
+type ProxyLimitsConfig struct {
+	// Maximum request body size, larger requests are rejected with 413, defaults to 10MiB
+	MaxRequestBodySize int64 `mapstructure:"maxRequestBodySize"`
+	// Maximum size of a non-streaming response the gateway holds in memory, defaults to 1MiB
+	MaxResponseBufferSize int64 `mapstructure:"maxResponseBufferSize"`
+	// Responses larger than this or of unknown length are streamed to the client without buffering, defaults to 256KiB
+	StreamingThreshold int64 `mapstructure:"streamingThreshold"`
+	// Seconds to wait for a non-SSE upstream response when the instance sets no timeout, defaults to 30
+	ReadTimeout int `mapstructure:"readTimeout"`
+	// Milliseconds after which a proxied request is logged as slow with its instance and JSON-RPC method, 0 disables the log
+	SlowRequestThreshold int `mapstructure:"slowRequestThreshold"`
+}
+
+// InstanceFallbackConfig gateway fallback to the last instance config it loaded while the database is unavailable
+type InstanceFallbackConfig struct {
+	// Seconds a cached instance may still be served after a failed database lookup, defaults to 600, negative disables the fallback
+	StaleTTL int `mapstructure:"staleTTL"`
+	// Consecutive database errors that open the lookup breaker, defaults to 3
+	FailureThreshold int `mapstructure:"failureThreshold"`
+	// Seconds lookups skip the database once the breaker opens, defaults to 10
+	Cooldown int `mapstructure:"cooldown"`
+	// Seconds a single instance lookup may take, defaults to 3
+	LookupTimeout int `mapstructure:"lookupTimeout"`
+}
+
+// UpstreamTLSConfig TLS defaults of the gateway for HTTPS upstreams, instances may add their own
+// CA and client certificate in the tls section of their mcpServers config
+type UpstreamTLSConfig struct {
+	// PEM bundle of CAs trusted in addition to the system roots
+	CAFile string `mapstructure:"caFile"`
+	// Client certificate and key presented to upstreams that require mTLS, both must be set
+	ClientCertFile string `mapstructure:"clientCertFile"`
+	ClientKeyFile  string `mapstructure:"clientKeyFile"`
+	// Skip upstream certificate verification for every instance, only for testing
+	InsecureSkipVerify bool `mapstructure:"insecureSkipVerify"`
+}
+
+// OutboundProxyConfig HTTP proxy the gateway uses to reach upstreams, instances may override each
+// field in the proxy section of their mcpServers config. Empty uses no proxy.
+type OutboundProxyConfig struct {
+	// Proxy for http upstreams, e.g. http://proxy.corp:3128
+	HTTPProxy string `mapstructure:"httpProxy"`
+	// Proxy for https upstreams
+	HTTPSProxy string `mapstructure:"httpsProxy"`
+	// Comma separated hosts, domains and CIDRs reached without the proxy
+	NoProxy string `mapstructure:"noProxy"`
+}
+
+// SSEHeartbeatConfig keepalive comments the gateway writes to idle SSE clients
+type SSEHeartbeatConfig struct {
+	// Enable heartbeats, disabled by default, instances may enable or disable them individually
+	Enabled bool `mapstructure:"enabled"`
+	// Seconds without upstream data before a keepalive comment is sent, defaults to 30
+	Interval int `mapstructure:"interval"`
+}
+
+// GatewayCORSConfig default CORS policy of the gateway, instances may replace it in the cors
+// section of their mcpServers config
+type GatewayCORSConfig struct {
+	// Origins allowed to call the gateway from a browser, "*" allows any origin and
+	// "https://*.example.com" its subdomains, no CORS headers are sent when empty
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+	// Methods allowed in preflight responses, defaults to GET, POST, DELETE and OPTIONS
+	AllowedMethods []string `mapstructure:"allowedMethods"`
+	// Request headers allowed in preflight responses, defaults to the headers used by MCP clients
+	AllowedHeaders []string `mapstructure:"allowedHeaders"`
+	// Response headers readable by browsers, defaults to Mcp-Session-Id and X-Request-ID
+	ExposedHeaders []string `mapstructure:"exposedHeaders"`
+	// Allow cookies and authorization headers on cross-origin requests
+	AllowCredentials bool `mapstructure:"allowCredentials"`
+	// Seconds browsers may cache a preflight response, 0 leaves it to the browser
+	MaxAge int `mapstructure:"maxAge"`
+}
+
+// SSEConnectionsConfig gateway SSE connection and streamable HTTP session tracking
+type SSEConnectionsConfig struct {
+	// Publish connection and session counts to redis and accept drain commands from the market, disabled by default
+	Enabled bool `mapstructure:"enabled"`
+	// Maximum open SSE connections per instance, further connections are rejected with 503, 0 means unlimited
+	MaxPerInstance int `mapstructure:"maxPerInstance"`
+	// Seconds a streamable HTTP session stays active without requests, defaults to 1800
+	SessionTTL int `mapstructure:"sessionTTL"`
+}
+
+// DebugVarsConfig serves the expvar runtime metrics at /debug/vars
+type DebugVarsConfig struct {
+	// Serve /debug/vars without authentication, disabled by default, only enable when the port is not publicly reachable
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// OpenAPIConfig serves the generated OpenAPI document and Swagger UI of the service
+type OpenAPIConfig struct {
+	// Serve /openapi.json and /swagger under the route prefix, disabled by default
+	Enabled bool `mapstructure:"enabled"`
+	// Base URL of the swagger-ui-dist assets, defaults to the jsDelivr CDN
+	SwaggerUIURL string `mapstructure:"swaggerUIURL"`
+}
+
+// CatalogConfig MCP server catalog configuration
+// The bundled catalog is imported by the init service, a remote registry can be synced on demand
+type CatalogConfig struct {
+	// Default URL of the remote catalog JSON used by catalog sync when no URL is given
+	RegistryURL string `mapstructure:"registryURL"`
+	// Timeout of fetching the remote catalog in seconds
+	SyncTimeout int `mapstructure:"syncTimeout"`
+}
+
+// IconConfig uploaded icon configuration
+// Icons are stored in the code package storage backend, under storage.staticPath for the filesystem backend
+type IconConfig struct {
+	// Maximum icon file size in KiB
+	MaxFileSize int `mapstructure:"maxFileSize"`
+	// Minimum and maximum width and height in pixels
+	MinDimension int `mapstructure:"minDimension"`
+	MaxDimension int `mapstructure:"maxDimension"`
+	// Hours an unreferenced icon is kept before the cleanup job deletes it,
+	// so icons uploaded for a form that is not saved yet are not removed
+	CleanupGracePeriod int `mapstructure:"cleanupGracePeriod"`
+}
+
+// InstanceEventsConfig persisted container events of hosting instances
+type InstanceEventsConfig struct {
+	// Events kept per instance, the least recently seen events are deleted
+	HistorySize int `mapstructure:"historySize"`
+}
+
+// HealthMonitorConfig scheduled health checks of direct and proxy instances
+// Checks are enabled per instance, this configures the shared checker and the transition webhooks
+type HealthMonitorConfig struct {
+	// Check results kept per instance, older results are deleted
+	HistorySize int `mapstructure:"historySize"`
+	// Timeout of a single check in seconds
+	CheckTimeout int `mapstructure:"checkTimeout"`
+	// Concurrent checks of a monitor run
+	Concurrency int `mapstructure:"concurrency"`
+	// URLs notified with a JSON POST when an instance goes from up to down or from down to up
+	Webhooks []string `mapstructure:"webhooks"`
+	// Timeout of a webhook request in seconds
+	WebhookTimeout int `mapstructure:"webhookTimeout"`
+}
+
+// TokenExpiryConfig expiry notifications and rotation of instance tokens
+type TokenExpiryConfig struct {
+	// Days before expiry a token is reported as expiring and the notification is sent
+	WarningDays int `mapstructure:"warningDays"`
+	// Hours a rotated token stays valid when the rotate request sets no grace period
+	GracePeriod int `mapstructure:"gracePeriod"`
+	// URLs notified with a JSON POST once per token when it starts expiring
+	Webhooks []string `mapstructure:"webhooks"`
+	// Timeout of a webhook request in seconds
+	WebhookTimeout int `mapstructure:"webhookTimeout"`
+}
+
+// InstanceSlugConfig custom gateway paths of instances, addressed as /{prefix}/{slug}/...
+type InstanceSlugConfig struct {
+	// Hours a former slug keeps redirecting to the current path of the instance with 308 after the slug
+	// is changed or removed, 0 disables the redirect and the former slug returns 404 right away
+	RedirectPeriod int `mapstructure:"redirectPeriod"`
+}
+
+// ImageScanConfig vulnerability scanning of hosting instance images, disabled when trivyServer is empty
+// Images are scanned asynchronously after create, environments with a critical vulnerability policy scan before create
+type ImageScanConfig struct {
+	// Address of the trivy server holding the vulnerability database, e.g. http://trivy.security:4954
+	TrivyServer string `mapstructure:"trivyServer"`
+	// Token of the trivy server when it requires one
+	TrivyToken string `mapstructure:"trivyToken"`
+	// Path of the trivy CLI, defaults to trivy in PATH
+	TrivyPath string `mapstructure:"trivyPath"`
+	// Timeout of a scan in seconds
+	Timeout int `mapstructure:"timeout"`
+}
+
+// ChangeRequestConfig two-person approval of instance changes in protected environments
+type ChangeRequestConfig struct {
+	// Hours a change request waits for review before it expires
+	ExpireHours int `mapstructure:"expireHours"`
+}
+
+// NotifyConfig email and Feishu notifications of instance events, email is disabled when smtp.host is empty
+// and Feishu when feishu.appID is empty. For email users choose the events they receive and immediate or digest delivery in their notification preferences
+type NotifyConfig struct {
+	SMTP   SMTPConfig   `mapstructure:"smtp"`
+	Feishu FeishuConfig `mapstructure:"feishu"`
+	// Base URL of the web console for links in emails, defaults to domain
+	ConsoleURL string `mapstructure:"consoleURL"`
+	// Minutes between digest emails
+	DigestInterval int `mapstructure:"digestInterval"`
+	// Retries of a failed email, with exponential backoff starting at 5 seconds
+	MaxRetries int `mapstructure:"maxRetries"`
+}
+
+// SMTPConfig SMTP server sending notification emails
+type SMTPConfig struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+	// Username and password for PLAIN auth, no authentication when username is empty
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Sender address, e.g. "MCPBox <noreply@example.com>"
+	From string `mapstructure:"from"`
+	// starttls (default), tls for implicit TLS or none for a local relay
+	TLS string `mapstructure:"tls"`
+	// Skip verification of the server certificate
+	InsecureSkipVerify bool `mapstructure:"insecureSkipVerify"`
+	// Timeout of sending one email in seconds
+	Timeout int `mapstructure:"timeout"`
+}
+
+// FeishuConfig Feishu (Lark) bot app sending instance event cards to the instance creator,
+// resolved by the Feishu ID of the user, or to a group chat
+type FeishuConfig struct {
+	AppID     string `mapstructure:"appID"`
+	AppSecret string `mapstructure:"appSecret"`
+	// Open platform URL, https://open.feishu.cn by default, https://open.larksuite.com for Lark
+	BaseURL string `mapstructure:"baseURL"`
+	// Type of the Feishu ID of users: open_id (default), union_id or user_id
+	UserIDType string `mapstructure:"userIDType"`
+	// Group chat receiving events of instances whose creator has no Feishu ID, empty to skip them
+	ChatID string `mapstructure:"chatID"`
+	// Also send every event to the group chat, not only those without a Feishu receiver
+	AlwaysNotifyChat bool `mapstructure:"alwaysNotifyChat"`
+	// Per-event enable flags keyed by instance operation, e.g. crash: true; unset events use the defaults
+	Events map[string]bool `mapstructure:"events"`
+	// Messages per second to one chat, the open platform throttles bots above 5
+	RatePerSecond float64 `mapstructure:"ratePerSecond"`
+	// Timeout of one request in seconds
+	Timeout int `mapstructure:"timeout"`
+}
+
 type LogConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
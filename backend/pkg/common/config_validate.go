@@ -0,0 +1,170 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// ConfigError lists every problem found in a configuration file, keyed by config path
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration, %d problem(s):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// ConfigValidator collects configuration problems so that all of them are reported at once at startup
+type ConfigValidator struct {
+	problems []string
+}
+
+// Addf records a problem of the config key
+func (v *ConfigValidator) Addf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+// Err returns a *ConfigError with all recorded problems, nil when there are none
+func (v *ConfigValidator) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Problems: v.problems}
+}
+
+// Required records a problem when value is empty
+func (v *ConfigValidator) Required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Addf(key, "is required")
+	}
+}
+
+// Positive records a problem when value is not greater than zero
+func (v *ConfigValidator) Positive(key string, value int64) {
+	if value <= 0 {
+		v.Addf(key, "must be greater than 0, got %d", value)
+	}
+}
+
+// Port records a problem when port is not a valid TCP port
+func (v *ConfigValidator) Port(key string, port int) {
+	if port <= 0 || port > 65535 {
+		v.Addf(key, "must be a port between 1 and 65535, got %d", port)
+	}
+}
+
+// ProxyURL records a problem when value is set and is not an http, https or socks5 proxy URL
+func (v *ConfigValidator) ProxyURL(key, value string) {
+	if value == "" {
+		return
+	}
+	if err := model.ValidateProxyURL(value); err != nil {
+		v.Addf(key, "%v", err)
+	}
+}
+
+// CORSOrigins records a problem for every entry that is not "*" or an http(s) origin
+func (v *ConfigValidator) CORSOrigins(key string, origins []string) {
+	for i, origin := range origins {
+		if err := model.ValidateCORSOrigin(origin); err != nil {
+			v.Addf(fmt.Sprintf("%s[%d]", key, i), "%v", err)
+		}
+	}
+}
+
+// CORSCredentials records a problem when credentials are allowed for any origin
+func (v *ConfigValidator) CORSCredentials(key string, origins []string, allowCredentials bool) {
+	if err := model.ValidateCORSCredentials(origins, allowCredentials); err != nil {
+		v.Addf(key, "%v", err)
+	}
+}
+
+// TrustedProxies records a problem for every entry that is not an IP or CIDR
+func (v *ConfigValidator) TrustedProxies(key string, entries []string) {
+	for i, entry := range entries {
+		if _, err := ParseTrustedProxies([]string{entry}); err != nil {
+			v.Addf(fmt.Sprintf("%s[%d]", key, i), "%v", err)
+		}
+	}
+}
+
+// WritableDir records a problem when path is not a writable directory. A missing directory is
+// accepted when its nearest existing parent is writable, since services create it on demand.
+func (v *ConfigValidator) WritableDir(key, path string) {
+	if strings.TrimSpace(path) == "" {
+		v.Addf(key, "is required")
+		return
+	}
+	dir := path
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				v.Addf(key, "%s is not a directory", dir)
+				return
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			v.Addf(key, "cannot access %s: %v", dir, err)
+			return
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			v.Addf(key, "%s does not exist", path)
+			return
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		v.Addf(key, "%s is not writable: %v", dir, err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// MySQL validates the connection settings of database.mysql
+func (v *ConfigValidator) MySQL(key string, cfg MySQLConfig) {
+	v.Required(key+".host", cfg.Host)
+	v.Port(key+".port", cfg.Port)
+	v.Required(key+".username", cfg.Username)
+	v.Required(key+".database", cfg.Database)
+}
+
+// Redis validates the address of database.redis
+func (v *ConfigValidator) Redis(key string, cfg RedisConfig) {
+	v.Required(key+".host", cfg.Host)
+	v.Port(key+".port", cfg.Port)
+	if cfg.DB < 0 {
+		v.Addf(key+".db", "must not be negative, got %d", cfg.DB)
+	}
+}
+
+// Service validates the address of a service other services call
+func (v *ConfigValidator) Service(key string, svc *Service) {
+	if svc == nil {
+		v.Addf(key, "is required")
+		return
+	}
+	v.Required(key+".host", svc.Host)
+	v.Port(key+".port", svc.Port)
+}
+
+// CodeBackend validates the code package storage backend
+func (v *ConfigValidator) CodeBackend(key string, cfg CodeStorageConfig) {
+	switch cfg.Type {
+	case "", CodeStorageFilesystem:
+	case CodeStorageS3:
+		v.Required(key+".s3.endpoint", cfg.S3.Endpoint)
+		v.Required(key+".s3.bucket", cfg.S3.Bucket)
+	default:
+		v.Addf(key+".type", "unsupported storage type %q, expected %s or %s", cfg.Type, CodeStorageFilesystem, CodeStorageS3)
+	}
+}
@@ -0,0 +1,62 @@
+package common_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestConfigValidator(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &common.ConfigValidator{}
+	v.MySQL("database.mysql", common.MySQLConfig{Host: "mysql", Port: 3306, Username: "mcp"})
+	v.Redis("database.redis", common.RedisConfig{Host: "redis", Port: 70000})
+	v.Service("services.mcpMarket", nil)
+	v.Positive("code.upload.maxFileSize", 0)
+	v.WritableDir("storage.rootPath", dir)
+	v.WritableDir("storage.codePath", filepath.Join(dir, "missing", "code"))
+	v.WritableDir("storage.staticPath", file)
+	v.CodeBackend("storage.codeBackend", common.CodeStorageConfig{Type: "ftp"})
+	v.CORSOrigins("cors.allowedOrigins", []string{"*", "https://*.example.com", "app.example.com"})
+	v.CORSCredentials("cors.allowCredentials", []string{"*"}, true)
+	v.CORSCredentials("cors.allowCredentials", []string{"https://*.example.com"}, true)
+	v.TrustedProxies("publicAccess.trustedProxies", []string{"10.0.0.0/8", "192.168.1.10", "ingress"})
+
+	err := v.Err()
+	var configErr *common.ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Err() = %v, want *ConfigError", err)
+	}
+	wantKeys := []string{
+		"database.mysql.database",
+		"database.redis.port",
+		"services.mcpMarket",
+		"code.upload.maxFileSize",
+		"storage.staticPath",
+		"storage.codeBackend.type",
+		"cors.allowedOrigins[2]",
+		"cors.allowCredentials",
+		"publicAccess.trustedProxies[2]",
+	}
+	if len(configErr.Problems) != len(wantKeys) {
+		t.Fatalf("Problems = %q, want %d problems", configErr.Problems, len(wantKeys))
+	}
+	for i, key := range wantKeys {
+		if !strings.HasPrefix(configErr.Problems[i], key+": ") {
+			t.Errorf("problem %d = %q, want key %s", i, configErr.Problems[i], key)
+		}
+	}
+
+	if err := (&common.ConfigValidator{}).Err(); err != nil {
+		t.Errorf("Err() without problems = %v, want nil", err)
+	}
+}
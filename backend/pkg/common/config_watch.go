@@ -0,0 +1,127 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// configReloadDebounce editors and ConfigMap updates produce several events per change,
+// events within this window trigger a single reload
+const configReloadDebounce = 500 * time.Millisecond
+
+// ConfigChange a configuration value that differs between two loads
+type ConfigChange struct {
+	// Key dotted mapstructure path, e.g. proxyLimits.maxRequestBodySize
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// WatchConfigFile re-reads the config file when it changes on disk or the process receives SIGHUP,
+// and calls reload with the freshly read config. Reloads are serialized, watching stops with ctx.
+func WatchConfigFile(ctx context.Context, path string, reload func(v *viper.Viper)) {
+	var mu sync.Mutex
+	var timer *time.Timer
+	trigger := func(reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(configReloadDebounce, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			v := viper.New()
+			v.SetConfigType("yaml")
+			v.SetConfigFile(path)
+			if err := v.ReadInConfig(); err != nil {
+				logger.Error("Failed to reload config file, keeping current settings",
+					zap.String("path", path), zap.String("reason", reason), zap.Error(err))
+				return
+			}
+			logger.Info("Config file reloaded", zap.String("path", path), zap.String("reason", reason))
+			reload(v)
+		})
+	}
+
+	// viper watches the config directory, so ConfigMap symlink swaps are detected too
+	watcher := viper.New()
+	watcher.SetConfigType("yaml")
+	watcher.SetConfigFile(path)
+	if err := watcher.ReadInConfig(); err != nil {
+		logger.Warn("Config file watch disabled, reload with SIGHUP", zap.String("path", path), zap.Error(err))
+	} else {
+		watcher.OnConfigChange(func(e fsnotify.Event) { trigger("file " + e.Op.String()) })
+		watcher.WatchConfig()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				trigger("SIGHUP")
+			}
+		}
+	}()
+}
+
+// DiffConfig compares two configs of the same struct type and returns the changed leaf values,
+// keyed by their mapstructure path. Fields tagged mapstructure:"-" are ignored.
+func DiffConfig(oldCfg, newCfg interface{}) []ConfigChange {
+	var changes []ConfigChange
+	diffValue("", reflect.ValueOf(oldCfg), reflect.ValueOf(newCfg), &changes)
+	return changes
+}
+
+func diffValue(key string, oldValue, newValue reflect.Value, changes *[]ConfigChange) {
+	for oldValue.Kind() == reflect.Ptr && newValue.Kind() == reflect.Ptr {
+		if oldValue.IsNil() || newValue.IsNil() {
+			break
+		}
+		oldValue, newValue = oldValue.Elem(), newValue.Elem()
+	}
+	if oldValue.Kind() != reflect.Struct || oldValue.Type() != newValue.Type() {
+		if !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			*changes = append(*changes, ConfigChange{Key: key, Old: oldValue.Interface(), New: newValue.Interface()})
+		}
+		return
+	}
+	t := oldValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("mapstructure")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if key != "" {
+			name = fmt.Sprintf("%s.%s", key, name)
+		}
+		diffValue(name, oldValue.Field(i), newValue.Field(i), changes)
+	}
+}
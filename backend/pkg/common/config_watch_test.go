@@ -0,0 +1,36 @@
+package common_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestDiffConfig(t *testing.T) {
+	type nested struct {
+		TTL     int      `mapstructure:"ttl"`
+		Methods []string `mapstructure:"methods"`
+	}
+	type config struct {
+		Name    string  `mapstructure:"-"`
+		Level   string  `mapstructure:"level"`
+		Cache   nested  `mapstructure:"cache"`
+		Pointer *nested `mapstructure:"pointer"`
+	}
+	oldCfg := config{Name: "a", Level: "info", Cache: nested{TTL: 30, Methods: []string{"tools/list"}}, Pointer: &nested{TTL: 1}}
+	newCfg := config{Name: "b", Level: "debug", Cache: nested{TTL: 30, Methods: []string{"tools/list", "prompts/list"}}, Pointer: &nested{TTL: 2}}
+
+	got := common.DiffConfig(oldCfg, newCfg)
+	want := []common.ConfigChange{
+		{Key: "level", Old: "info", New: "debug"},
+		{Key: "cache.methods", Old: []string{"tools/list"}, New: []string{"tools/list", "prompts/list"}},
+		{Key: "pointer.ttl", Old: 1, New: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffConfig() = %+v, want %+v", got, want)
+	}
+	if changes := common.DiffConfig(oldCfg, oldCfg); len(changes) != 0 {
+		t.Errorf("DiffConfig() of equal configs = %+v, want none", changes)
+	}
+}
@@ -38,8 +38,10 @@ const (
 	// Static resource access path prefix
 	StaticPrefix = "/static"
 
-	// Default hosting image address
-	DefatuleHostingImg = "ccr.ccs.tencentyun.com/itqm-private/mcp-hosting"
+	// Default hosting image address, overridable by image.hostingImage
+	DefaultHostingImage = "ccr.ccs.tencentyun.com/itqm-private/mcp-hosting"
+	// Default supergateway image address, overridable by image.supergatewayImage
+	DefaultSupergatewayImage = "ccr.ccs.tencentyun.com/itqm-private/supergateway:3.2.0-uvx"
 
 	SourceServerName = "qm-mcp-server"
 
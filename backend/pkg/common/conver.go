@@ -1,18 +1,19 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	codepb "qm-mcp-server/api/market/code"
 	instancepb "qm-mcp-server/api/market/instance"
 	"qm-mcp-server/pkg/database/model"
 )
 
-// ConvertToInstanceInfo converts database model to proto message
-func ConvertToInstanceInfo(instance *model.McpInstance) *instancepb.ListResp_InstanceInfo {
+// ConvertToInstanceInfo converts database model to proto message, timestamps follow the API version negotiated in ctx
+func ConvertToInstanceInfo(ctx context.Context, instance *model.McpInstance) *instancepb.ListResp_InstanceInfo {
 	accessType, _ := ConvertToProtoAccessType(model.AccessType(instance.AccessType))
 	mcpProtocol, _ := ConvertToProtoMcpProtocol(model.McpProtocol(instance.McpProtocol))
 	tokens := ConvertToProtoMcpToken(instance.Tokens)
-	return &instancepb.ListResp_InstanceInfo{
+	info := &instancepb.ListResp_InstanceInfo{
 		InstanceId:                 instance.InstanceID,
 		InstanceName:               instance.InstanceName,
 		AccessType:                 accessType,
@@ -29,13 +30,25 @@ func ConvertToInstanceInfo(instance *model.McpInstance) *instancepb.ListResp_Ins
 		SourceConfig:               string(instance.SourceConfig),
 		TargetConfig:               string(instance.TargetConfig),
 		PublicProxyConfig:          string(instance.PublicProxyConfig),
-		CreatedAt:                  instance.CreatedAt.String(),
-		UpdatedAt:                  instance.UpdatedAt.String(),
+		CreatedAt:                  FormatTime(ctx, instance.CreatedAt),
+		UpdatedAt:                  FormatTime(ctx, instance.UpdatedAt),
+		CreatedAtMs:                TimeMillis(instance.CreatedAt),
+		UpdatedAtMs:                TimeMillis(instance.UpdatedAt),
 		McpProtocol:                mcpProtocol,
 		Tokens:                     tokens,
 		IconPath:                   instance.IconPath,
 		ServicePath:                instance.ServicePath,
+		Labels:                     instance.GetLabels(),
+		Metadata:                   string(instance.Metadata),
+		Slug:                       instance.GetSlug(),
+		Locked:                     instance.Locked,
+		HealthStatus:               instance.HealthStatus,
 	}
+	if instance.HealthCheckedAt != nil {
+		info.HealthCheckedAt = FormatTime(ctx, *instance.HealthCheckedAt)
+		info.HealthCheckedAtMs = TimeMillis(*instance.HealthCheckedAt)
+	}
+	return info
 }
 
 // ConvertToModelMcpProtocol converts string to McpProtocol enum value
@@ -122,19 +135,6 @@ func ConvertToProtoAccessType(accessType model.AccessType) (instancepb.AccessTyp
 	}
 }
 
-func ConvertToProtoMcpToken(tokens []model.McpToken) []*instancepb.McpToken {
-	protoTokens := make([]*instancepb.McpToken, 0, len(tokens))
-	for _, token := range tokens {
-		protoTokens = append(protoTokens, &instancepb.McpToken{
-			Token:     token.Token,
-			ExpireAt:  token.ExpireAt,
-			PublishAt: token.PublishAt,
-			Usages:    token.Usages,
-		})
-	}
-	return protoTokens
-}
-
 // convertProtoTokensToModel converts tokens from proto structure to model structure
 func ConvertProtoTokensToModel(tokens []*instancepb.McpToken) []model.McpToken {
 	var modelTokens []model.McpToken
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// FormatCSV is the value of the format query parameter that selects a CSV export
+	FormatCSV = "csv"
+	// CSVContentType is the media type of CSV exports, also accepted in the Accept header
+	CSVContentType = "text/csv"
+	// MaxCSVRows caps the data rows of one CSV export; larger exports continue with NextPageTokenHeader
+	MaxCSVRows = 10000
+	// NextPageTokenHeader carries the token of the next page of a truncated CSV export
+	NextPageTokenHeader = "X-Next-Page-Token"
+
+	// csvFlushRows is how many rows are buffered before the response is flushed
+	csvFlushRows = 500
+)
+
+// utf8BOM lets Excel detect that the export is UTF-8
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// WantsCSV reports whether the request asks for CSV, either with format=csv or an Accept header
+// preferring text/csv. An explicit format parameter wins over the Accept header.
+func WantsCSV(c *gin.Context, format string) bool {
+	if format != "" {
+		return strings.EqualFold(format, FormatCSV)
+	}
+	return c.NegotiateFormat(gin.MIMEJSON, CSVContentType) == CSVContentType
+}
+
+// CSVRows produces the data rows of an export by calling write once per row
+type CSVRows func(write func(record ...string) error) error
+
+// GinCSV streams a CSV attachment: a UTF-8 BOM, the header row (always written, even when there
+// are no rows), then the rows. nextPageToken is sent in NextPageTokenHeader when the export
+// was truncated. Cells that spreadsheets would evaluate as formulas are prefixed with a quote.
+func GinCSV(c *gin.Context, fileName string, header []string, nextPageToken string, rows CSVRows) error {
+	c.Header("Content-Type", CSVContentType+"; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	if nextPageToken != "" {
+		c.Header(NextPageTokenHeader, nextPageToken)
+	}
+	c.Status(http.StatusOK)
+
+	if _, err := c.Writer.Write(utf8BOM); err != nil {
+		return err
+	}
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	written := 0
+	err := rows(func(record ...string) error {
+		for i, cell := range record {
+			record[i] = EscapeCSVFormula(cell)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		if written++; written%csvFlushRows == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	w.Flush()
+	c.Writer.Flush()
+	if err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// EscapeCSVFormula prefixes text starting with =, +, -, @, tab or carriage return with a single
+// quote so spreadsheets show it as text instead of evaluating it. Numbers are left unchanged.
+func EscapeCSVFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+// EncodePageToken encodes a pagination cursor as an opaque token
+func EncodePageToken(cursor int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(cursor, 10)))
+}
+
+// DecodePageToken decodes a token from EncodePageToken, an empty token decodes to 0
+func DecodePageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid page token")
+	}
+	cursor, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, fmt.Errorf("invalid page token")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,90 @@
+package common_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWantsCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		accept string
+		want   bool
+	}{
+		{"format parameter", "csv", "", true},
+		{"accept header", "", "text/csv", true},
+		{"format overrides accept", "json", "text/csv", false},
+		{"default json", "", "", false},
+		{"json preferred", "", "application/json, text/csv;q=0.5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+			if got := common.WantsCSV(c, tt.format); got != tt.want {
+				t.Errorf("WantsCSV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGinCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	err := common.GinCSV(c, "export.csv", []string{"name", "value"}, "next", func(write func(record ...string) error) error {
+		if err := write("a,\"b\"", "-1.5"); err != nil {
+			return err
+		}
+		return write("=SUM(A1)", "中文")
+	})
+	if err != nil {
+		t.Fatalf("GinCSV() error = %v", err)
+	}
+	want := "\xEF\xBB\xBFname,value\n\"a,\"\"b\"\"\",-1.5\n'=SUM(A1),中文\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if got := w.Header().Get(common.NextPageTokenHeader); got != "next" {
+		t.Errorf("next page token = %q", got)
+	}
+}
+
+func TestGinCSVHeaderWithoutRows(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if err := common.GinCSV(c, "export.csv", []string{"name"}, "", func(func(record ...string) error) error { return nil }); err != nil {
+		t.Fatalf("GinCSV() error = %v", err)
+	}
+	if got := w.Body.String(); got != "\xEF\xBB\xBFname\n" {
+		t.Errorf("body = %q", got)
+	}
+	if _, ok := w.Header()[common.NextPageTokenHeader]; ok {
+		t.Error("next page token header set for a complete export")
+	}
+}
+
+func TestPageToken(t *testing.T) {
+	token := common.EncodePageToken(1234)
+	if got, err := common.DecodePageToken(token); err != nil || got != 1234 {
+		t.Errorf("DecodePageToken(%q) = %v, %v", token, got, err)
+	}
+	if got, err := common.DecodePageToken(""); err != nil || got != 0 {
+		t.Errorf("DecodePageToken(\"\") = %v, %v", got, err)
+	}
+	if _, err := common.DecodePageToken("not a token"); err == nil {
+		t.Error("DecodePageToken() accepted an invalid token")
+	}
+}
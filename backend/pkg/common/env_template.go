@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envPlaceholderPattern 环境变量值中的占位符，如 {{.InstanceID}}
+var envPlaceholderPattern = regexp.MustCompile(`\{\{\s*\.([A-Za-z][A-Za-z0-9]*)\s*\}\}`)
+
+// EnvTemplateFields 环境变量值中支持引用的实例信息
+var EnvTemplateFields = []string{"InstanceID", "PublicURL", "Port", "ServiceName"}
+
+// EnvTemplateData 解析环境变量占位符时使用的实例信息，PublicURL 在未配置对外访问域名时为空
+type EnvTemplateData struct {
+	InstanceID  string
+	PublicURL   string
+	Port        int32
+	ServiceName string
+}
+
+// field 返回占位符对应的值，未知占位符返回 false
+func (d EnvTemplateData) field(name string) (string, bool) {
+	switch name {
+	case "InstanceID":
+		return d.InstanceID, true
+	case "PublicURL":
+		return d.PublicURL, true
+	case "Port":
+		return strconv.Itoa(int(d.Port)), true
+	case "ServiceName":
+		return d.ServiceName, true
+	}
+	return "", false
+}
+
+// HasEnvPlaceholder 判断环境变量值是否包含占位符
+func HasEnvPlaceholder(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// ValidateEnvTemplate 校验环境变量值中的占位符，只允许引用 EnvTemplateFields 中的字段
+func ValidateEnvTemplate(key, value string) error {
+	_, err := resolveEnvTemplate(key, value, nil)
+	return err
+}
+
+// ResolveEnvTemplates 解析环境变量值中的占位符，返回新的环境变量，不含占位符的值原样保留
+func ResolveEnvTemplates(envVars map[string]string, data EnvTemplateData) (map[string]string, error) {
+	if envVars == nil {
+		return nil, nil
+	}
+	resolved := make(map[string]string, len(envVars))
+	for k, v := range envVars {
+		value, err := resolveEnvTemplate(k, v, &data)
+		if err != nil {
+			return nil, err
+		}
+		resolved[k] = value
+	}
+	return resolved, nil
+}
+
+// resolveEnvTemplate 替换单个环境变量值中的占位符，data 为空时只做校验
+func resolveEnvTemplate(key, value string, data *EnvTemplateData) (string, error) {
+	if !HasEnvPlaceholder(value) {
+		return value, nil
+	}
+	var resolveErr error
+	result := envPlaceholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPlaceholderPattern.FindStringSubmatch(match)[1]
+		v, ok := EnvTemplateData{}.field(name)
+		if !ok {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("environment variable %s references unknown placeholder %s, supported placeholders: %s",
+					key, match, envTemplateFieldList())
+			}
+			return match
+		}
+		if data == nil {
+			return ""
+		}
+		v, _ = data.field(name)
+		if name == "PublicURL" && v == "" && resolveErr == nil {
+			resolveErr = fmt.Errorf("environment variable %s references %s but no public access domain is configured", key, match)
+		}
+		return v
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	// 替换后仍有 "{{" 说明存在无法识别的模板语法
+	if HasEnvPlaceholder(result) {
+		return "", fmt.Errorf("environment variable %s contains an invalid placeholder, supported placeholders: %s", key, envTemplateFieldList())
+	}
+	return result, nil
+}
+
+// envTemplateFieldList 支持的占位符列表，用于错误提示
+func envTemplateFieldList() string {
+	names := make([]string, len(EnvTemplateFields))
+	for i, name := range EnvTemplateFields {
+		names[i] = "{{." + name + "}}"
+	}
+	return strings.Join(names, ", ")
+}
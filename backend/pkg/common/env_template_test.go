@@ -0,0 +1,62 @@
+package common_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestResolveEnvTemplates(t *testing.T) {
+	data := common.EnvTemplateData{
+		InstanceID:  "abc123",
+		PublicURL:   "https://mcp.example.com/mcp/abc123/sse",
+		Port:        8080,
+		ServiceName: "mcp-svc-abc123",
+	}
+	got, err := common.ResolveEnvTemplates(map[string]string{
+		"ID":       "{{.InstanceID}}",
+		"CALLBACK": "{{ .PublicURL }}/callback",
+		"ADDR":     "http://{{.ServiceName}}:{{.Port}}",
+		"PLAIN":    "value",
+	}, data)
+	if err != nil {
+		t.Fatalf("ResolveEnvTemplates() error = %v", err)
+	}
+	want := map[string]string{
+		"ID":       "abc123",
+		"CALLBACK": "https://mcp.example.com/mcp/abc123/sse/callback",
+		"ADDR":     "http://mcp-svc-abc123:8080",
+		"PLAIN":    "value",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveEnvTemplates() = %v, want %v", got, want)
+	}
+
+	if _, err := common.ResolveEnvTemplates(map[string]string{"URL": "{{.PublicURL}}"}, common.EnvTemplateData{InstanceID: "abc123"}); err == nil {
+		t.Error("ResolveEnvTemplates() expected error for PublicURL without public domain")
+	}
+}
+
+func TestValidateEnvTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"plain", "value", false},
+		{"known placeholder", "{{.InstanceID}}-{{.Port}}", false},
+		{"spaces", "{{ .ServiceName }}", false},
+		{"unknown placeholder", "{{.Namespace}}", true},
+		{"lowercase field", "{{.instanceID}}", true},
+		{"template function", `{{printf "%s" .InstanceID}}`, true},
+		{"unterminated", "{{.InstanceID", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := common.ValidateEnvTemplate("KEY", tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnvTemplate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	i18nresp "qm-mcp-server/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error 带错误码的业务错误
+// service/biz 层返回该错误，handler 通过 GinErrorFrom 转换为对应的 HTTP 状态码和本地化消息
+type Error struct {
+	Code int           // i18n 错误码
+	Args []interface{} // 本地化消息参数
+	Err  error         // 原始错误
+	Data interface{}   // 随错误响应返回的数据，如冲突时建议的名称
+}
+
+// NewError 创建带错误码的错误
+func NewError(code int, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+// WrapError 使用错误码包装原始错误，原始错误作为消息的最后一个参数
+// 原始错误已携带映射了 HTTP 状态码的错误码时直接返回原始错误，避免状态码被外层覆盖
+func WrapError(err error, code int, args ...interface{}) *Error {
+	if e, ok := AsError(err); ok && i18nresp.HTTPStatus(e.Code) != http.StatusOK {
+		return e
+	}
+	return &Error{Code: code, Args: append(args, err), Err: err}
+}
+
+// Error 实现 error 接口，使用默认语言输出
+func (e *Error) Error() string {
+	return i18nresp.Format(i18nresp.DefaultLanguage, e.Code, e.Args...)
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrInstanceNotFound 实例不存在
+func ErrInstanceNotFound(instanceID string) *Error {
+	return NewError(i18nresp.CodeInstanceNotFound, instanceID)
+}
+
+// ErrInstanceNameConflict 实例名称冲突
+func ErrInstanceNameConflict(name string) *Error {
+	return NewError(i18nresp.CodeInstanceNameAlreadyExists, name)
+}
+
+// ErrTemplateNotFound 模板不存在
+func ErrTemplateNotFound(templateID interface{}) *Error {
+	return NewError(i18nresp.CodeTemplateNotFound, templateID)
+}
+
+// ErrTemplateNameConflict 模板名称冲突
+func ErrTemplateNameConflict(name string) *Error {
+	return NewError(i18nresp.CodeTemplateNameAlreadyExists, name)
+}
+
+// ErrValidation 字段校验失败
+func ErrValidation(field, reason string) *Error {
+	return NewError(i18nresp.CodeFieldValidationFailed, field, reason)
+}
+
+// ErrRequiredField 必填字段缺失，以字段校验错误列表形式返回
+func ErrRequiredField(field string) error {
+	return ValidationErrors{Required(field)}
+}
+
+// ErrEnvironmentUnreachable 环境不可达
+func ErrEnvironmentUnreachable(err error) *Error {
+	return WrapError(err, i18nresp.CodeEnvironmentUnreachable)
+}
+
+// ErrContainerRuntime 容器运行时错误
+func ErrContainerRuntime(err error) *Error {
+	return WrapError(err, i18nresp.CodeContainerRuntimeError)
+}
+
+// AsError 从错误链中提取带错误码的错误
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// GinErrorFrom 根据错误返回响应
+// 字段校验错误返回 422 及错误列表，带错误码的错误按错误码映射 HTTP 状态码并输出本地化消息，其余错误按内部错误处理
+func GinErrorFrom(c *gin.Context, err error) {
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		GinValidationError(c, ve)
+		return
+	}
+	if e, ok := AsError(err); ok {
+		if e.Data != nil {
+			i18nresp.ErrorWithData(c, e.Code, i18nresp.FormatWithGin(c, e.Code, e.Args...), e.Data)
+			return
+		}
+		i18nresp.ErrorResponse(c, e.Code, i18nresp.FormatWithGin(c, e.Code, e.Args...))
+		return
+	}
+	GinError(c, i18nresp.CodeInternalError, fmt.Sprint(err))
+}
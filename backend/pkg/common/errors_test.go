@@ -0,0 +1,77 @@
+package common_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinErrorFrom(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{
+			name:       "InstanceNotFound",
+			err:        common.ErrInstanceNotFound("a1b2"),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "TemplateNotFoundWrapped",
+			err:        fmt.Errorf("获取模板详情失败: %w", common.ErrTemplateNotFound(1)),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "InstanceNameConflict",
+			err:        common.ErrInstanceNameConflict("demo"),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "TemplateNameConflict",
+			err:        common.ErrTemplateNameConflict("demo"),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "RequiredField",
+			err:        common.ErrRequiredField("name"),
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "EnvironmentUnreachable",
+			err:        common.ErrEnvironmentUnreachable(errors.New("dial tcp: i/o timeout")),
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "ContainerRuntime",
+			err:        fmt.Errorf("重启容器失败: %w", common.ErrContainerRuntime(errors.New("deployment not found"))),
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "UntypedError",
+			err:        errors.New("boom"),
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+			common.GinErrorFrom(c, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GinErrorFrom() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
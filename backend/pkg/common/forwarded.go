@@ -0,0 +1,122 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultTrustedProxies 未配置 publicAccess.trustedProxies 时信任的反向代理：只有本机。
+// 集群内的 ingress 需要在配置中显式加入其所在网段，私有网络中的其他客户端同样可以伪造 X-Forwarded-* 请求头
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+var (
+	trustedProxiesMu sync.RWMutex
+	// trustedProxies 可信反向代理网段，由 SetPublicAccess 根据配置设置
+	trustedProxies, _ = ParseTrustedProxies(DefaultTrustedProxies)
+)
+
+var (
+	// forwardedHostPattern X-Forwarded-Host 允许的取值：域名或 IP，可带端口
+	forwardedHostPattern = regexp.MustCompile(`^(\[[0-9A-Fa-f:.]+\]|[A-Za-z0-9.-]+)(:[0-9]{1,5})?$`)
+	// forwardedPrefixPattern X-Forwarded-Prefix 允许的取值：由 URL 安全字符组成的绝对路径
+	forwardedPrefixPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+)
+
+// Forwarded 反向代理通过 X-Forwarded-* 请求头传递的客户端访问信息，未传递或不可信时为空
+type Forwarded struct {
+	// Scheme 客户端访问使用的协议，http 或 https
+	Scheme string
+	// Host 客户端访问使用的 Host，可带端口
+	Host string
+	// Prefix 反向代理转发前去掉的路径前缀，如 /tenant-a
+	Prefix string
+}
+
+// ParseTrustedProxies 解析可信反向代理列表，每项为 IP 或 CIDR
+func ParseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP or CIDR %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// setTrustedProxies 设置可信反向代理，未配置时使用 DefaultTrustedProxies，非法的配置项在启动校验时已报告，这里忽略
+func setTrustedProxies(entries []string) {
+	if len(entries) == 0 {
+		entries = DefaultTrustedProxies
+	}
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if parsed, err := ParseTrustedProxies([]string{entry}); err == nil {
+			nets = append(nets, parsed...)
+		}
+	}
+	trustedProxiesMu.Lock()
+	defer trustedProxiesMu.Unlock()
+	trustedProxies = nets
+}
+
+// IsTrustedProxy 判断请求的直接来源 (RemoteAddr) 是否为可信反向代理
+func IsTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestForwarded 读取可信反向代理传递的 X-Forwarded-Proto/Host/Prefix
+// 请求不是来自可信反向代理时忽略这些请求头，取值非法的请求头单独忽略，避免伪造的请求头改写返回给客户端的地址
+func RequestForwarded(r *http.Request) Forwarded {
+	var fwd Forwarded
+	if r == nil || !IsTrustedProxy(r.RemoteAddr) {
+		return fwd
+	}
+	if scheme := strings.ToLower(firstHeaderValue(r.Header.Get("X-Forwarded-Proto"))); scheme == "http" || scheme == "https" {
+		fwd.Scheme = scheme
+	}
+	if host := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); forwardedHostPattern.MatchString(host) {
+		fwd.Host = host
+	}
+	if prefix := firstHeaderValue(r.Header.Get("X-Forwarded-Prefix")); prefix != "" {
+		prefix = path.Clean("/" + prefix)
+		if forwardedPrefixPattern.MatchString(prefix) {
+			fwd.Prefix = prefix
+		}
+	}
+	return fwd
+}
@@ -0,0 +1,96 @@
+package common_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestRequestForwarded(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       common.Forwarded
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "127.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "HTTPS, http", "X-Forwarded-Host": "mcp.example.com:8443", "X-Forwarded-Prefix": "/tenant-a/"},
+			want:       common.Forwarded{Scheme: "https", Host: "mcp.example.com:8443", Prefix: "/tenant-a"},
+		},
+		{
+			name:       "private network is not trusted by default",
+			remoteAddr: "10.0.0.2:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example.com"},
+		},
+		{
+			name:       "untrusted client",
+			remoteAddr: "203.0.113.9:52000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example.com"},
+		},
+		{
+			name:       "invalid values are dropped",
+			remoteAddr: "[::1]:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "javascript", "X-Forwarded-Host": "evil.example.com/path", "X-Forwarded-Prefix": "/a b"},
+		},
+		{
+			name:       "prefix is cleaned",
+			remoteAddr: "127.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-Prefix": "tenant-a/../tenant-b"},
+			want:       common.Forwarded{Prefix: "/tenant-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/mcp-gateway/abc/sse", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if got := common.RequestForwarded(req); got != tt.want {
+				t.Errorf("RequestForwarded() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesConfig(t *testing.T) {
+	common.SetPublicAccess(common.PublicAccessConfig{TrustedProxies: []string{"198.51.100.7"}}, "")
+	defer common.SetPublicAccess(common.PublicAccessConfig{}, "")
+
+	if !common.IsTrustedProxy("198.51.100.7:443") {
+		t.Error("IsTrustedProxy() = false for a configured proxy")
+	}
+	if common.IsTrustedProxy("127.0.0.1:443") {
+		t.Error("IsTrustedProxy() = true for a default network replaced by the configuration")
+	}
+
+	req := httptest.NewRequest("GET", "http://gateway.local/mcp-gateway/abc", nil)
+	req.RemoteAddr = "198.51.100.7:443"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Prefix", "/tenant-a")
+	if got, want := common.RequestOrigin(req), "https://gateway.local"; got != want {
+		t.Errorf("RequestOrigin() = %q, want %q", got, want)
+	}
+	if got, want := common.RequestPublicPathPrefix(req), "/tenant-a"+common.GetGatewayRoutePrefix(); got != want {
+		t.Errorf("RequestPublicPathPrefix() = %q, want %q", got, want)
+	}
+
+	if _, err := common.ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies() with an invalid CIDR error = nil")
+	}
+}
+
+func TestRequestPublicPathPrefixConfigured(t *testing.T) {
+	common.SetPublicAccess(common.PublicAccessConfig{PathPrefix: "/api/gateway"}, "")
+	defer common.SetPublicAccess(common.PublicAccessConfig{}, "")
+
+	req := httptest.NewRequest("GET", "/mcp-gateway/abc", nil)
+	req.RemoteAddr = "127.0.0.1:41000"
+	req.Header.Set("X-Forwarded-Prefix", "/tenant-a")
+	if got, want := common.RequestPublicPathPrefix(req), "/api/gateway"; got != want {
+		t.Errorf("RequestPublicPathPrefix() = %q, want %q", got, want)
+	}
+}
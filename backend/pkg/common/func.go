@@ -100,8 +100,8 @@ func SetKubeConfig(byteCfg []byte) *rest.Config {
 	return config
 }
 
-// createTargetProxyConfigForDefatuleHostingImg creates target proxy configuration
-func CreateTargetProxyConfigForDefatuleHostingImg(serviceName string, servicePort int32, mcpName string, mcpProtocol model.McpProtocol) *model.McpServersConfig {
+// CreateTargetProxyConfigForDefaultHostingImage creates target proxy configuration
+func CreateTargetProxyConfigForDefaultHostingImage(serviceName string, servicePort int32, mcpName string, mcpProtocol model.McpProtocol) *model.McpServersConfig {
 	addr := fmt.Sprintf("http://%s:%d", serviceName, servicePort)
 	if mcpProtocol == model.McpProtocolSSE {
 		addr += fmt.Sprintf("/%s", mcpProtocol.String())
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	hostingImagesMu sync.RWMutex
+	// hostingImages 默认托管镜像仓库地址（不含标签），由服务加载配置后通过 SetHostingImage 设置
+	hostingImages = []string{DefaultHostingImage}
+)
+
+// SetHostingImage 设置默认托管镜像，为空时使用内置默认值
+func SetHostingImage(image string) {
+	if image == "" {
+		image = DefaultHostingImage
+	}
+	hostingImagesMu.Lock()
+	defer hostingImagesMu.Unlock()
+	hostingImages = []string{ImageRepository(image)}
+}
+
+// IsDefaultHostingImage 判断镜像是否为默认托管镜像（忽略标签和摘要）
+// overrides 为环境级别覆盖的托管镜像，匹配任意一个即视为默认托管镜像
+func IsDefaultHostingImage(image string, overrides ...string) bool {
+	repo := ImageRepository(image)
+	if repo == "" {
+		return false
+	}
+	for _, override := range overrides {
+		if override != "" && ImageRepository(override) == repo {
+			return true
+		}
+	}
+	hostingImagesMu.RLock()
+	defer hostingImagesMu.RUnlock()
+	for _, hostingImage := range hostingImages {
+		if hostingImage == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageRepository 去掉镜像地址中的标签和摘要，返回仓库地址
+// 例如 registry:5000/org/app:v1 -> registry:5000/org/app
+func ImageRepository(image string) string {
+	image = strings.TrimSpace(image)
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
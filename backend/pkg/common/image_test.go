@@ -0,0 +1,51 @@
+package common_test
+
+import (
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestIsDefaultHostingImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		image      string
+		overrides  []string
+		want       bool
+	}{
+		{"builtin default", "", common.DefaultHostingImage + ":latest", nil, true},
+		{"builtin default without tag", "", common.DefaultHostingImage, nil, true},
+		{"builtin default by digest", "", common.DefaultHostingImage + "@sha256:abc", nil, true},
+		{"other image", "", "ccr.ccs.tencentyun.com/itqm-private/mcp-hosting-custom:v1", nil, false},
+		{"empty image", "", "", nil, false},
+		{"configured mirror", "harbor.local:5000/mcp/mcp-hosting:1.0", "harbor.local:5000/mcp/mcp-hosting:2.0", nil, true},
+		{"builtin default replaced by mirror", "harbor.local:5000/mcp/mcp-hosting", common.DefaultHostingImage, nil, false},
+		{"environment override", "", "registry.internal/mcp-hosting:v2", []string{"", "registry.internal/mcp-hosting"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			common.SetHostingImage(tt.configured)
+			defer common.SetHostingImage("")
+			if got := common.IsDefaultHostingImage(tt.image, tt.overrides...); got != tt.want {
+				t.Errorf("IsDefaultHostingImage(%q, %v) = %v, want %v", tt.image, tt.overrides, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                         "nginx",
+		"nginx:1.27":                    "nginx",
+		"localhost:5000/app":            "localhost:5000/app",
+		"localhost:5000/app:dev":        "localhost:5000/app",
+		"ghcr.io/org/app@sha256:abc":    "ghcr.io/org/app",
+		"ghcr.io/org/app:v1@sha256:abc": "ghcr.io/org/app",
+	}
+	for image, want := range tests {
+		if got := common.ImageRepository(image); got != want {
+			t.Errorf("ImageRepository(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UserLabelPrefix 托管实例的用户标签同步到 Kubernetes Pod 标签时添加的前缀，避免与系统标签冲突
+const UserLabelPrefix = "mcp.user/"
+
+// maxLabelLength Kubernetes 标签名称和标签值的最大长度
+const maxLabelLength = 63
+
+// labelPattern Kubernetes 标签值格式：字母数字开头和结尾，中间可以包含 "-"、"_"、"."
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// 标签选择器操作符
+const (
+	LabelOpEquals       = "="
+	LabelOpNotEquals    = "!="
+	LabelOpExists       = "exists"
+	LabelOpDoesNotExist = "!"
+)
+
+// LabelRequirement 标签选择器中的单个条件
+type LabelRequirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+// LabelValues 标签键及其已使用的值
+type LabelValues struct {
+	Key    string
+	Values []string
+}
+
+// ValidateLabelKey 校验标签键，键加上 UserLabelPrefix 前缀后作为 Kubernetes 标签名称
+func ValidateLabelKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("label key must not be empty")
+	}
+	if len(key) > maxLabelLength {
+		return fmt.Errorf("label key %q must be no more than %d characters", key, maxLabelLength)
+	}
+	if !labelPattern.MatchString(key) {
+		return fmt.Errorf("label key %q must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character", key)
+	}
+	return nil
+}
+
+// ValidateLabelValue 校验标签值，空值合法
+func ValidateLabelValue(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLabelLength {
+		return fmt.Errorf("value of label %q must be no more than %d characters", key, maxLabelLength)
+	}
+	if !labelPattern.MatchString(value) {
+		return fmt.Errorf("value of label %q must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character", key)
+	}
+	return nil
+}
+
+// ValidateLabels 校验实例标签，按键排序后返回第一个错误
+func ValidateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(key, labels[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseLabelSelector 解析逗号分隔的标签选择器
+// 支持 key=value、key==value、key!=value、key（存在）、!key（不存在）
+func ParseLabelSelector(selector string) ([]LabelRequirement, error) {
+	var requirements []LabelRequirement
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req LabelRequirement
+		switch {
+		case strings.HasPrefix(part, "!") && !strings.Contains(part, "="):
+			req = LabelRequirement{Key: strings.TrimSpace(part[1:]), Operator: LabelOpDoesNotExist}
+		case strings.Contains(part, "!="):
+			key, value, _ := strings.Cut(part, "!=")
+			req = LabelRequirement{Key: strings.TrimSpace(key), Operator: LabelOpNotEquals, Value: strings.TrimSpace(value)}
+		case strings.Contains(part, "="):
+			key, value, _ := strings.Cut(part, "=")
+			value = strings.TrimPrefix(value, "=")
+			req = LabelRequirement{Key: strings.TrimSpace(key), Operator: LabelOpEquals, Value: strings.TrimSpace(value)}
+		default:
+			req = LabelRequirement{Key: part, Operator: LabelOpExists}
+		}
+
+		if err := ValidateLabelKey(req.Key); err != nil {
+			return nil, fmt.Errorf("invalid requirement %q: %w", part, err)
+		}
+		if err := ValidateLabelValue(req.Key, req.Value); err != nil {
+			return nil, fmt.Errorf("invalid requirement %q: %w", part, err)
+		}
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
+
+// Matches 标签是否满足条件，键不存在时 != 条件视为满足
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case LabelOpEquals:
+		return ok && value == r.Value
+	case LabelOpNotEquals:
+		return !ok || value != r.Value
+	case LabelOpExists:
+		return ok
+	case LabelOpDoesNotExist:
+		return !ok
+	default:
+		return false
+	}
+}
+
+// MatchLabelSelector 标签是否满足全部条件
+func MatchLabelSelector(requirements []LabelRequirement, labels map[string]string) bool {
+	for _, r := range requirements {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// PodLabels 将实例标签转换为带 UserLabelPrefix 前缀的 Kubernetes Pod 标签
+func PodLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	podLabels := make(map[string]string, len(labels))
+	for key, value := range labels {
+		podLabels[UserLabelPrefix+key] = value
+	}
+	return podLabels
+}
+
+// CollectLabelValues 汇总多个实例的标签，按键和值排序，用于标签自动补全
+func CollectLabelValues(labelSets []map[string]string) []LabelValues {
+	values := make(map[string]map[string]struct{})
+	for _, labels := range labelSets {
+		for key, value := range labels {
+			if values[key] == nil {
+				values[key] = make(map[string]struct{})
+			}
+			values[key][value] = struct{}{}
+		}
+	}
+
+	result := make([]LabelValues, 0, len(values))
+	for key, set := range values {
+		lv := LabelValues{Key: key, Values: make([]string, 0, len(set))}
+		for value := range set {
+			lv.Values = append(lv.Values, value)
+		}
+		sort.Strings(lv.Values)
+		result = append(result, lv)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,122 @@
+package common_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{"valid", map[string]string{"team": "data", "env": "prod", "cost-center": "42"}, false},
+		{"empty value", map[string]string{"team": ""}, false},
+		{"dotted key", map[string]string{"app.tier": "backend"}, false},
+		{"empty key", map[string]string{"": "data"}, true},
+		{"slash in key", map[string]string{"team/name": "data"}, true},
+		{"key starts with dash", map[string]string{"-team": "data"}, true},
+		{"key too long", map[string]string{strings.Repeat("k", 64): "data"}, true},
+		{"value with space", map[string]string{"team": "data eng"}, true},
+		{"value ends with dot", map[string]string{"team": "data."}, true},
+		{"value too long", map[string]string{"team": strings.Repeat("v", 64)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := common.ValidateLabels(tt.labels); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLabels(%v) error = %v, wantErr %v", tt.labels, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []common.LabelRequirement
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"team=data", []common.LabelRequirement{{Key: "team", Operator: common.LabelOpEquals, Value: "data"}}, false},
+		{"team==data", []common.LabelRequirement{{Key: "team", Operator: common.LabelOpEquals, Value: "data"}}, false},
+		{" env != prod , cost-center, !deprecated ", []common.LabelRequirement{
+			{Key: "env", Operator: common.LabelOpNotEquals, Value: "prod"},
+			{Key: "cost-center", Operator: common.LabelOpExists},
+			{Key: "deprecated", Operator: common.LabelOpDoesNotExist},
+		}, false},
+		{"team=", []common.LabelRequirement{{Key: "team", Operator: common.LabelOpEquals}}, false},
+		{"=data", nil, true},
+		{"team=data eng", nil, true},
+		{"!", nil, true},
+		{"team in (a,b)", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := common.ParseLabelSelector(tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLabelSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLabelSelector(%q) = %+v, want %+v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLabelSelector(t *testing.T) {
+	labels := map[string]string{"team": "data", "env": "prod"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"team=data", true},
+		{"team=ml", false},
+		{"team=data,env=prod", true},
+		{"env!=prod", false},
+		{"owner!=alice", true},
+		{"env", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!team", false},
+	}
+	for _, tt := range tests {
+		requirements, err := common.ParseLabelSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q) error = %v", tt.selector, err)
+		}
+		if got := common.MatchLabelSelector(requirements, labels); got != tt.want {
+			t.Errorf("MatchLabelSelector(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestPodLabels(t *testing.T) {
+	got := common.PodLabels(map[string]string{"team": "data"})
+	want := map[string]string{"mcp.user/team": "data"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PodLabels() = %v, want %v", got, want)
+	}
+	if got := common.PodLabels(nil); got != nil {
+		t.Errorf("PodLabels(nil) = %v, want nil", got)
+	}
+}
+
+func TestCollectLabelValues(t *testing.T) {
+	got := common.CollectLabelValues([]map[string]string{
+		{"team": "data", "env": "prod"},
+		{"team": "ml"},
+		nil,
+		{"team": "data", "env": "dev"},
+	})
+	want := []common.LabelValues{
+		{Key: "env", Values: []string{"dev", "prod"}},
+		{Key: "team", Values: []string{"data", "ml"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectLabelValues() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,13 @@
+package common
+
+// MaintenanceState 全局维护模式状态，维护期间拒绝修改实例、模板和环境的请求
+type MaintenanceState struct {
+	// 维护说明，展示在前端横幅中
+	Message string `json:"message,omitempty"`
+	// 开启维护的时间，毫秒时间戳
+	StartedAt int64 `json:"startedAt"`
+	// 预计结束时间，毫秒时间戳，0 表示未指定
+	EndAt int64 `json:"endAt,omitempty"`
+	// 开启维护的用户
+	Operator string `json:"operator,omitempty"`
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 结构化元数据限制，元数据随列表接口返回，限制大小避免响应膨胀
+const (
+	// MaxMetadataSize 元数据序列化后的最大字节数
+	MaxMetadataSize = 8 * 1024
+	// MaxMetadataKeys 元数据顶层键的最大数量
+	MaxMetadataKeys = 50
+	// MaxMetadataKeyLength 元数据键的最大长度
+	MaxMetadataKeyLength = 63
+)
+
+// metadataKeyPattern 元数据选择器中的键，键会写入 JSON 路径，只允许常见的标识符字符
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// MetadataRequirement 元数据选择器中的单个条件，Value 为空时只要求键存在
+type MetadataRequirement struct {
+	Key    string
+	Value  string
+	Exists bool
+}
+
+// ValidateMetadata 校验元数据为 JSON 对象并且不超过大小和键数量限制，空字符串表示未设置
+func ValidateMetadata(metadata string) error {
+	if strings.TrimSpace(metadata) == "" {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(metadata), &fields); err != nil || fields == nil {
+		return fmt.Errorf("must be a JSON object")
+	}
+	if size := len(NormalizeMetadata(metadata)); size > MaxMetadataSize {
+		return fmt.Errorf("must be at most %d bytes, got %d", MaxMetadataSize, size)
+	}
+	if len(fields) > MaxMetadataKeys {
+		return fmt.Errorf("must have at most %d keys, got %d", MaxMetadataKeys, len(fields))
+	}
+	for key := range fields {
+		if key == "" || len(key) > MaxMetadataKeyLength {
+			return fmt.Errorf("key %q must be 1 to %d characters", key, MaxMetadataKeyLength)
+		}
+	}
+	return nil
+}
+
+// NormalizeMetadata 压缩元数据用于保存，未设置或空对象保存为 NULL
+func NormalizeMetadata(metadata string) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(metadata)); err != nil || buf.String() == "{}" || buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// ParseMetadataSelector 解析逗号分隔的元数据选择器，支持 key=value（顶层字符串值相等）和 key（存在）
+func ParseMetadataSelector(selector string) ([]MetadataRequirement, error) {
+	var requirements []MetadataRequirement
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(part, "=")
+		req := MetadataRequirement{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), Exists: !hasValue}
+		if len(req.Key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(req.Key) {
+			return nil, fmt.Errorf("invalid requirement %q: key must start with a letter or digit and contain only letters, digits, '_', '-' or '.'", part)
+		}
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
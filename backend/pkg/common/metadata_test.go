@@ -0,0 +1,71 @@
+package common_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	manyKeys := make([]string, 0, common.MaxMetadataKeys+1)
+	for i := range common.MaxMetadataKeys + 1 {
+		manyKeys = append(manyKeys, fmt.Sprintf(`"k%d":1`, i))
+	}
+	tests := []struct {
+		name     string
+		metadata string
+		wantErr  bool
+	}{
+		{"empty", "", false},
+		{"object", `{"runbook":"https://wiki.example.com/mcp","oncall":{"primary":"alice@example.com"}}`, false},
+		{"array", `["a"]`, true},
+		{"string", `"runbook"`, true},
+		{"null", `null`, true},
+		{"malformed", `{"a":`, true},
+		{"too large", `{"notes":"` + strings.Repeat("x", common.MaxMetadataSize) + `"}`, true},
+		{"too many keys", "{" + strings.Join(manyKeys, ",") + "}", true},
+		{"empty key", `{"":"x"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := common.ValidateMetadata(tt.metadata); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeMetadata(t *testing.T) {
+	if got := string(common.NormalizeMetadata("{\n  \"team\": \"data\"\n}")); got != `{"team":"data"}` {
+		t.Errorf("NormalizeMetadata() = %s, want compact JSON", got)
+	}
+	for _, metadata := range []string{"", "{}", " { } "} {
+		if got := common.NormalizeMetadata(metadata); got != nil {
+			t.Errorf("NormalizeMetadata(%q) = %s, want nil", metadata, got)
+		}
+	}
+}
+
+func TestParseMetadataSelector(t *testing.T) {
+	got, err := common.ParseMetadataSelector("team=payments, oncall ,tier=")
+	if err != nil {
+		t.Fatalf("ParseMetadataSelector() error = %v", err)
+	}
+	want := []common.MetadataRequirement{
+		{Key: "team", Value: "payments"},
+		{Key: "oncall", Exists: true},
+		{Key: "tier"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMetadataSelector() = %+v, want %+v", got, want)
+	}
+
+	for _, selector := range []string{`te"am=x`, "=x", "$.a=b", "!team"} {
+		if _, err := common.ParseMetadataSelector(selector); err == nil {
+			t.Errorf("ParseMetadataSelector(%q) error = nil", selector)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NameSuggestionBase 去掉名称末尾的 "-N" 序号（N >= 2），"github-mcp-2" 返回 "github-mcp"
+func NameSuggestionBase(name string) string {
+	i := strings.LastIndex(name, "-")
+	if i <= 0 {
+		return name
+	}
+	n, err := strconv.Atoi(name[i+1:])
+	if err != nil || n < 2 || strconv.Itoa(n) != name[i+1:] {
+		return name
+	}
+	return name[:i]
+}
+
+// SuggestName 名称已被占用时返回下一个可用的 "name-N" 形式的名称建议
+// 按 NameSuggestionBase 去掉已有的序号后从 2 开始递增，跳过 taken 中已存在的名称
+func SuggestName(name string, taken []string) string {
+	used := make(map[string]struct{}, len(taken))
+	for _, t := range taken {
+		used[t] = struct{}{}
+	}
+	if _, ok := used[name]; !ok {
+		return name
+	}
+	base := NameSuggestionBase(name)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", base, n)
+		if _, ok := used[candidate]; !ok {
+			return candidate
+		}
+	}
+}
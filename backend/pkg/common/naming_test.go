@@ -0,0 +1,51 @@
+package common_test
+
+import (
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestNameSuggestionBase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"github-mcp", "github-mcp"},
+		{"github-mcp-2", "github-mcp"},
+		{"github-mcp-10", "github-mcp"},
+		{"github-mcp-1", "github-mcp-1"},
+		{"github-mcp-02", "github-mcp-02"},
+		{"mcp-v2", "mcp-v2"},
+		{"-2", "-2"},
+		{"2", "2"},
+	}
+	for _, tt := range tests {
+		if got := common.NameSuggestionBase(tt.name); got != tt.want {
+			t.Errorf("NameSuggestionBase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		taken []string
+		want  string
+	}{
+		{"available", "fetch", []string{"fetch-2"}, "fetch"},
+		{"first suggestion", "fetch", []string{"fetch"}, "fetch-2"},
+		{"skip taken", "fetch", []string{"fetch", "fetch-2", "fetch-3"}, "fetch-4"},
+		{"fill gap", "fetch", []string{"fetch", "fetch-3"}, "fetch-2"},
+		{"numbered input", "fetch-2", []string{"fetch", "fetch-2"}, "fetch-3"},
+		{"unrelated names", "fetch", []string{"fetch", "fetch-tool", "fetcher-2"}, "fetch-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := common.SuggestName(tt.input, tt.taken); got != tt.want {
+				t.Errorf("SuggestName(%q, %v) = %q, want %q", tt.input, tt.taken, got, tt.want)
+			}
+		})
+	}
+}
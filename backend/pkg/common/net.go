@@ -1,10 +1,68 @@
 package common
 
 import (
+	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 )
 
+// Endpoint host and port parsed from a service URL
+type Endpoint struct {
+	Scheme string // lower-case scheme, e.g. http / https
+	Host   string // hostname, IPv6 literals without brackets
+	Port   int    // explicit port, or the scheme default
+	Path   string // URL path
+}
+
+// Address returns host:port, IPv6 literals are bracketed
+func (e *Endpoint) Address() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// ParseEndpoint parses an absolute URL into host and port, the port defaults
+// to the scheme's well-known port when omitted
+func ParseEndpoint(rawURL string) (*Endpoint, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("url %q has no host", rawURL)
+	}
+
+	endpoint := &Endpoint{Scheme: strings.ToLower(u.Scheme), Path: u.Path}
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		// 未指定端口，按协议推断
+		endpoint.Host = u.Hostname()
+		port, ok := defaultPorts[endpoint.Scheme]
+		if !ok {
+			return nil, fmt.Errorf("cannot determine port of url %q with scheme %q", rawURL, u.Scheme)
+		}
+		endpoint.Port = port
+		return endpoint, nil
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %q in url %q", portStr, rawURL)
+	}
+	endpoint.Host = host
+	endpoint.Port = port
+	return endpoint, nil
+}
+
+// defaultPorts well-known ports of supported schemes
+var defaultPorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ws":    80,
+	"wss":   443,
+}
+
 // GetPublicIP get current server's public IP address
 func GetPublicIP() (string, error) {
 	// First try to get from environment variable
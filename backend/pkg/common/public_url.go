@@ -0,0 +1,206 @@
+package common
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+var (
+	publicAccessMu sync.RWMutex
+	// publicAccess 对外访问配置，由服务加载配置后通过 SetPublicAccess 设置
+	publicAccess PublicAccessConfig
+)
+
+// SetPublicAccess 设置对外访问配置，未配置 domains 时使用 defaultDomain 作为唯一访问地址
+func SetPublicAccess(cfg PublicAccessConfig, defaultDomain string) {
+	if len(cfg.Domains) == 0 && defaultDomain != "" {
+		cfg.Domains = []PublicDomainConfig{{Name: "default", URL: defaultDomain}}
+	}
+	setTrustedProxies(cfg.TrustedProxies)
+	publicAccessMu.Lock()
+	defer publicAccessMu.Unlock()
+	publicAccess = cfg
+}
+
+// GetPublicPathPrefix 获取对外暴露的网关路径前缀，未配置时使用网关路由前缀
+func GetPublicPathPrefix() string {
+	publicAccessMu.RLock()
+	prefix := publicAccess.PathPrefix
+	publicAccessMu.RUnlock()
+	if prefix == "" {
+		prefix = GetGatewayRoutePrefix()
+	}
+	return path.Join("/", prefix)
+}
+
+// RequestPublicPathPrefix 获取请求对应的对外网关路径前缀，配置了 publicAccess.pathPrefix 时使用配置，
+// 否则在网关路由前缀前加上可信反向代理传递的 X-Forwarded-Prefix
+func RequestPublicPathPrefix(r *http.Request) string {
+	publicAccessMu.RLock()
+	configured := publicAccess.PathPrefix != ""
+	publicAccessMu.RUnlock()
+	if configured {
+		return GetPublicPathPrefix()
+	}
+	return path.Join("/", RequestForwarded(r).Prefix, GetGatewayRoutePrefix())
+}
+
+// RequestOrigin 获取请求的访问来源 (scheme://host)，请求来自可信反向代理时优先使用 X-Forwarded-Proto/Host
+func RequestOrigin(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	fwd := RequestForwarded(r)
+	host := fwd.Host
+	if host == "" {
+		host = r.Host
+	}
+	if host == "" {
+		return ""
+	}
+	scheme := fwd.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + host
+}
+
+// GetPublicBaseURL 根据请求来源选择对外访问地址
+// 优先使用 Host 匹配的域名，其次使用第一个配置的域名，均未配置时使用请求来源
+func GetPublicBaseURL(origin string) string {
+	host := ""
+	if u, err := url.Parse(origin); err == nil {
+		host = u.Host
+	}
+
+	publicAccessMu.RLock()
+	defer publicAccessMu.RUnlock()
+	for _, domain := range publicAccess.Domains {
+		if domainMatchesHost(domain, host) {
+			return strings.TrimRight(domain.URL, "/")
+		}
+	}
+	if len(publicAccess.Domains) > 0 {
+		return strings.TrimRight(publicAccess.Domains[0].URL, "/")
+	}
+	return strings.TrimRight(origin, "/")
+}
+
+// PublicProxyRelativePath 获取实例相对网关前缀的访问路径，如 /{instanceId}/sse
+// 兼容旧数据中保存的完整地址 (域名 + 网关前缀 + 实例路径)，无法识别时返回 false
+func PublicProxyRelativePath(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return path.Join("/", u.Path), true
+	}
+	for _, prefix := range []string{GetGatewayRoutePrefix(), GetPublicPathPrefix()} {
+		prefix = strings.Trim(prefix, "/")
+		if prefix == "" {
+			continue
+		}
+		marker := "/" + prefix + "/"
+		if i := strings.Index(u.Path, marker); i >= 0 {
+			return path.Join("/", u.Path[i+len(marker):]), true
+		}
+	}
+	return "", false
+}
+
+// ResolvePublicProxyURL 将实例相对路径解析为对外访问地址，无法识别的地址原样返回
+func ResolvePublicProxyURL(origin, rawURL string) string {
+	relPath, ok := PublicProxyRelativePath(rawURL)
+	if !ok {
+		return rawURL
+	}
+	return GetPublicBaseURL(origin) + strings.TrimRight(GetPublicPathPrefix(), "/") + relPath
+}
+
+// ResolvePublicProxyConfig 将保存的公共代理配置解析为对外访问地址，用于构建响应
+func ResolvePublicProxyConfig(raw json.RawMessage, origin string) json.RawMessage {
+	resolved, _ := rewritePublicProxyConfig(raw, func(rawURL string) string {
+		return ResolvePublicProxyURL(origin, rawURL)
+	})
+	return resolved
+}
+
+// RelativePublicProxyConfig 将公共代理配置中的完整地址改写为实例相对路径，用于迁移旧数据
+// 返回改写后的配置以及是否发生变化
+func RelativePublicProxyConfig(raw json.RawMessage) (json.RawMessage, bool) {
+	return rewritePublicProxyConfig(raw, func(rawURL string) string {
+		if relPath, ok := PublicProxyRelativePath(rawURL); ok {
+			return relPath
+		}
+		return rawURL
+	})
+}
+
+// rewritePublicProxyConfig 改写配置中每个 MCP 服务的 URL
+func rewritePublicProxyConfig(raw json.RawMessage, rewrite func(string) string) (json.RawMessage, bool) {
+	if len(raw) == 0 {
+		return raw, false
+	}
+	var cfg model.McpServersConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return raw, false
+	}
+	changed := false
+	for _, mcpConfig := range cfg.McpServers {
+		if mcpConfig == nil || mcpConfig.URL == "" {
+			continue
+		}
+		if u := rewrite(mcpConfig.URL); u != mcpConfig.URL {
+			mcpConfig.URL = u
+			changed = true
+		}
+	}
+	if !changed {
+		return raw, false
+	}
+	data, err := json.Marshal(&cfg)
+	if err != nil {
+		return raw, false
+	}
+	return data, true
+}
+
+// domainMatchesHost 判断请求 Host 是否属于该域名，配置未带端口时忽略请求中的端口
+func domainMatchesHost(domain PublicDomainConfig, host string) bool {
+	if host == "" {
+		return false
+	}
+	hosts := domain.Hosts
+	if len(hosts) == 0 {
+		if u, err := url.Parse(domain.URL); err == nil && u.Host != "" {
+			hosts = []string{u.Host}
+		}
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) || (!strings.Contains(h, ":") && strings.EqualFold(h, hostname)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstHeaderValue 获取逗号分隔的请求头中的第一个值
+func firstHeaderValue(value string) string {
+	value, _, _ = strings.Cut(value, ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,81 @@
+package common_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestResolvePublicProxyURL(t *testing.T) {
+	common.SetPublicAccess(common.PublicAccessConfig{
+		Domains: []common.PublicDomainConfig{
+			{Name: "external", URL: "https://mcp.example.com/"},
+			{Name: "internal", URL: "http://10.0.0.8:8080", Hosts: []string{"mcp.internal", "10.0.0.8:8080"}},
+		},
+	}, "")
+	defer common.SetPublicAccess(common.PublicAccessConfig{}, "")
+
+	tests := []struct {
+		name   string
+		origin string
+		rawURL string
+		want   string
+	}{
+		{"external host", "https://mcp.example.com", "/abc/sse", "https://mcp.example.com/mcp-gateway/abc/sse"},
+		{"internal host ignoring port", "http://mcp.internal:30080", "/abc", "http://10.0.0.8:8080/mcp-gateway/abc"},
+		{"internal host with port", "http://10.0.0.8:8080", "/abc", "http://10.0.0.8:8080/mcp-gateway/abc"},
+		{"unknown host uses first domain", "http://localhost:5173", "/abc", "https://mcp.example.com/mcp-gateway/abc"},
+		{"legacy absolute url", "http://mcp.internal", "http://old.example.com/mcp-gateway/abc/sse", "http://10.0.0.8:8080/mcp-gateway/abc/sse"},
+		{"unrecognized url", "", "http://other.example.com/abc", "http://other.example.com/abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := common.ResolvePublicProxyURL(tt.origin, tt.rawURL); got != tt.want {
+				t.Errorf("ResolvePublicProxyURL(%q, %q) = %q, want %q", tt.origin, tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePublicProxyURLPathPrefix(t *testing.T) {
+	common.SetPublicAccess(common.PublicAccessConfig{PathPrefix: "/api/gateway/"}, "http://demo.mcp-box.com")
+	defer common.SetPublicAccess(common.PublicAccessConfig{}, "")
+
+	want := "http://demo.mcp-box.com/api/gateway/abc/sse"
+	if got := common.ResolvePublicProxyURL("http://localhost", "/abc/sse"); got != want {
+		t.Errorf("ResolvePublicProxyURL() = %q, want %q", got, want)
+	}
+}
+
+func TestRelativePublicProxyConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantURL     string
+		wantChanged bool
+	}{
+		{"legacy absolute url", `{"mcpServers":{"mcp-abc":{"type":"sse","url":"http://demo.mcp-box.com/mcp-gateway/abc/sse"}}}`, "/abc/sse", true},
+		{"already relative", `{"mcpServers":{"mcp-abc":{"type":"streamable-http","url":"/abc"}}}`, "/abc", false},
+		{"unrecognized url", `{"mcpServers":{"mcp-abc":{"type":"sse","url":"http://other.example.com/abc"}}}`, "http://other.example.com/abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := common.RelativePublicProxyConfig(json.RawMessage(tt.raw))
+			if changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			var cfg struct {
+				McpServers map[string]struct {
+					URL string `json:"url"`
+				} `json:"mcpServers"`
+			}
+			if err := json.Unmarshal(got, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if url := cfg.McpServers["mcp-abc"].URL; url != tt.wantURL {
+				t.Errorf("url = %q, want %q", url, tt.wantURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+const (
+	// requestTimeoutKey 服务配置中网关等待非 SSE 上游响应的时间（秒）
+	requestTimeoutKey = "timeout"
+	// sseReadTimeoutKey 服务配置中网关代理 SSE 流的最长时间（秒）
+	sseReadTimeoutKey = "sseReadTimeout"
+)
+
+// RequestTimeouts 返回目标配置中的请求超时和 SSE 读取超时（秒），多个服务时按名称顺序取第一个设置了该值的服务，0 表示未设置
+func RequestTimeouts(targetConfig json.RawMessage) (timeout, sseReadTimeout int32) {
+	servers, err := parseServerFields(targetConfig)
+	if err != nil {
+		return 0, 0
+	}
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		fields := servers[name]
+		if timeout == 0 {
+			timeout = serverTimeout(fields, requestTimeoutKey)
+		}
+		if sseReadTimeout == 0 {
+			sseReadTimeout = serverTimeout(fields, sseReadTimeoutKey)
+		}
+	}
+	return timeout, sseReadTimeout
+}
+
+// ApplyRequestTimeouts 将请求超时和 SSE 读取超时（秒）写入目标配置的每个服务，为 0 的值保留服务原有设置。
+// 服务配置中的其他字段原样保留
+func ApplyRequestTimeouts(targetConfig json.RawMessage, timeout, sseReadTimeout int32) (json.RawMessage, error) {
+	if len(targetConfig) == 0 || (timeout <= 0 && sseReadTimeout <= 0) {
+		return targetConfig, nil
+	}
+	servers, err := parseServerFields(targetConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range servers {
+		if fields == nil {
+			continue
+		}
+		if timeout > 0 {
+			fields[requestTimeoutKey] = json.RawMessage(fmt.Sprint(timeout))
+		}
+		if sseReadTimeout > 0 {
+			fields[sseReadTimeoutKey] = json.RawMessage(fmt.Sprint(sseReadTimeout))
+		}
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(targetConfig, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mcp servers config: %w", err)
+	}
+	if config["mcpServers"], err = json.Marshal(servers); err != nil {
+		return nil, err
+	}
+	return MarshalAndAssignConfig(config)
+}
+
+// parseServerFields 按字段解析目标配置中的每个服务，未知字段不会在重新序列化时丢失
+func parseServerFields(targetConfig json.RawMessage) (map[string]map[string]json.RawMessage, error) {
+	var config struct {
+		McpServers map[string]map[string]json.RawMessage `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(targetConfig, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mcp servers config: %w", err)
+	}
+	return config.McpServers, nil
+}
+
+// serverTimeout 读取服务配置中的超时秒数，缺失或格式错误时为 0
+func serverTimeout(fields map[string]json.RawMessage, key string) int32 {
+	var seconds int32
+	if raw, ok := fields[key]; ok && json.Unmarshal(raw, &seconds) == nil && seconds > 0 {
+		return seconds
+	}
+	return 0
+}
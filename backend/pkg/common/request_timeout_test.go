@@ -0,0 +1,55 @@
+package common_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestApplyRequestTimeouts(t *testing.T) {
+	config := json.RawMessage(`{"mcpServers":{"a":{"url":"http://a/mcp","timeout":10,"args":["x"]},"b":{"url":"http://b/sse"}}}`)
+
+	got, err := common.ApplyRequestTimeouts(config, 0, 600)
+	if err != nil {
+		t.Fatalf("ApplyRequestTimeouts() error = %v", err)
+	}
+	var parsed struct {
+		McpServers map[string]map[string]any `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(got, &parsed); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if parsed.McpServers["a"]["timeout"] != float64(10) {
+		t.Errorf("timeout of a = %v, want the existing 10 kept", parsed.McpServers["a"]["timeout"])
+	}
+	if parsed.McpServers["b"]["timeout"] != nil {
+		t.Errorf("timeout of b = %v, want unset", parsed.McpServers["b"]["timeout"])
+	}
+	for _, name := range []string{"a", "b"} {
+		if parsed.McpServers[name]["sseReadTimeout"] != float64(600) {
+			t.Errorf("sseReadTimeout of %s = %v, want 600", name, parsed.McpServers[name]["sseReadTimeout"])
+		}
+	}
+	if parsed.McpServers["a"]["args"] == nil {
+		t.Error("unknown server fields must be kept")
+	}
+
+	if timeout, sseReadTimeout := common.RequestTimeouts(got); timeout != 10 || sseReadTimeout != 600 {
+		t.Errorf("RequestTimeouts() = %d, %d, want 10, 600", timeout, sseReadTimeout)
+	}
+}
+
+func TestApplyRequestTimeoutsUnset(t *testing.T) {
+	config := json.RawMessage(`{"mcpServers":{"a":{"url":"http://a/mcp"}}}`)
+	got, err := common.ApplyRequestTimeouts(config, 0, 0)
+	if err != nil || string(got) != string(config) {
+		t.Errorf("ApplyRequestTimeouts() = %s, %v, want the config unchanged", got, err)
+	}
+	if timeout, sseReadTimeout := common.RequestTimeouts(config); timeout != 0 || sseReadTimeout != 0 {
+		t.Errorf("RequestTimeouts() = %d, %d, want 0, 0", timeout, sseReadTimeout)
+	}
+	if _, err := common.ApplyRequestTimeouts(json.RawMessage(`not json`), 30, 0); err == nil {
+		t.Error("ApplyRequestTimeouts() on invalid config, want error")
+	}
+}
@@ -65,6 +65,12 @@ func BindAndValidateUniversal(c *gin.Context, req interface{}) error {
 			return err
 		}
 	}
+
+	// 5. Run the validator registered for the request type, if any
+	if err := ValidateRequest(req); err != nil {
+		GinErrorFrom(c, err)
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// maxSlugLength 实例自定义路径的最大长度，与 DNS 标签一致
+const maxSlugLength = 63
+
+// slugPattern 实例自定义路径格式：小写字母、数字和 "-"，以字母或数字开头和结尾
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// IsInstanceID 判断网关路径中的实例标识是否为实例 ID，不是时按自定义路径查找
+func IsInstanceID(key string) bool {
+	_, err := uuid.Parse(key)
+	return err == nil
+}
+
+// ValidateInstanceSlug 校验实例自定义路径，网关通过 /{prefix}/{slug}/... 访问实例。
+// 不能是 UUID 格式，否则无法与实例 ID 区分
+func ValidateInstanceSlug(slug string) error {
+	if len(slug) > maxSlugLength {
+		return fmt.Errorf("slug must be no more than %d characters", maxSlugLength)
+	}
+	if !slugPattern.MatchString(slug) {
+		return fmt.Errorf("slug must consist of lowercase letters, digits or '-', and must start and end with a letter or digit")
+	}
+	if IsInstanceID(slug) {
+		return fmt.Errorf("slug must not be a UUID")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package common_test
+
+import (
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+)
+
+func TestValidateInstanceSlug(t *testing.T) {
+	tests := []struct {
+		name    string
+		slug    string
+		wantErr bool
+	}{
+		{"valid", "github", false},
+		{"dashes and digits", "github-mcp-2", false},
+		{"single character", "g", false},
+		{"max length", strings.Repeat("a", 63), false},
+		{"empty", "", true},
+		{"uppercase", "GitHub", true},
+		{"underscore", "github_mcp", true},
+		{"slash", "github/mcp", true},
+		{"starts with dash", "-github", true},
+		{"ends with dash", "github-", true},
+		{"too long", strings.Repeat("a", 64), true},
+		{"uuid", "550e8400-e29b-41d4-a716-446655440000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := common.ValidateInstanceSlug(tt.slug); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateInstanceSlug(%q) error = %v, wantErr %v", tt.slug, err, tt.wantErr)
+			}
+		})
+	}
+}
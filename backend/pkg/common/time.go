@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+const (
+	// APIVersionHeader 客户端通过该请求头协商响应格式，响应中回写实际使用的版本
+	APIVersionHeader = "X-API-Version"
+	// APIVersionV1 原有响应格式，未携带版本头时使用
+	APIVersionV1 = 1
+	// APIVersionV2 响应中的时间统一为 RFC3339 UTC
+	APIVersionV2 = 2
+)
+
+type apiVersionKey struct{}
+
+// ParseAPIVersion 解析版本头，缺失或无法识别时返回 v1，高于当前支持的版本按最高版本处理
+func ParseAPIVersion(header string) int {
+	version, err := strconv.Atoi(header)
+	if err != nil || version < APIVersionV1 {
+		return APIVersionV1
+	}
+	if version > APIVersionV2 {
+		return APIVersionV2
+	}
+	return version
+}
+
+// SetAPIVersionToContext 将协商的 API 版本写入上下文
+func SetAPIVersionToContext(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, apiVersionKey{}, version)
+}
+
+// APIVersionFromContext 从上下文获取协商的 API 版本，默认 v1
+func APIVersionFromContext(ctx context.Context) int {
+	if version, ok := ctx.Value(apiVersionKey{}).(int); ok {
+		return version
+	}
+	return APIVersionV1
+}
+
+// FormatTime 格式化响应中的时间，v2 为 RFC3339 UTC，v1 保持 time.String() 的旧格式
+func FormatTime(ctx context.Context, t time.Time) string {
+	if APIVersionFromContext(ctx) >= APIVersionV2 {
+		return formatTimeUTC(t)
+	}
+	return t.String()
+}
+
+// FormatTimeRFC3339 格式化响应中的时间，v2 为 RFC3339 UTC，v1 保持本地时区的 RFC3339
+func FormatTimeRFC3339(ctx context.Context, t time.Time) string {
+	if APIVersionFromContext(ctx) >= APIVersionV2 {
+		return formatTimeUTC(t)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// TimeMillis 返回毫秒时间戳供前端排序，零值时间返回 0
+func TimeMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// formatTimeUTC 零值时间返回空字符串
+func formatTimeUTC(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseAPIVersion(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int
+	}{
+		{"", APIVersionV1},
+		{"abc", APIVersionV1},
+		{"0", APIVersionV1},
+		{"1", APIVersionV1},
+		{"2", APIVersionV2},
+		{"3", APIVersionV2},
+	}
+	for _, tt := range tests {
+		if got := ParseAPIVersion(tt.header); got != tt.want {
+			t.Errorf("ParseAPIVersion(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, cst)
+	v2 := SetAPIVersionToContext(context.Background(), APIVersionV2)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"v1 legacy", FormatTime(context.Background(), ts), ts.String()},
+		{"v1 rfc3339", FormatTimeRFC3339(context.Background(), ts), "2024-05-01T10:00:00+08:00"},
+		{"v2", FormatTime(v2, ts), "2024-05-01T02:00:00Z"},
+		{"v2 rfc3339", FormatTimeRFC3339(v2, ts), "2024-05-01T02:00:00Z"},
+		{"v2 zero", FormatTime(v2, time.Time{}), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if got := TimeMillis(ts); got != 1714528800000 {
+		t.Errorf("TimeMillis = %d, want 1714528800000", got)
+	}
+	if got := TimeMillis(time.Time{}); got != 0 {
+		t.Errorf("TimeMillis(zero) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	instancepb "qm-mcp-server/api/market/instance"
+	"qm-mcp-server/pkg/database/model"
+)
+
+// defaultTokenWarningDays 未设置时令牌过期预警的天数
+const defaultTokenWarningDays = 7
+
+var (
+	tokenExpiryMu sync.RWMutex
+	// tokenWarningWindow 令牌过期预警窗口，由服务加载配置后通过 SetTokenExpiry 设置
+	tokenWarningWindow = defaultTokenWarningDays * 24 * time.Hour
+)
+
+// SetTokenExpiry 设置令牌过期预警窗口，warningDays 不大于 0 时使用默认值
+func SetTokenExpiry(cfg TokenExpiryConfig) {
+	days := cfg.WarningDays
+	if days <= 0 {
+		days = defaultTokenWarningDays
+	}
+	tokenExpiryMu.Lock()
+	defer tokenExpiryMu.Unlock()
+	tokenWarningWindow = time.Duration(days) * 24 * time.Hour
+}
+
+// TokenWarningWindow 距离过期不足该时长的令牌视为即将过期
+func TokenWarningWindow() time.Duration {
+	tokenExpiryMu.RLock()
+	defer tokenExpiryMu.RUnlock()
+	return tokenWarningWindow
+}
+
+// ConvertToProtoMcpToken converts tokens to proto structure with their expiry state
+func ConvertToProtoMcpToken(tokens []model.McpToken) []*instancepb.McpToken {
+	now := time.Now()
+	window := TokenWarningWindow()
+	protoTokens := make([]*instancepb.McpToken, 0, len(tokens))
+	for i := range tokens {
+		protoTokens = append(protoTokens, ConvertToProtoTokenAt(&tokens[i], now, window))
+	}
+	return protoTokens
+}
+
+// ConvertToProtoTokenAt converts a token to proto structure with its state at now
+func ConvertToProtoTokenAt(token *model.McpToken, now time.Time, window time.Duration) *instancepb.McpToken {
+	return &instancepb.McpToken{
+		Token:           token.Token,
+		ExpireAt:        token.ExpireAt,
+		PublishAt:       token.PublishAt,
+		Usages:          token.Usages,
+		DaysUntilExpiry: token.DaysUntilExpiry(now),
+		State:           token.State(now, window),
+		RotatedAt:       token.RotatedAt,
+	}
+}
@@ -0,0 +1,47 @@
+package common_test
+
+import (
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestConvertToProtoTokenAt(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	window := 7 * 24 * time.Hour
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		token     model.McpToken
+		wantState string
+		wantDays  int32
+	}{
+		{"never expires", model.McpToken{}, model.TokenStateActive, -1},
+		{"active", model.McpToken{ExpireAt: now.Add(30 * day).UnixMilli()}, model.TokenStateActive, 30},
+		{"expiring", model.McpToken{ExpireAt: now.Add(36 * time.Hour).UnixMilli()}, model.TokenStateExpiring, 2},
+		{"rotating", model.McpToken{ExpireAt: now.Add(time.Hour).UnixMilli(), RotatedAt: now.UnixMilli()}, model.TokenStateRotating, 1},
+		{"expired", model.McpToken{ExpireAt: now.Add(-time.Minute).UnixMilli(), RotatedAt: now.Add(-day).UnixMilli()}, model.TokenStateExpired, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := common.ConvertToProtoTokenAt(&tt.token, now, window)
+			if got.State != tt.wantState || got.DaysUntilExpiry != tt.wantDays {
+				t.Errorf("state = %s days = %d, want %s and %d", got.State, got.DaysUntilExpiry, tt.wantState, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestSetTokenExpiry(t *testing.T) {
+	defer common.SetTokenExpiry(common.TokenExpiryConfig{})
+	common.SetTokenExpiry(common.TokenExpiryConfig{WarningDays: 3})
+	if got := common.TokenWarningWindow(); got != 72*time.Hour {
+		t.Errorf("TokenWarningWindow() = %s, want 72h", got)
+	}
+	common.SetTokenExpiry(common.TokenExpiryConfig{})
+	if got := common.TokenWarningWindow(); got != 7*24*time.Hour {
+		t.Errorf("TokenWarningWindow() default = %s, want 168h", got)
+	}
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	i18nresp "qm-mcp-server/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 校验规则
+const (
+	RuleRequired = "required"
+	RuleMin      = "min"
+	RuleRange    = "range"
+	RuleJSON     = "json"
+	RuleInvalid  = "invalid"
+)
+
+// ruleCodes 校验规则对应的 i18n 错误码
+var ruleCodes = map[string]int{
+	RuleRequired: i18nresp.CodeRuleRequired,
+	RuleMin:      i18nresp.CodeRuleMin,
+	RuleRange:    i18nresp.CodeRuleRange,
+	RuleJSON:     i18nresp.CodeRuleJSON,
+	RuleInvalid:  i18nresp.CodeRuleInvalid,
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string        `json:"field"`
+	Rule    string        `json:"rule"`
+	Message string        `json:"message"`
+	args    []interface{} // 本地化消息参数（不含字段名）
+}
+
+// ValidationErrors 字段校验错误列表
+type ValidationErrors []*FieldError
+
+// Error 实现 error 接口，使用默认语言输出
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(ve))
+	for _, fe := range ve {
+		msgs = append(msgs, fe.localize(i18nresp.DefaultLanguage))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// localize 按语言生成字段错误消息
+func (fe *FieldError) localize(lang i18nresp.SupportedLanguage) string {
+	code, ok := ruleCodes[fe.Rule]
+	if !ok {
+		code = i18nresp.CodeRuleInvalid
+	}
+	return i18nresp.Format(lang, code, append([]interface{}{fe.Field}, fe.args...)...)
+}
+
+// Required 字段不能为空
+func Required(field string) *FieldError {
+	return &FieldError{Field: field, Rule: RuleRequired}
+}
+
+// Min 字段必须不小于 min
+func Min(field string, min interface{}) *FieldError {
+	return &FieldError{Field: field, Rule: RuleMin, args: []interface{}{min}}
+}
+
+// Range 字段必须在 [min, max] 范围内
+func Range(field string, min, max interface{}) *FieldError {
+	return &FieldError{Field: field, Rule: RuleRange, args: []interface{}{min, max}}
+}
+
+// InvalidJSON 字段不是合法的 JSON
+func InvalidJSON(field string, reason string) *FieldError {
+	return &FieldError{Field: field, Rule: RuleJSON, args: []interface{}{reason}}
+}
+
+// Invalid 字段值无效
+func Invalid(field string, reason string) *FieldError {
+	return &FieldError{Field: field, Rule: RuleInvalid, args: []interface{}{reason}}
+}
+
+// Validation 收集校验错误
+type Validation struct {
+	errs ValidationErrors
+}
+
+// Add 添加校验错误，nil 会被忽略
+func (v *Validation) Add(errs ...*FieldError) *Validation {
+	for _, fe := range errs {
+		if fe != nil {
+			v.errs = append(v.errs, fe)
+		}
+	}
+	return v
+}
+
+// Required 字符串字段必填
+func (v *Validation) Required(field, value string) *Validation {
+	if strings.TrimSpace(value) == "" {
+		v.Add(Required(field))
+	}
+	return v
+}
+
+// RequiredInt 整型字段必填（必须大于 0）
+func (v *Validation) RequiredInt(field string, value int64) *Validation {
+	if value <= 0 {
+		v.Add(Required(field))
+	}
+	return v
+}
+
+// Range 整型字段范围校验，0 表示未设置时跳过
+func (v *Validation) Range(field string, value, min, max int64) *Validation {
+	if value != 0 && (value < min || value > max) {
+		v.Add(Range(field, min, max))
+	}
+	return v
+}
+
+// JSON 字段为合法 JSON，空值跳过
+func (v *Validation) JSON(field, value string) *Validation {
+	if value != "" && !json.Valid([]byte(value)) {
+		v.Add(InvalidJSON(field, "malformed JSON"))
+	}
+	return v
+}
+
+// Err 返回校验结果，无错误时返回 nil
+func (v *Validation) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// RequestValidator 请求校验函数
+type RequestValidator func(req interface{}) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = make(map[reflect.Type]RequestValidator)
+)
+
+// RegisterValidator 为请求类型注册校验函数，BindAndValidate 绑定参数后自动调用
+func RegisterValidator[T any](fn func(req *T) error) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[reflect.TypeOf((*T)(nil))] = func(req interface{}) error {
+		return fn(req.(*T))
+	}
+}
+
+// ValidateRequest 执行请求类型注册的校验函数
+func ValidateRequest(req interface{}) error {
+	validatorsMu.RLock()
+	fn, ok := validators[reflect.TypeOf(req)]
+	validatorsMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return fn(req)
+}
+
+// GinValidationError 返回字段校验错误列表，HTTP 状态码 422
+func GinValidationError(c *gin.Context, ve ValidationErrors) {
+	lang := i18nresp.GetLanguageFromGin(c)
+	for _, fe := range ve {
+		fe.Message = fe.localize(lang)
+	}
+	i18nresp.ErrorWithData(c, i18nresp.CodeRequestValidationFailed, "", ve)
+}
@@ -0,0 +1,106 @@
+package container
+
+import (
+	"sync"
+	"time"
+)
+
+// EntryCache runtime entry cache
+// Entries are keyed by an identifier (e.g. environment ID) and a config hash, so a changed
+// config never reuses a stale clientset. Concurrent first use of the same key creates the
+// entry only once, and entries unused for longer than ttl are evicted.
+type EntryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cachedEntry
+	calls   map[string]*entryCall
+	now     func() time.Time
+}
+
+// cachedEntry cached runtime entry
+type cachedEntry struct {
+	entry    *Entry
+	hash     string
+	lastUsed time.Time
+}
+
+// entryCall in-flight entry creation shared by concurrent callers
+type entryCall struct {
+	done  chan struct{}
+	hash  string
+	entry *Entry
+	err   error
+}
+
+// NewEntryCache creates runtime entry cache, ttl <= 0 disables idle eviction
+func NewEntryCache(ttl time.Duration) *EntryCache {
+	return &EntryCache{
+		ttl:     ttl,
+		entries: make(map[string]*cachedEntry),
+		calls:   make(map[string]*entryCall),
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached entry for key, creating it with create when missing,
+// expired or cached with a different config hash
+func (c *EntryCache) Get(key, hash string, create func() (*Entry, error)) (*Entry, error) {
+	c.mu.Lock()
+	c.evictExpired()
+	if ce, ok := c.entries[key]; ok && ce.hash == hash {
+		ce.lastUsed = c.now()
+		c.mu.Unlock()
+		return ce.entry, nil
+	}
+	if call, ok := c.calls[key]; ok && call.hash == hash {
+		c.mu.Unlock()
+		<-call.done
+		return call.entry, call.err
+	}
+	call := &entryCall{done: make(chan struct{}), hash: hash}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.entry, call.err = create()
+
+	c.mu.Lock()
+	// Invalidate during creation drops the call, its result must not be cached
+	if c.calls[key] == call {
+		delete(c.calls, key)
+		if call.err == nil {
+			c.entries[key] = &cachedEntry{entry: call.entry, hash: hash, lastUsed: c.now()}
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.entry, call.err
+}
+
+// Invalidate removes the cached entry for key
+func (c *EntryCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	delete(c.calls, key)
+}
+
+// Len returns the number of cached entries
+func (c *EntryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evictExpired removes entries idle longer than ttl, caller must hold mu
+func (c *EntryCache) evictExpired() {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+	for key, ce := range c.entries {
+		if now.Sub(ce.lastUsed) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package container_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/container"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestEntryCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		run         func(cache *container.EntryCache, create func() (*container.Entry, error))
+		wantCreates int32
+	}{
+		{
+			name: "ReuseSameHash",
+			ttl:  time.Minute,
+			run: func(cache *container.EntryCache, create func() (*container.Entry, error)) {
+				cache.Get("1", "a", create)
+				cache.Get("1", "a", create)
+			},
+			wantCreates: 1,
+		},
+		{
+			name: "RecreateOnHashChange",
+			ttl:  time.Minute,
+			run: func(cache *container.EntryCache, create func() (*container.Entry, error)) {
+				cache.Get("1", "a", create)
+				cache.Get("1", "b", create)
+			},
+			wantCreates: 2,
+		},
+		{
+			name: "RecreateAfterInvalidate",
+			ttl:  time.Minute,
+			run: func(cache *container.EntryCache, create func() (*container.Entry, error)) {
+				cache.Get("1", "a", create)
+				cache.Invalidate("1")
+				cache.Get("1", "a", create)
+			},
+			wantCreates: 2,
+		},
+		{
+			name: "RecreateAfterIdleTTL",
+			ttl:  10 * time.Millisecond,
+			run: func(cache *container.EntryCache, create func() (*container.Entry, error)) {
+				cache.Get("1", "a", create)
+				time.Sleep(30 * time.Millisecond)
+				cache.Get("1", "a", create)
+			},
+			wantCreates: 2,
+		},
+		{
+			name: "ConcurrentFirstUse",
+			ttl:  time.Minute,
+			run: func(cache *container.EntryCache, create func() (*container.Entry, error)) {
+				var wg sync.WaitGroup
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						cache.Get("1", "a", create)
+					}()
+				}
+				wg.Wait()
+			},
+			wantCreates: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var creates int32
+			create := func() (*container.Entry, error) {
+				atomic.AddInt32(&creates, 1)
+				time.Sleep(5 * time.Millisecond)
+				return container.NewEntry(container.Config{Runtime: container.RuntimeDocker})
+			}
+			cache := container.NewEntryCache(tt.ttl)
+
+			tt.run(cache, create)
+
+			if got := atomic.LoadInt32(&creates); got != tt.wantCreates {
+				t.Errorf("create calls = %d, want %d", got, tt.wantCreates)
+			}
+		})
+	}
+}
+
+// newStubAPIServer 模拟 Kubernetes API Server，仅响应命名空间查询
+func newStubAPIServer(b *testing.B) *rest.Config {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"default"}}`))
+	}))
+	b.Cleanup(server.Close)
+	return &rest.Config{Host: server.URL}
+}
+
+// BenchmarkGetRuntimeEntry 对比每次新建 Entry 与使用缓存的耗时
+// 本地 stub API Server 下新建约 1ms（clientset 构建 + 命名空间校验请求），缓存命中为微秒级以下
+func BenchmarkGetRuntimeEntry(b *testing.B) {
+	cfg := container.Config{Runtime: container.RuntimeKubernetes, Namespace: "default", Kubeconfig: newStubAPIServer(b)}
+
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := container.NewEntry(cfg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Cached", func(b *testing.B) {
+		cache := container.NewEntryCache(time.Minute)
+		create := func() (*container.Entry, error) { return container.NewEntry(cfg) }
+		for i := 0; i < b.N; i++ {
+			if _, err := cache.Get("1", "hash", create); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"time"
@@ -69,6 +70,13 @@ type DockerPort struct {
 
 // Create creates container
 func (dcm *DockerContainerManager) Create(ctx context.Context, options ContainerCreateOptions) (string, error) {
+	if len(options.InitContainers) > 0 || len(options.Sidecars) > 0 {
+		return "", fmt.Errorf("init containers and sidecars are not supported in Docker environment")
+	}
+	if len(options.ConfigFiles) > 0 {
+		return "", fmt.Errorf("config files are not supported in Docker environment")
+	}
+
 	// Build docker run command
 	args := []string{"run", "-d"}
 
@@ -137,8 +145,8 @@ func (dcm *DockerContainerManager) Create(ctx context.Context, options Container
 	// Set health check
 	if options.ReadinessProbe != nil {
 		if options.ReadinessProbe.HTTPGet != nil {
-			healthCmd := fmt.Sprintf("curl -f http://localhost:%d%s || exit 1",
-				options.ReadinessProbe.HTTPGet.Port,
+			healthCmd := fmt.Sprintf("curl -f http://localhost:%s%s || exit 1",
+				options.ReadinessProbe.HTTPGet.Port.String(),
 				options.ReadinessProbe.HTTPGet.Path)
 			args = append(args, "--health-cmd", healthCmd)
 			args = append(args, "--health-interval", "30s")
@@ -239,13 +247,21 @@ func (dcm *DockerContainerManager) GetInfo(ctx context.Context, containerName st
 	// Get container IP
 	ip, _ := dcm.getContainerIP(ctx, containerName)
 
+	// Docker runs a single container per instance
+	var readyReplicas int32
+	if dockerInfo.State == "running" {
+		readyReplicas = 1
+	}
+
 	return &ContainerInfo{
-		Name:      strings.TrimPrefix(dockerInfo.Names[0], "/"),
-		Status:    dockerInfo.State,
-		IP:        ip,
-		Ports:     ports,
-		Labels:    dockerInfo.Labels,
-		CreatedAt: time.Unix(dockerInfo.Created, 0).Format("2006-01-02 15:04:05"),
+		Name:          strings.TrimPrefix(dockerInfo.Names[0], "/"),
+		Status:        dockerInfo.State,
+		IP:            ip,
+		Ports:         ports,
+		Labels:        dockerInfo.Labels,
+		CreatedAt:     time.Unix(dockerInfo.Created, 0).Format("2006-01-02 15:04:05"),
+		Replicas:      1,
+		ReadyReplicas: readyReplicas,
 	}, nil
 }
 
@@ -284,7 +300,7 @@ func (dcm *DockerContainerManager) IsReady(ctx context.Context, containerName st
 // GetEvents gets container events (Docker doesn't have direct event concept, returns log information)
 func (dcm *DockerContainerManager) GetEvents(ctx context.Context, containerName string) ([]ContainerEvent, error) {
 	// Docker doesn't have an event system like Kubernetes, here we return the last few lines of container logs as events
-	logs, err := dcm.GetLogs(ctx, containerName, 10)
+	logs, err := dcm.GetLogs(ctx, containerName, LogOptions{Lines: 10})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get container logs: %w", err)
 	}
@@ -306,14 +322,18 @@ func (dcm *DockerContainerManager) GetEvents(ctx context.Context, containerName
 	return events, nil
 }
 
-// GetLogs gets container logs
-func (dcm *DockerContainerManager) GetLogs(ctx context.Context, containerName string, lines int64) (string, error) {
+// GetLogs gets container logs, Docker keeps the logs across restarts so previous is ignored
+func (dcm *DockerContainerManager) GetLogs(ctx context.Context, containerName string, options LogOptions) (string, error) {
+	if options.Container != "" {
+		return "", fmt.Errorf("container selector is not supported in Docker environment")
+	}
+
 	// Build docker logs command
 	args := []string{"logs"}
 
 	// Set line limit
-	if lines > 0 {
-		args = append(args, "--tail", fmt.Sprintf("%d", lines))
+	if options.Lines > 0 {
+		args = append(args, "--tail", fmt.Sprintf("%d", options.Lines))
 	}
 
 	// Add container name
@@ -329,6 +349,21 @@ func (dcm *DockerContainerManager) GetLogs(ctx context.Context, containerName st
 	return string(output), nil
 }
 
+// GetSpec gets running container spec (Docker environment not supported)
+func (dcm *DockerContainerManager) GetSpec(ctx context.Context, containerName string) (*ContainerSpec, error) {
+	return nil, fmt.Errorf("Docker environment does not support drift detection")
+}
+
+// StreamLogs streams container logs (Docker environment not supported)
+func (dcm *DockerContainerManager) StreamLogs(ctx context.Context, containerName string, options LogStreamOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Docker environment does not support streaming container logs")
+}
+
+// List lists containers by labels (Docker environment not supported)
+func (dcm *DockerContainerManager) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	return nil, fmt.Errorf("Docker environment does not support listing containers by label")
+}
+
 // GetWarningEvents gets container warning events
 func (dcm *DockerContainerManager) GetWarningEvents(ctx context.Context, containerName string) ([]ContainerEvent, error) {
 	// Check if container has error status
@@ -410,6 +445,16 @@ func (dsm *DockerServiceManager) Get(ctx context.Context, serviceName string) (*
 	}, nil
 }
 
+// GetReadyAddresses gets ready backend addresses of a service
+func (dsm *DockerServiceManager) GetReadyAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	return nil, fmt.Errorf("Docker environment does not support resolving service endpoints")
+}
+
+// List lists services by labels (Docker environment not supported)
+func (dsm *DockerServiceManager) List(ctx context.Context, labels map[string]string) ([]ServiceInfo, error) {
+	return nil, fmt.Errorf("Docker environment does not support listing services by label")
+}
+
 // Restart restarts service
 func (dsm *DockerServiceManager) Restart(ctx context.Context, options ContainerCreateOptions) error {
 	// Get existing service information
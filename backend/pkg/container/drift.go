@@ -0,0 +1,149 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"qm-mcp-server/pkg/k8s"
+)
+
+// Drift kinds
+const (
+	DriftChanged = "changed" // value differs between stored config and running container
+	DriftMissing = "missing" // present in stored config but not in running container
+	DriftExtra   = "extra"   // present in running container but not in stored config
+)
+
+// Drift fields
+const (
+	DriftFieldImage      = "image"
+	DriftFieldCommand    = "command"
+	DriftFieldArgs       = "args"
+	DriftFieldWorkingDir = "workingDir"
+	DriftFieldEnv        = "env"
+	DriftFieldMount      = "mount"
+	DriftFieldReplicas   = "replicas"
+	// DriftFieldImageDigest the image tag now resolves to a different digest than when the container was created
+	DriftFieldImageDigest = "imageDigest"
+)
+
+// ContainerSpec running container spec fetched from the runtime
+type ContainerSpec struct {
+	Image      string             // image name
+	Command    []string           // execution command
+	Args       []string           // command arguments
+	WorkingDir string             // working directory
+	EnvVars    map[string]string  // environment variables with literal values
+	Mounts     []k8s.UnifiedMount // hostPath and PVC volume mounts
+	Replicas   int32              // desired replica count
+}
+
+// DriftItem a single difference between stored config and running container
+type DriftItem struct {
+	Field    string // drifted field, see DriftField constants
+	Kind     string // drift kind, see DriftChanged/DriftMissing/DriftExtra
+	Name     string // env var name or mount path, empty for single-valued fields
+	Expected string // value in stored config, env values are never reported
+	Actual   string // value in running container, env values are never reported
+}
+
+// String formats the drift item for messages and logs
+func (d DriftItem) String() string {
+	target := d.Field
+	if d.Name != "" {
+		target = fmt.Sprintf("%s %s", d.Field, d.Name)
+	}
+	if d.Kind == DriftChanged && (d.Expected != "" || d.Actual != "") {
+		return fmt.Sprintf("%s %s (%s -> %s)", target, d.Kind, d.Expected, d.Actual)
+	}
+	return fmt.Sprintf("%s %s", target, d.Kind)
+}
+
+// ComputeDrift compares the stored create options and replica count with the running container spec,
+// returns the differences sorted by field and name, empty when in sync
+func ComputeDrift(expected ContainerCreateOptions, replicas int32, actual *ContainerSpec) []DriftItem {
+	var items []DriftItem
+	changed := func(field, want, got string) {
+		if want != got {
+			items = append(items, DriftItem{Field: field, Kind: DriftChanged, Expected: want, Actual: got})
+		}
+	}
+
+	changed(DriftFieldImage, expected.ImageName, actual.Image)
+	changed(DriftFieldCommand, strings.Join(expected.Command, " "), strings.Join(actual.Command, " "))
+	changed(DriftFieldArgs, strings.Join(expected.CommandArgs, " "), strings.Join(actual.Args, " "))
+	changed(DriftFieldWorkingDir, expected.WorkingDir, actual.WorkingDir)
+	if replicas != actual.Replicas {
+		items = append(items, DriftItem{Field: DriftFieldReplicas, Kind: DriftChanged,
+			Expected: fmt.Sprint(replicas), Actual: fmt.Sprint(actual.Replicas)})
+	}
+
+	// 环境变量可能包含密钥，只报告变量名
+	for name, value := range expected.EnvVars {
+		got, ok := actual.EnvVars[name]
+		switch {
+		case !ok:
+			items = append(items, DriftItem{Field: DriftFieldEnv, Kind: DriftMissing, Name: name})
+		case got != value:
+			items = append(items, DriftItem{Field: DriftFieldEnv, Kind: DriftChanged, Name: name})
+		}
+	}
+	for name := range actual.EnvVars {
+		if _, ok := expected.EnvVars[name]; !ok {
+			items = append(items, DriftItem{Field: DriftFieldEnv, Kind: DriftExtra, Name: name})
+		}
+	}
+
+	// 挂载按容器内路径比较
+	actualMounts := make(map[string]k8s.UnifiedMount, len(actual.Mounts))
+	for _, m := range actual.Mounts {
+		actualMounts[m.MountPath] = m
+	}
+	expectedMounts := make(map[string]bool, len(expected.Mounts))
+	for _, m := range expected.Mounts {
+		expectedMounts[m.MountPath] = true
+		got, ok := actualMounts[m.MountPath]
+		if !ok {
+			items = append(items, DriftItem{Field: DriftFieldMount, Kind: DriftMissing, Name: m.MountPath, Expected: mountSource(m)})
+			continue
+		}
+		if want, have := mountSource(m), mountSource(got); want != have {
+			items = append(items, DriftItem{Field: DriftFieldMount, Kind: DriftChanged, Name: m.MountPath, Expected: want, Actual: have})
+		}
+	}
+	for _, m := range actual.Mounts {
+		if !expectedMounts[m.MountPath] {
+			items = append(items, DriftItem{Field: DriftFieldMount, Kind: DriftExtra, Name: m.MountPath, Actual: mountSource(m)})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Field != items[j].Field {
+			return items[i].Field < items[j].Field
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items
+}
+
+// mountSource describes where a mount comes from, e.g. "pvc:data/sub (ro)"
+func mountSource(m k8s.UnifiedMount) string {
+	var source string
+	switch m.Type {
+	case k8s.MountTypeHostPath:
+		source = "hostPath:" + m.HostPath
+	case k8s.MountTypePVC:
+		source = "pvc:" + m.PVCName
+	default:
+		source = string(m.Type)
+	}
+	// 只有 PVC 挂载会设置子路径
+	if m.Type == k8s.MountTypePVC && m.SubPath != "" {
+		source += "/" + m.SubPath
+	}
+	if m.ReadOnly {
+		source += " (ro)"
+	}
+	return source
+}
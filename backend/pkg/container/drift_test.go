@@ -0,0 +1,77 @@
+package container_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/k8s"
+)
+
+func TestComputeDrift(t *testing.T) {
+	expected := container.ContainerCreateOptions{
+		ImageName:   "mcp/server:1.0",
+		Command:     []string{"node"},
+		CommandArgs: []string{"index.js", "--port", "8080"},
+		EnvVars:     map[string]string{"API_KEY": "secret", "LOG_LEVEL": "info"},
+		Mounts: []k8s.UnifiedMount{
+			{Type: k8s.MountTypePVC, MountPath: "/data", PVCName: "data", SubPath: "mcp"},
+			{Type: k8s.MountTypeHostPath, MountPath: "/cache", HostPath: "/var/cache/mcp", SubPath: "ignored"},
+		},
+	}
+	inSync := &container.ContainerSpec{
+		Image:    "mcp/server:1.0",
+		Command:  []string{"node"},
+		Args:     []string{"index.js", "--port", "8080"},
+		EnvVars:  map[string]string{"API_KEY": "secret", "LOG_LEVEL": "info"},
+		Replicas: 2,
+		Mounts: []k8s.UnifiedMount{
+			{Type: k8s.MountTypeHostPath, MountPath: "/cache", HostPath: "/var/cache/mcp"},
+			{Type: k8s.MountTypePVC, MountPath: "/data", PVCName: "data", SubPath: "mcp"},
+		},
+	}
+	if items := container.ComputeDrift(expected, 2, inSync); len(items) != 0 {
+		t.Fatalf("ComputeDrift() on matching spec = %v, want no drift", items)
+	}
+
+	drifted := &container.ContainerSpec{
+		Image:    "mcp/server:1.1",
+		Command:  []string{"node"},
+		Args:     []string{"index.js", "--port", "8080"},
+		EnvVars:  map[string]string{"API_KEY": "rotated", "DEBUG": "1"},
+		Replicas: 1,
+		Mounts: []k8s.UnifiedMount{
+			{Type: k8s.MountTypePVC, MountPath: "/data", PVCName: "data-restore", SubPath: "mcp"},
+			{Type: k8s.MountTypeHostPath, MountPath: "/tmp/debug", HostPath: "/tmp", ReadOnly: true},
+		},
+	}
+	want := []container.DriftItem{
+		{Field: container.DriftFieldEnv, Kind: container.DriftChanged, Name: "API_KEY"},
+		{Field: container.DriftFieldEnv, Kind: container.DriftExtra, Name: "DEBUG"},
+		{Field: container.DriftFieldEnv, Kind: container.DriftMissing, Name: "LOG_LEVEL"},
+		{Field: container.DriftFieldImage, Kind: container.DriftChanged, Expected: "mcp/server:1.0", Actual: "mcp/server:1.1"},
+		{Field: container.DriftFieldMount, Kind: container.DriftMissing, Name: "/cache", Expected: "hostPath:/var/cache/mcp"},
+		{Field: container.DriftFieldMount, Kind: container.DriftChanged, Name: "/data", Expected: "pvc:data/mcp", Actual: "pvc:data-restore/mcp"},
+		{Field: container.DriftFieldMount, Kind: container.DriftExtra, Name: "/tmp/debug", Actual: "hostPath:/tmp (ro)"},
+		{Field: container.DriftFieldReplicas, Kind: container.DriftChanged, Expected: "2", Actual: "1"},
+	}
+	if got := container.ComputeDrift(expected, 2, drifted); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeDrift() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDriftItemString(t *testing.T) {
+	tests := []struct {
+		item container.DriftItem
+		want string
+	}{
+		{container.DriftItem{Field: "image", Kind: container.DriftChanged, Expected: "a:1", Actual: "a:2"}, "image changed (a:1 -> a:2)"},
+		{container.DriftItem{Field: "env", Kind: container.DriftChanged, Name: "API_KEY"}, "env API_KEY changed"},
+		{container.DriftItem{Field: "mount", Kind: container.DriftExtra, Name: "/tmp", Actual: "hostPath:/tmp"}, "mount /tmp extra"},
+	}
+	for _, tt := range tests {
+		if got := tt.item.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// HostingStartupScriptPath mounted startup script of hosted MCP containers
+	HostingStartupScriptPath = "/app/init/startup.sh"
+	// HostingMcpServersConfigPath mounted mcpServers config served by mcp-hosting
+	HostingMcpServersConfigPath = "/app/mcp-servers.json"
+)
+
+// HostingScript startup script of a hosted MCP container.
+// Every field is written verbatim into a mounted file, the container command line only executes
+// the mounted script, so the content can not change how the script itself is generated.
+type HostingScript struct {
+	CodePackageInstall string // downloads and extracts the code package
+	InitScript         string // user initialization script
+	Command            string // startup command of SSE and streamable HTTP servers
+	McpServersConfig   string // mcpServers config of stdio servers, served by mcp-hosting
+	Port               int32  // mcp-hosting port of stdio servers
+}
+
+// Build returns the container command, arguments and the files to mount.
+// Servers with a McpServersConfig are started by mcp-hosting, others run Command.
+func (h HostingScript) Build() (command []string, args []string, files []ConfigFile) {
+	initScript := h.InitScript
+	if len(strings.TrimSpace(initScript)) == 0 {
+		initScript = "echo 'No initialization commands specified'"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\nset -e\n\n")
+	sb.WriteString("# Download and extract code package\n")
+	sb.WriteString(h.CodePackageInstall)
+	sb.WriteString("\n\necho \"[$(date)] Starting initialization script execution...\"\n")
+	sb.WriteString(initScript)
+	sb.WriteString("\necho \"[$(date)] Initialization script execution completed\"\n\n")
+
+	if h.McpServersConfig != "" {
+		hosting := fmt.Sprintf("mcp-hosting --port=%d --mcp-servers-config %s", h.Port, HostingMcpServersConfigPath)
+		sb.WriteString(fmt.Sprintf("echo \"[$(date)] Starting main program: %s\"\n", hosting))
+		sb.WriteString("exec " + hosting + "\n")
+		files = append(files, ConfigFile{Path: HostingMcpServersConfigPath, Content: h.McpServersConfig, Mode: 0644})
+	} else {
+		sb.WriteString("echo \"Starting startup command script\"\n")
+		sb.WriteString(h.Command)
+		sb.WriteString("\n")
+	}
+
+	files = append([]ConfigFile{{Path: HostingStartupScriptPath, Content: sb.String(), Mode: 0755}}, files...)
+	return []string{"/bin/sh"}, []string{HostingStartupScriptPath}, files
+}
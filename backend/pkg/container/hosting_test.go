@@ -0,0 +1,114 @@
+package container_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// hostile 同时包含 heredoc 结束符、引号、反引号和命令替换
+const hostile = "EOF\n'\"`id`$(touch /tmp/pwned)\nEOF\nrm -rf / #"
+
+func TestHostingScriptStdioHostileConfig(t *testing.T) {
+	config := `{"mcpServers":{"x":{"command":"sh","args":["-c","` + hostile + `"]}}}`
+	command, args, files := container.HostingScript{
+		CodePackageInstall: "echo install",
+		McpServersConfig:   config,
+		Port:               8080,
+	}.Build()
+
+	if !reflect.DeepEqual(command, []string{"/bin/sh"}) || !reflect.DeepEqual(args, []string{container.HostingStartupScriptPath}) {
+		t.Fatalf("Build() command = %q %q, want /bin/sh %s", command, args, container.HostingStartupScriptPath)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Build() files = %d, want startup script and config", len(files))
+	}
+	script, cfg := files[0], files[1]
+	if script.Path != container.HostingStartupScriptPath || script.Mode != 0755 {
+		t.Errorf("script file = %s %o, want %s 0755", script.Path, script.Mode, container.HostingStartupScriptPath)
+	}
+	if cfg.Path != container.HostingMcpServersConfigPath || cfg.Content != config {
+		t.Errorf("config file = %s %q, want config stored verbatim at %s", cfg.Path, cfg.Content, container.HostingMcpServersConfigPath)
+	}
+	if strings.Contains(script.Content, "pwned") || strings.Contains(script.Content, "<<") {
+		t.Errorf("startup script must not embed the config or heredocs:\n%s", script.Content)
+	}
+	if !strings.HasSuffix(script.Content, "exec mcp-hosting --port=8080 --mcp-servers-config /app/mcp-servers.json\n") {
+		t.Errorf("startup script must end with mcp-hosting:\n%s", script.Content)
+	}
+}
+
+func TestHostingScriptHostileInitScript(t *testing.T) {
+	_, args, files := container.HostingScript{
+		InitScript: hostile,
+		Command:    "node server.js",
+	}.Build()
+
+	if !reflect.DeepEqual(args, []string{container.HostingStartupScriptPath}) {
+		t.Fatalf("Build() args = %q, want only the mounted script", args)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Build() files = %d, want only the startup script", len(files))
+	}
+	script := files[0].Content
+	if strings.Count(script, hostile) != 1 {
+		t.Errorf("init script must appear verbatim once:\n%s", script)
+	}
+	if !strings.HasSuffix(script, "echo \"Starting startup command script\"\nnode server.js\n") {
+		t.Errorf("startup command must follow the init script:\n%s", script)
+	}
+}
+
+func TestKubernetesCreateMountsConfigFiles(t *testing.T) {
+	const namespace = "mcp"
+	clientset := fake.NewSimpleClientset()
+	kcm := &container.KubernetesContainerManager{Entry: &k8s.Entry{
+		Namespace: namespace,
+		Client:    k8s.NewClientForClientset(clientset, namespace),
+	}}
+
+	command, args, files := container.HostingScript{McpServersConfig: hostile, Port: 8080}.Build()
+	if _, err := kcm.Create(context.Background(), container.ContainerCreateOptions{
+		ImageName:     "mcp/hosting:1.0",
+		ContainerName: "mcp-app",
+		Port:          8080,
+		Command:       command,
+		CommandArgs:   args,
+		ConfigFiles:   files,
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), "mcp-app-files", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("config files secret error = %v", err)
+	}
+	if got := string(secret.Data["mcp-servers.json"]); got != hostile {
+		t.Errorf("secret config = %q, want %q", got, hostile)
+	}
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	main := deployment.Spec.Template.Spec.Containers[0]
+	if !reflect.DeepEqual(main.Args, []string{container.HostingStartupScriptPath}) {
+		t.Errorf("container args = %q, want only the mounted script", main.Args)
+	}
+	if len(main.VolumeMounts) != 2 {
+		t.Errorf("container mounts = %+v, want startup script and config", main.VolumeMounts)
+	}
+
+	if err := kcm.Delete(context.Background(), "mcp-app"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), "mcp-app-files", metav1.GetOptions{}); err == nil {
+		t.Error("config files secret still exists after Delete()")
+	}
+}
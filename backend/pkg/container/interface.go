@@ -2,6 +2,8 @@ package container
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"qm-mcp-server/pkg/k8s"
 
@@ -47,21 +49,43 @@ type ConfigMapMount struct {
 	MountPath     string `json:"mountPath"`     // mount path
 }
 
+// ConfigFile file mounted into the main container, content never passes through the command line
+type ConfigFile struct {
+	Path    string `json:"path"`           // absolute path inside the container
+	Content string `json:"content"`        // file content
+	Mode    int32  `json:"mode,omitempty"` // file mode, defaults to 0644
+}
+
 // ContainerCreateOptions container creation options
 type ContainerCreateOptions struct {
-	ImageName        string             `json:"imageName"`        // image name
-	ContainerName    string             `json:"containerName"`    // container name
-	ServiceName      string             `json:"serviceName"`      // service name
-	Port             int32              `json:"port"`             // port
-	Command          []string           `json:"command"`          // execution command (overrides image ENTRYPOINT, Docker: --entrypoint, K8s: command)
-	CommandArgs      []string           `json:"commandArgs"`      // command arguments (overrides image CMD, Docker: args after image, K8s: args)
-	EnvVars          map[string]string  `json:"envVars"`          // environment variables
-	Mounts           []k8s.UnifiedMount `json:"mounts"`           // volume mounts
-	ReadinessProbe   *corev1.Probe      `json:"readinessProbe"`   // readiness probe
-	Labels           map[string]string  `json:"labels"`           // labels
-	RestartPolicy    string             `json:"restartPolicy"`    // restart policy (Docker: no/always/unless-stopped/on-failure)
-	WorkingDir       string             `json:"workingDir"`       // working directory
-	ImagePullSecrets []string           `json:"imagePullSecrets"` // image pull secret names list (only applicable to Kubernetes)
+	ImageName         string                        `json:"imageName"`                   // image name
+	ContainerName     string                        `json:"containerName"`               // container name
+	ServiceName       string                        `json:"serviceName"`                 // service name
+	Port              int32                         `json:"port"`                        // port
+	Command           []string                      `json:"command"`                     // execution command (overrides image ENTRYPOINT, Docker: --entrypoint, K8s: command)
+	CommandArgs       []string                      `json:"commandArgs"`                 // command arguments (overrides image CMD, Docker: args after image, K8s: args)
+	EnvVars           map[string]string             `json:"envVars"`                     // environment variables
+	Mounts            []k8s.UnifiedMount            `json:"mounts"`                      // volume mounts
+	ReadinessProbe    *corev1.Probe                 `json:"readinessProbe"`              // readiness probe
+	Labels            map[string]string             `json:"labels"`                      // labels
+	RestartPolicy     string                        `json:"restartPolicy"`               // restart policy (Docker: no/always/unless-stopped/on-failure)
+	WorkingDir        string                        `json:"workingDir"`                  // working directory
+	ImagePullSecrets  []string                      `json:"imagePullSecrets"`            // image pull secret names list (only applicable to Kubernetes)
+	Replicas          int32                         `json:"replicas"`                    // replica count, defaults to 1 (only applicable to Kubernetes)
+	InitContainers    []k8s.InitContainerOptions    `json:"initContainers,omitempty"`    // init containers run before the main container (only applicable to Kubernetes)
+	InitSharedPath    string                        `json:"initSharedPath,omitempty"`    // mount path of the volume shared with init containers
+	Sidecars          []k8s.SidecarContainerOptions `json:"sidecars,omitempty"`          // sidecar containers sharing the Pod network with the main container (only applicable to Kubernetes)
+	ConfigFiles       []ConfigFile                  `json:"configFiles,omitempty"`       // files stored in a per-container secret and mounted read-only (only applicable to Kubernetes)
+	NodeSelector      map[string]string             `json:"nodeSelector,omitempty"`      // node selector of the Pod (only applicable to Kubernetes)
+	ResourceRequests  map[string]string             `json:"resourceRequests,omitempty"`  // resource requests of the main container (only applicable to Kubernetes)
+	ResourceLimits    map[string]string             `json:"resourceLimits,omitempty"`    // resource limits of the main container (only applicable to Kubernetes)
+	PriorityClassName string                        `json:"priorityClassName,omitempty"` // priority class of the Pod (only applicable to Kubernetes)
+	DisruptionBudget  bool                          `json:"disruptionBudget,omitempty"`  // create a PodDisruptionBudget with minAvailable=1 (only applicable to Kubernetes)
+	PodAffinity       []k8s.PodAffinityTerm         `json:"podAffinity,omitempty"`       // affinity and anti-affinity to other Pods (only applicable to Kubernetes)
+	AllowedEgress     []string                      `json:"allowedEgress,omitempty"`     // CIDRs and DNS names the Pod may connect to besides DNS, unrestricted when empty (only applicable to Kubernetes)
+	ServiceAccount    string                        `json:"serviceAccount,omitempty"`    // existing service account the Pod runs as, must exist before creation (only applicable to Kubernetes)
+	IsolateAccount    bool                          `json:"isolateAccount,omitempty"`    // run the Pod as a dedicated service account without permissions and token mount (only applicable to Kubernetes)
+	InheritedDefaults []string                      `json:"inheritedDefaults,omitempty"` // values merged from environment defaults, e.g. "envVars.HTTP_PROXY"; later default changes do not apply
 
 }
 
@@ -73,6 +97,10 @@ type ContainerInfo struct {
 	Ports     []int32           // port list
 	Labels    map[string]string // labels
 	CreatedAt string            // creation time
+
+	Replicas      int32 // desired replica count
+	ReadyReplicas int32 // ready replica count
+	RestartCount  int32 // total container restart count of all replicas
 }
 
 // ServiceInfo service information
@@ -81,6 +109,7 @@ type ServiceInfo struct {
 	ClusterIP string            // cluster IP
 	Ports     []int32           // port list
 	Labels    map[string]string // labels
+	CreatedAt string            // creation time
 }
 
 // ContainerEvent container event
@@ -91,6 +120,24 @@ type ContainerEvent struct {
 	Timestamp int64  // timestamp
 }
 
+// LogStreamOptions options for streaming container logs
+type LogStreamOptions struct {
+	SinceTime *time.Time // only return logs after this time, nil for all available logs
+	UntilTime *time.Time // only return logs before this time, nil for no upper bound
+	Previous  bool       // return logs of the previous terminated container (only applicable to k8s)
+	Container string     // container selector, see LogOptions.Container
+}
+
+// LogContainerAll container selector returning the logs of the main container and all sidecars
+const LogContainerAll = "all"
+
+// LogOptions container log query options
+type LogOptions struct {
+	Lines     int64  // number of tail lines
+	Previous  bool   // return logs of the previous terminated container (only applicable to k8s)
+	Container string // sidecar name, empty for the main container, LogContainerAll for all containers (only applicable to k8s)
+}
+
 // ContainerManager container manager interface
 type ContainerManager interface {
 	// Create creates a container
@@ -109,8 +156,14 @@ type ContainerManager interface {
 	GetEvents(ctx context.Context, containerName string) ([]ContainerEvent, error)
 	// GetWarningEvents gets container warning events
 	GetWarningEvents(ctx context.Context, containerName string) ([]ContainerEvent, error)
-	// GetLogs gets container logs
-	GetLogs(ctx context.Context, containerName string, lines int64) (string, error)
+	// GetLogs gets container logs, options.Previous returns the logs of the last terminated container
+	GetLogs(ctx context.Context, containerName string, options LogOptions) (string, error)
+	// GetSpec gets the running container spec, used to detect drift from the stored create options
+	GetSpec(ctx context.Context, containerName string) (*ContainerSpec, error)
+	// StreamLogs streams the complete available container logs with timestamps, the caller must close the reader
+	StreamLogs(ctx context.Context, containerName string, options LogStreamOptions) (io.ReadCloser, error)
+	// List lists containers carrying all given labels (only applicable to k8s)
+	List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error)
 }
 
 // ServiceManager service manager interface
@@ -123,6 +176,10 @@ type ServiceManager interface {
 	Get(ctx context.Context, serviceName string) (*ServiceInfo, error)
 	// Restart restarts a service
 	Restart(ctx context.Context, options ContainerCreateOptions) error
+	// GetReadyAddresses gets ready backend addresses (ip:port) of a service
+	GetReadyAddresses(ctx context.Context, serviceName string) ([]string, error)
+	// List lists services carrying all given labels (only applicable to k8s)
+	List(ctx context.Context, labels map[string]string) ([]ServiceInfo, error)
 }
 
 // ContainerRuntime container runtime interface
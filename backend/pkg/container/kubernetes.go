@@ -3,6 +3,8 @@ package container
 import (
 	"context"
 	"fmt"
+	"io"
+	"path"
 	"strings"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	"qm-mcp-server/pkg/k8s"
+	"qm-mcp-server/pkg/logger"
 )
 
 // KubernetesRuntime Kubernetes runtime implementation
@@ -20,7 +23,11 @@ type KubernetesRuntime struct {
 
 // NewKubernetesRuntime creates Kubernetes runtime
 func NewKubernetesRuntime(kubeconfig *rest.Config, namespace string) (*KubernetesRuntime, error) {
-	k8sEntry, err := k8s.NewEntry(kubeconfig, namespace)
+	var opts []k8s.ClientOption
+	if l := logger.L(); l != nil {
+		opts = append(opts, k8s.WithLogger(l.Named("k8s")))
+	}
+	k8sEntry, err := k8s.NewEntry(kubeconfig, namespace, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Kubernetes client: %w", err)
 	}
@@ -57,7 +64,7 @@ func (kcm *KubernetesContainerManager) Create(ctx context.Context, options Conta
 		AppName:   options.ContainerName,
 		Namespace: kcm.Entry.Namespace,
 		Port:      options.Port,
-		Replicas:  1, // default single replica
+		Replicas:  options.Replicas, // defaults to single replica when unset
 	}
 
 	// Set execution command (if specified)
@@ -105,18 +112,142 @@ func (kcm *KubernetesContainerManager) Create(ctx context.Context, options Conta
 		deploymentOptions.ImagePullSecrets = options.ImagePullSecrets
 	}
 
+	// Set init containers and the volume shared with them
+	if len(options.InitContainers) > 0 {
+		deploymentOptions.InitContainers = options.InitContainers
+		deploymentOptions.InitSharedPath = options.InitSharedPath
+	}
+
+	// Set sidecar containers
+	if len(options.Sidecars) > 0 {
+		deploymentOptions.Sidecars = options.Sidecars
+	}
+
+	// Set node selector and main container resources
+	deploymentOptions.NodeSelector = options.NodeSelector
+	deploymentOptions.ResourceRequests = options.ResourceRequests
+	deploymentOptions.ResourceLimits = options.ResourceLimits
+	deploymentOptions.PriorityClassName = options.PriorityClassName
+	deploymentOptions.PodAffinity = options.PodAffinity
+
+	// Store config files in a per-container secret and mount them read-only
+	if len(options.ConfigFiles) > 0 {
+		secretFiles, err := kcm.applyConfigFiles(options)
+		if err != nil {
+			return "", err
+		}
+		deploymentOptions.SecretFiles = secretFiles
+	}
+
+	// Run the Pod as a dedicated account without permissions, or as an existing account
+	switch {
+	case options.IsolateAccount:
+		accountName := serviceAccountName(options.ContainerName)
+		if _, err := kcm.Entry.Client.ServiceAccount().ApplyRestricted(accountName, options.Labels); err != nil {
+			return "", fmt.Errorf("failed to create service account: %w", err)
+		}
+		deploymentOptions.ServiceAccountName = accountName
+		deploymentOptions.DisableTokenMount = true
+	case options.ServiceAccount != "":
+		if _, err := kcm.Entry.Client.ServiceAccount().Get(options.ServiceAccount); err != nil {
+			return "", fmt.Errorf("failed to get service account %s: %w", options.ServiceAccount, err)
+		}
+		deploymentOptions.ServiceAccountName = options.ServiceAccount
+	}
+
+	// Restrict egress before any Pod starts, DNS names are resolved to their current addresses
+	if len(options.AllowedEgress) > 0 {
+		cidrs, err := k8s.ResolveEgressCIDRs(ctx, options.AllowedEgress)
+		if err != nil {
+			return "", err
+		}
+		selector := map[string]string{"app": options.ContainerName}
+		if _, err := kcm.Entry.Client.NetworkPolicy().ApplyEgress(egressPolicyName(options.ContainerName), selector, options.Labels, cidrs); err != nil {
+			return "", fmt.Errorf("failed to create egress NetworkPolicy: %w", err)
+		}
+	}
+
 	// Create deployment
 	deploymentName, err := kcm.Entry.Client.Deployment().Create(deploymentOptions)
 	if err != nil {
 		return "", err
 	}
 
+	// Keep voluntary evictions (node drains, cluster autoscaler scale-down) from interrupting long-lived sessions
+	if options.DisruptionBudget {
+		selector := map[string]string{"app": options.ContainerName}
+		if _, err := kcm.Entry.Client.PodDisruptionBudget().Apply(disruptionBudgetName(options.ContainerName), selector, options.Labels); err != nil {
+			return "", fmt.Errorf("failed to create PodDisruptionBudget: %w", err)
+		}
+	}
+
 	return deploymentName, nil
 }
 
-// Delete deletes container (Deployment)
+// Delete deletes container (Deployment), its config files secret, its PodDisruptionBudget, its egress NetworkPolicy
+// and its dedicated service account
 func (kcm *KubernetesContainerManager) Delete(ctx context.Context, containerName string) error {
-	return kcm.Entry.Client.Deployment().Delete(containerName)
+	if err := kcm.Entry.Client.Deployment().Delete(containerName); err != nil {
+		return err
+	}
+	if err := kcm.Entry.Client.PodDisruptionBudget().Delete(disruptionBudgetName(containerName)); err != nil {
+		return err
+	}
+	if err := kcm.Entry.Client.NetworkPolicy().Delete(egressPolicyName(containerName)); err != nil {
+		return err
+	}
+	if err := kcm.Entry.Client.ServiceAccount().Delete(serviceAccountName(containerName)); err != nil {
+		return err
+	}
+	return kcm.Entry.Client.Secret().Delete(configFilesSecretName(containerName))
+}
+
+// disruptionBudgetName PodDisruptionBudget of a container
+func disruptionBudgetName(containerName string) string {
+	return containerName
+}
+
+// egressPolicyName egress NetworkPolicy of a container
+func egressPolicyName(containerName string) string {
+	return containerName + "-egress"
+}
+
+// serviceAccountName dedicated service account of a container
+func serviceAccountName(containerName string) string {
+	return containerName + "-sa"
+}
+
+// configFilesSecretName secret holding the config files of a container
+func configFilesSecretName(containerName string) string {
+	return containerName + "-files"
+}
+
+// applyConfigFiles creates or updates the config files secret, keyed by file name
+func (kcm *KubernetesContainerManager) applyConfigFiles(options ContainerCreateOptions) ([]k8s.SecretFile, error) {
+	secretName := configFilesSecretName(options.ContainerName)
+	data := make(map[string][]byte, len(options.ConfigFiles))
+	secretFiles := make([]k8s.SecretFile, 0, len(options.ConfigFiles))
+	for _, file := range options.ConfigFiles {
+		if !path.IsAbs(file.Path) {
+			return nil, fmt.Errorf("config file path must be absolute: %s", file.Path)
+		}
+		key := path.Base(file.Path)
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("duplicate config file name: %s", key)
+		}
+		data[key] = []byte(file.Content)
+		secretFiles = append(secretFiles, k8s.SecretFile{
+			SecretName: secretName,
+			Key:        key,
+			MountPath:  file.Path,
+			Mode:       file.Mode,
+		})
+	}
+
+	if _, err := kcm.Entry.Client.Secret().ApplyOpaque(secretName, data, options.Labels); err != nil {
+		return nil, fmt.Errorf("failed to apply config files secret: %w", err)
+	}
+	return secretFiles, nil
 }
 
 // Scale sets container replica count (Deployment)
@@ -276,16 +407,58 @@ func (kcm *KubernetesContainerManager) GetInfo(ctx context.Context, containerNam
 		podIP = podIPs[0] // take the first IP
 	}
 
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	// Sum container restarts of all Pods, used to detect crashes between status checks
+	var restartCount int32
+	if pods, err := kcm.Entry.Client.Deployment().GetPods(containerName); err == nil {
+		for _, pod := range pods {
+			for _, cs := range pod.Status.ContainerStatuses {
+				restartCount += cs.RestartCount
+			}
+		}
+	}
+
 	return &ContainerInfo{
-		Name:      deployment.Name,
-		Status:    status,
-		IP:        podIP,
-		Ports:     ports,
-		Labels:    deployment.Labels,
-		CreatedAt: deployment.CreationTimestamp.Format(time.RFC3339),
+		Name:          deployment.Name,
+		Status:        status,
+		IP:            podIP,
+		Ports:         ports,
+		Labels:        deployment.Labels,
+		CreatedAt:     deployment.CreationTimestamp.Format(time.RFC3339),
+		Replicas:      replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+		RestartCount:  restartCount,
 	}, nil
 }
 
+// List lists Deployments carrying all given labels
+func (kcm *KubernetesContainerManager) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	deployments, err := kcm.Entry.Client.Deployment().List(labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Deployments: %w", err)
+	}
+
+	infos := make([]ContainerInfo, 0, len(deployments))
+	for _, deployment := range deployments {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		infos = append(infos, ContainerInfo{
+			Name:          deployment.Name,
+			Labels:        deployment.Labels,
+			CreatedAt:     deployment.CreationTimestamp.Format(time.RFC3339),
+			Replicas:      replicas,
+			ReadyReplicas: deployment.Status.ReadyReplicas,
+		})
+	}
+	return infos, nil
+}
+
 // IsReady checks if container is ready
 func (kcm *KubernetesContainerManager) IsReady(ctx context.Context, containerName string) (bool, string, error) {
 	ready, err := kcm.Entry.Client.Deployment().IsReady(containerName)
@@ -295,9 +468,33 @@ func (kcm *KubernetesContainerManager) IsReady(ctx context.Context, containerNam
 
 	if ready {
 		return true, "ready", nil
-	} else {
+	}
+
+	// Init container failures keep Pods in Init state and not ready sidecars keep Pods unready,
+	// report them instead of a generic message
+	pods, err := kcm.Entry.Client.Deployment().GetPods(containerName)
+	if err != nil {
 		return false, "not ready", nil
 	}
+	var failures []string
+	for i := range pods {
+		failures = append(failures, k8s.InitContainerFailures(&pods[i])...)
+	}
+	// Pods are only ready when all sidecars are ready, report the ones holding it back
+	for i := range pods {
+		failures = append(failures, k8s.NotReadyContainers(&pods[i], containerName)...)
+	}
+	// Pending pods blocked by scheduling, and pods not created at all because of quota or a missing priority class
+	for i := range pods {
+		failures = append(failures, k8s.SchedulingBlockers(&pods[i])...)
+	}
+	if deployment, err := kcm.Entry.Client.Deployment().Get(containerName); err == nil {
+		failures = append(failures, k8s.ReplicaFailures(deployment)...)
+	}
+	if len(failures) > 0 {
+		return false, strings.Join(failures, "\n"), nil
+	}
+	return false, "not ready", nil
 }
 
 // GetEvents gets container events
@@ -323,8 +520,8 @@ func (kcm *KubernetesContainerManager) GetEvents(ctx context.Context, containerN
 	return containerEvents, nil
 }
 
-// GetLogs gets container logs
-func (kcm *KubernetesContainerManager) GetLogs(ctx context.Context, containerName string, lines int64) (string, error) {
+// GetLogs gets container logs, previous returns the logs of the most recently terminated container
+func (kcm *KubernetesContainerManager) GetLogs(ctx context.Context, containerName string, options LogOptions) (string, error) {
 	// Get Pod list through Deployment name
 	pods, err := kcm.Entry.Client.Deployment().GetPods(containerName)
 	if err != nil {
@@ -335,10 +532,22 @@ func (kcm *KubernetesContainerManager) GetLogs(ctx context.Context, containerNam
 		return "", fmt.Errorf("no Pod found for Deployment %s", containerName)
 	}
 
+	if options.Previous {
+		pod := lastTerminatedPod(pods)
+		if pod == nil {
+			return "", fmt.Errorf("no restarted container found for Deployment %s", containerName)
+		}
+		logs, err := kcm.podLogs(pod, containerName, options)
+		if err != nil {
+			return "", fmt.Errorf("failed to get previous container logs of Pod %s: %w", pod.Name, err)
+		}
+		return logs, nil
+	}
+
 	// Get logs from the first running Pod
-	for _, pod := range pods {
-		if pod.Status.Phase == corev1.PodRunning {
-			logs, err := kcm.Entry.Client.Pod().GetLogs(pod.Name, lines)
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning {
+			logs, err := kcm.podLogs(&pods[i], containerName, options)
 			if err == nil {
 				return logs, nil
 			}
@@ -355,7 +564,7 @@ func (kcm *KubernetesContainerManager) GetLogs(ctx context.Context, containerNam
 	}
 
 	if latestPod != nil {
-		logs, err := kcm.Entry.Client.Pod().GetLogs(latestPod.Name, lines)
+		logs, err := kcm.podLogs(latestPod, containerName, options)
 		if err != nil {
 			return "", fmt.Errorf("failed to get Pod %s logs: %w", latestPod.Name, err)
 		}
@@ -365,6 +574,187 @@ func (kcm *KubernetesContainerManager) GetLogs(ctx context.Context, containerNam
 	return "", fmt.Errorf("no available Pod found")
 }
 
+// podLogs gets the logs of the selected containers of a Pod, multiple containers are joined with a header per container
+func (kcm *KubernetesContainerManager) podLogs(pod *corev1.Pod, containerName string, options LogOptions) (string, error) {
+	names, err := logContainers(pod, containerName, options.Container)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 1 {
+		return kcm.Entry.Client.Pod().GetLogs(pod.Name, names[0], options.Lines, options.Previous)
+	}
+	var result strings.Builder
+	for _, name := range names {
+		logs, err := kcm.Entry.Client.Pod().GetLogs(pod.Name, name, options.Lines, options.Previous)
+		if err != nil {
+			// A sidecar may not have restarted, skip it instead of failing all logs
+			logs = fmt.Sprintf("failed to get logs: %v\n", err)
+		}
+		result.WriteString(logHeader(name))
+		result.WriteString(logs)
+	}
+	return result.String(), nil
+}
+
+// logContainers resolves the container selector to container names of a Pod,
+// empty selects the main container which is named after the Deployment
+func logContainers(pod *corev1.Pod, containerName, selector string) ([]string, error) {
+	switch selector {
+	case "":
+		return []string{containerName}, nil
+	case LogContainerAll:
+		names := make([]string, 0, len(pod.Spec.Containers))
+		for _, c := range pod.Spec.Containers {
+			names = append(names, c.Name)
+		}
+		return names, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == selector {
+			return []string{selector}, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found in Pod %s", selector, pod.Name)
+}
+
+// GetSpec gets the spec of a running Pod, or the Deployment Pod template if no Pod is running
+func (kcm *KubernetesContainerManager) GetSpec(ctx context.Context, containerName string) (*ContainerSpec, error) {
+	deployment, err := kcm.Entry.Client.Deployment().Get(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Deployment information: %w", err)
+	}
+
+	podSpec := deployment.Spec.Template.Spec
+	if pods, err := kcm.Entry.Client.Deployment().GetPods(containerName); err == nil {
+		for _, pod := range pods {
+			if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+				podSpec = pod.Spec
+				break
+			}
+		}
+	}
+	if len(podSpec.Containers) == 0 {
+		return nil, fmt.Errorf("no container found in Deployment %s", containerName)
+	}
+	c := podSpec.Containers[0]
+	for _, candidate := range podSpec.Containers {
+		if candidate.Name == containerName {
+			c = candidate
+			break
+		}
+	}
+
+	spec := &ContainerSpec{
+		Image:      c.Image,
+		Command:    c.Command,
+		Args:       c.Args,
+		WorkingDir: c.WorkingDir,
+		EnvVars:    make(map[string]string, len(c.Env)),
+		Replicas:   1,
+	}
+	if deployment.Spec.Replicas != nil {
+		spec.Replicas = *deployment.Spec.Replicas
+	}
+	// Env vars from ConfigMap/Secret references are not managed by instances
+	for _, env := range c.Env {
+		if env.ValueFrom == nil {
+			spec.EnvVars[env.Name] = env.Value
+		}
+	}
+
+	// Only hostPath and PVC volumes are managed by instances, injected volumes such as service account tokens are skipped
+	volumes := make(map[string]corev1.Volume, len(podSpec.Volumes))
+	for _, v := range podSpec.Volumes {
+		volumes[v.Name] = v
+	}
+	for _, vm := range c.VolumeMounts {
+		v, ok := volumes[vm.Name]
+		if !ok {
+			continue
+		}
+		mount := k8s.UnifiedMount{MountPath: vm.MountPath, SubPath: vm.SubPath, ReadOnly: vm.ReadOnly}
+		switch {
+		case v.HostPath != nil:
+			mount.Type, mount.HostPath = k8s.MountTypeHostPath, v.HostPath.Path
+		case v.PersistentVolumeClaim != nil:
+			mount.Type, mount.PVCName = k8s.MountTypePVC, v.PersistentVolumeClaim.ClaimName
+		default:
+			continue
+		}
+		spec.Mounts = append(spec.Mounts, mount)
+	}
+	return spec, nil
+}
+
+// StreamLogs streams the complete available logs of a running Pod, or the latest Pod if none is running
+func (kcm *KubernetesContainerManager) StreamLogs(ctx context.Context, containerName string, options LogStreamOptions) (io.ReadCloser, error) {
+	pods, err := kcm.Entry.Client.Deployment().GetPods(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Pod list for Deployment: %w", err)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no Pod found for Deployment %s", containerName)
+	}
+
+	var target *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning {
+			target = &pods[i]
+			break
+		}
+		if target == nil || pods[i].CreationTimestamp.After(target.CreationTimestamp.Time) {
+			target = &pods[i]
+		}
+	}
+
+	names, err := logContainers(target, containerName, options.Container)
+	if err != nil {
+		return nil, err
+	}
+	streams := make([]io.ReadCloser, 0, len(names))
+	for _, name := range names {
+		// Kubernetes log API only supports sinceTime, the upper bound is applied on the timestamps of each line
+		logs, err := kcm.Entry.Client.Pod().StreamLogs(ctx, target.Name, k8s.LogStreamOptions{
+			SinceTime:  options.SinceTime,
+			Previous:   options.Previous,
+			Timestamps: true,
+			Container:  name,
+		})
+		if err != nil {
+			for _, stream := range streams {
+				stream.Close()
+			}
+			return nil, fmt.Errorf("failed to get Pod %s logs: %w", target.Name, err)
+		}
+		if options.UntilTime != nil {
+			logs = NewUntilLogReader(logs, *options.UntilTime)
+		}
+		streams = append(streams, logs)
+	}
+	if len(streams) == 1 {
+		return streams[0], nil
+	}
+	return NewSectionLogReader(names, streams), nil
+}
+
+// lastTerminatedPod returns the Pod whose previous container terminated most recently, nil if no container restarted
+func lastTerminatedPod(pods []corev1.Pod) *corev1.Pod {
+	var target *corev1.Pod
+	var finishedAt time.Time
+	for i := range pods {
+		for _, cs := range pods[i].Status.ContainerStatuses {
+			terminated := cs.LastTerminationState.Terminated
+			if terminated == nil {
+				continue
+			}
+			if target == nil || terminated.FinishedAt.After(finishedAt) {
+				target, finishedAt = &pods[i], terminated.FinishedAt.Time
+			}
+		}
+	}
+	return target
+}
+
 // GetWarningEvents gets container warning events
 func (kcm *KubernetesContainerManager) GetWarningEvents(ctx context.Context, containerName string) ([]ContainerEvent, error) {
 	// Use DeploymentManager to get Deployment-related warning events
@@ -397,7 +787,8 @@ type KubernetesServiceManager struct {
 func (ksm *KubernetesServiceManager) Create(ctx context.Context, serviceName string, port int32, selector map[string]string) (*ServiceInfo, error) {
 	svcCfg := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: serviceName,
+			Name:   serviceName,
+			Labels: selector,
 		},
 		Spec: corev1.ServiceSpec{
 			ClusterIP: "None", // Headless Service
@@ -426,6 +817,7 @@ func (ksm *KubernetesServiceManager) Create(ctx context.Context, serviceName str
 		ClusterIP: service.Spec.ClusterIP,
 		Ports:     ports,
 		Labels:    service.Labels,
+		CreatedAt: service.CreationTimestamp.Format(time.RFC3339),
 	}, nil
 }
 
@@ -452,9 +844,61 @@ func (ksm *KubernetesServiceManager) Get(ctx context.Context, serviceName string
 		ClusterIP: service.Spec.ClusterIP,
 		Ports:     ports,
 		Labels:    service.Labels,
+		CreatedAt: service.CreationTimestamp.Format(time.RFC3339),
 	}, nil
 }
 
+// List lists Services carrying all given labels. Services created before labels were set on them
+// are matched by their pod selector instead and reported with the selector as labels
+func (ksm *KubernetesServiceManager) List(ctx context.Context, labels map[string]string) ([]ServiceInfo, error) {
+	services, err := ksm.Entry.Service.ListSelecting(labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Services: %w", err)
+	}
+	labeled, err := ksm.Entry.Service.List(labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Services: %w", err)
+	}
+	seen := make(map[string]bool, len(services))
+	for _, service := range services {
+		seen[service.Name] = true
+	}
+	for _, service := range labeled {
+		if !seen[service.Name] {
+			services = append(services, service)
+		}
+	}
+
+	infos := make([]ServiceInfo, 0, len(services))
+	for _, service := range services {
+		var ports []int32
+		for _, port := range service.Spec.Ports {
+			ports = append(ports, port.Port)
+		}
+		serviceLabels := service.Labels
+		if len(serviceLabels) == 0 {
+			serviceLabels = service.Spec.Selector
+		}
+		infos = append(infos, ServiceInfo{
+			Name:      service.Name,
+			ClusterIP: service.Spec.ClusterIP,
+			Ports:     ports,
+			Labels:    serviceLabels,
+			CreatedAt: service.CreationTimestamp.Format(time.RFC3339),
+		})
+	}
+	return infos, nil
+}
+
+// GetReadyAddresses gets ready pod addresses (ip:port) behind the service
+func (ksm *KubernetesServiceManager) GetReadyAddresses(ctx context.Context, serviceName string) ([]string, error) {
+	addrs, err := ksm.Entry.Service.GetReadyAddresses(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Service endpoints: %w", err)
+	}
+	return addrs, nil
+}
+
 // Restart restarts service
 func (ksm *KubernetesServiceManager) Restart(ctx context.Context, options ContainerCreateOptions) error {
 	// Get existing service information
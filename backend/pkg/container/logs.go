@@ -0,0 +1,96 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// untilLogReader reads timestamped log lines and stops at the first line after the until time
+type untilLogReader struct {
+	src     io.ReadCloser
+	reader  *bufio.Reader
+	until   time.Time
+	pending []byte
+	done    bool
+}
+
+// NewUntilLogReader wraps a log stream whose lines start with an RFC3339Nano timestamp,
+// returning only the lines logged at or before until. Lines without a timestamp are kept.
+func NewUntilLogReader(src io.ReadCloser, until time.Time) io.ReadCloser {
+	return &untilLogReader{src: src, reader: bufio.NewReader(src), until: until}
+}
+
+func (r *untilLogReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) > 0 && lineAfter(line, r.until) {
+			r.done = true
+			return 0, io.EOF
+		}
+		r.pending = line
+		if err == io.EOF {
+			r.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *untilLogReader) Close() error {
+	return r.src.Close()
+}
+
+// lineAfter reports whether the timestamp prefix of a log line is after until
+func lineAfter(line []byte, until time.Time) bool {
+	end := bytes.IndexByte(line, ' ')
+	if end < 0 {
+		end = len(bytes.TrimRight(line, "\r\n"))
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(line[:end]))
+	return err == nil && ts.After(until)
+}
+
+// logHeader starts the section of a container when logs of several containers are returned together
+func logHeader(name string) string {
+	return fmt.Sprintf("==> %s <==\n", name)
+}
+
+// sectionLogReader reads several container log streams one after another, each preceded by a header line
+type sectionLogReader struct {
+	reader  io.Reader
+	streams []io.ReadCloser
+}
+
+// NewSectionLogReader concatenates the log streams of the named containers, closing it closes all streams
+func NewSectionLogReader(names []string, streams []io.ReadCloser) io.ReadCloser {
+	readers := make([]io.Reader, 0, 2*len(streams))
+	for i, stream := range streams {
+		readers = append(readers, strings.NewReader(logHeader(names[i])), stream)
+	}
+	return &sectionLogReader{reader: io.MultiReader(readers...), streams: streams}
+}
+
+func (r *sectionLogReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func (r *sectionLogReader) Close() error {
+	var errs []error
+	for _, stream := range r.streams {
+		if err := stream.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package container_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"qm-mcp-server/pkg/container"
+)
+
+func TestUntilLogReader(t *testing.T) {
+	logs := "2024-05-01T10:00:00.000000001Z starting\n" +
+		"panic: boom\n" +
+		"2024-05-01T10:00:05Z ready\n" +
+		"2024-05-01T10:00:10.5Z request handled\n" +
+		"2024-05-01T10:00:20Z shutting down\n"
+	until := time.Date(2024, 5, 1, 10, 0, 10, 0, time.UTC)
+	tests := []struct {
+		name  string
+		logs  string
+		until time.Time
+		want  string
+	}{
+		{"StopAtFirstLineAfter", logs, until, logs[:strings.Index(logs, "2024-05-01T10:00:10.5Z")]},
+		{"InclusiveBound", logs, until.Add(500 * time.Millisecond), logs[:strings.Index(logs, "2024-05-01T10:00:20Z")]},
+		{"AllBeforeBound", logs, until.Add(time.Hour), logs},
+		{"NoTrailingNewline", "2024-05-01T10:00:00Z last line", until, "2024-05-01T10:00:00Z last line"},
+		{"Empty", "", until, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := io.NopCloser(iotest.OneByteReader(strings.NewReader(tt.logs)))
+			got, err := io.ReadAll(container.NewUntilLogReader(src, tt.until))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NewUntilLogReader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionLogReader(t *testing.T) {
+	main := io.NopCloser(strings.NewReader("main line\n"))
+	sidecar := io.NopCloser(strings.NewReader("sidecar line\n"))
+	reader := container.NewSectionLogReader([]string{"mcp-app", "token-refresher"}, []io.ReadCloser{main, sidecar})
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	want := "==> mcp-app <==\nmain line\n==> token-refresher <==\nsidecar line\n"
+	if string(got) != want {
+		t.Errorf("NewSectionLogReader() = %q, want %q", got, want)
+	}
+	if err := reader.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
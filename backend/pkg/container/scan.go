@@ -0,0 +1,219 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/registry"
+)
+
+// Vulnerability severities, from most to least severe
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// maxTopFindings findings kept in a scan report, the most severe first
+const maxTopFindings = 10
+
+// severityRank orders severities for sorting, unknown severities sort last
+var severityRank = map[string]int{
+	SeverityCritical: 0,
+	SeverityHigh:     1,
+	SeverityMedium:   2,
+	SeverityLow:      3,
+	SeverityUnknown:  4,
+}
+
+// ImageScanner scans container images for known vulnerabilities
+type ImageScanner interface {
+	// Name scanner name recorded in reports
+	Name() string
+	// Scan scans image, cred is nil for anonymous registry access
+	Scan(ctx context.Context, image string, cred *registry.Credential) (*ScanReport, error)
+}
+
+// Vulnerability a single finding of a scan
+type Vulnerability struct {
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	Title            string `json:"title,omitempty"`
+	URL              string `json:"url,omitempty"`
+}
+
+// ScanReport vulnerability summary of an image: counts per severity and the most severe findings
+type ScanReport struct {
+	Image       string          `json:"image"`
+	Scanner     string          `json:"scanner"`
+	ScannedAt   time.Time       `json:"scannedAt"`
+	Critical    int             `json:"critical"`
+	High        int             `json:"high"`
+	Medium      int             `json:"medium"`
+	Low         int             `json:"low"`
+	Unknown     int             `json:"unknown"`
+	TopFindings []Vulnerability `json:"topFindings,omitempty"`
+}
+
+// NewScanReport summarizes findings, the same vulnerability reported for a package in several
+// layers or targets is counted once
+func NewScanReport(image, scanner string, findings []Vulnerability) *ScanReport {
+	report := &ScanReport{Image: image, Scanner: scanner, ScannedAt: time.Now()}
+	seen := make(map[string]bool, len(findings))
+	unique := make([]Vulnerability, 0, len(findings))
+	for _, f := range findings {
+		f.Severity = strings.ToUpper(f.Severity)
+		if _, ok := severityRank[f.Severity]; !ok {
+			f.Severity = SeverityUnknown
+		}
+		key := f.ID + "|" + f.Package + "|" + f.InstalledVersion
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, f)
+
+		switch f.Severity {
+		case SeverityCritical:
+			report.Critical++
+		case SeverityHigh:
+			report.High++
+		case SeverityMedium:
+			report.Medium++
+		case SeverityLow:
+			report.Low++
+		default:
+			report.Unknown++
+		}
+	}
+
+	// 同级别时优先展示已有修复版本的漏洞
+	sort.SliceStable(unique, func(i, j int) bool {
+		a, b := unique[i], unique[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if (a.FixedVersion != "") != (b.FixedVersion != "") {
+			return a.FixedVersion != ""
+		}
+		return a.ID < b.ID
+	})
+	if len(unique) > maxTopFindings {
+		unique = unique[:maxTopFindings]
+	}
+	report.TopFindings = unique
+	return report
+}
+
+// TrivyScanner scans images with the trivy CLI in client/server mode: the trivy server holds the
+// vulnerability database, the client pulls the image layers and sends them for analysis
+type TrivyScanner struct {
+	// Binary path of the trivy CLI, defaults to trivy in PATH
+	Binary string
+	// ServerURL address of the trivy server, e.g. http://trivy.security:4954
+	ServerURL string
+	// Token sent to the trivy server when it requires one
+	Token string
+	// Insecure skips TLS verification of the registry
+	Insecure bool
+}
+
+// Name implements ImageScanner
+func (t *TrivyScanner) Name() string {
+	return "trivy"
+}
+
+// Scan implements ImageScanner, registry credentials are passed through the environment so they
+// don't show up in the process list
+func (t *TrivyScanner) Scan(ctx context.Context, image string, cred *registry.Credential) (*ScanReport, error) {
+	binary := t.Binary
+	if binary == "" {
+		binary = "trivy"
+	}
+	args := []string{"image", "--server", t.ServerURL, "--format", "json", "--quiet", "--scanners", "vuln"}
+	if deadline, ok := ctx.Deadline(); ok {
+		args = append(args, "--timeout", time.Until(deadline).Round(time.Second).String())
+	}
+	args = append(args, image)
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = os.Environ()
+	if t.Token != "" {
+		cmd.Env = append(cmd.Env, "TRIVY_TOKEN="+t.Token)
+	}
+	if t.Insecure {
+		cmd.Env = append(cmd.Env, "TRIVY_INSECURE=true")
+	}
+	if cred != nil {
+		cmd.Env = append(cmd.Env, "TRIVY_USERNAME="+cred.Username, "TRIVY_PASSWORD="+cred.Password)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("trivy scan failed: %w: %s", err, lastLine(msg))
+		}
+		return nil, fmt.Errorf("trivy scan failed: %w", err)
+	}
+	findings, err := ParseTrivyReport(output)
+	if err != nil {
+		return nil, err
+	}
+	return NewScanReport(image, t.Name(), findings), nil
+}
+
+// ParseTrivyReport extracts the vulnerabilities of a trivy JSON report
+func ParseTrivyReport(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string `json:"VulnerabilityID"`
+				PkgName          string `json:"PkgName"`
+				InstalledVersion string `json:"InstalledVersion"`
+				FixedVersion     string `json:"FixedVersion"`
+				Severity         string `json:"Severity"`
+				Title            string `json:"Title"`
+				PrimaryURL       string `json:"PrimaryURL"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid trivy report: %w", err)
+	}
+	var findings []Vulnerability
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			findings = append(findings, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Severity:         v.Severity,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Title:            v.Title,
+				URL:              v.PrimaryURL,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// lastLine last line of a multi-line message, trivy prints the cause of a failure last
+func lastLine(msg string) string {
+	if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+		return msg[i+1:]
+	}
+	return msg
+}
@@ -0,0 +1,70 @@
+package container_test
+
+import (
+	"testing"
+
+	"qm-mcp-server/pkg/container"
+)
+
+func TestParseTrivyReport(t *testing.T) {
+	data := []byte(`{
+  "SchemaVersion": 2,
+  "ArtifactName": "ghcr.io/example/mcp-server:1.0",
+  "Results": [
+    {"Target": "debian 12", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0002", "PkgName": "openssl", "InstalledVersion": "3.0.11", "Severity": "CRITICAL", "Title": "no fix yet"},
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "InstalledVersion": "3.0.11", "FixedVersion": "3.0.13", "Severity": "CRITICAL", "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2024-0001"},
+      {"VulnerabilityID": "CVE-2024-0003", "PkgName": "zlib", "InstalledVersion": "1.2.13", "Severity": "medium"}
+    ]},
+    {"Target": "app/package-lock.json", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "InstalledVersion": "3.0.11", "FixedVersion": "3.0.13", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "GHSA-xxxx", "PkgName": "lodash", "InstalledVersion": "4.17.20", "Severity": "HIGH"},
+      {"VulnerabilityID": "CVE-2024-0004", "PkgName": "tar", "InstalledVersion": "6.1.0", "Severity": "NEGLIGIBLE"}
+    ]},
+    {"Target": "usr/bin/node"}
+  ]
+}`)
+	findings, err := container.ParseTrivyReport(data)
+	if err != nil {
+		t.Fatalf("ParseTrivyReport() error = %v", err)
+	}
+	report := container.NewScanReport("ghcr.io/example/mcp-server:1.0", "trivy", findings)
+
+	if report.Critical != 2 || report.High != 1 || report.Medium != 1 || report.Low != 0 || report.Unknown != 1 {
+		t.Errorf("counts = %d/%d/%d/%d/%d, want 2/1/1/0/1", report.Critical, report.High, report.Medium, report.Low, report.Unknown)
+	}
+	var ids []string
+	for _, f := range report.TopFindings {
+		ids = append(ids, f.ID)
+	}
+	want := []string{"CVE-2024-0001", "CVE-2024-0002", "GHSA-xxxx", "CVE-2024-0003", "CVE-2024-0004"}
+	if len(ids) != len(want) {
+		t.Fatalf("top findings = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("top findings = %v, want %v", ids, want)
+		}
+	}
+
+	if _, err := container.ParseTrivyReport([]byte("FATAL error")); err == nil {
+		t.Error("ParseTrivyReport() of a non JSON output error = nil")
+	}
+}
+
+func TestNewScanReportLimitsFindings(t *testing.T) {
+	const maxTopFindings = 10
+	var findings []container.Vulnerability
+	for i := 0; i < maxTopFindings+5; i++ {
+		findings = append(findings, container.Vulnerability{ID: string(rune('a' + i)), Package: "pkg", Severity: container.SeverityLow})
+	}
+	findings = append(findings, container.Vulnerability{ID: "z", Package: "pkg", Severity: container.SeverityCritical})
+
+	report := container.NewScanReport("image", "trivy", findings)
+	if report.Low != maxTopFindings+5 || len(report.TopFindings) != maxTopFindings {
+		t.Errorf("low = %d, top findings = %d, want %d and %d", report.Low, len(report.TopFindings), maxTopFindings+5, maxTopFindings)
+	}
+	if report.TopFindings[0].ID != "z" {
+		t.Errorf("first finding = %s, want the critical one", report.TopFindings[0].ID)
+	}
+}
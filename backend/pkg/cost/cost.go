@@ -0,0 +1,108 @@
+// Package cost estimates the cost of hosting instances from their resource requests and running time.
+package cost
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// bytesPerGiB bytes in one GiB
+const bytesPerGiB = 1 << 30
+
+// Rates prices of one CPU core and one GiB of memory per hour
+type Rates struct {
+	CPUHour float64
+	GiBHour float64
+}
+
+// Resources reserved by one replica
+type Resources struct {
+	CPUCores  float64
+	MemoryGiB float64
+}
+
+// Add adds the resources of another container
+func (r Resources) Add(other Resources) Resources {
+	return Resources{CPUCores: r.CPUCores + other.CPUCores, MemoryGiB: r.MemoryGiB + other.MemoryGiB}
+}
+
+// HourlyCost cost of one replica running for one hour
+func (r Resources) HourlyCost(rates Rates) float64 {
+	return r.CPUCores*rates.CPUHour + r.MemoryGiB*rates.GiBHour
+}
+
+// ContainerResources resources reserved by a container, the request of each resource falls back to its limit
+func ContainerResources(requests, limits map[string]string) Resources {
+	quantity := func(name corev1.ResourceName) *resource.Quantity {
+		for _, values := range []map[string]string{requests, limits} {
+			if q, err := resource.ParseQuantity(values[string(name)]); err == nil {
+				return &q
+			}
+		}
+		return nil
+	}
+	var r Resources
+	if q := quantity(corev1.ResourceCPU); q != nil {
+		r.CPUCores = float64(q.MilliValue()) / 1000
+	}
+	if q := quantity(corev1.ResourceMemory); q != nil {
+		r.MemoryGiB = float64(q.Value()) / bytesPerGiB
+	}
+	return r
+}
+
+// State of an instance from At on, stopped instances do not accrue cost
+type State struct {
+	At       time.Time
+	Running  bool
+	Replicas int32
+}
+
+// Usage running time of an instance within a period
+type Usage struct {
+	// RunningHours hours with at least one replica running
+	RunningHours float64
+	// ReplicaHours running hours multiplied by the running replicas
+	ReplicaHours float64
+	// End state at the end of the period, the initial state of the next period
+	End State
+}
+
+// Accumulate sums the running time within [start, end) from the state at start and the state changes in the period.
+// Changes before start or at or after end are ignored
+func Accumulate(initial State, changes []State, start, end time.Time) Usage {
+	sorted := make([]State, 0, len(changes))
+	for _, change := range changes {
+		if !change.At.Before(start) && change.At.Before(end) {
+			sorted = append(sorted, change)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+
+	var usage Usage
+	current, from := initial, start
+	accrue := func(to time.Time) {
+		if current.Running && current.Replicas > 0 && to.After(from) {
+			hours := to.Sub(from).Hours()
+			usage.RunningHours += hours
+			usage.ReplicaHours += hours * float64(current.Replicas)
+		}
+	}
+	for _, change := range sorted {
+		accrue(change.At)
+		current, from = change, change.At
+	}
+	accrue(end)
+	current.At = end
+	usage.End = current
+	return usage
+}
+
+// Round rounds an amount to 4 decimal places
+func Round(amount float64) float64 {
+	return math.Round(amount*10000) / 10000
+}
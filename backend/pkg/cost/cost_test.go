@@ -0,0 +1,63 @@
+package cost_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/cost"
+)
+
+func TestContainerResources(t *testing.T) {
+	r := cost.ContainerResources(map[string]string{"cpu": "500m"}, map[string]string{"cpu": "2", "memory": "512Mi"})
+	if r.CPUCores != 0.5 || r.MemoryGiB != 0.5 {
+		t.Errorf("ContainerResources() = %+v, want request cpu and memory limit", r)
+	}
+	if r := cost.ContainerResources(nil, nil); r != (cost.Resources{}) {
+		t.Errorf("ContainerResources() without resources = %+v", r)
+	}
+	total := r.Add(cost.Resources{CPUCores: 0.25, MemoryGiB: 1})
+	if got := total.HourlyCost(cost.Rates{CPUHour: 0.04, GiBHour: 0.01}); math.Abs(got-0.045) > 1e-9 {
+		t.Errorf("HourlyCost() = %v, want 0.045", got)
+	}
+}
+
+func TestAccumulate(t *testing.T) {
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+	tests := []struct {
+		name         string
+		initial      cost.State
+		changes      []cost.State
+		runningHours float64
+		replicaHours float64
+		endRunning   bool
+	}{
+		{"running all day", cost.State{Running: true, Replicas: 2}, nil, 24, 48, true},
+		{"stopped all day", cost.State{Replicas: 2}, nil, 0, 0, false},
+		{"stopped and started", cost.State{Running: true, Replicas: 1}, []cost.State{
+			{At: at(6), Replicas: 1},
+			{At: at(18), Running: true, Replicas: 1},
+		}, 12, 12, true},
+		{"scaled out", cost.State{Running: true, Replicas: 1}, []cost.State{
+			{At: at(12), Running: true, Replicas: 3},
+		}, 24, 48, true},
+		{"changes outside the day", cost.State{}, []cost.State{
+			{At: day.Add(-time.Hour), Running: true, Replicas: 1},
+			{At: at(20), Running: true, Replicas: 1},
+			{At: at(24), Replicas: 1},
+		}, 4, 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := cost.Accumulate(tt.initial, tt.changes, day, at(24))
+			if math.Abs(usage.RunningHours-tt.runningHours) > 1e-9 || math.Abs(usage.ReplicaHours-tt.replicaHours) > 1e-9 {
+				t.Errorf("Accumulate() = %v running hours, %v replica hours, want %v and %v",
+					usage.RunningHours, usage.ReplicaHours, tt.runningHours, tt.replicaHours)
+			}
+			if usage.End.Running != tt.endRunning || !usage.End.At.Equal(at(24)) {
+				t.Errorf("end state = %+v, want running %v", usage.End, tt.endRunning)
+			}
+		})
+	}
+}
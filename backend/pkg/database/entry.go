@@ -1,14 +1,49 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/migration"
 	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
 )
 
-// Init 初始化数据库连接
+// Init 初始化数据库连接，并检查表结构版本，版本低于当前程序要求时返回错误
 func Init(databaseConfig *common.DatabaseConfig) error {
+	if err := Connect(databaseConfig); err != nil {
+		return err
+	}
+	// 兼容模式下表结构由 AutoMigrate 维护，不检查迁移版本
+	if databaseConfig.MySQL.AutoMigrate {
+		return nil
+	}
+	return migration.Check(context.Background(), mysql.GetDB())
+}
+
+// Migrate 初始化数据库连接并执行未执行的迁移，由 init 任务和 cmd/migrate 调用
+func Migrate(databaseConfig *common.DatabaseConfig) error {
+	if err := Connect(databaseConfig); err != nil {
+		return err
+	}
+	lockTimeout := time.Duration(databaseConfig.MySQL.MigrateLockTimeout) * time.Second
+	applied, err := migration.Up(context.Background(), mysql.GetDB(), migration.Options{
+		LockTimeout: lockTimeout,
+		Adopt:       mysql.InitTables,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	logger.Info("Database schema is up to date", zap.Int("applied", applied))
+	return nil
+}
+
+// Connect 只初始化 MySQL 连接，不检查表结构版本
+func Connect(databaseConfig *common.DatabaseConfig) error {
 	// 初始化 MySQL 配置
 	mysqlConfig := &mysql.Config{
 		Host:                databaseConfig.MySQL.Host,
@@ -22,13 +57,7 @@ func Init(databaseConfig *common.DatabaseConfig) error {
 		HealthCheckInterval: 30 * time.Second,
 		MaxRetries:          3,
 		RetryInterval:       5 * time.Second,
+		AutoMigrate:         databaseConfig.MySQL.AutoMigrate,
 	}
-	err := mysql.InitDB(mysqlConfig)
-	if err != nil {
-		return err
-	}
-
-	// 初始化数据库连接、
-
-	return nil
+	return mysql.InitDB(mysqlConfig)
 }
@@ -0,0 +1,271 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+const (
+	// versionTable 记录已执行的迁移版本
+	versionTable = "schema_migrations"
+	// lockName MySQL 命名锁，多个副本同时启动时只有一个执行迁移
+	lockName = "mcpbox_schema_migration"
+	// legacyTable 迁移框架引入前 AutoMigrate 创建的表，用于识别旧数据库
+	legacyTable = "mcp_instance"
+
+	// BaselineVersion 基线迁移的最后一个版本，接管旧数据库时基线视为已执行
+	BaselineVersion = 2
+	// DefaultLockTimeout 等待其他进程完成迁移的默认时间
+	DefaultLockTimeout = 60 * time.Second
+)
+
+// ErrSchemaOutdated 数据库结构版本低于当前程序要求
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// Migration 一个版本化的 SQL 迁移，文件名格式为 {版本号}_{名称}.sql
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// Options 迁移选项
+type Options struct {
+	// LockTimeout 等待迁移锁的时间，默认 DefaultLockTimeout
+	LockTimeout time.Duration
+	// Adopt 数据库由旧版本 AutoMigrate 创建且没有迁移记录时调用，补齐旧结构后基线迁移记为已执行
+	Adopt func() error
+}
+
+// record schema_migrations 表记录
+type record struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"type:datetime(3);not null"`
+}
+
+func (record) TableName() string {
+	return versionTable
+}
+
+// Migrations 返回内置的迁移，按版本号升序
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(sqlFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		versionPart, label, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(versionPart)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+		content, err := sqlFiles.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		statements := splitStatements(string(content))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("migration %s has no statements", entry.Name())
+		}
+		migrations = append(migrations, Migration{Version: version, Name: label, Statements: statements})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion 当前程序要求的数据库结构版本
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// splitStatements 按以分号结尾的行拆分语句，忽略 -- 注释行
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Up 在迁移锁内执行所有未执行的迁移，返回本次执行的迁移数量
+// MySQL DDL 不支持事务，迁移中途失败时需要修复后重新执行，已完成的语句应可重复执行
+func Up(ctx context.Context, db *gorm.DB, opts Options) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+
+	applied := 0
+	// 命名锁属于数据库会话，锁定、迁移和释放必须使用同一个连接
+	err = db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := acquireLock(conn, opts.LockTimeout); err != nil {
+			return err
+		}
+		defer releaseLock(conn)
+
+		if err := conn.AutoMigrate(&record{}); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", versionTable, err)
+		}
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 && conn.Migrator().HasTable(legacyTable) {
+			if err := adopt(conn, migrations, opts.Adopt); err != nil {
+				return err
+			}
+			if done, err = appliedVersions(conn); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range migrations {
+			if done[m.Version] {
+				continue
+			}
+			startTime := time.Now()
+			for i, statement := range m.Statements {
+				if err := conn.Exec(statement).Error; err != nil {
+					return fmt.Errorf("migration %d_%s failed at statement %d: %w", m.Version, m.Name, i+1, err)
+				}
+			}
+			if err := conn.Create(&record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error; err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+			}
+			applied++
+			logger.Info("Database migration applied",
+				zap.Int("version", m.Version),
+				zap.String("name", m.Name),
+				zap.Duration("elapsed", time.Since(startTime)))
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// adopt 接管旧数据库：补齐 AutoMigrate 结构后将基线迁移记为已执行，不重复建表
+func adopt(conn *gorm.DB, migrations []Migration, adoptFn func() error) error {
+	logger.Info("Adopting database created before versioned migrations", zap.Int("baseline", BaselineVersion))
+	if adoptFn != nil {
+		if err := adoptFn(); err != nil {
+			return fmt.Errorf("failed to bring legacy schema up to date: %w", err)
+		}
+	}
+	for _, m := range migrations {
+		if m.Version > BaselineVersion {
+			break
+		}
+		if err := conn.Create(&record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to record baseline migration %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// appliedVersions 已执行的迁移版本
+func appliedVersions(db *gorm.DB) (map[int]bool, error) {
+	var versions []int
+	if err := db.Model(&record{}).Pluck("version", &versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", versionTable, err)
+	}
+	done := make(map[int]bool, len(versions))
+	for _, v := range versions {
+		done[v] = true
+	}
+	return done, nil
+}
+
+func acquireLock(conn *gorm.DB, timeout time.Duration) error {
+	var result sql.NullInt64
+	if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&result).Error; err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !result.Valid || result.Int64 != 1 {
+		return fmt.Errorf("timed out after %s waiting for migration lock %s held by another process", timeout, lockName)
+	}
+	return nil
+}
+
+func releaseLock(conn *gorm.DB) {
+	if err := conn.Exec("SELECT RELEASE_LOCK(?)", lockName).Error; err != nil {
+		logger.Warn("Failed to release migration lock", zap.Error(err))
+	}
+}
+
+// CurrentVersion 数据库当前结构版本，没有迁移记录时为 0
+func CurrentVersion(ctx context.Context, db *gorm.DB) (int, error) {
+	db = db.WithContext(ctx)
+	if !db.Migrator().HasTable(versionTable) {
+		return 0, nil
+	}
+	var version sql.NullInt64
+	if err := db.Model(&record{}).Select("MAX(version)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// Check 数据库结构版本低于当前程序要求时返回 ErrSchemaOutdated，服务启动时调用以快速失败；
+// 结构版本更高时 (滚动升级中的旧副本) 允许继续运行
+func Check(ctx context.Context, db *gorm.DB) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	current, err := CurrentVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("%w: version %d, this binary requires %d; run the init job or cmd/migrate first", ErrSchemaOutdated, current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) < BaselineVersion {
+		t.Fatalf("Migrations() = %d migrations, want at least the %d baseline migrations", len(migrations), BaselineVersion)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, versions must be consecutive from 1", i, m.Version)
+		}
+		for _, statement := range m.Statements {
+			if strings.HasSuffix(statement, ";") || strings.HasPrefix(strings.TrimSpace(statement), "--") {
+				t.Errorf("migration %d statement not split cleanly: %q", m.Version, statement)
+			}
+		}
+	}
+
+	tables := map[string]bool{}
+	for _, m := range migrations[:BaselineVersion] {
+		for _, statement := range m.Statements {
+			if name, ok := strings.CutPrefix(statement, "CREATE TABLE IF NOT EXISTS `"); ok {
+				tables[name[:strings.Index(name, "`")]] = true
+			}
+		}
+	}
+	for _, table := range []string{"sys_user", "sys_role", "sys_dept", "mcp_instance", "mcp_template", "mcp_environment", "mcp_code_package"} {
+		if !tables[table] {
+			t.Errorf("baseline migrations do not create %s", table)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	content := `-- comment
+CREATE TABLE a (
+  id int COMMENT 'a;b'
+);
+
+-- another
+ALTER TABLE a ADD COLUMN b int;
+UPDATE a SET b = 1`
+	got := splitStatements(content)
+	want := []string{
+		"CREATE TABLE a (\n  id int COMMENT 'a;b'\n)",
+		"ALTER TABLE a ADD COLUMN b int",
+		"UPDATE a SET b = 1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("splitStatements() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,119 @@
+-- 基线：系统表 (部门、角色、用户及关联、登录加密密钥)
+-- 与迁移框架引入前 AutoMigrate 创建的结构一致，包含仓库中手动创建的索引
+
+CREATE TABLE IF NOT EXISTS `sys_dept` (
+  `dept_id` bigint unsigned AUTO_INCREMENT COMMENT 'ID',
+  `pid` bigint unsigned COMMENT '上级部门',
+  `sub_count` bigint DEFAULT 0 COMMENT '子部门数目',
+  `name` varchar(255) NOT NULL COMMENT '名称',
+  `dept_sort` bigint DEFAULT 999 COMMENT '排序',
+  `enabled` boolean NOT NULL COMMENT '状态',
+  `create_by` varchar(255) COMMENT '创建者',
+  `update_by` varchar(255) COMMENT '更新者',
+  `create_time` datetime(3) NULL COMMENT '创建日期',
+  `update_time` datetime(3) NULL COMMENT '更新时间',
+  `image_url` varchar(255) COMMENT '图片',
+  `source` varchar(32) NOT NULL COMMENT '部门来源 PLATFORM：自建，FEISHU:飞书',
+  `corp_id` varchar(255) COMMENT '第三方来源配置标识',
+  `open_department_id` varchar(255) COMMENT '第三方部门id',
+  PRIMARY KEY (`dept_id`),
+  INDEX `idx_pid` (`pid`),
+  INDEX `idx_enabled` (`enabled`),
+  INDEX `idx_name` (`name`),
+  INDEX `idx_source` (`source`),
+  INDEX `idx_corp_id` (`corp_id`),
+  INDEX `idx_open_department_id` (`open_department_id`),
+  INDEX `idx_dept_sort` (`dept_sort`)
+);
+
+CREATE TABLE IF NOT EXISTS `sys_role` (
+  `role_id` bigint unsigned AUTO_INCREMENT COMMENT 'ID',
+  `name` varchar(100) NOT NULL COMMENT '名称',
+  `level` bigint COMMENT '角色级别',
+  `description` varchar(255) COMMENT '描述',
+  `data_scope` varchar(255) COMMENT '数据权限',
+  `create_by` varchar(255) COMMENT '创建者',
+  `update_by` varchar(255) COMMENT '更新者',
+  `create_time` datetime(3) NULL COMMENT '创建日期',
+  `update_time` datetime(3) NULL COMMENT '更新时间',
+  PRIMARY KEY (`role_id`),
+  UNIQUE INDEX `uniq_name` (`name`),
+  INDEX `role_name_index` (`name`),
+  INDEX `idx_level` (`level`),
+  INDEX `idx_data_scope` (`data_scope`),
+  INDEX `idx_create_by` (`create_by`),
+  INDEX `idx_create_time` (`create_time`),
+  INDEX `idx_update_time` (`update_time`)
+);
+
+CREATE TABLE IF NOT EXISTS `sys_user` (
+  `user_id` bigint unsigned AUTO_INCREMENT COMMENT 'ID',
+  `dept_id` bigint unsigned COMMENT '部门名称',
+  `username` varchar(180) COMMENT '用户名',
+  `nick_name` varchar(255) COMMENT '昵称',
+  `gender` varchar(2) COMMENT '性别',
+  `phone` varchar(255) COMMENT '手机号码',
+  `email` varchar(180) COMMENT '邮箱',
+  `avatar_name` varchar(255) COMMENT '头像地址',
+  `avatar_path` varchar(255) COMMENT '头像真实路径',
+  `password` varchar(255) COMMENT '密码',
+  `salt` varchar(255) COMMENT '密码盐',
+  `is_admin` boolean DEFAULT false COMMENT '是否为admin账号',
+  `enabled` boolean COMMENT '状态：1启用、0禁用',
+  `create_by` varchar(255) COMMENT '创建者',
+  `update_by` varchar(255) COMMENT '更新者',
+  `pwd_reset_time` datetime(3) NULL COMMENT '修改密码的时间',
+  `create_time` datetime(3) NULL COMMENT '创建日期',
+  `update_time` datetime(3) NULL COMMENT '更新时间',
+  `enterprise_wechat_id` varchar(255) COMMENT '企业微信ID',
+  `create_qagent` boolean DEFAULT false COMMENT '是否创建QAgent账号',
+  `ding_talk_id` varchar(128) COMMENT '钉钉ID',
+  `feishu_id` varchar(128) COMMENT '飞书ID',
+  `source` varchar(32) COMMENT '来源 PLATFORM：自建，FEISHU:飞书',
+  `third_party_open_id` varchar(128) COMMENT '第三方平台唯一id 飞书：openId',
+  `third_party_union_id` varchar(128) COMMENT '第三方平台唯一id[跨应用] 飞书：union_id',
+  `corp_id` varchar(128) COMMENT '来源中的corpId 飞书：corpId 钉钉：corpId',
+  PRIMARY KEY (`user_id`),
+  UNIQUE INDEX `uniq_username` (`username`),
+  UNIQUE INDEX `uniq_email` (`email`),
+  INDEX `inx_enabled` (`enabled`),
+  INDEX `idx_dept_id` (`dept_id`),
+  INDEX `idx_enabled` (`enabled`),
+  INDEX `idx_nick_name` (`nick_name`)
+);
+
+CREATE TABLE IF NOT EXISTS `sys_roles_depts` (
+  `role_id` bigint unsigned NOT NULL COMMENT '角色ID',
+  `dept_id` bigint unsigned NOT NULL COMMENT '部门ID',
+  PRIMARY KEY (`role_id`,`dept_id`),
+  INDEX `idx_role_id` (`role_id`),
+  INDEX `idx_dept_id` (`dept_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `sys_users_roles` (
+  `user_id` bigint unsigned NOT NULL COMMENT '用户ID',
+  `role_id` bigint unsigned NOT NULL COMMENT '角色ID',
+  PRIMARY KEY (`user_id`,`role_id`),
+  INDEX `idx_user_id` (`user_id`),
+  INDEX `idx_role_id` (`role_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `sys_encryption_key` (
+  `key_id` varchar(64) COMMENT '密钥ID',
+  `public_key` text COMMENT '公钥(PEM格式)',
+  `private_key` text COMMENT '私钥(PEM格式)',
+  `algorithm` varchar(32) DEFAULT 'RSA-2048' COMMENT '加密算法',
+  `key_size` bigint DEFAULT 2048 COMMENT '密钥长度',
+  `status` varchar(16) DEFAULT 'ACTIVE' COMMENT '密钥状态:ACTIVE,EXPIRED,REVOKED',
+  `client_id` varchar(128) COMMENT '客户端标识',
+  `issued_at` datetime(3) NULL COMMENT '签发时间',
+  `expires_at` datetime(3) NULL COMMENT '过期时间',
+  `create_time` datetime(3) NULL COMMENT '创建时间',
+  `update_time` datetime(3) NULL COMMENT '更新时间',
+  PRIMARY KEY (`key_id`),
+  INDEX `idx_client_id` (`client_id`),
+  INDEX `idx_status` (`status`),
+  INDEX `idx_expires_at` (`expires_at`),
+  INDEX `idx_issued_at` (`issued_at`),
+  UNIQUE INDEX `uniq_key_id` (`key_id`)
+);
@@ -0,0 +1,163 @@
+-- 基线：MCP 业务表 (环境、镜像仓库凭证、代码包、模板、实例及实例事件和操作记录)
+-- 与迁移框架引入前 AutoMigrate 创建的结构一致，包含仓库中手动创建的索引
+
+CREATE TABLE IF NOT EXISTS `mcp_environment` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `name` varchar(100) NOT NULL COMMENT '环境名称',
+  `environment` varchar(20) NOT NULL COMMENT '运行环境 (kubernetes/docker)',
+  `config` text COMMENT '连接配置',
+  `namespace` varchar(100) NOT NULL COMMENT '命名空间',
+  `hosting_image` varchar(255) COMMENT '托管镜像地址',
+  `supergateway_image` varchar(255) COMMENT 'supergateway 镜像地址',
+  `max_instances` bigint DEFAULT 0 COMMENT '托管实例数量上限',
+  `max_total_memory` varchar(20) COMMENT '托管实例内存限制总量上限',
+  `max_total_cpu` varchar(20) COMMENT '托管实例 CPU 限制总量上限',
+  `quota_exclude_inactive` boolean DEFAULT false COMMENT '禁用和已停止的实例是否不计入配额',
+  `defaults` json COMMENT '实例默认值 (JSON格式)',
+  `creator_id` varchar(100) NOT NULL COMMENT '创建人ID',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  `is_deleted` boolean DEFAULT false COMMENT '是否删除',
+  PRIMARY KEY (`id`),
+  INDEX `idx_name` (`name`),
+  INDEX `idx_environment` (`environment`),
+  INDEX `idx_is_deleted` (`is_deleted`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_registry_credentials` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `name` varchar(100) NOT NULL COMMENT '凭证名称',
+  `registry_host` varchar(255) NOT NULL COMMENT '镜像仓库地址',
+  `username` varchar(255) NOT NULL COMMENT '用户名',
+  `password` text NOT NULL COMMENT '加密后的密码或令牌',
+  `creator_id` varchar(100) COMMENT '创建人ID',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_mcp_registry_credential_name` (`name`),
+  INDEX `idx_mcp_registry_credential_host` (`registry_host`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_code_package` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `package_id` varchar(100) NOT NULL COMMENT '包ID',
+  `package_type` varchar(10) NOT NULL COMMENT '包类型 (tar/zip)',
+  `package_path` varchar(500) NOT NULL COMMENT '包存储目录路径',
+  `original_path` varchar(500) COMMENT '原始压缩包文件路径',
+  `extracted_path` varchar(500) COMMENT '解压后的绝对路径',
+  `original_name` varchar(255) COMMENT '原始文件名',
+  `file_size` bigint COMMENT '文件大小(字节)',
+  `is_deleted` boolean DEFAULT false COMMENT '是否删除',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  CONSTRAINT `uni_mcp_code_package_package_id` UNIQUE (`package_id`),
+  UNIQUE INDEX `idx_code_package_package_id` (`package_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_template` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `name` varchar(200) NOT NULL COMMENT '实例名称',
+  `port` int DEFAULT 0 COMMENT '端口号',
+  `init_script` text COMMENT '初始化脚本',
+  `command` text COMMENT '启动命令',
+  `environment_variables` json COMMENT '环境变量 (JSON格式)',
+  `volume_mounts` json COMMENT '卷挂载配置列表 (JSON格式)',
+  `startup_timeout` int DEFAULT 0 COMMENT '启动超时时间（秒）',
+  `running_timeout` int DEFAULT 0 COMMENT '运行超时时间（秒）',
+  `environment_id` int DEFAULT 0 COMMENT '环境ID',
+  `package_id` varchar(100) COMMENT '包ID',
+  `access_type` varchar(20) NOT NULL COMMENT '访问类型 (直连-direct/代理-proxy/托管-hosting)',
+  `mcp_protocol` varchar(20) NOT NULL COMMENT 'MCP协议 (SSE-1/StreamableHttp-2/Stdio-3)',
+  `mcp_servers` json COMMENT 'MCP服务器配置 (JSON格式)',
+  `img_address` varchar(100) NOT NULL COMMENT '镜像地址',
+  `mcp_server_id` varchar(100) COMMENT 'MCP 服务器ID',
+  `tokens` json COMMENT 'MCP 实例令牌 (JSON格式)',
+  `notes` text COMMENT '备注',
+  `service_path` varchar(100) NOT NULL DEFAULT '' COMMENT 'MCP 服务路径',
+  `icon_path` varchar(100) NOT NULL DEFAULT '' COMMENT 'MCP 图标路径',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_mcp_template_mcp_server_id` (`mcp_server_id`),
+  INDEX `idx_mcp_template_environment_id` (`environment_id`),
+  UNIQUE INDEX `idx_mcp_template_name` (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_instance` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `instance_name` varchar(200) NOT NULL COMMENT '实例名称',
+  `notes` text COMMENT '备注',
+  `access_type` varchar(20) NOT NULL COMMENT '访问类型 (直连-direct/代理-proxy/托管-hosting)',
+  `mcp_protocol` varchar(20) NOT NULL COMMENT 'MCP 协议 (sse/streamableHttp/stdio)',
+  `status` varchar(20) NOT NULL DEFAULT 'active' COMMENT '实例状态 (活跃-active/不活跃-inactive)',
+  `package_id` varchar(100) NOT NULL COMMENT '实例所属套餐ID',
+  `environment_id` bigint unsigned DEFAULT 0 COMMENT '环境ID',
+  `source_type` varchar(20) NOT NULL COMMENT '实例来源 (MCP 市场-market/实例模版-template/自定义-custom)',
+  `mcp_server_id` varchar(100) NOT NULL COMMENT 'MCP 服务器ID',
+  `template_id` bigint unsigned NOT NULL COMMENT '实例模版ID',
+  `tokens` json COMMENT 'MCP 实例令牌 (JSON格式)',
+  `img_addr` varchar(100) NOT NULL DEFAULT '' COMMENT '镜像地址',
+  `port` int DEFAULT 0 COMMENT '端口号',
+  `init_script` text COMMENT '初始化脚本',
+  `command` text COMMENT '启动命令',
+  `environment_variables` json COMMENT '环境变量 (JSON格式)',
+  `volume_mounts` json COMMENT '卷挂载配置列表 (JSON格式)',
+  `init_containers` json COMMENT '初始化容器列表 (JSON格式)',
+  `init_shared_path` varchar(255) NOT NULL DEFAULT '' COMMENT '初始化容器共享卷挂载路径',
+  `sidecars` json COMMENT '边车容器列表 (JSON格式)',
+  `startup_timeout` bigint DEFAULT 0 COMMENT '容器启动超时时间 (毫秒时间戳)',
+  `running_timeout` bigint DEFAULT 0 COMMENT '容器运行超时时间 (毫秒时间戳)',
+  `container_create_options` json COMMENT '容器创建选项 (JSON格式)',
+  `container_status` varchar(20) NOT NULL DEFAULT 'pending' COMMENT '容器状态 (启动中-pending/运行中-running/启动超时停止-init-timeout-stop/运行超时停止-run-timeout-stop/异常强制停止-exception-force-stop/手动停止-manual-stop)',
+  `container_name` varchar(100) NOT NULL COMMENT '容器名称',
+  `container_service_name` varchar(100) NOT NULL COMMENT '容器服务名称',
+  `container_is_ready` boolean NOT NULL COMMENT '容器服务名称',
+  `container_last_message` text COMMENT '容器上次状态信息',
+  `container_restart_count` int NOT NULL DEFAULT 0 COMMENT '已记录的容器重启次数，用于检测新的崩溃重启',
+  `replicas` int DEFAULT 1 COMMENT '容器副本数 (缩容到0时为0)',
+  `previous_replicas` int DEFAULT 0 COMMENT '缩容到0前的副本数，启动时恢复',
+  `source_config` json COMMENT 'MCP 来源服务配置 (JSON格式)',
+  `target_config` json COMMENT 'MCP 目标服务配置 (JSON格式)',
+  `public_proxy_config` json COMMENT 'MCP 公网代理服务配置 (JSON格式)',
+  `service_path` varchar(100) NOT NULL DEFAULT '' COMMENT 'MCP 服务路径',
+  `icon_path` varchar(100) NOT NULL DEFAULT '' COMMENT 'MCP 图标路径',
+  `labels` json COMMENT '实例标签 (JSON格式)',
+  `locked` boolean NOT NULL DEFAULT false COMMENT '是否锁定，锁定后不能修改配置',
+  `lock_reason` varchar(500) NOT NULL DEFAULT '' COMMENT '锁定原因',
+  `locked_by` varchar(100) NOT NULL DEFAULT '' COMMENT '锁定用户',
+  `locked_at` timestamp(3) COMMENT '锁定时间',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_mcp_instance_instance_id` (`instance_id`),
+  UNIQUE INDEX `idx_mcp_instance_name` (`instance_name`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_instance_events` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `dedup_key` varchar(64) NOT NULL COMMENT '去重键(reason+message+timestamp 的哈希)',
+  `type` varchar(20) NOT NULL COMMENT '事件级别 (Warning/Normal)',
+  `reason` varchar(255) COMMENT '事件原因',
+  `message` text COMMENT '事件消息',
+  `first_timestamp` bigint NOT NULL COMMENT '事件发生时间（秒级时间戳）',
+  `last_timestamp` bigint NOT NULL COMMENT '最后一次采集到该事件的时间（秒级时间戳）',
+  `count` int NOT NULL DEFAULT 1 COMMENT '采集到该事件的次数',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_mcp_instance_event_dedup` (`instance_id`,`dedup_key`)
+);
+
+CREATE TABLE IF NOT EXISTS `mcp_instance_operations` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `operation` varchar(50) NOT NULL COMMENT '操作类型',
+  `actor` varchar(100) NOT NULL COMMENT '操作人，平台自动操作为 system',
+  `detail` text COMMENT '操作详情',
+  `created_at` timestamp(3) NOT NULL COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_mcp_instance_operation_instance` (`instance_id`,`created_at`)
+);
@@ -0,0 +1,26 @@
+-- MCP 服务目录：内置和远程同步的目录条目，一键安装时生成模板或实例
+
+CREATE TABLE IF NOT EXISTS `mcp_catalog` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `catalog_id` varchar(100) NOT NULL COMMENT '目录条目ID',
+  `name` varchar(200) NOT NULL COMMENT '名称',
+  `description` text COMMENT '描述',
+  `category` varchar(100) NOT NULL DEFAULT '' COMMENT '分类',
+  `icon_url` varchar(500) NOT NULL DEFAULT '' COMMENT '图标地址',
+  `repository_url` varchar(500) NOT NULL DEFAULT '' COMMENT '源码仓库地址',
+  `version` varchar(50) NOT NULL DEFAULT '' COMMENT '版本',
+  `access_type` varchar(20) NOT NULL COMMENT '访问类型 (直连-direct/代理-proxy/托管-hosting)',
+  `mcp_protocol` varchar(20) NOT NULL COMMENT 'MCP 协议 (sse/streamable-http/stdio)',
+  `img_address` varchar(255) NOT NULL DEFAULT '' COMMENT '镜像地址',
+  `command` text COMMENT '启动命令',
+  `port` int DEFAULT 0 COMMENT '端口号',
+  `service_path` varchar(100) NOT NULL DEFAULT '' COMMENT 'MCP 服务路径',
+  `mcp_servers` json COMMENT 'MCP 服务器配置，${NAME} 在安装时替换为环境变量的值 (JSON格式)',
+  `env_schema` json COMMENT '安装时需要填写的环境变量 (JSON格式)',
+  `source` varchar(20) NOT NULL COMMENT '来源 (内置-bundled/远程-remote)',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_mcp_catalog_catalog_id` (`catalog_id`),
+  INDEX `idx_mcp_catalog_category` (`category`)
+);
@@ -0,0 +1,21 @@
+-- MCP 服务目录：安装次数、用户评分、管理员认证和展示顺序
+
+ALTER TABLE `mcp_catalog`
+  ADD COLUMN `install_count` bigint NOT NULL DEFAULT 0 COMMENT '安装次数',
+  ADD COLUMN `rating_count` bigint NOT NULL DEFAULT 0 COMMENT '评分人数',
+  ADD COLUMN `rating_avg` double NOT NULL DEFAULT 0 COMMENT '平均评分',
+  ADD COLUMN `verified` boolean NOT NULL DEFAULT false COMMENT '是否经管理员认证',
+  ADD COLUMN `display_order` int NOT NULL DEFAULT 0 COMMENT '展示顺序，越小越靠前',
+  ADD INDEX `idx_mcp_catalog_display_order` (`display_order`);
+
+CREATE TABLE IF NOT EXISTS `mcp_catalog_rating` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `catalog_id` varchar(100) NOT NULL COMMENT '目录条目ID',
+  `user_id` bigint unsigned NOT NULL COMMENT '评分用户ID',
+  `rating` int NOT NULL COMMENT '评分 (1-5)',
+  `comment` text COMMENT '评价内容',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_mcp_catalog_rating_user` (`catalog_id`, `user_id`)
+);
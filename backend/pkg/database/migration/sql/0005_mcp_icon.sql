@@ -0,0 +1,14 @@
+-- 上传的图标：按内容哈希命名，清理任务删除未被引用的图标
+
+CREATE TABLE IF NOT EXISTS `mcp_icon` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `name` varchar(80) NOT NULL COMMENT '文件名 ({sha256}.{扩展名})',
+  `content_type` varchar(50) NOT NULL COMMENT 'MIME 类型',
+  `size` bigint NOT NULL COMMENT '文件大小 (字节)',
+  `width` int NOT NULL COMMENT '宽度 (像素)',
+  `height` int NOT NULL COMMENT '高度 (像素)',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_mcp_icon_name` (`name`),
+  INDEX `idx_mcp_icon_created_at` (`created_at`)
+);
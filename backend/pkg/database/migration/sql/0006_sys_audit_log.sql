@@ -0,0 +1,16 @@
+-- 审计日志：批量操作等用户请求只记录一条，详情中包含完整的资源ID列表
+
+CREATE TABLE IF NOT EXISTS `sys_audit_log` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `actor` varchar(100) NOT NULL COMMENT '操作人，平台自动操作为 system',
+  `action` varchar(100) NOT NULL COMMENT '操作类型',
+  `resource_type` varchar(50) NOT NULL COMMENT '资源类型',
+  `resource_id` varchar(100) COMMENT '资源ID，批量操作为空',
+  `detail` json COMMENT '操作详情',
+  `request_id` varchar(100) COMMENT '请求ID',
+  `created_at` timestamp(3) NOT NULL COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_sys_audit_log_actor` (`actor`),
+  INDEX `idx_sys_audit_log_action` (`action`),
+  INDEX `idx_sys_audit_log_created_at` (`created_at`)
+);
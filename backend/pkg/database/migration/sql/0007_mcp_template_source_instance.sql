@@ -0,0 +1,4 @@
+-- 实例另存为模板：记录模板的来源实例
+
+ALTER TABLE `mcp_template`
+  ADD COLUMN `source_instance_id` varchar(100) NOT NULL DEFAULT '' COMMENT '来源实例ID，由实例另存为模板时记录';
@@ -0,0 +1,18 @@
+-- 直连和代理实例的定时健康检查：实例上记录检查配置和最近一次的健康状态，检查结果保存在历史表中
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `health_monitor` json COMMENT '健康检查配置 (JSON格式)，仅直连和代理实例',
+  ADD COLUMN `health_status` varchar(20) NOT NULL DEFAULT '' COMMENT '健康状态 (up/down)，未检查时为空',
+  ADD COLUMN `health_checked_at` timestamp(3) COMMENT '最近一次健康检查时间';
+
+CREATE TABLE IF NOT EXISTS `mcp_instance_health_checks` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `success` boolean NOT NULL COMMENT '检查是否成功',
+  `status_code` int NOT NULL DEFAULT 0 COMMENT 'HTTP 响应状态码，端口探测为 0',
+  `latency_ms` bigint NOT NULL DEFAULT 0 COMMENT '检查耗时（毫秒）',
+  `error` varchar(500) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `checked_at` timestamp(3) NOT NULL COMMENT '检查时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_mcp_instance_health_check_instance` (`instance_id`,`checked_at`)
+);
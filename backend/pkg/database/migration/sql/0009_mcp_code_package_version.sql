@@ -0,0 +1,7 @@
+-- 代码包内容变更跟踪：代码包修改时递增内容版本，实例记录容器最近一次创建时的版本，版本落后的实例需要重新部署
+
+ALTER TABLE `mcp_code_package`
+  ADD COLUMN `content_version` int NOT NULL DEFAULT 0 COMMENT '内容版本，代码包文件修改时递增';
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `code_package_version` int NOT NULL DEFAULT 0 COMMENT '容器最近一次创建时代码包的内容版本';
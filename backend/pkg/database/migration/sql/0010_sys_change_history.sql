@@ -0,0 +1,14 @@
+-- 实例和模板修改的字段级变更记录，每次修改一条，通过 audit_log_id 关联审计日志
+
+CREATE TABLE IF NOT EXISTS `sys_change_history` (
+  `id` bigint unsigned AUTO_INCREMENT COMMENT '主键ID',
+  `audit_log_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '审计日志ID，审计日志写入失败时为 0',
+  `resource_type` varchar(50) NOT NULL COMMENT '资源类型',
+  `resource_id` varchar(100) NOT NULL COMMENT '资源ID',
+  `actor` varchar(100) NOT NULL COMMENT '操作人',
+  `changes` json COMMENT '修改的字段列表，敏感字段只记录字段名',
+  `request_id` varchar(100) COMMENT '请求ID',
+  `created_at` timestamp(3) NOT NULL COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  INDEX `idx_sys_change_history_resource` (`resource_type`,`resource_id`,`created_at`)
+);
@@ -0,0 +1,7 @@
+-- 实例和模板的结构化元数据，用于记录运维手册、值班联系人等，不再塞进备注
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `metadata` json COMMENT '结构化元数据 (JSON对象)，如运维手册和值班联系人';
+
+ALTER TABLE `mcp_template`
+  ADD COLUMN `metadata` json COMMENT '结构化元数据 (JSON对象)，如运维手册和值班联系人';
@@ -0,0 +1,8 @@
+-- 托管实例镜像漏洞扫描：实例上记录最近一次的扫描结果，环境上配置严重漏洞拦截策略
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `image_scan` json COMMENT '镜像漏洞扫描结果 (JSON格式)，仅托管实例';
+
+ALTER TABLE `mcp_environment`
+  ADD COLUMN `block_critical_vulnerabilities` boolean NOT NULL DEFAULT false COMMENT '严重漏洞超过上限时拒绝创建托管实例',
+  ADD COLUMN `max_critical_vulnerabilities` int NOT NULL DEFAULT 0 COMMENT '允许的严重漏洞数上限';
@@ -0,0 +1,6 @@
+-- 托管实例来源记录：容器创建时镜像标签解析到的摘要、代码包内容校验和以及模板版本
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `code_package_checksum` varchar(80) NOT NULL DEFAULT '' COMMENT '容器最近一次创建时代码包内容的 sha256',
+  ADD COLUMN `image_digest` varchar(100) NOT NULL DEFAULT '' COMMENT '容器最近一次创建时镜像标签解析到的摘要',
+  ADD COLUMN `template_updated_at` timestamp(3) COMMENT '创建时模板的更新时间，作为模板版本';
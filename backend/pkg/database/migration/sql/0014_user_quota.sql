@@ -0,0 +1,20 @@
+-- 用户配额：实例和代码包记录创建用户，管理员为用户设置实例数、托管实例数和代码包存储上限
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `creator_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '创建用户ID，0 表示系统创建或创建于配额功能之前',
+  ADD INDEX `idx_mcp_instance_creator_id` (`creator_id`);
+
+ALTER TABLE `mcp_code_package`
+  ADD COLUMN `creator_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '上传用户ID',
+  ADD INDEX `idx_mcp_code_package_creator_id` (`creator_id`);
+
+CREATE TABLE IF NOT EXISTS `mcp_user_quota` (
+  `user_id` bigint unsigned NOT NULL COMMENT '用户ID',
+  `max_instances` int NOT NULL DEFAULT 0 COMMENT '实例数上限，0 表示不限制',
+  `max_hosting_instances` int NOT NULL DEFAULT 0 COMMENT '托管实例数上限，0 表示不限制',
+  `max_package_bytes` bigint NOT NULL DEFAULT 0 COMMENT '代码包存储上限（字节），0 表示不限制',
+  `updated_by` varchar(100) NOT NULL DEFAULT '' COMMENT '最近一次修改配额的用户',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`user_id`)
+);
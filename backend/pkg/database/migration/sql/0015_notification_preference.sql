@@ -0,0 +1,14 @@
+-- 实例事件邮件通知：用户选择接收的事件以及即时发送或定时汇总
+
+CREATE TABLE IF NOT EXISTS `mcp_notification_preference` (
+  `user_id` bigint unsigned NOT NULL COMMENT '用户ID',
+  `enabled` boolean NOT NULL DEFAULT false COMMENT '是否开启邮件通知',
+  `email` varchar(180) NOT NULL DEFAULT '' COMMENT '接收地址，为空时使用用户邮箱',
+  `events` json COMMENT '接收的事件 (JSON数组)',
+  `mode` varchar(20) NOT NULL DEFAULT 'immediate' COMMENT '发送方式 (immediate/digest)',
+  `last_digest_at` timestamp(3) COMMENT '最近一次汇总的截止时间',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`user_id`),
+  INDEX `idx_mcp_notification_preference_mode` (`enabled`, `mode`)
+);
@@ -0,0 +1,28 @@
+-- 受保护环境的双人审批：编辑和删除受保护环境中的实例先创建变更请求，由另一位管理员审批后执行
+
+ALTER TABLE `mcp_environment`
+  ADD COLUMN `protected` boolean NOT NULL DEFAULT false COMMENT '编辑和删除实例需要审批';
+
+CREATE TABLE IF NOT EXISTS `change_requests` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `operation` varchar(50) NOT NULL COMMENT '变更操作 (instance.edit/instance.delete)',
+  `resource_type` varchar(50) NOT NULL COMMENT '资源类型',
+  `resource_id` varchar(100) NOT NULL COMMENT '资源ID',
+  `resource_name` varchar(200) NOT NULL DEFAULT '' COMMENT '资源名称',
+  `environment_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '环境ID',
+  `payload` json COMMENT '审批通过后执行的请求 (JSON格式)',
+  `status` varchar(20) NOT NULL DEFAULT 'pending' COMMENT '状态 (pending/approved/rejected/expired/failed)',
+  `requested_by` varchar(100) NOT NULL COMMENT '申请人',
+  `requested_by_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '申请人用户ID',
+  `reviewed_by` varchar(100) NOT NULL DEFAULT '' COMMENT '审批人',
+  `reviewed_by_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '审批人用户ID',
+  `review_comment` varchar(500) NOT NULL DEFAULT '' COMMENT '审批意见',
+  `reviewed_at` timestamp(3) NULL COMMENT '审批时间',
+  `result` text COMMENT '执行失败的原因',
+  `expires_at` timestamp(3) NOT NULL COMMENT '过期时间',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  `updated_at` timestamp(3) NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_change_requests_resource` (`resource_id`, `status`),
+  KEY `idx_change_requests_status` (`status`, `expires_at`)
+);
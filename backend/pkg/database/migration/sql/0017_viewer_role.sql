@@ -0,0 +1,4 @@
+-- 只读角色：可以查看实例、状态和日志，不能修改任何资源，响应中的环境变量、令牌和配置被脱敏
+
+INSERT IGNORE INTO `sys_role` (`name`, `level`, `description`, `data_scope`, `create_by`, `update_by`, `create_time`, `update_time`)
+VALUES ('viewer', 3, '只读角色，可以查看实例、状态和日志，敏感字段脱敏', 'all', 'System', 'System', NOW(3), NOW(3));
@@ -0,0 +1,27 @@
+-- 托管实例成本估算：环境的 CPU 和内存小时费率，以及每晚汇总的每日成本
+
+ALTER TABLE `mcp_environment`
+  ADD COLUMN `cost_per_cpu_hour` double NOT NULL DEFAULT 0 COMMENT '每 CPU 核每小时费用',
+  ADD COLUMN `cost_per_gib_hour` double NOT NULL DEFAULT 0 COMMENT '每 GiB 内存每小时费用';
+
+CREATE TABLE IF NOT EXISTS `mcp_instance_daily_cost` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `instance_name` varchar(200) NOT NULL DEFAULT '' COMMENT '实例名称',
+  `environment_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '环境ID',
+  `day` varchar(10) NOT NULL COMMENT '日期 (YYYY-MM-DD)',
+  `labels` json COMMENT '汇总时的实例标签 (JSON格式)',
+  `running_hours` double NOT NULL DEFAULT 0 COMMENT '运行小时数',
+  `replica_hours` double NOT NULL DEFAULT 0 COMMENT '运行小时数乘以副本数',
+  `cpu_cores` double NOT NULL DEFAULT 0 COMMENT '每个副本请求的 CPU 核数',
+  `memory_gib` double NOT NULL DEFAULT 0 COMMENT '每个副本请求的内存 GiB',
+  `cpu_hour_rate` double NOT NULL DEFAULT 0 COMMENT '当天的每 CPU 核每小时费用',
+  `gib_hour_rate` double NOT NULL DEFAULT 0 COMMENT '当天的每 GiB 内存每小时费用',
+  `cost` double NOT NULL DEFAULT 0 COMMENT '当天成本',
+  `running_at_end` boolean NOT NULL DEFAULT false COMMENT '当天结束时是否运行，作为次日的初始状态',
+  `replicas_at_end` int NOT NULL DEFAULT 0 COMMENT '当天结束时的副本数',
+  `created_at` timestamp(3) NOT NULL COMMENT '汇总时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_mcp_instance_daily_cost` (`instance_id`, `day`),
+  KEY `idx_mcp_instance_daily_cost_day` (`day`)
+);
@@ -0,0 +1,16 @@
+-- 实例自定义访问路径：网关通过 /{prefix}/{slug}/... 访问实例，修改后旧路径在宽限期内重定向到新路径
+
+ALTER TABLE `mcp_instance`
+  ADD COLUMN `slug` varchar(63) DEFAULT NULL COMMENT '网关自定义访问路径，未设置时为 NULL',
+  ADD UNIQUE INDEX `idx_mcp_instance_slug` (`slug`);
+
+CREATE TABLE IF NOT EXISTS `mcp_instance_slug_alias` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键ID',
+  `slug` varchar(63) NOT NULL COMMENT '旧的自定义访问路径',
+  `instance_id` varchar(100) NOT NULL COMMENT '实例ID',
+  `expires_at` timestamp(3) NOT NULL COMMENT '重定向截止时间',
+  `created_at` timestamp(3) NOT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_mcp_instance_slug_alias_slug` (`slug`),
+  KEY `idx_mcp_instance_slug_alias_instance_id` (`instance_id`)
+);
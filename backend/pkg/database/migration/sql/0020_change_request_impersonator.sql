@@ -0,0 +1,4 @@
+-- 管理员模拟用户提交的变更请求记录管理员本人，该管理员不能审批自己代为提交的请求
+
+ALTER TABLE `change_requests`
+  ADD COLUMN `impersonated_by_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '模拟申请人提交的管理员用户ID' AFTER `requested_by_id`;
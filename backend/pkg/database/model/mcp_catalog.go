@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CatalogSource 目录条目来源
+type CatalogSource string
+
+const (
+	// CatalogSourceBundled 随 init 服务内置的目录
+	CatalogSourceBundled CatalogSource = "bundled"
+	// CatalogSourceRemote 从远程目录地址同步
+	CatalogSourceRemote CatalogSource = "remote"
+)
+
+// CatalogEnvVar 安装目录条目时需要填写的环境变量
+type CatalogEnvVar struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// McpCatalogEntry MCP 服务目录条目
+// 一键安装时根据条目生成模板或实例，生成的模板和实例的 McpServerID 为条目的 CatalogID
+// 安装次数、评分、认证和展示顺序由市场服务维护，同步目录时保持不变
+type McpCatalogEntry struct {
+	ID            uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	CatalogID     string          `gorm:"size:100;not null;uniqueIndex:idx_mcp_catalog_catalog_id;comment:目录条目ID" json:"catalogID"`
+	Name          string          `gorm:"size:200;not null;comment:名称" json:"name"`
+	Description   string          `gorm:"type:text;comment:描述" json:"description"`
+	Category      string          `gorm:"size:100;not null;default:'';index:idx_mcp_catalog_category;comment:分类" json:"category"`
+	IconURL       string          `gorm:"size:500;not null;default:'';comment:图标地址" json:"iconURL"`
+	RepositoryURL string          `gorm:"size:500;not null;default:'';comment:源码仓库地址" json:"repositoryURL"`
+	Version       string          `gorm:"size:50;not null;default:'';comment:版本" json:"version"`
+	AccessType    AccessType      `gorm:"size:20;not null;comment:访问类型 (直连-direct/代理-proxy/托管-hosting)" json:"accessType"`
+	McpProtocol   McpProtocol     `gorm:"size:20;not null;comment:MCP 协议 (sse/streamable-http/stdio)" json:"mcpProtocol"`
+	ImgAddress    string          `gorm:"size:255;not null;default:'';comment:镜像地址" json:"imgAddress"`
+	Command       string          `gorm:"type:text;comment:启动命令" json:"command"`
+	Port          int32           `gorm:"default:0;comment:端口号" json:"port"`
+	ServicePath   string          `gorm:"size:100;not null;default:'';comment:MCP 服务路径" json:"servicePath"`
+	McpServers    json.RawMessage `gorm:"type:json;comment:MCP 服务器配置，${NAME} 在安装时替换为环境变量的值 (JSON格式)" json:"mcpServers"`
+	EnvSchema     json.RawMessage `gorm:"type:json;comment:安装时需要填写的环境变量 (JSON格式)" json:"envSchema"`
+	Source        CatalogSource   `gorm:"size:20;not null;comment:来源 (内置-bundled/远程-remote)" json:"source"`
+	InstallCount  int64           `gorm:"not null;default:0;comment:安装次数" json:"installCount"`
+	RatingCount   int64           `gorm:"not null;default:0;comment:评分人数" json:"ratingCount"`
+	RatingAvg     float64         `gorm:"not null;default:0;comment:平均评分" json:"ratingAvg"`
+	Verified      bool            `gorm:"not null;default:false;comment:是否经管理员认证" json:"verified"`
+	DisplayOrder  int32           `gorm:"not null;default:0;index:idx_mcp_catalog_display_order;comment:展示顺序，越小越靠前" json:"displayOrder"`
+	CreatedAt     time.Time       `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt     time.Time       `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpCatalogEntry) TableName() string {
+	return "mcp_catalog"
+}
+
+// PrepareForCreate 准备创建记录（设置创建和更新时间）
+func (m *McpCatalogEntry) PrepareForCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// PrepareForUpdate 准备更新记录（设置更新时间）
+func (m *McpCatalogEntry) PrepareForUpdate() {
+	m.UpdatedAt = time.Now()
+}
+
+// ValidateForCreate 验证创建目录条目的必要字段
+func (m *McpCatalogEntry) ValidateForCreate() error {
+	if m.CatalogID == "" {
+		return fmt.Errorf("catalog id is required")
+	}
+	if m.Name == "" {
+		return fmt.Errorf("catalog name is required")
+	}
+	if m.AccessType == "" {
+		return fmt.Errorf("access type is required")
+	}
+	if m.McpProtocol == "" {
+		return fmt.Errorf("mcp protocol is required")
+	}
+	return nil
+}
+
+// EnvVars 解析安装时需要填写的环境变量
+func (m *McpCatalogEntry) EnvVars() ([]CatalogEnvVar, error) {
+	if len(m.EnvSchema) == 0 || string(m.EnvSchema) == "null" {
+		return nil, nil
+	}
+	var vars []CatalogEnvVar
+	if err := json.Unmarshal(m.EnvSchema, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse env schema of catalog entry %s: %w", m.CatalogID, err)
+	}
+	return vars, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// 目录条目评分范围
+const (
+	CatalogRatingMin = 1
+	CatalogRatingMax = 5
+)
+
+// McpCatalogRating 用户对目录条目的评分，每个用户对每个条目只保留一条评分
+type McpCatalogRating struct {
+	ID        uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	CatalogID string    `gorm:"size:100;not null;uniqueIndex:idx_mcp_catalog_rating_user,priority:1;comment:目录条目ID" json:"catalogID"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_mcp_catalog_rating_user,priority:2;comment:评分用户ID" json:"userID"`
+	Rating    int32     `gorm:"not null;comment:评分 (1-5)" json:"rating"`
+	Comment   string    `gorm:"type:text;comment:评价内容" json:"comment"`
+	CreatedAt time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpCatalogRating) TableName() string {
+	return "mcp_catalog_rating"
+}
+
+// PrepareForCreate 准备创建记录（设置创建和更新时间）
+func (m *McpCatalogRating) PrepareForCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// ValidateForCreate 验证创建评分的必要字段
+func (m *McpCatalogRating) ValidateForCreate() error {
+	if m.CatalogID == "" {
+		return fmt.Errorf("catalog id is required")
+	}
+	if m.UserID == 0 {
+		return fmt.Errorf("user id is required")
+	}
+	if m.Rating < CatalogRatingMin || m.Rating > CatalogRatingMax {
+		return fmt.Errorf("rating must be between %d and %d", CatalogRatingMin, CatalogRatingMax)
+	}
+	return nil
+}
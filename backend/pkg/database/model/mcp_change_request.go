@@ -0,0 +1,83 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 变更请求操作
+const (
+	ChangeOperationInstanceEdit   = "instance.edit"   // 编辑实例
+	ChangeOperationInstanceDelete = "instance.delete" // 删除实例
+)
+
+// 变更请求状态
+const (
+	ChangeRequestStatusPending  = "pending"  // 待审批
+	ChangeRequestStatusApproved = "approved" // 已审批并执行成功
+	ChangeRequestStatusRejected = "rejected" // 已拒绝
+	ChangeRequestStatusExpired  = "expired"  // 超过有效期未审批
+	ChangeRequestStatusFailed   = "failed"   // 已审批但执行失败
+)
+
+// McpChangeRequest 受保护环境中实例的变更请求，保存待执行的请求内容，另一位管理员审批后按正常流程执行
+type McpChangeRequest struct {
+	ID            uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	Operation     string          `gorm:"size:50;not null;comment:变更操作 (instance.edit/instance.delete)" json:"operation"`
+	ResourceType  string          `gorm:"size:50;not null;comment:资源类型" json:"resourceType"`
+	ResourceID    string          `gorm:"size:100;not null;index:idx_change_requests_resource,priority:1;comment:资源ID" json:"resourceId"`
+	ResourceName  string          `gorm:"size:200;not null;default:'';comment:资源名称" json:"resourceName"`
+	EnvironmentID uint            `gorm:"not null;default:0;comment:环境ID" json:"environmentId"`
+	Payload       json.RawMessage `gorm:"type:json;comment:审批通过后执行的请求 (JSON格式)" json:"payload"`
+	Status        string          `gorm:"size:20;not null;default:'pending';index:idx_change_requests_resource,priority:2;index:idx_change_requests_status,priority:1;comment:状态" json:"status"`
+	RequestedBy   string          `gorm:"size:100;not null;comment:申请人" json:"requestedBy"`
+	RequestedByID uint            `gorm:"not null;default:0;comment:申请人用户ID" json:"requestedById"`
+	// 管理员模拟申请人提交时记录管理员自己的用户ID，该管理员同样不能审批
+	ImpersonatedByID uint       `gorm:"not null;default:0;comment:模拟申请人提交的管理员用户ID" json:"impersonatedById"`
+	ReviewedBy       string     `gorm:"size:100;not null;default:'';comment:审批人" json:"reviewedBy"`
+	ReviewedByID     uint       `gorm:"not null;default:0;comment:审批人用户ID" json:"reviewedById"`
+	ReviewComment    string     `gorm:"size:500;not null;default:'';comment:审批意见" json:"reviewComment"`
+	ReviewedAt       *time.Time `gorm:"type:timestamp(3);comment:审批时间" json:"reviewedAt"`
+	Result           string     `gorm:"type:text;comment:执行失败的原因" json:"result"`
+	ExpiresAt        time.Time  `gorm:"type:timestamp(3);not null;index:idx_change_requests_status,priority:2;comment:过期时间" json:"expiresAt"`
+	CreatedAt        time.Time  `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt        time.Time  `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpChangeRequest) TableName() string {
+	return "change_requests"
+}
+
+// PrepareForCreate 准备创建记录（设置状态和创建时间）
+func (m *McpChangeRequest) PrepareForCreate() {
+	now := time.Now()
+	if m.Status == "" {
+		m.Status = ChangeRequestStatusPending
+	}
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// ValidateForCreate 验证变更请求的必要字段
+func (m *McpChangeRequest) ValidateForCreate() error {
+	if m.Operation != ChangeOperationInstanceEdit && m.Operation != ChangeOperationInstanceDelete {
+		return fmt.Errorf("unsupported operation: %s", m.Operation)
+	}
+	if m.ResourceID == "" {
+		return fmt.Errorf("resource id is required")
+	}
+	if m.RequestedBy == "" {
+		return fmt.Errorf("requested by is required")
+	}
+	if m.ExpiresAt.IsZero() {
+		return fmt.Errorf("expires at is required")
+	}
+	return nil
+}
+
+// IsExpired 待审批的请求是否已过有效期
+func (m *McpChangeRequest) IsExpired(now time.Time) bool {
+	return m.Status == ChangeRequestStatusPending && !now.Before(m.ExpiresAt)
+}
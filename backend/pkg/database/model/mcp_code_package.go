@@ -34,6 +34,12 @@ type McpCodePackage struct {
 	IsDeleted     bool        `gorm:"default:false;comment:是否删除" json:"isDeleted"`
 	CreatedAt     time.Time   `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
 	UpdatedAt     time.Time   `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+
+	// 内容版本，代码包文件修改时递增，与实例记录的版本比较判断实例是否运行旧代码
+	ContentVersion int `gorm:"not null;default:0;comment:内容版本，代码包文件修改时递增" json:"contentVersion"`
+
+	// 上传用户ID，用于统计用户的代码包存储配额
+	CreatorID uint `gorm:"not null;default:0;index;comment:上传用户ID" json:"creatorId"`
 }
 
 // TableName 指定表名
@@ -13,16 +13,48 @@ const (
 	McpEnvironmentDocker     McpEnvironmentType = "docker"
 )
 
+// EnvironmentDefaults 环境级默认值，托管实例构建容器配置时合并，实例指定的值优先
+type EnvironmentDefaults struct {
+	EnvVars                   map[string]string `json:"envVars,omitempty"`
+	NodeSelector              map[string]string `json:"nodeSelector,omitempty"`
+	ResourceRequests          map[string]string `json:"resourceRequests,omitempty"`
+	ResourceLimits            map[string]string `json:"resourceLimits,omitempty"`
+	ImagePullSecrets          []string          `json:"imagePullSecrets,omitempty"`
+	Labels                    map[string]string `json:"labels,omitempty"`
+	PriorityClassName         string            `json:"priorityClassName,omitempty"`
+	CreatePodDisruptionBudget bool              `json:"createPodDisruptionBudget,omitempty"`
+	IsolateServiceAccount     bool              `json:"isolateServiceAccount,omitempty"`
+}
+
 type McpEnvironment struct {
 	ID          uint               `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
 	Name        string             `gorm:"size:100;not null;comment:环境名称" json:"name"`
 	Environment McpEnvironmentType `gorm:"size:20;not null;comment:运行环境 (kubernetes/docker)" json:"environment"`
 	Config      string             `gorm:"type:text;comment:连接配置" json:"config"`
 	Namespace   string             `gorm:"size:100;not null;comment:命名空间" json:"namespace"`
-	CreatorID   string             `gorm:"size:100;not null;comment:创建人ID" json:"creatorID"`
-	CreatedAt   time.Time          `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
-	UpdatedAt   time.Time          `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
-	IsDeleted   bool               `gorm:"default:false;comment:是否删除" json:"isDeleted"`
+	// 环境级别覆盖的托管镜像和 supergateway 镜像，为空时使用全局配置
+	HostingImage      string `gorm:"size:255;comment:托管镜像地址" json:"hostingImage"`
+	SupergatewayImage string `gorm:"size:255;comment:supergateway 镜像地址" json:"supergatewayImage"`
+	// 托管实例配额，0 或空表示不限制；资源总量按实例各容器声明的资源限制乘以副本数累加
+	MaxInstances         int    `gorm:"default:0;comment:托管实例数量上限" json:"maxInstances"`
+	MaxTotalMemory       string `gorm:"size:20;comment:托管实例内存限制总量上限" json:"maxTotalMemory"`
+	MaxTotalCPU          string `gorm:"size:20;comment:托管实例 CPU 限制总量上限" json:"maxTotalCPU"`
+	QuotaExcludeInactive bool   `gorm:"default:false;comment:禁用和已停止的实例是否不计入配额" json:"quotaExcludeInactive"`
+	// 托管实例继承的默认值，创建时合并在实例配置之下并写入容器创建选项
+	Defaults json.RawMessage `gorm:"type:json;comment:实例默认值 (JSON格式)" json:"defaults"`
+	// 镜像漏洞策略：开启后创建托管实例前扫描镜像，严重漏洞数超过上限时拒绝创建
+	BlockCriticalVulnerabilities bool `gorm:"not null;default:false;comment:严重漏洞超过上限时拒绝创建托管实例" json:"blockCriticalVulnerabilities"`
+	MaxCriticalVulnerabilities   int  `gorm:"not null;default:0;comment:允许的严重漏洞数上限" json:"maxCriticalVulnerabilities"`
+	// 受保护环境：编辑和删除实例需要另一位管理员审批
+	Protected bool `gorm:"not null;default:false;comment:编辑和删除实例需要审批" json:"protected"`
+	// 成本费率：托管实例每个 CPU 核和每 GiB 内存每小时的费用，每日成本记录保存当时的费率，修改后不影响历史成本
+	CostPerCPUHour float64 `gorm:"column:cost_per_cpu_hour;not null;default:0;comment:每 CPU 核每小时费用" json:"costPerCPUHour"`
+	CostPerGiBHour float64 `gorm:"column:cost_per_gib_hour;not null;default:0;comment:每 GiB 内存每小时费用" json:"costPerGiBHour"`
+
+	CreatorID string    `gorm:"size:100;not null;comment:创建人ID" json:"creatorID"`
+	CreatedAt time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+	IsDeleted bool      `gorm:"default:false;comment:是否删除" json:"isDeleted"`
 }
 
 // TableName 指定表名
@@ -60,6 +92,32 @@ func (m *McpEnvironment) SetConfig(config map[string]interface{}) error {
 	return nil
 }
 
+// GetDefaults 解析实例默认值，未设置时返回空默认值
+func (m *McpEnvironment) GetDefaults() (*EnvironmentDefaults, error) {
+	defaults := &EnvironmentDefaults{}
+	if len(m.Defaults) == 0 || string(m.Defaults) == "null" {
+		return defaults, nil
+	}
+	if err := json.Unmarshal(m.Defaults, defaults); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal defaults: %w", err)
+	}
+	return defaults, nil
+}
+
+// SetDefaults 设置实例默认值
+func (m *McpEnvironment) SetDefaults(defaults *EnvironmentDefaults) error {
+	if defaults == nil {
+		m.Defaults = nil
+		return nil
+	}
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return fmt.Errorf("failed to marshal defaults: %w", err)
+	}
+	m.Defaults = data
+	return nil
+}
+
 // IsDeleted 检查环境是否已被删除
 func (m *McpEnvironment) IsDeletedRecord() bool {
 	return m.IsDeleted
@@ -122,6 +180,12 @@ func (m *McpEnvironment) ValidateForCreate() error {
 	if m.Environment != McpEnvironmentKubernetes && m.Environment != McpEnvironmentDocker {
 		return fmt.Errorf("invalid environment type: %s", m.Environment)
 	}
+	if m.MaxInstances < 0 {
+		return fmt.Errorf("maxInstances must not be negative")
+	}
+	if m.MaxCriticalVulnerabilities < 0 {
+		return fmt.Errorf("maxCriticalVulnerabilities must not be negative")
+	}
 
 	return nil
 }
@@ -182,13 +246,25 @@ func (m *McpEnvironment) GetDockerConfig() (map[string]interface{}, error) {
 // Clone 创建环境的副本
 func (m *McpEnvironment) Clone() *McpEnvironment {
 	return &McpEnvironment{
-		ID:          0, // 新副本不包含ID
-		Name:        m.Name + "_copy",
-		Environment: m.Environment,
-		Config:      m.Config,
-		Namespace:   m.Namespace,
-		CreatedAt:   time.Time{},
-		UpdatedAt:   time.Time{},
-		IsDeleted:   false,
+		ID:                           0, // 新副本不包含ID
+		Name:                         m.Name + "_copy",
+		Environment:                  m.Environment,
+		Config:                       m.Config,
+		Namespace:                    m.Namespace,
+		HostingImage:                 m.HostingImage,
+		SupergatewayImage:            m.SupergatewayImage,
+		MaxInstances:                 m.MaxInstances,
+		MaxTotalMemory:               m.MaxTotalMemory,
+		MaxTotalCPU:                  m.MaxTotalCPU,
+		QuotaExcludeInactive:         m.QuotaExcludeInactive,
+		Defaults:                     m.Defaults,
+		BlockCriticalVulnerabilities: m.BlockCriticalVulnerabilities,
+		MaxCriticalVulnerabilities:   m.MaxCriticalVulnerabilities,
+		Protected:                    m.Protected,
+		CostPerCPUHour:               m.CostPerCPUHour,
+		CostPerGiBHour:               m.CostPerGiBHour,
+		CreatedAt:                    time.Time{},
+		UpdatedAt:                    time.Time{},
+		IsDeleted:                    false,
 	}
 }
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// McpIcon 上传的图标，文件名为内容的 SHA-256，相同内容只保存一份
+// 未被实例、模板或目录条目引用且超过保留时间的图标由清理任务删除
+type McpIcon struct {
+	ID          uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	Name        string    `gorm:"size:80;not null;uniqueIndex:idx_mcp_icon_name;comment:文件名 ({sha256}.{扩展名})" json:"name"`
+	ContentType string    `gorm:"size:50;not null;comment:MIME 类型" json:"contentType"`
+	Size        int64     `gorm:"not null;comment:文件大小 (字节)" json:"size"`
+	Width       int32     `gorm:"not null;comment:宽度 (像素)" json:"width"`
+	Height      int32     `gorm:"not null;comment:高度 (像素)" json:"height"`
+	CreatedAt   time.Time `gorm:"type:timestamp(3);not null;index:idx_mcp_icon_created_at;comment:创建时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (McpIcon) TableName() string {
+	return "mcp_icon"
+}
+
+// PrepareForCreate 准备创建记录（设置创建时间）
+func (m *McpIcon) PrepareForCreate() {
+	m.CreatedAt = time.Now()
+}
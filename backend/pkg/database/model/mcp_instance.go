@@ -3,6 +3,10 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -63,6 +67,8 @@ const (
 	ContainerStatusManualStop ContainerStatus = "manual-stop"
 	// 创建失败
 	ContainerStatusCreateFailed ContainerStatus = "create-failed"
+	// 排队等待创建，环境同时创建的容器数已满
+	ContainerStatusQueued ContainerStatus = "queued"
 )
 
 const DefaultMcpType = "sse"
@@ -83,7 +89,9 @@ type McpInstance struct {
 	ID                     uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
 	InstanceID             string          `gorm:"size:100;not null;comment:实例ID" json:"instanceID"`
 	InstanceName           string          `gorm:"size:200;not null;comment:实例名称" json:"instanceName"`
+	Slug                   *string         `gorm:"size:63;uniqueIndex:idx_mcp_instance_slug;comment:网关自定义访问路径，未设置时为 NULL" json:"slug"`
 	Notes                  string          `gorm:"type:text;comment:备注" json:"notes"`
+	Metadata               json.RawMessage `gorm:"type:json;comment:结构化元数据 (JSON对象)，如运维手册和值班联系人" json:"metadata"`
 	AccessType             AccessType      `gorm:"size:20;not null;comment:访问类型 (直连-direct/代理-proxy/托管-hosting)" json:"accessType"`
 	McpProtocol            McpProtocol     `gorm:"size:20;not null;comment:MCP 协议 (sse/streamableHttp/stdio)" json:"mcpProtocol"`
 	Status                 InstanceStatus  `gorm:"size:20;not null;default:active;comment:实例状态 (活跃-active/不活跃-inactive)" json:"status"`
@@ -99,6 +107,9 @@ type McpInstance struct {
 	Command                string          `gorm:"type:text;comment:启动命令" json:"command"`
 	EnvironmentVariables   json.RawMessage `gorm:"type:json;comment:环境变量 (JSON格式)" json:"environmentVariables"`
 	VolumeMounts           json.RawMessage `gorm:"type:json;comment:卷挂载配置列表 (JSON格式)" json:"volumeMounts"`
+	InitContainers         json.RawMessage `gorm:"type:json;comment:初始化容器列表 (JSON格式)" json:"initContainers"`
+	InitSharedPath         string          `gorm:"size:255;not null;default:'';comment:初始化容器共享卷挂载路径" json:"initSharedPath"`
+	Sidecars               json.RawMessage `gorm:"type:json;comment:边车容器列表 (JSON格式)" json:"sidecars"`
 	StartupTimeout         int64           `gorm:"type:bigint;default:0;comment:容器启动超时时间 (毫秒时间戳)" json:"startupTimeout"`
 	RunningTimeout         int64           `gorm:"type:bigint;default:0;comment:容器运行超时时间 (毫秒时间戳)" json:"runningTimeout"`
 	ContainerCreateOptions json.RawMessage `gorm:"type:json;comment:容器创建选项 (JSON格式)" json:"containerCreateOptions"`
@@ -107,20 +118,104 @@ type McpInstance struct {
 	ContainerServiceName   string          `gorm:"size:100;not null;comment:容器服务名称" json:"containerServiceName"`
 	ContainerIsReady       bool            `gorm:"not null;comment:容器服务名称" json:"containerIsReady"`
 	ContainerLastMessage   string          `gorm:"type:text;comment:容器上次状态信息" json:"containerLastMessage"`
+	ContainerRestartCount  int32           `gorm:"not null;default:0;comment:已记录的容器重启次数，用于检测新的崩溃重启" json:"containerRestartCount"`
+	Replicas               int32           `gorm:"default:1;comment:容器副本数 (缩容到0时为0)" json:"replicas"`
+	PreviousReplicas       int32           `gorm:"default:0;comment:缩容到0前的副本数，启动时恢复" json:"previousReplicas"`
 	SourceConfig           json.RawMessage `gorm:"type:json;comment:MCP 来源服务配置 (JSON格式)" json:"sourceConfig"`
 	TargetConfig           json.RawMessage `gorm:"type:json;comment:MCP 目标服务配置 (JSON格式)" json:"targetConfig"`
 	PublicProxyConfig      json.RawMessage `gorm:"type:json;comment:MCP 公网代理服务配置 (JSON格式)" json:"publicProxyConfig"`
 	ServicePath            string          `gorm:"size:100;not null;default:'';comment:MCP 服务路径" json:"servicePath"`
 	IconPath               string          `gorm:"size:100;not null;default:'';comment:MCP 图标路径" json:"iconPath"`
+	Labels                 json.RawMessage `gorm:"type:json;comment:实例标签 (JSON格式)" json:"labels"`
+	Locked                 bool            `gorm:"not null;default:false;comment:是否锁定，锁定后不能修改配置" json:"locked"`
+	LockReason             string          `gorm:"size:500;not null;default:'';comment:锁定原因" json:"lockReason"`
+	LockedBy               string          `gorm:"size:100;not null;default:'';comment:锁定用户" json:"lockedBy"`
+	LockedAt               *time.Time      `gorm:"type:timestamp(3);comment:锁定时间" json:"lockedAt"`
+	HealthMonitor          json.RawMessage `gorm:"type:json;comment:健康检查配置 (JSON格式)，仅直连和代理实例" json:"healthMonitor"`
+	HealthStatus           string          `gorm:"size:20;not null;default:'';comment:健康状态 (up/down)，未检查时为空" json:"healthStatus"`
+	HealthCheckedAt        *time.Time      `gorm:"type:timestamp(3);comment:最近一次健康检查时间" json:"healthCheckedAt"`
+	ImageScan              json.RawMessage `gorm:"type:json;comment:镜像漏洞扫描结果 (JSON格式)，仅托管实例" json:"imageScan"`
+	CodePackageVersion     int             `gorm:"not null;default:0;comment:容器最近一次创建时代码包的内容版本" json:"codePackageVersion"`
+	CodePackageChecksum    string          `gorm:"size:80;not null;default:'';comment:容器最近一次创建时代码包内容的 sha256" json:"codePackageChecksum"`
+	ImageDigest            string          `gorm:"size:100;not null;default:'';comment:容器最近一次创建时镜像标签解析到的摘要" json:"imageDigest"`
+	TemplateUpdatedAt      *time.Time      `gorm:"type:timestamp(3);comment:创建时模板的更新时间，作为模板版本" json:"templateUpdatedAt"`
+	CreatorID              uint            `gorm:"not null;default:0;index;comment:创建用户ID，0 表示系统创建或创建于配额功能之前" json:"creatorId"`
 	CreatedAt              time.Time       `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
 	UpdatedAt              time.Time       `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
 }
 
+// 令牌状态，由过期时间和轮换时间推算，不保存
+const (
+	TokenStateActive   = "active"   // 有效
+	TokenStateExpiring = "expiring" // 即将过期
+	TokenStateRotating = "rotating" // 已轮换，宽限期内仍然有效
+	TokenStateExpired  = "expired"  // 已过期
+)
+
+// 令牌权限范围，保存在令牌的 Usages 中，由网关校验
+const (
+	// TokenScopeDiscovery 只能初始化会话和列出工具、资源
+	TokenScopeDiscovery = "discovery"
+	// TokenScopeFull 可以调用所有方法
+	TokenScopeFull = "full"
+)
+
+// IsTokenScope 判断是否为支持的令牌权限范围
+func IsTokenScope(scope string) bool {
+	return scope == TokenScopeDiscovery || scope == TokenScopeFull
+}
+
 type McpToken struct {
 	Token     string   `json:"token"`
 	ExpireAt  int64    `json:"expireAt"`
 	PublishAt int64    `json:"publishAt"`
 	Usages    []string `json:"usages"`
+	// RotatedAt 令牌被轮换的时间 (毫秒时间戳)，轮换后 ExpireAt 为宽限期结束时间
+	RotatedAt int64 `json:"rotatedAt,omitempty"`
+	// NotifiedAt 发送即将过期通知的时间 (毫秒时间戳)，避免重复通知
+	NotifiedAt int64 `json:"notifiedAt,omitempty"`
+}
+
+// State 返回令牌在 now 时的状态，window 内过期的令牌视为即将过期
+func (t *McpToken) State(now time.Time, window time.Duration) string {
+	switch {
+	case t.ExpireAt > 0 && t.ExpireAt < now.UnixMilli():
+		return TokenStateExpired
+	case t.RotatedAt > 0:
+		return TokenStateRotating
+	case t.ExpireAt > 0 && t.ExpireAt <= now.Add(window).UnixMilli():
+		return TokenStateExpiring
+	default:
+		return TokenStateActive
+	}
+}
+
+// Scope 返回令牌的权限范围，只声明了 discovery 的令牌为 discovery，
+// 其余令牌（包括 Usages 为历史自由文本的令牌）为 full
+func (t *McpToken) Scope() string {
+	scope := TokenScopeFull
+	for _, usage := range t.Usages {
+		switch usage {
+		case TokenScopeFull:
+			return TokenScopeFull
+		case TokenScopeDiscovery:
+			scope = TokenScopeDiscovery
+		}
+	}
+	return scope
+}
+
+// DaysUntilExpiry 返回令牌距离过期的天数，不足一天按一天计算，已过期为 0，永不过期为 -1
+func (t *McpToken) DaysUntilExpiry(now time.Time) int32 {
+	if t.ExpireAt <= 0 {
+		return -1
+	}
+	remaining := t.ExpireAt - now.UnixMilli()
+	if remaining <= 0 {
+		return 0
+	}
+	day := (24 * time.Hour).Milliseconds()
+	return int32((remaining + day - 1) / day)
 }
 
 // McpConfig 表示单个 MCP 服务器配置
@@ -132,6 +227,143 @@ type McpConfig struct {
 	Headers        map[string]string `json:"headers,omitempty"`
 	Timeout        int               `json:"timeout,omitempty"`
 	SseReadTimeout int               `json:"sseReadTimeout,omitempty"`
+	// 网关请求体和响应缓冲大小上限（字节），覆盖网关全局配置，用于大负载的 MCP 服务
+	MaxRequestBodySize    int64 `json:"maxRequestBodySize,omitempty"`
+	MaxResponseBufferSize int64 `json:"maxResponseBufferSize,omitempty"`
+	// 网关向空闲 SSE 连接发送心跳注释，覆盖网关全局配置，间隔单位为秒
+	SseHeartbeat         *bool `json:"sseHeartbeat,omitempty"`
+	SseHeartbeatInterval int   `json:"sseHeartbeatInterval,omitempty"`
+	// 网关连接 HTTPS 上游时的 TLS 配置，未设置时使用网关全局配置
+	TLS *McpTLSConfig `json:"tls,omitempty"`
+	// 出站代理，代理模式下网关经此连接上游，托管模式下注入容器环境变量；未设置的字段使用网关全局配置
+	Proxy *McpProxyConfig `json:"proxy,omitempty"`
+	// 网关按 JSON-RPC 方法和工具名放行或拦截请求，未设置时不检查请求体
+	Policy *McpPolicyConfig `json:"policy,omitempty"`
+	// 网关在响应返回客户端前替换其中的敏感内容
+	Redaction *McpRedactionConfig `json:"redaction,omitempty"`
+	// 金丝雀路由，按请求头或权重把新会话转发到其他版本的上游，未命中时使用 URL
+	Routes []*McpRouteConfig `json:"routes,omitempty"`
+	// 浏览器跨域访问策略，设置后替换网关全局策略
+	CORS *McpCORSConfig `json:"cors,omitempty"`
+}
+
+// McpCORSConfig 网关跨域访问策略，未设置的方法、请求头使用网关默认值
+type McpCORSConfig struct {
+	// 允许的来源，"*" 允许所有来源，"https://*.example.com" 允许其子域名
+	AllowedOrigins   []string `json:"allowedOrigins,omitempty"`
+	AllowedMethods   []string `json:"allowedMethods,omitempty"`
+	AllowedHeaders   []string `json:"allowedHeaders,omitempty"`
+	ExposedHeaders   []string `json:"exposedHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	// 预检结果缓存时间（秒）
+	MaxAge int `json:"maxAge,omitempty"`
+}
+
+// ValidateCORSOrigin 校验跨域来源，"*" 或不带路径的 http(s) 来源，主机名可以 "*." 开头匹配子域名
+func ValidateCORSOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil || strings.Contains(u.Host, "*") {
+		return fmt.Errorf("origin must be \"*\" or an http(s) origin such as https://app.example.com, got %q", origin)
+	}
+	return nil
+}
+
+// ValidateCORSCredentials 校验携带凭据的跨域策略，允许所有来源时不能携带凭据，浏览器也会拒绝这种组合
+func ValidateCORSCredentials(origins []string, allowCredentials bool) error {
+	if allowCredentials && slices.Contains(origins, "*") {
+		return fmt.Errorf(`allowCredentials cannot be used with the "*" origin, list the allowed origins instead`)
+	}
+	return nil
+}
+
+// McpRouteConfig 网关金丝雀路由规则，目标需与主目标使用相同协议。
+// 命中 MatchHeader 的会话总是使用该路由，其余会话按 Weight 百分比分流
+type McpRouteConfig struct {
+	TargetURL string `json:"targetURL"`
+	// 分流的会话百分比，所有路由之和不超过 100
+	Weight int `json:"weight,omitempty"`
+	// 请求头匹配，"Name" 匹配存在该请求头，"Name: value" 匹配请求头的值
+	MatchHeader string `json:"matchHeader,omitempty"`
+}
+
+// McpRedactionConfig 网关响应脱敏配置，作用于 JSON 响应和 SSE 消息的 data 行，匹配内容替换为 [REDACTED]
+type McpRedactionConfig struct {
+	// 正则表达式，使用 Go RE2 语法
+	Patterns []string `json:"patterns,omitempty"`
+	// 按原文匹配的字符串
+	Literals []string `json:"literals,omitempty"`
+	// 单个响应或 SSE 消息的处理大小上限（字节），超出时不转发，未设置时使用网关默认值
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// 单个响应或 SSE 消息的处理耗时上限（毫秒），超出时不转发，未设置时使用网关默认值
+	TimeBudgetMs int `json:"timeBudgetMs,omitempty"`
+}
+
+// McpPolicyConfig 网关 JSON-RPC 访问策略，名称以 * 结尾时按前缀匹配。
+// 拒绝列表优先于允许列表，允许列表为空时不限制；工具规则只作用于 tools/call
+type McpPolicyConfig struct {
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	DenyMethods  []string `json:"denyMethods,omitempty"`
+	AllowTools   []string `json:"allowTools,omitempty"`
+	DenyTools    []string `json:"denyTools,omitempty"`
+	// 请求体无法解析或超过检查上限时放行，默认拒绝
+	FailOpen bool `json:"failOpen,omitempty"`
+	// 检查的请求体大小上限（字节），未设置时使用网关默认值
+	MaxInspectSize int64 `json:"maxInspectSize,omitempty"`
+}
+
+// McpProxyConfig 出站 HTTP 代理配置，与 HTTP_PROXY / HTTPS_PROXY / NO_PROXY 环境变量含义相同
+type McpProxyConfig struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// EnvVars 转换为容器环境变量，同时设置大小写两种形式以兼容不同运行时
+func (c *McpProxyConfig) EnvVars() map[string]string {
+	if c == nil {
+		return nil
+	}
+	envVars := make(map[string]string)
+	for _, kv := range [][2]string{{"HTTP_PROXY", c.HTTPProxy}, {"HTTPS_PROXY", c.HTTPSProxy}, {"NO_PROXY", c.NoProxy}} {
+		if kv[1] == "" {
+			continue
+		}
+		envVars[kv[0]] = kv[1]
+		envVars[strings.ToLower(kv[0])] = kv[1]
+	}
+	return envVars
+}
+
+// ValidateProxyURL 校验出站代理地址，支持 http、https 和 socks5 代理
+func ValidateProxyURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("proxy URL must use the http, https or socks5 scheme, got %q", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL must contain a host, got %q", raw)
+	}
+	return nil
+}
+
+// McpTLSConfig 网关连接上游 MCP 服务的 TLS 配置，证书和私钥均为 PEM 内容
+type McpTLSConfig struct {
+	// 信任的 CA 证书，追加到系统和网关全局 CA 之后
+	CACert string `json:"caCert,omitempty"`
+	// 跳过上游证书校验，仅用于测试，只有管理员可以设置
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// mTLS 客户端证书和私钥，需同时设置
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
 }
 
 // McpServersConfig 统一的 MCP 服务器配置结构
@@ -161,6 +393,32 @@ func (m *McpServersConfig) GetMcpConfigByName(name string) (*McpConfig, error) {
 	return nil, fmt.Errorf("mcp server config not found for name: %s", name)
 }
 
+// ServerNames 按名称排序返回所有 MCP 服务器名称
+func (m *McpServersConfig) ServerNames() []string {
+	if m == nil {
+		return nil
+	}
+	names := make([]string, 0, len(m.McpServers))
+	for name := range m.McpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InsecureTLS 是否有服务跳过上游证书校验
+func (m *McpServersConfig) InsecureTLS() bool {
+	if m == nil {
+		return false
+	}
+	for _, cfg := range m.McpServers {
+		if cfg != nil && cfg.TLS != nil && cfg.TLS.InsecureSkipVerify {
+			return true
+		}
+	}
+	return false
+}
+
 // 为了向后兼容，保留原有类型别名
 type SourceConfig = McpServersConfig
 type TargetConfig = McpServersConfig
@@ -238,3 +496,53 @@ func (m *McpInstance) GetTargetConfig() (string, *McpServersConfig, *McpConfig,
 func (m *McpInstance) GetPublicProxyConfig() (string, *McpServersConfig, *McpConfig, error) {
 	return parseMcpServersConfig(m.PublicProxyConfig)
 }
+
+// GetLabels 获取实例标签，未设置或格式错误时返回 nil
+func (m *McpInstance) GetLabels() map[string]string {
+	if len(m.Labels) == 0 {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(m.Labels, &labels); err != nil {
+		return nil
+	}
+	return labels
+}
+
+// GetSlug 获取网关自定义访问路径，未设置时返回空字符串
+func (m *McpInstance) GetSlug() string {
+	if m.Slug == nil {
+		return ""
+	}
+	return *m.Slug
+}
+
+// DesiredReplicas 获取实例启动时应运行的副本数
+// 缩容到0后返回缩容前记录的副本数，未设置时默认为1
+func (m *McpInstance) DesiredReplicas() int32 {
+	if m.Replicas > 0 {
+		return m.Replicas
+	}
+	if m.PreviousReplicas > 0 {
+		return m.PreviousReplicas
+	}
+	return 1
+}
+
+// CodeOutdated 托管实例的容器是否在代码包最近一次修改之前创建，运行的仍是旧代码
+func (m *McpInstance) CodeOutdated(packageVersion int) bool {
+	return m.AccessType == AccessTypeHosting && m.PackageID != "" && m.CodePackageVersion < packageVersion
+}
+
+// IsStopped 实例是否已禁用或容器已停止
+func (m *McpInstance) IsStopped() bool {
+	if m.Status == InstanceStatusInactive {
+		return true
+	}
+	switch m.ContainerStatus {
+	case ContainerStatusInitTimeoutStop, ContainerStatusRunTimeoutStop, ContainerStatusExceptionForceStop,
+		ContainerStatusManualStop, ContainerStatusCreateFailed:
+		return true
+	}
+	return false
+}
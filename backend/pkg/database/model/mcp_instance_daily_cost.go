@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CostDayLayout 每日成本记录的日期格式
+const CostDayLayout = "2006-01-02"
+
+// McpInstanceDailyCost 托管实例每日成本，由每晚的汇总任务写入。
+// 记录保存当天使用的资源量和费率，环境费率修改后不重新计算历史成本；实例删除后记录保留
+type McpInstanceDailyCost struct {
+	ID            uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	InstanceID    string          `gorm:"size:100;not null;uniqueIndex:uniq_mcp_instance_daily_cost,priority:1;comment:实例ID" json:"instanceId"`
+	InstanceName  string          `gorm:"size:200;not null;default:'';comment:实例名称" json:"instanceName"`
+	EnvironmentID uint            `gorm:"not null;default:0;comment:环境ID" json:"environmentId"`
+	Day           string          `gorm:"size:10;not null;uniqueIndex:uniq_mcp_instance_daily_cost,priority:2;index:idx_mcp_instance_daily_cost_day;comment:日期 (YYYY-MM-DD)" json:"day"`
+	Labels        json.RawMessage `gorm:"type:json;comment:汇总时的实例标签 (JSON格式)" json:"labels"`
+	RunningHours  float64         `gorm:"not null;default:0;comment:运行小时数" json:"runningHours"`
+	ReplicaHours  float64         `gorm:"not null;default:0;comment:运行小时数乘以副本数" json:"replicaHours"`
+	CPUCores      float64         `gorm:"column:cpu_cores;not null;default:0;comment:每个副本请求的 CPU 核数" json:"cpuCores"`
+	MemoryGiB     float64         `gorm:"column:memory_gib;not null;default:0;comment:每个副本请求的内存 GiB" json:"memoryGiB"`
+	CPUHourRate   float64         `gorm:"column:cpu_hour_rate;not null;default:0;comment:当天的每 CPU 核每小时费用" json:"cpuHourRate"`
+	GiBHourRate   float64         `gorm:"column:gib_hour_rate;not null;default:0;comment:当天的每 GiB 内存每小时费用" json:"gibHourRate"`
+	Cost          float64         `gorm:"not null;default:0;comment:当天成本" json:"cost"`
+	RunningAtEnd  bool            `gorm:"not null;default:false;comment:当天结束时是否运行，作为次日的初始状态" json:"runningAtEnd"`
+	ReplicasAtEnd int32           `gorm:"not null;default:0;comment:当天结束时的副本数" json:"replicasAtEnd"`
+	CreatedAt     time.Time       `gorm:"type:timestamp(3);not null;comment:汇总时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (McpInstanceDailyCost) TableName() string {
+	return "mcp_instance_daily_cost"
+}
+
+// GetLabels 获取汇总时的实例标签，未设置或格式错误时返回 nil
+func (c *McpInstanceDailyCost) GetLabels() map[string]string {
+	if len(c.Labels) == 0 {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(c.Labels, &labels); err != nil {
+		return nil
+	}
+	return labels
+}
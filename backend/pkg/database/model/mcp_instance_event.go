@@ -0,0 +1,51 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// 容器事件级别，与 Kubernetes Event Type 保持一致
+const (
+	InstanceEventTypeWarning = "Warning"
+	InstanceEventTypeNormal  = "Normal"
+)
+
+// McpInstanceEvent 实例容器事件历史
+// Kubernetes 事件默认一小时后过期，采集到的事件持久化到该表便于事后排查
+type McpInstanceEvent struct {
+	ID             uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	InstanceID     string    `gorm:"size:100;not null;uniqueIndex:idx_mcp_instance_event_dedup,priority:1;comment:实例ID" json:"instanceId"`
+	DedupKey       string    `gorm:"size:64;not null;uniqueIndex:idx_mcp_instance_event_dedup,priority:2;comment:去重键(reason+message+timestamp 的哈希)" json:"-"`
+	Type           string    `gorm:"size:20;not null;comment:事件级别 (Warning/Normal)" json:"type"`
+	Reason         string    `gorm:"size:255;comment:事件原因" json:"reason"`
+	Message        string    `gorm:"type:text;comment:事件消息" json:"message"`
+	FirstTimestamp int64     `gorm:"not null;comment:事件发生时间（秒级时间戳）" json:"firstTimestamp"`
+	LastTimestamp  int64     `gorm:"not null;comment:最后一次采集到该事件的时间（秒级时间戳）" json:"lastTimestamp"`
+	Count          int32     `gorm:"not null;default:1;comment:采集到该事件的次数" json:"count"`
+	CreatedAt      time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpInstanceEvent) TableName() string {
+	return "mcp_instance_events"
+}
+
+// InstanceEventDedupKey 计算事件去重键，同一实例下 reason+message+timestamp 相同的事件视为同一事件
+func InstanceEventDedupKey(reason, message string, timestamp int64) string {
+	sum := sha256.Sum256([]byte(reason + "\x00" + message + "\x00" + strconv.FormatInt(timestamp, 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// PrepareForCreate 准备创建记录（设置去重键、采集时间和创建更新时间）
+func (e *McpInstanceEvent) PrepareForCreate() {
+	now := time.Now()
+	e.DedupKey = InstanceEventDedupKey(e.Reason, e.Message, e.FirstTimestamp)
+	e.LastTimestamp = now.Unix()
+	e.Count = 1
+	e.CreatedAt = now
+	e.UpdatedAt = now
+}
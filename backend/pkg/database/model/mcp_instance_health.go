@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 实例健康状态，未开启健康检查或尚未检查时为空
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// 健康检查方式
+const (
+	HealthCheckModePort       = "port"       // 探测服务地址的端口连通性
+	HealthCheckModeHTTP       = "http"       // 请求服务地址（或指定路径），校验响应状态码
+	HealthCheckModeInitialize = "initialize" // 完成一次 MCP initialize 握手
+)
+
+// DefaultHealthCheckInterval 未设置检查间隔时使用的间隔（秒）
+const DefaultHealthCheckInterval = 60
+
+// HealthMonitorConfig 直连和代理实例的定时健康检查配置
+type HealthMonitorConfig struct {
+	Enabled bool `json:"enabled"`
+	// 检查间隔（秒）
+	Interval int32 `json:"interval"`
+	// 检查方式 (port/http/initialize)，为空时为 port
+	Mode string `json:"mode,omitempty"`
+	// http 方式请求的路径，为空时请求服务地址本身
+	Path string `json:"path,omitempty"`
+	// http 方式期望的响应状态码，0 表示任意 2xx
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+}
+
+// IntervalDuration 检查间隔，未设置时使用 DefaultHealthCheckInterval
+func (c *HealthMonitorConfig) IntervalDuration() time.Duration {
+	if c.Interval <= 0 {
+		return DefaultHealthCheckInterval * time.Second
+	}
+	return time.Duration(c.Interval) * time.Second
+}
+
+// GetHealthMonitor 获取实例健康检查配置，未配置时返回 nil
+func (m *McpInstance) GetHealthMonitor() *HealthMonitorConfig {
+	if len(m.HealthMonitor) == 0 {
+		return nil
+	}
+	var cfg HealthMonitorConfig
+	if err := json.Unmarshal(m.HealthMonitor, &cfg); err != nil {
+		return nil
+	}
+	return &cfg
+}
+
+// McpInstanceHealthCheck 实例健康检查结果，每个实例只保留最近的若干条
+type McpInstanceHealthCheck struct {
+	ID         uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	InstanceID string    `gorm:"size:100;not null;index:idx_mcp_instance_health_check_instance,priority:1;comment:实例ID" json:"instanceId"`
+	Success    bool      `gorm:"not null;comment:检查是否成功" json:"success"`
+	StatusCode int32     `gorm:"not null;default:0;comment:HTTP 响应状态码，端口探测为 0" json:"statusCode"`
+	LatencyMs  int64     `gorm:"not null;default:0;comment:检查耗时（毫秒）" json:"latencyMs"`
+	Error      string    `gorm:"size:500;not null;default:'';comment:失败原因" json:"error"`
+	CheckedAt  time.Time `gorm:"type:timestamp(3);not null;index:idx_mcp_instance_health_check_instance,priority:2;comment:检查时间" json:"checkedAt"`
+}
+
+// TableName 指定表名
+func (McpInstanceHealthCheck) TableName() string {
+	return "mcp_instance_health_checks"
+}
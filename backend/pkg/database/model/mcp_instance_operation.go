@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// InstanceOperationActorSystem 平台自动触发的操作（容器监控、状态查询等）的操作人
+const InstanceOperationActorSystem = "system"
+
+// 实例生命周期操作类型
+const (
+	InstanceOperationCreate         = "create"          // 创建实例
+	InstanceOperationEdit           = "edit"            // 编辑实例
+	InstanceOperationRestart        = "restart"         // 手动重启
+	InstanceOperationDisable        = "disable"         // 禁用实例
+	InstanceOperationEnable         = "enable"          // 启用实例
+	InstanceOperationDelete         = "delete"          // 删除实例
+	InstanceOperationScale          = "scale"           // 扩缩容
+	InstanceOperationDrain          = "drain"           // 排空网关连接
+	InstanceOperationReady          = "ready"           // 容器变为就绪
+	InstanceOperationUnready        = "unready"         // 容器运行中但未就绪
+	InstanceOperationCrash          = "crash"           // 容器崩溃并被 Kubernetes 自动重启
+	InstanceOperationRecreate       = "recreate"        // 容器不存在，监控自动重建
+	InstanceOperationStartupTimeout = "startup-timeout" // 启动超时，容器已清理
+	InstanceOperationRunningTimeout = "running-timeout" // 运行超时
+	InstanceOperationHealthDown     = "health-down"     // 健康检查由正常变为异常
+	InstanceOperationHealthUp       = "health-up"       // 健康检查由异常恢复正常
+	InstanceOperationTokenRotate    = "token-rotate"    // 轮换令牌
+	InstanceOperationTokenCreate    = "token-create"    // 为客户端连接配置生成令牌
+	InstanceOperationTokenExpiring  = "token-expiring"  // 令牌即将过期
+	InstanceOperationCodeOutdated   = "code-outdated"   // 代码包已修改，实例仍运行旧代码
+	InstanceOperationCreateFailed   = "create-failed"   // 排队的容器创建失败
+	InstanceOperationImageChanged   = "image-changed"   // 镜像标签指向新的摘要，容器改用新镜像
+)
+
+// McpInstanceOperation 实例生命周期操作记录，与容器事件合并为实例时间线
+type McpInstanceOperation struct {
+	ID         uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	InstanceID string    `gorm:"size:100;not null;index:idx_mcp_instance_operation_instance,priority:1;comment:实例ID" json:"instanceId"`
+	Operation  string    `gorm:"size:50;not null;comment:操作类型" json:"operation"`
+	Actor      string    `gorm:"size:100;not null;comment:操作人，平台自动操作为 system" json:"actor"`
+	Detail     string    `gorm:"type:text;comment:操作详情" json:"detail"`
+	CreatedAt  time.Time `gorm:"type:timestamp(3);not null;index:idx_mcp_instance_operation_instance,priority:2;comment:操作时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (McpInstanceOperation) TableName() string {
+	return "mcp_instance_operations"
+}
+
+// PrepareForCreate 准备创建记录（设置默认操作人和创建时间）
+func (o *McpInstanceOperation) PrepareForCreate() {
+	if o.Actor == "" {
+		o.Actor = InstanceOperationActorSystem
+	}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now()
+	}
+}
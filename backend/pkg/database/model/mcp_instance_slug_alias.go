@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// McpInstanceSlugAlias 实例修改前的网关自定义访问路径，过期前网关将旧路径永久重定向到实例的当前路径。
+// 旧路径被其他实例使用或实例删除时记录一并删除
+type McpInstanceSlugAlias struct {
+	ID         uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	Slug       string    `gorm:"size:63;not null;uniqueIndex:uniq_mcp_instance_slug_alias_slug;comment:旧的自定义访问路径" json:"slug"`
+	InstanceID string    `gorm:"size:100;not null;index:idx_mcp_instance_slug_alias_instance_id;comment:实例ID" json:"instanceId"`
+	ExpiresAt  time.Time `gorm:"type:timestamp(3);not null;comment:重定向截止时间" json:"expiresAt"`
+	CreatedAt  time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (McpInstanceSlugAlias) TableName() string {
+	return "mcp_instance_slug_alias"
+}
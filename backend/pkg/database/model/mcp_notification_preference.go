@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+// 通知发送方式
+const (
+	NotificationModeImmediate = "immediate" // 事件发生时立即发送
+	NotificationModeDigest    = "digest"    // 按汇总间隔合并发送
+)
+
+// McpNotificationPreference 用户的实例事件通知偏好，只通知用户创建的实例的事件
+type McpNotificationPreference struct {
+	UserID  uint `gorm:"primarykey;comment:用户ID" json:"userId"`
+	Enabled bool `gorm:"not null;default:false;index:idx_mcp_notification_preference_mode,priority:1;comment:是否开启邮件通知" json:"enabled"`
+	// Email 接收地址，为空时使用用户资料中的邮箱
+	Email string `gorm:"size:180;not null;default:'';comment:接收地址，为空时使用用户邮箱" json:"email"`
+	// Events 接收的实例操作类型，如 crash、health-down
+	Events       json.RawMessage `gorm:"type:json;comment:接收的事件 (JSON数组)" json:"events"`
+	Mode         string          `gorm:"size:20;not null;default:'immediate';index:idx_mcp_notification_preference_mode,priority:2;comment:发送方式 (immediate/digest)" json:"mode"`
+	LastDigestAt *time.Time      `gorm:"type:timestamp(3);comment:最近一次汇总的截止时间" json:"lastDigestAt"`
+	CreatedAt    time.Time       `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt    time.Time       `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpNotificationPreference) TableName() string {
+	return "mcp_notification_preference"
+}
+
+// PrepareForCreate 准备创建记录（设置创建和更新时间）
+func (m *McpNotificationPreference) PrepareForCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// ValidateForCreate 验证通知偏好的必要字段
+func (m *McpNotificationPreference) ValidateForCreate() error {
+	if m.UserID == 0 {
+		return fmt.Errorf("user id is required")
+	}
+	if m.Mode != NotificationModeImmediate && m.Mode != NotificationModeDigest {
+		return fmt.Errorf("mode must be %s or %s", NotificationModeImmediate, NotificationModeDigest)
+	}
+	if m.Email != "" {
+		if _, err := mail.ParseAddress(m.Email); err != nil {
+			return fmt.Errorf("invalid email: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetEvents 解析接收的事件
+func (m *McpNotificationPreference) GetEvents() []string {
+	var events []string
+	if len(m.Events) > 0 {
+		_ = json.Unmarshal(m.Events, &events)
+	}
+	return events
+}
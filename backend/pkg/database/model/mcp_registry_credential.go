@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// McpRegistryCredential 私有镜像仓库凭证
+// 密码/令牌加密后存储，托管实例镜像地址匹配仓库地址时自动同步为镜像拉取密钥
+type McpRegistryCredential struct {
+	ID           uint      `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	Name         string    `gorm:"size:100;not null;uniqueIndex:idx_mcp_registry_credential_name;comment:凭证名称" json:"name"`
+	RegistryHost string    `gorm:"size:255;not null;index:idx_mcp_registry_credential_host;comment:镜像仓库地址" json:"registryHost"`
+	Username     string    `gorm:"size:255;not null;comment:用户名" json:"username"`
+	Password     string    `gorm:"type:text;not null;comment:加密后的密码或令牌" json:"-"`
+	CreatorID    string    `gorm:"size:100;comment:创建人ID" json:"creatorID"`
+	CreatedAt    time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpRegistryCredential) TableName() string {
+	return "mcp_registry_credentials"
+}
+
+// PrepareForCreate 准备创建记录（设置创建和更新时间）
+func (m *McpRegistryCredential) PrepareForCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// PrepareForUpdate 准备更新记录（设置更新时间）
+func (m *McpRegistryCredential) PrepareForUpdate() {
+	m.UpdatedAt = time.Now()
+}
+
+// ValidateForCreate 验证创建凭证的必要字段
+func (m *McpRegistryCredential) ValidateForCreate() error {
+	if m.Name == "" {
+		return fmt.Errorf("credential name is required")
+	}
+	if m.RegistryHost == "" {
+		return fmt.Errorf("registry host is required")
+	}
+	if m.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if m.Password == "" {
+		return fmt.Errorf("password is required")
+	}
+	return nil
+}
+
+// PullSecretName 同步到 Kubernetes 命名空间中的镜像拉取密钥名称
+func (m *McpRegistryCredential) PullSecretName() string {
+	return fmt.Sprintf("mcpbox-registry-%d", m.ID)
+}
@@ -24,8 +24,10 @@ type McpTemplate struct {
 	McpServerID          string          `gorm:"size:100;comment:MCP 服务器ID" json:"mcpServerID"`
 	Tokens               json.RawMessage `gorm:"type:json;comment:MCP 实例令牌 (JSON格式)" json:"tokens"`
 	Notes                string          `gorm:"type:text;comment:备注" json:"notes"`
+	Metadata             json.RawMessage `gorm:"type:json;comment:结构化元数据 (JSON对象)，如运维手册和值班联系人" json:"metadata"`
 	ServicePath          string          `gorm:"size:100;not null;default:'';comment:MCP 服务路径" json:"servicePath"`
 	IconPath             string          `gorm:"size:100;not null;default:'';comment:MCP 图标路径" json:"iconPath"`
+	SourceInstanceID     string          `gorm:"size:100;not null;default:'';comment:来源实例ID，由实例另存为模板时记录" json:"sourceInstanceID"`
 	CreatedAt            time.Time       `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
 	UpdatedAt            time.Time       `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
 }
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// McpUserQuota 用户配额，由管理员设置，各上限为 0 表示不限制。
+// 没有配额记录的用户不受限制
+type McpUserQuota struct {
+	UserID              uint      `gorm:"primarykey;comment:用户ID" json:"userId"`
+	MaxInstances        int       `gorm:"not null;default:0;comment:实例数上限，0 表示不限制" json:"maxInstances"`
+	MaxHostingInstances int       `gorm:"not null;default:0;comment:托管实例数上限，0 表示不限制" json:"maxHostingInstances"`
+	MaxPackageBytes     int64     `gorm:"not null;default:0;comment:代码包存储上限（字节），0 表示不限制" json:"maxPackageBytes"`
+	UpdatedBy           string    `gorm:"size:100;not null;default:'';comment:最近一次修改配额的用户" json:"updatedBy"`
+	CreatedAt           time.Time `gorm:"type:timestamp(3);not null;comment:创建时间" json:"createdAt"`
+	UpdatedAt           time.Time `gorm:"type:timestamp(3);not null;comment:更新时间" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (McpUserQuota) TableName() string {
+	return "mcp_user_quota"
+}
+
+// PrepareForCreate 准备创建记录（设置创建和更新时间）
+func (m *McpUserQuota) PrepareForCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// ValidateForCreate 验证配额的必要字段
+func (m *McpUserQuota) ValidateForCreate() error {
+	if m.UserID == 0 {
+		return fmt.Errorf("user id is required")
+	}
+	if m.MaxInstances < 0 || m.MaxHostingInstances < 0 || m.MaxPackageBytes < 0 {
+		return fmt.Errorf("quota limits cannot be negative")
+	}
+	return nil
+}
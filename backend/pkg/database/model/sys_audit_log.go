@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计日志操作类型
+const (
+	AuditActionInstanceBulk      = "instance.bulk"       // 批量操作实例
+	AuditActionCodePackageDeploy = "code.package.deploy" // 重新部署使用代码包的实例
+	AuditActionInstanceEdit      = "instance.edit"       // 编辑实例
+	AuditActionTemplateEdit      = "template.edit"       // 编辑模板
+	// AuditActionConnectionToken 令牌被写入客户端连接配置，配置中的令牌为明文
+	AuditActionConnectionToken = "instance.connection.token"
+	// 受保护环境的变更请求，详情中包含申请人和审批人
+	AuditActionChangeRequestCreate  = "change_request.create"
+	AuditActionChangeRequestApprove = "change_request.approve"
+	AuditActionChangeRequestReject  = "change_request.reject"
+	// AuditActionUserImpersonate 管理员模拟用户执行的请求，操作人为管理员，详情中包含被模拟的用户
+	AuditActionUserImpersonate = "user.impersonate"
+)
+
+// 审计日志资源类型
+const (
+	AuditResourceInstance    = "instance"
+	AuditResourceCodePackage = "code_package"
+	AuditResourceTemplate    = "template"
+	AuditResourceUser        = "user"
+)
+
+// SysAuditLog 审计日志，记录一次用户请求涉及的操作，批量操作只记录一条，详情中包含完整的资源ID列表
+type SysAuditLog struct {
+	ID           uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	Actor        string          `gorm:"size:100;not null;index:idx_sys_audit_log_actor;comment:操作人，平台自动操作为 system" json:"actor"`
+	Action       string          `gorm:"size:100;not null;index:idx_sys_audit_log_action;comment:操作类型" json:"action"`
+	ResourceType string          `gorm:"size:50;not null;comment:资源类型" json:"resourceType"`
+	ResourceID   string          `gorm:"size:100;comment:资源ID，批量操作为空" json:"resourceId"`
+	Detail       json.RawMessage `gorm:"type:json;comment:操作详情" json:"detail"`
+	RequestID    string          `gorm:"size:100;comment:请求ID" json:"requestId"`
+	CreatedAt    time.Time       `gorm:"type:timestamp(3);not null;index:idx_sys_audit_log_created_at;comment:操作时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (SysAuditLog) TableName() string {
+	return "sys_audit_log"
+}
+
+// PrepareForCreate 准备创建记录（设置默认操作人和创建时间）
+func (l *SysAuditLog) PrepareForCreate() {
+	if l.Actor == "" {
+		l.Actor = InstanceOperationActorSystem
+	}
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+}
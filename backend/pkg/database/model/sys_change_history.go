@@ -0,0 +1,23 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SysChangeHistory 实例和模板修改的字段级变更记录，关联同一次修改的审计日志
+type SysChangeHistory struct {
+	ID           uint            `gorm:"primarykey;autoIncrement;comment:主键ID" json:"ID"`
+	AuditLogID   uint            `gorm:"not null;default:0;comment:审计日志ID，审计日志写入失败时为 0" json:"auditLogId"`
+	ResourceType string          `gorm:"size:50;not null;index:idx_sys_change_history_resource,priority:1;comment:资源类型" json:"resourceType"`
+	ResourceID   string          `gorm:"size:100;not null;index:idx_sys_change_history_resource,priority:2;comment:资源ID" json:"resourceId"`
+	Actor        string          `gorm:"size:100;not null;comment:操作人" json:"actor"`
+	Changes      json.RawMessage `gorm:"type:json;comment:修改的字段列表，敏感字段只记录字段名" json:"changes"`
+	RequestID    string          `gorm:"size:100;comment:请求ID" json:"requestId"`
+	CreatedAt    time.Time       `gorm:"type:timestamp(3);not null;index:idx_sys_change_history_resource,priority:3;comment:修改时间" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (SysChangeHistory) TableName() string {
+	return "sys_change_history"
+}
@@ -21,6 +21,14 @@ const (
 	DataScopeSelf DataScope = "self"
 )
 
+// RoleViewer 只读角色，可以查看实例、状态和日志，不能修改任何资源，响应中的敏感字段被脱敏
+const RoleViewer = "viewer"
+
+// ViewerPermissions 只读角色的权限集合
+var ViewerPermissions = []string{
+	"instance:view", "instance:log", "template:view", "environment:view", "dashboard:view",
+}
+
 // SysRole 角色数据库模型
 type SysRole struct {
 	RoleID      uint       `gorm:"primarykey;autoIncrement;column:role_id;comment:ID" json:"roleId"`
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -21,6 +22,9 @@ var (
 	wg     sync.WaitGroup
 )
 
+// mysqlErrDuplicateEntry MySQL 唯一键冲突错误码 ER_DUP_ENTRY
+const mysqlErrDuplicateEntry = 1062
+
 // Config MySQL配置
 type Config struct {
 	Host                string        `validate:"required"`
@@ -34,6 +38,8 @@ type Config struct {
 	HealthCheckInterval time.Duration `validate:"required"`
 	MaxRetries          int           `validate:"min=0"`
 	RetryInterval       time.Duration `validate:"required"`
+	// AutoMigrate 兼容模式：连接时执行各仓库的 AutoMigrate 和索引创建，默认由版本化迁移管理表结构
+	AutoMigrate bool
 }
 
 // InitHook 初始化钩子函数类型
@@ -75,6 +81,29 @@ func RegisterInit(initHook InitHook) {
 	hookManager.Register(initHook)
 }
 
+// tableInit 仓库的旧版建表逻辑
+type tableInit struct {
+	table string
+	fn    func() error
+}
+
+var tableInits []tableInit
+
+// RegisterTableInit 注册仓库的建表逻辑 (AutoMigrate 和索引)，仅在兼容模式或接管旧数据库时执行
+func RegisterTableInit(table string, fn func() error) {
+	tableInits = append(tableInits, tableInit{table: table, fn: fn})
+}
+
+// InitTables 执行所有仓库的建表逻辑，需在数据库连接初始化后调用
+func InitTables() error {
+	for _, t := range tableInits {
+		if err := t.fn(); err != nil {
+			return fmt.Errorf("failed to initialize %s table: %w", t.table, err)
+		}
+	}
+	return nil
+}
+
 // InitDB 初始化数据库连接
 func InitDB(config *Config) error {
 	if config == nil {
@@ -137,6 +166,9 @@ func initConnection(config *Config) error {
 	// 调用所有初始化钩子
 	hookManager.CallHooks(db)
 
+	if config.AutoMigrate {
+		return InitTables()
+	}
 	return nil
 }
 
@@ -244,3 +276,12 @@ func Close() error {
 	}
 	return sqlDB.Close()
 }
+
+// IsDuplicateKeyError 是否为违反唯一索引的错误，并发写入相同唯一键时由数据库返回
+func IsDuplicateKeyError(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
@@ -0,0 +1,156 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpCatalogRepo *McpCatalogRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpCatalogRepository()
+	})
+	RegisterTableInit("mcp_catalog", func() error {
+		return McpCatalogRepo.InitTable()
+	})
+}
+
+// McpCatalogRepository 封装 mcp_catalog 表的操作
+type McpCatalogRepository struct{}
+
+// NewMcpCatalogRepository 创建 McpCatalogRepository 实例
+func NewMcpCatalogRepository() *McpCatalogRepository {
+	McpCatalogRepo = &McpCatalogRepository{}
+	return McpCatalogRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpCatalogRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpCatalogEntry{})
+}
+
+// Upsert 按 catalog_id 创建或更新目录条目，创建时间保持不变
+func (r *McpCatalogRepository) Upsert(ctx context.Context, entry *model.McpCatalogEntry) error {
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "catalog_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "description", "category", "icon_url", "repository_url", "version",
+			"access_type", "mcp_protocol", "img_address", "command", "port", "service_path",
+			"mcp_servers", "env_schema", "source", "updated_at",
+		}),
+	}).Create(entry).Error
+}
+
+// FindByCatalogID 根据目录条目ID查找
+func (r *McpCatalogRepository) FindByCatalogID(ctx context.Context, catalogID string) (*model.McpCatalogEntry, error) {
+	var entry model.McpCatalogEntry
+	if err := r.getDB().WithContext(ctx).Where("catalog_id = ?", catalogID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// 目录条目排序方式，为空时按管理员设置的展示顺序排序
+const (
+	CatalogSortPopular = "popular"
+	CatalogSortRating  = "rating"
+	CatalogSortName    = "name"
+)
+
+// FindWithPagination 分页查询目录条目，keyword 匹配名称、描述和条目ID，category 为空时查询全部分类，
+// verifiedOnly 只查询已认证的条目
+func (r *McpCatalogRepository) FindWithPagination(ctx context.Context, keyword, category string, verifiedOnly bool, sortBy string, page, pageSize int32) ([]*model.McpCatalogEntry, int64, error) {
+	var entries []*model.McpCatalogEntry
+	var total int64
+
+	query := r.getDB().WithContext(ctx)
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("name LIKE ? OR description LIKE ? OR catalog_id LIKE ?", like, like, like)
+	}
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if verifiedOnly {
+		query = query.Where("verified = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch sortBy {
+	case CatalogSortPopular:
+		query = query.Order("install_count DESC")
+	case CatalogSortRating:
+		query = query.Order("rating_avg DESC, rating_count DESC")
+	case CatalogSortName:
+	default:
+		query = query.Order("display_order ASC, verified DESC")
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("name ASC, id ASC").Offset(int(offset)).Limit(int(pageSize)).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// IncrementInstallCount 安装次数加一
+func (r *McpCatalogRepository) IncrementInstallCount(ctx context.Context, catalogID string) error {
+	return r.getDB().WithContext(ctx).
+		Where("catalog_id = ?", catalogID).
+		UpdateColumn("install_count", gorm.Expr("install_count + 1")).Error
+}
+
+// UpdateCuration 更新条目的认证状态和展示顺序
+func (r *McpCatalogRepository) UpdateCuration(ctx context.Context, catalogID string, verified bool, displayOrder int32) error {
+	result := r.getDB().WithContext(ctx).
+		Where("catalog_id = ?", catalogID).
+		Updates(map[string]interface{}{
+			"verified":      verified,
+			"display_order": displayOrder,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CategoryCount 分类及其条目数
+type CategoryCount struct {
+	Category string
+	Count    int64
+}
+
+// Categories 查询所有分类及其条目数，按分类名称排序
+func (r *McpCatalogRepository) Categories(ctx context.Context) ([]CategoryCount, error) {
+	var categories []CategoryCount
+	err := r.getDB().WithContext(ctx).
+		Select("category, COUNT(*) AS count").
+		Where("category <> ''").
+		Group("category").
+		Order("category ASC").
+		Scan(&categories).Error
+	return categories, err
+}
+
+// InitTable 初始化表结构
+func (r *McpCatalogRepository) InitTable() error {
+	mod := &model.McpCatalogEntry{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpCatalogRatingRepo *McpCatalogRatingRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpCatalogRatingRepository()
+	})
+	RegisterTableInit("mcp_catalog_rating", func() error {
+		return McpCatalogRatingRepo.InitTable()
+	})
+}
+
+// McpCatalogRatingRepository 封装 mcp_catalog_rating 表的操作
+type McpCatalogRatingRepository struct{}
+
+// NewMcpCatalogRatingRepository 创建 McpCatalogRatingRepository 实例
+func NewMcpCatalogRatingRepository() *McpCatalogRatingRepository {
+	McpCatalogRatingRepo = &McpCatalogRatingRepository{}
+	return McpCatalogRatingRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpCatalogRatingRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpCatalogRating{})
+}
+
+// Upsert 创建或更新用户对条目的评分，同一用户重复评分时覆盖原评分，
+// 并在同一事务中刷新条目的评分人数和平均评分
+func (r *McpCatalogRatingRepository) Upsert(ctx context.Context, rating *model.McpCatalogRating) error {
+	if err := rating.ValidateForCreate(); err != nil {
+		return err
+	}
+	rating.PrepareForCreate()
+	return GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "catalog_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rating", "comment", "updated_at"}),
+		}).Create(rating).Error; err != nil {
+			return err
+		}
+
+		var summary struct {
+			Count int64
+			Avg   float64
+		}
+		if err := tx.Model(&model.McpCatalogRating{}).
+			Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS avg").
+			Where("catalog_id = ?", rating.CatalogID).
+			Scan(&summary).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.McpCatalogEntry{}).
+			Where("catalog_id = ?", rating.CatalogID).
+			UpdateColumns(map[string]interface{}{
+				"rating_count": summary.Count,
+				"rating_avg":   summary.Avg,
+			}).Error
+	})
+}
+
+// FindWithPagination 分页查询条目的评分，按更新时间倒序
+func (r *McpCatalogRatingRepository) FindWithPagination(ctx context.Context, catalogID string, page, pageSize int32) ([]*model.McpCatalogRating, int64, error) {
+	var ratings []*model.McpCatalogRating
+	var total int64
+
+	query := r.getDB().WithContext(ctx).Where("catalog_id = ?", catalogID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("updated_at DESC, id DESC").Offset(int(offset)).Limit(int(pageSize)).Find(&ratings).Error; err != nil {
+		return nil, 0, err
+	}
+	return ratings, total, nil
+}
+
+// InitTable 初始化表结构
+func (r *McpCatalogRatingRepository) InitTable() error {
+	mod := &model.McpCatalogRating{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
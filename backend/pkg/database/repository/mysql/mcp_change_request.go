@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var McpChangeRequestRepo *McpChangeRequestRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpChangeRequestRepository()
+	})
+	RegisterTableInit("change_requests", func() error {
+		return McpChangeRequestRepo.InitTable()
+	})
+}
+
+// McpChangeRequestRepository 封装 change_requests 表的操作
+type McpChangeRequestRepository struct{}
+
+// NewMcpChangeRequestRepository 创建 McpChangeRequestRepository 实例
+func NewMcpChangeRequestRepository() *McpChangeRequestRepository {
+	McpChangeRequestRepo = &McpChangeRequestRepository{}
+	return McpChangeRequestRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpChangeRequestRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpChangeRequest{})
+}
+
+// Create 创建变更请求
+func (r *McpChangeRequestRepository) Create(ctx context.Context, request *model.McpChangeRequest) error {
+	if err := request.ValidateForCreate(); err != nil {
+		return err
+	}
+	request.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Create(request).Error
+}
+
+// FindByID 查询变更请求，不存在时返回 nil
+func (r *McpChangeRequestRepository) FindByID(ctx context.Context, id uint) (*model.McpChangeRequest, error) {
+	var request model.McpChangeRequest
+	if err := r.getDB().WithContext(ctx).Where("id = ?", id).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// FindPendingByResource 查询资源未过期的待审批请求，没有时返回 nil
+func (r *McpChangeRequestRepository) FindPendingByResource(ctx context.Context, resourceType, resourceID string, now time.Time) (*model.McpChangeRequest, error) {
+	var request model.McpChangeRequest
+	err := r.getDB().WithContext(ctx).
+		Where("resource_type = ? AND resource_id = ? AND status = ? AND expires_at > ?", resourceType, resourceID, model.ChangeRequestStatusPending, now).
+		Order("id DESC").First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// FindPage 分页查询变更请求，从最新开始，status 为空时不过滤状态，requestedByID 为 0 时不过滤申请人，同时返回总数
+func (r *McpChangeRequestRepository) FindPage(ctx context.Context, status string, requestedByID uint, page, pageSize int) ([]*model.McpChangeRequest, int64, error) {
+	query := r.getDB().WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if requestedByID != 0 {
+		query = query.Where("requested_by_id = ?", requestedByID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var requests []*model.McpChangeRequest
+	err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// ExpirePending 将超过有效期的待审批请求标记为过期，返回标记的数量
+func (r *McpChangeRequestRepository) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
+	result := r.getDB().WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", model.ChangeRequestStatusPending, now).
+		Updates(map[string]any{"status": model.ChangeRequestStatusExpired, "updated_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// Review 记录审批结果，只更新仍处于待审批且未过期的请求，返回是否更新成功，多位管理员同时审批时只有一位成功
+func (r *McpChangeRequestRepository) Review(ctx context.Context, request *model.McpChangeRequest) (bool, error) {
+	result := r.getDB().WithContext(ctx).
+		Where("id = ? AND status = ? AND expires_at > ?", request.ID, model.ChangeRequestStatusPending, *request.ReviewedAt).
+		Updates(map[string]any{
+			"status":         request.Status,
+			"reviewed_by":    request.ReviewedBy,
+			"reviewed_by_id": request.ReviewedByID,
+			"review_comment": request.ReviewComment,
+			"reviewed_at":    request.ReviewedAt,
+			"updated_at":     time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateResult 更新审批后执行的结果
+func (r *McpChangeRequestRepository) UpdateResult(ctx context.Context, id uint, status, result string) error {
+	return r.getDB().WithContext(ctx).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "result": result, "updated_at": time.Now()}).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpChangeRequestRepository) InitTable() error {
+	mod := &model.McpChangeRequest{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
@@ -14,10 +14,10 @@ var McpCodePackageRepo *McpCodePackageRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewMcpCodePackageRepository(db)
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize code_package table: %v", err))
-		}
+		NewMcpCodePackageRepository(db)
+	})
+	RegisterTableInit("mcp_code_package", func() error {
+		return McpCodePackageRepo.InitTable()
 	})
 }
 
@@ -68,6 +68,42 @@ func (r *McpCodePackageRepository) FindByPackageID(ctx context.Context, packageI
 	return &pkg, nil
 }
 
+// IncrementContentVersion 代码包内容修改后递增内容版本，返回新的版本
+func (r *McpCodePackageRepository) IncrementContentVersion(ctx context.Context, packageID string) (int, error) {
+	var version int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.McpCodePackage{}).Where("package_id = ? AND is_deleted = false", packageID).
+			Updates(map[string]interface{}{
+				"content_version": gorm.Expr("content_version + 1"),
+				"updated_at":      time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.McpCodePackage{}).Where("package_id = ?", packageID).
+			Select("content_version").Scan(&version).Error
+	})
+	return version, err
+}
+
+// FindContentVersions 查询代码包的内容版本，返回包ID到版本的映射，已删除的代码包不返回
+func (r *McpCodePackageRepository) FindContentVersions(ctx context.Context, packageIDs []string) (map[string]int, error) {
+	versions := make(map[string]int, len(packageIDs))
+	if len(packageIDs) == 0 {
+		return versions, nil
+	}
+	var packages []*model.McpCodePackage
+	err := r.db.WithContext(ctx).Select("package_id", "content_version").
+		Where("package_id IN ? AND is_deleted = false", packageIDs).Find(&packages).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		versions[pkg.PackageID] = pkg.ContentVersion
+	}
+	return versions, nil
+}
+
 // FindByOriginalName finds code package by original name
 func (r *McpCodePackageRepository) FindByOriginalName(ctx context.Context, originalName string) (*model.McpCodePackage, error) {
 	var pkg model.McpCodePackage
@@ -122,6 +158,14 @@ func (r *McpCodePackageRepository) FindAll(ctx context.Context) ([]*model.McpCod
 	return packages, nil
 }
 
+// SumFileSizeByCreator 统计用户上传的未删除代码包的文件大小之和
+func (r *McpCodePackageRepository) SumFileSizeByCreator(ctx context.Context, creatorID uint) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Select("COALESCE(SUM(file_size), 0)").
+		Where("creator_id = ? AND is_deleted = false", creatorID).Scan(&total).Error
+	return total, err
+}
+
 // FindWithPagination 分页查询代码包记录
 func (r *McpCodePackageRepository) FindWithPagination(ctx context.Context, page, pageSize int32, filters map[string]interface{}) ([]*model.McpCodePackage, int64, error) {
 	var packages []*model.McpCodePackage
@@ -16,10 +16,10 @@ var McpEnvironmentRepo *McpEnvironmentRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewMcpEnvironmentRepository()
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize mcp_environment table: %v", err))
-		}
+		NewMcpEnvironmentRepository()
+	})
+	RegisterTableInit("mcp_environment", func() error {
+		return McpEnvironmentRepo.InitTable()
 	})
 }
 
@@ -92,7 +92,7 @@ func (r *McpEnvironmentRepository) FindByID(ctx context.Context, id uint) (*mode
 		First(&environment).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("environment not found with id: %d", id)
+			return nil, fmt.Errorf("environment not found with id: %d: %w", id, err)
 		}
 		return nil, fmt.Errorf("failed to find environment: %v", err)
 	}
@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpIconRepo *McpIconRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpIconRepository()
+	})
+	RegisterTableInit("mcp_icon", func() error {
+		return McpIconRepo.InitTable()
+	})
+}
+
+// McpIconRepository 封装 mcp_icon 表的操作
+type McpIconRepository struct{}
+
+// NewMcpIconRepository 创建 McpIconRepository 实例
+func NewMcpIconRepository() *McpIconRepository {
+	McpIconRepo = &McpIconRepository{}
+	return McpIconRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpIconRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpIcon{})
+}
+
+// Create 保存图标记录，相同内容重复上传时重置创建时间，避免刚上传的图标被清理
+func (r *McpIconRepository) Create(ctx context.Context, icon *model.McpIcon) error {
+	icon.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at"}),
+	}).Create(icon).Error
+}
+
+// FindByName 根据文件名查找图标
+func (r *McpIconRepository) FindByName(ctx context.Context, name string) (*model.McpIcon, error) {
+	var icon model.McpIcon
+	if err := r.getDB().WithContext(ctx).Where("name = ?", name).First(&icon).Error; err != nil {
+		return nil, err
+	}
+	return &icon, nil
+}
+
+// FindUnreferenced 查询创建时间早于 before 且未被实例、模板或目录条目引用的图标
+// 图标路径以 /{name} 结尾即视为引用，不依赖路由前缀
+func (r *McpIconRepository) FindUnreferenced(ctx context.Context, before time.Time, limit int) ([]*model.McpIcon, error) {
+	var icons []*model.McpIcon
+	err := r.getDB().WithContext(ctx).
+		Where("created_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM mcp_instance WHERE mcp_instance.icon_path LIKE CONCAT('%/', mcp_icon.name))").
+		Where("NOT EXISTS (SELECT 1 FROM mcp_template WHERE mcp_template.icon_path LIKE CONCAT('%/', mcp_icon.name))").
+		Where("NOT EXISTS (SELECT 1 FROM mcp_catalog WHERE mcp_catalog.icon_url LIKE CONCAT('%/', mcp_icon.name))").
+		Order("id ASC").
+		Limit(limit).
+		Find(&icons).Error
+	return icons, err
+}
+
+// DeleteCreatedBefore 删除创建时间早于 before 的图标记录，期间重新上传过的图标不删除
+func (r *McpIconRepository) DeleteCreatedBefore(ctx context.Context, name string, before time.Time) (bool, error) {
+	result := r.getDB().WithContext(ctx).Where("name = ? AND created_at < ?", name, before).Delete(&model.McpIcon{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// InitTable 初始化表结构
+func (r *McpIconRepository) InitTable() error {
+	mod := &model.McpIcon{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
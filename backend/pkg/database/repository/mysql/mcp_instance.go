@@ -2,9 +2,11 @@ package mysql
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/database/model"
 
 	"gorm.io/gorm"
@@ -12,12 +14,23 @@ import (
 
 var McpInstanceRepo *McpInstanceRepository
 
+// InstanceStatusObserver 实例状态、容器状态或就绪状态变化后的回调，old 只包含这三个字段
+type InstanceStatusObserver func(ctx context.Context, old, instance *model.McpInstance)
+
+// instanceStatusObservers 已注册的状态变化回调，服务启动前注册，之后只读
+var instanceStatusObservers []InstanceStatusObserver
+
+// OnInstanceStatusChange 注册实例状态变化回调，回调在 Update 成功后同步执行，不应阻塞
+func OnInstanceStatusChange(observer InstanceStatusObserver) {
+	instanceStatusObservers = append(instanceStatusObservers, observer)
+}
+
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewMcpInstanceRepository()
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize mcp_instance table: %v", err))
-		}
+		NewMcpInstanceRepository()
+	})
+	RegisterTableInit("mcp_instance", func() error {
+		return McpInstanceRepo.InitTable()
 	})
 }
 
@@ -54,10 +67,124 @@ func (r *McpInstanceRepository) Create(ctx context.Context, instance *model.McpI
 	return r.getDB().WithContext(ctx).Create(instance).Error
 }
 
-// Update 更新实例
+// Update 更新实例，状态发生变化时通知已注册的回调
 func (r *McpInstanceRepository) Update(ctx context.Context, instance *model.McpInstance) error {
 	instance.UpdatedAt = time.Now()
-	return r.getDB().WithContext(ctx).Where("instance_id = ?", instance.InstanceID).Save(instance).Error
+
+	// 有回调时先读取更新前的状态，读取失败不影响更新
+	var old *model.McpInstance
+	if len(instanceStatusObservers) > 0 {
+		old = &model.McpInstance{}
+		if err := r.getDB().WithContext(ctx).Select("status", "container_status", "container_is_ready").
+			Where("instance_id = ?", instance.InstanceID).Take(old).Error; err != nil {
+			old = nil
+		}
+	}
+
+	if err := r.getDB().WithContext(ctx).Where("instance_id = ?", instance.InstanceID).Save(instance).Error; err != nil {
+		return err
+	}
+
+	if old != nil && (old.Status != instance.Status || old.ContainerStatus != instance.ContainerStatus || old.ContainerIsReady != instance.ContainerIsReady) {
+		for _, observer := range instanceStatusObservers {
+			observer(ctx, old, instance)
+		}
+	}
+	return nil
+}
+
+// UpdateLock 更新实例锁定状态，不修改更新时间
+func (r *McpInstanceRepository) UpdateLock(ctx context.Context, instanceID string, locked bool, reason, lockedBy string, lockedAt *time.Time) error {
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumns(map[string]interface{}{
+			"locked":      locked,
+			"lock_reason": reason,
+			"locked_by":   lockedBy,
+			"locked_at":   lockedAt,
+		}).Error
+}
+
+// UpdateHealthMonitor 更新实例健康检查配置，关闭检查时清空健康状态，不修改更新时间
+func (r *McpInstanceRepository) UpdateHealthMonitor(ctx context.Context, instanceID string, monitor json.RawMessage, enabled bool) error {
+	columns := map[string]interface{}{"health_monitor": monitor}
+	if !enabled {
+		columns["health_status"] = ""
+		columns["health_checked_at"] = nil
+	}
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumns(columns).Error
+}
+
+// UpdateHealthStatus 记录健康检查结果，不修改更新时间
+func (r *McpInstanceRepository) UpdateHealthStatus(ctx context.Context, instanceID, status string, checkedAt time.Time) error {
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumns(map[string]interface{}{
+			"health_status":     status,
+			"health_checked_at": checkedAt,
+		}).Error
+}
+
+// UpdateImageScan 记录镜像漏洞扫描结果，不修改更新时间
+func (r *McpInstanceRepository) UpdateImageScan(ctx context.Context, instanceID string, imageScan json.RawMessage) error {
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumn("image_scan", imageScan).Error
+}
+
+// ClaimQueued 将排队中的活跃实例更新为启动中，返回是否认领成功。
+// 条件更新保证多副本时同一实例只被认领一次，实例已删除或停用时认领失败
+func (r *McpInstanceRepository) ClaimQueued(ctx context.Context, instanceID string) (bool, error) {
+	result := r.getDB().WithContext(ctx).
+		Where("instance_id = ? AND container_status = ? AND status = ?", instanceID, model.ContainerStatusQueued, model.InstanceStatusActive).
+		UpdateColumns(map[string]interface{}{
+			"container_status":       model.ContainerStatusPending,
+			"container_last_message": "container is pending",
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateContainerStatus 只更新容器状态和状态信息
+func (r *McpInstanceRepository) UpdateContainerStatus(ctx context.Context, instanceID string, status model.ContainerStatus, message string) error {
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumns(map[string]interface{}{
+			"container_status":       status,
+			"container_last_message": message,
+		}).Error
+}
+
+// FindHealthMonitored 查询开启了健康检查的活跃直连和代理实例
+func (r *McpInstanceRepository) FindHealthMonitored(ctx context.Context) ([]*model.McpInstance, error) {
+	var instances []*model.McpInstance
+	err := r.getDB().WithContext(ctx).
+		Where("status = ? AND access_type IN ?", model.InstanceStatusActive, []model.AccessType{model.AccessTypeDirect, model.AccessTypeProxy}).
+		Where("JSON_EXTRACT(health_monitor, '$.enabled') = true").
+		Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// UpdateTokens 只更新实例令牌，不修改更新时间
+func (r *McpInstanceRepository) UpdateTokens(ctx context.Context, instanceID string, tokens []model.McpToken) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumn("tokens", data).Error
+}
+
+// UpdatePublicProxyConfig 只更新公共代理配置，不修改更新时间
+func (r *McpInstanceRepository) UpdatePublicProxyConfig(ctx context.Context, instanceID string, publicProxyConfig json.RawMessage) error {
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ?", instanceID).
+		UpdateColumn("public_proxy_config", publicProxyConfig).Error
 }
 
 // Delete 删除实例
@@ -100,7 +227,19 @@ func (r *McpInstanceRepository) FindByInstanceID(ctx context.Context, instanceID
 	var instance model.McpInstance
 	if err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).First(&instance).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("instance not found: %s", instanceID)
+			return nil, fmt.Errorf("instance not found: %s: %w", instanceID, err)
+		}
+		return nil, fmt.Errorf("failed to find instance: %v", err)
+	}
+	return &instance, nil
+}
+
+// FindBySlug 根据网关自定义访问路径查找实例
+func (r *McpInstanceRepository) FindBySlug(ctx context.Context, slug string) (*model.McpInstance, error) {
+	var instance model.McpInstance
+	if err := r.getDB().WithContext(ctx).Where("slug = ?", slug).First(&instance).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("instance not found: %s: %w", slug, err)
 		}
 		return nil, fmt.Errorf("failed to find instance: %v", err)
 	}
@@ -162,6 +301,16 @@ func (r *McpInstanceRepository) FindHostingInstances(ctx context.Context) ([]*mo
 	return instances, nil
 }
 
+// FindByAccessType 查询指定访问类型的所有实例，不限运行状态
+func (r *McpInstanceRepository) FindByAccessType(ctx context.Context, accessType model.AccessType) ([]*model.McpInstance, error) {
+	var instances []*model.McpInstance
+	err := r.getDB().WithContext(ctx).Model(&model.McpInstance{}).Where("access_type = ?", accessType).Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
 // FindWithPagination 分页查询实例
 func (r *McpInstanceRepository) FindWithPagination(ctx context.Context, page, pageSize int32, filters map[string]interface{}, sortBy, sortOrder string) ([]*model.McpInstance, int64, error) {
 	var instances []*model.McpInstance
@@ -201,6 +350,14 @@ func (r *McpInstanceRepository) FindWithPagination(ctx context.Context, page, pa
 			if mcpProtocol, ok := value.(model.McpProtocol); ok {
 				query = query.Where("mcp_protocol = ?", mcpProtocol)
 			}
+		case "labelSelector":
+			if requirements, ok := value.([]common.LabelRequirement); ok {
+				query = applyLabelSelector(query, requirements)
+			}
+		case "metadataSelector":
+			if requirements, ok := value.([]common.MetadataRequirement); ok {
+				query = applyMetadataSelector(query, requirements)
+			}
 		}
 	}
 
@@ -238,6 +395,16 @@ func (r *McpInstanceRepository) FindWithPagination(ctx context.Context, page, pa
 	return instances, total, nil
 }
 
+// FindByLabelSelector 查询标签匹配选择器的实例，按实例ID排序，最多返回 limit 条
+func (r *McpInstanceRepository) FindByLabelSelector(ctx context.Context, requirements []common.LabelRequirement, limit int) ([]*model.McpInstance, error) {
+	var instances []*model.McpInstance
+	query := applyLabelSelector(r.getDB().WithContext(ctx), requirements)
+	if err := query.Order("instance_id ASC").Limit(limit).Find(&instances).Error; err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
 // FindByPackageID finds instances by package ID
 func (r *McpInstanceRepository) FindByPackageID(ctx context.Context, packageID string) ([]*model.McpInstance, error) {
 	var instances []*model.McpInstance
@@ -248,6 +415,29 @@ func (r *McpInstanceRepository) FindByPackageID(ctx context.Context, packageID s
 	return instances, nil
 }
 
+// CountByCreator 统计用户创建的实例数和其中的托管实例数
+func (r *McpInstanceRepository) CountByCreator(ctx context.Context, creatorID uint) (total, hosting int64, err error) {
+	var counts struct {
+		Total   int64
+		Hosting int64
+	}
+	err = r.getDB().WithContext(ctx).
+		Select("COUNT(*) AS total, COALESCE(SUM(access_type = ?), 0) AS hosting", model.AccessTypeHosting).
+		Where("creator_id = ?", creatorID).
+		Scan(&counts).Error
+	return counts.Total, counts.Hosting, err
+}
+
+// FindByCreator 查询用户创建的实例
+func (r *McpInstanceRepository) FindByCreator(ctx context.Context, creatorID uint) ([]*model.McpInstance, error) {
+	var instances []*model.McpInstance
+	err := r.getDB().WithContext(ctx).Where("creator_id = ?", creatorID).Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
 // FindByName 根据实例名称查询实例
 func (r *McpInstanceRepository) FindByName(ctx context.Context, name string) (*model.McpInstance, error) {
 	var instance model.McpInstance
@@ -258,6 +448,96 @@ func (r *McpInstanceRepository) FindByName(ctx context.Context, name string) (*m
 	return &instance, nil
 }
 
+// applyLabelSelector 按标签选择器过滤，标签键已经过 common.ValidateLabelKey 校验，可以安全拼接为 JSON 路径
+func applyLabelSelector(query *gorm.DB, requirements []common.LabelRequirement) *gorm.DB {
+	for _, r := range requirements {
+		path := fmt.Sprintf(`$."%s"`, r.Key)
+		switch r.Operator {
+		case common.LabelOpEquals:
+			query = query.Where("JSON_UNQUOTE(JSON_EXTRACT(labels, ?)) = ?", path, r.Value)
+		case common.LabelOpNotEquals:
+			query = query.Where("(JSON_EXTRACT(labels, ?) IS NULL OR JSON_UNQUOTE(JSON_EXTRACT(labels, ?)) <> ?)", path, path, r.Value)
+		case common.LabelOpExists:
+			query = query.Where("JSON_CONTAINS_PATH(labels, 'one', ?) = 1", path)
+		case common.LabelOpDoesNotExist:
+			query = query.Where("(labels IS NULL OR JSON_CONTAINS_PATH(labels, 'one', ?) = 0)", path)
+		}
+	}
+	return query
+}
+
+// applyMetadataSelector 按元数据顶层键过滤，键已经过 common.ParseMetadataSelector 校验，可以安全拼接为 JSON 路径，
+// 相等条件只匹配字符串值
+func applyMetadataSelector(query *gorm.DB, requirements []common.MetadataRequirement) *gorm.DB {
+	for _, r := range requirements {
+		path := fmt.Sprintf(`$."%s"`, r.Key)
+		if r.Exists {
+			query = query.Where("JSON_CONTAINS_PATH(metadata, 'one', ?) = 1", path)
+			continue
+		}
+		query = query.Where("JSON_TYPE(JSON_EXTRACT(metadata, ?)) = 'STRING' AND JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ?", path, path, r.Value)
+	}
+	return query
+}
+
+// FindAllLabels 查询所有设置了标签的实例的标签
+func (r *McpInstanceRepository) FindAllLabels(ctx context.Context) ([]map[string]string, error) {
+	var raws []string
+	if err := r.getDB().WithContext(ctx).Where("labels IS NOT NULL").Pluck("labels", &raws).Error; err != nil {
+		return nil, err
+	}
+	labelSets := make([]map[string]string, 0, len(raws))
+	for _, raw := range raws {
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(raw), &labels); err != nil {
+			continue
+		}
+		labelSets = append(labelSets, labels)
+	}
+	return labelSets, nil
+}
+
+// FindNamesByPrefix 查询名称为 name 或以 "name-" 开头的实例名称，用于生成可用的实例名称建议
+// name 中的 LIKE 通配符只会多查出部分名称，调用方需要再按格式过滤
+func (r *McpInstanceRepository) FindNamesByPrefix(ctx context.Context, name string) ([]string, error) {
+	var names []string
+	err := r.getDB().WithContext(ctx).
+		Where("instance_name = ? OR instance_name LIKE ?", name, name+"-%").
+		Pluck("instance_name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// FindExistingInstanceIDs 返回 instanceIDs 中存在实例记录的实例ID
+func (r *McpInstanceRepository) FindExistingInstanceIDs(ctx context.Context, instanceIDs []string) ([]string, error) {
+	var existing []string
+	if len(instanceIDs) == 0 {
+		return existing, nil
+	}
+	err := r.getDB().WithContext(ctx).Model(&model.McpInstance{}).
+		Where("instance_id IN ?", instanceIDs).
+		Pluck("instance_id", &existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// FindByInstanceIDs 根据实例ID批量查找实例，不存在的实例ID忽略
+func (r *McpInstanceRepository) FindByInstanceIDs(ctx context.Context, instanceIDs []string) ([]*model.McpInstance, error) {
+	var instances []*model.McpInstance
+	if len(instanceIDs) == 0 {
+		return instances, nil
+	}
+	err := r.getDB().WithContext(ctx).Where("instance_id IN ?", instanceIDs).Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
 // FindByEnvironmentID finds instances by environment ID
 func (r *McpInstanceRepository) FindByEnvironmentID(ctx context.Context, environmentID uint) ([]*model.McpInstance, error) {
 	var instances []*model.McpInstance
@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpInstanceDailyCostRepo *McpInstanceDailyCostRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpInstanceDailyCostRepository()
+	})
+	RegisterTableInit("mcp_instance_daily_cost", func() error {
+		return McpInstanceDailyCostRepo.InitTable()
+	})
+}
+
+// McpInstanceDailyCostRepository 封装 mcp_instance_daily_cost 表的操作
+type McpInstanceDailyCostRepository struct{}
+
+// NewMcpInstanceDailyCostRepository 创建 McpInstanceDailyCostRepository 实例
+func NewMcpInstanceDailyCostRepository() *McpInstanceDailyCostRepository {
+	McpInstanceDailyCostRepo = &McpInstanceDailyCostRepository{}
+	return McpInstanceDailyCostRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpInstanceDailyCostRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpInstanceDailyCost{})
+}
+
+// Upsert 写入每日成本，同一实例同一天重复汇总时覆盖
+func (r *McpInstanceDailyCostRepository) Upsert(ctx context.Context, costs []*model.McpInstanceDailyCost) error {
+	if len(costs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, c := range costs {
+		c.CreatedAt = now
+	}
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "instance_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"instance_name", "environment_id", "labels", "running_hours", "replica_hours", "cpu_cores", "memory_gib",
+			"cpu_hour_rate", "gib_hour_rate", "cost", "running_at_end", "replicas_at_end", "created_at",
+		}),
+	}).CreateInBatches(costs, 200).Error
+}
+
+// FindLatestBefore 查询实例在 day 之前最近一天的成本记录，没有时返回 nil
+func (r *McpInstanceDailyCostRepository) FindLatestBefore(ctx context.Context, instanceID, day string) (*model.McpInstanceDailyCost, error) {
+	var c model.McpInstanceDailyCost
+	err := r.getDB().WithContext(ctx).Where("instance_id = ? AND day < ?", instanceID, day).Order("day DESC").First(&c).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindByDays 查询 [from, to) 内的成本记录，instanceID 为空时查询所有实例，按日期排列
+func (r *McpInstanceDailyCostRepository) FindByDays(ctx context.Context, instanceID, from, to string) ([]*model.McpInstanceDailyCost, error) {
+	query := r.getDB().WithContext(ctx).Where("day >= ? AND day < ?", from, to)
+	if instanceID != "" {
+		query = query.Where("instance_id = ?", instanceID)
+	}
+	var costs []*model.McpInstanceDailyCost
+	err := query.Order("day ASC, instance_id ASC").Find(&costs).Error
+	return costs, err
+}
+
+// LatestDay 最近一次汇总的日期，没有记录时为空
+func (r *McpInstanceDailyCostRepository) LatestDay(ctx context.Context) (string, error) {
+	var days []string
+	if err := r.getDB().WithContext(ctx).Order("day DESC").Limit(1).Pluck("day", &days).Error; err != nil {
+		return "", err
+	}
+	if len(days) == 0 {
+		return "", nil
+	}
+	return days[0], nil
+}
+
+// InitTable 初始化表结构
+func (r *McpInstanceDailyCostRepository) InitTable() error {
+	mod := &model.McpInstanceDailyCost{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
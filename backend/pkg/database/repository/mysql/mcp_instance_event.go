@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpInstanceEventRepo *McpInstanceEventRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpInstanceEventRepository()
+	})
+	RegisterTableInit("mcp_instance_events", func() error {
+		return McpInstanceEventRepo.InitTable()
+	})
+}
+
+// McpInstanceEventRepository 封装 mcp_instance_events 表的操作
+type McpInstanceEventRepository struct{}
+
+// NewMcpInstanceEventRepository 创建 McpInstanceEventRepository 实例
+func NewMcpInstanceEventRepository() *McpInstanceEventRepository {
+	McpInstanceEventRepo = &McpInstanceEventRepository{}
+	return McpInstanceEventRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpInstanceEventRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpInstanceEvent{})
+}
+
+// Upsert 批量写入事件，实例下去重键相同的事件只更新最后采集时间和采集次数
+func (r *McpInstanceEventRepository) Upsert(ctx context.Context, events []*model.McpInstanceEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, event := range events {
+		event.PrepareForCreate()
+	}
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "instance_id"}, {Name: "dedup_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_timestamp": time.Now().Unix(),
+			"count":          gorm.Expr("count + 1"),
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&events).Error
+}
+
+// FindWithPagination 分页查询实例事件，eventType 为空时查询全部级别，按最后采集时间倒序
+func (r *McpInstanceEventRepository) FindWithPagination(ctx context.Context, instanceID, eventType string, page, pageSize int32) ([]*model.McpInstanceEvent, int64, error) {
+	var events []*model.McpInstanceEvent
+	var total int64
+
+	query := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("last_timestamp DESC, id DESC").Offset(int(offset)).Limit(int(pageSize)).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// FindByInstanceID 按事件发生时间查询实例的前 limit 条事件，ascending 为 false 时从最新开始
+func (r *McpInstanceEventRepository) FindByInstanceID(ctx context.Context, instanceID string, ascending bool, limit int) ([]*model.McpInstanceEvent, error) {
+	var events []*model.McpInstanceEvent
+	order := "first_timestamp DESC, id DESC"
+	if ascending {
+		order = "first_timestamp ASC, id ASC"
+	}
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Order(order).Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// CountByInstanceID 统计实例的历史事件数量
+func (r *McpInstanceEventRepository) CountByInstanceID(ctx context.Context, instanceID string) (int64, error) {
+	var count int64
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Count(&count).Error
+	return count, err
+}
+
+// Prune 只保留实例最后采集时间最新的 keep 条事件，keep 不大于 0 时不删除
+func (r *McpInstanceEventRepository) Prune(ctx context.Context, instanceID string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	var oldest model.McpInstanceEvent
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).
+		Order("last_timestamp DESC, id DESC").Offset(keep - 1).Limit(1).Take(&oldest).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.getDB().WithContext(ctx).
+		Where("instance_id = ? AND (last_timestamp < ? OR (last_timestamp = ? AND id < ?))",
+			instanceID, oldest.LastTimestamp, oldest.LastTimestamp, oldest.ID).
+		Delete(&model.McpInstanceEvent{}).Error
+}
+
+// DeleteByInstanceID 删除实例的全部事件
+func (r *McpInstanceEventRepository) DeleteByInstanceID(ctx context.Context, instanceID string) error {
+	return r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Delete(&model.McpInstanceEvent{}).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpInstanceEventRepository) InitTable() error {
+	mod := &model.McpInstanceEvent{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
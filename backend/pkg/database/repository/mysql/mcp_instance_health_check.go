@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var McpInstanceHealthCheckRepo *McpInstanceHealthCheckRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpInstanceHealthCheckRepository()
+	})
+	RegisterTableInit("mcp_instance_health_checks", func() error {
+		return McpInstanceHealthCheckRepo.InitTable()
+	})
+}
+
+// McpInstanceHealthCheckRepository 封装 mcp_instance_health_checks 表的操作
+type McpInstanceHealthCheckRepository struct{}
+
+// NewMcpInstanceHealthCheckRepository 创建 McpInstanceHealthCheckRepository 实例
+func NewMcpInstanceHealthCheckRepository() *McpInstanceHealthCheckRepository {
+	McpInstanceHealthCheckRepo = &McpInstanceHealthCheckRepository{}
+	return McpInstanceHealthCheckRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpInstanceHealthCheckRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpInstanceHealthCheck{})
+}
+
+// Create 写入检查结果，并删除该实例最近 keep 条之前的记录
+func (r *McpInstanceHealthCheckRepository) Create(ctx context.Context, check *model.McpInstanceHealthCheck, keep int) error {
+	if err := r.getDB().WithContext(ctx).Create(check).Error; err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+	var oldest model.McpInstanceHealthCheck
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", check.InstanceID).
+		Order("checked_at DESC, id DESC").Offset(keep - 1).Limit(1).Take(&oldest).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return GetDB().WithContext(ctx).Where("instance_id = ? AND id < ?", check.InstanceID, oldest.ID).
+		Delete(&model.McpInstanceHealthCheck{}).Error
+}
+
+// FindByInstanceID 查询实例最近的 limit 条检查结果，从最新开始
+func (r *McpInstanceHealthCheckRepository) FindByInstanceID(ctx context.Context, instanceID string, limit int) ([]*model.McpInstanceHealthCheck, error) {
+	var checks []*model.McpInstanceHealthCheck
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).
+		Order("checked_at DESC, id DESC").Limit(limit).Find(&checks).Error
+	return checks, err
+}
+
+// DeleteByInstanceID 删除实例的全部检查结果
+func (r *McpInstanceHealthCheckRepository) DeleteByInstanceID(ctx context.Context, instanceID string) error {
+	return GetDB().WithContext(ctx).Where("instance_id = ?", instanceID).Delete(&model.McpInstanceHealthCheck{}).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpInstanceHealthCheckRepository) InitTable() error {
+	mod := &model.McpInstanceHealthCheck{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
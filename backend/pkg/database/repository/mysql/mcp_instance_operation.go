@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var McpInstanceOperationRepo *McpInstanceOperationRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpInstanceOperationRepository()
+	})
+	RegisterTableInit("mcp_instance_operations", func() error {
+		return McpInstanceOperationRepo.InitTable()
+	})
+}
+
+// McpInstanceOperationRepository 封装 mcp_instance_operations 表的操作
+type McpInstanceOperationRepository struct{}
+
+// NewMcpInstanceOperationRepository 创建 McpInstanceOperationRepository 实例
+func NewMcpInstanceOperationRepository() *McpInstanceOperationRepository {
+	McpInstanceOperationRepo = &McpInstanceOperationRepository{}
+	return McpInstanceOperationRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpInstanceOperationRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpInstanceOperation{})
+}
+
+// Create 写入操作记录
+func (r *McpInstanceOperationRepository) Create(ctx context.Context, operation *model.McpInstanceOperation) error {
+	operation.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Create(operation).Error
+}
+
+// FindByInstanceID 按操作时间查询实例的前 limit 条操作记录，ascending 为 false 时从最新开始
+func (r *McpInstanceOperationRepository) FindByInstanceID(ctx context.Context, instanceID string, ascending bool, limit int) ([]*model.McpInstanceOperation, error) {
+	var operations []*model.McpInstanceOperation
+	order := "created_at DESC, id DESC"
+	if ascending {
+		order = "created_at ASC, id ASC"
+	}
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Order(order).Limit(limit).Find(&operations).Error
+	return operations, err
+}
+
+// FindFirstByOperation 查询实例最早的一条指定类型的操作记录，不存在时返回 nil
+func (r *McpInstanceOperationRepository) FindFirstByOperation(ctx context.Context, instanceID, operation string) (*model.McpInstanceOperation, error) {
+	var operations []*model.McpInstanceOperation
+	err := r.getDB().WithContext(ctx).Where("instance_id = ? AND operation = ?", instanceID, operation).
+		Order("created_at ASC, id ASC").Limit(1).Find(&operations).Error
+	if err != nil || len(operations) == 0 {
+		return nil, err
+	}
+	return operations[0], nil
+}
+
+// FindByInstanceIDsSince 查询实例在 (since, until] 内的指定类型操作记录，按时间先后排列，最多 limit 条
+func (r *McpInstanceOperationRepository) FindByInstanceIDsSince(ctx context.Context, instanceIDs, operations []string, since, until time.Time, limit int) ([]*model.McpInstanceOperation, error) {
+	if len(instanceIDs) == 0 || len(operations) == 0 {
+		return nil, nil
+	}
+	var records []*model.McpInstanceOperation
+	err := r.getDB().WithContext(ctx).
+		Where("instance_id IN ? AND operation IN ? AND created_at > ? AND created_at <= ?", instanceIDs, operations, since, until).
+		Order("created_at ASC, id ASC").Limit(limit).Find(&records).Error
+	return records, err
+}
+
+// CountByInstanceID 统计实例的操作记录数量
+func (r *McpInstanceOperationRepository) CountByInstanceID(ctx context.Context, instanceID string) (int64, error) {
+	var count int64
+	err := r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Count(&count).Error
+	return count, err
+}
+
+// InitTable 初始化表结构
+func (r *McpInstanceOperationRepository) InitTable() error {
+	mod := &model.McpInstanceOperation{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpInstanceSlugAliasRepo *McpInstanceSlugAliasRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpInstanceSlugAliasRepository()
+	})
+	RegisterTableInit("mcp_instance_slug_alias", func() error {
+		return McpInstanceSlugAliasRepo.InitTable()
+	})
+}
+
+// McpInstanceSlugAliasRepository 封装 mcp_instance_slug_alias 表的操作
+type McpInstanceSlugAliasRepository struct{}
+
+// NewMcpInstanceSlugAliasRepository 创建 McpInstanceSlugAliasRepository 实例
+func NewMcpInstanceSlugAliasRepository() *McpInstanceSlugAliasRepository {
+	McpInstanceSlugAliasRepo = &McpInstanceSlugAliasRepository{}
+	return McpInstanceSlugAliasRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpInstanceSlugAliasRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpInstanceSlugAlias{})
+}
+
+// Upsert 保存旧路径，路径已存在时改为指向新的实例和截止时间
+func (r *McpInstanceSlugAliasRepository) Upsert(ctx context.Context, alias *model.McpInstanceSlugAlias) error {
+	alias.CreatedAt = time.Now()
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"instance_id", "expires_at", "created_at"}),
+	}).Create(alias).Error
+}
+
+// FindActive 查询未过期的旧路径
+func (r *McpInstanceSlugAliasRepository) FindActive(ctx context.Context, slug string, now time.Time) (*model.McpInstanceSlugAlias, error) {
+	var alias model.McpInstanceSlugAlias
+	if err := r.getDB().WithContext(ctx).Where("slug = ? AND expires_at > ?", slug, now).First(&alias).Error; err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// DeleteBySlug 删除旧路径，路径被实例重新使用时调用
+func (r *McpInstanceSlugAliasRepository) DeleteBySlug(ctx context.Context, slug string) error {
+	return r.getDB().WithContext(ctx).Where("slug = ?", slug).Delete(&model.McpInstanceSlugAlias{}).Error
+}
+
+// DeleteByInstanceID 删除实例的所有旧路径
+func (r *McpInstanceSlugAliasRepository) DeleteByInstanceID(ctx context.Context, instanceID string) error {
+	return r.getDB().WithContext(ctx).Where("instance_id = ?", instanceID).Delete(&model.McpInstanceSlugAlias{}).Error
+}
+
+// DeleteExpired 删除已过期的旧路径
+func (r *McpInstanceSlugAliasRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.getDB().WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.McpInstanceSlugAlias{}).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpInstanceSlugAliasRepository) InitTable() error {
+	mod := &model.McpInstanceSlugAlias{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
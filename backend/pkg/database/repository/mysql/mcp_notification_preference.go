@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpNotificationPreferenceRepo *McpNotificationPreferenceRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpNotificationPreferenceRepository()
+	})
+	RegisterTableInit("mcp_notification_preference", func() error {
+		return McpNotificationPreferenceRepo.InitTable()
+	})
+}
+
+// McpNotificationPreferenceRepository 封装 mcp_notification_preference 表的操作
+type McpNotificationPreferenceRepository struct{}
+
+// NewMcpNotificationPreferenceRepository 创建 McpNotificationPreferenceRepository 实例
+func NewMcpNotificationPreferenceRepository() *McpNotificationPreferenceRepository {
+	McpNotificationPreferenceRepo = &McpNotificationPreferenceRepository{}
+	return McpNotificationPreferenceRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpNotificationPreferenceRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpNotificationPreference{})
+}
+
+// FindByUserID 查询用户的通知偏好，未设置时返回 nil
+func (r *McpNotificationPreferenceRepository) FindByUserID(ctx context.Context, userID uint) (*model.McpNotificationPreference, error) {
+	var preference model.McpNotificationPreference
+	if err := r.getDB().WithContext(ctx).Where("user_id = ?", userID).First(&preference).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// FindEnabledByMode 查询开启了通知且使用指定发送方式的偏好
+func (r *McpNotificationPreferenceRepository) FindEnabledByMode(ctx context.Context, mode string) ([]*model.McpNotificationPreference, error) {
+	var preferences []*model.McpNotificationPreference
+	err := r.getDB().WithContext(ctx).Where("enabled = ? AND mode = ?", true, mode).Find(&preferences).Error
+	return preferences, err
+}
+
+// Upsert 创建或覆盖用户的通知偏好
+func (r *McpNotificationPreferenceRepository) Upsert(ctx context.Context, preference *model.McpNotificationPreference) error {
+	if err := preference.ValidateForCreate(); err != nil {
+		return err
+	}
+	preference.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "email", "events", "mode", "last_digest_at", "updated_at"}),
+	}).Create(preference).Error
+}
+
+// UpdateLastDigestAt 更新最近一次汇总的截止时间
+func (r *McpNotificationPreferenceRepository) UpdateLastDigestAt(ctx context.Context, userID uint, at time.Time) error {
+	return r.getDB().WithContext(ctx).Where("user_id = ?", userID).
+		Updates(map[string]any{"last_digest_at": at, "updated_at": time.Now()}).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpNotificationPreferenceRepository) InitTable() error {
+	mod := &model.McpNotificationPreference{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
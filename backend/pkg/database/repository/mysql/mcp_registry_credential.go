@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var McpRegistryCredentialRepo *McpRegistryCredentialRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpRegistryCredentialRepository()
+	})
+	RegisterTableInit("mcp_registry_credentials", func() error {
+		return McpRegistryCredentialRepo.InitTable()
+	})
+}
+
+// McpRegistryCredentialRepository 封装 mcp_registry_credentials 表的操作
+type McpRegistryCredentialRepository struct{}
+
+// NewMcpRegistryCredentialRepository 创建 McpRegistryCredentialRepository 实例
+func NewMcpRegistryCredentialRepository() *McpRegistryCredentialRepository {
+	McpRegistryCredentialRepo = &McpRegistryCredentialRepository{}
+	return McpRegistryCredentialRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpRegistryCredentialRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpRegistryCredential{})
+}
+
+// Create 创建仓库凭证
+func (r *McpRegistryCredentialRepository) Create(ctx context.Context, credential *model.McpRegistryCredential) error {
+	return r.getDB().WithContext(ctx).Create(credential).Error
+}
+
+// Update 更新仓库凭证
+func (r *McpRegistryCredentialRepository) Update(ctx context.Context, credential *model.McpRegistryCredential) error {
+	return r.getDB().WithContext(ctx).Where("id = ?", credential.ID).Save(credential).Error
+}
+
+// Delete 删除仓库凭证
+func (r *McpRegistryCredentialRepository) Delete(ctx context.Context, id uint) error {
+	return r.getDB().WithContext(ctx).Where("id = ?", id).Delete(&model.McpRegistryCredential{}).Error
+}
+
+// FindByID 根据ID查找仓库凭证
+func (r *McpRegistryCredentialRepository) FindByID(ctx context.Context, id uint) (*model.McpRegistryCredential, error) {
+	var credential model.McpRegistryCredential
+	if err := r.getDB().WithContext(ctx).Where("id = ?", id).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindByName 根据名称查找仓库凭证
+func (r *McpRegistryCredentialRepository) FindByName(ctx context.Context, name string) (*model.McpRegistryCredential, error) {
+	var credential model.McpRegistryCredential
+	if err := r.getDB().WithContext(ctx).Where("name = ?", name).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindByRegistryHost 根据规范化后的仓库地址查找凭证，同一仓库存在多个凭证时取最近更新的
+func (r *McpRegistryCredentialRepository) FindByRegistryHost(ctx context.Context, registryHost string) (*model.McpRegistryCredential, error) {
+	var credential model.McpRegistryCredential
+	err := r.getDB().WithContext(ctx).
+		Where("registry_host = ?", registryHost).
+		Order("updated_at DESC, id DESC").
+		First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindWithPagination 分页查询仓库凭证，registryHost 为空时查询全部
+func (r *McpRegistryCredentialRepository) FindWithPagination(ctx context.Context, registryHost string, page, pageSize int32) ([]*model.McpRegistryCredential, int64, error) {
+	var credentials []*model.McpRegistryCredential
+	var total int64
+
+	query := r.getDB().WithContext(ctx)
+	if registryHost != "" {
+		query = query.Where("registry_host = ?", registryHost)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(int(offset)).Limit(int(pageSize)).Find(&credentials).Error; err != nil {
+		return nil, 0, err
+	}
+	return credentials, total, nil
+}
+
+// InitTable 初始化表结构
+func (r *McpRegistryCredentialRepository) InitTable() error {
+	mod := &model.McpRegistryCredential{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
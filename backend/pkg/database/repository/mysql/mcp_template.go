@@ -13,11 +13,8 @@ import (
 var McpTemplateRepo *McpTemplateRepository
 
 func init() {
-	RegisterInit(func(db *gorm.DB) {
-		repo := NewMcpTemplateRepository()
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize mcp_template table: %v", err))
-		}
+	RegisterTableInit("mcp_template", func() error {
+		return NewMcpTemplateRepository().InitTable()
 	})
 }
 
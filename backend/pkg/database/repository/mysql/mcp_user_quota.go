@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var McpUserQuotaRepo *McpUserQuotaRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewMcpUserQuotaRepository()
+	})
+	RegisterTableInit("mcp_user_quota", func() error {
+		return McpUserQuotaRepo.InitTable()
+	})
+}
+
+// McpUserQuotaRepository 封装 mcp_user_quota 表的操作
+type McpUserQuotaRepository struct{}
+
+// NewMcpUserQuotaRepository 创建 McpUserQuotaRepository 实例
+func NewMcpUserQuotaRepository() *McpUserQuotaRepository {
+	McpUserQuotaRepo = &McpUserQuotaRepository{}
+	return McpUserQuotaRepo
+}
+
+// getDB 获取数据库连接
+func (r *McpUserQuotaRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.McpUserQuota{})
+}
+
+// FindByUserID 查询用户配额，未设置配额时返回 nil
+func (r *McpUserQuotaRepository) FindByUserID(ctx context.Context, userID uint) (*model.McpUserQuota, error) {
+	var quota model.McpUserQuota
+	if err := r.getDB().WithContext(ctx).Where("user_id = ?", userID).First(&quota).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// Upsert 创建或覆盖用户配额
+func (r *McpUserQuotaRepository) Upsert(ctx context.Context, quota *model.McpUserQuota) error {
+	if err := quota.ValidateForCreate(); err != nil {
+		return err
+	}
+	quota.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_instances", "max_hosting_instances", "max_package_bytes", "updated_by", "updated_at"}),
+	}).Create(quota).Error
+}
+
+// InitTable 初始化表结构
+func (r *McpUserQuotaRepository) InitTable() error {
+	mod := &model.McpUserQuota{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var SysAuditLogRepo *SysAuditLogRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewSysAuditLogRepository()
+	})
+	RegisterTableInit("sys_audit_log", func() error {
+		return SysAuditLogRepo.InitTable()
+	})
+}
+
+// SysAuditLogRepository 封装 sys_audit_log 表的操作
+type SysAuditLogRepository struct{}
+
+// NewSysAuditLogRepository 创建 SysAuditLogRepository 实例
+func NewSysAuditLogRepository() *SysAuditLogRepository {
+	SysAuditLogRepo = &SysAuditLogRepository{}
+	return SysAuditLogRepo
+}
+
+// getDB 获取数据库连接
+func (r *SysAuditLogRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.SysAuditLog{})
+}
+
+// Create 写入审计日志
+func (r *SysAuditLogRepository) Create(ctx context.Context, log *model.SysAuditLog) error {
+	log.PrepareForCreate()
+	return r.getDB().WithContext(ctx).Create(log).Error
+}
+
+// SysAuditLogFilter 审计日志查询条件，零值字段不过滤
+type SysAuditLogFilter struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	// From 起始时间（包含），To 结束时间（不包含）
+	From time.Time
+	To   time.Time
+	// BeforeID 只查询 ID 小于该值的记录，用于按 ID 游标分页
+	BeforeID uint
+}
+
+// List 按时间从新到旧查询审计日志，最多返回 limit 条
+func (r *SysAuditLogRepository) List(ctx context.Context, filter SysAuditLogFilter, limit int) ([]*model.SysAuditLog, error) {
+	query := r.getDB().WithContext(ctx)
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	var logs []*model.SysAuditLog
+	err := query.Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// InitTable 初始化表结构
+func (r *SysAuditLogRepository) InitTable() error {
+	mod := &model.SysAuditLog{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
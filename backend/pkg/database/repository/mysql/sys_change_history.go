@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"qm-mcp-server/pkg/database/model"
+
+	"gorm.io/gorm"
+)
+
+var SysChangeHistoryRepo *SysChangeHistoryRepository
+
+func init() {
+	RegisterInit(func(db *gorm.DB) {
+		NewSysChangeHistoryRepository()
+	})
+	RegisterTableInit("sys_change_history", func() error {
+		return SysChangeHistoryRepo.InitTable()
+	})
+}
+
+// SysChangeHistoryRepository 封装 sys_change_history 表的操作
+type SysChangeHistoryRepository struct{}
+
+// NewSysChangeHistoryRepository 创建 SysChangeHistoryRepository 实例
+func NewSysChangeHistoryRepository() *SysChangeHistoryRepository {
+	SysChangeHistoryRepo = &SysChangeHistoryRepository{}
+	return SysChangeHistoryRepo
+}
+
+// getDB 获取数据库连接
+func (r *SysChangeHistoryRepository) getDB() *gorm.DB {
+	return GetDB().Model(&model.SysChangeHistory{})
+}
+
+// Create 写入变更记录
+func (r *SysChangeHistoryRepository) Create(ctx context.Context, history *model.SysChangeHistory) error {
+	return r.getDB().WithContext(ctx).Create(history).Error
+}
+
+// FindByResource 分页查询资源的变更记录，从最新开始，同时返回总数
+func (r *SysChangeHistoryRepository) FindByResource(ctx context.Context, resourceType, resourceID string, page, pageSize int) ([]*model.SysChangeHistory, int64, error) {
+	var total int64
+	query := r.getDB().WithContext(ctx).Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var histories []*model.SysChangeHistory
+	err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&histories).Error
+	return histories, total, err
+}
+
+// InitTable 初始化表结构
+func (r *SysChangeHistoryRepository) InitTable() error {
+	mod := &model.SysChangeHistory{}
+	if err := r.getDB().AutoMigrate(mod); err != nil {
+		return fmt.Errorf("failed to migrate table: %v", err)
+	}
+	return nil
+}
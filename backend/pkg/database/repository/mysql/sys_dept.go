@@ -16,10 +16,10 @@ var SysDeptRepo *SysDeptRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysDeptRepository()
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_dept table: %v", err))
-		}
+		NewSysDeptRepository()
+	})
+	RegisterTableInit("sys_dept", func() error {
+		return SysDeptRepo.InitTable()
 	})
 }
 
@@ -15,10 +15,10 @@ var SysEncryptionKeyRepo *SysEncryptionKeyRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysEncryptionKeyRepository(db)
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_encryption_key table: %v", err))
-		}
+		NewSysEncryptionKeyRepository(db)
+	})
+	RegisterTableInit("sys_encryption_key", func() error {
+		return SysEncryptionKeyRepo.InitTable()
 	})
 }
 
@@ -19,10 +19,10 @@ type SysRoleRepository struct{}
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysRoleRepository()
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_role table: %v", err))
-		}
+		NewSysRoleRepository()
+	})
+	RegisterTableInit("sys_role", func() error {
+		return SysRoleRepo.InitTable()
 	})
 }
 
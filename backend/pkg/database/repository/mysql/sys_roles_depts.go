@@ -15,10 +15,10 @@ var SysRolesDeptsRepo *SysRolesDeptsRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysRolesDeptsRepository(db)
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_roles_depts table: %v", err))
-		}
+		NewSysRolesDeptsRepository(db)
+	})
+	RegisterTableInit("sys_roles_depts", func() error {
+		return SysRolesDeptsRepo.InitTable()
 	})
 }
 
@@ -17,10 +17,10 @@ var SysUserRepo *SysUserRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysUserRepository(db)
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_user table: %v", err))
-		}
+		NewSysUserRepository(db)
+	})
+	RegisterTableInit("sys_user", func() error {
+		return SysUserRepo.InitTable()
 	})
 }
 
@@ -146,6 +146,22 @@ func (r *SysUserRepository) FindByID(ctx context.Context, id uint) (*model.SysUs
 	return &user, nil
 }
 
+// FindByIDs 根据ID列表查找用户
+func (r *SysUserRepository) FindByIDs(ctx context.Context, ids []uint) ([]*model.SysUser, error) {
+	if r.getDB() == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var users []*model.SysUser
+	if err := r.getDB().WithContext(ctx).Where("user_id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to find users: %v", err)
+	}
+	return users, nil
+}
+
 // FindByUsername 根据用户名查找用户
 func (r *SysUserRepository) FindByUsername(ctx context.Context, username string) (*model.SysUser, error) {
 	if r.getDB() == nil {
@@ -15,10 +15,10 @@ var SysUsersRolesRepo *SysUsersRolesRepository
 
 func init() {
 	RegisterInit(func(db *gorm.DB) {
-		repo := NewSysUsersRolesRepository(db)
-		if err := repo.InitTable(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize sys_users_roles table: %v", err))
-		}
+		NewSysUsersRolesRepository(db)
+	})
+	RegisterTableInit("sys_users_roles", func() error {
+		return SysUsersRolesRepo.InitTable()
 	})
 }
 
@@ -235,6 +235,23 @@ func (r *SysUsersRolesRepository) FindUserIDsByRoleID(ctx context.Context, roleI
 	return userIDs, nil
 }
 
+// HasRoleName 检查用户是否拥有指定名称的角色
+func (r *SysUsersRolesRepository) HasRoleName(ctx context.Context, userID uint, roleName string) (bool, error) {
+	if r.getDB() == nil {
+		return false, fmt.Errorf("database connection is nil")
+	}
+
+	var count int64
+	err := r.getDB().WithContext(ctx).
+		Joins("JOIN sys_role ON sys_role.role_id = sys_users_roles.role_id").
+		Where("sys_users_roles.user_id = ? AND sys_role.name = ?", userID, roleName).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check role %s of user %d: %v", roleName, userID, err)
+	}
+	return count > 0, nil
+}
+
 // Exists 检查用户角色关联是否存在
 func (r *SysUsersRolesRepository) Exists(ctx context.Context, userID, roleID uint) (bool, error) {
 	if r.getDB() == nil {
@@ -51,6 +51,14 @@ const (
 	CodeForeignKeyViolation = 5003
 	CodeDataConflict        = 5004
 	CodeResourceExhausted   = 5005
+	// 幂等键相关错误
+	CodeIdempotencyKeyInvalid        = 5006
+	CodeIdempotencyKeyConflict       = 5007
+	CodeIdempotencyRequestInProgress = 5008
+	// 维护模式相关错误
+	CodeMaintenanceInProgress      = 5009
+	CodeMaintenanceInProgressUntil = 5010
+	CodeMaintenanceStateFailure    = 5011
 
 	// 系统错误 (6000-6999)
 	CodeDatabaseError      = 6000
@@ -255,6 +263,8 @@ const (
 	CodeGetK8sRuntimeEntryFailure        = 8858 // 获取Kubernetes运行时入口失败
 	CodeFailedToFindCodePackage          = 8861 // 查找代码包失败
 	CodeFailedToGenerateDownloadZip      = 8862 // 生成下载ZIP包失败
+	CodeScaleContainerFailure            = 8863 // 调整容器副本数失败
+	CodeContainerScaled                  = 8864 // 容器副本数已调整
 
 	// 实例相关错误 (8900-8999)
 	CodeInstanceNameAlreadyExists  = 8900
@@ -269,6 +279,59 @@ const (
 	CodeUnsupportedAccessType      = 8909
 	CodeAccessTypeConvertFailure   = 8910
 	CodeMCPProtocolConvertFailure  = 8911
+	CodeInstanceConnectionsFailure = 8912
+	CodeInstanceDrainFailure       = 8913
+	CodeInstanceDrainSuccess       = 8914
+	CodeInstanceRestartReady       = 8915
+	CodeInstanceRestartNotReady    = 8916
+	CodeApplyManifestInvalid       = 8917
+	CodeApplyFieldImmutable        = 8918
+	CodeApplyEnvironmentNotFound   = 8919
+	CodeInstanceLabelsQueryFailure = 8920
+	CodeInstanceLocked             = 8921
+	CodeInstanceLockFailure        = 8922
+	CodeContainerRestartedLogs     = 8923 // 容器已重启，附带上一个容器的日志
+	CodeInstanceDriftCheckFailure  = 8924
+	CodeInstanceConfigDrifted      = 8925
+	CodeInstanceTimelineFailure    = 8926
+	CodeStatusCheckTimeout         = 8927
+	CodeStatusBatchDeadline        = 8928
+	CodeInstanceBulkQueryFailure   = 8929
+	CodeInstanceBulkTooMany        = 8930
+	CodeInstanceEnableFailure      = 8931
+	CodeInstanceEnableSuccess      = 8932
+	CodeInstanceAlreadyEnabled     = 8933
+	CodeHealthMonitorFailure       = 8934
+	CodeHealthMonitorUnsupported   = 8935
+	CodeHealthHistoryFailure       = 8936
+	CodeInstanceTokenNotFound      = 8937
+	CodeInstanceTokenRotating      = 8938
+	CodeTokenRotateFailure         = 8939
+	CodeTokenRotateSuccess         = 8940
+	CodeAffinityTargetInvalid      = 8941
+	CodeServiceAccountNotFound     = 8942
+	CodeChangeHistoryFailure       = 8943
+	CodeConnectionTokenUnsupported = 8944
+	CodeConnectionFailure          = 8945
+	CodeImageScanBlocked           = 8946
+	CodeImageScanNotConfigured     = 8947
+	CodeImageScanFailure           = 8948
+	CodeImageDigestChanged         = 8949
+	CodeUserQuotaExceeded          = 8950
+	CodeUserQuotaFailure           = 8951
+	CodeChangeRequestPending       = 8952
+	CodeChangeRequestExists        = 8953
+	CodeChangeRequestNotFound      = 8954
+	CodeChangeRequestNotPending    = 8955
+	CodeChangeRequestSelfReview    = 8956
+	CodeChangeRequestExpired       = 8957
+	CodeChangeRequestFailure       = 8958
+	CodeViewerReadOnly             = 8959
+	CodeImpersonationForbidden     = 8960
+	CodeImpersonationUserNotFound  = 8961
+	CodeImpersonationAdminRefused  = 8962
+	CodeInstanceSlugAlreadyExists  = 8963
+	CodeInstanceUnlockForbidden    = 8964
 
 	// 数据库相关错误 (9000-9099)
 	CodeEncryptionKeyNotExists   = 9000
@@ -302,4 +365,101 @@ const (
 	CodeStorageSizeCannotBeEmpty          = 9118
 	CodeInvalidAccessMode                 = 9119
 	CodeConfigParameterCannotBeEmpty      = 9120
+
+	// 结构化 API 错误 (9300-9399)，对应非 200 的 HTTP 状态码
+	CodeInstanceNotFound          = 9300 // 实例不存在 -> 404
+	CodeTemplateNotFound          = 9301 // 模板不存在 -> 404
+	CodeTemplateNameAlreadyExists = 9302 // 模板名称冲突 -> 409
+	CodeFieldValidationFailed     = 9303 // 字段校验失败 -> 422
+	CodeEnvironmentUnreachable    = 9304 // 环境不可达 -> 502
+	CodeContainerRuntimeError     = 9305 // 容器运行时错误 -> 502
+	CodeRequestValidationFailed   = 9306 // 请求参数校验失败 -> 422
+	CodeImageNotFound             = 9307 // 镜像不存在 -> 422
+	CodeImageRegistryUnreachable  = 9308 // 镜像仓库不可达 -> 502
+	CodeImageAccessDenied         = 9309 // 镜像仓库拒绝访问 -> 422
+
+	// 字段校验规则 (9310-9329)
+	CodeRuleRequired = 9310
+	CodeRuleMin      = 9311
+	CodeRuleRange    = 9312
+	CodeRuleJSON     = 9313
+	CodeRuleInvalid  = 9314
+
+	// 实例服务消息 (9400-9419)
+	CodeGetInstanceFailure          = 9400
+	CodeCreateInstanceFailure       = 9401
+	CodeEditInstanceFailure         = 9402
+	CodeSourceTypeConvertFailure    = 9403
+	CodeUnsupportedMcpProtocol      = 9404
+	CodeHostingRequiresKubernetes   = 9405
+	CodeMarshalConfigFailure        = 9406
+	CodeInstanceNoRestartNeeded     = 9407
+	CodeInstanceNotManaged          = 9408
+	CodeLogsRequireKubernetes       = 9409
+	CodeGetInstanceLogsFailure      = 9410
+	CodeGetEnvironmentFailure       = 9411
+	CodeInstanceStatusUpdateFailure = 9412
+	CodeInstanceDeleteSuccess       = 9413
+	CodeInstanceRestartSuccess      = 9414
+	CodeInstanceDisableSuccess      = 9415
+	CodeInstanceLogsSuccess         = 9416
+	CodeInstanceEventsQueryFailure  = 9417
+	CodeCircuitBreakerResetFailure  = 9418
+	CodeCircuitBreakerResetSuccess  = 9419
+
+	// 模板服务消息 (9420-9429)
+	CodeTemplateCreateFailure       = 9420
+	CodeTemplateQueryFailure        = 9421
+	CodeTemplateUpdateFailure       = 9422
+	CodeTemplateDeleteFailure       = 9423
+	CodeTemplateFieldProcessFailure = 9424
+	CodeTemplateNameCheckFailure    = 9425
+	CodeTemplateUpdateSuccess       = 9426
+
+	// 环境服务消息 (9430-9449)
+	CodeEnvironmentTypeUnsupported  = 9430
+	CodeEnvironmentNameConflict     = 9431
+	CodeEnvironmentValidateFailure  = 9432
+	CodeEnvironmentSaveFailure      = 9433
+	CodeEnvironmentQueryFailure     = 9434
+	CodeEnvironmentRemoveFailure    = 9435
+	CodeEnvironmentListFailure      = 9436
+	CodeEnvironmentIDNotFound       = 9437
+	CodeEnvironmentIDInvalid        = 9438
+	CodeEnvironmentDeleteSuccess    = 9439
+	CodeNamespaceRequiresKubernetes = 9440
+	CodeEnvironmentQuotaExceeded    = 9441
+	CodeEnvironmentQuotaFailure     = 9442
+	CodeOrphanScanFailure           = 9443
+	CodeOrphanCleanupUnconfirmed    = 9444
+	CodeOrphanCleanupFailure        = 9445
+	CodeMetricsUnavailable          = 9446
+	CodeEnvironmentResourcesFailure = 9447
+
+	// 镜像仓库凭证服务消息 (9450-9469)
+	CodeRegistryCredentialNotFound      = 9450
+	CodeRegistryCredentialNameConflict  = 9451
+	CodeRegistryCredentialIDInvalid     = 9452
+	CodeRegistryCredentialSaveFailure   = 9453
+	CodeRegistryCredentialQueryFailure  = 9454
+	CodeRegistryCredentialDeleteFailure = 9455
+	CodeRegistryCredentialDeleteSuccess = 9456
+	CodeImagePullSecretSyncFailure      = 9457
+
+	// MCP 服务目录消息 (9470-9489)
+	CodeCatalogEntryNotFound         = 9470
+	CodeCatalogQueryFailure          = 9471
+	CodeCatalogSyncFailure           = 9472
+	CodeCatalogRegistryURLRequired   = 9473
+	CodeCatalogEnvRequired           = 9474
+	CodeCatalogUnsupportedAccessType = 9475
+	CodeCatalogRateFailure           = 9476
+	CodeCatalogCurationFailure       = 9477
+
+	// 图标消息 (9490-9499)
+	CodeIconFileRequired = 9490
+	CodeIconTooLarge     = 9491
+	CodeIconInvalid      = 9492
+	CodeIconSaveFailure  = 9493
+	CodeIconNotFound     = 9494
 )
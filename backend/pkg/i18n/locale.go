@@ -3,6 +3,7 @@ package i18n
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +32,8 @@ func GetLanguageFromContext(ctx context.Context) SupportedLanguage {
 }
 
 // GetLanguageFromGin 从 Gin 上下文获取语言
+// 优先级: ?lang= 参数 > Accept-Language 请求头 > 中间件写入的上下文 > 默认语言
+// 消息模板缺失时由 MessageLoader 回退到 en-US
 func GetLanguageFromGin(c *gin.Context) SupportedLanguage {
 	// 1. 优先从查询参数获取
 	if lang := c.Query("lang"); lang != "" {
@@ -71,17 +74,31 @@ func parseSupportedLanguage(lang string) SupportedLanguage {
 }
 
 // parseAcceptLanguage 解析 Accept-Language 头
+// 按权重 q 从高到低依次匹配支持的语言，如 "en-GB;q=0.8, zh-TW;q=0.9" 返回 zh-CN
 func parseAcceptLanguage(acceptLang string) SupportedLanguage {
-	// 简单解析 Accept-Language，取第一个支持的语言
-	languages := strings.Split(acceptLang, ",")
-	for _, lang := range languages {
-		// 移除权重信息 (如 zh-CN;q=0.9)
-		lang = strings.Split(strings.TrimSpace(lang), ";")[0]
-		if supportedLang := parseSupportedLanguage(lang); supportedLang != "" {
-			return supportedLang
+	best := SupportedLanguage("")
+	bestQ := -1.0
+	for _, part := range strings.Split(acceptLang, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		supportedLang := parseSupportedLanguage(fields[0])
+		if supportedLang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		// 权重相同时保留先出现的语言
+		if q > bestQ && q > 0 {
+			best, bestQ = supportedLang, q
 		}
 	}
-	return ""
+	return best
 }
 
 // SetLanguageToContext 设置语言到上下文
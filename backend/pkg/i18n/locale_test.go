@@ -0,0 +1,75 @@
+package i18n_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetLanguageFromGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		want           i18n.SupportedLanguage
+	}{
+		{name: "Default", target: "/", want: i18n.DefaultLanguage},
+		{name: "AcceptEnglish", target: "/", acceptLanguage: "en-US", want: i18n.LanguageEnUS},
+		{name: "AcceptChinese", target: "/", acceptLanguage: "zh-CN,zh;q=0.9", want: i18n.LanguageZhCN},
+		{name: "HighestWeightWins", target: "/", acceptLanguage: "zh-CN;q=0.5, en-GB;q=0.8", want: i18n.LanguageEnUS},
+		{name: "SkipUnsupported", target: "/", acceptLanguage: "fr-FR, en;q=0.3", want: i18n.LanguageEnUS},
+		{name: "QueryOverridesHeader", target: "/?lang=zh-CN", acceptLanguage: "en-US", want: i18n.LanguageZhCN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.acceptLanguage != "" {
+				c.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+
+			if got := i18n.GetLanguageFromGin(c); got != tt.want {
+				t.Errorf("GetLanguageFromGin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorResponseWithArgsLocalized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	messages := make(map[string]string)
+	for _, lang := range []string{"zh-CN", "en-US"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("Accept-Language", lang)
+
+		i18n.ErrorResponseWithArgs(c, i18n.CodeInstanceNotFound, "a1b2")
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: status = %d, want %d", lang, w.Code, http.StatusNotFound)
+		}
+		var body struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: unmarshal response: %v", lang, err)
+		}
+		if body.Message == "" {
+			t.Fatalf("%s: empty message", lang)
+		}
+		messages[lang] = body.Message
+	}
+	if messages["zh-CN"] == messages["en-US"] {
+		t.Errorf("zh-CN and en-US messages should differ, both = %q", messages["zh-CN"])
+	}
+}
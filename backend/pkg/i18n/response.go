@@ -3,14 +3,17 @@ package i18n
 import (
 	"net/http"
 
+	"qm-mcp-server/pkg/logger"
+
 	"github.com/gin-gonic/gin"
 )
 
 // Response unified response structure
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"requestId,omitempty"` // 请求 ID，仅错误响应返回，便于用户反馈问题时提供
 }
 
 // SuccessResponse success response
@@ -27,10 +30,11 @@ func ErrorResponse(c *gin.Context, code int, message string) {
 	if message == "" {
 		message = GetLocalizedMessageWithGin(c, code)
 	}
-	c.JSON(http.StatusOK, Response{
-		Code:    code,
-		Message: message,
-		Data:    nil,
+	c.JSON(HTTPStatus(code), Response{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(logger.RequestIDKey),
+		Data:      nil,
 	})
 }
 
@@ -44,20 +48,22 @@ func ErrorWithData(c *gin.Context, code int, message string, data interface{}) {
 	if message == "" {
 		message = GetLocalizedMessageWithGin(c, code)
 	}
-	c.JSON(http.StatusOK, Response{
-		Code:    code,
-		Message: message,
-		Data:    data,
+	c.JSON(HTTPStatus(code), Response{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(logger.RequestIDKey),
+		Data:      data,
 	})
 }
 
 // ErrorResponseWithArgs 带参数的错误响应
 func ErrorResponseWithArgs(c *gin.Context, code int, args ...interface{}) {
 	message := GetLocalizedMessageWithGin(c, code, args...)
-	c.JSON(http.StatusOK, Response{
-		Code:    code,
-		Message: message,
-		Data:    nil,
+	c.JSON(HTTPStatus(code), Response{
+		Code:      code,
+		Message:   message,
+		RequestID: c.GetString(logger.RequestIDKey),
+		Data:      nil,
 	})
 }
 
@@ -0,0 +1,76 @@
+package i18n
+
+import "net/http"
+
+// codeHTTPStatus 错误码与 HTTP 状态码的映射
+// 未登记的错误码保持历史行为，统一返回 200，由响应体中的 code 区分错误
+var codeHTTPStatus = map[int]int{
+	CodeInstanceNotFound:               http.StatusNotFound,
+	CodeInstanceNotExists:              http.StatusNotFound,
+	CodeTemplateNotFound:               http.StatusNotFound,
+	CodeEnvironmentIDNotFound:          http.StatusNotFound,
+	CodeRegistryCredentialNotFound:     http.StatusNotFound,
+	CodeCatalogEntryNotFound:           http.StatusNotFound,
+	CodeChangeRequestNotFound:          http.StatusNotFound,
+	CodeImpersonationUserNotFound:      http.StatusNotFound,
+	CodeIconNotFound:                   http.StatusNotFound,
+	CodeInstanceTokenNotFound:          http.StatusNotFound,
+	CodeInstanceNameAlreadyExists:      http.StatusConflict,
+	CodeInstanceSlugAlreadyExists:      http.StatusConflict,
+	CodeTemplateNameAlreadyExists:      http.StatusConflict,
+	CodeEnvironmentNameConflict:        http.StatusConflict,
+	CodeRegistryCredentialNameConflict: http.StatusConflict,
+	CodeIdempotencyKeyConflict:         http.StatusConflict,
+	CodeIdempotencyRequestInProgress:   http.StatusConflict,
+	CodeInstanceConfigDrifted:          http.StatusConflict,
+	CodeImageDigestChanged:             http.StatusConflict,
+	CodeEnvironmentQuotaExceeded:       http.StatusConflict,
+	CodeUserQuotaExceeded:              http.StatusConflict,
+	CodeChangeRequestExists:            http.StatusConflict,
+	CodeChangeRequestNotPending:        http.StatusConflict,
+	CodeChangeRequestExpired:           http.StatusConflict,
+	CodeInstanceTokenRotating:          http.StatusConflict,
+	CodeChangeRequestPending:           http.StatusAccepted,
+	CodeFieldValidationFailed:          http.StatusUnprocessableEntity,
+	CodeRequestValidationFailed:        http.StatusUnprocessableEntity,
+	CodeEnvironmentValidateFailure:     http.StatusUnprocessableEntity,
+	CodeImageNotFound:                  http.StatusUnprocessableEntity,
+	CodeImageAccessDenied:              http.StatusUnprocessableEntity,
+	CodeApplyManifestInvalid:           http.StatusUnprocessableEntity,
+	CodeInstanceBulkTooMany:            http.StatusUnprocessableEntity,
+	CodeHealthMonitorUnsupported:       http.StatusUnprocessableEntity,
+	CodeAffinityTargetInvalid:          http.StatusUnprocessableEntity,
+	CodeServiceAccountNotFound:         http.StatusUnprocessableEntity,
+	CodeImageScanBlocked:               http.StatusUnprocessableEntity,
+	CodeImageScanNotConfigured:         http.StatusUnprocessableEntity,
+	CodeCatalogEnvRequired:             http.StatusUnprocessableEntity,
+	CodeIconInvalid:                    http.StatusUnprocessableEntity,
+	CodeIconTooLarge:                   http.StatusRequestEntityTooLarge,
+	CodeIconFileRequired:               http.StatusBadRequest,
+	CodeCatalogRegistryURLRequired:     http.StatusBadRequest,
+	CodeIdempotencyKeyInvalid:          http.StatusBadRequest,
+	CodeOrphanCleanupUnconfirmed:       http.StatusBadRequest,
+	CodeMetricsUnavailable:             http.StatusServiceUnavailable,
+	CodeInsufficientPermissions:        http.StatusForbidden,
+	CodeChangeRequestSelfReview:        http.StatusForbidden,
+	CodeViewerReadOnly:                 http.StatusForbidden,
+	CodeImpersonationForbidden:         http.StatusForbidden,
+	CodeImpersonationAdminRefused:      http.StatusForbidden,
+	CodeInstanceUnlockForbidden:        http.StatusForbidden,
+	CodeMaintenanceInProgress:          http.StatusLocked,
+	CodeMaintenanceInProgressUntil:     http.StatusLocked,
+	CodeInstanceLocked:                 http.StatusLocked,
+	CodeEnvironmentUnreachable:         http.StatusBadGateway,
+	CodeContainerRuntimeError:          http.StatusBadGateway,
+	CodeImageRegistryUnreachable:       http.StatusBadGateway,
+	CodeImageScanFailure:               http.StatusBadGateway,
+	CodeCatalogSyncFailure:             http.StatusBadGateway,
+}
+
+// HTTPStatus 根据错误码获取对应的 HTTP 状态码
+func HTTPStatus(code int) int {
+	if status, ok := codeHTTPStatus[code]; ok {
+		return status
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,218 @@
+// Package icon validates uploaded instance, template and catalog icons and stores them
+// under content-hash names so the same image is only stored once.
+package icon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidIcon is matched by the errors returned when the uploaded data is not an acceptable icon.
+var ErrInvalidIcon = errors.New("invalid icon")
+
+// ValidationError describes why the uploaded data is not an acceptable icon.
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidIcon.Error() + ": " + e.Reason
+}
+
+// Is makes errors.Is(err, ErrInvalidIcon) report true.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidIcon
+}
+
+// invalid returns a ValidationError with a formatted reason.
+func invalid(format string, args ...interface{}) error {
+	return &ValidationError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Supported icon formats
+const (
+	FormatPNG  = "png"
+	FormatSVG  = "svg"
+	FormatWebP = "webp"
+)
+
+// Default limits used when the configuration leaves them unset
+const (
+	DefaultMaxFileSize  = 512 << 10
+	DefaultMinDimension = 16
+	DefaultMaxDimension = 1024
+)
+
+// KeyPrefix is the storage key prefix of icon objects.
+const KeyPrefix = "icons"
+
+// nameRegexp matches the content-hash file names produced by Process.
+var nameRegexp = regexp.MustCompile(`^[0-9a-f]{64}\.(png|svg|webp)$`)
+
+// Limits bounds the accepted file size in bytes and the width and height in pixels.
+type Limits struct {
+	MaxFileSize  int
+	MinDimension int
+	MaxDimension int
+}
+
+// withDefaults fills unset limits with the defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = DefaultMaxFileSize
+	}
+	if l.MinDimension <= 0 {
+		l.MinDimension = DefaultMinDimension
+	}
+	if l.MaxDimension <= 0 {
+		l.MaxDimension = DefaultMaxDimension
+	}
+	return l
+}
+
+// Icon is a validated icon ready to be stored.
+type Icon struct {
+	// Name is the content-hash file name, {sha256}.{format}
+	Name        string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	// Data is the stored content, SVG icons are sanitized before hashing
+	Data []byte
+}
+
+// Key returns the storage key of the icon.
+func (i *Icon) Key() string {
+	return Key(i.Name)
+}
+
+// Key returns the storage key of the icon with the given file name.
+func Key(name string) string {
+	return path.Join(KeyPrefix, name)
+}
+
+// ValidName reports whether name is a file name produced by Process.
+func ValidName(name string) bool {
+	return nameRegexp.MatchString(name)
+}
+
+// ContentType returns the MIME type of the icon file name.
+func ContentType(name string) string {
+	switch path.Ext(name) {
+	case "." + FormatPNG:
+		return "image/png"
+	case "." + FormatSVG:
+		return "image/svg+xml"
+	case "." + FormatWebP:
+		return "image/webp"
+	}
+	return "application/octet-stream"
+}
+
+// Process detects the format of data, validates the size and dimensions against limits,
+// sanitizes SVG markup and names the result by the SHA-256 of the stored content.
+func Process(data []byte, limits Limits) (*Icon, error) {
+	limits = limits.withDefaults()
+	if len(data) == 0 {
+		return nil, invalid("empty file")
+	}
+	if len(data) > limits.MaxFileSize {
+		return nil, invalid("file is %d bytes, the limit is %d", len(data), limits.MaxFileSize)
+	}
+
+	var (
+		icon = &Icon{Data: data}
+		err  error
+	)
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		icon.Format = FormatPNG
+		icon.Width, icon.Height, err = pngSize(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		icon.Format = FormatWebP
+		icon.Width, icon.Height, err = webpSize(data)
+	case looksLikeSVG(data):
+		icon.Format = FormatSVG
+		icon.Data, err = SanitizeSVG(data)
+		if err == nil {
+			icon.Width, icon.Height, err = svgSize(icon.Data)
+		}
+	default:
+		return nil, invalid("only PNG, SVG and WebP images are supported")
+	}
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+
+	if icon.Width < limits.MinDimension || icon.Height < limits.MinDimension ||
+		icon.Width > limits.MaxDimension || icon.Height > limits.MaxDimension {
+		return nil, invalid("image is %dx%d, width and height must be between %d and %d",
+			icon.Width, icon.Height, limits.MinDimension, limits.MaxDimension)
+	}
+
+	sum := sha256.Sum256(icon.Data)
+	icon.Name = hex.EncodeToString(sum[:]) + "." + icon.Format
+	icon.ContentType = ContentType(icon.Name)
+	return icon, nil
+}
+
+// pngSize reads the dimensions from the PNG header.
+func pngSize(data []byte) (int, int, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed PNG: %v", err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// webpSize reads the dimensions from the first chunk of a WebP file,
+// see https://developers.google.com/speed/webp/docs/riff_container
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 30 {
+		return 0, 0, fmt.Errorf("malformed WebP: file too short")
+	}
+	chunk := data[12:16]
+	payload := data[20:]
+	switch string(chunk) {
+	case "VP8 ":
+		// 3 byte frame tag, 3 byte start code, then 14 bit width and height
+		if payload[3] != 0x9d || payload[4] != 0x01 || payload[5] != 0x2a {
+			return 0, 0, fmt.Errorf("malformed WebP: bad VP8 start code")
+		}
+		width := int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// 1 byte signature, then 14 bit width-1 and height-1
+		if payload[0] != 0x2f {
+			return 0, 0, fmt.Errorf("malformed WebP: bad VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:5])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X":
+		// 1 byte flags, 3 reserved bytes, then 24 bit canvas width-1 and height-1
+		width := int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16
+		height := int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, fmt.Errorf("malformed WebP: unknown chunk %q", chunk)
+}
+
+// looksLikeSVG reports whether data is XML markup with an svg root element.
+func looksLikeSVG(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	text := strings.TrimSpace(strings.TrimPrefix(string(head), "\ufeff"))
+	return strings.HasPrefix(text, "<") && strings.Contains(text, "<svg")
+}
@@ -0,0 +1,117 @@
+package icon
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func pngData(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// webpLosslessData builds a VP8L header for a width x height image, enough for size detection.
+func webpLosslessData(width, height int) []byte {
+	bits := uint32(width-1) | uint32(height-1)<<14
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f")
+	data = append(data, byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24))
+	return append(data, make([]byte, 16)...)
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		format     string
+		width      int
+		height     int
+		wantErrMsg string
+	}{
+		{name: "png", data: pngData(t, 64, 32), format: FormatPNG, width: 64, height: 32},
+		{name: "webp", data: webpLosslessData(128, 128), format: FormatWebP, width: 128, height: 128},
+		{name: "svg size", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="48px" height="48"></svg>`), format: FormatSVG, width: 48, height: 48},
+		{name: "svg viewBox", data: []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" width="100%" viewBox="0 0 24 24"></svg>`), format: FormatSVG, width: 24, height: 24},
+		{name: "png too small", data: pngData(t, 8, 8), wantErrMsg: "between 16 and 1024"},
+		{name: "png too large", data: pngData(t, 2048, 16), wantErrMsg: "between 16 and 1024"},
+		{name: "gif", data: []byte("GIF89a\x10\x00\x10\x00"), wantErrMsg: "only PNG, SVG and WebP"},
+		{name: "svg without size", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), wantErrMsg: "viewBox"},
+		{name: "malformed svg", data: []byte(`<svg viewBox="0 0 24 24"><g></svg>`), wantErrMsg: "malformed SVG"},
+		{name: "html", data: []byte(`<html><svg viewBox="0 0 24 24"></svg></html>`), wantErrMsg: "root element must be svg"},
+		{name: "empty", data: nil, wantErrMsg: "empty file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icon, err := Process(tt.data, Limits{})
+			if tt.wantErrMsg != "" {
+				if !errors.Is(err, ErrInvalidIcon) || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("Process() error = %v, want %q", err, tt.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if icon.Format != tt.format || icon.Width != tt.width || icon.Height != tt.height {
+				t.Errorf("Process() = %s %dx%d, want %s %dx%d", icon.Format, icon.Width, icon.Height, tt.format, tt.width, tt.height)
+			}
+			if !ValidName(icon.Name) || !strings.HasSuffix(icon.Name, "."+tt.format) {
+				t.Errorf("Process() name = %q", icon.Name)
+			}
+		})
+	}
+}
+
+func TestProcessFileSizeLimit(t *testing.T) {
+	data := pngData(t, 32, 32)
+	if _, err := Process(data, Limits{MaxFileSize: len(data) - 1}); !errors.Is(err, ErrInvalidIcon) {
+		t.Errorf("Process() error = %v, want ErrInvalidIcon", err)
+	}
+	if _, err := Process(data, Limits{MaxFileSize: len(data)}); err != nil {
+		t.Errorf("Process() error = %v", err)
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "y">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:evil="http://example.com/ns" viewBox="0 0 24 24" onload="alert(1)">
+  <!-- comment -->
+  <script>alert(1)</script>
+  <style>.a{fill:url(#g)}</style>
+  <style>@import url(https://example.com/x.css);</style>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml">x</div></foreignObject>
+  <a href="javascript:alert(1)"><path d="M0 0"/></a>
+  <defs><linearGradient id="g"><stop offset="0" stop-color="#fff"/></linearGradient></defs>
+  <path class="a" d="M0 0h24v24H0z" fill="url(#g)" onclick="alert(1)" evil:attr="x"/>
+  <rect width="1" height="1" fill="url(https://example.com/track)" style="background:url(javascript:alert(1))"/>
+  <use href="#g" xlink:href="https://example.com/sprite.svg#i"/>
+  <image href="data:image/svg+xml;base64,PHN2Zz4=" width="1" height="1"/>
+  <image href="data:image/png;base64,iVBORw0KGgo=" width="1" height="1"/>
+</svg>`
+	out, err := SanitizeSVG([]byte(input))
+	if err != nil {
+		t.Fatalf("SanitizeSVG() error = %v", err)
+	}
+	got := string(out)
+	for _, banned := range []string{"script", "alert", "onload", "onclick", "foreignObject", "<a", "@import", "example.com", "evil", "ENTITY", "comment", "svg+xml"} {
+		if strings.Contains(got, banned) {
+			t.Errorf("SanitizeSVG() output contains %q:\n%s", banned, got)
+		}
+	}
+	for _, kept := range []string{`xmlns="http://www.w3.org/2000/svg"`, `xmlns:xlink=`, `viewBox="0 0 24 24"`, `<style>.a{fill:url(#g)}</style>`, `fill="url(#g)"`, `<use href="#g">`, `data:image/png;base64`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("SanitizeSVG() output is missing %q:\n%s", kept, got)
+		}
+	}
+	if _, err := SanitizeSVG(out); err != nil {
+		t.Errorf("SanitizeSVG() output is not well-formed: %v", err)
+	}
+}
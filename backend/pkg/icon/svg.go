@@ -0,0 +1,254 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// svgElements are the elements kept by SanitizeSVG, anything else is dropped together with its children.
+// Scripts, foreign objects, links and animations that can set attributes are deliberately absent.
+var svgElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "symbol": true, "use": true, "title": true, "desc": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true, "polyline": true, "polygon": true,
+	"text": true, "tspan": true, "textPath": true, "image": true, "style": true,
+	"linearGradient": true, "radialGradient": true, "stop": true, "pattern": true, "clipPath": true, "mask": true, "marker": true,
+	"filter": true, "feBlend": true, "feColorMatrix": true, "feComponentTransfer": true, "feComposite": true,
+	"feFlood": true, "feGaussianBlur": true, "feMerge": true, "feMergeNode": true, "feMorphology": true,
+	"feOffset": true, "feDropShadow": true, "feFuncA": true, "feFuncR": true, "feFuncG": true, "feFuncB": true,
+}
+
+// svgNamespaces are the namespaces an icon may declare.
+var svgNamespaces = map[string]bool{
+	"http://www.w3.org/2000/svg":   true,
+	"http://www.w3.org/1999/xlink": true,
+}
+
+var (
+	// urlRefRegexp matches url(...) references in attribute values and style sheets
+	urlRefRegexp = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")\s]*)`)
+	// dataImageRegexp matches inline raster images, SVG data URLs are rejected since they may carry scripts
+	dataImageRegexp = regexp.MustCompile(`(?i)^data:image/(png|jpe?g|gif|webp);base64,`)
+	// unsafeCSSRegexp matches style constructs that load resources or run code
+	unsafeCSSRegexp = regexp.MustCompile(`(?i)(@import|expression\s*\(|javascript:|behavior\s*:|-moz-binding)`)
+	// svgLengthRegexp matches absolute lengths in pixels
+	svgLengthRegexp = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*(px)?\s*$`)
+)
+
+// SanitizeSVG re-serializes the SVG markup keeping only allow-listed elements and attributes.
+// Event handler attributes, external references, scripts, foreign objects, DTDs and comments are removed.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+
+	var (
+		out bytes.Buffer
+		// stack holds the names of the open elements that were written
+		stack []xml.Name
+		// skip counts the nesting depth inside a dropped element
+		skip int
+		// style collects the content of a style element until it can be checked
+		style   *bytes.Buffer
+		hasRoot bool
+	)
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed SVG: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			if !hasRoot {
+				if t.Name.Space != "" || t.Name.Local != "svg" {
+					return nil, fmt.Errorf("root element must be svg, got %s", t.Name.Local)
+				}
+				hasRoot = true
+			} else if len(stack) == 0 {
+				return nil, fmt.Errorf("malformed SVG: multiple root elements")
+			}
+			if t.Name.Space != "" || !svgElements[t.Name.Local] {
+				skip = 1
+				continue
+			}
+			if t.Name.Local == "style" {
+				style = &bytes.Buffer{}
+			}
+			writeStart(&out, t.Name.Local, sanitizeAttrs(t.Name.Local, t.Attr))
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("malformed SVG: unexpected end element %s", t.Name.Local)
+			}
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if name.Local == "style" && style != nil {
+				if css := style.String(); !unsafeCSSRegexp.MatchString(css) && safeURLRefs(css) {
+					xml.EscapeText(&out, []byte(css))
+				}
+				style = nil
+			}
+			out.WriteString("</" + name.Local + ">")
+		case xml.CharData:
+			if skip > 0 || len(stack) == 0 {
+				continue
+			}
+			if style != nil {
+				style.Write(t)
+				continue
+			}
+			xml.EscapeText(&out, t)
+		}
+		// comments, processing instructions and DTDs are dropped
+	}
+
+	if !hasRoot {
+		return nil, fmt.Errorf("no svg element found")
+	}
+	if len(stack) > 0 || skip > 0 {
+		return nil, fmt.Errorf("malformed SVG: unclosed element")
+	}
+	return out.Bytes(), nil
+}
+
+// sanitizeAttrs keeps presentation attributes, dropping event handlers and unsafe references.
+func sanitizeAttrs(element string, attrs []xml.Attr) []xml.Attr {
+	kept := make([]xml.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		name := strings.ToLower(attr.Name.Local)
+		switch attr.Name.Space {
+		case "":
+			if name == "xmlns" && !svgNamespaces[attr.Value] {
+				continue
+			}
+		case "xmlns":
+			if !svgNamespaces[attr.Value] {
+				continue
+			}
+		case "xml":
+		case "xlink":
+			if name != "href" {
+				continue
+			}
+		default:
+			continue
+		}
+		if strings.HasPrefix(name, "on") {
+			continue
+		}
+		if name == "href" && !safeHref(element, attr.Value) {
+			continue
+		}
+		if unsafeCSSRegexp.MatchString(strings.Join(strings.Fields(attr.Value), "")) || !safeURLRefs(attr.Value) {
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// safeHref allows fragment references, and inline raster images for image elements.
+func safeHref(element, value string) bool {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	return element == "image" && dataImageRegexp.MatchString(value)
+}
+
+// safeURLRefs reports whether every url(...) in value points to a fragment of the same document.
+func safeURLRefs(value string) bool {
+	for _, match := range urlRefRegexp.FindAllStringSubmatch(value, -1) {
+		if !strings.HasPrefix(match[1], "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// writeStart writes a start tag with escaped attribute values.
+func writeStart(out *bytes.Buffer, name string, attrs []xml.Attr) {
+	out.WriteString("<" + name)
+	for _, attr := range attrs {
+		out.WriteByte(' ')
+		if attr.Name.Space != "" {
+			out.WriteString(attr.Name.Space + ":")
+		}
+		out.WriteString(attr.Name.Local + `="`)
+		xml.EscapeText(out, []byte(attr.Value))
+		out.WriteByte('"')
+	}
+	out.WriteByte('>')
+}
+
+// svgSize reads the dimensions from the width and height attributes of the root element,
+// falling back to the viewBox when they are missing or relative.
+func svgSize(data []byte) (int, int, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed SVG: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var width, height, viewBox string
+		for _, attr := range start.Attr {
+			if attr.Name.Space != "" {
+				continue
+			}
+			switch attr.Name.Local {
+			case "width":
+				width = attr.Value
+			case "height":
+				height = attr.Value
+			case "viewBox":
+				viewBox = attr.Value
+			}
+		}
+		if w, h := svgLength(width), svgLength(height); w > 0 && h > 0 {
+			return w, h, nil
+		}
+		fields := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' })
+		if len(fields) == 4 {
+			w, errW := strconv.ParseFloat(fields[2], 64)
+			h, errH := strconv.ParseFloat(fields[3], 64)
+			if errW == nil && errH == nil && w > 0 && h > 0 {
+				return int(math.Ceil(w)), int(math.Ceil(h)), nil
+			}
+		}
+		return 0, 0, fmt.Errorf("SVG must declare width and height or a viewBox")
+	}
+}
+
+// svgLength parses a pixel length, returning 0 for missing or relative lengths.
+func svgLength(value string) int {
+	match := svgLengthRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Ceil(f))
+}
@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -13,8 +14,21 @@ import (
 // 具体资源操作通过子管理器（如 PodManager）实现
 
 type Client struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	namespace string
+	logger    *zap.Logger
+}
+
+// ClientOption Client 初始化选项
+type ClientOption func(*Client)
+
+// WithLogger 注入结构化日志实例，未注入时使用 no-op 日志，不输出任何内容
+func WithLogger(logger *zap.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
 }
 
 // 获取 Pod 管理器，支持创建、删除、等待就绪、获取状态等操作
@@ -37,11 +51,44 @@ func (c *Client) Volume() *VolumeManager {
 	return &VolumeManager{client: c}
 }
 
+// 获取 Secret 管理器，支持镜像拉取密钥等 Secret 的查询
+func (c *Client) Secret() *SecretManager {
+	return &SecretManager{client: c}
+}
+
 // 获取 Node 管理器，支持节点的查询等操作
 func (c *Client) Node() *NodeManager {
 	return &NodeManager{client: c}
 }
 
+// 获取 PodDisruptionBudget 管理器，限制节点排空等主动驱逐
+func (c *Client) PodDisruptionBudget() *PodDisruptionBudgetManager {
+	return &PodDisruptionBudgetManager{client: c}
+}
+
+// 获取 NetworkPolicy 管理器，限制托管实例的出站流量
+func (c *Client) NetworkPolicy() *NetworkPolicyManager {
+	return &NetworkPolicyManager{client: c}
+}
+
+// 获取 ServiceAccount 管理器，为托管实例创建无权限的专用账号
+func (c *Client) ServiceAccount() *ServiceAccountManager {
+	return &ServiceAccountManager{client: c}
+}
+
+// 获取指标管理器，通过 metrics.k8s.io 查询 Pod 资源使用量
+func (c *Client) Metrics() *MetricsManager {
+	return &MetricsManager{client: c}
+}
+
+// Logger 获取日志实例
+func (c *Client) Logger() *zap.Logger {
+	if c.logger == nil {
+		return zap.NewNop()
+	}
+	return c.logger
+}
+
 // GetNamespace 获取当前命名空间
 func (c *Client) GetNamespace() string {
 	return c.namespace
@@ -69,7 +116,7 @@ func (c *Client) ListNamespaces() ([]string, error) {
 }
 
 // NewClient 通过 kubeconfig 内容和 namespace 初始化 Client
-func NewClient(config *rest.Config, namespace string) (*Client, error) {
+func NewClient(config *rest.Config, namespace string, opts ...ClientOption) (*Client, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -80,5 +127,15 @@ func NewClient(config *rest.Config, namespace string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{clientset: clientset, namespace: namespace}, nil
+	return NewClientForClientset(clientset, namespace, opts...), nil
+}
+
+// NewClientForClientset 使用已有的 clientset 初始化 Client，不校验 namespace 是否存在
+// 便于复用已创建的 clientset 或在测试中注入 fake clientset
+func NewClientForClientset(clientset kubernetes.Interface, namespace string, opts ...ClientOption) *Client {
+	client := &Client{clientset: clientset, namespace: namespace, logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
@@ -3,12 +3,14 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // DeploymentManager 负责 Deployment 相关操作
@@ -16,6 +18,41 @@ type DeploymentManager struct {
 	client *Client
 }
 
+// InitSharedVolumeName 初始化容器与主容器共享的 emptyDir 卷名称
+const InitSharedVolumeName = "init-shared"
+
+// DefaultInitSharedPath 共享卷默认挂载路径
+const DefaultInitSharedPath = "/mcp-init"
+
+// InitContainerOptions 初始化容器配置，在主容器启动前按顺序执行
+type InitContainerOptions struct {
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	Command []string          `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	EnvVars map[string]string `json:"envVars,omitempty"`
+}
+
+// SidecarContainerOptions 边车容器配置，与主容器在同一 Pod 中运行，共享网络命名空间
+type SidecarContainerOptions struct {
+	Name             string            `json:"name"`
+	Image            string            `json:"image"`
+	Command          []string          `json:"command,omitempty"`
+	Args             []string          `json:"args,omitempty"`
+	EnvVars          map[string]string `json:"envVars,omitempty"`
+	Ports            []int32           `json:"ports,omitempty"`
+	ResourceRequests map[string]string `json:"resourceRequests,omitempty"`
+	ResourceLimits   map[string]string `json:"resourceLimits,omitempty"`
+}
+
+// SecretFile 将 Secret 中的单个键以只读文件方式挂载到主容器
+type SecretFile struct {
+	SecretName string `json:"secretName"`
+	Key        string `json:"key"`
+	MountPath  string `json:"mountPath"`      // 文件在容器内的完整路径
+	Mode       int32  `json:"mode,omitempty"` // 文件权限，默认 0644
+}
+
 // DeploymentCreateOptions Deployment 创建选项
 type DeploymentCreateOptions struct {
 	ImageName string `json:"imageName"`
@@ -39,16 +76,55 @@ type DeploymentCreateOptions struct {
 	// 卷挂载配置
 	VolumeMounts []UnifiedMount `json:"volumeMounts,omitempty"`
 
+	// 以文件方式挂载的 Secret 键
+	SecretFiles []SecretFile `json:"secretFiles,omitempty"`
+
 	// 健康检查
 	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
 	LivenessProbe  *corev1.Probe `json:"livenessProbe,omitempty"`
 
+	// 初始化容器，与主容器通过挂载在 InitSharedPath 的 emptyDir 卷共享文件
+	InitContainers []InitContainerOptions `json:"initContainers,omitempty"`
+	InitSharedPath string                 `json:"initSharedPath,omitempty"` // 默认为 DefaultInitSharedPath
+
+	// 边车容器，排在主容器之后，Pod 就绪需要所有容器就绪
+	Sidecars []SidecarContainerOptions `json:"sidecars,omitempty"`
+
 	// 镜像拉取
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
 
+	// 节点选择器
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
 	// 资源限制
 	ResourceRequests map[string]string `json:"resourceRequests,omitempty"`
 	ResourceLimits   map[string]string `json:"resourceLimits,omitempty"`
+
+	// Pod 优先级类，集群资源不足时优先调度，并降低被抢占的可能
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// 与其他 Pod 的亲和/反亲和规则
+	PodAffinity []PodAffinityTerm `json:"podAffinity,omitempty"`
+
+	// Pod 使用的服务账号，为空时使用命名空间的 default 账号
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// 为 true 时不向 Pod 挂载服务账号令牌
+	DisableTokenMount bool `json:"disableTokenMount,omitempty"`
+}
+
+// DefaultTopologyKey 亲和规则默认的拓扑域，按节点判断
+const DefaultTopologyKey = "kubernetes.io/hostname"
+
+// PodAffinityTerm 与其他 Pod 的亲和/反亲和规则，MatchLabels 和 MatchIn 同时满足的 Pod 为目标
+type PodAffinityTerm struct {
+	// Anti 为 true 时不与目标 Pod 调度到同一拓扑域
+	Anti bool `json:"anti,omitempty"`
+	// Required 为 true 时为硬性要求，否则按 Weight 优先
+	Required    bool                `json:"required,omitempty"`
+	MatchLabels map[string]string   `json:"matchLabels,omitempty"`
+	MatchIn     map[string][]string `json:"matchIn,omitempty"` // 标签值为其中之一
+	TopologyKey string              `json:"topologyKey,omitempty"`
+	Weight      int32               `json:"weight,omitempty"` // 非硬性要求的权重 1-100，默认 100
 }
 
 // Create 创建 Deployment
@@ -77,6 +153,13 @@ func (dm *DeploymentManager) Create(options DeploymentCreateOptions) (string, er
 		return "", err
 	}
 
+	// 构建初始化容器及共享卷
+	initContainers, sharedVolume, sharedMount := dm.buildInitContainers(options)
+	if sharedVolume != nil {
+		volumes = append(volumes, *sharedVolume)
+		volumeMounts = append(volumeMounts, *sharedMount)
+	}
+
 	// 构建容器
 	container := dm.buildContainer(options, volumeMounts)
 
@@ -103,21 +186,38 @@ func (dm *DeploymentManager) Create(options DeploymentCreateOptions) (string, er
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					Containers:       []corev1.Container{container},
-					Volumes:          volumes,
-					RestartPolicy:    corev1.RestartPolicyAlways, // Deployment 中总是 Always
-					ImagePullSecrets: dm.buildImagePullSecrets(options.ImagePullSecrets),
+					InitContainers:    initContainers,
+					Containers:        append([]corev1.Container{container}, dm.buildSidecars(options)...),
+					Volumes:           volumes,
+					RestartPolicy:     corev1.RestartPolicyAlways, // Deployment 中总是 Always
+					ImagePullSecrets:  dm.buildImagePullSecrets(options.ImagePullSecrets),
+					NodeSelector:      options.NodeSelector,
+					PriorityClassName: options.PriorityClassName,
 				},
 			},
 		},
 	}
 
+	// 服务账号及令牌挂载
+	deployment.Spec.Template.Spec.ServiceAccountName = options.ServiceAccountName
+	if options.DisableTokenMount {
+		automount := false
+		deployment.Spec.Template.Spec.AutomountServiceAccountToken = &automount
+	}
+
 	// 如果有节点亲和性，设置到 PodSpec 中
 	if nodeAffinity != nil {
 		deployment.Spec.Template.Spec.Affinity = &corev1.Affinity{
 			NodeAffinity: nodeAffinity,
 		}
 	}
+	if podAffinity, podAntiAffinity := dm.buildPodAffinity(options.PodAffinity); podAffinity != nil || podAntiAffinity != nil {
+		if deployment.Spec.Template.Spec.Affinity == nil {
+			deployment.Spec.Template.Spec.Affinity = &corev1.Affinity{}
+		}
+		deployment.Spec.Template.Spec.Affinity.PodAffinity = podAffinity
+		deployment.Spec.Template.Spec.Affinity.PodAntiAffinity = podAntiAffinity
+	}
 
 	// 创建 Deployment
 	createdDeployment, err := dm.client.clientset.AppsV1().Deployments(targetNamespace).Create(
@@ -147,6 +247,16 @@ func (dm *DeploymentManager) Get(deploymentName string) (*appsv1.Deployment, err
 		context.Background(), deploymentName, metav1.GetOptions{})
 }
 
+// List 列出命名空间中带有全部指定标签的 Deployment
+func (dm *DeploymentManager) List(selector map[string]string) ([]appsv1.Deployment, error) {
+	list, err := dm.client.clientset.AppsV1().Deployments(dm.client.namespace).List(
+		context.Background(), metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
 // Scale 设置 Deployment 副本数
 func (dm *DeploymentManager) Scale(deploymentName string, replicas int32) error {
 	// 获取当前 Deployment
@@ -322,6 +432,17 @@ func (dm *DeploymentManager) GetPods(deploymentName string) ([]corev1.Pod, error
 	return podList.Items, nil
 }
 
+// ReplicaFailures 返回 Deployment 无法创建 Pod 的原因，如超出 ResourceQuota 或优先级类不存在，没有时返回 nil
+func ReplicaFailures(deployment *appsv1.Deployment) []string {
+	var failures []string
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue {
+			failures = append(failures, fmt.Sprintf("pods cannot be created: %s", cond.Message))
+		}
+	}
+	return failures
+}
+
 // GetPodIPs 获取 Deployment 管理的 Pod IP 列表
 func (dm *DeploymentManager) GetPodIPs(deploymentName string) ([]string, error) {
 	pods, err := dm.GetPods(deploymentName)
@@ -347,6 +468,25 @@ func (dm *DeploymentManager) validateCreateOptions(options DeploymentCreateOptio
 	if options.AppName == "" {
 		return fmt.Errorf("应用名称不能为空")
 	}
+	names := map[string]bool{options.AppName: true}
+	for _, ic := range options.InitContainers {
+		if ic.Name == "" || ic.Image == "" {
+			return fmt.Errorf("初始化容器名称和镜像不能为空")
+		}
+		if names[ic.Name] {
+			return fmt.Errorf("容器名称重复: %s", ic.Name)
+		}
+		names[ic.Name] = true
+	}
+	for _, sc := range options.Sidecars {
+		if sc.Name == "" || sc.Image == "" {
+			return fmt.Errorf("边车容器名称和镜像不能为空")
+		}
+		if names[sc.Name] {
+			return fmt.Errorf("容器名称重复: %s", sc.Name)
+		}
+		names[sc.Name] = true
+	}
 	return nil
 }
 
@@ -429,6 +569,71 @@ func (dm *DeploymentManager) buildContainer(options DeploymentCreateOptions, vol
 	return container
 }
 
+// buildInitContainers 构建初始化容器，未配置时返回 nil
+// 所有初始化容器和主容器挂载同一个 emptyDir 卷，用于传递依赖安装结果等文件
+func (dm *DeploymentManager) buildInitContainers(options DeploymentCreateOptions) ([]corev1.Container, *corev1.Volume, *corev1.VolumeMount) {
+	if len(options.InitContainers) == 0 {
+		return nil, nil, nil
+	}
+	sharedPath := options.InitSharedPath
+	if sharedPath == "" {
+		sharedPath = DefaultInitSharedPath
+	}
+	sharedMount := corev1.VolumeMount{Name: InitSharedVolumeName, MountPath: sharedPath}
+	sharedVolume := corev1.Volume{
+		Name:         InitSharedVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+
+	containers := make([]corev1.Container, 0, len(options.InitContainers))
+	for _, ic := range options.InitContainers {
+		container := corev1.Container{
+			Name:         ic.Name,
+			Image:        ic.Image,
+			Command:      ic.Command,
+			Args:         ic.Args,
+			VolumeMounts: []corev1.VolumeMount{sharedMount},
+		}
+		container.Env = buildEnvVars(ic.EnvVars)
+		containers = append(containers, container)
+	}
+	return containers, &sharedVolume, &sharedMount
+}
+
+// buildSidecars 构建边车容器
+func (dm *DeploymentManager) buildSidecars(options DeploymentCreateOptions) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(options.Sidecars))
+	for _, sc := range options.Sidecars {
+		container := corev1.Container{
+			Name:      sc.Name,
+			Image:     sc.Image,
+			Command:   sc.Command,
+			Args:      sc.Args,
+			Env:       buildEnvVars(sc.EnvVars),
+			Resources: buildResources(sc.ResourceRequests, sc.ResourceLimits),
+		}
+		for _, port := range sc.Ports {
+			container.Ports = append(container.Ports, corev1.ContainerPort{ContainerPort: port, Protocol: corev1.ProtocolTCP})
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
+
+// buildEnvVars 按名称排序构建环境变量，保证相同配置生成相同的 Pod 模板
+func buildEnvVars(envVars map[string]string) []corev1.EnvVar {
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var env []corev1.EnvVar
+	for _, key := range keys {
+		env = append(env, corev1.EnvVar{Name: key, Value: envVars[key]})
+	}
+	return env
+}
+
 // buildVolumes 构建卷和卷挂载
 func (dm *DeploymentManager) buildVolumes(options DeploymentCreateOptions) ([]corev1.Volume, []corev1.VolumeMount, error) {
 	var volumes []corev1.Volume
@@ -484,6 +689,35 @@ func (dm *DeploymentManager) buildVolumes(options DeploymentCreateOptions) ([]co
 		}
 	}
 
+	// 处理 SecretFiles，通过 subPath 挂载单个文件，不覆盖目标目录中的其他内容
+	for i, sf := range options.SecretFiles {
+		if sf.SecretName == "" || sf.Key == "" || sf.MountPath == "" {
+			return nil, nil, fmt.Errorf("Secret 文件的名称、键和挂载路径不能为空")
+		}
+		volumeName := fmt.Sprintf("secret-file-%d", i)
+		mode := sf.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: sf.SecretName,
+					Items:      []corev1.KeyToPath{{Key: sf.Key, Path: sf.Key, Mode: &mode}},
+				},
+			},
+		})
+
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: sf.MountPath,
+			SubPath:   sf.Key,
+			ReadOnly:  true,
+		})
+	}
+
 	return volumes, volumeMounts, nil
 }
 
@@ -500,20 +734,25 @@ func (dm *DeploymentManager) buildImagePullSecrets(secrets []string) []corev1.Lo
 
 // buildResourceRequirements 构建资源需求
 func (dm *DeploymentManager) buildResourceRequirements(options DeploymentCreateOptions) corev1.ResourceRequirements {
+	return buildResources(options.ResourceRequests, options.ResourceLimits)
+}
+
+// buildResources 根据资源请求和限制构建容器资源配置
+func buildResources(requests, limits map[string]string) corev1.ResourceRequirements {
 	requirements := corev1.ResourceRequirements{}
 
 	// 设置资源请求
-	if len(options.ResourceRequests) > 0 {
+	if len(requests) > 0 {
 		requirements.Requests = corev1.ResourceList{}
-		for k, v := range options.ResourceRequests {
+		for k, v := range requests {
 			requirements.Requests[corev1.ResourceName(k)] = parseQuantity(v)
 		}
 	}
 
 	// 设置资源限制
-	if len(options.ResourceLimits) > 0 {
+	if len(limits) > 0 {
 		requirements.Limits = corev1.ResourceList{}
-		for k, v := range options.ResourceLimits {
+		for k, v := range limits {
 			requirements.Limits[corev1.ResourceName(k)] = parseQuantity(v)
 		}
 	}
@@ -596,6 +835,59 @@ func (dm *DeploymentManager) buildAutoNodeAffinity(options DeploymentCreateOptio
 	return nil, nil
 }
 
+// buildPodAffinity 将亲和规则转换为 Pod 亲和性和反亲和性，没有对应规则时返回 nil
+func (dm *DeploymentManager) buildPodAffinity(terms []PodAffinityTerm) (*corev1.PodAffinity, *corev1.PodAntiAffinity) {
+	var affinity corev1.PodAffinity
+	var antiAffinity corev1.PodAntiAffinity
+	for _, t := range terms {
+		selector := &metav1.LabelSelector{MatchLabels: t.MatchLabels}
+		keys := make([]string, 0, len(t.MatchIn))
+		for key := range t.MatchIn {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      key,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   t.MatchIn[key],
+			})
+		}
+		term := corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: t.TopologyKey}
+		if term.TopologyKey == "" {
+			term.TopologyKey = DefaultTopologyKey
+		}
+
+		if t.Required {
+			if t.Anti {
+				antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+			} else {
+				affinity.RequiredDuringSchedulingIgnoredDuringExecution = append(affinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+			}
+			continue
+		}
+		weighted := corev1.WeightedPodAffinityTerm{Weight: t.Weight, PodAffinityTerm: term}
+		if weighted.Weight <= 0 {
+			weighted.Weight = 100
+		}
+		if t.Anti {
+			antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, weighted)
+		} else {
+			affinity.PreferredDuringSchedulingIgnoredDuringExecution = append(affinity.PreferredDuringSchedulingIgnoredDuringExecution, weighted)
+		}
+	}
+
+	var podAffinity *corev1.PodAffinity
+	if len(affinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 || len(affinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0 {
+		podAffinity = &affinity
+	}
+	var podAntiAffinity *corev1.PodAntiAffinity
+	if len(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 || len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0 {
+		podAntiAffinity = &antiAffinity
+	}
+	return podAffinity, podAntiAffinity
+}
+
 // buildFlexibleNodeAffinity 构建节点亲和性策略
 // 使用硬亲和性，必须调度到指定节点
 func (dm *DeploymentManager) buildFlexibleNodeAffinity(nodeNames []string) *corev1.NodeAffinity {
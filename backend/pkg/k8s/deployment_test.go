@@ -0,0 +1,308 @@
+package k8s_test
+
+import (
+	"context"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateWithInitContainers(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName: "mcp/server:1.0",
+		AppName:   "mcp-app",
+		InitContainers: []k8s.InitContainerOptions{{
+			Name:    "deps",
+			Image:   "node:20",
+			Command: []string{"sh", "-c"},
+			Args:    []string{"npm install --prefix /mcp-init"},
+			EnvVars: map[string]string{"B": "2", "A": "1"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	if len(spec.InitContainers) != 1 {
+		t.Fatalf("InitContainers = %d, want 1", len(spec.InitContainers))
+	}
+	ic := spec.InitContainers[0]
+	if ic.Image != "node:20" || len(ic.Env) != 2 || ic.Env[0].Name != "A" {
+		t.Errorf("init container = %+v, want image node:20 and env sorted by name", ic)
+	}
+	if len(ic.VolumeMounts) != 1 || ic.VolumeMounts[0].MountPath != k8s.DefaultInitSharedPath {
+		t.Errorf("init container mounts = %+v, want shared volume at %s", ic.VolumeMounts, k8s.DefaultInitSharedPath)
+	}
+	main := spec.Containers[0]
+	if len(main.VolumeMounts) != 1 || main.VolumeMounts[0].Name != k8s.InitSharedVolumeName {
+		t.Errorf("main container mounts = %+v, want shared volume", main.VolumeMounts)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("volumes = %+v, want one emptyDir", spec.Volumes)
+	}
+
+	if _, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName:      "mcp/server:1.0",
+		AppName:        "dup",
+		InitContainers: []k8s.InitContainerOptions{{Name: "dup", Image: "busybox"}},
+	}); err == nil {
+		t.Error("Create() with init container named like the main container, want error")
+	}
+}
+
+func TestCreateWithSidecars(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName: "mcp/server:1.0",
+		AppName:   "mcp-app",
+		Sidecars: []k8s.SidecarContainerOptions{{
+			Name:           "token-refresher",
+			Image:          "oauth/refresher:1.0",
+			Ports:          []int32{9090},
+			ResourceLimits: map[string]string{"memory": "64Mi"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != 2 || containers[0].Name != "mcp-app" || containers[1].Name != "token-refresher" {
+		t.Fatalf("containers = %+v, want main container followed by the sidecar", containers)
+	}
+	sidecar := containers[1]
+	if len(sidecar.Ports) != 1 || sidecar.Ports[0].ContainerPort != 9090 {
+		t.Errorf("sidecar ports = %+v, want 9090", sidecar.Ports)
+	}
+	if got := sidecar.Resources.Limits.Memory().String(); got != "64Mi" {
+		t.Errorf("sidecar memory limit = %s, want 64Mi", got)
+	}
+}
+
+// 托管实例的 Pod 不占用节点端口，同端口的实例之间不会冲突
+func TestCreateWithoutHostPorts(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName:      "mcp/server:1.0",
+		AppName:        "mcp-app",
+		Port:           8080,
+		InitContainers: []k8s.InitContainerOptions{{Name: "deps", Image: "node:20"}},
+		Sidecars:       []k8s.SidecarContainerOptions{{Name: "token-refresher", Image: "oauth/refresher:1.0", Ports: []int32{9090}}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	if spec.HostNetwork {
+		t.Error("HostNetwork = true, want pods in their own network namespace")
+	}
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				t.Errorf("container %s port %d HostPort = %d, want 0", container.Name, port.ContainerPort, port.HostPort)
+			}
+		}
+	}
+}
+
+func TestCreateWithSecretFiles(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName: "mcp/server:1.0",
+		AppName:   "mcp-app",
+		SecretFiles: []k8s.SecretFile{
+			{SecretName: "mcp-app-files", Key: "startup.sh", MountPath: "/app/init/startup.sh", Mode: 0755},
+			{SecretName: "mcp-app-files", Key: "mcp-servers.json", MountPath: "/app/mcp-servers.json"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	if len(spec.Volumes) != 2 {
+		t.Fatalf("volumes = %d, want 2", len(spec.Volumes))
+	}
+	script := spec.Volumes[0].Secret
+	if script == nil || script.SecretName != "mcp-app-files" || len(script.Items) != 1 || *script.Items[0].Mode != 0755 {
+		t.Errorf("startup script volume = %+v, want secret item with mode 0755", script)
+	}
+	if mode := *spec.Volumes[1].Secret.Items[0].Mode; mode != 0644 {
+		t.Errorf("config volume mode = %o, want 0644", mode)
+	}
+	mounts := spec.Containers[0].VolumeMounts
+	if len(mounts) != 2 || mounts[0].MountPath != "/app/init/startup.sh" || mounts[0].SubPath != "startup.sh" || !mounts[0].ReadOnly {
+		t.Errorf("main container mounts = %+v, want read-only subPath mounts", mounts)
+	}
+
+	if _, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName:   "mcp/server:1.0",
+		AppName:     "bad",
+		SecretFiles: []k8s.SecretFile{{SecretName: "bad-files", MountPath: "/app/x"}},
+	}); err == nil {
+		t.Error("Create() with a secret file without key, want error")
+	}
+}
+
+func TestCreateWithNodeSelectorAndResources(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName:      "mcp/server:1.0",
+		AppName:        "mcp-app",
+		NodeSelector:   map[string]string{"pool": "mcp"},
+		ResourceLimits: map[string]string{"memory": "512Mi"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	if spec.NodeSelector["pool"] != "mcp" {
+		t.Errorf("node selector = %v, want pool=mcp", spec.NodeSelector)
+	}
+	if got := spec.Containers[0].Resources.Limits.Memory().String(); got != "512Mi" {
+		t.Errorf("memory limit = %s, want 512Mi", got)
+	}
+}
+
+func TestCreateWithPriorityClass(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	if _, err := dm.Create(k8s.DeploymentCreateOptions{ImageName: "mcp/server:1.0", AppName: "mcp-app", PriorityClassName: "mcp-critical"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := deployment.Spec.Template.Spec.PriorityClassName; got != "mcp-critical" {
+		t.Errorf("PriorityClassName = %q, want mcp-critical", got)
+	}
+}
+
+func TestCreateWithPodAffinity(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	_, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName: "mcp/server:1.0",
+		AppName:   "mcp-app",
+		PodAffinity: []k8s.PodAffinityTerm{
+			{Required: true, MatchIn: map[string][]string{"instance": {"a", "b"}}},
+			{Anti: true, MatchLabels: map[string]string{"mcp.user/tier": "heavy"}, TopologyKey: "topology.kubernetes.io/zone", Weight: 50},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	affinity := deployment.Spec.Template.Spec.Affinity
+	if affinity == nil || affinity.PodAffinity == nil || affinity.PodAntiAffinity == nil {
+		t.Fatalf("affinity = %+v, want pod affinity and anti-affinity", affinity)
+	}
+
+	required := affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required) != 1 || required[0].TopologyKey != k8s.DefaultTopologyKey {
+		t.Fatalf("required affinity = %+v, want one term on %s", required, k8s.DefaultTopologyKey)
+	}
+	expr := required[0].LabelSelector.MatchExpressions
+	if len(expr) != 1 || expr[0].Key != "instance" || expr[0].Operator != metav1.LabelSelectorOpIn || len(expr[0].Values) != 2 {
+		t.Errorf("required selector = %+v, want instance In [a b]", expr)
+	}
+
+	preferred := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(preferred) != 1 || preferred[0].Weight != 50 || preferred[0].PodAffinityTerm.TopologyKey != "topology.kubernetes.io/zone" {
+		t.Errorf("preferred anti-affinity = %+v, want weight 50 on zone", preferred)
+	}
+	if preferred[0].PodAffinityTerm.LabelSelector.MatchLabels["mcp.user/tier"] != "heavy" {
+		t.Errorf("anti-affinity selector = %+v, want tier label", preferred[0].PodAffinityTerm.LabelSelector)
+	}
+}
+
+func TestListDeployments(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	for name, labels := range map[string]map[string]string{
+		"managed":   {"managed-by": "qm-mcp-server", "instance": "a"},
+		"unmanaged": {"instance": "b"},
+	} {
+		if _, err := dm.Create(k8s.DeploymentCreateOptions{ImageName: "mcp/server:1.0", AppName: name, Labels: labels}); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+	}
+
+	deployments, err := dm.List(map[string]string{"managed-by": "qm-mcp-server"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(deployments) != 1 || deployments[0].Name != "managed" {
+		t.Errorf("List() = %d deployments, want only managed", len(deployments))
+	}
+}
+
+func TestCreateWithServiceAccount(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	dm := k8s.NewClientForClientset(clientset, testNamespace).Deployment()
+
+	if _, err := dm.Create(k8s.DeploymentCreateOptions{
+		ImageName:          "mcp/server:1.0",
+		AppName:            "mcp-app",
+		ServiceAccountName: "mcp-app",
+		DisableTokenMount:  true,
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(testNamespace).Get(context.Background(), "mcp-app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	spec := deployment.Spec.Template.Spec
+	if spec.ServiceAccountName != "mcp-app" {
+		t.Errorf("ServiceAccountName = %q, want mcp-app", spec.ServiceAccountName)
+	}
+	if spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken {
+		t.Errorf("AutomountServiceAccountToken = %v, want false", spec.AutomountServiceAccountToken)
+	}
+}
@@ -13,13 +13,14 @@ type Entry struct {
 	Service    *ServiceManager
 	Volume     *VolumeManager
 	Node       *NodeManager
+	Secret     *SecretManager
 }
 
 var K8sEntry *Entry
 
 // NewEntry 初始化 Entry，注入 PodManager、ServiceManager 和 VolumeManager
-func NewEntry(kubeconfig *rest.Config, namespace string) (*Entry, error) {
-	client, err := NewClient(kubeconfig, namespace)
+func NewEntry(kubeconfig *rest.Config, namespace string, opts ...ClientOption) (*Entry, error) {
+	client, err := NewClient(kubeconfig, namespace, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,5 +32,6 @@ func NewEntry(kubeconfig *rest.Config, namespace string) (*Entry, error) {
 		Service:    client.Service(),
 		Volume:     client.Volume(),
 		Node:       client.Node(),
+		Secret:     client.Secret(),
 	}, nil
 }
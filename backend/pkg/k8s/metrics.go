@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ErrMetricsUnavailable 集群没有提供 metrics.k8s.io（通常是未安装 metrics-server）
+var ErrMetricsUnavailable = errors.New("metrics API (metrics.k8s.io) is not available, check that metrics-server is installed")
+
+// metricsPodsPath metrics.k8s.io Pod 指标路径
+const metricsPodsPath = "/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods"
+
+// MetricsManager 负责查询 metrics.k8s.io 资源使用量
+// 直接请求聚合 API 并解析响应，不依赖 metrics 客户端
+type MetricsManager struct {
+	client *Client
+}
+
+// PodUsage Pod 当前资源使用量，为所有容器之和
+type PodUsage struct {
+	Name   string
+	Labels map[string]string
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// podMetricsList metrics.k8s.io/v1beta1 PodMetricsList 中用到的字段
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// ListPodUsage 查询命名空间中带有全部指定标签的 Pod 的当前资源使用量。
+// 指标 API 未注册或不可用时返回包装了 ErrMetricsUnavailable 的错误
+func (mm *MetricsManager) ListPodUsage(ctx context.Context, selector map[string]string) ([]PodUsage, error) {
+	data, err := mm.client.clientset.CoreV1().RESTClient().Get().
+		AbsPath(fmt.Sprintf(metricsPodsPath, mm.client.namespace)).
+		Param("labelSelector", labels.SelectorFromSet(selector).String()).
+		DoRaw(ctx)
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+			return nil, fmt.Errorf("%w: %v", ErrMetricsUnavailable, err)
+		}
+		return nil, err
+	}
+
+	var list podMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod metrics: %w", err)
+	}
+	usages := make([]PodUsage, 0, len(list.Items))
+	for _, item := range list.Items {
+		usage := PodUsage{Name: item.Metadata.Name, Labels: item.Metadata.Labels}
+		for _, c := range item.Containers {
+			usage.CPU.Add(c.Usage[corev1.ResourceCPU])
+			usage.Memory.Add(c.Usage[corev1.ResourceMemory])
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
@@ -0,0 +1,69 @@
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newMetricsClient 创建请求 handler 的客户端，fake clientset 不支持聚合 API 请求
+func newMetricsClient(t *testing.T, handler http.HandlerFunc) *k8s.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("NewForConfig() error = %v", err)
+	}
+	return k8s.NewClientForClientset(clientset, testNamespace)
+}
+
+func TestListPodUsage(t *testing.T) {
+	client := newMetricsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/metrics.k8s.io/v1beta1/namespaces/mcp/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.URL.Query().Get("labelSelector"); got != "managed-by=qm-mcp-server" {
+			t.Errorf("labelSelector = %q, want managed-by=qm-mcp-server", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"PodMetricsList","apiVersion":"metrics.k8s.io/v1beta1","items":[
+			{"metadata":{"name":"a-1","labels":{"instance":"a"}},"containers":[
+				{"name":"main","usage":{"cpu":"150m","memory":"64Mi"}},
+				{"name":"sidecar","usage":{"cpu":"50m","memory":"16Mi"}}]}]}`))
+	})
+
+	usages, err := client.Metrics().ListPodUsage(context.Background(), map[string]string{"managed-by": "qm-mcp-server"})
+	if err != nil {
+		t.Fatalf("ListPodUsage() error = %v", err)
+	}
+	if len(usages) != 1 {
+		t.Fatalf("ListPodUsage() = %d pods, want 1", len(usages))
+	}
+	usage := usages[0]
+	if usage.Name != "a-1" || usage.Labels["instance"] != "a" {
+		t.Errorf("pod = %s %v, want a-1 with instance label", usage.Name, usage.Labels)
+	}
+	if usage.CPU.MilliValue() != 200 || usage.Memory.Value() != 80<<20 {
+		t.Errorf("usage = %s cpu %s memory, want 200m and 80Mi", usage.CPU.String(), usage.Memory.String())
+	}
+}
+
+func TestListPodUsageMetricsUnavailable(t *testing.T) {
+	client := newMetricsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, err := client.Metrics().ListPodUsage(context.Background(), nil)
+	if !errors.Is(err, k8s.ErrMetricsUnavailable) {
+		t.Errorf("ListPodUsage() error = %v, want ErrMetricsUnavailable", err)
+	}
+}
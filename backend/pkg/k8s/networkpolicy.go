@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// enforcingNetworkPlugins 会执行 NetworkPolicy 的常见 CNI 插件，按 kube-system 中 DaemonSet 名称匹配
+var enforcingNetworkPlugins = []string{"calico", "cilium", "weave", "antrea", "kube-router", "canal"}
+
+// NetworkPolicyManager 负责 NetworkPolicy 相关操作
+// 通过 Client 组合实现
+
+type NetworkPolicyManager struct {
+	client *Client
+}
+
+// ApplyEgress 创建或更新出站 NetworkPolicy，选中的 Pod 只能访问 cidrs 中的地址，
+// 以及任意目的地的 53 端口，保证集群内外的 DNS 解析可用。入站流量不受影响
+func (nm *NetworkPolicyManager) ApplyEgress(name string, selector, labels map[string]string, cidrs []string) (*networkingv1.NetworkPolicy, error) {
+	policies := nm.client.clientset.NetworkingV1().NetworkPolicies(nm.client.namespace)
+
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dnsPort := intstr.FromInt32(53)
+	egress := []networkingv1.NetworkPolicyEgressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &dnsPort},
+			{Protocol: &tcp, Port: &dnsPort},
+		},
+	}}
+	if len(cidrs) > 0 {
+		peers := make([]networkingv1.NetworkPolicyPeer, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: peers})
+	}
+	spec := networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{MatchLabels: selector},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		Egress:      egress,
+	}
+
+	existing, err := policies.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: nm.client.namespace,
+				Labels:    labels,
+			},
+			Spec: spec,
+		}
+		return policies.Create(context.Background(), policy, metav1.CreateOptions{})
+	}
+
+	existing.Labels = labels
+	existing.Spec = spec
+	return policies.Update(context.Background(), existing, metav1.UpdateOptions{})
+}
+
+// Get 获取 NetworkPolicy 详情
+func (nm *NetworkPolicyManager) Get(name string) (*networkingv1.NetworkPolicy, error) {
+	return nm.client.clientset.NetworkingV1().NetworkPolicies(nm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// Delete 删除 NetworkPolicy，不存在时忽略
+func (nm *NetworkPolicyManager) Delete(name string) error {
+	err := nm.client.clientset.NetworkingV1().NetworkPolicies(nm.client.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// EnforcementDetected 根据 kube-system 中的 DaemonSet 判断集群 CNI 是否执行 NetworkPolicy，
+// 只能识别常见插件，无权限读取 kube-system 时返回错误
+func (nm *NetworkPolicyManager) EnforcementDetected(ctx context.Context) (bool, error) {
+	list, err := nm.client.clientset.AppsV1().DaemonSets("kube-system").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, ds := range list.Items {
+		for _, plugin := range enforcingNetworkPlugins {
+			if strings.Contains(ds.Name, plugin) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ResolveEgressCIDRs 将出站目的地转换为 CIDR 列表，CIDR 原样保留，IP 转换为单地址 CIDR，
+// 域名解析为当前的全部地址。解析结果只在调用时有效，域名地址变化后需要重新生成策略
+func ResolveEgressCIDRs(ctx context.Context, destinations []string) ([]string, error) {
+	var cidrs []string
+	seen := make(map[string]bool)
+	add := func(cidr string) {
+		if !seen[cidr] {
+			seen[cidr] = true
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	for _, dest := range destinations {
+		if prefix, err := netip.ParsePrefix(dest); err == nil {
+			add(prefix.Masked().String())
+			continue
+		}
+		if addr, err := netip.ParseAddr(dest); err == nil {
+			add(netip.PrefixFrom(addr, addr.BitLen()).String())
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, dest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve egress destination %s: %w", dest, err)
+		}
+		for _, ip := range addrs {
+			addr, ok := netip.AddrFromSlice(ip.IP)
+			if !ok {
+				continue
+			}
+			addr = addr.Unmap()
+			add(netip.PrefixFrom(addr, addr.BitLen()).String())
+		}
+	}
+	return cidrs, nil
+}
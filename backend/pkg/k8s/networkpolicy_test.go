@@ -0,0 +1,101 @@
+package k8s_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyEgressNetworkPolicy(t *testing.T) {
+	nm := k8s.NewClientForClientset(fake.NewSimpleClientset(), testNamespace).NetworkPolicy()
+	selector := map[string]string{"app": "mcp-app"}
+
+	if _, err := nm.ApplyEgress("mcp-app-egress", selector, nil, []string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("ApplyEgress() create error = %v", err)
+	}
+	if _, err := nm.ApplyEgress("mcp-app-egress", selector, nil, []string{"203.0.113.7/32", "2001:db8::/32"}); err != nil {
+		t.Fatalf("ApplyEgress() update error = %v", err)
+	}
+
+	policy, err := nm.Get("mcp-app-egress")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(policy.Spec.PolicyTypes, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}) {
+		t.Errorf("policyTypes = %v, want egress only", policy.Spec.PolicyTypes)
+	}
+	if !reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, selector) {
+		t.Errorf("podSelector = %v, want %v", policy.Spec.PodSelector.MatchLabels, selector)
+	}
+	if len(policy.Spec.Egress) != 2 {
+		t.Fatalf("egress rules = %d, want DNS rule and destination rule", len(policy.Spec.Egress))
+	}
+	dns := policy.Spec.Egress[0]
+	if len(dns.To) != 0 || len(dns.Ports) != 2 || dns.Ports[0].Port.IntValue() != 53 {
+		t.Errorf("DNS rule = %+v, want port 53 to any destination", dns)
+	}
+	var cidrs []string
+	for _, peer := range policy.Spec.Egress[1].To {
+		cidrs = append(cidrs, peer.IPBlock.CIDR)
+	}
+	if want := []string{"203.0.113.7/32", "2001:db8::/32"}; !reflect.DeepEqual(cidrs, want) {
+		t.Errorf("destinations = %v, want %v", cidrs, want)
+	}
+
+	if err := nm.Delete("mcp-app-egress"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := nm.Delete("mcp-app-egress"); err != nil {
+		t.Errorf("Delete() missing policy error = %v, want nil", err)
+	}
+}
+
+func TestNetworkPolicyEnforcementDetected(t *testing.T) {
+	daemonSet := func(name string) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"}}
+	}
+	tests := []struct {
+		name       string
+		daemonSets []string
+		want       bool
+	}{
+		{"calico", []string{"kube-proxy", "calico-node"}, true},
+		{"cilium", []string{"cilium"}, true},
+		{"flannel only", []string{"kube-proxy", "kube-flannel-ds"}, false},
+		{"no daemon sets", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, name := range tt.daemonSets {
+				if _, err := clientset.AppsV1().DaemonSets("kube-system").Create(context.Background(), daemonSet(name), metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := k8s.NewClientForClientset(clientset, testNamespace).NetworkPolicy().EnforcementDetected(context.Background())
+			if err != nil {
+				t.Fatalf("EnforcementDetected() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EnforcementDetected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEgressCIDRs(t *testing.T) {
+	got, err := k8s.ResolveEgressCIDRs(context.Background(), []string{"10.1.2.3/8", "192.0.2.10", "2001:db8::1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ResolveEgressCIDRs() error = %v", err)
+	}
+	if want := []string{"10.0.0.0/8", "192.0.2.10/32", "2001:db8::1/128"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveEgressCIDRs() = %v, want %v", got, want)
+	}
+}
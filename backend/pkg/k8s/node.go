@@ -3,8 +3,8 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"log"
 
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -36,14 +36,14 @@ type NodeInfo struct {
 
 // ListNodes 获取所有节点列表
 func (nm *NodeManager) ListNodes() ([]NodeInfo, error) {
-	log.Printf("正在查询集群中的所有节点...")
+	nm.client.Logger().Debug("listing nodes")
 
 	nodeList, err := nm.client.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("查询节点列表失败: %w", err)
 	}
 
-	log.Printf("成功查询到 %d 个节点", len(nodeList.Items))
+	nm.client.Logger().Debug("listed nodes", zap.Int("count", len(nodeList.Items)))
 
 	var nodeInfos []NodeInfo
 	for _, node := range nodeList.Items {
@@ -112,7 +112,7 @@ func (nm *NodeManager) ListNodes() ([]NodeInfo, error) {
 
 // GetNode 获取指定节点信息
 func (nm *NodeManager) GetNode(name string) (*NodeInfo, error) {
-	log.Printf("正在查询节点 '%s'...", name)
+	nm.client.Logger().Debug("getting node", zap.String("node", name))
 
 	node, err := nm.client.clientset.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
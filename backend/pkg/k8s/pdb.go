@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PodDisruptionBudgetManager 负责 PodDisruptionBudget 相关操作
+// 通过 Client 组合实现
+
+type PodDisruptionBudgetManager struct {
+	client *Client
+}
+
+// Apply 创建或更新 minAvailable 为 1 的 PodDisruptionBudget，
+// 单副本时集群自动扩缩容和节点排空不能主动驱逐该 Pod
+func (pm *PodDisruptionBudgetManager) Apply(name string, selector, labels map[string]string) (*policyv1.PodDisruptionBudget, error) {
+	pdbs := pm.client.clientset.PolicyV1().PodDisruptionBudgets(pm.client.namespace)
+	minAvailable := intstr.FromInt32(1)
+	spec := policyv1.PodDisruptionBudgetSpec{
+		MinAvailable: &minAvailable,
+		Selector:     &metav1.LabelSelector{MatchLabels: selector},
+	}
+
+	existing, err := pdbs.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		pdb := &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: pm.client.namespace,
+				Labels:    labels,
+			},
+			Spec: spec,
+		}
+		return pdbs.Create(context.Background(), pdb, metav1.CreateOptions{})
+	}
+
+	existing.Labels = labels
+	existing.Spec = spec
+	return pdbs.Update(context.Background(), existing, metav1.UpdateOptions{})
+}
+
+// Get 获取 PodDisruptionBudget 详情
+func (pm *PodDisruptionBudgetManager) Get(name string) (*policyv1.PodDisruptionBudget, error) {
+	return pm.client.clientset.PolicyV1().PodDisruptionBudgets(pm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// Delete 删除 PodDisruptionBudget，不存在时忽略
+func (pm *PodDisruptionBudgetManager) Delete(name string) error {
+	err := pm.client.clientset.PolicyV1().PodDisruptionBudgets(pm.client.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package k8s_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyPodDisruptionBudget(t *testing.T) {
+	pm := k8s.NewClientForClientset(fake.NewSimpleClientset(), testNamespace).PodDisruptionBudget()
+	labels := map[string]string{"app": "mcp-app", "managed-by": "qm-mcp-server"}
+
+	if _, err := pm.Apply("mcp-app", map[string]string{"app": "old"}, labels); err != nil {
+		t.Fatalf("Apply() create error = %v", err)
+	}
+	if _, err := pm.Apply("mcp-app", map[string]string{"app": "mcp-app"}, labels); err != nil {
+		t.Fatalf("Apply() update error = %v", err)
+	}
+
+	pdb, err := pm.Get("mcp-app")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.IntValue() != 1 {
+		t.Errorf("minAvailable = %v, want 1", pdb.Spec.MinAvailable)
+	}
+	if !reflect.DeepEqual(pdb.Spec.Selector.MatchLabels, map[string]string{"app": "mcp-app"}) {
+		t.Errorf("selector = %v, want updated selector", pdb.Spec.Selector.MatchLabels)
+	}
+	if !reflect.DeepEqual(pdb.Labels, labels) {
+		t.Errorf("labels = %v, want %v", pdb.Labels, labels)
+	}
+
+	if err := pm.Delete("mcp-app"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := pm.Delete("mcp-app"); err != nil {
+		t.Errorf("Delete() missing budget error = %v, want nil", err)
+	}
+}
@@ -3,6 +3,8 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"io"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -10,6 +12,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 // PodManager 负责 Pod 相关操作
@@ -316,6 +320,117 @@ func (pm *PodManager) IsReady(podName string) (isReady bool, runInfo string, err
 	return podReady, fmt.Sprintf("Pod 异常: %s", strings.Join(runInfos, "\n")), nil
 }
 
+// initWaitingFailures 初始化容器处于这些等待原因时不会自行恢复
+var initWaitingFailures = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// NotReadyContainers 返回 Pod 中除 skip 外未就绪容器的描述，用于报告边车容器状态
+func NotReadyContainers(pod *corev1.Pod, skip string) []string {
+	var issues []string
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == skip || cs.Ready {
+			continue
+		}
+		switch {
+		case cs.State.Waiting != nil:
+			issues = append(issues, fmt.Sprintf("container %s not ready: %s", cs.Name, cs.State.Waiting.Reason))
+		case cs.State.Terminated != nil:
+			issues = append(issues, fmt.Sprintf("container %s not ready: %s (exit code %d)", cs.Name, cs.State.Terminated.Reason, cs.State.Terminated.ExitCode))
+		default:
+			issues = append(issues, fmt.Sprintf("container %s not ready", cs.Name))
+		}
+	}
+	return issues
+}
+
+// SchedulingBlockers 返回 Pending Pod 无法调度的原因，如资源不足或等待抢占低优先级 Pod，已调度的 Pod 返回 nil
+func SchedulingBlockers(pod *corev1.Pod) []string {
+	if pod.Status.Phase != corev1.PodPending {
+		return nil
+	}
+	if pod.Status.NominatedNodeName != "" {
+		return []string{fmt.Sprintf("pod %s pending: waiting for lower priority pods to be preempted on node %s", pod.Name, pod.Status.NominatedNodeName)}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return []string{fmt.Sprintf("pod %s pending: unschedulable: %s", pod.Name, cond.Message)}
+		}
+	}
+	return nil
+}
+
+// InitContainerFailures 返回 Pod 中执行失败的初始化容器描述，没有失败时返回 nil
+// 正在执行或等待前序初始化容器完成的不算失败
+func InitContainerFailures(pod *corev1.Pod) []string {
+	var failures []string
+	for _, ics := range pod.Status.InitContainerStatuses {
+		switch {
+		case ics.State.Terminated != nil && ics.State.Terminated.ExitCode != 0:
+			t := ics.State.Terminated
+			failures = append(failures, fmt.Sprintf("init container %s failed: %s (exit code %d)", ics.Name, t.Reason, t.ExitCode))
+		case ics.State.Waiting != nil && ics.LastTerminationState.Terminated != nil && ics.LastTerminationState.Terminated.ExitCode != 0:
+			// 失败后等待重试，如 CrashLoopBackOff
+			t := ics.LastTerminationState.Terminated
+			failures = append(failures, fmt.Sprintf("init container %s failed: %s (exit code %d), %s after %d restarts",
+				ics.Name, t.Reason, t.ExitCode, ics.State.Waiting.Reason, ics.RestartCount))
+		case ics.State.Waiting != nil && initWaitingFailures[ics.State.Waiting.Reason]:
+			w := ics.State.Waiting
+			failures = append(failures, fmt.Sprintf("init container %s failed: %s: %s", ics.Name, w.Reason, w.Message))
+		}
+	}
+	return failures
+}
+
+// Pod watch 时归类的状态，状态变化时才需要重新检查实例
+const (
+	PodStateReady     = "ready"
+	PodStateNotReady  = "not-ready"
+	PodStateFailed    = "failed"
+	PodStateCrashLoop = "crash-loop"
+)
+
+// PodState 归类 Pod 当前状态并返回异常原因，崩溃重启优先于就绪判断
+func PodState(pod *corev1.Pod) (state, reason string) {
+	if pod.Status.Phase == corev1.PodFailed {
+		return PodStateFailed, strings.TrimSpace(pod.Status.Reason + " " + pod.Status.Message)
+	}
+	for _, cs := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
+			reason = fmt.Sprintf("container %s: CrashLoopBackOff after %d restarts", cs.Name, cs.RestartCount)
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				reason += fmt.Sprintf(", last exit: %s (exit code %d)", t.Reason, t.ExitCode)
+			}
+			return PodStateCrashLoop, reason
+		}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return PodStateReady, ""
+		}
+	}
+	return PodStateNotReady, ""
+}
+
+// Watch 监听命名空间中匹配标签选择器的 Pod 变化，调用方负责 Stop
+func (pm *PodManager) Watch(ctx context.Context, labelSelector string) (watch.Interface, error) {
+	return pm.client.clientset.CoreV1().Pods(pm.client.namespace).Watch(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+}
+
+// List 列出命名空间中带有全部指定标签的 Pod
+func (pm *PodManager) List(selector map[string]string) ([]corev1.Pod, error) {
+	list, err := pm.client.clientset.CoreV1().Pods(pm.client.namespace).List(
+		context.Background(), metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
 // GetStatus 获取 Pod 当前状态
 func (pm *PodManager) GetStatus(podName string) (corev1.PodPhase, error) {
 	pod, err := pm.client.clientset.CoreV1().Pods(pm.client.namespace).Get(context.Background(), podName, metav1.GetOptions{})
@@ -787,13 +902,41 @@ func (pm *PodManager) setEnvironmentVariables(container *corev1.Container, envVa
 	}
 }
 
-// GetLogs 获取 Pod 日志
-func (pm *PodManager) GetLogs(podName string, lines int64) (string, error) {
-	return pm.GetLogsWithNamespace(podName, pm.client.namespace, lines)
+// GetLogs 获取 Pod 日志，container 为空时 Pod 只能有一个容器，previous 为 true 时获取上一个已终止容器的日志
+func (pm *PodManager) GetLogs(podName, container string, lines int64, previous bool) (string, error) {
+	return pm.GetLogsWithNamespace(podName, pm.client.namespace, container, lines, previous)
+}
+
+// LogStreamOptions Pod 日志流选项
+type LogStreamOptions struct {
+	SinceTime  *time.Time // 只返回该时间之后的日志，nil 表示全部可用日志
+	Previous   bool       // 返回上一个已终止容器的日志，用于查看重启前的日志
+	Timestamps bool       // 每行日志前添加 RFC3339Nano 时间戳
+	Container  string     // 容器名称，Pod 只有一个容器时可为空
+}
+
+// StreamLogs 以流的方式获取 Pod 日志，不在内存中缓存日志内容，调用方负责关闭返回的 ReadCloser
+func (pm *PodManager) StreamLogs(ctx context.Context, podName string, options LogStreamOptions) (io.ReadCloser, error) {
+	logOptions := &corev1.PodLogOptions{
+		Follow:     false,
+		Previous:   options.Previous,
+		Timestamps: options.Timestamps,
+		Container:  options.Container,
+	}
+	if options.SinceTime != nil {
+		sinceTime := metav1.NewTime(*options.SinceTime)
+		logOptions.SinceTime = &sinceTime
+	}
+
+	logs, err := pm.client.clientset.CoreV1().Pods(pm.client.namespace).GetLogs(podName, logOptions).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取 Pod 日志失败: %w", err)
+	}
+	return logs, nil
 }
 
 // GetLogsWithNamespace 获取指定命名空间中 Pod 的日志
-func (pm *PodManager) GetLogsWithNamespace(podName, namespace string, lines int64) (string, error) {
+func (pm *PodManager) GetLogsWithNamespace(podName, namespace, container string, lines int64, previous bool) (string, error) {
 	// 设置默认行数
 	if lines <= 0 {
 		lines = 100
@@ -802,7 +945,9 @@ func (pm *PodManager) GetLogsWithNamespace(podName, namespace string, lines int6
 	// 构建日志获取选项
 	logOptions := &corev1.PodLogOptions{
 		TailLines: &lines,
-		Follow:    false, // 不跟踪，只获取现有日志
+		Follow:    false,     // 不跟踪，只获取现有日志
+		Previous:  previous,  // 容器崩溃重启后，崩溃前的日志只能从上一个容器获取
+		Container: container, // Pod 有多个容器时必须指定
 	}
 
 	// 获取日志请求
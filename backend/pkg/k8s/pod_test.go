@@ -0,0 +1,136 @@
+package k8s_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSchedulingBlockers(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "mcp-1"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  corev1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}},
+		},
+	}
+	want := []string{"pod mcp-1 pending: unschedulable: 0/3 nodes are available: 3 Insufficient cpu."}
+	if got := k8s.SchedulingBlockers(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("SchedulingBlockers() = %v, want %v", got, want)
+	}
+
+	pod.Status.NominatedNodeName = "node-1"
+	want = []string{"pod mcp-1 pending: waiting for lower priority pods to be preempted on node node-1"}
+	if got := k8s.SchedulingBlockers(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("SchedulingBlockers() = %v, want %v", got, want)
+	}
+
+	pod.Status.Phase = corev1.PodRunning
+	if got := k8s.SchedulingBlockers(pod); got != nil {
+		t.Errorf("SchedulingBlockers() on running pod = %v, want nil", got)
+	}
+}
+
+func TestInitContainerFailures(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+		{Name: "done", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}},
+		{Name: "crash", RestartCount: 3,
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}}},
+		{Name: "pull", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"}}},
+		{Name: "next", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}},
+	}}}
+	want := []string{
+		"init container crash failed: Error (exit code 1), CrashLoopBackOff after 3 restarts",
+		"init container pull failed: ImagePullBackOff: not found",
+	}
+	if got := k8s.InitContainerFailures(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("InitContainerFailures() = %v, want %v", got, want)
+	}
+
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{Name: "exit", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}}},
+	}
+	if got := k8s.InitContainerFailures(pod); !reflect.DeepEqual(got, []string{"init container exit failed: Error (exit code 2)"}) {
+		t.Errorf("InitContainerFailures() = %v", got)
+	}
+}
+
+func TestNotReadyContainers(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+		{Name: "main"},
+		{Name: "ready", Ready: true},
+		{Name: "pull", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+		{Name: "probe", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+	}}}
+	want := []string{"container pull not ready: ImagePullBackOff", "container probe not ready"}
+	if got := k8s.NotReadyContainers(pod, "main"); !reflect.DeepEqual(got, want) {
+		t.Errorf("NotReadyContainers() = %v, want %v", got, want)
+	}
+}
+
+func TestPodState(t *testing.T) {
+	ready := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	crash := corev1.ContainerStatus{Name: "main", RestartCount: 4,
+		State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}}}
+
+	tests := []struct {
+		name       string
+		status     corev1.PodStatus
+		wantState  string
+		wantReason string
+	}{
+		{"pending", corev1.PodStatus{Phase: corev1.PodPending}, k8s.PodStateNotReady, ""},
+		{"ready", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: ready}, k8s.PodStateReady, ""},
+		{"failed", corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "low memory"}, k8s.PodStateFailed, "Evicted low memory"},
+		{"crash loop", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: ready, ContainerStatuses: []corev1.ContainerStatus{crash}},
+			k8s.PodStateCrashLoop, "container main: CrashLoopBackOff after 4 restarts, last exit: Error (exit code 1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, reason := k8s.PodState(&corev1.Pod{Status: tt.status})
+			if state != tt.wantState || reason != tt.wantReason {
+				t.Errorf("PodState() = %q, %q, want %q, %q", state, reason, tt.wantState, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPodManagerWatch(t *testing.T) {
+	const namespace = "mcp"
+	clientset := fake.NewSimpleClientset()
+	pm := k8s.NewClientForClientset(clientset, namespace).Pod()
+
+	w, err := pm.Watch(context.Background(), "managed-by=qm-mcp-server")
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer w.Stop()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mcp-app", Namespace: namespace, Labels: map[string]string{"managed-by": "qm-mcp-server"}}}
+	if _, err := clientset.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	select {
+	case event := <-w.ResultChan():
+		if event.Type != watch.Added || event.Object.(*corev1.Pod).Name != "mcp-app" {
+			t.Errorf("event = %s %v, want ADDED mcp-app", event.Type, event.Object)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no watch event received")
+	}
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretManager 负责 Secret 相关操作
+// 通过 Client 组合实现
+
+type SecretManager struct {
+	client *Client
+}
+
+// Get 获取 Secret 详情
+func (sm *SecretManager) Get(name string) (*corev1.Secret, error) {
+	return sm.client.clientset.CoreV1().Secrets(sm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// GetDockerConfigJSON 获取镜像拉取密钥中的 .dockerconfigjson 内容
+func (sm *SecretManager) GetDockerConfigJSON(name string) ([]byte, error) {
+	secret, err := sm.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("secret %s is not of type %s", name, corev1.SecretTypeDockerConfigJson)
+	}
+	return secret.Data[corev1.DockerConfigJsonKey], nil
+}
+
+// ApplyDockerConfigJSON 创建或更新 kubernetes.io/dockerconfigjson 类型的镜像拉取密钥
+func (sm *SecretManager) ApplyDockerConfigJSON(name string, dockerConfigJSON []byte, labels map[string]string) (*corev1.Secret, error) {
+	secrets := sm.client.clientset.CoreV1().Secrets(sm.client.namespace)
+	existing, err := secrets.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: sm.client.namespace,
+				Labels:    labels,
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfigJSON},
+		}
+		return secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	}
+
+	if existing.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("secret %s already exists with type %s", name, existing.Type)
+	}
+	existing.Data = map[string][]byte{corev1.DockerConfigJsonKey: dockerConfigJSON}
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		existing.Labels[k] = v
+	}
+	return secrets.Update(context.Background(), existing, metav1.UpdateOptions{})
+}
+
+// ApplyOpaque 创建或更新 Opaque 类型的 Secret，Data 整体替换
+func (sm *SecretManager) ApplyOpaque(name string, data map[string][]byte, labels map[string]string) (*corev1.Secret, error) {
+	secrets := sm.client.clientset.CoreV1().Secrets(sm.client.namespace)
+	existing, err := secrets.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: sm.client.namespace,
+				Labels:    labels,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		return secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	}
+
+	if existing.Type != corev1.SecretTypeOpaque {
+		return nil, fmt.Errorf("secret %s already exists with type %s", name, existing.Type)
+	}
+	existing.Data = data
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		existing.Labels[k] = v
+	}
+	return secrets.Update(context.Background(), existing, metav1.UpdateOptions{})
+}
+
+// Delete 删除 Secret，不存在时视为成功
+func (sm *SecretManager) Delete(name string) error {
+	err := sm.client.clientset.CoreV1().Secrets(sm.client.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyDockerConfigJSON(t *testing.T) {
+	opaque := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: testNamespace},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	sm := k8s.NewClientForClientset(fake.NewSimpleClientset(opaque), testNamespace).Secret()
+	labels := map[string]string{"app": "mcpbox"}
+
+	if _, err := sm.ApplyDockerConfigJSON("pull", []byte(`{"auths":{}}`), labels); err != nil {
+		t.Fatalf("ApplyDockerConfigJSON() create error = %v", err)
+	}
+	if _, err := sm.ApplyDockerConfigJSON("pull", []byte(`{"auths":{"ghcr.io":{}}}`), labels); err != nil {
+		t.Fatalf("ApplyDockerConfigJSON() update error = %v", err)
+	}
+
+	data, err := sm.GetDockerConfigJSON("pull")
+	if err != nil {
+		t.Fatalf("GetDockerConfigJSON() error = %v", err)
+	}
+	if got, want := string(data), `{"auths":{"ghcr.io":{}}}`; got != want {
+		t.Errorf("GetDockerConfigJSON() = %s, want %s", got, want)
+	}
+
+	if _, err := sm.ApplyDockerConfigJSON("opaque", []byte(`{}`), labels); err == nil {
+		t.Error("ApplyDockerConfigJSON() over an opaque secret, want error")
+	}
+	if _, err := sm.GetDockerConfigJSON("opaque"); err == nil {
+		t.Error("GetDockerConfigJSON() on an opaque secret, want error")
+	}
+}
+
+func TestApplyOpaqueAndDelete(t *testing.T) {
+	pull := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: testNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+	}
+	sm := k8s.NewClientForClientset(fake.NewSimpleClientset(pull), testNamespace).Secret()
+
+	if _, err := sm.ApplyOpaque("files", map[string][]byte{"a": []byte("1"), "b": []byte("2")}, nil); err != nil {
+		t.Fatalf("ApplyOpaque() create error = %v", err)
+	}
+	if _, err := sm.ApplyOpaque("files", map[string][]byte{"a": []byte("3")}, map[string]string{"app": "mcpbox"}); err != nil {
+		t.Fatalf("ApplyOpaque() update error = %v", err)
+	}
+	secret, err := sm.Get("files")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(secret.Data) != 1 || string(secret.Data["a"]) != "3" {
+		t.Errorf("ApplyOpaque() data = %v, want only a=3", secret.Data)
+	}
+	if secret.Labels["app"] != "mcpbox" {
+		t.Errorf("ApplyOpaque() labels = %v, want app=mcpbox", secret.Labels)
+	}
+
+	if _, err := sm.ApplyOpaque("pull", map[string][]byte{}, nil); err == nil {
+		t.Error("ApplyOpaque() over a dockerconfigjson secret, want error")
+	}
+
+	if err := sm.Delete("files"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := sm.Delete("files"); err != nil {
+		t.Errorf("Delete() of a missing secret error = %v, want nil", err)
+	}
+}
@@ -2,9 +2,12 @@ package k8s
 
 import (
 	"context"
+	"net"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // ServiceManager 负责 Service 相关操作
@@ -31,3 +34,48 @@ func (sm *ServiceManager) Delete(name string) error {
 func (sm *ServiceManager) Get(name string) (*corev1.Service, error) {
 	return sm.client.clientset.CoreV1().Services(sm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
 }
+
+// List 列出命名空间中带有全部指定标签的 Service
+func (sm *ServiceManager) List(selector map[string]string) ([]corev1.Service, error) {
+	list, err := sm.client.clientset.CoreV1().Services(sm.client.namespace).List(
+		context.Background(), metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// ListSelecting 列出 Pod 选择器包含全部指定标签的 Service，用于查找没有设置自身标签的 Service。
+// 选择器不支持服务端过滤，需要列出整个命名空间
+func (sm *ServiceManager) ListSelecting(selector map[string]string) ([]corev1.Service, error) {
+	list, err := sm.client.clientset.CoreV1().Services(sm.client.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var services []corev1.Service
+	for _, svc := range list.Items {
+		if len(svc.Spec.Selector) > 0 && labels.SelectorFromSet(selector).Matches(labels.Set(svc.Spec.Selector)) {
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+// GetReadyAddresses 获取 Service 背后就绪 Pod 的地址列表（ip:port），通过 Endpoints 解析
+func (sm *ServiceManager) GetReadyAddresses(name string) ([]string, error) {
+	endpoints, err := sm.client.clientset.CoreV1().Endpoints(sm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Ports) == 0 {
+			continue
+		}
+		port := strconv.Itoa(int(subset.Ports[0].Port))
+		for _, addr := range subset.Addresses {
+			addrs = append(addrs, net.JoinHostPort(addr.IP, port))
+		}
+	}
+	return addrs, nil
+}
@@ -0,0 +1,74 @@
+package k8s_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetReadyAddresses(t *testing.T) {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: testNamespace},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+			NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.3"}},
+			Ports:             []corev1.EndpointPort{{Port: 8080}},
+		}},
+	}
+	sm := k8s.NewClientForClientset(fake.NewSimpleClientset(endpoints), testNamespace).Service()
+
+	got, err := sm.GetReadyAddresses("svc")
+	if err != nil {
+		t.Fatalf("GetReadyAddresses() error = %v", err)
+	}
+	want := []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetReadyAddresses() = %v, want %v", got, want)
+	}
+
+	if _, err := sm.GetReadyAddresses("missing"); err == nil {
+		t.Error("GetReadyAddresses() on missing service, want error")
+	}
+}
+
+func TestListServices(t *testing.T) {
+	managed := map[string]string{"managed-by": "qm-mcp-server", "instance": "a"}
+	objects := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Namespace: testNamespace, Labels: managed}, Spec: corev1.ServiceSpec{Selector: managed}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: testNamespace}, Spec: corev1.ServiceSpec{Selector: managed}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "headless", Namespace: testNamespace}},
+	}
+	clientset := fake.NewSimpleClientset(&objects[0], &objects[1], &objects[2], &objects[3])
+	sm := k8s.NewClientForClientset(clientset, testNamespace).Service()
+	selector := map[string]string{"managed-by": "qm-mcp-server"}
+
+	names := func(services []corev1.Service) []string {
+		var out []string
+		for _, svc := range services {
+			out = append(out, svc.Name)
+		}
+		return out
+	}
+
+	labeled, err := sm.List(selector)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := names(labeled); !reflect.DeepEqual(got, []string{"labeled"}) {
+		t.Errorf("List() = %v, want [labeled]", got)
+	}
+
+	selecting, err := sm.ListSelecting(selector)
+	if err != nil {
+		t.Fatalf("ListSelecting() error = %v", err)
+	}
+	if got := names(selecting); !reflect.DeepEqual(got, []string{"labeled", "legacy"}) {
+		t.Errorf("ListSelecting() = %v, want [labeled legacy]", got)
+	}
+}
@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountManager 负责 ServiceAccount 相关操作
+// 通过 Client 组合实现
+
+type ServiceAccountManager struct {
+	client *Client
+}
+
+// ApplyRestricted 创建或更新不自动挂载令牌的 ServiceAccount，不绑定任何角色，
+// 使用该账号的 Pod 无法访问 Kubernetes API
+func (sm *ServiceAccountManager) ApplyRestricted(name string, labels map[string]string) (*corev1.ServiceAccount, error) {
+	accounts := sm.client.clientset.CoreV1().ServiceAccounts(sm.client.namespace)
+	automount := false
+
+	existing, err := accounts.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		account := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: sm.client.namespace,
+				Labels:    labels,
+			},
+			AutomountServiceAccountToken: &automount,
+		}
+		return accounts.Create(context.Background(), account, metav1.CreateOptions{})
+	}
+
+	existing.Labels = labels
+	existing.AutomountServiceAccountToken = &automount
+	return accounts.Update(context.Background(), existing, metav1.UpdateOptions{})
+}
+
+// Get 获取 ServiceAccount 详情
+func (sm *ServiceAccountManager) Get(name string) (*corev1.ServiceAccount, error) {
+	return sm.client.clientset.CoreV1().ServiceAccounts(sm.client.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// Delete 删除 ServiceAccount，不存在时忽略
+func (sm *ServiceAccountManager) Delete(name string) error {
+	err := sm.client.clientset.CoreV1().ServiceAccounts(sm.client.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
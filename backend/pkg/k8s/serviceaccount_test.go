@@ -0,0 +1,40 @@
+package k8s_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyRestrictedServiceAccount(t *testing.T) {
+	sm := k8s.NewClientForClientset(fake.NewSimpleClientset(), testNamespace).ServiceAccount()
+	labels := map[string]string{"app": "mcp-app", "managed-by": "qm-mcp-server"}
+
+	if _, err := sm.ApplyRestricted("mcp-app", nil); err != nil {
+		t.Fatalf("ApplyRestricted() create error = %v", err)
+	}
+	if _, err := sm.ApplyRestricted("mcp-app", labels); err != nil {
+		t.Fatalf("ApplyRestricted() update error = %v", err)
+	}
+
+	account, err := sm.Get("mcp-app")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if account.AutomountServiceAccountToken == nil || *account.AutomountServiceAccountToken {
+		t.Errorf("AutomountServiceAccountToken = %v, want false", account.AutomountServiceAccountToken)
+	}
+	if !reflect.DeepEqual(account.Labels, labels) {
+		t.Errorf("labels = %v, want %v", account.Labels, labels)
+	}
+
+	if err := sm.Delete("mcp-app"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := sm.Delete("mcp-app"); err != nil {
+		t.Errorf("Delete() missing account error = %v, want nil", err)
+	}
+}
@@ -3,8 +3,8 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"log"
 
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -53,57 +53,29 @@ func (vm *VolumeManager) ListPVCs(namespace ...string) ([]PVCInfo, error) {
 		targetNamespace = metav1.NamespaceAll
 	}
 
-	log.Printf("正在查询命名空间 '%s' 中的 PVC...", targetNamespace)
+	vm.client.Logger().Debug("listing PVCs", zap.String("namespace", targetNamespace))
 
 	pvcList, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(targetNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("查询命名空间 '%s' 中的 PVC 失败: %w", targetNamespace, err)
 	}
 
-	log.Printf("成功查询到 %d 个 PVC", len(pvcList.Items))
+	vm.client.Logger().Debug("listed PVCs", zap.String("namespace", targetNamespace), zap.Int("count", len(pvcList.Items)))
 
-	var pvcInfos []PVCInfo
-	for _, pvc := range pvcList.Items {
-		// 获取访问模式
-		var accessModes []string
-		for _, mode := range pvc.Spec.AccessModes {
-			accessModes = append(accessModes, string(mode))
-		}
-
-		// 获取容量
-		capacity := ""
-		if pvc.Status.Capacity != nil {
-			if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
-				capacity = storage.String()
-			}
-		}
-
-		// 获取存储类名称
-		storageClass := ""
-		if pvc.Spec.StorageClassName != nil {
-			storageClass = *pvc.Spec.StorageClassName
-		}
+	// 一次性查询 Pod 并按 PVC 建立索引，避免每个 PVC 都查询一次 Pod 列表
+	boundPodsIndex, err := vm.indexPVCBoundPods(targetNamespace)
+	if err != nil {
+		vm.client.Logger().Warn("failed to index pods bound to PVCs", zap.String("namespace", targetNamespace), zap.Error(err))
+	}
 
-		// 获取绑定的Pod列表
-		boundPods, err := vm.GetPVCBoundPods(pvc.Name, pvc.Namespace)
-		if err != nil {
-			log.Printf("获取PVC '%s' 绑定的Pod列表失败: %v", pvc.Name, err)
+	var pvcInfos []PVCInfo
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		boundPods := boundPodsIndex[pvcIndexKey(pvc.Namespace, pvc.Name)]
+		if boundPodsIndex == nil {
 			boundPods = []string{} // 如果获取失败，设置为空列表
 		}
-
-		pvcInfo := PVCInfo{
-			Name:         pvc.Name,
-			Namespace:    pvc.Namespace,
-			Status:       string(pvc.Status.Phase),
-			VolumeName:   pvc.Spec.VolumeName,
-			StorageClass: storageClass,
-			Capacity:     capacity,
-			AccessModes:  accessModes,
-			Labels:       pvc.Labels,
-			CreationTime: pvc.CreationTimestamp.Format("2006-01-02 15:04:05"),
-			Pods:         boundPods,
-		}
-		pvcInfos = append(pvcInfos, pvcInfo)
+		pvcInfos = append(pvcInfos, pvcToInfo(pvc, boundPods))
 	}
 
 	return pvcInfos, nil
@@ -118,13 +90,73 @@ func (vm *VolumeManager) GetPVC(name string, namespace ...string) (*PVCInfo, err
 		targetNamespace = vm.client.namespace
 	}
 
-	log.Printf("正在查询命名空间 '%s' 中的 PVC '%s'...", targetNamespace, name)
+	vm.client.Logger().Debug("getting PVC", zap.String("namespace", targetNamespace), zap.String("pvc", name))
 
 	pvc, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(targetNamespace).Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("查询命名空间 '%s' 中的 PVC '%s' 失败: %w", targetNamespace, name, err)
 	}
 
+	// 获取绑定的Pod列表
+	boundPods, err := vm.GetPVCBoundPods(pvc.Name, pvc.Namespace)
+	if err != nil {
+		vm.client.Logger().Warn("failed to get pods bound to PVC",
+			zap.String("namespace", pvc.Namespace), zap.String("pvc", pvc.Name), zap.Error(err))
+		boundPods = []string{} // 如果获取失败，设置为空列表
+	}
+
+	pvcInfo := pvcToInfo(pvc, boundPods)
+	return &pvcInfo, nil
+}
+
+// GetPVCBoundPods 获取PVC绑定的Pod名称列表
+func (vm *VolumeManager) GetPVCBoundPods(pvcName, namespace string) ([]string, error) {
+	vm.client.Logger().Debug("scanning pods bound to PVC", zap.String("namespace", namespace), zap.String("pvc", pvcName))
+
+	index, err := vm.indexPVCBoundPods(namespace)
+	if err != nil {
+		return nil, err
+	}
+	boundPods := index[pvcIndexKey(namespace, pvcName)]
+
+	vm.client.Logger().Debug("found pods bound to PVC",
+		zap.String("namespace", namespace), zap.String("pvc", pvcName), zap.Strings("pods", boundPods))
+	return boundPods, nil
+}
+
+// indexPVCBoundPods 查询一次命名空间内的 Pod，建立 "namespace/PVC名称" -> Pod 名称列表 的索引
+// namespace 为空时查询所有命名空间
+func (vm *VolumeManager) indexPVCBoundPods(namespace string) (map[string][]string, error) {
+	podList, err := vm.client.clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("查询Pod列表失败: %w", err)
+	}
+
+	index := make(map[string][]string)
+	for _, pod := range podList.Items {
+		// 同一个 Pod 多次挂载同一 PVC 时只记录一次
+		seen := make(map[string]bool)
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			key := pvcIndexKey(pod.Namespace, volume.PersistentVolumeClaim.ClaimName)
+			if !seen[key] {
+				seen[key] = true
+				index[key] = append(index[key], pod.Name)
+			}
+		}
+	}
+	return index, nil
+}
+
+// pvcIndexKey PVC 索引键
+func pvcIndexKey(namespace, pvcName string) string {
+	return namespace + "/" + pvcName
+}
+
+// pvcToInfo 将 PVC 转换为 PVCInfo
+func pvcToInfo(pvc *corev1.PersistentVolumeClaim, boundPods []string) PVCInfo {
 	// 获取访问模式
 	var accessModes []string
 	for _, mode := range pvc.Spec.AccessModes {
@@ -145,14 +177,7 @@ func (vm *VolumeManager) GetPVC(name string, namespace ...string) (*PVCInfo, err
 		storageClass = *pvc.Spec.StorageClassName
 	}
 
-	// 获取绑定的Pod列表
-	boundPods, err := vm.GetPVCBoundPods(pvc.Name, pvc.Namespace)
-	if err != nil {
-		log.Printf("获取PVC '%s' 绑定的Pod列表失败: %v", pvc.Name, err)
-		boundPods = []string{} // 如果获取失败，设置为空列表
-	}
-
-	pvcInfo := &PVCInfo{
+	return PVCInfo{
 		Name:         pvc.Name,
 		Namespace:    pvc.Namespace,
 		Status:       string(pvc.Status.Phase),
@@ -164,33 +189,6 @@ func (vm *VolumeManager) GetPVC(name string, namespace ...string) (*PVCInfo, err
 		CreationTime: pvc.CreationTimestamp.Format("2006-01-02 15:04:05"),
 		Pods:         boundPods,
 	}
-
-	return pvcInfo, nil
-}
-
-// GetPVCBoundPods 获取PVC绑定的Pod名称列表
-func (vm *VolumeManager) GetPVCBoundPods(pvcName, namespace string) ([]string, error) {
-	log.Printf("正在查询PVC '%s' 在命名空间 '%s' 中绑定的Pod...", pvcName, namespace)
-
-	// 查询所有Pod
-	podList, err := vm.client.clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("查询Pod列表失败: %w", err)
-	}
-
-	var boundPods []string
-	for _, pod := range podList.Items {
-		// 检查Pod的卷声明
-		for _, volume := range pod.Spec.Volumes {
-			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvcName {
-				boundPods = append(boundPods, pod.Name)
-				break // 找到匹配的PVC后跳出内层循环
-			}
-		}
-	}
-
-	log.Printf("PVC '%s' 绑定了 %d 个Pod: %v", pvcName, len(boundPods), boundPods)
-	return boundPods, nil
 }
 
 // ListStorageClasses 列出所有存储类
@@ -339,7 +337,9 @@ func (vm *VolumeManager) CreatePVCWithParams(name, nodeName, accessMode, storage
 		}
 	}
 
-	log.Printf("正在创建 PVC '%s'，存储大小: %s，访问模式: %s，节点: %s", name, storageQuantity, accessMode, nodeName)
+	vm.client.Logger().Info("creating PVC",
+		zap.String("namespace", vm.client.namespace), zap.String("pvc", name), zap.String("size", storageQuantity),
+		zap.String("access_mode", accessMode), zap.String("node", nodeName))
 
 	// 创建PVC
 	createdPVC, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(vm.client.namespace).Create(context.Background(), pvc, metav1.CreateOptions{})
@@ -347,7 +347,7 @@ func (vm *VolumeManager) CreatePVCWithParams(name, nodeName, accessMode, storage
 		return nil, fmt.Errorf("创建 PVC '%s' 失败: %w", name, err)
 	}
 
-	log.Printf("成功创建 PVC '%s'", name)
+	vm.client.Logger().Info("created PVC", zap.String("namespace", vm.client.namespace), zap.String("pvc", name))
 
 	// 转换为PVCInfo结构返回
 	var accessModeStrings []string
@@ -364,7 +364,8 @@ func (vm *VolumeManager) CreatePVCWithParams(name, nodeName, accessMode, storage
 	// 获取绑定的Pod列表（新创建的PVC通常没有绑定的Pod）
 	boundPods, err := vm.GetPVCBoundPods(createdPVC.Name, createdPVC.Namespace)
 	if err != nil {
-		log.Printf("获取PVC '%s' 绑定的Pod列表失败: %v", createdPVC.Name, err)
+		vm.client.Logger().Warn("failed to get pods bound to PVC",
+			zap.String("namespace", createdPVC.Namespace), zap.String("pvc", createdPVC.Name), zap.Error(err))
 		boundPods = []string{} // 如果获取失败，设置为空列表
 	}
 
@@ -393,14 +394,14 @@ func (vm *VolumeManager) DeletePVC(name string, namespace ...string) error {
 		targetNamespace = vm.client.namespace
 	}
 
-	log.Printf("正在删除命名空间 '%s' 中的 PVC '%s'...", targetNamespace, name)
+	vm.client.Logger().Info("deleting PVC", zap.String("namespace", targetNamespace), zap.String("pvc", name))
 
 	err := vm.client.clientset.CoreV1().PersistentVolumeClaims(targetNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
 	if err != nil {
 		return fmt.Errorf("删除命名空间 '%s' 中的 PVC '%s' 失败: %w", targetNamespace, name, err)
 	}
 
-	log.Printf("成功删除 PVC '%s'", name)
+	vm.client.Logger().Info("deleted PVC", zap.String("namespace", targetNamespace), zap.String("pvc", name))
 	return nil
 }
 
@@ -414,7 +415,7 @@ func (vm *VolumeManager) GetPVCBoundNode(pvcName string, namespace ...string) ([
 		targetNamespace = vm.client.namespace
 	}
 
-	log.Printf("正在查询 PVC '%s' 绑定的节点信息...", pvcName)
+	vm.client.Logger().Debug("getting nodes bound to PVC", zap.String("namespace", targetNamespace), zap.String("pvc", pvcName))
 
 	// 1. 获取 PVC 信息
 	pvc, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(targetNamespace).Get(
@@ -446,7 +447,8 @@ func (vm *VolumeManager) GetPVCBoundNode(pvcName string, namespace ...string) ([
 		return nil, fmt.Errorf("从 PV '%s' 提取节点信息失败: %w", pvName, err)
 	}
 
-	log.Printf("PVC '%s' 绑定到节点: %v", pvcName, nodes)
+	vm.client.Logger().Debug("found nodes bound to PVC",
+		zap.String("namespace", targetNamespace), zap.String("pvc", pvcName), zap.Strings("nodes", nodes))
 	return nodes, nil
 }
 
@@ -457,7 +459,7 @@ func (vm *VolumeManager) extractNodesFromPV(pv *corev1.PersistentVolume) ([]stri
 
 	// 检查是否为本地存储
 	if pv.Spec.Local != nil {
-		log.Printf("检测到本地存储 PV '%s'，路径: %s", pv.Name, pv.Spec.Local.Path)
+		vm.client.Logger().Debug("detected local PV", zap.String("pv", pv.Name), zap.String("path", pv.Spec.Local.Path))
 	}
 
 	// 从 NodeAffinity 中提取节点信息
@@ -507,7 +509,7 @@ func (vm *VolumeManager) CreateNodeAffinityForPVC(pvcName string, namespace ...s
 		},
 	}
 
-	log.Printf("为 PVC '%s' 创建节点亲和性配置，目标节点: %v", pvcName, nodes)
+	vm.client.Logger().Debug("built node affinity for PVC", zap.String("pvc", pvcName), zap.Strings("nodes", nodes))
 	return nodeAffinity, nil
 }
 
@@ -537,20 +539,20 @@ func (vm *VolumeManager) CreateSoftNodeAffinityForPVC(pvcName string, namespace
 		},
 	}
 
-	log.Printf("为 PVC '%s' 创建软节点亲和性配置，优先节点: %v", pvcName, nodes)
+	vm.client.Logger().Debug("built preferred node affinity for PVC", zap.String("pvc", pvcName), zap.Strings("nodes", nodes))
 	return nodeAffinity, nil
 }
 
 // CheckPermissions 检查当前用户是否有查询 PVC 的权限
 func (vm *VolumeManager) CheckPermissions() error {
-	log.Printf("检查命名空间 '%s' 的 PVC 查询权限...", vm.client.namespace)
+	vm.client.Logger().Debug("checking PVC permissions", zap.String("namespace", vm.client.namespace))
 
 	_, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(vm.client.namespace).List(context.Background(), metav1.ListOptions{Limit: 1})
 	if err != nil {
 		return fmt.Errorf("权限检查失败，无法查询命名空间 '%s' 中的 PVC: %w", vm.client.namespace, err)
 	}
 
-	log.Printf("权限检查通过")
+	vm.client.Logger().Debug("PVC permission check passed", zap.String("namespace", vm.client.namespace))
 	return nil
 }
 
@@ -573,57 +575,30 @@ func (vm *VolumeManager) ListPVCsWithFilter(labelSelector, fieldSelector string,
 		FieldSelector: fieldSelector,
 	}
 
-	log.Printf("正在使用过滤器查询命名空间 '%s' 中的 PVC (标签: '%s', 字段: '%s')...", targetNamespace, labelSelector, fieldSelector)
+	vm.client.Logger().Debug("listing PVCs with filter",
+		zap.String("namespace", targetNamespace), zap.String("label_selector", labelSelector), zap.String("field_selector", fieldSelector))
 
 	pvcList, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(targetNamespace).List(context.Background(), listOptions)
 	if err != nil {
 		return nil, fmt.Errorf("使用过滤器查询命名空间 '%s' 中的 PVC 失败: %w", targetNamespace, err)
 	}
 
-	log.Printf("成功查询到 %d 个符合条件的 PVC", len(pvcList.Items))
-
-	var pvcInfos []PVCInfo
-	for _, pvc := range pvcList.Items {
-		// 获取访问模式
-		var accessModes []string
-		for _, mode := range pvc.Spec.AccessModes {
-			accessModes = append(accessModes, string(mode))
-		}
-
-		// 获取容量
-		capacity := ""
-		if pvc.Status.Capacity != nil {
-			if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
-				capacity = storage.String()
-			}
-		}
+	vm.client.Logger().Debug("listed PVCs with filter", zap.String("namespace", targetNamespace), zap.Int("count", len(pvcList.Items)))
 
-		// 获取存储类名称
-		storageClass := ""
-		if pvc.Spec.StorageClassName != nil {
-			storageClass = *pvc.Spec.StorageClassName
-		}
+	// 一次性查询 Pod 并按 PVC 建立索引，避免每个 PVC 都查询一次 Pod 列表
+	boundPodsIndex, err := vm.indexPVCBoundPods(targetNamespace)
+	if err != nil {
+		vm.client.Logger().Warn("failed to index pods bound to PVCs", zap.String("namespace", targetNamespace), zap.Error(err))
+	}
 
-		// 获取绑定的Pod列表
-		boundPods, err := vm.GetPVCBoundPods(pvc.Name, pvc.Namespace)
-		if err != nil {
-			log.Printf("获取PVC '%s' 绑定的Pod列表失败: %v", pvc.Name, err)
+	var pvcInfos []PVCInfo
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		boundPods := boundPodsIndex[pvcIndexKey(pvc.Namespace, pvc.Name)]
+		if boundPodsIndex == nil {
 			boundPods = []string{} // 如果获取失败，设置为空列表
 		}
-
-		pvcInfo := PVCInfo{
-			Name:         pvc.Name,
-			Namespace:    pvc.Namespace,
-			Status:       string(pvc.Status.Phase),
-			VolumeName:   pvc.Spec.VolumeName,
-			StorageClass: storageClass,
-			Capacity:     capacity,
-			AccessModes:  accessModes,
-			Labels:       pvc.Labels,
-			CreationTime: pvc.CreationTimestamp.Format("2006-01-02 15:04:05"),
-			Pods:         boundPods,
-		}
-		pvcInfos = append(pvcInfos, pvcInfo)
+		pvcInfos = append(pvcInfos, pvcToInfo(pvc, boundPods))
 	}
 
 	return pvcInfos, nil
@@ -697,7 +672,8 @@ func (vm *VolumeManager) CreateHostPathPV(name, hostPath, nodeName string, stora
 		pv.Spec.StorageClassName = storageClassName
 	}
 
-	log.Printf("正在创建 HostPath PV '%s'，路径: %s，节点: %s，存储大小: %s", name, hostPath, nodeName, storageQuantity)
+	vm.client.Logger().Info("creating HostPath PV",
+		zap.String("pv", name), zap.String("path", hostPath), zap.String("node", nodeName), zap.String("size", storageQuantity))
 
 	// 创建PV
 	createdPV, err := vm.client.clientset.CoreV1().PersistentVolumes().Create(context.Background(), pv, metav1.CreateOptions{})
@@ -705,7 +681,7 @@ func (vm *VolumeManager) CreateHostPathPV(name, hostPath, nodeName string, stora
 		return nil, fmt.Errorf("创建 PV '%s' 失败: %w", name, err)
 	}
 
-	log.Printf("成功创建 HostPath PV '%s'", name)
+	vm.client.Logger().Info("created HostPath PV", zap.String("pv", name))
 	return createdPV, nil
 }
 
@@ -789,20 +765,24 @@ func (vm *VolumeManager) CreateHostPathPVCWithPV(name, hostPath, nodeName, acces
 		pvc.Spec.StorageClassName = &storageClassName
 	}
 
-	log.Printf("正在创建 PVC '%s'，绑定到 PV '%s'，存储大小: %s，访问模式: %s，节点: %s，存储类: %s", name, pvName, storageQuantity, accessMode, nodeName, storageClassName)
+	vm.client.Logger().Info("creating PVC bound to PV",
+		zap.String("namespace", vm.client.namespace), zap.String("pvc", name), zap.String("pv", pvName),
+		zap.String("size", storageQuantity), zap.String("access_mode", accessMode), zap.String("node", nodeName),
+		zap.String("storage_class", storageClassName))
 
 	// 创建PVC
 	createdPVC, err := vm.client.clientset.CoreV1().PersistentVolumeClaims(vm.client.namespace).Create(context.Background(), pvc, metav1.CreateOptions{})
 	if err != nil {
 		// 如果PVC创建失败，尝试清理已创建的PV
-		log.Printf("PVC创建失败，正在清理PV '%s'", pvName)
+		vm.client.Logger().Warn("PVC creation failed, cleaning up PV",
+			zap.String("namespace", vm.client.namespace), zap.String("pvc", name), zap.String("pv", pvName), zap.Error(err))
 		if deleteErr := vm.client.clientset.CoreV1().PersistentVolumes().Delete(context.Background(), pvName, metav1.DeleteOptions{}); deleteErr != nil {
-			log.Printf("清理PV失败: %v", deleteErr)
+			vm.client.Logger().Error("failed to clean up PV", zap.String("pv", pvName), zap.Error(deleteErr))
 		}
 		return nil, fmt.Errorf("创建 PVC '%s' 失败: %w", name, err)
 	}
 
-	log.Printf("成功创建 PVC '%s'，已绑定到 PV '%s'", name, pvName)
+	vm.client.Logger().Info("created PVC bound to PV", zap.String("namespace", vm.client.namespace), zap.String("pvc", name), zap.String("pv", pvName))
 
 	// 转换为PVCInfo结构返回
 	var accessModeStrings []string
@@ -819,7 +799,8 @@ func (vm *VolumeManager) CreateHostPathPVCWithPV(name, hostPath, nodeName, acces
 	// 获取绑定的Pod列表（新创建的PVC通常没有绑定的Pod）
 	boundPods, err := vm.GetPVCBoundPods(createdPVC.Name, createdPVC.Namespace)
 	if err != nil {
-		log.Printf("获取PVC '%s' 绑定的Pod列表失败: %v", createdPVC.Name, err)
+		vm.client.Logger().Warn("failed to get pods bound to PVC",
+			zap.String("namespace", createdPVC.Namespace), zap.String("pvc", createdPVC.Name), zap.Error(err))
 		boundPods = []string{} // 如果获取失败，设置为空列表
 	}
 
@@ -0,0 +1,116 @@
+package k8s_test
+
+import (
+	"fmt"
+	"testing"
+
+	"qm-mcp-server/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "mcp"
+
+// newFakeClientset 创建包含 pvcCount 个 PVC 的 fake clientset，每个 PVC 挂载到一个 Pod，另有一个不挂载 PVC 的 Pod
+func newFakeClientset(pvcCount int) *fake.Clientset {
+	objects := []runtime.Object{
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "no-volume", Namespace: testNamespace}},
+	}
+	for i := 0; i < pvcCount; i++ {
+		pvcName := fmt.Sprintf("pvc-%d", i)
+		objects = append(objects,
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: pvcName, Namespace: testNamespace}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: testNamespace},
+				Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+					},
+				}}},
+			},
+		)
+	}
+	return fake.NewSimpleClientset(objects...)
+}
+
+// countPodLists 统计 fake clientset 收到的 Pod 列表请求次数
+func countPodLists(clientset *fake.Clientset) int {
+	count := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestListPVCsPodListCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		list func(vm *k8s.VolumeManager) ([]k8s.PVCInfo, error)
+	}{
+		{
+			name: "ListPVCs",
+			list: func(vm *k8s.VolumeManager) ([]k8s.PVCInfo, error) { return vm.ListPVCs(testNamespace) },
+		},
+		{
+			name: "ListPVCsWithFilter",
+			list: func(vm *k8s.VolumeManager) ([]k8s.PVCInfo, error) {
+				return vm.ListPVCsWithFilter("", "", testNamespace)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const pvcCount = 20
+			clientset := newFakeClientset(pvcCount)
+			vm := k8s.NewClientForClientset(clientset, testNamespace).Volume()
+
+			pvcInfos, err := tt.list(vm)
+			if err != nil {
+				t.Fatalf("list PVCs error = %v", err)
+			}
+			if len(pvcInfos) != pvcCount {
+				t.Fatalf("got %d PVCs, want %d", len(pvcInfos), pvcCount)
+			}
+			for _, info := range pvcInfos {
+				want := "pod-" + info.Name[len("pvc-"):]
+				if len(info.Pods) != 1 || info.Pods[0] != want {
+					t.Errorf("PVC %s pods = %v, want [%s]", info.Name, info.Pods, want)
+				}
+			}
+			if got := countPodLists(clientset); got != 1 {
+				t.Errorf("pod list calls = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestGetPVCBoundPods(t *testing.T) {
+	vm := k8s.NewClientForClientset(newFakeClientset(3), testNamespace).Volume()
+
+	pods, err := vm.GetPVCBoundPods("pvc-1", testNamespace)
+	if err != nil {
+		t.Fatalf("GetPVCBoundPods() error = %v", err)
+	}
+	if len(pods) != 1 || pods[0] != "pod-1" {
+		t.Errorf("GetPVCBoundPods() = %v, want [pod-1]", pods)
+	}
+}
+
+func BenchmarkListPVCs(b *testing.B) {
+	clientset := newFakeClientset(200)
+	vm := k8s.NewClientForClientset(clientset, testNamespace).Volume()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vm.ListPVCs(testNamespace); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(countPodLists(clientset))/float64(b.N), "podlists/op")
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// RequestIDHeader 请求 ID 的 HTTP 头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey 上下文中请求 ID 的键，gin.Context 与 request context 共用
+	RequestIDKey = "RequestID"
+)
+
+// NewRequestID 生成新的请求 ID
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// WithRequestID 将请求 ID 写入上下文
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// RequestIDFromContext 从上下文获取请求 ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
+// FromContext 获取带请求 ID 字段的日志实例，上下文中没有请求 ID 时返回默认日志实例
+func FromContext(ctx context.Context) *Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return With(zap.String("request_id", requestID))
+	}
+	return defaultLogger
+}
@@ -0,0 +1,33 @@
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestRequestIDFromContext(t *testing.T) {
+	if err := logger.Init("info", "json"); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "Empty", ctx: context.Background(), want: ""},
+		{name: "WithRequestID", ctx: logger.WithRequestID(context.Background(), "req-1"), want: "req-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logger.RequestIDFromContext(tt.ctx); got != tt.want {
+				t.Errorf("RequestIDFromContext() = %q, want %q", got, tt.want)
+			}
+			if logger.FromContext(tt.ctx) == nil {
+				t.Errorf("FromContext() returned nil")
+			}
+		})
+	}
+}
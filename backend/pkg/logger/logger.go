@@ -10,7 +10,11 @@ type Logger struct {
 	*zap.Logger
 }
 
-var defaultLogger *Logger
+var (
+	defaultLogger *Logger
+	// atomicLevel 默认日志实例的级别，支持运行时修改
+	atomicLevel zap.AtomicLevel
+)
 
 // Init 初始化日志配置
 func Init(level string, format string) error {
@@ -30,7 +34,8 @@ func Init(level string, format string) error {
 	}
 
 	// 配置日志
-	cfg.Level = zap.NewAtomicLevelAt(logLevel)
+	atomicLevel = zap.NewAtomicLevelAt(logLevel)
+	cfg.Level = atomicLevel
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	cfg.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 	cfg.Development = true
@@ -48,6 +53,21 @@ func Init(level string, format string) error {
 	return nil
 }
 
+// SetLevel 运行时修改日志级别，配置热加载时调用
+func SetLevel(text string) error {
+	var logLevel zapcore.Level
+	if err := logLevel.UnmarshalText([]byte(text)); err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(logLevel)
+	return nil
+}
+
+// Level 当前日志级别
+func Level() string {
+	return atomicLevel.String()
+}
+
 // Sync 同步日志
 func Sync() error {
 	return defaultLogger.Sync()
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"strconv"
+
+	"qm-mcp-server/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionMiddleware API 版本协商中间件
+// 读取 X-API-Version 请求头写入请求上下文，service 层据此选择响应格式，响应头回写实际使用的版本
+func APIVersionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := common.ParseAPIVersion(c.GetHeader(common.APIVersionHeader))
+		c.Request = c.Request.WithContext(common.SetAPIVersionToContext(c.Request.Context(), version))
+		c.Header(common.APIVersionHeader, strconv.Itoa(version))
+
+		c.Next()
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/i18n"
 	"qm-mcp-server/pkg/jwt"
 	"qm-mcp-server/pkg/logger"
@@ -22,6 +23,11 @@ var SkipPaths = []string{
 	"/authz/refresh",
 	"/authz/validate",
 	"/market/code/download",
+	"/market/assets/icons/",
+	"/market/openapi.json",
+	"/market/swagger",
+	"/authz/openapi.json",
+	"/authz/swagger",
 }
 
 // AuthTokenMiddleware 用户token验证中间件
@@ -77,6 +83,8 @@ func AuthTokenMiddleware(secret string) gin.HandlerFunc {
 		// 检查令牌是否有效
 		c.Set("userId", claims.UserID)
 		c.Set("username", claims.Username)
+		ctx := common.SetActorToContext(c.Request.Context(), claims.Username)
+		c.Request = c.Request.WithContext(common.SetUserIDToContext(ctx, uint(claims.UserID)))
 		c.Next()
 	}
 }
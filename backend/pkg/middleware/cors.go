@@ -41,7 +41,8 @@ func CORSMiddleware(domains []string) gin.HandlerFunc {
 		}
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, HEAD")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Request-ID, X-API-Version, Idempotency-Key, X-Impersonate-User")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, X-API-Version, Idempotent-Replayed, X-Impersonated-User, X-Next-Page-Token")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400") // 预检请求结果缓存24小时
 
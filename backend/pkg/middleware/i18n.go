@@ -18,11 +18,12 @@ func I18nMiddleware() gin.HandlerFunc {
 		// 解析为支持的语言类型
 		supportedLang := parseSupportedLanguage(lang)
 
-		// 将语言代码存储到上下文中
+		// 将语言代码存储到 Gin 上下文和请求上下文中，service 层可通过 FormatWithContext 获取
 		i18nresp.SetLanguageToGin(c, supportedLang)
+		c.Request = c.Request.WithContext(i18nresp.SetLanguageToContext(c.Request.Context(), supportedLang))
 
 		// 设置响应头，告知客户端当前使用的语言
-		c.Header("Accept-Language", string(supportedLang))
+		c.Header("Content-Language", string(supportedLang))
 
 		c.Next()
 	}
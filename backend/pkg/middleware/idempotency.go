@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader 幂等键请求头，也可以在 JSON 请求体的 idempotencyKey 字段中传递
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader 返回重放的原始响应时设置的响应头
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL 幂等键及其响应的保留时间
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// idempotencyKeyField 请求体中的幂等键字段
+	idempotencyKeyField = "idempotencyKey"
+	// maxIdempotencyKeyLength 幂等键最大长度
+	maxIdempotencyKeyLength = 255
+	// idempotencyPendingTTL 处理中记录的保留时间，进程异常退出时避免键被长期占用
+	idempotencyPendingTTL = 5 * time.Minute
+	// idempotencyWaitTimeout 等待相同幂等键的请求处理完成的最长时间
+	idempotencyWaitTimeout = 60 * time.Second
+	// idempotencyPollInterval 等待期间查询记录的间隔
+	idempotencyPollInterval = 100 * time.Millisecond
+)
+
+// IdempotencyStore 幂等记录存储，多个 market 副本共享同一份记录
+type IdempotencyStore interface {
+	// Claim 键不存在时写入 value 并返回 true，键已存在时返回已有的值，已有的值恰好过期时返回 nil
+	Claim(key string, value []byte, ttl time.Duration) ([]byte, bool, error)
+	// Set 覆盖写入
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete 删除记录
+	Delete(key string) error
+}
+
+// idempotencyRecord 幂等记录，请求处理完成前 Completed 为 false
+type idempotencyRecord struct {
+	// Fingerprint 请求方法、路径和请求体的摘要，相同幂等键的不同请求返回冲突
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// idempotencyWriter 记录响应体，请求成功后保存用于重放
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 创建接口的幂等中间件
+// 请求携带幂等键时，相同用户在同一接口上重复提交相同请求返回首次成功的响应而不会重复创建资源；
+// 幂等键被不同请求使用时返回 409，相同请求并发提交时后到的请求等待首个请求完成后重放其响应。
+// 只保存成功的响应，失败的请求会释放幂等键以便客户端重试；存储不可用时不做幂等校验
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		// 试运行不创建资源，不占用幂等键，之后使用相同幂等键的正式请求照常执行
+		if isDryRun(c, body) {
+			c.Next()
+			return
+		}
+		key := idempotencyKey(c, body)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			i18n.ErrorResponseWithArgs(c, i18n.CodeIdempotencyKeyInvalid, fmt.Sprintf("longer than %d characters", maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		storeKey := idempotencyStoreKey(c, key)
+		fingerprint := idempotencyFingerprint(c, body)
+		pending, _ := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint})
+
+		deadline := time.Now().Add(idempotencyWaitTimeout)
+		for {
+			existing, claimed, err := store.Claim(storeKey, pending, idempotencyPendingTTL)
+			if err != nil {
+				logger.Warn("幂等记录存储不可用，跳过幂等校验", zap.String("path", c.FullPath()), zap.Error(err))
+				c.Next()
+				return
+			}
+			if claimed {
+				break
+			}
+			if existing == nil {
+				// 已有记录刚好被删除或过期，重新占用
+				continue
+			}
+
+			var record idempotencyRecord
+			if err := json.Unmarshal(existing, &record); err != nil {
+				logger.Warn("幂等记录格式错误，跳过幂等校验", zap.String("path", c.FullPath()), zap.Error(err))
+				c.Next()
+				return
+			}
+			if record.Fingerprint != fingerprint {
+				i18n.ErrorResponseWithArgs(c, i18n.CodeIdempotencyKeyConflict)
+				c.Abort()
+				return
+			}
+			if record.Completed {
+				c.Header(IdempotencyReplayedHeader, "true")
+				c.Data(record.Status, record.ContentType, record.Body)
+				c.Abort()
+				return
+			}
+
+			// 相同请求仍在处理中，等待其完成后重放响应
+			if time.Now().After(deadline) {
+				i18n.ErrorResponseWithArgs(c, i18n.CodeIdempotencyRequestInProgress)
+				c.Abort()
+				return
+			}
+			select {
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			case <-time.After(idempotencyPollInterval):
+			}
+		}
+
+		completed := false
+		defer func() {
+			// 请求失败或处理器 panic 时释放幂等键，允许客户端重试
+			if completed {
+				return
+			}
+			if err := store.Delete(storeKey); err != nil {
+				logger.Warn("释放幂等键失败", zap.String("path", c.FullPath()), zap.Error(err))
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if !idempotencySucceeded(writer.Status(), writer.body.Bytes()) {
+			return
+		}
+		record, _ := json.Marshal(&idempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      writer.Status(),
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		if err := store.Set(storeKey, record, ttl); err != nil {
+			logger.Warn("保存幂等响应失败", zap.String("path", c.FullPath()), zap.Error(err))
+			return
+		}
+		completed = true
+	}
+}
+
+// isDryRun 请求是否要求试运行，查询参数 ?dryRun=true 或 JSON 请求体中的 "dryRun": true
+func isDryRun(c *gin.Context, body []byte) bool {
+	if dryRun, _ := strconv.ParseBool(c.Query("dryRun")); dryRun {
+		return true
+	}
+	var fields struct {
+		DryRun bool `json:"dryRun"`
+	}
+	return len(body) > 0 && json.Unmarshal(body, &fields) == nil && fields.DryRun
+}
+
+// idempotencyKey 读取幂等键，请求头优先于 JSON 请求体中的字段
+func idempotencyKey(c *gin.Context, body []byte) string {
+	if key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader)); key != "" {
+		return key
+	}
+	contentType := c.GetHeader("Content-Type")
+	if len(body) == 0 || (contentType != "" && !strings.HasPrefix(contentType, "application/json")) {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	var key string
+	if err := json.Unmarshal(fields[idempotencyKeyField], &key); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+// idempotencyStoreKey 幂等键按接口和用户隔离，摘要后存储以限制键长度
+func idempotencyStoreKey(c *gin.Context, key string) string {
+	userID, _ := c.Get("userId")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s %s\n%v\n%s", c.Request.Method, c.FullPath(), userID, key)))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyFingerprint 请求摘要，用于识别幂等键是否被不同的请求使用
+func idempotencyFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencySucceeded 响应是否成功，业务错误码非 0 的响应同样视为失败
+func idempotencySucceeded(status int, body []byte) bool {
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return false
+	}
+	var envelope struct {
+		Code *int `json:"code"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Code == nil {
+		return true
+	}
+	return *envelope.Code == i18n.CodeSuccess
+}
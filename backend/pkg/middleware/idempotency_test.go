@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore in-memory IdempotencyStore for tests
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string][]byte)}
+}
+
+func (m *memoryIdempotencyStore) Claim(key string, value []byte, ttl time.Duration) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[key]; ok {
+		return existing, false, nil
+	}
+	m.records[key] = value
+	return nil, true, nil
+}
+
+func (m *memoryIdempotencyStore) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = value
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+// newIdempotencyRouter 创建接口计数创建次数，fail 返回 true 时模拟创建失败
+func newIdempotencyRouter(store IdempotencyStore, created *int32, fail func() bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/instance/create", IdempotencyMiddleware(store, DefaultIdempotencyTTL), func(c *gin.Context) {
+		// 模拟较慢的创建，使并发的重复请求在首个请求完成前到达
+		time.Sleep(50 * time.Millisecond)
+		if fail != nil && fail() {
+			i18n.ErrorResponse(c, i18n.CodeInternalError, "create failed")
+			return
+		}
+		n := atomic.AddInt32(created, 1)
+		i18n.SuccessResponse(c, gin.H{"instanceId": n})
+	})
+	return router
+}
+
+func postCreate(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/instance/create", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	var created int32
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &created, nil)
+
+	const n = 10
+	responses := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = postCreate(router, "key-1", `{"name":"demo"}`)
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Fatalf("created %d instances for duplicate submissions, want 1", created)
+	}
+	replayed := 0
+	for i, w := range responses {
+		if w.Code != http.StatusOK {
+			t.Fatalf("response %d status = %d, want 200: %s", i, w.Code, w.Body.String())
+		}
+		if w.Body.String() != responses[0].Body.String() {
+			t.Errorf("response %d body = %s, want %s", i, w.Body.String(), responses[0].Body.String())
+		}
+		if w.Header().Get(IdempotencyReplayedHeader) == "true" {
+			replayed++
+		}
+	}
+	if replayed != n-1 {
+		t.Errorf("replayed responses = %d, want %d", replayed, n-1)
+	}
+
+	// 稍后重试同样返回首次的响应
+	w := postCreate(router, "key-1", `{"name":"demo"}`)
+	if created != 1 || w.Body.String() != responses[0].Body.String() {
+		t.Errorf("retry created = %d body = %s, want the original response", created, w.Body.String())
+	}
+}
+
+func TestIdempotencyKeyConflict(t *testing.T) {
+	var created int32
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &created, nil)
+
+	if w := postCreate(router, "key-1", `{"name":"a"}`); w.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", w.Code)
+	}
+	w := postCreate(router, "key-1", `{"name":"b"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("conflicting payload status = %d, want 409", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":5007`) {
+		t.Errorf("conflicting payload body = %s, want code 5007", w.Body.String())
+	}
+	if created != 1 {
+		t.Errorf("created = %d, want 1", created)
+	}
+}
+
+func TestIdempotencyFailureReleasesKey(t *testing.T) {
+	var created, attempts int32
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &created, func() bool {
+		return atomic.AddInt32(&attempts, 1) == 1
+	})
+
+	// 未登记 HTTP 状态码的业务错误返回 200，按响应体中的错误码识别失败
+	if w := postCreate(router, "key-1", `{"name":"demo"}`); !strings.Contains(w.Body.String(), `"code":1007`) {
+		t.Fatalf("first request body = %s, want code 1007", w.Body.String())
+	}
+	w := postCreate(router, "key-1", `{"name":"demo"}`)
+	if w.Code != http.StatusOK || w.Header().Get(IdempotencyReplayedHeader) != "" {
+		t.Fatalf("retry after failure status = %d replayed = %q, want a new successful request", w.Code, w.Header().Get(IdempotencyReplayedHeader))
+	}
+	if created != 1 {
+		t.Errorf("created = %d, want 1", created)
+	}
+}
+
+func TestIdempotencyKeySources(t *testing.T) {
+	var created int32
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &created, nil)
+
+	// 请求体中的幂等键
+	body := `{"name":"demo","idempotencyKey":"body-key"}`
+	postCreate(router, "", body)
+	if w := postCreate(router, "", body); w.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Errorf("request with body key was not replayed")
+	}
+
+	// 未携带幂等键时不做幂等处理
+	postCreate(router, "", `{"name":"demo"}`)
+	postCreate(router, "", `{"name":"demo"}`)
+	if created != 3 {
+		t.Errorf("created = %d, want 3", created)
+	}
+
+	if w := postCreate(router, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("overlong key status = %d, want 400", w.Code)
+	}
+}
+
+func TestIdempotencyDryRunSkipsKey(t *testing.T) {
+	var created int32
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &created, nil)
+
+	// 查询参数和请求体中的 dryRun 都按试运行处理
+	for i, target := range []struct{ url, body string }{
+		{"/instance/create?dryRun=true", `{"name":"demo"}`},
+		{"/instance/create", `{"name":"demo","dryRun":true}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, target.url, strings.NewReader(target.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Header().Get(IdempotencyReplayedHeader) != "" {
+			t.Fatalf("dry run %d was replayed", i)
+		}
+	}
+	// 试运行之后使用相同幂等键的正式请求正常执行
+	if w := postCreate(router, "key-1", `{"name":"demo"}`); w.Code != http.StatusOK || w.Header().Get(IdempotencyReplayedHeader) != "" {
+		t.Errorf("request after dry runs status = %d replayed = %q, want a new successful request", w.Code, w.Header().Get(IdempotencyReplayedHeader))
+	}
+	if created != 3 {
+		t.Errorf("created = %d, want 3", created)
+	}
+}
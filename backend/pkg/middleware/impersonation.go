@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// ImpersonateUserHeader 管理员模拟的用户，值为用户ID或用户名
+	ImpersonateUserHeader = "X-Impersonate-User"
+	// ImpersonatedUserHeader 模拟生效时的响应头，值为被模拟的用户名，前端据此展示提示
+	ImpersonatedUserHeader = "X-Impersonated-User"
+)
+
+// ImpersonationUser 模拟前后的用户身份
+type ImpersonationUser struct {
+	UserID   int64
+	Username string
+	IsAdmin  bool
+}
+
+// ImpersonationPolicy 管理员模拟用户的策略
+type ImpersonationPolicy struct {
+	// IsAdmin 判断当前登录用户是否为管理员
+	IsAdmin func(c *gin.Context) (bool, error)
+	// FindUser 按用户ID或用户名查找要模拟的用户，不存在时返回 nil
+	FindUser func(ctx context.Context, user string) (*ImpersonationUser, error)
+	// Audit 请求处理完成后记录审计日志，包含管理员和被模拟的用户
+	Audit func(c *gin.Context, impersonator, user *ImpersonationUser)
+}
+
+// DefaultImpersonationPolicy 基于用户表和审计日志表的模拟策略，市场服务和认证服务共用，
+// 同一请求在两个服务中的操作人和审计记录一致
+func DefaultImpersonationPolicy() ImpersonationPolicy {
+	return ImpersonationPolicy{
+		IsAdmin:  isAdminUser,
+		FindUser: findImpersonationUser,
+		Audit:    recordImpersonation,
+	}
+}
+
+// isAdminUser 判断当前登录用户是否为管理员
+func isAdminUser(c *gin.Context) (bool, error) {
+	user, err := mysql.SysUserRepo.FindByID(c.Request.Context(), uint(c.GetInt64("userId")))
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.IsAdmin, nil
+}
+
+// findImpersonationUser 按用户ID或用户名查找要模拟的用户
+func findImpersonationUser(ctx context.Context, ref string) (*ImpersonationUser, error) {
+	var user *model.SysUser
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		users, err := mysql.SysUserRepo.FindByIDs(ctx, []uint{uint(id)})
+		if err != nil {
+			return nil, err
+		}
+		if len(users) > 0 {
+			user = users[0]
+		}
+	} else {
+		exists, err := mysql.SysUserRepo.ExistsByUsername(ctx, ref)
+		if err != nil || !exists {
+			return nil, err
+		}
+		if user, err = mysql.SysUserRepo.FindByUsername(ctx, ref); err != nil {
+			return nil, err
+		}
+	}
+	if user == nil || user.Username == nil {
+		return nil, nil
+	}
+	return &ImpersonationUser{UserID: int64(user.UserID), Username: *user.Username, IsAdmin: user.IsAdmin}, nil
+}
+
+// recordImpersonation 记录模拟用户执行的请求，操作人为管理员，同一请求中的其他审计日志以被模拟的用户为操作人，可按请求ID关联
+func recordImpersonation(c *gin.Context, impersonator, user *ImpersonationUser) {
+	ctx := c.Request.Context()
+	detail, _ := json.Marshal(map[string]any{
+		"impersonator":   impersonator.Username,
+		"impersonatorId": impersonator.UserID,
+		"user":           user.Username,
+		"userId":         user.UserID,
+		"method":         c.Request.Method,
+		"path":           c.Request.URL.Path,
+		"status":         c.Writer.Status(),
+	})
+	record := &model.SysAuditLog{
+		Actor:        impersonator.Username,
+		Action:       model.AuditActionUserImpersonate,
+		ResourceType: model.AuditResourceUser,
+		ResourceID:   strconv.FormatInt(user.UserID, 10),
+		Detail:       detail,
+		RequestID:    logger.RequestIDFromContext(ctx),
+	}
+	if err := mysql.SysAuditLogRepo.Create(ctx, record); err != nil {
+		logger.FromContext(ctx).Warn("Failed to record audit log", zap.String("action", record.Action),
+			zap.String("resourceId", record.ResourceID), zap.Error(err))
+	}
+}
+
+// ImpersonationMiddleware 管理员模拟用户中间件，注册在认证中间件之后、只读角色中间件之前
+// 请求带 X-Impersonate-User 时，管理员以目标用户的身份和权限执行请求，不能模拟其他管理员；
+// 每个模拟请求都记录审计日志，响应带 X-Impersonated-User 头
+func ImpersonationMiddleware(policy ImpersonationPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target := c.GetHeader(ImpersonateUserHeader)
+		if _, ok := c.Get("userId"); !ok || target == "" {
+			c.Next()
+			return
+		}
+
+		admin, err := policy.IsAdmin(c)
+		if err != nil {
+			logger.Warn("读取用户权限失败，拒绝模拟用户", zap.String("path", c.FullPath()), zap.Error(err))
+		}
+		if !admin {
+			i18n.ErrorWithCode(c, i18n.CodeImpersonationForbidden)
+			c.Abort()
+			return
+		}
+		user, err := policy.FindUser(c.Request.Context(), target)
+		if err != nil {
+			logger.Error("查找模拟用户失败", zap.String("user", target), zap.Error(err))
+			i18n.ErrorWithCode(c, i18n.CodeDatabaseError)
+			c.Abort()
+			return
+		}
+		if user == nil {
+			i18n.ErrorResponseWithArgs(c, i18n.CodeImpersonationUserNotFound, target)
+			c.Abort()
+			return
+		}
+		if user.IsAdmin {
+			i18n.ErrorResponseWithArgs(c, i18n.CodeImpersonationAdminRefused, user.Username)
+			c.Abort()
+			return
+		}
+
+		impersonator := &ImpersonationUser{UserID: c.GetInt64("userId"), Username: c.GetString("username"), IsAdmin: true}
+		c.Set("userId", user.UserID)
+		c.Set("username", user.Username)
+		ctx := common.SetActorToContext(c.Request.Context(), user.Username)
+		ctx = common.SetImpersonatorIDToContext(ctx, uint(impersonator.UserID))
+		c.Request = c.Request.WithContext(common.SetUserIDToContext(ctx, uint(user.UserID)))
+		c.Header(ImpersonatedUserHeader, user.Username)
+		logger.Info("管理员模拟用户", zap.String("impersonator", impersonator.Username),
+			zap.String("user", user.Username), zap.String("method", c.Request.Method), zap.String("path", c.Request.URL.Path))
+
+		c.Next()
+		policy.Audit(c, impersonator, user)
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newImpersonationRouter(admin bool, audits *[]string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userId", int64(1))
+		c.Set("username", "root")
+	})
+	users := map[string]*ImpersonationUser{
+		"alice": {UserID: 7, Username: "alice"},
+		"7":     {UserID: 7, Username: "alice"},
+		"ops":   {UserID: 2, Username: "ops", IsAdmin: true},
+	}
+	router.Use(ImpersonationMiddleware(ImpersonationPolicy{
+		IsAdmin: func(c *gin.Context) (bool, error) { return admin, nil },
+		FindUser: func(ctx context.Context, user string) (*ImpersonationUser, error) {
+			return users[user], nil
+		},
+		Audit: func(c *gin.Context, impersonator, user *ImpersonationUser) {
+			*audits = append(*audits, impersonator.Username+">"+user.Username)
+		},
+	}))
+	router.GET("/instance/list", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		c.JSON(http.StatusOK, gin.H{
+			"code":     0,
+			"userId":   c.GetInt64("userId"),
+			"username": c.GetString("username"),
+			"actor":    common.ActorFromContext(ctx),
+			"ctxUser":  common.UserIDFromContext(ctx),
+			"realUser": common.ImpersonatorIDFromContext(ctx),
+		})
+	})
+	return router
+}
+
+func TestImpersonationMiddleware(t *testing.T) {
+	logger.Init("error", "json")
+	tests := []struct {
+		name       string
+		admin      bool
+		header     string
+		wantStatus int
+		wantBody   string
+		wantHeader string
+		wantAudits int
+	}{
+		{"no header", true, "", http.StatusOK, `"realUser":0`, "", 0},
+		{"impersonate by username", true, "alice", http.StatusOK, `"actor":"alice","code":0,"ctxUser":7,"realUser":1,"userId":7,"username":"alice"`, "alice", 1},
+		{"impersonate by id", true, "7", http.StatusOK, `"userId":7`, "alice", 1},
+		{"not an admin", false, "alice", http.StatusForbidden, `"code":8960`, "", 0},
+		{"unknown user", true, "bob", http.StatusNotFound, `"code":8961`, "", 0},
+		{"admin target", true, "ops", http.StatusForbidden, `"code":8962`, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audits []string
+			router := newImpersonationRouter(tt.admin, &audits)
+			req := httptest.NewRequest(http.MethodGet, "/instance/list", nil)
+			if tt.header != "" {
+				req.Header.Set(ImpersonateUserHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get(ImpersonatedUserHeader); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", ImpersonatedUserHeader, got, tt.wantHeader)
+			}
+			if len(audits) != tt.wantAudits || (tt.wantAudits > 0 && audits[0] != "root>alice") {
+				t.Errorf("audits = %v, want %d", audits, tt.wantAudits)
+			}
+		})
+	}
+}
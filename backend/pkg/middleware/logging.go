@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 	"qm-mcp-server/pkg/logger"
 	"strings"
 	"time"
@@ -62,7 +63,7 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 				// 将解析后的 JSON 请求体添加到日志字段中
 				logFields = append(logFields, zap.Any("json", jsonBody))
 				// 立即使用 logFields 记录请求日志
-				logger.Info("收到请求", logFields...)
+				logger.FromContext(c.Request.Context()).Info("收到请求", logFields...)
 			}
 		}
 
@@ -76,12 +77,9 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 		// 记录响应信息
 		latency := time.Since(start)
 		// 检查Content-Type是否为流式数据或下载数据
-		responseContentType := c.Writer.Header().Get("Content-Type")
-		if strings.Contains(responseContentType, "text/event-stream") ||
-			strings.Contains(responseContentType, "application/octet-stream") ||
-			strings.Contains(c.Writer.Header().Get("Content-Disposition"), "attachment") {
+		if isStreamOrDownload(c.Writer.Header()) {
 			// 流式数据和下载数据只记录基本信息
-			logger.Info("请求完成",
+			logger.FromContext(c.Request.Context()).Info("请求完成",
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.Int("status", c.Writer.Status()),
@@ -89,7 +87,7 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 			)
 		} else {
 			// 其他数据记录完整响应
-			logger.Info("请求完成",
+			logger.FromContext(c.Request.Context()).Info("请求完成",
 				zap.String("method", c.Request.Method),
 				zap.String("path", c.Request.URL.Path),
 				zap.Int("status", c.Writer.Status()),
@@ -100,13 +98,23 @@ func RequestResponseLoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-// bodyLogWriter 自定义的 ResponseWriter，用于捕获响应体
+// bodyLogWriter 自定义的 ResponseWriter，用于捕获响应体，流式数据和下载数据不捕获，避免大文件缓存在内存中
 type bodyLogWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if !isStreamOrDownload(w.Header()) {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
+
+// isStreamOrDownload 响应是否为流式数据或下载数据
+func isStreamOrDownload(header http.Header) bool {
+	contentType := header.Get("Content-Type")
+	return strings.Contains(contentType, "text/event-stream") ||
+		strings.Contains(contentType, "application/octet-stream") ||
+		strings.Contains(header.Get("Content-Disposition"), "attachment")
+}
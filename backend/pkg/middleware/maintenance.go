@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MaintenanceMiddleware 维护模式中间件，注册在修改实例、模板和环境的接口上
+// 维护期间返回 423 及预计结束时间，查询接口和网关代理不受影响；状态读取失败时放行请求
+func MaintenanceMiddleware(getState func() (*common.MaintenanceState, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := getState()
+		if err != nil {
+			logger.Warn("读取维护模式状态失败，跳过维护检查", zap.String("path", c.FullPath()), zap.Error(err))
+			c.Next()
+			return
+		}
+		if state == nil {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		data := gin.H{
+			"message":     state.Message,
+			"startedAt":   common.FormatTimeRFC3339(ctx, time.UnixMilli(state.StartedAt)),
+			"startedAtMs": state.StartedAt,
+		}
+		if state.EndAt > 0 {
+			endAt := common.FormatTimeRFC3339(ctx, time.UnixMilli(state.EndAt))
+			data["endAt"] = endAt
+			data["endAtMs"] = state.EndAt
+			i18n.ErrorWithData(c, i18n.CodeMaintenanceInProgressUntil, i18n.GetLocalizedMessageWithGin(c, i18n.CodeMaintenanceInProgressUntil, endAt), data)
+		} else {
+			i18n.ErrorWithData(c, i18n.CodeMaintenanceInProgress, "", data)
+		}
+		c.Abort()
+	}
+}
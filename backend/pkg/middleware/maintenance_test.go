@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newMaintenanceRouter(state *common.MaintenanceState, err error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	maintenance := MaintenanceMiddleware(func() (*common.MaintenanceState, error) {
+		return state, err
+	})
+	router.POST("/instance/create", maintenance, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0})
+	})
+	router.POST("/instance/list", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0})
+	})
+	return router
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	logger.Init("error", "json")
+	endAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		state      *common.MaintenanceState
+		err        error
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"not in maintenance", nil, nil, "/instance/create", http.StatusOK, `"code":0`},
+		{"state unavailable", nil, errors.New("redis down"), "/instance/create", http.StatusOK, `"code":0`},
+		{"maintenance without end time", &common.MaintenanceState{Message: "upgrading"}, nil, "/instance/create", http.StatusLocked, `"code":5009`},
+		{"maintenance with end time", &common.MaintenanceState{EndAt: endAt.UnixMilli()}, nil, "/instance/create", http.StatusLocked, `"endAtMs":1714564800000`},
+		{"read endpoint", &common.MaintenanceState{}, nil, "/instance/list", http.StatusOK, `"code":0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newMaintenanceRouter(tt.state, tt.err)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"qm-mcp-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestIDLength 客户端传入的请求 ID 最大长度，超出或包含非法字符时重新生成
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware 请求 ID 中间件
+// 沿用请求头中的 X-Request-ID，缺失时生成新的 ID；写入 gin 上下文、请求上下文和请求头（供代理和服务间调用透传），并在响应头中返回
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logger.RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = logger.NewRequestID()
+		}
+
+		c.Set(logger.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Request.Header.Set(logger.RequestIDHeader, requestID)
+		c.Header(logger.RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// isValidRequestID 校验客户端传入的请求 ID，仅允许可打印 ASCII 字符
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
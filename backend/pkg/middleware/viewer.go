@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/i18n"
+	"qm-mcp-server/pkg/logger"
+	"qm-mcp-server/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ViewerPolicy 只读角色的访问策略，路由为注册时的完整路径（c.FullPath()）
+type ViewerPolicy struct {
+	// IsViewer 判断当前用户是否为只读角色
+	IsViewer func(c *gin.Context) (bool, error)
+	// ReadRoutes 使用 POST 提交查询条件的只读接口，只读角色可以访问
+	ReadRoutes []string
+	// DeniedRoutes 只读角色不能访问的查询接口，如代码文件和连接凭据
+	DeniedRoutes []string
+}
+
+// redactedEnvFields 环境变量字段，只读角色只能看到变量名
+var redactedEnvFields = []string{"environmentvariables", "resolvedenvironmentvariables", "envvars", "envs"}
+
+// redactedServerFields mcpServers 配置中每个服务的环境变量和请求头字段，只读角色只能看到名称
+var redactedServerFields = []string{"env", "headers"}
+
+// clearedFields TLS 客户端证书和私钥字段，只读角色看到空值
+var clearedFields = []string{"clientkey", "clientcert"}
+
+// proxyURLFields 代理地址字段，只读角色看到去掉 user:pass 后的地址
+var proxyURLFields = []string{"httpproxy", "httpsproxy"}
+
+// maskedFields 令牌和密码字段，只读角色看到掩码后的值
+var maskedFields = []string{"token", "newtoken", "password", "secret"}
+
+// ViewerMiddleware 只读角色中间件，注册在认证中间件之后
+// 只读角色只能访问 GET 接口和 ReadRoutes 中的查询接口，DeniedRoutes 中的接口也被拒绝；
+// 允许访问的接口的 JSON 响应统一在这里脱敏：环境变量和 mcpServers 中的 env、headers 只保留名称，令牌和密码掩码，
+// TLS 客户端证书和私钥清空，代理地址去掉凭据，配置字段（含 kubeconfig）移除。
+// 角色读取失败时按只读角色处理
+func ViewerMiddleware(policy ViewerPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("userId"); !ok {
+			c.Next()
+			return
+		}
+		viewer, err := policy.IsViewer(c)
+		if err != nil {
+			logger.Warn("读取用户角色失败，按只读角色处理", zap.String("path", c.FullPath()), zap.Error(err))
+			viewer = true
+		}
+		if !viewer {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		readable := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || slices.Contains(policy.ReadRoutes, route)
+		if !readable || slices.Contains(policy.DeniedRoutes, route) {
+			i18n.ErrorWithCode(c, i18n.CodeViewerReadOnly)
+			c.Abort()
+			return
+		}
+
+		writer := &redactWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.flushRedacted()
+	}
+}
+
+// redactWriter 缓存 JSON 响应，请求结束后脱敏写出；流式数据和下载数据直接写出
+type redactWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *redactWriter) Write(data []byte) (int, error) {
+	if w.buffered || strings.Contains(w.Header().Get("Content-Type"), "application/json") {
+		w.buffered = true
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *redactWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flushRedacted 写出脱敏后的响应，响应体不是合法 JSON 时不返回内容，避免敏感字段泄露
+func (w *redactWriter) flushRedacted() {
+	if !w.buffered {
+		return
+	}
+	data, err := RedactJSON(w.body.Bytes())
+	if err != nil {
+		logger.Warn("响应脱敏失败", zap.Error(err))
+		data = []byte("{}")
+	}
+	w.ResponseWriter.Write(data)
+}
+
+// RedactJSON 按只读角色的规则脱敏 JSON，字段名不区分大小写
+func RedactJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(value))
+}
+
+// redactValue 递归脱敏
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			name := strings.ToLower(key)
+			switch {
+			case strings.HasSuffix(name, "config"):
+				delete(v, key)
+			case slices.Contains(redactedEnvFields, name):
+				clearValues(field)
+			case name == "mcpservers":
+				v[key] = redactMcpServers(field)
+			case slices.Contains(clearedFields, name):
+				if _, ok := field.(string); ok {
+					v[key] = ""
+				}
+			case slices.Contains(proxyURLFields, name):
+				if s, ok := field.(string); ok {
+					v[key] = common.StripURLCredentials(s)
+				}
+			case slices.Contains(maskedFields, name):
+				if s, ok := field.(string); ok && s != "" {
+					v[key] = utils.MaskToken(s)
+				}
+			default:
+				v[key] = redactValue(field)
+			}
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// clearValues 清空对象中每个字段的值，只保留字段名
+func clearValues(field any) {
+	if values, ok := field.(map[string]any); ok {
+		for name := range values {
+			values[name] = ""
+		}
+	}
+}
+
+// redactMcpServers 脱敏 mcpServers 配置，清空每个服务的 env 和 headers 的值，tls 和 proxy 中的凭据由 redactValue 处理。
+// 配置可以是对象或 JSON 字符串，字符串无法解析时不返回内容
+func redactMcpServers(config any) any {
+	s, isString := config.(string)
+	if isString {
+		if s == "" {
+			return s
+		}
+		if err := json.Unmarshal([]byte(s), &config); err != nil {
+			return ""
+		}
+	}
+	root, ok := config.(map[string]any)
+	if !ok {
+		return redactValue(config)
+	}
+	servers := root
+	for key, field := range root {
+		if strings.ToLower(key) == "mcpservers" {
+			servers, _ = field.(map[string]any)
+		}
+	}
+	for _, server := range servers {
+		fields, ok := server.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, field := range fields {
+			if slices.Contains(redactedServerFields, strings.ToLower(key)) {
+				clearValues(field)
+			}
+		}
+	}
+	redacted := redactValue(root)
+	if !isString {
+		return redacted
+	}
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,222 @@
+// Package notify sends user notifications by email and Feishu (Lark) bot messages
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of an SMTP connection
+const (
+	// TLSModeStartTLS upgrades a plain connection with STARTTLS, the server must support it
+	TLSModeStartTLS = "starttls"
+	// TLSModeTLS connects with implicit TLS, usually port 465
+	TLSModeTLS = "tls"
+	// TLSModeNone sends in plain text, only for local relays
+	TLSModeNone = "none"
+)
+
+// Message an email with a plain text and an optional HTML body
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPSender sends messages through an SMTP server
+type SMTPSender struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth, no authentication when Username is empty
+	Username string
+	Password string
+	// From sender address, e.g. "MCPBox <noreply@example.com>"
+	From string
+	// TLSMode one of the TLSMode constants, defaults to starttls
+	TLSMode string
+	// InsecureSkipVerify skips verification of the server certificate
+	InsecureSkipVerify bool
+	// Timeout of the whole SMTP conversation, defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// Send implements Sender
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", s.From, err)
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	data, err := BuildMessage(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := &tls.Config{ServerName: s.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+	var conn net.Conn
+	if s.TLSMode == TLSModeTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to smtp server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if s.TLSMode == "" || s.TLSMode == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	return client.Quit()
+}
+
+// BuildMessage encodes msg as a MIME message, multipart/alternative when it has an HTML body
+func BuildMessage(from string, msg *Message, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{`text/plain; charset="utf-8"`, msg.Text},
+		{`text/html; charset="utf-8"`, msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	header("Content-Type", fmt.Sprintf(`multipart/alternative; boundary="%s"`, mw.Boundary()))
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes content with quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// SendWithRetry sends msg, retrying up to retries times with exponential backoff starting at backoff.
+// Returns the last error when every attempt failed or ctx is done
+func SendWithRetry(ctx context.Context, sender Sender, msg *Message, retries int, backoff time.Duration) error {
+	return Retry(ctx, retries, backoff, func() error {
+		return sender.Send(ctx, msg)
+	})
+}
+
+// Retry calls send until it succeeds, retrying up to retries times with exponential backoff starting at backoff.
+// Returns the last error when every attempt failed or ctx is done
+func Retry(ctx context.Context, retries int, backoff time.Duration, send func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = send(); err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("send failed after %d attempts: %w", attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("send cancelled after %d attempts: %w", attempt+1, err)
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Feishu receive id types of a message
+const (
+	FeishuReceiveOpenID  = "open_id"
+	FeishuReceiveUnionID = "union_id"
+	FeishuReceiveUserID  = "user_id"
+	FeishuReceiveChatID  = "chat_id"
+)
+
+// Feishu card header colors
+const (
+	FeishuColorBlue   = "blue"
+	FeishuColorGreen  = "green"
+	FeishuColorOrange = "orange"
+	FeishuColorRed    = "red"
+)
+
+// FeishuBaseURL open platform of Feishu, Lark uses https://open.larksuite.com
+const FeishuBaseURL = "https://open.feishu.cn"
+
+// tokenRefreshMargin refreshes the tenant access token this long before it expires
+const tokenRefreshMargin = 5 * time.Minute
+
+// Feishu error codes of an invalid or expired tenant access token
+var feishuTokenErrors = map[int]bool{99991661: true, 99991663: true, 99991668: true}
+
+// FeishuBot sends interactive cards as a Feishu (Lark) bot app.
+// Messages to the same receiver are rate limited to stay below the per chat limit of the open platform
+type FeishuBot struct {
+	AppID     string
+	AppSecret string
+	// BaseURL of the open platform, defaults to FeishuBaseURL
+	BaseURL string
+	// Timeout of one request, defaults to 10 seconds
+	Timeout time.Duration
+	// RatePerSecond messages per second to one receiver, defaults to 4
+	RatePerSecond float64
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// FeishuLink a card button opening url, buttons never call back into the platform
+type FeishuLink struct {
+	Text string
+	URL  string
+}
+
+// feishuResponse common fields of open platform responses
+type feishuResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// SendCard sends card to receiveID, receiveIDType is one of the FeishuReceive constants.
+// Waits for the rate limit of the receiver, returns early when ctx is done
+func (b *FeishuBot) SendCard(ctx context.Context, receiveIDType, receiveID string, card map[string]any) error {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("marshal card: %w", err)
+	}
+	if err := b.limiter(receiveIDType + ":" + receiveID).Wait(ctx); err != nil {
+		return fmt.Errorf("wait for rate limit: %w", err)
+	}
+	token, err := b.tenantToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]string{"receive_id": receiveID, "msg_type": "interactive", "content": string(content)}
+	endpoint := "/open-apis/im/v1/messages?receive_id_type=" + url.QueryEscape(receiveIDType)
+	var resp feishuResponse
+	if err := b.post(ctx, endpoint, token, body, &resp); err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		if feishuTokenErrors[resp.Code] {
+			b.tokenMu.Lock()
+			b.token = ""
+			b.tokenMu.Unlock()
+		}
+		return fmt.Errorf("send feishu message: code %d: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// limiter returns the rate limiter of a receiver
+func (b *FeishuBot) limiter(key string) *rate.Limiter {
+	b.limitersMu.Lock()
+	defer b.limitersMu.Unlock()
+	if b.limiters == nil {
+		b.limiters = make(map[string]*rate.Limiter)
+	}
+	limiter, ok := b.limiters[key]
+	if !ok {
+		perSecond := b.RatePerSecond
+		if perSecond <= 0 {
+			perSecond = 4
+		}
+		limiter = rate.NewLimiter(rate.Limit(perSecond), 1)
+		b.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// tenantToken returns the cached tenant access token, fetching a new one shortly before it expires
+func (b *FeishuBot) tenantToken(ctx context.Context) (string, error) {
+	b.tokenMu.Lock()
+	defer b.tokenMu.Unlock()
+	if b.token != "" && time.Until(b.tokenExpiry) > tokenRefreshMargin {
+		return b.token, nil
+	}
+
+	var resp struct {
+		feishuResponse
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"`
+	}
+	body := map[string]string{"app_id": b.AppID, "app_secret": b.AppSecret}
+	if err := b.post(ctx, "/open-apis/auth/v3/tenant_access_token/internal", "", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Code != 0 {
+		return "", fmt.Errorf("get tenant access token: code %d: %s", resp.Code, resp.Msg)
+	}
+	b.token = resp.TenantAccessToken
+	b.tokenExpiry = time.Now().Add(time.Duration(resp.Expire) * time.Second)
+	return b.token, nil
+}
+
+// post sends body as JSON to the open platform and decodes the response into out
+func (b *FeishuBot) post(ctx context.Context, endpoint, token string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	baseURL := b.BaseURL
+	if baseURL == "" {
+		baseURL = FeishuBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read response of %s: %w", endpoint, err)
+	}
+	// business errors come with non-2xx status codes too, prefer the code in the body
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("request %s: status %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+	return nil
+}
+
+// FeishuInstanceCard builds an interactive card for an instance event, links are rendered as buttons
+func FeishuInstanceCard(event InstanceEvent, color string, links ...FeishuLink) map[string]any {
+	field := func(label, value string) map[string]any {
+		return map[string]any{
+			"is_short": true,
+			"text":     map[string]any{"tag": "plain_text", "content": label + ": " + value},
+		}
+	}
+	fields := []any{
+		field("Instance", event.InstanceName),
+		field("Event", event.Event),
+		field("Time", event.Time.UTC().Format("2006-01-02 15:04:05 MST")),
+	}
+	if event.Status != "" {
+		fields = append(fields, field("Status", event.Status))
+	}
+	elements := []any{map[string]any{"tag": "div", "fields": fields}}
+	if event.Message != "" {
+		elements = append(elements, map[string]any{
+			"tag":  "div",
+			"text": map[string]any{"tag": "plain_text", "content": event.Message},
+		})
+	}
+
+	var actions []any
+	for i, link := range links {
+		if link.URL == "" {
+			continue
+		}
+		buttonType := "default"
+		if i == 0 {
+			buttonType = "primary"
+		}
+		actions = append(actions, map[string]any{
+			"tag":  "button",
+			"text": map[string]any{"tag": "plain_text", "content": link.Text},
+			"type": buttonType,
+			"url":  link.URL,
+		})
+	}
+	if len(actions) > 0 {
+		elements = append(elements, map[string]any{"tag": "action", "actions": actions})
+	}
+
+	return map[string]any{
+		"config": map[string]any{"wide_screen_mode": true},
+		"header": map[string]any{
+			"template": color,
+			"title":    map[string]any{"tag": "plain_text", "content": fmt.Sprintf("%s %s: %s", subjectPrefix, event.InstanceName, event.Event)},
+		},
+		"elements": elements,
+	}
+}
@@ -0,0 +1,94 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/notify"
+)
+
+// fakeFeishu records the messages sent to a fake open platform
+type fakeFeishu struct {
+	mu           sync.Mutex
+	tokenFetches int
+	messages     []map[string]string
+	receiveTypes []string
+	authHeaders  []string
+}
+
+func (f *fakeFeishu) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/open-apis/auth/v3/tenant_access_token/internal":
+		f.tokenFetches++
+		json.NewEncoder(w).Encode(map[string]any{"code": 0, "tenant_access_token": "t-123", "expire": 7200})
+	case "/open-apis/im/v1/messages":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.messages = append(f.messages, body)
+		f.receiveTypes = append(f.receiveTypes, r.URL.Query().Get("receive_id_type"))
+		f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+		if body["receive_id"] == "oc_invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"code": 230001, "msg": "invalid receive_id"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"code": 0})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestFeishuBotSendCard(t *testing.T) {
+	fake := &fakeFeishu{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	bot := &notify.FeishuBot{AppID: "cli_1", AppSecret: "secret", BaseURL: server.URL, RatePerSecond: 20}
+	card := notify.FeishuInstanceCard(notify.InstanceEvent{
+		InstanceID:   "3f1c",
+		InstanceName: "weather",
+		Event:        "crash",
+		Message:      "exit code 137",
+		Time:         time.Now(),
+	}, notify.FeishuColorRed, notify.FeishuLink{Text: "View logs", URL: "https://mcp.example.com/instance-log?instanceId=3f1c"})
+
+	start := time.Now()
+	for range 3 {
+		if err := bot.SendCard(t.Context(), notify.FeishuReceiveOpenID, "ou_1", card); err != nil {
+			t.Fatalf("SendCard() error = %v", err)
+		}
+	}
+	// burst of one at 20 per second, the third message waits for two intervals
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 messages to one chat took %v, want rate limited", elapsed)
+	}
+	if err := bot.SendCard(t.Context(), notify.FeishuReceiveChatID, "oc_invalid", card); err == nil || !strings.Contains(err.Error(), "230001") {
+		t.Errorf("SendCard() to an invalid chat error = %v", err)
+	}
+
+	if fake.tokenFetches != 1 {
+		t.Errorf("tenant token fetched %d times, want 1", fake.tokenFetches)
+	}
+	if fake.receiveTypes[0] != "open_id" || fake.receiveTypes[3] != "chat_id" || fake.authHeaders[0] != "Bearer t-123" {
+		t.Errorf("receive types = %q, auth = %q", fake.receiveTypes, fake.authHeaders)
+	}
+	msg := fake.messages[0]
+	if msg["receive_id"] != "ou_1" || msg["msg_type"] != "interactive" {
+		t.Errorf("message = %v", msg)
+	}
+	for _, want := range []string{"weather: crash", "exit code 137", "instance-log?instanceId=3f1c", `"template":"red"`} {
+		if !strings.Contains(msg["content"], want) {
+			t.Errorf("card does not contain %q:\n%s", want, msg["content"])
+		}
+	}
+	if strings.Contains(msg["content"], `"value"`) {
+		t.Errorf("card buttons must only link to the console:\n%s", msg["content"])
+	}
+}
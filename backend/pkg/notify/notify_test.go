@@ -0,0 +1,202 @@
+package notify_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/notify"
+)
+
+func TestRenderInstanceEvents(t *testing.T) {
+	event := notify.InstanceEvent{
+		InstanceID:   "3f1c",
+		InstanceName: "weather",
+		Event:        "crash",
+		Status:       "active / running",
+		Message:      "exit code 137 <OOMKilled>",
+		Link:         "https://mcp.example.com/instance-log?instanceId=3f1c",
+		Time:         time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC),
+	}
+	msg, err := notify.RenderInstanceEvents([]string{"dev@example.com"}, []notify.InstanceEvent{event})
+	if err != nil {
+		t.Fatalf("RenderInstanceEvents() error = %v", err)
+	}
+	if msg.Subject != "[MCPBox] weather: crash" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	for _, want := range []string{"weather (3f1c)", "exit code 137 <OOMKilled>", event.Link, "2026-10-01 08:30:00 UTC"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text body does not contain %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "exit code 137 &lt;OOMKilled&gt;") {
+		t.Errorf("html body does not escape the message:\n%s", msg.HTML)
+	}
+
+	digest, err := notify.RenderInstanceEvents([]string{"dev@example.com"}, []notify.InstanceEvent{event, event})
+	if err != nil {
+		t.Fatalf("RenderInstanceEvents() error = %v", err)
+	}
+	if digest.Subject != "[MCPBox] 2 events of your MCP instances" {
+		t.Errorf("digest subject = %q", digest.Subject)
+	}
+	if _, err := notify.RenderInstanceEvents(nil, nil); err == nil {
+		t.Error("RenderInstanceEvents() without events error = nil")
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	data, err := notify.BuildMessage("MCPBox <noreply@example.com>", &notify.Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "实例 weather: crash",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("BuildMessage() error = %v", err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "实例 weather: crash" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType() error = %v", err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "plain body" || bodies[1] != "<p>html body</p>" {
+		t.Errorf("parts = %q", bodies)
+	}
+}
+
+// fakeSender fails its first `failures` sends
+type fakeSender struct {
+	failures int
+	calls    int
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg *notify.Message) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestSendWithRetry(t *testing.T) {
+	sender := &fakeSender{failures: 2}
+	if err := notify.SendWithRetry(t.Context(), sender, &notify.Message{}, 2, time.Millisecond); err != nil || sender.calls != 3 {
+		t.Errorf("SendWithRetry() = %v after %d calls, want success after 3", err, sender.calls)
+	}
+
+	sender = &fakeSender{failures: 5}
+	if err := notify.SendWithRetry(t.Context(), sender, &notify.Message{}, 1, time.Millisecond); err == nil || sender.calls != 2 {
+		t.Errorf("SendWithRetry() = %v after %d calls, want failure after 2", err, sender.calls)
+	}
+}
+
+// serveSMTP answers one SMTP session on ln and returns the received message
+func serveSMTP(ln net.Listener) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return received
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer ln.Close()
+	received := serveSMTP(ln)
+
+	addr := ln.Addr().(*net.TCPAddr)
+	sender := &notify.SMTPSender{
+		Host:    "127.0.0.1",
+		Port:    addr.Port,
+		From:    "MCPBox <noreply@example.com>",
+		TLSMode: notify.TLSModeNone,
+		Timeout: 5 * time.Second,
+	}
+	err = sender.Send(t.Context(), &notify.Message{To: []string{"dev@example.com"}, Subject: "hello", Text: "body"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "Subject: hello") || !strings.Contains(data, "body") {
+			t.Errorf("received message:\n%s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	sender.TLSMode = notify.TLSModeStartTLS
+	serveSMTP(ln)
+	if err := sender.Send(t.Context(), &notify.Message{To: []string{"dev@example.com"}, Text: "body"}); err == nil {
+		t.Error("Send() with starttls to a server without STARTTLS error = nil")
+	}
+}
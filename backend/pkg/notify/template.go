@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+// subjectPrefix prefix of notification subjects, lets users filter the emails
+const subjectPrefix = "[MCPBox]"
+
+// InstanceEvent an instance event to notify about
+type InstanceEvent struct {
+	InstanceID   string
+	InstanceName string
+	// Event instance operation, e.g. crash or health-down
+	Event string
+	// Status instance and container status when the event was sent, e.g. active / running
+	Status string
+	// Message details of the event or the last container message
+	Message string
+	// Link deep link to the instance in the web console, omitted when empty
+	Link string
+	Time time.Time
+}
+
+const textTemplate = `{{if gt (len .) 1}}{{len .}} events of your MCP instances:
+{{end}}{{range .}}
+Instance: {{.InstanceName}} ({{.InstanceID}})
+Event:    {{.Event}}
+Time:     {{.Time.UTC.Format "2006-01-02 15:04:05 MST"}}
+{{- if .Status}}
+Status:   {{.Status}}{{end}}
+{{- if .Message}}
+Message:  {{.Message}}{{end}}
+{{- if .Link}}
+Open:     {{.Link}}{{end}}
+{{end}}
+You receive this email because of your notification preferences.
+`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html><body style="font-family: sans-serif; font-size: 14px; color: #1f2329;">
+{{if gt (len .) 1}}<p>{{len .}} events of your MCP instances:</p>{{end}}
+{{range .}}<table style="border-collapse: collapse; margin-bottom: 16px;">
+<tr><td style="padding: 2px 12px 2px 0; color: #646a73;">Instance</td><td><b>{{.InstanceName}}</b> <span style="color: #8f959e;">{{.InstanceID}}</span></td></tr>
+<tr><td style="padding: 2px 12px 2px 0; color: #646a73;">Event</td><td>{{.Event}}</td></tr>
+<tr><td style="padding: 2px 12px 2px 0; color: #646a73;">Time</td><td>{{.Time.UTC.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+{{if .Status}}<tr><td style="padding: 2px 12px 2px 0; color: #646a73;">Status</td><td>{{.Status}}</td></tr>{{end}}
+{{if .Message}}<tr><td style="padding: 2px 12px 2px 0; color: #646a73;">Message</td><td><pre style="margin: 0; white-space: pre-wrap;">{{.Message}}</pre></td></tr>{{end}}
+{{if .Link}}<tr><td></td><td><a href="{{.Link}}">Open instance</a></td></tr>{{end}}
+</table>
+{{end}}<p style="color: #8f959e; font-size: 12px;">You receive this email because of your notification preferences.</p>
+</body></html>
+`
+
+var (
+	instanceEventText = texttemplate.Must(texttemplate.New("text").Parse(textTemplate))
+	instanceEventHTML = htmltemplate.Must(htmltemplate.New("html").Parse(htmlTemplate))
+)
+
+// RenderInstanceEvents builds the email for events, a single event gets a subject naming the instance,
+// several events are rendered as a digest
+func RenderInstanceEvents(to []string, events []InstanceEvent) (*Message, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events to render")
+	}
+	var text, html bytes.Buffer
+	if err := instanceEventText.Execute(&text, events); err != nil {
+		return nil, fmt.Errorf("render text body: %w", err)
+	}
+	if err := instanceEventHTML.Execute(&html, events); err != nil {
+		return nil, fmt.Errorf("render html body: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s %d events of your MCP instances", subjectPrefix, len(events))
+	if len(events) == 1 {
+		subject = fmt.Sprintf("%s %s: %s", subjectPrefix, events[0].InstanceName, events[0].Event)
+	}
+	return &Message{To: to, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,773 @@
+// Package generator builds the OpenAPI 3 documents of the HTTP services from the proto
+// request/response types and the gin route registrations. It runs at build time through
+// cmd/openapi-gen, the documents are embedded by pkg/openapi.
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Service an HTTP service documented by its own OpenAPI document
+type Service struct {
+	// Name document name, specs/<name>.json
+	Name  string
+	Title string
+	// AppDir package registering the gin routes, relative to the backend root
+	AppDir string
+	// ProtoDir proto definitions of the service, empty when the service has none
+	ProtoDir string
+	// PrefixConst constant in pkg/common holding the default route prefix
+	PrefixConst string
+	// Envelope responses are wrapped in the {code, message, data} envelope
+	Envelope bool
+	// Auth routes require the session token unless listed in middleware.SkipPaths
+	Auth bool
+}
+
+// Services documented services
+var Services = []Service{
+	{
+		Name:        "market",
+		Title:       "MCPBox Market API",
+		AppDir:      "internal/market/app",
+		ProtoDir:    "api/market",
+		PrefixConst: "MarketRoutePrefix",
+		Envelope:    true,
+		Auth:        true,
+	},
+	{
+		Name:        "authz",
+		Title:       "MCPBox Authz API",
+		AppDir:      "internal/authz/app",
+		ProtoDir:    "api/authz",
+		PrefixConst: "AuthzRoutePrefix",
+		Envelope:    true,
+		Auth:        true,
+	},
+	{
+		Name:        "gateway",
+		Title:       "MCPBox Gateway API",
+		AppDir:      "internal/gateway/app",
+		PrefixConst: "GatewayRoutePrefix",
+	},
+}
+
+const (
+	// constFile file declaring the default route prefixes
+	constFile = "pkg/common/const.go"
+	// authFile file declaring the paths exempt from authentication
+	authFile = "pkg/middleware/auth.go"
+)
+
+// object a JSON object of the document, maps keep the output sorted and stable
+type object = map[string]any
+
+var (
+	// ginParamPattern :name and *name path parameters of gin routes
+	ginParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+	// openAPIParamPattern {name} path parameters of the document and of google.api.http paths
+	openAPIParamPattern = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// Generate builds the documents of all services, keyed by document name
+func Generate(root string) (map[string][]byte, error) {
+	skipPaths, err := parseStringValues(filepath.Join(root, authFile), "SkipPaths")
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string][]byte, len(Services))
+	for _, svc := range Services {
+		doc, err := generateService(root, svc, skipPaths)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", svc.Name, err)
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		docs[svc.Name] = buf.Bytes()
+	}
+	return docs, nil
+}
+
+func generateService(root string, svc Service, skipPaths []string) (object, error) {
+	prefixes, err := parseStringValues(filepath.Join(root, constFile), svc.PrefixConst)
+	if err != nil {
+		return nil, err
+	}
+	prefix := "/" + strings.Trim(prefixes[0], "/")
+
+	routes, err := parseRoutes(filepath.Join(root, svc.AppDir))
+	if err != nil {
+		return nil, err
+	}
+	var files []*protoFile
+	if svc.ProtoDir != "" {
+		if files, err = parseProtoDir(filepath.Join(root, svc.ProtoDir)); err != nil {
+			return nil, err
+		}
+	}
+
+	b := newBuilder(svc, prefix, files)
+	for _, r := range routes {
+		b.addRoute(r, skipPaths)
+	}
+	return b.document(), nil
+}
+
+// builder assembles one OpenAPI document
+type builder struct {
+	svc    Service
+	prefix string
+
+	messages map[string]*protoMessage
+	enums    map[string]*protoEnum
+	rpcs     []*protoRPC
+
+	paths    object
+	schemas  object
+	tags     map[string]bool
+	opIDs    map[string]int
+	building map[string]bool
+}
+
+func newBuilder(svc Service, prefix string, files []*protoFile) *builder {
+	b := &builder{
+		svc:      svc,
+		prefix:   prefix,
+		messages: make(map[string]*protoMessage),
+		enums:    make(map[string]*protoEnum),
+		paths:    object{},
+		schemas:  object{},
+		tags:     make(map[string]bool),
+		opIDs:    make(map[string]int),
+		building: make(map[string]bool),
+	}
+	for _, file := range files {
+		for _, msg := range file.messages {
+			b.messages[msg.fullName] = msg
+		}
+		for _, enum := range file.enums {
+			b.enums[enum.fullName] = enum
+		}
+		for _, service := range file.services {
+			b.rpcs = append(b.rpcs, service.rpcs...)
+		}
+	}
+	return b
+}
+
+// document returns the assembled document
+func (b *builder) document() object {
+	b.addCommonSchemas()
+
+	tags := make([]string, 0, len(b.tags))
+	for tag := range b.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	tagList := make([]any, 0, len(tags))
+	for _, tag := range tags {
+		tagList = append(tagList, object{"name": tag})
+	}
+
+	components := object{
+		"schemas":   b.schemas,
+		"responses": b.errorResponses(),
+	}
+	doc := object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   b.svc.Title,
+			"version": "dev",
+			"description": "Generated from the proto definitions and route registrations by cmd/openapi-gen, do not edit. " +
+				"Paths are relative to the service route prefix in servers, which follows the deployment configuration.",
+		},
+		"servers":    []any{object{"url": b.prefix}},
+		"tags":       tagList,
+		"paths":      b.paths,
+		"components": components,
+	}
+	if b.svc.Auth {
+		components["securitySchemes"] = object{
+			"bearerAuth": object{
+				"type":         "http",
+				"scheme":       "bearer",
+				"bearerFormat": "JWT",
+				"description":  "Session token returned by the authz login API, sent in the Authorization header",
+			},
+			"tokenQuery": object{
+				"type":        "apiKey",
+				"in":          "query",
+				"name":        "token",
+				"description": "Session token passed as the token query parameter, for links that cannot set headers",
+			},
+		}
+		doc["security"] = []any{object{"bearerAuth": []any{}}, object{"tokenQuery": []any{}}}
+	} else {
+		components["securitySchemes"] = object{
+			"instanceToken": object{
+				"type":        "http",
+				"scheme":      "bearer",
+				"description": "Instance access token, required when the instance has tokens configured",
+			},
+		}
+	}
+	return doc
+}
+
+// addRoute documents a route, the request and response types come from the proto method
+// bound to the same path, or with the same name as the handler
+func (b *builder) addRoute(r route, skipPaths []string) {
+	relPath := ginParamPattern.ReplaceAllString(r.path, "{$1}")
+	if relPath == "" {
+		relPath = "/"
+	}
+	fullPath := relPath
+	if r.prefixed {
+		fullPath = strings.TrimSuffix(b.prefix+relPath, "/")
+	}
+
+	rpc := b.findRPC(r.method, relPath, r.handler)
+	op := object{
+		"operationId": b.operationID(r, rpc),
+		"tags":        []any{b.tagOf(r, relPath)},
+		"responses":   b.responses(r, rpc),
+	}
+	if summary, description := b.summary(r, rpc); summary != "" {
+		op["summary"] = summary
+		if description != "" {
+			op["description"] = description
+		}
+	}
+	if rpc != nil {
+		op["x-proto-rpc"] = rpc.pkg + "." + rpc.name
+	}
+	if params, body := b.requestOf(r, relPath, rpc); len(params) > 0 || body != nil {
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if body != nil {
+			op["requestBody"] = body
+		}
+	}
+	if b.svc.Auth && isSkipped(fullPath, skipPaths) {
+		op["security"] = []any{}
+	}
+	if !b.svc.Auth && strings.Contains(r.path, "*") {
+		op["security"] = []any{object{}, object{"instanceToken": []any{}}}
+	}
+
+	pathItem, ok := b.paths[relPath].(object)
+	if !ok {
+		pathItem = object{}
+		if !r.prefixed {
+			// 未挂在服务前缀下的路由（如 /health）相对根路径
+			pathItem["servers"] = []any{object{"url": "/"}}
+		}
+		b.paths[relPath] = pathItem
+	}
+	pathItem[strings.ToLower(r.method)] = op
+}
+
+// isSkipped reports whether the authentication middleware lets a path through
+func isSkipped(path string, skipPaths []string) bool {
+	for _, skip := range skipPaths {
+		if strings.HasPrefix(path, skip) {
+			return true
+		}
+	}
+	return false
+}
+
+// findRPC finds the proto method of a route by method and path, falling back to the handler name
+func (b *builder) findRPC(method, relPath, handler string) *protoRPC {
+	want := pathShape(relPath)
+	for _, rpc := range b.rpcs {
+		if rpc.method != method {
+			continue
+		}
+		rpcPath := strings.TrimPrefix(rpc.path, b.prefix)
+		if pathShape(rpcPath) == want {
+			return rpc
+		}
+	}
+	if handler == "" {
+		return nil
+	}
+	name := strings.TrimSuffix(handler, "Handler")
+	for _, rpc := range b.rpcs {
+		if rpc.name == name || rpc.name == handler {
+			return rpc
+		}
+	}
+	return nil
+}
+
+// pathShape path with parameter names removed, /instance/{id} and /instance/{instanceId} match
+func pathShape(path string) string {
+	return openAPIParamPattern.ReplaceAllString(strings.TrimSuffix(path, "/"), "{}")
+}
+
+// operationID unique id of an operation, the handler or proto method name
+func (b *builder) operationID(r route, rpc *protoRPC) string {
+	id := r.handler
+	if rpc != nil {
+		id = rpc.name
+	}
+	if id == "" {
+		// 闭包处理函数按方法和路径命名，如 GET /health -> getHealth
+		id = strings.ToLower(r.method)
+		for _, part := range strings.FieldsFunc(r.path, func(c rune) bool {
+			return c == '/' || c == '-' || c == ':' || c == '*'
+		}) {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	if b.opIDs[id] > 0 && rpc == nil {
+		// Any 注册的同一处理函数按方法区分
+		id += strings.ToUpper(r.method[:1]) + strings.ToLower(r.method[1:])
+	}
+	b.opIDs[id]++
+	if n := b.opIDs[id]; n > 1 {
+		id = fmt.Sprintf("%s%d", id, n)
+	}
+	return id
+}
+
+// tagOf groups operations by the first segment of their path
+func (b *builder) tagOf(r route, relPath string) string {
+	tag := strings.Split(strings.TrimPrefix(relPath, "/"), "/")[0]
+	if !r.prefixed || tag == "" || strings.HasPrefix(tag, "{") {
+		tag = b.svc.Name
+	}
+	b.tags[tag] = true
+	return tag
+}
+
+// summary first line of the proto method comment or of the comment above the registration
+func (b *builder) summary(r route, rpc *protoRPC) (string, string) {
+	text := r.comment
+	if rpc != nil && rpc.comment != "" {
+		text = rpc.comment
+	}
+	if text == "" && strings.Contains(r.path, "*") {
+		text = "Proxy to the MCP server of an instance"
+	}
+	lines := strings.SplitN(text, "\n", 2)
+	if len(lines) == 2 {
+		return strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1])
+	}
+	return strings.TrimSpace(lines[0]), ""
+}
+
+// requestOf path and query parameters and request body of an operation.
+// Bodies are bound from JSON (or multipart when the message has bytes fields) for
+// POST/PUT/PATCH, the other methods bind the message fields from the query string.
+func (b *builder) requestOf(r route, relPath string, rpc *protoRPC) ([]any, object) {
+	pathParams := ginParamPattern.FindAllStringSubmatch(r.path, -1)
+	var msg *protoMessage
+	if rpc != nil {
+		msg = b.messages[b.resolve(rpc.input, rpc.pkg)]
+	}
+
+	var params []any
+	bound := make(map[*protoField]bool)
+	for _, m := range pathParams {
+		name := m[1]
+		schema := object{"type": "string"}
+		description := ""
+		if msg != nil {
+			for _, f := range msg.fields {
+				if f.tags["uri"] == name || jsonName(f) == name {
+					schema = b.fieldSchema(f, false)
+					description = fieldDescription(f)
+					bound[f] = true
+					break
+				}
+			}
+		}
+		param := object{"name": name, "in": "path", "required": true, "schema": stripDescription(schema)}
+		if strings.HasPrefix(m[0], "*") {
+			description = "Remaining path, may contain slashes"
+		}
+		if description != "" {
+			param["description"] = description
+		}
+		params = append(params, param)
+	}
+	if msg == nil {
+		return params, nil
+	}
+
+	switch r.method {
+	case "POST", "PUT", "PATCH":
+		return params, b.requestBody(msg, bound)
+	}
+	for _, f := range msg.fields {
+		if bound[f] {
+			continue
+		}
+		name := queryName(f)
+		if name == "" || name == "-" || !b.isScalar(f) {
+			// 表单绑定不支持 map 和消息类型
+			continue
+		}
+		schema := b.fieldSchema(f, false)
+		param := object{"name": name, "in": "query", "schema": stripDescription(schema)}
+		if description := fieldDescription(f); description != "" {
+			param["description"] = description
+		}
+		if isRequired(f) {
+			param["required"] = true
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// isScalar reports whether a field is a scalar or an enum, the types query binding supports
+func (b *builder) isScalar(f *protoField) bool {
+	if f.typ == "map" {
+		return false
+	}
+	if _, ok := b.typeSchema(f.typ, f.scope, false)["$ref"]; !ok {
+		return true
+	}
+	return b.enums[b.resolve(f.typ, f.scope)] != nil
+}
+
+// requestBody body schema of a request message, path parameters are left out
+func (b *builder) requestBody(msg *protoMessage, bound map[*protoField]bool) object {
+	multipart := false
+	for _, f := range msg.fields {
+		if f.typ == "bytes" {
+			multipart = true
+		}
+	}
+	if !multipart {
+		if len(bound) == 0 {
+			return object{"required": true, "content": object{"application/json": object{"schema": b.ref(msg.fullName)}}}
+		}
+		schema := b.messageSchema(msg, bound, false)
+		return object{"required": true, "content": object{"application/json": object{"schema": schema}}}
+	}
+	schema := b.messageSchema(msg, bound, true)
+	return object{"required": true, "content": object{"multipart/form-data": object{"schema": schema}}}
+}
+
+// responses success and error responses of an operation
+func (b *builder) responses(r route, rpc *protoRPC) object {
+	var data object
+	if rpc != nil {
+		data = b.ref(b.resolve(rpc.output, rpc.pkg))
+	}
+
+	responses := object{}
+	switch {
+	case b.svc.Envelope:
+		envelope := object{"$ref": "#/components/schemas/Response"}
+		schema := envelope
+		if data != nil {
+			schema = object{"allOf": []any{envelope, object{"type": "object", "properties": object{"data": data}}}}
+		}
+		responses["200"] = object{
+			"description": "Successful response, the result is in data",
+			"content":     object{"application/json": object{"schema": schema}},
+		}
+		responses["400"] = object{"$ref": "#/components/responses/BadRequest"}
+		if b.svc.Auth {
+			responses["401"] = object{"$ref": "#/components/responses/Unauthorized"}
+		}
+		if strings.Contains(r.path, ":") {
+			responses["404"] = object{"$ref": "#/components/responses/NotFound"}
+		}
+		responses["500"] = object{"$ref": "#/components/responses/InternalError"}
+	case strings.Contains(r.path, "*"):
+		// 网关代理的响应由上游 MCP 服务决定，网关自身的错误为 JSON-RPC 格式
+		responses["200"] = object{
+			"description": "Response of the upstream MCP server, SSE streams use text/event-stream",
+			"content": object{
+				"application/json":  object{"schema": object{}},
+				"text/event-stream": object{"schema": object{"type": "string"}},
+			},
+		}
+		for _, status := range []string{"401", "403", "404", "413", "502", "503", "504"} {
+			responses[status] = object{"$ref": "#/components/responses/GatewayError"}
+		}
+	default:
+		responses["200"] = object{"description": "Successful response"}
+	}
+	return responses
+}
+
+// errorResponses shared error responses
+func (b *builder) errorResponses() object {
+	if !b.svc.Envelope {
+		return object{
+			"GatewayError": object{
+				"description": "Gateway error in JSON-RPC format, Retry-After is set when the request may be retried",
+				"content":     object{"application/json": object{"schema": object{"$ref": "#/components/schemas/GatewayError"}}},
+			},
+		}
+	}
+	errorContent := object{"application/json": object{"schema": object{"$ref": "#/components/schemas/ErrorResponse"}}}
+	responses := object{
+		"BadRequest":    object{"description": "Invalid request parameters", "content": errorContent},
+		"NotFound":      object{"description": "Resource not found", "content": errorContent},
+		"InternalError": object{"description": "Internal error", "content": errorContent},
+	}
+	if b.svc.Auth {
+		responses["Unauthorized"] = object{"description": "Missing, invalid or expired session token", "content": errorContent}
+	}
+	return responses
+}
+
+// addCommonSchemas schemas of the response envelope and the errors
+func (b *builder) addCommonSchemas() {
+	if !b.svc.Envelope {
+		b.schemas["GatewayError"] = object{
+			"type":     "object",
+			"required": []any{"jsonrpc", "id", "error"},
+			"properties": object{
+				"jsonrpc": object{"type": "string", "enum": []any{"2.0"}},
+				"id":      object{"nullable": true, "description": "Always null, the gateway does not read the request id"},
+				"error": object{
+					"type":     "object",
+					"required": []any{"code", "message", "data"},
+					"properties": object{
+						"code":    object{"type": "integer", "description": "JSON-RPC error code, -32000 to -32099 are gateway errors"},
+						"message": object{"type": "string"},
+						"data": object{
+							"type": "object",
+							"properties": object{
+								"status":     object{"type": "integer", "description": "HTTP status"},
+								"instanceId": object{"type": "string"},
+								"requestId":  object{"type": "string"},
+								"retryAfter": object{"type": "integer", "description": "Seconds until the request may be retried"},
+							},
+						},
+					},
+				},
+			},
+		}
+		return
+	}
+	b.schemas["Response"] = object{
+		"type":     "object",
+		"required": []any{"code", "message"},
+		"properties": object{
+			"code":    object{"type": "integer", "description": "Business code, 0 on success"},
+			"message": object{"type": "string", "description": "Localized message, the language follows Accept-Language or the lang query parameter"},
+			"data":    object{"description": "Result of the request"},
+		},
+	}
+	b.schemas["ErrorResponse"] = object{
+		"type":     "object",
+		"required": []any{"code", "message"},
+		"properties": object{
+			"code":      object{"type": "integer", "description": "Business error code, see pkg/i18n/codes.go"},
+			"message":   object{"type": "string", "description": "Localized error message"},
+			"data":      object{"nullable": true},
+			"requestId": object{"type": "string", "description": "Request ID, also returned in the X-Request-ID header"},
+		},
+	}
+}
+
+// resolve full name of a type referenced from scope, following proto scoping rules
+func (b *builder) resolve(typ, scope string) string {
+	typ = strings.TrimPrefix(typ, ".")
+	for s := scope; s != ""; {
+		candidate := s + "." + typ
+		if b.messages[candidate] != nil || b.enums[candidate] != nil {
+			return candidate
+		}
+		i := strings.LastIndex(s, ".")
+		if i < 0 {
+			break
+		}
+		s = s[:i]
+	}
+	return typ
+}
+
+// ref reference to the schema of a message or enum, the schema is added on first use
+func (b *builder) ref(fullName string) object {
+	if fullName == "google.protobuf.Empty" {
+		return object{"type": "object"}
+	}
+	if _, ok := b.schemas[fullName]; !ok && !b.building[fullName] {
+		switch {
+		case b.messages[fullName] != nil:
+			b.building[fullName] = true
+			b.schemas[fullName] = b.messageSchema(b.messages[fullName], nil, false)
+			delete(b.building, fullName)
+		case b.enums[fullName] != nil:
+			b.schemas[fullName] = enumSchema(b.enums[fullName])
+		default:
+			return object{"type": "object", "description": "Unknown type " + fullName}
+		}
+	}
+	return object{"$ref": "#/components/schemas/" + fullName}
+}
+
+// messageSchema object schema of a message, skipping the given fields
+func (b *builder) messageSchema(msg *protoMessage, skip map[*protoField]bool, multipart bool) object {
+	properties := object{}
+	var required []any
+	for _, f := range msg.fields {
+		if skip[f] {
+			continue
+		}
+		name := jsonName(f)
+		if multipart {
+			name = queryName(f)
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = b.fieldSchema(f, multipart)
+		if isRequired(f) {
+			required = append(required, name)
+		}
+	}
+	schema := object{"type": "object", "properties": properties}
+	if msg.comment != "" {
+		schema["description"] = msg.comment
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchema schema of a field with its description
+func (b *builder) fieldSchema(f *protoField, multipart bool) object {
+	var schema object
+	if f.typ == "map" {
+		schema = object{"type": "object", "additionalProperties": b.typeSchema(f.mapValue, f.scope, multipart)}
+	} else {
+		schema = b.typeSchema(f.typ, f.scope, multipart)
+	}
+	if f.repeated {
+		schema = object{"type": "array", "items": schema}
+	}
+	if description := fieldDescription(f); description != "" {
+		if _, isRef := schema["$ref"]; isRef {
+			// OpenAPI 3.0 忽略 $ref 的同级字段，需要 allOf 包装
+			schema = object{"allOf": []any{schema}}
+		}
+		schema["description"] = description
+	}
+	return schema
+}
+
+// typeSchema schema of a scalar, message or enum type
+func (b *builder) typeSchema(typ, scope string, multipart bool) object {
+	switch typ {
+	case "string":
+		return object{"type": "string"}
+	case "bool":
+		return object{"type": "boolean"}
+	case "int32", "sint32", "sfixed32":
+		return object{"type": "integer", "format": "int32"}
+	case "uint32", "fixed32":
+		return object{"type": "integer", "format": "int64", "minimum": 0}
+	case "int64", "sint64", "sfixed64":
+		return object{"type": "integer", "format": "int64"}
+	case "uint64", "fixed64":
+		return object{"type": "integer", "format": "int64", "minimum": 0}
+	case "double":
+		return object{"type": "number", "format": "double"}
+	case "float":
+		return object{"type": "number", "format": "float"}
+	case "bytes":
+		if multipart {
+			return object{"type": "string", "format": "binary"}
+		}
+		return object{"type": "string", "format": "byte"}
+	}
+	return b.ref(b.resolve(typ, scope))
+}
+
+// enumSchema enums are serialized as numbers by encoding/json
+func enumSchema(enum *protoEnum) object {
+	values := make([]any, 0, len(enum.values))
+	names := make([]any, 0, len(enum.values))
+	var lines []string
+	for _, v := range enum.values {
+		values = append(values, v.number)
+		names = append(names, v.name)
+		lines = append(lines, fmt.Sprintf("%d: %s", v.number, v.name))
+	}
+	description := strings.Join(lines, ", ")
+	if enum.comment != "" {
+		description = enum.comment + "\n" + description
+	}
+	return object{
+		"type":            "integer",
+		"format":          "int32",
+		"enum":            values,
+		"x-enum-varnames": names,
+		"description":     description,
+	}
+}
+
+// jsonName JSON property name of a field, protoc-gen-go uses the proto field name by default
+func jsonName(f *protoField) string {
+	if tag, ok := f.tags["json"]; ok {
+		name := strings.Split(tag, ",")[0]
+		if name != "" {
+			return name
+		}
+	}
+	return f.name
+}
+
+// queryName name a field is bound from in query strings and forms, the form tag wins over json
+func queryName(f *protoField) string {
+	if tag, ok := f.tags["form"]; ok {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return jsonName(f)
+}
+
+// fieldDescription desc tag or leading comment of a field
+func fieldDescription(f *protoField) string {
+	if desc := f.tags["desc"]; desc != "" {
+		return desc
+	}
+	return f.comment
+}
+
+// isRequired reports whether binding or validation requires the field
+func isRequired(f *protoField) bool {
+	for _, key := range []string{"binding", "validate"} {
+		for _, rule := range strings.Split(f.tags[key], ",") {
+			if rule == "required" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripDescription parameters carry the description themselves
+func stripDescription(schema object) object {
+	if _, ok := schema["allOf"]; ok {
+		return schema["allOf"].([]any)[0].(object)
+	}
+	delete(schema, "description")
+	return schema
+}
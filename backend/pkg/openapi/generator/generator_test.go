@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProto(t *testing.T) {
+	src := `syntax = "proto3";
+package demo;
+
+// Item 条目
+message Item {
+  // @inject_tag: json:"itemId" uri:"itemId" desc:"条目ID" binding:"required"
+  int64 id = 1;
+  repeated string tags = 2; // 标签
+  map<string, Inner> inner = 3;
+  message Inner {
+    Kind kind = 1;
+  }
+  enum Kind {
+    KIND_A = 0;
+    KIND_B = 1;
+  }
+}
+
+service Demo {
+  // 获取条目
+  rpc Get(Item) returns (Item.Inner) {
+    option (google.api.http) = {
+      get: "/items/{itemId}"
+    };
+  }
+}
+`
+	file, err := parseProto(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.messages) != 2 || file.messages[1].fullName != "demo.Item.Inner" {
+		t.Fatalf("unexpected messages %+v", file.messages)
+	}
+	item := file.messages[0]
+	if len(item.fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d", len(item.fields))
+	}
+	id := item.fields[0]
+	if jsonName(id) != "itemId" || id.tags["uri"] != "itemId" || fieldDescription(id) != "条目ID" || !isRequired(id) {
+		t.Errorf("unexpected id field %+v", id)
+	}
+	if tags := item.fields[1]; !tags.repeated || tags.comment != "标签" {
+		t.Errorf("unexpected tags field %+v", tags)
+	}
+	if inner := item.fields[2]; inner.typ != "map" || inner.mapValue != "Inner" {
+		t.Errorf("unexpected inner field %+v", inner)
+	}
+	if len(file.enums) != 1 || len(file.enums[0].values) != 2 {
+		t.Fatalf("unexpected enums %+v", file.enums)
+	}
+
+	rpc := file.services[0].rpcs[0]
+	if rpc.method != "GET" || rpc.path != "/items/{itemId}" || rpc.output != "Item.Inner" || rpc.comment != "获取条目" {
+		t.Errorf("unexpected rpc %+v", rpc)
+	}
+
+	b := newBuilder(Service{Name: "demo"}, "/demo", []*protoFile{file})
+	if got := b.resolve("Kind", "demo.Item.Inner"); got != "demo.Item.Kind" {
+		t.Errorf("resolve(Kind) = %s", got)
+	}
+	r := route{method: "GET", path: "/items/:itemId", prefixed: true, handler: "GetHandler"}
+	if b.findRPC(r.method, "/items/{itemId}", r.handler) != rpc {
+		t.Error("route not matched to rpc")
+	}
+	params, body := b.requestOf(r, "/items/{itemId}", rpc)
+	if body != nil || len(params) != 2 {
+		t.Fatalf("expected the path parameter and the tags query parameter, got %v", params)
+	}
+	if path := params[0].(object); path["in"] != "path" || path["name"] != "itemId" || path["description"] != "条目ID" {
+		t.Errorf("unexpected path parameter %v", path)
+	}
+}
+
+func TestCollectRoutes(t *testing.T) {
+	src := `package app
+
+func setup() {
+	prefix := "api"
+	// 健康检查
+	r.GET("/health", health)
+	r.Any(fmt.Sprintf("/%s/*path", prefix), gin.WrapH(proxy))
+	api := r.Group(common.GetPrefix())
+	users := api.Group("/users")
+	users.DELETE("/:id", svc.DeleteUser)
+	logger.Info("done", zap.Any("headers", headers))
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "app.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := collectRoutes(fset, file)
+	want := []route{
+		{method: "GET", path: "/health", handler: "health", comment: "健康检查"},
+		{method: "GET", path: "/*path", prefixed: true, handler: "proxy"},
+		{method: "POST", path: "/*path", prefixed: true, handler: "proxy"},
+		{method: "DELETE", path: "/*path", prefixed: true, handler: "proxy"},
+		{method: "DELETE", path: "/users/:id", prefixed: true, handler: "DeleteUser"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("expected %d routes, got %+v", len(want), routes)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+}
+
+// TestSpecsUpToDate 提交的文档需与 proto 和路由保持一致，修改后运行 make openapi
+func TestSpecsUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..", "..")
+	docs, err := Generate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, doc := range docs {
+		existing, err := os.ReadFile(filepath.Join(root, "pkg", "openapi", "specs", name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(existing, doc) {
+			t.Errorf("specs/%s.json is out of date, run make openapi", name)
+		}
+	}
+}
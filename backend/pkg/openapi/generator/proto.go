@@ -0,0 +1,583 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// protoFile the parts of a proto file used for the OpenAPI document
+type protoFile struct {
+	pkg      string
+	messages []*protoMessage
+	enums    []*protoEnum
+	services []*protoService
+}
+
+// protoMessage a message, nested messages are flattened with their full name
+type protoMessage struct {
+	fullName string
+	comment  string
+	fields   []*protoField
+}
+
+// protoField a message field with the tags injected by protoc-go-inject-tag
+type protoField struct {
+	name     string
+	typ      string
+	repeated bool
+	mapValue string // value type of map fields, the key is always a string in JSON
+	comment  string
+	tags     map[string]string
+	// scope full name of the enclosing message, used to resolve the field type
+	scope string
+}
+
+// protoEnum an enum, serialized as its number by encoding/json
+type protoEnum struct {
+	fullName string
+	comment  string
+	values   []protoEnumValue
+}
+
+// protoEnumValue an enum constant
+type protoEnumValue struct {
+	name   string
+	number int
+}
+
+// protoService a service with its HTTP bound methods
+type protoService struct {
+	name string
+	rpcs []*protoRPC
+}
+
+// protoRPC a method, method and path come from the google.api.http option
+type protoRPC struct {
+	name    string
+	comment string
+	input   string
+	output  string
+	method  string
+	path    string
+	pkg     string
+}
+
+var (
+	// injectTagPattern comment written by protoc-go-inject-tag into the generated struct tags
+	injectTagPattern = regexp.MustCompile(`@inject_tag:\s*(.*)$`)
+	// structTagPattern a key:"value" pair of a struct tag
+	structTagPattern = regexp.MustCompile(`([a-zA-Z_]+):"([^"]*)"`)
+)
+
+// parseProtoDir parses every .proto file below dir
+func parseProtoDir(dir string) ([]*protoFile, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".proto") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	files := make([]*protoFile, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parseProto(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// protoToken a lexical token of a proto file, comments are kept as tokens
+type protoToken struct {
+	text    string
+	line    int
+	comment bool
+}
+
+// tokenize splits a proto file into identifiers, strings, symbols and comments
+func tokenize(src string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			tokens = append(tokens, protoToken{text: strings.TrimSpace(src[i+2 : i+end]), line: line, comment: true})
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			text := src[i+2 : i+2+end]
+			tokens = append(tokens, protoToken{text: strings.TrimSpace(text), line: line, comment: true})
+			line += strings.Count(text, "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, protoToken{text: src[i+1 : i+1+end], line: line})
+			i += end + 2
+		case isIdentByte(c):
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			tokens = append(tokens, protoToken{text: src[i:j], line: line})
+			i = j
+		default:
+			tokens = append(tokens, protoToken{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// protoParser recursive descent parser over the token stream
+type protoParser struct {
+	tokens []protoToken
+	pos    int
+	// comments collected since the last statement, attached to the next definition
+	comments []string
+	file     *protoFile
+}
+
+// parseProto parses the messages, enums and services of a proto file
+func parseProto(src string) (*protoFile, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{tokens: tokens, file: &protoFile{}}
+	for !p.eof() {
+		if err := p.parseTopLevel(); err != nil {
+			return nil, err
+		}
+	}
+	return p.file, nil
+}
+
+func (p *protoParser) eof() bool {
+	p.skipComments()
+	return p.pos >= len(p.tokens)
+}
+
+// skipComments collects comments until the next non-comment token
+func (p *protoParser) skipComments() {
+	for p.pos < len(p.tokens) && p.tokens[p.pos].comment {
+		p.comments = append(p.comments, p.tokens[p.pos].text)
+		p.pos++
+	}
+}
+
+// takeComments returns and clears the collected comments
+func (p *protoParser) takeComments() []string {
+	comments := p.comments
+	p.comments = nil
+	return comments
+}
+
+func (p *protoParser) next() (protoToken, error) {
+	p.skipComments()
+	if p.pos >= len(p.tokens) {
+		return protoToken{}, fmt.Errorf("unexpected end of file")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *protoParser) peek() string {
+	p.skipComments()
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *protoParser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.text != text {
+		return fmt.Errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+// skipStatement skips to the end of the current statement, including nested blocks
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				if p.peek() == ";" {
+					p.pos++
+				}
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseTopLevel() error {
+	switch p.peek() {
+	case "package":
+		p.pos++
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		p.file.pkg = t.text
+		p.takeComments()
+		return p.expect(";")
+	case "message":
+		p.pos++
+		return p.parseMessage(p.file.pkg)
+	case "enum":
+		p.pos++
+		return p.parseEnum(p.file.pkg)
+	case "service":
+		p.pos++
+		return p.parseService()
+	default:
+		p.takeComments()
+		return p.skipStatement()
+	}
+}
+
+func (p *protoParser) parseMessage(scope string) error {
+	comment := commentText(p.takeComments())
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	msg := &protoMessage{fullName: scope + "." + name.text, comment: comment}
+	p.file.messages = append(p.file.messages, msg)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch p.peek() {
+		case "}":
+			p.pos++
+			p.takeComments()
+			return nil
+		case "message":
+			p.pos++
+			if err := p.parseMessage(msg.fullName); err != nil {
+				return err
+			}
+		case "enum":
+			p.pos++
+			if err := p.parseEnum(msg.fullName); err != nil {
+				return err
+			}
+		case "oneof":
+			// oneof 的字段按普通字段输出
+			p.pos++
+			p.takeComments()
+			if _, err := p.next(); err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for p.peek() != "}" {
+				if p.peek() == "" {
+					return fmt.Errorf("oneof in %s is not closed", msg.fullName)
+				}
+				if err := p.parseField(msg); err != nil {
+					return err
+				}
+			}
+			p.pos++
+		case "option", "reserved", "extensions":
+			p.takeComments()
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case "":
+			return fmt.Errorf("message %s is not closed", msg.fullName)
+		default:
+			if err := p.parseField(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseField(msg *protoMessage) error {
+	field := &protoField{scope: msg.fullName}
+	comments := p.takeComments()
+
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.text == "repeated" || t.text == "optional" {
+		field.repeated = t.text == "repeated"
+		if t, err = p.next(); err != nil {
+			return err
+		}
+	}
+	if t.text == "map" {
+		// map<key, value>
+		if err := p.expect("<"); err != nil {
+			return err
+		}
+		if _, err := p.next(); err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		value, err := p.next()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+		field.typ = "map"
+		field.mapValue = value.text
+	} else {
+		field.typ = t.text
+	}
+
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	field.name = name.text
+	if err := p.skipStatement(); err != nil {
+		return err
+	}
+	// 与分号同一行的行尾注释属于当前字段
+	end := p.tokens[p.pos-1].line
+	if p.pos < len(p.tokens) && p.tokens[p.pos].comment && p.tokens[p.pos].line == end {
+		comments = append(comments, p.tokens[p.pos].text)
+		p.pos++
+	}
+
+	field.tags, field.comment = parseFieldComments(comments)
+	msg.fields = append(msg.fields, field)
+	return nil
+}
+
+// parseFieldComments splits the leading comments of a field into injected tags and description
+func parseFieldComments(comments []string) (map[string]string, string) {
+	tags := make(map[string]string)
+	var text []string
+	for _, comment := range comments {
+		if m := injectTagPattern.FindStringSubmatch(comment); m != nil {
+			for _, kv := range structTagPattern.FindAllStringSubmatch(m[1], -1) {
+				tags[kv[1]] = kv[2]
+			}
+			continue
+		}
+		text = append(text, comment)
+	}
+	return tags, strings.Join(text, "\n")
+}
+
+func (p *protoParser) parseEnum(scope string) error {
+	comment := commentText(p.takeComments())
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	enum := &protoEnum{fullName: scope + "." + name.text, comment: comment}
+	p.file.enums = append(p.file.enums, enum)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return fmt.Errorf("enum %s is not closed", enum.fullName)
+		}
+		valueName, err := p.next()
+		if err != nil {
+			return err
+		}
+		if valueName.text == "option" || valueName.text == "reserved" {
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		number, err := p.next()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(number.text)
+		if err != nil {
+			return fmt.Errorf("enum %s: invalid value %q", enum.fullName, number.text)
+		}
+		enum.values = append(enum.values, protoEnumValue{name: valueName.text, number: n})
+		if err := p.skipStatement(); err != nil {
+			return err
+		}
+		p.takeComments()
+	}
+	p.pos++
+	p.takeComments()
+	return nil
+}
+
+func (p *protoParser) parseService() error {
+	p.takeComments()
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	service := &protoService{name: name.text}
+	p.file.services = append(p.file.services, service)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		switch p.peek() {
+		case "rpc":
+			p.pos++
+			rpc, err := p.parseRPC()
+			if err != nil {
+				return err
+			}
+			service.rpcs = append(service.rpcs, rpc)
+		case "":
+			return fmt.Errorf("service %s is not closed", service.name)
+		default:
+			p.takeComments()
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+	p.pos++
+	p.takeComments()
+	return nil
+}
+
+// parseRPC parses rpc Name(Input) returns (Output) { option (google.api.http) = { get: "/path" }; }
+func (p *protoParser) parseRPC() (*protoRPC, error) {
+	rpc := &protoRPC{comment: commentText(p.takeComments()), pkg: p.file.pkg}
+	var parts []string
+	for len(parts) < 3 {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "(", ")", "returns", "stream":
+			continue
+		}
+		parts = append(parts, t.text)
+	}
+	rpc.name, rpc.input, rpc.output = parts[0], parts[1], parts[2]
+	if p.peek() == ")" {
+		p.pos++
+	}
+
+	if p.peek() == ";" {
+		p.pos++
+		return rpc, nil
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	depth := 1
+	for depth > 0 {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "get", "post", "put", "delete", "patch":
+			if p.peek() != ":" {
+				continue
+			}
+			p.pos++
+			path, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if rpc.method == "" {
+				rpc.method = strings.ToUpper(t.text)
+				rpc.path = path.text
+			}
+		}
+	}
+	if p.peek() == ";" {
+		p.pos++
+	}
+	p.takeComments()
+	return rpc, nil
+}
+
+// commentText joins leading comments into a description, injected tags are dropped
+func commentText(comments []string) string {
+	var text []string
+	for _, comment := range comments {
+		if injectTagPattern.MatchString(comment) || comment == "" {
+			continue
+		}
+		text = append(text, comment)
+	}
+	return strings.Join(text, "\n")
+}
@@ -0,0 +1,223 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// anyMethods methods documented for routes registered with Any, the ones MCP clients use
+var anyMethods = []string{"GET", "POST", "DELETE"}
+
+// routeMethods gin router methods that register a route
+var routeMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "DELETE": true, "PATCH": true, "Any": true,
+}
+
+// route a route registration found in the service's app package
+type route struct {
+	method string
+	// path gin path relative to the service prefix, or to the root when prefixed is false
+	path     string
+	prefixed bool
+	handler  string
+	comment  string
+}
+
+// routerScope path of a gin router group variable
+type routerScope struct {
+	prefixed bool
+	path     string
+}
+
+// parseRoutes collects the gin route registrations of the Go files in dir.
+// Paths built with fmt.Sprintf("/%s/...", prefix) and groups created from a non-literal
+// prefix are relative to the service prefix, literal paths on the engine are relative to the root.
+func parseRoutes(dir string) ([]route, error) {
+	fset := token.NewFileSet()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var routes []route
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, collectRoutes(fset, file)...)
+	}
+	return routes, nil
+}
+
+// collectRoutes walks the functions of a file in source order, tracking router groups
+func collectRoutes(fset *token.FileSet, file *ast.File) []route {
+	// comments by the line they end on, a comment directly above a registration describes it
+	comments := make(map[int]string)
+	for _, group := range file.Comments {
+		comments[fset.Position(group.End()).Line] = strings.TrimSpace(group.Text())
+	}
+
+	var routes []route
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		groups := make(map[string]routerScope)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				// g := router.Group(prefix)
+				if len(node.Lhs) != 1 || len(node.Rhs) != 1 {
+					return true
+				}
+				ident, ok := node.Lhs[0].(*ast.Ident)
+				call, ok2 := node.Rhs[0].(*ast.CallExpr)
+				if !ok || !ok2 {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok || sel.Sel.Name != "Group" || len(call.Args) == 0 {
+					return true
+				}
+				parent := scopeOf(groups, sel.X)
+				if lit, ok := stringLiteral(call.Args[0]); ok {
+					groups[ident.Name] = routerScope{prefixed: parent.prefixed, path: parent.path + lit}
+				} else {
+					groups[ident.Name] = routerScope{prefixed: true, path: parent.path}
+				}
+			case *ast.CallExpr:
+				sel, ok := node.Fun.(*ast.SelectorExpr)
+				if !ok || !routeMethods[sel.Sel.Name] || len(node.Args) < 2 {
+					return true
+				}
+				path, prefixed, ok := routePath(node.Args[0])
+				if !ok {
+					// 非路由调用，如 zap.Any("headers", ...)
+					return true
+				}
+				scope := scopeOf(groups, sel.X)
+				r := route{
+					path:     scope.path + path,
+					prefixed: scope.prefixed || prefixed,
+					handler:  handlerName(node.Args[len(node.Args)-1]),
+					comment:  comments[fset.Position(node.Pos()).Line-1],
+				}
+				methods := []string{sel.Sel.Name}
+				if sel.Sel.Name == "Any" {
+					methods = anyMethods
+				}
+				for _, method := range methods {
+					r.method = method
+					routes = append(routes, r)
+				}
+			}
+			return true
+		})
+	}
+	return routes
+}
+
+// scopeOf returns the group a router expression refers to, the engine itself is the root
+func scopeOf(groups map[string]routerScope, expr ast.Expr) routerScope {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if scope, ok := groups[ident.Name]; ok {
+			return scope
+		}
+	}
+	return routerScope{}
+}
+
+// routePath resolves the path argument of a registration, literal paths must be empty or
+// start with a slash, other expressions must be fmt.Sprintf("/%s/...", prefix)
+func routePath(expr ast.Expr) (string, bool, bool) {
+	if lit, ok := stringLiteral(expr); ok {
+		return lit, false, lit == "" || strings.HasPrefix(lit, "/")
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if ok && len(call.Args) > 0 {
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" {
+			if format, ok := stringLiteral(call.Args[0]); ok && strings.HasPrefix(format, "/%s") {
+				return strings.TrimPrefix(format, "/%s"), true, true
+			}
+		}
+	}
+	return "", false, false
+}
+
+// handlerName name of the handler method or of the wrapped http.Handler, empty for closures
+func handlerName(expr ast.Expr) string {
+	switch h := expr.(type) {
+	case *ast.SelectorExpr:
+		return h.Sel.Name
+	case *ast.Ident:
+		return h.Name
+	case *ast.CallExpr:
+		// gin.WrapH(handler)
+		if len(h.Args) == 1 {
+			return handlerName(h.Args[0])
+		}
+	}
+	return ""
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// parseStringValues reads a string constant or string slice variable declared in a Go file,
+// used for the route prefixes and the paths exempt from authentication
+func parseStringValues(path, name string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, ident := range value.Names {
+				if ident.Name != name || i >= len(value.Values) {
+					continue
+				}
+				if lit, ok := stringLiteral(value.Values[i]); ok {
+					return []string{lit}, nil
+				}
+				composite, ok := value.Values[i].(*ast.CompositeLit)
+				if !ok {
+					return nil, fmt.Errorf("%s is not a string or string slice", name)
+				}
+				values := make([]string, 0, len(composite.Elts))
+				for _, elt := range composite.Elts {
+					if lit, ok := stringLiteral(elt); ok {
+						values = append(values, lit)
+					}
+				}
+				return values, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", name, path)
+}
@@ -0,0 +1,105 @@
+// Package openapi serves the OpenAPI documents generated at build time and a Swagger UI page.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../../cmd/openapi-gen -root ../..
+
+//go:embed specs/*.json
+var specs embed.FS
+
+// DefaultSwaggerUIURL swagger-ui-dist assets used when none is configured
+const DefaultSwaggerUIURL = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5"
+
+// Options document serving options
+type Options struct {
+	// BasePath path /openapi.json and /swagger are registered under
+	BasePath string
+	// RoutePrefix route prefix of the service at runtime, written to servers in the document
+	RoutePrefix string
+	// SwaggerUIURL base URL of the swagger-ui-dist assets
+	SwaggerUIURL string
+	// Version service version, written to info.version
+	Version string
+}
+
+// Spec returns the document of a service with the runtime route prefix and version applied
+func Spec(service string, opts Options) ([]byte, error) {
+	data, err := specs.ReadFile("specs/" + service + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("openapi document of %s not found: %w", service, err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if opts.RoutePrefix != "" {
+		doc["servers"] = []any{map[string]any{"url": "/" + strings.Trim(opts.RoutePrefix, "/")}}
+	}
+	if info, ok := doc["info"].(map[string]any); ok && opts.Version != "" {
+		info["version"] = opts.Version
+	}
+	return json.Marshal(doc)
+}
+
+// Register serves GET <BasePath>/openapi.json and the Swagger UI page at GET <BasePath>/swagger
+func Register(router gin.IRoutes, service string, opts Options) error {
+	spec, err := Spec(service, opts)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix("/"+strings.Trim(opts.BasePath, "/"), "/")
+	uiURL := strings.TrimSuffix(opts.SwaggerUIURL, "/")
+	if uiURL == "" {
+		uiURL = DefaultSwaggerUIURL
+	}
+
+	var page strings.Builder
+	err = swaggerPage.Execute(&page, map[string]string{
+		"Title":    service + " API",
+		"AssetURL": uiURL,
+		"SpecURL":  base + "/openapi.json",
+	})
+	if err != nil {
+		return err
+	}
+	html := page.String()
+
+	router.GET(base+"/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	router.GET(base+"/swagger", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+	})
+	return nil
+}
+
+var swaggerPage = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.AssetURL}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.AssetURL}}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "{{.SpecURL}}",
+      dom_id: "#swagger-ui",
+      persistAuthorization: true
+    });
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/container"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// affinityPathSegment path segment marking the pod token in rewritten SSE endpoint paths,
+	// e.g. /{prefix}/{instanceId}/_pod/{token}/messages/?session_id=xxx
+	affinityPathSegment = "_pod"
+
+	// endpointCacheTTL how long resolved pod addresses of a service are reused
+	endpointCacheTTL = 5 * time.Second
+	// runtimeEntryIdleTTL idle time after which a cached runtime entry is dropped
+	runtimeEntryIdleTTL = 30 * time.Minute
+)
+
+// endpointResolver resolves ready pod addresses behind hosting instance services
+type endpointResolver struct {
+	entries *container.EntryCache
+
+	mu    sync.Mutex
+	cache map[string]*resolvedEndpoints
+}
+
+// resolvedEndpoints cached ready pod addresses of a service
+type resolvedEndpoints struct {
+	addrs    []string
+	expireAt time.Time
+}
+
+var defaultEndpointResolver = newEndpointResolver()
+
+func newEndpointResolver() *endpointResolver {
+	return &endpointResolver{
+		entries: container.NewEntryCache(runtimeEntryIdleTTL),
+		cache:   make(map[string]*resolvedEndpoints),
+	}
+}
+
+// ReadyAddresses returns ready pod addresses (ip:port) behind the instance service
+func (r *endpointResolver) ReadyAddresses(ctx context.Context, instance *model.McpInstance) ([]string, error) {
+	key := fmt.Sprintf("%d/%s", instance.EnvironmentID, instance.ContainerServiceName)
+
+	r.mu.Lock()
+	if cached, ok := r.cache[key]; ok && time.Now().Before(cached.expireAt) {
+		r.mu.Unlock()
+		return cached.addrs, nil
+	}
+	r.mu.Unlock()
+
+	entry, err := r.runtimeEntry(ctx, instance.EnvironmentID)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := entry.GetServiceManager().GetReadyAddresses(ctx, instance.ContainerServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cache[key] = &resolvedEndpoints{addrs: addrs, expireAt: time.Now().Add(endpointCacheTTL)}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// runtimeEntry returns the cached Kubernetes runtime entry of the environment
+func (r *endpointResolver) runtimeEntry(ctx context.Context, environmentID uint) (*container.Entry, error) {
+	environment, err := mysql.McpEnvironmentRepo.FindByID(ctx, environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get environment: %w", err)
+	}
+	if environment.Environment != model.McpEnvironmentKubernetes {
+		return nil, fmt.Errorf("session affinity requires a Kubernetes environment, got %s", environment.Environment)
+	}
+
+	sum := sha256.Sum256([]byte(environment.Namespace + "\x00" + environment.Config))
+	key := strconv.FormatUint(uint64(environment.ID), 10)
+	return r.entries.Get(key, hex.EncodeToString(sum[:]), func() (*container.Entry, error) {
+		return container.NewEntry(container.Config{
+			Runtime:    container.RuntimeKubernetes,
+			Namespace:  environment.Namespace,
+			Kubeconfig: common.SetKubeConfig([]byte(environment.Config)),
+		})
+	})
+}
+
+// PickAddress picks a ready pod address for a new SSE connection
+func (r *endpointResolver) PickAddress(ctx context.Context, instance *model.McpInstance) (string, error) {
+	addrs, err := r.ReadyAddresses(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no ready pod behind service %s", instance.ContainerServiceName)
+	}
+	return addrs[rand.Intn(len(addrs))], nil
+}
+
+// IsReadyAddress reports whether addr is a ready pod of the instance service.
+// Pod tokens come from clients, so they are only honored for known pods.
+func (r *endpointResolver) IsReadyAddress(ctx context.Context, instance *model.McpInstance, addr string) bool {
+	addrs, err := r.ReadyAddresses(ctx, instance)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// needsSessionAffinity reports whether SSE requests of the instance must stick to one pod.
+// Single-replica instances keep routing through the Service VIP.
+func needsSessionAffinity(instanceInfo *InstanceInfo) bool {
+	return instanceInfo.AccessType == model.AccessTypeHosting &&
+		instanceInfo.McpProtocol == model.McpProtocolSSE &&
+		instanceInfo.Instance != nil &&
+		instanceInfo.Instance.DesiredReplicas() > 1
+}
+
+// routeSSEReqToPod pins a new SSE connection to one ready pod and records it in the request context,
+// falls back to the Service VIP when no pod can be resolved
+func routeSSEReqToPod(req *http.Request, instanceInfo *InstanceInfo) {
+	if !needsSessionAffinity(instanceInfo) {
+		return
+	}
+	addr, err := defaultEndpointResolver.PickAddress(req.Context(), instanceInfo.Instance)
+	if err != nil {
+		logger.FromContext(req.Context()).Warn("Failed to pick pod for SSE session, routing through service",
+			zap.String("instance_id", instanceInfo.InstanceID),
+			zap.Error(err),
+		)
+		return
+	}
+	req.URL.Host = addr
+	*req = *req.WithContext(context.WithValue(req.Context(), PodAddrKey, addr))
+}
+
+// podAddrFromEventReq strips the pod token from an SSE event request path and returns
+// the pod to route to, empty when the request should go through the Service VIP
+func podAddrFromEventReq(req *http.Request, instanceInfo *InstanceInfo, prefix string) string {
+	token, rest, ok := splitAffinityPath(req.URL.Path, prefix)
+	if !ok {
+		return ""
+	}
+	req.URL.Path = rest
+	if !needsSessionAffinity(instanceInfo) {
+		return ""
+	}
+	addr, err := decodePodToken(token)
+	if err != nil || !defaultEndpointResolver.IsReadyAddress(req.Context(), instanceInfo.Instance, addr) {
+		logger.FromContext(req.Context()).Warn("SSE session pod is not available, routing through service",
+			zap.String("instance_id", instanceInfo.InstanceID),
+			zap.String("pod_token", token),
+			zap.Error(err),
+		)
+		return ""
+	}
+	return addr
+}
+
+// encodePodToken encodes a pod address into a path-safe token
+func encodePodToken(addr string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(addr))
+}
+
+// decodePodToken decodes a pod token back to a pod address
+func decodePodToken(token string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	addr := string(b)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// affinityPrefix returns the proxy prefix with the pod token appended
+func affinityPrefix(prefix, token string) string {
+	return path.Join(prefix, affinityPathSegment, token)
+}
+
+// splitAffinityPath extracts the pod token following prefix and returns the path without it
+func splitAffinityPath(reqPath, prefix string) (token string, rest string, ok bool) {
+	marker := path.Join("/", prefix, affinityPathSegment) + "/"
+	if !strings.HasPrefix(reqPath, marker) {
+		return "", reqPath, false
+	}
+	remain := strings.TrimPrefix(reqPath, marker)
+	token, after, _ := strings.Cut(remain, "/")
+	if token == "" {
+		return "", reqPath, false
+	}
+	return token, path.Join("/", prefix) + "/" + after, true
+}
+
+// extractSessionID extracts the MCP session identifier from an SSE endpoint event payload
+func extractSessionID(msg string) string {
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		if err != nil {
+			return ""
+		}
+		query := u.Query()
+		if sessionID := query.Get("session_id"); sessionID != "" {
+			return sessionID
+		}
+		return query.Get("sessionId")
+	}
+	return ""
+}
@@ -1,6 +1,9 @@
 package proxy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -10,38 +13,45 @@ import (
 	"go.uber.org/zap"
 )
 
-// 定义错误类型
+// 定义错误类型，status 为返回给客户端的 HTTP 状态码
 type proxyError struct {
 	message string
 	status  int
+	// location redirect target, the request is redirected with status instead of failing
+	location string
 }
 
 func (e *proxyError) Error() string {
 	return e.message
 }
 
-// errorHandler 处理代理请求过程中的错误
+// errorHandler 处理代理请求过程中的错误，以 JSON-RPC 格式的错误对象返回给客户端
 func errorHandler(w http.ResponseWriter, r *http.Request, err error) {
-	// 检查是否是连接中断相关的错误
-	if isProxyConnectionError(err) {
-		// 连接中断是正常情况，使用 Debug 级别记录
+	// 请求体在转发过程中超出大小限制
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeRequestTooLarge(w, r, maxBytesErr.Limit)
+		return
+	}
+
+	// 客户端主动断开时无需响应
+	if errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled) {
 		logger.Debug("Proxy connection interrupted",
 			zap.Error(err),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
 			zap.String("remote_addr", r.RemoteAddr),
 		)
-		// 对于连接中断，不需要向客户端发送错误响应
 		return
 	}
 
-	// 其他错误使用 Error 级别记录
-	logger.Error("Proxy error", zap.Error(err))
+	logger.FromContext(r.Context()).Error("Proxy error", zap.Error(err))
 	if pe, ok := err.(*proxyError); ok {
-		http.Error(w, pe.message, pe.status)
-	} else {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		writeGatewayError(w, r, pe.status, pe.message)
+		return
 	}
+	// 上游连接失败或超时
+	writeGatewayError(w, r, upstreamErrorStatus(err), fmt.Sprintf("upstream request failed: %v", err))
 }
 
 type wrapPool struct{}
@@ -52,22 +62,6 @@ func (p *wrapPool) Get() []byte { return pool.GetBuf(32 * 1024) }
 
 func (p *wrapPool) Put(buf []byte) { pool.PutBuf(buf) }
 
-// isProxyConnectionError 检查代理错误是否与连接中断相关
-func isProxyConnectionError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errorStr := err.Error()
-	return strings.Contains(errorStr, "connection reset") ||
-		strings.Contains(errorStr, "broken pipe") ||
-		strings.Contains(errorStr, "connection refused") ||
-		strings.Contains(errorStr, "context canceled") ||
-		strings.Contains(errorStr, "context deadline exceeded") ||
-		strings.Contains(errorStr, "use of closed network connection") ||
-		strings.Contains(errorStr, "client disconnected") ||
-		strings.Contains(errorStr, "EOF")
-}
-
 // proxyLogger 实现 io.Writer 接口，将日志转发到 zap logger
 type proxyLogger struct{}
 
@@ -105,6 +99,17 @@ type contextKey string
 const (
 	IsSSEReqKey     contextKey = "isSSEReq"
 	InstanceInfoKey contextKey = "instanceInfo"
+	PodAddrKey      contextKey = "podAddr" // 会话亲和时选中的 Pod 地址
+	// 可缓存请求的缓存键，响应返回后写入缓存
+	ResponseCacheCallKey contextKey = "responseCacheCall"
+	// 已登记的 SSE 连接，排空时结束该连接
+	SSEConnKey contextKey = "sseConn"
+	// 金丝雀路由选中的上游
+	RouteChoiceKey contextKey = "routeChoice"
+	// 请求令牌的权限范围，只有受限的令牌才设置
+	TokenScopeKey contextKey = "tokenScope"
+	// 客户端访问网关使用的对外地址和路径前缀，在实例请求头写入请求前记录
+	PublicAccessKey contextKey = "publicAccess"
 
 	MCP_SERVER_SUBFIX_SSE = "sse"
 	MCP_SERVER_SUBFIX_MCP = "mcp"
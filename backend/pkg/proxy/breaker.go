@@ -0,0 +1,309 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultFailureThreshold consecutive connection errors that open the breaker
+	defaultFailureThreshold = 5
+	// defaultBreakerCooldown time the breaker stays open before a probe request is allowed
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// CircuitBreakerStore publishes breaker state changes so that the market can report them
+type CircuitBreakerStore interface {
+	Save(instanceID string, state *common.CircuitBreakerState) error
+	Delete(instanceID string) error
+}
+
+// circuitBreaker breaker state of one upstream, a server of a multi-server instance has its own breaker
+type circuitBreaker struct {
+	state     string
+	failures  int
+	lastError string
+	openedAt  time.Time
+	// probing a half-open probe request is in flight
+	probing bool
+}
+
+// breakerRegistry per-instance circuit breakers of the gateway
+type breakerRegistry struct {
+	threshold int
+	cooldown  time.Duration
+	store     CircuitBreakerStore
+
+	mu sync.Mutex
+	// breakers instance id -> server name -> breaker, single-server instances use the empty server name
+	breakers map[string]map[string]*circuitBreaker
+	now      func() time.Time
+}
+
+var (
+	breakersMu     sync.RWMutex
+	activeBreakers *breakerRegistry
+)
+
+// SetCircuitBreaker configures the per-instance circuit breaker, a disabled config turns it off.
+// store may be nil, breaker state is then only kept in memory.
+func SetCircuitBreaker(cfg common.CircuitBreakerConfig, store CircuitBreakerStore) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	if !cfg.Enabled {
+		activeBreakers = nil
+		return
+	}
+	registry := &breakerRegistry{
+		threshold: cfg.FailureThreshold,
+		cooldown:  time.Duration(cfg.Cooldown) * time.Second,
+		store:     store,
+		breakers:  make(map[string]map[string]*circuitBreaker),
+		now:       time.Now,
+	}
+	if registry.threshold <= 0 {
+		registry.threshold = defaultFailureThreshold
+	}
+	if registry.cooldown <= 0 {
+		registry.cooldown = defaultBreakerCooldown
+	}
+	activeBreakers = registry
+}
+
+// getBreakers returns the active breaker registry, nil when disabled
+func getBreakers() *breakerRegistry {
+	breakersMu.RLock()
+	defer breakersMu.RUnlock()
+	return activeBreakers
+}
+
+// ResetCircuitBreaker closes the breakers of all servers of an instance, called when an admin resets it
+func ResetCircuitBreaker(instanceID string) {
+	registry := getBreakers()
+	if registry == nil {
+		return
+	}
+	registry.mu.Lock()
+	delete(registry.breakers, instanceID)
+	registry.mu.Unlock()
+	logger.Info("Circuit breaker reset", zap.String("instance_id", instanceID))
+}
+
+// allow reports whether a request to a server of the instance may be sent upstream, and when rejected
+// how long until the next probe. An open breaker lets a single probe through after the cooldown.
+func (r *breakerRegistry) allow(instanceID, serverName string) (bool, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[instanceID][serverName]
+	if !ok {
+		return true, 0
+	}
+	switch b.state {
+	case common.CircuitBreakerOpen:
+		if wait := b.openedAt.Add(r.cooldown).Sub(r.now()); wait > 0 {
+			return false, wait
+		}
+		b.state = common.CircuitBreakerHalfOpen
+		b.probing = true
+		return true, 0
+	case common.CircuitBreakerHalfOpen:
+		if b.probing {
+			return false, r.cooldown
+		}
+		b.probing = true
+		return true, 0
+	}
+	return true, 0
+}
+
+// record updates the breaker of a server of the instance with the outcome of an upstream round trip
+func (r *breakerRegistry) record(instanceID, serverName string, err error) {
+	switch {
+	case err == nil:
+		r.recordSuccess(instanceID, serverName)
+	case isUpstreamFailure(err):
+		r.recordFailure(instanceID, serverName, err)
+	default:
+		// 客户端取消等与上游无关的错误，释放探测名额
+		r.mu.Lock()
+		if b, ok := r.breakers[instanceID][serverName]; ok {
+			b.probing = false
+		}
+		r.mu.Unlock()
+	}
+}
+
+// recordSuccess closes the breaker once the upstream answers. The published instance state is
+// removed when no other server of the instance is still tripped.
+func (r *breakerRegistry) recordSuccess(instanceID, serverName string) {
+	r.mu.Lock()
+	servers := r.breakers[instanceID]
+	b, ok := servers[serverName]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(servers, serverName)
+	if len(servers) == 0 {
+		delete(r.breakers, instanceID)
+	}
+	var tripped *common.CircuitBreakerState
+	for _, other := range servers {
+		if other.state != common.CircuitBreakerClosed {
+			tripped = r.snapshot(other)
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if b.state != common.CircuitBreakerClosed {
+		logger.Info("Circuit breaker closed", zap.String("instance_id", instanceID), zap.String("server_name", serverName))
+		r.publish(instanceID, tripped)
+	}
+}
+
+// recordFailure counts a connection error and opens the breaker at the threshold,
+// a failed half-open probe reopens it immediately
+func (r *breakerRegistry) recordFailure(instanceID, serverName string, err error) {
+	r.mu.Lock()
+	servers, ok := r.breakers[instanceID]
+	if !ok {
+		servers = make(map[string]*circuitBreaker)
+		r.breakers[instanceID] = servers
+	}
+	b, ok := servers[serverName]
+	if !ok {
+		b = &circuitBreaker{state: common.CircuitBreakerClosed}
+		servers[serverName] = b
+	}
+	b.failures++
+	b.lastError = err.Error()
+	b.probing = false
+	opened := false
+	if b.state == common.CircuitBreakerHalfOpen || (b.state == common.CircuitBreakerClosed && b.failures >= r.threshold) {
+		b.state = common.CircuitBreakerOpen
+		b.openedAt = r.now()
+		opened = true
+	}
+	snapshot := r.snapshot(b)
+	r.mu.Unlock()
+
+	if opened {
+		logger.Warn("Circuit breaker opened",
+			zap.String("instance_id", instanceID),
+			zap.String("server_name", serverName),
+			zap.Int("failures", snapshot.Failures),
+			zap.String("last_error", snapshot.LastError),
+		)
+		r.publish(instanceID, snapshot)
+	}
+}
+
+// snapshot copies the breaker state, the caller holds r.mu
+func (r *breakerRegistry) snapshot(b *circuitBreaker) *common.CircuitBreakerState {
+	state := &common.CircuitBreakerState{
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if !b.openedAt.IsZero() {
+		state.OpenedAt = b.openedAt.UnixMilli()
+		state.RetryAt = b.openedAt.Add(r.cooldown).UnixMilli()
+	}
+	return state
+}
+
+// publish saves the breaker state of the instance to the store, nil state deletes it
+func (r *breakerRegistry) publish(instanceID string, state *common.CircuitBreakerState) {
+	if r.store == nil {
+		return
+	}
+	var err error
+	if state == nil {
+		err = r.store.Delete(instanceID)
+	} else {
+		err = r.store.Save(instanceID, state)
+	}
+	if err != nil {
+		logger.Warn("Failed to publish circuit breaker state", zap.String("instance_id", instanceID), zap.Error(err))
+	}
+}
+
+// isUpstreamFailure reports whether a round trip error means the upstream is unreachable:
+// dial errors, refused or reset connections and timeouts. Client cancellations are not counted.
+func isUpstreamFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rejectOpenCircuit answers a request to an instance or server whose breaker is open, returns false
+// when the request may be proxied
+func rejectOpenCircuit(w http.ResponseWriter, req *http.Request) bool {
+	registry := getBreakers()
+	if registry == nil {
+		return false
+	}
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok {
+		return false
+	}
+	allowed, retryAfter := registry.allow(instanceInfo.InstanceID, instanceInfo.ServerName)
+	if allowed {
+		return false
+	}
+
+	retrySeconds := int(math.Ceil(retryAfter.Seconds()))
+	body := newGatewayError(req, http.StatusServiceUnavailable,
+		fmt.Sprintf("instance %s appears to be down, requests are rejected for %ds", instanceInfo.InstanceID, retrySeconds))
+	body.Error.Data.RetryAfter = retrySeconds
+	writeGatewayErrorBody(w, body)
+	return true
+}
+
+// breakerTransport sends every upstream round trip through the instance's transport and
+// records its outcome in the breaker of the instance's server
+type breakerTransport struct{}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	base := http.RoundTripper(getTransports().base)
+	if ok && instanceInfo.Transport != nil {
+		base = instanceInfo.Transport
+	}
+	resp, err := base.RoundTrip(req)
+	// Unreachable canary routes fall back to the primary target, only its outcome counts for the breaker
+	if err != nil && ok {
+		if retry := fallbackToPrimary(req, instanceInfo, err); retry != nil {
+			resp, err = base.RoundTrip(retry)
+		}
+	}
+	if registry := getBreakers(); registry != nil && ok {
+		registry.record(instanceInfo.InstanceID, instanceInfo.ServerName, err)
+	}
+	return resp, err
+}
@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+)
+
+// memoryBreakerStore in-memory CircuitBreakerStore for tests
+type memoryBreakerStore map[string]*common.CircuitBreakerState
+
+func (m memoryBreakerStore) Save(instanceID string, state *common.CircuitBreakerState) error {
+	m[instanceID] = state
+	return nil
+}
+
+func (m memoryBreakerStore) Delete(instanceID string) error {
+	delete(m, instanceID)
+	return nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	logger.Init("error", "json")
+	store := memoryBreakerStore{}
+	SetCircuitBreaker(common.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, Cooldown: 10}, store)
+	defer SetCircuitBreaker(common.CircuitBreakerConfig{}, nil)
+
+	registry := getBreakers()
+	now := time.Unix(1000, 0)
+	registry.now = func() time.Time { return now }
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	// 未达到阈值时继续转发
+	registry.record("abc", "", refused)
+	if rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Fatal("request rejected below the failure threshold")
+	}
+
+	// 达到阈值后熔断，立即返回 503
+	registry.record("abc", "", refused)
+	if state := store["abc"]; state == nil || state.State != common.CircuitBreakerOpen || state.Failures != 2 {
+		t.Fatalf("published state = %+v, want open with 2 failures", state)
+	}
+	w := httptest.NewRecorder()
+	if !rejectOpenCircuit(w, newInstanceReq(http.MethodPost, "", nil)) {
+		t.Fatal("request not rejected while the breaker is open")
+	}
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "10" {
+		t.Errorf("rejection = %d Retry-After %q, want 503 and 10", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 冷却后只放行一个探测请求，探测失败重新熔断
+	now = now.Add(10 * time.Second)
+	if rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Fatal("probe request rejected after the cooldown")
+	}
+	if !rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Error("second request allowed while the probe is in flight")
+	}
+	registry.record("abc", "", refused)
+	if !rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Fatal("request allowed after a failed probe")
+	}
+
+	// 探测成功后恢复
+	now = now.Add(10 * time.Second)
+	if rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Fatal("probe request rejected after the cooldown")
+	}
+	registry.record("abc", "", nil)
+	if _, ok := store["abc"]; ok {
+		t.Error("breaker state still published after a successful probe")
+	}
+	if rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Error("request rejected after the breaker closed")
+	}
+
+	// 管理员重置
+	registry.record("abc", "", refused)
+	registry.record("abc", "", refused)
+	ResetCircuitBreaker("abc")
+	if rejectOpenCircuit(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "", nil)) {
+		t.Error("request rejected after reset")
+	}
+}
+
+// 多服务实例的每个服务单独熔断，一个服务不可达不影响其他服务
+func TestCircuitBreakerPerServer(t *testing.T) {
+	logger.Init("error", "json")
+	store := memoryBreakerStore{}
+	SetCircuitBreaker(common.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, Cooldown: 10}, store)
+	defer SetCircuitBreaker(common.CircuitBreakerConfig{}, nil)
+
+	registry := getBreakers()
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	serverReq := func(serverName string) *http.Request {
+		return newInstanceReq(http.MethodPost, "", &InstanceInfo{InstanceID: "abc", ServerName: serverName})
+	}
+
+	registry.record("abc", "down", refused)
+	registry.record("abc", "up", nil)
+	registry.record("abc", "down", refused)
+	if !rejectOpenCircuit(httptest.NewRecorder(), serverReq("down")) {
+		t.Fatal("request to the failing server not rejected")
+	}
+	if rejectOpenCircuit(httptest.NewRecorder(), serverReq("up")) {
+		t.Error("request to the healthy server rejected")
+	}
+	if state := store["abc"]; state == nil || state.State != common.CircuitBreakerOpen {
+		t.Fatalf("published state = %+v, want open", state)
+	}
+
+	// 一个服务熔断后恢复，不会清除仍在熔断的其他服务的状态
+	registry.record("abc", "up", refused)
+	registry.record("abc", "up", refused)
+	registry.record("abc", "up", nil)
+	if rejectOpenCircuit(httptest.NewRecorder(), serverReq("up")) {
+		t.Error("request to the recovered server rejected")
+	}
+	if state := store["abc"]; state == nil || state.State != common.CircuitBreakerOpen {
+		t.Errorf("published state = %+v after a success of another server, want open", state)
+	}
+
+	ResetCircuitBreaker("abc")
+	if rejectOpenCircuit(httptest.NewRecorder(), serverReq("down")) {
+		t.Error("request rejected after reset")
+	}
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"client canceled", context.Canceled, false},
+		{"other error", errors.New("malformed response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUpstreamFailure(tt.err); got != tt.want {
+				t.Errorf("isUpstreamFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
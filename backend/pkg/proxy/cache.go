@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ResponseCacheHeader marks whether a response was served from the cache: HIT / MISS / BYPASS
+	ResponseCacheHeader = "X-MCP-Cache"
+	// ResponseCacheBypassHeader skips the cache lookup when set to a non-empty value,
+	// the fresh upstream response still refreshes the cache
+	ResponseCacheBypassHeader = "X-MCP-Cache-Bypass"
+
+	// defaultResponseCacheTTL cache TTL used when none is configured
+	defaultResponseCacheTTL = 30 * time.Second
+	// maxCachedBodySize requests larger than this are never cached, responses are bounded
+	// by the response buffer limit
+	maxCachedBodySize = 1 << 20
+)
+
+// defaultCachedMethods JSON-RPC methods cached when none are configured
+var defaultCachedMethods = []string{"tools/list", "prompts/list", "resources/list"}
+
+// responseCacheStats cache counters, exposed through expvar at /debug/vars
+var responseCacheStats = expvar.NewMap("gateway_response_cache")
+
+// ResponseCacheStore storage of cached JSON-RPC results, keyed per instance so that
+// all entries of an instance can be dropped when it is edited or restarted
+type ResponseCacheStore interface {
+	Get(instanceID, key string) ([]byte, bool, error)
+	Set(instanceID, key string, value []byte, ttl time.Duration) error
+}
+
+// responseCache caches results of idempotent MCP methods
+type responseCache struct {
+	store   ResponseCacheStore
+	ttl     time.Duration
+	methods map[string]bool
+}
+
+var (
+	responseCacheMu     sync.RWMutex
+	activeResponseCache *responseCache
+)
+
+// SetResponseCache configures the gateway response cache, a disabled config turns it off
+func SetResponseCache(cfg common.ResponseCacheConfig, store ResponseCacheStore) {
+	responseCacheMu.Lock()
+	defer responseCacheMu.Unlock()
+
+	if !cfg.Enabled || store == nil {
+		activeResponseCache = nil
+		return
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = defaultCachedMethods
+	}
+	cache := &responseCache{
+		store:   store,
+		ttl:     time.Duration(cfg.TTL) * time.Second,
+		methods: make(map[string]bool, len(methods)),
+	}
+	if cache.ttl <= 0 {
+		cache.ttl = defaultResponseCacheTTL
+	}
+	for _, method := range methods {
+		cache.methods[method] = true
+	}
+	activeResponseCache = cache
+}
+
+// getResponseCache returns the active response cache, nil when disabled
+func getResponseCache() *responseCache {
+	responseCacheMu.RLock()
+	defer responseCacheMu.RUnlock()
+	return activeResponseCache
+}
+
+// cachedCall a cacheable JSON-RPC request
+type cachedCall struct {
+	instanceID string
+	key        string
+	id         json.RawMessage
+}
+
+// jsonRPCRequest fields of a JSON-RPC request used for caching
+type jsonRPCRequest struct {
+	ID     json.RawMessage
+	Method string
+	Params json.RawMessage
+}
+
+// UnmarshalJSON reads the exact "id", "method" and "params" keys, like policyCall. A case-variant
+// copy of a key would otherwise cache the upstream result under another method or params.
+func (r *jsonRPCRequest) UnmarshalJSON(data []byte) error {
+	fields, err := exactFields(data, "id", "method", "params")
+	if err != nil {
+		return err
+	}
+	r.ID, r.Params = fields["id"], fields["params"]
+	if raw, ok := fields["method"]; ok {
+		return json.Unmarshal(raw, &r.Method)
+	}
+	return nil
+}
+
+// jsonRPCResponse JSON-RPC response
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// serveFromCache answers a cacheable request from the cache, returns false when the request
+// has to be proxied. Misses and bypasses are recorded in the request context so that
+// modifyResponse stores the upstream result.
+func serveFromCache(w http.ResponseWriter, req *http.Request) bool {
+	cache := getResponseCache()
+	if cache == nil {
+		return false
+	}
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok {
+		return false
+	}
+	call, ok := cache.cacheableCall(req, instanceInfo)
+	if !ok {
+		return false
+	}
+
+	if req.Header.Get(ResponseCacheBypassHeader) != "" {
+		responseCacheStats.Add("bypass", 1)
+		w.Header().Set(ResponseCacheHeader, "BYPASS")
+		*req = *req.WithContext(context.WithValue(req.Context(), ResponseCacheCallKey, call))
+		return false
+	}
+
+	result, found, err := cache.store.Get(call.instanceID, call.key)
+	if err != nil {
+		responseCacheStats.Add("errors", 1)
+		logger.FromContext(req.Context()).Warn("Failed to read response cache", zap.Error(err))
+	}
+	if found {
+		body, err := json.Marshal(&jsonRPCResponse{JSONRPC: "2.0", ID: call.id, Result: result})
+		if err == nil {
+			responseCacheStats.Add("hits", 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(ResponseCacheHeader, "HIT")
+			w.WriteHeader(http.StatusOK)
+			w.Write(body)
+			return true
+		}
+	}
+
+	responseCacheStats.Add("misses", 1)
+	w.Header().Set(ResponseCacheHeader, "MISS")
+	*req = *req.WithContext(context.WithValue(req.Context(), ResponseCacheCallKey, call))
+	return false
+}
+
+// cacheableCall parses a streamable-http POST and returns its cache key when the
+// JSON-RPC method is in the allow-list. The request body is restored for proxying.
+// Instances with canary routes are not cached: the route is only chosen when the request is
+// proxied and the upstream versions may answer differently.
+func (c *responseCache) cacheableCall(req *http.Request, instanceInfo *InstanceInfo) (*cachedCall, bool) {
+	if req.Method != http.MethodPost || instanceInfo.McpProtocol != model.McpProtocolStreamableHttp || req.Body == nil {
+		return nil, false
+	}
+	if instanceInfo.McpConfig != nil && len(instanceInfo.McpConfig.Routes) > 0 {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxCachedBodySize+1))
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	if err != nil || len(body) > maxCachedBodySize {
+		return nil, false
+	}
+
+	var rpcReq jsonRPCRequest
+	if err := json.Unmarshal(body, &rpcReq); err != nil || len(rpcReq.ID) == 0 || !c.methods[rpcReq.Method] {
+		return nil, false
+	}
+	paramsHash, err := hashParams(rpcReq.Params)
+	if err != nil {
+		return nil, false
+	}
+
+	// 多服务实例按服务区分缓存
+	key := rpcReq.Method + ":" + paramsHash
+	if instanceInfo.ServerName != "" {
+		key = instanceInfo.ServerName + ":" + key
+	}
+	return &cachedCall{instanceID: instanceInfo.InstanceID, key: key, id: rpcReq.ID}, true
+}
+
+// hashParams hashes JSON-RPC params, object keys are sorted so that equivalent params share a key
+func hashParams(params json.RawMessage) (string, error) {
+	canonical := []byte("null")
+	if len(bytes.TrimSpace(params)) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(params, &v); err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		canonical = b
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// storeCachedResponse stores the result of a successful JSON response, the response body is
+// restored so that it still reaches the client unchanged. At most maxBufferSize bytes are
+// held in memory, larger responses are passed through uncached.
+func storeCachedResponse(resp *http.Response, call *cachedCall, maxBufferSize int64) {
+	cache := getResponseCache()
+	if cache == nil || resp.StatusCode != http.StatusOK ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") ||
+		resp.Header.Get("Content-Encoding") != "" {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBufferSize+1))
+	resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+	if err != nil {
+		return
+	}
+	if int64(len(body)) > maxBufferSize {
+		logger.FromContext(resp.Request.Context()).Info("Response exceeds buffer limit, not cached",
+			zap.String("instance_id", call.instanceID),
+			zap.Int64("limit", maxBufferSize),
+		)
+		return
+	}
+
+	var rpcResp jsonRPCResponse
+	if err := json.Unmarshal(body, &rpcResp); err != nil || len(rpcResp.Result) == 0 || len(rpcResp.Error) > 0 {
+		return
+	}
+	if err := cache.store.Set(call.instanceID, call.key, rpcResp.Result, cache.ttl); err != nil {
+		responseCacheStats.Add("errors", 1)
+		logger.FromContext(resp.Request.Context()).Warn("Failed to write response cache", zap.Error(err))
+		return
+	}
+	responseCacheStats.Add("stores", 1)
+}
+
+// readCloser combines a replayed body reader with the original body closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,183 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+// memoryCacheStore in-memory ResponseCacheStore for tests
+type memoryCacheStore map[string][]byte
+
+func (m memoryCacheStore) Get(instanceID, key string) ([]byte, bool, error) {
+	v, ok := m[instanceID+"/"+key]
+	return v, ok, nil
+}
+
+func (m memoryCacheStore) Set(instanceID, key string, value []byte, ttl time.Duration) error {
+	m[instanceID+"/"+key] = value
+	return nil
+}
+
+func TestResponseCache(t *testing.T) {
+	store := memoryCacheStore{}
+	SetResponseCache(common.ResponseCacheConfig{Enabled: true}, store)
+	defer SetResponseCache(common.ResponseCacheConfig{}, nil)
+
+	listReq := `{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"b":1,"a":2}}`
+
+	// 首次请求未命中，上游响应写入缓存
+	req := newInstanceReq(http.MethodPost, listReq, &InstanceInfo{InstanceID: "abc", McpProtocol: model.McpProtocolStreamableHttp})
+	w := httptest.NewRecorder()
+	if serveFromCache(w, req) {
+		t.Fatal("serveFromCache() on empty cache = true, want false")
+	}
+	if got := w.Header().Get(ResponseCacheHeader); got != "MISS" {
+		t.Errorf("%s = %q, want MISS", ResponseCacheHeader, got)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != listReq {
+		t.Errorf("request body = %q, want it restored", body)
+	}
+	call, ok := req.Context().Value(ResponseCacheCallKey).(*cachedCall)
+	if !ok {
+		t.Fatal("cache call not recorded in request context")
+	}
+	upstream := `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(upstream)),
+		Request:    req,
+	}
+	storeCachedResponse(resp, call, defaultMaxResponseBufferSize)
+	if body, _ := io.ReadAll(resp.Body); string(body) != upstream {
+		t.Errorf("response body = %q, want it restored", body)
+	}
+
+	// 参数顺序不同的相同请求命中缓存，并使用本次请求的 id
+	req = newInstanceReq(http.MethodPost, `{"jsonrpc":"2.0","id":"x","method":"tools/list","params":{"a":2,"b":1}}`, &InstanceInfo{InstanceID: "abc", McpProtocol: model.McpProtocolStreamableHttp})
+	w = httptest.NewRecorder()
+	if !serveFromCache(w, req) {
+		t.Fatal("serveFromCache() after store = false, want hit")
+	}
+	if got := w.Header().Get(ResponseCacheHeader); got != "HIT" {
+		t.Errorf("%s = %q, want HIT", ResponseCacheHeader, got)
+	}
+	if want := `{"jsonrpc":"2.0","id":"x","result":{"tools":[]}}`; w.Body.String() != want {
+		t.Errorf("cached body = %q, want %q", w.Body.String(), want)
+	}
+
+	// 绕过请求头跳过查询
+	req = newInstanceReq(http.MethodPost, listReq, &InstanceInfo{InstanceID: "abc", McpProtocol: model.McpProtocolStreamableHttp})
+	req.Header.Set(ResponseCacheBypassHeader, "1")
+	w = httptest.NewRecorder()
+	if serveFromCache(w, req) || w.Header().Get(ResponseCacheHeader) != "BYPASS" {
+		t.Errorf("bypass request: header = %q, want BYPASS without serving", w.Header().Get(ResponseCacheHeader))
+	}
+}
+
+func TestResponseCacheSkipsUncacheable(t *testing.T) {
+	SetResponseCache(common.ResponseCacheConfig{Enabled: true, Methods: []string{"tools/list"}}, memoryCacheStore{})
+	defer SetResponseCache(common.ResponseCacheConfig{}, nil)
+
+	tests := []struct {
+		name     string
+		body     string
+		protocol model.McpProtocol
+	}{
+		{"method not allowed", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{}}`, model.McpProtocolStreamableHttp},
+		{"notification", `{"jsonrpc":"2.0","method":"tools/list"}`, model.McpProtocolStreamableHttp},
+		{"sse instance", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, model.McpProtocolSSE},
+		{"batch request", `[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`, model.McpProtocolStreamableHttp},
+		{"case-variant method", `{"jsonrpc":"2.0","id":1,"method":"tools/call","Method":"tools/list"}`, model.McpProtocolStreamableHttp},
+		{"duplicate params", `{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{},"params":{"cursor":"x"}}`, model.McpProtocolStreamableHttp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newInstanceReq(http.MethodPost, tt.body, &InstanceInfo{InstanceID: "abc", McpProtocol: tt.protocol})
+			w := httptest.NewRecorder()
+			if serveFromCache(w, req) || w.Header().Get(ResponseCacheHeader) != "" {
+				t.Errorf("uncacheable request got %s = %q", ResponseCacheHeader, w.Header().Get(ResponseCacheHeader))
+			}
+		})
+	}
+}
+
+// 金丝雀路由的实例不走缓存，主目标和金丝雀各自返回自己的工具列表
+func TestResponseCacheSkipsCanaryRoutes(t *testing.T) {
+	logger.Init("error", "json")
+	SetResponseCache(common.ResponseCacheConfig{Enabled: true}, memoryCacheStore{})
+	defer SetResponseCache(common.ResponseCacheConfig{}, nil)
+
+	upstream := func(tool string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":%q}]}}`, tool)
+		}))
+	}
+	primary := upstream("primary-tool")
+	defer primary.Close()
+	canary := upstream("canary-tool")
+	defer canary.Close()
+
+	targetConfig := fmt.Sprintf(`{"mcpServers":{"fetch":{"url":"%s/mcp","routes":[{"targetURL":"%s/mcp","matchHeader":"X-Canary"}]}}}`,
+		primary.URL, canary.URL)
+	instanceLookupMu.Lock()
+	previous := activeInstanceLookup
+	activeInstanceLookup = newInstanceLookup(common.InstanceFallbackConfig{},
+		func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+			return &model.McpInstance{
+				InstanceID:   instanceID,
+				Status:       model.InstanceStatusActive,
+				AccessType:   model.AccessTypeProxy,
+				McpProtocol:  model.McpProtocolStreamableHttp,
+				TargetConfig: []byte(targetConfig),
+			}, nil
+		})
+	instanceLookupMu.Unlock()
+	defer func() {
+		instanceLookupMu.Lock()
+		activeInstanceLookup = previous
+		instanceLookupMu.Unlock()
+	}()
+
+	mrp := NewMCPReverseProxy()
+	for _, tt := range []struct {
+		canary    bool
+		wantRoute string
+		wantTool  string
+	}{
+		{canary: false, wantRoute: "primary", wantTool: "primary-tool"},
+		{canary: true, wantRoute: "route-1", wantTool: "canary-tool"},
+		{canary: false, wantRoute: "primary", wantTool: "primary-tool"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, common.GetGatewayRoutePrefix()+"/abc/mcp",
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+		if tt.canary {
+			req.Header.Set("X-Canary", "1")
+		}
+		rec := httptest.NewRecorder()
+		mrp.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(ResponseCacheHeader); got != "" {
+			t.Errorf("%s = %q, want no caching for instances with routes", ResponseCacheHeader, got)
+		}
+		if got := rec.Header().Get(RouteHeader); got != tt.wantRoute {
+			t.Errorf("%s = %q, want %q", RouteHeader, got, tt.wantRoute)
+		}
+		if !strings.Contains(rec.Body.String(), tt.wantTool) {
+			t.Errorf("canary=%v body = %s, want %s", tt.canary, rec.Body.String(), tt.wantTool)
+		}
+	}
+}
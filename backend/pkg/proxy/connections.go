@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// sseConnectionRetryAfter seconds a rejected client should wait before reconnecting
+	sseConnectionRetryAfter = 5
+	// sseConnectionReportInterval how often connection counts are published to the store
+	sseConnectionReportInterval = 5 * time.Second
+)
+
+var (
+	// sseConnectionStats open SSE connections per instance, exposed through expvar at /debug/vars
+	sseConnectionStats = expvar.NewMap("gateway_sse_connections")
+	// sseRejectedStats SSE connections rejected by the per-instance cap
+	sseRejectedStats = expvar.NewMap("gateway_sse_rejected")
+)
+
+// SSEConnectionStore publishes the SSE connection counts of this gateway so that the market can report them
+type SSEConnectionStore interface {
+	SaveConnectionCounts(counts map[string]int) error
+}
+
+// sseConn an open SSE connection, closing drain ends the stream
+type sseConn struct {
+	drain   chan struct{}
+	drained bool
+}
+
+// sseConnections open SSE connections of the gateway per instance
+type sseConnections struct {
+	mu             sync.Mutex
+	maxPerInstance int
+	store          SSEConnectionStore
+	conns          map[string]map[*sseConn]struct{}
+	// reported instances whose non-zero count was published, so that the drop to zero is published too
+	reported map[string]bool
+}
+
+var activeSSEConns = &sseConnections{
+	conns:    make(map[string]map[*sseConn]struct{}),
+	reported: make(map[string]bool),
+}
+
+// SetSSEConnections configures the per-instance SSE connection cap, 0 means unlimited.
+// store may be nil, counts are then only exposed through expvar.
+func SetSSEConnections(cfg common.SSEConnectionsConfig, store SSEConnectionStore) {
+	activeSSEConns.mu.Lock()
+	defer activeSSEConns.mu.Unlock()
+	activeSSEConns.maxPerInstance = cfg.MaxPerInstance
+	activeSSEConns.store = store
+}
+
+// SetSSEConnectionLimit changes the per-instance SSE connection cap without touching open connections,
+// connections above a lowered cap stay open until they close
+func SetSSEConnectionLimit(maxPerInstance int) {
+	activeSSEConns.mu.Lock()
+	defer activeSSEConns.mu.Unlock()
+	activeSSEConns.maxPerInstance = maxPerInstance
+}
+
+// acquire registers a new connection, returns false when the instance is at its cap
+func (c *sseConnections) acquire(instanceID string) (*sseConn, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conns := c.conns[instanceID]
+	if c.maxPerInstance > 0 && len(conns) >= c.maxPerInstance {
+		return nil, false
+	}
+	if conns == nil {
+		conns = make(map[*sseConn]struct{})
+		c.conns[instanceID] = conns
+	}
+	conn := &sseConn{drain: make(chan struct{})}
+	conns[conn] = struct{}{}
+	sseConnectionStats.Add(instanceID, 1)
+	return conn, true
+}
+
+// release removes a closed connection
+func (c *sseConnections) release(instanceID string, conn *sseConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conns := c.conns[instanceID]
+	if _, ok := conns[conn]; !ok {
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(c.conns, instanceID)
+	}
+	sseConnectionStats.Add(instanceID, -1)
+}
+
+// count returns the open connections of an instance
+func (c *sseConnections) count(instanceID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.conns[instanceID])
+}
+
+// drain ends every open SSE connection of an instance, returns the number of connections drained
+func (c *sseConnections) drain(instanceID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	drained := 0
+	for conn := range c.conns[instanceID] {
+		if !conn.drained {
+			conn.drained = true
+			close(conn.drain)
+			drained++
+		}
+	}
+	return drained
+}
+
+// counts returns the connection counts to publish, instances whose connections all closed
+// since the last report are included once with a zero count
+func (c *sseConnections) counts() (map[string]int, SSEConnectionStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int, len(c.conns))
+	for instanceID := range c.reported {
+		if _, ok := c.conns[instanceID]; !ok {
+			counts[instanceID] = 0
+			delete(c.reported, instanceID)
+		}
+	}
+	for instanceID, conns := range c.conns {
+		counts[instanceID] = len(conns)
+		c.reported[instanceID] = true
+	}
+	return counts, c.store
+}
+
+// DrainSSEConnections gracefully closes all SSE connections of an instance, each client
+// receives a final error event asking it to reconnect later. Called before a planned restart.
+func DrainSSEConnections(instanceID string) {
+	drained := activeSSEConns.drain(instanceID)
+	logger.Info("Draining SSE connections", zap.String("instance_id", instanceID), zap.Int("connections", drained))
+}
+
+// ReportSSEConnections publishes the connection counts periodically until ctx is canceled
+func ReportSSEConnections(ctx context.Context) {
+	ticker := time.NewTicker(sseConnectionReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			counts, store := activeSSEConns.counts()
+			if store == nil || len(counts) == 0 {
+				continue
+			}
+			if err := store.SaveConnectionCounts(counts); err != nil {
+				logger.Warn("Failed to publish SSE connection counts", zap.Error(err))
+			}
+		}
+	}
+}
+
+// trackSSEConnection registers an SSE request with its instance and returns the function
+// releasing it, requests above the instance cap are rejected with 503
+func trackSSEConnection(w http.ResponseWriter, req *http.Request) (func(), bool) {
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok {
+		return func() {}, true
+	}
+
+	conn, ok := activeSSEConns.acquire(instanceInfo.InstanceID)
+	if !ok {
+		sseRejectedStats.Add(instanceInfo.InstanceID, 1)
+		logger.FromContext(req.Context()).Warn("SSE connection limit reached",
+			zap.String("instance_id", instanceInfo.InstanceID),
+			zap.Int("connections", activeSSEConns.count(instanceInfo.InstanceID)),
+		)
+		body := newGatewayError(req, http.StatusServiceUnavailable,
+			fmt.Sprintf("instance %s has too many open SSE connections", instanceInfo.InstanceID))
+		body.Error.Data.RetryAfter = sseConnectionRetryAfter
+		writeGatewayErrorBody(w, body)
+		return nil, false
+	}
+
+	*req = *req.WithContext(context.WithValue(req.Context(), SSEConnKey, conn))
+	return func() { activeSSEConns.release(instanceInfo.InstanceID, conn) }, true
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestSSEConnectionLimit(t *testing.T) {
+	logger.Init("error", "json")
+	SetSSEConnections(common.SSEConnectionsConfig{MaxPerInstance: 1}, nil)
+	defer SetSSEConnections(common.SSEConnectionsConfig{}, nil)
+
+	release, ok := trackSSEConnection(httptest.NewRecorder(), newInstanceReq(http.MethodGet, "", nil))
+	if !ok {
+		t.Fatal("first connection rejected")
+	}
+
+	// 达到上限后拒绝新连接
+	w := httptest.NewRecorder()
+	if _, ok := trackSSEConnection(w, newInstanceReq(http.MethodGet, "", nil)); ok {
+		t.Fatal("connection above the cap accepted")
+	}
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("rejection = %d Retry-After %q, want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 连接关闭后释放名额，连接数归零时上报一次 0
+	release()
+	if counts, _ := activeSSEConns.counts(); counts["abc"] != 0 {
+		t.Errorf("count after release = %d, want 0", counts["abc"])
+	}
+	release, ok = trackSSEConnection(httptest.NewRecorder(), newInstanceReq(http.MethodGet, "", nil))
+	if !ok {
+		t.Fatal("connection rejected after release")
+	}
+	if counts, _ := activeSSEConns.counts(); counts["abc"] != 1 {
+		t.Errorf("reported count = %d, want 1", counts["abc"])
+	}
+	release()
+	if counts, _ := activeSSEConns.counts(); len(counts) != 1 || counts["abc"] != 0 {
+		t.Errorf("counts after close = %v, want a single zero for abc", counts)
+	}
+	if counts, _ := activeSSEConns.counts(); len(counts) != 0 {
+		t.Errorf("counts after zero report = %v, want none", counts)
+	}
+}
+
+func TestDrainSSEConnections(t *testing.T) {
+	logger.Init("error", "json")
+
+	req := newInstanceReq(http.MethodGet, "", nil)
+	release, ok := trackSSEConnection(httptest.NewRecorder(), req)
+	if !ok {
+		t.Fatal("connection rejected")
+	}
+	defer release()
+	conn := req.Context().Value(SSEConnKey).(*sseConn)
+
+	// 上游一直没有数据，排空后以结束事件关闭流
+	upstream, upstreamWriter := io.Pipe()
+	defer upstreamWriter.Close()
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	reader := &SSEResponseBodyReader{
+		src:   upstream,
+		info:  &InstanceInfo{InstanceID: "abc"},
+		req:   req.WithContext(ctx),
+		drain: conn.drain,
+	}
+
+	DrainSSEConnections("abc")
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(got), "event: error\ndata: ") || !strings.Contains(string(got), "draining") {
+		t.Errorf("drained stream = %q, want a final error event", got)
+	}
+}
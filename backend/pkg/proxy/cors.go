@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// defaultCORSMethods methods allowed in preflight responses when the policy sets none
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions}
+	// defaultCORSHeaders request headers allowed in preflight responses when the policy sets none
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "Accept", McpSessionHeader, "Mcp-Protocol-Version", "Last-Event-ID", logger.RequestIDHeader}
+	// defaultCORSExposedHeaders response headers readable by browsers when the policy sets none
+	defaultCORSExposedHeaders = []string{McpSessionHeader, logger.RequestIDHeader}
+)
+
+// corsPolicy compiled CORS policy
+type corsPolicy struct {
+	anyOrigin bool
+	origins   map[string]struct{}
+	// suffixes wildcard origins split at the *, e.g. {"https://", ".example.com"}
+	suffixes         [][2]string
+	methods          map[string]struct{}
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+func compileCORS(cfg *model.McpCORSConfig) *corsPolicy {
+	if len(cfg.AllowedOrigins) == 0 {
+		return nil
+	}
+	policy := &corsPolicy{
+		origins:          make(map[string]struct{}, len(cfg.AllowedOrigins)),
+		methods:          make(map[string]struct{}),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			policy.suffixes = append(policy.suffixes, [2]string{scheme + "://", host})
+		default:
+			policy.origins[origin] = struct{}{}
+		}
+	}
+
+	// 允许所有来源时不携带凭据，否则任何网站都能以用户身份调用网关；配置校验也会拒绝这种组合
+	if policy.anyOrigin && policy.allowCredentials {
+		logger.Warn(`CORS credentials are not allowed with the "*" origin, ignoring allowCredentials`)
+		policy.allowCredentials = false
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		policy.methods[method] = struct{}{}
+		allowMethods = append(allowMethods, method)
+	}
+	policy.allowMethods = strings.Join(allowMethods, ", ")
+
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	policy.allowHeaders = strings.Join(headers, ", ")
+	exposed := cfg.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = defaultCORSExposedHeaders
+	}
+	policy.exposeHeaders = strings.Join(exposed, ", ")
+	if cfg.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+	return policy
+}
+
+// allowOrigin reports whether a browser origin may call the gateway
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+	for _, s := range p.suffixes {
+		if strings.HasPrefix(origin, s[0]) && strings.HasSuffix(origin, s[1]) && len(origin) > len(s[0])+len(s[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	corsMu            sync.RWMutex
+	activeCORS        *corsPolicy
+	instanceCORSCache = newCompiledConfigs(compileCORS)
+)
+
+// SetCORS configures the default CORS policy of the gateway, no allowed origins turns it off.
+// Safe to call while serving, the config reload applies changes with it.
+func SetCORS(cfg common.GatewayCORSConfig) {
+	policy := compileCORS(&model.McpCORSConfig{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	})
+	corsMu.Lock()
+	activeCORS = policy
+	corsMu.Unlock()
+}
+
+// corsPolicyFor returns the CORS policy of a request, the instance's own policy replaces the
+// gateway default. Requests whose instance is unknown use the default.
+func corsPolicyFor(req *http.Request) *corsPolicy {
+	if instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo); ok &&
+		instanceInfo.McpConfig != nil && instanceInfo.McpConfig.CORS != nil {
+		return instanceCORSCache.get(instanceInfo.McpConfig.CORS)
+	}
+	corsMu.RLock()
+	defer corsMu.RUnlock()
+	return activeCORS
+}
+
+// isPreflight reports whether a request is a CORS preflight, which never carries credentials
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// applyCORS sets the CORS headers of a browser request and answers preflights locally,
+// returns true when the response was written. Without a policy requests pass through unchanged.
+func applyCORS(w http.ResponseWriter, req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	policy := corsPolicyFor(req)
+	if policy == nil {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	preflight := isPreflight(req)
+	if !policy.allowOrigin(origin) {
+		if !preflight {
+			return false
+		}
+		logger.FromContext(req.Context()).Info("Rejected CORS preflight", zap.String("origin", origin))
+		writeGatewayError(w, req, http.StatusForbidden, "origin "+origin+" is not allowed")
+		return true
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if policy.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		header.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+		return false
+	}
+
+	method := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	if _, ok := policy.methods[method]; !ok {
+		writeGatewayError(w, req, http.StatusForbidden, "method "+method+" is not allowed")
+		return true
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", policy.allowMethods)
+	header.Set("Access-Control-Allow-Headers", policy.allowHeaders)
+	if policy.maxAge != "" {
+		header.Set("Access-Control-Max-Age", policy.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// stripUpstreamCORS removes the CORS headers of an upstream response when the gateway applies its
+// own policy, the reverse proxy would otherwise send both and browsers reject duplicate headers
+func stripUpstreamCORS(resp *http.Response) {
+	if resp.Request.Header.Get("Origin") == "" || corsPolicyFor(resp.Request) == nil {
+		return
+	}
+	for key := range resp.Header {
+		if strings.HasPrefix(key, "Access-Control-") {
+			resp.Header.Del(key)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	info := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{CORS: &model.McpCORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true, MaxAge: 600,
+	}}}
+
+	req := newInstanceReq(http.MethodOptions, "", info)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	if !isPreflight(req) || !applyCORS(w, req) {
+		t.Fatal("preflight was not answered by the gateway")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST, DELETE, OPTIONS",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// 不允许的来源和方法在网关拒绝
+	req = newInstanceReq(http.MethodOptions, "", info)
+	req.Header.Set("Origin", "https://example.org")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	if w = httptest.NewRecorder(); !applyCORS(w, req) || w.Code != http.StatusForbidden {
+		t.Errorf("preflight of disallowed origin status = %d, want 403", w.Code)
+	}
+	req = newInstanceReq(http.MethodOptions, "", info)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PATCH")
+	if w = httptest.NewRecorder(); !applyCORS(w, req) || w.Code != http.StatusForbidden {
+		t.Errorf("preflight of disallowed method status = %d, want 403", w.Code)
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	SetCORS(common.GatewayCORSConfig{AllowedOrigins: []string{"https://console.example.com"}})
+	defer SetCORS(common.GatewayCORSConfig{})
+
+	// 实例未设置策略时使用网关默认策略
+	req := newInstanceReq(http.MethodPost, "", nil)
+	req.Header.Set("Origin", "https://console.example.com")
+	w := httptest.NewRecorder()
+	if applyCORS(w, req) {
+		t.Fatal("applyCORS() answered an actual request")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://console.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "Mcp-Session-Id, X-Request-ID" {
+		t.Errorf("Access-Control-Expose-Headers = %q, want the default exposed headers", got)
+	}
+
+	// 实例策略替换默认策略
+	req = newInstanceReq(http.MethodPost, "", &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{
+		CORS: &model.McpCORSConfig{AllowedOrigins: []string{"https://agent.example.com"}},
+	}})
+	req.Header.Set("Origin", "https://console.example.com")
+	w = httptest.NewRecorder()
+	applyCORS(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none for an origin outside the instance policy", got)
+	}
+
+	// 上游的跨域响应头被网关策略替换
+	req = newInstanceReq(http.MethodPost, "", nil)
+	req.Header.Set("Origin", "https://console.example.com")
+	resp := &http.Response{Header: http.Header{"Access-Control-Allow-Origin": {"*"}, "Content-Type": {"application/json"}}, Request: req}
+	stripUpstreamCORS(resp)
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" || resp.Header.Get("Content-Type") == "" {
+		t.Errorf("upstream headers = %v, want only the CORS headers removed", resp.Header)
+	}
+}
+
+func TestCORSAnyOriginWithoutCredentials(t *testing.T) {
+	logger.Init("error", "json")
+	info := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{CORS: &model.McpCORSConfig{
+		AllowedOrigins: []string{"*"}, AllowCredentials: true,
+	}}}
+
+	req := newInstanceReq(http.MethodPost, "", info)
+	req.Header.Set("Origin", "https://evil.example.org")
+	w := httptest.NewRecorder()
+	applyCORS(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://evil.example.org" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none for the \"*\" origin", got)
+	}
+}
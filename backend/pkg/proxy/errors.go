@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+// Errors returned by GetInstanceInfo, mapped to HTTP statuses by instanceError
+var (
+	errInstanceNotFound = errors.New("instance not found")
+	errInstanceDisabled = errors.New("instance is not active")
+	errServerNotFound   = errors.New("mcp server not found")
+)
+
+// JSON-RPC error codes of gateway errors, -32000 to -32099 is reserved for implementation-defined server errors
+const (
+	rpcCodeParseError          = -32700
+	rpcCodeInvalidRequest      = -32600
+	rpcCodeInternalError       = -32603
+	rpcCodeUnauthorized        = -32001
+	rpcCodeForbidden           = -32002
+	rpcCodeNotFound            = -32003
+	rpcCodeRequestTimeout      = -32004
+	rpcCodeRequestTooLarge     = -32005
+	rpcCodeUpstreamFailure     = -32010
+	rpcCodeServiceUnavailable  = -32011
+	rpcCodeUpstreamTimeout     = -32012
+	rpcCodeMethodNotAllowed    = -32013
+	rpcCodePolicyDenied        = -32014
+	rpcCodeScopeDenied         = -32015
+	rpcCodeSessionExpired      = -32016
+	rpcCodeGatewayUnknownError = -32099
+)
+
+// rpcCodes JSON-RPC error code of each HTTP status returned by the gateway
+var rpcCodes = map[int]int{
+	http.StatusBadRequest:            rpcCodeInvalidRequest,
+	http.StatusUnauthorized:          rpcCodeUnauthorized,
+	http.StatusForbidden:             rpcCodeForbidden,
+	http.StatusNotFound:              rpcCodeNotFound,
+	http.StatusMethodNotAllowed:      rpcCodeMethodNotAllowed,
+	http.StatusRequestTimeout:        rpcCodeRequestTimeout,
+	http.StatusRequestEntityTooLarge: rpcCodeRequestTooLarge,
+	http.StatusInternalServerError:   rpcCodeInternalError,
+	http.StatusBadGateway:            rpcCodeUpstreamFailure,
+	http.StatusServiceUnavailable:    rpcCodeServiceUnavailable,
+	http.StatusGatewayTimeout:        rpcCodeUpstreamTimeout,
+}
+
+// gatewayError JSON-RPC style error response of the gateway
+type gatewayError struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Error   gatewayErrorBody `json:"error"`
+}
+
+// gatewayErrorBody JSON-RPC error object
+type gatewayErrorBody struct {
+	Code    int              `json:"code"`
+	Message string           `json:"message"`
+	Data    gatewayErrorData `json:"data"`
+}
+
+// gatewayErrorData details of a gateway error
+type gatewayErrorData struct {
+	Status     int    `json:"status"`
+	InstanceID string `json:"instanceId,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
+	// RetryAfter seconds until the request may be retried
+	RetryAfter int `json:"retryAfter,omitempty"`
+	// SessionID streamable HTTP session the upstream no longer knows
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// newGatewayError builds the error response of a failed request
+func newGatewayError(req *http.Request, status int, message string) *gatewayError {
+	code, ok := rpcCodes[status]
+	if !ok {
+		code = rpcCodeGatewayUnknownError
+	}
+	data := gatewayErrorData{
+		Status:    status,
+		RequestID: logger.RequestIDFromContext(req.Context()),
+	}
+	if instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo); ok {
+		data.InstanceID = instanceInfo.InstanceID
+	}
+	return &gatewayError{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("null"),
+		Error:   gatewayErrorBody{Code: code, Message: message, Data: data},
+	}
+}
+
+// writeGatewayError writes a JSON error response, headers must not have been sent yet
+func writeGatewayError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	writeGatewayErrorBody(w, newGatewayError(req, status, message))
+}
+
+// writeGatewayErrorBody writes a prepared JSON error response
+func writeGatewayErrorBody(w http.ResponseWriter, body *gatewayError) {
+	data, _ := json.Marshal(body)
+	if body.Error.Data.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(body.Error.Data.RetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(body.Error.Data.Status)
+	w.Write(data)
+}
+
+// sseErrorFrame formats a gateway error as an SSE error event, used when the stream
+// fails after the response headers were sent
+func sseErrorFrame(req *http.Request, status int, message string) []byte {
+	data, _ := json.Marshal(newGatewayError(req, status, message))
+	return []byte(fmt.Sprintf("event: error\ndata: %s\n\n", data))
+}
+
+// instanceError maps a GetInstanceInfo failure to the gateway error status
+func instanceError(err error) *proxyError {
+	switch {
+	case errors.Is(err, errInstanceNotFound), errors.Is(err, errServerNotFound):
+		return &proxyError{message: err.Error(), status: http.StatusNotFound}
+	case errors.Is(err, errInstanceDisabled):
+		return &proxyError{message: err.Error(), status: http.StatusForbidden}
+	case errors.Is(err, errDatabaseUnavailable):
+		return &proxyError{message: err.Error(), status: http.StatusServiceUnavailable}
+	default:
+		return &proxyError{message: fmt.Sprintf("failed to get MCP configuration: %v", err), status: http.StatusBadGateway}
+	}
+}
+
+// upstreamErrorStatus HTTP status of a failed upstream round trip, timeouts map to 504
+func upstreamErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// checkInstanceToken verifies the bearer token of a request against the instance tokens,
+// instances without tokens are open. Matched tokens are removed so they do not reach the upstream,
+// the scope of restricted tokens is stored in the request context.
+func checkInstanceToken(req *http.Request, instance *model.McpInstance) *proxyError {
+	if instance == nil || len(instance.Tokens) == 0 {
+		return nil
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return &proxyError{message: "missing bearer token", status: http.StatusUnauthorized}
+	}
+	now := time.Now().UnixMilli()
+	for _, t := range instance.Tokens {
+		if t.Token != token {
+			continue
+		}
+		if t.ExpireAt > 0 && t.ExpireAt < now {
+			return &proxyError{message: "token expired", status: http.StatusUnauthorized}
+		}
+		req.Header.Del("Authorization")
+		if scope := t.Scope(); scope != model.TokenScopeFull {
+			*req = *req.WithContext(context.WithValue(req.Context(), TokenScopeKey, scope))
+		}
+		return nil
+	}
+	return &proxyError{message: "invalid token", status: http.StatusUnauthorized}
+}
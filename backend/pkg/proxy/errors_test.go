@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+// decodeGatewayError decodes a gateway error response and checks the common fields
+func decodeGatewayError(t *testing.T, body []byte) *gatewayError {
+	t.Helper()
+	var resp gatewayError
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("invalid JSON error body %q: %v", body, err)
+	}
+	if resp.JSONRPC != "2.0" || resp.Error.Data.InstanceID != "abc" || resp.Error.Data.RequestID != "req-1" {
+		t.Errorf("error body = %s, want jsonrpc 2.0 with instanceId and requestId", body)
+	}
+	return &resp
+}
+
+func TestErrorHandler(t *testing.T) {
+	logger.Init("error", "json")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   int
+	}{
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, http.StatusBadGateway, rpcCodeUpstreamFailure},
+		{"upstream timeout", fmt.Errorf("read: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, rpcCodeUpstreamTimeout},
+		{"upstream eof", io.ErrUnexpectedEOF, http.StatusBadGateway, rpcCodeUpstreamFailure},
+		{"body too large", &http.MaxBytesError{Limit: 8}, http.StatusRequestEntityTooLarge, rpcCodeRequestTooLarge},
+		{"proxy error", &proxyError{message: "boom", status: http.StatusInternalServerError}, http.StatusInternalServerError, rpcCodeInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			errorHandler(w, newInstanceReq(http.MethodPost, "", nil), tt.err)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if resp := decodeGatewayError(t, w.Body.Bytes()); resp.Error.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", resp.Error.Code, tt.wantCode)
+			}
+		})
+	}
+
+	// 客户端断开时不写响应
+	w := httptest.NewRecorder()
+	errorHandler(w, newInstanceReq(http.MethodPost, "", nil), context.Canceled)
+	if w.Body.Len() != 0 {
+		t.Errorf("canceled request got body %q, want none", w.Body.String())
+	}
+}
+
+func TestInstanceError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unknown instance", fmt.Errorf("%w: abc", errInstanceNotFound), http.StatusNotFound},
+		{"unknown server", fmt.Errorf("%w: \"fetch\"", errServerNotFound), http.StatusNotFound},
+		{"disabled instance", fmt.Errorf("%w: abc", errInstanceDisabled), http.StatusForbidden},
+		{"broken config", errors.New("invalid character"), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instanceError(tt.err); got.status != tt.want {
+				t.Errorf("instanceError(%v) status = %d, want %d", tt.err, got.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckInstanceToken(t *testing.T) {
+	now := time.Now().UnixMilli()
+	instance := &model.McpInstance{Tokens: []model.McpToken{
+		{Token: "valid"},
+		{Token: "expired", ExpireAt: now - 1000},
+		{Token: "future", ExpireAt: now + 60000},
+	}}
+
+	tests := []struct {
+		name          string
+		instance      *model.McpInstance
+		authorization string
+		wantStatus    int
+	}{
+		{"open instance", &model.McpInstance{}, "", 0},
+		{"valid token", instance, "Bearer valid", 0},
+		{"unexpired token", instance, "Bearer future", 0},
+		{"missing token", instance, "", http.StatusUnauthorized},
+		{"wrong token", instance, "Bearer nope", http.StatusUnauthorized},
+		{"expired token", instance, "Bearer expired", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp/abc", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			status := 0
+			if pe := checkInstanceToken(req, tt.instance); pe != nil {
+				status = pe.status
+			}
+			if status != tt.wantStatus {
+				t.Errorf("checkInstanceToken() status = %d, want %d", status, tt.wantStatus)
+			}
+			if status == 0 && len(tt.instance.Tokens) > 0 && req.Header.Get("Authorization") != "" {
+				t.Error("gateway token forwarded to the upstream")
+			}
+		})
+	}
+}
+
+// failingReader returns data then fails
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSSEUpstreamFailureEmitsErrorEvent(t *testing.T) {
+	logger.Init("error", "json")
+
+	reader := &SSEResponseBodyReader{
+		src:  &failingReader{data: "event: message\ndata: {}\n\n", err: syscall.ECONNRESET},
+		info: &InstanceInfo{InstanceID: "abc"},
+		req:  newInstanceReq(http.MethodPost, "", nil),
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := strings.Split(strings.TrimSpace(string(got)), "\n\n")
+	if len(frames) != 2 || frames[0] != "event: message\ndata: {}" {
+		t.Fatalf("stream = %q, want the message followed by an error event", got)
+	}
+	data, ok := strings.CutPrefix(frames[1], "event: error\ndata: ")
+	if !ok {
+		t.Fatalf("last frame = %q, want an error event", frames[1])
+	}
+	if resp := decodeGatewayError(t, []byte(data)); resp.Error.Data.Status != http.StatusBadGateway {
+		t.Errorf("error event status = %d, want 502", resp.Error.Data.Status)
+	}
+
+	// 客户端断开时不发送错误事件
+	ctx, cancel := context.WithCancel(newInstanceReq(http.MethodPost, "", nil).Context())
+	cancel()
+	reader = &SSEResponseBodyReader{
+		src:  &failingReader{err: context.Canceled},
+		info: &InstanceInfo{InstanceID: "abc"},
+		req:  newInstanceReq(http.MethodPost, "", nil).WithContext(ctx),
+	}
+	if got, _ := io.ReadAll(reader); len(got) != 0 {
+		t.Errorf("canceled stream = %q, want empty", got)
+	}
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+)
+
+// defaultSSEHeartbeatInterval idle time before a keepalive comment is sent to SSE clients
+const defaultSSEHeartbeatInterval = 30 * time.Second
+
+// sseHeartbeatComment SSE comment line ignored by clients, keeps idle connections from being cut by load balancers
+var sseHeartbeatComment = []byte(": keepalive\n\n")
+
+// sseHeartbeatSettings gateway-wide heartbeat settings, instances may override both
+type sseHeartbeatSettings struct {
+	enabled  bool
+	interval time.Duration
+}
+
+var (
+	heartbeatMu     sync.RWMutex
+	activeHeartbeat = sseHeartbeatSettings{interval: defaultSSEHeartbeatInterval}
+)
+
+// SetSSEHeartbeat configures keepalive comments on idle SSE connections
+func SetSSEHeartbeat(cfg common.SSEHeartbeatConfig) {
+	settings := sseHeartbeatSettings{
+		enabled:  cfg.Enabled,
+		interval: time.Duration(cfg.Interval) * time.Second,
+	}
+	if settings.interval <= 0 {
+		settings.interval = defaultSSEHeartbeatInterval
+	}
+
+	heartbeatMu.Lock()
+	activeHeartbeat = settings
+	heartbeatMu.Unlock()
+}
+
+// heartbeatIntervalFor returns the heartbeat interval of an instance, 0 when heartbeats are disabled
+func heartbeatIntervalFor(instanceInfo *InstanceInfo) time.Duration {
+	heartbeatMu.RLock()
+	settings := activeHeartbeat
+	heartbeatMu.RUnlock()
+
+	if instanceInfo != nil && instanceInfo.McpConfig != nil {
+		if instanceInfo.McpConfig.SseHeartbeat != nil {
+			settings.enabled = *instanceInfo.McpConfig.SseHeartbeat
+		}
+		if instanceInfo.McpConfig.SseHeartbeatInterval > 0 {
+			settings.interval = time.Duration(instanceInfo.McpConfig.SseHeartbeatInterval) * time.Second
+		}
+	}
+	if !settings.enabled {
+		return 0
+	}
+	return settings.interval
+}
+
+// sseMessage an upstream SSE message read in the background
+type sseMessage struct {
+	data []byte
+	err  error
+}
+
+// next returns the next upstream message. With heartbeats or draining enabled the upstream
+// is read in the background: a keepalive comment is returned whenever it stays silent for the
+// interval and a drain ends the stream with a final event. Both only go to the client and
+// always fall between whole messages.
+func (r *SSEResponseBodyReader) next() ([]byte, error) {
+	if r.heartbeat <= 0 && r.drain == nil {
+		return r.readMessage()
+	}
+	if r.messages == nil {
+		r.messages = make(chan sseMessage)
+		go r.readMessages()
+	}
+
+	var heartbeat <-chan time.Time
+	if r.heartbeat > 0 {
+		timer := time.NewTimer(r.heartbeat)
+		defer timer.Stop()
+		heartbeat = timer.C
+	}
+	select {
+	case msg := <-r.messages:
+		return msg.data, msg.err
+	case <-heartbeat:
+		return sseHeartbeatComment, nil
+	case <-r.drain:
+		return r.drainFrame(), io.EOF
+	}
+}
+
+// drainFrame final event sent to a client whose connection is drained
+func (r *SSEResponseBodyReader) drainFrame() []byte {
+	if r.req == nil {
+		return nil
+	}
+	return sseErrorFrame(r.req, http.StatusServiceUnavailable,
+		fmt.Sprintf("instance %s is draining connections, reconnect later", r.info.InstanceID))
+}
+
+// readMessages forwards upstream messages to next until the stream ends or the client goes away
+func (r *SSEResponseBodyReader) readMessages() {
+	var clientGone <-chan struct{}
+	if r.req != nil {
+		clientGone = r.req.Context().Done()
+	}
+	for {
+		data, err := r.readMessage()
+		select {
+		case r.messages <- sseMessage{data: data, err: err}:
+		case <-clientGone:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
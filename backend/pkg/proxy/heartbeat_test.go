@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestHeartbeatIntervalFor(t *testing.T) {
+	SetSSEHeartbeat(common.SSEHeartbeatConfig{Enabled: true, Interval: 15})
+	defer SetSSEHeartbeat(common.SSEHeartbeatConfig{})
+
+	enabled, disabled := true, false
+	tests := []struct {
+		name   string
+		config *model.McpConfig
+		want   time.Duration
+	}{
+		{"gateway default", &model.McpConfig{}, 15 * time.Second},
+		{"instance interval", &model.McpConfig{SseHeartbeatInterval: 5}, 5 * time.Second},
+		{"instance disabled", &model.McpConfig{SseHeartbeat: &disabled}, 0},
+		{"instance enabled", &model.McpConfig{SseHeartbeat: &enabled}, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heartbeatIntervalFor(&InstanceInfo{McpConfig: tt.config}); got != tt.want {
+				t.Errorf("heartbeatIntervalFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	SetSSEHeartbeat(common.SSEHeartbeatConfig{})
+	if got := heartbeatIntervalFor(&InstanceInfo{McpConfig: &model.McpConfig{SseHeartbeat: &enabled}}); got != defaultSSEHeartbeatInterval {
+		t.Errorf("instance enabled on disabled gateway = %v, want %v", got, defaultSSEHeartbeatInterval)
+	}
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	upstream, upstreamWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/mcp/abc/sse", nil).WithContext(ctx)
+
+	reader := bufio.NewReader(&SSEResponseBodyReader{
+		src:       upstream,
+		info:      &InstanceInfo{InstanceID: "abc"},
+		req:       req,
+		heartbeat: 10 * time.Millisecond,
+	})
+	readFrame := func() string {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			frame.WriteString(line)
+			if err != nil || line == "\n" {
+				return frame.String()
+			}
+		}
+	}
+
+	// 上游空闲时只向客户端写心跳注释
+	if got := readFrame(); got != string(sseHeartbeatComment) {
+		t.Fatalf("idle frame = %q, want keepalive comment", got)
+	}
+
+	// 上游消息在心跳之间完整转发
+	go upstreamWriter.Write([]byte("event: message\ndata: {}\n\n"))
+	for {
+		got := readFrame()
+		if got == string(sseHeartbeatComment) {
+			continue
+		}
+		if got != "event: message\ndata: {}\n\n" {
+			t.Fatalf("frame = %q, want the upstream message", got)
+		}
+		break
+	}
+	upstreamWriter.Close()
+}
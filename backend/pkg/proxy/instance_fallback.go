@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// InstanceStaleHeader set to "true" when the instance config was served from the gateway cache
+	// because the database was unavailable
+	InstanceStaleHeader = "X-MCP-Instance-Stale"
+
+	// defaultInstanceStaleTTL how long a cached instance may be served after a failed lookup
+	defaultInstanceStaleTTL = 10 * time.Minute
+	// defaultLookupFailureThreshold consecutive database errors that open the lookup breaker
+	defaultLookupFailureThreshold = 3
+	// defaultLookupCooldown time lookups skip the database once the breaker opens
+	defaultLookupCooldown = 10 * time.Second
+	// defaultLookupTimeout time a single instance lookup may take
+	defaultLookupTimeout = 3 * time.Second
+)
+
+// errDatabaseUnavailable the instance lookup failed and no cached instance could be served
+var errDatabaseUnavailable = errors.New("instance database unavailable")
+
+// instanceLookupStats lookup counters, exposed through expvar at /debug/vars
+var instanceLookupStats = expvar.NewMap("gateway_instance_lookup")
+
+// resolvedInstance instance loaded from the database with the settings resolved alongside it,
+// so that requests served from the cache route the same way as the ones that reached the database
+type resolvedInstance struct {
+	*model.McpInstance
+	// defaultHostingImage the hosting instance runs the default hosting image, configured globally
+	// or overridden by its environment
+	defaultHostingImage bool
+}
+
+// cachedInstance last instance loaded from the database
+type cachedInstance struct {
+	instance *resolvedInstance
+	loadedAt time.Time
+}
+
+// instanceLookup loads instances from the database, keeps the last loaded copy of each one and
+// serves it while the database is unavailable. A breaker skips the database after repeated errors
+// so that an outage does not add the lookup timeout to every request.
+type instanceLookup struct {
+	staleTTL  time.Duration
+	threshold int
+	cooldown  time.Duration
+	timeout   time.Duration
+	find      func(ctx context.Context, instanceID string) (*model.McpInstance, error)
+	// hostingImage returns the hosting image an environment overrides the default with
+	hostingImage func(ctx context.Context, environmentID uint) (string, error)
+	now          func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*cachedInstance
+	failures int
+	openedAt time.Time
+	// probing a lookup is testing whether the database recovered
+	probing bool
+}
+
+var (
+	instanceLookupMu     sync.RWMutex
+	activeInstanceLookup = newInstanceLookup(common.InstanceFallbackConfig{}, findInstance)
+)
+
+func newInstanceLookup(cfg common.InstanceFallbackConfig, find func(ctx context.Context, instanceID string) (*model.McpInstance, error)) *instanceLookup {
+	l := &instanceLookup{
+		staleTTL:     time.Duration(cfg.StaleTTL) * time.Second,
+		threshold:    cfg.FailureThreshold,
+		cooldown:     time.Duration(cfg.Cooldown) * time.Second,
+		timeout:      time.Duration(cfg.LookupTimeout) * time.Second,
+		find:         find,
+		hostingImage: environmentHostingImage,
+		now:          time.Now,
+		entries:      make(map[string]*cachedInstance),
+	}
+	if cfg.StaleTTL == 0 {
+		l.staleTTL = defaultInstanceStaleTTL
+	}
+	if l.threshold <= 0 {
+		l.threshold = defaultLookupFailureThreshold
+	}
+	if l.cooldown <= 0 {
+		l.cooldown = defaultLookupCooldown
+	}
+	if l.timeout <= 0 {
+		l.timeout = defaultLookupTimeout
+	}
+	return l
+}
+
+// SetInstanceFallback configures the stale instance fallback, cached instances are dropped
+func SetInstanceFallback(cfg common.InstanceFallbackConfig) {
+	lookup := newInstanceLookup(cfg, findInstance)
+	instanceLookupMu.Lock()
+	activeInstanceLookup = lookup
+	instanceLookupMu.Unlock()
+}
+
+func getInstanceLookup() *instanceLookup {
+	instanceLookupMu.RLock()
+	defer instanceLookupMu.RUnlock()
+	return activeInstanceLookup
+}
+
+// get returns the instance, stale is true when it was served from the cache after a failed lookup
+func (l *instanceLookup) get(instanceID string) (instance *resolvedInstance, stale bool, err error) {
+	if l.allow() {
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		instance, err = l.load(ctx, instanceID)
+		cancel()
+		switch {
+		case err == nil:
+			l.recordSuccess()
+			l.store(instanceID, instance)
+			return instance, false, nil
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// 数据库可用，实例已删除
+			l.recordSuccess()
+			l.forget(instanceID)
+			return nil, false, err
+		}
+		l.recordFailure(err)
+	} else {
+		err = fmt.Errorf("lookup breaker open")
+	}
+
+	cached := l.cached(instanceID)
+	if cached == nil {
+		instanceLookupStats.Add("unavailable", 1)
+		return nil, false, fmt.Errorf("%w: %v", errDatabaseUnavailable, err)
+	}
+	instanceLookupStats.Add("stale", 1)
+	logger.Warn("Instance lookup failed, serving cached instance",
+		zap.String("instance_id", instanceID),
+		zap.Duration("age", l.now().Sub(cached.loadedAt)),
+		zap.Error(err))
+	return cached.instance.copy(), true, nil
+}
+
+// load queries the instance and the hosting image of its environment within the lookup timeout
+func (l *instanceLookup) load(ctx context.Context, instanceID string) (*resolvedInstance, error) {
+	instance, err := l.find(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &resolvedInstance{McpInstance: instance}
+	if instance.AccessType != model.AccessTypeHosting {
+		return resolved, nil
+	}
+	if common.IsDefaultHostingImage(instance.ImgAddr) {
+		resolved.defaultHostingImage = true
+		return resolved, nil
+	}
+	hostingImage, err := l.hostingImage(ctx, instance.EnvironmentID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 环境已删除时按未覆盖默认镜像处理，不能当作实例不存在
+	case err != nil:
+		return nil, err
+	case hostingImage != "":
+		resolved.defaultHostingImage = common.IsDefaultHostingImage(instance.ImgAddr, hostingImage)
+	}
+	return resolved, nil
+}
+
+// environmentHostingImage returns the hosting image configured on an environment
+func environmentHostingImage(ctx context.Context, environmentID uint) (string, error) {
+	environment, err := mysql.McpEnvironmentRepo.FindByID(ctx, environmentID)
+	if err != nil {
+		return "", err
+	}
+	return environment.HostingImage, nil
+}
+
+// copy returns a copy whose instance may be modified without touching the cached one
+func (r *resolvedInstance) copy() *resolvedInstance {
+	instance := *r.McpInstance
+	return &resolvedInstance{McpInstance: &instance, defaultHostingImage: r.defaultHostingImage}
+}
+
+// allow reports whether the database may be queried, an open breaker lets a single probe through
+// after the cooldown
+func (l *instanceLookup) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failures < l.threshold {
+		return true
+	}
+	if l.probing || l.now().Sub(l.openedAt) < l.cooldown {
+		return false
+	}
+	l.probing = true
+	return true
+}
+
+func (l *instanceLookup) recordSuccess() {
+	l.mu.Lock()
+	opened := l.failures >= l.threshold
+	l.failures, l.probing = 0, false
+	l.mu.Unlock()
+	if opened {
+		logger.Info("Instance database recovered, lookup breaker closed")
+	}
+}
+
+func (l *instanceLookup) recordFailure(err error) {
+	instanceLookupStats.Add("errors", 1)
+	l.mu.Lock()
+	l.failures++
+	l.probing = false
+	opened := l.failures == l.threshold
+	if l.failures >= l.threshold {
+		l.openedAt = l.now()
+	}
+	l.mu.Unlock()
+	if opened {
+		instanceLookupStats.Add("breaker_opened", 1)
+		logger.Warn("Instance database unavailable, lookup breaker opened",
+			zap.Int("failures", l.threshold),
+			zap.Duration("cooldown", l.cooldown),
+			zap.Error(err))
+	}
+}
+
+func (l *instanceLookup) store(instanceID string, instance *resolvedInstance) {
+	if l.staleTTL < 0 {
+		return
+	}
+	copied := instance.copy()
+	l.mu.Lock()
+	l.entries[instanceID] = &cachedInstance{instance: copied, loadedAt: l.now()}
+	l.mu.Unlock()
+}
+
+func (l *instanceLookup) forget(instanceID string) {
+	l.mu.Lock()
+	delete(l.entries, instanceID)
+	l.mu.Unlock()
+}
+
+// cached returns the cached instance when it is not older than the stale TTL
+func (l *instanceLookup) cached(instanceID string) *cachedInstance {
+	if l.staleTTL < 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cached, ok := l.entries[instanceID]
+	if !ok {
+		return nil
+	}
+	if l.now().Sub(cached.loadedAt) > l.staleTTL {
+		delete(l.entries, instanceID)
+		return nil
+	}
+	return cached
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+func TestInstanceLookupFallback(t *testing.T) {
+	logger.Init("error", "json")
+
+	calls := 0
+	var dbErr error
+	lookup := newInstanceLookup(common.InstanceFallbackConfig{StaleTTL: 60, FailureThreshold: 2, Cooldown: 10},
+		func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+			calls++
+			if dbErr != nil {
+				return nil, dbErr
+			}
+			if instanceID == "gone" {
+				return nil, fmt.Errorf("instance not found: %s: %w", instanceID, gorm.ErrRecordNotFound)
+			}
+			return &model.McpInstance{InstanceID: instanceID, Status: model.InstanceStatusActive}, nil
+		})
+	now := time.Unix(1000, 0)
+	lookup.now = func() time.Time { return now }
+
+	if _, stale, err := lookup.get("abc"); err != nil || stale {
+		t.Fatalf("get() with database up = stale %v, err %v", stale, err)
+	}
+
+	dbErr = errors.New("connection refused")
+	instance, stale, err := lookup.get("abc")
+	if err != nil || !stale || instance.InstanceID != "abc" {
+		t.Fatalf("get() with database down = %v, stale %v, err %v, want cached instance", instance, stale, err)
+	}
+	if _, _, err := lookup.get("new"); !errors.Is(err, errDatabaseUnavailable) {
+		t.Errorf("get() of uncached instance with database down err = %v, want errDatabaseUnavailable", err)
+	}
+
+	// 达到阈值后熔断，冷却期内不再查询数据库
+	callsBefore := calls
+	if _, stale, err := lookup.get("abc"); err != nil || !stale {
+		t.Errorf("get() with breaker open = stale %v, err %v, want cached instance", stale, err)
+	}
+	if calls != callsBefore {
+		t.Errorf("database queried %d times while the breaker is open", calls-callsBefore)
+	}
+
+	// 冷却结束后探测成功，熔断关闭
+	now = now.Add(11 * time.Second)
+	dbErr = nil
+	if _, stale, err := lookup.get("abc"); err != nil || stale {
+		t.Errorf("get() after recovery = stale %v, err %v", stale, err)
+	}
+	if calls != callsBefore+1 {
+		t.Errorf("probe queried the database %d times, want 1", calls-callsBefore)
+	}
+
+	// 缓存超过 staleTTL 后不再使用
+	dbErr = errors.New("connection refused")
+	now = now.Add(61 * time.Second)
+	if _, _, err := lookup.get("abc"); !errors.Is(err, errDatabaseUnavailable) {
+		t.Errorf("get() of expired cache err = %v, want errDatabaseUnavailable", err)
+	}
+
+	// 实例已删除时不使用缓存
+	dbErr = nil
+	lookup.store("gone", &resolvedInstance{McpInstance: &model.McpInstance{InstanceID: "gone"}})
+	if _, _, err := lookup.get("gone"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("get() of deleted instance err = %v, want ErrRecordNotFound", err)
+	}
+	if lookup.cached("gone") != nil {
+		t.Errorf("deleted instance is still cached")
+	}
+}
+
+func TestInstanceLookupHostingImage(t *testing.T) {
+	logger.Init("error", "json")
+
+	var dbErr error
+	lookup := newInstanceLookup(common.InstanceFallbackConfig{StaleTTL: 60},
+		func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+			if dbErr != nil {
+				return nil, dbErr
+			}
+			return &model.McpInstance{InstanceID: instanceID, AccessType: model.AccessTypeHosting,
+				ImgAddr: "harbor.local:5000/mcp/mcp-hosting:v1", EnvironmentID: 3}, nil
+		})
+	queries := 0
+	lookup.hostingImage = func(ctx context.Context, environmentID uint) (string, error) {
+		queries++
+		return "harbor.local:5000/mcp/mcp-hosting", nil
+	}
+
+	instance, _, err := lookup.get("abc")
+	if err != nil || !instance.defaultHostingImage || queries != 1 {
+		t.Fatalf("get() = %+v, err %v, environment queries %d, want default hosting image resolved once", instance, err, queries)
+	}
+
+	// 数据库不可用时使用缓存中解析好的结果，不再查询环境
+	dbErr = errors.New("connection refused")
+	instance, stale, err := lookup.get("abc")
+	if err != nil || !stale || !instance.defaultHostingImage || queries != 1 {
+		t.Errorf("get() with database down = %+v, stale %v, err %v, environment queries %d, want cached default hosting image",
+			instance, stale, err, queries)
+	}
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxRequestBodySize requests larger than this are rejected with 413
+	defaultMaxRequestBodySize = 10 << 20
+	// defaultMaxResponseBufferSize non-streaming responses larger than this are never held in memory
+	defaultMaxResponseBufferSize = 1 << 20
+	// defaultStreamingThreshold responses larger than this are streamed without buffering
+	defaultStreamingThreshold = 256 << 10
+)
+
+// proxyLimits request and response size limits in bytes, the default upstream timeout
+// and the duration after which a request is logged as slow
+type proxyLimits struct {
+	maxRequestBodySize    int64
+	maxResponseBufferSize int64
+	streamingThreshold    int64
+	readTimeout           time.Duration
+	slowRequestThreshold  time.Duration
+}
+
+var (
+	limitsMu     sync.RWMutex
+	activeLimits = proxyLimits{
+		maxRequestBodySize:    defaultMaxRequestBodySize,
+		maxResponseBufferSize: defaultMaxResponseBufferSize,
+		streamingThreshold:    defaultStreamingThreshold,
+		readTimeout:           DefaultReadTimeout,
+	}
+)
+
+// SetProxyLimits configures the gateway size limits and upstream timeout, unset values keep their defaults.
+// Safe to call while serving, the config reload applies changes with it.
+func SetProxyLimits(cfg common.ProxyLimitsConfig) {
+	limits := proxyLimits{
+		maxRequestBodySize:    cfg.MaxRequestBodySize,
+		maxResponseBufferSize: cfg.MaxResponseBufferSize,
+		streamingThreshold:    cfg.StreamingThreshold,
+		readTimeout:           time.Duration(cfg.ReadTimeout) * time.Second,
+		slowRequestThreshold:  time.Duration(cfg.SlowRequestThreshold) * time.Millisecond,
+	}
+	if limits.maxRequestBodySize <= 0 {
+		limits.maxRequestBodySize = defaultMaxRequestBodySize
+	}
+	if limits.maxResponseBufferSize <= 0 {
+		limits.maxResponseBufferSize = defaultMaxResponseBufferSize
+	}
+	if limits.streamingThreshold <= 0 {
+		limits.streamingThreshold = defaultStreamingThreshold
+	}
+	if limits.readTimeout <= 0 {
+		limits.readTimeout = DefaultReadTimeout
+	}
+
+	limitsMu.Lock()
+	activeLimits = limits
+	limitsMu.Unlock()
+}
+
+// limitsFor returns the gateway limits with the overrides of the instance's server config applied
+func limitsFor(instanceInfo *InstanceInfo) proxyLimits {
+	limitsMu.RLock()
+	limits := activeLimits
+	limitsMu.RUnlock()
+
+	if instanceInfo == nil || instanceInfo.McpConfig == nil {
+		return limits
+	}
+	if instanceInfo.McpConfig.MaxRequestBodySize > 0 {
+		limits.maxRequestBodySize = instanceInfo.McpConfig.MaxRequestBodySize
+	}
+	if instanceInfo.McpConfig.MaxResponseBufferSize > 0 {
+		limits.maxResponseBufferSize = instanceInfo.McpConfig.MaxResponseBufferSize
+	}
+	return limits
+}
+
+// limitRequestBody rejects requests whose declared body exceeds the instance limit and caps
+// the body of the others, returns true when the request was rejected. Chunked bodies that
+// exceed the limit while proxying are answered with 413 by errorHandler.
+func limitRequestBody(w http.ResponseWriter, req *http.Request) bool {
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok || req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	limits := limitsFor(instanceInfo)
+	if req.ContentLength > limits.maxRequestBodySize {
+		writeRequestTooLarge(w, req, limits.maxRequestBodySize)
+		return true
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, limits.maxRequestBodySize)
+	return false
+}
+
+// writeRequestTooLarge answers an oversized request with 413
+func writeRequestTooLarge(w http.ResponseWriter, req *http.Request, limit int64) {
+	instanceID := ""
+	if instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo); ok {
+		instanceID = instanceInfo.InstanceID
+	}
+	logger.FromContext(req.Context()).Warn("Request body exceeds limit",
+		zap.String("instance_id", instanceID),
+		zap.Int64("content_length", req.ContentLength),
+		zap.Int64("limit", limit),
+	)
+
+	w.Header().Set("Connection", "close")
+	writeGatewayError(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds the limit of %d bytes", limit))
+}
+
+// isStreamingResponse reports whether a response is passed through without buffering:
+// SSE streams, responses of unknown length and responses above the streaming threshold
+func isStreamingResponse(resp *http.Response, limits proxyLimits) bool {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return true
+	}
+	return resp.ContentLength < 0 || resp.ContentLength > limits.streamingThreshold
+}
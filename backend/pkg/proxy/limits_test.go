@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	logger.Init("error", "json")
+	SetProxyLimits(common.ProxyLimitsConfig{MaxRequestBodySize: 8})
+	defer SetProxyLimits(common.ProxyLimitsConfig{})
+
+	tests := []struct {
+		name   string
+		body   string
+		config *model.McpConfig
+		reject bool
+	}{
+		{"within limit", "12345678", &model.McpConfig{}, false},
+		{"exceeds limit", "123456789", &model.McpConfig{}, true},
+		{"instance override", "123456789", &model.McpConfig{MaxRequestBodySize: 16}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := newInstanceReq(http.MethodPost, tt.body, &InstanceInfo{InstanceID: "abc", McpConfig: tt.config})
+			if got := limitRequestBody(w, req); got != tt.reject {
+				t.Fatalf("limitRequestBody() = %v, want %v", got, tt.reject)
+			}
+			if tt.reject && w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want 413", w.Code)
+			}
+		})
+	}
+}
+
+func TestLimitRequestBodyChunked(t *testing.T) {
+	logger.Init("error", "json")
+	SetProxyLimits(common.ProxyLimitsConfig{MaxRequestBodySize: 8})
+	defer SetProxyLimits(common.ProxyLimitsConfig{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	rp := &httputil.ReverseProxy{
+		Director:     func(req *http.Request) { req.URL.Scheme, req.URL.Host = target.Scheme, target.Host },
+		ErrorHandler: errorHandler,
+	}
+
+	// 未声明长度的请求体在转发过程中超限
+	w := httptest.NewRecorder()
+	req := newInstanceReq(http.MethodPost, strings.Repeat("x", 64), &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{}})
+	req.ContentLength = -1
+	if limitRequestBody(w, req) {
+		t.Fatal("chunked request rejected before reading")
+	}
+	rp.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
+
+func TestIsStreamingResponse(t *testing.T) {
+	limits := proxyLimits{streamingThreshold: 100}
+	tests := []struct {
+		name          string
+		contentType   string
+		contentLength int64
+		want          bool
+	}{
+		{"small json", "application/json", 10, false},
+		{"large json", "application/json", 101, true},
+		{"unknown length", "application/json", -1, true},
+		{"sse", "text/event-stream", 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{"Content-Type": []string{tt.contentType}}, ContentLength: tt.contentLength}
+			if got := isStreamingResponse(resp, limits); got != tt.want {
+				t.Errorf("isStreamingResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,7 +18,6 @@ import (
 
 	"qm-mcp-server/pkg/common"
 	"qm-mcp-server/pkg/database/model"
-	"qm-mcp-server/pkg/database/repository/mysql"
 	"qm-mcp-server/pkg/logger"
 
 	"go.uber.org/zap"
@@ -26,7 +25,7 @@ import (
 )
 
 const (
-	// DefaultReadTimeout default read timeout
+	// DefaultReadTimeout default read timeout, proxyLimits.readTimeout overrides it
 	DefaultReadTimeout = 30 * time.Second
 )
 
@@ -41,11 +40,9 @@ func NewMCPReverseProxy() *McpReverseProxy {
 		Director:       director,
 		ErrorHandler:   errorHandler,
 		ModifyResponse: modifyResponse,
-		Transport: &http.Transport{
-			Proxy: http.ProxyURL(nil),
-		},
-		BufferPool: newWrapPool(),
-		ErrorLog:   log.New(&proxyLogger{}, "", 0),
+		Transport:      &breakerTransport{},
+		BufferPool:     newWrapPool(),
+		ErrorLog:       log.New(&proxyLogger{}, "", 0),
 	}
 
 	return &McpReverseProxy{
@@ -76,25 +73,78 @@ func (mrp *McpReverseProxy) ServeHTTP(respWriter http.ResponseWriter, req *http.
 				zap.String("remote_addr", req.RemoteAddr),
 			)
 			// panic(r) // Re-throw panic that is not ErrAbortHandler
-			respWriter.WriteHeader(http.StatusInternalServerError)
-			respWriter.Write([]byte(fmt.Sprintf("Internal Server Error: %v", r)))
+			writeGatewayError(respWriter, req, http.StatusInternalServerError, fmt.Sprintf("internal server error: %v", r))
 		}
 	}()
 
-	err := mrp.reqHandler(req)
-	if err != nil {
-		respWriter.WriteHeader(http.StatusMethodNotAllowed)
-		respWriter.Write([]byte(err.Error()))
+	pe := mrp.reqHandler(req)
+	// CORS headers are set on every response including gateway errors, preflights are answered here
+	if applyCORS(respWriter, req) {
+		return
+	}
+	// Former slugs of an instance redirect to its current path during the redirect period
+	if pe != nil && pe.location != "" {
+		http.Redirect(respWriter, req, pe.location, pe.status)
+		return
+	}
+	if pe != nil {
+		logger.FromContext(req.Context()).Warn("Rejected proxy request",
+			zap.String("path", req.URL.Path),
+			zap.Int("status", pe.status),
+			zap.String("error", pe.message),
+		)
+		writeGatewayError(respWriter, req, pe.status, pe.message)
 		return
 	}
 
+	// Instances served from the cache during a database outage are marked for the client
+	if instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo); ok && instanceInfo.Stale {
+		respWriter.Header().Set(InstanceStaleHeader, "true")
+	}
+
+	// The timeout covers the whole round trip including the streamed response body
+	req, cancel := withRequestTimeout(req)
+	defer cancel()
+
+	// Oversized request bodies are rejected before they are read
+	if limitRequestBody(respWriter, req) {
+		return
+	}
+	// Restricted tokens may only call the methods of their scope
+	if enforceTokenScope(respWriter, req) {
+		return
+	}
+	// Methods and tools denied by the instance policy never reach the upstream
+	if enforcePolicy(respWriter, req) {
+		return
+	}
+	// Idempotent MCP methods may be answered from the response cache
+	if serveFromCache(respWriter, req) {
+		return
+	}
+	// Instances whose upstream keeps failing are rejected until the breaker cooldown ends
+	if rejectOpenCircuit(respWriter, req) {
+		return
+	}
+	// SSE connections are counted per instance and capped to protect gateway file descriptors
+	if isSSEReq, _ := req.Context().Value(IsSSEReqKey).(bool); isSSEReq {
+		release, ok := trackSSEConnection(respWriter, req)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	// Requests slower than the configured threshold are logged for tuning instance timeouts
+	defer trackSlowRequest(req)()
 	mrp.proxy.ServeHTTP(respWriter, req)
 }
 
-func (mrp *McpReverseProxy) reqHandler(req *http.Request) error {
+// reqHandler resolves the target instance of a request, failures carry the HTTP status returned to the client
+func (mrp *McpReverseProxy) reqHandler(req *http.Request) *proxyError {
 	pathStr := req.URL.Path
 	if pathStr == "" {
-		return fmt.Errorf("method Not Allowed: Path is empty")
+		return &proxyError{message: "path is empty", status: http.StatusBadRequest}
 	}
 	isSSEReq := false
 	if strings.HasSuffix(pathStr, MCP_SERVER_SUBFIX_SSE) {
@@ -104,54 +154,67 @@ func (mrp *McpReverseProxy) reqHandler(req *http.Request) error {
 	prefix := common.GetGatewayRoutePrefix()
 	prefix = strings.Trim(prefix, "/")
 	if !strings.HasPrefix(pathStr, fmt.Sprintf("/%s", prefix)) {
-		return fmt.Errorf("method Not Allowed: Path Prefix is not match")
+		return &proxyError{message: "path prefix does not match", status: http.StatusNotFound}
 	}
 	parts := strings.Split(pathStr, "/")
-	instanceId := parts[2]
+	instanceId := ""
+	if len(parts) > 2 {
+		instanceId = parts[2]
+	}
 	// Validate if instanceId is valid
 	if len(instanceId) == 0 {
-		return fmt.Errorf("method Not Allowed: InstanceId is empty")
+		return &proxyError{message: "instanceId is empty", status: http.StatusNotFound}
+	}
+	// Multi-server instances are addressed as /{prefix}/{instanceId}/{serverName}/...
+	serverName := ""
+	if len(parts) > 3 {
+		serverName = parts[3]
 	}
 
-	// mcp config validation
-	instanceInfo, err := GetInstanceInfo(instanceId)
-	if err != nil {
-		return fmt.Errorf("failed to get MCP configuration: %v", err.Error())
+	// mcp config validation, instanceId may also be the slug of the instance
+	instanceInfo, err := GetInstanceInfo(instanceId, serverName)
+	var moved *slugMovedError
+	if errors.As(err, &moved) {
+		return &proxyError{message: err.Error(), status: http.StatusPermanentRedirect, location: movedLocation(req, parts, moved.pathKey)}
 	}
-	if instanceInfo.McpConfig.Headers != nil {
-		for key, value := range instanceInfo.McpConfig.Headers {
-			req.Header.Set(key, value)
-		}
+	if err != nil {
+		return instanceError(err)
 	}
-
-	// Store instanceId in context
+	// Store instanceId in context, gateway errors and the CORS policy refer to it
 	ctx := context.WithValue(req.Context(), InstanceInfoKey, instanceInfo)
 	ctx = context.WithValue(ctx, IsSSEReqKey, isSSEReq)
+	// Instance headers may overwrite X-Forwarded-*, record the public address the client used first
+	ctx = context.WithValue(ctx, PublicAccessKey, requestPublicAccess(req))
 	*req = *req.WithContext(ctx)
 
-	if isSSEReq {
-		if instanceInfo.McpConfig.SseReadTimeout > 0 {
-			ctx2, _ := context.WithTimeout(req.Context(), time.Duration(instanceInfo.McpConfig.SseReadTimeout)*time.Second)
-			*req = *req.WithContext(ctx2)
-		} else {
-			*req = *req.WithContext(req.Context())
-		}
-	} else {
-		timeout := instanceInfo.McpConfig.Timeout
-		if timeout > 0 {
-			ctx2, _ := context.WithTimeout(req.Context(), time.Duration(timeout)*time.Second)
-			*req = *req.WithContext(ctx2)
-		} else {
-			ctx2, _ := context.WithTimeout(req.Context(), DefaultReadTimeout)
-			*req = *req.WithContext(ctx2)
+	// CORS preflights carry no credentials, they are answered by the gateway and never proxied
+	if isPreflight(req) && corsPolicyFor(req) != nil {
+		return nil
+	}
+	if pe := checkInstanceToken(req, instanceInfo.Instance); pe != nil {
+		return pe
+	}
+	// Reject target URLs without a resolvable host and port before proxying
+	if _, err := common.ParseEndpoint(instanceInfo.McpConfig.URL); err != nil {
+		return &proxyError{message: fmt.Sprintf("invalid MCP target URL: %v", err), status: http.StatusBadGateway}
+	}
+	if instanceInfo.McpConfig.Headers != nil {
+		for key, value := range instanceInfo.McpConfig.Headers {
+			req.Header.Set(key, value)
 		}
 	}
+
 	return nil
 }
 
 // director handles request modification before sending to target server
 func director(req *http.Request) {
-	logger.Info("Before director",
+	reqLogger := logger.FromContext(req.Context())
+	// 透传请求 ID，便于关联网关与 MCP 服务的日志
+	if requestID := logger.RequestIDFromContext(req.Context()); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
+	reqLogger.Info("Before director",
 		zap.String("method", req.Method),
 		zap.String("host", req.Host),
 		zap.String("url", req.URL.String()),
@@ -159,44 +222,70 @@ func director(req *http.Request) {
 
 	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
 	if !ok {
-		logger.Error("No InstanceInfo found in context")
+		reqLogger.Error("No InstanceInfo found in context")
 		return
 	}
+	// Upstream responses of redacted instances must not use encodings the gateway cannot decode,
+	// the transport then negotiates gzip itself and decompresses transparently
+	if instanceInfo.McpConfig.Redaction != nil {
+		req.Header.Del("Accept-Encoding")
+	}
 	isSSEReq, ok2 := req.Context().Value(IsSSEReqKey).(bool)
 	if !ok2 {
-		logger.Error("No IsSSEReqKey found in context")
+		reqLogger.Error("No IsSSEReqKey found in context")
 		return
 	}
 
 	parts := strings.Split(req.URL.Path, "/")
 	pathNum := len(parts)
 	if pathNum <= 2 {
-		logger.Error("Path is too short")
+		reqLogger.Error("Path is too short")
 		return
 	}
 
-	prefix := getProxyPrefix(instanceInfo.InstanceID)
+	prefix := getProxyPrefix(instanceInfo.pathKey(), instanceInfo.ServerName)
 
-	targetUrl, err := url.Parse(instanceInfo.McpConfig.URL)
+	// Canary routes may send the request to another version of the upstream
+	targetUrl, err := url.Parse(selectRoute(req, instanceInfo, isSSEReq, prefix))
 	if err != nil {
-		logger.Error("Failed to parse URL", zap.Error(err))
+		reqLogger.Error("Failed to parse URL", zap.Error(err))
 		return
 	}
+	rewriteTarget(req, instanceInfo, isSSEReq, prefix, targetUrl)
+
+	// Log request info
+	reqLogger.Info("After director",
+		zap.String("instance_id", instanceInfo.InstanceID),
+		zap.Bool("is_ssereq", isSSEReq),
+		zap.String("url", req.URL.String()),
+	)
 
+}
+
+// rewriteTarget points the request at the target URL according to the access type and protocol of the instance
+func rewriteTarget(req *http.Request, instanceInfo *InstanceInfo, isSSEReq bool, prefix string, targetUrl *url.URL) {
 	switch instanceInfo.AccessType {
 	case model.AccessTypeHosting:
 		switch instanceInfo.McpProtocol {
 		case model.McpProtocolSSE:
 			if isSSEReq {
 				handleHostingSSEReq(req, instanceInfo, targetUrl)
+				// Multi-replica instances: pin the SSE connection to one pod, canary routes have their own targets
+				if routeIndexOf(req) == primaryRoute {
+					routeSSEReqToPod(req, instanceInfo)
+				}
 			} else {
+				// Event POSTs must land on the pod holding the SSE session
+				podAddr := podAddrFromEventReq(req, instanceInfo, prefix)
 				handleHostingSSEReqForEvent(req, instanceInfo, prefix, targetUrl)
+				if podAddr != "" && routeIndexOf(req) == primaryRoute {
+					req.URL.Host = podAddr
+				}
 			}
 		case model.McpProtocolStreamableHttp:
 			handleHostingStreamableHTTPReq(req, instanceInfo, targetUrl)
 		default:
-			logger.Error("McpProtocol is not supported")
-			return
+			logger.FromContext(req.Context()).Error("McpProtocol is not supported")
 		}
 	case model.AccessTypeProxy:
 		switch instanceInfo.McpProtocol {
@@ -209,24 +298,35 @@ func director(req *http.Request) {
 		case model.McpProtocolStreamableHttp:
 			handleProxyStreamableHTTPPathReq(req, instanceInfo, targetUrl)
 		default:
-			logger.Error("McpProtocol is not supported")
-			return
+			logger.FromContext(req.Context()).Error("McpProtocol is not supported")
 		}
 	default:
-		logger.Error("AccessType is not supported")
-		return
+		logger.FromContext(req.Context()).Error("AccessType is not supported")
 	}
-	// Log request info
-	logger.Info("After director",
-		zap.String("instance_id", instanceInfo.InstanceID),
-		zap.Bool("is_ssereq", isSSEReq),
-		zap.String("url", req.URL.String()),
-	)
-
 }
 
 // Handle response modification before sending to client
 func modifyResponse(resp *http.Response) error {
+	stripUpstreamCORS(resp)
+	markRoute(resp)
+	requestInstance, _ := resp.Request.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	// Sessions are tracked first, a 404 of an expired session is replaced by a gateway error
+	trackMcpSession(resp, requestInstance)
+	// Secrets are redacted before the response is cached or measured for streaming
+	if err := redactResponse(resp, requestInstance); err != nil {
+		return err
+	}
+	// Streaming responses are never buffered, the reverse proxy flushes every write of a
+	// response of unknown length
+	limits := limitsFor(requestInstance)
+	streaming := isStreamingResponse(resp, limits)
+	if call, ok := resp.Request.Context().Value(ResponseCacheCallKey).(*cachedCall); ok && !streaming {
+		storeCachedResponse(resp, call, limits.maxResponseBufferSize)
+	}
+	if streaming {
+		resp.ContentLength = -1
+	}
+
 	// Check if it is SSE response
 	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
 		// Get instanceId from context
@@ -277,11 +377,29 @@ func modifyResponse(resp *http.Response) error {
 
 		host := resp.Request.Host
 
+		// Pod pinned by session affinity, embedded into the rewritten endpoint path
+		podToken := ""
+		if podAddr, ok := resp.Request.Context().Value(PodAddrKey).(string); ok && podAddr != "" {
+			podToken = encodePodToken(podAddr)
+		}
+
+		// Draining the instance ends the stream
+		var drain chan struct{}
+		if conn, ok := resp.Request.Context().Value(SSEConnKey).(*sseConn); ok {
+			drain = conn.drain
+		}
+
 		// Replace response body with our custom Reader
 		resp.Body = io.NopCloser(&SSEResponseBodyReader{
-			host: host,
-			src:  reader,
-			info: instanceInfo,
+			host:      host,
+			src:       reader,
+			info:      instanceInfo,
+			podToken:  podToken,
+			req:       resp.Request,
+			heartbeat: heartbeatIntervalFor(instanceInfo),
+			drain:     drain,
+			redactor:  redactorFor(instanceInfo),
+			route:     routeIndexOf(resp.Request),
 		})
 
 		// Ensure response header allows chunked transfer
@@ -297,6 +415,22 @@ type SSEResponseBodyReader struct {
 	buffer bytes.Buffer  // Used for buffering data and processing
 	reader *bufio.Reader // Convenient for reading by line or delimiter
 	info   *InstanceInfo
+	// podToken identifies the pod serving this SSE session, empty when routed through the Service VIP
+	podToken string
+	// req the client request, used to report upstream failures as SSE error events
+	req *http.Request
+	// done the upstream stream has ended
+	done bool
+	// heartbeat idle interval after which a keepalive comment is sent to the client, 0 disables it
+	heartbeat time.Duration
+	// messages upstream messages read in the background when heartbeats or draining are enabled
+	messages chan sseMessage
+	// drain closed when the instance's connections are drained, the stream then ends with a final event
+	drain chan struct{}
+	// redactor redacts the data lines of upstream messages, nil when the instance has no redaction config
+	redactor *redactor
+	// route canary route serving this SSE session, recorded in the endpoint path so that event POSTs follow it
+	route int
 }
 
 func (r *SSEResponseBodyReader) Read(p []byte) (n int, err error) {
@@ -311,114 +445,141 @@ func (r *SSEResponseBodyReader) Read(p []byte) (n int, err error) {
 		if r.buffer.Len() > 0 {
 			return r.buffer.Read(p)
 		}
-
-		// Buffer is empty, read next SSE message from source
-		// SSE messages are separated by `\n\n`
-		msgBytes, readErr := r.reader.ReadBytes('\n')
-		if readErr != nil && readErr != io.EOF {
-			// Check if it is connection-related error
-			if isConnectionError(readErr) {
-				// Connection interruption is normal, return EOF
-				logger.Debug("SSE connection interrupted", zap.Error(readErr))
-				return 0, io.EOF
-			}
-			// Other read errors
-			return 0, readErr
+		if r.done {
+			return 0, io.EOF
 		}
 
-		// Continue reading until message boundary `\n\n` is encountered
-		for {
-			line, err := r.reader.ReadBytes('\n')
-			msgBytes = append(msgBytes, line...)
-			if err != nil {
-				// Check if it is connection-related error
-				if isConnectionError(err) {
-					logger.Debug("SSE connection interrupted during message read", zap.Error(err))
-					readErr = io.EOF
-				} else {
-					readErr = err
+		// Buffer is empty, read next SSE message from source
+		msgBytes, readErr := r.next()
+		// Write modified data into internal buffer
+		r.buffer.Write(msgBytes)
+
+		if readErr != nil {
+			// The stream ends here, upstream failures are reported to the client as an error event
+			r.done = true
+			if readErr != io.EOF {
+				if frame := r.errorFrame(readErr); frame != nil {
+					r.buffer.Write(frame)
 				}
-				break
-			}
-			if len(bytes.TrimSpace(line)) == 0 { // Message ends
-				break
 			}
 		}
+	}
+}
 
-		if len(msgBytes) > 0 {
-			msgStr := string(msgBytes)
-			// Handle SSE messages of type event: endpoint
-			if strings.Contains(msgStr, "event: endpoint") || strings.Contains(msgStr, "event:endpoint") {
-				// Add prefix proxy rule
-				// If contains data: / , replace with data: /{prefix}/
-				// If contains data:/ , replace with data: /{prefix}/
-				prefix := getProxyPrefix(r.info.InstanceID)
-				if strings.Contains(msgStr, "data: /") {
-					msgBytes = bytes.ReplaceAll(msgBytes, []byte("data: /"), []byte(fmt.Sprintf("data: /%s/", strings.Trim(prefix, "/"))))
-				} else if strings.Contains(msgStr, "data:/") {
-					msgBytes = bytes.ReplaceAll(msgBytes, []byte("data:/"), []byte(fmt.Sprintf("data:/%s/", strings.Trim(prefix, "/"))))
-				}
-				logger.Info("Replace SSE event:endpoint", zap.String("old", msgStr), zap.String("new", string(msgBytes)))
-			}
-
-			// Write modified data into internal buffer
-			r.buffer.Write(msgBytes)
+// readMessage reads the next SSE message from the upstream and rewrites endpoint events
+func (r *SSEResponseBodyReader) readMessage() ([]byte, error) {
+	// SSE messages are separated by `\n\n`
+	msgBytes, readErr := r.reader.ReadBytes('\n')
+
+	// Continue reading until message boundary `\n\n` is encountered
+	for readErr == nil && len(bytes.TrimSpace(msgBytes)) > 0 {
+		line, err := r.reader.ReadBytes('\n')
+		msgBytes = append(msgBytes, line...)
+		if err != nil {
+			readErr = err
+			break
 		}
-
-		// If source is exhausted (EOF) and our buffer is also empty, return EOF
-		if readErr == io.EOF && r.buffer.Len() == 0 {
-			return 0, io.EOF
+		if len(bytes.TrimSpace(line)) == 0 { // Message ends
+			break
 		}
+	}
 
-		// If other error occurs, return directly
-		if readErr != nil && readErr != io.EOF {
-			return 0, readErr
+	if len(msgBytes) > 0 {
+		msgStr := string(msgBytes)
+		// Handle SSE messages of type event: endpoint
+		if strings.Contains(msgStr, "event: endpoint") || strings.Contains(msgStr, "event:endpoint") {
+			// Add prefix proxy rule: data: /messages becomes data: /{prefix}/messages,
+			// absolute upstream URLs are replaced by the public address of the gateway
+			prefix := getPublicProxyPrefix(r.req, r.info.pathKey(), r.info.ServerName)
+			if r.route != primaryRoute {
+				prefix = routePrefix(prefix, r.route)
+			}
+			if r.podToken != "" {
+				prefix = affinityPrefix(prefix, r.podToken)
+			}
+			msgBytes = rewriteEndpointData(msgBytes, prefix, publicBaseURL(r.req))
+			logger.Info("Replace SSE event:endpoint",
+				zap.String("old", msgStr),
+				zap.String("new", string(msgBytes)),
+				zap.String("session_id", extractSessionID(msgStr)),
+				zap.String("pod_token", r.podToken),
+			)
+		} else {
+			msgBytes = r.redactSSE(msgBytes)
 		}
 	}
+	return msgBytes, readErr
 }
 
-// isConnectionError checks if error is related to connection interruption
-func isConnectionError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errorStr := err.Error()
-	return strings.Contains(errorStr, "connection reset") ||
-		strings.Contains(errorStr, "broken pipe") ||
-		strings.Contains(errorStr, "connection refused") ||
-		strings.Contains(errorStr, "context canceled") ||
-		strings.Contains(errorStr, "context deadline exceeded") ||
-		strings.Contains(errorStr, "use of closed network connection")
+// errorFrame returns the SSE error event for an upstream read failure, nil when the client
+// went away and nobody is left to receive it
+func (r *SSEResponseBodyReader) errorFrame(readErr error) []byte {
+	if r.req == nil || errors.Is(readErr, context.Canceled) || errors.Is(r.req.Context().Err(), context.Canceled) {
+		logger.Debug("SSE connection interrupted", zap.Error(readErr))
+		return nil
+	}
+	logger.FromContext(r.req.Context()).Warn("SSE upstream stream failed",
+		zap.String("instance_id", r.info.InstanceID),
+		zap.Error(readErr),
+	)
+	return sseErrorFrame(r.req, upstreamErrorStatus(readErr), fmt.Sprintf("upstream stream failed: %v", readErr))
 }
 
 type InstanceInfo struct {
-	InstanceID  string
+	InstanceID string
+	// PathKey the instance ID or slug the client used in the request path
+	PathKey     string
 	AccessType  model.AccessType
 	McpProtocol model.McpProtocol
 	Instance    *model.McpInstance
 	McpConfig   *model.McpConfig
+	// ServerName the server addressed by the request path, empty for single-server instances
+	ServerName string
+	// DefaultHostingImage the hosting instance runs the default hosting image, whose endpoints need a trailing slash
+	DefaultHostingImage bool
+	// Stale the instance was served from the gateway cache because the database was unavailable
+	Stale bool
+	// Transport reaches the upstream with the TLS and proxy config of the target server
+	Transport http.RoundTripper
+}
+
+// pathKey the instance segment of proxied paths, endpoints advertised to the client keep the form it connected with
+func (info *InstanceInfo) pathKey() string {
+	if info.PathKey != "" {
+		return info.PathKey
+	}
+	return info.InstanceID
 }
 
-func GetInstanceInfo(instanceID string) (*InstanceInfo, error) {
-	instance, err := mysql.McpInstanceRepo.FindByInstanceID(context.Background(), instanceID)
+// GetInstanceInfo loads the proxy target of an instance addressed by its ID or slug, serverName selects
+// the server of multi-server instances and is ignored for single-server instances. A former slug within
+// its redirect period returns a *slugMovedError
+func GetInstanceInfo(pathKey, serverName string) (*InstanceInfo, error) {
+	resolved, stale, err := getInstanceLookup().get(pathKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", errInstanceNotFound, pathKey)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("instance not found: %s", instanceID)
+	instance := resolved.McpInstance
+	if pathKey != instance.InstanceID && pathKey != instance.GetSlug() {
+		return nil, &slugMovedError{slug: pathKey, pathKey: instancePathKey(instance)}
 	}
+	instanceID := instance.InstanceID
 
 	// Ensure instance is active
 	if instance.Status != model.InstanceStatusActive {
-		return nil, fmt.Errorf("instance is not active: %s", instanceID)
+		return nil, fmt.Errorf("%w: %s", errInstanceDisabled, instanceID)
 	}
 
-	_, _, targetConfig, err := instance.GetTargetConfig()
+	_, servers, _, err := instance.GetTargetConfig()
 	if err != nil {
 		return nil, err
 	}
-	if targetConfig == nil {
-		return nil, fmt.Errorf("target config not found: %s", instanceID)
+	serverName, targetConfig, err := selectTargetConfig(servers, serverName)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, instanceID)
 	}
 
 	// Stdio forwarding is not supported
@@ -463,24 +624,134 @@ func GetInstanceInfo(instanceID string) (*InstanceInfo, error) {
 		}
 	}
 
+	transport, err := upstreamTransport(targetConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, instanceID)
+	}
+
 	instanceInfo := &InstanceInfo{
-		InstanceID:  instanceID,
-		AccessType:  instance.AccessType,
-		McpProtocol: model.McpProtocol(targetConfig.Transport),
-		Instance:    instance,
-		McpConfig:   targetConfig,
+		InstanceID:          instanceID,
+		PathKey:             pathKey,
+		AccessType:          instance.AccessType,
+		McpProtocol:         model.McpProtocol(targetConfig.Transport),
+		Instance:            instance,
+		McpConfig:           targetConfig,
+		ServerName:          serverName,
+		DefaultHostingImage: resolved.defaultHostingImage,
+		Stale:               stale,
+		Transport:           transport,
 	}
 
 	return instanceInfo, nil
 }
 
-// Get proxy prefix
-func getProxyPrefix(instanceID string) string {
+// selectTargetConfig picks the target server, single-server instances ignore serverName and
+// return an empty name so that their paths keep the /{prefix}/{instanceId}/... layout
+func selectTargetConfig(servers *model.McpServersConfig, serverName string) (string, *model.McpConfig, error) {
+	if servers == nil || len(servers.McpServers) == 0 {
+		return "", nil, fmt.Errorf("target config not found")
+	}
+	if len(servers.McpServers) == 1 {
+		for _, targetConfig := range servers.McpServers {
+			if targetConfig == nil {
+				return "", nil, fmt.Errorf("target config not found")
+			}
+			return "", targetConfig, nil
+		}
+	}
+	if serverName == "" {
+		return "", nil, fmt.Errorf("%w: server name is required for multi-server instance", errServerNotFound)
+	}
+	targetConfig, ok := servers.McpServers[serverName]
+	if !ok || targetConfig == nil {
+		return "", nil, fmt.Errorf("%w: %q", errServerNotFound, serverName)
+	}
+	return serverName, targetConfig, nil
+}
+
+// Get proxy prefix, pathKey is the instance ID or slug and serverName is appended for multi-server instances
+func getProxyPrefix(pathKey, serverName string) string {
 	prefix := common.GetGatewayRoutePrefix()
-	prefix = path.Join(prefix, instanceID)
+	prefix = path.Join(prefix, pathKey, serverName)
 	return prefix
 }
 
+// publicAccess public address of the gateway as seen by the client, resolved from the
+// configuration and the X-Forwarded-* headers of trusted reverse proxies
+type publicAccess struct {
+	// baseURL scheme://host the client connected to, e.g. https://mcp.example.com
+	baseURL string
+	// prefix gateway path prefix advertised to clients
+	prefix string
+}
+
+// requestPublicAccess resolves the public address of the gateway from the client request
+func requestPublicAccess(req *http.Request) *publicAccess {
+	return &publicAccess{
+		baseURL: common.GetPublicBaseURL(common.RequestOrigin(req)),
+		prefix:  common.RequestPublicPathPrefix(req),
+	}
+}
+
+// publicAccessOf returns the public address recorded for the request, nil when it was not recorded
+func publicAccessOf(req *http.Request) *publicAccess {
+	if req == nil {
+		return nil
+	}
+	access, _ := req.Context().Value(PublicAccessKey).(*publicAccess)
+	return access
+}
+
+// publicBaseURL returns scheme://host the client used to reach the gateway, empty when unknown
+func publicBaseURL(req *http.Request) string {
+	if access := publicAccessOf(req); access != nil {
+		return access.baseURL
+	}
+	return ""
+}
+
+// Get proxy prefix advertised to clients, differs from the route prefix when an external
+// reverse proxy rewrites the gateway path or forwards X-Forwarded-Prefix
+func getPublicProxyPrefix(req *http.Request, pathKey, serverName string) string {
+	prefix := common.GetPublicPathPrefix()
+	if access := publicAccessOf(req); access != nil {
+		prefix = access.prefix
+	}
+	return path.Join(prefix, pathKey, serverName)
+}
+
+// rewriteEndpointData points the data lines of an endpoint event at the gateway. Relative paths get the
+// public prefix, absolute URLs of the upstream are replaced by the public base URL plus the prefix so that
+// clients behind TLS terminating load balancers never see internal http:// addresses
+func rewriteEndpointData(msg []byte, prefix, baseURL string) []byte {
+	prefix = "/" + strings.Trim(prefix, "/")
+	lines := bytes.SplitAfter(msg, []byte("\n"))
+	for i, line := range lines {
+		rest, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		value := strings.TrimRight(string(rest), "\r\n")
+		eol := string(rest[len(value):])
+		field := "data:"
+		if strings.HasPrefix(value, " ") {
+			field = "data: "
+		}
+		value = strings.TrimSpace(value)
+
+		var endpoint string
+		if strings.HasPrefix(value, "/") {
+			endpoint = prefix + value
+		} else if u, err := url.Parse(value); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			endpoint = baseURL + prefix + u.RequestURI()
+		} else {
+			continue
+		}
+		lines[i] = []byte(field + endpoint + eol)
+	}
+	return bytes.Join(lines, nil)
+}
+
 // Hosting mode, SSE long connection request handling
 func handleHostingSSEReq(req *http.Request, instanceInfo *InstanceInfo, targetUrl *url.URL) string {
 	req.URL.Scheme = targetUrl.Scheme
@@ -506,7 +777,7 @@ func handleHostingSSEReqForEvent(req *http.Request, instanceInfo *InstanceInfo,
 	if strings.HasPrefix(req.URL.Path, path.Join(prefix)) {
 		req.URL.Path = strings.Replace(req.URL.Path, path.Join(prefix), "", 1)
 	}
-	if strings.Contains(instanceInfo.Instance.ImgAddr, common.DefatuleHostingImg) {
+	if instanceInfo.DefaultHostingImage {
 		req.URL.Path = strings.TrimRight(req.URL.Path, "/") + "/"
 	}
 	return req.URL.Path
@@ -527,7 +798,7 @@ func handleHostingStreamableHTTPReq(req *http.Request, instanceInfo *InstanceInf
 			req.Header.Set(key, value)
 		}
 	}
-	if strings.Contains(instanceInfo.Instance.ImgAddr, common.DefatuleHostingImg) {
+	if instanceInfo.DefaultHostingImage {
 		req.URL.Path = strings.TrimRight(req.URL.Path, "/") + "/"
 	}
 	return req.URL.Path
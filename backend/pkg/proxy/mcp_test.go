@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+// newInstanceReq builds a gateway request to instance abc with the instance info and request ID req-1 in its context,
+// a nil info stands for an instance without configuration
+func newInstanceReq(method, body string, info *InstanceInfo) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, "/mcp/abc", reader)
+	if info == nil {
+		info = &InstanceInfo{InstanceID: "abc"}
+	}
+	ctx := logger.WithRequestID(req.Context(), "req-1")
+	return req.WithContext(context.WithValue(ctx, InstanceInfoKey, info))
+}
+
+func TestHostingReqTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name                string
+		defaultHostingImage bool
+		streamable          bool
+		reqPath             string
+		target              string
+		wantPath            string
+	}{
+		{"sse event default image", true, false, "/mcp/abc/message", "http://svc:8080/sse", "/message/"},
+		{"sse event default image keeps single slash", true, false, "/mcp/abc/message/", "http://svc:8080/sse", "/message/"},
+		{"sse event custom image", false, false, "/mcp/abc/message", "http://svc:8080/sse", "/message"},
+		{"streamable default image", true, true, "/mcp/abc", "http://svc:8080/mcp", "/mcp/"},
+		{"streamable custom image", false, true, "/mcp/abc", "http://svc:8080/mcp", "/mcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetUrl, err := url.Parse(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			instanceInfo := &InstanceInfo{
+				InstanceID:          "abc",
+				AccessType:          model.AccessTypeHosting,
+				Instance:            &model.McpInstance{InstanceID: "abc"},
+				McpConfig:           &model.McpConfig{},
+				DefaultHostingImage: tt.defaultHostingImage,
+			}
+			req := httptest.NewRequest("GET", tt.reqPath, nil)
+
+			var got string
+			if tt.streamable {
+				got = handleHostingStreamableHTTPReq(req, instanceInfo, targetUrl)
+			} else {
+				got = handleHostingSSEReqForEvent(req, instanceInfo, "/mcp/abc", targetUrl)
+			}
+			if got != tt.wantPath {
+				t.Errorf("path = %q, want %q", got, tt.wantPath)
+			}
+			if req.URL.Host != targetUrl.Host {
+				t.Errorf("host = %q, want %q", req.URL.Host, targetUrl.Host)
+			}
+		})
+	}
+}
+
+func TestDefaultHostingImageNonHosting(t *testing.T) {
+	lookup := newInstanceLookup(common.InstanceFallbackConfig{}, func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+		return &model.McpInstance{InstanceID: instanceID, AccessType: model.AccessTypeProxy, ImgAddr: common.DefaultHostingImage}, nil
+	})
+	instance, err := lookup.load(context.Background(), "abc")
+	if err != nil || instance.defaultHostingImage {
+		t.Errorf("load() for proxy instance = %+v, err %v, want no default hosting image", instance, err)
+	}
+}
+
+func TestSelectTargetConfig(t *testing.T) {
+	single := &model.McpServersConfig{McpServers: map[string]*model.McpConfig{
+		"github": {URL: "https://github.example.com/sse"},
+	}}
+	multi := &model.McpServersConfig{McpServers: map[string]*model.McpConfig{
+		"github": {URL: "https://github.example.com/sse"},
+		"fetch":  {URL: "https://fetch.example.com/mcp", Headers: map[string]string{"Authorization": "Bearer x"}},
+	}}
+	tests := []struct {
+		name       string
+		servers    *model.McpServersConfig
+		serverName string
+		wantName   string
+		wantURL    string
+		wantErr    bool
+	}{
+		{"single server ignores path segment", single, "sse", "", "https://github.example.com/sse", false},
+		{"single server without segment", single, "", "", "https://github.example.com/sse", false},
+		{"multi server by name", multi, "fetch", "fetch", "https://fetch.example.com/mcp", false},
+		{"multi server unknown name", multi, "sse", "", "", true},
+		{"multi server without name", multi, "", "", "", true},
+		{"no servers", &model.McpServersConfig{}, "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, targetConfig, err := selectTargetConfig(tt.servers, tt.serverName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectTargetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if name != tt.wantName || targetConfig.URL != tt.wantURL {
+				t.Errorf("selectTargetConfig() = %q, %q, want %q, %q", name, targetConfig.URL, tt.wantName, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestProxySSEEventKeepsServerName(t *testing.T) {
+	targetUrl, _ := url.Parse("https://fetch.example.com/sse")
+	prefix := getProxyPrefix("abc", "fetch")
+	req := httptest.NewRequest("POST", prefix+"/messages/?session_id=1", nil)
+
+	if got := handleProxySSEReqForEvent(req, prefix, targetUrl); got != "/messages/" {
+		t.Errorf("path = %q, want %q", got, "/messages/")
+	}
+	if req.URL.Host != targetUrl.Host {
+		t.Errorf("host = %q, want %q", req.URL.Host, targetUrl.Host)
+	}
+}
+
+func TestSSEEndpointRewriteKeepsServerName(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	reader := &SSEResponseBodyReader{
+		src:  strings.NewReader("event: endpoint\ndata: /messages/?session_id=1\n\n"),
+		info: &InstanceInfo{InstanceID: "abc", ServerName: "fetch"},
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "data: " + getPublicProxyPrefix(nil, "abc", "fetch") + "/messages/?session_id=1"
+	if !strings.Contains(string(got), want) {
+		t.Errorf("rewritten event = %q, want it to contain %q", got, want)
+	}
+	if !strings.HasSuffix(getPublicProxyPrefix(nil, "abc", "fetch"), "/abc/fetch") {
+		t.Errorf("public prefix = %q, want suffix /abc/fetch", getPublicProxyPrefix(nil, "abc", "fetch"))
+	}
+}
+
+func TestSSEEndpointRewriteBehindLoadBalancer(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	gatewayPrefix := common.GetGatewayRoutePrefix()
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		data       string
+		want       string
+	}{
+		{
+			name:       "https termination",
+			remoteAddr: "127.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "mcp.example.com"},
+			data:       "http://10.1.2.3:8080/messages?sessionId=1",
+			want:       "data: https://mcp.example.com" + gatewayPrefix + "/abc/messages?sessionId=1",
+		},
+		{
+			name:       "path prefix ingress",
+			remoteAddr: "127.0.0.1:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Prefix": "/tenant-a/"},
+			data:       "/messages/?session_id=1",
+			want:       "data: /tenant-a" + gatewayPrefix + "/abc/messages/?session_id=1",
+		},
+		{
+			name:       "direct access ignores spoofed headers",
+			remoteAddr: "203.0.113.9:52000",
+			headers:    map[string]string{"X-Forwarded-Host": "evil.example.com", "X-Forwarded-Prefix": "/phish"},
+			data:       "http://10.1.2.3:8080/messages?sessionId=1",
+			want:       "data: http://gateway.local:8085" + gatewayPrefix + "/abc/messages?sessionId=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://gateway.local:8085"+gatewayPrefix+"/abc/sse", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), PublicAccessKey, requestPublicAccess(req)))
+
+			reader := &SSEResponseBodyReader{
+				src:  strings.NewReader("event: endpoint\ndata: " + tt.data + "\n\n"),
+				info: &InstanceInfo{InstanceID: "abc"},
+				req:  req,
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "event: endpoint\n" + tt.want + "\n\n"; string(got) != want {
+				t.Errorf("rewritten event = %q, want %q", got, want)
+			}
+		})
+	}
+}
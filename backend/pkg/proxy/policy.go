@@ -0,0 +1,326 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxInspectSize request bodies larger than this are not inspected when the policy sets no limit
+	defaultMaxInspectSize = 1 << 20
+	// maxCompiledConfigs compiled config sections kept in memory per kind
+	maxCompiledConfigs = 1024
+	// toolsCallMethod the only method the tool rules apply to
+	toolsCallMethod = "tools/call"
+)
+
+// policyStats policy counters, exposed through expvar at /debug/vars
+var policyStats = expvar.NewMap("gateway_policy")
+
+// ruleSet pre-compiled name patterns, names ending with * match by prefix
+type ruleSet struct {
+	exact    map[string]struct{}
+	prefixes []string
+}
+
+func compileRuleSet(patterns []string) *ruleSet {
+	if len(patterns) == 0 {
+		return nil
+	}
+	rules := &ruleSet{exact: make(map[string]struct{}, len(patterns))}
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			rules.prefixes = append(rules.prefixes, prefix)
+			continue
+		}
+		rules.exact[pattern] = struct{}{}
+	}
+	return rules
+}
+
+// match reports whether name matches any pattern, a nil rule set matches nothing
+func (r *ruleSet) match(name string) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := r.exact[name]; ok {
+		return true
+	}
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcPolicy compiled JSON-RPC policy of a server config
+type rpcPolicy struct {
+	allowMethods   *ruleSet
+	denyMethods    *ruleSet
+	allowTools     *ruleSet
+	denyTools      *ruleSet
+	failOpen       bool
+	maxInspectSize int64
+}
+
+func compilePolicy(cfg *model.McpPolicyConfig) *rpcPolicy {
+	policy := &rpcPolicy{
+		allowMethods:   compileRuleSet(cfg.AllowMethods),
+		denyMethods:    compileRuleSet(cfg.DenyMethods),
+		allowTools:     compileRuleSet(cfg.AllowTools),
+		denyTools:      compileRuleSet(cfg.DenyTools),
+		failOpen:       cfg.FailOpen,
+		maxInspectSize: cfg.MaxInspectSize,
+	}
+	if policy.maxInspectSize <= 0 {
+		policy.maxInspectSize = defaultMaxInspectSize
+	}
+	return policy
+}
+
+// evaluate returns why a call is rejected, empty when it is allowed
+func (p *rpcPolicy) evaluate(method, tool string) string {
+	if p.denyMethods.match(method) || (p.allowMethods != nil && !p.allowMethods.match(method)) {
+		return fmt.Sprintf("method %q is not allowed by the instance policy", method)
+	}
+	if method != toolsCallMethod {
+		return ""
+	}
+	if p.denyTools.match(tool) || (p.allowTools != nil && !p.allowTools.match(tool)) {
+		return fmt.Sprintf("tool %q is not allowed by the instance policy", tool)
+	}
+	return ""
+}
+
+// compiledConfigs values compiled from an instance config section, compiled once per distinct
+// config and shared between requests. The cache is reset when it grows past maxCompiledConfigs.
+type compiledConfigs[C any, V any] struct {
+	compile func(*C) V
+
+	mu     sync.RWMutex
+	values map[string]V
+}
+
+func newCompiledConfigs[C any, V any](compile func(*C) V) *compiledConfigs[C, V] {
+	return &compiledConfigs[C, V]{compile: compile, values: make(map[string]V)}
+}
+
+// get returns the compiled value of cfg, keyed by its JSON encoding
+func (c *compiledConfigs[C, V]) get(cfg *C) V {
+	keyBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return c.compile(cfg)
+	}
+	key := string(keyBytes)
+
+	c.mu.RLock()
+	value, ok := c.values[key]
+	c.mu.RUnlock()
+	if ok {
+		return value
+	}
+
+	value = c.compile(cfg)
+	c.mu.Lock()
+	if len(c.values) >= maxCompiledConfigs {
+		c.values = make(map[string]V)
+	}
+	c.values[key] = value
+	c.mu.Unlock()
+	return value
+}
+
+var policies = newCompiledConfigs(compilePolicy)
+
+// policyFor returns the compiled policy of the instance's server config, nil when it has none
+func policyFor(instanceInfo *InstanceInfo) *rpcPolicy {
+	if instanceInfo == nil || instanceInfo.McpConfig == nil || instanceInfo.McpConfig.Policy == nil {
+		return nil
+	}
+	return policies.get(instanceInfo.McpConfig.Policy)
+}
+
+// policyCall fields of a JSON-RPC request checked by the policy
+type policyCall struct {
+	ID     json.RawMessage
+	Method string
+	Params struct {
+		Name string
+	}
+}
+
+// UnmarshalJSON reads only the exact "method", "params" and "name" keys. encoding/json matches keys
+// case-insensitively and keeps the last match, while upstream SDKs are case-sensitive, so a message
+// carrying both "method" and "Method" would be checked with a different method than the upstream
+// runs. Messages with duplicate or case-variant copies of these keys are rejected.
+func (c *policyCall) UnmarshalJSON(data []byte) error {
+	fields, err := exactFields(data, "method", "params")
+	if err != nil {
+		return err
+	}
+	c.ID = fields["id"]
+	if raw, ok := fields["method"]; ok {
+		if err := json.Unmarshal(raw, &c.Method); err != nil {
+			return fmt.Errorf("invalid method: %v", err)
+		}
+	}
+	// Positional params carry no tool name
+	if raw, ok := fields["params"]; ok && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		params, err := exactFields(raw, "name")
+		if err != nil {
+			return fmt.Errorf("invalid params: %v", err)
+		}
+		if name, ok := params["name"]; ok {
+			if err := json.Unmarshal(name, &c.Params.Name); err != nil {
+				return fmt.Errorf("invalid params.name: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// exactFields decodes a JSON object into its raw values by key, the checked keys must appear at
+// most once and exactly as written
+func exactFields(data []byte, checked ...string) (map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		for _, name := range checked {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			if key != name {
+				return nil, fmt.Errorf("key %q must be written as %q", key, name)
+			}
+			if _, dup := fields[key]; dup {
+				return nil, fmt.Errorf("duplicate key %q", key)
+			}
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+// enforcePolicy checks the JSON-RPC messages of a POST against the instance policy and answers
+// violations with a JSON-RPC error without proxying, returns true when the request was rejected.
+// The inspected body is restored for proxying.
+func enforcePolicy(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodPost || req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok {
+		return false
+	}
+	policy := policyFor(instanceInfo)
+	if policy == nil {
+		return false
+	}
+
+	calls, err := inspectCalls(req, policy.maxInspectSize)
+	if err != nil {
+		return rejectUninspected(w, req, policy, err.Error())
+	}
+
+	for _, call := range calls {
+		reason := policy.evaluate(call.Method, call.Params.Name)
+		if reason == "" {
+			continue
+		}
+		policyStats.Add("denied", 1)
+		logger.FromContext(req.Context()).Warn("Rejected request by instance policy",
+			zap.String("instance_id", instanceInfo.InstanceID),
+			zap.String("method", call.Method),
+			zap.String("tool", call.Params.Name),
+		)
+		gatewayErr := newGatewayError(req, http.StatusForbidden, reason)
+		gatewayErr.Error.Code = rpcCodePolicyDenied
+		// 批量请求整体拒绝，只有单个请求才回显 id
+		if len(calls) == 1 && len(call.ID) > 0 {
+			gatewayErr.ID = call.ID
+		}
+		writeGatewayErrorBody(w, gatewayErr)
+		return true
+	}
+	return false
+}
+
+// inspectCalls reads the JSON-RPC messages of a request body of at most limit bytes,
+// the body is restored for proxying
+func inspectCalls(req *http.Request, limit int64) ([]policyCall, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("request body exceeds the inspection limit of %d bytes", limit)
+	}
+	calls, err := parsePolicyCalls(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON-RPC request: %v", err)
+	}
+	return calls, nil
+}
+
+// parsePolicyCalls parses a single JSON-RPC message or a batch
+func parsePolicyCalls(body []byte) ([]policyCall, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []policyCall
+		if err := json.Unmarshal(trimmed, &calls); err != nil {
+			return nil, err
+		}
+		if len(calls) == 0 {
+			return nil, fmt.Errorf("empty batch")
+		}
+		return calls, nil
+	}
+	var call policyCall
+	if err := json.Unmarshal(trimmed, &call); err != nil {
+		return nil, err
+	}
+	return []policyCall{call}, nil
+}
+
+// rejectUninspected handles a body the policy could not inspect, failOpen policies let it through
+func rejectUninspected(w http.ResponseWriter, req *http.Request, policy *rpcPolicy, message string) bool {
+	policyStats.Add("uninspected", 1)
+	if policy.failOpen {
+		logger.FromContext(req.Context()).Info("Policy inspection failed, request allowed", zap.String("error", message))
+		return false
+	}
+	logger.FromContext(req.Context()).Warn("Policy inspection failed, request rejected", zap.String("error", message))
+	gatewayErr := newGatewayError(req, http.StatusBadRequest, message)
+	gatewayErr.Error.Code = rpcCodeParseError
+	writeGatewayErrorBody(w, gatewayErr)
+	return true
+}
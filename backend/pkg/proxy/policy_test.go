@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestRPCPolicyEvaluate(t *testing.T) {
+	policy := compilePolicy(&model.McpPolicyConfig{
+		DenyMethods: []string{"resources/*"},
+		AllowTools:  []string{"get_*", "search"},
+		DenyTools:   []string{"get_secret"},
+	})
+	tests := []struct {
+		method, tool string
+		allowed      bool
+	}{
+		{"tools/list", "", true},
+		{"initialize", "", true},
+		{"resources/read", "", false},
+		{"tools/call", "get_issue", true},
+		{"tools/call", "search", true},
+		{"tools/call", "get_secret", false},
+		{"tools/call", "delete_repo", false},
+	}
+	for _, tt := range tests {
+		if got := policy.evaluate(tt.method, tt.tool) == ""; got != tt.allowed {
+			t.Errorf("evaluate(%q, %q) allowed = %v, want %v", tt.method, tt.tool, got, tt.allowed)
+		}
+	}
+
+	allowOnly := compilePolicy(&model.McpPolicyConfig{AllowMethods: []string{"initialize", "tools/list"}})
+	if allowOnly.evaluate("tools/call", "search") == "" {
+		t.Error("method outside allowMethods was allowed")
+	}
+}
+
+func TestEnforcePolicy(t *testing.T) {
+	info := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{Policy: &model.McpPolicyConfig{DenyTools: []string{"delete_*"}}}}
+
+	// 允许的请求继续转发，请求体保持不变
+	listReq := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	req := newInstanceReq(http.MethodPost, listReq, info)
+	w := httptest.NewRecorder()
+	if enforcePolicy(w, req) {
+		t.Fatalf("enforcePolicy() rejected an allowed request: %s", w.Body.String())
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != listReq {
+		t.Errorf("request body = %q, want it restored", body)
+	}
+
+	// 被拒绝的工具调用返回带原 id 的 JSON-RPC 错误
+	req = newInstanceReq(http.MethodPost, `{"jsonrpc":"2.0","id":"call-7","method":"tools/call","params":{"name":"delete_repo"}}`, info)
+	w = httptest.NewRecorder()
+	if !enforcePolicy(w, req) {
+		t.Fatal("enforcePolicy() allowed a denied tool call")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", w.Code)
+	}
+	var resp gatewayError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid error response: %v", err)
+	}
+	if string(resp.ID) != `"call-7"` || resp.Error.Code != rpcCodePolicyDenied {
+		t.Errorf("response id = %s code = %d, want \"call-7\" and %d", resp.ID, resp.Error.Code, rpcCodePolicyDenied)
+	}
+
+	// 大小写不同的重复工具名不能绕过拒绝规则
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_repo","Name":"search"}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_repo"},"Params":{"name":"search"}}`,
+	} {
+		w = httptest.NewRecorder()
+		if !enforcePolicy(w, newInstanceReq(http.MethodPost, body, info)) || w.Code != http.StatusBadRequest {
+			t.Errorf("enforcePolicy(%s) = %d, want 400", body, w.Code)
+		}
+	}
+
+	// 批量请求中任一调用被拒绝时整体拒绝
+	req = newInstanceReq(http.MethodPost, `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_user"}}]`, info)
+	if !enforcePolicy(httptest.NewRecorder(), req) {
+		t.Error("enforcePolicy() allowed a batch with a denied tool call")
+	}
+}
+
+func TestEnforcePolicyUninspectable(t *testing.T) {
+	closed := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{Policy: &model.McpPolicyConfig{
+		DenyTools: []string{"delete_repo"}, MaxInspectSize: 64,
+	}}}
+	open := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{Policy: &model.McpPolicyConfig{
+		DenyTools: []string{"delete_repo"}, MaxInspectSize: 64, FailOpen: true,
+	}}}
+	large := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":{"q":"` + strings.Repeat("x", 64) + `"}}}`
+
+	for _, body := range []string{"not json", large} {
+		w := httptest.NewRecorder()
+		if !enforcePolicy(w, newInstanceReq(http.MethodPost, body, closed)) {
+			t.Errorf("fail-closed policy allowed %.20q", body)
+		} else if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+
+		req := newInstanceReq(http.MethodPost, body, open)
+		if enforcePolicy(httptest.NewRecorder(), req) {
+			t.Errorf("fail-open policy rejected %.20q", body)
+		}
+		if got, _ := io.ReadAll(req.Body); string(got) != body {
+			t.Errorf("request body = %.20q, want it restored", got)
+		}
+	}
+}
+
+func TestEnforcePolicySkipsRequestsWithoutPolicy(t *testing.T) {
+	if enforcePolicy(httptest.NewRecorder(), newInstanceReq(http.MethodPost, "not json", nil)) {
+		t.Error("enforcePolicy() rejected a request of an instance without policy")
+	}
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// RedactedPlaceholder replaces every redacted match
+	RedactedPlaceholder = "[REDACTED]"
+
+	// defaultMaxRedactSize responses and SSE messages larger than this are not forwarded when the config sets no limit
+	defaultMaxRedactSize = 1 << 20
+	// defaultRedactTimeBudget redaction time allowed per response or SSE message when the config sets none
+	defaultRedactTimeBudget = 100 * time.Millisecond
+)
+
+var (
+	// errRedactionBudget redaction of a response exceeded its size or time budget
+	errRedactionBudget = errors.New("response exceeds the redaction budget")
+	// errInvalidRedaction the redaction config of the instance does not compile
+	errInvalidRedaction = errors.New("invalid response redaction config")
+)
+
+// redactionStats redaction counters, exposed through expvar at /debug/vars
+var redactionStats = expvar.NewMap("gateway_redaction")
+
+// redactor compiled response redaction of a server config, all patterns and literals are
+// combined into one expression so that each response is scanned once
+type redactor struct {
+	re          *regexp.Regexp
+	err         error
+	maxBodySize int64
+	budget      time.Duration
+}
+
+func compileRedactor(cfg *model.McpRedactionConfig) *redactor {
+	r := &redactor{
+		maxBodySize: cfg.MaxBodySize,
+		budget:      time.Duration(cfg.TimeBudgetMs) * time.Millisecond,
+	}
+	if r.maxBodySize <= 0 {
+		r.maxBodySize = defaultMaxRedactSize
+	}
+	if r.budget <= 0 {
+		r.budget = defaultRedactTimeBudget
+	}
+
+	alternatives := make([]string, 0, len(cfg.Patterns)+len(cfg.Literals))
+	for _, pattern := range cfg.Patterns {
+		if pattern == "" {
+			continue
+		}
+		// 单独编译以便定位无效的表达式
+		if _, err := regexp.Compile(pattern); err != nil {
+			r.err = fmt.Errorf("%w: pattern %q: %v", errInvalidRedaction, pattern, err)
+			return r
+		}
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+	for _, literal := range cfg.Literals {
+		if literal != "" {
+			alternatives = append(alternatives, regexp.QuoteMeta(literal))
+		}
+	}
+	if len(alternatives) > 0 {
+		r.re = regexp.MustCompile(strings.Join(alternatives, "|"))
+	}
+	return r
+}
+
+// redact replaces every match in data, returning the number of replacements. Data larger than the
+// size budget, or whose redaction runs past the time budget, fails instead of passing unredacted.
+func (r *redactor) redact(data []byte) ([]byte, int, error) {
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	if int64(len(data)) > r.maxBodySize {
+		return nil, 0, fmt.Errorf("%w: larger than %d bytes", errRedactionBudget, r.maxBodySize)
+	}
+	if r.re == nil {
+		return data, 0, nil
+	}
+
+	deadline := time.Now().Add(r.budget)
+	count := 0
+	redacted := r.re.ReplaceAllFunc(data, func([]byte) []byte {
+		count++
+		return []byte(RedactedPlaceholder)
+	})
+	if time.Now().After(deadline) {
+		return nil, 0, fmt.Errorf("%w: took longer than %s", errRedactionBudget, r.budget)
+	}
+	if count > 0 {
+		redactionStats.Add("redactions", int64(count))
+	}
+	return redacted, count, nil
+}
+
+// redactSSEMessage redacts the data lines of an SSE message, other fields are left untouched
+func (r *redactor) redactSSEMessage(msg []byte) ([]byte, error) {
+	if int64(len(msg)) > r.maxBodySize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", errRedactionBudget, r.maxBodySize)
+	}
+	lines := bytes.SplitAfter(msg, []byte("\n"))
+	for i, line := range lines {
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		redacted, _, err := r.redact(line)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = redacted
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+var redactors = newCompiledConfigs(compileRedactor)
+
+// redactorFor returns the compiled redaction of the instance's server config, nil when it has none
+func redactorFor(instanceInfo *InstanceInfo) *redactor {
+	if instanceInfo == nil || instanceInfo.McpConfig == nil || instanceInfo.McpConfig.Redaction == nil {
+		return nil
+	}
+	return redactors.get(instanceInfo.McpConfig.Redaction)
+}
+
+// redactResponse redacts a JSON response of an instance with a redaction config, the body is
+// replaced by the redacted copy. Responses that cannot be redacted are not forwarded.
+func redactResponse(resp *http.Response, instanceInfo *InstanceInfo) error {
+	r := redactorFor(instanceInfo)
+	if r == nil || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	var reader io.Reader = resp.Body
+	switch encoding := resp.Header.Get("Content-Encoding"); encoding {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return &proxyError{message: fmt.Sprintf("failed to create gzip reader: %v", err), status: http.StatusBadGateway}
+		}
+		reader = gz
+	default:
+		return redactionFailed(resp, fmt.Errorf("cannot redact response with content encoding %q", encoding))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, r.maxBodySize+1))
+	resp.Body.Close()
+	if err != nil {
+		return &proxyError{message: fmt.Sprintf("failed to read upstream response: %v", err), status: upstreamErrorStatus(err)}
+	}
+	redacted, _, err := r.redact(body)
+	if err != nil {
+		return redactionFailed(resp, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(redacted))
+	resp.ContentLength = int64(len(redacted))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(redacted)))
+	resp.Header.Del("Content-Encoding")
+	return nil
+}
+
+// redactionFailed logs a response that could not be redacted and returns the error sent to the client
+func redactionFailed(resp *http.Response, err error) error {
+	redactionStats.Add("rejected", 1)
+	logger.FromContext(resp.Request.Context()).Warn("Response redaction failed, response dropped", zap.Error(err))
+	return &proxyError{message: err.Error(), status: http.StatusBadGateway}
+}
+
+// redactSSE redacts an upstream SSE message, messages that cannot be redacted are replaced by an error event
+func (r *SSEResponseBodyReader) redactSSE(msg []byte) []byte {
+	if r.redactor == nil || len(msg) == 0 {
+		return msg
+	}
+	redacted, err := r.redactor.redactSSEMessage(msg)
+	if err == nil {
+		return redacted
+	}
+	redactionStats.Add("rejected", 1)
+	if r.req == nil {
+		return nil
+	}
+	logger.FromContext(r.req.Context()).Warn("SSE message redaction failed, message dropped",
+		zap.String("instance_id", r.info.InstanceID),
+		zap.Error(err),
+	)
+	return sseErrorFrame(r.req, http.StatusBadGateway, fmt.Sprintf("message dropped: %v", err))
+}
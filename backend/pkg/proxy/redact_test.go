@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestRedactorRedact(t *testing.T) {
+	r := compileRedactor(&model.McpRedactionConfig{
+		Patterns: []string{`sk-[A-Za-z0-9]{8,}`},
+		Literals: []string{"p@ss.word"},
+	})
+	got, count, err := r.redact([]byte(`{"text":"key sk-abcdef123456 and p@ss.word, not pXss.word"}`))
+	if err != nil {
+		t.Fatalf("redact() error = %v", err)
+	}
+	want := `{"text":"key [REDACTED] and [REDACTED], not pXss.word"}`
+	if string(got) != want || count != 2 {
+		t.Errorf("redact() = %s (%d), want %s (2)", got, count, want)
+	}
+
+	small := compileRedactor(&model.McpRedactionConfig{Literals: []string{"secret"}, MaxBodySize: 8})
+	if _, _, err := small.redact([]byte("0123456789")); !errors.Is(err, errRedactionBudget) {
+		t.Errorf("redact() of oversized data error = %v, want errRedactionBudget", err)
+	}
+
+	invalid := compileRedactor(&model.McpRedactionConfig{Patterns: []string{"sk-[a-z"}})
+	if _, _, err := invalid.redact([]byte("sk-a")); !errors.Is(err, errInvalidRedaction) {
+		t.Errorf("redact() with invalid pattern error = %v, want errInvalidRedaction", err)
+	}
+}
+
+func TestRedactResponse(t *testing.T) {
+	info := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{
+		Redaction: &model.McpRedactionConfig{Literals: []string{"top-secret"}},
+	}}
+	req := httptest.NewRequest(http.MethodPost, "/mcp/abc", nil)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"content":"top-secret"}}`))
+	zw.Close()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+		Body:       io.NopCloser(&gz),
+		Request:    req,
+	}
+	if err := redactResponse(resp, info); err != nil {
+		t.Fatalf("redactResponse() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	want := `{"jsonrpc":"2.0","id":1,"result":{"content":"[REDACTED]"}}`
+	if string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+	if resp.Header.Get("Content-Encoding") != "" || resp.ContentLength != int64(len(want)) {
+		t.Errorf("Content-Encoding = %q ContentLength = %d, want decoded body of %d bytes",
+			resp.Header.Get("Content-Encoding"), resp.ContentLength, len(want))
+	}
+
+	// 无法解码的响应不转发
+	resp = &http.Response{
+		Header:  http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"br"}},
+		Body:    io.NopCloser(strings.NewReader("...")),
+		Request: req,
+	}
+	if err := redactResponse(resp, info); err == nil {
+		t.Error("redactResponse() of brotli response error = nil, want it rejected")
+	}
+}
+
+func TestSSERedaction(t *testing.T) {
+	info := &InstanceInfo{InstanceID: "abc", McpConfig: &model.McpConfig{
+		Redaction: &model.McpRedactionConfig{Patterns: []string{`ghp_\w+`}},
+	}}
+	upstream := "event: message\ndata: {\"result\":\"token ghp_abc123\"}\n\n"
+	reader := &SSEResponseBodyReader{
+		src:      strings.NewReader(upstream),
+		info:     info,
+		req:      httptest.NewRequest(http.MethodGet, "/mcp/abc/sse", nil),
+		redactor: redactorFor(info),
+	}
+	got, _ := io.ReadAll(reader)
+	want := "event: message\ndata: {\"result\":\"token [REDACTED]\"}\n\n"
+	if string(got) != want {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+}
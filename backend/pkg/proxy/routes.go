@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// RouteHeader response header naming the upstream that served a request of an instance
+	// with canary routes: primary or route-{n}
+	RouteHeader = "X-MCP-Route"
+	// McpSessionHeader streamable-http session header, prefixed with the route of canary sessions
+	McpSessionHeader = "Mcp-Session-Id"
+
+	// routePathSegment path segment marking the route in rewritten SSE endpoint paths,
+	// e.g. /{prefix}/{instanceId}/_route/1/messages/?session_id=xxx
+	routePathSegment = "_route"
+	// routeSessionPrefix prefix of the route index in streamable-http session ids, e.g. r1.{sessionId}
+	routeSessionPrefix = "r"
+	// primaryRoute index of the primary target
+	primaryRoute = 0
+)
+
+// routeChoice upstream chosen for a request of an instance with canary routes
+type routeChoice struct {
+	// index 0 for the primary target, n for the n-th route
+	index int
+	// origURL request URL before it was rewritten to the target, replayed to the primary on fallback
+	origURL  url.URL
+	isSSEReq bool
+	prefix   string
+	// body request body of a canary attempt, nil for the primary target and bodyless requests
+	body *canaryBody
+}
+
+// name value of the route response header
+func (c *routeChoice) name() string {
+	if c.index == primaryRoute {
+		return "primary"
+	}
+	return "route-" + strconv.Itoa(c.index)
+}
+
+// canaryBody request body of a canary attempt. It stays open when the canary cannot be reached so
+// that the request can be replayed to the primary target, the server closes the client body.
+type canaryBody struct {
+	io.ReadCloser
+	read bool
+}
+
+func (b *canaryBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.read = true
+	}
+	return n, err
+}
+
+func (b *canaryBody) Close() error {
+	return nil
+}
+
+// selectRoute picks the upstream of a request and returns its target URL. Event requests follow
+// the route recorded in their SSE endpoint path, streamable-http requests the route recorded in their
+// session id, new sessions are matched by header and then split by weight. Instances without
+// routes always use the primary target and get no route recorded.
+func selectRoute(req *http.Request, instanceInfo *InstanceInfo, isSSEReq bool, prefix string) string {
+	routes := instanceInfo.McpConfig.Routes
+	if len(routes) == 0 {
+		return instanceInfo.McpConfig.URL
+	}
+
+	index := primaryRoute
+	switch {
+	case instanceInfo.McpProtocol == model.McpProtocolSSE && !isSSEReq:
+		if token, rest, ok := splitRoutePath(req.URL.Path, prefix); ok {
+			req.URL.Path = rest
+			index = parseRouteIndex(token, len(routes))
+		}
+	case instanceInfo.McpProtocol == model.McpProtocolStreamableHttp && req.Header.Get(McpSessionHeader) != "":
+		sessionID, sessionIndex := splitRouteSession(req.Header.Get(McpSessionHeader), len(routes))
+		req.Header.Set(McpSessionHeader, sessionID)
+		index = sessionIndex
+	default:
+		index = pickRoute(req, routes)
+	}
+
+	choice := &routeChoice{index: index, origURL: *req.URL, isSSEReq: isSSEReq, prefix: prefix}
+	if index != primaryRoute && req.Body != nil && req.Body != http.NoBody {
+		choice.body = &canaryBody{ReadCloser: req.Body}
+		req.Body = choice.body
+	}
+	*req = *req.WithContext(context.WithValue(req.Context(), RouteChoiceKey, choice))
+	if index == primaryRoute {
+		return instanceInfo.McpConfig.URL
+	}
+	return routes[index-1].TargetURL
+}
+
+// pickRoute chooses the route of a new session, header matches win over weights
+func pickRoute(req *http.Request, routes []*model.McpRouteConfig) int {
+	for i, route := range routes {
+		if route != nil && matchRouteHeader(req, route.MatchHeader) {
+			return i + 1
+		}
+	}
+	roll := rand.Intn(100)
+	for i, route := range routes {
+		if route == nil || route.Weight <= 0 {
+			continue
+		}
+		if roll < route.Weight {
+			return i + 1
+		}
+		roll -= route.Weight
+	}
+	return primaryRoute
+}
+
+// matchRouteHeader reports whether the request matches a "Name" or "Name: value" header rule
+func matchRouteHeader(req *http.Request, rule string) bool {
+	name, value, hasValue := strings.Cut(rule, ":")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false
+	}
+	if !hasValue {
+		return req.Header.Get(name) != ""
+	}
+	return req.Header.Get(name) == strings.TrimSpace(value)
+}
+
+// parseRouteIndex parses a route index from a client supplied token, unknown routes use the primary target
+func parseRouteIndex(token string, routes int) int {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 1 || index > routes {
+		return primaryRoute
+	}
+	return index
+}
+
+// splitRouteSession strips the route prefix from a streamable-http session id,
+// ids without a prefix belong to the primary target
+func splitRouteSession(sessionID string, routes int) (string, int) {
+	head, rest, ok := strings.Cut(sessionID, ".")
+	if !ok || !strings.HasPrefix(head, routeSessionPrefix) {
+		return sessionID, primaryRoute
+	}
+	index := parseRouteIndex(strings.TrimPrefix(head, routeSessionPrefix), routes)
+	if index == primaryRoute {
+		return sessionID, primaryRoute
+	}
+	return rest, index
+}
+
+// routePrefix returns the proxy prefix with the route appended
+func routePrefix(prefix string, index int) string {
+	return path.Join(prefix, routePathSegment, strconv.Itoa(index))
+}
+
+// splitRoutePath extracts the route token following prefix and returns the path without it
+func splitRoutePath(reqPath, prefix string) (token string, rest string, ok bool) {
+	marker := path.Join("/", prefix, routePathSegment) + "/"
+	if !strings.HasPrefix(reqPath, marker) {
+		return "", reqPath, false
+	}
+	token, after, _ := strings.Cut(strings.TrimPrefix(reqPath, marker), "/")
+	if token == "" {
+		return "", reqPath, false
+	}
+	return token, path.Join("/", prefix) + "/" + after, true
+}
+
+// markRoute reports the route that served the response and records it in the session id of
+// new streamable-http canary sessions
+func markRoute(resp *http.Response) {
+	choice, ok := resp.Request.Context().Value(RouteChoiceKey).(*routeChoice)
+	if !ok {
+		return
+	}
+	resp.Header.Set(RouteHeader, choice.name())
+	if sessionID := resp.Header.Get(McpSessionHeader); sessionID != "" && choice.index != primaryRoute {
+		resp.Header.Set(McpSessionHeader, routeSessionPrefix+strconv.Itoa(choice.index)+"."+sessionID)
+	}
+}
+
+// routeIndexOf returns the route that served a request, 0 for the primary target
+func routeIndexOf(req *http.Request) int {
+	if choice, ok := req.Context().Value(RouteChoiceKey).(*routeChoice); ok {
+		return choice.index
+	}
+	return primaryRoute
+}
+
+// fallbackToPrimary returns the request replayed to the primary target when a canary route could
+// not be reached, nil when the failed round trip must be reported as is
+func fallbackToPrimary(req *http.Request, instanceInfo *InstanceInfo, err error) *http.Request {
+	choice, ok := req.Context().Value(RouteChoiceKey).(*routeChoice)
+	if !ok || choice.index == primaryRoute || !isDialFailure(err) || (choice.body != nil && choice.body.read) {
+		return nil
+	}
+	primary, parseErr := url.Parse(instanceInfo.McpConfig.URL)
+	if parseErr != nil {
+		return nil
+	}
+	logger.FromContext(req.Context()).Warn("Canary route unreachable, falling back to primary target",
+		zap.String("instance_id", instanceInfo.InstanceID),
+		zap.String("route", choice.name()),
+		zap.Error(err),
+	)
+
+	choice.index = primaryRoute
+	retry := req.Clone(req.Context())
+	origURL := choice.origURL
+	retry.URL = &origURL
+	if choice.body != nil {
+		retry.Body = choice.body.ReadCloser
+	}
+	rewriteTarget(retry, instanceInfo, choice.isSSEReq, choice.prefix, primary)
+	return retry
+}
+
+// isDialFailure reports whether the upstream could not be connected to, the request was then not sent
+func isDialFailure(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+func newRouteInfo(protocol model.McpProtocol, primary string, routes ...*model.McpRouteConfig) *InstanceInfo {
+	return &InstanceInfo{
+		InstanceID:  "abc",
+		AccessType:  model.AccessTypeProxy,
+		McpProtocol: protocol,
+		McpConfig:   &model.McpConfig{URL: primary, Routes: routes},
+	}
+}
+
+func directRoute(req *http.Request, info *InstanceInfo, isSSEReq bool) *http.Request {
+	ctx := context.WithValue(req.Context(), InstanceInfoKey, info)
+	req = req.WithContext(context.WithValue(ctx, IsSSEReqKey, isSSEReq))
+	director(req)
+	return req
+}
+
+func TestPickRoute(t *testing.T) {
+	routes := []*model.McpRouteConfig{
+		{TargetURL: "https://v2.example.com/mcp", MatchHeader: "X-Canary: v2"},
+		{TargetURL: "https://v3.example.com/mcp", Weight: 100},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/mcp/abc", nil)
+	req.Header.Set("X-Canary", "v2")
+	if got := pickRoute(req, routes); got != 1 {
+		t.Errorf("pickRoute() with matching header = %d, want 1", got)
+	}
+	req.Header.Set("X-Canary", "v1")
+	if got := pickRoute(req, routes); got != 2 {
+		t.Errorf("pickRoute() by weight = %d, want 2", got)
+	}
+	if got := pickRoute(req, routes[:1]); got != primaryRoute {
+		t.Errorf("pickRoute() without match or weight = %d, want primary", got)
+	}
+}
+
+func TestStreamableRouteSession(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	info := newRouteInfo(model.McpProtocolStreamableHttp, "https://v1.example.com/mcp",
+		&model.McpRouteConfig{TargetURL: "https://v2.example.com/mcp", Weight: 100})
+
+	// 新会话按权重分流，上游返回的会话 id 带上路由前缀
+	req := directRoute(httptest.NewRequest(http.MethodPost, getProxyPrefix("abc", ""), nil), info, false)
+	if req.URL.Host != "v2.example.com" {
+		t.Fatalf("new session host = %q, want v2.example.com", req.URL.Host)
+	}
+	resp := &http.Response{Header: http.Header{McpSessionHeader: {"s-1"}}, Request: req}
+	markRoute(resp)
+	if got := resp.Header.Get(McpSessionHeader); got != "r1.s-1" {
+		t.Errorf("session id = %q, want r1.s-1", got)
+	}
+	if got := resp.Header.Get(RouteHeader); got != "route-1" {
+		t.Errorf("%s = %q, want route-1", RouteHeader, got)
+	}
+
+	// 后续请求跟随会话 id 中的路由，转发时去掉前缀
+	req = httptest.NewRequest(http.MethodPost, getProxyPrefix("abc", ""), nil)
+	req.Header.Set(McpSessionHeader, "r1.s-1")
+	req = directRoute(req, info, false)
+	if req.URL.Host != "v2.example.com" || req.Header.Get(McpSessionHeader) != "s-1" {
+		t.Errorf("sticky request host = %q session = %q, want v2.example.com and s-1", req.URL.Host, req.Header.Get(McpSessionHeader))
+	}
+
+	// 没有路由前缀的会话属于主目标
+	req = httptest.NewRequest(http.MethodPost, getProxyPrefix("abc", ""), nil)
+	req.Header.Set(McpSessionHeader, "s-0")
+	if req = directRoute(req, info, false); req.URL.Host != "v1.example.com" {
+		t.Errorf("primary session host = %q, want v1.example.com", req.URL.Host)
+	}
+}
+
+func TestSSERouteStickiness(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	info := newRouteInfo(model.McpProtocolSSE, "https://v1.example.com/sse",
+		&model.McpRouteConfig{TargetURL: "https://v2.example.com/sse", MatchHeader: "X-Canary"})
+
+	sseReq := httptest.NewRequest(http.MethodGet, getProxyPrefix("abc", "")+"/sse", nil)
+	sseReq.Header.Set("X-Canary", "1")
+	sseReq = directRoute(sseReq, info, true)
+	if sseReq.URL.Host != "v2.example.com" {
+		t.Fatalf("SSE host = %q, want v2.example.com", sseReq.URL.Host)
+	}
+
+	reader := &SSEResponseBodyReader{
+		src:   strings.NewReader("event: endpoint\ndata: /messages/?session_id=1\n\n"),
+		info:  info,
+		route: routeIndexOf(sseReq),
+	}
+	got, _ := io.ReadAll(reader)
+	endpoint := routePrefix(getPublicProxyPrefix(nil, "abc", ""), 1) + "/messages/?session_id=1"
+	if !strings.Contains(string(got), endpoint) {
+		t.Fatalf("rewritten event = %q, want it to contain %q", got, endpoint)
+	}
+
+	// 事件请求不带匹配请求头，仍跟随 SSE 连接选中的路由
+	eventReq := httptest.NewRequest(http.MethodPost, routePrefix(getProxyPrefix("abc", ""), 1)+"/messages/?session_id=1", nil)
+	eventReq = directRoute(eventReq, info, false)
+	if eventReq.URL.Host != "v2.example.com" || eventReq.URL.Path != "/messages/" {
+		t.Errorf("event request = %s, want https://v2.example.com/messages/", eventReq.URL)
+	}
+}
+
+func TestCanaryFallbackToPrimary(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer primary.Close()
+	// 关闭的端口模拟不可达的金丝雀
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	canary := "http://" + listener.Addr().String() + "/mcp"
+	listener.Close()
+
+	info := newRouteInfo(model.McpProtocolStreamableHttp, primary.URL+"/mcp",
+		&model.McpRouteConfig{TargetURL: canary, Weight: 100})
+	req := httptest.NewRequest(http.MethodPost, getProxyPrefix("abc", ""), strings.NewReader(`{"method":"ping"}`))
+	req = directRoute(req, info, false)
+	req.RequestURI = ""
+
+	resp, err := (&breakerTransport{}).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v, want fallback to primary", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"method":"ping"}` {
+		t.Errorf("primary received body %q, want the original request body", body)
+	}
+	markRoute(resp)
+	if got := resp.Header.Get(RouteHeader); got != "primary" {
+		t.Errorf("%s = %q, want primary", RouteHeader, got)
+	}
+}
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// discoveryMethods JSON-RPC methods a discovery token may call. Besides listing, the session
+// handshake and keepalive are allowed so that clients can connect at all.
+var discoveryMethods = map[string]struct{}{
+	"initialize":                {},
+	"notifications/initialized": {},
+	"ping":                      {},
+	"tools/list":                {},
+	"resources/list":            {},
+}
+
+// allowedByScope reports whether a token scope permits a JSON-RPC message. Messages without a
+// method are responses to requests of the server and are always allowed.
+func allowedByScope(scope, method string) bool {
+	if scope != model.TokenScopeDiscovery || method == "" {
+		return true
+	}
+	_, ok := discoveryMethods[method]
+	return ok
+}
+
+// enforceTokenScope checks the JSON-RPC messages of a POST against the scope of the request token
+// and answers violations with a JSON-RPC permission error without proxying, returns true when the
+// request was rejected. Streamable HTTP messages and SSE event POSTs both carry the method in the
+// body, bodyless requests such as the SSE stream itself are not restricted.
+func enforceTokenScope(w http.ResponseWriter, req *http.Request) bool {
+	scope, ok := req.Context().Value(TokenScopeKey).(string)
+	if !ok || req.Method != http.MethodPost || req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+
+	// Restricted tokens fail closed, a body that cannot be inspected is not proxied
+	calls, err := inspectCalls(req, defaultMaxInspectSize)
+	if err != nil {
+		logger.FromContext(req.Context()).Warn("Token scope inspection failed, request rejected", zap.Error(err))
+		gatewayErr := newGatewayError(req, http.StatusBadRequest, err.Error())
+		gatewayErr.Error.Code = rpcCodeParseError
+		writeGatewayErrorBody(w, gatewayErr)
+		return true
+	}
+
+	for _, call := range calls {
+		if allowedByScope(scope, call.Method) {
+			continue
+		}
+		policyStats.Add("scope_denied", 1)
+		logger.FromContext(req.Context()).Warn("Rejected request outside token scope",
+			zap.String("scope", scope),
+			zap.String("method", call.Method),
+		)
+		gatewayErr := newGatewayError(req, http.StatusForbidden,
+			fmt.Sprintf("method %q is not permitted for tokens with scope %q", call.Method, scope))
+		gatewayErr.Error.Code = rpcCodeScopeDenied
+		if len(calls) == 1 && len(call.ID) > 0 {
+			gatewayErr.ID = call.ID
+		}
+		writeGatewayErrorBody(w, gatewayErr)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+func scopedRequest(t *testing.T, body string, usages ...string) *http.Request {
+	t.Helper()
+	instance := &model.McpInstance{Tokens: []model.McpToken{{Token: "secret", Usages: usages}}}
+	req := httptest.NewRequest(http.MethodPost, "/mcp/abc", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	if pe := checkInstanceToken(req, instance); pe != nil {
+		t.Fatalf("checkInstanceToken() = %s", pe.message)
+	}
+	return req
+}
+
+func TestEnforceTokenScope(t *testing.T) {
+	// discovery 令牌可以握手和列出工具，请求体保持不变
+	list := `[{"jsonrpc":"2.0","id":1,"method":"initialize"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`
+	req := scopedRequest(t, list, model.TokenScopeDiscovery)
+	w := httptest.NewRecorder()
+	if enforceTokenScope(w, req) {
+		t.Fatalf("enforceTokenScope() rejected discovery methods: %s", w.Body.String())
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != list {
+		t.Errorf("forwarded body = %s, want %s", body, list)
+	}
+
+	// 调用工具返回 JSON-RPC 权限错误并回显 id
+	req = scopedRequest(t, `{"jsonrpc":"2.0","id":"7","method":"tools/call","params":{"name":"search"}}`, model.TokenScopeDiscovery)
+	w = httptest.NewRecorder()
+	if !enforceTokenScope(w, req) {
+		t.Fatal("enforceTokenScope() allowed tools/call for a discovery token")
+	}
+	var resp gatewayError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusForbidden || resp.Error.Code != rpcCodeScopeDenied || string(resp.ID) != `"7"` {
+		t.Errorf("response = %d %s, want 403 with code %d and id \"7\"", w.Code, w.Body.String(), rpcCodeScopeDenied)
+	}
+
+	// full 令牌和使用场景为历史自由文本的令牌不受限制
+	for _, usages := range [][]string{{model.TokenScopeFull}, {model.TokenScopeDiscovery, model.TokenScopeFull}, {"ci"}, nil} {
+		req = scopedRequest(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`, usages...)
+		if enforceTokenScope(httptest.NewRecorder(), req) {
+			t.Errorf("enforceTokenScope() rejected a token with usages %v", usages)
+		}
+	}
+
+	// 大小写不同的重复键不能绕过检查，上游按区分大小写的 method 执行
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","Method":"tools/list","params":{"name":"search"}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":1,"METHOD":"tools/list"}`,
+	} {
+		req = scopedRequest(t, body, model.TokenScopeDiscovery)
+		w = httptest.NewRecorder()
+		if !enforceTokenScope(w, req) || w.Code != http.StatusBadRequest {
+			t.Errorf("enforceTokenScope(%s) = %d, want 400", body, w.Code)
+		}
+	}
+
+	// 无法解析的请求体不转发
+	req = scopedRequest(t, `{"method":`, model.TokenScopeDiscovery)
+	w = httptest.NewRecorder()
+	if !enforceTokenScope(w, req) || w.Code != http.StatusBadRequest {
+		t.Errorf("enforceTokenScope() of an invalid body = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,235 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// McpSessionIDHeader session header of the MCP streamable HTTP transport
+	McpSessionIDHeader = "Mcp-Session-Id"
+	// defaultMcpSessionTTL idle time after which a session is no longer counted as active
+	defaultMcpSessionTTL = 30 * time.Minute
+	// maxSessionErrorBody bytes of an upstream 404 body read to recover the JSON-RPC request id
+	maxSessionErrorBody = 64 << 10
+)
+
+// mcpSessionStats active streamable HTTP sessions per instance, exposed through expvar at /debug/vars
+var mcpSessionStats = expvar.NewMap("gateway_mcp_sessions")
+
+// McpSessionStore publishes the active streamable HTTP session counts of this gateway so that the market can report them
+type McpSessionStore interface {
+	SaveSessionCounts(counts map[string]int) error
+}
+
+// mcpSessions streamable HTTP sessions seen by the gateway per instance, a session is active
+// until it is terminated with DELETE, rejected by the upstream or idle for ttl
+type mcpSessions struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	store McpSessionStore
+	// sessions last request time of each session id per instance
+	sessions map[string]map[string]time.Time
+	// reported instances whose non-zero count was published, so that the drop to zero is published too
+	reported map[string]bool
+	now      func() time.Time
+}
+
+var activeMcpSessions = &mcpSessions{
+	ttl:      defaultMcpSessionTTL,
+	sessions: make(map[string]map[string]time.Time),
+	reported: make(map[string]bool),
+	now:      time.Now,
+}
+
+// SetMcpSessions configures the session idle TTL in seconds, 0 uses the default.
+// store may be nil, counts are then only exposed through expvar.
+func SetMcpSessions(ttl int, store McpSessionStore) {
+	activeMcpSessions.mu.Lock()
+	defer activeMcpSessions.mu.Unlock()
+	activeMcpSessions.ttl = sessionTTL(ttl)
+	activeMcpSessions.store = store
+}
+
+// SetMcpSessionTTL changes the session idle TTL in seconds without touching tracked sessions
+func SetMcpSessionTTL(ttl int) {
+	activeMcpSessions.mu.Lock()
+	defer activeMcpSessions.mu.Unlock()
+	activeMcpSessions.ttl = sessionTTL(ttl)
+}
+
+// sessionTTL converts the configured TTL, 0 or negative uses the default
+func sessionTTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultMcpSessionTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// touch records a request of a session
+func (s *mcpSessions) touch(instanceID, sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := s.sessions[instanceID]
+	if sessions == nil {
+		sessions = make(map[string]time.Time)
+		s.sessions[instanceID] = sessions
+	}
+	if _, ok := sessions[sessionID]; !ok {
+		mcpSessionStats.Add(instanceID, 1)
+	}
+	sessions[sessionID] = s.now()
+}
+
+// forget removes a terminated session, returns false when the session was not tracked
+func (s *mcpSessions) forget(instanceID, sessionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := s.sessions[instanceID]
+	if _, ok := sessions[sessionID]; !ok {
+		return false
+	}
+	delete(sessions, sessionID)
+	if len(sessions) == 0 {
+		delete(s.sessions, instanceID)
+	}
+	mcpSessionStats.Add(instanceID, -1)
+	return true
+}
+
+// expire removes sessions idle for longer than the TTL, the caller holds the lock
+func (s *mcpSessions) expire() {
+	idleBefore := s.now().Add(-s.ttl)
+	for instanceID, sessions := range s.sessions {
+		for sessionID, lastSeen := range sessions {
+			if lastSeen.Before(idleBefore) {
+				delete(sessions, sessionID)
+				mcpSessionStats.Add(instanceID, -1)
+			}
+		}
+		if len(sessions) == 0 {
+			delete(s.sessions, instanceID)
+		}
+	}
+}
+
+// count returns the active sessions of an instance
+func (s *mcpSessions) count(instanceID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	return len(s.sessions[instanceID])
+}
+
+// counts returns the session counts to publish, instances whose sessions all ended
+// since the last report are included once with a zero count
+func (s *mcpSessions) counts() (map[string]int, McpSessionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	counts := make(map[string]int, len(s.sessions))
+	for instanceID := range s.reported {
+		if _, ok := s.sessions[instanceID]; !ok {
+			counts[instanceID] = 0
+			delete(s.reported, instanceID)
+		}
+	}
+	for instanceID, sessions := range s.sessions {
+		counts[instanceID] = len(sessions)
+		s.reported[instanceID] = true
+	}
+	return counts, s.store
+}
+
+// ReportMcpSessions publishes the session counts periodically until ctx is canceled
+func ReportMcpSessions(ctx context.Context) {
+	ticker := time.NewTicker(sseConnectionReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			counts, store := activeMcpSessions.counts()
+			if store == nil || len(counts) == 0 {
+				continue
+			}
+			if err := store.SaveSessionCounts(counts); err != nil {
+				logger.Warn("Failed to publish MCP session counts", zap.Error(err))
+			}
+		}
+	}
+}
+
+// trackMcpSession follows the session of a streamable HTTP exchange. Session ids issued by the
+// upstream are recorded, DELETE terminates them, and a 404 for a session id means the upstream no
+// longer knows the session, e.g. after a restart. The 404 is then replaced by a session expired
+// JSON-RPC error so that clients start a new session instead of failing with an opaque upstream error.
+func trackMcpSession(resp *http.Response, instanceInfo *InstanceInfo) {
+	if instanceInfo == nil {
+		return
+	}
+	instanceID := instanceInfo.InstanceID
+	sessionID := resp.Request.Header.Get(McpSessionIDHeader)
+
+	switch {
+	case sessionID != "" && resp.StatusCode == http.StatusNotFound:
+		activeMcpSessions.forget(instanceID, sessionID)
+		logger.FromContext(resp.Request.Context()).Info("MCP session expired upstream",
+			zap.String("instance_id", instanceID),
+			zap.String("session_id", sessionID),
+		)
+		replaceWithSessionExpired(resp, sessionID)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+	case sessionID != "" && resp.Request.Method == http.MethodDelete:
+		activeMcpSessions.forget(instanceID, sessionID)
+	default:
+		// The initialize response carries the id of a new session
+		if issued := resp.Header.Get(McpSessionIDHeader); issued != "" {
+			sessionID = issued
+		}
+		if sessionID != "" {
+			activeMcpSessions.touch(instanceID, sessionID)
+		}
+	}
+}
+
+// replaceWithSessionExpired turns the upstream 404 of an unknown session into a JSON-RPC error
+// answering the request id when the upstream returned one, the status stays 404 as the
+// streamable HTTP transport tells clients to re-initialize on it
+func replaceWithSessionExpired(resp *http.Response, sessionID string) {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSessionErrorBody))
+	resp.Body.Close()
+
+	errBody := newGatewayError(resp.Request, http.StatusNotFound,
+		fmt.Sprintf("session %s expired or is unknown to the upstream, start a new session with an initialize request without %s", sessionID, McpSessionIDHeader))
+	errBody.Error.Code = rpcCodeSessionExpired
+	errBody.Error.Data.SessionID = sessionID
+	var rpcResp jsonRPCResponse
+	if err := json.Unmarshal(body, &rpcResp); err == nil && len(rpcResp.ID) > 0 {
+		errBody.ID = rpcResp.ID
+	}
+
+	data, _ := json.Marshal(errBody)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del(McpSessionIDHeader)
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+)
+
+// newSessionResp builds an upstream response to a request of the session, empty sessionID sends none
+func newSessionResp(method, sessionID string, status int, header http.Header, body string) *http.Response {
+	req := httptest.NewRequest(method, "/mcp/abc", nil)
+	if sessionID != "" {
+		req.Header.Set(McpSessionIDHeader, sessionID)
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}
+}
+
+// resetMcpSessions clears the tracked sessions, the clock is restored after the test
+func resetMcpSessions(t *testing.T) {
+	logger.Init("error", "json")
+	activeMcpSessions.mu.Lock()
+	activeMcpSessions.sessions = make(map[string]map[string]time.Time)
+	activeMcpSessions.reported = make(map[string]bool)
+	activeMcpSessions.mu.Unlock()
+	SetMcpSessions(0, nil)
+	t.Cleanup(func() { activeMcpSessions.now = time.Now })
+}
+
+func TestMcpSessionLifecycle(t *testing.T) {
+	resetMcpSessions(t)
+	info := &InstanceInfo{InstanceID: "abc"}
+
+	// initialize 响应返回新的会话ID
+	trackMcpSession(newSessionResp(http.MethodPost, "", http.StatusOK, http.Header{McpSessionIDHeader: {"s1"}}, `{}`), info)
+	trackMcpSession(newSessionResp(http.MethodPost, "s1", http.StatusAccepted, nil, ""), info)
+	trackMcpSession(newSessionResp(http.MethodPost, "", http.StatusOK, http.Header{McpSessionIDHeader: {"s2"}}, `{}`), info)
+	if got := activeMcpSessions.count("abc"); got != 2 {
+		t.Fatalf("active sessions = %d, want 2", got)
+	}
+
+	// 上游不支持客户端终止会话时保留会话
+	trackMcpSession(newSessionResp(http.MethodDelete, "s1", http.StatusMethodNotAllowed, nil, ""), info)
+	if got := activeMcpSessions.count("abc"); got != 2 {
+		t.Errorf("active sessions after rejected DELETE = %d, want 2", got)
+	}
+	trackMcpSession(newSessionResp(http.MethodDelete, "s1", http.StatusOK, nil, ""), info)
+	trackMcpSession(newSessionResp(http.MethodDelete, "s2", http.StatusNoContent, nil, ""), info)
+	if counts, _ := activeMcpSessions.counts(); len(counts) != 0 {
+		t.Errorf("counts after DELETE = %v, want none", counts)
+	}
+}
+
+func TestMcpSessionExpiredUpstream(t *testing.T) {
+	resetMcpSessions(t)
+	info := &InstanceInfo{InstanceID: "abc"}
+	trackMcpSession(newSessionResp(http.MethodPost, "", http.StatusOK, http.Header{McpSessionIDHeader: {"s1"}}, `{}`), info)
+
+	// 上游重启后不再认识该会话
+	header := http.Header{"Content-Type": {"text/plain"}, "Content-Length": {"19"}, McpSessionIDHeader: {"s1"}}
+	resp := newSessionResp(http.MethodPost, "s1", http.StatusNotFound, header, `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"Session not found"}}`)
+	trackMcpSession(resp, info)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get(McpSessionIDHeader) != "" {
+		t.Errorf("headers = %v, want JSON without %s", resp.Header, McpSessionIDHeader)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if resp.ContentLength != int64(len(data)) {
+		t.Errorf("ContentLength = %d, want %d", resp.ContentLength, len(data))
+	}
+	var body gatewayError
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("body %s: %v", data, err)
+	}
+	if body.JSONRPC != "2.0" || string(body.ID) != "7" || body.Error.Code != rpcCodeSessionExpired ||
+		body.Error.Data.SessionID != "s1" || body.Error.Data.Status != http.StatusNotFound {
+		t.Errorf("body = %s, want session expired error answering id 7", data)
+	}
+	if got := activeMcpSessions.count("abc"); got != 0 {
+		t.Errorf("active sessions = %d, want 0", got)
+	}
+
+	// 不带会话ID的 404 原样返回
+	resp = newSessionResp(http.MethodPost, "", http.StatusNotFound, nil, "not found")
+	trackMcpSession(resp, info)
+	if data, _ := io.ReadAll(resp.Body); string(data) != "not found" {
+		t.Errorf("body without session = %q, want upstream body", data)
+	}
+}
+
+func TestMcpSessionIdleTTL(t *testing.T) {
+	resetMcpSessions(t)
+	SetMcpSessionTTL(60)
+	now := time.Now()
+	activeMcpSessions.now = func() time.Time { return now }
+	info := &InstanceInfo{InstanceID: "abc"}
+
+	trackMcpSession(newSessionResp(http.MethodPost, "", http.StatusOK, http.Header{McpSessionIDHeader: {"idle"}}, `{}`), info)
+	trackMcpSession(newSessionResp(http.MethodPost, "", http.StatusOK, http.Header{McpSessionIDHeader: {"busy"}}, `{}`), info)
+	now = now.Add(45 * time.Second)
+	trackMcpSession(newSessionResp(http.MethodPost, "busy", http.StatusOK, nil, `{}`), info)
+	now = now.Add(30 * time.Second)
+
+	if counts, _ := activeMcpSessions.counts(); counts["abc"] != 1 {
+		t.Errorf("active sessions = %v, want only the busy session", counts)
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// slowLogInspectSize request body bytes kept for the slow request log, the JSON-RPC method of
+// larger bodies is not logged
+const slowLogInspectSize = 64 << 10
+
+// slowRequestStats slow request counters, exposed through expvar at /debug/vars
+var slowRequestStats = expvar.NewMap("gateway_slow_requests")
+
+// slowRequestThreshold returns the duration after which a proxied request is logged as slow, 0 when disabled
+func slowRequestThreshold() time.Duration {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return activeLimits.slowRequestThreshold
+}
+
+// bodyPrefix keeps the first bytes of a request body while it is proxied, the slow request log
+// parses the JSON-RPC method from them only once the request turned out to be slow
+type bodyPrefix struct {
+	io.ReadCloser
+	mu    sync.Mutex
+	buf   []byte
+	limit int
+}
+
+func (b *bodyPrefix) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if room := b.limit - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(n, room)]...)
+	}
+	b.mu.Unlock()
+	return n, err
+}
+
+// rpcMethods returns the JSON-RPC methods of the body joined by ",", empty when the body was
+// larger than the kept prefix or is not JSON-RPC
+func (b *bodyPrefix) rpcMethods() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	calls, err := parsePolicyCalls(b.buf)
+	if err != nil {
+		return ""
+	}
+	methods := make([]string, 0, len(calls))
+	for _, call := range calls {
+		if call.Method != "" {
+			methods = append(methods, call.Method)
+		}
+	}
+	return strings.Join(methods, ",")
+}
+
+// trackSlowRequest starts timing a proxied request and returns the function that logs it once the
+// response has been written, when it took longer than proxyLimits.slowRequestThreshold. GET requests
+// open SSE streams that stay connected by design and are not tracked.
+func trackSlowRequest(req *http.Request) func() {
+	threshold := slowRequestThreshold()
+	if threshold <= 0 || req.Method == http.MethodGet {
+		return func() {}
+	}
+	var body *bodyPrefix
+	if req.Body != nil && req.Body != http.NoBody {
+		body = &bodyPrefix{ReadCloser: req.Body, limit: slowLogInspectSize}
+		req.Body = body
+	}
+
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		if duration < threshold {
+			return
+		}
+		slowRequestStats.Add("requests", 1)
+		instanceID := ""
+		if instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo); ok {
+			instanceID = instanceInfo.InstanceID
+		}
+		method := ""
+		if body != nil {
+			method = body.rpcMethods()
+		}
+		logger.FromContext(req.Context()).Warn("Slow proxy request",
+			zap.String("instance_id", instanceID),
+			zap.Duration("duration", duration),
+			zap.String("method", method),
+			zap.String("http_method", req.Method),
+			zap.String("path", req.URL.Path),
+		)
+	}
+}
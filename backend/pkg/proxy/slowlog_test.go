@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestBodyPrefixRPCMethods(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  string
+	}{
+		{name: "single request", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`, limit: 1024, want: "tools/call"},
+		{name: "batch", body: `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, limit: 1024, want: "tools/list,notifications/initialized"},
+		{name: "response without method", body: `{"jsonrpc":"2.0","id":1,"result":{}}`, limit: 1024, want: ""},
+		{name: "truncated body", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`, limit: 10, want: ""},
+		{name: "not json", body: `hello`, limit: 1024, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bodyPrefix{ReadCloser: io.NopCloser(strings.NewReader(tt.body)), limit: tt.limit}
+			read, err := io.ReadAll(body)
+			if err != nil || string(read) != tt.body {
+				t.Fatalf("ReadAll() = %q, %v, want the body unchanged", read, err)
+			}
+			if got := body.rpcMethods(); got != tt.want {
+				t.Errorf("rpcMethods() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackSlowRequest(t *testing.T) {
+	logger.Init("error", "json")
+
+	delay := 0 * time.Millisecond
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer upstream.Close()
+
+	targetConfig := fmt.Sprintf(`{"mcpServers":{"fetch":{"url":"%s/mcp"}}}`, upstream.URL)
+	instanceLookupMu.Lock()
+	previous := activeInstanceLookup
+	activeInstanceLookup = newInstanceLookup(common.InstanceFallbackConfig{},
+		func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+			return &model.McpInstance{
+				InstanceID:   instanceID,
+				Status:       model.InstanceStatusActive,
+				AccessType:   model.AccessTypeProxy,
+				McpProtocol:  model.McpProtocolStreamableHttp,
+				TargetConfig: []byte(targetConfig),
+			}, nil
+		})
+	instanceLookupMu.Unlock()
+	SetProxyLimits(common.ProxyLimitsConfig{SlowRequestThreshold: 50})
+	defer func() {
+		instanceLookupMu.Lock()
+		activeInstanceLookup = previous
+		instanceLookupMu.Unlock()
+		SetProxyLimits(common.ProxyLimitsConfig{})
+	}()
+
+	slowRequests := func() int64 {
+		if v, ok := slowRequestStats.Get("requests").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+
+	mrp := NewMCPReverseProxy()
+	send := func() {
+		body := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+		req := httptest.NewRequest(http.MethodPost, common.GetGatewayRoutePrefix()+"/abc/mcp", body)
+		rec := httptest.NewRecorder()
+		mrp.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	before := slowRequests()
+	send()
+	if got := slowRequests(); got != before {
+		t.Errorf("slow requests = %d after a fast request, want %d", got, before)
+	}
+
+	delay = 100 * time.Millisecond
+	send()
+	if got := slowRequests(); got != before+1 {
+		t.Errorf("slow requests = %d after a slow request, want %d", got, before+1)
+	}
+}
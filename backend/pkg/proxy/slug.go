@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/database/repository/mysql"
+
+	"gorm.io/gorm"
+)
+
+// slugMovedError the request used a former slug of an instance that still redirects to its current path
+type slugMovedError struct {
+	slug string
+	// pathKey current slug of the instance, its ID when the slug was removed
+	pathKey string
+}
+
+func (e *slugMovedError) Error() string {
+	return fmt.Sprintf("instance %s moved to %s", e.slug, e.pathKey)
+}
+
+// findInstance loads the instance addressed by the request path. Keys that are not UUIDs are slugs,
+// a former slug within its redirect period resolves to the instance that used it
+func findInstance(ctx context.Context, key string) (*model.McpInstance, error) {
+	if common.IsInstanceID(key) {
+		return mysql.McpInstanceRepo.FindByInstanceID(ctx, key)
+	}
+	instance, err := mysql.McpInstanceRepo.FindBySlug(ctx, key)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return instance, err
+	}
+	alias, aliasErr := mysql.McpInstanceSlugAliasRepo.FindActive(ctx, key, time.Now())
+	if errors.Is(aliasErr, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if aliasErr != nil {
+		return nil, aliasErr
+	}
+	return mysql.McpInstanceRepo.FindByInstanceID(ctx, alias.InstanceID)
+}
+
+// instancePathKey preferred instance segment of gateway paths, the slug when one is set
+func instancePathKey(instance *model.McpInstance) string {
+	if slug := instance.GetSlug(); slug != "" {
+		return slug
+	}
+	return instance.InstanceID
+}
+
+// movedLocation public path of the request with the instance segment replaced by pathKey,
+// parts is the request path split by "/" with the instance segment at index 2
+func movedLocation(req *http.Request, parts []string, pathKey string) string {
+	segments := append([]string{strings.TrimRight(requestPublicAccess(req).prefix, "/"), pathKey}, parts[3:]...)
+	location := strings.Join(segments, "/")
+	if req.URL.RawQuery != "" {
+		location += "?" + req.URL.RawQuery
+	}
+	return location
+}
+
+// ForgetInstanceSlug drops the instance cached under a slug that was changed, removed or taken over by
+// another instance, so that the stale fallback never serves it for the wrong instance
+func ForgetInstanceSlug(slug string) {
+	getInstanceLookup().forget(slug)
+}
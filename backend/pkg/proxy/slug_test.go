@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+func TestSlugRouting(t *testing.T) {
+	logger.Init("error", "json")
+
+	var upstreamPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer upstream.Close()
+
+	const instanceID = "550e8400-e29b-41d4-a716-446655440000"
+	slug := "github"
+	instance := &model.McpInstance{
+		InstanceID:   instanceID,
+		Slug:         &slug,
+		Status:       model.InstanceStatusActive,
+		AccessType:   model.AccessTypeProxy,
+		McpProtocol:  model.McpProtocolStreamableHttp,
+		TargetConfig: []byte(fmt.Sprintf(`{"mcpServers":{"github":{"url":"%s/mcp"}}}`, upstream.URL)),
+	}
+	instanceLookupMu.Lock()
+	previous := activeInstanceLookup
+	activeInstanceLookup = newInstanceLookup(common.InstanceFallbackConfig{},
+		func(ctx context.Context, key string) (*model.McpInstance, error) {
+			// gh 是修改前的旧路径
+			if key == instanceID || key == "github" || key == "gh" {
+				return instance, nil
+			}
+			return nil, fmt.Errorf("instance not found: %s: %w", key, gorm.ErrRecordNotFound)
+		})
+	instanceLookupMu.Unlock()
+	defer func() {
+		instanceLookupMu.Lock()
+		activeInstanceLookup = previous
+		instanceLookupMu.Unlock()
+	}()
+
+	mrp := NewMCPReverseProxy()
+	prefix := common.GetGatewayRoutePrefix()
+	for _, key := range []string{instanceID, "github"} {
+		rec := httptest.NewRecorder()
+		mrp.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, prefix+"/"+key+"/mcp", nil))
+		if rec.Code != http.StatusOK || upstreamPath != "/mcp" {
+			t.Errorf("request by %s = status %d, upstream path %q, want 200 and /mcp", key, rec.Code, upstreamPath)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mrp.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, prefix+"/gh/mcp?x=1", nil))
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("request by former slug status = %d, want 308", rec.Code)
+	}
+	if want := common.GetPublicPathPrefix() + "/github/mcp?x=1"; rec.Header().Get("Location") != want {
+		t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), want)
+	}
+
+	rec = httptest.NewRecorder()
+	mrp.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, prefix+"/unknown/mcp", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("request by unknown slug status = %d, want 404", rec.Code)
+	}
+}
+
+// SSE 端点改写使用客户端连接时的路径形式
+func TestSSEEndpointRewriteKeepsSlug(t *testing.T) {
+	if err := logger.Init("error", "json"); err != nil {
+		t.Fatal(err)
+	}
+	reader := &SSEResponseBodyReader{
+		src:  strings.NewReader("event: endpoint\ndata: /messages/?session_id=1\n\n"),
+		info: &InstanceInfo{InstanceID: "550e8400-e29b-41d4-a716-446655440000", PathKey: "github"},
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "data: " + getPublicProxyPrefix(nil, "github", "") + "/messages/?session_id=1"
+	if !strings.Contains(string(got), want) {
+		t.Errorf("rewritten event = %q, want it to contain %q", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pendingRequestTimeouts request timeout contexts whose cancel has not run yet, each one holds a timer
+var pendingRequestTimeouts atomic.Int64
+
+// requestTimeout returns the timeout applied to a proxied request, 0 means no timeout.
+// SSE streams only time out when the instance sets sseReadTimeout, other requests use the
+// instance timeout or the gateway read timeout.
+func requestTimeout(instanceInfo *InstanceInfo, isSSEReq bool) time.Duration {
+	if isSSEReq {
+		if instanceInfo.McpConfig.SseReadTimeout > 0 {
+			return time.Duration(instanceInfo.McpConfig.SseReadTimeout) * time.Second
+		}
+		return 0
+	}
+	if instanceInfo.McpConfig.Timeout > 0 {
+		return time.Duration(instanceInfo.McpConfig.Timeout) * time.Second
+	}
+	return limitsFor(instanceInfo).readTimeout
+}
+
+// withRequestTimeout derives the request context with the timeout of its instance,
+// the returned cancel must be called once the response has been written
+func withRequestTimeout(req *http.Request) (*http.Request, context.CancelFunc) {
+	instanceInfo, ok := req.Context().Value(InstanceInfoKey).(*InstanceInfo)
+	if !ok || instanceInfo == nil || instanceInfo.McpConfig == nil {
+		return req, func() {}
+	}
+	isSSEReq, _ := req.Context().Value(IsSSEReqKey).(bool)
+	timeout := requestTimeout(instanceInfo, isSSEReq)
+	if timeout <= 0 {
+		return req, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	pendingRequestTimeouts.Add(1)
+	var once sync.Once
+	return req.WithContext(ctx), func() {
+		once.Do(func() {
+			cancel()
+			pendingRequestTimeouts.Add(-1)
+		})
+	}
+}
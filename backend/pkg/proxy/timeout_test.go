@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+	"qm-mcp-server/pkg/logger"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		timeout        int
+		sseReadTimeout int
+		isSSEReq       bool
+		want           time.Duration
+	}{
+		{name: "sse without read timeout", isSSEReq: true, want: 0},
+		{name: "sse with read timeout", sseReadTimeout: 300, isSSEReq: true, want: 300 * time.Second},
+		{name: "sse ignores request timeout", timeout: 10, isSSEReq: true, want: 0},
+		{name: "request with instance timeout", timeout: 10, want: 10 * time.Second},
+		{name: "request ignores sse read timeout", sseReadTimeout: 300, want: DefaultReadTimeout},
+		{name: "request without timeout", want: DefaultReadTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &InstanceInfo{McpConfig: &model.McpConfig{Timeout: tt.timeout, SseReadTimeout: tt.sseReadTimeout}}
+			if got := requestTimeout(info, tt.isSSEReq); got != tt.want {
+				t.Errorf("requestTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 每个请求的超时定时器在响应结束后释放，不随请求数累积
+func TestRequestTimeoutsReleased(t *testing.T) {
+	logger.Init("error", "json")
+
+	var inFlight int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight = pendingRequestTimeouts.Load()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	}))
+	defer upstream.Close()
+
+	targetConfig := fmt.Sprintf(`{"mcpServers":{"fetch":{"url":"%s/mcp","timeout":60}}}`, upstream.URL)
+	instanceLookupMu.Lock()
+	previous := activeInstanceLookup
+	activeInstanceLookup = newInstanceLookup(common.InstanceFallbackConfig{},
+		func(ctx context.Context, instanceID string) (*model.McpInstance, error) {
+			return &model.McpInstance{
+				InstanceID:   instanceID,
+				Status:       model.InstanceStatusActive,
+				AccessType:   model.AccessTypeProxy,
+				McpProtocol:  model.McpProtocolStreamableHttp,
+				TargetConfig: []byte(targetConfig),
+			}, nil
+		})
+	instanceLookupMu.Unlock()
+	defer func() {
+		instanceLookupMu.Lock()
+		activeInstanceLookup = previous
+		instanceLookupMu.Unlock()
+	}()
+
+	mrp := NewMCPReverseProxy()
+	before := pendingRequestTimeouts.Load()
+	const requests = 200
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest(http.MethodPost, common.GetGatewayRoutePrefix()+"/abc/mcp", nil)
+		rec := httptest.NewRecorder()
+		mrp.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, body %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	if inFlight != before+1 {
+		t.Errorf("pending request timeouts while proxying = %d, want %d", inFlight, before+1)
+	}
+	if after := pendingRequestTimeouts.Load(); after != before {
+		t.Errorf("pending request timeouts = %d after %d requests, want %d", after, requests, before)
+	}
+}
@@ -0,0 +1,260 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+var (
+	// errInvalidTLSConfig the tls section of the instance's target config cannot be loaded
+	errInvalidTLSConfig = errors.New("invalid upstream TLS config")
+	// errInvalidProxyConfig the proxy section of the instance's target config is not a valid proxy URL
+	errInvalidProxyConfig = errors.New("invalid upstream proxy config")
+)
+
+// upstreamTransports HTTP transports used to reach upstreams. Instances without their own TLS
+// or proxy config share the base transport, the others get one transport per distinct config so
+// that connections are pooled and reused between requests.
+type upstreamTransports struct {
+	insecureSkipVerify bool
+	// rootCAs system roots and the gateway CA bundle, nil when no CA bundle is configured
+	rootCAs    *x509.CertPool
+	clientCert *tls.Certificate
+	proxy      common.OutboundProxyConfig
+	base       *http.Transport
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+var (
+	transportsMu     sync.RWMutex
+	activeTransports = &upstreamTransports{
+		base:       newUpstreamTransport(nil, common.OutboundProxyConfig{}),
+		transports: make(map[string]*http.Transport),
+	}
+)
+
+func newUpstreamTransport(tlsConfig *tls.Config, proxy common.OutboundProxyConfig) *http.Transport {
+	return &http.Transport{
+		Proxy:             proxyFunc(proxy),
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: tlsConfig != nil,
+	}
+}
+
+// proxyFunc resolves the proxy of each upstream request, the environment of the gateway is ignored
+func proxyFunc(proxy common.OutboundProxyConfig) func(*http.Request) (*url.URL, error) {
+	if proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
+		return http.ProxyURL(nil)
+	}
+	resolve := (&httpproxy.Config{
+		HTTPProxy:  proxy.HTTPProxy,
+		HTTPSProxy: proxy.HTTPSProxy,
+		NoProxy:    proxy.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return resolve(req.URL)
+	}
+}
+
+// SetUpstreamTransport loads the gateway TLS and outbound proxy defaults for upstream connections,
+// transports built with the previous defaults are closed once their idle connections are released
+func SetUpstreamTransport(tlsCfg common.UpstreamTLSConfig, proxy common.OutboundProxyConfig) error {
+	t := &upstreamTransports{
+		insecureSkipVerify: tlsCfg.InsecureSkipVerify,
+		proxy:              proxy,
+		transports:         make(map[string]*http.Transport),
+	}
+	if tlsCfg.CAFile != "" {
+		pem, err := os.ReadFile(tlsCfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read upstream CA file: %w", err)
+		}
+		t.rootCAs = systemCertPool()
+		if !t.rootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("upstream CA file %s contains no PEM certificates", tlsCfg.CAFile)
+		}
+	}
+	if tlsCfg.ClientCertFile != "" || tlsCfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCfg.ClientCertFile, tlsCfg.ClientKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load upstream client certificate: %w", err)
+		}
+		t.clientCert = &cert
+	}
+	for _, proxyURL := range []string{proxy.HTTPProxy, proxy.HTTPSProxy} {
+		if proxyURL == "" {
+			continue
+		}
+		if err := model.ValidateProxyURL(proxyURL); err != nil {
+			return err
+		}
+	}
+	var baseTLS *tls.Config
+	if t.insecureSkipVerify || t.rootCAs != nil || t.clientCert != nil {
+		baseTLS = t.tlsConfig(nil, nil, nil)
+	}
+	t.base = newUpstreamTransport(baseTLS, proxy)
+
+	transportsMu.Lock()
+	previous := activeTransports
+	activeTransports = t
+	transportsMu.Unlock()
+	previous.closeIdleConnections()
+	return nil
+}
+
+func getTransports() *upstreamTransports {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+	return activeTransports
+}
+
+// upstreamTransport returns the transport for the TLS and proxy config of the target server,
+// servers without either use the gateway defaults
+func upstreamTransport(targetConfig *model.McpConfig) (http.RoundTripper, error) {
+	return getTransports().get(targetConfig.TLS, targetConfig.Proxy)
+}
+
+func (t *upstreamTransports) get(tlsCfg *model.McpTLSConfig, proxyCfg *model.McpProxyConfig) (http.RoundTripper, error) {
+	if tlsCfg != nil && *tlsCfg == (model.McpTLSConfig{}) {
+		tlsCfg = nil
+	}
+	if proxyCfg != nil && *proxyCfg == (model.McpProxyConfig{}) {
+		proxyCfg = nil
+	}
+	if tlsCfg == nil && proxyCfg == nil {
+		return t.base, nil
+	}
+	key := transportKey(tlsCfg, proxyCfg)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.transports[key]; ok {
+		return transport, nil
+	}
+
+	tlsConfig := t.base.TLSClientConfig
+	if tlsCfg != nil {
+		var rootCAs *x509.CertPool
+		if tlsCfg.CACert != "" {
+			if t.rootCAs != nil {
+				rootCAs = t.rootCAs.Clone()
+			} else {
+				rootCAs = systemCertPool()
+			}
+			if !rootCAs.AppendCertsFromPEM([]byte(tlsCfg.CACert)) {
+				return nil, fmt.Errorf("%w: caCert contains no PEM certificates", errInvalidTLSConfig)
+			}
+		}
+		var clientCert *tls.Certificate
+		if tlsCfg.ClientCert != "" || tlsCfg.ClientKey != "" {
+			cert, err := tls.X509KeyPair([]byte(tlsCfg.ClientCert), []byte(tlsCfg.ClientKey))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errInvalidTLSConfig, err)
+			}
+			clientCert = &cert
+		}
+		tlsConfig = t.tlsConfig(tlsCfg, rootCAs, clientCert)
+	}
+
+	proxy, err := mergeProxy(t.proxy, proxyCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := newUpstreamTransport(tlsConfig, proxy)
+	t.transports[key] = transport
+	return transport, nil
+}
+
+// transportKey identifies the transport of a TLS and proxy config
+func transportKey(tlsCfg *model.McpTLSConfig, proxyCfg *model.McpProxyConfig) string {
+	var tlsPart model.McpTLSConfig
+	if tlsCfg != nil {
+		tlsPart = *tlsCfg
+	}
+	var proxyPart model.McpProxyConfig
+	if proxyCfg != nil {
+		proxyPart = *proxyCfg
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%t\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s",
+		tlsPart.InsecureSkipVerify, tlsPart.CACert, tlsPart.ClientCert, tlsPart.ClientKey,
+		proxyPart.HTTPProxy, proxyPart.HTTPSProxy, proxyPart.NoProxy))
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeProxy overrides the gateway proxy with the fields set by the instance
+func mergeProxy(proxy common.OutboundProxyConfig, cfg *model.McpProxyConfig) (common.OutboundProxyConfig, error) {
+	if cfg == nil {
+		return proxy, nil
+	}
+	for _, proxyURL := range []string{cfg.HTTPProxy, cfg.HTTPSProxy} {
+		if proxyURL == "" {
+			continue
+		}
+		if err := model.ValidateProxyURL(proxyURL); err != nil {
+			return proxy, fmt.Errorf("%w: %v", errInvalidProxyConfig, err)
+		}
+	}
+	if cfg.HTTPProxy != "" {
+		proxy.HTTPProxy = cfg.HTTPProxy
+	}
+	if cfg.HTTPSProxy != "" {
+		proxy.HTTPSProxy = cfg.HTTPSProxy
+	}
+	if cfg.NoProxy != "" {
+		proxy.NoProxy = cfg.NoProxy
+	}
+	return proxy, nil
+}
+
+// tlsConfig merges the instance TLS settings into the gateway defaults
+func (t *upstreamTransports) tlsConfig(cfg *model.McpTLSConfig, rootCAs *x509.CertPool, clientCert *tls.Certificate) *tls.Config {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.insecureSkipVerify || (cfg != nil && cfg.InsecureSkipVerify),
+		RootCAs:            t.rootCAs,
+	}
+	if rootCAs != nil {
+		tlsConfig.RootCAs = rootCAs
+	}
+	if clientCert == nil {
+		clientCert = t.clientCert
+	}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+	return tlsConfig
+}
+
+func (t *upstreamTransports) closeIdleConnections() {
+	t.base.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, transport := range t.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// systemCertPool returns a copy of the system roots, or an empty pool when they cannot be loaded
+func systemCertPool() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		return x509.NewCertPool()
+	}
+	return pool
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qm-mcp-server/pkg/common"
+	"qm-mcp-server/pkg/database/model"
+)
+
+func TestUpstreamTransportTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	roundTrip := func(cfg *model.McpTLSConfig) error {
+		transport, err := upstreamTransport(&model.McpConfig{TLS: cfg})
+		if err != nil {
+			return err
+		}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := roundTrip(nil); err == nil {
+		t.Errorf("round trip to a server signed by an unknown CA succeeded without TLS config")
+	}
+	if err := roundTrip(&model.McpTLSConfig{CACert: caCert}); err != nil {
+		t.Errorf("round trip with instance CA failed: %v", err)
+	}
+	if err := roundTrip(&model.McpTLSConfig{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("round trip with insecureSkipVerify failed: %v", err)
+	}
+	if _, err := upstreamTransport(&model.McpConfig{TLS: &model.McpTLSConfig{CACert: "not a pem"}}); !errors.Is(err, errInvalidTLSConfig) {
+		t.Errorf("upstreamTransport() with invalid CA err = %v, want errInvalidTLSConfig", err)
+	}
+
+	// 相同的 TLS 配置复用同一个 transport
+	first, _ := upstreamTransport(&model.McpConfig{TLS: &model.McpTLSConfig{CACert: caCert}})
+	second, _ := upstreamTransport(&model.McpConfig{TLS: &model.McpTLSConfig{CACert: caCert}})
+	if first != second {
+		t.Errorf("transports of identical TLS configs are not reused")
+	}
+	if base, _ := upstreamTransport(&model.McpConfig{TLS: &model.McpTLSConfig{}}); base != getTransports().base {
+		t.Errorf("empty TLS config does not use the base transport")
+	}
+}
+
+func TestUpstreamTransportProxy(t *testing.T) {
+	var proxied []string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxyServer.Close()
+
+	if err := SetUpstreamTransport(common.UpstreamTLSConfig{}, common.OutboundProxyConfig{HTTPProxy: proxyServer.URL, NoProxy: "internal.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetUpstreamTransport(common.UpstreamTLSConfig{}, common.OutboundProxyConfig{})
+
+	resolve := func(cfg *model.McpProxyConfig, target string) string {
+		transport, err := upstreamTransport(&model.McpConfig{Proxy: cfg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		proxyURL, err := transport.(*http.Transport).Proxy(req)
+		if err != nil || proxyURL == nil {
+			return ""
+		}
+		return proxyURL.String()
+	}
+
+	if got := resolve(nil, "http://saas.example.com/mcp"); got != proxyServer.URL {
+		t.Errorf("gateway proxy = %q, want %q", got, proxyServer.URL)
+	}
+	if got := resolve(nil, "http://internal.example.com/mcp"); got != "" {
+		t.Errorf("noProxy host proxied through %q", got)
+	}
+	if got := resolve(&model.McpProxyConfig{HTTPProxy: "http://instance-proxy:3128"}, "http://saas.example.com/mcp"); got != "http://instance-proxy:3128" {
+		t.Errorf("instance proxy = %q, want http://instance-proxy:3128", got)
+	}
+	if _, err := upstreamTransport(&model.McpConfig{Proxy: &model.McpProxyConfig{HTTPProxy: "ftp://proxy"}}); !errors.Is(err, errInvalidProxyConfig) {
+		t.Errorf("upstreamTransport() with invalid proxy err = %v, want errInvalidProxyConfig", err)
+	}
+
+	// 请求实际经过网关代理转发
+	transport, _ := upstreamTransport(&model.McpConfig{})
+	req, _ := http.NewRequest(http.MethodGet, "http://saas.example.com/mcp", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://saas.example.com/mcp" {
+		t.Errorf("proxied requests = %v, want the upstream URL", proxied)
+	}
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"qm-mcp-server/pkg/common"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// CircuitBreakerPrefix 网关熔断状态前缀
+	CircuitBreakerPrefix = "gateway_circuit_breaker:"
+	// CircuitBreakerResetChannel 熔断重置通知频道，网关订阅后重置内存中的熔断器
+	CircuitBreakerResetChannel = "gateway_circuit_breaker_reset"
+	// DefaultCircuitBreakerStateTTL 熔断状态过期时间，避免网关异常退出后残留
+	DefaultCircuitBreakerStateTTL = 24 * time.Hour
+)
+
+// CircuitBreakerStore 基于 Redis 的熔断状态存储，供 market 查询实例的熔断状态
+type CircuitBreakerStore struct{}
+
+// Save 保存实例的熔断状态，多个网关副本时以最后一次状态变化为准
+func (CircuitBreakerStore) Save(instanceID string, state *common.CircuitBreakerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal circuit breaker state: %v", err)
+	}
+	return Set(CircuitBreakerPrefix+instanceID, data, DefaultCircuitBreakerStateTTL)
+}
+
+// Delete 删除实例的熔断状态，熔断器恢复关闭时调用
+func (CircuitBreakerStore) Delete(instanceID string) error {
+	return Del(CircuitBreakerPrefix + instanceID)
+}
+
+// GetCircuitBreakerState 获取实例的熔断状态，未记录时返回 nil
+func GetCircuitBreakerState(instanceID string) (*common.CircuitBreakerState, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("redis client not initialized")
+	}
+
+	data, err := client.client.Get(context.Background(), CircuitBreakerPrefix+instanceID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get circuit breaker state: %v", err)
+	}
+
+	var state common.CircuitBreakerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal circuit breaker state: %v", err)
+	}
+	return &state, nil
+}
+
+// ResetCircuitBreaker 删除实例的熔断状态并通知所有网关重置熔断器
+func ResetCircuitBreaker(instanceID string) error {
+	if err := Del(CircuitBreakerPrefix + instanceID); err != nil {
+		return fmt.Errorf("failed to delete circuit breaker state: %v", err)
+	}
+	if err := Publish(CircuitBreakerResetChannel, instanceID); err != nil {
+		return fmt.Errorf("failed to publish circuit breaker reset: %v", err)
+	}
+	return nil
+}
+
+// SubscribeCircuitBreakerReset 订阅熔断重置通知，阻塞直到 ctx 取消
+func SubscribeCircuitBreakerReset(ctx context.Context, handler func(instanceID string)) error {
+	return Subscribe(ctx, CircuitBreakerResetChannel, handler)
+}
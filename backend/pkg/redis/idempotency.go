@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyPrefix 创建接口幂等记录前缀
+const IdempotencyPrefix = "idempotency:"
+
+// IdempotencyStore 基于 Redis 的幂等记录存储，多个 market 副本共享记录
+type IdempotencyStore struct{}
+
+// Claim 键不存在时写入 value，已存在时返回已有的值
+func (IdempotencyStore) Claim(key string, value []byte, ttl time.Duration) ([]byte, bool, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, false, fmt.Errorf("redis client not initialized")
+	}
+
+	ctx := context.Background()
+	claimed, err := client.client.SetNX(ctx, IdempotencyPrefix+key, value, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %v", err)
+	}
+	if claimed {
+		return nil, true, nil
+	}
+
+	existing, err := client.client.Get(ctx, IdempotencyPrefix+key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get idempotency record: %v", err)
+	}
+	return existing, false, nil
+}
+
+// Set 保存幂等记录
+func (IdempotencyStore) Set(key string, value []byte, ttl time.Duration) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	if err := client.client.Set(context.Background(), IdempotencyPrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set idempotency record: %v", err)
+	}
+	return nil
+}
+
+// Delete 删除幂等记录
+func (IdempotencyStore) Delete(key string) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	if err := client.client.Del(context.Background(), IdempotencyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to delete idempotency record: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package redis
+
+import "context"
+
+const (
+	// InstanceSlugChannel 实例自定义访问路径变化通知频道，网关订阅后丢弃按该路径缓存的实例
+	InstanceSlugChannel = "gateway_instance_slug_change"
+)
+
+// PublishInstanceSlugChange 通知所有网关实例的自定义访问路径已被修改、清除或重新使用
+func PublishInstanceSlugChange(slug string) error {
+	return Publish(InstanceSlugChannel, slug)
+}
+
+// SubscribeInstanceSlugChange 订阅自定义访问路径变化通知，阻塞直到 ctx 取消
+func SubscribeInstanceSlugChange(ctx context.Context, handler func(slug string)) error {
+	return Subscribe(ctx, InstanceSlugChannel, handler)
+}
@@ -0,0 +1,188 @@
+package redis
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DefaultLeaderLockTTL 后台任务锁的默认过期时间，持有者每 1/3 过期时间续期一次
+const DefaultLeaderLockTTL = 30 * time.Second
+
+// jobLockStats 各后台任务锁的持有者、fencing token 和本副本是否持有，通过 expvar 在 /debug/vars 暴露
+var jobLockStats = expvar.NewMap("job_locks")
+
+// LeaderElector 多个副本竞争同一个锁，只有持有锁的副本执行后台任务
+type LeaderElector struct {
+	name  string
+	owner string
+	ttl   time.Duration
+	stats *expvar.Map
+
+	mu     sync.Mutex
+	lock   *Lock
+	ctx    context.Context
+	cancel context.CancelFunc
+	// changed 获得或失去锁时关闭并替换，用于等待状态变化
+	changed chan struct{}
+}
+
+// NewLeaderElector 创建后台任务的选举器，owner 为当前副本标识，需调用 Run 开始竞争
+func NewLeaderElector(name, owner string, ttl time.Duration) *LeaderElector {
+	if ttl <= 0 {
+		ttl = DefaultLeaderLockTTL
+	}
+	stats := new(expvar.Map).Init()
+	jobLockStats.Set(name, stats)
+	return &LeaderElector{
+		name:    name,
+		owner:   owner,
+		ttl:     ttl,
+		stats:   stats,
+		changed: make(chan struct{}),
+	}
+}
+
+// Run 定期获取或续期锁，阻塞直到 ctx 取消，退出时释放持有的锁
+func (e *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.tick(ctx)
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) tick(ctx context.Context) {
+	e.mu.Lock()
+	lock := e.lock
+	e.mu.Unlock()
+
+	if lock != nil {
+		// 续期失败时立即放弃锁，此时其他副本可能已获得锁，正在执行的批次需要中止
+		if err := lock.Renew(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("Lost job lock, aborting current run",
+				zap.String("lock", e.name), zap.Int64("token", lock.Token()), zap.Error(err))
+			e.setLock(nil)
+		}
+	} else {
+		lock, err := AcquireLock(ctx, e.name, e.owner, e.ttl)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("Failed to acquire job lock", zap.String("lock", e.name), zap.Error(err))
+			}
+		} else if lock != nil {
+			logger.Info("Acquired job lock", zap.String("lock", e.name), zap.Int64("token", lock.Token()))
+			e.setLock(lock)
+		}
+	}
+
+	holder, token, err := LockHolder(ctx, e.name)
+	if err == nil {
+		e.stats.Set("holder", expvarString(holder))
+		e.stats.Set("token", expvarInt(token))
+	}
+}
+
+// setLock 切换持有状态，失去锁时取消正在执行任务的上下文
+func (e *LeaderElector) setLock(lock *Lock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cancel != nil {
+		e.cancel()
+		e.ctx, e.cancel = nil, nil
+	}
+	e.lock = lock
+	if lock != nil {
+		e.ctx, e.cancel = context.WithCancel(WithLock(context.Background(), lock))
+	}
+	close(e.changed)
+	e.changed = make(chan struct{})
+	e.stats.Set("leader", expvarBool(lock != nil))
+}
+
+func (e *LeaderElector) release() {
+	e.mu.Lock()
+	lock := e.lock
+	e.mu.Unlock()
+	if lock == nil {
+		return
+	}
+	e.setLock(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := lock.Release(ctx); err != nil {
+		logger.Warn("Failed to release job lock", zap.String("lock", e.name), zap.Error(err))
+	}
+}
+
+// LeaderContext 当前副本持有锁时返回派生自 parent 的上下文，失去锁时该上下文被取消；
+// 未持有锁时返回 false，调用方应跳过本次执行
+func (e *LeaderElector) LeaderContext(parent context.Context) (context.Context, context.CancelFunc, bool) {
+	e.mu.Lock()
+	lock, leaderCtx := e.lock, e.ctx
+	e.mu.Unlock()
+	if lock == nil {
+		return nil, nil, false
+	}
+
+	ctx, cancel := context.WithCancel(WithLock(parent, lock))
+	stop := context.AfterFunc(leaderCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}, true
+}
+
+// RunWhileLeader 持续运行的任务只在持有锁期间运行，失去锁时取消 fn 的上下文，重新获得后再次启动，
+// 阻塞直到 ctx 取消
+func (e *LeaderElector) RunWhileLeader(ctx context.Context, fn func(ctx context.Context)) {
+	for {
+		e.mu.Lock()
+		changed := e.changed
+		e.mu.Unlock()
+
+		if leaderCtx, cancel, ok := e.LeaderContext(ctx); ok {
+			fn(leaderCtx)
+			cancel()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+func expvarString(s string) *expvar.String {
+	v := new(expvar.String)
+	v.Set(s)
+	return v
+}
+
+func expvarInt(i int64) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(i)
+	return v
+}
+
+func expvarBool(b bool) *expvar.Int {
+	if b {
+		return expvarInt(1)
+	}
+	return expvarInt(0)
+}
@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// lockKeyPrefix 分布式锁的键，值为 "{持有者}|{fencing token}"
+	lockKeyPrefix = "lock:"
+	// lockFenceKeySuffix 每个锁单调递增的 fencing token 计数器
+	lockFenceKeySuffix = ":fence"
+)
+
+// ErrLockLost 锁已过期或被其他副本持有，持有者应停止写入
+var ErrLockLost = errors.New("distributed lock lost")
+
+// acquireLockScript SET NX PX 成功时分配新的 fencing token 并写入锁的值
+var acquireLockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  local token = redis.call('INCR', KEYS[2])
+  redis.call('SET', KEYS[1], ARGV[1] .. '|' .. token, 'PX', ARGV[2])
+  return token
+end
+return 0
+`)
+
+// renewLockScript 仍由自己持有时延长过期时间
+var renewLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript 仍由自己持有时删除锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// lockStore 锁的读写操作，比较和写入需要原子完成
+type lockStore interface {
+	// acquire 锁未被持有时写入 owner 并分配新的 fencing token，已被持有时返回 0
+	acquire(ctx context.Context, key, fenceKey, owner string, ttl time.Duration) (int64, error)
+	// renew 锁的值仍为 value 时延长过期时间
+	renew(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// release 锁的值仍为 value 时删除锁
+	release(ctx context.Context, key, value string) error
+	// get 读取锁的值，未被持有时返回空字符串
+	get(ctx context.Context, key string) (string, error)
+}
+
+// activeLockStore 当前使用的锁存储，测试时替换为内存实现
+var activeLockStore lockStore = redisLockStore{}
+
+// redisLockStore 基于 Redis 脚本的锁存储
+type redisLockStore struct{}
+
+func (redisLockStore) acquire(ctx context.Context, key, fenceKey, owner string, ttl time.Duration) (int64, error) {
+	client := GetClient()
+	if client == nil {
+		return 0, fmt.Errorf("redis client not initialized")
+	}
+	return acquireLockScript.Run(ctx, client.client, []string{key, fenceKey}, owner, ttl.Milliseconds()).Int64()
+}
+
+func (redisLockStore) renew(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	client := GetClient()
+	if client == nil {
+		return false, fmt.Errorf("redis client not initialized")
+	}
+	ok, err := renewLockScript.Run(ctx, client.client, []string{key}, value, ttl.Milliseconds()).Int64()
+	return ok != 0, err
+}
+
+func (redisLockStore) release(ctx context.Context, key, value string) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	return releaseLockScript.Run(ctx, client.client, []string{key}, value).Err()
+}
+
+func (redisLockStore) get(ctx context.Context, key string) (string, error) {
+	client := GetClient()
+	if client == nil {
+		return "", fmt.Errorf("redis client not initialized")
+	}
+	value, err := client.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+// Lock 已获取的分布式锁，过期前需要调用 Renew 续期
+type Lock struct {
+	name  string
+	owner string
+	token int64
+	ttl   time.Duration
+}
+
+// AcquireLock 尝试获取锁，锁被其他副本持有时返回 nil, nil
+func AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (*Lock, error) {
+	key := lockKeyPrefix + name
+	token, err := activeLockStore.acquire(ctx, key, key+lockFenceKeySuffix, owner, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %v", name, err)
+	}
+	if token == 0 {
+		return nil, nil
+	}
+	return &Lock{name: name, owner: owner, token: token, ttl: ttl}, nil
+}
+
+// Name 锁名称
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Token fencing token，每次获取锁时递增，用于识别过期持有者的写入
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+func (l *Lock) value() string {
+	return fmt.Sprintf("%s|%d", l.owner, l.token)
+}
+
+// Renew 延长锁的过期时间，锁已丢失时返回 ErrLockLost
+func (l *Lock) Renew(ctx context.Context) error {
+	ok, err := activeLockStore.renew(ctx, lockKeyPrefix+l.name, l.value(), l.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to renew lock %s: %v", l.name, err)
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Verify 检查锁仍由自己以相同 fencing token 持有，写入前调用
+func (l *Lock) Verify(ctx context.Context) error {
+	owner, token, err := LockHolder(ctx, l.name)
+	if err != nil {
+		return err
+	}
+	if owner != l.owner || token != l.token {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release 释放锁，锁已被其他副本持有时不做处理
+func (l *Lock) Release(ctx context.Context) error {
+	if err := activeLockStore.release(ctx, lockKeyPrefix+l.name, l.value()); err != nil {
+		return fmt.Errorf("failed to release lock %s: %v", l.name, err)
+	}
+	return nil
+}
+
+// LockHolder 查询锁的当前持有者和 fencing token，未被持有时返回空字符串
+func LockHolder(ctx context.Context, name string) (string, int64, error) {
+	value, err := activeLockStore.get(ctx, lockKeyPrefix+name)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get lock %s: %v", name, err)
+	}
+	if value == "" {
+		return "", 0, nil
+	}
+	idx := strings.LastIndex(value, "|")
+	if idx < 0 {
+		return value, 0, nil
+	}
+	token, _ := strconv.ParseInt(value[idx+1:], 10, 64)
+	return value[:idx], token, nil
+}
+
+type lockContextKey struct{}
+
+// WithLock 将持有的锁写入上下文，任务写入前通过 VerifyLock 检查
+func WithLock(ctx context.Context, lock *Lock) context.Context {
+	return context.WithValue(ctx, lockContextKey{}, lock)
+}
+
+// VerifyLock 检查上下文中的锁仍然有效，上下文没有锁 (单副本或未启用) 时返回 nil
+func VerifyLock(ctx context.Context) error {
+	lock, ok := ctx.Value(lockContextKey{}).(*Lock)
+	if !ok || lock == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return ErrLockLost
+	}
+	return lock.Verify(ctx)
+}
@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/logger"
+)
+
+// memoryLockStore in-memory lockStore for tests, keys do not expire
+type memoryLockStore struct {
+	mu     sync.Mutex
+	values map[string]string
+	fences map[string]int64
+}
+
+func (m *memoryLockStore) acquire(ctx context.Context, key, fenceKey, owner string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.values[key]; ok {
+		return 0, nil
+	}
+	m.fences[fenceKey]++
+	token := m.fences[fenceKey]
+	m.values[key] = owner + "|" + strconv.FormatInt(token, 10)
+	return token, nil
+}
+
+func (m *memoryLockStore) renew(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key] == value, nil
+}
+
+func (m *memoryLockStore) release(ctx context.Context, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[key] == value {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (m *memoryLockStore) get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+// expire drops a lock as if its TTL had passed
+func (m *memoryLockStore) expire(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, lockKeyPrefix+name)
+}
+
+func useMemoryLockStore(t *testing.T) *memoryLockStore {
+	t.Helper()
+	logger.Init("error", "json")
+	store := &memoryLockStore{values: map[string]string{}, fences: map[string]int64{}}
+	previous := activeLockStore
+	activeLockStore = store
+	t.Cleanup(func() { activeLockStore = previous })
+	return store
+}
+
+func TestLockContention(t *testing.T) {
+	useMemoryLockStore(t)
+	ctx := context.Background()
+
+	a, err := AcquireLock(ctx, "sync", "a", time.Minute)
+	if err != nil || a == nil || a.Token() != 1 {
+		t.Fatalf("AcquireLock(a) = %+v, %v, want token 1", a, err)
+	}
+	if b, err := AcquireLock(ctx, "sync", "b", time.Minute); err != nil || b != nil {
+		t.Fatalf("AcquireLock(b) while held = %+v, %v, want nil", b, err)
+	}
+
+	// 只有持有者能释放锁
+	impostor := &Lock{name: "sync", owner: "b", token: a.Token(), ttl: time.Minute}
+	if err := impostor.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if owner, token, _ := LockHolder(ctx, "sync"); owner != "a" || token != 1 {
+		t.Errorf("LockHolder() after a foreign release = %s %d, want a 1", owner, token)
+	}
+	if err := a.Renew(ctx); err != nil {
+		t.Errorf("Renew() by the holder error = %v", err)
+	}
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if owner, _, _ := LockHolder(ctx, "sync"); owner != "" {
+		t.Errorf("LockHolder() after release = %q, want empty", owner)
+	}
+
+	b, err := AcquireLock(ctx, "sync", "b", time.Minute)
+	if err != nil || b == nil || b.Token() != 2 {
+		t.Fatalf("AcquireLock(b) after release = %+v, %v, want token 2", b, err)
+	}
+	if err := a.Renew(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Renew() by the former holder = %v, want ErrLockLost", err)
+	}
+}
+
+func TestLockVerifyRejectsStaleToken(t *testing.T) {
+	store := useMemoryLockStore(t)
+	ctx := context.Background()
+
+	stale, _ := AcquireLock(ctx, "sync", "a", time.Minute)
+	if err := VerifyLock(WithLock(ctx, stale)); err != nil {
+		t.Fatalf("VerifyLock() while held = %v", err)
+	}
+	// 同一副本在锁过期后重新获得锁，旧的 fencing token 不再有效
+	store.expire("sync")
+	current, _ := AcquireLock(ctx, "sync", "a", time.Minute)
+	if current == nil || current.Token() == stale.Token() {
+		t.Fatalf("AcquireLock() after expiry = %+v, want a new token", current)
+	}
+	if err := stale.Verify(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Verify() with a stale token = %v, want ErrLockLost", err)
+	}
+	if err := VerifyLock(WithLock(ctx, current)); err != nil {
+		t.Errorf("VerifyLock() with the current token = %v", err)
+	}
+	if err := VerifyLock(ctx); err != nil {
+		t.Errorf("VerifyLock() without a lock = %v, want nil", err)
+	}
+}
+
+func TestLeaderContextCancelledOnLostLock(t *testing.T) {
+	store := useMemoryLockStore(t)
+	ctx := context.Background()
+	elector := NewLeaderElector(t.Name(), "a", time.Minute)
+	other := NewLeaderElector(t.Name(), "b", time.Minute)
+
+	elector.tick(ctx)
+	other.tick(ctx)
+	if _, _, ok := other.LeaderContext(ctx); ok {
+		t.Fatal("second replica became leader while the lock is held")
+	}
+	leaderCtx, cancel, ok := elector.LeaderContext(ctx)
+	if !ok {
+		t.Fatal("LeaderContext() = false after acquiring the lock")
+	}
+	defer cancel()
+
+	// 锁过期后被其他副本获得，续期失败时取消正在执行的任务
+	store.expire(t.Name())
+	other.tick(ctx)
+	elector.tick(ctx)
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader context not cancelled after the renewal failed")
+	}
+	if err := VerifyLock(leaderCtx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("VerifyLock() after losing the lock = %v, want ErrLockLost", err)
+	}
+	if _, _, ok := elector.LeaderContext(ctx); ok {
+		t.Error("LeaderContext() = true after losing the lock")
+	}
+	if _, _, ok := other.LeaderContext(ctx); !ok {
+		t.Error("LeaderContext() of the new holder = false")
+	}
+}
+
+func TestRunWhileLeaderRestarts(t *testing.T) {
+	store := useMemoryLockStore(t)
+	ctx, cancelRun := context.WithCancel(context.Background())
+	elector := NewLeaderElector(t.Name(), "a", time.Minute)
+	other := NewLeaderElector(t.Name(), "b", time.Minute)
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		elector.RunWhileLeader(ctx, func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+		})
+	}()
+	wait := func(ch chan struct{}, what string) {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the task to %s", what)
+		}
+	}
+
+	elector.tick(ctx)
+	wait(started, "start")
+
+	// 失去锁时停止任务
+	store.expire(t.Name())
+	other.tick(ctx)
+	elector.tick(ctx)
+	wait(stopped, "stop")
+
+	// 重新获得锁后再次启动
+	other.release()
+	elector.tick(ctx)
+	wait(started, "restart")
+
+	cancelRun()
+	wait(stopped, "stop on cancel")
+	wait(done, "return")
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"qm-mcp-server/pkg/common"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaintenanceKey 全局维护模式状态，多个 market 副本共享，关闭维护时删除
+const MaintenanceKey = "maintenance:global"
+
+// SetMaintenance 开启维护模式，已开启时覆盖维护说明和结束时间
+func SetMaintenance(state *common.MaintenanceState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance state: %v", err)
+	}
+	return Set(MaintenanceKey, data, 0)
+}
+
+// GetMaintenance 获取维护模式状态，未开启时返回 nil
+func GetMaintenance() (*common.MaintenanceState, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("redis client not initialized")
+	}
+
+	data, err := client.client.Get(context.Background(), MaintenanceKey).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get maintenance state: %v", err)
+	}
+
+	var state common.MaintenanceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal maintenance state: %v", err)
+	}
+	return &state, nil
+}
+
+// ClearMaintenance 关闭维护模式
+func ClearMaintenance() error {
+	return Del(MaintenanceKey)
+}
@@ -105,6 +105,31 @@ func (c *Client) SetWithExpiration(key string, value interface{}, seconds int) e
 	return c.Set(key, value, expiration)
 }
 
+// Publish 发布消息到频道
+func (c *Client) Publish(channel string, message interface{}) error {
+	ctx := context.Background()
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe 订阅频道，每条消息调用 handler，阻塞直到 ctx 取消
+func (c *Client) Subscribe(ctx context.Context, channel string, handler func(payload string)) error {
+	pubsub := c.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler(msg.Payload)
+		}
+	}
+}
+
 // 全局方法封装
 func Set(key string, value interface{}, expiration time.Duration) error {
 	if globalClient == nil {
@@ -154,3 +179,17 @@ func SetWithExpiration(key string, value interface{}, seconds int) error {
 	}
 	return globalClient.SetWithExpiration(key, value, seconds)
 }
+
+func Publish(channel string, message interface{}) error {
+	if globalClient == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	return globalClient.Publish(channel, message)
+}
+
+func Subscribe(ctx context.Context, channel string, handler func(payload string)) error {
+	if globalClient == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	return globalClient.Subscribe(ctx, channel, handler)
+}
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ResponseCachePrefix 网关响应缓存前缀
+	ResponseCachePrefix = "gateway_response_cache:"
+	// ResponseCacheKeysPrefix 实例下所有响应缓存键的集合前缀，用于实例编辑或重启时整体失效
+	ResponseCacheKeysPrefix = "gateway_response_cache_keys:"
+)
+
+// ResponseCacheStore 基于 Redis 的网关响应缓存存储，多个网关副本共享缓存
+type ResponseCacheStore struct{}
+
+// Get 获取缓存的响应，未命中时返回 false
+func (ResponseCacheStore) Get(instanceID, key string) ([]byte, bool, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, false, fmt.Errorf("redis client not initialized")
+	}
+
+	value, err := client.client.Get(context.Background(), responseCacheKey(instanceID, key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get response cache: %v", err)
+	}
+	return value, true, nil
+}
+
+// Set 保存响应并记录到实例的缓存键集合
+func (ResponseCacheStore) Set(instanceID, key string, value []byte, ttl time.Duration) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+
+	ctx := context.Background()
+	cacheKey := responseCacheKey(instanceID, key)
+	keysKey := ResponseCacheKeysPrefix + instanceID
+
+	pipe := client.client.TxPipeline()
+	pipe.Set(ctx, cacheKey, value, ttl)
+	pipe.SAdd(ctx, keysKey, cacheKey)
+	// 集合与缓存同时过期，避免长期残留
+	pipe.Expire(ctx, keysKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set response cache: %v", err)
+	}
+	return nil
+}
+
+// DeleteResponseCache 删除实例的所有网关响应缓存，实例编辑或重启后调用
+func DeleteResponseCache(instanceID string) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+
+	ctx := context.Background()
+	keysKey := ResponseCacheKeysPrefix + instanceID
+
+	cacheKeys, err := client.client.SMembers(ctx, keysKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get response cache keys: %v", err)
+	}
+	cacheKeys = append(cacheKeys, keysKey)
+	if err := client.client.Del(ctx, cacheKeys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete response cache: %v", err)
+	}
+	return nil
+}
+
+// responseCacheKey 响应缓存键
+func responseCacheKey(instanceID, key string) string {
+	return ResponseCachePrefix + instanceID + ":" + key
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// SSEConnectionsPrefix 网关 SSE 连接数前缀，每个实例一个 hash，字段为网关标识
+	SSEConnectionsPrefix = "gateway_sse_connections:"
+	// SSEDrainChannel 实例连接排空通知频道，网关订阅后关闭实例的所有 SSE 连接
+	SSEDrainChannel = "gateway_sse_drain"
+	// McpSessionsPrefix 网关 streamable HTTP 活跃会话数前缀，每个实例一个 hash，字段为网关标识
+	McpSessionsPrefix = "gateway_mcp_sessions:"
+	// SSEConnectionCountTTL 超过该时间未刷新的连接数视为失效，例如网关异常退出
+	SSEConnectionCountTTL = 30 * time.Second
+)
+
+// GatewayCount 单个网关上实例的 SSE 连接数或活跃会话数
+type GatewayCount struct {
+	Gateway   string `json:"gateway"`
+	Count     int    `json:"count"`
+	UpdatedAt int64  `json:"updatedAt"` // 毫秒时间戳
+}
+
+// SSEConnectionStore 基于 Redis 的网关 SSE 连接数和会话数存储，供 market 查询实例的连接数
+type SSEConnectionStore struct {
+	gateway string
+}
+
+// NewSSEConnectionStore 创建连接数存储，以主机名和进程号区分网关副本
+func NewSSEConnectionStore() SSEConnectionStore {
+	hostname, _ := os.Hostname()
+	return SSEConnectionStore{gateway: fmt.Sprintf("%s-%d", hostname, os.Getpid())}
+}
+
+// SaveConnectionCounts 保存本网关各实例的连接数，连接数为 0 时删除记录
+func (s SSEConnectionStore) SaveConnectionCounts(counts map[string]int) error {
+	if err := s.saveCounts(SSEConnectionsPrefix, counts); err != nil {
+		return fmt.Errorf("failed to save sse connection counts: %v", err)
+	}
+	return nil
+}
+
+// SaveSessionCounts 保存本网关各实例的 streamable HTTP 活跃会话数，会话数为 0 时删除记录
+func (s SSEConnectionStore) SaveSessionCounts(counts map[string]int) error {
+	if err := s.saveCounts(McpSessionsPrefix, counts); err != nil {
+		return fmt.Errorf("failed to save mcp session counts: %v", err)
+	}
+	return nil
+}
+
+// saveCounts 保存本网关各实例的计数，记录在 SSEConnectionCountTTL 后过期
+func (s SSEConnectionStore) saveCounts(prefix string, counts map[string]int) error {
+	client := GetClient()
+	if client == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	pipe := client.client.TxPipeline()
+	for instanceID, count := range counts {
+		key := prefix + instanceID
+		if count == 0 {
+			pipe.HDel(ctx, key, s.gateway)
+			continue
+		}
+		data, err := json.Marshal(&GatewayCount{Gateway: s.gateway, Count: count, UpdatedAt: now})
+		if err != nil {
+			return err
+		}
+		pipe.HSet(ctx, key, s.gateway, data)
+		pipe.Expire(ctx, key, SSEConnectionCountTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetSSEConnectionCounts 获取实例在各网关上的 SSE 连接数，忽略已失效的记录
+func GetSSEConnectionCounts(instanceID string) ([]*GatewayCount, error) {
+	counts, err := getGatewayCounts(SSEConnectionsPrefix + instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sse connection counts: %v", err)
+	}
+	return counts, nil
+}
+
+// GetMcpSessionCounts 获取实例在各网关上的 streamable HTTP 活跃会话数，忽略已失效的记录
+func GetMcpSessionCounts(instanceID string) ([]*GatewayCount, error) {
+	counts, err := getGatewayCounts(McpSessionsPrefix + instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mcp session counts: %v", err)
+	}
+	return counts, nil
+}
+
+// getGatewayCounts 读取各网关上报的计数，按网关标识排序
+func getGatewayCounts(key string) ([]*GatewayCount, error) {
+	client := GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("redis client not initialized")
+	}
+
+	entries, err := client.client.HGetAll(context.Background(), key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	staleBefore := time.Now().Add(-SSEConnectionCountTTL).UnixMilli()
+	counts := make([]*GatewayCount, 0, len(entries))
+	for _, data := range entries {
+		var count GatewayCount
+		if err := json.Unmarshal([]byte(data), &count); err != nil || count.UpdatedAt < staleBefore {
+			continue
+		}
+		counts = append(counts, &count)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Gateway < counts[j].Gateway })
+	return counts, nil
+}
+
+// DrainSSEConnections 通知所有网关关闭实例的 SSE 连接
+func DrainSSEConnections(instanceID string) error {
+	if err := Publish(SSEDrainChannel, instanceID); err != nil {
+		return fmt.Errorf("failed to publish sse drain: %v", err)
+	}
+	return nil
+}
+
+// SubscribeSSEDrain 订阅连接排空通知，阻塞直到 ctx 取消
+func SubscribeSSEDrain(ctx context.Context, handler func(instanceID string)) error {
+	return Subscribe(ctx, SSEDrainChannel, handler)
+}
@@ -0,0 +1,333 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRegistry Docker Hub registry host
+	DefaultRegistry = "registry-1.docker.io"
+	// DefaultTag tag used when the image reference has none
+	DefaultTag = "latest"
+)
+
+// manifestAccept manifest media types accepted by the check
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.v1+prettyjws",
+}, ", ")
+
+var (
+	// ErrManifestNotFound image repository or tag does not exist
+	ErrManifestNotFound = errors.New("image manifest not found")
+	// ErrUnauthorized registry rejected the credentials
+	ErrUnauthorized = errors.New("registry access denied")
+)
+
+// Reference parsed image reference
+type Reference struct {
+	Registry   string // registry host, e.g. registry-1.docker.io
+	Repository string // repository path, e.g. library/nginx
+	Reference  string // tag or digest
+}
+
+// String returns the reference in registry/repository:tag form
+func (r Reference) String() string {
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		return r.Registry + "/" + r.Repository + "@" + r.Reference
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Reference
+}
+
+// ParseReference parses an image address following Docker reference rules
+// nginx -> registry-1.docker.io/library/nginx:latest
+func ParseReference(image string) (Reference, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return Reference{}, fmt.Errorf("image is empty")
+	}
+
+	ref := Reference{Registry: DefaultRegistry}
+	name := image
+	if i := strings.Index(name, "/"); i > 0 {
+		host := name[:i]
+		// 首段包含 . 或 : 或为 localhost 时视为仓库地址
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			name = name[i+1:]
+		}
+	}
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = DefaultRegistry
+	}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Reference = name[i+1:]
+		name = name[:i]
+	} else if i := strings.LastIndex(name, ":"); i >= 0 {
+		ref.Reference = name[i+1:]
+		name = name[:i]
+	} else {
+		ref.Reference = DefaultTag
+	}
+	if name == "" || ref.Reference == "" {
+		return Reference{}, fmt.Errorf("invalid image reference: %s", image)
+	}
+	if ref.Registry == DefaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// Credential registry basic auth credential
+type Credential struct {
+	Username string
+	Password string
+}
+
+// dockerConfigJSON content of a kubernetes.io/dockerconfigjson secret
+type dockerConfigJSON struct {
+	Auths map[string]struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	} `json:"auths"`
+}
+
+// CredentialFromDockerConfigJSON finds the credential for registry in .dockerconfigjson content
+func CredentialFromDockerConfigJSON(data []byte, registry string) (*Credential, bool) {
+	var cfg dockerConfigJSON
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, false
+	}
+	for server, auth := range cfg.Auths {
+		if NormalizeRegistry(server) != NormalizeRegistry(registry) {
+			continue
+		}
+		if auth.Username != "" || auth.Password != "" {
+			return &Credential{Username: auth.Username, Password: auth.Password}, true
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			continue
+		}
+		if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+			return &Credential{Username: username, Password: password}, true
+		}
+	}
+	return nil, false
+}
+
+// DockerConfigJSON builds .dockerconfigjson content holding the credential for registry
+func DockerConfigJSON(registry string, cred Credential) ([]byte, error) {
+	server := NormalizeRegistry(registry)
+	if server == DefaultRegistry {
+		// kubelet 按 Docker Hub 的历史地址匹配凭证
+		server = "https://index.docker.io/v1/"
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(cred.Username + ":" + cred.Password))
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			server: map[string]string{
+				"username": cred.Username,
+				"password": cred.Password,
+				"auth":     auth,
+			},
+		},
+	})
+}
+
+// normalizeRegistry strips scheme and path from a registry server address
+func NormalizeRegistry(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	switch server {
+	case "docker.io", "index.docker.io":
+		return DefaultRegistry
+	}
+	return strings.ToLower(server)
+}
+
+// Checker checks image availability with the registry v2 API
+type Checker struct {
+	client *http.Client
+	// insecure registries queried over plain HTTP
+	insecure map[string]bool
+}
+
+// NewChecker creates checker, insecureRegistries are queried over plain HTTP
+func NewChecker(timeout time.Duration, insecureRegistries []string) *Checker {
+	insecure := make(map[string]bool, len(insecureRegistries))
+	for _, r := range insecureRegistries {
+		insecure[NormalizeRegistry(r)] = true
+	}
+	return &Checker{
+		client:   &http.Client{Timeout: timeout},
+		insecure: insecure,
+	}
+}
+
+// ManifestExists checks the manifest with a HEAD request, returns ErrManifestNotFound
+// when the repository or tag does not exist and ErrUnauthorized when access is denied
+func (c *Checker) ManifestExists(ctx context.Context, ref Reference, cred *Credential) error {
+	_, err := c.ResolveDigest(ctx, ref, cred)
+	return err
+}
+
+// ResolveDigest resolves the reference to the digest of its manifest with a HEAD request, errors are the
+// same as ManifestExists. The digest is empty when the registry does not return Docker-Content-Digest,
+// a digest reference resolves to itself in that case
+func (c *Checker) ResolveDigest(ctx context.Context, ref Reference, cred *Credential) (string, error) {
+	scheme := "https"
+	if c.insecure[ref.Registry] {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, ref.Registry, ref.Repository, ref.Reference)
+
+	resp, err := c.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref, cred)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = c.headManifest(ctx, manifestURL, authorization); err != nil {
+			return "", err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+			return digest, nil
+		}
+		if strings.HasPrefix(ref.Reference, "sha256:") {
+			return ref.Reference, nil
+		}
+		return "", nil
+	case http.StatusNotFound:
+		return "", ErrManifestNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// Docker Hub 对不存在的仓库同样返回 401
+		return "", ErrUnauthorized
+	default:
+		return "", fmt.Errorf("unexpected registry response status: %s", resp.Status)
+	}
+}
+
+// headManifest sends the manifest HEAD request
+func (c *Checker) headManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// authorize builds the Authorization header from the WWW-Authenticate challenge
+func (c *Checker) authorize(ctx context.Context, challenge string, ref Reference, cred *Credential) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if cred == nil {
+			return "", ErrUnauthorized
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password)), nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, params, ref, cred)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported registry auth challenge: %q", challenge)
+	}
+}
+
+// fetchToken requests a pull token from the registry token service
+func (c *Checker) fetchToken(ctx context.Context, params map[string]string, ref Reference, cred *Credential) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry auth challenge has no realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry auth realm: %w", err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if cred != nil {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header, e.g.
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return scheme, params
+}
@@ -0,0 +1,153 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/registry"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		want    registry.Reference
+		wantErr bool
+	}{
+		{"nginx", registry.Reference{Registry: registry.DefaultRegistry, Repository: "library/nginx", Reference: "latest"}, false},
+		{"nginx:1.27", registry.Reference{Registry: registry.DefaultRegistry, Repository: "library/nginx", Reference: "1.27"}, false},
+		{"docker.io/mcp/fetch:v1", registry.Reference{Registry: registry.DefaultRegistry, Repository: "mcp/fetch", Reference: "v1"}, false},
+		{"ccr.ccs.tencentyun.com/itqm-private/mcp-hosting", registry.Reference{Registry: "ccr.ccs.tencentyun.com", Repository: "itqm-private/mcp-hosting", Reference: "latest"}, false},
+		{"localhost:5000/app:dev", registry.Reference{Registry: "localhost:5000", Repository: "app", Reference: "dev"}, false},
+		{"ghcr.io/org/app@sha256:abc", registry.Reference{Registry: "ghcr.io", Repository: "org/app", Reference: "sha256:abc"}, false},
+		{"", registry.Reference{}, true},
+		{"nginx:", registry.Reference{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := registry.ParseReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference(%q) = %+v, want %+v", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialFromDockerConfigJSON(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("bob:secret"))
+	data := []byte(fmt.Sprintf(`{"auths":{"https://index.docker.io/v1/":{"auth":%q},"ghcr.io":{"username":"alice","password":"pw"}}}`, auth))
+
+	tests := []struct {
+		registry string
+		want     *registry.Credential
+	}{
+		{registry.DefaultRegistry, &registry.Credential{Username: "bob", Password: "secret"}},
+		{"ghcr.io", &registry.Credential{Username: "alice", Password: "pw"}},
+		{"quay.io", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			got, ok := registry.CredentialFromDockerConfigJSON(data, tt.registry)
+			if ok != (tt.want != nil) {
+				t.Fatalf("found = %v, want %v", ok, tt.want != nil)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("credential = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerConfigJSON(t *testing.T) {
+	cred := registry.Credential{Username: "bob", Password: "secret"}
+	for _, host := range []string{"docker.io", "ghcr.io"} {
+		t.Run(host, func(t *testing.T) {
+			data, err := registry.DockerConfigJSON(host, cred)
+			if err != nil {
+				t.Fatalf("DockerConfigJSON() error = %v", err)
+			}
+			got, ok := registry.CredentialFromDockerConfigJSON(data, host)
+			if !ok || *got != cred {
+				t.Errorf("CredentialFromDockerConfigJSON() = %+v, %v, want %+v", got, ok, cred)
+			}
+		})
+	}
+}
+
+func TestManifestExists(t *testing.T) {
+	const token = "pull-token"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if user, pass, ok := r.BasicAuth(); ok && (user != "bob" || pass != "secret") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"token":%q}`, token)
+		case "/v2/team/app/manifests/v1":
+			if r.Method != http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:1111")
+			w.WriteHeader(http.StatusOK)
+		case "/v2/team/app/manifests/sha256:2222":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := mustHost(t, server.URL)
+	checker := registry.NewChecker(5*time.Second, []string{host})
+
+	tests := []struct {
+		name       string
+		ref        registry.Reference
+		cred       *registry.Credential
+		wantDigest string
+		wantErr    error
+	}{
+		{"exists anonymous", registry.Reference{Registry: host, Repository: "team/app", Reference: "v1"}, nil, "sha256:1111", nil},
+		{"exists with credential", registry.Reference{Registry: host, Repository: "team/app", Reference: "v1"}, &registry.Credential{Username: "bob", Password: "secret"}, "sha256:1111", nil},
+		{"digest without header", registry.Reference{Registry: host, Repository: "team/app", Reference: "sha256:2222"}, nil, "sha256:2222", nil},
+		{"bad credential", registry.Reference{Registry: host, Repository: "team/app", Reference: "v1"}, &registry.Credential{Username: "bob", Password: "wrong"}, "", registry.ErrUnauthorized},
+		{"missing tag", registry.Reference{Registry: host, Repository: "team/app", Reference: "v2"}, nil, "", registry.ErrManifestNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checker.ManifestExists(context.Background(), tt.ref, tt.cred); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ManifestExists() error = %v, want %v", err, tt.wantErr)
+			}
+			digest, err := checker.ResolveDigest(context.Background(), tt.ref, tt.cred)
+			if !errors.Is(err, tt.wantErr) || digest != tt.wantDigest {
+				t.Errorf("ResolveDigest() = %q, %v, want %q, %v", digest, err, tt.wantDigest, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
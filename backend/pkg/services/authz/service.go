@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"qm-mcp-server/api/authz/user_auth"
+	"qm-mcp-server/pkg/logger"
 )
 
 type AuthzService struct {
@@ -43,6 +44,9 @@ func (s *AuthzService) GetUserInfo(ctx context.Context) (*user_auth.GetUserInfoR
 
 	httpReq.Header.Set("Content-Type", s.ContentType)
 	httpReq.Header.Set("Authorization", s.Authorization)
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		httpReq.Header.Set(logger.RequestIDHeader, requestID)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
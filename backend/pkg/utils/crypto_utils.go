@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/crypto/scrypt"
 )
 
 const AlgorithmRSA2048 = "RSA-2048"
@@ -131,6 +134,118 @@ func DecryptPassword(encryptedPassword string, privateKeyPEM string, maxAge time
 	return password, timestamp, nil
 }
 
+// AESEncrypt encrypt data at rest using AES-256-GCM, the key is derived from secret with SHA256
+func AESEncrypt(plaintext string, secret string) (string, error) {
+	gcm, err := newAESGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	return aesSeal(gcm, plaintext)
+}
+
+// AESDecrypt decrypt data encrypted by AESEncrypt
+func AESDecrypt(ciphertext string, secret string) (string, error) {
+	gcm, err := newAESGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	return aesOpen(gcm, ciphertext)
+}
+
+// AESEncryptWithKey encrypt data using AES-256-GCM with a 32-byte key, e.g. one derived by DerivePassphraseKey
+func AESEncryptWithKey(plaintext string, key []byte) (string, error) {
+	gcm, err := newAESGCMWithKey(key)
+	if err != nil {
+		return "", err
+	}
+	return aesSeal(gcm, plaintext)
+}
+
+// AESDecryptWithKey decrypt data encrypted by AESEncryptWithKey
+func AESDecryptWithKey(ciphertext string, key []byte) (string, error) {
+	gcm, err := newAESGCMWithKey(key)
+	if err != nil {
+		return "", err
+	}
+	return aesOpen(gcm, ciphertext)
+}
+
+// ScryptParams cost parameters of DerivePassphraseKey, stored next to the salt so they can be raised later
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// DefaultScryptParams scrypt cost recommended for interactive use, about 100ms per derivation
+var DefaultScryptParams = ScryptParams{N: 1 << 15, R: 8, P: 1}
+
+// DerivePassphraseKey derive a 32-byte AES key from a user chosen passphrase with scrypt and a random salt
+func DerivePassphraseKey(passphrase string, salt []byte, params ScryptParams) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("salt is empty")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	return key, nil
+}
+
+// aesSeal encrypt plaintext with a random nonce
+func aesSeal(gcm cipher.AEAD, plaintext string) (string, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	// Return Base64 encoded nonce|ciphertext
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// aesOpen decrypt ciphertext produced by aesSeal
+func aesOpen(gcm cipher.AEAD, ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %v", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("AES decryption failed: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// newAESGCM create AES-GCM cipher with key derived from secret
+func newAESGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("encryption secret is empty")
+	}
+	key := sha256.Sum256([]byte(secret))
+	return newAESGCMWithKey(key[:])
+}
+
+// newAESGCMWithKey create AES-GCM cipher with a 32-byte key
+func newAESGCMWithKey(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
 // generateKeyID generate key ID
 func generateKeyID() string {
 	return fmt.Sprintf("key_%s_%d", uuid.New().String()[:8], time.Now().Unix())
@@ -236,6 +351,23 @@ func GenerateRandomSalt(length int) (string, error) {
 	return base64.StdEncoding.EncodeToString(salt), nil
 }
 
+// GenerateAccessToken generate a random URL-safe access token of 32 bytes
+func GenerateAccessToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate access token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// MaskToken keep the first and last 4 characters of a token for logs and notifications
+func MaskToken(token string) string {
+	if len(token) <= 12 {
+		return "****"
+	}
+	return token[:4] + "****" + token[len(token)-4:]
+}
+
 // HashPasswordWithSalt hash password with salt
 func HashPasswordWithSalt(password, salt string) (string, error) {
 	// Combine password and salt
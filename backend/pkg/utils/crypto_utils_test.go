@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"bytes"
 	"encoding/base64"
 	"qm-mcp-server/pkg/utils"
 	"testing"
@@ -39,3 +40,91 @@ func TestRSAEncrypt(t *testing.T) {
 		})
 	}
 }
+
+func TestAESEncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		secret    string
+		decSecret string
+		wantErr   bool
+	}{
+		{name: "round trip", plaintext: "registry-token", secret: "s3cret", decSecret: "s3cret"},
+		{name: "empty plaintext", plaintext: "", secret: "s3cret", decSecret: "s3cret"},
+		{name: "wrong secret", plaintext: "registry-token", secret: "s3cret", decSecret: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := utils.AESEncrypt(tt.plaintext, tt.secret)
+			if err != nil {
+				t.Fatalf("AESEncrypt() failed: %v", err)
+			}
+			if tt.plaintext != "" && ciphertext == base64.StdEncoding.EncodeToString([]byte(tt.plaintext)) {
+				t.Fatalf("AESEncrypt() returned plaintext")
+			}
+			got, err := utils.AESDecrypt(ciphertext, tt.decSecret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AESDecrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.plaintext {
+				t.Errorf("AESDecrypt() = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+
+	if _, err := utils.AESEncrypt("data", ""); err == nil {
+		t.Errorf("AESEncrypt() with empty secret should fail")
+	}
+}
+
+func TestDerivePassphraseKey(t *testing.T) {
+	// 测试使用较低的成本，与默认参数的派生过程相同
+	params := utils.ScryptParams{N: 1 << 10, R: 8, P: 1}
+	key, err := utils.DerivePassphraseKey("backup-pass", []byte("salt-1"), params)
+	if err != nil {
+		t.Fatalf("DerivePassphraseKey() failed: %v", err)
+	}
+	again, _ := utils.DerivePassphraseKey("backup-pass", []byte("salt-1"), params)
+	if !bytes.Equal(key, again) {
+		t.Errorf("DerivePassphraseKey() is not deterministic for the same salt")
+	}
+	if other, _ := utils.DerivePassphraseKey("backup-pass", []byte("salt-2"), params); bytes.Equal(key, other) {
+		t.Errorf("DerivePassphraseKey() returned the same key for different salts")
+	}
+
+	ciphertext, err := utils.AESEncryptWithKey("registry-token", key)
+	if err != nil {
+		t.Fatalf("AESEncryptWithKey() failed: %v", err)
+	}
+	if got, err := utils.AESDecryptWithKey(ciphertext, key); err != nil || got != "registry-token" {
+		t.Errorf("AESDecryptWithKey() = %q, %v, want registry-token", got, err)
+	}
+	wrong, _ := utils.DerivePassphraseKey("other-pass", []byte("salt-1"), params)
+	if _, err := utils.AESDecryptWithKey(ciphertext, wrong); err == nil {
+		t.Errorf("AESDecryptWithKey() with a key from another passphrase should fail")
+	}
+
+	if _, err := utils.DerivePassphraseKey("backup-pass", nil, params); err == nil {
+		t.Errorf("DerivePassphraseKey() without salt should fail")
+	}
+	if _, err := utils.AESEncryptWithKey("data", []byte("short")); err == nil {
+		t.Errorf("AESEncryptWithKey() with a short key should fail")
+	}
+}
+
+func TestGenerateAccessToken(t *testing.T) {
+	first, err := utils.GenerateAccessToken()
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() failed: %v", err)
+	}
+	second, _ := utils.GenerateAccessToken()
+	if len(first) != 43 || first == second {
+		t.Errorf("GenerateAccessToken() = %q, %q, want two distinct 43 character tokens", first, second)
+	}
+	if got := utils.MaskToken(first); got != first[:4]+"****"+first[39:] {
+		t.Errorf("MaskToken() = %q", got)
+	}
+	if got := utils.MaskToken("short"); got != "****" {
+		t.Errorf("MaskToken(short) = %q, want ****", got)
+	}
+}
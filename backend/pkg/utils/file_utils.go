@@ -2,6 +2,8 @@ package utils
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -155,6 +157,41 @@ func AddFileToZip(zipWriter *zip.Writer, filePath, nameInZip string) error {
 	return nil
 }
 
+// DirChecksum returns the sha256 of the regular files under dir as "sha256:<hex>". Files are hashed in
+// lexical path order together with their slash separated relative paths, so the checksum only changes
+// when a file is added, removed, renamed or modified
+func DirChecksum(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(rel), info.Size())
+		_, err = io.Copy(hash, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute checksum of %s: %w", dir, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // CheckExtractedPathNotEmpty checks if the extracted path is not empty
 func CheckExtractedPathNotEmpty(path string) error {
 	// Check if path exists
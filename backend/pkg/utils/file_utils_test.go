@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestDirChecksum(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checksum := func() string {
+		t.Helper()
+		sum, err := utils.DirChecksum(dir)
+		if err != nil {
+			t.Fatalf("DirChecksum() error = %v", err)
+		}
+		return sum
+	}
+
+	write("main.py", "print('hi')")
+	write("lib/util.py", "x = 1")
+	first := checksum()
+	if !strings.HasPrefix(first, "sha256:") || len(first) != len("sha256:")+64 {
+		t.Fatalf("DirChecksum() = %q, want sha256:<hex>", first)
+	}
+	if again := checksum(); again != first {
+		t.Errorf("checksum changed without changes: %s -> %s", first, again)
+	}
+
+	// renaming a file changes the checksum even though the content is the same
+	if err := os.Rename(filepath.Join(dir, "lib/util.py"), filepath.Join(dir, "lib/utils.py")); err != nil {
+		t.Fatal(err)
+	}
+	renamed := checksum()
+	if renamed == first {
+		t.Error("checksum unchanged after rename")
+	}
+	write("lib/utils.py", "x = 2")
+	if checksum() == renamed {
+		t.Error("checksum unchanged after modification")
+	}
+
+	if _, err := utils.DirChecksum(filepath.Join(dir, "missing")); err == nil {
+		t.Error("DirChecksum() of a missing directory error = nil")
+	}
+}
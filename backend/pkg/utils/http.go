@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -10,6 +13,9 @@ import (
 	"time"
 )
 
+// probeUserAgent User-Agent of probe requests
+const probeUserAgent = "qm-mcp-server-health-checker/1.0"
+
 // PortProbeOptions port probe options
 type PortProbeOptions struct {
 	Host    string        // host address
@@ -85,7 +91,7 @@ func ProbeHTTP(ctx context.Context, options HTTPProbeOptions, expectedStatus int
 	}
 
 	// set User-Agent
-	req.Header.Set("User-Agent", "qm-mcp-server-health-checker/1.0")
+	req.Header.Set("User-Agent", probeUserAgent)
 
 	// perform request
 	resp, err := client.Do(req)
@@ -170,6 +176,68 @@ func ProbePortFromURL(ctx context.Context, urlStr string, timeout time.Duration)
 	})
 }
 
+// Service path probe results
+const (
+	ServicePathOK         = "ok"             // the port is open and the path is served
+	ServicePathPortClosed = "port_closed"    // the port does not accept connections
+	ServicePathNotFound   = "path_not_found" // the port is open but the path returns 404
+	ServicePathHTTPFailed = "http_failed"    // the port is open but the HTTP request failed
+)
+
+// ServicePathProbeResult service path probe result
+type ServicePathProbeResult struct {
+	Result     string        // one of the ServicePath* results
+	StatusCode int           // HTTP status code, 0 when no response was received
+	Error      string        // error message
+	Latency    time.Duration // response latency
+}
+
+// ProbeServicePath checks that the port of urlStr accepts connections and that its exact path is served,
+// so a server listening on another path is reported separately from a closed port.
+// Any status other than 404 means the path exists: MCP endpoints answer a plain GET with 400 or 405
+// (streamable HTTP without a session) or start an event stream (SSE), which is closed right away.
+func ProbeServicePath(ctx context.Context, urlStr string, timeout time.Duration) *ServicePathProbeResult {
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	portResult := ProbePortFromURL(ctx, urlStr, timeout)
+	if !portResult.Success {
+		return &ServicePathProbeResult{Result: ServicePathPortClosed, Error: portResult.Error, Latency: portResult.Latency}
+	}
+
+	result := &ServicePathProbeResult{Result: ServicePathHTTPFailed}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create request: %v", err)
+		return result
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", probeUserAgent)
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = fmt.Sprintf("port is open but the request failed: %v", err)
+		return result
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode == http.StatusNotFound {
+		path := req.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		result.Result = ServicePathNotFound
+		result.Error = fmt.Sprintf("port is open but path %s returned 404", path)
+		return result
+	}
+	result.Result = ServicePathOK
+	return result
+}
+
 // ProbePort probe connectivity of specified host and port
 func ProbePort(ctx context.Context, options PortProbeOptions) *PortProbeResult {
 	start := time.Now()
@@ -248,3 +316,29 @@ func IsHTTP204Available(ctx context.Context, url string, timeout time.Duration)
 	})
 	return result.Success
 }
+
+// PostJSON sends payload as a JSON POST request, non-2xx responses are returned as errors
+func PostJSON(ctx context.Context, url string, payload interface{}, timeout time.Duration) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", probeUserAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
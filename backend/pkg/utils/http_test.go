@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestProbeServicePath(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /api/message\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + listener.Addr().String() + "/sse"
+	listener.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantResult string
+		wantStatus int
+	}{
+		{"sse stream", server.URL + "/api/sse", utils.ServicePathOK, http.StatusOK},
+		{"streamable without session", server.URL + "/mcp", utils.ServicePathOK, http.StatusMethodNotAllowed},
+		{"wrong path", server.URL + "/sse", utils.ServicePathNotFound, http.StatusNotFound},
+		{"port closed", closedURL, utils.ServicePathPortClosed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.ProbeServicePath(t.Context(), tt.url, 2*time.Second)
+			if got.Result != tt.wantResult || got.StatusCode != tt.wantStatus {
+				t.Errorf("ProbeServicePath() = %s/%d, want %s/%d (error: %s)", got.Result, got.StatusCode, tt.wantResult, tt.wantStatus, got.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,478 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// McpConfigFormatClient mcpServers 为客户端配置格式（Claude Desktop、Cursor、VS Code、Zed 等），使用前转换为标准格式
+const McpConfigFormatClient = "client"
+
+// defaultClientServerName name of a server pasted without its surrounding mcpServers object
+const defaultClientServerName = "server"
+
+// clientServersKeys root keys under which clients keep their servers, compared by normalized name:
+// mcpServers (Claude Desktop, Cursor, Windsurf), servers (VS Code mcp.json), context_servers (Zed)
+var clientServersKeys = []string{"mcpservers", "servers", "contextservers"}
+
+// clientServerFieldAliases client specific server fields and the canonical field they map to,
+// compared by normalized name
+var clientServerFieldAliases = map[string]string{
+	"serverurl":   "url", // Windsurf
+	"httpurl":     "url", // Gemini CLI, always streamable-http
+	"environment": "env",
+	"alwaysallow": "autoApprove", // Cline
+}
+
+// clientProtocolTypes client transport types and the protocol they map to, compared by normalized name,
+// an empty protocol means the protocol is determined from the url
+var clientProtocolTypes = map[string]string{
+	"stdio":          model.McpProtocolStdio.String(),
+	"local":          model.McpProtocolStdio.String(),
+	"sse":            model.McpProtocolSSE.String(),
+	"http":           model.McpProtocolStreamableHttp.String(),
+	"streamablehttp": model.McpProtocolStreamableHttp.String(),
+	"remote":         "",
+}
+
+// McpConfigChange a change made while converting a client configuration, Path locates the changed
+// field in the canonical configuration
+type McpConfigChange struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// McpConfigConversion result of converting a client configuration into the canonical mcpServers shape
+type McpConfigConversion struct {
+	// McpServers canonical configuration
+	McpServers string `json:"mcpServers"`
+	// EnvironmentVariables env of stdio servers, hosting containers take them from the instance
+	// environment variables rather than from the configuration
+	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
+	// Changes everything that was transformed, empty when the configuration was already canonical
+	Changes []McpConfigChange `json:"changes,omitempty"`
+}
+
+// change records a transformation
+func (c *McpConfigConversion) change(path, format string, args ...any) {
+	c.Changes = append(c.Changes, McpConfigChange{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateMcpConfigLenient converts a client configuration with ConvertClientMcpConfig and validates
+// the result, result.Conversion holds the canonical configuration and what was transformed
+func ValidateMcpConfigLenient(configData []byte) (*McpValidationResult, error) {
+	conversion, convertErr := ConvertClientMcpConfig(configData)
+	if convertErr != nil {
+		result := &McpValidationResult{}
+		return result.fail(convertErr), nil
+	}
+	result, err := ValidateMcpConfig([]byte(conversion.McpServers))
+	if err != nil {
+		return nil, err
+	}
+	result.Conversion = conversion
+	return result, nil
+}
+
+// ConvertClientMcpConfig recognizes the configuration dialects of common MCP clients and normalizes
+// them into the canonical mcpServers shape: servers are looked up under mcpServers in any key casing,
+// servers or context_servers, field names are matched ignoring case, command lines are split into
+// command and args, transport types are mapped to our protocol names and unsupported fields are
+// dropped. The env of stdio servers is moved into the suggested environment variables. Entries that
+// cannot be converted are kept as is so that validation reports them
+func ConvertClientMcpConfig(configData []byte) (*McpConfigConversion, *McpConfigError) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(configData, &root); err != nil {
+		return nil, &McpConfigError{Message: jsonErrorMessage(configData, err)}
+	}
+
+	conversion := &McpConfigConversion{}
+	servers, err := findClientServers(root, conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := make(map[string]json.RawMessage, len(servers))
+	envSource := make(map[string]string)
+	for _, name := range sortedKeys(servers) {
+		serverName := uniqueServerName(sanitizeServerName(name), canonical)
+		path := mcpServersField + "." + serverName
+		if serverName != name {
+			conversion.change(path, "renamed server %q to %q", name, serverName)
+		}
+		server, env := convertClientServer(path, servers[name], conversion)
+		canonical[serverName] = server
+		for _, key := range sortedKeys(env) {
+			if first, ok := envSource[key]; ok {
+				if conversion.EnvironmentVariables[key] != env[key] {
+					conversion.change(path+".env."+key, "conflicts with the value of server %q, keeping that value", first)
+				}
+				continue
+			}
+			if conversion.EnvironmentVariables == nil {
+				conversion.EnvironmentVariables = make(map[string]string)
+			}
+			envSource[key] = serverName
+			conversion.EnvironmentVariables[key] = env[key]
+		}
+	}
+
+	data, marshalErr := json.MarshalIndent(map[string]any{mcpServersField: canonical}, "", "  ")
+	if marshalErr != nil {
+		return nil, &McpConfigError{Message: fmt.Sprintf("failed to serialize configuration: %v", marshalErr)}
+	}
+	conversion.McpServers = string(data)
+	return conversion, nil
+}
+
+// findClientServers locates the server map of a client configuration, other root fields are dropped
+func findClientServers(root map[string]json.RawMessage, conversion *McpConfigConversion) (map[string]json.RawMessage, *McpConfigError) {
+	servers, found, err := clientServersIn(root)
+	if err != nil {
+		return nil, err
+	}
+	if servers == nil {
+		// VS Code settings.json keeps its servers in "mcp": {"servers": {...}}
+		for _, key := range sortedKeys(root) {
+			var nested map[string]json.RawMessage
+			if normalizeFieldName(key) != "mcp" || json.Unmarshal(root[key], &nested) != nil {
+				continue
+			}
+			if nestedServers, nestedKey, _ := clientServersIn(nested); nestedServers != nil {
+				servers, found = nestedServers, key
+				conversion.change(mcpServersField, "moved servers from %q", key+"."+nestedKey)
+				break
+			}
+		}
+	} else if found != mcpServersField {
+		conversion.change(mcpServersField, "moved servers from %q", found)
+	}
+
+	switch {
+	case servers != nil:
+		for _, key := range sortedKeys(root) {
+			if key != found {
+				conversion.change(key, "ignored unsupported field %q", key)
+			}
+		}
+	case looksLikeClientServerFields(root):
+		// A single server entry pasted without its name
+		servers = map[string]json.RawMessage{defaultClientServerName: marshalJSON(root)}
+		conversion.change(mcpServersField+"."+defaultClientServerName, "wrapped a single server entry as %q", defaultClientServerName)
+	case len(root) > 0 && allClientServers(root):
+		// A server map pasted without the surrounding mcpServers object
+		servers = root
+		conversion.change(mcpServersField, "wrapped servers in %q", mcpServersField)
+	default:
+		return nil, &McpConfigError{Path: mcpServersField, Message: "no MCP servers found, expected an object of servers under mcpServers"}
+	}
+	if len(servers) == 0 {
+		return nil, &McpConfigError{Path: mcpServersField, Message: "must contain at least one server"}
+	}
+	return servers, nil
+}
+
+// clientServersIn returns the server map of an object and the key it was found under
+func clientServersIn(fields map[string]json.RawMessage) (map[string]json.RawMessage, string, *McpConfigError) {
+	for _, key := range sortedKeys(fields) {
+		if !slices.Contains(clientServersKeys, normalizeFieldName(key)) {
+			continue
+		}
+		var servers map[string]json.RawMessage
+		if err := json.Unmarshal(fields[key], &servers); err != nil || servers == nil {
+			return nil, "", &McpConfigError{Path: key, Message: "must be an object"}
+		}
+		return servers, key, nil
+	}
+	return nil, "", nil
+}
+
+// looksLikeClientServer reports whether raw is an object with a command or url
+func looksLikeClientServer(raw json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return false
+	}
+	return looksLikeClientServerFields(fields)
+}
+
+// looksLikeClientServerFields reports whether the fields contain a command or url
+func looksLikeClientServerFields(fields map[string]json.RawMessage) bool {
+	for key := range fields {
+		switch canonicalServerField(key) {
+		case "command", "url":
+			return true
+		}
+	}
+	return false
+}
+
+// allClientServers reports whether every value of the object is a server entry
+func allClientServers(fields map[string]json.RawMessage) bool {
+	for _, raw := range fields {
+		if !looksLikeClientServer(raw) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertClientServer normalizes a single server entry and returns it with the env to move into the
+// instance environment variables, an entry that is not an object is returned unchanged
+func convertClientServer(path string, raw json.RawMessage, conversion *McpConfigConversion) (json.RawMessage, map[string]string) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return raw, nil
+	}
+
+	server := make(map[string]json.RawMessage, len(fields))
+	streamable := false
+	for _, key := range sortedKeys(fields) {
+		field := canonicalServerField(key)
+		if field == "" {
+			conversion.change(path+"."+key, "ignored unsupported field %q", key)
+			continue
+		}
+		if _, ok := server[field]; ok {
+			conversion.change(path+"."+key, "ignored duplicate of field %q", field)
+			continue
+		}
+		if field != key {
+			conversion.change(path+"."+field, "renamed field %q to %q", key, field)
+		}
+		server[field] = fields[key]
+		streamable = streamable || normalizeFieldName(key) == "httpurl"
+	}
+	// Gemini CLI httpUrl is always streamable-http
+	if _, ok := server["type"]; streamable && !ok {
+		server["type"] = marshalJSON(model.McpProtocolStreamableHttp.String())
+	}
+
+	convertClientCommand(path, server, conversion)
+	convertClientType(path, server, conversion)
+	if _, ok := server["command"]; !ok {
+		return marshalJSON(server), nil
+	}
+	env, ok := clientEnv(server["env"])
+	if !ok {
+		return marshalJSON(server), nil
+	}
+	if len(env) > 0 {
+		conversion.change(path+".env", "moved %d variables to environmentVariables", len(env))
+	}
+	delete(server, "env")
+	return marshalJSON(server), env
+}
+
+// convertClientCommand flattens a Zed style command object and splits command lines into command and args
+func convertClientCommand(path string, server map[string]json.RawMessage, conversion *McpConfigConversion) {
+	if raw, ok := server["command"]; ok {
+		var command struct {
+			Path string          `json:"path"`
+			Args json.RawMessage `json:"args"`
+			Env  json.RawMessage `json:"env"`
+		}
+		if json.Unmarshal(raw, &command) == nil && command.Path != "" {
+			server["command"] = marshalJSON(command.Path)
+			if _, ok := server["args"]; !ok && len(command.Args) > 0 {
+				server["args"] = command.Args
+			}
+			if _, ok := server["env"]; !ok && len(command.Env) > 0 {
+				server["env"] = command.Env
+			}
+			conversion.change(path+".command", "flattened command object into command, args and env")
+		}
+	}
+
+	if raw, ok := server["args"]; ok {
+		var line string
+		if json.Unmarshal(raw, &line) == nil {
+			if args, ok := splitCommandLine(line); ok {
+				server["args"] = marshalJSON(args)
+				conversion.change(path+".args", "split args string into a list")
+			}
+		}
+	}
+
+	var command string
+	if json.Unmarshal(server["command"], &command) != nil || !strings.ContainsFunc(strings.TrimSpace(command), unicode.IsSpace) {
+		return
+	}
+	words, ok := splitCommandLine(command)
+	if !ok || len(words) < 2 {
+		return
+	}
+	var args []string
+	if raw, ok := server["args"]; ok && json.Unmarshal(raw, &args) != nil {
+		return
+	}
+	server["command"] = marshalJSON(words[0])
+	server["args"] = marshalJSON(append(words[1:], args...))
+	conversion.change(path+".command", "split command line into command and args")
+}
+
+// convertClientType maps client transport types to our protocol names
+func convertClientType(path string, server map[string]json.RawMessage, conversion *McpConfigConversion) {
+	for _, field := range []string{"type", "transport"} {
+		var value string
+		if json.Unmarshal(server[field], &value) != nil {
+			continue
+		}
+		protocol, ok := clientProtocolTypes[normalizeFieldName(value)]
+		switch {
+		case !ok || protocol == value:
+		case protocol == "":
+			delete(server, field)
+			conversion.change(path+"."+field, "removed %s %q, the protocol is determined from the url", field, value)
+		default:
+			server[field] = marshalJSON(protocol)
+			conversion.change(path+"."+field, "mapped %s %q to %q", field, value, protocol)
+		}
+	}
+}
+
+// clientEnv parses an env object, numbers and booleans are converted to strings
+func clientEnv(raw json.RawMessage) (map[string]string, bool) {
+	if len(raw) == 0 || isJSONNull(raw) {
+		return nil, true
+	}
+	var values map[string]any
+	if json.Unmarshal(raw, &values) != nil {
+		return nil, false
+	}
+	env := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			env[key] = v
+		case float64:
+			env[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			env[key] = strconv.FormatBool(v)
+		case nil:
+			env[key] = ""
+		default:
+			return nil, false
+		}
+	}
+	return env, true
+}
+
+// canonicalServerField returns the canonical name of a client server field, empty when unsupported
+func canonicalServerField(key string) string {
+	if _, ok := mcpServerFields[key]; ok {
+		return key
+	}
+	normalized := normalizeFieldName(key)
+	if field, ok := clientServerFieldAliases[normalized]; ok {
+		return field
+	}
+	for field := range mcpServerFields {
+		if normalizeFieldName(field) == normalized {
+			return field
+		}
+	}
+	return ""
+}
+
+// normalizeFieldName lower cases a field name and removes '_' and '-', so that
+// mcp_servers, McpServers and mcp-servers compare equal
+func normalizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// sanitizeServerName replaces characters not allowed in server names with '-', names such as
+// @modelcontextprotocol/server-github become modelcontextprotocol-server-github
+func sanitizeServerName(name string) string {
+	if isValidServiceName(name) {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	sanitized := strings.Trim(b.String(), "-")
+	for len(sanitized) > 0 && !unicode.IsLetter(rune(sanitized[0])) {
+		sanitized = sanitized[1:]
+	}
+	if sanitized == "" {
+		return defaultClientServerName
+	}
+	return sanitized
+}
+
+// uniqueServerName appends a number to name when it is already taken
+func uniqueServerName(name string, taken map[string]json.RawMessage) string {
+	if _, ok := taken[name]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// splitCommandLine splits a command line into words honouring single and double quotes and backslash
+// escapes, ok is false for unterminated quotes
+func splitCommandLine(line string) ([]string, bool) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, false
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, true
+}
+
+// marshalJSON encodes values that cannot fail to encode, such as strings and decoded JSON
+func marshalJSON(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,148 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestConvertClientMcpConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		wantServers string
+		wantEnv     map[string]string
+		wantPaths   []string
+	}{
+		{
+			name:        "canonical config is unchanged",
+			config:      `{"mcpServers":{"github":{"url":"https://mcp.example.com/sse"}}}`,
+			wantServers: `{"mcpServers":{"github":{"url":"https://mcp.example.com/sse"}}}`,
+		},
+		{
+			name:        "claude desktop stdio with env",
+			config:      `{"globalShortcut":"Ctrl+Space","mcpServers":{"github":{"command":"npx","args":["-y","@modelcontextprotocol/server-github"],"env":{"GITHUB_TOKEN":"x","PORT":8080}}}}`,
+			wantServers: `{"mcpServers":{"github":{"command":"npx","args":["-y","@modelcontextprotocol/server-github"]}}}`,
+			wantEnv:     map[string]string{"GITHUB_TOKEN": "x", "PORT": "8080"},
+			wantPaths:   []string{"globalShortcut", "mcpServers.github.env"},
+		},
+		{
+			name:        "vs code servers with http type",
+			config:      `{"inputs":[],"servers":{"remote":{"type":"http","url":"https://mcp.example.com/mcp"}}}`,
+			wantServers: `{"mcpServers":{"remote":{"type":"streamable-http","url":"https://mcp.example.com/mcp"}}}`,
+			wantPaths:   []string{"mcpServers", "inputs", "mcpServers.remote.type"},
+		},
+		{
+			name:        "vs code settings nested under mcp",
+			config:      `{"mcp":{"servers":{"fetch":{"type":"stdio","command":"uvx","args":["mcp-server-fetch"]}}}}`,
+			wantServers: `{"mcpServers":{"fetch":{"type":"stdio","command":"uvx","args":["mcp-server-fetch"]}}}`,
+			wantPaths:   []string{"mcpServers"},
+		},
+		{
+			name:        "zed command object",
+			config:      `{"context_servers":{"fetch":{"command":{"path":"uvx","args":["mcp-server-fetch"],"env":{"DEBUG":"1"}},"settings":{}}}}`,
+			wantServers: `{"mcpServers":{"fetch":{"command":"uvx","args":["mcp-server-fetch"]}}}`,
+			wantEnv:     map[string]string{"DEBUG": "1"},
+			wantPaths:   []string{"mcpServers", "mcpServers.fetch.settings", "mcpServers.fetch.command", "mcpServers.fetch.env"},
+		},
+		{
+			name:        "key casing, aliases and command line",
+			config:      `{"McpServers":{"@scope/fetch":{"Command":"uvx 'mcp-server-fetch' --verbose"},"docs":{"serverUrl":"https://docs.example.com/mcp"}}}`,
+			wantServers: `{"mcpServers":{"scope-fetch":{"command":"uvx","args":["mcp-server-fetch","--verbose"]},"docs":{"url":"https://docs.example.com/mcp"}}}`,
+			wantPaths: []string{
+				"mcpServers", "mcpServers.scope-fetch", "mcpServers.scope-fetch.command",
+				"mcpServers.scope-fetch.command", "mcpServers.docs.url",
+			},
+		},
+		{
+			name:        "single server without name",
+			config:      `{"httpUrl":"https://mcp.example.com/api"}`,
+			wantServers: `{"mcpServers":{"server":{"type":"streamable-http","url":"https://mcp.example.com/api"}}}`,
+			wantPaths:   []string{"mcpServers.server", "mcpServers.server.url"},
+		},
+		{
+			name:        "server map without wrapper and conflicting env",
+			config:      `{"a":{"command":"uvx","env":{"TOKEN":"1"}},"b":{"command":"npx","env":{"TOKEN":"2"}}}`,
+			wantServers: `{"mcpServers":{"a":{"command":"uvx"},"b":{"command":"npx"}}}`,
+			wantEnv:     map[string]string{"TOKEN": "1"},
+			wantPaths:   []string{"mcpServers", "mcpServers.a.env", "mcpServers.b.env", "mcpServers.b.env.TOKEN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion, err := utils.ConvertClientMcpConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("ConvertClientMcpConfig() error = %v", err)
+			}
+			if !jsonEqual(t, conversion.McpServers, tt.wantServers) {
+				t.Errorf("McpServers = %s, want %s", conversion.McpServers, tt.wantServers)
+			}
+			if !reflect.DeepEqual(conversion.EnvironmentVariables, tt.wantEnv) {
+				t.Errorf("EnvironmentVariables = %v, want %v", conversion.EnvironmentVariables, tt.wantEnv)
+			}
+			var paths []string
+			for _, change := range conversion.Changes {
+				paths = append(paths, change.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("change paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestConvertClientMcpConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   utils.McpConfigError
+	}{
+		{"invalid json", `{"mcpServers":`, utils.McpConfigError{Message: "invalid JSON at line 1, column 14: unexpected end of JSON input"}},
+		{"no servers", `{"theme":"dark"}`, utils.McpConfigError{Path: "mcpServers", Message: "no MCP servers found, expected an object of servers under mcpServers"}},
+		{"servers not an object", `{"servers":[]}`, utils.McpConfigError{Path: "servers", Message: "must be an object"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.ConvertClientMcpConfig([]byte(tt.config))
+			if err == nil {
+				t.Fatal("ConvertClientMcpConfig() error = nil")
+			}
+			if *err != tt.want {
+				t.Errorf("error = %+v, want %+v", *err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMcpConfigLenient(t *testing.T) {
+	result, err := utils.ValidateMcpConfigLenient([]byte(`{"servers":{"fetch":{"command":"uvx mcp-server-fetch","env":{"DEBUG":"1"}}}}`))
+	if err != nil {
+		t.Fatalf("ValidateMcpConfigLenient() error = %v", err)
+	}
+	if !result.IsValid || result.ProtocolType != "stdio" || result.ServiceName != "fetch" {
+		t.Fatalf("result = %+v, want valid stdio server fetch", result)
+	}
+	if result.Conversion == nil || result.Conversion.EnvironmentVariables["DEBUG"] != "1" {
+		t.Errorf("Conversion = %+v, want DEBUG moved to environment variables", result.Conversion)
+	}
+
+	result, _ = utils.ValidateMcpConfigLenient([]byte(`{"mcpServers":{"github":{"type":"sse"}}}`))
+	if result.IsValid || len(result.Errors) != 1 || result.Errors[0].Path != "mcpServers.github.url" {
+		t.Errorf("Errors = %+v, want missing url of the converted config", result.Errors)
+	}
+}
+
+// jsonEqual compares two JSON documents ignoring formatting and key order
+func jsonEqual(t *testing.T, a, b string) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
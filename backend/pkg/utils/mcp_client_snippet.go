@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// MCP 客户端类型，用于生成可直接粘贴到客户端的连接配置
+const (
+	McpClientGeneric = "generic"
+	McpClientCursor  = "cursor"
+	McpClientClaude  = "claude"
+	McpClientVSCode  = "vscode"
+)
+
+// McpClients supported client types
+var McpClients = []string{McpClientGeneric, McpClientCursor, McpClientClaude, McpClientVSCode}
+
+// mcpRemotePackage npm package bridging stdio only clients such as Claude Desktop to remote servers
+const mcpRemotePackage = "mcp-remote"
+
+// McpClientEndpoint a remote MCP server a client connects to
+type McpClientEndpoint struct {
+	Name string
+	URL  string
+	// Protocol sse or streamable-http, determined from the URL when empty
+	Protocol string
+}
+
+// McpClientSnippet connection configuration of a client
+type McpClientSnippet struct {
+	// Config JSON to paste into the client configuration file
+	Config string
+	// Curl command sending an initialize request to the first endpoint, for smoke testing
+	Curl string
+}
+
+// clientServerSnippet a server entry of a client configuration, fields are ordered as clients document them
+type clientServerSnippet struct {
+	Type    string            `json:"type,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// BuildMcpClientSnippet renders the configuration of client for the endpoints, headers such as the
+// Authorization bearer token are added to every server. Cursor and the generic format use url and headers
+// under mcpServers, VS Code uses servers with an http or sse type, Claude Desktop only starts stdio servers
+// and connects through mcp-remote with the headers passed as environment variables
+func BuildMcpClientSnippet(client string, endpoints []McpClientEndpoint, headers map[string]string) (*McpClientSnippet, error) {
+	if !slices.Contains(McpClients, client) {
+		return nil, fmt.Errorf("unsupported client %q, supported clients are %v", client, McpClients)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints to connect to")
+	}
+
+	servers := make(map[string]*clientServerSnippet, len(endpoints))
+	for _, endpoint := range endpoints {
+		protocol := endpointProtocol(endpoint)
+		server := &clientServerSnippet{}
+		switch client {
+		case McpClientGeneric:
+			server.Type, server.URL, server.Headers = protocol, endpoint.URL, headers
+		case McpClientCursor:
+			server.URL, server.Headers = endpoint.URL, headers
+		case McpClientVSCode:
+			server.Type, server.URL, server.Headers = "http", endpoint.URL, headers
+			if protocol == model.McpProtocolSSE.String() {
+				server.Type = protocol
+			}
+		case McpClientClaude:
+			server.Command = "npx"
+			server.Args, server.Env = mcpRemoteArgs(endpoint.URL, protocol, headers)
+		}
+		servers[endpoint.Name] = server
+	}
+
+	rootKey := mcpServersField
+	if client == McpClientVSCode {
+		rootKey = "servers"
+	}
+	data, err := json.MarshalIndent(map[string]any{rootKey: servers}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize client configuration: %w", err)
+	}
+	return &McpClientSnippet{
+		Config: string(data),
+		Curl:   curlCommand(endpoints[0].URL, endpointProtocol(endpoints[0]), headers),
+	}, nil
+}
+
+// endpointProtocol protocol of an endpoint, stdio servers are exposed as sse by the gateway
+func endpointProtocol(endpoint McpClientEndpoint) string {
+	switch endpoint.Protocol {
+	case model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String():
+		return endpoint.Protocol
+	case model.McpProtocolStdio.String():
+		return model.McpProtocolSSE.String()
+	}
+	return determineProtocolType(McpServerConfig{URL: endpoint.URL})
+}
+
+// mcpRemoteArgs arguments of npx mcp-remote, header values are passed through environment variables
+// because clients split arguments containing spaces on some platforms
+func mcpRemoteArgs(url, protocol string, headers map[string]string) ([]string, map[string]string) {
+	args := []string{"-y", mcpRemotePackage, url}
+	var env map[string]string
+	for _, name := range sortedKeys(headers) {
+		envName := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_HEADER"
+		args = append(args, "--header", fmt.Sprintf("%s:${%s}", name, envName))
+		if env == nil {
+			env = make(map[string]string, len(headers))
+		}
+		env[envName] = headers[name]
+	}
+	transport := "http-only"
+	if protocol == model.McpProtocolSSE.String() {
+		transport = "sse-only"
+	}
+	return append(args, "--transport", transport), env
+}
+
+// curlCommand command checking that the endpoint answers: an initialize request for streamable-http,
+// opening the event stream for sse
+func curlCommand(url, protocol string, headers map[string]string) string {
+	parts := []string{"curl", "-sS"}
+	if protocol == model.McpProtocolSSE.String() {
+		parts = append(parts, "-N", "-H", shellQuote("Accept: text/event-stream"))
+	} else {
+		parts = append(parts, "-X", "POST",
+			"-H", shellQuote("Content-Type: application/json"),
+			"-H", shellQuote("Accept: application/json, text/event-stream"))
+	}
+	for _, name := range sortedKeys(headers) {
+		parts = append(parts, "-H", shellQuote(name+": "+headers[name]))
+	}
+	if protocol != model.McpProtocolSSE.String() {
+		parts = append(parts, "-d", shellQuote(string(mcpInitializeRequest())))
+	}
+	return strings.Join(append(parts, shellQuote(url)), " ")
+}
+
+// shellQuote quotes s for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
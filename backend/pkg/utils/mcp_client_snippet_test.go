@@ -0,0 +1,81 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestBuildMcpClientSnippet(t *testing.T) {
+	endpoints := []utils.McpClientEndpoint{{Name: "mcp-1234abcd", URL: "https://mcp.example.com/1234abcd", Protocol: "streamable-http"}}
+	headers := map[string]string{"Authorization": "Bearer secret"}
+	tests := []struct {
+		name       string
+		client     string
+		endpoints  []utils.McpClientEndpoint
+		headers    map[string]string
+		wantConfig string
+	}{
+		{
+			name:       "generic",
+			client:     utils.McpClientGeneric,
+			endpoints:  endpoints,
+			headers:    headers,
+			wantConfig: `{"mcpServers":{"mcp-1234abcd":{"type":"streamable-http","url":"https://mcp.example.com/1234abcd","headers":{"Authorization":"Bearer secret"}}}}`,
+		},
+		{
+			name:       "cursor without token",
+			client:     utils.McpClientCursor,
+			endpoints:  endpoints,
+			wantConfig: `{"mcpServers":{"mcp-1234abcd":{"url":"https://mcp.example.com/1234abcd"}}}`,
+		},
+		{
+			name:       "vscode sse",
+			client:     utils.McpClientVSCode,
+			endpoints:  []utils.McpClientEndpoint{{Name: "docs", URL: "https://mcp.example.com/abcd/sse", Protocol: "stdio"}},
+			headers:    headers,
+			wantConfig: `{"servers":{"docs":{"type":"sse","url":"https://mcp.example.com/abcd/sse","headers":{"Authorization":"Bearer secret"}}}}`,
+		},
+		{
+			name:      "claude through mcp-remote",
+			client:    utils.McpClientClaude,
+			endpoints: endpoints,
+			headers:   headers,
+			wantConfig: `{"mcpServers":{"mcp-1234abcd":{"command":"npx","args":["-y","mcp-remote","https://mcp.example.com/1234abcd",` +
+				`"--header","Authorization:${AUTHORIZATION_HEADER}","--transport","http-only"],"env":{"AUTHORIZATION_HEADER":"Bearer secret"}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, err := utils.BuildMcpClientSnippet(tt.client, tt.endpoints, tt.headers)
+			if err != nil {
+				t.Fatalf("BuildMcpClientSnippet() error = %v", err)
+			}
+			if !jsonEqual(t, snippet.Config, tt.wantConfig) {
+				t.Errorf("Config = %s, want %s", snippet.Config, tt.wantConfig)
+			}
+		})
+	}
+
+	if _, err := utils.BuildMcpClientSnippet("windsurf", endpoints, nil); err == nil {
+		t.Error("BuildMcpClientSnippet() with unsupported client error = nil")
+	}
+}
+
+func TestBuildMcpClientSnippetCurl(t *testing.T) {
+	headers := map[string]string{"Authorization": "Bearer it's"}
+	snippet, _ := utils.BuildMcpClientSnippet(utils.McpClientGeneric,
+		[]utils.McpClientEndpoint{{Name: "a", URL: "https://mcp.example.com/a", Protocol: "streamable-http"}}, headers)
+	for _, want := range []string{"curl -sS -X POST", `-H 'Authorization: Bearer it'\''s'`, `"method":"initialize"`, "'https://mcp.example.com/a'"} {
+		if !strings.Contains(snippet.Curl, want) {
+			t.Errorf("Curl = %s, want it to contain %s", snippet.Curl, want)
+		}
+	}
+
+	snippet, _ = utils.BuildMcpClientSnippet(utils.McpClientGeneric,
+		[]utils.McpClientEndpoint{{Name: "a", URL: "https://mcp.example.com/a/sse"}}, nil)
+	if want := "curl -sS -N -H 'Accept: text/event-stream' 'https://mcp.example.com/a/sse'"; snippet.Curl != want {
+		t.Errorf("Curl = %s, want %s", snippet.Curl, want)
+	}
+}
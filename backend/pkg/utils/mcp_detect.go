@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// Confidence of a detected MCP protocol
+const (
+	// McpDetectConfidenceHigh the initialize handshake completed
+	McpDetectConfidenceHigh = "high"
+	// McpDetectConfidenceMedium the server answered in a protocol specific way without completing
+	// the handshake, e.g. a JSON-RPC error because authentication is required
+	McpDetectConfidenceMedium = "medium"
+	// McpDetectConfidenceLow guessed from content types or the URL suffix
+	McpDetectConfidenceLow = "low"
+)
+
+// McpProtocolDetection result of DetectMcpProtocol
+type McpProtocolDetection struct {
+	// Protocol sse or streamable-http, empty when the protocol could not be determined
+	Protocol   string
+	Confidence string
+	// ServerName, ServerVersion and ProtocolVersion come from the initialize result, empty unless
+	// the handshake completed
+	ServerName      string
+	ServerVersion   string
+	ProtocolVersion string
+	// Evidence observations the result is based on, in probe order
+	Evidence []string
+}
+
+// DetectMcpProtocol probes an MCP server URL to find out whether it speaks SSE or streamable HTTP.
+// Both initialize handshakes are attempted, the SSE one first when the URL looks like an SSE endpoint.
+// Each attempt gets half of the timeout, so a stream that never announces an endpoint does not
+// starve the other attempt. options.Protocol is ignored.
+func DetectMcpProtocol(ctx context.Context, options MCPProbeOptions) *McpProtocolDetection {
+	detection := &McpProtocolDetection{}
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.URL == "" {
+		detection.Evidence = append(detection.Evidence, "URL cannot be empty")
+		return detection
+	}
+
+	sse, streamable := model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String()
+	order := []string{streamable, sse}
+	if isSSEStyleURL(options.URL) {
+		order = []string{sse, streamable}
+	}
+
+	handshakes := make(map[string]*mcpHandshake, len(order))
+	for _, protocol := range order {
+		attemptCtx, cancel := context.WithTimeout(ctx, options.Timeout/2)
+		var handshake *mcpHandshake
+		if protocol == sse {
+			handshake = initializeSSE(attemptCtx, options)
+		} else {
+			handshake = initializeStreamableHTTP(attemptCtx, options)
+		}
+		cancel()
+		handshakes[protocol] = handshake
+		detection.Evidence = append(detection.Evidence, describeHandshake(protocol, handshake))
+
+		if handshake.err == nil {
+			detection.Protocol, detection.Confidence = protocol, McpDetectConfidenceHigh
+			if result := handshake.result; result != nil {
+				detection.ServerName = result.ServerInfo.Name
+				detection.ServerVersion = result.ServerInfo.Version
+				detection.ProtocolVersion = result.ProtocolVersion
+			}
+			return detection
+		}
+	}
+
+	sseHandshake, streamableHandshake := handshakes[sse], handshakes[streamable]
+	switch {
+	case sseHandshake.endpoint != "":
+		detection.Protocol, detection.Confidence = sse, McpDetectConfidenceMedium
+	case streamableHandshake.jsonRPC:
+		detection.Protocol, detection.Confidence = streamable, McpDetectConfidenceMedium
+	case isSuccessStatus(sseHandshake.status) && strings.HasPrefix(sseHandshake.contentType, "text/event-stream"):
+		detection.Protocol, detection.Confidence = sse, McpDetectConfidenceLow
+	case strings.HasPrefix(streamableHandshake.contentType, "text/event-stream"):
+		detection.Protocol, detection.Confidence = streamable, McpDetectConfidenceLow
+	case isSSEStyleURL(options.URL):
+		detection.Protocol, detection.Confidence = sse, McpDetectConfidenceLow
+		detection.Evidence = append(detection.Evidence, "URL path ends with /sse")
+	case strings.HasSuffix(urlPath(options.URL), "/mcp"):
+		detection.Protocol, detection.Confidence = streamable, McpDetectConfidenceLow
+		detection.Evidence = append(detection.Evidence, "URL path ends with /mcp")
+	}
+	return detection
+}
+
+// describeHandshake one line of evidence for a handshake attempt
+func describeHandshake(protocol string, h *mcpHandshake) string {
+	var details []string
+	if h.status != 0 {
+		details = append(details, fmt.Sprintf("status %d", h.status))
+	}
+	if h.contentType != "" {
+		details = append(details, "content type "+h.contentType)
+	}
+	if h.endpoint != "" {
+		details = append(details, "endpoint "+h.endpoint)
+	}
+	outcome := "initialize handshake completed"
+	if h.err != nil {
+		outcome = h.err.Error()
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("%s: %s", protocol, outcome)
+	}
+	return fmt.Sprintf("%s: %s (%s)", protocol, outcome, strings.Join(details, ", "))
+}
+
+// isSSEStyleURL reports whether the URL path ends with /sse, the conventional SSE endpoint
+func isSSEStyleURL(rawURL string) bool {
+	return strings.HasSuffix(urlPath(rawURL), "/sse")
+}
+
+// urlPath lower-cased URL path without a trailing slash, empty when the URL is invalid
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Path), "/")
+}
+
+// isSuccessStatus reports whether status is 2xx
+func isSuccessStatus(status int) bool {
+	return status >= 200 && status < 300
+}
@@ -0,0 +1,99 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+// newStreamableServer serves a streamable HTTP initialize result at /mcp
+func newStreamableServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `event: message`+"\n"+`data: {"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26","serverInfo":{"name":"weather","version":"1.2.0"}}}`+"\n\n")
+		case http.MethodDelete:
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newSSEServer serves an SSE stream at /sse, authorized tells whether initialize is answered
+func newSSEServer(t *testing.T, authorized bool) *httptest.Server {
+	messages := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /message?sessionId=1\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case msg := <-messages:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID int `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		messages <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"serverInfo":{"name":"files","version":"0.3"}}}`, req.ID)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDetectMcpProtocol(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":null,"error":{"code":-32001,"message":"unauthorized"}}`)
+	}))
+	defer unauthorized.Close()
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+
+	tests := []struct {
+		name           string
+		url            string
+		wantProtocol   string
+		wantConfidence string
+		wantServer     string
+	}{
+		{"streamable handshake", newStreamableServer(t).URL + "/mcp", "streamable-http", utils.McpDetectConfidenceHigh, "weather"},
+		{"sse handshake", newSSEServer(t, true).URL + "/sse", "sse", utils.McpDetectConfidenceHigh, "files"},
+		{"sse endpoint without initialize", newSSEServer(t, false).URL + "/sse", "sse", utils.McpDetectConfidenceMedium, ""},
+		{"streamable authentication required", unauthorized.URL + "/v1", "streamable-http", utils.McpDetectConfidenceMedium, ""},
+		{"suffix only", notFound.URL + "/mcp/", "streamable-http", utils.McpDetectConfidenceLow, ""},
+		{"not detected", notFound.URL + "/api", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.DetectMcpProtocol(t.Context(), utils.MCPProbeOptions{URL: tt.url, Timeout: 2 * time.Second})
+			if got.Protocol != tt.wantProtocol || got.Confidence != tt.wantConfidence || got.ServerName != tt.wantServer {
+				t.Errorf("DetectMcpProtocol() = %s/%s server %q, want %s/%s server %q (evidence: %v)",
+					got.Protocol, got.Confidence, got.ServerName, tt.wantProtocol, tt.wantConfidence, tt.wantServer, got.Evidence)
+			}
+		})
+	}
+}
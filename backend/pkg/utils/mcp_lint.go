@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// Severity of a lint finding
+const (
+	// McpLintSeverityError the configuration is rejected when deploying
+	McpLintSeverityError = "error"
+	// McpLintSeverityWarning the configuration is accepted but probably not what was intended
+	McpLintSeverityWarning = "warning"
+)
+
+// McpLintFinding a single lint finding, Path locates the field like McpConfigError.Path
+type McpLintFinding struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// McpLintOptions options of LintMcpConfig
+type McpLintOptions struct {
+	// Probe performs an MCP initialize handshake against every url based server
+	Probe bool
+	// ProbeTimeout timeout of probing one server, defaults to 10 seconds
+	ProbeTimeout time.Duration
+}
+
+// McpLintResult result of LintMcpConfig
+type McpLintResult struct {
+	// Valid no error findings, warnings do not make a configuration invalid
+	Valid    bool               `json:"valid"`
+	Servers  []McpServerSummary `json:"servers,omitempty"`
+	Findings []McpLintFinding   `json:"findings"`
+}
+
+// Errors number of error findings
+func (r *McpLintResult) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == McpLintSeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// add appends findings and keeps Valid in sync
+func (r *McpLintResult) add(findings ...McpLintFinding) {
+	r.Findings = append(r.Findings, findings...)
+	r.Valid = r.Errors() == 0
+}
+
+// lintServer the fields of a server entry the lint checks look at beyond McpServerConfig
+type lintServer struct {
+	McpServerConfig
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// LintMcpConfig validates an mcpServers configuration like ValidateMcpConfig and adds warnings for
+// settings that are accepted but likely mistakes. With options.Probe every url based server is probed
+// concurrently; servers that do not answer the initialize handshake are errors and servers speaking
+// another protocol are warnings. Nothing is written anywhere, so it is safe to run from CI.
+func LintMcpConfig(ctx context.Context, configData []byte, options McpLintOptions) *McpLintResult {
+	result := &McpLintResult{Valid: true, Findings: []McpLintFinding{}}
+	validation, err := ValidateMcpConfig(configData)
+	if err != nil {
+		result.add(McpLintFinding{Severity: McpLintSeverityError, Path: mcpServersField, Message: err.Error()})
+		return result
+	}
+	result.Servers = validation.Servers
+	for _, e := range validation.Errors {
+		result.add(McpLintFinding{Severity: McpLintSeverityError, Path: e.Path, Message: e.Message})
+	}
+	if !validation.IsValid {
+		return result
+	}
+
+	var config struct {
+		McpServers map[string]lintServer `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(configData, &config); err != nil {
+		result.add(McpLintFinding{Severity: McpLintSeverityError, Path: mcpServersField, Message: err.Error()})
+		return result
+	}
+	for _, server := range validation.Servers {
+		result.add(lintServerWarnings(mcpServersField+"."+server.Name, server, config.McpServers[server.Name])...)
+	}
+	if options.Probe {
+		result.add(probeServers(ctx, validation.Servers, config.McpServers, options.ProbeTimeout)...)
+	}
+	return result
+}
+
+// lintServerWarnings settings of a valid server entry that are probably mistakes
+func lintServerWarnings(path string, summary McpServerSummary, server lintServer) []McpLintFinding {
+	var findings []McpLintFinding
+	warn := func(field, format string, args ...any) {
+		findings = append(findings, McpLintFinding{Severity: McpLintSeverityWarning, Path: path + field, Message: fmt.Sprintf(format, args...)})
+	}
+	if summary.ProtocolType == model.McpProtocolStdio.String() {
+		if server.URL != "" {
+			warn(".url", "is ignored for %s servers", summary.ProtocolType)
+		}
+		return findings
+	}
+	if server.Command != "" {
+		warn(".command", "is ignored for %s servers", summary.ProtocolType)
+	}
+	if u, err := url.Parse(server.URL); err == nil && u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		warn(".url", "uses plain http, requests and headers are sent unencrypted")
+	}
+	return findings
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// probeServers probes the url based servers concurrently, findings keep the server order
+func probeServers(ctx context.Context, summaries []McpServerSummary, servers map[string]lintServer, timeout time.Duration) []McpLintFinding {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	results := make([][]McpLintFinding, len(summaries))
+	var wg sync.WaitGroup
+	for i, summary := range summaries {
+		if summary.Url == "" || summary.ProtocolType == model.McpProtocolStdio.String() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeServer(ctx, mcpServersField+"."+summary.Name, summary, servers[summary.Name].Headers, timeout)
+		}()
+	}
+	wg.Wait()
+
+	var findings []McpLintFinding
+	for _, r := range results {
+		findings = append(findings, r...)
+	}
+	return findings
+}
+
+// probeServer performs the initialize handshake against one server
+func probeServer(ctx context.Context, path string, summary McpServerSummary, headers map[string]string, timeout time.Duration) []McpLintFinding {
+	detection := DetectMcpProtocol(ctx, MCPProbeOptions{URL: summary.Url, Headers: headers, Timeout: timeout})
+	// A low confidence protocol is only guessed from content types or the URL, the server did not answer initialize
+	if detection.Protocol == "" || detection.Confidence == McpDetectConfidenceLow {
+		return []McpLintFinding{{
+			Severity: McpLintSeverityError,
+			Path:     path + ".url",
+			Message:  "unreachable or not an MCP server: " + strings.Join(detection.Evidence, "; "),
+		}}
+	}
+	if detection.Protocol != summary.ProtocolType {
+		return []McpLintFinding{{
+			Severity: McpLintSeverityWarning,
+			Path:     path,
+			Message:  fmt.Sprintf("server speaks %s (%s confidence), configured as %s", detection.Protocol, detection.Confidence, summary.ProtocolType),
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package utils_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestLintMcpConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		valid    bool
+		findings []utils.McpLintFinding
+	}{
+		{
+			name:     "valid",
+			config:   `{"mcpServers":{"github":{"url":"https://example.com/mcp"}}}`,
+			valid:    true,
+			findings: []utils.McpLintFinding{},
+		},
+		{
+			name:   "errors",
+			config: `{"mcpServers":{"github":{"type":"sse"}}}`,
+			findings: []utils.McpLintFinding{
+				{Severity: utils.McpLintSeverityError, Path: "mcpServers.github.url", Message: "sse protocol must contain a valid url field"},
+			},
+		},
+		{
+			name:   "warnings",
+			config: `{"mcpServers":{"a":{"url":"http://example.com/mcp","command":"npx"},"b":{"type":"stdio","command":"npx","url":"http://localhost/mcp"}}}`,
+			valid:  true,
+			findings: []utils.McpLintFinding{
+				{Severity: utils.McpLintSeverityWarning, Path: "mcpServers.a.command", Message: "is ignored for streamable-http servers"},
+				{Severity: utils.McpLintSeverityWarning, Path: "mcpServers.a.url", Message: "uses plain http, requests and headers are sent unencrypted"},
+				{Severity: utils.McpLintSeverityWarning, Path: "mcpServers.b.url", Message: "is ignored for stdio servers"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := utils.LintMcpConfig(context.Background(), []byte(tt.config), utils.McpLintOptions{})
+			if result.Valid != tt.valid {
+				t.Errorf("Valid = %v, want %v, findings %+v", result.Valid, tt.valid, result.Findings)
+			}
+			if fmt.Sprint(result.Findings) != fmt.Sprint(tt.findings) {
+				t.Errorf("Findings = %+v, want %+v", result.Findings, tt.findings)
+			}
+		})
+	}
+}
+
+func TestLintMcpConfigProbe(t *testing.T) {
+	server := newStreamableServer(t)
+	config := fmt.Sprintf(`{"mcpServers":{"ok":{"url":"%s/mcp"},"sse":{"type":"sse","url":"%s/mcp"},"down":{"url":"http://127.0.0.1:1/mcp"},"local":{"command":"npx"}}}`,
+		server.URL, server.URL)
+
+	result := utils.LintMcpConfig(context.Background(), []byte(config), utils.McpLintOptions{Probe: true, ProbeTimeout: 2 * time.Second})
+	if result.Valid || result.Errors() != 1 {
+		t.Fatalf("result = %+v, want one error", result)
+	}
+	if f := result.Findings[0]; f.Severity != utils.McpLintSeverityError || f.Path != "mcpServers.down.url" {
+		t.Errorf("first finding = %+v, want unreachable server", f)
+	}
+	if len(result.Findings) != 2 {
+		t.Fatalf("findings = %+v, want 2", result.Findings)
+	}
+	if f := result.Findings[1]; f.Severity != utils.McpLintSeverityWarning || f.Path != "mcpServers.sse" {
+		t.Errorf("second finding = %+v, want protocol mismatch", f)
+	}
+}
@@ -0,0 +1,334 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// mcpProbeProtocolVersion MCP protocol version sent in the initialize request
+const mcpProbeProtocolVersion = "2025-03-26"
+
+// maxProbeResponseSize max bytes read from a JSON initialize response
+const maxProbeResponseSize = 1 << 20
+
+// MCPProbeOptions MCP initialize handshake probe options
+type MCPProbeOptions struct {
+	URL      string            // MCP server URL
+	Protocol string            // sse or streamable-http
+	Headers  map[string]string // extra request headers, e.g. authorization
+	Timeout  time.Duration     // timeout of the whole handshake
+}
+
+// sseEvent a single server-sent event
+type sseEvent struct {
+	name string
+	data string
+}
+
+// McpInitializeResult server information returned in the initialize result
+type McpInitializeResult struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
+
+// mcpHandshake outcome of an initialize handshake attempt, also used as evidence by protocol detection
+type mcpHandshake struct {
+	status      int
+	contentType string
+	// endpoint message endpoint announced by an SSE stream
+	endpoint string
+	// jsonRPC the server answered the initialize request with a JSON-RPC message, possibly an error
+	jsonRPC bool
+	// result initialize result, set when the handshake succeeded
+	result *McpInitializeResult
+	err    error
+}
+
+// fail records the failure of the handshake and returns it
+func (h *mcpHandshake) fail(status int, format string, args ...interface{}) *mcpHandshake {
+	if status != 0 {
+		h.status = status
+	}
+	h.err = fmt.Errorf(format, args...)
+	return h
+}
+
+// ProbeMCPInitialize performs an MCP initialize handshake, succeeds when the server returns an initialize result
+// SSE servers are probed by opening the event stream and posting the request to the announced endpoint
+func ProbeMCPInitialize(ctx context.Context, options MCPProbeOptions) *HTTPProbeResult {
+	start := time.Now()
+	result := &HTTPProbeResult{
+		Success: false,
+	}
+
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.URL == "" {
+		result.Error = "URL cannot be empty"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	var handshake *mcpHandshake
+	if options.Protocol == "sse" {
+		handshake = initializeSSE(ctx, options)
+	} else {
+		handshake = initializeStreamableHTTP(ctx, options)
+	}
+	result.StatusCode = handshake.status
+	result.Latency = time.Since(start)
+	if handshake.err != nil {
+		result.Error = handshake.err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// initializeStreamableHTTP posts the initialize request, the result is returned as JSON or as an event stream
+func initializeStreamableHTTP(ctx context.Context, options MCPProbeOptions) *mcpHandshake {
+	h := &mcpHandshake{}
+	req, err := newMCPProbeRequest(ctx, http.MethodPost, options.URL, options.Headers, mcpInitializeRequest())
+	if err != nil {
+		return h.fail(0, "%v", err)
+	}
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return h.fail(0, "request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	h.status = resp.StatusCode
+	h.contentType = resp.Header.Get("Content-Type")
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		defer closeMCPSession(options, sessionID)
+	}
+
+	if strings.HasPrefix(h.contentType, "text/event-stream") {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return h.fail(0, "expected 2xx status code, got: %d", resp.StatusCode)
+		}
+		h.jsonRPC, h.result, h.err = readInitializeEvent(bufio.NewReader(resp.Body))
+		return h
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeResponseSize))
+	if err != nil {
+		return h.fail(0, "failed to read response: %v", err)
+	}
+	// Error statuses such as 401 may still carry a JSON-RPC error, protocol detection uses it as evidence
+	h.jsonRPC = isJSONRPCMessage(body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return h.fail(0, "expected 2xx status code, got: %d", resp.StatusCode)
+	}
+	_, h.result, h.err = parseInitializeResponse(body)
+	return h
+}
+
+// initializeSSE opens the event stream, posts the initialize request to the endpoint event and waits for the result
+func initializeSSE(ctx context.Context, options MCPProbeOptions) *mcpHandshake {
+	h := &mcpHandshake{}
+	req, err := newMCPProbeRequest(ctx, http.MethodGet, options.URL, options.Headers, nil)
+	if err != nil {
+		return h.fail(0, "%v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return h.fail(0, "request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	h.status = resp.StatusCode
+	h.contentType = resp.Header.Get("Content-Type")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return h.fail(0, "expected 2xx status code, got: %d", resp.StatusCode)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for h.endpoint == "" {
+		event, err := readSSEEvent(reader)
+		if err != nil {
+			return h.fail(0, "no endpoint event received: %v", err)
+		}
+		if event.name == "endpoint" {
+			h.endpoint = strings.TrimSpace(event.data)
+		}
+	}
+	base, err := url.Parse(options.URL)
+	if err != nil {
+		return h.fail(0, "failed to parse URL: %v", err)
+	}
+	messageURL, err := base.Parse(h.endpoint)
+	if err != nil {
+		return h.fail(0, "invalid endpoint %q: %v", h.endpoint, err)
+	}
+
+	postReq, err := newMCPProbeRequest(ctx, http.MethodPost, messageURL.String(), options.Headers, mcpInitializeRequest())
+	if err != nil {
+		return h.fail(0, "%v", err)
+	}
+	postResp, err := http.DefaultClient.Do(postReq)
+	if err != nil {
+		return h.fail(0, "initialize request failed: %v", err)
+	}
+	postResp.Body.Close()
+	if postResp.StatusCode < 200 || postResp.StatusCode >= 300 {
+		return h.fail(postResp.StatusCode, "initialize request expected 2xx status code, got: %d", postResp.StatusCode)
+	}
+
+	h.jsonRPC, h.result, h.err = readInitializeEvent(reader)
+	return h
+}
+
+// newMCPProbeRequest creates a probe request with the extra headers, body is sent as JSON
+func newMCPProbeRequest(ctx context.Context, method, rawURL string, headers map[string]string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", probeUserAgent)
+	return req, nil
+}
+
+// closeMCPSession terminates the session created by the probe, errors are ignored
+func closeMCPSession(options MCPProbeOptions, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+	req, err := newMCPProbeRequest(ctx, http.MethodDelete, options.URL, options.Headers, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// mcpInitializeRequest JSON-RPC initialize request of the probe
+func mcpInitializeRequest() []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": mcpProbeProtocolVersion,
+			"capabilities":    map[string]interface{}{},
+			"clientInfo": map[string]string{
+				"name":    "qm-mcp-server-health-checker",
+				"version": "1.0",
+			},
+		},
+	})
+	return body
+}
+
+// readInitializeEvent reads message events until the initialize response arrives,
+// jsonRPC reports whether a JSON-RPC response to the request was received
+func readInitializeEvent(reader *bufio.Reader) (jsonRPC bool, result *McpInitializeResult, err error) {
+	for {
+		event, err := readSSEEvent(reader)
+		if err != nil {
+			return false, nil, fmt.Errorf("no initialize response received: %v", err)
+		}
+		if event.name != "" && event.name != "message" {
+			continue
+		}
+		matched, result, err := parseInitializeResponse([]byte(event.data))
+		if matched {
+			return isJSONRPCMessage([]byte(event.data)), result, err
+		}
+	}
+}
+
+// parseInitializeResponse checks a JSON-RPC message, matched is false when it is not the initialize response
+func parseInitializeResponse(data []byte) (matched bool, result *McpInitializeResult, err error) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return true, nil, fmt.Errorf("invalid initialize response: %v", err)
+	}
+	if string(msg.ID) != "1" {
+		return false, nil, nil
+	}
+	if msg.Error != nil {
+		return true, nil, fmt.Errorf("initialize failed: %d %s", msg.Error.Code, msg.Error.Message)
+	}
+	if len(msg.Result) == 0 || string(msg.Result) == "null" {
+		return true, nil, fmt.Errorf("initialize response has no result")
+	}
+	result = &McpInitializeResult{}
+	// serverInfo is optional, a malformed one does not fail the handshake
+	_ = json.Unmarshal(msg.Result, result)
+	return true, result, nil
+}
+
+// isJSONRPCMessage reports whether data is a JSON-RPC 2.0 message
+func isJSONRPCMessage(data []byte) bool {
+	var msg struct {
+		JSONRPC string `json:"jsonrpc"`
+	}
+	return json.Unmarshal(data, &msg) == nil && msg.JSONRPC == "2.0"
+}
+
+// readSSEEvent reads the next event of a server-sent event stream, multi-line data is joined with "\n"
+func readSSEEvent(reader *bufio.Reader) (*sseEvent, error) {
+	event := &sseEvent{}
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(data) > 0 || event.name != "" {
+				event.data = strings.Join(data, "\n")
+				return event, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.name = value
+		case "data":
+			data = append(data, value)
+		}
+		if err != nil {
+			event.data = strings.Join(data, "\n")
+			return event, nil
+		}
+	}
+}
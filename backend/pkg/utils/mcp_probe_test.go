@@ -0,0 +1,90 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestProbeMCPInitializeStreamableHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		want     bool
+	}{
+		{"result", `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26"}}`, http.StatusOK, true},
+		{"error", `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"bad request"}}`, http.StatusOK, false},
+		{"status", `unauthorized`, http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodDelete {
+					return
+				}
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("missing authorization header")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			result := utils.ProbeMCPInitialize(t.Context(), utils.MCPProbeOptions{
+				URL:      server.URL + "/mcp",
+				Protocol: "streamable-http",
+				Headers:  map[string]string{"Authorization": "Bearer token"},
+				Timeout:  2 * time.Second,
+			})
+			if result.Success != tt.want {
+				t.Errorf("ProbeMCPInitialize() success = %v, want %v (error: %s)", result.Success, tt.want, result.Error)
+			}
+		})
+	}
+}
+
+func TestProbeMCPInitializeSSE(t *testing.T) {
+	messages := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /message?sessionId=1\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case msg := <-messages:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "initialize" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		messages <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{}}`, req.ID)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	result := utils.ProbeMCPInitialize(t.Context(), utils.MCPProbeOptions{
+		URL:      server.URL + "/sse",
+		Protocol: "sse",
+		Timeout:  2 * time.Second,
+	})
+	if !result.Success {
+		t.Errorf("ProbeMCPInitialize() failed: %s", result.Error)
+	}
+}
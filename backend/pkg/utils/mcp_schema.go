@@ -0,0 +1,527 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"qm-mcp-server/pkg/database/model"
+)
+
+// mcpServersField root field of the MCP configuration
+const mcpServersField = "mcpServers"
+
+// McpConfigError a single MCP configuration error, Path locates the failing field,
+// e.g. mcpServers.github.url
+type McpConfigError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *McpConfigError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// mcpFieldKind expected JSON type of a server field
+type mcpFieldKind int
+
+const (
+	kindString mcpFieldKind = iota
+	kindStringList
+	kindStringMap
+	kindURL
+	kindSeconds
+	kindBytes
+	kindBool
+	kindTLS
+	kindProxy
+	kindProxyURL
+	kindPolicy
+	kindPatternList
+	kindRedaction
+	kindRegexList
+	kindMillis
+	kindRoutes
+	kindPercent
+	kindCORS
+	kindOriginList
+)
+
+// mcpServerFields fields accepted in a server entry and their expected types
+var mcpServerFields = map[string]mcpFieldKind{
+	"command":        kindString,
+	"args":           kindStringList,
+	"env":            kindStringMap,
+	"cwd":            kindString,
+	"type":           kindString,
+	"transport":      kindString,
+	"url":            kindURL,
+	"headers":        kindStringMap,
+	"timeout":        kindSeconds,
+	"sseReadTimeout": kindSeconds,
+	"disabled":       kindBool,
+	"autoApprove":    kindStringList,
+	"description":    kindString,
+	// 网关大小限制的实例级覆盖
+	"maxRequestBodySize":    kindBytes,
+	"maxResponseBufferSize": kindBytes,
+	// 网关 SSE 心跳的实例级覆盖
+	"sseHeartbeat":         kindBool,
+	"sseHeartbeatInterval": kindSeconds,
+	// 网关连接 HTTPS 上游的 TLS 配置
+	"tls": kindTLS,
+	// 出站代理，覆盖网关全局配置
+	"proxy": kindProxy,
+	// 网关 JSON-RPC 方法和工具访问策略
+	"policy": kindPolicy,
+	// 网关响应脱敏规则
+	"redaction": kindRedaction,
+	// 网关金丝雀路由
+	"routes": kindRoutes,
+	// 网关跨域访问策略
+	"cors": kindCORS,
+}
+
+// mcpTLSFields fields accepted in the tls section of a server entry
+var mcpTLSFields = map[string]mcpFieldKind{
+	"caCert":             kindString,
+	"insecureSkipVerify": kindBool,
+	"clientCert":         kindString,
+	"clientKey":          kindString,
+}
+
+// mcpProxyFields fields accepted in the proxy section of a server entry
+var mcpProxyFields = map[string]mcpFieldKind{
+	"httpProxy":  kindProxyURL,
+	"httpsProxy": kindProxyURL,
+	"noProxy":    kindString,
+}
+
+// mcpPolicyFields fields accepted in the policy section of a server entry
+var mcpPolicyFields = map[string]mcpFieldKind{
+	"allowMethods":   kindPatternList,
+	"denyMethods":    kindPatternList,
+	"allowTools":     kindPatternList,
+	"denyTools":      kindPatternList,
+	"failOpen":       kindBool,
+	"maxInspectSize": kindBytes,
+}
+
+// mcpRedactionFields fields accepted in the redaction section of a server entry
+var mcpRedactionFields = map[string]mcpFieldKind{
+	"patterns":     kindRegexList,
+	"literals":     kindStringList,
+	"maxBodySize":  kindBytes,
+	"timeBudgetMs": kindMillis,
+}
+
+// mcpRouteFields fields accepted in each entry of the routes section of a server entry
+var mcpRouteFields = map[string]mcpFieldKind{
+	"targetURL":   kindURL,
+	"weight":      kindPercent,
+	"matchHeader": kindString,
+}
+
+// mcpCORSFields fields accepted in the cors section of a server entry
+var mcpCORSFields = map[string]mcpFieldKind{
+	"allowedOrigins":   kindOriginList,
+	"allowedMethods":   kindStringList,
+	"allowedHeaders":   kindStringList,
+	"exposedHeaders":   kindStringList,
+	"allowCredentials": kindBool,
+	"maxAge":           kindSeconds,
+}
+
+// validateMcpConfigSchema checks the structure of an mcpServers configuration and
+// returns every field error found
+func validateMcpConfigSchema(configData []byte) []*McpConfigError {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(configData, &root); err != nil {
+		return []*McpConfigError{{Message: jsonErrorMessage(configData, err)}}
+	}
+
+	var errs []*McpConfigError
+	for _, key := range sortedKeys(root) {
+		if key != mcpServersField {
+			errs = append(errs, unknownFieldError(key, key, []string{mcpServersField}))
+		}
+	}
+
+	rawServers, ok := root[mcpServersField]
+	if !ok || isJSONNull(rawServers) {
+		return append(errs, &McpConfigError{Path: mcpServersField, Message: "is required"})
+	}
+	var servers map[string]json.RawMessage
+	if err := json.Unmarshal(rawServers, &servers); err != nil {
+		return append(errs, &McpConfigError{Path: mcpServersField, Message: "must be an object"})
+	}
+	if len(servers) == 0 {
+		errs = append(errs, &McpConfigError{Path: mcpServersField, Message: "must contain at least one server"})
+	}
+
+	for _, name := range sortedKeys(servers) {
+		path := mcpServersField + "." + name
+		if !isValidServiceName(name) {
+			errs = append(errs, &McpConfigError{
+				Path:    path,
+				Message: "invalid server name, must start with a letter and contain only letters, digits, '_' or '-'",
+			})
+		}
+		errs = append(errs, validateMcpServerSchema(path, servers[name])...)
+	}
+	return errs
+}
+
+// validateMcpServerSchema checks a single server entry
+func validateMcpServerSchema(path string, raw json.RawMessage) []*McpConfigError {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return []*McpConfigError{{Path: path, Message: "must be an object"}}
+	}
+
+	known := make([]string, 0, len(mcpServerFields))
+	for field := range mcpServerFields {
+		known = append(known, field)
+	}
+	sort.Strings(known)
+
+	var errs []*McpConfigError
+	for _, key := range sortedKeys(fields) {
+		kind, ok := mcpServerFields[key]
+		if !ok {
+			errs = append(errs, unknownFieldError(path+"."+key, key, known))
+			continue
+		}
+		errs = append(errs, validateMcpField(path+"."+key, kind, fields[key])...)
+	}
+	return errs
+}
+
+// validateMcpField checks a server field against its expected type
+func validateMcpField(path string, kind mcpFieldKind, raw json.RawMessage) []*McpConfigError {
+	switch kind {
+	case kindString:
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be a string"}}
+		}
+	case kindURL:
+		var s string
+		if json.Unmarshal(raw, &s) != nil || !isHTTPURL(s) {
+			return []*McpConfigError{{Path: path, Message: "must be a valid http(s) URL"}}
+		}
+	case kindBool:
+		var b bool
+		if json.Unmarshal(raw, &b) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be a boolean"}}
+		}
+	case kindSeconds, kindBytes, kindMillis:
+		var n float64
+		if json.Unmarshal(raw, &n) != nil || n < 0 || n != float64(int64(n)) {
+			return []*McpConfigError{{Path: path, Message: "must be a non-negative integer"}}
+		}
+	case kindStringList:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be an array of strings"}}
+		}
+		var errs []*McpConfigError
+		for i, item := range items {
+			var s string
+			if json.Unmarshal(item, &s) != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must be a string"})
+			}
+		}
+		return errs
+	case kindTLS:
+		return validateMcpTLS(path, raw)
+	case kindProxy:
+		return validateMcpSection(path, raw, mcpProxyFields)
+	case kindPolicy:
+		return validateMcpSection(path, raw, mcpPolicyFields)
+	case kindPercent:
+		var n float64
+		if json.Unmarshal(raw, &n) != nil || n < 0 || n > 100 || n != float64(int64(n)) {
+			return []*McpConfigError{{Path: path, Message: "must be an integer between 0 and 100"}}
+		}
+	case kindCORS:
+		return validateMcpCORS(path, raw)
+	case kindOriginList:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be an array of strings"}}
+		}
+		var errs []*McpConfigError
+		for i, item := range items {
+			var s string
+			if json.Unmarshal(item, &s) != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must be a string"})
+				continue
+			}
+			if err := model.ValidateCORSOrigin(s); err != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: err.Error()})
+			}
+		}
+		return errs
+	case kindRoutes:
+		return validateMcpRoutes(path, raw)
+	case kindRedaction:
+		return validateMcpSection(path, raw, mcpRedactionFields)
+	case kindRegexList:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be an array of strings"}}
+		}
+		var errs []*McpConfigError
+		for i, item := range items {
+			var s string
+			if json.Unmarshal(item, &s) != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must be a string"})
+				continue
+			}
+			if s == "" {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must not be empty"})
+				continue
+			}
+			if _, err := regexp.Compile(s); err != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: fmt.Sprintf("invalid regular expression: %v", err)})
+			}
+		}
+		return errs
+	case kindPatternList:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be an array of strings"}}
+		}
+		var errs []*McpConfigError
+		for i, item := range items {
+			var s string
+			if json.Unmarshal(item, &s) != nil {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must be a string"})
+				continue
+			}
+			if s == "" || strings.Contains(strings.TrimSuffix(s, "*"), "*") {
+				errs = append(errs, &McpConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: "must be a name, '*' is only allowed at the end"})
+			}
+		}
+		return errs
+	case kindProxyURL:
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be a string"}}
+		}
+		if s == "" {
+			return nil
+		}
+		if err := model.ValidateProxyURL(s); err != nil {
+			return []*McpConfigError{{Path: path, Message: err.Error()}}
+		}
+	case kindStringMap:
+		var entries map[string]json.RawMessage
+		if json.Unmarshal(raw, &entries) != nil {
+			return []*McpConfigError{{Path: path, Message: "must be an object of string values"}}
+		}
+		var errs []*McpConfigError
+		for _, key := range sortedKeys(entries) {
+			var s string
+			if json.Unmarshal(entries[key], &s) != nil {
+				errs = append(errs, &McpConfigError{Path: path + "." + key, Message: "must be a string"})
+			}
+		}
+		return errs
+	}
+	return nil
+}
+
+// validateMcpSection checks a nested object of a server entry against its known fields
+func validateMcpSection(path string, raw json.RawMessage, known map[string]mcpFieldKind) []*McpConfigError {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return []*McpConfigError{{Path: path, Message: "must be an object"}}
+	}
+	var errs []*McpConfigError
+	for _, key := range sortedKeys(fields) {
+		kind, ok := known[key]
+		if !ok {
+			errs = append(errs, unknownFieldError(path+"."+key, key, sortedKeys(known)))
+			continue
+		}
+		errs = append(errs, validateMcpField(path+"."+key, kind, fields[key])...)
+	}
+	return errs
+}
+
+// validateMcpTLS checks the tls section of a server entry, certificates and keys must be valid PEM
+func validateMcpTLS(path string, raw json.RawMessage) []*McpConfigError {
+	if errs := validateMcpSection(path, raw, mcpTLSFields); len(errs) > 0 {
+		return errs
+	}
+	var errs []*McpConfigError
+
+	var cfg struct {
+		CACert     string `json:"caCert"`
+		ClientCert string `json:"clientCert"`
+		ClientKey  string `json:"clientKey"`
+	}
+	_ = json.Unmarshal(raw, &cfg)
+	if cfg.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(cfg.CACert)) {
+		errs = append(errs, &McpConfigError{Path: path + ".caCert", Message: "must contain PEM encoded certificates"})
+	}
+	switch {
+	case cfg.ClientCert == "" && cfg.ClientKey == "":
+	case cfg.ClientCert == "" || cfg.ClientKey == "":
+		errs = append(errs, &McpConfigError{Path: path, Message: "clientCert and clientKey must be set together"})
+	default:
+		if _, err := tls.X509KeyPair([]byte(cfg.ClientCert), []byte(cfg.ClientKey)); err != nil {
+			errs = append(errs, &McpConfigError{Path: path + ".clientCert", Message: fmt.Sprintf("invalid client certificate or key: %v", err)})
+		}
+	}
+	return errs
+}
+
+// validateMcpCORS checks the cors section of a server entry, credentials cannot be allowed for any origin
+func validateMcpCORS(path string, raw json.RawMessage) []*McpConfigError {
+	if errs := validateMcpSection(path, raw, mcpCORSFields); len(errs) > 0 {
+		return errs
+	}
+	var cfg model.McpCORSConfig
+	_ = json.Unmarshal(raw, &cfg)
+	if err := model.ValidateCORSCredentials(cfg.AllowedOrigins, cfg.AllowCredentials); err != nil {
+		return []*McpConfigError{{Path: path + ".allowCredentials", Message: err.Error()}}
+	}
+	return nil
+}
+
+// validateMcpRoutes checks the routes section of a server entry, every route needs a target and
+// either a weight or a header match, and the weights must not exceed 100 in total
+func validateMcpRoutes(path string, raw json.RawMessage) []*McpConfigError {
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) != nil {
+		return []*McpConfigError{{Path: path, Message: "must be an array of objects"}}
+	}
+	var errs []*McpConfigError
+	total := 0
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if sectionErrs := validateMcpSection(itemPath, item, mcpRouteFields); len(sectionErrs) > 0 {
+			errs = append(errs, sectionErrs...)
+			continue
+		}
+		var route model.McpRouteConfig
+		_ = json.Unmarshal(item, &route)
+		if route.TargetURL == "" {
+			errs = append(errs, &McpConfigError{Path: itemPath + ".targetURL", Message: "is required"})
+		}
+		if route.Weight == 0 && strings.TrimSpace(route.MatchHeader) == "" {
+			errs = append(errs, &McpConfigError{Path: itemPath, Message: "must set a weight or a matchHeader"})
+		}
+		total += route.Weight
+	}
+	if total > 100 {
+		errs = append(errs, &McpConfigError{Path: path, Message: fmt.Sprintf("weights must not exceed 100 in total, got %d", total)})
+	}
+	return errs
+}
+
+// unknownFieldError reports an unknown field, suggesting the closest known field name
+func unknownFieldError(path, key string, known []string) *McpConfigError {
+	msg := fmt.Sprintf("unknown field %q", key)
+	if suggestion := closestField(key, known); suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return &McpConfigError{Path: path, Message: msg}
+}
+
+// closestField returns the known field within edit distance 2 of key, ignoring case
+func closestField(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, field := range known {
+		d := editDistance(strings.ToLower(key), strings.ToLower(field))
+		if d < bestDistance {
+			best, bestDistance = field, d
+		}
+	}
+	return best
+}
+
+// editDistance Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// jsonErrorMessage describes a JSON decoding error with its line and column
+func jsonErrorMessage(data []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := offsetPosition(data, syntaxErr.Offset)
+		return fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, column, syntaxErr)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return "must be a JSON object"
+	}
+	return fmt.Sprintf("invalid JSON: %v", err)
+}
+
+// offsetPosition converts the offset of a syntax error, which counts the offending
+// byte, into 1-based line and column numbers
+func offsetPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := max(len(before)-bytes.LastIndexByte(before, '\n')-1, 1)
+	return line, column
+}
+
+// isHTTPURL reports whether s is an absolute http(s) URL with a host
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// isJSONNull reports whether raw is the JSON null literal
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// sortedKeys returns map keys in sorted order, keeping error output stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
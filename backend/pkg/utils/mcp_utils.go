@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"qm-mcp-server/pkg/database/model"
+	"reflect"
 	"strings"
 	"unicode"
 )
@@ -17,6 +18,14 @@ type McpServerConfig struct {
 	URL       string   `json:"url,omitempty"`
 }
 
+// McpServerSummary validation summary of a single server entry
+type McpServerSummary struct {
+	Name         string `json:"name"`
+	ProtocolType string `json:"protocolType"`
+	Url          string `json:"url,omitempty"`
+	HasCommand   bool   `json:"hasCommand"`
+}
+
 // McpServersConfig MCP server configuration root structure
 type McpServersConfig struct {
 	McpServers map[string]McpServerConfig `json:"mcpServers"`
@@ -34,6 +43,50 @@ type McpValidationResult struct {
 	HasTransport bool   `json:"hasTransport"`
 	HasURL       bool   `json:"hasURL"`
 	Url          string `json:"url,omitempty"`
+	// Errors 每个出错字段的路径和原因，ErrorMessage 为其拼接结果
+	Errors []*McpConfigError `json:"errors,omitempty"`
+	// Servers 按名称排序的所有服务，上面的单服务字段描述其中第一个
+	Servers []McpServerSummary `json:"servers,omitempty"`
+	// Conversion 宽松模式下客户端配置的转换结果，校验的是转换后的配置
+	Conversion *McpConfigConversion `json:"conversion,omitempty"`
+}
+
+// fail records configuration errors and marks the result invalid
+func (r *McpValidationResult) fail(errs ...*McpConfigError) *McpValidationResult {
+	r.IsValid = false
+	r.Errors = append(r.Errors, errs...)
+	msgs := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	r.ErrorMessage = strings.Join(msgs, "; ")
+	return r
+}
+
+// CheckProtocol checks every server against the expected protocol, an empty protocol accepts any url based protocol,
+// requireCommand requires a single server with a start command (hosting stdio mode) instead of a url
+func (r *McpValidationResult) CheckProtocol(protocol string, requireCommand bool) []*McpConfigError {
+	if requireCommand && len(r.Servers) > 1 {
+		return []*McpConfigError{{
+			Path:    mcpServersField,
+			Message: fmt.Sprintf("hosting instances support exactly one server, got %d", len(r.Servers)),
+		}}
+	}
+	var errs []*McpConfigError
+	for _, server := range r.Servers {
+		path := mcpServersField + "." + server.Name
+		switch {
+		case requireCommand:
+			if !server.HasCommand {
+				errs = append(errs, &McpConfigError{Path: path + ".command", Message: "is required"})
+			}
+		case server.Url == "":
+			errs = append(errs, &McpConfigError{Path: path + ".url", Message: "is required"})
+		case protocol != "" && server.ProtocolType != protocol:
+			errs = append(errs, &McpConfigError{Path: path, Message: fmt.Sprintf("protocol type is %s, expected %s", server.ProtocolType, protocol)})
+		}
+	}
+	return errs
 }
 
 // ValidateMcpConfigFromString validate MCP configuration format from string
@@ -56,72 +109,67 @@ func ValidateMcpConfigFromMap(configMap map[string]interface{}) (*McpValidationR
 func ValidateMcpConfig(configData []byte) (*McpValidationResult, error) {
 	result := &McpValidationResult{}
 
-	// Parse JSON data
+	// Check structure first, so that every failing field is reported with its path
+	if errs := validateMcpConfigSchema(configData); len(errs) > 0 {
+		return result.fail(errs...), nil
+	}
+
 	var config McpServersConfig
 	if err := json.Unmarshal(configData, &config); err != nil {
-		result.ErrorMessage = fmt.Sprintf("JSON parsing failed: %v", err)
-		return result, nil
+		return result.fail(&McpConfigError{Message: jsonErrorMessage(configData, err)}), nil
 	}
 
-	// Check if mcpServers field exists
-	if config.McpServers == nil {
-		result.ErrorMessage = "missing mcpServers field"
-		return result, nil
-	}
+	validProtocols := []string{model.McpProtocolStdio.String(), model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String()}
+	var errs []*McpConfigError
+	for _, serviceName := range sortedKeys(config.McpServers) {
+		serviceConfig := config.McpServers[serviceName]
+		path := mcpServersField + "." + serviceName
 
-	// Check if there is at least one service configuration
-	if len(config.McpServers) == 0 || len(config.McpServers) > 1 {
-		result.ErrorMessage = "mcpServers cannot be empty and must contain exactly one service configuration"
-		return result, nil
-	}
+		// Determine protocol type logic
+		protocolType := determineProtocolType(serviceConfig)
+		result.Servers = append(result.Servers, McpServerSummary{
+			Name:         serviceName,
+			ProtocolType: protocolType,
+			Url:          serviceConfig.URL,
+			HasCommand:   serviceConfig.Command != "",
+		})
 
-	// Get the first service name and configuration
-	var serviceName string
-	var serviceConfig McpServerConfig
-	for name, cfg := range config.McpServers {
-		// Validate name: must be letters
-		if !isValidServiceName(name) {
-			result.ErrorMessage = fmt.Sprintf("invalid service name: %s, service name must consist of letters", name)
-			return result, nil
+		// Validate if protocol type is valid
+		validProtocol := false
+		for _, validT := range validProtocols {
+			if protocolType == validT {
+				validProtocol = true
+				break
+			}
+		}
+		if !validProtocol {
+			errs = append(errs, &McpConfigError{
+				Path:    path,
+				Message: fmt.Sprintf("invalid protocol type: %s, valid values are: %v", protocolType, validProtocols),
+			})
+			continue
+		}
+
+		// Validate required fields based on protocol type
+		if err := validateProtocolFields(path, protocolType, serviceConfig); err != nil {
+			errs = append(errs, err)
 		}
-		serviceName = name
-		serviceConfig = cfg
-		break // Only process the first service
 	}
 
+	// Single-server fields describe the first server
+	serviceName := result.Servers[0].Name
+	serviceConfig := config.McpServers[serviceName]
 	result.ServiceName = serviceName
-
-	// Check if fields exist
+	result.ProtocolType = result.Servers[0].ProtocolType
 	result.HasArgs = len(serviceConfig.Args) > 0
 	result.HasCommand = serviceConfig.Command != ""
 	result.HasType = serviceConfig.Type != ""
 	result.HasTransport = serviceConfig.Transport != ""
 	result.HasURL = serviceConfig.URL != ""
-	if result.HasURL {
-		result.Url = serviceConfig.URL
-	}
+	result.Url = serviceConfig.URL
 
-	// Determine protocol type logic
-	protocolType := determineProtocolType(serviceConfig)
-	result.ProtocolType = protocolType
-
-	// Validate if protocol type is valid
-	validProtocol := false
-	for _, validT := range []string{model.McpProtocolStdio.String(), model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String()} {
-		if protocolType == validT {
-			validProtocol = true
-			break
-		}
-	}
-	if !validProtocol {
-		result.ErrorMessage = fmt.Sprintf("invalid protocol type: %s, valid values are: %v", protocolType, []string{model.McpProtocolStdio.String(), model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String()})
-		return result, nil
-	}
-
-	// Validate required fields based on protocol type
-	if err := validateProtocolFields(protocolType, serviceConfig); err != nil {
-		result.ErrorMessage = err.Error()
-		return result, nil
+	if len(errs) > 0 {
+		return result.fail(errs...), nil
 	}
 
 	// Validation successful
@@ -156,19 +204,19 @@ func determineProtocolType(config McpServerConfig) string {
 	return ""
 }
 
-// validateProtocolFields validates protocol fields
-func validateProtocolFields(protocolType string, config McpServerConfig) error {
+// validateProtocolFields validates protocol fields, path is the path of the service entry
+func validateProtocolFields(path, protocolType string, config McpServerConfig) *McpConfigError {
 	switch protocolType {
 	case model.McpProtocolSSE.String(), model.McpProtocolStreamableHttp.String():
 		if config.URL == "" {
-			return fmt.Errorf("%s protocol must contain a valid url field", protocolType)
+			return &McpConfigError{Path: path + ".url", Message: fmt.Sprintf("%s protocol must contain a valid url field", protocolType)}
 		}
 	case model.McpProtocolStdio.String():
 		if config.Command == "" {
-			return fmt.Errorf("%s protocol must contain a valid command field", protocolType)
+			return &McpConfigError{Path: path + ".command", Message: fmt.Sprintf("%s protocol must contain a valid command field", protocolType)}
 		}
 	default:
-		return fmt.Errorf("unknown protocol type: %s", protocolType)
+		return &McpConfigError{Path: path, Message: fmt.Sprintf("unknown protocol type: %s", protocolType)}
 	}
 	return nil
 }
@@ -223,5 +271,8 @@ func CompareMcpValidationResult(a, b *McpValidationResult) bool {
 	if a.Url != b.Url {
 		return false
 	}
+	if !reflect.DeepEqual(a.Servers, b.Servers) {
+		return false
+	}
 	return true
 }
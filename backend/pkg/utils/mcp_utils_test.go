@@ -0,0 +1,258 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestValidateMcpConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		wantValid    bool
+		wantProtocol string
+		wantErrors   []utils.McpConfigError
+	}{
+		{
+			name:         "sse url",
+			config:       `{"mcpServers":{"github":{"url":"https://mcp.example.com/sse","headers":{"Authorization":"Bearer x"}}}}`,
+			wantValid:    true,
+			wantProtocol: "sse",
+		},
+		{
+			name:         "stdio command",
+			config:       `{"mcpServers":{"fetch":{"command":"uvx","args":["mcp-server-fetch"],"env":{"DEBUG":"1"}}}}`,
+			wantValid:    true,
+			wantProtocol: "stdio",
+		},
+		{
+			name:       "syntax error position",
+			config:     "{\n  \"mcpServers\": {\n    \"github\": {\"url\": }\n  }\n}",
+			wantErrors: []utils.McpConfigError{{Message: "invalid JSON at line 3, column 23: invalid character '}' looking for beginning of value"}},
+		},
+		{
+			name:       "root is not an object",
+			config:     `[]`,
+			wantErrors: []utils.McpConfigError{{Message: "must be a JSON object"}},
+		},
+		{
+			name:   "unknown root key with suggestion",
+			config: `{"mcpServer":{"github":{"url":"https://mcp.example.com"}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServer", Message: `unknown field "mcpServer", did you mean "mcpServers"?`},
+				{Path: "mcpServers", Message: "is required"},
+			},
+		},
+		{
+			name:         "multiple servers",
+			config:       `{"mcpServers":{"b":{"url":"https://b.example.com/mcp"},"a":{"url":"https://a.example.com/mcp"}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:       "no servers",
+			config:     `{"mcpServers":{}}`,
+			wantErrors: []utils.McpConfigError{{Path: "mcpServers", Message: "must contain at least one server"}},
+		},
+		{
+			name:   "errors of every server are reported",
+			config: `{"mcpServers":{"a":{"type":"sse"},"b":{"type":"stdio"}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.a.url", Message: "sse protocol must contain a valid url field"},
+				{Path: "mcpServers.b.command", Message: "stdio protocol must contain a valid command field"},
+			},
+		},
+		{
+			name:   "invalid url and unknown field",
+			config: `{"mcpServers":{"github":{"URL":"https://mcp.example.com","url":"mcp.example.com/sse"}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.github.URL", Message: `unknown field "URL", did you mean "url"?`},
+				{Path: "mcpServers.github.url", Message: "must be a valid http(s) URL"},
+			},
+		},
+		{
+			name:   "non-string headers and env",
+			config: `{"mcpServers":{"fetch":{"command":"uvx","args":["a",1],"env":{"PORT":8080},"headers":{"X-Retry":true}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.fetch.args[1]", Message: "must be a string"},
+				{Path: "mcpServers.fetch.env.PORT", Message: "must be a string"},
+				{Path: "mcpServers.fetch.headers.X-Retry", Message: "must be a string"},
+			},
+		},
+		{
+			name:   "invalid server name",
+			config: `{"mcpServers":{"1github":{"url":"https://mcp.example.com"}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.1github", Message: "invalid server name, must start with a letter and contain only letters, digits, '_' or '-'"},
+			},
+		},
+		{
+			name:         "tls insecure skip verify",
+			config:       `{"mcpServers":{"internal":{"url":"https://mcp.internal/mcp","tls":{"insecureSkipVerify":true}}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:   "invalid tls section",
+			config: `{"mcpServers":{"internal":{"url":"https://mcp.internal/mcp","tls":{"caCert":"not a pem","clientCert":"x"}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.internal.tls.caCert", Message: "must contain PEM encoded certificates"},
+				{Path: "mcpServers.internal.tls", Message: "clientCert and clientKey must be set together"},
+			},
+		},
+		{
+			name:   "unknown tls field",
+			config: `{"mcpServers":{"internal":{"url":"https://mcp.internal/mcp","tls":{"caCerts":""}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.internal.tls.caCerts", Message: `unknown field "caCerts", did you mean "caCert"?`},
+			},
+		},
+		{
+			name:   "invalid proxy url",
+			config: `{"mcpServers":{"saas":{"url":"https://mcp.example.com/mcp","proxy":{"httpsProxy":"proxy.corp:3128","noProxy":".svc"}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.saas.proxy.httpsProxy", Message: `proxy URL must use the http, https or socks5 scheme, got "proxy.corp:3128"`},
+			},
+		},
+		{
+			name:         "json-rpc policy",
+			config:       `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","policy":{"denyTools":["delete_*"],"failOpen":true}}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:   "invalid policy pattern",
+			config: `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","policy":{"denyTools":["*_repo",""],"allowMethods":"tools/list"}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.ops.policy.allowMethods", Message: "must be an array of strings"},
+				{Path: "mcpServers.ops.policy.denyTools[0]", Message: "must be a name, '*' is only allowed at the end"},
+				{Path: "mcpServers.ops.policy.denyTools[1]", Message: "must be a name, '*' is only allowed at the end"},
+			},
+		},
+		{
+			name:         "response redaction",
+			config:       `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","redaction":{"patterns":["sk-[A-Za-z0-9]{20,}"],"literals":["s3cr3t"],"timeBudgetMs":50}}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:   "invalid redaction pattern",
+			config: `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","redaction":{"patterns":["sk-[a-z"]}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.ops.redaction.patterns[0]", Message: "invalid regular expression: error parsing regexp: missing closing ]: `[a-z`"},
+			},
+		},
+		{
+			name:         "canary routes",
+			config:       `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","routes":[{"targetURL":"https://mcp-v2.example.com/mcp","weight":10,"matchHeader":"X-Canary: v2"}]}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:   "invalid canary routes",
+			config: `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","routes":[{"targetURL":"https://a.example.com/mcp","weight":80},{"targetURL":"https://b.example.com/mcp","weight":30},{"weight":0}]}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.ops.routes[2].targetURL", Message: "is required"},
+				{Path: "mcpServers.ops.routes[2]", Message: "must set a weight or a matchHeader"},
+				{Path: "mcpServers.ops.routes", Message: "weights must not exceed 100 in total, got 110"},
+			},
+		},
+		{
+			name:         "cors",
+			config:       `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","cors":{"allowedOrigins":["https://*.example.com","http://localhost:3000"],"allowCredentials":true,"maxAge":600}}}}`,
+			wantValid:    true,
+			wantProtocol: "streamable-http",
+		},
+		{
+			name:   "cors credentials with any origin",
+			config: `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","cors":{"allowedOrigins":["*"],"allowCredentials":true}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.ops.cors.allowCredentials", Message: `allowCredentials cannot be used with the "*" origin, list the allowed origins instead`},
+			},
+		},
+		{
+			name:   "invalid cors origin",
+			config: `{"mcpServers":{"ops":{"url":"https://mcp.example.com/mcp","cors":{"allowedOrigins":["https://app.example.com/path"]}}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.ops.cors.allowedOrigins[0]", Message: `origin must be "*" or an http(s) origin such as https://app.example.com, got "https://app.example.com/path"`},
+			},
+		},
+		{
+			name:   "missing url for sse type",
+			config: `{"mcpServers":{"github":{"type":"sse"}}}`,
+			wantErrors: []utils.McpConfigError{
+				{Path: "mcpServers.github.url", Message: "sse protocol must contain a valid url field"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := utils.ValidateMcpConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("ValidateMcpConfig() error = %v", err)
+			}
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (%s)", result.IsValid, tt.wantValid, result.ErrorMessage)
+			}
+			if tt.wantValid && result.ProtocolType != tt.wantProtocol {
+				t.Errorf("ProtocolType = %q, want %q", result.ProtocolType, tt.wantProtocol)
+			}
+			got := make([]utils.McpConfigError, 0, len(result.Errors))
+			for _, e := range result.Errors {
+				got = append(got, *e)
+			}
+			if len(tt.wantErrors) == 0 {
+				tt.wantErrors = []utils.McpConfigError{}
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("Errors = %+v, want %+v", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestCheckProtocol(t *testing.T) {
+	single, _ := utils.ValidateMcpConfig([]byte(`{"mcpServers":{"github":{"url":"https://mcp.example.com/mcp"}}}`))
+	multi, _ := utils.ValidateMcpConfig([]byte(`{"mcpServers":{"github":{"url":"https://mcp.example.com/mcp"},"fetch":{"url":"https://fetch.example.com/sse"}}}`))
+	tests := []struct {
+		name           string
+		result         *utils.McpValidationResult
+		protocol       string
+		requireCommand bool
+		wantPaths      []string
+	}{
+		{"protocol matches", single, "streamable-http", false, nil},
+		{"protocol mismatch", single, "sse", false, []string{"mcpServers.github"}},
+		{"missing command", single, "", true, []string{"mcpServers.github.command"}},
+		{"mismatch of one server", multi, "streamable-http", false, []string{"mcpServers.fetch"}},
+		{"hosting with multiple servers", multi, "", true, []string{"mcpServers"}},
+		{"any protocol", multi, "", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range tt.result.CheckProtocol(tt.protocol, tt.requireCommand) {
+				got = append(got, e.Path)
+			}
+			if !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("CheckProtocol() paths = %v, want %v", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestValidateMcpConfigServers(t *testing.T) {
+	result, _ := utils.ValidateMcpConfig([]byte(`{"mcpServers":{"b":{"url":"https://b.example.com/sse"},"a":{"command":"uvx"}}}`))
+	want := []utils.McpServerSummary{
+		{Name: "a", ProtocolType: "stdio", HasCommand: true},
+		{Name: "b", ProtocolType: "sse", Url: "https://b.example.com/sse"},
+	}
+	if !reflect.DeepEqual(result.Servers, want) {
+		t.Errorf("Servers = %+v, want %+v", result.Servers, want)
+	}
+	if result.ServiceName != "a" {
+		t.Errorf("ServiceName = %q, want first server %q", result.ServiceName, "a")
+	}
+}
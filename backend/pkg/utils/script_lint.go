@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 脚本问题级别
+const (
+	ScriptSeverityError   = "error"
+	ScriptSeverityWarning = "warning"
+)
+
+// scriptSyntaxTimeout sh -n 语法检查超时时间
+const scriptSyntaxTimeout = 3 * time.Second
+
+// ScriptIssue 初始化脚本或启动命令的校验问题
+type ScriptIssue struct {
+	Field    string `json:"field"`    // initScript / command
+	Line     int    `json:"line"`     // 从 1 开始，0 表示无法定位
+	Severity string `json:"severity"` // error / warning
+	Message  string `json:"message"`
+}
+
+// ScriptLintOptions 脚本检查上下文
+type ScriptLintOptions struct {
+	Image   string            // 运行脚本的镜像，已知时检查包管理器是否匹配
+	EnvVars map[string]string // 容器环境变量，包括平台注入的变量
+}
+
+// scriptBuiltinVars 容器内 shell 默认可用的变量，不报告未定义
+var scriptBuiltinVars = map[string]bool{
+	"HOME": true, "PATH": true, "PWD": true, "OLDPWD": true, "HOSTNAME": true, "USER": true,
+	"SHELL": true, "TERM": true, "IFS": true, "LINENO": true, "RANDOM": true, "PPID": true,
+	"OPTARG": true, "OPTIND": true, "PS1": true, "PS2": true, "PS4": true, "LANG": true, "TMPDIR": true,
+}
+
+var (
+	// shSyntaxErrorRegex 匹配 dash (sh: 3: ...) 和 busybox/bash (sh: line 3: ...) 的语法错误输出
+	shSyntaxErrorRegex = regexp.MustCompile(`^[^:]*: (?:line )?(\d+): (.*)$`)
+	scriptAssignRegex  = regexp.MustCompile(`(?:^|[\s;&|(])(?:export\s+|local\s+|readonly\s+)?([A-Za-z_][A-Za-z0-9_]*)=`)
+	scriptForRegex     = regexp.MustCompile(`\bfor\s+([A-Za-z_][A-Za-z0-9_]*)\s+in\b`)
+	scriptReadRegex    = regexp.MustCompile(`\bread\s+((?:-[a-zA-Z]+\s+)*)([A-Za-z_][A-Za-z0-9_ ]*)`)
+	scriptVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// LintScript 检查脚本语法和常见错误，脚本只做语法解析不会执行
+func LintScript(ctx context.Context, field, script string, opts ScriptLintOptions) []ScriptIssue {
+	if strings.TrimSpace(script) == "" {
+		return nil
+	}
+	issues := CheckScriptSyntax(ctx, field, script)
+	issues = append(issues, lintScriptContent(field, script, opts)...)
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// CheckScriptSyntax 使用 sh -n 检查语法，环境中没有 sh 时跳过
+func CheckScriptSyntax(ctx context.Context, field, script string) []ScriptIssue {
+	shell, err := exec.LookPath("/bin/sh")
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, scriptSyntaxTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, shell, "-n")
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		return nil
+	} else if ctx.Err() != nil {
+		return []ScriptIssue{{Field: field, Severity: ScriptSeverityWarning, Message: "syntax check timed out"}}
+	}
+
+	var issues []ScriptIssue
+	for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		issue := ScriptIssue{Field: field, Severity: ScriptSeverityError, Message: line}
+		if m := shSyntaxErrorRegex.FindStringSubmatch(line); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Message = m[2]
+		}
+		issues = append(issues, issue)
+	}
+	if len(issues) == 0 {
+		issues = append(issues, ScriptIssue{Field: field, Severity: ScriptSeverityError, Message: "syntax error"})
+	}
+	return issues
+}
+
+// scriptLine 去掉注释后的脚本行，code 不含引号内容，expand 不含单引号内容
+type scriptLine struct {
+	code   string
+	expand string
+}
+
+// splitScriptLines 按 shell 引号规则拆分脚本，引号可以跨行
+func splitScriptLines(script string) []scriptLine {
+	lines := []scriptLine{}
+	var code, expand strings.Builder
+	inSingle, inDouble, inComment := false, false, false
+	flush := func() {
+		lines = append(lines, scriptLine{code: code.String(), expand: expand.String()})
+		code.Reset()
+		expand.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		if c == '\n' {
+			inComment = false
+			flush()
+			continue
+		}
+		switch {
+		case inComment:
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			}
+		case c == '\\' && i+1 < len(script) && script[i+1] != '\n':
+			// 转义字符原样跳过，不参与变量展开
+			i++
+			if !inDouble {
+				code.WriteByte(' ')
+			}
+		case inDouble:
+			if c == '"' {
+				inDouble = false
+				continue
+			}
+			expand.WriteByte(c)
+		case c == '\'':
+			inSingle = true
+		case c == '"':
+			inDouble = true
+		case c == '#' && (code.Len() == 0 || strings.ContainsRune(" \t;&|(", rune(code.String()[code.Len()-1]))):
+			inComment = true
+		default:
+			code.WriteByte(c)
+			expand.WriteByte(c)
+		}
+	}
+	flush()
+	return lines
+}
+
+// lintScriptContent 检查 shebang、包管理器和未定义变量
+func lintScriptContent(field, script string, opts ScriptLintOptions) []ScriptIssue {
+	var issues []ScriptIssue
+	rawLines := strings.Split(script, "\n")
+	for i, raw := range rawLines {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		// 脚本被嵌入平台生成的启动脚本，由 /bin/sh 执行，shebang 不会生效
+		if strings.HasPrefix(raw, "#!") && !strings.HasSuffix(raw, "/sh") && !strings.HasSuffix(raw, " sh") {
+			issues = append(issues, ScriptIssue{
+				Field: field, Line: i + 1, Severity: ScriptSeverityWarning,
+				Message: fmt.Sprintf("shebang %q is ignored, the script is run by /bin/sh", raw),
+			})
+		}
+		break
+	}
+
+	lines := splitScriptLines(script)
+	alpine := strings.Contains(strings.ToLower(opts.Image), "alpine")
+	defined := scriptDefinedVars(lines)
+	reported := map[string]bool{}
+	for i, line := range lines {
+		if alpine {
+			for _, word := range strings.FieldsFunc(line.code, func(r rune) bool { return strings.ContainsRune(" \t;&|()", r) }) {
+				if word == "apt" || word == "apt-get" {
+					issues = append(issues, ScriptIssue{
+						Field: field, Line: i + 1, Severity: ScriptSeverityWarning,
+						Message: fmt.Sprintf("%s is not available on alpine image %s, use apk instead", word, opts.Image),
+					})
+					break
+				}
+			}
+		}
+		for _, name := range scriptVarRefs(line.expand) {
+			if reported[name] || defined[name] || scriptBuiltinVars[name] {
+				continue
+			}
+			if _, ok := opts.EnvVars[name]; ok {
+				continue
+			}
+			reported[name] = true
+			issues = append(issues, ScriptIssue{
+				Field: field, Line: i + 1, Severity: ScriptSeverityWarning,
+				Message: fmt.Sprintf("variable %s is not set in the environment variables or the script", name),
+			})
+		}
+	}
+	return issues
+}
+
+// scriptDefinedVars 收集脚本中赋值、for 循环和 read 定义的变量
+func scriptDefinedVars(lines []scriptLine) map[string]bool {
+	defined := map[string]bool{}
+	for _, line := range lines {
+		for _, m := range scriptAssignRegex.FindAllStringSubmatch(line.code, -1) {
+			defined[m[1]] = true
+		}
+		for _, m := range scriptForRegex.FindAllStringSubmatch(line.code, -1) {
+			defined[m[1]] = true
+		}
+		for _, m := range scriptReadRegex.FindAllStringSubmatch(line.code, -1) {
+			for _, name := range strings.Fields(m[2]) {
+				defined[name] = true
+			}
+		}
+	}
+	return defined
+}
+
+// scriptVarRefs 返回 $NAME 和 ${NAME} 引用，带默认值的 ${NAME:-x} 等形式不算未定义
+func scriptVarRefs(text string) []string {
+	var names []string
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 >= len(text) {
+			continue
+		}
+		rest := text[i+1:]
+		braced := strings.HasPrefix(rest, "{")
+		if braced {
+			rest = strings.TrimPrefix(rest[1:], "#")
+		}
+		name := scriptVarNameRegex.FindString(rest)
+		if name == "" {
+			continue
+		}
+		if braced {
+			after := rest[len(name):]
+			if after != "" && strings.ContainsRune(":-=?+", rune(after[0])) {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
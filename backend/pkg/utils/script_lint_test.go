@@ -0,0 +1,80 @@
+package utils_test
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"qm-mcp-server/pkg/utils"
+)
+
+func TestLintScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   utils.ScriptLintOptions
+		want   []utils.ScriptIssue
+	}{
+		{
+			name:   "clean script",
+			script: "#!/bin/sh\nDIR=/app\nfor f in a b; do echo \"$f $DIR $MCP_PORT\"; done\nread -r NAME\necho $NAME ${HOME}",
+			opts:   utils.ScriptLintOptions{EnvVars: map[string]string{"MCP_PORT": "8080"}},
+		},
+		{
+			name:   "ignored shebang",
+			script: "\n#!/bin/bash\necho ok",
+			want: []utils.ScriptIssue{
+				{Field: "initScript", Line: 2, Severity: utils.ScriptSeverityWarning, Message: `shebang "#!/bin/bash" is ignored, the script is run by /bin/sh`},
+			},
+		},
+		{
+			name:   "apt on alpine",
+			script: "set -e\napk add curl && apt-get install -y git\necho 'apt is fine in quotes' # apt",
+			opts:   utils.ScriptLintOptions{Image: "node:20-alpine"},
+			want: []utils.ScriptIssue{
+				{Field: "initScript", Line: 2, Severity: utils.ScriptSeverityWarning, Message: "apt-get is not available on alpine image node:20-alpine, use apk instead"},
+			},
+		},
+		{
+			name:   "apt on unknown image",
+			script: "apt-get update",
+		},
+		{
+			name:   "unresolved variables",
+			script: "echo $TOKEN\necho \"${API_KEY}\" '$QUOTED' ${OPT:-x} \\$ESCAPED\n# $COMMENT\necho $TOKEN",
+			want: []utils.ScriptIssue{
+				{Field: "initScript", Line: 1, Severity: utils.ScriptSeverityWarning, Message: "variable TOKEN is not set in the environment variables or the script"},
+				{Field: "initScript", Line: 2, Severity: utils.ScriptSeverityWarning, Message: "variable API_KEY is not set in the environment variables or the script"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.LintScript(context.Background(), "initScript", tt.script, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintScript() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckScriptSyntax(t *testing.T) {
+	if _, err := exec.LookPath("/bin/sh"); err != nil {
+		t.Skip("/bin/sh not available")
+	}
+
+	if issues := utils.CheckScriptSyntax(context.Background(), "command", "echo ok\nnode server.js"); len(issues) != 0 {
+		t.Errorf("CheckScriptSyntax() on a valid script = %+v, want none", issues)
+	}
+
+	// 语法检查只解析不执行
+	issues := utils.CheckScriptSyntax(context.Background(), "command", "touch /tmp/should-not-exist\nif true; then\necho hi\n")
+	if len(issues) != 1 {
+		t.Fatalf("CheckScriptSyntax() = %+v, want one error", issues)
+	}
+	if issues[0].Severity != utils.ScriptSeverityError || issues[0].Line != 4 || issues[0].Field != "command" {
+		t.Errorf("CheckScriptSyntax() = %+v, want error at line 4", issues[0])
+	}
+}